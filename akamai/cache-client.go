@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/jmhodges/clock"
+	"github.com/letsencrypt/boulder/cdn"
 	"github.com/letsencrypt/boulder/core"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
@@ -57,12 +58,6 @@ type CachePurgeClient struct {
 	clk          clock.Clock
 }
 
-// errFatal is used by CachePurgeClient.purge to indicate that it failed for a
-// reason that cannot be remediated by retying a purge request
-type errFatal string
-
-func (e errFatal) Error() string { return string(e) }
-
 var (
 	// ErrAllRetriesFailed lets the caller of Purge to know if all the purge submission
 	// attempts failed
@@ -169,7 +164,7 @@ func (cpc *CachePurgeClient) purge(urls []string) error {
 
 	reqJSON, err := json.Marshal(purgeReq)
 	if err != nil {
-		return errFatal(err.Error())
+		return cdn.FatalError(err.Error())
 	}
 	req, err := http.NewRequest(
 		"POST",
@@ -177,7 +172,7 @@ func (cpc *CachePurgeClient) purge(urls []string) error {
 		bytes.NewBuffer(reqJSON),
 	)
 	if err != nil {
-		return errFatal(err.Error())
+		return cdn.FatalError(err.Error())
 	}
 
 	// Create authorization header for request
@@ -187,7 +182,7 @@ func (cpc *CachePurgeClient) purge(urls []string) error {
 		core.RandomString(16),
 	)
 	if err != nil {
-		return errFatal(err.Error())
+		return cdn.FatalError(err.Error())
 	}
 	req.Header.Set("Authorization", authHeader)
 	req.Header.Set("Content-Type", "application/json")
@@ -214,6 +209,17 @@ func (cpc *CachePurgeClient) purge(urls []string) error {
 		return err
 	}
 
+	// Akamai rate limits purge requests with a 429, and its errors while
+	// overloaded may not be JSON, so these are retried without looking at the
+	// body. Any other 4xx means the request itself is bad and won't succeed
+	// if it's sent again.
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return fmt.Errorf("Unexpected HTTP status code '%d': %s", resp.StatusCode, string(body))
+	}
+	if resp.StatusCode >= 400 {
+		return cdn.FatalError(fmt.Sprintf("Unexpected HTTP status code '%d': %s", resp.StatusCode, string(body)))
+	}
+
 	// Check purge was successful
 	var purgeInfo purgeResponse
 	err = json.Unmarshal(body, &purgeInfo)
@@ -222,7 +228,7 @@ func (cpc *CachePurgeClient) purge(urls []string) error {
 	}
 	if purgeInfo.HTTPStatus != http.StatusCreated || resp.StatusCode != http.StatusCreated {
 		if purgeInfo.HTTPStatus == http.StatusForbidden {
-			return errFatal(fmt.Sprintf("Unauthorized to purge URLs %q", urls))
+			return cdn.FatalError(fmt.Sprintf("Unauthorized to purge URLs %q", urls))
 		}
 		return fmt.Errorf("Unexpected HTTP status code '%d': %s", resp.StatusCode, string(body))
 	}
@@ -240,7 +246,7 @@ func (cpc *CachePurgeClient) purgeBatch(urls []string) error {
 
 		err := cpc.purge(urls)
 		if err != nil {
-			if _, ok := err.(errFatal); ok {
+			if cdn.IsFatal(err) {
 				cpc.stats.Inc("FatalFailures", 1)
 				return err
 			}
@@ -263,19 +269,34 @@ func (cpc *CachePurgeClient) purgeBatch(urls []string) error {
 
 var akamaiBatchSize = 100
 
+// akamaiBytesPerRequest is the largest request body the CCU v3 API accepts.
+// Leave some room for the JSON around the URLs.
+var akamaiBytesPerRequest = 50000 - 100
+
+// batchSize returns how many of the URLs at the start of urls fit in a single
+// purge request.
+func batchSize(urls []string) int {
+	size := 0
+	for i, u := range urls {
+		// Each URL is quoted and followed by a comma
+		size += len(u) + 3
+		if i == akamaiBatchSize || (i > 0 && size > akamaiBytesPerRequest) {
+			return i
+		}
+	}
+	return len(urls)
+}
+
 // Purge attempts to send a purge request to the Akamai CCU API cpc.retries number
 //  of times before giving up and returning ErrAllRetriesFailed
 func (cpc *CachePurgeClient) Purge(urls []string) error {
-	for i := 0; i < len(urls); {
-		sliceEnd := i + akamaiBatchSize
-		if sliceEnd > len(urls) {
-			sliceEnd = len(urls)
-		}
-		err := cpc.purgeBatch(urls[i:sliceEnd])
+	for len(urls) > 0 {
+		n := batchSize(urls)
+		err := cpc.purgeBatch(urls[:n])
 		if err != nil {
 			return err
 		}
-		i += akamaiBatchSize
+		urls = urls[n:]
 	}
 	return nil
 }
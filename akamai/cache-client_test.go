@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/jmhodges/clock"
+	"github.com/letsencrypt/boulder/cdn"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
 	"github.com/letsencrypt/boulder/test"
@@ -157,6 +158,16 @@ func TestV3Purge(t *testing.T) {
 	err = client.Purge([]string{"http:/test.com"})
 	test.AssertError(t, err, "Purge didn't fail with 403 response from malformed URL")
 	test.Assert(t, client.clk.Since(started) < time.Second, "Purge should've failed out immediately")
+
+	as.responseCode = http.StatusTooManyRequests
+	err = client.Purge([]string{"http://test.com"})
+	test.AssertEquals(t, err, ErrAllRetriesFailed)
+
+	started = client.clk.Now()
+	as.responseCode = http.StatusBadRequest
+	err = client.Purge([]string{"http://test.com"})
+	test.Assert(t, cdn.IsFatal(err), "Purge didn't fail fatally with 400 response")
+	test.Assert(t, client.clk.Since(started) < time.Second, "Purge should've failed out immediately")
 }
 
 func TestNewCachePurgeClient(t *testing.T) {
@@ -0,0 +1,83 @@
+package bdns
+
+import (
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+)
+
+// CapturedExchange is one DNS query sent to a resolver and the response it
+// returned, in wire format.
+type CapturedExchange struct {
+	Time     time.Time
+	Server   string
+	Query    []byte
+	Response []byte `json:",omitempty"`
+	// Error is set if no response was received.
+	Error string `json:",omitempty"`
+}
+
+// Capture records the raw DNS messages exchanged by the lookups made with a
+// context returned by WithCapture, e.g. so that what the resolvers said during
+// a validation can be reconstructed later. Responses are recorded before
+// DNSClientImpl changes them in any way. A nil *Capture records nothing.
+type Capture struct {
+	mu        sync.Mutex
+	exchanges []CapturedExchange
+}
+
+type captureKey struct{}
+
+// WithCapture returns a context that makes DNSClientImpl record its DNS
+// exchanges in c.
+func WithCapture(ctx context.Context, c *Capture) context.Context {
+	return context.WithValue(ctx, captureKey{}, c)
+}
+
+// captureFrom returns the Capture of ctx, or nil if it has none.
+func captureFrom(ctx context.Context) *Capture {
+	c, _ := ctx.Value(captureKey{}).(*Capture)
+	return c
+}
+
+// add records an exchange of query with server. Messages are stored with
+// name compression, as they're normally sent.
+func (c *Capture) add(t time.Time, server string, query, resp *dns.Msg, err error) {
+	if c == nil {
+		return
+	}
+	e := CapturedExchange{Time: t, Server: server}
+	e.Query, _ = packCompressed(query)
+	if resp != nil {
+		var packErr error
+		e.Response, packErr = packCompressed(resp)
+		if packErr != nil && err == nil {
+			err = packErr
+		}
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.exchanges = append(c.exchanges, e)
+}
+
+func packCompressed(m *dns.Msg) ([]byte, error) {
+	m = m.Copy()
+	m.Compress = true
+	return m.Pack()
+}
+
+// Exchanges returns the exchanges recorded so far, in the order they
+// finished.
+func (c *Capture) Exchanges() []CapturedExchange {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]CapturedExchange(nil), c.exchanges...)
+}
@@ -0,0 +1,54 @@
+package bdns
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestCapture(t *testing.T) {
+	fc := clock.NewFake()
+	fc.Set(time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC))
+	client := NewTestDNSClientImpl(time.Second*10, []string{"a"}, testStats, fc, 1)
+	client.dnsClient = &truncatingExchanger{}
+	client.tcpClient = &clientSubnetExchanger{}
+
+	// Lookups without a capture aren't recorded anywhere
+	_, _, err := client.LookupTXT(context.Background(), "example.com")
+	test.AssertNotError(t, err, "LookupTXT failed")
+
+	capture := &Capture{}
+	_, _, err = client.LookupTXT(WithCapture(context.Background(), capture), "example.com")
+	test.AssertNotError(t, err, "LookupTXT failed")
+	exchanges := capture.Exchanges()
+	// The truncated UDP response and the TCP retry
+	test.AssertEquals(t, len(exchanges), 2)
+	for _, e := range exchanges {
+		test.AssertEquals(t, e.Server, "a")
+		test.AssertEquals(t, e.Time, fc.Now())
+		test.AssertEquals(t, e.Error, "")
+		var query dns.Msg
+		test.AssertNotError(t, query.Unpack(e.Query), "captured query didn't unpack")
+		test.AssertEquals(t, query.Question[0].Name, "example.com.")
+		test.AssertEquals(t, query.Question[0].Qtype, dns.TypeTXT)
+	}
+
+	// dns.Msg refuses to unpack truncated messages, so check the TC bit of
+	// the header directly
+	test.Assert(t, exchanges[0].Response[2]&0x02 != 0, "captured UDP response wasn't truncated")
+	var resp dns.Msg
+	test.AssertNotError(t, resp.Unpack(exchanges[1].Response), "captured response didn't unpack")
+	test.AssertEquals(t, resp.Answer[0].(*dns.TXT).Txt[0], "hello")
+	// The response is captured as the resolver sent it, before the client
+	// subnet option is stripped
+	test.AssertEquals(t, len(resp.IsEdns0().Option), 2)
+
+	var nilCapture *Capture
+	nilCapture.add(fc.Now(), "a", new(dns.Msg), nil, nil)
+	test.AssertEquals(t, len(nilCapture.Exchanges()), 0)
+}
@@ -151,17 +151,40 @@ type DNSClient interface {
 	LookupMX(context.Context, string) ([]string, error)
 }
 
+// defaultUDPSize is the largest UDP response we tell resolvers we accept,
+// unless configured otherwise. This is needed sometimes when there are a very
+// large number of CAA records present.
+const defaultUDPSize = 4096
+
+// EDNSConfig configures the EDNS0 OPT record sent with each query.
+type EDNSConfig struct {
+	// UDPSize is the largest UDP response resolvers are told we accept. Larger
+	// responses are truncated and the query is retried over TCP. Defaults to
+	// 4096.
+	UDPSize uint16
+	// DNSSEC sets the DO bit in queries, asking resolvers to include DNSSEC
+	// records in their responses.
+	DNSSEC bool
+}
+
 // DNSClientImpl represents a client that talks to an external resolver
 type DNSClientImpl struct {
 	dnsClient                exchanger
+	tcpClient                exchanger
 	servers                  []string
+	zones                    []zone
 	allowRestrictedAddresses bool
 	maxTries                 int
+	udpSize                  uint16
+	dnssec                   bool
 	clk                      clock.Clock
 
-	queryTime       *prometheus.HistogramVec
-	totalLookupTime *prometheus.HistogramVec
-	timeoutCounter  *prometheus.CounterVec
+	queryTime           *prometheus.HistogramVec
+	totalLookupTime     *prometheus.HistogramVec
+	timeoutCounter      *prometheus.CounterVec
+	truncatedCounter    *prometheus.CounterVec
+	tcpRetryCounter     *prometheus.CounterVec
+	clientSubnetCounter *prometheus.CounterVec
 }
 
 var _ DNSClient = &DNSClientImpl{}
@@ -186,6 +209,10 @@ func NewDNSClientImpl(
 	// Set timeout for underlying net.Conn
 	dnsClient.ReadTimeout = readTimeout
 	dnsClient.Net = "udp"
+	// Truncated responses are retried over TCP
+	tcpClient := new(dns.Client)
+	tcpClient.ReadTimeout = readTimeout
+	tcpClient.Net = "tcp"
 
 	queryTime := prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -210,18 +237,60 @@ func NewDNSClientImpl(
 		},
 		[]string{"qtype", "type", "resolver"},
 	)
-	stats.MustRegister(queryTime, totalLookupTime, timeoutCounter)
+	truncatedCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_truncated",
+			Help: "Counter of UDP responses that were truncated and retried over TCP",
+		},
+		[]string{"qtype", "resolver"},
+	)
+	tcpRetryCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_tcp_retries",
+			Help: "Counter of queries retried over TCP after a truncated UDP response, by result",
+		},
+		[]string{"qtype", "result"},
+	)
+	clientSubnetCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dns_client_subnet_stripped",
+			Help: "Counter of responses with an EDNS Client Subnet option that was stripped",
+		},
+		[]string{"resolver"},
+	)
+	stats.MustRegister(queryTime, totalLookupTime, timeoutCounter, truncatedCounter, tcpRetryCounter, clientSubnetCounter)
 
 	return &DNSClientImpl{
 		dnsClient:                dnsClient,
+		tcpClient:                tcpClient,
 		servers:                  servers,
 		allowRestrictedAddresses: false,
 		maxTries:                 maxTries,
+		udpSize:                  defaultUDPSize,
 		clk:                      clk,
 		queryTime:                queryTime,
 		totalLookupTime:          totalLookupTime,
 		timeoutCounter:           timeoutCounter,
+		truncatedCounter:         truncatedCounter,
+		tcpRetryCounter:          tcpRetryCounter,
+		clientSubnetCounter:      clientSubnetCounter,
+	}
+}
+
+// SetEDNS configures the EDNS0 OPT record sent with each query.
+func (dnsClient *DNSClientImpl) SetEDNS(config EDNSConfig) error {
+	udpSize := config.UDPSize
+	if udpSize == 0 {
+		udpSize = defaultUDPSize
 	}
+	// Resolvers treat sizes below 512 as 512 (RFC 6891 Section 6.2.5), so
+	// don't pretend to configure one
+	if udpSize < dns.MinMsgSize {
+		return fmt.Errorf("EDNS UDP size %d is smaller than the minimum of %d", udpSize, dns.MinMsgSize)
+	}
+	dnsClient.udpSize = udpSize
+	dnsClient.dnssec = config.DNSSEC
+	return nil
 }
 
 // NewTestDNSClientImpl constructs a new DNS resolver object that utilizes the
@@ -234,7 +303,7 @@ func NewTestDNSClientImpl(readTimeout time.Duration, servers []string, stats met
 }
 
 // exchangeOne performs a single DNS exchange with a randomly chosen server
-// out of the server list, returning the response, time, and error (if any).
+// out of the server list for hostname (see SetZones), returning the response, time, and error (if any).
 // We assume that the upstream resolver requests and validates DNSSEC records
 // itself.
 func (dnsClient *DNSClientImpl) exchangeOne(ctx context.Context, hostname string, qtype uint16) (resp *dns.Msg, err error) {
@@ -248,22 +317,25 @@ func (dnsClient *DNSClientImpl) exchangeOne(ctx context.Context, hostname string
 	// metrics about the percentage of responses that are secured with
 	// DNSSEC.
 	m.AuthenticatedData = true
-	// Tell the resolver that we're willing to receive responses up to 4096 bytes.
-	// This happens sometimes when there are a very large number of CAA records
-	// present.
-	m.SetEdns0(4096, false)
-
-	if len(dnsClient.servers) < 1 {
+	// Tell the resolver how large a UDP response we're willing to receive, and
+	// whether to include DNSSEC records. The OPT record has no options: in
+	// particular we never send an EDNS Client Subnet option (RFC 7871), which
+	// would let the answer depend on the network we query from.
+	m.SetEdns0(dnsClient.udpSize, dnsClient.dnssec)
+
+	servers := dnsClient.serversFor(hostname)
+	if len(servers) < 1 {
 		return nil, fmt.Errorf("Not configured with at least one DNS Server")
 	}
 
 	// Randomly pick a server
-	chosenServerIndex := rand.Intn(len(dnsClient.servers))
-	chosenServer := dnsClient.servers[chosenServerIndex]
+	chosenServerIndex := rand.Intn(len(servers))
+	chosenServer := servers[chosenServerIndex]
 
 	start := dnsClient.clk.Now()
 	client := dnsClient.dnsClient
 	qtypeStr := dns.TypeToString[qtype]
+	capture := captureFrom(ctx)
 	tries := 1
 	defer func() {
 		result, authenticated := "failed", ""
@@ -283,18 +355,29 @@ func (dnsClient *DNSClientImpl) exchangeOne(ctx context.Context, hostname string
 		ch := make(chan dnsResp, 1)
 
 		go func() {
-			rsp, rtt, err := client.Exchange(m, chosenServer)
-			result, authenticated := "failed", ""
-			if rsp != nil {
-				result = dns.RcodeToString[rsp.Rcode]
-				authenticated = fmt.Sprintf("%t", rsp.AuthenticatedData)
+			rsp, err := dnsClient.exchange(client, m, chosenServer, qtypeStr)
+			capture.add(dnsClient.clk.Now(), chosenServer, m, rsp, err)
+			if err == nil && rsp != nil && rsp.Truncated {
+				dnsClient.truncatedCounter.With(prometheus.Labels{
+					"qtype":    qtypeStr,
+					"resolver": chosenServer,
+				}).Inc()
+				rsp, err = dnsClient.exchange(dnsClient.tcpClient, m, chosenServer, qtypeStr)
+				capture.add(dnsClient.clk.Now(), chosenServer, m, rsp, err)
+				result := "success"
+				if err != nil {
+					result = "failed"
+				}
+				dnsClient.tcpRetryCounter.With(prometheus.Labels{
+					"qtype":  qtypeStr,
+					"result": result,
+				}).Inc()
+			}
+			if rsp != nil && stripClientSubnet(rsp) {
+				dnsClient.clientSubnetCounter.With(prometheus.Labels{
+					"resolver": chosenServer,
+				}).Inc()
 			}
-			dnsClient.queryTime.With(prometheus.Labels{
-				"qtype":              qtypeStr,
-				"result":             result,
-				"authenticated_data": authenticated,
-				"resolver":           chosenServer,
-			}).Observe(rtt.Seconds())
 			ch <- dnsResp{m: rsp, err: err}
 		}()
 		select {
@@ -331,8 +414,8 @@ func (dnsClient *DNSClientImpl) exchangeOne(ctx context.Context, hostname string
 					// chosen server index modulo the number of servers. This ensures that
 					// if one dns server isn't available we retry with the next in the
 					// list.
-					chosenServerIndex = (chosenServerIndex + 1) % len(dnsClient.servers)
-					chosenServer = dnsClient.servers[chosenServerIndex]
+					chosenServerIndex = (chosenServerIndex + 1) % len(servers)
+					chosenServer = servers[chosenServerIndex]
 					continue
 				} else if isRetryable && !hasRetriesLeft {
 					dnsClient.timeoutCounter.With(prometheus.Labels{
@@ -354,6 +437,44 @@ type dnsResp struct {
 	err error
 }
 
+// exchange sends a query to server with client and records the query time.
+func (dnsClient *DNSClientImpl) exchange(client exchanger, m *dns.Msg, server, qtypeStr string) (*dns.Msg, error) {
+	rsp, rtt, err := client.Exchange(m, server)
+	result, authenticated := "failed", ""
+	if rsp != nil {
+		result = dns.RcodeToString[rsp.Rcode]
+		authenticated = fmt.Sprintf("%t", rsp.AuthenticatedData)
+	}
+	dnsClient.queryTime.With(prometheus.Labels{
+		"qtype":              qtypeStr,
+		"result":             result,
+		"authenticated_data": authenticated,
+		"resolver":           server,
+	}).Observe(rtt.Seconds())
+	return rsp, err
+}
+
+// stripClientSubnet removes any EDNS Client Subnet options from a response's
+// OPT record and reports whether there were any. We never send one, so a
+// resolver that returns one is misbehaving, and its answer mustn't be treated
+// as scoped to some client network.
+func stripClientSubnet(m *dns.Msg) bool {
+	opt := m.IsEdns0()
+	if opt == nil {
+		return false
+	}
+	var kept []dns.EDNS0
+	for _, option := range opt.Option {
+		if option.Option() == dns.EDNS0SUBNET || option.Option() == dns.EDNS0SUBNETDRAFT {
+			continue
+		}
+		kept = append(kept, option)
+	}
+	stripped := len(kept) != len(opt.Option)
+	opt.Option = kept
+	return stripped
+}
+
 // LookupTXT sends a DNS query to find all TXT records associated with
 // the provided hostname which it returns along with the returned
 // DNS authority section.
@@ -741,3 +741,109 @@ func TestRotateServerOnErr(t *testing.T) {
 	// We expect that the C server eventually served all of the lookups attempted
 	test.AssertEquals(t, mock.lookups["c"], maxTries*2)
 }
+
+// truncatingExchanger is a dns.Exchange implementation that returns a
+// truncated response, and records the queries it was sent. It's used over UDP
+// by TestTruncatedRetryOverTCP.
+type truncatingExchanger struct {
+	queries []*dns.Msg
+}
+
+func (e *truncatingExchanger) Exchange(m *dns.Msg, a string) (*dns.Msg, time.Duration, error) {
+	e.queries = append(e.queries, m)
+	resp := new(dns.Msg)
+	resp.SetReply(m)
+	resp.Truncated = true
+	return resp, 2 * time.Millisecond, nil
+}
+
+// clientSubnetExchanger is a dns.Exchange implementation that answers with a
+// TXT record and an EDNS Client Subnet option, and records the queries it was
+// sent.
+type clientSubnetExchanger struct {
+	queries []*dns.Msg
+}
+
+func (e *clientSubnetExchanger) Exchange(m *dns.Msg, a string) (*dns.Msg, time.Duration, error) {
+	e.queries = append(e.queries, m)
+	resp := new(dns.Msg)
+	resp.SetReply(m)
+	resp.Answer = append(resp.Answer, &dns.TXT{
+		Hdr: dns.RR_Header{Name: m.Question[0].Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET},
+		Txt: []string{"hello"},
+	})
+	resp.SetEdns0(4096, false)
+	opt := resp.IsEdns0()
+	opt.Option = append(opt.Option,
+		&dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, SourceScope: 24, Address: net.ParseIP("192.0.2.0").To4()},
+		&dns.EDNS0_NSID{Code: dns.EDNS0NSID, Nsid: "abcd"},
+	)
+	return resp, 2 * time.Millisecond, nil
+}
+
+func TestTruncatedRetryOverTCP(t *testing.T) {
+	client := NewTestDNSClientImpl(time.Second*10, []string{"a"}, testStats, clock.NewFake(), 1)
+	udp := &truncatingExchanger{}
+	tcp := &clientSubnetExchanger{}
+	client.dnsClient = udp
+	client.tcpClient = tcp
+
+	txts, _, err := client.LookupTXT(context.Background(), "example.com")
+	test.AssertNotError(t, err, "LookupTXT failed")
+	test.AssertDeepEquals(t, txts, []string{"hello"})
+	test.AssertEquals(t, len(udp.queries), 1)
+	test.AssertEquals(t, len(tcp.queries), 1)
+	test.AssertEquals(t, test.CountCounter(client.truncatedCounter.With(prometheus.Labels{
+		"qtype":    "TXT",
+		"resolver": "a",
+	})), 1)
+	test.AssertEquals(t, test.CountCounter(client.tcpRetryCounter.With(prometheus.Labels{
+		"qtype":  "TXT",
+		"result": "success",
+	})), 1)
+}
+
+func TestEDNS(t *testing.T) {
+	client := NewTestDNSClientImpl(time.Second*10, []string{"a"}, testStats, clock.NewFake(), 1)
+	mock := &clientSubnetExchanger{}
+	client.dnsClient = mock
+
+	// By default a 4096 byte UDP size is sent without the DO bit
+	_, _, err := client.LookupTXT(context.Background(), "example.com")
+	test.AssertNotError(t, err, "LookupTXT failed")
+	opt := mock.queries[0].IsEdns0()
+	test.Assert(t, opt != nil, "query had no OPT record")
+	test.AssertEquals(t, opt.UDPSize(), uint16(4096))
+	test.Assert(t, !opt.Do(), "query had the DO bit set")
+	test.AssertEquals(t, len(opt.Option), 0)
+	test.AssertEquals(t, test.CountCounter(client.clientSubnetCounter.With(prometheus.Labels{
+		"resolver": "a",
+	})), 1)
+
+	err = client.SetEDNS(EDNSConfig{UDPSize: 1232, DNSSEC: true})
+	test.AssertNotError(t, err, "SetEDNS failed")
+	_, _, err = client.LookupTXT(context.Background(), "example.com")
+	test.AssertNotError(t, err, "LookupTXT failed")
+	opt = mock.queries[1].IsEdns0()
+	test.AssertEquals(t, opt.UDPSize(), uint16(1232))
+	test.Assert(t, opt.Do(), "query didn't have the DO bit set")
+
+	err = client.SetEDNS(EDNSConfig{UDPSize: 100})
+	test.AssertError(t, err, "SetEDNS accepted a UDP size below 512")
+}
+
+func TestStripClientSubnet(t *testing.T) {
+	m := new(dns.Msg)
+	test.Assert(t, !stripClientSubnet(m), "stripped a client subnet from a response without EDNS")
+
+	m.SetEdns0(4096, false)
+	opt := m.IsEdns0()
+	opt.Option = []dns.EDNS0{
+		&dns.EDNS0_NSID{Code: dns.EDNS0NSID, Nsid: "abcd"},
+		&dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP("192.0.2.0").To4()},
+	}
+	test.Assert(t, stripClientSubnet(m), "didn't strip a client subnet")
+	test.AssertEquals(t, len(opt.Option), 1)
+	test.AssertEquals(t, opt.Option[0].Option(), uint16(dns.EDNS0NSID))
+	test.Assert(t, !stripClientSubnet(m), "stripped a client subnet twice")
+}
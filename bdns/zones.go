@@ -0,0 +1,96 @@
+package bdns
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/letsencrypt/boulder/iana"
+)
+
+// ZoneConfig sends the queries for names at or under any of Suffixes to
+// Servers instead of the default resolvers, e.g. to resolve internal zones
+// with internal resolvers that know them.
+type ZoneConfig struct {
+	Suffixes []string
+	Servers  []string
+}
+
+// zone is a suffix and the resolvers queries for names under it are sent to.
+type zone struct {
+	suffix  string
+	servers []string
+}
+
+// SetZones configures split-horizon resolution: queries for names under a
+// zone's suffixes go to the zone's servers, and all other queries go to the
+// default servers. A name under more than one suffix uses the longest. To keep
+// public names from leaking to internal resolvers, a zone's suffixes must be
+// below any public suffix (e.g. "corp.example.com", or a name under a TLD that
+// isn't IANA assigned like "internal"), and its servers can't also be default
+// servers or servers of another zone.
+func (dnsClient *DNSClientImpl) SetZones(configs []ZoneConfig) error {
+	defaults := make(map[string]bool, len(dnsClient.servers))
+	for _, server := range dnsClient.servers {
+		defaults[server] = true
+	}
+	seenSuffixes := make(map[string]bool)
+	zoneOf := make(map[string]int)
+	var zones []zone
+	for i, config := range configs {
+		if len(config.Suffixes) == 0 || len(config.Servers) == 0 {
+			return fmt.Errorf("DNS zone %d must have at least one suffix and one server", i)
+		}
+		for _, server := range config.Servers {
+			if defaults[server] {
+				return fmt.Errorf("DNS zone server %q is also a default server", server)
+			}
+			if z, ok := zoneOf[server]; ok && z != i {
+				return fmt.Errorf("DNS zone server %q is a server of more than one zone", server)
+			}
+			zoneOf[server] = i
+		}
+		for _, suffix := range config.Suffixes {
+			suffix = canonicalName(suffix)
+			if suffix == "" {
+				return fmt.Errorf("DNS zone %d has an empty suffix", i)
+			}
+			if seenSuffixes[suffix] {
+				return fmt.Errorf("DNS zone suffix %q is configured more than once", suffix)
+			}
+			seenSuffixes[suffix] = true
+			// A suffix that has an IANA TLD must be below its public suffix,
+			// so that the zone only covers names someone controls.
+			if publicSuffix, err := iana.ExtractSuffix(suffix); err == nil && publicSuffix == suffix {
+				return fmt.Errorf("DNS zone suffix %q is a public suffix", suffix)
+			}
+			zones = append(zones, zone{suffix: suffix, servers: config.Servers})
+		}
+	}
+	dnsClient.zones = zones
+	return nil
+}
+
+// serversFor returns the servers to query for hostname.
+func (dnsClient *DNSClientImpl) serversFor(hostname string) []string {
+	name := canonicalName(hostname)
+	var match *zone
+	for i, z := range dnsClient.zones {
+		// Only match on a label boundary, so that "notinternal" isn't
+		// sent to the resolvers for "internal"
+		if name != z.suffix && !strings.HasSuffix(name, "."+z.suffix) {
+			continue
+		}
+		if match == nil || len(z.suffix) > len(match.suffix) {
+			match = &dnsClient.zones[i]
+		}
+	}
+	if match == nil {
+		return dnsClient.servers
+	}
+	return match.servers
+}
+
+// canonicalName lowercases name and removes any trailing dot.
+func canonicalName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
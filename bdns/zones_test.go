@@ -0,0 +1,107 @@
+package bdns
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestSetZones(t *testing.T) {
+	testCases := []struct {
+		name  string
+		zones []ZoneConfig
+		err   string
+	}{
+		{
+			name:  "no zones",
+			zones: nil,
+		},
+		{
+			name: "internal zones",
+			zones: []ZoneConfig{
+				{Suffixes: []string{"internal", "corp.example.com."}, Servers: []string{"10.0.0.53:53"}},
+				{Suffixes: []string{"lab.internal"}, Servers: []string{"10.1.0.53:53"}},
+			},
+		},
+		{
+			name:  "no servers",
+			zones: []ZoneConfig{{Suffixes: []string{"internal"}}},
+			err:   "DNS zone 0 must have at least one suffix and one server",
+		},
+		{
+			name:  "default server",
+			zones: []ZoneConfig{{Suffixes: []string{"internal"}, Servers: []string{"8.8.8.8:53"}}},
+			err:   `DNS zone server "8.8.8.8:53" is also a default server`,
+		},
+		{
+			name: "shared server",
+			zones: []ZoneConfig{
+				{Suffixes: []string{"internal"}, Servers: []string{"10.0.0.53:53"}},
+				{Suffixes: []string{"corp"}, Servers: []string{"10.0.0.53:53"}},
+			},
+			err: `DNS zone server "10.0.0.53:53" is a server of more than one zone`,
+		},
+		{
+			name:  "TLD",
+			zones: []ZoneConfig{{Suffixes: []string{"com"}, Servers: []string{"10.0.0.53:53"}}},
+			err:   `DNS zone suffix "com" is a public suffix`,
+		},
+		{
+			name:  "public suffix",
+			zones: []ZoneConfig{{Suffixes: []string{"CO.UK."}, Servers: []string{"10.0.0.53:53"}}},
+			err:   `DNS zone suffix "co.uk" is a public suffix`,
+		},
+		{
+			name:  "duplicate suffix",
+			zones: []ZoneConfig{{Suffixes: []string{"internal", "internal."}, Servers: []string{"10.0.0.53:53"}}},
+			err:   `DNS zone suffix "internal" is configured more than once`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := NewTestDNSClientImpl(time.Second, []string{"8.8.8.8:53"}, testStats, clock.NewFake(), 1)
+			err := client.SetZones(tc.zones)
+			if tc.err == "" {
+				test.AssertNotError(t, err, "SetZones failed")
+			} else {
+				test.AssertError(t, err, "SetZones accepted invalid zones")
+				test.AssertEquals(t, err.Error(), tc.err)
+			}
+		})
+	}
+}
+
+func TestZoneServers(t *testing.T) {
+	client := NewTestDNSClientImpl(time.Second*10, []string{"public"}, testStats, clock.NewFake(), 1)
+	err := client.SetZones([]ZoneConfig{
+		{Suffixes: []string{"internal", "corp.example.com"}, Servers: []string{"internal"}},
+		{Suffixes: []string{"lab.internal"}, Servers: []string{"lab"}},
+	})
+	test.AssertNotError(t, err, "SetZones failed")
+	mock := &rotateFailureExchanger{lookups: make(map[string]int)}
+	client.dnsClient = mock
+
+	testCases := []struct {
+		hostname string
+		server   string
+	}{
+		{"example.com", "public"},
+		{"corp.example.com", "internal"},
+		{"www.CORP.example.com.", "internal"},
+		{"notcorp.example.com", "public"},
+		{"host.internal", "internal"},
+		{"host.lab.internal", "lab"},
+		{"internal.example.net", "public"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.hostname, func(t *testing.T) {
+			mock.lookups = make(map[string]int)
+			_, _, err := client.LookupTXT(context.Background(), tc.hostname)
+			test.AssertNotError(t, err, "LookupTXT failed")
+			test.AssertDeepEquals(t, mock.lookups, map[string]int{tc.server: 1})
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/asn1"
 	"encoding/hex"
@@ -14,6 +15,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"strings"
 	"time"
 
@@ -35,8 +37,12 @@ import (
 	csrlib "github.com/letsencrypt/boulder/csr"
 	berrors "github.com/letsencrypt/boulder/errors"
 	"github.com/letsencrypt/boulder/goodkey"
+	"github.com/letsencrypt/boulder/killswitch"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/policy"
+	"github.com/letsencrypt/boulder/probs"
+	"github.com/letsencrypt/boulder/trace"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -116,36 +122,48 @@ type CertificateAuthorityImpl struct {
 	// A map from issuer cert common name to an internalIssuer struct
 	issuers map[string]*internalIssuer
 	// The common name of the default issuer cert
-	defaultIssuer     *internalIssuer
-	sa                certificateStorage
-	pa                core.PolicyAuthority
-	keyPolicy         goodkey.KeyPolicy
-	clk               clock.Clock
-	log               blog.Logger
-	stats             metrics.Scope
-	prefix            int // Prepended to the serial number
-	validityPeriod    time.Duration
-	backdate          time.Duration
-	maxNames          int
-	forceCNFromSAN    bool
-	enableMustStaple  bool
-	signatureCount    *prometheus.CounterVec
-	csrExtensionCount *prometheus.CounterVec
-	orphanQueue       *goque.Queue
+	defaultIssuer      *internalIssuer
+	sa                 certificateStorage
+	pa                 core.PolicyAuthority
+	keyPolicy          goodkey.KeyPolicy
+	profileKeyPolicies map[string]goodkey.KeyPolicy // By CFSSL profile, if they differ from keyPolicy
+	clk                clock.Clock
+	log                blog.Logger
+	stats              metrics.Scope
+	prefix             int // Prepended to the serial number
+	validity           map[string]validityPolicy // By CFSSL profile
+	nameLimits         csrlib.NameLimits
+	forceCNFromSAN     bool
+	enableMustStaple   bool
+	signatureCount     *prometheus.CounterVec
+	csrExtensionCount  *prometheus.CounterVec
+	orphanQueue        *goque.Queue
+	killSwitch         *killswitch.Switch
+	exemptionKey       []byte
 }
 
 // Issuer represents a single issuer certificate, along with its key.
 type Issuer struct {
 	Signer crypto.Signer
 	Cert   *x509.Certificate
+	// OCSPSigner, if set, signs the issuer's OCSP responses instead of Signer,
+	// so that OCSP signing can use a different HSM slot or key from issuance.
+	// Unless OCSPCert is set it must be for the issuer key.
+	OCSPSigner crypto.Signer
+	// OCSPCert is a delegated OCSP responder certificate for OCSPSigner,
+	// issued by Cert, which is included in the issuer's OCSP responses.
+	OCSPCert *x509.Certificate
 }
 
 // internalIssuer represents the fully initialized internal state for a single
 // issuer, including the cfssl signer and OCSP signer objects.
 type internalIssuer struct {
 	cert       *x509.Certificate
+	signer     crypto.Signer
 	eeSigner   *local.Signer
 	ocspSigner ocsp.Signer
+	// The key OCSP responses are signed with, if it isn't signer
+	ocspKey crypto.Signer
 }
 
 func makeInternalIssuers(
@@ -166,9 +184,7 @@ func makeInternalIssuers(
 			return nil, err
 		}
 
-		// Set up our OCSP signer. Note this calls for both the issuer cert and the
-		// OCSP signing cert, which are the same in our case.
-		ocspSigner, err := ocsp.NewSigner(iss.Cert, iss.Cert, iss.Signer, lifespanOCSP)
+		ocspSigner, err := makeOCSPSigner(iss, lifespanOCSP)
 		if err != nil {
 			return nil, err
 		}
@@ -178,13 +194,49 @@ func makeInternalIssuers(
 		}
 		internalIssuers[cn] = &internalIssuer{
 			cert:       iss.Cert,
+			signer:     iss.Signer,
 			eeSigner:   eeSigner,
 			ocspSigner: ocspSigner,
+			ocspKey:    iss.OCSPSigner,
 		}
 	}
 	return internalIssuers, nil
 }
 
+// makeOCSPSigner sets up the signer of an issuer's OCSP responses, which uses
+// the issuer's OCSP key if it has one and its issuance key otherwise.
+func makeOCSPSigner(iss Issuer, lifespanOCSP time.Duration) (ocsp.Signer, error) {
+	cn := iss.Cert.Subject.CommonName
+	if iss.OCSPSigner == nil {
+		if iss.OCSPCert != nil {
+			return nil, fmt.Errorf("Issuer %q has an OCSP cert but no OCSP signer.", cn)
+		}
+		// Note this calls for both the issuer cert and the OCSP signing cert,
+		// which are the same in our case.
+		return ocsp.NewSigner(iss.Cert, iss.Cert, iss.Signer, lifespanOCSP)
+	}
+	if iss.OCSPCert == nil {
+		if !core.KeyDigestEquals(iss.OCSPSigner.Public(), iss.Cert.PublicKey) {
+			return nil, fmt.Errorf("OCSP key of issuer %q doesn't match its cert", cn)
+		}
+		return ocsp.NewSigner(iss.Cert, iss.Cert, iss.OCSPSigner, lifespanOCSP)
+	}
+	if err := iss.OCSPCert.CheckSignatureFrom(iss.Cert); err != nil {
+		return nil, fmt.Errorf("OCSP cert of issuer %q isn't signed by it: %s", cn, err)
+	}
+	ocspSigning := false
+	for _, eku := range iss.OCSPCert.ExtKeyUsage {
+		ocspSigning = ocspSigning || eku == x509.ExtKeyUsageOCSPSigning
+	}
+	if !ocspSigning {
+		return nil, fmt.Errorf("OCSP cert of issuer %q doesn't have the OCSP signing extended key usage", cn)
+	}
+	if !core.KeyDigestEquals(iss.OCSPSigner.Public(), iss.OCSPCert.PublicKey) {
+		return nil, fmt.Errorf("OCSP key of issuer %q doesn't match its OCSP cert", cn)
+	}
+	return ocsp.NewSigner(iss.Cert, iss.OCSPCert, iss.OCSPSigner, lifespanOCSP)
+}
+
 // NewCertificateAuthorityImpl creates a CA instance that can sign certificates
 // from a single issuer (the first first in the issuers slice), and can sign OCSP
 // for any of the issuer certificates provided.
@@ -260,29 +312,42 @@ func NewCertificateAuthorityImpl(
 		[]string{"purpose"})
 	stats.MustRegister(signatureCount)
 
+	profileKeyPolicies := make(map[string]goodkey.KeyPolicy, len(config.ProfileKeyPolicies))
+	for name, kpConfig := range config.ProfileKeyPolicies {
+		if name != rsaProfile && name != ecdsaProfile {
+			return nil, fmt.Errorf("key policy configured for unknown profile %q", name)
+		}
+		profilePolicy := keyPolicy
+		if err := kpConfig.Apply(&profilePolicy); err != nil {
+			return nil, fmt.Errorf("key policy for profile %q: %s", name, err)
+		}
+		profileKeyPolicies[name] = profilePolicy
+	}
+
 	ca = &CertificateAuthorityImpl{
-		sa:                sa,
-		pa:                pa,
-		issuers:           internalIssuers,
-		defaultIssuer:     defaultIssuer,
-		rsaProfile:        rsaProfile,
-		ecdsaProfile:      ecdsaProfile,
-		prefix:            config.SerialPrefix,
-		clk:               clk,
-		log:               logger,
-		stats:             stats,
-		keyPolicy:         keyPolicy,
-		forceCNFromSAN:    !config.DoNotForceCN, // Note the inversion here
-		enableMustStaple:  config.EnableMustStaple,
-		signatureCount:    signatureCount,
-		csrExtensionCount: csrExtensionCount,
-		orphanQueue:       orphanQueue,
+		sa:                 sa,
+		pa:                 pa,
+		issuers:            internalIssuers,
+		defaultIssuer:      defaultIssuer,
+		rsaProfile:         rsaProfile,
+		ecdsaProfile:       ecdsaProfile,
+		prefix:             config.SerialPrefix,
+		clk:                clk,
+		log:                logger,
+		stats:              stats,
+		keyPolicy:          keyPolicy,
+		profileKeyPolicies: profileKeyPolicies,
+		forceCNFromSAN:     !config.DoNotForceCN, // Note the inversion here
+		enableMustStaple:   config.EnableMustStaple,
+		signatureCount:     signatureCount,
+		csrExtensionCount:  csrExtensionCount,
+		orphanQueue:        orphanQueue,
 	}
 
 	if config.Expiry == "" {
 		return nil, errors.New("Config must specify an expiry period.")
 	}
-	ca.validityPeriod, err = time.ParseDuration(config.Expiry)
+	lifetime, err := time.ParseDuration(config.Expiry)
 	if err != nil {
 		return nil, err
 	}
@@ -290,16 +355,85 @@ func NewCertificateAuthorityImpl(
 	// TODO(briansmith): Make the backdate setting mandatory after the
 	// production ca.json has been updated to include it. Until then, manually
 	// default to 1h, which is the backdating duration we currently use.
-	ca.backdate = config.Backdate.Duration
-	if ca.backdate == 0 {
-		ca.backdate = time.Hour
+	backdate := config.Backdate.Duration
+	if backdate == 0 {
+		backdate = time.Hour
+	}
+
+	defaultValidity, err := newValidityPolicy(backdate, lifetime, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiry or backdate: %s", err)
+	}
+	ca.validity = map[string]validityPolicy{
+		rsaProfile:   defaultValidity,
+		ecdsaProfile: defaultValidity,
+	}
+	for name, vc := range config.Validity {
+		if name != rsaProfile && name != ecdsaProfile {
+			return nil, fmt.Errorf("validity configured for unknown profile %q", name)
+		}
+		ca.validity[name], err = newValidityPolicy(
+			vc.Backdate.Duration, vc.Lifetime.Duration, vc.MaxBackdate.Duration, vc.MaxLifetime.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("validity for profile %q: %s", name, err)
+		}
+	}
+	profiles := make([]string, 0, len(ca.validity))
+	for name := range ca.validity {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+	for _, name := range profiles {
+		p := ca.validity[name]
+		logger.AuditInfof("Validity policy: profile=[%s] backdate=[%s] lifetime=[%s] maxBackdate=[%s] maxLifetime=[%s]",
+			name, p.backdate, p.lifetime, p.maxBackdate, p.maxLifetime)
 	}
 
-	ca.maxNames = config.MaxNames
+	ca.nameLimits = csrlib.NameLimits{
+		Default: config.MaxNames,
+		RSA:     config.RSAMaxNames,
+		ECDSA:   config.ECDSAMaxNames,
+	}
 
 	return ca, nil
 }
 
+// CheckHSM is a health check that signs a fixed digest with the keys of every
+// issuer, failing if any of them can't be used (e.g. because the HSM session
+// was lost).
+func (ca *CertificateAuthorityImpl) CheckHSM(_ context.Context) error {
+	digest := sha256.Sum256([]byte("boulder CA health check"))
+	for cn, issuer := range ca.issuers {
+		_, err := issuer.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+		ca.noteSignError(err)
+		if err != nil {
+			return fmt.Errorf("signing with key of issuer %q: %s", cn, err)
+		}
+		if issuer.ocspKey == nil {
+			continue
+		}
+		_, err = issuer.ocspKey.Sign(rand.Reader, digest[:], crypto.SHA256)
+		ca.noteSignError(err)
+		if err != nil {
+			return fmt.Errorf("signing with OCSP key of issuer %q: %s", cn, err)
+		}
+	}
+	return nil
+}
+
+// SetKillSwitch makes the CA refuse to issue certificates and precertificates
+// while ks is stopped. OCSP signing is not affected.
+func (ca *CertificateAuthorityImpl) SetKillSwitch(ks *killswitch.Switch) {
+	ca.killSwitch = ks
+}
+
+// SetPolicyExemptionKey sets the key policy exemption tokens are signed with,
+// which must be the same as the RA's. Without it, requests with policy
+// exemptions are rejected.
+func (ca *CertificateAuthorityImpl) SetPolicyExemptionKey(key []byte) {
+	ca.exemptionKey = key
+}
+
 // noteSignError is called after operations that may cause a CFSSL
 // or PKCS11 signing error.
 func (ca *CertificateAuthorityImpl) noteSignError(err error) {
@@ -410,7 +544,10 @@ func (ca *CertificateAuthorityImpl) GenerateOCSP(ctx context.Context, xferObj co
 			core.SerialToString(cert.SerialNumber), cn, err)
 	}
 
+	_, span := trace.Start(ctx, "HSM sign OCSP", trace.KindInternal)
 	ocspResponse, err := issuer.ocspSigner.Sign(signRequest)
+	span.SetError(err)
+	span.End()
 	ca.noteSignError(err)
 	if err == nil {
 		ca.signatureCount.With(prometheus.Labels{"purpose": "ocsp"}).Inc()
@@ -425,6 +562,10 @@ func (ca *CertificateAuthorityImpl) GenerateOCSP(ctx context.Context, xferObj co
 func (ca *CertificateAuthorityImpl) IssueCertificate(ctx context.Context, issueReq *caPB.IssueCertificateRequest) (core.Certificate, error) {
 	emptyCert := core.Certificate{}
 
+	if err := ca.killSwitch.Check(); err != nil {
+		return emptyCert, err
+	}
+
 	if issueReq.RegistrationID == nil {
 		return emptyCert, berrors.InternalServerError("RegistrationID is nil")
 	}
@@ -437,12 +578,12 @@ func (ca *CertificateAuthorityImpl) IssueCertificate(ctx context.Context, issueR
 		orderID = *issueReq.OrderID
 	}
 
-	serialBigInt, validity, err := ca.generateSerialNumberAndValidity()
+	serialBigInt, err := ca.generateSerialNumber()
 	if err != nil {
 		return emptyCert, err
 	}
 
-	certDER, err := ca.issueCertificateOrPrecertificate(ctx, issueReq, serialBigInt, validity, certType)
+	certDER, err := ca.issueCertificateOrPrecertificate(ctx, issueReq, serialBigInt, certType)
 	if err != nil {
 		return emptyCert, err
 	}
@@ -451,12 +592,16 @@ func (ca *CertificateAuthorityImpl) IssueCertificate(ctx context.Context, issueR
 }
 
 func (ca *CertificateAuthorityImpl) IssuePrecertificate(ctx context.Context, issueReq *caPB.IssueCertificateRequest) (*caPB.IssuePrecertificateResponse, error) {
-	serialBigInt, validity, err := ca.generateSerialNumberAndValidity()
+	if err := ca.killSwitch.Check(); err != nil {
+		return nil, err
+	}
+
+	serialBigInt, err := ca.generateSerialNumber()
 	if err != nil {
 		return nil, err
 	}
 
-	precertDER, err := ca.issueCertificateOrPrecertificate(ctx, issueReq, serialBigInt, validity, precertType)
+	precertDER, err := ca.issueCertificateOrPrecertificate(ctx, issueReq, serialBigInt, precertType)
 	if err != nil {
 		return nil, err
 	}
@@ -472,6 +617,9 @@ func (ca *CertificateAuthorityImpl) IssuePrecertificate(ctx context.Context, iss
 // and the response and certificate are stored in the database.
 func (ca *CertificateAuthorityImpl) IssueCertificateForPrecertificate(ctx context.Context, req *caPB.IssueCertificateForPrecertificateRequest) (core.Certificate, error) {
 	emptyCert := core.Certificate{}
+	if err := ca.killSwitch.Check(); err != nil {
+		return emptyCert, err
+	}
 	precert, err := x509.ParseCertificate(req.DER)
 	if err != nil {
 		return emptyCert, err
@@ -508,7 +656,68 @@ type validity struct {
 	NotAfter  time.Time
 }
 
-func (ca *CertificateAuthorityImpl) generateSerialNumberAndValidity() (*big.Int, validity, error) {
+// Root program ceilings on the validity of subscriber certificates, which no
+// validity policy may exceed: the Baseline Requirements' maximum validity
+// period of 825 days, and a limit on backdating well beyond what clock skew
+// calls for, since backdating any further would misrepresent when a
+// certificate was issued.
+const (
+	maxLifetimeCeiling = 825 * 24 * time.Hour
+	maxBackdateCeiling = 48 * time.Hour
+)
+
+// validityPolicy sets the validity period of the certificates issued with a
+// profile: notBefore is backdate before the time of issuance, to allow for
+// relying parties with slow clocks, and notAfter is lifetime after notBefore.
+// The backdate and lifetime are at most maxBackdate and maxLifetime, which
+// are at most the root program ceilings.
+type validityPolicy struct {
+	backdate    time.Duration
+	lifetime    time.Duration
+	maxBackdate time.Duration
+	maxLifetime time.Duration
+}
+
+// newValidityPolicy returns a validityPolicy if backdate and lifetime are
+// within maxBackdate and maxLifetime, and those are within the root program
+// ceilings. A zero maxBackdate or maxLifetime defaults to the ceiling.
+func newValidityPolicy(backdate, lifetime, maxBackdate, maxLifetime time.Duration) (validityPolicy, error) {
+	if maxBackdate == 0 {
+		maxBackdate = maxBackdateCeiling
+	}
+	if maxLifetime == 0 {
+		maxLifetime = maxLifetimeCeiling
+	}
+	switch {
+	case maxBackdate < 0 || maxBackdate > maxBackdateCeiling:
+		return validityPolicy{}, fmt.Errorf("maxBackdate %s must be positive and at most the root program ceiling of %s",
+			maxBackdate, maxBackdateCeiling)
+	case maxLifetime < 0 || maxLifetime > maxLifetimeCeiling:
+		return validityPolicy{}, fmt.Errorf("maxLifetime %s must be positive and at most the root program ceiling of %s",
+			maxLifetime, maxLifetimeCeiling)
+	case backdate < 0 || backdate > maxBackdate:
+		return validityPolicy{}, fmt.Errorf("backdate %s can't be negative or more than %s", backdate, maxBackdate)
+	case lifetime <= 0 || lifetime > maxLifetime:
+		return validityPolicy{}, fmt.Errorf("lifetime %s must be positive and at most %s", lifetime, maxLifetime)
+	}
+	return validityPolicy{
+		backdate:    backdate,
+		lifetime:    lifetime,
+		maxBackdate: maxBackdate,
+		maxLifetime: maxLifetime,
+	}, nil
+}
+
+// validityAt returns the validity period of a certificate issued at now.
+func (p validityPolicy) validityAt(now time.Time) validity {
+	notBefore := now.Add(-p.backdate)
+	return validity{
+		NotBefore: notBefore,
+		NotAfter:  notBefore.Add(p.lifetime),
+	}
+}
+
+func (ca *CertificateAuthorityImpl) generateSerialNumber() (*big.Int, error) {
 	// We want 136 bits of random number, plus an 8-bit instance id prefix.
 	const randBits = 136
 	serialBytes := make([]byte, randBits/8+1)
@@ -517,35 +726,121 @@ func (ca *CertificateAuthorityImpl) generateSerialNumberAndValidity() (*big.Int,
 	if err != nil {
 		err = berrors.InternalServerError("failed to generate serial: %s", err)
 		ca.log.AuditErrf("Serial randomness failed, err=[%v]", err)
-		return nil, validity{}, err
+		return nil, err
 	}
 	serialBigInt := big.NewInt(0)
 	serialBigInt = serialBigInt.SetBytes(serialBytes)
 
-	notBefore := ca.clk.Now().Add(-1 * ca.backdate)
-	validity := validity{
-		NotBefore: notBefore,
-		NotAfter:  notBefore.Add(ca.validityPeriod),
+	return serialBigInt, nil
+}
+
+// profileName returns the CFSSL signing profile used to issue certificates
+// for key's type.
+func (ca *CertificateAuthorityImpl) profileName(key interface{}) (string, error) {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return ca.rsaProfile, nil
+	case *ecdsa.PublicKey:
+		return ca.ecdsaProfile, nil
+	default:
+		return "", berrors.InternalServerError("unsupported key type %T", key)
+	}
+}
+
+// profileFor returns the CFSSL signing profile used to issue certificates for
+// key, checking that key is acceptable under that profile's key policy.
+func (ca *CertificateAuthorityImpl) profileFor(key interface{}) (string, error) {
+	profile, err := ca.profileName(key)
+	if err != nil {
+		return "", err
+	}
+	if profilePolicy, ok := ca.profileKeyPolicies[profile]; ok {
+		if err := profilePolicy.GoodKey(key); err != nil {
+			return "", err
+		}
+	}
+	return profile, nil
+}
+
+// CheckCertificateRequest runs every check IssuePrecertificate makes of a
+// request before signing, returning all of the problems with the request
+// rather than just the first. It never signs anything.
+func (ca *CertificateAuthorityImpl) CheckCertificateRequest(ctx context.Context, issueReq *caPB.IssueCertificateRequest) ([]*probs.ProblemDetails, error) {
+	if issueReq.RegistrationID == nil {
+		return nil, berrors.InternalServerError("RegistrationID is nil")
+	}
+	csr, err := x509.ParseCertificateRequest(issueReq.Csr)
+	if err != nil {
+		return []*probs.ProblemDetails{probs.BadCSR("CSR could not be parsed")}, nil
+	}
+
+	var errs []error
+	exemptions, err := policy.ParseExemptions(ca.exemptionKey, *issueReq.RegistrationID, issueReq.Exemptions)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, csrlib.CheckCSR(
+		csr,
+		ca.nameLimits.ForKey(csr.PublicKey),
+		&ca.keyPolicy,
+		ca.pa,
+		ca.forceCNFromSAN,
+		*issueReq.RegistrationID,
+		policy.ExemptEntries(exemptions),
+	)...)
+	if _, err := ca.extensionsFromCSR(csr); err != nil {
+		errs = append(errs, err)
+	}
+	// CheckCSR has already reported keys of unsupported types, so only the
+	// profile's own key policy is of interest here.
+	if _, err := ca.profileFor(csr.PublicKey); err != nil && !berrors.Is(err, berrors.InternalServer) {
+		errs = append(errs, err)
 	}
 
-	return serialBigInt, validity, nil
+	var problems []*probs.ProblemDetails
+	for _, err := range errs {
+		problems = append(problems, csrProblem(err))
+	}
+	return problems, nil
+}
+
+// csrProblem converts an error from checking a CSR to the problem the CA's
+// callers would see if issuance failed with it.
+func csrProblem(err error) *probs.ProblemDetails {
+	if berrors.Is(err, berrors.BadPublicKey) {
+		return probs.BadPublicKey("%s", err)
+	}
+	return probs.Malformed("%s", err)
 }
 
-func (ca *CertificateAuthorityImpl) issueCertificateOrPrecertificate(ctx context.Context, issueReq *caPB.IssueCertificateRequest, serialBigInt *big.Int, validity validity, certType certificateType) ([]byte, error) {
+func (ca *CertificateAuthorityImpl) issueCertificateOrPrecertificate(ctx context.Context, issueReq *caPB.IssueCertificateRequest, serialBigInt *big.Int, certType certificateType) ([]byte, error) {
 	csr, err := x509.ParseCertificateRequest(issueReq.Csr)
 	if err != nil {
 		return nil, err
 	}
 
+	// The CA checks the signatures of the exemptions itself rather than
+	// trusting the RA, but not their expiry: that was checked when the order
+	// was created.
+	exemptions, err := policy.ParseExemptions(ca.exemptionKey, *issueReq.RegistrationID, issueReq.Exemptions)
+	if err != nil {
+		ca.log.AuditErr(err.Error())
+		return nil, err
+	}
+
 	if err := csrlib.VerifyCSR(
 		csr,
-		ca.maxNames,
+		ca.nameLimits.ForKey(csr.PublicKey),
 		&ca.keyPolicy,
 		ca.pa,
 		ca.forceCNFromSAN,
 		*issueReq.RegistrationID,
+		policy.ExemptEntries(exemptions),
 	); err != nil {
 		ca.log.AuditErr(err.Error())
+		if berrors.Is(err, berrors.BadPublicKey) {
+			return nil, err
+		}
 		return nil, berrors.MalformedError(err.Error())
 	}
 
@@ -554,6 +849,13 @@ func (ca *CertificateAuthorityImpl) issueCertificateOrPrecertificate(ctx context
 		return nil, err
 	}
 
+	profile, err := ca.profileFor(csr.PublicKey)
+	if err != nil {
+		ca.log.AuditErr(err.Error())
+		return nil, err
+	}
+	validity := ca.validity[profile].validityAt(ca.clk.Now())
+
 	issuer := ca.defaultIssuer
 
 	if issuer.cert.NotAfter.Before(validity.NotAfter) {
@@ -568,18 +870,6 @@ func (ca *CertificateAuthorityImpl) issueCertificateOrPrecertificate(ctx context
 		Bytes: csr.Raw,
 	}))
 
-	var profile string
-	switch csr.PublicKey.(type) {
-	case *rsa.PublicKey:
-		profile = ca.rsaProfile
-	case *ecdsa.PublicKey:
-		profile = ca.ecdsaProfile
-	default:
-		err = berrors.InternalServerError("unsupported key type %T", csr.PublicKey)
-		ca.log.AuditErr(err.Error())
-		return nil, err
-	}
-
 	// Send the cert off for signing
 	req := signer.SignRequest{
 		Request: csrPEM,
@@ -604,10 +894,16 @@ func (ca *CertificateAuthorityImpl) issueCertificateOrPrecertificate(ctx context
 		req.Subject.SerialNumber = serialHex
 	}
 
-	ca.log.AuditInfof("Signing: serial=[%s] names=[%s] csr=[%s]",
-		serialHex, strings.Join(csr.DNSNames, ", "), hex.EncodeToString(csr.Raw))
+	ca.log.AuditInfof("Signing: serial=[%s] names=[%s] profile=[%s] notBefore=[%s] notAfter=[%s] csr=[%s]",
+		serialHex, strings.Join(csr.DNSNames, ", "), profile,
+		validity.NotBefore.UTC().Format(time.RFC3339), validity.NotAfter.UTC().Format(time.RFC3339),
+		hex.EncodeToString(csr.Raw))
 
+	_, span := trace.Start(ctx, fmt.Sprintf("HSM sign %s", certType), trace.KindInternal)
+	span.SetAttribute("serial", serialHex)
 	certPEM, err := issuer.eeSigner.Sign(req)
+	span.SetError(err)
+	span.End()
 	ca.noteSignError(err)
 	if err != nil {
 		err = berrors.InternalServerError("failed to sign certificate: %s", err)
@@ -724,7 +1020,11 @@ func (ca *CertificateAuthorityImpl) integrateOrphan() error {
 	if err != nil {
 		return fmt.Errorf("failed to parse orphan: %s", err)
 	}
-	issued := cert.NotBefore.Add(-ca.backdate)
+	profile, err := ca.profileName(cert.PublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to find profile of orphan: %s", err)
+	}
+	issued := cert.NotBefore.Add(-ca.validity[profile].backdate)
 	_, err = ca.sa.AddCertificate(context.Background(), orphan.DER, orphan.RegID, orphan.OCSPResp, &issued)
 	if err != nil && !berrors.Is(err, berrors.Duplicate) {
 		return fmt.Errorf("failed to store orphaned certificate: %s", err)
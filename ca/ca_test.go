@@ -11,6 +11,7 @@ import (
 	"encoding/asn1"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math/big"
 	"os"
@@ -35,9 +36,11 @@ import (
 	"github.com/letsencrypt/boulder/core"
 	berrors "github.com/letsencrypt/boulder/errors"
 	"github.com/letsencrypt/boulder/goodkey"
+	"github.com/letsencrypt/boulder/killswitch"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
 	"github.com/letsencrypt/boulder/policy"
+	"github.com/letsencrypt/boulder/probs"
 	"github.com/letsencrypt/boulder/test"
 )
 
@@ -269,7 +272,7 @@ func setup(t *testing.T) *testCtx {
 		},
 	}
 
-	issuers := []Issuer{{caKey, caCert}}
+	issuers := []Issuer{{Signer: caKey, Cert: caCert}}
 
 	keyPolicy := goodkey.KeyPolicy{
 		AllowRSA:           true,
@@ -439,8 +442,9 @@ func issueCertificateSubTestIssueCertificate(t *testing.T, i *TestCertificateIss
 }
 
 func issueCertificateSubTestValidityUsesCAClock(t *testing.T, i *TestCertificateIssuance) {
-	test.AssertEquals(t, i.cert.NotBefore, i.ca.clk.Now().Add(-1*i.ca.backdate))
-	test.AssertEquals(t, i.cert.NotAfter, i.cert.NotBefore.Add(i.ca.validityPeriod))
+	p := i.ca.validity[rsaProfileName]
+	test.AssertEquals(t, i.cert.NotBefore, i.ca.clk.Now().Add(-1*p.backdate))
+	test.AssertEquals(t, i.cert.NotAfter, i.cert.NotBefore.Add(p.lifetime))
 }
 
 // Test issuing when multiple issuers are present.
@@ -580,6 +584,86 @@ func TestOCSP(t *testing.T) {
 	test.AssertEquals(t, parsedNewCertOcspResp.SerialNumber.Cmp(parsedNewCert.SerialNumber), 0)
 }
 
+// makeOCSPResponderCert returns a delegated OCSP responder cert for key,
+// issued by caCert, with the given extended key usages.
+func makeOCSPResponderCert(t *testing.T, key crypto.Signer, ekus []x509.ExtKeyUsage) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "OCSP responder"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  ekus,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, key.Public(), caKey)
+	test.AssertNotError(t, err, "Failed to create OCSP responder cert")
+	cert, err := x509.ParseCertificate(der)
+	test.AssertNotError(t, err, "Failed to parse OCSP responder cert")
+	return cert
+}
+
+func TestOCSPSigner(t *testing.T) {
+	testCtx := setup(t)
+	ocspKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "Failed to generate OCSP key")
+	responderCert := makeOCSPResponderCert(t, ocspKey, []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning})
+
+	newCA := func(issuer Issuer) (*CertificateAuthorityImpl, error) {
+		return NewCertificateAuthorityImpl(
+			testCtx.caConfig,
+			&mockSA{},
+			testCtx.pa,
+			testCtx.fc,
+			testCtx.stats,
+			[]Issuer{issuer},
+			testCtx.keyPolicy,
+			testCtx.logger,
+			nil)
+	}
+
+	// An OCSP key without a cert must be the issuer key
+	_, err = newCA(Issuer{Signer: caKey, Cert: caCert, OCSPSigner: ocspKey})
+	test.AssertError(t, err, "CA accepted an OCSP key that isn't the issuer key")
+	// An OCSP cert must be a delegated OCSP responder cert for the OCSP key
+	_, err = newCA(Issuer{Signer: caKey, Cert: caCert, OCSPCert: responderCert})
+	test.AssertError(t, err, "CA accepted an OCSP cert without an OCSP key")
+	_, err = newCA(Issuer{Signer: caKey, Cert: caCert, OCSPSigner: caKey, OCSPCert: responderCert})
+	test.AssertError(t, err, "CA accepted an OCSP key that doesn't match the OCSP cert")
+	_, err = newCA(Issuer{Signer: caKey, Cert: caCert, OCSPSigner: ocspKey,
+		OCSPCert: makeOCSPResponderCert(t, ocspKey, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth})})
+	test.AssertError(t, err, "CA accepted an OCSP cert without the OCSP signing EKU")
+	_, err = newCA(Issuer{Signer: caKey, Cert: caCert, OCSPSigner: ocspKey, OCSPCert: caCert})
+	test.AssertError(t, err, "CA accepted an OCSP cert not issued by the issuer")
+
+	// Certificates are signed with the issuer key, OCSP responses with the
+	// OCSP key
+	ca, err := newCA(Issuer{Signer: caKey, Cert: caCert, OCSPSigner: ocspKey, OCSPCert: responderCert})
+	test.AssertNotError(t, err, "Failed to create CA")
+	cert, err := ca.IssueCertificate(ctx, &caPB.IssueCertificateRequest{Csr: CNandSANCSR, RegistrationID: &arbitraryRegID})
+	test.AssertNotError(t, err, "Failed to issue")
+	parsedCert, err := x509.ParseCertificate(cert.DER)
+	test.AssertNotError(t, err, "Failed to parse cert")
+	test.AssertNotError(t, parsedCert.CheckSignatureFrom(caCert), "Cert wasn't signed by the issuer")
+
+	ocspResp, err := ca.GenerateOCSP(ctx, core.OCSPSigningRequest{
+		CertDER: cert.DER,
+		Status:  string(core.OCSPStatusGood),
+	})
+	test.AssertNotError(t, err, "Failed to generate OCSP")
+	parsed, err := ocsp.ParseResponse(ocspResp, caCert)
+	test.AssertNotError(t, err, "Failed to parse validate OCSP")
+	test.Assert(t, parsed.Certificate != nil, "OCSP response doesn't include the responder cert")
+	test.AssertByteEquals(t, parsed.Certificate.Raw, responderCert.Raw)
+	test.AssertEquals(t, parsed.SerialNumber.Cmp(parsedCert.SerialNumber), 0)
+
+	// The health check covers the OCSP key
+	test.AssertNotError(t, ca.CheckHSM(ctx), "CheckHSM failed")
+	ca, err = newCA(Issuer{Signer: caKey, Cert: caCert, OCSPSigner: brokenSigner{ocspKey}, OCSPCert: responderCert})
+	test.AssertNotError(t, err, "Failed to create CA")
+	err = ca.CheckHSM(ctx)
+	test.AssertError(t, err, "CheckHSM succeeded with a broken OCSP key")
+	test.AssertContains(t, err.Error(), "OCSP key")
+}
+
 func TestInvalidCSRs(t *testing.T) {
 	testCases := []struct {
 		name         string
@@ -611,6 +695,15 @@ func TestInvalidCSRs(t *testing.T) {
 		// * DNSNames = not-example.com, www.not-example.com, mail.not-example.com
 		{"RejectShortKey", "./testdata/short_key.der.csr", nil, "Issued a certificate with too short a key."},
 
+		// Test that the CA rejects CSRs with keys generated by vulnerable
+		// Infineon hardware (ROCA).
+		//
+		// CSR generated by Go, with its public key then swapped for a ROCA-weak
+		// one (so its signature is invalid):
+		// * CN = not-example.com
+		// * DNSNames = not-example.com
+		{"RejectROCAKey", "./testdata/roca_key.der.csr", nil, "Issued a certificate for a ROCA-weak key."},
+
 		// CSR generated by Go:
 		// * Random RSA public key.
 		// * CN = aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa.com
@@ -653,7 +746,11 @@ func TestInvalidCSRs(t *testing.T) {
 					_, err = ca.IssuePrecertificate(ctx, issueReq)
 				}
 
-				test.Assert(t, berrors.Is(err, berrors.Malformed), "Incorrect error type returned")
+				expectedType := berrors.Malformed
+				if testCase.name == "RejectShortKey" || testCase.name == "RejectROCAKey" {
+					expectedType = berrors.BadPublicKey
+				}
+				test.Assert(t, berrors.Is(err, expectedType), "Incorrect error type returned")
 				test.AssertEquals(t, signatureCountByPurpose("cert", ca.signatureCount), 0)
 
 				test.AssertError(t, err, testCase.errorMessage)
@@ -691,6 +788,196 @@ func TestRejectValidityTooLong(t *testing.T) {
 	test.Assert(t, berrors.Is(err, berrors.InternalServer), "Incorrect error type returned")
 }
 
+func TestProfileKeyPolicies(t *testing.T) {
+	testCtx := setup(t)
+	sa := &mockSA{}
+	testCtx.caConfig.ProfileKeyPolicies = map[string]goodkey.Config{
+		rsaProfileName: {RSAKeySizes: []int{3072, 4096}},
+	}
+	ca, err := NewCertificateAuthorityImpl(
+		testCtx.caConfig,
+		sa,
+		testCtx.pa,
+		testCtx.fc,
+		testCtx.stats,
+		testCtx.issuers,
+		testCtx.keyPolicy,
+		testCtx.logger,
+		nil)
+	test.AssertNotError(t, err, "Failed to create CA")
+
+	// CNandSANCSR has a 2048 bit RSA key, which the RSA profile's key policy
+	// doesn't allow
+	_, err = ca.IssuePrecertificate(ctx, &caPB.IssueCertificateRequest{Csr: CNandSANCSR, RegistrationID: &arbitraryRegID})
+	test.AssertError(t, err, "Issued a certificate for a key the profile doesn't allow")
+	test.Assert(t, berrors.Is(err, berrors.BadPublicKey), "Incorrect error type returned")
+
+	// The ECDSA profile has no key policy of its own
+	_, err = ca.IssuePrecertificate(ctx, &caPB.IssueCertificateRequest{Csr: ECDSACSR, RegistrationID: &arbitraryRegID})
+	test.AssertNotError(t, err, "Failed to issue certificate for ECDSA key")
+
+	testCtx.caConfig.ProfileKeyPolicies = map[string]goodkey.Config{
+		"unknownProfile": {RSAKeySizes: []int{4096}},
+	}
+	_, err = NewCertificateAuthorityImpl(
+		testCtx.caConfig,
+		sa,
+		testCtx.pa,
+		testCtx.fc,
+		testCtx.stats,
+		testCtx.issuers,
+		testCtx.keyPolicy,
+		testCtx.logger,
+		nil)
+	test.AssertError(t, err, "Created CA with a key policy for an unknown profile")
+}
+
+func TestNewValidityPolicy(t *testing.T) {
+	p, err := newValidityPolicy(time.Hour, 90*24*time.Hour, 0, 0)
+	test.AssertNotError(t, err, "newValidityPolicy failed")
+	test.AssertEquals(t, p.maxBackdate, maxBackdateCeiling)
+	test.AssertEquals(t, p.maxLifetime, maxLifetimeCeiling)
+
+	for _, tc := range []struct {
+		backdate, lifetime, maxBackdate, maxLifetime time.Duration
+	}{
+		{time.Hour, 0, 0, 0},
+		{-time.Hour, 24 * time.Hour, 0, 0},
+		{time.Hour, 826 * 24 * time.Hour, 0, 0},
+		{72 * time.Hour, 24 * time.Hour, 0, 0},
+		{2 * time.Hour, 24 * time.Hour, time.Hour, 0},
+		{time.Hour, 91 * 24 * time.Hour, 0, 90 * 24 * time.Hour},
+		{time.Hour, 24 * time.Hour, 0, 900 * 24 * time.Hour},
+		{time.Hour, 24 * time.Hour, 72 * time.Hour, 0},
+	} {
+		_, err := newValidityPolicy(tc.backdate, tc.lifetime, tc.maxBackdate, tc.maxLifetime)
+		test.AssertError(t, err, fmt.Sprintf("invalid validity policy %+v was accepted", tc))
+	}
+}
+
+func TestProfileValidity(t *testing.T) {
+	testCtx := setup(t)
+	log := blog.NewMock()
+	testCtx.caConfig.Validity = map[string]ca_config.ValidityConfig{
+		ecdsaProfileName: {
+			Backdate:    cmd.ConfigDuration{Duration: 10 * time.Minute},
+			Lifetime:    cmd.ConfigDuration{Duration: 90 * 24 * time.Hour},
+			MaxLifetime: cmd.ConfigDuration{Duration: 100 * 24 * time.Hour},
+		},
+	}
+	newCA := func() (*CertificateAuthorityImpl, error) {
+		return NewCertificateAuthorityImpl(
+			testCtx.caConfig,
+			&mockSA{},
+			testCtx.pa,
+			testCtx.fc,
+			testCtx.stats,
+			testCtx.issuers,
+			testCtx.keyPolicy,
+			log,
+			nil)
+	}
+	ca, err := newCA()
+	test.AssertNotError(t, err, "Failed to create CA")
+	test.AssertEquals(t, len(log.GetAllMatching(`Validity policy: profile=\[ecdsaEE\] backdate=\[10m0s\] lifetime=\[2160h0m0s\] maxBackdate=\[48h0m0s\] maxLifetime=\[2400h0m0s\]`)), 1)
+	test.AssertEquals(t, len(log.GetAllMatching(`Validity policy: profile=\[rsaEE\] backdate=\[1h0m0s\] lifetime=\[8760h0m0s\]`)), 1)
+
+	// The ECDSA profile's validity applies to ECDSA keys, and Expiry and
+	// Backdate still apply to RSA keys
+	precert, err := ca.IssuePrecertificate(ctx, &caPB.IssueCertificateRequest{Csr: ECDSACSR, RegistrationID: &arbitraryRegID})
+	test.AssertNotError(t, err, "Failed to issue certificate for ECDSA key")
+	cert, err := x509.ParseCertificate(precert.DER)
+	test.AssertNotError(t, err, "Failed to parse precertificate")
+	test.AssertEquals(t, cert.NotBefore, testCtx.fc.Now().Add(-10*time.Minute))
+	test.AssertEquals(t, cert.NotAfter, cert.NotBefore.Add(90*24*time.Hour))
+	test.AssertEquals(t, len(log.GetAllMatching(`Signing: serial=\[[0-9a-f]+\] names=\[.*\] profile=\[ecdsaEE\] notBefore=\[`+
+		cert.NotBefore.Format(time.RFC3339)+`\] notAfter=\[`+cert.NotAfter.Format(time.RFC3339)+`\]`)), 1)
+
+	precert, err = ca.IssuePrecertificate(ctx, &caPB.IssueCertificateRequest{Csr: CNandSANCSR, RegistrationID: &arbitraryRegID})
+	test.AssertNotError(t, err, "Failed to issue certificate for RSA key")
+	cert, err = x509.ParseCertificate(precert.DER)
+	test.AssertNotError(t, err, "Failed to parse precertificate")
+	test.AssertEquals(t, cert.NotAfter.Sub(cert.NotBefore), 8760*time.Hour)
+
+	testCtx.caConfig.Validity = map[string]ca_config.ValidityConfig{
+		ecdsaProfileName: {Lifetime: cmd.ConfigDuration{Duration: 900 * 24 * time.Hour}},
+	}
+	_, err = newCA()
+	test.AssertError(t, err, "Created CA with a lifetime beyond the root program ceiling")
+	testCtx.caConfig.Validity = map[string]ca_config.ValidityConfig{
+		"unknownProfile": {Lifetime: cmd.ConfigDuration{Duration: 90 * 24 * time.Hour}},
+	}
+	_, err = newCA()
+	test.AssertError(t, err, "Created CA with validity for an unknown profile")
+	testCtx.caConfig.Validity = nil
+	testCtx.caConfig.Expiry = "20000h"
+	_, err = newCA()
+	test.AssertError(t, err, "Created CA with an expiry beyond the root program ceiling")
+}
+
+func TestCheckCertificateRequest(t *testing.T) {
+	testCtx := setup(t)
+	testCtx.caConfig.ProfileKeyPolicies = map[string]goodkey.Config{
+		rsaProfileName: {RSAKeySizes: []int{3072, 4096}},
+	}
+	ca, err := NewCertificateAuthorityImpl(
+		testCtx.caConfig,
+		&mockSA{},
+		testCtx.pa,
+		testCtx.fc,
+		testCtx.stats,
+		testCtx.issuers,
+		testCtx.keyPolicy,
+		testCtx.logger,
+		nil)
+	test.AssertNotError(t, err, "Failed to create CA")
+
+	problems, err := ca.CheckCertificateRequest(ctx, &caPB.IssueCertificateRequest{Csr: ECDSACSR, RegistrationID: &arbitraryRegID})
+	test.AssertNotError(t, err, "CheckCertificateRequest failed")
+	test.AssertEquals(t, len(problems), 0)
+
+	// TLSFeatureUnknownCSR has both an unsupported TLS feature and a 2048 bit
+	// RSA key, which the RSA profile's key policy doesn't allow
+	problems, err = ca.CheckCertificateRequest(ctx, &caPB.IssueCertificateRequest{Csr: TLSFeatureUnknownCSR, RegistrationID: &arbitraryRegID})
+	test.AssertNotError(t, err, "CheckCertificateRequest failed")
+	test.AssertEquals(t, len(problems), 2)
+	test.AssertEquals(t, problems[0].Type, probs.MalformedProblem)
+	test.AssertEquals(t, problems[1].Type, probs.BadPublicKeyProblem)
+
+	problems, err = ca.CheckCertificateRequest(ctx, &caPB.IssueCertificateRequest{Csr: []byte{1, 2, 3}, RegistrationID: &arbitraryRegID})
+	test.AssertNotError(t, err, "CheckCertificateRequest failed")
+	test.AssertEquals(t, len(problems), 1)
+	test.AssertEquals(t, problems[0].Type, probs.BadCSRProblem)
+
+	// Nothing was signed
+	test.AssertEquals(t, signatureCountByPurpose(string(precertType), ca.signatureCount), 0)
+}
+
+func TestProfileNameLimits(t *testing.T) {
+	testCtx := setup(t)
+	testCtx.caConfig.RSAMaxNames = 1
+	ca, err := NewCertificateAuthorityImpl(
+		testCtx.caConfig,
+		&mockSA{},
+		testCtx.pa,
+		testCtx.fc,
+		testCtx.stats,
+		testCtx.issuers,
+		testCtx.keyPolicy,
+		testCtx.logger,
+		nil)
+	test.AssertNotError(t, err, "Failed to create CA")
+
+	// CNandSANCSR has an RSA key and two names, more than the RSA profile allows
+	_, err = ca.IssuePrecertificate(ctx, &caPB.IssueCertificateRequest{Csr: CNandSANCSR, RegistrationID: &arbitraryRegID})
+	test.AssertError(t, err, "Issued a certificate with more names than the profile allows")
+	test.Assert(t, berrors.Is(err, berrors.Malformed), "Incorrect error type returned")
+
+	// The ECDSA profile uses the default limit
+	_, err = ca.IssuePrecertificate(ctx, &caPB.IssueCertificateRequest{Csr: ECDSACSR, RegistrationID: &arbitraryRegID})
+	test.AssertNotError(t, err, "Failed to issue certificate for ECDSA key")
+}
+
 func TestSingleAIAEnforcement(t *testing.T) {
 	pa, err := policy.New(nil)
 	test.AssertNotError(t, err, "Couldn't create PA")
@@ -942,6 +1229,52 @@ func TestIssueCertificateForPrecertificate(t *testing.T) {
 	test.Assert(t, list, "returned cert doesn't contain SCT list")
 }
 
+func TestKillSwitch(t *testing.T) {
+	testCtx := setup(t)
+	ca, err := NewCertificateAuthorityImpl(
+		testCtx.caConfig,
+		&mockSA{},
+		testCtx.pa,
+		testCtx.fc,
+		testCtx.stats,
+		testCtx.issuers,
+		testCtx.keyPolicy,
+		testCtx.logger,
+		nil)
+	test.AssertNotError(t, err, "Failed to create CA")
+	ks := killswitch.New(testCtx.logger)
+	ca.SetKillSwitch(ks)
+
+	issueReq := caPB.IssueCertificateRequest{Csr: CNandSANCSR, RegistrationID: &arbitraryRegID, OrderID: new(int64)}
+	cert, err := ca.IssueCertificate(ctx, &issueReq)
+	test.AssertNotError(t, err, "Failed to issue with the kill switch off")
+	precert, err := ca.IssuePrecertificate(ctx, &issueReq)
+	test.AssertNotError(t, err, "Failed to issue precert with the kill switch off")
+
+	ks.Set(true, "maintenance")
+	_, err = ca.IssueCertificate(ctx, &issueReq)
+	test.Assert(t, berrors.Is(err, berrors.IssuanceDisabled), "IssueCertificate didn't return IssuanceDisabled")
+	_, err = ca.IssuePrecertificate(ctx, &issueReq)
+	test.Assert(t, berrors.Is(err, berrors.IssuanceDisabled), "IssuePrecertificate didn't return IssuanceDisabled")
+	_, err = ca.IssueCertificateForPrecertificate(ctx, &caPB.IssueCertificateForPrecertificateRequest{
+		DER:            precert.DER,
+		RegistrationID: &arbitraryRegID,
+		OrderID:        new(int64),
+	})
+	test.Assert(t, berrors.Is(err, berrors.IssuanceDisabled), "IssueCertificateForPrecertificate didn't return IssuanceDisabled")
+
+	// OCSP signing continues while issuance is stopped
+	_, err = ca.GenerateOCSP(ctx, core.OCSPSigningRequest{
+		CertDER: cert.DER,
+		Status:  string(core.OCSPStatusRevoked),
+	})
+	test.AssertNotError(t, err, "Failed to generate OCSP with the kill switch on")
+
+	ks.Set(false, "")
+	_, err = ca.IssueCertificate(ctx, &issueReq)
+	test.AssertNotError(t, err, "Failed to issue after the kill switch was turned off")
+}
+
 type queueSA struct {
 	fail      bool
 	duplicate bool
@@ -1056,3 +1389,42 @@ func TestOrphanQueue(t *testing.T) {
 		t.Fatalf("Unexpected error, wanted %q, got %q", goque.ErrEmpty, err)
 	}
 }
+
+type brokenSigner struct {
+	crypto.Signer
+}
+
+func (bs brokenSigner) Sign(_ io.Reader, _ []byte, _ crypto.SignerOpts) ([]byte, error) {
+	return nil, errors.New("session closed")
+}
+
+func TestCheckHSM(t *testing.T) {
+	testCtx := setup(t)
+	ca, err := NewCertificateAuthorityImpl(
+		testCtx.caConfig,
+		&mockSA{},
+		testCtx.pa,
+		testCtx.fc,
+		testCtx.stats,
+		testCtx.issuers,
+		testCtx.keyPolicy,
+		testCtx.logger,
+		nil)
+	test.AssertNotError(t, err, "Failed to create CA")
+	test.AssertNotError(t, ca.CheckHSM(ctx), "CheckHSM failed with a working key")
+
+	ca, err = NewCertificateAuthorityImpl(
+		testCtx.caConfig,
+		&mockSA{},
+		testCtx.pa,
+		testCtx.fc,
+		testCtx.stats,
+		[]Issuer{{Signer: brokenSigner{caKey}, Cert: caCert}},
+		testCtx.keyPolicy,
+		testCtx.logger,
+		nil)
+	test.AssertNotError(t, err, "Failed to create CA")
+	err = ca.CheckHSM(ctx)
+	test.AssertError(t, err, "CheckHSM succeeded with a broken key")
+	test.AssertContains(t, err.Error(), "session closed")
+}
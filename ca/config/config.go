@@ -5,6 +5,7 @@ import (
 	"github.com/letsencrypt/pkcs11key"
 
 	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/goodkey"
 )
 
 // CAConfig structs have configuration information for the certificate
@@ -38,7 +39,11 @@ type CAConfig struct {
 	Backdate cmd.ConfigDuration
 	// The maximum number of subjectAltNames in a single certificate
 	MaxNames int
-	CFSSL    cfsslConfig.Config
+	// RSAMaxNames and ECDSAMaxNames, if non-zero, override MaxNames for
+	// certificates issued with RSAProfile and ECDSAProfile respectively.
+	RSAMaxNames   int
+	ECDSAMaxNames int
+	CFSSL         cfsslConfig.Config
 
 	// DoNotForceCN is a temporary config setting. It controls whether
 	// to add a certificate's serial to its Subject, and whether to
@@ -57,25 +62,92 @@ type CAConfig struct {
 	// hashes of known easily enumerable keys.
 	WeakKeyFile string
 
+	// FermatRounds is the number of rounds of Fermat factorization to attempt
+	// on RSA keys, rejecting those whose primes are too close together. Zero
+	// disables the check.
+	FermatRounds int
+
+	// KeyPolicy configures the RSA key sizes, ECDSA curves and RSA exponents
+	// that are accepted. If omitted, Boulder's default key policy applies.
+	KeyPolicy goodkey.Config
+
+	// ProfileKeyPolicies further restricts the keys accepted for certificates
+	// issued with a given CFSSL signing profile (i.e. RSAProfile or
+	// ECDSAProfile). Each is applied on top of KeyPolicy, which must already
+	// allow every key the profile's policy does.
+	ProfileKeyPolicies map[string]goodkey.Config
+
+	// Validity sets the validity period of certificates issued with a given
+	// CFSSL signing profile (i.e. RSAProfile or ECDSAProfile), overriding
+	// Expiry and Backdate, which apply to profiles without an entry.
+	Validity map[string]ValidityConfig
+
 	SAService *cmd.GRPCClientConfig
 
 	// Path to directory holding orphan queue files, if not provided an orphan queue
 	// is not used.
 	OrphanQueueDir string
 
+	// IssuanceKillSwitchFile, if set, is the path to a JSON file like
+	// {"stopIssuance": true, "reason": "scheduled maintenance"}. While
+	// stopIssuance is true, the CA refuses to issue certificates. The file
+	// is reloaded whenever it changes.
+	IssuanceKillSwitchFile string
+
+	// PolicyExemptionKey, if set, is the key policy exemption tokens are
+	// signed with, which must be the same as the RA's. The CA checks the
+	// tokens of orders it issues for with names exempt from the hostname
+	// policy.
+	PolicyExemptionKey cmd.PasswordConfig
+
 	Features map[string]bool
 }
 
-// IssuerConfig contains info about an issuer: private key and issuer cert.
-// It should contain either a File path to a PEM-format private key,
-// or a PKCS11Config defining how to load a module for an HSM.
-type IssuerConfig struct {
+// ValidityConfig sets the validity period of the certificates issued with a
+// profile, and the limits on it. Every value is checked against the root
+// program ceilings the CA enforces, and the policy in effect for each profile
+// is audit logged when the CA starts.
+type ValidityConfig struct {
+	// Backdate is how long before the time of issuance notBefore is set,
+	// so that relying parties with slow clocks accept new certificates.
+	Backdate cmd.ConfigDuration
+	// Lifetime is the time from notBefore to notAfter.
+	Lifetime cmd.ConfigDuration
+	// MaxBackdate and MaxLifetime are the most Backdate and Lifetime may be,
+	// e.g. as committed to in the CPS. They default to the root program
+	// ceilings, which they can't exceed.
+	MaxBackdate cmd.ConfigDuration
+	MaxLifetime cmd.ConfigDuration
+}
+
+// KeyConfig contains info about a private key. It should contain either a
+// File path to a PEM-format private key, or a PKCS11Config defining how to
+// load a module for an HSM.
+type KeyConfig struct {
 	// A file from which a pkcs11key.Config will be read and parsed, if present
 	ConfigFile string
 	File       string
 	PKCS11     *pkcs11key.Config
-	CertFile   string
 	// Number of sessions to open with the HSM. For maximum performance,
 	// this should be equal to the number of cores in the HSM. Defaults to 1.
 	NumSessions int
 }
+
+// IssuerConfig contains info about an issuer: private key and issuer cert.
+type IssuerConfig struct {
+	KeyConfig
+	CertFile string
+	// OCSP optionally configures a separate key for signing the issuer's
+	// OCSP responses, e.g. in a different HSM slot, so that OCSP signing
+	// doesn't contend with issuance. If it's nil, the issuer key signs both.
+	OCSP *OCSPSignerConfig
+}
+
+// OCSPSignerConfig contains info about the key that signs an issuer's OCSP
+// responses. If CertFile is empty, the key must be the issuer key, e.g. a copy
+// of it in a different HSM slot. Otherwise CertFile is a delegated OCSP
+// responder cert for the key, issued by the issuer.
+type OCSPSignerConfig struct {
+	KeyConfig
+	CertFile string
+}
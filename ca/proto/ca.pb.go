@@ -11,6 +11,7 @@ It is generated from these files:
 It has these top-level messages:
 	IssueCertificateRequest
 	IssuePrecertificateResponse
+	CheckCertificateRequestResponse
 	IssueCertificateForPrecertificateRequest
 	GenerateOCSPRequest
 	OCSPResponse
@@ -39,10 +40,12 @@ var _ = math.Inf
 const _ = proto1.ProtoPackageIsVersion2 // please upgrade the proto package
 
 type IssueCertificateRequest struct {
-	Csr              []byte `protobuf:"bytes,1,opt,name=csr" json:"csr,omitempty"`
-	RegistrationID   *int64 `protobuf:"varint,2,opt,name=registrationID" json:"registrationID,omitempty"`
-	OrderID          *int64 `protobuf:"varint,3,opt,name=orderID" json:"orderID,omitempty"`
-	XXX_unrecognized []byte `json:"-"`
+	Csr            []byte `protobuf:"bytes,1,opt,name=csr" json:"csr,omitempty"`
+	RegistrationID *int64 `protobuf:"varint,2,opt,name=registrationID" json:"registrationID,omitempty"`
+	OrderID        *int64 `protobuf:"varint,3,opt,name=orderID" json:"orderID,omitempty"`
+	// exemptions are the policy exemption tokens of the order
+	Exemptions       []string `protobuf:"bytes,4,rep,name=exemptions" json:"exemptions,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
 }
 
 func (m *IssueCertificateRequest) Reset()                    { *m = IssueCertificateRequest{} }
@@ -71,8 +74,15 @@ func (m *IssueCertificateRequest) GetOrderID() int64 {
 	return 0
 }
 
+func (m *IssueCertificateRequest) GetExemptions() []string {
+	if m != nil {
+		return m.Exemptions
+	}
+	return nil
+}
+
 type IssuePrecertificateResponse struct {
-	DER              []byte `protobuf:"bytes,1,opt,name=DER,json=dER" json:"DER,omitempty"`
+	DER              []byte `protobuf:"bytes,1,opt,name=DER" json:"DER,omitempty"`
 	XXX_unrecognized []byte `json:"-"`
 }
 
@@ -88,9 +98,30 @@ func (m *IssuePrecertificateResponse) GetDER() []byte {
 	return nil
 }
 
+// CheckCertificateRequestResponse lists every problem that would stop the CA
+// issuing for a request. It is empty if there are none.
+type CheckCertificateRequestResponse struct {
+	Problems         []*core.ProblemDetails `protobuf:"bytes,1,rep,name=problems" json:"problems,omitempty"`
+	XXX_unrecognized []byte                 `json:"-"`
+}
+
+func (m *CheckCertificateRequestResponse) Reset()         { *m = CheckCertificateRequestResponse{} }
+func (m *CheckCertificateRequestResponse) String() string { return proto1.CompactTextString(m) }
+func (*CheckCertificateRequestResponse) ProtoMessage()    {}
+func (*CheckCertificateRequestResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor0, []int{2}
+}
+
+func (m *CheckCertificateRequestResponse) GetProblems() []*core.ProblemDetails {
+	if m != nil {
+		return m.Problems
+	}
+	return nil
+}
+
 type IssueCertificateForPrecertificateRequest struct {
-	DER              []byte   `protobuf:"bytes,1,opt,name=DER,json=dER" json:"DER,omitempty"`
-	SCTs             [][]byte `protobuf:"bytes,2,rep,name=SCTs,json=sCTs" json:"SCTs,omitempty"`
+	DER              []byte   `protobuf:"bytes,1,opt,name=DER" json:"DER,omitempty"`
+	SCTs             [][]byte `protobuf:"bytes,2,rep,name=SCTs" json:"SCTs,omitempty"`
 	RegistrationID   *int64   `protobuf:"varint,3,opt,name=registrationID" json:"registrationID,omitempty"`
 	OrderID          *int64   `protobuf:"varint,4,opt,name=orderID" json:"orderID,omitempty"`
 	XXX_unrecognized []byte   `json:"-"`
@@ -99,10 +130,12 @@ type IssueCertificateForPrecertificateRequest struct {
 func (m *IssueCertificateForPrecertificateRequest) Reset() {
 	*m = IssueCertificateForPrecertificateRequest{}
 }
-func (m *IssueCertificateForPrecertificateRequest) String() string { return proto1.CompactTextString(m) }
-func (*IssueCertificateForPrecertificateRequest) ProtoMessage()    {}
+func (m *IssueCertificateForPrecertificateRequest) String() string {
+	return proto1.CompactTextString(m)
+}
+func (*IssueCertificateForPrecertificateRequest) ProtoMessage() {}
 func (*IssueCertificateForPrecertificateRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor0, []int{2}
+	return fileDescriptor0, []int{3}
 }
 
 func (m *IssueCertificateForPrecertificateRequest) GetDER() []byte {
@@ -144,7 +177,7 @@ type GenerateOCSPRequest struct {
 func (m *GenerateOCSPRequest) Reset()                    { *m = GenerateOCSPRequest{} }
 func (m *GenerateOCSPRequest) String() string            { return proto1.CompactTextString(m) }
 func (*GenerateOCSPRequest) ProtoMessage()               {}
-func (*GenerateOCSPRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+func (*GenerateOCSPRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
 
 func (m *GenerateOCSPRequest) GetCertDER() []byte {
 	if m != nil {
@@ -182,7 +215,7 @@ type OCSPResponse struct {
 func (m *OCSPResponse) Reset()                    { *m = OCSPResponse{} }
 func (m *OCSPResponse) String() string            { return proto1.CompactTextString(m) }
 func (*OCSPResponse) ProtoMessage()               {}
-func (*OCSPResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*OCSPResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
 
 func (m *OCSPResponse) GetResponse() []byte {
 	if m != nil {
@@ -194,6 +227,7 @@ func (m *OCSPResponse) GetResponse() []byte {
 func init() {
 	proto1.RegisterType((*IssueCertificateRequest)(nil), "ca.IssueCertificateRequest")
 	proto1.RegisterType((*IssuePrecertificateResponse)(nil), "ca.IssuePrecertificateResponse")
+	proto1.RegisterType((*CheckCertificateRequestResponse)(nil), "ca.CheckCertificateRequestResponse")
 	proto1.RegisterType((*IssueCertificateForPrecertificateRequest)(nil), "ca.IssueCertificateForPrecertificateRequest")
 	proto1.RegisterType((*GenerateOCSPRequest)(nil), "ca.GenerateOCSPRequest")
 	proto1.RegisterType((*OCSPResponse)(nil), "ca.OCSPResponse")
@@ -213,6 +247,7 @@ type CertificateAuthorityClient interface {
 	IssueCertificate(ctx context.Context, in *IssueCertificateRequest, opts ...grpc.CallOption) (*core.Certificate, error)
 	IssuePrecertificate(ctx context.Context, in *IssueCertificateRequest, opts ...grpc.CallOption) (*IssuePrecertificateResponse, error)
 	IssueCertificateForPrecertificate(ctx context.Context, in *IssueCertificateForPrecertificateRequest, opts ...grpc.CallOption) (*core.Certificate, error)
+	CheckCertificateRequest(ctx context.Context, in *IssueCertificateRequest, opts ...grpc.CallOption) (*CheckCertificateRequestResponse, error)
 	GenerateOCSP(ctx context.Context, in *GenerateOCSPRequest, opts ...grpc.CallOption) (*OCSPResponse, error)
 }
 
@@ -251,6 +286,15 @@ func (c *certificateAuthorityClient) IssueCertificateForPrecertificate(ctx conte
 	return out, nil
 }
 
+func (c *certificateAuthorityClient) CheckCertificateRequest(ctx context.Context, in *IssueCertificateRequest, opts ...grpc.CallOption) (*CheckCertificateRequestResponse, error) {
+	out := new(CheckCertificateRequestResponse)
+	err := grpc.Invoke(ctx, "/ca.CertificateAuthority/CheckCertificateRequest", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *certificateAuthorityClient) GenerateOCSP(ctx context.Context, in *GenerateOCSPRequest, opts ...grpc.CallOption) (*OCSPResponse, error) {
 	out := new(OCSPResponse)
 	err := grpc.Invoke(ctx, "/ca.CertificateAuthority/GenerateOCSP", in, out, c.cc, opts...)
@@ -266,6 +310,7 @@ type CertificateAuthorityServer interface {
 	IssueCertificate(context.Context, *IssueCertificateRequest) (*core.Certificate, error)
 	IssuePrecertificate(context.Context, *IssueCertificateRequest) (*IssuePrecertificateResponse, error)
 	IssueCertificateForPrecertificate(context.Context, *IssueCertificateForPrecertificateRequest) (*core.Certificate, error)
+	CheckCertificateRequest(context.Context, *IssueCertificateRequest) (*CheckCertificateRequestResponse, error)
 	GenerateOCSP(context.Context, *GenerateOCSPRequest) (*OCSPResponse, error)
 }
 
@@ -327,6 +372,24 @@ func _CertificateAuthority_IssueCertificateForPrecertificate_Handler(srv interfa
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CertificateAuthority_CheckCertificateRequest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IssueCertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CertificateAuthorityServer).CheckCertificateRequest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ca.CertificateAuthority/CheckCertificateRequest",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CertificateAuthorityServer).CheckCertificateRequest(ctx, req.(*IssueCertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _CertificateAuthority_GenerateOCSP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GenerateOCSPRequest)
 	if err := dec(in); err != nil {
@@ -361,6 +424,10 @@ var _CertificateAuthority_serviceDesc = grpc.ServiceDesc{
 			MethodName: "IssueCertificateForPrecertificate",
 			Handler:    _CertificateAuthority_IssueCertificateForPrecertificate_Handler,
 		},
+		{
+			MethodName: "CheckCertificateRequest",
+			Handler:    _CertificateAuthority_CheckCertificateRequest_Handler,
+		},
 		{
 			MethodName: "GenerateOCSP",
 			Handler:    _CertificateAuthority_GenerateOCSP_Handler,
@@ -437,31 +504,35 @@ var _OCSPGenerator_serviceDesc = grpc.ServiceDesc{
 func init() { proto1.RegisterFile("ca/proto/ca.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 403 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x53, 0x41, 0xab, 0xd3, 0x40,
-	0x10, 0x6e, 0x92, 0x3e, 0xeb, 0x1b, 0xa2, 0xf4, 0xed, 0x53, 0x5f, 0x48, 0x05, 0x6b, 0x0e, 0x12,
-	0x44, 0x52, 0xe8, 0xd5, 0x53, 0x4d, 0xaa, 0x14, 0x04, 0xcb, 0xb6, 0x5e, 0xbc, 0x2d, 0xdb, 0xa9,
-	0x06, 0x31, 0x5b, 0x67, 0x37, 0x82, 0x07, 0xff, 0x84, 0xbf, 0xce, 0x9f, 0x23, 0x49, 0x93, 0x36,
-	0x0d, 0xa9, 0x3d, 0x78, 0x9b, 0x99, 0x2f, 0x3b, 0xdf, 0x37, 0xdf, 0x4c, 0xe0, 0x46, 0x8a, 0xc9,
-	0x8e, 0x94, 0x51, 0x13, 0x29, 0xa2, 0x32, 0x60, 0xb6, 0x14, 0xfe, 0x63, 0xa9, 0x08, 0x6b, 0x40,
-	0x11, 0xee, 0xa1, 0xe0, 0x1b, 0xdc, 0x2d, 0xb4, 0xce, 0x31, 0x46, 0x32, 0xe9, 0x36, 0x95, 0xc2,
-	0x20, 0xc7, 0xef, 0x39, 0x6a, 0xc3, 0x86, 0xe0, 0x48, 0x4d, 0x9e, 0x35, 0xb6, 0x42, 0x97, 0x17,
-	0x21, 0x7b, 0x01, 0x0f, 0x09, 0x3f, 0xa7, 0xda, 0x90, 0x30, 0xa9, 0xca, 0x16, 0x89, 0x67, 0x8f,
-	0xad, 0xd0, 0xe1, 0xad, 0x2a, 0xf3, 0x60, 0xa0, 0x68, 0x83, 0xb4, 0x48, 0x3c, 0xa7, 0xfc, 0xa0,
-	0x4e, 0x83, 0x09, 0x8c, 0x4a, 0xba, 0x25, 0xa1, 0x6c, 0x32, 0xea, 0x9d, 0xca, 0x34, 0x16, 0x94,
-	0xc9, 0x9c, 0xd7, 0x94, 0x9b, 0x39, 0x0f, 0x7e, 0x5b, 0x10, 0xb6, 0x05, 0xbe, 0x55, 0xd4, 0x7e,
-	0x7f, 0x50, 0x7c, 0xfa, 0x9c, 0x31, 0xe8, 0xaf, 0xe2, 0xb5, 0xf6, 0xec, 0xb1, 0x13, 0xba, 0xbc,
-	0xaf, 0xe3, 0xb5, 0xee, 0x98, 0xc2, 0xb9, 0x34, 0x45, 0xff, 0x74, 0x8a, 0x5f, 0x70, 0xfb, 0x0e,
-	0x33, 0x24, 0x61, 0xf0, 0x43, 0xbc, 0x5a, 0xd6, 0xf4, 0x1e, 0x0c, 0x0a, 0x51, 0x47, 0x09, 0x75,
-	0xca, 0x9e, 0xc0, 0x3d, 0x6d, 0x84, 0xc9, 0x75, 0x69, 0xd8, 0x35, 0xaf, 0xb2, 0xa2, 0x4e, 0x28,
-	0xb4, 0xca, 0x4a, 0x09, 0x57, 0xbc, 0xca, 0xd8, 0x53, 0xb8, 0x26, 0xfc, 0xa1, 0xbe, 0xe2, 0x66,
-	0x66, 0x2a, 0xf2, 0x63, 0x21, 0x78, 0x09, 0xee, 0x9e, 0xb6, 0x72, 0xcd, 0x87, 0xfb, 0x54, 0xc5,
-	0x15, 0xf1, 0x21, 0x9f, 0xfe, 0xb1, 0xe1, 0x51, 0xc3, 0xba, 0x59, 0x6e, 0xbe, 0x28, 0x4a, 0xcd,
-	0x4f, 0x96, 0xc0, 0xb0, 0xed, 0x2b, 0x1b, 0x45, 0x52, 0x44, 0x67, 0xce, 0xc1, 0xbf, 0x89, 0xca,
-	0xb3, 0x69, 0x20, 0x41, 0x8f, 0x7d, 0x84, 0xdb, 0x8e, 0x7d, 0xfe, 0xbb, 0xd1, 0xb3, 0x03, 0xd8,
-	0x7d, 0x05, 0x41, 0x8f, 0x6d, 0xe1, 0xf9, 0xc5, 0xa5, 0xb3, 0x57, 0x5d, 0x24, 0xe7, 0x6e, 0xa3,
-	0x5b, 0xfe, 0x6b, 0x70, 0x9b, 0x8b, 0x64, 0x77, 0x45, 0xcb, 0x8e, 0xd5, 0xfa, 0xc3, 0x02, 0x68,
-	0x9a, 0x1e, 0xf4, 0xa6, 0xef, 0xe1, 0x41, 0x51, 0xa9, 0x3e, 0x57, 0xf4, 0x5f, 0xdd, 0xde, 0x0c,
-	0x3e, 0x5d, 0x95, 0x7f, 0xe4, 0xdf, 0x00, 0x00, 0x00, 0xff, 0xff, 0x5d, 0x91, 0xda, 0xc7, 0xc0,
-	0x03, 0x00, 0x00,
+	// 475 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x54, 0xc1, 0x6e, 0xd3, 0x40,
+	0x10, 0x8d, 0xe3, 0x94, 0x34, 0x43, 0x40, 0xe9, 0xb6, 0x10, 0x2b, 0x45, 0xd4, 0x2c, 0x12, 0xb2,
+	0x10, 0x4a, 0x50, 0xae, 0x9c, 0x4a, 0x5c, 0x50, 0x24, 0x24, 0xa2, 0x0d, 0x5c, 0x90, 0x38, 0x2c,
+	0xdb, 0x29, 0xb5, 0xda, 0x66, 0xc3, 0xec, 0x06, 0xc1, 0x81, 0x4f, 0xe0, 0xc2, 0xcf, 0xf0, 0x7b,
+	0x68, 0x1d, 0xdb, 0x75, 0x83, 0x93, 0x1c, 0x7a, 0x9b, 0x79, 0x6f, 0xbc, 0xf3, 0xf2, 0xe6, 0x29,
+	0xb0, 0xa7, 0xe4, 0x60, 0x4e, 0xda, 0xea, 0x81, 0x92, 0xfd, 0xb4, 0x60, 0x75, 0x25, 0x7b, 0x0f,
+	0x94, 0x26, 0xcc, 0x09, 0x4d, 0xb8, 0xa4, 0xf8, 0x6f, 0x0f, 0xba, 0x63, 0x63, 0x16, 0x38, 0x42,
+	0xb2, 0xc9, 0x59, 0xa2, 0xa4, 0x45, 0x81, 0xdf, 0x16, 0x68, 0x2c, 0xeb, 0x80, 0xaf, 0x0c, 0x05,
+	0x5e, 0xe8, 0x45, 0x6d, 0xe1, 0x4a, 0xf6, 0x0c, 0xee, 0x13, 0x7e, 0x4d, 0x8c, 0x25, 0x69, 0x13,
+	0x3d, 0x1b, 0xc7, 0x41, 0x3d, 0xf4, 0x22, 0x5f, 0xac, 0xa0, 0x2c, 0x80, 0xa6, 0xa6, 0x53, 0xa4,
+	0x71, 0x1c, 0xf8, 0xe9, 0x40, 0xde, 0xb2, 0xc7, 0x00, 0xf8, 0x03, 0xaf, 0xe6, 0x6e, 0xd0, 0x04,
+	0x8d, 0xd0, 0x8f, 0x5a, 0xa2, 0x84, 0xf0, 0x01, 0x1c, 0xa6, 0x72, 0x26, 0x84, 0xaa, 0xac, 0xc8,
+	0xcc, 0xf5, 0xcc, 0xa0, 0x93, 0x14, 0x9f, 0x88, 0x5c, 0x52, 0x7c, 0x22, 0xf8, 0x14, 0x8e, 0x46,
+	0xe7, 0xa8, 0x2e, 0xfe, 0xd7, 0x5f, 0x7c, 0xf4, 0x12, 0x76, 0xe7, 0xa4, 0xbf, 0x5c, 0xe2, 0x95,
+	0x09, 0xbc, 0xd0, 0x8f, 0xee, 0x0e, 0x0f, 0xfa, 0xa9, 0x05, 0x93, 0x25, 0x1a, 0xa3, 0x95, 0xc9,
+	0xa5, 0x11, 0xc5, 0x14, 0xff, 0xe3, 0x41, 0xb4, 0xea, 0xca, 0x1b, 0x4d, 0xab, 0xa2, 0x0a, 0x9b,
+	0x6e, 0x6a, 0x62, 0x0c, 0x1a, 0xd3, 0xd1, 0x07, 0x13, 0xd4, 0x43, 0x3f, 0x6a, 0x8b, 0xb4, 0xae,
+	0xb0, 0xce, 0xdf, 0x66, 0x5d, 0xe3, 0x86, 0x75, 0xfc, 0x17, 0xec, 0xbf, 0xc5, 0x19, 0x92, 0xb4,
+	0xf8, 0x7e, 0x34, 0x9d, 0xe4, 0xeb, 0x03, 0x68, 0x3a, 0x51, 0xd7, 0x12, 0xf2, 0x96, 0x3d, 0x84,
+	0x3b, 0xc6, 0x4a, 0xbb, 0x30, 0xe9, 0x95, 0x5a, 0x22, 0xeb, 0x1c, 0x4e, 0x28, 0x8d, 0x9e, 0xa5,
+	0x12, 0x76, 0x44, 0xd6, 0xb1, 0x47, 0xd0, 0x22, 0xfc, 0xae, 0x2f, 0xf0, 0xf4, 0xd8, 0x66, 0xcb,
+	0xaf, 0x01, 0xfe, 0x1c, 0xda, 0xcb, 0xb5, 0x99, 0xab, 0x3d, 0xd8, 0xa5, 0xac, 0xce, 0x16, 0x17,
+	0xfd, 0xf0, 0xaf, 0x0f, 0x07, 0x25, 0xeb, 0x8e, 0x17, 0xf6, 0x5c, 0x53, 0x62, 0x7f, 0xb2, 0x18,
+	0x3a, 0xab, 0xbe, 0xb2, 0xc3, 0xbe, 0x92, 0xfd, 0x35, 0x19, 0xec, 0xed, 0x2d, 0x2f, 0x55, 0x62,
+	0x78, 0x8d, 0x7d, 0x84, 0xfd, 0x8a, 0x90, 0x6c, 0x7e, 0xe8, 0xa8, 0x20, 0xab, 0xa3, 0xc5, 0x6b,
+	0xec, 0x0c, 0x9e, 0x6c, 0x3d, 0x3a, 0x7b, 0x51, 0xb5, 0x64, 0x5d, 0x36, 0xaa, 0xe5, 0x7f, 0x86,
+	0xee, 0x9a, 0xc8, 0x6e, 0xfe, 0x09, 0x4f, 0x1d, 0xb9, 0x25, 0xec, 0xbc, 0xc6, 0x5e, 0x41, 0xbb,
+	0x9c, 0x13, 0xd6, 0x75, 0x9f, 0x55, 0x24, 0xa7, 0xd7, 0x71, 0x44, 0xf9, 0xa6, 0xbc, 0x36, 0x7c,
+	0x07, 0xf7, 0x1c, 0x92, 0x8d, 0x6b, 0xba, 0xd5, 0x6b, 0xaf, 0x9b, 0x9f, 0x76, 0xd2, 0xbf, 0x99,
+	0x7f, 0x03, 0x00, 0x1d, 0xa3, 0x66, 0xe0, 0x95, 0x04, 0x00, 0x00,
 }
@@ -0,0 +1,26 @@
+// Package cdn purges OCSP responses from the CDNs that cache them, so that
+// revocations are seen by relying parties without waiting for the cached
+// responses to expire.
+package cdn
+
+// Purger removes URLs from a CDN's cache. The Akamai CachePurgeClient, the
+// FastlyPurger and the CloudflarePurger implement it.
+type Purger interface {
+	// Purge purges urls, splitting them into as many requests as the CDN's
+	// API limits require. It returns a FatalError if the URLs can't be
+	// purged no matter how many times it's retried.
+	Purge(urls []string) error
+}
+
+// FatalError is returned by a Purger to indicate that a purge failed for a
+// reason that cannot be remediated by retrying the purge request.
+type FatalError string
+
+func (e FatalError) Error() string { return string(e) }
+
+// IsFatal returns true if err is a purge error that will happen again if the
+// same URLs are purged again.
+func IsFatal(err error) bool {
+	_, ok := err.(FatalError)
+	return ok
+}
@@ -0,0 +1,118 @@
+package cdn
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+)
+
+// cloudflareAPI is the default base URL of the Cloudflare API.
+const cloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+// cloudflareBatchSize is the most URLs Cloudflare accepts in a single purge
+// request.
+const cloudflareBatchSize = 30
+
+type cloudflarePurgeRequest struct {
+	Files []string `json:"files"`
+}
+
+type cloudflareResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// CloudflarePurger purges URLs from a Cloudflare zone's cache.
+type CloudflarePurger struct {
+	client   *http.Client
+	endpoint string
+	apiToken string
+	log      blog.Logger
+	stats    metrics.Scope
+}
+
+// NewCloudflarePurger returns a CloudflarePurger that purges URLs from zoneID
+// using the Cloudflare API at baseURL, authenticating with apiToken. If
+// baseURL is empty the public Cloudflare API is used.
+func NewCloudflarePurger(baseURL, zoneID, apiToken string, log blog.Logger, stats metrics.Scope) (*CloudflarePurger, error) {
+	if zoneID == "" || apiToken == "" {
+		return nil, fmt.Errorf("Cloudflare zone ID and API token are required")
+	}
+	if baseURL == "" {
+		baseURL = cloudflareAPI
+	}
+	if _, err := url.Parse(baseURL); err != nil {
+		return nil, err
+	}
+	return &CloudflarePurger{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		endpoint: fmt.Sprintf("%s/zones/%s/purge_cache", strings.TrimSuffix(baseURL, "/"), url.PathEscape(zoneID)),
+		apiToken: apiToken,
+		log:      log,
+		stats:    stats.NewScope("Cloudflare"),
+	}, nil
+}
+
+// Purge purges urls in batches of up to 30, stopping at the first batch that
+// fails.
+func (cp *CloudflarePurger) Purge(urls []string) error {
+	for len(urls) > 0 {
+		n := len(urls)
+		if n > cloudflareBatchSize {
+			n = cloudflareBatchSize
+		}
+		if err := cp.purge(urls[:n]); err != nil {
+			return err
+		}
+		urls = urls[n:]
+	}
+	cp.stats.Inc("SuccessfulPurges", 1)
+	return nil
+}
+
+func (cp *CloudflarePurger) purge(urls []string) error {
+	reqJSON, err := json.Marshal(cloudflarePurgeRequest{Files: urls})
+	if err != nil {
+		return FatalError(err.Error())
+	}
+	req, err := http.NewRequest("POST", cp.endpoint, bytes.NewReader(reqJSON))
+	if err != nil {
+		return FatalError(err.Error())
+	}
+	req.Header.Set("Authorization", "Bearer "+cp.apiToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := cp.client.Do(req)
+	if err != nil {
+		return err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	if err := checkStatus(resp.StatusCode, http.StatusOK, body); err != nil {
+		return err
+	}
+
+	var purgeResp cloudflareResponse
+	if err := json.Unmarshal(body, &purgeResp); err != nil {
+		return fmt.Errorf("%s. Body was: %s", err, body)
+	}
+	if !purgeResp.Success {
+		return FatalError(fmt.Sprintf("Cloudflare purge failed: %s", body))
+	}
+	cp.log.Infof("Sent successful Cloudflare purge request for URLs: %s", urls)
+	return nil
+}
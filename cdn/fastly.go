@@ -0,0 +1,99 @@
+package cdn
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+)
+
+// fastlyAPI is the default base URL of the Fastly API.
+const fastlyAPI = "https://api.fastly.com"
+
+// FastlyPurger purges URLs using Fastly's single URL purge API, which takes
+// one URL per request.
+type FastlyPurger struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+	log     blog.Logger
+	stats   metrics.Scope
+}
+
+// NewFastlyPurger returns a FastlyPurger that authenticates to the Fastly API
+// at baseURL with apiKey. If baseURL is empty the public Fastly API is used.
+func NewFastlyPurger(baseURL, apiKey string, log blog.Logger, stats metrics.Scope) (*FastlyPurger, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("Fastly API key is required")
+	}
+	if baseURL == "" {
+		baseURL = fastlyAPI
+	}
+	if _, err := url.Parse(baseURL); err != nil {
+		return nil, err
+	}
+	return &FastlyPurger{
+		client:  &http.Client{Timeout: 30 * time.Second},
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		log:     log,
+		stats:   stats.NewScope("Fastly"),
+	}, nil
+}
+
+// Purge purges each of urls in turn, stopping at the first that fails.
+func (fp *FastlyPurger) Purge(urls []string) error {
+	for _, u := range urls {
+		if err := fp.purge(u); err != nil {
+			return err
+		}
+	}
+	fp.stats.Inc("SuccessfulPurges", 1)
+	fp.log.Infof("Sent successful Fastly purge requests for URLs: %s", urls)
+	return nil
+}
+
+func (fp *FastlyPurger) purge(purgeURL string) error {
+	parsed, err := url.Parse(purgeURL)
+	if err != nil || parsed.Host == "" {
+		return FatalError(fmt.Sprintf("Invalid URL to purge %q", purgeURL))
+	}
+	// The API takes the URL to purge without its scheme.
+	endpoint := fmt.Sprintf("%s/purge/%s", fp.baseURL, strings.TrimPrefix(purgeURL, parsed.Scheme+"://"))
+	req, err := http.NewRequest("POST", endpoint, nil)
+	if err != nil {
+		return FatalError(err.Error())
+	}
+	req.Header.Set("Fastly-Key", fp.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := fp.client.Do(req)
+	if err != nil {
+		return err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	return checkStatus(resp.StatusCode, http.StatusOK, body)
+}
+
+// checkStatus returns nil if status is the expected status code of a
+// successful purge. Otherwise it returns an error, which is fatal unless the
+// request was rate limited or the CDN had a server error.
+func checkStatus(status, expected int, body []byte) error {
+	if status == expected {
+		return nil
+	}
+	err := fmt.Errorf("Unexpected HTTP status code '%d': %s", status, body)
+	if status == http.StatusTooManyRequests || status >= 500 {
+		return err
+	}
+	return FatalError(err.Error())
+}
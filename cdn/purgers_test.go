@@ -0,0 +1,88 @@
+package cdn
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestFastlyPurger(t *testing.T) {
+	var purged []string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.Header.Get("Fastly-Key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		purged = append(purged, r.URL.Path)
+		w.WriteHeader(status)
+		fmt.Fprint(w, `{"status": "ok", "id": "1"}`)
+	}))
+	defer srv.Close()
+
+	_, err := NewFastlyPurger(srv.URL, "", blog.NewMock(), metrics.NewNoopScope())
+	test.AssertError(t, err, "NewFastlyPurger accepted an empty API key")
+
+	fp, err := NewFastlyPurger(srv.URL, "key", blog.NewMock(), metrics.NewNoopScope())
+	test.AssertNotError(t, err, "NewFastlyPurger failed")
+	err = fp.Purge([]string{"http://ocsp.example.com/abc", "http://ocsp.example.com/def"})
+	test.AssertNotError(t, err, "Purge failed")
+	test.AssertDeepEquals(t, purged, []string{"/purge/ocsp.example.com/abc", "/purge/ocsp.example.com/def"})
+
+	status = http.StatusServiceUnavailable
+	err = fp.Purge([]string{"http://ocsp.example.com/abc"})
+	test.AssertError(t, err, "Purge didn't fail with a 503 response")
+	test.Assert(t, !IsFatal(err), "503 response was fatal")
+
+	status = http.StatusBadRequest
+	err = fp.Purge([]string{"http://ocsp.example.com/abc"})
+	test.Assert(t, IsFatal(err), "400 response wasn't fatal")
+
+	err = fp.Purge([]string{"not a url"})
+	test.Assert(t, IsFatal(err), "invalid URL wasn't fatal")
+}
+
+func TestCloudflarePurger(t *testing.T) {
+	var batches [][]string
+	success := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/zones/zone/purge_cache" || r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var req cloudflarePurgeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		batches = append(batches, req.Files)
+		fmt.Fprintf(w, `{"success": %t, "errors": [], "messages": [], "result": {"id": "1"}}`, success)
+	}))
+	defer srv.Close()
+
+	_, err := NewCloudflarePurger(srv.URL, "", "token", blog.NewMock(), metrics.NewNoopScope())
+	test.AssertError(t, err, "NewCloudflarePurger accepted an empty zone ID")
+
+	cp, err := NewCloudflarePurger(srv.URL, "zone", "token", blog.NewMock(), metrics.NewNoopScope())
+	test.AssertNotError(t, err, "NewCloudflarePurger failed")
+	err = cp.Purge(numberedURLs(70))
+	test.AssertNotError(t, err, "Purge failed")
+	test.AssertEquals(t, len(batches), 3)
+	test.AssertEquals(t, len(batches[0]), cloudflareBatchSize)
+	test.AssertEquals(t, len(batches[2]), 10)
+
+	success = false
+	err = cp.Purge(numberedURLs(1))
+	test.Assert(t, IsFatal(err), "unsuccessful purge wasn't fatal")
+
+	cp, err = NewCloudflarePurger(srv.URL, "zone", "wrong", blog.NewMock(), metrics.NewNoopScope())
+	test.AssertNotError(t, err, "NewCloudflarePurger failed")
+	err = cp.Purge(numberedURLs(1))
+	test.Assert(t, IsFatal(err), "403 response wasn't fatal")
+}
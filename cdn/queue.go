@@ -0,0 +1,264 @@
+package cdn
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/core"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+)
+
+// ErrQueueFull is returned by PurgeQueue.Add when the queue already holds its
+// maximum number of URLs.
+var ErrQueueFull = errors.New("CDN purge queue too large")
+
+// queueBatchSize is the most URLs the queue passes to a Purger at once. It's
+// the largest batch Akamai accepts, and the other CDNs' Purgers split
+// batches further if they need to.
+const queueBatchSize = 100
+
+// queueBytesPerBatch is the most bytes of URLs, as JSON, the queue passes to a
+// Purger at once. Akamai rejects purge requests larger than 50,000 bytes, so
+// this leaves some room for the JSON around the URLs.
+const queueBytesPerBatch = 50000 - 100
+
+// queuedURL is a URL waiting to be purged, and the time it was queued.
+type queuedURL struct {
+	URL   string    `json:"url"`
+	Added time.Time `json:"added"`
+}
+
+// PurgeQueue holds URLs waiting to be purged and sends them to a Purger in
+// batches that fit in a single Akamai purge request. Batches that fail for a reason
+// that might go away, such as rate limiting, stay at the front of the queue
+// and are retried with exponential backoff. If a backlog file is configured
+// the queue is saved to it after each call to Process, and loaded from it by
+// NewPurgeQueue, so that URLs queued before a restart are still purged.
+type PurgeQueue struct {
+	mu      sync.Mutex
+	queue   []queuedURL
+	dirty   bool
+	maxSize int
+
+	client      Purger
+	backlogFile string
+
+	// failures is the number of consecutive batches that failed, and
+	// retryAfter the time before which Process won't try again.
+	failures   int
+	retryAfter time.Time
+	backoff    time.Duration
+	maxBackoff time.Duration
+
+	clk        clock.Clock
+	log        blog.Logger
+	length     prometheus.Gauge
+	oldestAge  prometheus.Gauge
+	purgedURLs *prometheus.CounterVec
+}
+
+// NewPurgeQueue returns a PurgeQueue that sends URLs to client, holding at
+// most maxSize of them. If backlogFile is not empty, URLs left over from a
+// previous run are loaded from it.
+func NewPurgeQueue(
+	client Purger,
+	backlogFile string,
+	maxSize int,
+	backoff time.Duration,
+	clk clock.Clock,
+	log blog.Logger,
+	stats metrics.Scope,
+) (*PurgeQueue, error) {
+	length := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "cdn_purge_queue_length",
+		Help: "Number of URLs waiting to be purged",
+	})
+	oldestAge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "cdn_purge_queue_oldest_seconds",
+		Help: "Age in seconds of the oldest URL waiting to be purged",
+	})
+	purgedURLs := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cdn_purged_urls",
+		Help: "Number of URLs removed from the purge queue, by result",
+	}, []string{"result"})
+	stats.MustRegister(length, oldestAge, purgedURLs)
+
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	q := &PurgeQueue{
+		maxSize:     maxSize,
+		client:      client,
+		backlogFile: backlogFile,
+		backoff:     backoff,
+		maxBackoff:  5 * time.Minute,
+		clk:         clk,
+		log:         log,
+		length:      length,
+		oldestAge:   oldestAge,
+		purgedURLs:  purgedURLs,
+	}
+	if backlogFile != "" {
+		contents, err := ioutil.ReadFile(backlogFile)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if len(contents) > 0 {
+			if err := json.Unmarshal(contents, &q.queue); err != nil {
+				return nil, err
+			}
+			log.Infof("Loaded %d URLs to purge from %s", len(q.queue), backlogFile)
+		}
+	}
+	q.updateGauges()
+	return q, nil
+}
+
+// Add queues urls to be purged, returning ErrQueueFull if the queue is
+// already full.
+func (q *PurgeQueue) Add(urls []string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.queue) >= q.maxSize {
+		return ErrQueueFull
+	}
+	now := q.clk.Now()
+	for _, u := range urls {
+		q.queue = append(q.queue, queuedURL{URL: u, Added: now})
+	}
+	q.dirty = true
+	q.updateGauges()
+	return nil
+}
+
+// Len returns the number of URLs waiting to be purged.
+func (q *PurgeQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queue)
+}
+
+// Process purges batches of queued URLs until the queue is empty, maxBatches
+// batches have been sent, or a batch fails. A maxBatches of zero means no
+// limit. It then saves the queue to the backlog file if it has changed.
+// Process must not be called concurrently with itself.
+func (q *PurgeQueue) Process(maxBatches int) error {
+	for sent := 0; maxBatches <= 0 || sent < maxBatches; sent++ {
+		if q.clk.Now().Before(q.retryAfter) {
+			break
+		}
+		q.mu.Lock()
+		urls := urlsOf(q.queue)
+		q.mu.Unlock()
+		batch := urls[:batchSize(urls)]
+		if len(batch) == 0 {
+			break
+		}
+
+		err := q.client.Purge(batch)
+		if err != nil && !IsFatal(err) {
+			q.failures++
+			q.retryAfter = q.clk.Now().Add(core.RetryBackoff(q.failures, q.backoff, q.maxBackoff, 2))
+			q.log.Errf("Failed to purge %d URLs, retrying after %s: %s", len(batch), q.retryAfter, err)
+			break
+		}
+		q.failures = 0
+		result := "success"
+		if err != nil {
+			// Sending this batch again would fail the same way, so drop it
+			// rather than let it block the rest of the queue.
+			result = "fatal"
+			q.log.AuditErrf("Failed to purge %d URLs, dropping them: %s: %q", len(batch), err, batch)
+		}
+		q.purgedURLs.WithLabelValues(result).Add(float64(len(batch)))
+
+		q.mu.Lock()
+		// Add only appends, so the batch is still at the front of the queue.
+		q.queue = q.queue[len(batch):]
+		q.dirty = true
+		q.mu.Unlock()
+	}
+	q.mu.Lock()
+	q.updateGauges()
+	q.mu.Unlock()
+	return q.Persist()
+}
+
+// Persist saves the queue to the backlog file, if one is configured and the
+// queue has changed since it was last saved.
+func (q *PurgeQueue) Persist() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.backlogFile == "" || !q.dirty {
+		return nil
+	}
+	contents, err := json.Marshal(q.queue)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file and rename it so that a crash part way
+	// through doesn't leave a truncated backlog.
+	tmp, err := ioutil.TempFile(filepath.Dir(q.backlogFile), filepath.Base(q.backlogFile))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(contents); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), q.backlogFile); err != nil {
+		return err
+	}
+	q.dirty = false
+	return nil
+}
+
+// updateGauges sets the queue length and age metrics. q.mu must be held.
+func (q *PurgeQueue) updateGauges() {
+	q.length.Set(float64(len(q.queue)))
+	if len(q.queue) == 0 {
+		q.oldestAge.Set(0)
+		return
+	}
+	q.oldestAge.Set(q.clk.Since(q.queue[0].Added).Seconds())
+}
+
+// batchSize returns how many of the URLs at the start of urls fit in a single
+// batch.
+func batchSize(urls []string) int {
+	size := 0
+	for i, u := range urls {
+		// Each URL is quoted and followed by a comma
+		size += len(u) + 3
+		if i == queueBatchSize || (i > 0 && size > queueBytesPerBatch) {
+			return i
+		}
+	}
+	return len(urls)
+}
+
+// urlsOf returns a copy of the URLs of queued, up to as many as could fit in a
+// batch.
+func urlsOf(queued []queuedURL) []string {
+	if len(queued) > queueBatchSize+1 {
+		queued = queued[:queueBatchSize+1]
+	}
+	urls := make([]string, len(queued))
+	for i, u := range queued {
+		urls[i] = u.URL
+	}
+	return urls
+}
@@ -0,0 +1,132 @@
+package cdn
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+// fakePurger records the batches it's asked to purge, and fails with err if
+// it's set.
+type fakePurger struct {
+	batches [][]string
+	err     error
+}
+
+func (fp *fakePurger) Purge(urls []string) error {
+	if fp.err != nil {
+		return fp.err
+	}
+	fp.batches = append(fp.batches, urls)
+	return nil
+}
+
+func numberedURLs(n int) []string {
+	var urls []string
+	for i := 0; i < n; i++ {
+		urls = append(urls, fmt.Sprintf("http://ocsp.example.com/%d", i))
+	}
+	return urls
+}
+
+func TestBatchSize(t *testing.T) {
+	test.AssertEquals(t, batchSize(nil), 0)
+	test.AssertEquals(t, batchSize(numberedURLs(5)), 5)
+	test.AssertEquals(t, batchSize(numberedURLs(250)), queueBatchSize)
+
+	long := make([]byte, 20000)
+	for i := range long {
+		long[i] = 'a'
+	}
+	var urls []string
+	for i := 0; i < 4; i++ {
+		urls = append(urls, "http://ocsp.example.com/"+string(long))
+	}
+	test.AssertEquals(t, batchSize(urls), 2)
+	// A single URL that's too long is still sent on its own
+	test.AssertEquals(t, batchSize([]string{string(make([]byte, 60000))}), 1)
+}
+
+func TestPurgeQueue(t *testing.T) {
+	fc := clock.NewFake()
+	fp := &fakePurger{}
+	q, err := NewPurgeQueue(fp, "", 300, time.Second, fc, blog.NewMock(), metrics.NewNoopScope())
+	test.AssertNotError(t, err, "NewPurgeQueue failed")
+
+	test.AssertNotError(t, q.Add(numberedURLs(250)), "Add failed")
+	test.AssertNotError(t, q.Add(numberedURLs(60)), "Add failed")
+	test.AssertEquals(t, q.Add(numberedURLs(1)), ErrQueueFull)
+	test.AssertEquals(t, test.CountGauge(q.length), 310)
+
+	// Only two batches are sent per call
+	fc.Add(time.Minute)
+	test.AssertNotError(t, q.Process(2), "Process failed")
+	test.AssertEquals(t, len(fp.batches), 2)
+	test.AssertEquals(t, len(fp.batches[0]), queueBatchSize)
+	test.AssertEquals(t, q.Len(), 110)
+	test.AssertEquals(t, test.CountGauge(q.oldestAge), 60)
+
+	// A retryable failure keeps the URLs queued and backs off
+	fp.err = errors.New("429")
+	test.AssertNotError(t, q.Process(0), "Process failed")
+	test.AssertEquals(t, q.Len(), 110)
+	fp.err = nil
+	test.AssertNotError(t, q.Process(0), "Process failed")
+	test.AssertEquals(t, len(fp.batches), 2)
+	fc.Add(2 * time.Second)
+	test.AssertNotError(t, q.Process(0), "Process failed")
+	test.AssertEquals(t, len(fp.batches), 4)
+	test.AssertEquals(t, q.Len(), 0)
+
+	// A fatal failure drops the batch
+	test.AssertNotError(t, q.Add(numberedURLs(10)), "Add failed")
+	fp.err = FatalError("403")
+	test.AssertNotError(t, q.Process(0), "Process failed")
+	test.AssertEquals(t, q.Len(), 0)
+	test.AssertEquals(t, test.CountCounter(q.purgedURLs.With(prometheus.Labels{"result": "fatal"})), 10)
+	test.AssertEquals(t, test.CountCounter(q.purgedURLs.With(prometheus.Labels{"result": "success"})), 310)
+}
+
+func TestPurgeQueueBacklog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "akamai-backlog")
+	test.AssertNotError(t, err, "TempDir failed")
+	defer os.RemoveAll(dir)
+	backlog := filepath.Join(dir, "backlog.json")
+
+	fc := clock.NewFake()
+	fp := &fakePurger{err: errors.New("503")}
+	q, err := NewPurgeQueue(fp, backlog, 1000, time.Second, fc, blog.NewMock(), metrics.NewNoopScope())
+	test.AssertNotError(t, err, "NewPurgeQueue failed")
+	test.AssertNotError(t, q.Add(numberedURLs(5)), "Add failed")
+	test.AssertNotError(t, q.Process(0), "Process failed")
+
+	// The URLs that couldn't be purged are loaded by the next queue
+	fc.Add(time.Hour)
+	fp.err = nil
+	q, err = NewPurgeQueue(fp, backlog, 1000, time.Second, fc, blog.NewMock(), metrics.NewNoopScope())
+	test.AssertNotError(t, err, "NewPurgeQueue failed")
+	test.AssertEquals(t, q.Len(), 5)
+	test.AssertEquals(t, test.CountGauge(q.oldestAge), 3600)
+	test.AssertNotError(t, q.Process(0), "Process failed")
+	test.AssertDeepEquals(t, fp.batches, [][]string{numberedURLs(5)})
+
+	q, err = NewPurgeQueue(fp, backlog, 1000, time.Second, fc, blog.NewMock(), metrics.NewNoopScope())
+	test.AssertNotError(t, err, "NewPurgeQueue failed")
+	test.AssertEquals(t, q.Len(), 0)
+
+	err = ioutil.WriteFile(backlog, []byte("not json"), 0644)
+	test.AssertNotError(t, err, "WriteFile failed")
+	_, err = NewPurgeQueue(fp, backlog, 1000, time.Second, fc, blog.NewMock(), metrics.NewNoopScope())
+	test.AssertError(t, err, "NewPurgeQueue accepted a corrupt backlog")
+}
@@ -0,0 +1,653 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"golang.org/x/net/context"
+	jose "gopkg.in/square/go-jose.v2"
+
+	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/features"
+	bgrpc "github.com/letsencrypt/boulder/grpc"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/policy"
+	rapb "github.com/letsencrypt/boulder/ra/proto"
+	"github.com/letsencrypt/boulder/sa"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+)
+
+const usageString = `
+usage:
+account-admin lookup --config <path> (--key <path> | --key-digest <digest> | --contact <contact>)
+account-admin show --config <path> --regID <id> [--limit <n>]
+account-admin update-contacts --config <path> --regID <id> --contacts <contacts> --reason <text>
+account-admin deactivate --config <path> --regID <id> --reason <text>
+account-admin pause --config <path> --regID <id> --reason <text>
+account-admin unpause --config <path> --regID <id> --reason <text>
+account-admin exempt --config <path> --regID <id> --identifier <name> --entry <entry> [--lifetime <duration>] --reason <text>
+account-admin whitelist-challenge --config <path> --regID <id> --challenge <type> [--lifetime <duration>] --reason <text>
+account-admin unwhitelist-challenge --config <path> --regID <id> --challenge <type> --reason <text>
+
+command descriptions:
+  lookup           Find accounts by public key or contact
+  show             Show an account with its most recent orders and certificates
+  update-contacts  Replace the contacts of an account
+  deactivate       Permanently deactivate an account
+  pause            Stop an account from making any ACME requests until it is unpaused
+  unpause          Allow a paused account to make ACME requests again
+  exempt           Print a policy exemption token, which the account can present
+                   in one new order to issue for an identifier despite one
+                   hostname policy entry
+  whitelist-challenge
+                   Enable a challenge type for an account even if it is
+                   disabled for everyone else
+  unwhitelist-challenge
+                   Remove an account's challenge whitelist entry
+
+args:
+  config      File path to the configuration file for this service
+  key         File containing the account's public key, as a JWK or PEM
+  key-digest  Base64 SHA-256 digest of the account key's SubjectPublicKeyInfo
+  contact     Contact URL, e.g. mailto:admin@example.com
+  regID       ID of the account
+  limit       Number of recent orders and certificates to show (default 10)
+  contacts    Comma separated list of contact URLs. An empty list removes all contacts
+  identifier  Name the account is exempted for, e.g. www.example.com
+  entry       Hostname policy entry the identifier is exempt from, e.g. example.com
+  challenge   Challenge type, e.g. tls-sni-01
+  lifetime    How long the exemption token can be used for (default 24h, at most 168h),
+              or how long the challenge whitelist entry lasts (default forever)
+  reason      Why the account is being changed, e.g. a support ticket reference
+`
+
+type config struct {
+	AccountAdmin struct {
+		cmd.DBConfig
+		// The tool needs a TLSConfig to set up its gRPC client certs, but
+		// doesn't get the TLS field from ServiceConfig, so declares its own.
+		TLS cmd.TLSConfig
+
+		RAService *cmd.GRPCClientConfig
+		SAService *cmd.GRPCClientConfig
+
+		// PolicyExemptionKey is the key the exempt command signs tokens
+		// with, the same as the RA's and CA's.
+		PolicyExemptionKey cmd.PasswordConfig
+
+		Features map[string]bool
+	}
+
+	Syslog cmd.SyslogConfig
+}
+
+// accountRA and accountSA are the parts of the RA and SA used by the tool.
+type accountRA interface {
+	UpdateRegistration(ctx context.Context, base, updates core.Registration) (core.Registration, error)
+	DeactivateRegistration(ctx context.Context, reg core.Registration) error
+}
+
+type accountSA interface {
+	GetRegistration(ctx context.Context, regID int64) (core.Registration, error)
+	GetRegistrationByKey(ctx context.Context, jwk *jose.JSONWebKey) (core.Registration, error)
+	UpdateRegistration(ctx context.Context, reg core.Registration) error
+	AddChallengeWhitelistEntry(ctx context.Context, req *sapb.ChallengeWhitelistEntry) (*sapb.ChallengeWhitelistEntry, error)
+	RemoveChallengeWhitelistEntry(ctx context.Context, req *sapb.RemoveChallengeWhitelistEntryRequest) error
+}
+
+// accountDB is used for the lookups that the SA has no RPCs for.
+type accountDB interface {
+	Select(i interface{}, query string, args ...interface{}) ([]interface{}, error)
+}
+
+// accountAdmin holds what each subcommand needs. Every command is audit logged
+// along with the operator's username.
+type accountAdmin struct {
+	rac      accountRA
+	sac      accountSA
+	dbMap    accountDB
+	log      blog.Logger
+	clk      clock.Clock
+	out      io.Writer
+	username string
+	// exemptionKey is the key policy exemption tokens are signed with
+	exemptionKey []byte
+}
+
+type orderSummary struct {
+	ID      int64     `db:"id"`
+	Created time.Time `db:"created"`
+	Expires time.Time `db:"expires"`
+	Serial  string    `db:"certificateSerial"`
+}
+
+type certificateSummary struct {
+	Serial  string    `db:"serial"`
+	Issued  time.Time `db:"issued"`
+	Expires time.Time `db:"expires"`
+}
+
+func setupContext(c config) accountAdmin {
+	logger := cmd.NewLogger(c.Syslog)
+
+	tlsConfig, err := c.AccountAdmin.TLS.Load()
+	cmd.FailOnError(err, "TLS config")
+
+	clk := cmd.Clock()
+
+	clientMetrics := bgrpc.NewClientMetrics(metrics.NewNoopScope())
+	raConn, err := bgrpc.ClientSetup(c.AccountAdmin.RAService, tlsConfig, clientMetrics, clk)
+	cmd.FailOnError(err, "Failed to load credentials and create gRPC connection to RA")
+	rac := bgrpc.NewRegistrationAuthorityClient(rapb.NewRegistrationAuthorityClient(raConn))
+
+	saConn, err := bgrpc.ClientSetup(c.AccountAdmin.SAService, tlsConfig, clientMetrics, clk)
+	cmd.FailOnError(err, "Failed to load credentials and create gRPC connection to SA")
+	sac := bgrpc.NewStorageAuthorityClient(sapb.NewStorageAuthorityClient(saConn))
+
+	dbURL, err := c.AccountAdmin.DBConfig.URL()
+	cmd.FailOnError(err, "Couldn't load DB URL")
+	dbMap, err := sa.NewDbMap(dbURL, c.AccountAdmin.DBConfig.MaxDBConns)
+	cmd.FailOnError(err, "Couldn't setup database connection")
+
+	u, err := user.Current()
+	cmd.FailOnError(err, "Couldn't determine the current user")
+
+	exemptionKey, err := c.AccountAdmin.PolicyExemptionKey.Pass()
+	cmd.FailOnError(err, "Couldn't load policy exemption key")
+
+	return accountAdmin{
+		rac:          rac,
+		sac:          sac,
+		dbMap:        dbMap,
+		log:          logger,
+		clk:          clk,
+		out:          os.Stdout,
+		username:     u.Username,
+		exemptionKey: []byte(exemptionKey),
+	}
+}
+
+// loadKey reads a public key from a file containing either a JWK or a PEM
+// encoded SubjectPublicKeyInfo.
+func loadKey(filename string) (*jose.JSONWebKey, error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var jwk jose.JSONWebKey
+	if err := json.Unmarshal(contents, &jwk); err == nil {
+		return &jwk, nil
+	}
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, fmt.Errorf("%q contains neither a JWK nor a PEM public key", filename)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public key in %q: %s", filename, err)
+	}
+	return &jose.JSONWebKey{Key: key}, nil
+}
+
+// escapeLike escapes the wildcard characters of a MySQL LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (aa accountAdmin) lookupByKey(ctx context.Context, jwk *jose.JSONWebKey) error {
+	digest, err := core.KeyDigest(jwk)
+	if err != nil {
+		return err
+	}
+	aa.log.AuditInfof("Looked up account by key: digest=[%s] user=[%s]", digest, aa.username)
+	reg, err := aa.sac.GetRegistrationByKey(ctx, jwk)
+	if err != nil {
+		return err
+	}
+	return aa.printAccounts([]core.Registration{reg})
+}
+
+func (aa accountAdmin) lookupByKeyDigest(ctx context.Context, digest string) error {
+	aa.log.AuditInfof("Looked up account by key: digest=[%s] user=[%s]", digest, aa.username)
+	var ids []int64
+	_, err := aa.dbMap.Select(&ids, "SELECT id FROM registrations WHERE jwk_sha256 = ?", digest)
+	if err != nil {
+		return err
+	}
+	return aa.printAccountIDs(ctx, ids)
+}
+
+func (aa accountAdmin) lookupByContact(ctx context.Context, contact string) error {
+	aa.log.AuditInfof("Looked up accounts by contact: contact=[%s] user=[%s]", contact, aa.username)
+	// Contacts are stored as a JSON list, so match the quoted contact anywhere
+	// in it. There's no index that can help with this, so it is a full scan of
+	// the registrations table.
+	var ids []int64
+	_, err := aa.dbMap.Select(
+		&ids,
+		"SELECT id FROM registrations WHERE contact LIKE ? ORDER BY id",
+		`%"`+escapeLike(contact)+`"%`,
+	)
+	if err != nil {
+		return err
+	}
+	return aa.printAccountIDs(ctx, ids)
+}
+
+func (aa accountAdmin) printAccountIDs(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return fmt.Errorf("no matching accounts found")
+	}
+	var regs []core.Registration
+	for _, id := range ids {
+		reg, err := aa.sac.GetRegistration(ctx, id)
+		if err != nil {
+			return err
+		}
+		regs = append(regs, reg)
+	}
+	return aa.printAccounts(regs)
+}
+
+func (aa accountAdmin) printAccounts(regs []core.Registration) error {
+	w := tabwriter.NewWriter(aa.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tCREATED\tINITIAL IP\tCONTACTS")
+	for _, reg := range regs {
+		var contacts []string
+		if reg.Contact != nil {
+			contacts = *reg.Contact
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
+			reg.ID,
+			reg.Status,
+			reg.CreatedAt.UTC().Format(time.RFC3339),
+			reg.InitialIP,
+			strings.Join(contacts, ","))
+	}
+	return w.Flush()
+}
+
+func (aa accountAdmin) show(ctx context.Context, regID int64, limit int) error {
+	aa.log.AuditInfof("Showed account %d: user=[%s]", regID, aa.username)
+	reg, err := aa.sac.GetRegistration(ctx, regID)
+	if err != nil {
+		return err
+	}
+	err = aa.printAccounts([]core.Registration{reg})
+	if err != nil {
+		return err
+	}
+
+	var orders []orderSummary
+	_, err = aa.dbMap.Select(
+		&orders,
+		"SELECT id, created, expires, certificateSerial FROM orders WHERE registrationID = ? ORDER BY created DESC LIMIT ?",
+		regID,
+		limit,
+	)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(aa.out, "\nRecent orders:\n")
+	w := tabwriter.NewWriter(aa.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED\tEXPIRES\tCERTIFICATE")
+	for _, o := range orders {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n",
+			o.ID,
+			o.Created.UTC().Format(time.RFC3339),
+			o.Expires.UTC().Format(time.RFC3339),
+			o.Serial)
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+
+	var certs []certificateSummary
+	_, err = aa.dbMap.Select(
+		&certs,
+		"SELECT serial, issued, expires FROM certificates WHERE registrationID = ? ORDER BY issued DESC LIMIT ?",
+		regID,
+		limit,
+	)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(aa.out, "\nRecent certificates:\n")
+	w = tabwriter.NewWriter(aa.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SERIAL\tISSUED\tEXPIRES")
+	for _, c := range certs {
+		fmt.Fprintf(w, "%s\t%s\t%s\n",
+			c.Serial,
+			c.Issued.UTC().Format(time.RFC3339),
+			c.Expires.UTC().Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+// updateContacts replaces the contacts of an account. The RA validates the new
+// contacts the same way it would for a request from the account itself.
+func (aa accountAdmin) updateContacts(ctx context.Context, regID int64, contacts []string, reason string) error {
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("--reason must be provided")
+	}
+	reg, err := aa.sac.GetRegistration(ctx, regID)
+	if err != nil {
+		return err
+	}
+	var old []string
+	if reg.Contact != nil {
+		old = *reg.Contact
+	}
+	_, err = aa.rac.UpdateRegistration(ctx, reg, core.Registration{Contact: &contacts})
+	if err != nil {
+		return err
+	}
+	aa.log.AuditInfof("Updated contacts of account %d: old=[%s] new=[%s] user=[%s] reason=[%s]",
+		regID, strings.Join(old, ","), strings.Join(contacts, ","), aa.username, reason)
+	return nil
+}
+
+func (aa accountAdmin) deactivate(ctx context.Context, regID int64, reason string) error {
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("--reason must be provided")
+	}
+	reg, err := aa.sac.GetRegistration(ctx, regID)
+	if err != nil {
+		return err
+	}
+	if reg.Status == core.StatusPaused {
+		return fmt.Errorf("account %d is paused and must be unpaused before it can be deactivated", regID)
+	}
+	err = aa.rac.DeactivateRegistration(ctx, reg)
+	if err != nil {
+		return err
+	}
+	aa.log.AuditInfof("Deactivated account %d: user=[%s] reason=[%s]", regID, aa.username, reason)
+	return nil
+}
+
+// setStatus moves an account from the from status to the to status. It is
+// used to pause and unpause accounts.
+func (aa accountAdmin) setStatus(ctx context.Context, regID int64, from, to core.AcmeStatus, reason string) error {
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("--reason must be provided")
+	}
+	reg, err := aa.sac.GetRegistration(ctx, regID)
+	if err != nil {
+		return err
+	}
+	if reg.Status != from {
+		return fmt.Errorf("account %d has status %q, expected %q", regID, reg.Status, from)
+	}
+	reg.Status = to
+	err = aa.sac.UpdateRegistration(ctx, reg)
+	if err != nil {
+		return err
+	}
+	aa.log.AuditInfof("Changed status of account %d from %s to %s: user=[%s] reason=[%s]",
+		regID, from, to, aa.username, reason)
+	return nil
+}
+
+// maxExemptionLifetime is the longest an exemption token can be used for.
+const maxExemptionLifetime = 7 * 24 * time.Hour
+
+// exempt prints a policy exemption token for the account to issue for
+// identifier despite the hostname policy entry, valid for lifetime.
+func (aa accountAdmin) exempt(ctx context.Context, regID int64, identifier, entry string, lifetime time.Duration, reason string) error {
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("--reason must be provided")
+	}
+	if lifetime <= 0 || lifetime > maxExemptionLifetime {
+		return fmt.Errorf("--lifetime must be positive and at most %s", maxExemptionLifetime)
+	}
+	reg, err := aa.sac.GetRegistration(ctx, regID)
+	if err != nil {
+		return err
+	}
+	if reg.Status != core.StatusValid {
+		return fmt.Errorf("account %d has status %q, expected %q", regID, reg.Status, core.StatusValid)
+	}
+	e := policy.Exemption{
+		ID:             core.RandomString(16),
+		RegistrationID: regID,
+		Identifier:     strings.ToLower(identifier),
+		Entry:          strings.ToLower(entry),
+		Expires:        aa.clk.Now().Add(lifetime).UTC(),
+	}
+	token, err := policy.SignExemption(aa.exemptionKey, e)
+	if err != nil {
+		return err
+	}
+	aa.log.AuditInfof("Issued policy exemption %s to account %d: identifier=[%s] entry=[%s] expires=[%s] user=[%s] reason=[%s]",
+		e.ID, regID, e.Identifier, e.Entry, e.Expires.Format(time.RFC3339), aa.username, reason)
+	fmt.Fprintln(aa.out, token)
+	return nil
+}
+
+// whitelistChallenge enables a challenge type for an account, for lifetime or
+// forever if it's zero. It replaces any existing entry for the challenge type
+// and account. The RAs pick the entry up the next time they update their
+// challenge whitelist from the SA.
+func (aa accountAdmin) whitelistChallenge(ctx context.Context, regID int64, challengeType string, lifetime time.Duration, reason string) error {
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("--reason must be provided")
+	}
+	if !core.ValidChallenge(challengeType) {
+		return fmt.Errorf("unknown challenge type %q", challengeType)
+	}
+	if lifetime < 0 {
+		return fmt.Errorf("--lifetime can't be negative")
+	}
+	reg, err := aa.sac.GetRegistration(ctx, regID)
+	if err != nil {
+		return err
+	}
+	if reg.Status != core.StatusValid {
+		return fmt.Errorf("account %d has status %q, expected %q", regID, reg.Status, core.StatusValid)
+	}
+	var expires int64
+	expiresStr := "never"
+	if lifetime > 0 {
+		t := aa.clk.Now().Add(lifetime).UTC()
+		expires = t.UnixNano()
+		expiresStr = t.Format(time.RFC3339)
+	}
+	entry, err := aa.sac.AddChallengeWhitelistEntry(ctx, &sapb.ChallengeWhitelistEntry{
+		ChallengeType:  &challengeType,
+		RegistrationID: &regID,
+		CreatedBy:      &aa.username,
+		Expires:        &expires,
+		Reason:         &reason,
+	})
+	if err != nil {
+		return err
+	}
+	aa.log.AuditInfof("Whitelisted %s challenges for account %d: id=[%d] expires=[%s] user=[%s] reason=[%s]",
+		challengeType, regID, *entry.Id, expiresStr, aa.username, reason)
+	return nil
+}
+
+// unwhitelistChallenge removes an account's challenge whitelist entry for a
+// challenge type. Entries in the PA's challenges whitelist file aren't
+// affected.
+func (aa accountAdmin) unwhitelistChallenge(ctx context.Context, regID int64, challengeType string, reason string) error {
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("--reason must be provided")
+	}
+	err := aa.sac.RemoveChallengeWhitelistEntry(ctx, &sapb.RemoveChallengeWhitelistEntryRequest{
+		ChallengeType:  &challengeType,
+		RegistrationID: &regID,
+		RemovedBy:      &aa.username,
+		Reason:         &reason,
+	})
+	if err != nil {
+		return err
+	}
+	aa.log.AuditInfof("Removed %s challenge whitelist entry of account %d: user=[%s] reason=[%s]",
+		challengeType, regID, aa.username, reason)
+	return nil
+}
+
+func splitContacts(contacts string) []string {
+	parsed := []string{}
+	for _, c := range strings.Split(contacts, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			parsed = append(parsed, c)
+		}
+	}
+	return parsed
+}
+
+func main() {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, usageString)
+		os.Exit(1)
+	}
+	if len(os.Args) <= 2 {
+		usage()
+	}
+
+	command := os.Args[1]
+	flagSet := flag.NewFlagSet(command, flag.ContinueOnError)
+	configFile := flagSet.String("config", "", "File path to the configuration file for this service")
+	keyFile := flagSet.String("key", "", "File containing the account's public key")
+	keyDigest := flagSet.String("key-digest", "", "Base64 SHA-256 digest of the account key")
+	contact := flagSet.String("contact", "", "Contact URL to look up")
+	regID := flagSet.Int64("regID", 0, "ID of the account")
+	limit := flagSet.Int("limit", 10, "Number of recent orders and certificates to show")
+	contacts := flagSet.String("contacts", "", "Comma separated list of contact URLs")
+	reason := flagSet.String("reason", "", "Why the account is being changed")
+	identifier := flagSet.String("identifier", "", "Name the account is exempted for")
+	entry := flagSet.String("entry", "", "Hostname policy entry the identifier is exempt from")
+	lifetime := flagSet.Duration("lifetime", 24*time.Hour, "How long the exemption token or challenge whitelist entry lasts")
+	challenge := flagSet.String("challenge", "", "Challenge type to whitelist")
+	err := flagSet.Parse(os.Args[2:])
+	cmd.FailOnError(err, "Error parsing flagset")
+
+	if *configFile == "" {
+		usage()
+	}
+
+	var c config
+	err = cmd.ReadConfigFile(*configFile, &c)
+	cmd.FailOnError(err, "Reading JSON config file into config structure")
+	err = features.Set(c.AccountAdmin.Features)
+	cmd.FailOnError(err, "Failed to set feature flags")
+
+	ctx := context.Background()
+	switch command {
+	case "lookup":
+		aa := setupContext(c)
+		switch {
+		case *keyFile != "":
+			jwk, err := loadKey(*keyFile)
+			cmd.FailOnError(err, "Failed to load key")
+			err = aa.lookupByKey(ctx, jwk)
+			cmd.FailOnError(err, "Failed to look up account")
+		case *keyDigest != "":
+			err = aa.lookupByKeyDigest(ctx, *keyDigest)
+			cmd.FailOnError(err, "Failed to look up account")
+		case *contact != "":
+			err = aa.lookupByContact(ctx, *contact)
+			cmd.FailOnError(err, "Failed to look up accounts")
+		default:
+			usage()
+		}
+
+	case "show":
+		if *regID == 0 {
+			usage()
+		}
+		aa := setupContext(c)
+		err = aa.show(ctx, *regID, *limit)
+		cmd.FailOnError(err, "Failed to show account")
+
+	case "update-contacts":
+		contactsSet := false
+		flagSet.Visit(func(f *flag.Flag) {
+			if f.Name == "contacts" {
+				contactsSet = true
+			}
+		})
+		if *regID == 0 || !contactsSet {
+			usage()
+		}
+		aa := setupContext(c)
+		err = aa.updateContacts(ctx, *regID, splitContacts(*contacts), *reason)
+		cmd.FailOnError(err, "Failed to update contacts")
+
+	case "deactivate":
+		if *regID == 0 {
+			usage()
+		}
+		aa := setupContext(c)
+		err = aa.deactivate(ctx, *regID, *reason)
+		cmd.FailOnError(err, "Failed to deactivate account")
+
+	case "pause":
+		if *regID == 0 {
+			usage()
+		}
+		aa := setupContext(c)
+		err = aa.setStatus(ctx, *regID, core.StatusValid, core.StatusPaused, *reason)
+		cmd.FailOnError(err, "Failed to pause account")
+
+	case "unpause":
+		if *regID == 0 {
+			usage()
+		}
+		aa := setupContext(c)
+		err = aa.setStatus(ctx, *regID, core.StatusPaused, core.StatusValid, *reason)
+		cmd.FailOnError(err, "Failed to unpause account")
+
+	case "exempt":
+		if *regID == 0 || *identifier == "" || *entry == "" {
+			usage()
+		}
+		aa := setupContext(c)
+		err = aa.exempt(ctx, *regID, *identifier, *entry, *lifetime, *reason)
+		cmd.FailOnError(err, "Failed to issue policy exemption")
+
+	case "whitelist-challenge":
+		if *regID == 0 || *challenge == "" {
+			usage()
+		}
+		// Unlike exemption tokens, whitelist entries last forever unless a
+		// lifetime is given.
+		var wlLifetime time.Duration
+		flagSet.Visit(func(f *flag.Flag) {
+			if f.Name == "lifetime" {
+				wlLifetime = *lifetime
+			}
+		})
+		aa := setupContext(c)
+		err = aa.whitelistChallenge(ctx, *regID, *challenge, wlLifetime, *reason)
+		cmd.FailOnError(err, "Failed to whitelist challenge")
+
+	case "unwhitelist-challenge":
+		if *regID == 0 || *challenge == "" {
+			usage()
+		}
+		aa := setupContext(c)
+		err = aa.unwhitelistChallenge(ctx, *regID, *challenge, *reason)
+		cmd.FailOnError(err, "Failed to remove challenge whitelist entry")
+
+	default:
+		usage()
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"golang.org/x/net/context"
+	jose "gopkg.in/square/go-jose.v2"
+
+	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/policy"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+	"github.com/letsencrypt/boulder/test"
+)
+
+type fakeSA struct {
+	regs      map[int64]core.Registration
+	whitelist map[int64]*sapb.ChallengeWhitelistEntry
+}
+
+func (sa *fakeSA) GetRegistration(_ context.Context, regID int64) (core.Registration, error) {
+	reg, present := sa.regs[regID]
+	if !present {
+		return core.Registration{}, berrors.NotFoundError("registration with ID '%d' not found", regID)
+	}
+	return reg, nil
+}
+
+func (sa *fakeSA) GetRegistrationByKey(_ context.Context, jwk *jose.JSONWebKey) (core.Registration, error) {
+	return core.Registration{}, berrors.NotFoundError("no registrations with public key")
+}
+
+func (sa *fakeSA) UpdateRegistration(_ context.Context, reg core.Registration) error {
+	sa.regs[reg.ID] = reg
+	return nil
+}
+
+func (sa *fakeSA) AddChallengeWhitelistEntry(_ context.Context, req *sapb.ChallengeWhitelistEntry) (*sapb.ChallengeWhitelistEntry, error) {
+	id := int64(len(sa.whitelist) + 1)
+	req.Id = &id
+	sa.whitelist[*req.RegistrationID] = req
+	return req, nil
+}
+
+func (sa *fakeSA) RemoveChallengeWhitelistEntry(_ context.Context, req *sapb.RemoveChallengeWhitelistEntryRequest) error {
+	e, present := sa.whitelist[*req.RegistrationID]
+	if !present || *e.ChallengeType != *req.ChallengeType {
+		return berrors.NotFoundError("no unexpired %s whitelist entry for registration ID %d", *req.ChallengeType, *req.RegistrationID)
+	}
+	delete(sa.whitelist, *req.RegistrationID)
+	return nil
+}
+
+// fakeRA passes updates straight through to the fakeSA, rejecting contacts
+// that aren't mailto URLs like the real RA would.
+type fakeRA struct {
+	sa *fakeSA
+}
+
+func (ra *fakeRA) UpdateRegistration(ctx context.Context, base, update core.Registration) (core.Registration, error) {
+	for _, c := range *update.Contact {
+		if !strings.HasPrefix(c, "mailto:") {
+			return core.Registration{}, berrors.MalformedError("contact method %q is not supported", c)
+		}
+	}
+	base.Contact = update.Contact
+	return base, ra.sa.UpdateRegistration(ctx, base)
+}
+
+func (ra *fakeRA) DeactivateRegistration(ctx context.Context, reg core.Registration) error {
+	if reg.Status != core.StatusValid {
+		return berrors.MalformedError("only valid registrations can be deactivated")
+	}
+	reg.Status = core.StatusDeactivated
+	return ra.sa.UpdateRegistration(ctx, reg)
+}
+
+type fakeDB struct {
+	queries []string
+	args    [][]interface{}
+	ids     []int64
+}
+
+func (db *fakeDB) Select(i interface{}, query string, args ...interface{}) ([]interface{}, error) {
+	db.queries = append(db.queries, query)
+	db.args = append(db.args, args)
+	if ids, ok := i.(*[]int64); ok {
+		*ids = db.ids
+	}
+	return nil, nil
+}
+
+func setup() (accountAdmin, *fakeSA, *fakeDB, *bytes.Buffer, *blog.Mock) {
+	contacts := []string{"mailto:old@example.com"}
+	sa := &fakeSA{
+		regs: map[int64]core.Registration{
+			1: {ID: 1, Status: core.StatusValid, Contact: &contacts},
+		},
+		whitelist: map[int64]*sapb.ChallengeWhitelistEntry{},
+	}
+	db := &fakeDB{}
+	out := new(bytes.Buffer)
+	log := blog.NewMock()
+	fc := clock.NewFake()
+	fc.Set(time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC))
+	return accountAdmin{
+		rac:          &fakeRA{sa},
+		sac:          sa,
+		dbMap:        db,
+		log:          log,
+		clk:          fc,
+		out:          out,
+		username:     "operator",
+		exemptionKey: []byte("policy exemption test key"),
+	}, sa, db, out, log
+}
+
+func TestLookupByContact(t *testing.T) {
+	aa, _, db, out, log := setup()
+	db.ids = []int64{1}
+	err := aa.lookupByContact(context.Background(), "mailto:100%_off@example.com")
+	test.AssertNotError(t, err, "lookupByContact failed")
+	test.AssertEquals(t, db.args[0][0], `%"mailto:100\%\_off@example.com"%`)
+	test.AssertContains(t, out.String(), "mailto:old@example.com")
+	test.AssertEquals(t, len(log.GetAllMatching(`Looked up accounts by contact: contact=\[mailto:100%_off@example.com\] user=\[operator\]`)), 1)
+
+	db.ids = nil
+	err = aa.lookupByContact(context.Background(), "mailto:nobody@example.com")
+	test.AssertError(t, err, "lookupByContact didn't fail for unknown contact")
+}
+
+func TestUpdateContacts(t *testing.T) {
+	aa, sa, _, _, log := setup()
+	ctx := context.Background()
+
+	err := aa.updateContacts(ctx, 1, []string{"mailto:new@example.com"}, "")
+	test.AssertError(t, err, "updateContacts succeeded without a reason")
+
+	err = aa.updateContacts(ctx, 1, []string{"tel:+15555555555"}, "ticket 1234")
+	test.AssertError(t, err, "updateContacts accepted a contact the RA rejects")
+
+	err = aa.updateContacts(ctx, 1, []string{"mailto:new@example.com"}, "ticket 1234")
+	test.AssertNotError(t, err, "updateContacts failed")
+	test.AssertDeepEquals(t, *sa.regs[1].Contact, []string{"mailto:new@example.com"})
+	test.AssertEquals(t, len(log.GetAllMatching(`Updated contacts of account 1: old=\[mailto:old@example.com\] new=\[mailto:new@example.com\] user=\[operator\] reason=\[ticket 1234\]`)), 1)
+}
+
+func TestPauseAndDeactivate(t *testing.T) {
+	aa, sa, _, _, log := setup()
+	ctx := context.Background()
+
+	err := aa.setStatus(ctx, 1, core.StatusValid, core.StatusPaused, "abuse report")
+	test.AssertNotError(t, err, "pause failed")
+	test.AssertEquals(t, sa.regs[1].Status, core.StatusPaused)
+	test.AssertEquals(t, len(log.GetAllMatching(`Changed status of account 1 from valid to paused: user=\[operator\] reason=\[abuse report\]`)), 1)
+
+	// Pausing twice is an error, as is deactivating a paused account
+	err = aa.setStatus(ctx, 1, core.StatusValid, core.StatusPaused, "abuse report")
+	test.AssertError(t, err, "paused account was paused again")
+	err = aa.deactivate(ctx, 1, "abuse report")
+	test.AssertError(t, err, "paused account was deactivated")
+
+	err = aa.setStatus(ctx, 1, core.StatusPaused, core.StatusValid, "resolved")
+	test.AssertNotError(t, err, "unpause failed")
+	err = aa.deactivate(ctx, 1, "")
+	test.AssertError(t, err, "deactivate succeeded without a reason")
+	err = aa.deactivate(ctx, 1, "requested by subscriber")
+	test.AssertNotError(t, err, "deactivate failed")
+	test.AssertEquals(t, sa.regs[1].Status, core.StatusDeactivated)
+
+	err = aa.setStatus(ctx, 2, core.StatusValid, core.StatusPaused, "abuse report")
+	test.AssertError(t, err, "unknown account was paused")
+}
+
+func TestExempt(t *testing.T) {
+	aa, sa, _, out, log := setup()
+	ctx := context.Background()
+
+	err := aa.exempt(ctx, 1, "WWW.example.net", "example.net", 2*time.Hour, "ticket 1234")
+	test.AssertNotError(t, err, "exempt failed")
+	e, err := policy.ParseExemption(aa.exemptionKey, strings.TrimSpace(out.String()))
+	test.AssertNotError(t, err, "printed token didn't parse")
+	test.AssertEquals(t, e.RegistrationID, int64(1))
+	test.AssertEquals(t, e.Identifier, "www.example.net")
+	test.AssertEquals(t, e.Entry, "example.net")
+	test.AssertEquals(t, e.Expires, aa.clk.Now().Add(2*time.Hour))
+	test.AssertEquals(t, len(log.GetAllMatching(`Issued policy exemption `+e.ID+` to account 1: identifier=\[www.example.net\] entry=\[example.net\] expires=\[2019-04-01T02:00:00Z\] user=\[operator\] reason=\[ticket 1234\]`)), 1)
+
+	err = aa.exempt(ctx, 1, "www.example.net", "example.net", time.Hour, "")
+	test.AssertError(t, err, "exempt succeeded without a reason")
+	err = aa.exempt(ctx, 1, "www.example.net", "example.net", 8*24*time.Hour, "ticket 1234")
+	test.AssertError(t, err, "exempt succeeded with too long a lifetime")
+	err = aa.exempt(ctx, 2, "www.example.net", "example.net", time.Hour, "ticket 1234")
+	test.AssertError(t, err, "unknown account was exempted")
+	reg := sa.regs[1]
+	reg.Status = core.StatusDeactivated
+	sa.regs[1] = reg
+	err = aa.exempt(ctx, 1, "www.example.net", "example.net", time.Hour, "ticket 1234")
+	test.AssertError(t, err, "deactivated account was exempted")
+}
+
+func TestWhitelistChallenge(t *testing.T) {
+	aa, sa, _, _, log := setup()
+	ctx := context.Background()
+
+	err := aa.whitelistChallenge(ctx, 1, core.ChallengeTypeTLSSNI01, 0, "ticket 1234")
+	test.AssertNotError(t, err, "whitelistChallenge failed")
+	e := sa.whitelist[1]
+	test.AssertEquals(t, *e.ChallengeType, core.ChallengeTypeTLSSNI01)
+	test.AssertEquals(t, *e.Expires, int64(0))
+	test.AssertEquals(t, *e.CreatedBy, "operator")
+	test.AssertEquals(t, len(log.GetAllMatching(`Whitelisted tls-sni-01 challenges for account 1: id=\[1\] expires=\[never\] user=\[operator\] reason=\[ticket 1234\]`)), 1)
+
+	err = aa.whitelistChallenge(ctx, 1, core.ChallengeTypeTLSSNI01, 48*time.Hour, "ticket 1235")
+	test.AssertNotError(t, err, "whitelistChallenge failed")
+	test.AssertEquals(t, *sa.whitelist[1].Expires, aa.clk.Now().Add(48*time.Hour).UnixNano())
+	test.AssertEquals(t, len(log.GetAllMatching(`expires=\[2019-04-03T00:00:00Z\] user=\[operator\] reason=\[ticket 1235\]`)), 1)
+
+	err = aa.whitelistChallenge(ctx, 1, core.ChallengeTypeTLSSNI01, 0, "")
+	test.AssertError(t, err, "whitelistChallenge succeeded without a reason")
+	err = aa.whitelistChallenge(ctx, 1, "bogus-01", 0, "ticket 1234")
+	test.AssertError(t, err, "unknown challenge type was whitelisted")
+	err = aa.whitelistChallenge(ctx, 2, core.ChallengeTypeTLSSNI01, 0, "ticket 1234")
+	test.AssertError(t, err, "unknown account was whitelisted")
+
+	err = aa.unwhitelistChallenge(ctx, 1, core.ChallengeTypeTLSSNI01, "")
+	test.AssertError(t, err, "unwhitelistChallenge succeeded without a reason")
+	err = aa.unwhitelistChallenge(ctx, 1, core.ChallengeTypeTLSSNI01, "ticket 1236")
+	test.AssertNotError(t, err, "unwhitelistChallenge failed")
+	test.AssertEquals(t, len(sa.whitelist), 0)
+	test.AssertEquals(t, len(log.GetAllMatching(`Removed tls-sni-01 challenge whitelist entry of account 1: user=\[operator\] reason=\[ticket 1236\]`)), 1)
+	err = aa.unwhitelistChallenge(ctx, 1, core.ChallengeTypeTLSSNI01, "ticket 1236")
+	test.AssertError(t, err, "missing whitelist entry was removed")
+}
+
+func TestShow(t *testing.T) {
+	aa, _, db, out, _ := setup()
+	err := aa.show(context.Background(), 1, 5)
+	test.AssertNotError(t, err, "show failed")
+	test.AssertEquals(t, len(db.queries), 2)
+	test.AssertContains(t, db.queries[0], "FROM orders")
+	test.AssertContains(t, db.queries[1], "FROM certificates")
+	test.AssertDeepEquals(t, db.args[0], []interface{}{int64(1), 5})
+	test.AssertContains(t, out.String(), "Recent orders:")
+	test.AssertContains(t, out.String(), "Recent certificates:")
+}
+
+func TestLoadKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "account-admin")
+	test.AssertNotError(t, err, "creating temp dir")
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	jwkFile := filepath.Join(dir, "key.jwk")
+	jwkJSON := `{"kty":"EC","crv":"P-256","x":"weNJy2HscCSM6AEDTDg04biOvhFhyyWvOHQfeF_PxMQ","y":"e8lnCO-AlStT-NJVX-crhB7QRYhiix03illJOVAOyck"}`
+	test.AssertNotError(t, ioutil.WriteFile(jwkFile, []byte(jwkJSON), 0600), "writing JWK")
+	jwk, err := loadKey(jwkFile)
+	test.AssertNotError(t, err, "loadKey failed for JWK")
+	_, err = core.KeyDigest(jwk)
+	test.AssertNotError(t, err, "KeyDigest failed")
+
+	badFile := filepath.Join(dir, "bad")
+	test.AssertNotError(t, ioutil.WriteFile(badFile, []byte("not a key"), 0600), "writing bad key")
+	_, err = loadKey(badFile)
+	test.AssertError(t, err, "loadKey accepted garbage")
+}
+
+func TestSplitContacts(t *testing.T) {
+	test.AssertDeepEquals(t, splitContacts(""), []string{})
+	test.AssertDeepEquals(t,
+		splitContacts("mailto:a@example.com, mailto:b@example.com,"),
+		[]string{"mailto:a@example.com", "mailto:b@example.com"})
+}
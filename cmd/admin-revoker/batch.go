@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bufio"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"golang.org/x/net/context"
+	"gopkg.in/go-gorp/gorp.v2"
+	jose "gopkg.in/square/go-jose.v2"
+
+	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/revocation"
+)
+
+// batchRevoker revokes a list of certificates by serial with bounded
+// parallelism. Every serial that is successfully revoked is appended to a
+// checkpoint file so that an interrupted batch can be resumed without
+// re-revoking certificates that were already handled.
+type batchRevoker struct {
+	revoke      func(ctx context.Context, serial string) error
+	parallelism int
+	checkpoint  string
+	clk         clock.Clock
+	log         blog.Logger
+}
+
+// batchFailure records a serial that could not be revoked and why.
+type batchFailure struct {
+	Serial string `json:"serial"`
+	Error  string `json:"error"`
+}
+
+// batchReport describes the outcome of a batch revocation. It is signed and
+// written out at the end of the batch so there is a durable, verifiable record
+// of what was revoked.
+type batchReport struct {
+	Operator   string    `json:"operator"`
+	Reason     int       `json:"reason"`
+	ReasonText string    `json:"reasonText"`
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished"`
+	// Revoked lists the serials revoked by this invocation.
+	Revoked []string `json:"revoked"`
+	// PreviouslyRevoked lists the serials that the checkpoint file showed were
+	// revoked by an earlier, interrupted invocation, or that were found to be
+	// already revoked, e.g. because it was interrupted before checkpointing
+	// them.
+	PreviouslyRevoked []string       `json:"previouslyRevoked"`
+	Failed            []batchFailure `json:"failed"`
+}
+
+// readSerials reads one serial per line, ignoring blank lines and lines
+// starting with "#". Duplicate serials are only returned once.
+func readSerials(r io.Reader) ([]string, error) {
+	var serials []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !core.ValidSerial(line) {
+			return nil, fmt.Errorf("invalid serial %q", line)
+		}
+		if seen[line] {
+			continue
+		}
+		seen[line] = true
+		serials = append(serials, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return serials, nil
+}
+
+// cohort selects certificates by when they were issued and which account
+// they were issued to. Zero fields don't restrict the selection.
+type cohort struct {
+	issuedAfter    time.Time
+	issuedBefore   time.Time
+	registrationID int64
+}
+
+// parseCohort parses a comma separated list of cohort criteria, e.g.
+// "issuedAfter=2019-02-01T00:00:00Z,issuedBefore=2019-02-02T00:00:00Z,account=123".
+// Times are RFC 3339. At least one criterion must be given, so that a typo
+// can't select every certificate.
+func parseCohort(spec string) (cohort, error) {
+	var c cohort
+	for _, criterion := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(criterion), "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return cohort{}, fmt.Errorf("cohort criterion %q isn't of the form name=value", criterion)
+		}
+		var err error
+		switch parts[0] {
+		case "issuedAfter":
+			c.issuedAfter, err = time.Parse(time.RFC3339, parts[1])
+		case "issuedBefore":
+			c.issuedBefore, err = time.Parse(time.RFC3339, parts[1])
+		case "account":
+			c.registrationID, err = strconv.ParseInt(parts[1], 10, 64)
+			if err == nil && c.registrationID <= 0 {
+				err = fmt.Errorf("invalid account ID %d", c.registrationID)
+			}
+		default:
+			err = fmt.Errorf("unknown cohort criterion %q", parts[0])
+		}
+		if err != nil {
+			return cohort{}, err
+		}
+	}
+	if !c.issuedAfter.IsZero() && !c.issuedBefore.IsZero() && !c.issuedBefore.After(c.issuedAfter) {
+		return cohort{}, fmt.Errorf("cohort issuedBefore must be after issuedAfter")
+	}
+	return c, nil
+}
+
+// query returns the SQL query selecting the serials of the cohort's
+// certificates, and its arguments.
+func (c cohort) query() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if !c.issuedAfter.IsZero() {
+		conditions = append(conditions, "issued >= ?")
+		args = append(args, c.issuedAfter)
+	}
+	if !c.issuedBefore.IsZero() {
+		conditions = append(conditions, "issued < ?")
+		args = append(args, c.issuedBefore)
+	}
+	if c.registrationID != 0 {
+		conditions = append(conditions, "registrationID = ?")
+		args = append(args, c.registrationID)
+	}
+	return "SELECT serial FROM certificates WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// selectCohort returns the serials of all certificates in the cohort.
+func selectCohort(dbMap gorp.SqlExecutor, c cohort) ([]string, error) {
+	var serials []string
+	query, args := c.query()
+	_, err := dbMap.Select(&serials, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return serials, nil
+}
+
+// loadCheckpoint returns the set of serials recorded in the checkpoint file.
+// A missing checkpoint file is treated as empty.
+func loadCheckpoint(filename string) (map[string]bool, error) {
+	done := make(map[string]bool)
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	serials, err := readSerials(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading checkpoint %q: %s", filename, err)
+	}
+	for _, s := range serials {
+		done[s] = true
+	}
+	return done, nil
+}
+
+// run revokes each of the serials that isn't already in the checkpoint file
+// and fills in the revocation results of the report. A serial that revoke
+// reports is already revoked is checkpointed like one it revoked.
+func (br *batchRevoker) run(ctx context.Context, serials []string, report *batchReport) error {
+	done, err := loadCheckpoint(br.checkpoint)
+	if err != nil {
+		return err
+	}
+	checkpoint, err := os.OpenFile(br.checkpoint, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = checkpoint.Close() }()
+
+	report.Started = br.clk.Now()
+	work := make(chan string)
+	var mu sync.Mutex
+	var checkpointErr error
+	var wg sync.WaitGroup
+	for i := 0; i < br.parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for serial := range work {
+				err := br.revoke(ctx, serial)
+				mu.Lock()
+				if err != nil && !berrors.Is(err, berrors.AlreadyRevoked) {
+					br.log.Errf("Failed to revoke %s: %s", serial, err)
+					report.Failed = append(report.Failed, batchFailure{Serial: serial, Error: err.Error()})
+				} else {
+					if err != nil {
+						br.log.Infof("Certificate %s was already revoked", serial)
+						report.PreviouslyRevoked = append(report.PreviouslyRevoked, serial)
+					} else {
+						report.Revoked = append(report.Revoked, serial)
+					}
+					_, err = fmt.Fprintln(checkpoint, serial)
+					if err == nil {
+						err = checkpoint.Sync()
+					}
+					if err != nil && checkpointErr == nil {
+						checkpointErr = fmt.Errorf("writing checkpoint: %s", err)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, serial := range serials {
+		if done[serial] {
+			report.PreviouslyRevoked = append(report.PreviouslyRevoked, serial)
+			continue
+		}
+		work <- serial
+	}
+	close(work)
+	wg.Wait()
+	report.Finished = br.clk.Now()
+
+	br.log.AuditInfof("Batch revocation finished: revoked=[%d] previouslyRevoked=[%d] failed=[%d] reason=[%s] user=[%s]",
+		len(report.Revoked), len(report.PreviouslyRevoked), len(report.Failed), report.ReasonText, report.Operator)
+	return checkpointErr
+}
+
+// loadReportSigner reads a PEM encoded RSA or ECDSA private key to sign batch
+// reports with.
+func loadReportSigner(filename string) (jose.Signer, error) {
+	keyPEM, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %q", filename)
+	}
+	var key crypto.Signer
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		key = k
+	} else if k, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		key = k
+	} else if k, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := k.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported key type in %q", filename)
+		}
+		key = signer
+	} else {
+		return nil, fmt.Errorf("unable to parse private key in %q", filename)
+	}
+
+	var alg jose.SignatureAlgorithm
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		alg = jose.RS256
+	case *ecdsa.PrivateKey:
+		switch k.Curve.Params().BitSize {
+		case 256:
+			alg = jose.ES256
+		case 384:
+			alg = jose.ES384
+		default:
+			return nil, fmt.Errorf("unsupported ECDSA curve in %q", filename)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type in %q", filename)
+	}
+	return jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: key}, &jose.SignerOptions{EmbedJWK: true})
+}
+
+// signReport serializes the report as a JWS signed by signer. The embedded JWK
+// lets the report be verified by anyone who knows which key to expect.
+func signReport(report batchReport, signer jose.Signer) ([]byte, error) {
+	payload, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(jws.FullSerialize()), nil
+}
+
+// batchRevokeReason validates a reason code the same way revokeBySerial does,
+// but returns an error instead of panicking so that a bad argument is caught
+// before any certificates are revoked.
+func batchRevokeReason(code int) (revocation.Reason, error) {
+	reason := revocation.Reason(code)
+	if _, ok := revocation.ReasonToString[reason]; !ok {
+		return 0, fmt.Errorf("invalid reason code: %d", code)
+	}
+	return reason, nil
+}
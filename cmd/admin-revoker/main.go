@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/user"
 	"sort"
@@ -32,15 +33,26 @@ admin-revoker serial-revoke --config <path> <serial> <reason-code>
 admin-revoker reg-revoke --config <path> <registration-id> <reason-code>
 admin-revoker list-reasons --config <path>
 admin-revoker auth-revoke --config <path> <domain>
+admin-revoker batch-revoke --config <path> (--serials <path> | --cohort <criteria>) --checkpoint <path> --report <path> --report-key <path> [--parallelism <n>] <reason-code>
 
 command descriptions:
   serial-revoke   Revoke a single certificate by the hex serial number
   reg-revoke      Revoke all certificates associated with a registration ID
   list-reasons    List all revocation reason codes
   auth-revoke     Revoke all pending/valid authorizations for a domain
+  batch-revoke    Revoke many certificates, resumably, and write a signed report
 
 args:
-  config    File path to the configuration file for this service
+  config       File path to the configuration file for this service
+  serials      File containing one hex serial number per line
+  cohort       Comma separated criteria selecting certificates: any of
+               issuedAfter=<RFC 3339 time>, issuedBefore=<RFC 3339 time>
+               and account=<registration ID>
+  checkpoint   File recording revoked serials. Re-running with the same
+               checkpoint skips serials that were already revoked
+  report       File to write the signed (JWS) report of the batch to
+  report-key   PEM private key (RSA or ECDSA) used to sign the report
+  parallelism  Number of certificates to revoke concurrently (default 5)
 `
 
 type config struct {
@@ -84,7 +96,7 @@ func setupContext(c config) (core.RegistrationAuthority, blog.Logger, *gorp.DbMa
 	return rac, logger, dbMap, sac
 }
 
-func revokeBySerial(ctx context.Context, serial string, reasonCode revocation.Reason, rac core.RegistrationAuthority, logger blog.Logger, tx *gorp.Transaction) (err error) {
+func revokeBySerial(ctx context.Context, serial string, reasonCode revocation.Reason, rac core.RegistrationAuthority, logger blog.Logger, tx gorp.SqlExecutor) (err error) {
 	if reasonCode < 0 || reasonCode == 7 || reasonCode > 10 {
 		panic(fmt.Sprintf("Invalid reason code: %d", reasonCode))
 	}
@@ -100,6 +112,13 @@ func revokeBySerial(ctx context.Context, serial string, reasonCode revocation.Re
 	if err != nil {
 		return
 	}
+	status, err := sa.SelectCertificateStatus(tx, "WHERE serial = ?", serial)
+	if err != nil {
+		return err
+	}
+	if status.Status == core.OCSPStatusRevoked {
+		return berrors.AlreadyRevokedError("certificate with serial %q is already revoked", serial)
+	}
 
 	u, err := user.Current()
 	err = rac.AdministrativelyRevokeCertificate(ctx, *cert, reasonCode, u.Username)
@@ -120,6 +139,11 @@ func revokeByReg(ctx context.Context, regID int64, reasonCode revocation.Reason,
 
 	for _, cert := range certs {
 		err = revokeBySerial(ctx, cert.Serial, reasonCode, rac, logger, tx)
+		if berrors.Is(err, berrors.AlreadyRevoked) {
+			logger.Infof("Certificate %s was already revoked", cert.Serial)
+			err = nil
+			continue
+		}
 		if err != nil {
 			return
 		}
@@ -147,6 +171,12 @@ func main() {
 	command := os.Args[1]
 	flagSet := flag.NewFlagSet(command, flag.ContinueOnError)
 	configFile := flagSet.String("config", "", "File path to the configuration file for this service")
+	serialsFile := flagSet.String("serials", "", "File containing one hex serial number per line")
+	cohortSpec := flagSet.String("cohort", "", "Comma separated criteria selecting certificates: issuedAfter, issuedBefore and account")
+	checkpointFile := flagSet.String("checkpoint", "", "File recording revoked serials, for resuming")
+	reportFile := flagSet.String("report", "", "File to write the signed report to")
+	reportKeyFile := flagSet.String("report-key", "", "PEM private key used to sign the report")
+	parallelism := flagSet.Int("parallelism", 5, "Number of certificates to revoke concurrently")
 	err := flagSet.Parse(os.Args[2:])
 	cmd.FailOnError(err, "Error parsing flagset")
 
@@ -232,6 +262,76 @@ func main() {
 		logger.Infof("Revoked %d pending authorizations and %d final authorizations",
 			pendingAuthsRevoked, authsRevoked)
 
+	case command == "batch-revoke" && len(args) == 1:
+		// 1: reasonCode
+		code, err := strconv.Atoi(args[0])
+		cmd.FailOnError(err, "Reason code argument must be an integer")
+		reasonCode, err := batchRevokeReason(code)
+		cmd.FailOnError(err, "Invalid reason code")
+		if (*serialsFile == "") == (*cohortSpec == "") {
+			cmd.Fail("Exactly one of --serials and --cohort must be provided")
+		}
+		if *checkpointFile == "" || *reportFile == "" || *reportKeyFile == "" {
+			usage()
+		}
+		if *parallelism < 1 {
+			cmd.Fail("--parallelism must be at least 1")
+		}
+		var selected cohort
+		if *cohortSpec != "" {
+			selected, err = parseCohort(*cohortSpec)
+			cmd.FailOnError(err, "Invalid --cohort")
+		}
+		// Load the signing key before revoking anything, so that a bad key
+		// can't leave us without a report.
+		signer, err := loadReportSigner(*reportKeyFile)
+		cmd.FailOnError(err, "Couldn't load report signing key")
+
+		rac, logger, dbMap, _ := setupContext(c)
+		defer logger.AuditPanic()
+
+		var serials []string
+		if *serialsFile != "" {
+			f, err := os.Open(*serialsFile)
+			cmd.FailOnError(err, "Couldn't open serials file")
+			serials, err = readSerials(f)
+			cmd.FailOnError(err, "Couldn't read serials file")
+			_ = f.Close()
+		} else {
+			serials, err = selectCohort(dbMap, selected)
+			cmd.FailOnError(err, "Couldn't select certificate cohort")
+		}
+
+		u, err := user.Current()
+		cmd.FailOnError(err, "Couldn't determine the current user")
+		report := batchReport{
+			Operator:   u.Username,
+			Reason:     int(reasonCode),
+			ReasonText: revocation.ReasonToString[reasonCode],
+		}
+		br := &batchRevoker{
+			revoke: func(ctx context.Context, serial string) error {
+				return revokeBySerial(ctx, serial, reasonCode, rac, logger, dbMap)
+			},
+			parallelism: *parallelism,
+			checkpoint:  *checkpointFile,
+			clk:         cmd.Clock(),
+			log:         logger,
+		}
+		runErr := br.run(ctx, serials, &report)
+
+		// Always try to write the report, even if the checkpoint couldn't be
+		// written, so there is a record of what was revoked.
+		signed, err := signReport(report, signer)
+		cmd.FailOnError(err, "Couldn't sign report")
+		err = ioutil.WriteFile(*reportFile, signed, 0600)
+		cmd.FailOnError(err, "Couldn't write report")
+		cmd.FailOnError(runErr, "Batch revocation failed")
+		if len(report.Failed) > 0 {
+			cmd.Fail(fmt.Sprintf("Failed to revoke %d of %d certificates, re-run with the same checkpoint to retry",
+				len(report.Failed), len(serials)))
+		}
+
 	default:
 		usage()
 	}
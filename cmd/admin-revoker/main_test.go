@@ -1 +1,222 @@
 package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"golang.org/x/net/context"
+	jose "gopkg.in/square/go-jose.v2"
+
+	berrors "github.com/letsencrypt/boulder/errors"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/test"
+)
+
+const (
+	serialA = "0000000000000000000000000000000000a1"
+	serialB = "0000000000000000000000000000000000b2"
+	serialC = "0000000000000000000000000000000000c3"
+)
+
+func TestReadSerials(t *testing.T) {
+	input := "# incident 1234\n" + serialA + "\n\n  " + serialB + "  \n" + serialA + "\n"
+	serials, err := readSerials(strings.NewReader(input))
+	test.AssertNotError(t, err, "readSerials failed")
+	test.AssertDeepEquals(t, serials, []string{serialA, serialB})
+
+	_, err = readSerials(strings.NewReader("not-a-serial\n"))
+	test.AssertError(t, err, "readSerials accepted an invalid serial")
+}
+
+func TestParseCohort(t *testing.T) {
+	c, err := parseCohort("issuedAfter=2019-02-01T00:00:00Z, issuedBefore=2019-02-02T00:00:00Z,account=123")
+	test.AssertNotError(t, err, "parseCohort failed")
+	query, args := c.query()
+	test.AssertEquals(t, query, "SELECT serial FROM certificates WHERE issued >= ? AND issued < ? AND registrationID = ?")
+	test.AssertDeepEquals(t, args, []interface{}{
+		time.Date(2019, 2, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2019, 2, 2, 0, 0, 0, 0, time.UTC),
+		int64(123),
+	})
+
+	// Values are bound as parameters, never spliced into the query
+	c, err = parseCohort("account=1")
+	test.AssertNotError(t, err, "parseCohort failed")
+	query, args = c.query()
+	test.AssertEquals(t, query, "SELECT serial FROM certificates WHERE registrationID = ?")
+	test.AssertDeepEquals(t, args, []interface{}{int64(1)})
+
+	for _, invalid := range []string{
+		"",
+		"issued BETWEEN '2019-02-01' AND '2019-02-02'",
+		"account=1 OR 1=1",
+		"account=0",
+		"issuedAfter=2019-02-01",
+		"issuedAfter=2019-02-02T00:00:00Z,issuedBefore=2019-02-01T00:00:00Z",
+		"serial=00",
+	} {
+		_, err := parseCohort(invalid)
+		test.AssertError(t, err, fmt.Sprintf("parseCohort accepted %q", invalid))
+	}
+}
+
+func TestBatchRevokeReason(t *testing.T) {
+	_, err := batchRevokeReason(1)
+	test.AssertNotError(t, err, "keyCompromise rejected")
+	_, err = batchRevokeReason(7)
+	test.AssertError(t, err, "unused reason code 7 accepted")
+	_, err = batchRevokeReason(11)
+	test.AssertError(t, err, "reason code 11 accepted")
+}
+
+func TestBatchRevokeResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "admin-revoker")
+	test.AssertNotError(t, err, "creating temp dir")
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	var revoked []string
+	failing := map[string]bool{serialB: true}
+	br := &batchRevoker{
+		revoke: func(_ context.Context, serial string) error {
+			if failing[serial] {
+				return fmt.Errorf("RA unavailable")
+			}
+			revoked = append(revoked, serial)
+			return nil
+		},
+		// With one worker the revoke func is never called concurrently.
+		parallelism: 1,
+		checkpoint:  filepath.Join(dir, "checkpoint"),
+		clk:         clock.NewFake(),
+		log:         blog.NewMock(),
+	}
+	serials := []string{serialA, serialB, serialC}
+
+	var report batchReport
+	err = br.run(context.Background(), serials, &report)
+	test.AssertNotError(t, err, "first run failed")
+	test.AssertDeepEquals(t, report.Revoked, []string{serialA, serialC})
+	test.AssertEquals(t, len(report.Failed), 1)
+	test.AssertEquals(t, report.Failed[0].Serial, serialB)
+
+	// A second run should only retry the serial that failed.
+	delete(failing, serialB)
+	revoked = nil
+	report = batchReport{}
+	err = br.run(context.Background(), serials, &report)
+	test.AssertNotError(t, err, "second run failed")
+	test.AssertDeepEquals(t, revoked, []string{serialB})
+	test.AssertDeepEquals(t, report.Revoked, []string{serialB})
+	sort.Strings(report.PreviouslyRevoked)
+	test.AssertDeepEquals(t, report.PreviouslyRevoked, []string{serialA, serialC})
+	test.AssertEquals(t, len(report.Failed), 0)
+}
+
+func TestBatchRevokeResumeAlreadyRevoked(t *testing.T) {
+	dir, err := ioutil.TempDir("", "admin-revoker")
+	test.AssertNotError(t, err, "creating temp dir")
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	// serialB was revoked by an earlier run that was interrupted before it
+	// was checkpointed.
+	checkpoint := filepath.Join(dir, "checkpoint")
+	err = ioutil.WriteFile(checkpoint, []byte(serialA+"\n"), 0600)
+	test.AssertNotError(t, err, "writing checkpoint")
+	revoked := map[string]bool{serialA: true, serialB: true}
+	br := &batchRevoker{
+		revoke: func(_ context.Context, serial string) error {
+			if revoked[serial] {
+				return berrors.AlreadyRevokedError("certificate with serial %q is already revoked", serial)
+			}
+			revoked[serial] = true
+			return nil
+		},
+		parallelism: 1,
+		checkpoint:  checkpoint,
+		clk:         clock.NewFake(),
+		log:         blog.NewMock(),
+	}
+
+	var report batchReport
+	err = br.run(context.Background(), []string{serialA, serialB, serialC}, &report)
+	test.AssertNotError(t, err, "run failed")
+	test.AssertDeepEquals(t, report.Revoked, []string{serialC})
+	sort.Strings(report.PreviouslyRevoked)
+	test.AssertDeepEquals(t, report.PreviouslyRevoked, []string{serialA, serialB})
+	test.AssertEquals(t, len(report.Failed), 0)
+
+	done, err := loadCheckpoint(checkpoint)
+	test.AssertNotError(t, err, "loadCheckpoint failed")
+	test.AssertDeepEquals(t, done, map[string]bool{serialA: true, serialB: true, serialC: true})
+}
+
+func TestBatchRevokeParallel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "admin-revoker")
+	test.AssertNotError(t, err, "creating temp dir")
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	br := &batchRevoker{
+		revoke:      func(context.Context, string) error { return nil },
+		parallelism: 3,
+		checkpoint:  filepath.Join(dir, "checkpoint"),
+		clk:         clock.NewFake(),
+		log:         blog.NewMock(),
+	}
+	var serials []string
+	for i := 0; i < 50; i++ {
+		serials = append(serials, fmt.Sprintf("%036x", i))
+	}
+	var report batchReport
+	err = br.run(context.Background(), serials, &report)
+	test.AssertNotError(t, err, "run failed")
+	test.AssertEquals(t, len(report.Revoked), len(serials))
+
+	done, err := loadCheckpoint(br.checkpoint)
+	test.AssertNotError(t, err, "loadCheckpoint failed")
+	test.AssertEquals(t, len(done), len(serials))
+}
+
+func TestSignReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "admin-revoker")
+	test.AssertNotError(t, err, "creating temp dir")
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "generating key")
+	der, err := x509.MarshalECPrivateKey(key)
+	test.AssertNotError(t, err, "marshaling key")
+	keyFile := filepath.Join(dir, "key.pem")
+	err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600)
+	test.AssertNotError(t, err, "writing key")
+
+	signer, err := loadReportSigner(keyFile)
+	test.AssertNotError(t, err, "loadReportSigner failed")
+	signed, err := signReport(batchReport{Operator: "root", Revoked: []string{serialA}}, signer)
+	test.AssertNotError(t, err, "signReport failed")
+
+	jws, err := jose.ParseSigned(string(signed))
+	test.AssertNotError(t, err, "parsing signed report")
+	payload, err := jws.Verify(&key.PublicKey)
+	test.AssertNotError(t, err, "verifying signed report")
+	var report batchReport
+	err = json.Unmarshal(payload, &report)
+	test.AssertNotError(t, err, "unmarshaling report")
+	test.AssertEquals(t, report.Operator, "root")
+	test.AssertDeepEquals(t, report.Revoked, []string{serialA})
+
+	_, err = loadReportSigner(filepath.Join(dir, "missing.pem"))
+	test.AssertError(t, err, "loadReportSigner accepted a missing file")
+}
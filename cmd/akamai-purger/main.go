@@ -2,14 +2,14 @@ package main
 
 import (
 	"context"
-	"errors"
 	"flag"
+	"fmt"
 	"os"
-	"sync"
 	"time"
 
 	"github.com/letsencrypt/boulder/akamai"
 	akamaipb "github.com/letsencrypt/boulder/akamai/proto"
+	"github.com/letsencrypt/boulder/cdn"
 	"github.com/letsencrypt/boulder/cmd"
 	corepb "github.com/letsencrypt/boulder/core/proto"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
@@ -23,6 +23,26 @@ type config struct {
 		// PurgeInterval is how often we will send a purge request
 		PurgeInterval cmd.ConfigDuration
 
+		// Backend is the CDN to purge URLs from: "akamai", the default,
+		// "fastly" or "cloudflare".
+		Backend string
+
+		// Fastly configures the "fastly" backend. BaseURL defaults to the
+		// public Fastly API.
+		Fastly struct {
+			BaseURL string
+			APIKey  string
+		}
+
+		// Cloudflare configures the "cloudflare" backend. BaseURL defaults
+		// to the public Cloudflare API.
+		Cloudflare struct {
+			BaseURL  string
+			ZoneID   string
+			APIToken string
+		}
+
+		// BaseURL and the fields after it configure the "akamai" backend.
 		BaseURL           string
 		ClientToken       string
 		ClientSecret      string
@@ -30,33 +50,31 @@ type config struct {
 		V3Network         string
 		PurgeRetries      int
 		PurgeRetryBackoff cmd.ConfigDuration
+
+		// PurgeBatchesPerInterval is the most purge requests that will be
+		// sent each PurgeInterval, to stay within Akamai's rate limits. Zero
+		// means no limit.
+		PurgeBatchesPerInterval int
+		// QueueBackoff is the initial delay before retrying after a purge
+		// request fails because of rate limiting or an Akamai error. It
+		// doubles with each consecutive failure.
+		QueueBackoff cmd.ConfigDuration
+		// BacklogFile, if set, is where URLs waiting to be purged are saved
+		// so that they survive a restart.
+		BacklogFile string
 	}
 	Syslog cmd.SyslogConfig
 }
 
 type akamaiPurger struct {
-	mu      sync.Mutex
-	toPurge []string
-
-	client *akamai.CachePurgeClient
-	log    blog.Logger
+	queue           *cdn.PurgeQueue
+	batchesPerPurge int
+	log             blog.Logger
 }
 
 func (ap *akamaiPurger) purge() {
-	ap.mu.Lock()
-	urls := ap.toPurge[:]
-	ap.toPurge = []string{}
-	ap.mu.Unlock()
-	if len(urls) == 0 {
-		return
-	}
-
-	if err := ap.client.Purge(urls); err != nil {
-		// Add the URLs back to the queue?
-		ap.mu.Lock()
-		ap.toPurge = append(urls, ap.toPurge...)
-		ap.mu.Unlock()
-		ap.log.Errf("Failed to purge %d URLs: %s", len(urls), err)
+	if err := ap.queue.Process(ap.batchesPerPurge); err != nil {
+		ap.log.Errf("Failed to save purge backlog: %s", err)
 	}
 }
 
@@ -65,12 +83,9 @@ func (ap *akamaiPurger) purge() {
 var maxQueueSize = 1000000
 
 func (ap *akamaiPurger) Purge(ctx context.Context, req *akamaipb.PurgeRequest) (*corepb.Empty, error) {
-	ap.mu.Lock()
-	defer ap.mu.Unlock()
-	if len(ap.toPurge) >= maxQueueSize {
-		return nil, errors.New("Akamai purge queue too large")
+	if err := ap.queue.Add(req.Urls); err != nil {
+		return nil, err
 	}
-	ap.toPurge = append(ap.toPurge, req.Urls...)
 	return &corepb.Empty{}, nil
 }
 
@@ -108,22 +123,57 @@ func main() {
 		cmd.Fail("PurgeInterval must be > 0")
 	}
 
-	ccu, err := akamai.NewCachePurgeClient(
-		c.AkamaiPurger.BaseURL,
-		c.AkamaiPurger.ClientToken,
-		c.AkamaiPurger.ClientSecret,
-		c.AkamaiPurger.AccessToken,
-		c.AkamaiPurger.V3Network,
-		c.AkamaiPurger.PurgeRetries,
-		c.AkamaiPurger.PurgeRetryBackoff.Duration,
+	var purger cdn.Purger
+	switch c.AkamaiPurger.Backend {
+	case "", "akamai":
+		purger, err = akamai.NewCachePurgeClient(
+			c.AkamaiPurger.BaseURL,
+			c.AkamaiPurger.ClientToken,
+			c.AkamaiPurger.ClientSecret,
+			c.AkamaiPurger.AccessToken,
+			c.AkamaiPurger.V3Network,
+			c.AkamaiPurger.PurgeRetries,
+			c.AkamaiPurger.PurgeRetryBackoff.Duration,
+			logger,
+			scope,
+		)
+		cmd.FailOnError(err, "Failed to setup Akamai CCU client")
+	case "fastly":
+		purger, err = cdn.NewFastlyPurger(
+			c.AkamaiPurger.Fastly.BaseURL,
+			c.AkamaiPurger.Fastly.APIKey,
+			logger,
+			scope,
+		)
+		cmd.FailOnError(err, "Failed to setup Fastly purge client")
+	case "cloudflare":
+		purger, err = cdn.NewCloudflarePurger(
+			c.AkamaiPurger.Cloudflare.BaseURL,
+			c.AkamaiPurger.Cloudflare.ZoneID,
+			c.AkamaiPurger.Cloudflare.APIToken,
+			logger,
+			scope,
+		)
+		cmd.FailOnError(err, "Failed to setup Cloudflare purge client")
+	default:
+		cmd.Fail(fmt.Sprintf("Unknown CDN backend %q", c.AkamaiPurger.Backend))
+	}
+
+	queue, err := cdn.NewPurgeQueue(
+		purger,
+		c.AkamaiPurger.BacklogFile,
+		maxQueueSize,
+		c.AkamaiPurger.QueueBackoff.Duration,
+		clk,
 		logger,
 		scope,
 	)
-	cmd.FailOnError(err, "Failed to setup Akamai CCU client")
+	cmd.FailOnError(err, "Failed to load purge backlog")
 
 	ap := akamaiPurger{
-		client: ccu,
-		log:    logger,
+		queue:           queue,
+		batchesPerPurge: c.AkamaiPurger.PurgeBatchesPerInterval,
+		log:             logger,
 	}
 
 	stop, stopped := make(chan bool, 1), make(chan bool, 1)
@@ -140,7 +190,8 @@ func main() {
 		}
 		// As we may have missed a tick by calling ticker.Stop() and
 		// writing to the stop channel call ap.purge one last time just
-		// in case there is anything that still needs to be purged.
+		// in case there is anything that still needs to be purged. Anything
+		// left over is saved to the backlog file for the next run.
 		ap.purge()
 		stopped <- true
 	}()
@@ -150,19 +201,35 @@ func main() {
 	cmd.FailOnError(err, "Unable to setup Akamai purger gRPC server")
 	akamaipb.RegisterAkamaiPurgerServer(grpcSrv, &ap)
 
-	go cmd.CatchSignals(logger, grpcSrv.GracefulStop)
+	go cmd.CatchSignals(logger, func() {
+		// We wait 15 seconds (or ShutdownStopTimeout, if configured) in total
+		// for the in-flight RPCs to finish and any remaining URLs to be
+		// emptied from the current queue, if we pass that deadline we exit
+		// early.
+		timeout := 15 * time.Second
+		if c.AkamaiPurger.ShutdownStopTimeout.Duration > 0 {
+			timeout = c.AkamaiPurger.ShutdownStopTimeout.Duration
+		}
+		deadline := time.Now().Add(timeout)
+
+		// Stop accepting new URLs before purging the ones already queued.
+		cmd.GracefulStop(logger, timeout, grpcSrv)
+
+		// Stop the ticker and signal that we want to shutdown by writing to
+		// the stop channel.
+		ticker.Stop()
+		stop <- true
+		select {
+		case <-time.After(time.Until(deadline)):
+			cmd.Fail("Timed out waiting for purger to finish work")
+		case <-stopped:
+		}
+	})
 
 	err = cmd.FilterShutdownErrors(grpcSrv.Serve(l))
 	cmd.FailOnError(err, "Akamai purger gRPC service failed")
 
-	// Stop the ticker and signal that we want to shutdown by writing to the
-	// stop channel. We wait 15 seconds for any remaining URLs to be emptied
-	// from the current queue, if we pass that deadline we exit early.
-	ticker.Stop()
-	stop <- true
-	select {
-	case <-time.After(time.Second * 15):
-		cmd.Fail("Timed out waiting for purger to finish work")
-	case <-stopped:
-	}
+	// Serve returns once the server has been stopped. Wait for CatchSignals
+	// to finish purging and exit.
+	select {}
 }
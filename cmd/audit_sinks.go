@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	blog "github.com/letsencrypt/boulder/log"
+)
+
+// auditSinkTimeout is how long an audit sink has to deliver a message over
+// HTTPS, and how long the queued messages have to be delivered on exit.
+const auditSinkTimeout = 10 * time.Second
+
+// AuditSinkConfig configures a destination audit messages are delivered to in
+// addition to syslog.
+type AuditSinkConfig struct {
+	// Name identifies the sink in metrics and errors. Defaults to Type.
+	Name string
+	// Type is "file" to append messages to File, "https" to POST them to
+	// URL, or "kafka-rest" to produce them to Topic through the Kafka REST
+	// Proxy at URL.
+	Type string
+
+	File string
+	// MaxBytes, if non-zero, rotates File before it grows larger than this,
+	// keeping MaxBackups old files.
+	MaxBytes   int64
+	MaxBackups int
+
+	URL   string
+	Topic string
+	// CACertFile, if set, is a PEM file of the CA certificates URL's
+	// certificate is verified against, instead of the system roots.
+	CACertFile string
+}
+
+// Load returns the blog.AuditSink the AuditSinkConfig describes.
+func (ac AuditSinkConfig) Load() (blog.AuditSink, error) {
+	switch ac.Type {
+	case "file":
+		if ac.File == "" {
+			return nil, fmt.Errorf("file audit sink needs a File")
+		}
+		return blog.NewFileAuditSink(ac.File, ac.MaxBytes, ac.MaxBackups)
+	case "https", "kafka-rest":
+		u, err := url.Parse(ac.URL)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme != "https" {
+			return nil, fmt.Errorf("%s audit sink needs an https URL, got %q", ac.Type, ac.URL)
+		}
+		transport := &http.Transport{}
+		if ac.CACertFile != "" {
+			pemBytes, err := ioutil.ReadFile(ac.CACertFile)
+			if err != nil {
+				return nil, err
+			}
+			roots := x509.NewCertPool()
+			if !roots.AppendCertsFromPEM(pemBytes) {
+				return nil, fmt.Errorf("no CA certificates found in %q", ac.CACertFile)
+			}
+			transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+		}
+		client := &http.Client{Transport: transport, Timeout: auditSinkTimeout}
+		if ac.Type == "https" {
+			return blog.NewHTTPAuditSink(ac.URL, client), nil
+		}
+		if ac.Topic == "" {
+			return nil, fmt.Errorf("kafka-rest audit sink needs a Topic")
+		}
+		return blog.NewKafkaRESTAuditSink(ac.URL, ac.Topic, client), nil
+	default:
+		return nil, fmt.Errorf("unknown audit sink type %q", ac.Type)
+	}
+}
+
+// auditSinks are the audit sinks of the logger created by NewLogger, if
+// any. CatchSignals delivers their queued messages before exiting.
+var auditSinks *blog.AuditSinks
+
+// withAuditSinks returns logger sending audit messages to the sinks in
+// logConf as well.
+func withAuditSinks(logger blog.Logger, logConf SyslogConfig) (blog.Logger, *blog.AuditSinks, error) {
+	sinks := blog.NewAuditSinks(logConf.AuditQueueSize)
+	names := make(map[string]bool)
+	for _, ac := range logConf.AuditSinks {
+		name := ac.Name
+		if name == "" {
+			name = ac.Type
+		}
+		if names[name] {
+			return nil, nil, fmt.Errorf("audit sink name %q is used more than once", name)
+		}
+		names[name] = true
+		sink, err := ac.Load()
+		if err != nil {
+			return nil, nil, err
+		}
+		sinks.Add(name, sink)
+	}
+	logger, err := blog.WithAuditSinks(logger, sinks)
+	if err != nil {
+		return nil, nil, err
+	}
+	return logger, sinks, nil
+}
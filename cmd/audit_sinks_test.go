@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestAuditSinkConfigLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit-sinks")
+	test.AssertNotError(t, err, "creating temp dir")
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name   string
+		config AuditSinkConfig
+		valid  bool
+	}{
+		{"file", AuditSinkConfig{Type: "file", File: filepath.Join(dir, "audit.log")}, true},
+		{"file without path", AuditSinkConfig{Type: "file"}, false},
+		{"https", AuditSinkConfig{Type: "https", URL: "https://collector.example.com/audit"}, true},
+		{"plain http", AuditSinkConfig{Type: "https", URL: "http://collector.example.com/audit"}, false},
+		{"missing CA file", AuditSinkConfig{Type: "https", URL: "https://collector.example.com", CACertFile: filepath.Join(dir, "nope.pem")}, false},
+		{"kafka", AuditSinkConfig{Type: "kafka-rest", URL: "https://kafka-rest.example.com", Topic: "audit"}, true},
+		{"kafka without topic", AuditSinkConfig{Type: "kafka-rest", URL: "https://kafka-rest.example.com"}, false},
+		{"unknown type", AuditSinkConfig{Type: "carrier-pigeon"}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.config.Load()
+			if tc.valid {
+				test.AssertNotError(t, err, "valid audit sink config wasn't loaded")
+			} else {
+				test.AssertError(t, err, "invalid audit sink config was loaded")
+			}
+		})
+	}
+}
+
+func TestWithAuditSinksDuplicateNames(t *testing.T) {
+	logger := blog.NewJSON(nil, 0, 0)
+	_, _, err := withAuditSinks(logger, SyslogConfig{AuditSinks: []AuditSinkConfig{
+		{Type: "https", URL: "https://one.example.com"},
+		{Type: "https", URL: "https://two.example.com"},
+	}})
+	test.AssertError(t, err, "audit sinks with the same name were accepted")
+}
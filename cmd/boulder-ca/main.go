@@ -19,9 +19,12 @@ import (
 	caPB "github.com/letsencrypt/boulder/ca/proto"
 	"github.com/letsencrypt/boulder/cmd"
 	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/faults"
 	"github.com/letsencrypt/boulder/features"
 	"github.com/letsencrypt/boulder/goodkey"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
+	"github.com/letsencrypt/boulder/health"
+	"github.com/letsencrypt/boulder/killswitch"
 	"github.com/letsencrypt/boulder/policy"
 	sapb "github.com/letsencrypt/boulder/sa/proto"
 )
@@ -39,10 +42,17 @@ func loadIssuers(c config) ([]ca.Issuer, error) {
 	for _, issuerConfig := range c.CA.Issuers {
 		priv, cert, err := loadIssuer(issuerConfig)
 		cmd.FailOnError(err, "Couldn't load private key")
-		issuers = append(issuers, ca.Issuer{
-			Signer: priv,
+		issuer := ca.Issuer{
+			Signer: faults.Signer(priv),
 			Cert:   cert,
-		})
+		}
+		if issuerConfig.OCSP != nil {
+			ocspPriv, ocspCert, err := loadOCSPSigner(*issuerConfig.OCSP, cert)
+			cmd.FailOnError(err, "Couldn't load OCSP private key")
+			issuer.OCSPSigner = faults.Signer(ocspPriv)
+			issuer.OCSPCert = ocspCert
+		}
+		issuers = append(issuers, issuer)
 	}
 	return issuers, nil
 }
@@ -53,7 +63,7 @@ func loadIssuer(issuerConfig ca_config.IssuerConfig) (crypto.Signer, *x509.Certi
 		return nil, nil, err
 	}
 
-	signer, err := loadSigner(issuerConfig)
+	signer, err := loadSigner(issuerConfig.KeyConfig)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -64,11 +74,35 @@ func loadIssuer(issuerConfig ca_config.IssuerConfig) (crypto.Signer, *x509.Certi
 	return signer, cert, err
 }
 
-func loadSigner(issuerConfig ca_config.IssuerConfig) (crypto.Signer, error) {
-	if issuerConfig.File != "" {
-		keyBytes, err := ioutil.ReadFile(issuerConfig.File)
+// loadOCSPSigner loads the key that signs the OCSP responses of issuerCert,
+// and its delegated OCSP responder cert if it has one.
+func loadOCSPSigner(ocspConfig ca_config.OCSPSignerConfig, issuerCert *x509.Certificate) (crypto.Signer, *x509.Certificate, error) {
+	signer, err := loadSigner(ocspConfig.KeyConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ocspConfig.CertFile == "" {
+		if !core.KeyDigestEquals(signer.Public(), issuerCert.PublicKey) {
+			return nil, nil, fmt.Errorf("OCSP key did not match issuer cert %s", issuerCert.Subject.CommonName)
+		}
+		return signer, nil, nil
+	}
+
+	cert, err := core.LoadCert(ocspConfig.CertFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !core.KeyDigestEquals(signer.Public(), cert.PublicKey) {
+		return nil, nil, fmt.Errorf("OCSP key did not match OCSP cert %s", ocspConfig.CertFile)
+	}
+	return signer, cert, nil
+}
+
+func loadSigner(keyConfig ca_config.KeyConfig) (crypto.Signer, error) {
+	if keyConfig.File != "" {
+		keyBytes, err := ioutil.ReadFile(keyConfig.File)
 		if err != nil {
-			return nil, fmt.Errorf("Could not read key file %s", issuerConfig.File)
+			return nil, fmt.Errorf("Could not read key file %s", keyConfig.File)
 		}
 
 		signer, err := helpers.ParsePrivateKeyPEM(keyBytes)
@@ -79,8 +113,8 @@ func loadSigner(issuerConfig ca_config.IssuerConfig) (crypto.Signer, error) {
 	}
 
 	var pkcs11Config *pkcs11key.Config
-	if issuerConfig.ConfigFile != "" {
-		contents, err := ioutil.ReadFile(issuerConfig.ConfigFile)
+	if keyConfig.ConfigFile != "" {
+		contents, err := ioutil.ReadFile(keyConfig.ConfigFile)
 		if err != nil {
 			return nil, err
 		}
@@ -90,7 +124,7 @@ func loadSigner(issuerConfig ca_config.IssuerConfig) (crypto.Signer, error) {
 			return nil, err
 		}
 	} else {
-		pkcs11Config = issuerConfig.PKCS11
+		pkcs11Config = keyConfig.PKCS11
 	}
 	if pkcs11Config.Module == "" ||
 		pkcs11Config.TokenLabel == "" ||
@@ -98,7 +132,7 @@ func loadSigner(issuerConfig ca_config.IssuerConfig) (crypto.Signer, error) {
 		pkcs11Config.PrivateKeyLabel == "" {
 		return nil, fmt.Errorf("Missing a field in pkcs11Config %#v", pkcs11Config)
 	}
-	numSessions := issuerConfig.NumSessions
+	numSessions := keyConfig.NumSessions
 	if numSessions <= 0 {
 		numSessions = 1
 	}
@@ -139,6 +173,10 @@ func main() {
 	}
 
 	scope, logger := cmd.StatsAndLogging(c.Syslog, c.CA.DebugAddr)
+	cmd.SetupTracing(c.CA.Tracing, scope, logger)
+	cmd.SetupFaults(c.CA.Faults, logger)
+	cmd.FailOnError(cmd.ReloadFeatures(c.CA.FeaturesFile, c.CA.Features), "Failed to load feature flags file")
+	cmd.FailOnError(cmd.LoadFeatureOperators(c.CA.FeatureOperatorsFile), "Failed to load feature operators file")
 	defer logger.AuditPanic()
 	logger.Info(cmd.VersionString())
 
@@ -146,18 +184,29 @@ func main() {
 
 	pa, err := policy.New(c.PA.Challenges)
 	cmd.FailOnError(err, "Couldn't create PA")
+	pa.SetAllowReservedNames(c.PA.AllowReservedNames)
 
-	if c.CA.HostnamePolicyFile == "" {
-		cmd.FailOnError(fmt.Errorf("HostnamePolicyFile was empty."), "")
+	if c.CA.HostnamePolicyRemote != nil {
+		source, err := c.CA.HostnamePolicyRemote.Load()
+		cmd.FailOnError(err, "Invalid hostname policy remote source")
+		err = pa.SetHostnamePolicyRemote(source)
+		cmd.FailOnError(err, "Couldn't fetch hostname policy")
+	} else {
+		if c.CA.HostnamePolicyFile == "" {
+			cmd.FailOnError(fmt.Errorf("HostnamePolicyFile was empty."), "")
+		}
+		err = pa.SetHostnamePolicyFile(c.CA.HostnamePolicyFile)
+		cmd.FailOnError(err, "Couldn't load hostname policy file")
 	}
-	err = pa.SetHostnamePolicyFile(c.CA.HostnamePolicyFile)
-	cmd.FailOnError(err, "Couldn't load hostname policy file")
 
 	issuers, err := loadIssuers(c)
 	cmd.FailOnError(err, "Couldn't load issuers")
 
 	kp, err := goodkey.NewKeyPolicy(c.CA.WeakKeyFile)
 	cmd.FailOnError(err, "Unable to create key policy")
+	kp.FermatRounds = c.CA.FermatRounds
+	err = c.CA.KeyPolicy.Apply(&kp)
+	cmd.FailOnError(err, "Invalid key policy")
 
 	tlsConfig, err := c.CA.TLS.Load()
 	cmd.FailOnError(err, "TLS config")
@@ -188,10 +237,26 @@ func main() {
 		orphanQueue)
 	cmd.FailOnError(err, "Failed to create CA impl")
 
+	if c.CA.IssuanceKillSwitchFile != "" {
+		ks := killswitch.New(logger)
+		err = ks.WatchFile(c.CA.IssuanceKillSwitchFile)
+		cmd.FailOnError(err, "Couldn't load issuance kill switch file")
+		cai.SetKillSwitch(ks)
+	}
+
+	exemptionKey, err := c.CA.PolicyExemptionKey.Pass()
+	cmd.FailOnError(err, "Couldn't load policy exemption key")
+	if exemptionKey != "" {
+		cai.SetPolicyExemptionKey([]byte(exemptionKey))
+	}
+
 	if orphanQueue != nil {
 		go cai.OrphanIntegrationLoop()
 	}
 
+	health.Default.AddCheck("hostnamePolicy", pa.CheckHostnamePolicy)
+	health.Default.AddCheck("hsm", cai.CheckHSM)
+
 	serverMetrics := bgrpc.NewServerMetrics(scope)
 	caSrv, caListener, err := bgrpc.NewServer(c.CA.GRPCCA, tlsConfig, serverMetrics, clk)
 	cmd.FailOnError(err, "Unable to setup CA gRPC server")
@@ -210,9 +275,10 @@ func main() {
 			"OCSPGenerator gRPC service failed")
 	}()
 
+	health.Default.SetServing(true)
+
 	go cmd.CatchSignals(logger, func() {
-		caSrv.GracefulStop()
-		ocspSrv.GracefulStop()
+		cmd.GracefulStop(logger, c.CA.ShutdownStopTimeout.Duration, caSrv, ocspSrv)
 	})
 
 	select {}
@@ -4,12 +4,13 @@ import (
 	"testing"
 
 	"github.com/letsencrypt/boulder/ca/config"
+	"github.com/letsencrypt/boulder/core"
 )
 
 func TestLoadIssuerSuccess(t *testing.T) {
 	signer, cert, err := loadIssuer(ca_config.IssuerConfig{
-		File:     "../../test/test-ca.key",
-		CertFile: "../../test/test-ca2.pem",
+		KeyConfig: ca_config.KeyConfig{File: "../../test/test-ca.key"},
+		CertFile:  "../../test/test-ca2.pem",
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -24,8 +25,8 @@ func TestLoadIssuerSuccess(t *testing.T) {
 
 func TestLoadIssuerBadKey(t *testing.T) {
 	_, _, err := loadIssuer(ca_config.IssuerConfig{
-		File:     "/dev/null",
-		CertFile: "../../test/test-ca2.pem",
+		KeyConfig: ca_config.KeyConfig{File: "/dev/null"},
+		CertFile:  "../../test/test-ca2.pem",
 	})
 	if err == nil {
 		t.Fatal("loadIssuer succeeded when loading key from /dev/null")
@@ -34,10 +35,44 @@ func TestLoadIssuerBadKey(t *testing.T) {
 
 func TestLoadIssuerBadCert(t *testing.T) {
 	_, _, err := loadIssuer(ca_config.IssuerConfig{
-		File:     "../../test/test-ca.key",
-		CertFile: "/dev/null",
+		KeyConfig: ca_config.KeyConfig{File: "../../test/test-ca.key"},
+		CertFile:  "/dev/null",
 	})
 	if err == nil {
 		t.Fatal("loadIssuer succeeded when loading key from /dev/null")
 	}
 }
+
+func TestLoadOCSPSigner(t *testing.T) {
+	issuerCert, err := core.LoadCert("../../test/test-ca2.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, cert, err := loadOCSPSigner(ca_config.OCSPSignerConfig{
+		KeyConfig: ca_config.KeyConfig{File: "../../test/test-ca.key"},
+	}, issuerCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer == nil {
+		t.Fatal("loadOCSPSigner returned nil signer")
+	}
+	if cert != nil {
+		t.Fatal("loadOCSPSigner returned a cert when none was configured")
+	}
+
+	_, _, err = loadOCSPSigner(ca_config.OCSPSignerConfig{
+		KeyConfig: ca_config.KeyConfig{File: "../../test/test-example.key"},
+	}, issuerCert)
+	if err == nil {
+		t.Fatal("loadOCSPSigner succeeded with a key that isn't the issuer key")
+	}
+
+	_, _, err = loadOCSPSigner(ca_config.OCSPSignerConfig{
+		KeyConfig: ca_config.KeyConfig{File: "../../test/test-ca.key"},
+		CertFile:  "../../test/test-example.pem",
+	}, issuerCert)
+	if err == nil {
+		t.Fatal("loadOCSPSigner succeeded with a key that doesn't match the OCSP cert")
+	}
+}
@@ -0,0 +1,453 @@
+// boulder-config-lint checks Boulder service configs for mistakes that would
+// otherwise only be found when a service starts: unknown feature flags, files
+// that are missing or don't parse (TLS credentials, issuer certificates,
+// hostname and rate limit policies, ...), and gRPC clients that don't line up
+// with any server. The configs of every service in a deployment should be
+// linted together, so that gRPC clients can be checked against the servers
+// they connect to:
+//
+//	boulder-config-lint test/config/*.json
+//
+// Relative paths in the configs are resolved against -dir, which should be
+// the services' working directory.
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/features"
+	"github.com/letsencrypt/boulder/goodkey"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/policy"
+	"github.com/letsencrypt/boulder/ratelimit"
+)
+
+// problem is a single mistake found in a config.
+type problem struct {
+	file string
+	// field is the path of the offending field, e.g. "ra.tls.certFile"
+	field string
+	msg   string
+}
+
+func (p problem) String() string {
+	return fmt.Sprintf("%s: %s: %s", p.file, p.field, p.msg)
+}
+
+// configFile is a config to lint and the name it's reported under.
+type configFile struct {
+	name     string
+	contents []byte
+}
+
+// grpcServer and grpcClient are the gRPC endpoints found in the configs,
+// cross-checked once every config has been walked. certNames are the DNS
+// names of the certificate the service presents, if its TLS config loaded.
+type grpcServer struct {
+	file, field string
+	port        string
+	clientNames []string
+	certNames   []string
+}
+
+type grpcClient struct {
+	file, field  string
+	addresses    []string
+	hostOverride string
+	certNames    []string
+}
+
+type linter struct {
+	problems []problem
+	servers  []grpcServer
+	clients  []grpcClient
+}
+
+// fileCheck checks the file a field refers to
+type fileCheck func(path string) error
+
+// fileChecks maps the lower cased names of fields holding the path of an
+// input file to the check for that file. JSON field names are matched case
+// insensitively, as encoding/json does. Fields naming files a service writes
+// are not included.
+var fileChecks = map[string]fileCheck{
+	"hostnamepolicyfile": func(path string) error {
+		pa, err := policy.New(nil)
+		if err != nil {
+			return err
+		}
+		return pa.SetHostnamePolicyFile(path)
+	},
+	"challengeswhitelistfile": func(path string) error {
+		pa, err := policy.New(nil)
+		if err != nil {
+			return err
+		}
+		return pa.SetChallengesWhitelistFile(path)
+	},
+	"challengesfile": func(path string) error {
+		pa, err := policy.New(nil)
+		if err != nil {
+			return err
+		}
+		return pa.SetChallengesFile(path)
+	},
+	"ratelimitpoliciesfilename": func(path string) error {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ratelimit.New().LoadPolicies(contents)
+	},
+	"weakkeyfile": func(path string) error {
+		_, err := goodkey.LoadWeakRSASuffixes(path)
+		return err
+	},
+	"configfile":                 checkJSON,
+	"issuercert":                 checkCertificates,
+	"issuercertpath":             checkCertificates,
+	"certfile":                   checkCertificates,
+	"intermediatebundlefilename": checkCertificates,
+	"smtptrustedrootfile":        checkCertificates,
+	"keyfile":                    checkExists,
+	"signingkeyfile":             checkExists,
+	"dbconnectfile":              checkExists,
+	"passwordfile":               checkExists,
+	"secretfile":                 checkExists,
+	"issuancekillswitchfile":     checkExists,
+	"featuresfile":               checkExists,
+	"ctpolicyfile":               checkExists,
+	"blockedkeysfile":            checkExists,
+	"suppressionfile":            checkExists,
+}
+
+func checkExists(path string) error {
+	_, err := os.Stat(path)
+	return err
+}
+
+func checkJSON(path string) error {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var v interface{}
+	return json.Unmarshal(contents, &v)
+}
+
+// checkCertificates checks that path is a PEM file holding at least one
+// certificate, and that every certificate in it parses.
+func checkCertificates(path string) error {
+	rest, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	found := false
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return err
+		}
+		found = true
+	}
+	if !found {
+		return errors.New("no PEM certificates found")
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m in order, so problems are reported in the
+// same order every time.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (l *linter) report(file, field, format string, args ...interface{}) {
+	l.problems = append(l.problems, problem{file: file, field: field, msg: fmt.Sprintf(format, args...)})
+}
+
+// lintFile checks one config. Every top level section is linted
+// independently, since that's how services read them.
+func (l *linter) lintFile(f configFile) {
+	var sections map[string]interface{}
+	if err := json.Unmarshal(f.contents, &sections); err != nil {
+		l.report(f.name, "", "not a JSON object: %s", err)
+		return
+	}
+	for _, name := range sortedKeys(sections) {
+		section, ok := sections[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		certNames := l.lintTLS(f.name, name, section)
+		l.lintObject(f.name, name, section, certNames)
+	}
+}
+
+// lintTLS checks the "tls" field of a section, if it has one, and returns the
+// DNS names of its certificate.
+func (l *linter) lintTLS(file, field string, section map[string]interface{}) []string {
+	for _, k := range sortedKeys(section) {
+		if strings.ToLower(k) != "tls" {
+			continue
+		}
+		field := field + "." + k
+		var tc cmd.TLSConfig
+		if err := remarshal(section[k], &tc); err != nil {
+			l.report(file, field, "%s", err)
+			return nil
+		}
+		// Only the files are of interest, don't start a reloader
+		tc.ReloadInterval = cmd.ConfigDuration{}
+		config, err := tc.Load()
+		if err != nil {
+			l.report(file, field, "%s", err)
+			return nil
+		}
+		cert, err := x509.ParseCertificate(config.Certificates[0].Certificate[0])
+		if err != nil {
+			l.report(file, field, "parsing certificate: %s", err)
+			return nil
+		}
+		return cert.DNSNames
+	}
+	return nil
+}
+
+// lintObject walks a JSON object, checking the fields it knows about.
+// certNames are the DNS names of the certificate of the section the object
+// is in.
+func (l *linter) lintObject(file, field string, obj map[string]interface{}, certNames []string) {
+	for _, k := range sortedKeys(obj) {
+		v := obj[k]
+		path := field + "." + k
+		lower := strings.ToLower(k)
+		switch {
+		case lower == "tls":
+			// Checked by lintTLS
+			continue
+		case lower == "features":
+			var fs map[string]bool
+			if err := remarshal(v, &fs); err != nil {
+				l.report(file, path, "%s", err)
+				continue
+			}
+			if err := features.Set(fs); err != nil {
+				l.report(file, path, "%s", err)
+			}
+			features.Reset()
+			continue
+		case lower == "certificatechains":
+			var chains map[string][]string
+			if err := remarshal(v, &chains); err != nil {
+				l.report(file, path, "%s", err)
+				continue
+			}
+			for url, files := range chains {
+				for _, f := range files {
+					if err := checkCertificates(f); err != nil {
+						l.report(file, path, "chain for %q: %s", url, err)
+					}
+				}
+			}
+			continue
+		case strings.HasPrefix(lower, "grpc"):
+			if server, ok := v.(map[string]interface{}); ok {
+				l.addServer(file, path, server, certNames)
+			}
+		}
+		switch v := v.(type) {
+		case string:
+			if check, ok := fileChecks[lower]; ok && v != "" {
+				if err := check(v); err != nil {
+					l.report(file, path, "%s", err)
+				}
+			}
+		case map[string]interface{}:
+			if _, ok := v["serverAddress"]; ok {
+				l.addClient(file, path, v, certNames)
+			} else if _, ok := v["serverAddresses"]; ok {
+				l.addClient(file, path, v, certNames)
+			}
+			l.lintObject(file, path, v, certNames)
+		case []interface{}:
+			for i, elem := range v {
+				if obj, ok := elem.(map[string]interface{}); ok {
+					l.lintObject(file, fmt.Sprintf("%s[%d]", path, i), obj, certNames)
+				}
+			}
+		}
+	}
+	if obj["serverCertificatePath"] != nil && obj["serverKeyPath"] != nil {
+		cert, _ := obj["serverCertificatePath"].(string)
+		key, _ := obj["serverKeyPath"].(string)
+		if _, err := tls.LoadX509KeyPair(cert, key); err != nil {
+			l.report(file, field+".serverCertificatePath", "%s", err)
+		}
+	}
+}
+
+func (l *linter) addServer(file, field string, obj map[string]interface{}, certNames []string) {
+	var sc cmd.GRPCServerConfig
+	if err := remarshal(obj, &sc); err != nil {
+		l.report(file, field, "%s", err)
+		return
+	}
+	_, port, err := net.SplitHostPort(sc.Address)
+	if err != nil {
+		l.report(file, field+".address", "%s", err)
+		return
+	}
+	l.servers = append(l.servers, grpcServer{
+		file:        file,
+		field:       field,
+		port:        port,
+		clientNames: sc.ClientNames,
+		certNames:   certNames,
+	})
+}
+
+func (l *linter) addClient(file, field string, obj map[string]interface{}, certNames []string) {
+	var cc cmd.GRPCClientConfig
+	if err := remarshal(obj, &cc); err != nil {
+		l.report(file, field, "%s", err)
+		return
+	}
+	addresses := cc.ServerAddresses
+	if cc.ServerAddress != "" {
+		addresses = append(addresses, cc.ServerAddress)
+	}
+	l.clients = append(l.clients, grpcClient{
+		file:         file,
+		field:        field,
+		addresses:    addresses,
+		hostOverride: cc.HostOverride,
+		certNames:    certNames,
+	})
+}
+
+// checkGRPC checks that every gRPC client connects to a port that one of the
+// linted servers listens on, that the server accepts the client's
+// certificate, and that the server's certificate is valid for the name the
+// client expects.
+func (l *linter) checkGRPC() {
+	for _, c := range l.clients {
+		for _, addr := range c.addresses {
+			host, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				l.report(c.file, c.field, "%s", err)
+				continue
+			}
+			if c.hostOverride != "" {
+				host = c.hostOverride
+			}
+			found := false
+			for _, s := range l.servers {
+				if s.port != port {
+					continue
+				}
+				found = true
+				if len(c.certNames) > 0 && len(s.clientNames) > 0 && !intersects(c.certNames, s.clientNames) {
+					l.report(c.file, c.field, "client certificate names %v aren't in the clientNames of %s in %s",
+						c.certNames, s.field, s.file)
+				}
+				if len(s.certNames) > 0 && !intersects([]string{host}, s.certNames) {
+					l.report(c.file, c.field, "the certificate of %s in %s isn't valid for %q (it's valid for %v)",
+						s.field, s.file, host, s.certNames)
+				}
+			}
+			if !found {
+				l.report(c.file, c.field, "no linted config has a gRPC server listening on port %s", port)
+			}
+		}
+	}
+}
+
+func intersects(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// remarshal converts a value decoded into interface{} into the typed config
+// struct the services use, so it's parsed exactly like they parse it.
+func remarshal(v interface{}, out interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+// lint checks the given configs, returning every problem found.
+func lint(files []configFile) []problem {
+	var l linter
+	for _, f := range files {
+		l.lintFile(f)
+	}
+	l.checkGRPC()
+	return l.problems
+}
+
+func main() {
+	dir := flag.String("dir", ".", "Directory relative paths in the configs are resolved against, i.e. the services' working directory")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-dir DIR] CONFIG...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// Only errors are of interest, e.g. not the reloader's "Loaded ..."
+	// messages
+	_ = blog.Set(blog.NewJSON(nil, 3, 0))
+
+	var files []configFile
+	for _, name := range flag.Args() {
+		contents, err := ioutil.ReadFile(name)
+		cmd.FailOnError(err, "Reading config file")
+		files = append(files, configFile{name: name, contents: contents})
+	}
+	cmd.FailOnError(os.Chdir(*dir), "Changing to -dir")
+
+	problems := lint(files)
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "%d problems found\n", len(problems))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+// readConfigs reads the configs matching pattern, relative to the root of
+// the repository, which must be the working directory.
+func readConfigs(t *testing.T, pattern string) []configFile {
+	names, err := filepath.Glob(pattern)
+	test.AssertNotError(t, err, "globbing configs")
+	var files []configFile
+	for _, name := range names {
+		contents, err := ioutil.ReadFile(name)
+		test.AssertNotError(t, err, "reading config")
+		files = append(files, configFile{name: name, contents: contents})
+	}
+	return files
+}
+
+// chdirRoot changes to the root of the repository, which the test configs'
+// paths are relative to, and returns a function undoing it.
+func chdirRoot(t *testing.T) func() {
+	wd, err := os.Getwd()
+	test.AssertNotError(t, err, "getting working directory")
+	test.AssertNotError(t, os.Chdir("../.."), "changing to repository root")
+	return func() { _ = os.Chdir(wd) }
+}
+
+func TestLintTestConfigs(t *testing.T) {
+	defer chdirRoot(t)()
+	for _, dir := range []string{"test/config", "test/config-next"} {
+		files := readConfigs(t, dir+"/*.json")
+		test.Assert(t, len(files) > 0, "no configs found in "+dir)
+		problems := lint(files)
+		for _, p := range problems {
+			t.Errorf("unexpected problem: %s", p)
+		}
+	}
+}
+
+func TestLintBadConfig(t *testing.T) {
+	defer chdirRoot(t)()
+	problems := lint(readConfigs(t, "cmd/boulder-config-lint/testdata/bad.json"))
+
+	expected := []struct {
+		field string
+		msg   string
+	}{
+		{"ra.features", "feature 'NoSuchFeature' doesn't exist"},
+		{"ra.hostnamePolicyFile", "no such file or directory"},
+		{"ra.rateLimitPoliciesFilename", "cannot unmarshal"},
+		{"sa.tls", "missing-key.pem"},
+		{"ra.saService", `isn't valid for "sa.boulder"`},
+		{"ra.saService", "aren't in the clientNames of sa.grpc"},
+		{"ra.vaService", "no linted config has a gRPC server listening on port 9092"},
+	}
+	test.AssertEquals(t, len(problems), len(expected))
+	for i, e := range expected {
+		test.AssertEquals(t, problems[i].field, e.field)
+		test.Assert(t, strings.Contains(problems[i].msg, e.msg),
+			"expected "+problems[i].String()+" to contain "+e.msg)
+	}
+}
+
+func TestLintNotJSON(t *testing.T) {
+	problems := lint([]configFile{{name: "bad.json", contents: []byte("[1, 2]")}})
+	test.AssertEquals(t, len(problems), 1)
+	test.Assert(t, strings.Contains(problems[0].msg, "not a JSON object"), "wrong problem: "+problems[0].String())
+}
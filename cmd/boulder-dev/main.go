@@ -0,0 +1,312 @@
+// boulder-dev runs the WFE2, RA, VA, CA and SA in a single process for local
+// development. Storage is in memory (see sa/memsa) and the CA issues from a
+// key and self-signed issuer certificate generated at startup, so no database
+// or HSM is needed, and nothing survives a restart. It must never be used in
+// production.
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/letsencrypt/boulder/bdns"
+	"github.com/letsencrypt/boulder/ca"
+	ca_config "github.com/letsencrypt/boulder/ca/config"
+	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/csr"
+	"github.com/letsencrypt/boulder/ctpolicy"
+	"github.com/letsencrypt/boulder/features"
+	"github.com/letsencrypt/boulder/goodkey"
+	"github.com/letsencrypt/boulder/policy"
+	"github.com/letsencrypt/boulder/ra"
+	"github.com/letsencrypt/boulder/sa/memsa"
+	"github.com/letsencrypt/boulder/va"
+	vaPB "github.com/letsencrypt/boulder/va/proto"
+	"github.com/letsencrypt/boulder/wfe2"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+type config struct {
+	Dev struct {
+		DebugAddr string
+
+		// ListenAddress is where the ACME v2 API is served
+		ListenAddress string
+
+		ShutdownStopTimeout cmd.ConfigDuration
+
+		// RateLimitPoliciesFilename is optional. If it's not set no rate limits
+		// are enforced.
+		RateLimitPoliciesFilename string
+
+		MaxContactsPerRegistration int
+
+		AuthorizationLifetimeDays        int
+		PendingAuthorizationLifetimeDays int
+		OrderLifetime                    cmd.ConfigDuration
+
+		DNSResolvers              []string
+		DNSTimeout                cmd.ConfigDuration
+		DNSTries                  int
+		DNSAllowLoopbackAddresses bool
+
+		PortConfig cmd.PortConfig
+
+		UserAgent          string
+		IssuerDomain       string
+		AccountURIPrefixes []string
+
+		AllowOrigins         []string
+		DirectoryCAAIdentity string
+
+		Features map[string]bool
+	}
+
+	// CA configures issuance. Its Issuers, Key and database settings are
+	// ignored: the issuer is always generated at startup.
+	CA ca_config.CAConfig
+
+	PA cmd.PAConfig
+
+	Syslog cmd.SyslogConfig
+}
+
+// caaChecker lets the RA call the VA's CAA check directly instead of over
+// gRPC.
+type caaChecker struct {
+	va *va.ValidationAuthorityImpl
+}
+
+func (c caaChecker) IsCAAValid(ctx context.Context, req *vaPB.IsCAAValidRequest, _ ...grpc.CallOption) (*vaPB.IsCAAValidResponse, error) {
+	return c.va.IsCAAValid(ctx, req)
+}
+
+// newIssuer generates an RSA key and a self-signed issuer certificate for it,
+// standing in for the HSM backed issuers of a real CA.
+func newIssuer(clk clock.Clock) (ca.Issuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return ca.Issuer{}, err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return ca.Issuer{}, err
+	}
+	skid := sha1.Sum(pubDER)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		return ca.Issuer{}, err
+	}
+	now := clk.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: fmt.Sprintf("boulder-dev issuer %x", skid[:3])},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(5, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		SubjectKeyId:          skid[:],
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return ca.Issuer{}, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return ca.Issuer{}, err
+	}
+	return ca.Issuer{Signer: crypto.Signer(key), Cert: cert}, nil
+}
+
+func main() {
+	listenAddr := flag.String("addr", "", "ACME API listen address override")
+	debugAddr := flag.String("debug-addr", "", "Debug server address override")
+	configFile := flag.String("config", "", "File path to the configuration file for this service")
+	flag.Parse()
+	if *configFile == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var c config
+	err := cmd.ReadConfigFile(*configFile, &c)
+	cmd.FailOnError(err, "Reading JSON config file into config structure")
+
+	err = features.Set(c.Dev.Features)
+	cmd.FailOnError(err, "Failed to set feature flags")
+
+	if *listenAddr != "" {
+		c.Dev.ListenAddress = *listenAddr
+	}
+	if *debugAddr != "" {
+		c.Dev.DebugAddr = *debugAddr
+	}
+
+	if c.CA.MaxNames == 0 {
+		cmd.Fail("Error in CA config: MaxNames must not be 0")
+	}
+
+	scope, logger := cmd.StatsAndLogging(c.Syslog, c.Dev.DebugAddr)
+	defer logger.AuditPanic()
+	logger.Info(cmd.VersionString())
+	logger.Warning("Running in development mode: all state is kept in memory and the CA key is not protected")
+
+	clk := cmd.Clock()
+
+	cmd.FailOnError(c.PA.CheckChallenges(), "Invalid PA configuration")
+	pa, err := policy.New(c.PA.Challenges)
+	cmd.FailOnError(err, "Couldn't create PA")
+	if c.CA.HostnamePolicyFile == "" {
+		cmd.Fail("HostnamePolicyFile must be provided.")
+	}
+	err = pa.SetHostnamePolicyFile(c.CA.HostnamePolicyFile)
+	cmd.FailOnError(err, "Couldn't load hostname policy file")
+	if c.PA.ChallengesWhitelistFile != "" {
+		err = pa.SetChallengesWhitelistFile(c.PA.ChallengesWhitelistFile)
+		cmd.FailOnError(err, "Couldn't load challenges whitelist file")
+	}
+
+	kp, err := goodkey.NewKeyPolicy(c.CA.WeakKeyFile)
+	cmd.FailOnError(err, "Unable to create key policy")
+
+	ssa := memsa.New(clk, logger)
+
+	issuer, err := newIssuer(clk)
+	cmd.FailOnError(err, "Couldn't generate issuer")
+	logger.Infof("Generated issuer %q", issuer.Cert.Subject.CommonName)
+
+	cai, err := ca.NewCertificateAuthorityImpl(
+		c.CA,
+		ssa,
+		pa,
+		clk,
+		scope.NewScope("CA"),
+		[]ca.Issuer{issuer},
+		kp,
+		logger,
+		nil)
+	cmd.FailOnError(err, "Failed to create CA impl")
+
+	err = c.Dev.PortConfig.Check()
+	cmd.FailOnError(err, "Invalid validation ports")
+	pc := c.Dev.PortConfig.WithDefaults()
+	dnsTries := c.Dev.DNSTries
+	if dnsTries < 1 {
+		dnsTries = 1
+	}
+	var resolver bdns.DNSClient
+	if c.Dev.DNSAllowLoopbackAddresses {
+		resolver = bdns.NewTestDNSClientImpl(c.Dev.DNSTimeout.Duration, c.Dev.DNSResolvers, scope, clk, dnsTries)
+	} else {
+		resolver = bdns.NewDNSClientImpl(c.Dev.DNSTimeout.Duration, c.Dev.DNSResolvers, scope, clk, dnsTries)
+	}
+	vai, err := va.NewValidationAuthorityImpl(
+		&pc,
+		nil,
+		resolver,
+		nil,
+		0,
+		c.Dev.UserAgent,
+		c.Dev.IssuerDomain,
+		scope.NewScope("VA"),
+		clk,
+		logger,
+		c.Dev.AccountURIPrefixes)
+	cmd.FailOnError(err, "Unable to create VA")
+
+	authorizationLifetime := 300 * 24 * time.Hour
+	if c.Dev.AuthorizationLifetimeDays != 0 {
+		authorizationLifetime = time.Duration(c.Dev.AuthorizationLifetimeDays) * 24 * time.Hour
+	}
+	pendingAuthorizationLifetime := 7 * 24 * time.Hour
+	if c.Dev.PendingAuthorizationLifetimeDays != 0 {
+		pendingAuthorizationLifetime = time.Duration(c.Dev.PendingAuthorizationLifetimeDays) * 24 * time.Hour
+	}
+	orderLifetime := 7 * 24 * time.Hour
+	if c.Dev.OrderLifetime.Duration != 0 {
+		orderLifetime = c.Dev.OrderLifetime.Duration
+	}
+
+	nameLimits := csr.NameLimits{Default: c.CA.MaxNames, RSA: c.CA.RSAMaxNames, ECDSA: c.CA.ECDSAMaxNames}
+	rai := ra.NewRegistrationAuthorityImpl(
+		clk,
+		logger,
+		scope.NewScope("RA"),
+		c.Dev.MaxContactsPerRegistration,
+		kp,
+		nameLimits,
+		c.CA.DoNotForceCN,
+		true,
+		authorizationLifetime,
+		pendingAuthorizationLifetime,
+		nil,
+		caaChecker{vai},
+		orderLifetime,
+		// No CT logs are configured, so certificates are issued without SCTs
+		ctpolicy.New(nil, nil, nil, logger, scope),
+		nil,
+		nil,
+	)
+	if c.Dev.RateLimitPoliciesFilename != "" {
+		err = rai.SetRateLimitPoliciesFile(c.Dev.RateLimitPoliciesFilename)
+		cmd.FailOnError(err, "Couldn't load rate limit policies file")
+	}
+	rai.PA = pa
+	rai.VA = vai
+	rai.CA = cai
+	rai.SA = ssa
+
+	// The WFE serves the issuer certificate at the issuer URLs of the CFSSL
+	// profiles, so they should point at ListenAddress.
+	issuerPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: issuer.Cert.Raw})
+	certChains := make(map[string][]byte)
+	for _, profile := range c.CA.CFSSL.Signing.Profiles {
+		for _, url := range profile.IssuerURL {
+			certChains[url] = append([]byte("\n"), issuerPEM...)
+		}
+	}
+
+	wfe, err := wfe2.NewWebFrontEndImpl(scope.NewScope("WFE"), clk, kp, certChains, logger)
+	cmd.FailOnError(err, "Unable to create WFE")
+	wfe.RA = rai
+	wfe.SA = ssa
+	wfe.AllowOrigins = c.Dev.AllowOrigins
+	wfe.AcceptRevocationReason = true
+	wfe.AllowAuthzDeactivation = true
+	wfe.NameLimits = nameLimits
+	wfe.DirectoryCAAIdentity = c.Dev.DirectoryCAAIdentity
+	wfe.IssuerCert = issuer.Cert.Raw
+
+	logger.Infof("Server running, listening on %s...\n", c.Dev.ListenAddress)
+	srv := &http.Server{
+		Addr:    c.Dev.ListenAddress,
+		Handler: wfe.Handler(),
+	}
+	go func() {
+		err := srv.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			cmd.FailOnError(err, "Running HTTP server")
+		}
+	}()
+
+	done := make(chan bool)
+	go cmd.CatchSignals(logger, func() {
+		cmd.ShutdownHTTP(logger, c.Dev.ShutdownStopTimeout.Duration, srv)
+		done <- true
+	})
+	<-done
+}
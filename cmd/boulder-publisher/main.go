@@ -12,6 +12,7 @@ import (
 	"github.com/letsencrypt/boulder/core"
 	"github.com/letsencrypt/boulder/features"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
+	"github.com/letsencrypt/boulder/health"
 	"github.com/letsencrypt/boulder/publisher"
 	pubPB "github.com/letsencrypt/boulder/publisher/proto"
 )
@@ -61,6 +62,10 @@ func main() {
 	}
 
 	scope, logger := cmd.StatsAndLogging(c.Syslog, c.Publisher.DebugAddr)
+	cmd.SetupTracing(c.Publisher.Tracing, scope, logger)
+	cmd.SetupFaults(c.Publisher.Faults, logger)
+	cmd.FailOnError(cmd.ReloadFeatures(c.Publisher.FeaturesFile, c.Publisher.Features), "Failed to load feature flags file")
+	cmd.FailOnError(cmd.LoadFeatureOperators(c.Publisher.FeatureOperatorsFile), "Failed to load feature operators file")
 	defer logger.AuditPanic()
 	logger.Info(cmd.VersionString())
 
@@ -105,8 +110,16 @@ func main() {
 		}()
 	}
 
-	go cmd.CatchSignals(logger, grpcSrv.GracefulStop)
+	go cmd.CatchSignals(logger, func() {
+		cmd.GracefulStop(logger, c.Publisher.ShutdownStopTimeout.Duration, grpcSrv)
+	})
+
+	health.Default.SetServing(true)
 
 	err = cmd.FilterShutdownErrors(grpcSrv.Serve(l))
 	cmd.FailOnError(err, "Publisher gRPC service failed")
+
+	// Serve returns once the server has been stopped. Wait for CatchSignals
+	// to finish shutting down and exit.
+	select {}
 }
@@ -11,14 +11,18 @@ import (
 	caPB "github.com/letsencrypt/boulder/ca/proto"
 	"github.com/letsencrypt/boulder/cmd"
 	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/csr"
 	"github.com/letsencrypt/boulder/ctpolicy"
 	"github.com/letsencrypt/boulder/features"
 	"github.com/letsencrypt/boulder/goodkey"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
+	"github.com/letsencrypt/boulder/health"
+	"github.com/letsencrypt/boulder/killswitch"
 	"github.com/letsencrypt/boulder/policy"
 	pubPB "github.com/letsencrypt/boulder/publisher/proto"
 	"github.com/letsencrypt/boulder/ra"
 	rapb "github.com/letsencrypt/boulder/ra/proto"
+	"github.com/letsencrypt/boulder/reloader"
 	sapb "github.com/letsencrypt/boulder/sa/proto"
 	vaPB "github.com/letsencrypt/boulder/va/proto"
 )
@@ -30,8 +34,29 @@ type config struct {
 
 		RateLimitPoliciesFilename string
 
+		// RateLimitOverridesUpdateInterval controls how often the RA fetches the
+		// rate limit overrides managed with the rate-limit-overrides tool from
+		// the SA. If zero, those overrides are not used.
+		RateLimitOverridesUpdateInterval cmd.ConfigDuration
+
+		// ChallengeWhitelistUpdateInterval controls how often the RA fetches
+		// the challenge whitelist entries managed with the account-admin tool
+		// from the SA. If zero, only the PA's ChallengesWhitelistFile is used.
+		ChallengeWhitelistUpdateInterval cmd.ConfigDuration
+
 		MaxContactsPerRegistration int
 
+		// BlockedContactDomainsFile, if set, is a YAML file listing email
+		// domains, e.g. of disposable mail providers, that can't be used as
+		// account contacts. It's reloaded whenever it changes.
+		BlockedContactDomainsFile string
+
+		// CountryPolicyFile, if set, is a YAML file mapping TLDs, registered
+		// domains and validation target networks to countries, and listing
+		// the countries certificates can't be issued for. It's reloaded
+		// whenever it changes.
+		CountryPolicyFile string
+
 		// UseIsSafeDomain determines whether to call VA.IsSafeDomain
 		UseIsSafeDomain bool // TODO: remove after va IsSafeDomain deploy
 
@@ -41,8 +66,12 @@ type config struct {
 		PublisherService    *cmd.GRPCClientConfig
 		AkamaiPurgerService *cmd.GRPCClientConfig
 
-		MaxNames     int
-		DoNotForceCN bool
+		MaxNames int
+		// RSAMaxNames and ECDSAMaxNames, if non-zero, override MaxNames for
+		// certificates issued with the CA's RSA and ECDSA profiles.
+		RSAMaxNames   int
+		ECDSAMaxNames int
+		DoNotForceCN  bool
 
 		// Controls behaviour of the RA when asked to create a new authz for
 		// a name/regID that already has a valid authz. False preserves historic
@@ -62,10 +91,41 @@ type config struct {
 		// you need to request a new challenge.
 		PendingAuthorizationLifetimeDays int
 
+		// ChallengeRetries is how many times a client may retry a failed
+		// challenge before it needs a new authorization. It requires the SA's
+		// StoreChallengeAttempts feature. Zero disables retries.
+		ChallengeRetries int
+
+		// DNS01Recheck, if Deadline is set, rechecks DNS-01 validations that
+		// fail because the TXT record couldn't be looked up every Interval,
+		// until Deadline after the first attempt. Meanwhile the challenge is
+		// processing. Every Interval the RA also takes over the rechecks of
+		// RAs that stopped, so it should stay set while any challenges are
+		// processing.
+		DNS01Recheck struct {
+			Deadline cmd.ConfigDuration
+			Interval cmd.ConfigDuration
+		}
+
+		// PolicyExemptionKey, if set, is the key policy exemption tokens are
+		// signed with, which must be the same as the CA's. Accounts can
+		// present the tokens in new orders for names the hostname policy
+		// would otherwise reject. Tokens are made with account-admin.
+		PolicyExemptionKey cmd.PasswordConfig
+
 		// WeakKeyFile is the path to a JSON file containing truncated RSA modulus
 		// hashes of known easily enumerable keys.
 		WeakKeyFile string
 
+		// FermatRounds is the number of rounds of Fermat factorization to attempt
+		// on RSA keys, rejecting those whose primes are too close together. Zero
+		// disables the check.
+		FermatRounds int
+
+		// KeyPolicy configures the RSA key sizes, ECDSA curves and RSA exponents
+		// that are accepted. If omitted, Boulder's default key policy applies.
+		KeyPolicy goodkey.Config
+
 		OrderLifetime cmd.ConfigDuration
 
 		// CTLogGroups contains groupings of CT logs which we want SCTs from.
@@ -79,12 +139,55 @@ type config struct {
 		// test them or because they are not yet approved by a browser/root
 		// program but we still want our certs to end up there.
 		InformationalCTLogs []cmd.LogDescription
+		// CTPolicyFile, if set, is a file containing a JSON object with
+		// CTLogGroups2 and InformationalCTLogs fields, used instead of the ones
+		// above. It is reloaded whenever it changes.
+		CTPolicyFile string
+		// CTLogBreakers, if Failures is set, times out slow submissions to
+		// each CT log and skips logs whose recent submissions keep failing.
+		CTLogBreakers struct {
+			SubmitTimeout cmd.ConfigDuration
+			Failures      int
+			Cooldown      cmd.ConfigDuration
+		}
 
 		// IssuerCertPath is the path to the intermediate used to issue certificates.
 		// It is required if the RevokeAtRA feature is enabled and is used to
 		// generate OCSP URLs to purge at revocation time.
 		IssuerCertPath string
 
+		// IssuanceKillSwitchFile, if set, is the path to a JSON file like
+		// {"stopIssuance": true, "reason": "scheduled maintenance"}. While
+		// stopIssuance is true, the RA refuses to issue certificates. The file
+		// is reloaded whenever it changes.
+		IssuanceKillSwitchFile string
+
+		// TopAccounts is how many of the most active accounts have their own
+		// account label in the account_issuance_requests metric. The rest are
+		// counted as "other". Defaults to 20.
+		TopAccounts int
+
+		// AccountReputation, if its Window is set, restricts the validations
+		// of accounts whose validations mostly fail.
+		AccountReputation struct {
+			Window                cmd.ConfigDuration
+			MinValidations        int64
+			MaxFailureRatio       float64
+			RestrictedValidations int64
+			RequireDNS01          bool
+		}
+
+		// IssuanceQueue, if its MaxConcurrent is set, limits how many
+		// certificates the RA asks the CA to sign at once. Requests beyond
+		// that wait in a bounded queue, renewals first, and are refused with
+		// a rateLimited problem when it's full or they've waited MaxWait.
+		IssuanceQueue struct {
+			MaxConcurrent  int
+			MaxQueued      int
+			RenewalReserve int
+			MaxWait        cmd.ConfigDuration
+		}
+
 		Features map[string]bool
 	}
 
@@ -118,6 +221,10 @@ func main() {
 	}
 
 	scope, logger := cmd.StatsAndLogging(c.Syslog, c.RA.DebugAddr)
+	cmd.SetupTracing(c.RA.Tracing, scope, logger)
+	cmd.SetupFaults(c.RA.Faults, logger)
+	cmd.FailOnError(cmd.ReloadFeatures(c.RA.FeaturesFile, c.RA.Features), "Failed to load feature flags file")
+	cmd.FailOnError(cmd.LoadFeatureOperators(c.RA.FeatureOperatorsFile), "Failed to load feature operators file")
 	defer logger.AuditPanic()
 	logger.Info(cmd.VersionString())
 
@@ -126,12 +233,26 @@ func main() {
 
 	pa, err := policy.New(c.PA.Challenges)
 	cmd.FailOnError(err, "Couldn't create PA")
-
-	if c.RA.HostnamePolicyFile == "" {
-		cmd.Fail("HostnamePolicyFile must be provided.")
+	pa.SetWildcardOrderPolicy(policy.WildcardOrderPolicy{
+		RejectBaseDomain: c.PA.RejectWildcardWithBaseDomain,
+		RejectSubdomains: c.PA.RejectWildcardWithSubdomains,
+	})
+	pa.SetAllowReservedNames(c.PA.AllowReservedNames)
+	err = pa.SetTokenFormat(c.PA.TokenFormat)
+	cmd.FailOnError(err, "Invalid token format")
+
+	if c.RA.HostnamePolicyRemote != nil {
+		source, err := c.RA.HostnamePolicyRemote.Load()
+		cmd.FailOnError(err, "Invalid hostname policy remote source")
+		err = pa.SetHostnamePolicyRemote(source)
+		cmd.FailOnError(err, "Couldn't fetch hostname policy")
+	} else {
+		if c.RA.HostnamePolicyFile == "" {
+			cmd.Fail("HostnamePolicyFile must be provided.")
+		}
+		err = pa.SetHostnamePolicyFile(c.RA.HostnamePolicyFile)
+		cmd.FailOnError(err, "Couldn't load hostname policy file")
 	}
-	err = pa.SetHostnamePolicyFile(c.RA.HostnamePolicyFile)
-	cmd.FailOnError(err, "Couldn't load hostname policy file")
 
 	if c.PA.ChallengesWhitelistFile != "" {
 		err = pa.SetChallengesWhitelistFile(c.PA.ChallengesWhitelistFile)
@@ -140,6 +261,19 @@ func main() {
 		logger.Info("No challengesWhitelistFile given, not loading")
 	}
 
+	if c.PA.ChallengesFile != "" {
+		err = pa.SetChallengesFile(c.PA.ChallengesFile)
+		cmd.FailOnError(err, "Couldn't load challenges file")
+	}
+
+	var challengeKillSwitch *killswitch.Challenges
+	if c.PA.ChallengeKillSwitchFile != "" {
+		challengeKillSwitch = killswitch.NewChallenges(cmd.Clock(), logger)
+		err = challengeKillSwitch.WatchFile(c.PA.ChallengeKillSwitchFile)
+		cmd.FailOnError(err, "Couldn't load challenge kill switch file")
+		pa.SetChallengeKillSwitch(challengeKillSwitch)
+	}
+
 	if features.Enabled(features.RevokeAtRA) && (c.RA.AkamaiPurgerService == nil || c.RA.IssuerCertPath == "") {
 		cmd.Fail("If the RevokeAtRA feature is enabled the AkamaiPurgerService and IssuerCertPath config fields must be populated")
 	}
@@ -176,32 +310,36 @@ func main() {
 		cmd.FailOnError(err, "Failed to load issuer certificate")
 	}
 
-	// Boulder's components assume that there will always be CT logs configured.
-	// Issuing a certificate without SCTs embedded is a miss-issuance event in the
-	// enviromnent Boulder is built for. Exit early if there is no CTLogGroups2
-	// configured.
-	if len(c.RA.CTLogGroups2) == 0 {
-		cmd.Fail("CTLogGroups2 must not be empty")
-	}
-
-	for i, g := range c.RA.CTLogGroups2 {
-		// Exit early if any of the log groups specify no logs
-		if len(g.Logs) == 0 {
-			cmd.Fail(
-				fmt.Sprintf("CTLogGroups2 index %d specifies no logs", i))
-		}
-		for _, l := range g.Logs {
-			if l.TemporalSet != nil {
-				err := l.Setup()
-				cmd.FailOnError(err, "Failed to setup a temporal log set")
-			}
+	if c.RA.CTPolicyFile != "" {
+		ctp = ctpolicy.New(pubc, nil, nil, logger, scope)
+		err = reloader.Register(reloader.Section{
+			Name: "CT policy",
+			File: c.RA.CTPolicyFile,
+			Load: ctp.LoadLogs,
+		})
+		cmd.FailOnError(err, "Couldn't load CT policy file")
+	} else {
+		// Exit early if the CT log groups can't be used, rather than
+		// misissuing certificates without SCTs.
+		cmd.FailOnError(ctpolicy.CheckGroups(c.RA.CTLogGroups2), "Invalid CT log groups")
+		for _, l := range c.RA.InformationalCTLogs {
+			cmd.FailOnError(l.CheckType(), "Invalid informational CT log")
 		}
+		ctp = ctpolicy.New(pubc, c.RA.CTLogGroups2, c.RA.InformationalCTLogs, logger, scope)
+	}
+	if bc := c.RA.CTLogBreakers; bc.Failures > 0 {
+		err = ctp.SetBreakers(ctpolicy.BreakerConfig{
+			SubmitTimeout: bc.SubmitTimeout.Duration,
+			Failures:      bc.Failures,
+			Cooldown:      bc.Cooldown.Duration,
+		})
+		cmd.FailOnError(err, "Invalid CT log breaker config")
 	}
-	ctp = ctpolicy.New(pubc, c.RA.CTLogGroups2, c.RA.InformationalCTLogs, logger, scope)
 
 	saConn, err := bgrpc.ClientSetup(c.RA.SAService, tlsConfig, clientMetrics, clk)
 	cmd.FailOnError(err, "Failed to load credentials and create gRPC connection to SA")
 	sac := bgrpc.NewStorageAuthorityClient(sapb.NewStorageAuthorityClient(saConn))
+	health.Default.AddCheck("sa", bgrpc.ConnCheck(saConn))
 
 	// TODO(patf): remove once RA.authorizationLifetimeDays is deployed
 	authorizationLifetime := 300 * 24 * time.Hour
@@ -217,6 +355,9 @@ func main() {
 
 	kp, err := goodkey.NewKeyPolicy(c.RA.WeakKeyFile)
 	cmd.FailOnError(err, "Unable to create key policy")
+	kp.FermatRounds = c.RA.FermatRounds
+	err = c.RA.KeyPolicy.Apply(&kp)
+	cmd.FailOnError(err, "Invalid key policy")
 
 	if c.RA.MaxNames == 0 {
 		cmd.Fail(fmt.Sprintf("Error in RA config: MaxNames must not be 0"))
@@ -228,7 +369,7 @@ func main() {
 		scope,
 		c.RA.MaxContactsPerRegistration,
 		kp,
-		c.RA.MaxNames,
+		csr.NameLimits{Default: c.RA.MaxNames, RSA: c.RA.RSAMaxNames, ECDSA: c.RA.ECDSAMaxNames},
 		c.RA.DoNotForceCN,
 		c.RA.ReuseValidAuthz,
 		authorizationLifetime,
@@ -243,20 +384,97 @@ func main() {
 
 	policyErr := rai.SetRateLimitPoliciesFile(c.RA.RateLimitPoliciesFilename)
 	cmd.FailOnError(policyErr, "Couldn't load rate limit policies file")
+	if c.RA.BlockedContactDomainsFile != "" {
+		err = rai.SetBlockedContactDomainsFile(c.RA.BlockedContactDomainsFile)
+		cmd.FailOnError(err, "Couldn't load blocked contact domains file")
+	}
+	if c.RA.CountryPolicyFile != "" {
+		err = rai.SetCountryPolicyFile(c.RA.CountryPolicyFile)
+		cmd.FailOnError(err, "Couldn't load country policy file")
+	}
 	rai.PA = pa
 
 	rai.VA = vac
 	rai.CA = cac
 	rai.SA = sac
 
+	if c.RA.IssuanceKillSwitchFile != "" {
+		rai.KillSwitch = killswitch.New(logger)
+		err = rai.KillSwitch.WatchFile(c.RA.IssuanceKillSwitchFile)
+		cmd.FailOnError(err, "Couldn't load issuance kill switch file")
+	}
+	rai.ChallengeKillSwitch = challengeKillSwitch
+
+	if c.RA.TopAccounts > 0 {
+		rai.SetTopAccounts(c.RA.TopAccounts)
+	}
+
+	if c.RA.ChallengeRetries > 0 {
+		rai.SetChallengeRetries(c.RA.ChallengeRetries)
+	}
+
+	if dr := c.RA.DNS01Recheck; dr.Deadline.Duration > 0 {
+		err = rai.SetDNS01Rechecks(ra.DNS01RecheckConfig{
+			Deadline: dr.Deadline.Duration,
+			Interval: dr.Interval.Duration,
+		})
+		cmd.FailOnError(err, "Invalid DNS-01 recheck config")
+	}
+
+	exemptionKey, err := c.RA.PolicyExemptionKey.Pass()
+	cmd.FailOnError(err, "Couldn't load policy exemption key")
+	if exemptionKey != "" {
+		rai.SetPolicyExemptionKey([]byte(exemptionKey))
+	}
+
+	if rc := c.RA.AccountReputation; rc.Window.Duration > 0 {
+		err = rai.SetAccountReputation(ra.ReputationConfig{
+			Window:                rc.Window.Duration,
+			MinValidations:        rc.MinValidations,
+			MaxFailureRatio:       rc.MaxFailureRatio,
+			RestrictedValidations: rc.RestrictedValidations,
+			RequireDNS01:          rc.RequireDNS01,
+		})
+		cmd.FailOnError(err, "Invalid account reputation config")
+	}
+
+	if qc := c.RA.IssuanceQueue; qc.MaxConcurrent > 0 {
+		err = rai.SetIssuanceQueue(ra.IssuanceQueueConfig{
+			MaxConcurrent:  qc.MaxConcurrent,
+			MaxQueued:      qc.MaxQueued,
+			RenewalReserve: qc.RenewalReserve,
+			MaxWait:        qc.MaxWait.Duration,
+		})
+		cmd.FailOnError(err, "Invalid issuance queue config")
+	}
+
+	if c.RA.RateLimitOverridesUpdateInterval.Duration > 0 {
+		go rai.UpdateRateLimitOverridesLoop(c.RA.RateLimitOverridesUpdateInterval.Duration)
+	}
+	if c.RA.ChallengeWhitelistUpdateInterval.Duration > 0 {
+		go rai.UpdateChallengeWhitelistLoop(c.RA.ChallengeWhitelistUpdateInterval.Duration)
+	}
+	if c.RA.DNS01Recheck.Deadline.Duration > 0 {
+		go rai.ResumeDNS01RechecksLoop()
+	}
+
 	serverMetrics := bgrpc.NewServerMetrics(scope)
 	grpcSrv, listener, err := bgrpc.NewServer(c.RA.GRPC, tlsConfig, serverMetrics, clk)
 	cmd.FailOnError(err, "Unable to setup RA gRPC server")
 	gw := bgrpc.NewRegistrationAuthorityServer(rai)
 	rapb.RegisterRegistrationAuthorityServer(grpcSrv, gw)
 
-	go cmd.CatchSignals(logger, grpcSrv.GracefulStop)
+	go cmd.CatchSignals(logger, func() {
+		cmd.GracefulStop(logger, c.RA.ShutdownStopTimeout.Duration, grpcSrv)
+	})
+
+	health.Default.AddCheck("hostnamePolicy", pa.CheckHostnamePolicy)
+	health.Default.SetServing(true)
 
 	err = cmd.FilterShutdownErrors(grpcSrv.Serve(listener))
 	cmd.FailOnError(err, "RA gRPC service failed")
+
+	// Serve returns once the server has been stopped. Wait for CatchSignals
+	// to finish shutting down and exit.
+	select {}
 }
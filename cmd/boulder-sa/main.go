@@ -1,17 +1,57 @@
 package main
 
 import (
+	"database/sql"
 	"flag"
+	"fmt"
 	"os"
 
+	"golang.org/x/net/context"
+
 	"github.com/letsencrypt/boulder/cmd"
 	"github.com/letsencrypt/boulder/features"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
+	"github.com/letsencrypt/boulder/health"
+	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/sa"
+	"github.com/letsencrypt/boulder/sa/migrate"
 	sapb "github.com/letsencrypt/boulder/sa/proto"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// checkMigrations returns an error if any of the migrations in dirs haven't
+// been applied to db, unless autoApply is true and they're all safe to apply
+// online, in which case it applies them.
+func checkMigrations(db *sql.DB, logger blog.Logger, dirs []string, autoApply bool) error {
+	migrations, err := migrate.Load(dirs...)
+	if err != nil {
+		return err
+	}
+	pending, err := migrate.Pending(db, migrations)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	for _, m := range pending {
+		if !autoApply {
+			return fmt.Errorf("migration %s hasn't been applied", m.Name)
+		}
+		if !m.SafeOnline {
+			return fmt.Errorf("migration %s hasn't been applied and isn't safe to apply online", m.Name)
+		}
+	}
+	for _, m := range pending {
+		err := migrate.Apply(db, m)
+		if err != nil {
+			return err
+		}
+		logger.AuditInfof("Applied migration %s", m.Name)
+	}
+	return nil
+}
+
 type config struct {
 	SA struct {
 		cmd.ServiceConfig
@@ -21,6 +61,52 @@ type config struct {
 
 		// Max simultaneous SQL queries caused by a single RPC.
 		ParallelismPerRPC int
+
+		// Migrations, if Dirs is set, makes the SA check at startup that
+		// every migration in the migrations subdirectory of each of Dirs has
+		// been applied, and refuse to start if not. If AutoApply is true,
+		// pending migrations are applied instead, as long as every one is
+		// annotated as safe to apply online. That requires a DB user allowed
+		// to change the schema.
+		Migrations struct {
+			Dirs      []string
+			AutoApply bool
+		}
+
+		// ReadCache, if Certificates or Authorizations is set, makes the SA
+		// cache up to that many issued certificates and finalized
+		// authorizations in memory. Finalized authorizations are cached for
+		// at most AuthorizationMaxAge, which must be set if they're cached.
+		ReadCache struct {
+			Certificates        int
+			Authorizations      int
+			AuthorizationMaxAge cmd.ConfigDuration
+		}
+
+		// TransactionRetries, if MaxAttempts is set, makes the SA run write
+		// transactions up to that many times when the database aborts them to
+		// break a deadlock or lock wait timeout. Retries back off from
+		// BaseDelay up to MaxDelay, with jitter.
+		TransactionRetries struct {
+			MaxAttempts int
+			BaseDelay   cmd.ConfigDuration
+			MaxDelay    cmd.ConfigDuration
+		}
+
+		// Prune, if RateLimitPoliciesFile is set, makes the SA delete rows of
+		// the fqdnSets and issuedNames tables once they're outside the
+		// windows of the rate limits in that file that count them, and
+		// older than MinRetention, which must be at least
+		// MaxCertificateLifetime, the longest lifetime of the CA's
+		// certificates. Rows are deleted every Interval, at most BatchSize
+		// per query.
+		Prune struct {
+			RateLimitPoliciesFile  string
+			MinRetention           cmd.ConfigDuration
+			MaxCertificateLifetime cmd.ConfigDuration
+			Interval               cmd.ConfigDuration
+			BatchSize              int
+		}
 	}
 
 	Syslog cmd.SyslogConfig
@@ -51,6 +137,10 @@ func main() {
 	}
 
 	scope, logger := cmd.StatsAndLogging(c.Syslog, c.SA.DebugAddr)
+	cmd.SetupTracing(c.SA.Tracing, scope, logger)
+	cmd.SetupFaults(c.SA.Faults, logger)
+	cmd.FailOnError(cmd.ReloadFeatures(c.SA.FeaturesFile, c.SA.Features), "Failed to load feature flags file")
+	cmd.FailOnError(cmd.LoadFeatureOperators(c.SA.FeatureOperatorsFile), "Failed to load feature operators file")
 	defer logger.AuditPanic()
 	logger.Info(cmd.VersionString())
 
@@ -62,6 +152,11 @@ func main() {
 	dbMap, err := sa.NewDbMap(dbURL, saConf.DBConfig.MaxDBConns)
 	cmd.FailOnError(err, "Couldn't connect to SA database")
 
+	if len(saConf.Migrations.Dirs) > 0 {
+		err = checkMigrations(dbMap.Db, logger, saConf.Migrations.Dirs, saConf.Migrations.AutoApply)
+		cmd.FailOnError(err, "Database schema isn't up to date")
+	}
+
 	// Export the MaxDBConns
 	dbConnStat := prometheus.NewGauge(prometheus.GaugeOpts{
 		Name: "max_db_connections",
@@ -71,6 +166,9 @@ func main() {
 	dbConnStat.Set(float64(saConf.DBConfig.MaxDBConns))
 
 	go sa.ReportDbConnCount(dbMap, scope)
+	health.Default.AddCheck("db", func(ctx context.Context) error {
+		return dbMap.Db.PingContext(ctx)
+	})
 
 	clk := cmd.Clock()
 
@@ -80,6 +178,32 @@ func main() {
 	}
 	sai, err := sa.NewSQLStorageAuthority(dbMap, clk, logger, scope, parallel)
 	cmd.FailOnError(err, "Failed to create SA impl")
+	if rc := saConf.ReadCache; rc.Certificates > 0 || rc.Authorizations > 0 {
+		err = sai.SetReadCache(sa.ReadCacheConfig{
+			Certificates:        rc.Certificates,
+			Authorizations:      rc.Authorizations,
+			AuthorizationMaxAge: rc.AuthorizationMaxAge.Duration,
+		})
+		cmd.FailOnError(err, "Invalid read cache config")
+	}
+	if tr := saConf.TransactionRetries; tr.MaxAttempts > 0 {
+		err = sai.SetTransactionRetries(sa.TransactionRetryConfig{
+			MaxAttempts: tr.MaxAttempts,
+			BaseDelay:   tr.BaseDelay.Duration,
+			MaxDelay:    tr.MaxDelay.Duration,
+		})
+		cmd.FailOnError(err, "Invalid transaction retries config")
+	}
+	if pc := saConf.Prune; pc.RateLimitPoliciesFile != "" {
+		err = sai.StartPruning(sa.PruneConfig{
+			RateLimitPoliciesFile:  pc.RateLimitPoliciesFile,
+			MinRetention:           pc.MinRetention.Duration,
+			MaxCertificateLifetime: pc.MaxCertificateLifetime.Duration,
+			Interval:               pc.Interval.Duration,
+			BatchSize:              pc.BatchSize,
+		})
+		cmd.FailOnError(err, "Invalid prune config")
+	}
 
 	tls, err := c.SA.TLS.Load()
 	cmd.FailOnError(err, "TLS config")
@@ -89,8 +213,16 @@ func main() {
 	gw := bgrpc.NewStorageAuthorityServer(sai)
 	sapb.RegisterStorageAuthorityServer(grpcSrv, gw)
 
-	go cmd.CatchSignals(logger, grpcSrv.GracefulStop)
+	go cmd.CatchSignals(logger, func() {
+		cmd.GracefulStop(logger, c.SA.ShutdownStopTimeout.Duration, grpcSrv)
+	})
+
+	health.Default.SetServing(true)
 
 	err = cmd.FilterShutdownErrors(grpcSrv.Serve(listener))
 	cmd.FailOnError(err, "SA gRPC service failed")
+
+	// Serve returns once the server has been stopped. Wait for CatchSignals
+	// to finish shutting down and exit.
+	select {}
 }
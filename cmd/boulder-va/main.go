@@ -9,6 +9,8 @@ import (
 	"github.com/letsencrypt/boulder/cmd"
 	"github.com/letsencrypt/boulder/features"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
+	"github.com/letsencrypt/boulder/health"
+	"github.com/letsencrypt/boulder/killswitch"
 	"github.com/letsencrypt/boulder/va"
 	vaPB "github.com/letsencrypt/boulder/va/proto"
 )
@@ -32,10 +34,61 @@ type config struct {
 		// will be turned into 1.
 		DNSTries     int
 		DNSResolvers []string
+		// DNSZones configures split-horizon DNS: queries for names under each
+		// zone's suffixes go to the zone's servers instead of DNSResolvers.
+		DNSZones []bdns.ZoneConfig
+		// EDNS configures the EDNS0 OPT record sent with DNS queries.
+		EDNS bdns.EDNSConfig
 
 		RemoteVAs                   []cmd.GRPCClientConfig
 		MaxRemoteValidationFailures int
 
+		// Concurrency, if it sets any limits, limits how many validations the
+		// VA performs at once, in total and of each challenge type.
+		Concurrency struct {
+			MaxValidations  int
+			MaxPerChallenge map[string]int
+			MaxQueued       int
+			QueueTimeout    cmd.ConfigDuration
+		}
+
+		// DNSCapture, if Directory is set, records the raw DNS queries and
+		// responses of each validation in Directory, keeping them for MaxAge
+		// and, if MaxBytes is set, up to MaxBytes in total. The limits are
+		// enforced every PruneInterval, by default every hour.
+		DNSCapture struct {
+			Directory     string
+			MaxAge        cmd.ConfigDuration
+			MaxBytes      int64
+			PruneInterval cmd.ConfigDuration
+		}
+
+		// DNSCache, if TTL is set, reuses the result of each DNS lookup for
+		// TTL, so that the validations of an order with many names under
+		// one base domain don't repeat the same lookups. It holds at most
+		// MaxEntries lookups, by default 10000.
+		DNSCache struct {
+			TTL        cmd.ConfigDuration
+			MaxEntries int
+		}
+
+		// HTTP01 sets the Accept header of HTTP-01 requests, alongside
+		// UserAgent, and how strictly their responses are checked: "lenient",
+		// "default" or "strict". With "strict", responses must have one of
+		// ContentTypes, by default "text/plain" or
+		// "application/octet-stream".
+		HTTP01 struct {
+			Accept       string
+			Strictness   string
+			ContentTypes []string
+		}
+
+		// ChallengeKillSwitchFile, if set, is the file of emergency kill
+		// switches for challenge types, shared with the RA. The VA refuses to
+		// validate challenges of the types it disables. See
+		// killswitch.Challenges.
+		ChallengeKillSwitchFile string
+
 		Features map[string]bool
 
 		AccountURIPrefixes []string
@@ -75,23 +128,16 @@ func main() {
 	}
 
 	scope, logger := cmd.StatsAndLogging(c.Syslog, c.VA.DebugAddr)
+	cmd.SetupTracing(c.VA.Tracing, scope, logger)
+	cmd.SetupFaults(c.VA.Faults, logger)
+	cmd.FailOnError(cmd.ReloadFeatures(c.VA.FeaturesFile, c.VA.Features), "Failed to load feature flags file")
+	cmd.FailOnError(cmd.LoadFeatureOperators(c.VA.FeatureOperatorsFile), "Failed to load feature operators file")
 	defer logger.AuditPanic()
 	logger.Info(cmd.VersionString())
 
-	pc := &cmd.PortConfig{
-		HTTPPort:  80,
-		HTTPSPort: 443,
-		TLSPort:   443,
-	}
-	if c.VA.PortConfig.HTTPPort != 0 {
-		pc.HTTPPort = c.VA.PortConfig.HTTPPort
-	}
-	if c.VA.PortConfig.HTTPSPort != 0 {
-		pc.HTTPSPort = c.VA.PortConfig.HTTPSPort
-	}
-	if c.VA.PortConfig.TLSPort != 0 {
-		pc.TLSPort = c.VA.PortConfig.TLSPort
-	}
+	err = c.VA.PortConfig.Check()
+	cmd.FailOnError(err, "Invalid validation ports")
+	pc := c.VA.PortConfig.WithDefaults()
 
 	sbc, err := newGoogleSafeBrowsingV4(c.VA.GoogleSafeBrowsing, logger)
 	cmd.FailOnError(err, "Failed to create Google Safe Browsing client")
@@ -103,22 +149,24 @@ func main() {
 		dnsTries = 1
 	}
 	clk := cmd.Clock()
-	var resolver bdns.DNSClient
 	if len(c.Common.DNSResolver) != 0 {
 		c.VA.DNSResolvers = append(c.VA.DNSResolvers, c.Common.DNSResolver)
 	}
+	var r *bdns.DNSClientImpl
 	if !c.Common.DNSAllowLoopbackAddresses {
-		r := bdns.NewDNSClientImpl(
+		r = bdns.NewDNSClientImpl(
 			dnsTimeout,
 			c.VA.DNSResolvers,
 			scope,
 			clk,
 			dnsTries)
-		resolver = r
 	} else {
-		r := bdns.NewTestDNSClientImpl(dnsTimeout, c.VA.DNSResolvers, scope, clk, dnsTries)
-		resolver = r
+		r = bdns.NewTestDNSClientImpl(dnsTimeout, c.VA.DNSResolvers, scope, clk, dnsTries)
 	}
+	err = r.SetZones(c.VA.DNSZones)
+	cmd.FailOnError(err, "Invalid DNS zones")
+	err = r.SetEDNS(c.VA.EDNS)
+	cmd.FailOnError(err, "Invalid DNS EDNS config")
 
 	tlsConfig, err := c.VA.TLS.Load()
 	cmd.FailOnError(err, "tlsConfig config")
@@ -140,9 +188,9 @@ func main() {
 	}
 
 	vai, err := va.NewValidationAuthorityImpl(
-		pc,
+		&pc,
 		sbc,
-		resolver,
+		r,
 		remotes,
 		c.VA.MaxRemoteValidationFailures,
 		c.VA.UserAgent,
@@ -152,6 +200,47 @@ func main() {
 		logger,
 		c.VA.AccountURIPrefixes)
 	cmd.FailOnError(err, "Unable to create VA server")
+	if cc := c.VA.Concurrency; cc.MaxValidations > 0 || len(cc.MaxPerChallenge) > 0 {
+		err = vai.SetConcurrencyLimits(va.ConcurrencyConfig{
+			MaxValidations:  cc.MaxValidations,
+			MaxPerChallenge: cc.MaxPerChallenge,
+			MaxQueued:       cc.MaxQueued,
+			QueueTimeout:    cc.QueueTimeout.Duration,
+		})
+		cmd.FailOnError(err, "Invalid validation concurrency limits")
+	}
+	err = vai.SetHTTP01Config(va.HTTP01Config{
+		Accept:       c.VA.HTTP01.Accept,
+		Strictness:   c.VA.HTTP01.Strictness,
+		ContentTypes: c.VA.HTTP01.ContentTypes,
+	})
+	cmd.FailOnError(err, "Invalid HTTP-01 config")
+	if dc := c.VA.DNSCache; dc.TTL.Duration > 0 {
+		err = vai.SetDNSCache(va.DNSCacheConfig{
+			TTL:        dc.TTL.Duration,
+			MaxEntries: dc.MaxEntries,
+		})
+		cmd.FailOnError(err, "Invalid DNS cache config")
+	}
+	if c.VA.ChallengeKillSwitchFile != "" {
+		ks := killswitch.NewChallenges(clk, logger)
+		err = ks.WatchFile(c.VA.ChallengeKillSwitchFile)
+		cmd.FailOnError(err, "Couldn't load challenge kill switch file")
+		vai.SetChallengeKillSwitch(ks)
+	}
+	if dc := c.VA.DNSCapture; dc.Directory != "" {
+		err = vai.SetDNSCapture(va.DNSCaptureConfig{
+			Directory: dc.Directory,
+			MaxAge:    dc.MaxAge.Duration,
+			MaxBytes:  dc.MaxBytes,
+		})
+		cmd.FailOnError(err, "Invalid DNS capture config")
+		interval := dc.PruneInterval.Duration
+		if interval == 0 {
+			interval = time.Hour
+		}
+		go vai.PruneDNSCapturesLoop(interval)
+	}
 
 	serverMetrics := bgrpc.NewServerMetrics(scope)
 	grpcSrv, l, err := bgrpc.NewServer(c.VA.GRPC, tlsConfig, serverMetrics, clk)
@@ -161,8 +250,16 @@ func main() {
 	vaPB.RegisterCAAServer(grpcSrv, vai)
 	cmd.FailOnError(err, "Unable to register CAA gRPC server")
 
-	go cmd.CatchSignals(logger, grpcSrv.GracefulStop)
+	go cmd.CatchSignals(logger, func() {
+		cmd.GracefulStop(logger, c.VA.ShutdownStopTimeout.Duration, grpcSrv)
+	})
+
+	health.Default.SetServing(true)
 
 	err = cmd.FilterShutdownErrors(grpcSrv.Serve(l))
 	cmd.FailOnError(err, "VA gRPC service failed")
+
+	// Serve returns once the server has been stopped. Wait for CatchSignals
+	// to finish shutting down and exit.
+	select {}
 }
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"context"
 	"flag"
 	"fmt"
 	"net/http"
@@ -13,10 +12,12 @@ import (
 	"github.com/letsencrypt/boulder/features"
 	"github.com/letsencrypt/boulder/goodkey"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
+	"github.com/letsencrypt/boulder/health"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
 	rapb "github.com/letsencrypt/boulder/ra/proto"
 	sapb "github.com/letsencrypt/boulder/sa/proto"
+	"github.com/letsencrypt/boulder/web"
 	"github.com/letsencrypt/boulder/wfe"
 )
 
@@ -31,13 +32,52 @@ type config struct {
 
 		AllowOrigins []string
 
-		ShutdownStopTimeout cmd.ConfigDuration
+		// WeakKeyFile is the path to a JSON file containing truncated RSA modulus
+		// hashes of known easily enumerable keys, such as those generated by
+		// Debian's broken OpenSSL package. Account keys and CSR keys on the list
+		// are rejected with a badPublicKey problem.
+		WeakKeyFile string
+
+		// FermatRounds is the number of rounds of Fermat factorization to attempt
+		// on RSA keys, rejecting those whose primes are too close together. Zero
+		// disables the check.
+		FermatRounds int
+
+		// KeyPolicy configures the RSA key sizes, ECDSA curves and RSA exponents
+		// that are accepted. If omitted, Boulder's default key policy applies.
+		KeyPolicy goodkey.Config
+
+		// RequestTimeout is the deadline budget for handling each request,
+		// including every RPC made on its behalf. Each RPC hop passes on what
+		// remains of it, so it should be shorter than the timeout of any load
+		// balancer in front of the WFE. Defaults to 5 minutes.
+		RequestTimeout cmd.ConfigDuration
 
 		SubscriberAgreementURL string
 
 		AcceptRevocationReason bool
 		AllowAuthzDeactivation bool
 
+		// MaintenanceFile, if set, is the path to a JSON file like
+		// {"enabled": true, "reason": "database upgrade", "retryAfter": "30m"}.
+		// While enabled is true, the WFE refuses POST requests with a 503
+		// Service Unavailable response. The file is reloaded whenever it
+		// changes.
+		MaintenanceFile string
+
+		// ProblemCatalogFile, if set, is the path to a YAML file mapping
+		// language tags to translations of problem details, each a
+		// "pattern" regular expression matching the English detail and the
+		// translated "detail". Problems sent to clients that prefer one of
+		// the languages with Accept-Language are translated. The file is
+		// reloaded whenever it changes.
+		ProblemCatalogFile string
+
+		// HTTPServer tunes the servers on ListenAddress and TLSListenAddress:
+		// HTTP/2, timeouts, header size, requests per connection, and the
+		// handover of listening sockets to a new process during deploys.
+		HTTPServer cmd.HTTPServerConfig
+
 		TLS cmd.TLSConfig
 
 		RAService *cmd.GRPCClientConfig
@@ -74,6 +114,9 @@ func setupWFE(c config, logger blog.Logger, stats metrics.Scope, clk clock.Clock
 	cmd.FailOnError(err, "Failed to load credentials and create gRPC connection to SA")
 	sac := bgrpc.NewStorageAuthorityClient(sapb.NewStorageAuthorityClient(saConn))
 
+	health.Default.AddCheck("ra", bgrpc.ConnCheck(raConn))
+	health.Default.AddCheck("sa", bgrpc.ConnCheck(saConn))
+
 	return rac, sac
 }
 
@@ -93,13 +136,20 @@ func main() {
 	cmd.FailOnError(err, "Failed to set feature flags")
 
 	scope, logger := cmd.StatsAndLogging(c.Syslog, c.WFE.DebugAddr)
+	cmd.SetupTracing(c.WFE.Tracing, scope, logger)
+	cmd.SetupFaults(c.WFE.Faults, logger)
+	cmd.FailOnError(cmd.ReloadFeatures(c.WFE.FeaturesFile, c.WFE.Features), "Failed to load feature flags file")
+	cmd.FailOnError(cmd.LoadFeatureOperators(c.WFE.FeatureOperatorsFile), "Failed to load feature operators file")
 	defer logger.AuditPanic()
 	logger.Info(cmd.VersionString())
 
 	clk := cmd.Clock()
 
-	kp, err := goodkey.NewKeyPolicy("") // don't load any weak keys
+	kp, err := goodkey.NewKeyPolicy(c.WFE.WeakKeyFile)
 	cmd.FailOnError(err, "Unable to create key policy")
+	kp.FermatRounds = c.WFE.FermatRounds
+	err = c.WFE.KeyPolicy.Apply(&kp)
+	cmd.FailOnError(err, "Invalid key policy")
 	wfe, err := wfe.NewWebFrontEndImpl(scope, clk, kp, logger)
 	cmd.FailOnError(err, "Unable to create WFE")
 	rac, sac := setupWFE(c, logger, scope, clk)
@@ -108,8 +158,19 @@ func main() {
 
 	wfe.SubscriberAgreementURL = c.WFE.SubscriberAgreementURL
 	wfe.AllowOrigins = c.WFE.AllowOrigins
+	wfe.RequestTimeout = c.WFE.RequestTimeout.Duration
 	wfe.AcceptRevocationReason = c.WFE.AcceptRevocationReason
 	wfe.AllowAuthzDeactivation = c.WFE.AllowAuthzDeactivation
+	if c.WFE.MaintenanceFile != "" {
+		wfe.Maintenance = web.NewMaintenance(logger)
+		err = wfe.Maintenance.WatchFile(c.WFE.MaintenanceFile)
+		cmd.FailOnError(err, "Couldn't load maintenance file")
+	}
+	if c.WFE.ProblemCatalogFile != "" {
+		wfe.ProblemCatalog = web.NewProblemCatalog()
+		err = wfe.ProblemCatalog.WatchFile(c.WFE.ProblemCatalogFile)
+		cmd.FailOnError(err, "Couldn't load problem catalog file")
+	}
 	wfe.DirectoryCAAIdentity = c.WFE.DirectoryCAAIdentity
 	wfe.DirectoryWebsite = c.WFE.DirectoryWebsite
 
@@ -120,13 +181,15 @@ func main() {
 
 	logger.Infof("Server running, listening on %s...", c.WFE.ListenAddress)
 	handler := wfe.Handler()
-	srv := &http.Server{
-		Addr:    c.WFE.ListenAddress,
-		Handler: handler,
-	}
+	health.Default.SetServing(true)
+	servers := cmd.NewHTTPServers(c.WFE.HTTPServer, scope)
+	srv, err := servers.New(c.WFE.ListenAddress, handler)
+	cmd.FailOnError(err, "Couldn't configure HTTP server")
+	listener, err := servers.Listen(c.WFE.ListenAddress)
+	cmd.FailOnError(err, "Couldn't listen for HTTP")
 
 	go func() {
-		err := srv.ListenAndServe()
+		err := srv.Serve(listener)
 		if err != nil && err != http.ErrServerClosed {
 			cmd.FailOnError(err, "Running HTTP server")
 		}
@@ -134,12 +197,12 @@ func main() {
 
 	var tlsSrv *http.Server
 	if c.WFE.TLSListenAddress != "" {
-		tlsSrv = &http.Server{
-			Addr:    c.WFE.TLSListenAddress,
-			Handler: handler,
-		}
+		tlsSrv, err = servers.New(c.WFE.TLSListenAddress, handler)
+		cmd.FailOnError(err, "Couldn't configure TLS server")
+		tlsListener, err := servers.Listen(c.WFE.TLSListenAddress)
+		cmd.FailOnError(err, "Couldn't listen for TLS")
 		go func() {
-			err := tlsSrv.ListenAndServeTLS(c.WFE.ServerCertificatePath, c.WFE.ServerKeyPath)
+			err := tlsSrv.ServeTLS(tlsListener, c.WFE.ServerCertificatePath, c.WFE.ServerKeyPath)
 			if err != nil && err != http.ErrServerClosed {
 				cmd.FailOnError(err, "Running TLS server")
 			}
@@ -148,13 +211,7 @@ func main() {
 
 	done := make(chan bool)
 	go cmd.CatchSignals(logger, func() {
-		ctx, cancel := context.WithTimeout(context.Background(),
-			c.WFE.ShutdownStopTimeout.Duration)
-		defer cancel()
-		_ = srv.Shutdown(ctx)
-		if tlsSrv != nil {
-			_ = tlsSrv.Shutdown(ctx)
-		}
+		servers.Shutdown(logger, c.WFE.ShutdownStopTimeout.Duration, srv, tlsSrv)
 		done <- true
 	})
 
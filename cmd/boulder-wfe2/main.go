@@ -2,7 +2,6 @@ package main
 
 import (
 	"bytes"
-	"context"
 	"crypto/x509"
 	"encoding/pem"
 	"flag"
@@ -10,17 +9,21 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/jmhodges/clock"
 	"github.com/letsencrypt/boulder/cmd"
 	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/csr"
 	"github.com/letsencrypt/boulder/features"
 	"github.com/letsencrypt/boulder/goodkey"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
+	"github.com/letsencrypt/boulder/health"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
 	rapb "github.com/letsencrypt/boulder/ra/proto"
 	sapb "github.com/letsencrypt/boulder/sa/proto"
+	"github.com/letsencrypt/boulder/web"
 	"github.com/letsencrypt/boulder/wfe2"
 )
 
@@ -35,13 +38,116 @@ type config struct {
 
 		AllowOrigins []string
 
-		ShutdownStopTimeout cmd.ConfigDuration
+		// AdminListenAddress, if set, is where the endpoints for operators,
+		// such as certificate search, are served. They are served over TLS
+		// using the TLS config below, and only to clients with a certificate
+		// from its CA that has one of AdminClientNames as a subjectAltName.
+		AdminListenAddress string
+		AdminClientNames   []string
+
+		// PortalListenAddress, if set, is where the account portal is served:
+		// read-only endpoints where subscribers, authenticated by their account
+		// key, can list their certificates, orders, rate limit usage and recent
+		// validation failures. It's served over plain HTTP, like
+		// ListenAddress, with the same HTTPServer tuning.
+		PortalListenAddress string
+
+		// WeakKeyFile is the path to a JSON file containing truncated RSA modulus
+		// hashes of known easily enumerable keys, such as those generated by
+		// Debian's broken OpenSSL package. Account keys and CSR keys on the list
+		// are rejected with a badPublicKey problem.
+		WeakKeyFile string
+
+		// FermatRounds is the number of rounds of Fermat factorization to attempt
+		// on RSA keys, rejecting those whose primes are too close together. Zero
+		// disables the check.
+		FermatRounds int
+
+		// KeyPolicy configures the RSA key sizes, ECDSA curves and RSA exponents
+		// that are accepted. If omitted, Boulder's default key policy applies.
+		KeyPolicy goodkey.Config
+
+		// RequestTimeout is the deadline budget for handling each request,
+		// including every RPC made on its behalf. Each RPC hop passes on what
+		// remains of it, so it should be shorter than the timeout of any load
+		// balancer in front of the WFE. Defaults to 5 minutes.
+		RequestTimeout cmd.ConfigDuration
 
 		SubscriberAgreementURL string
 
+		// TOSReacceptance, if Changed is set, requires accounts that haven't
+		// agreed to the current SubscriberAgreementURL to agree to it before
+		// creating new orders. Changed is when the terms of service changed,
+		// in RFC 3339 format, and accounts have GracePeriod after that to
+		// agree to them.
+		TOSReacceptance struct {
+			Changed     time.Time
+			GracePeriod cmd.ConfigDuration
+		}
+
 		AcceptRevocationReason bool
 		AllowAuthzDeactivation bool
 
+		// MaintenanceFile, if set, is the path to a JSON file like
+		// {"enabled": true, "reason": "database upgrade", "retryAfter": "30m"}.
+		// While enabled is true, the WFE refuses POST requests other than certificate
+		// downloads with a 503
+		// Service Unavailable response. The file is reloaded whenever it
+		// changes.
+		MaintenanceFile string
+
+		// ProblemCatalogFile, if set, is the path to a YAML file mapping
+		// language tags to translations of problem details, each a
+		// "pattern" regular expression matching the English detail and the
+		// translated "detail". Problems sent to clients that prefer one of
+		// the languages with Accept-Language are translated. The file is
+		// reloaded whenever it changes.
+		ProblemCatalogFile string
+
+		// Throttle limits the rate of new-nonce, new-account and new-order
+		// requests from each client IP, separately from the RA's rate limits,
+		// so abusive clients are turned away before they cost the RA and SA
+		// anything. If its requestsPerSecond is zero nothing is throttled.
+		Throttle web.ThrottleConfig
+
+		// Abuse configures scoring requests by the thumbprint of the key
+		// their JWS is signed with, their client IP and User-Agent, and
+		// tarpitting, challenging or rejecting those with high scores. See
+		// web.AbusePolicy.
+		Abuse struct {
+			// RulesFile, if set, is a YAML file of rules, each a "name", a
+			// "score" and the "keyThumbprints", "ipRanges" and "userAgent"
+			// pattern it matches, that score requests. It's reloaded
+			// whenever it changes. If empty, requests aren't scored.
+			RulesFile      string
+			TarpitScore    float64
+			TarpitDelay    cmd.ConfigDuration
+			ChallengeScore float64
+			ChallengeURL   string
+			RejectScore    float64
+		}
+
+		// MaxNames, RSAMaxNames and ECDSAMaxNames should match the RA's
+		// settings. They let the WFE reject requests with too many names
+		// before making any RPCs. If MaxNames is zero the WFE leaves the
+		// check to the RA.
+		MaxNames      int
+		RSAMaxNames   int
+		ECDSAMaxNames int
+
+		// OrderAuthzLongPoll is how long a conditional request for the state
+		// of an order's authorizations waits for one of them to change before
+		// it's answered. It should be well under RequestTimeout. If zero, such
+		// requests are answered immediately. OrderAuthzPollInterval is how
+		// often the SA is queried while waiting, by default every second.
+		OrderAuthzLongPoll     cmd.ConfigDuration
+		OrderAuthzPollInterval cmd.ConfigDuration
+
+		// HTTPServer tunes the servers on ListenAddress and TLSListenAddress:
+		// HTTP/2, timeouts, header size, requests per connection, and the
+		// handover of listening sockets to a new process during deploys.
+		HTTPServer cmd.HTTPServerConfig
+
 		TLS cmd.TLSConfig
 
 		RAService *cmd.GRPCClientConfig
@@ -70,6 +176,12 @@ type config struct {
 		// header of the WFE1 instance and the legacy 'reg' path component. This
 		// will differ in configuration for production and staging.
 		LegacyKeyIDPrefix string
+
+		// AccountURLPrefix is the prefix of the account URLs clients get from
+		// the ACME API, e.g. "https://acme-v02.api.letsencrypt.org/acme/acct/".
+		// Key IDs with it are accepted whatever the Host header, which the
+		// portal needs when it's on a host of its own.
+		AccountURLPrefix string
 	}
 
 	Syslog cmd.SyslogConfig
@@ -192,6 +304,9 @@ func setupWFE(c config, logger blog.Logger, stats metrics.Scope, clk clock.Clock
 	cmd.FailOnError(err, "Failed to load credentials and create gRPC connection to SA")
 	sac := bgrpc.NewStorageAuthorityClient(sapb.NewStorageAuthorityClient(saConn))
 
+	health.Default.AddCheck("ra", bgrpc.ConnCheck(raConn))
+	health.Default.AddCheck("sa", bgrpc.ConnCheck(saConn))
+
 	return rac, sac
 }
 
@@ -214,13 +329,20 @@ func main() {
 	cmd.FailOnError(err, "Failed to set feature flags")
 
 	scope, logger := cmd.StatsAndLogging(c.Syslog, c.WFE.DebugAddr)
+	cmd.SetupTracing(c.WFE.Tracing, scope, logger)
+	cmd.SetupFaults(c.WFE.Faults, logger)
+	cmd.FailOnError(cmd.ReloadFeatures(c.WFE.FeaturesFile, c.WFE.Features), "Failed to load feature flags file")
+	cmd.FailOnError(cmd.LoadFeatureOperators(c.WFE.FeatureOperatorsFile), "Failed to load feature operators file")
 	defer logger.AuditPanic()
 	logger.Info(cmd.VersionString())
 
 	clk := cmd.Clock()
 
-	kp, err := goodkey.NewKeyPolicy("") // don't load any weak keys
+	kp, err := goodkey.NewKeyPolicy(c.WFE.WeakKeyFile)
 	cmd.FailOnError(err, "Unable to create key policy")
+	kp.FermatRounds = c.WFE.FermatRounds
+	err = c.WFE.KeyPolicy.Apply(&kp)
+	cmd.FailOnError(err, "Invalid key policy")
 	wfe, err := wfe2.NewWebFrontEndImpl(scope, clk, kp, certChains, logger)
 	cmd.FailOnError(err, "Unable to create WFE")
 	rac, sac := setupWFE(c, logger, scope, clk)
@@ -228,12 +350,45 @@ func main() {
 	wfe.SA = sac
 
 	wfe.SubscriberAgreementURL = c.WFE.SubscriberAgreementURL
+	if tr := c.WFE.TOSReacceptance; !tr.Changed.IsZero() {
+		wfe.TOSReacceptanceDeadline = tr.Changed.Add(tr.GracePeriod.Duration)
+	}
 	wfe.AllowOrigins = c.WFE.AllowOrigins
+	wfe.RequestTimeout = c.WFE.RequestTimeout.Duration
 	wfe.AcceptRevocationReason = c.WFE.AcceptRevocationReason
 	wfe.AllowAuthzDeactivation = c.WFE.AllowAuthzDeactivation
+	if c.WFE.MaintenanceFile != "" {
+		wfe.Maintenance = web.NewMaintenance(logger)
+		err = wfe.Maintenance.WatchFile(c.WFE.MaintenanceFile)
+		cmd.FailOnError(err, "Couldn't load maintenance file")
+	}
+	if c.WFE.ProblemCatalogFile != "" {
+		wfe.ProblemCatalog = web.NewProblemCatalog()
+		err = wfe.ProblemCatalog.WatchFile(c.WFE.ProblemCatalogFile)
+		cmd.FailOnError(err, "Couldn't load problem catalog file")
+	}
+	wfe.NameLimits = csr.NameLimits{Default: c.WFE.MaxNames, RSA: c.WFE.RSAMaxNames, ECDSA: c.WFE.ECDSAMaxNames}
+	wfe.OrderAuthzLongPoll = c.WFE.OrderAuthzLongPoll.Duration
+	wfe.OrderAuthzPollInterval = c.WFE.OrderAuthzPollInterval.Duration
 	wfe.DirectoryCAAIdentity = c.WFE.DirectoryCAAIdentity
 	wfe.DirectoryWebsite = c.WFE.DirectoryWebsite
 	wfe.LegacyKeyIDPrefix = c.WFE.LegacyKeyIDPrefix
+	wfe.AccountURLPrefix = c.WFE.AccountURLPrefix
+	wfe.Throttle, err = web.NewThrottle(clk, c.WFE.Throttle)
+	cmd.FailOnError(err, "Invalid throttle config")
+	if c.WFE.Abuse.RulesFile != "" {
+		rules := web.NewAbuseRules()
+		err = rules.WatchFile(c.WFE.Abuse.RulesFile)
+		cmd.FailOnError(err, "Couldn't load abuse rules file")
+		wfe.AbuseHook, err = web.NewAbuseHook(rules, web.AbusePolicy{
+			TarpitScore:    c.WFE.Abuse.TarpitScore,
+			TarpitDelay:    c.WFE.Abuse.TarpitDelay.Duration,
+			ChallengeScore: c.WFE.Abuse.ChallengeScore,
+			ChallengeURL:   c.WFE.Abuse.ChallengeURL,
+			RejectScore:    c.WFE.Abuse.RejectScore,
+		}, clk, logger)
+		cmd.FailOnError(err, "Invalid abuse config")
+	}
 
 	wfe.IssuerCert, err = cmd.LoadCert(c.Common.IssuerCert)
 	cmd.FailOnError(err, fmt.Sprintf("Couldn't read issuer cert [%s]", c.Common.IssuerCert))
@@ -242,13 +397,15 @@ func main() {
 
 	logger.Infof("Server running, listening on %s...\n", c.WFE.ListenAddress)
 	handler := wfe.Handler()
-	srv := &http.Server{
-		Addr:    c.WFE.ListenAddress,
-		Handler: handler,
-	}
+	health.Default.SetServing(true)
+	servers := cmd.NewHTTPServers(c.WFE.HTTPServer, scope)
+	srv, err := servers.New(c.WFE.ListenAddress, handler)
+	cmd.FailOnError(err, "Couldn't configure HTTP server")
+	listener, err := servers.Listen(c.WFE.ListenAddress)
+	cmd.FailOnError(err, "Couldn't listen for HTTP")
 
 	go func() {
-		err := srv.ListenAndServe()
+		err := srv.Serve(listener)
 		if err != nil && err != http.ErrServerClosed {
 			cmd.FailOnError(err, "Running HTTP server")
 		}
@@ -256,26 +413,58 @@ func main() {
 
 	var tlsSrv *http.Server
 	if c.WFE.TLSListenAddress != "" {
-		tlsSrv = &http.Server{
-			Addr:    c.WFE.TLSListenAddress,
-			Handler: handler,
-		}
+		tlsSrv, err = servers.New(c.WFE.TLSListenAddress, handler)
+		cmd.FailOnError(err, "Couldn't configure TLS server")
+		tlsListener, err := servers.Listen(c.WFE.TLSListenAddress)
+		cmd.FailOnError(err, "Couldn't listen for TLS")
 		go func() {
-			err := tlsSrv.ListenAndServeTLS(c.WFE.ServerCertificatePath, c.WFE.ServerKeyPath)
+			err := tlsSrv.ServeTLS(tlsListener, c.WFE.ServerCertificatePath, c.WFE.ServerKeyPath)
 			if err != nil && err != http.ErrServerClosed {
 				cmd.FailOnError(err, "Running TLS server")
 			}
 		}()
 	}
 
+	var adminSrv *http.Server
+	if c.WFE.AdminListenAddress != "" {
+		if len(c.WFE.AdminClientNames) == 0 {
+			cmd.Fail("AdminClientNames must be set to serve the admin endpoints")
+		}
+		adminTLS, err := c.WFE.TLS.Load()
+		cmd.FailOnError(err, "TLS config")
+		adminSrv = &http.Server{
+			Addr:      c.WFE.AdminListenAddress,
+			Handler:   wfe.AdminHandler(c.WFE.AdminClientNames),
+			TLSConfig: adminTLS,
+		}
+		adminListener, err := servers.Listen(c.WFE.AdminListenAddress)
+		cmd.FailOnError(err, "Couldn't listen for admin requests")
+		go func() {
+			// The certificate and key come from adminTLS.
+			err := adminSrv.ServeTLS(adminListener, "", "")
+			if err != nil && err != http.ErrServerClosed {
+				cmd.FailOnError(err, "Running admin server")
+			}
+		}()
+	}
+
+	var portalSrv *http.Server
+	if c.WFE.PortalListenAddress != "" {
+		portalSrv, err = servers.New(c.WFE.PortalListenAddress, wfe.PortalHandler())
+		cmd.FailOnError(err, "Couldn't configure portal server")
+		portalListener, err := servers.Listen(c.WFE.PortalListenAddress)
+		cmd.FailOnError(err, "Couldn't listen for portal requests")
+		go func() {
+			err := portalSrv.Serve(portalListener)
+			if err != nil && err != http.ErrServerClosed {
+				cmd.FailOnError(err, "Running portal server")
+			}
+		}()
+	}
+
 	done := make(chan bool)
 	go cmd.CatchSignals(logger, func() {
-		ctx, cancel := context.WithTimeout(context.Background(), c.WFE.ShutdownStopTimeout.Duration)
-		defer cancel()
-		_ = srv.Shutdown(ctx)
-		if tlsSrv != nil {
-			_ = tlsSrv.Shutdown(ctx)
-		}
+		servers.Shutdown(logger, c.WFE.ShutdownStopTimeout.Duration, srv, tlsSrv, adminSrv, portalSrv)
 		done <- true
 	})
 
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/letsencrypt/boulder/pkcs11helpers"
+	"github.com/miekg/pkcs11"
+)
+
+// keyAttestation is a statement, made at the time of a ceremony, that a key
+// was generated on an HSM and can't be extracted from it.
+type keyAttestation struct {
+	Slot  uint   `json:"slot"`
+	Label string `json:"label"`
+	KeyID string `json:"keyID"`
+	// PublicKey is the DER encoded SubjectPublicKeyInfo of the key, and
+	// PublicKeySHA256 is its hex encoded SHA-256 hash.
+	PublicKey       []byte `json:"publicKey"`
+	PublicKeySHA256 string `json:"publicKeySHA256"`
+	// Attributes are the PKCS#11 attributes of the private key that show it
+	// was generated on and is bound to the HSM.
+	Attributes map[string]bool `json:"attributes"`
+	Created    time.Time       `json:"created"`
+}
+
+// signedKeyAttestation is the attestation written out by a ceremony. The
+// statement is signed by the key it describes, which shows it was made with
+// access to the key.
+type signedKeyAttestation struct {
+	// Statement is a JSON encoded keyAttestation
+	Statement          []byte `json:"statement"`
+	SignatureAlgorithm string `json:"signatureAlgorithm"`
+	// Signature is over the SHA-256 hash of Statement
+	Signature []byte `json:"signature"`
+}
+
+// attestedAttributes are the private key attributes included in attestations,
+// and the value each must have for the key to be acceptable
+var attestedAttributes = []struct {
+	name     string
+	attr     uint
+	required bool
+}{
+	{"CKA_TOKEN", pkcs11.CKA_TOKEN, true},
+	{"CKA_LOCAL", pkcs11.CKA_LOCAL, true},
+	{"CKA_SENSITIVE", pkcs11.CKA_SENSITIVE, true},
+	{"CKA_ALWAYS_SENSITIVE", pkcs11.CKA_ALWAYS_SENSITIVE, true},
+	{"CKA_EXTRACTABLE", pkcs11.CKA_EXTRACTABLE, false},
+	{"CKA_NEVER_EXTRACTABLE", pkcs11.CKA_NEVER_EXTRACTABLE, true},
+}
+
+// attestKey makes a signed attestation for the private key of signer. It fails
+// if the key's attributes show it could have been generated outside of the HSM
+// or could be extracted from it.
+func attestKey(ctx pkcs11helpers.PKCtx, slot uint, label, keyID string, signer *x509Signer, now time.Time) ([]byte, error) {
+	template := make([]*pkcs11.Attribute, len(attestedAttributes))
+	for i, a := range attestedAttributes {
+		template[i] = pkcs11.NewAttribute(a.attr, nil)
+	}
+	attrs, err := ctx.GetAttributeValue(signer.session, signer.objectHandle, template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve private key attributes: %s", err)
+	}
+	values := make(map[uint]bool, len(attrs))
+	for _, attr := range attrs {
+		// CK_BBOOL values are a single byte
+		values[attr.Type] = len(attr.Value) == 1 && attr.Value[0] != 0
+	}
+
+	attestation := keyAttestation{
+		Slot:       slot,
+		Label:      label,
+		KeyID:      keyID,
+		Attributes: make(map[string]bool, len(attestedAttributes)),
+		Created:    now.UTC(),
+	}
+	for _, a := range attestedAttributes {
+		value, present := values[a.attr]
+		if !present {
+			return nil, fmt.Errorf("HSM didn't return %s for the private key", a.name)
+		}
+		if value != a.required {
+			return nil, fmt.Errorf("private key has %s %t, but it must be %t", a.name, value, a.required)
+		}
+		attestation.Attributes[a.name] = value
+	}
+	attestation.PublicKey, err = x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public key: %s", err)
+	}
+	pubHash := sha256.Sum256(attestation.PublicKey)
+	attestation.PublicKeySHA256 = hex.EncodeToString(pubHash[:])
+
+	statement, err := json.Marshal(attestation)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Key attestation statement: %s\n", statement)
+	var sigAlg x509.SignatureAlgorithm
+	switch signer.keyType {
+	case pkcs11helpers.RSAKey:
+		sigAlg = x509.SHA256WithRSA
+	case pkcs11helpers.ECDSAKey:
+		sigAlg = x509.ECDSAWithSHA256
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+	digest := sha256.Sum256(statement)
+	signature, err := signer.Sign(&failReader{}, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign key attestation: %s", err)
+	}
+	return json.MarshalIndent(signedKeyAttestation{
+		Statement:          statement,
+		SignatureAlgorithm: sigAlg.String(),
+		Signature:          signature,
+	}, "", "  ")
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/letsencrypt/boulder/pkcs11helpers"
+	"github.com/letsencrypt/boulder/test"
+	"github.com/miekg/pkcs11"
+)
+
+func TestAttestKey(t *testing.T) {
+	tk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "Failed to generate test key")
+	attrValues := map[uint]bool{
+		pkcs11.CKA_TOKEN:             true,
+		pkcs11.CKA_LOCAL:             true,
+		pkcs11.CKA_SENSITIVE:         true,
+		pkcs11.CKA_ALWAYS_SENSITIVE:  true,
+		pkcs11.CKA_EXTRACTABLE:       false,
+		pkcs11.CKA_NEVER_EXTRACTABLE: true,
+	}
+	ctx := pkcs11helpers.MockCtx{
+		GetAttributeValueFunc: func(_ pkcs11.SessionHandle, _ pkcs11.ObjectHandle, template []*pkcs11.Attribute) ([]*pkcs11.Attribute, error) {
+			var attrs []*pkcs11.Attribute
+			for _, a := range template {
+				attrs = append(attrs, pkcs11.NewAttribute(a.Type, attrValues[a.Type]))
+			}
+			return attrs, nil
+		},
+		SignInitFunc: func(pkcs11.SessionHandle, []*pkcs11.Mechanism, pkcs11.ObjectHandle) error {
+			return nil
+		},
+		SignFunc: func(_ pkcs11.SessionHandle, digest []byte) ([]byte, error) {
+			r, s, err := ecdsa.Sign(rand.Reader, tk, digest)
+			if err != nil {
+				return nil, err
+			}
+			signature := make([]byte, 64)
+			rBytes, sBytes := r.Bytes(), s.Bytes()
+			copy(signature[32-len(rBytes):], rBytes)
+			copy(signature[64-len(sBytes):], sBytes)
+			return signature, nil
+		},
+	}
+	signer := &x509Signer{ctx: ctx, keyType: pkcs11helpers.ECDSAKey, pub: tk.Public()}
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	attestationBytes, err := attestKey(ctx, 1, "label", "01020304", signer, now)
+	test.AssertNotError(t, err, "attestKey failed")
+	var signed signedKeyAttestation
+	err = json.Unmarshal(attestationBytes, &signed)
+	test.AssertNotError(t, err, "Failed to unmarshal signed attestation")
+	test.AssertEquals(t, signed.SignatureAlgorithm, "ECDSA-SHA256")
+	var sig struct{ R, S *big.Int }
+	_, err = asn1.Unmarshal(signed.Signature, &sig)
+	test.AssertNotError(t, err, "Failed to unmarshal attestation signature")
+	digest := sha256.Sum256(signed.Statement)
+	test.Assert(t, ecdsa.Verify(&tk.PublicKey, digest[:], sig.R, sig.S), "Attestation signature doesn't verify")
+
+	var attestation keyAttestation
+	err = json.Unmarshal(signed.Statement, &attestation)
+	test.AssertNotError(t, err, "Failed to unmarshal attestation statement")
+	test.AssertEquals(t, attestation.Slot, uint(1))
+	test.AssertEquals(t, attestation.Label, "label")
+	test.AssertEquals(t, attestation.KeyID, "01020304")
+	test.AssertEquals(t, attestation.Created, now)
+	test.AssertEquals(t, len(attestation.Attributes), len(attestedAttributes))
+	test.AssertEquals(t, attestation.Attributes["CKA_EXTRACTABLE"], false)
+	pub, err := x509.ParsePKIXPublicKey(attestation.PublicKey)
+	test.AssertNotError(t, err, "Failed to parse attested public key")
+	test.AssertDeepEquals(t, pub, tk.Public())
+
+	// Keys that could have been exported aren't attested
+	attrValues[pkcs11.CKA_EXTRACTABLE] = true
+	_, err = attestKey(ctx, 1, "label", "01020304", signer, now)
+	test.AssertError(t, err, "attestKey didn't fail with an extractable key")
+	attrValues[pkcs11.CKA_EXTRACTABLE] = false
+	attrValues[pkcs11.CKA_LOCAL] = false
+	_, err = attestKey(ctx, 1, "label", "01020304", signer, now)
+	test.AssertError(t, err, "attestKey didn't fail with a key that wasn't generated on the HSM")
+}
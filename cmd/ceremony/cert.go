@@ -8,16 +8,13 @@ import (
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/hex"
-	"encoding/json"
 	"encoding/pem"
 	"errors"
-	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"math/big"
-	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -328,99 +325,70 @@ func (fr *failReader) Read([]byte) (int, error) {
 	return 0, errors.New("Empty reader used by x509.CreateCertificate")
 }
 
-func main() {
-	module := flag.String("module", "", "PKCS#11 module to use")
-	slot := flag.Uint("slot", 0, "ID of PKCS#11 slot containing token with signing key.")
-	pin := flag.String("pin", "", "PKCS#11 token PIN. If empty, will assume PED based login.")
-	label := flag.String("label", "", "PKCS#11 key label")
-	id := flag.String("id", "", "PKCS#11 hex key ID (simplified format, i.e. ffff")
-	profilePath := flag.String("profile", "", "Path to file containing certificate profile in JSON format. See https://godoc.org/github.com/letsencrypt/boulder/cmd/gen-ca#CertProfile for details.")
-	pubKeyPath := flag.String("publicKey", "", "Path to file containing the subject public key in PEM format")
-	issuerPath := flag.String("issuer", "", "Path to issuer cert if generating an intermediate")
-	outputPath := flag.String("output", "", "Path to store generated PEM certificate")
-	flag.Parse()
-
-	if *module == "" {
-		log.Fatal("--module is required")
-	}
-	if *label == "" {
-		log.Fatal("--label is required")
-	}
-	if *id == "" {
-		log.Fatal("--id is required")
-	}
-	if *profilePath == "" {
-		log.Fatal("--profile is required")
-	}
-	if *pubKeyPath == "" {
-		log.Fatal("--publicKey is required")
+// loadCertificate reads and parses a PEM encoded certificate.
+func loadCertificate(path string) (*x509.Certificate, error) {
+	pemBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate %q: %s", path, err)
 	}
-	if *outputPath == "" {
-		log.Fatal("--output is required")
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("failed to parse certificate PEM %q", path)
 	}
-
-	ctx, session, err := pkcs11helpers.Initialize(*module, *slot, *pin)
+	cert, err := x509.ParseCertificate(block.Bytes)
 	if err != nil {
-		log.Fatalf("Failed to setup session and PKCS#11 context: %s", err)
+		return nil, fmt.Errorf("failed to parse certificate %q: %s", path, err)
 	}
-	log.Println("Opened PKCS#11 session")
+	return cert, nil
+}
 
-	privKey, err := getKey(ctx, session, *label, *id)
+// loadPublicKey reads and parses a PEM encoded public key, returning it along
+// with its DER encoding.
+func loadPublicKey(path string) (crypto.PublicKey, []byte, error) {
+	pemBytes, err := ioutil.ReadFile(path)
 	if err != nil {
-		log.Fatalf("Failed to retrieve private key handle: %s", err)
+		return nil, nil, fmt.Errorf("failed to read public key %q: %s", path, err)
 	}
-	log.Println("Retrieved private key handle")
-
-	profileBytes, err := ioutil.ReadFile(*profilePath)
-	if err != nil {
-		log.Fatalf("Failed to read certificate profile %q: %s", *profilePath, err)
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, nil, fmt.Errorf("failed to parse public key PEM %q", path)
 	}
-	var profile CertProfile
-	err = json.Unmarshal(profileBytes, &profile)
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
 	if err != nil {
-		log.Fatalf("Failed to parse certificate profile: %s", err)
-	}
-	if err = verifyProfile(profile, *issuerPath == ""); err != nil {
-		log.Fatalf("Invalid certificate profile: %s", err)
+		return nil, nil, fmt.Errorf("failed to parse public key %q: %s", path, err)
 	}
+	return pub, block.Bytes, nil
+}
 
-	pubPEMBytes, err := ioutil.ReadFile(*pubKeyPath)
+// makeCrossTemplate generates the template for a cross-certificate, which has
+// the same subject and key as the certificate being cross-signed. The subject
+// in the profile must match it, so that the ceremony config documents it.
+func makeCrossTemplate(ctx pkcs11helpers.PKCtx, profile *CertProfile, toCrossSign *x509.Certificate, session pkcs11.SessionHandle) (*x509.Certificate, error) {
+	subject := toCrossSign.Subject
+	if profile.CommonName != subject.CommonName ||
+		len(subject.Organization) != 1 || profile.Organization != subject.Organization[0] ||
+		len(subject.Country) != 1 || profile.Country != subject.Country[0] {
+		return nil, fmt.Errorf("subject in profile doesn't match the subject %q of the certificate to cross-sign", subject)
+	}
+	template, err := makeTemplate(ctx, profile, toCrossSign.RawSubjectPublicKeyInfo, session)
 	if err != nil {
-		log.Fatalf("Failed to read public key %q: %s", *pubKeyPath, err)
-	}
-	pubPEM, _ := pem.Decode(pubPEMBytes)
-	if pubPEM == nil {
-		log.Fatal("Failed to parse public key")
-	}
-	pub, err := x509.ParsePKIXPublicKey(pubPEM.Bytes)
-	if err != nil {
-		log.Fatalf("Failed to parse public key: %s", err)
+		return nil, err
 	}
+	// Use the exact subject and key identifier of the certificate being
+	// cross-signed, so that either can be used to build a path.
+	template.RawSubject = toCrossSign.RawSubject
+	template.SubjectKeyId = toCrossSign.SubjectKeyId
+	return template, nil
+}
 
-	certTemplate, err := makeTemplate(ctx, &profile, pubPEM.Bytes, session)
-	if err != nil {
-		log.Fatalf("Failed to construct certificate template from profile: %s", err)
-	}
-	log.Println("Generated certificate template from profile")
-	var issuer *x509.Certificate
-	if *issuerPath != "" {
-		// If generating an intermediate, load the parent issuer and
-		// set the Authority Key Identifier in the template.
-		issuerPEMBytes, err := ioutil.ReadFile(*issuerPath)
-		if err != nil {
-			log.Fatalf("Failed to read issuer certificate %q: %s", *issuerPath, err)
-		}
-		issuerPEM, _ := pem.Decode(issuerPEMBytes)
-		if issuerPEM == nil {
-			log.Fatal("Failed to parse issuer certificate PEM")
-		}
-		issuer, err = x509.ParseCertificate(issuerPEM.Bytes)
-		if err != nil {
-			log.Fatalf("Failed to parse issuer certificate: %s", err)
-		}
-		certTemplate.AuthorityKeyId = issuer.SubjectKeyId
+// signCertificate signs the certificate described by template with signer and
+// verifies the result. If issuer is nil the certificate is self-signed,
+// otherwise issuer must be the certificate for signer's key.
+func signCertificate(template, issuer *x509.Certificate, pub crypto.PublicKey, signer crypto.Signer) ([]byte, error) {
+	if issuer != nil {
+		template.AuthorityKeyId = issuer.SubjectKeyId
 	} else {
-		issuer = certTemplate
+		issuer = template
 	}
 
 	// x509.CreateCertificate uses a io.Reader here for signing methods that require
@@ -428,33 +396,28 @@ func main() {
 	// at the HSM we don't need to pass a real reader. Instead of passing a nil reader
 	// we use one that always returns errors in case the internal usage of this reader
 	// changes.
-	certBytes, err := x509.CreateCertificate(&failReader{}, certTemplate, issuer, pub, privKey)
+	certBytes, err := x509.CreateCertificate(&failReader{}, template, issuer, pub, signer)
 	if err != nil {
-		log.Fatalf("Failed to create certificate: %s", err)
+		return nil, fmt.Errorf("failed to create certificate: %s", err)
 	}
 	log.Printf("Signed certificate: %x\n", certBytes)
 	cert, err := x509.ParseCertificate(certBytes)
 	if err != nil {
-		log.Fatalf("Failed to parse signed certificate: %s", err)
+		return nil, fmt.Errorf("failed to parse signed certificate: %s", err)
 	}
 
 	// If generating a root then the signing key is the public key
 	// in the cert itself, so set the parent to itself
-	var parent *x509.Certificate
-	if *issuerPath == "" {
+	parent := issuer
+	if issuer == template {
 		parent = cert
-	} else {
-		parent = issuer
 	}
 	if err := cert.CheckSignatureFrom(parent); err != nil {
-		log.Fatalf("Failed to verify certificate signature: %s", err)
+		return nil, fmt.Errorf("failed to verify certificate signature: %s", err)
 	}
 	log.Println("Verified certificate signature")
 
 	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certBytes})
 	log.Printf("Certificate PEM:\n%s", pemBytes)
-	if err := ioutil.WriteFile(*outputPath, pemBytes, os.ModePerm); err != nil {
-		log.Fatalf("Failed to write certificate to %q: %s", *outputPath, err)
-	}
-	log.Printf("Certificate written to %q\n", *outputPath)
+	return pemBytes, nil
 }
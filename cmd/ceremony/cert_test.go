@@ -10,6 +10,7 @@ import (
 	"encoding/asn1"
 	"errors"
 	"math/big"
+	"os"
 	"testing"
 
 	"github.com/letsencrypt/boulder/pkcs11helpers"
@@ -396,3 +397,29 @@ func TestVerifyProfile(t *testing.T) {
 		}
 	}
 }
+
+func TestMakeCrossTemplate(t *testing.T) {
+	toCrossSign, _, path := writeTestCert(t, 1, nil, nil)
+	defer os.Remove(path)
+	ctx := pkcs11helpers.MockCtx{
+		GenerateRandomFunc: func(pkcs11.SessionHandle, int) ([]byte, error) {
+			return []byte{1, 2, 3}, nil
+		},
+	}
+	profile := &CertProfile{
+		SignatureAlgorithm: "ECDSAWithSHA256",
+		CommonName:         "test",
+		Organization:       "test",
+		Country:            "US",
+		NotBefore:          "2019-01-01 00:00:00",
+		NotAfter:           "2029-01-01 00:00:00",
+	}
+	template, err := makeCrossTemplate(ctx, profile, toCrossSign, 0)
+	test.AssertNotError(t, err, "makeCrossTemplate failed")
+	test.AssertByteEquals(t, template.RawSubject, toCrossSign.RawSubject)
+	test.AssertByteEquals(t, template.SubjectKeyId, toCrossSign.SubjectKeyId)
+
+	profile.CommonName = "other"
+	_, err = makeCrossTemplate(ctx, profile, toCrossSign, 0)
+	test.AssertError(t, err, "makeCrossTemplate didn't fail with a mismatched subject")
+}
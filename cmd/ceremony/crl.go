@@ -0,0 +1,193 @@
+package main
+
+import (
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+)
+
+// CRLProfile contains the information required to generate a CRL
+type CRLProfile struct {
+	// SignatureAlgorithm should contain one of the allowed signature algorithms
+	// in AllowedSigAlgs
+	SignatureAlgorithm string
+
+	// ThisUpdate should contain the requested thisUpdate date for the CRL in
+	// the format "2006-01-02 15:04:05". Dates will always be UTC.
+	ThisUpdate string
+	// NextUpdate should contain the requested nextUpdate date for the CRL in
+	// the format "2006-01-02 15:04:05". Dates will always be UTC.
+	NextUpdate string
+	// Number is the CRL number, which must be greater than the number of any
+	// previous CRL from the same issuer
+	Number int64
+	// RevokedCertificates lists the certificates to include in the CRL
+	RevokedCertificates []RevokedCertificate
+}
+
+// RevokedCertificate is a certificate included in a CRL
+type RevokedCertificate struct {
+	// CertificatePath is the path to the revoked certificate in PEM format
+	CertificatePath string
+	// RevocationDate should contain the date the certificate was revoked in
+	// the format "2006-01-02 15:04:05". Dates will always be UTC.
+	RevocationDate string
+	// RevocationReason is the RFC 5280 reason code. If zero (unspecified) it
+	// is omitted from the CRL.
+	RevocationReason int
+}
+
+var (
+	oidExtensionAuthorityKeyID = asn1.ObjectIdentifier{2, 5, 29, 35}
+	oidExtensionCRLNumber      = asn1.ObjectIdentifier{2, 5, 29, 20}
+	oidExtensionReasonCode     = asn1.ObjectIdentifier{2, 5, 29, 21}
+)
+
+// sigAlgIdentifiers maps the allowed signature algorithms to their
+// AlgorithmIdentifiers and the hash used to compute the signed digest
+var sigAlgIdentifiers = map[x509.SignatureAlgorithm]struct {
+	id   pkix.AlgorithmIdentifier
+	hash crypto.Hash
+}{
+	x509.SHA256WithRSA:   {pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}, Parameters: asn1.NullRawValue}, crypto.SHA256},
+	x509.SHA384WithRSA:   {pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}, Parameters: asn1.NullRawValue}, crypto.SHA384},
+	x509.SHA512WithRSA:   {pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}, Parameters: asn1.NullRawValue}, crypto.SHA512},
+	x509.ECDSAWithSHA256: {pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}}, crypto.SHA256},
+	x509.ECDSAWithSHA384: {pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}}, crypto.SHA384},
+	x509.ECDSAWithSHA512: {pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}}, crypto.SHA512},
+}
+
+func verifyCRLProfile(profile CRLProfile) error {
+	if profile.SignatureAlgorithm == "" {
+		return errors.New("SignatureAlgorithm in CRL profile is required")
+	}
+	if profile.ThisUpdate == "" {
+		return errors.New("ThisUpdate in CRL profile is required")
+	}
+	if profile.NextUpdate == "" {
+		return errors.New("NextUpdate in CRL profile is required")
+	}
+	if profile.Number <= 0 {
+		return errors.New("Number in CRL profile must be positive")
+	}
+	for _, rc := range profile.RevokedCertificates {
+		if rc.CertificatePath == "" || rc.RevocationDate == "" {
+			return errors.New("CertificatePath and RevocationDate are required for each revoked certificate")
+		}
+		if rc.RevocationReason < 0 || rc.RevocationReason > 10 || rc.RevocationReason == 7 {
+			return fmt.Errorf("invalid revocation reason %d", rc.RevocationReason)
+		}
+	}
+	return nil
+}
+
+// makeCRL generates a CRL, signed by signer, for the certificates in profile
+// that were issued by issuer. x509.Certificate.CreateCRL isn't used because
+// it can't include a CRL number or reason codes, which RFC 5280 requires.
+func makeCRL(profile *CRLProfile, issuer *x509.Certificate, signer crypto.Signer) ([]byte, error) {
+	alg, ok := sigAlgIdentifiers[AllowedSigAlgs[profile.SignatureAlgorithm]]
+	if !ok {
+		return nil, fmt.Errorf("unsupported signature algorithm %q", profile.SignatureAlgorithm)
+	}
+	dateLayout := "2006-01-02 15:04:05"
+	thisUpdate, err := time.Parse(dateLayout, profile.ThisUpdate)
+	if err != nil {
+		return nil, err
+	}
+	nextUpdate, err := time.Parse(dateLayout, profile.NextUpdate)
+	if err != nil {
+		return nil, err
+	}
+	if !nextUpdate.After(thisUpdate) {
+		return nil, errors.New("NextUpdate must be after ThisUpdate")
+	}
+
+	var revoked []pkix.RevokedCertificate
+	for _, rc := range profile.RevokedCertificates {
+		cert, err := loadCertificate(rc.CertificatePath)
+		if err != nil {
+			return nil, err
+		}
+		if err := cert.CheckSignatureFrom(issuer); err != nil {
+			return nil, fmt.Errorf("certificate %q wasn't issued by the CRL issuer: %s", rc.CertificatePath, err)
+		}
+		revocationDate, err := time.Parse(dateLayout, rc.RevocationDate)
+		if err != nil {
+			return nil, err
+		}
+		entry := pkix.RevokedCertificate{
+			SerialNumber:   cert.SerialNumber,
+			RevocationTime: revocationDate,
+		}
+		if rc.RevocationReason != 0 {
+			reason, err := asn1.Marshal(asn1.Enumerated(rc.RevocationReason))
+			if err != nil {
+				return nil, err
+			}
+			entry.Extensions = []pkix.Extension{{Id: oidExtensionReasonCode, Value: reason}}
+		}
+		revoked = append(revoked, entry)
+	}
+
+	aki, err := asn1.Marshal(struct {
+		ID []byte `asn1:"optional,tag:0"`
+	}{issuer.SubjectKeyId})
+	if err != nil {
+		return nil, err
+	}
+	number, err := asn1.Marshal(big.NewInt(profile.Number))
+	if err != nil {
+		return nil, err
+	}
+	tbs := pkix.TBSCertificateList{
+		Version:             1, // v2
+		Signature:           alg.id,
+		Issuer:              issuer.Subject.ToRDNSequence(),
+		ThisUpdate:          thisUpdate,
+		NextUpdate:          nextUpdate,
+		RevokedCertificates: revoked,
+		Extensions: []pkix.Extension{
+			{Id: oidExtensionAuthorityKeyID, Value: aki},
+			{Id: oidExtensionCRLNumber, Value: number},
+		},
+	}
+	tbsBytes, err := asn1.Marshal(tbs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal CRL: %s", err)
+	}
+	h := alg.hash.New()
+	h.Write(tbsBytes)
+	signature, err := signer.Sign(&failReader{}, h.Sum(nil), alg.hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign CRL: %s", err)
+	}
+	crlBytes, err := asn1.Marshal(pkix.CertificateList{
+		TBSCertList:        tbs,
+		SignatureAlgorithm: alg.id,
+		SignatureValue:     asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal signed CRL: %s", err)
+	}
+	log.Printf("Signed CRL: %x\n", crlBytes)
+
+	crl, err := x509.ParseCRL(crlBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signed CRL: %s", err)
+	}
+	if err := issuer.CheckCRLSignature(crl); err != nil {
+		return nil, fmt.Errorf("failed to verify CRL signature: %s", err)
+	}
+	log.Println("Verified CRL signature")
+
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: crlBytes})
+	log.Printf("CRL PEM:\n%s", pemBytes)
+	return pemBytes, nil
+}
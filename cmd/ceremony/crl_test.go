@@ -0,0 +1,146 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+// hsmLikeSigner wraps a software key to ignore the io.Reader passed to Sign,
+// like x509Signer, which gets its randomness from the HSM.
+type hsmLikeSigner struct {
+	*ecdsa.PrivateKey
+}
+
+func (s hsmLikeSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.PrivateKey.Sign(rand.Reader, digest, opts)
+}
+
+// writeTestCert issues a certificate for a new key with issuerKey, or
+// self-signs it if issuer is nil, and writes it to a temporary file.
+func writeTestCert(t *testing.T, serial int64, issuer *x509.Certificate, issuerKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, string) {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "Failed to generate test key")
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "test", Organization: []string{"test"}, Country: []string{"US"}},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		SubjectKeyId:          []byte{byte(serial)},
+	}
+	if issuer == nil {
+		issuer, issuerKey = template, k
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, k.Public(), issuerKey)
+	test.AssertNotError(t, err, "Failed to create test certificate")
+	cert, err := x509.ParseCertificate(der)
+	test.AssertNotError(t, err, "Failed to parse test certificate")
+	f, err := ioutil.TempFile("", "ceremony-cert")
+	test.AssertNotError(t, err, "Failed to create temporary file")
+	defer f.Close()
+	err = pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	test.AssertNotError(t, err, "Failed to write test certificate")
+	return cert, k, f.Name()
+}
+
+func TestVerifyCRLProfile(t *testing.T) {
+	valid := CRLProfile{
+		SignatureAlgorithm: "ECDSAWithSHA256",
+		ThisUpdate:         "2019-01-01 00:00:00",
+		NextUpdate:         "2019-02-01 00:00:00",
+		Number:             1,
+		RevokedCertificates: []RevokedCertificate{
+			{CertificatePath: "a.pem", RevocationDate: "2019-01-01 00:00:00", RevocationReason: 1},
+		},
+	}
+	test.AssertNotError(t, verifyCRLProfile(valid), "verifyCRLProfile failed with a valid profile")
+
+	for _, modify := range []func(*CRLProfile){
+		func(p *CRLProfile) { p.SignatureAlgorithm = "" },
+		func(p *CRLProfile) { p.ThisUpdate = "" },
+		func(p *CRLProfile) { p.NextUpdate = "" },
+		func(p *CRLProfile) { p.Number = 0 },
+		func(p *CRLProfile) { p.RevokedCertificates[0].CertificatePath = "" },
+		func(p *CRLProfile) { p.RevokedCertificates[0].RevocationReason = 7 },
+		func(p *CRLProfile) { p.RevokedCertificates[0].RevocationReason = 11 },
+	} {
+		profile := valid
+		profile.RevokedCertificates = []RevokedCertificate{valid.RevokedCertificates[0]}
+		modify(&profile)
+		test.AssertError(t, verifyCRLProfile(profile), "verifyCRLProfile didn't fail with an invalid profile")
+	}
+}
+
+func TestMakeCRL(t *testing.T) {
+	issuer, issuerKey, issuerPath := writeTestCert(t, 1, nil, nil)
+	defer os.Remove(issuerPath)
+	_, _, revokedPath := writeTestCert(t, 2, issuer, issuerKey)
+	defer os.Remove(revokedPath)
+
+	profile := &CRLProfile{
+		SignatureAlgorithm: "ECDSAWithSHA256",
+		ThisUpdate:         "2019-01-01 00:00:00",
+		NextUpdate:         "2019-02-01 00:00:00",
+		Number:             5,
+		RevokedCertificates: []RevokedCertificate{
+			{CertificatePath: revokedPath, RevocationDate: "2018-12-01 00:00:00", RevocationReason: 1},
+		},
+	}
+	pemBytes, err := makeCRL(profile, issuer, hsmLikeSigner{issuerKey})
+	test.AssertNotError(t, err, "makeCRL failed")
+	block, _ := pem.Decode(pemBytes)
+	test.Assert(t, block != nil && block.Type == "X509 CRL", "makeCRL didn't return a CRL PEM")
+	crl, err := x509.ParseDERCRL(block.Bytes)
+	test.AssertNotError(t, err, "Failed to parse CRL")
+	test.AssertNotError(t, issuer.CheckCRLSignature(crl), "CRL signature doesn't verify")
+	test.AssertEquals(t, crl.TBSCertList.Version, 1)
+	test.AssertEquals(t, crl.TBSCertList.NextUpdate, time.Date(2019, 2, 1, 0, 0, 0, 0, time.UTC))
+
+	test.AssertEquals(t, len(crl.TBSCertList.Extensions), 2)
+	var number *big.Int
+	_, err = asn1.Unmarshal(crl.TBSCertList.Extensions[1].Value, &number)
+	test.AssertNotError(t, err, "Failed to parse CRL number")
+	test.AssertEquals(t, number.Int64(), int64(5))
+
+	revoked := crl.TBSCertList.RevokedCertificates
+	test.AssertEquals(t, len(revoked), 1)
+	test.AssertEquals(t, revoked[0].SerialNumber.Int64(), int64(2))
+	test.AssertEquals(t, len(revoked[0].Extensions), 1)
+	var reason asn1.Enumerated
+	_, err = asn1.Unmarshal(revoked[0].Extensions[0].Value, &reason)
+	test.AssertNotError(t, err, "Failed to parse reason code")
+	test.AssertEquals(t, reason, asn1.Enumerated(1))
+
+	// Certificates from another issuer can't be included
+	_, _, otherPath := writeTestCert(t, 3, nil, nil)
+	defer os.Remove(otherPath)
+	profile.RevokedCertificates[0].CertificatePath = otherPath
+	_, err = makeCRL(profile, issuer, hsmLikeSigner{issuerKey})
+	test.AssertError(t, err, "makeCRL didn't fail with a certificate from another issuer")
+
+	profile.RevokedCertificates = nil
+	profile.NextUpdate = profile.ThisUpdate
+	_, err = makeCRL(profile, issuer, hsmLikeSigner{issuerKey})
+	test.AssertError(t, err, "makeCRL didn't fail with NextUpdate equal to ThisUpdate")
+
+	profile.NextUpdate = "2019-02-01 00:00:00"
+	profile.SignatureAlgorithm = "MD5WithRSA"
+	_, err = makeCRL(profile, issuer, hsmLikeSigner{issuerKey})
+	test.AssertError(t, err, "makeCRL didn't fail with an unsupported signature algorithm")
+}
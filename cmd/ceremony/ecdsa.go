@@ -4,7 +4,6 @@ import (
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
-	"crypto/rand"
 	"errors"
 	"fmt"
 	"log"
@@ -134,16 +133,11 @@ func ecVerify(ctx pkcs11helpers.PKCtx, session pkcs11.SessionHandle, object pkcs
 // ecGenerate is used to generate and verify a ECDSA key pair of the type
 // specified by curveStr and with the provided label. It returns the public
 // part of the generated key pair as a ecdsa.PublicKey.
-func ecGenerate(ctx pkcs11helpers.PKCtx, session pkcs11.SessionHandle, label, curveStr string) (*ecdsa.PublicKey, error) {
+func ecGenerate(ctx pkcs11helpers.PKCtx, session pkcs11.SessionHandle, label string, keyID []byte, curveStr string) (*ecdsa.PublicKey, error) {
 	curve, present := stringToCurve[curveStr]
 	if !present {
 		return nil, fmt.Errorf("curve %q not supported", curveStr)
 	}
-	keyID := make([]byte, 4)
-	_, err := rand.Read(keyID)
-	if err != nil {
-		return nil, err
-	}
 	log.Printf("Generating ECDSA key with curve %s and ID %x\n", curveStr, keyID)
 	args := ecArgs(label, curve, keyID)
 	pub, priv, err := ctx.GenerateKeyPair(session, args.mechanism, args.publicAttrs, args.privateAttrs)
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/letsencrypt/boulder/pkcs11helpers"
+	"github.com/miekg/pkcs11"
+)
+
+type generateArgs struct {
+	mechanism    []*pkcs11.Mechanism
+	privateAttrs []*pkcs11.Attribute
+	publicAttrs  []*pkcs11.Attribute
+}
+
+func getRandomBytes(ctx pkcs11helpers.PKCtx, session pkcs11.SessionHandle) ([]byte, error) {
+	r, err := ctx.GenerateRandom(session, 4)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// KeyConfig contains the information required to generate a key pair on the
+// HSM.
+type KeyConfig struct {
+	// Type is the type of key to generate, either "RSA" or "ECDSA"
+	Type string
+	// RSAModLength is the size of the RSA modulus in bits. Only used if Type
+	// is "RSA"
+	RSAModLength uint
+	// RSAPublicExponent is the public RSA exponent. Only used if Type is "RSA",
+	// and defaults to 65537
+	RSAPublicExponent uint
+	// ECDSACurve is the type of ECDSA curve to use (P-224, P-256, P-384 or
+	// P-521). Only used if Type is "ECDSA"
+	ECDSACurve string
+}
+
+func (kc KeyConfig) validate() error {
+	switch kc.Type {
+	case "RSA":
+		if kc.RSAModLength == 0 {
+			return errors.New("RSAModLength is required for RSA keys")
+		}
+	case "ECDSA":
+		if kc.ECDSACurve == "" {
+			return errors.New("ECDSACurve is required for ECDSA keys")
+		}
+	default:
+		return errors.New("key Type may only be RSA or ECDSA")
+	}
+	return nil
+}
+
+// generateKey generates a key pair on the HSM, following these steps:
+//   1. Constructs templates for the private and public keys consisting
+//      of the appropriate PKCS#11 attributes.
+//   2. Executes a PKCS#11 GenerateKeyPair operation with the constructed
+//      templates and either CKM_RSA_PKCS_KEY_PAIR_GEN or CKM_EC_KEY_PAIR_GEN.
+//   3. Extracts the public key components from the returned public key object
+//      handle and construct a Golang public key object from them.
+//   4. Generates 4 bytes of random data from the HSM using a PKCS#11 GenerateRandom
+//      operation.
+//   5. Signs the random data with the private key object handle using a PKCS#11
+//      SignInit/Sign operation.
+//   6. Verifies the returned signature of the random data with the constructed
+//      public key.
+// It returns the public key and the hex encoded ID of the generated key pair,
+// which together with the label identifies the key in later ceremonies.
+func generateKey(ctx pkcs11helpers.PKCtx, session pkcs11.SessionHandle, label string, config KeyConfig) (crypto.PublicKey, string, error) {
+	if err := config.validate(); err != nil {
+		return nil, "", err
+	}
+	keyID := make([]byte, 4)
+	_, err := rand.Read(keyID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var pubKey crypto.PublicKey
+	switch config.Type {
+	case "RSA":
+		exponent := config.RSAPublicExponent
+		if exponent == 0 {
+			exponent = 65537
+		}
+		pubKey, err = rsaGenerate(ctx, session, label, keyID, config.RSAModLength, exponent)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to generate RSA key pair: %s", err)
+		}
+	case "ECDSA":
+		pubKey, err = ecGenerate(ctx, session, label, keyID, config.ECDSACurve)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to generate ECDSA key pair: %s", err)
+		}
+	}
+	return pubKey, hex.EncodeToString(keyID), nil
+}
+
+// publicKeyPEM returns the PEM encoding of pubKey.
+func publicKeyPEM(pubKey crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public key: %s", err)
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	log.Printf("Public key PEM:\n%s\n", pemBytes)
+	return pemBytes, nil
+}
@@ -0,0 +1,312 @@
+// ceremony is a tool for the key ceremonies of a Boulder CA. Each run performs
+// one ceremony, described by a JSON config file:
+//
+// "root" generates a key pair on an HSM and a self-signed root certificate for
+// it.
+//
+// "key" generates a key pair on an HSM, e.g. for an intermediate, and writes
+// out its public key.
+//
+// "intermediate" signs a certificate for a public key with an existing key on
+// an HSM, e.g. a root key.
+//
+// "cross-certificate" signs a certificate with the same subject and key as an
+// existing certificate with an existing key on an HSM, so that the existing
+// certificate also chains to the signing key's certificate.
+//
+// "crl" signs a CRL with an existing key on an HSM.
+//
+// Ceremonies that generate keys can also write out a key attestation: a
+// statement that the key was generated on and can't be extracted from the HSM,
+// signed by the key. Any action the tool takes is thoroughly logged, so the
+// log can be kept as a record of the ceremony.
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"github.com/letsencrypt/boulder/pkcs11helpers"
+	"github.com/miekg/pkcs11"
+)
+
+// PKCS11Config identifies the HSM key used by a ceremony.
+type PKCS11Config struct {
+	// Module is the path to the PKCS#11 module to use
+	Module string
+	// PIN is the PKCS#11 token PIN. If empty, PED based login is assumed.
+	PIN string
+	// Slot is the slot containing the token with the key
+	Slot uint
+	// Label is the label of the key, or of the key to generate
+	Label string
+	// KeyID is the hex encoded ID of the key (simplified format, i.e. ffff).
+	// It is required by ceremonies that sign with an existing key.
+	KeyID string
+}
+
+// InputsConfig contains the paths of the files a ceremony reads.
+type InputsConfig struct {
+	// PublicKeyPath is the PEM public key to issue an intermediate for
+	PublicKeyPath string
+	// IssuerCertificatePath is the PEM certificate of the signing key
+	IssuerCertificatePath string
+	// CertificateToCrossSignPath is the PEM certificate to cross-sign
+	CertificateToCrossSignPath string
+}
+
+// OutputsConfig contains the paths of the files a ceremony writes.
+type OutputsConfig struct {
+	PublicKeyPath   string
+	CertificatePath string
+	CRLPath         string
+	// AttestationPath, if set, is where ceremonies that generate a key write
+	// its attestation
+	AttestationPath string
+}
+
+type ceremonyConfig struct {
+	// CeremonyType is one of "root", "key", "intermediate",
+	// "cross-certificate" or "crl"
+	CeremonyType string
+	PKCS11       PKCS11Config
+	// Key describes the key to generate for "root" and "key" ceremonies
+	Key     KeyConfig
+	Inputs  InputsConfig
+	Outputs OutputsConfig
+	// CertProfile describes the certificate for "root", "intermediate" and
+	// "cross-certificate" ceremonies. See CertProfile for details.
+	CertProfile *CertProfile
+	// CRLProfile describes the CRL for "crl" ceremonies
+	CRLProfile *CRLProfile
+}
+
+// validate checks that the config has everything its ceremony needs.
+func (c ceremonyConfig) validate() error {
+	if c.PKCS11.Module == "" {
+		return errors.New("PKCS11.Module is required")
+	}
+	if c.PKCS11.Label == "" {
+		return errors.New("PKCS11.Label is required")
+	}
+	type requirement struct {
+		name  string
+		isSet bool
+	}
+	var required []requirement
+	switch c.CeremonyType {
+	case "root":
+		required = []requirement{
+			{"Outputs.PublicKeyPath", c.Outputs.PublicKeyPath != ""},
+			{"Outputs.CertificatePath", c.Outputs.CertificatePath != ""},
+			{"CertProfile", c.CertProfile != nil},
+		}
+	case "key":
+		required = []requirement{
+			{"Outputs.PublicKeyPath", c.Outputs.PublicKeyPath != ""},
+		}
+	case "intermediate":
+		required = []requirement{
+			{"PKCS11.KeyID", c.PKCS11.KeyID != ""},
+			{"Inputs.PublicKeyPath", c.Inputs.PublicKeyPath != ""},
+			{"Inputs.IssuerCertificatePath", c.Inputs.IssuerCertificatePath != ""},
+			{"Outputs.CertificatePath", c.Outputs.CertificatePath != ""},
+			{"CertProfile", c.CertProfile != nil},
+		}
+	case "cross-certificate":
+		required = []requirement{
+			{"PKCS11.KeyID", c.PKCS11.KeyID != ""},
+			{"Inputs.IssuerCertificatePath", c.Inputs.IssuerCertificatePath != ""},
+			{"Inputs.CertificateToCrossSignPath", c.Inputs.CertificateToCrossSignPath != ""},
+			{"Outputs.CertificatePath", c.Outputs.CertificatePath != ""},
+			{"CertProfile", c.CertProfile != nil},
+		}
+	case "crl":
+		required = []requirement{
+			{"PKCS11.KeyID", c.PKCS11.KeyID != ""},
+			{"Inputs.IssuerCertificatePath", c.Inputs.IssuerCertificatePath != ""},
+			{"Outputs.CRLPath", c.Outputs.CRLPath != ""},
+			{"CRLProfile", c.CRLProfile != nil},
+		}
+	default:
+		return fmt.Errorf("unknown CeremonyType %q", c.CeremonyType)
+	}
+	for _, r := range required {
+		if !r.isSet {
+			return fmt.Errorf("%s is required for %s ceremonies", r.name, c.CeremonyType)
+		}
+	}
+
+	if c.CeremonyType == "root" || c.CeremonyType == "key" {
+		if err := c.Key.validate(); err != nil {
+			return err
+		}
+	}
+	if c.CertProfile != nil {
+		if err := verifyProfile(*c.CertProfile, c.CeremonyType == "root"); err != nil {
+			return err
+		}
+	}
+	if c.CRLProfile != nil {
+		if err := verifyCRLProfile(*c.CRLProfile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(path string, contents []byte, description string) {
+	if err := ioutil.WriteFile(path, contents, 0644); err != nil {
+		log.Fatalf("Failed to write %s to %q: %s", description, path, err)
+	}
+	log.Printf("%s written to %q\n", description, path)
+}
+
+// generateCeremonyKey runs the key generation shared by "root" and "key"
+// ceremonies, writing out the public key and attestation, and returns a
+// signer for the new key.
+func generateCeremonyKey(ctx pkcs11helpers.PKCtx, session pkcs11.SessionHandle, c ceremonyConfig) *x509Signer {
+	pubKey, keyID, err := generateKey(ctx, session, c.PKCS11.Label, c.Key)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Generated key with label %q and ID %s\n", c.PKCS11.Label, keyID)
+	pemBytes, err := publicKeyPEM(pubKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+	writeFile(c.Outputs.PublicKeyPath, pemBytes, "Public key")
+
+	signer, err := getKey(ctx, session, c.PKCS11.Label, keyID)
+	if err != nil {
+		log.Fatalf("Failed to retrieve generated key: %s", err)
+	}
+	if c.Outputs.AttestationPath != "" {
+		attestation, err := attestKey(ctx, c.PKCS11.Slot, c.PKCS11.Label, keyID, signer, time.Now())
+		if err != nil {
+			log.Fatalf("Failed to attest key: %s", err)
+		}
+		writeFile(c.Outputs.AttestationPath, attestation, "Key attestation")
+	}
+	return signer
+}
+
+// signingKey retrieves the existing HSM key used by "intermediate",
+// "cross-certificate" and "crl" ceremonies, along with its certificate.
+func signingKey(ctx pkcs11helpers.PKCtx, session pkcs11.SessionHandle, c ceremonyConfig) (*x509Signer, *x509.Certificate) {
+	signer, err := getKey(ctx, session, c.PKCS11.Label, c.PKCS11.KeyID)
+	if err != nil {
+		log.Fatalf("Failed to retrieve signing key: %s", err)
+	}
+	log.Println("Retrieved signing key")
+	issuer, err := loadCertificate(c.Inputs.IssuerCertificatePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	issuerPub, err := x509.MarshalPKIXPublicKey(issuer.PublicKey)
+	if err != nil {
+		log.Fatalf("Failed to marshal issuer public key: %s", err)
+	}
+	signerPub, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		log.Fatalf("Failed to marshal signing public key: %s", err)
+	}
+	if !bytes.Equal(issuerPub, signerPub) {
+		log.Fatal("Issuer certificate doesn't match the signing key")
+	}
+	return signer, issuer
+}
+
+func main() {
+	configPath := flag.String("config", "", "Path to the ceremony config file in JSON format. See https://godoc.org/github.com/letsencrypt/boulder/cmd/ceremony for details.")
+	flag.Parse()
+
+	if *configPath == "" {
+		log.Fatal("--config is required")
+	}
+	configBytes, err := ioutil.ReadFile(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to read config %q: %s", *configPath, err)
+	}
+	var c ceremonyConfig
+	err = json.Unmarshal(configBytes, &c)
+	if err != nil {
+		log.Fatalf("Failed to parse config: %s", err)
+	}
+	if err := c.validate(); err != nil {
+		log.Fatalf("Invalid config: %s", err)
+	}
+
+	ctx, session, err := pkcs11helpers.Initialize(c.PKCS11.Module, c.PKCS11.Slot, c.PKCS11.PIN)
+	if err != nil {
+		log.Fatalf("Failed to setup session and PKCS#11 context: %s", err)
+	}
+	log.Println("Opened PKCS#11 session")
+
+	switch c.CeremonyType {
+	case "key":
+		generateCeremonyKey(ctx, session, c)
+	case "root":
+		signer := generateCeremonyKey(ctx, session, c)
+		pubDER, err := x509.MarshalPKIXPublicKey(signer.Public())
+		if err != nil {
+			log.Fatalf("Failed to marshal public key: %s", err)
+		}
+		template, err := makeTemplate(ctx, c.CertProfile, pubDER, session)
+		if err != nil {
+			log.Fatalf("Failed to construct certificate template from profile: %s", err)
+		}
+		log.Println("Generated certificate template from profile")
+		certPEM, err := signCertificate(template, nil, signer.Public(), signer)
+		if err != nil {
+			log.Fatal(err)
+		}
+		writeFile(c.Outputs.CertificatePath, certPEM, "Certificate")
+	case "intermediate", "cross-certificate":
+		signer, issuer := signingKey(ctx, session, c)
+		var template *x509.Certificate
+		var pub crypto.PublicKey
+		if c.CeremonyType == "intermediate" {
+			var pubDER []byte
+			pub, pubDER, err = loadPublicKey(c.Inputs.PublicKeyPath)
+			if err != nil {
+				log.Fatal(err)
+			}
+			template, err = makeTemplate(ctx, c.CertProfile, pubDER, session)
+		} else {
+			var toCrossSign *x509.Certificate
+			toCrossSign, err = loadCertificate(c.Inputs.CertificateToCrossSignPath)
+			if err != nil {
+				log.Fatal(err)
+			}
+			pub = toCrossSign.PublicKey
+			template, err = makeCrossTemplate(ctx, c.CertProfile, toCrossSign, session)
+		}
+		if err != nil {
+			log.Fatalf("Failed to construct certificate template from profile: %s", err)
+		}
+		log.Println("Generated certificate template from profile")
+		certPEM, err := signCertificate(template, issuer, pub, signer)
+		if err != nil {
+			log.Fatal(err)
+		}
+		writeFile(c.Outputs.CertificatePath, certPEM, "Certificate")
+	case "crl":
+		signer, issuer := signingKey(ctx, session, c)
+		crlPEM, err := makeCRL(c.CRLProfile, issuer, signer)
+		if err != nil {
+			log.Fatal(err)
+		}
+		writeFile(c.Outputs.CRLPath, crlPEM, "CRL")
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestCeremonyConfigValidate(t *testing.T) {
+	certProfile := &CertProfile{
+		SignatureAlgorithm: "ECDSAWithSHA256",
+		CommonName:         "CN",
+		Organization:       "O",
+		Country:            "C",
+		NotBefore:          "2019-01-01 00:00:00",
+		NotAfter:           "2039-01-01 00:00:00",
+		OCSPURL:            "http://ocsp",
+		CRLURL:             "http://crl",
+		IssuerURL:          "http://issuer",
+	}
+	pkcs11Config := PKCS11Config{Module: "module", Label: "label", KeyID: "ffff"}
+	testCases := []struct {
+		name  string
+		c     ceremonyConfig
+		valid bool
+	}{
+		{
+			name: "valid root",
+			c: ceremonyConfig{
+				CeremonyType: "root",
+				PKCS11:       PKCS11Config{Module: "module", Label: "label"},
+				Key:          KeyConfig{Type: "ECDSA", ECDSACurve: "P-384"},
+				Outputs:      OutputsConfig{PublicKeyPath: "pub.pem", CertificatePath: "cert.pem"},
+				CertProfile:  certProfile,
+			},
+			valid: true,
+		},
+		{
+			name: "root without key type",
+			c: ceremonyConfig{
+				CeremonyType: "root",
+				PKCS11:       PKCS11Config{Module: "module", Label: "label"},
+				Outputs:      OutputsConfig{PublicKeyPath: "pub.pem", CertificatePath: "cert.pem"},
+				CertProfile:  certProfile,
+			},
+		},
+		{
+			name: "valid key",
+			c: ceremonyConfig{
+				CeremonyType: "key",
+				PKCS11:       PKCS11Config{Module: "module", Label: "label"},
+				Key:          KeyConfig{Type: "RSA", RSAModLength: 2048},
+				Outputs:      OutputsConfig{PublicKeyPath: "pub.pem"},
+			},
+			valid: true,
+		},
+		{
+			name: "valid intermediate",
+			c: ceremonyConfig{
+				CeremonyType: "intermediate",
+				PKCS11:       pkcs11Config,
+				Inputs:       InputsConfig{PublicKeyPath: "pub.pem", IssuerCertificatePath: "root.pem"},
+				Outputs:      OutputsConfig{CertificatePath: "cert.pem"},
+				CertProfile:  certProfile,
+			},
+			valid: true,
+		},
+		{
+			name: "intermediate without key ID",
+			c: ceremonyConfig{
+				CeremonyType: "intermediate",
+				PKCS11:       PKCS11Config{Module: "module", Label: "label"},
+				Inputs:       InputsConfig{PublicKeyPath: "pub.pem", IssuerCertificatePath: "root.pem"},
+				Outputs:      OutputsConfig{CertificatePath: "cert.pem"},
+				CertProfile:  certProfile,
+			},
+		},
+		{
+			name: "intermediate without OCSP URL",
+			c: ceremonyConfig{
+				CeremonyType: "intermediate",
+				PKCS11:       pkcs11Config,
+				Inputs:       InputsConfig{PublicKeyPath: "pub.pem", IssuerCertificatePath: "root.pem"},
+				Outputs:      OutputsConfig{CertificatePath: "cert.pem"},
+				CertProfile:  &CertProfile{SignatureAlgorithm: "ECDSAWithSHA256", CommonName: "CN", Organization: "O", Country: "C", NotBefore: "a", NotAfter: "b"},
+			},
+		},
+		{
+			name: "cross-certificate without certificate to cross-sign",
+			c: ceremonyConfig{
+				CeremonyType: "cross-certificate",
+				PKCS11:       pkcs11Config,
+				Inputs:       InputsConfig{IssuerCertificatePath: "root.pem"},
+				Outputs:      OutputsConfig{CertificatePath: "cert.pem"},
+				CertProfile:  certProfile,
+			},
+		},
+		{
+			name: "valid crl",
+			c: ceremonyConfig{
+				CeremonyType: "crl",
+				PKCS11:       pkcs11Config,
+				Inputs:       InputsConfig{IssuerCertificatePath: "root.pem"},
+				Outputs:      OutputsConfig{CRLPath: "crl.pem"},
+				CRLProfile:   &CRLProfile{SignatureAlgorithm: "ECDSAWithSHA256", ThisUpdate: "a", NextUpdate: "b", Number: 1},
+			},
+			valid: true,
+		},
+		{
+			name: "crl without profile",
+			c: ceremonyConfig{
+				CeremonyType: "crl",
+				PKCS11:       pkcs11Config,
+				Inputs:       InputsConfig{IssuerCertificatePath: "root.pem"},
+				Outputs:      OutputsConfig{CRLPath: "crl.pem"},
+			},
+		},
+		{
+			name: "unknown ceremony type",
+			c:    ceremonyConfig{CeremonyType: "ocsp", PKCS11: pkcs11Config},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.c.validate()
+			if tc.valid {
+				test.AssertNotError(t, err, "validate failed with a valid config")
+			} else {
+				test.AssertError(t, err, "validate didn't fail with an invalid config")
+			}
+		})
+	}
+}
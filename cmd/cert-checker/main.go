@@ -2,14 +2,20 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
+	stdx509 "crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log/syslog"
 	"os"
 	"reflect"
 	"regexp"
 	"runtime"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -23,6 +29,7 @@ import (
 	"github.com/letsencrypt/boulder/cmd"
 	"github.com/letsencrypt/boulder/core"
 	"github.com/letsencrypt/boulder/features"
+	"github.com/letsencrypt/boulder/goodkey"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
 	"github.com/letsencrypt/boulder/policy"
@@ -79,6 +86,10 @@ func (r *report) dump() error {
 type reportEntry struct {
 	Valid    bool     `json:"valid"`
 	Problems []string `json:"problems,omitempty"`
+	// RegistrationID and Expires are only included in policy drift reports,
+	// where they are needed to scope an incident and contact subscribers.
+	RegistrationID int64      `json:"registrationID,omitempty"`
+	Expires        *time.Time `json:"expires,omitempty"`
 }
 
 /*
@@ -100,6 +111,12 @@ type certChecker struct {
 	issuedReport report
 	checkPeriod  time.Duration
 	stats        metrics.Scope
+	// When policyDrift is true, certificates are only checked against the
+	// current issuance policy (hostname policy, key policy and blocked keys)
+	// rather than against all of the usual cert-checker checks.
+	policyDrift bool
+	keyPolicy   goodkey.KeyPolicy
+	blockedKeys map[string]bool
 }
 
 func newChecker(saDbMap certDB, clk clock.Clock, pa core.PolicyAuthority, period time.Duration) certChecker {
@@ -166,14 +183,25 @@ func (c *certChecker) getCerts(unexpiredOnly bool) error {
 
 func (c *certChecker) processCerts(wg *sync.WaitGroup, badResultsOnly bool) {
 	for cert := range c.certs {
-		problems := c.checkCert(cert)
+		var problems []string
+		if c.policyDrift {
+			problems = c.checkPolicyDrift(cert)
+		} else {
+			problems = c.checkCert(cert)
+		}
 		valid := len(problems) == 0
 		c.rMu.Lock()
 		if !badResultsOnly || (badResultsOnly && !valid) {
-			c.issuedReport.Entries[cert.Serial] = reportEntry{
+			entry := reportEntry{
 				Valid:    valid,
 				Problems: problems,
 			}
+			if c.policyDrift {
+				expires := cert.Expires
+				entry.RegistrationID = cert.RegistrationID
+				entry.Expires = &expires
+			}
+			c.issuedReport.Entries[cert.Serial] = entry
 		}
 		c.rMu.Unlock()
 		if !valid {
@@ -303,6 +331,73 @@ func (c *certChecker) checkCert(cert core.Certificate) (problems []string) {
 	return problems
 }
 
+// newKeyPolicy returns the key policy the CA and RA enforce when configured
+// with the same weak key file, Fermat factorization rounds and key policy.
+func newKeyPolicy(weakKeyFile string, fermatRounds int, config goodkey.Config) (goodkey.KeyPolicy, error) {
+	kp, err := goodkey.NewKeyPolicy(weakKeyFile)
+	if err != nil {
+		return kp, err
+	}
+	kp.FermatRounds = fermatRounds
+	if err := config.Apply(&kp); err != nil {
+		return kp, err
+	}
+	return kp, nil
+}
+
+// loadBlockedKeys reads a file of hex encoded SHA-256 hashes of
+// SubjectPublicKeyInfo, one per line. Blank lines and lines starting with "#"
+// are ignored.
+func loadBlockedKeys(filename string) (map[string]bool, error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	blocked := make(map[string]bool)
+	for _, line := range strings.Split(string(contents), "\n") {
+		line = strings.ToLower(strings.TrimSpace(line))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if b, err := hex.DecodeString(line); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("invalid blocked key hash %q in %q", line, filename)
+		}
+		blocked[line] = true
+	}
+	return blocked, nil
+}
+
+// checkPolicyDrift returns the reasons, if any, that cert would not be issued
+// under the current policy: names the PA is no longer willing to issue for,
+// and keys that the key policy or the blocked keys list now reject.
+func (c *certChecker) checkPolicyDrift(cert core.Certificate) (problems []string) {
+	// The key policy works on crypto/x509 public keys rather than the zcrypto
+	// ones used by checkCert.
+	parsedCert, err := stdx509.ParseCertificate(cert.DER)
+	if err != nil {
+		return []string{fmt.Sprintf("Couldn't parse stored certificate: %s", err)}
+	}
+	names := core.UniqueLowerNames(append(parsedCert.DNSNames, parsedCert.Subject.CommonName))
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		id := core.AcmeIdentifier{Type: core.IdentifierDNS, Value: name}
+		if err := c.pa.WillingToIssueWildcard(id); err != nil {
+			problems = append(problems, fmt.Sprintf("Policy Authority is no longer willing to issue for '%s': %s", name, err))
+		}
+	}
+	if err := c.keyPolicy.GoodKey(parsedCert.PublicKey); err != nil {
+		problems = append(problems, fmt.Sprintf("Key policy no longer accepts public key: %s", err))
+	}
+	spkiHash := sha256.Sum256(parsedCert.RawSubjectPublicKeyInfo)
+	if c.blockedKeys[hex.EncodeToString(spkiHash[:])] {
+		problems = append(problems, "Public key is on the blocked keys list")
+	}
+	return problems
+}
+
 type config struct {
 	CertChecker struct {
 		cmd.DBConfig
@@ -314,6 +409,15 @@ type config struct {
 		BadResultsOnly      bool
 		CheckPeriod         cmd.ConfigDuration
 
+		// WeakKeyFile, FermatRounds, KeyPolicy and BlockedKeysFile are used by
+		// the policy drift check. The first three should match the CA and RA
+		// key policy configuration. BlockedKeysFile contains hex encoded
+		// SHA-256 hashes of SubjectPublicKeyInfo, one per line.
+		WeakKeyFile     string
+		FermatRounds    int
+		KeyPolicy       goodkey.Config
+		BlockedKeysFile string
+
 		Features map[string]bool
 	}
 
@@ -331,6 +435,7 @@ func main() {
 	connect := flag.String("db-connect", "", "SQL URI if not provided in the configuration file")
 	cp := flag.Duration("check-period", time.Hour*2160, "How far back to check")
 	unexpiredOnly := flag.Bool("unexpired-only", false, "Only check currently unexpired certificates")
+	policyDrift := flag.Bool("policy-drift", false, "Report unexpired certificates that would not be issued under the current hostname and key policies")
 
 	flag.Parse()
 	if *configFile == "" {
@@ -361,6 +466,14 @@ func main() {
 	config.CertChecker.UnexpiredOnly = *unexpiredOnly
 	config.CertChecker.BadResultsOnly = *badResultsOnly
 	config.CertChecker.CheckPeriod.Duration = *cp
+	if *policyDrift {
+		// Every unexpired certificate needs to be re-evaluated, however long
+		// ago it was issued.
+		config.CertChecker.UnexpiredOnly = true
+		if config.CertChecker.CheckPeriod.Duration < expectedValidityPeriod {
+			config.CertChecker.CheckPeriod.Duration = expectedValidityPeriod
+		}
+	}
 
 	// Validate PA config and set defaults if needed
 	cmd.FailOnError(config.PA.CheckChallenges(), "Invalid PA configuration")
@@ -374,6 +487,7 @@ func main() {
 
 	pa, err := policy.New(config.PA.Challenges)
 	cmd.FailOnError(err, "Failed to create PA")
+	pa.SetAllowReservedNames(config.PA.AllowReservedNames)
 	err = pa.SetHostnamePolicyFile(config.CertChecker.HostnamePolicyFile)
 	cmd.FailOnError(err, "Failed to load HostnamePolicyFile")
 
@@ -383,6 +497,15 @@ func main() {
 		pa,
 		config.CertChecker.CheckPeriod.Duration,
 	)
+	if *policyDrift {
+		checker.policyDrift = true
+		checker.keyPolicy, err = newKeyPolicy(config.CertChecker.WeakKeyFile, config.CertChecker.FermatRounds, config.CertChecker.KeyPolicy)
+		cmd.FailOnError(err, "Unable to create key policy")
+		if config.CertChecker.BlockedKeysFile != "" {
+			checker.blockedKeys, err = loadBlockedKeys(config.CertChecker.BlockedKeysFile)
+			cmd.FailOnError(err, "Failed to load BlockedKeysFile")
+		}
+	}
 	fmt.Fprintf(os.Stderr, "# Getting certificates issued in the last %s\n", config.CertChecker.CheckPeriod)
 
 	// Since we grab certificates in batches we don't want this to block, when it
@@ -3,13 +3,18 @@ package main
 import (
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
+	"encoding/hex"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"math/big"
 	mrand "math/rand"
+	"os"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -18,6 +23,7 @@ import (
 	"golang.org/x/net/context"
 
 	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/goodkey"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
 	"github.com/letsencrypt/boulder/policy"
@@ -411,3 +417,100 @@ func TestIsForbiddenDomain(t *testing.T) {
 		test.AssertEquals(t, result, tc.Expected)
 	}
 }
+
+func TestCheckPolicyDrift(t *testing.T) {
+	checker := newChecker(nil, clock.NewFake(), pa, expectedValidityPeriod)
+	checker.policyDrift = true
+	kp, err := goodkey.NewKeyPolicy("")
+	test.AssertNotError(t, err, "NewKeyPolicy failed")
+	checker.keyPolicy = kp
+
+	testKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rawCert := x509.Certificate{
+		Subject:      pkix.Name{CommonName: "example.com"},
+		NotAfter:     time.Now().Add(expectedValidityPeriod),
+		DNSNames:     []string{"example.com", "exactblacklist.letsencrypt.org"},
+		SerialNumber: big.NewInt(1337),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, &rawCert, &rawCert, &testKey.PublicKey, testKey)
+	test.AssertNotError(t, err, "Couldn't create certificate")
+	cert := core.Certificate{
+		RegistrationID: 7,
+		Serial:         core.SerialToString(rawCert.SerialNumber),
+		DER:            certDER,
+		Expires:        rawCert.NotAfter,
+	}
+
+	// Only the name that is now forbidden by the hostname policy is a problem
+	problems := checker.checkPolicyDrift(cert)
+	test.AssertEquals(t, len(problems), 1)
+	test.Assert(t, strings.Contains(problems[0], "exactblacklist.letsencrypt.org"), "Wrong problem: "+problems[0])
+
+	// Blocking the key adds another problem
+	parsed, err := x509.ParseCertificate(certDER)
+	test.AssertNotError(t, err, "Couldn't parse certificate")
+	spkiHash := sha256.Sum256(parsed.RawSubjectPublicKeyInfo)
+	checker.blockedKeys = map[string]bool{hex.EncodeToString(spkiHash[:]): true}
+	problems = checker.checkPolicyDrift(cert)
+	test.AssertEquals(t, len(problems), 2)
+	test.AssertEquals(t, problems[1], "Public key is on the blocked keys list")
+
+	// The report entry for a drifted certificate identifies its registration
+	// and expiry for outreach
+	checker.certs <- cert
+	close(checker.certs)
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+	checker.processCerts(wg, true)
+	entry := checker.issuedReport.Entries[cert.Serial]
+	test.AssertEquals(t, entry.Valid, false)
+	test.AssertEquals(t, entry.RegistrationID, int64(7))
+	test.Assert(t, entry.Expires.Equal(rawCert.NotAfter), "Wrong expiry in report entry")
+}
+
+func TestCheckPolicyDriftKeyPolicy(t *testing.T) {
+	checker := newChecker(nil, clock.NewFake(), pa, expectedValidityPeriod)
+	checker.policyDrift = true
+	kp, err := newKeyPolicy("", 100, goodkey.Config{RSAKeySizes: []int{3072, 4096}})
+	test.AssertNotError(t, err, "newKeyPolicy failed")
+	checker.keyPolicy = kp
+
+	testKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rawCert := x509.Certificate{
+		Subject:      pkix.Name{CommonName: "example.com"},
+		NotAfter:     time.Now().Add(expectedValidityPeriod),
+		DNSNames:     []string{"example.com"},
+		SerialNumber: big.NewInt(1337),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, &rawCert, &rawCert, &testKey.PublicKey, testKey)
+	test.AssertNotError(t, err, "Couldn't create certificate")
+	cert := core.Certificate{Serial: core.SerialToString(rawCert.SerialNumber), DER: certDER, Expires: rawCert.NotAfter}
+
+	// A key size the default policy allows but the configured one doesn't is
+	// a problem
+	problems := checker.checkPolicyDrift(cert)
+	test.AssertEquals(t, len(problems), 1)
+	test.Assert(t, strings.HasPrefix(problems[0], "Key policy no longer accepts public key"), "Wrong problem: "+problems[0])
+
+	_, err = newKeyPolicy("", 0, goodkey.Config{RSAKeySizes: []int{1024}})
+	test.AssertError(t, err, "newKeyPolicy accepted an invalid key policy")
+}
+
+func TestLoadBlockedKeys(t *testing.T) {
+	f, err := ioutil.TempFile("", "blocked-keys")
+	test.AssertNotError(t, err, "Couldn't create temp file")
+	defer func() { _ = os.Remove(f.Name()) }()
+	hash := strings.Repeat("ab", sha256.Size)
+	_, err = f.WriteString("# Debian weak keys\n\n" + strings.ToUpper(hash) + "\n")
+	test.AssertNotError(t, err, "Couldn't write temp file")
+	_ = f.Close()
+
+	blocked, err := loadBlockedKeys(f.Name())
+	test.AssertNotError(t, err, "loadBlockedKeys failed")
+	test.AssertDeepEquals(t, blocked, map[string]bool{hash: true})
+
+	err = ioutil.WriteFile(f.Name(), []byte("abcd\n"), 0600)
+	test.AssertNotError(t, err, "Couldn't write temp file")
+	_, err = loadBlockedKeys(f.Name())
+	test.AssertError(t, err, "loadBlockedKeys accepted a short hash")
+}
@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/faults"
+	"github.com/letsencrypt/boulder/reloader"
 )
 
 // PasswordConfig either contains a password or the path to a file
@@ -40,6 +42,41 @@ type ServiceConfig struct {
 	DebugAddr string
 	GRPC      *GRPCServerConfig
 	TLS       TLSConfig
+	Tracing   *TracingConfig
+	// FeaturesFile, if set, is a file containing a JSON object of feature
+	// flags in the same format as a service's Features config. The flags in it
+	// are applied on top of the configured Features and are reloaded whenever
+	// the file changes.
+	FeaturesFile string
+	// FeatureOperatorsFile, if set, is a file containing a JSON object mapping
+	// the names of the operators allowed to override feature flags through
+	// the debug server's /debug/features endpoint to the hex encoded SHA-256
+	// hashes of their passwords. If it isn't set no one is.
+	FeatureOperatorsFile string
+	// ShutdownStopTimeout is how long a service waits, after being signaled to
+	// stop, for in-flight requests and background work to finish before
+	// exiting anyway. Zero means wait indefinitely.
+	ShutdownStopTimeout ConfigDuration
+	// Faults are delays and failures to inject into this service's gRPC
+	// methods, database queries and HSM operations, for integration tests.
+	// Only binaries built with the 'integration' build tag can start with
+	// faults configured. See the faults package for details.
+	Faults []faults.Rule
+}
+
+// TracingConfig configures exporting distributed tracing spans to an
+// OpenTelemetry collector.
+type TracingConfig struct {
+	// Endpoint is the base URL of the collector's OTLP/HTTP receiver, e.g.
+	// "http://otel-collector:4318".
+	Endpoint string
+	// SampleRatio is the fraction of requests, between 0 and 1, that start a
+	// trace. Requests that are part of a trace started by another service are
+	// always traced.
+	SampleRatio float64
+	// ServiceName identifies this service in traces. Defaults to the name of
+	// the binary.
+	ServiceName string
 }
 
 // DBConfig defines how to connect to a database. The connect string may be
@@ -68,6 +105,53 @@ type SMTPConfig struct {
 	Server   string
 	Port     string
 	Username string
+
+	// API, if set, sends mail through a mail provider's HTTP API instead of
+	// the SMTP server.
+	API *MailAPIConfig
+	// DKIM, if set, adds a DKIM signature to outgoing mail.
+	DKIM *DKIMConfig
+}
+
+// MailAPIConfig configures sending mail through a mail provider's HTTP API.
+// The embedded PasswordConfig holds the API key, or for SES the secret access
+// key.
+type MailAPIConfig struct {
+	PasswordConfig
+	// Provider is "ses", "sendgrid" or "mailgun".
+	Provider string
+	// BaseURL overrides the provider's default API endpoint.
+	BaseURL string
+	// AccessKeyID and Region configure SES.
+	AccessKeyID string
+	Region      string
+	// Domain is the Mailgun sending domain.
+	Domain string
+	// MaxPerSecond limits how many messages are sent per second.
+	MaxPerSecond float64
+	// SuppressionFile holds addresses that have bounced, which mail is no
+	// longer sent to.
+	SuppressionFile string
+	// BounceWebhook, if set, receives the provider's bounce notifications
+	// and adds the addresses that bounced to SuppressionFile.
+	BounceWebhook *BounceWebhookConfig
+}
+
+// BounceWebhookConfig configures the HTTP server that receives a mail
+// provider's bounce notifications.
+type BounceWebhookConfig struct {
+	ListenAddress string
+	// SecretFile contains the secret that must be given in the "secret"
+	// query parameter of webhook requests.
+	SecretFile string
+}
+
+// DKIMConfig configures DKIM signing of outgoing mail. The public half of the
+// RSA key in KeyFile must be published at <Selector>._domainkey.<Domain>.
+type DKIMConfig struct {
+	Domain   string
+	Selector string
+	KeyFile  string
 }
 
 // PAConfig specifies how a policy authority should connect to its
@@ -78,12 +162,86 @@ type PAConfig struct {
 	EnforcePolicyWhitelist  bool
 	Challenges              map[string]bool
 	ChallengesWhitelistFile string
+	// ChallengesFile, if set, is a file containing a JSON object in the same
+	// format as Challenges. Once loaded it replaces Challenges, and it is
+	// reloaded whenever it changes. In it a challenge type may map to an
+	// object with "enableAt" and/or "disableAt" RFC 3339 times instead of a
+	// bool, to enable or disable it at a scheduled time.
+	ChallengesFile string
+	// ChallengeKillSwitchFile, if set, is a file of emergency kill switches
+	// for challenge types, reloaded whenever it changes. It's a JSON object
+	// mapping challenge types to objects with the "disabledBy" operator, a
+	// "reason", an optional "ticket" and a "sunset" RFC 3339 time after which
+	// the challenge type is no longer disabled. Challenge types it disables
+	// aren't offered to anyone, overriding Challenges and the whitelists.
+	ChallengeKillSwitchFile string
+	// RejectWildcardWithBaseDomain makes the RA reject new orders containing
+	// both a wildcard name, e.g. "*.example.com", and its base domain,
+	// "example.com".
+	RejectWildcardWithBaseDomain bool
+	// RejectWildcardWithSubdomains makes the RA reject new orders containing
+	// both a wildcard name and a name beneath its base domain that it doesn't
+	// cover, e.g. "*.example.com" and "www.shop.example.com". Names the
+	// wildcard covers are always rejected as redundant.
+	RejectWildcardWithSubdomains bool
+	// TokenFormat is the length and alphabet of the tokens generated for
+	// challenges. By default tokens are 32 random bytes, base64url encoded.
+	// It doesn't apply with the NewAuthorizationSchema feature, which stores
+	// tokens in the default format.
+	TokenFormat core.TokenFormat
+	// AllowReservedNames allows issuance for names under TLDs reserved for
+	// testing and documentation, like ".test" and ".example", and under ICANN
+	// name collision strings, like ".corp". They're rejected by default, and
+	// must only be allowed by private CAs that intentionally use them.
+	AllowReservedNames bool
 }
 
 // HostnamePolicyConfig specifies a file from which to load a policy regarding
 // what hostnames to issue for.
 type HostnamePolicyConfig struct {
 	HostnamePolicyFile string
+	// HostnamePolicyRemote, if set, fetches the hostname policy from a
+	// central HTTPS service instead of HostnamePolicyFile.
+	HostnamePolicyRemote *RemoteSourceConfig
+}
+
+// RemoteSourceConfig configures fetching a reloadable file from an HTTPS URL
+// instead of reading it from a local path.
+type RemoteSourceConfig struct {
+	URL string
+	// CACertFile is the path to a PEM bundle of the CA certificates trusted
+	// to issue the server's certificate. If empty, the system roots are used.
+	CACertFile string
+	// PinnedSPKIHashes are base64 encoded SHA-256 hashes of
+	// SubjectPublicKeyInfos, one of which must appear in the server's
+	// certificate chain.
+	PinnedSPKIHashes []string
+	// PollInterval is how often the URL is polled for changes. It defaults
+	// to one minute.
+	PollInterval ConfigDuration
+}
+
+// Load returns the reloader.RemoteSource the RemoteSourceConfig describes.
+func (rc *RemoteSourceConfig) Load() (*reloader.RemoteSource, error) {
+	if rc.URL == "" {
+		return nil, errors.New("remote source needs a URL")
+	}
+	source := &reloader.RemoteSource{
+		URL:              rc.URL,
+		PinnedSPKIHashes: rc.PinnedSPKIHashes,
+		PollInterval:     rc.PollInterval.Duration,
+	}
+	if rc.CACertFile != "" {
+		pemBytes, err := ioutil.ReadFile(rc.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		source.RootCAs = x509.NewCertPool()
+		if !source.RootCAs.AppendCertsFromPEM(pemBytes) {
+			return nil, fmt.Errorf("no CA certificates found in %q", rc.CACertFile)
+		}
+	}
+	return source, nil
 }
 
 // CheckChallenges checks whether the list of challenges in the PA config
@@ -105,10 +263,29 @@ type TLSConfig struct {
 	CertFile   *string
 	KeyFile    *string
 	CACertFile *string
+	// ReloadInterval is how often the files above are checked for changes.
+	// Changed files are used for new connections without restarting the
+	// service, so that certificates can be rotated in place. If zero, the
+	// files are only read at startup.
+	ReloadInterval ConfigDuration
+	// CARotationOverlap is how long CA certificates that are removed from
+	// CACertFile continue to be trusted after the change is reloaded. This
+	// allows rotating the internal CA one service at a time: peers that still
+	// present certificates issued by the old CA aren't rejected until the
+	// overlap ends. If zero, removed CA certificates stop being trusted as soon
+	// as the change is reloaded.
+	CARotationOverlap ConfigDuration
 }
 
 // Load reads and parses the certificates and key listed in the TLSConfig, and
 // returns a *tls.Config suitable for either client or server use.
+//
+// If ReloadInterval is set, the returned config's GetConfigForClient callback
+// returns a copy of the config holding the most recently loaded certificate,
+// key and CA bundle. Servers use it for every handshake and the gRPC client
+// credentials in the grpc package call it for every new connection. Configs
+// loaded from the same files share a single goroutine checking them for
+// changes, which runs until the process exits.
 func (t *TLSConfig) Load() (*tls.Config, error) {
 	if t == nil {
 		return nil, fmt.Errorf("nil TLS section in config")
@@ -122,25 +299,24 @@ func (t *TLSConfig) Load() (*tls.Config, error) {
 	if t.CACertFile == nil {
 		return nil, fmt.Errorf("nil CACertFile in TLSConfig")
 	}
-	caCertBytes, err := ioutil.ReadFile(*t.CACertFile)
+	r, err := loadTLSReloader(t)
 	if err != nil {
-		return nil, fmt.Errorf("reading CA cert from %q: %s", *t.CACertFile, err)
+		return nil, err
 	}
-	rootCAs := x509.NewCertPool()
-	if ok := rootCAs.AppendCertsFromPEM(caCertBytes); !ok {
-		return nil, fmt.Errorf("parsing CA certs from %s failed", *t.CACertFile)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	config := &tls.Config{
+		RootCAs:      r.pool,
+		ClientCAs:    r.pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		Certificates: []tls.Certificate{r.cert},
 	}
-	cert, err := tls.LoadX509KeyPair(*t.CertFile, *t.KeyFile)
-	if err != nil {
-		return nil, fmt.Errorf("loading key pair from %q and %q: %s",
-			*t.CertFile, *t.KeyFile, err)
+	if t.ReloadInterval.Duration > 0 {
+		config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.configFrom(config), nil
+		}
 	}
-	return &tls.Config{
-		RootCAs:      rootCAs,
-		ClientCAs:    rootCAs,
-		ClientAuth:   tls.RequireAndVerifyClientCert,
-		Certificates: []tls.Certificate{cert},
-	}, nil
+	return config, nil
 }
 
 // RPCServerConfig contains configuration particular to a specific RPC server
@@ -161,6 +337,10 @@ type OCSPUpdaterConfig struct {
 
 	OldOCSPBatchSize            int
 	RevokedCertificateBatchSize int
+	// RevokedCertificateSLA is how soon after a certificate is revoked its
+	// revoked OCSP response should be published. Responses are published in
+	// the order they're due, and those published late are counted.
+	RevokedCertificateSLA ConfigDuration
 
 	OCSPMinTimeToExpiry          ConfigDuration
 	OCSPStaleMaxAge              ConfigDuration
@@ -195,7 +375,20 @@ type GoogleSafeBrowsingConfig struct {
 // SyslogConfig defines the config for syslogging.
 type SyslogConfig struct {
 	StdoutLevel int
+	// SyslogLevel is the most verbose level sent to syslog, defaulting to
+	// LOG_INFO (6). If negative, syslog isn't used at all, which requires the
+	// JSON StdoutFormat.
 	SyslogLevel int
+	// StdoutFormat is either "text" (the default) for human readable lines or
+	// "json" for one JSON object per message, with the level, component,
+	// request ID and fields as separate keys, for log pipelines.
+	StdoutFormat string
+	// AuditSinks are where audit messages are delivered to in addition to
+	// syslog and stdout.
+	AuditSinks []AuditSinkConfig
+	// AuditQueueSize is the most audit messages queued for delivery to each
+	// audit sink. Defaults to 1000.
+	AuditQueueSize int
 }
 
 // StatsdConfig defines the config for Statsd.
@@ -254,8 +447,46 @@ func (d *ConfigDuration) UnmarshalYAML(unmarshal func(interface{}) error) error
 
 // GRPCClientConfig contains the information needed to talk to the gRPC service
 type GRPCClientConfig struct {
+	// ServerAddress is a host:port whose host is resolved using DNS. RPCs are
+	// balanced across every address it resolves to.
 	ServerAddress string
-	Timeout       ConfigDuration
+	// ServerAddresses is a static list of host:port backends, used instead of
+	// ServerAddress.
+	ServerAddresses []string
+	// HostOverride is the name the backends' certificates are verified
+	// against. Defaults to the host of ServerAddress, or of the first entry in
+	// ServerAddresses.
+	HostOverride string
+	Timeout      ConfigDuration
+	// SubsetSize, if non-zero, limits the number of backends this client sends
+	// RPCs to. Each client picks a different subset, spreading load evenly
+	// without every client using every backend. Backends that aren't reachable
+	// are replaced in the subset by the next available ones.
+	SubsetSize int
+	// RetryPolicies maps a gRPC service name (e.g. "sa.StorageAuthority") or
+	// method name (e.g. "sa.StorageAuthority/GetRegistration") to the policy
+	// used to retry its failed RPCs. Method names take precedence. Only
+	// configure policies for idempotent methods.
+	RetryPolicies map[string]GRPCRetryPolicy
+}
+
+// GRPCRetryPolicy controls how a client retries failed RPCs. Every attempt
+// shares the deadline set by the client's Timeout.
+type GRPCRetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first.
+	MaxAttempts int
+	// InitialBackoff is how long to wait before the first retry. The wait
+	// doubles for every subsequent retry, up to MaxBackoff.
+	InitialBackoff ConfigDuration
+	MaxBackoff     ConfigDuration
+	// RetryableStatusCodes lists the gRPC status codes (e.g. "UNAVAILABLE")
+	// that cause an RPC to be retried. Defaults to UNAVAILABLE.
+	RetryableStatusCodes []string
+	// HedgingDelay, if non-zero, hedges the RPC instead: a new attempt is
+	// started every HedgingDelay, or as soon as an attempt fails with a
+	// retryable code, without cancelling the attempts still in flight. The
+	// first successful response is used. Backoff settings are ignored.
+	HedgingDelay ConfigDuration
 }
 
 // GRPCServerConfig contains the information needed to run a gRPC service
@@ -279,6 +510,45 @@ type PortConfig struct {
 	HTTPPort  int
 	HTTPSPort int
 	TLSPort   int
+	// AllowNonstandardPorts must be set to use ports other than 80 (for
+	// HTTPPort) and 443 (for HTTPSPort and TLSPort), e.g. for private
+	// deployments behind port translation or for integration tests. It is
+	// never allowed in builds with the 'camode' build flag.
+	AllowNonstandardPorts bool
+}
+
+// WithDefaults returns a copy of the PortConfig with the standard port used in
+// place of any port that isn't set.
+func (pc PortConfig) WithDefaults() PortConfig {
+	if pc.HTTPPort == 0 {
+		pc.HTTPPort = 80
+	}
+	if pc.HTTPSPort == 0 {
+		pc.HTTPSPort = 443
+	}
+	if pc.TLSPort == 0 {
+		pc.TLSPort = 443
+	}
+	return pc
+}
+
+// Check returns an error if any port, after defaults are applied, is
+// nonstandard and nonstandard ports aren't allowed by both the config and the
+// build.
+func (pc PortConfig) Check() error {
+	pc = pc.WithDefaults()
+	if pc.HTTPPort == 80 && pc.HTTPSPort == 443 && pc.TLSPort == 443 {
+		return nil
+	}
+	if !nonstandardPortsBuildAllowed {
+		return fmt.Errorf("validation ports (HTTP %d, HTTPS %d, TLS %d) must be 80 and 443 in CA mode builds",
+			pc.HTTPPort, pc.HTTPSPort, pc.TLSPort)
+	}
+	if !pc.AllowNonstandardPorts {
+		return fmt.Errorf("validation ports (HTTP %d, HTTPS %d, TLS %d) aren't 80 and 443, and AllowNonstandardPorts isn't set",
+			pc.HTTPPort, pc.HTTPSPort, pc.TLSPort)
+	}
+	return nil
 }
 
 // CAADistributedResolverConfig specifies the HTTP client setup and interfaces
@@ -338,6 +608,14 @@ func (ts *TemporalSet) pick(exp time.Time) (*LogShard, error) {
 	return nil, fmt.Errorf("no valid shard available for temporal set %q for expiration date %q", ts.Name, exp)
 }
 
+// The API types of CT logs.
+const (
+	// LogTypeRFC6962 logs implement the API of RFC 6962.
+	LogTypeRFC6962 = "rfc6962"
+	// LogTypeStatic logs implement static-ct-api, also known as tiled logs.
+	LogTypeStatic = "static"
+)
+
 // LogDescription contains the information needed to submit certificates
 // to a CT log and verify returned receipts. If TemporalSet is non-nil then
 // URI and Key should be empty.
@@ -345,10 +623,28 @@ type LogDescription struct {
 	URI             string
 	Key             string
 	SubmitFinalCert bool
+	// Type is the API the log implements, LogTypeRFC6962 or LogTypeStatic,
+	// for every shard if it's a temporal set. It defaults to LogTypeRFC6962.
+	// The URI of a static log is its submission prefix.
+	Type string
 
 	*TemporalSet
 }
 
+// CheckType returns an error if the log's Type isn't a known API type.
+func (ld LogDescription) CheckType() error {
+	switch ld.Type {
+	case "", LogTypeRFC6962, LogTypeStatic:
+		return nil
+	}
+	return fmt.Errorf("unknown CT log type %q", ld.Type)
+}
+
+// Static returns true if the log implements static-ct-api.
+func (ld LogDescription) Static() bool {
+	return ld.Type == LogTypeStatic
+}
+
 // Info returns the URI and key of the log, either from a plain log description
 // or from the earliest valid shard from a temporal log set
 func (ld LogDescription) Info(exp time.Time) (string, string, error) {
@@ -62,15 +62,15 @@ func TestTLSConfigLoad(t *testing.T) {
 		TLSConfig
 		want string
 	}{
-		{TLSConfig{nil, &null, &null}, "nil CertFile in TLSConfig"},
-		{TLSConfig{&null, nil, &null}, "nil KeyFile in TLSConfig"},
-		{TLSConfig{&null, &null, nil}, "nil CACertFile in TLSConfig"},
-		{TLSConfig{&nonExistent, &key, &caCert}, "loading key pair.*no such file or directory"},
-		{TLSConfig{&cert, &nonExistent, &caCert}, "loading key pair.*no such file or directory"},
-		{TLSConfig{&cert, &key, &nonExistent}, "reading CA cert from.*no such file or directory"},
-		{TLSConfig{&null, &key, &caCert}, "loading key pair.*failed to find any PEM data"},
-		{TLSConfig{&cert, &null, &caCert}, "loading key pair.*failed to find any PEM data"},
-		{TLSConfig{&cert, &key, &null}, "parsing CA certs"},
+		{TLSConfig{CertFile: nil, KeyFile: &null, CACertFile: &null}, "nil CertFile in TLSConfig"},
+		{TLSConfig{CertFile: &null, KeyFile: nil, CACertFile: &null}, "nil KeyFile in TLSConfig"},
+		{TLSConfig{CertFile: &null, KeyFile: &null, CACertFile: nil}, "nil CACertFile in TLSConfig"},
+		{TLSConfig{CertFile: &nonExistent, KeyFile: &key, CACertFile: &caCert}, "loading key pair.*no such file or directory"},
+		{TLSConfig{CertFile: &cert, KeyFile: &nonExistent, CACertFile: &caCert}, "loading key pair.*no such file or directory"},
+		{TLSConfig{CertFile: &cert, KeyFile: &key, CACertFile: &nonExistent}, "reading CA cert from.*no such file or directory"},
+		{TLSConfig{CertFile: &null, KeyFile: &key, CACertFile: &caCert}, "loading key pair.*failed to find any PEM data"},
+		{TLSConfig{CertFile: &cert, KeyFile: &null, CACertFile: &caCert}, "loading key pair.*failed to find any PEM data"},
+		{TLSConfig{CertFile: &cert, KeyFile: &key, CACertFile: &null}, "parsing CA certs"},
 	}
 	for _, tc := range testCases {
 		var title [3]string
@@ -101,6 +101,26 @@ func TestTLSConfigLoad(t *testing.T) {
 	}
 }
 
+func TestRemoteSourceConfigLoad(t *testing.T) {
+	_, err := (&RemoteSourceConfig{}).Load()
+	test.AssertError(t, err, "Load accepted a remote source without a URL")
+
+	_, err = (&RemoteSourceConfig{URL: "https://example.com", CACertFile: "/dev/null"}).Load()
+	test.AssertError(t, err, "Load accepted a CA file without certificates")
+
+	source, err := (&RemoteSourceConfig{
+		URL:              "https://example.com/policy.yaml",
+		CACertFile:       "testdata/minica.pem",
+		PinnedSPKIHashes: []string{"AAAA"},
+		PollInterval:     ConfigDuration{time.Minute},
+	}).Load()
+	test.AssertNotError(t, err, "Load failed")
+	test.AssertEquals(t, source.URL, "https://example.com/policy.yaml")
+	test.AssertEquals(t, source.PollInterval, time.Minute)
+	test.AssertEquals(t, len(source.PinnedSPKIHashes), 1)
+	test.AssertEquals(t, len(source.RootCAs.Subjects()), 1)
+}
+
 func TestTemporalSetup(t *testing.T) {
 	for _, tc := range []struct {
 		ts  TemporalSet
@@ -207,3 +227,27 @@ func TestLogInfo(t *testing.T) {
 	test.AssertEquals(t, uri, "b")
 	test.AssertEquals(t, key, "b")
 }
+
+func TestLogType(t *testing.T) {
+	for _, typ := range []string{"", LogTypeRFC6962, LogTypeStatic} {
+		test.AssertNotError(t, LogDescription{Type: typ}.CheckType(), "known log type rejected")
+	}
+	test.AssertError(t, LogDescription{Type: "tiled"}.CheckType(), "unknown log type accepted")
+	test.Assert(t, LogDescription{Type: LogTypeStatic}.Static(), "static log wasn't static")
+	test.Assert(t, !LogDescription{}.Static(), "log with the default type was static")
+}
+
+func TestPortConfigCheck(t *testing.T) {
+	test.AssertNotError(t, PortConfig{}.Check(), "default ports rejected")
+	test.AssertNotError(t, PortConfig{HTTPPort: 80, HTTPSPort: 443, TLSPort: 443}.Check(), "standard ports rejected")
+	test.AssertError(t, PortConfig{HTTPPort: 5002}.Check(), "nonstandard port accepted without AllowNonstandardPorts")
+	err := PortConfig{HTTPPort: 5002, AllowNonstandardPorts: true}.Check()
+	if nonstandardPortsBuildAllowed {
+		test.AssertNotError(t, err, "nonstandard port rejected with AllowNonstandardPorts")
+	} else {
+		test.AssertError(t, err, "nonstandard port accepted in a camode build")
+	}
+
+	pc := PortConfig{TLSPort: 5001}.WithDefaults()
+	test.AssertEquals(t, pc, PortConfig{HTTPPort: 80, HTTPSPort: 443, TLSPort: 5001})
+}
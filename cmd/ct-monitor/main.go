@@ -0,0 +1,405 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	ct "github.com/google/certificate-transparency-go"
+	ctClient "github.com/google/certificate-transparency-go/client"
+	"github.com/google/certificate-transparency-go/jsonclient"
+	ctx509 "github.com/google/certificate-transparency-go/x509"
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/features"
+	bgrpc "github.com/letsencrypt/boulder/grpc"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+	"github.com/letsencrypt/boulder/staticct"
+)
+
+const usageString = `
+usage:
+ct-monitor --config <path>
+
+Watches CT logs for certificates and precertificates issued by this CA's
+issuers, and reports any whose serial isn't in the database: a sign of key
+compromise, or of an orphaned certificate that orphan-finder should add.
+Reports are audit logged and counted in the ct_monitor_entries metric with
+result "unknown", which should be alerted on.
+
+args:
+  config  File path to the configuration file for this service
+`
+
+const (
+	defaultBatchSize    = 256
+	defaultPollInterval = time.Minute
+	defaultGracePeriod  = time.Hour
+)
+
+type config struct {
+	CTMonitor struct {
+		DebugAddr string
+
+		TLS       cmd.TLSConfig
+		SAService *cmd.GRPCClientConfig
+
+		// IssuerCerts are PEM files of the issuers whose certificates are
+		// monitored.
+		IssuerCerts []string
+
+		// Logs are the CT logs to watch. Key, if set, is the base64 DER
+		// public key of the log, used to verify its tree heads. Type is the
+		// log's API, as in the RA's CT log config; static logs are read from
+		// MonitoringURI, and URI is their submission prefix.
+		Logs []struct {
+			URI           string
+			Key           string
+			Type          string
+			MonitoringURI string
+		}
+
+		// StateFile is where the index of the next entry to check in each
+		// log is kept. Logs that aren't in it are watched from their current
+		// size.
+		StateFile string
+
+		// BatchSize is the most entries requested from a log at once.
+		BatchSize int64
+		// PollInterval is how often the logs are checked for new entries.
+		PollInterval cmd.ConfigDuration
+		// GracePeriod is how old a log entry must be before it's checked,
+		// giving the CA time to store a certificate after logging its
+		// precertificate. Defaults to 1h.
+		GracePeriod cmd.ConfigDuration
+
+		Features map[string]bool
+	}
+
+	Syslog cmd.SyslogConfig
+}
+
+// logClient is the subset of ctClient.LogClient used by the monitor.
+type logClient interface {
+	GetSTH(ctx context.Context) (*ct.SignedTreeHead, error)
+	GetEntries(ctx context.Context, start, end int64) ([]ct.LogEntry, error)
+}
+
+type certificateGetter interface {
+	GetCertificate(ctx context.Context, serial string) (core.Certificate, error)
+}
+
+// issuer is a CA certificate whose issuance is monitored.
+type issuer struct {
+	cert *x509.Certificate
+	// keyHash is the SHA-256 hash of the issuer's public key, which
+	// identifies it in precertificate log entries.
+	keyHash [sha256.Size]byte
+}
+
+type monitor struct {
+	sa          certificateGetter
+	issuers     []issuer
+	log         blog.Logger
+	clk         clock.Clock
+	batchSize   int64
+	gracePeriod time.Duration
+
+	entries *prometheus.CounterVec
+	next    *prometheus.GaugeVec
+}
+
+func newMonitor(
+	sa certificateGetter,
+	issuers []*x509.Certificate,
+	batchSize int64,
+	gracePeriod time.Duration,
+	clk clock.Clock,
+	logger blog.Logger,
+	scope metrics.Scope,
+) *monitor {
+	if batchSize == 0 {
+		batchSize = defaultBatchSize
+	}
+	if gracePeriod == 0 {
+		gracePeriod = defaultGracePeriod
+	}
+	entries := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ct_monitor_entries",
+		Help: "Number of CT log entries checked, by log and result (ours, other, unknown). Unknown entries are certificates of our issuers that aren't in the database",
+	}, []string{"log", "result"})
+	scope.MustRegister(entries)
+	next := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ct_monitor_next_index",
+		Help: "Index of the next entry to check in each CT log",
+	}, []string{"log"})
+	scope.MustRegister(next)
+	m := &monitor{
+		sa:          sa,
+		log:         logger,
+		clk:         clk,
+		batchSize:   batchSize,
+		gracePeriod: gracePeriod,
+		entries:     entries,
+		next:        next,
+	}
+	for _, cert := range issuers {
+		m.issuers = append(m.issuers, issuer{
+			cert:    cert,
+			keyHash: sha256.Sum256(cert.RawSubjectPublicKeyInfo),
+		})
+	}
+	return m
+}
+
+// ours returns the certificate or precertificate of entry if it was issued
+// by one of the monitored issuers, and whether it's a precertificate.
+func (m *monitor) ours(entry ct.LogEntry) (*ctx509.Certificate, bool) {
+	switch {
+	case entry.X509Cert != nil:
+		cert := entry.X509Cert
+		for _, iss := range m.issuers {
+			if !bytes.Equal(cert.RawIssuer, iss.cert.RawSubject) {
+				continue
+			}
+			// Issuers can share a name across a key rollover, so if the
+			// certificate says which key signed it, that must match too.
+			if len(cert.AuthorityKeyId) > 0 && len(iss.cert.SubjectKeyId) > 0 &&
+				!bytes.Equal(cert.AuthorityKeyId, iss.cert.SubjectKeyId) {
+				continue
+			}
+			return cert, false
+		}
+	case entry.Precert != nil:
+		for _, iss := range m.issuers {
+			if entry.Precert.IssuerKeyHash == iss.keyHash {
+				return entry.Precert.TBSCertificate, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// checkEntry looks up the serial of entry in the database if it was issued
+// by one of the monitored issuers, and reports it if it isn't there. A
+// precertificate is expected to have a final certificate with the same
+// serial.
+func (m *monitor) checkEntry(ctx context.Context, logURI string, entry ct.LogEntry) error {
+	cert, precert := m.ours(entry)
+	if cert == nil {
+		m.entries.With(prometheus.Labels{"log": logURI, "result": "other"}).Inc()
+		return nil
+	}
+	serial := core.SerialToString(cert.SerialNumber)
+	_, err := m.sa.GetCertificate(ctx, serial)
+	if err == nil {
+		m.entries.With(prometheus.Labels{"log": logURI, "result": "ours"}).Inc()
+		return nil
+	}
+	if !berrors.Is(err, berrors.NotFound) {
+		return err
+	}
+	kind := "certificate"
+	if precert {
+		kind = "precertificate"
+	}
+	m.entries.With(prometheus.Labels{"log": logURI, "result": "unknown"}).Inc()
+	m.log.AuditErrf("CT log has a %s of ours that isn't in the database: log=[%s] index=[%d] serial=[%s] names=[%s] notBefore=[%s]",
+		kind, logURI, entry.Index, serial, strings.Join(cert.DNSNames, ","), cert.NotBefore)
+	return nil
+}
+
+// checkLog checks the entries of a log from index next up to its current
+// size, skipping those newer than the grace period, and returns the index
+// of the next entry to check.
+func (m *monitor) checkLog(ctx context.Context, logURI string, lc logClient, next int64) (int64, error) {
+	sth, err := lc.GetSTH(ctx)
+	if err != nil {
+		return next, err
+	}
+	size := int64(sth.TreeSize)
+	cutoff := m.clk.Now().Add(-m.gracePeriod)
+	for next < size {
+		end := next + m.batchSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		entries, err := lc.GetEntries(ctx, next, end)
+		if err != nil {
+			return next, err
+		}
+		if len(entries) == 0 {
+			return next, fmt.Errorf("log returned no entries from %d", next)
+		}
+		for _, entry := range entries {
+			if entry.Leaf.TimestampedEntry != nil {
+				logged := time.Unix(0, int64(entry.Leaf.TimestampedEntry.Timestamp)*int64(time.Millisecond))
+				if logged.After(cutoff) {
+					return next, nil
+				}
+			}
+			err := m.checkEntry(ctx, logURI, entry)
+			if err != nil {
+				return next, err
+			}
+			next = entry.Index + 1
+			m.next.With(prometheus.Labels{"log": logURI}).Set(float64(next))
+		}
+	}
+	return next, nil
+}
+
+// loadState reads the index of the next entry to check in each log.
+func loadState(filename string) (map[string]int64, error) {
+	state := make(map[string]int64)
+	contents, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(contents, &state)
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// saveState replaces the state file, so that it's never left half written.
+func saveState(filename string, state map[string]int64) error {
+	contents, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(contents)
+	if err == nil {
+		err = tmp.Close()
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+func newLogClient(uri, b64PK, logType, monitoringURI string) (logClient, error) {
+	ld := cmd.LogDescription{URI: uri, Key: b64PK, Type: logType}
+	err := ld.CheckType()
+	if err != nil {
+		return nil, err
+	}
+	if ld.Static() {
+		if monitoringURI == "" {
+			return nil, errors.New("static logs need a monitoring URI")
+		}
+		return staticct.NewClient(uri, monitoringURI, b64PK, &http.Client{Timeout: time.Minute})
+	}
+	var opts jsonclient.Options
+	if b64PK != "" {
+		opts.PublicKey = fmt.Sprintf("-----BEGIN PUBLIC KEY-----\n%s\n-----END PUBLIC KEY-----", b64PK)
+	}
+	return ctClient.New(strings.TrimSuffix(uri, "/"), &http.Client{Timeout: time.Minute}, opts)
+}
+
+func main() {
+	configFile := flag.String("config", "", "File path to the configuration file for this service")
+	flag.Parse()
+	if *configFile == "" {
+		fmt.Fprintf(os.Stderr, usageString)
+		os.Exit(1)
+	}
+
+	var c config
+	err := cmd.ReadConfigFile(*configFile, &c)
+	cmd.FailOnError(err, "Reading JSON config file into config structure")
+	conf := c.CTMonitor
+	err = features.Set(conf.Features)
+	cmd.FailOnError(err, "Failed to set feature flags")
+
+	scope, logger := cmd.StatsAndLogging(c.Syslog, conf.DebugAddr)
+	defer logger.AuditPanic()
+	logger.Info(cmd.VersionString())
+
+	if conf.StateFile == "" {
+		cmd.Fail("A state file is required")
+	}
+	var issuers []*x509.Certificate
+	for _, file := range conf.IssuerCerts {
+		cert, err := core.LoadCert(file)
+		cmd.FailOnError(err, fmt.Sprintf("Failed to load issuer certificate %q", file))
+		issuers = append(issuers, cert)
+	}
+	if len(issuers) == 0 {
+		cmd.Fail("At least one issuer certificate is required")
+	}
+	clients := make(map[string]logClient)
+	for _, l := range conf.Logs {
+		lc, err := newLogClient(l.URI, l.Key, l.Type, l.MonitoringURI)
+		cmd.FailOnError(err, fmt.Sprintf("Failed to create client for CT log %q", l.URI))
+		clients[l.URI] = lc
+	}
+
+	tlsConfig, err := conf.TLS.Load()
+	cmd.FailOnError(err, "TLS config")
+	clk := cmd.Clock()
+	clientMetrics := bgrpc.NewClientMetrics(scope)
+	conn, err := bgrpc.ClientSetup(conf.SAService, tlsConfig, clientMetrics, clk)
+	cmd.FailOnError(err, "Failed to load credentials and create gRPC connection to SA")
+	sac := bgrpc.NewStorageAuthorityClient(sapb.NewStorageAuthorityClient(conn))
+
+	m := newMonitor(sac, issuers, conf.BatchSize, conf.GracePeriod.Duration, clk, logger, scope)
+
+	state, err := loadState(conf.StateFile)
+	cmd.FailOnError(err, "Failed to load state file")
+	for uri, lc := range clients {
+		if _, ok := state[uri]; ok {
+			continue
+		}
+		sth, err := lc.GetSTH(context.Background())
+		cmd.FailOnError(err, fmt.Sprintf("Failed to get tree head of CT log %q", uri))
+		state[uri] = int64(sth.TreeSize)
+		logger.Infof("Watching CT log %s from its current size %d", uri, sth.TreeSize)
+	}
+
+	interval := conf.PollInterval.Duration
+	if interval == 0 {
+		interval = defaultPollInterval
+	}
+	go cmd.CatchSignals(logger, nil)
+	for {
+		for uri, lc := range clients {
+			next, err := m.checkLog(context.Background(), uri, lc, state[uri])
+			if err != nil {
+				logger.Warningf("Failed to check CT log %s from index %d: %s", uri, next, err)
+			}
+			state[uri] = next
+		}
+		err := saveState(conf.StateFile, state)
+		if err != nil {
+			logger.AuditErrf("Failed to save state file: %s", err)
+		}
+		clk.Sleep(interval)
+	}
+}
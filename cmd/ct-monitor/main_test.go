@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ct "github.com/google/certificate-transparency-go"
+	ctClient "github.com/google/certificate-transparency-go/client"
+	ctx509 "github.com/google/certificate-transparency-go/x509"
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/staticct"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func makeIssuer(t *testing.T, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "generating issuer key")
+	ski := sha256.Sum256(elliptic.Marshal(key.Curve, key.X, key.Y))
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		SubjectKeyId:          ski[:20],
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	test.AssertNotError(t, err, "creating issuer")
+	cert, err := x509.ParseCertificate(der)
+	test.AssertNotError(t, err, "parsing issuer")
+	return cert, key
+}
+
+func makeEntry(t *testing.T, index int64, serial int64, iss *x509.Certificate, key *ecdsa.PrivateKey, precert bool, logged time.Time) ct.LogEntry {
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "generating leaf key")
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		DNSNames:     []string{"example.com"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, iss, &leafKey.PublicKey, key)
+	test.AssertNotError(t, err, "creating leaf")
+	cert, err := ctx509.ParseCertificate(der)
+	test.AssertNotError(t, err, "parsing leaf")
+	entry := ct.LogEntry{
+		Index: index,
+		Leaf: ct.MerkleTreeLeaf{TimestampedEntry: &ct.TimestampedEntry{
+			Timestamp: uint64(logged.UnixNano() / int64(time.Millisecond)),
+		}},
+	}
+	if precert {
+		entry.Precert = &ct.Precertificate{
+			IssuerKeyHash:  sha256.Sum256(iss.RawSubjectPublicKeyInfo),
+			TBSCertificate: cert,
+		}
+	} else {
+		entry.X509Cert = cert
+	}
+	return entry
+}
+
+type fakeSA struct {
+	serials map[string]bool
+}
+
+func (sa *fakeSA) GetCertificate(_ context.Context, serial string) (core.Certificate, error) {
+	if !sa.serials[serial] {
+		return core.Certificate{}, berrors.NotFoundError("no certificate with serial %s", serial)
+	}
+	return core.Certificate{Serial: serial}, nil
+}
+
+type fakeLog struct {
+	entries []ct.LogEntry
+}
+
+func (l *fakeLog) GetSTH(context.Context) (*ct.SignedTreeHead, error) {
+	return &ct.SignedTreeHead{TreeSize: uint64(len(l.entries))}, nil
+}
+
+func (l *fakeLog) GetEntries(_ context.Context, start, end int64) ([]ct.LogEntry, error) {
+	return l.entries[start : end+1], nil
+}
+
+func TestCheckLog(t *testing.T) {
+	fc := clock.NewFake()
+	fc.Set(time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC))
+	old := fc.Now().Add(-2 * time.Hour)
+
+	ours, ourKey := makeIssuer(t, "our issuer")
+	// Another CA's issuer with the same name, but a different key
+	theirs, theirKey := makeIssuer(t, "our issuer")
+	other, otherKey := makeIssuer(t, "other issuer")
+
+	sa := &fakeSA{serials: map[string]bool{
+		core.SerialToString(big.NewInt(1)): true,
+	}}
+	log := blog.NewMock()
+	m := newMonitor(sa, []*x509.Certificate{ours}, 2, time.Hour, fc, log, metrics.NewNoopScope())
+
+	lc := &fakeLog{entries: []ct.LogEntry{
+		makeEntry(t, 0, 1, ours, ourKey, true, old),
+		makeEntry(t, 1, 1, ours, ourKey, false, old),
+		makeEntry(t, 2, 2, other, otherKey, false, old),
+		makeEntry(t, 3, 3, theirs, theirKey, false, old),
+		makeEntry(t, 4, 4, ours, ourKey, true, old),
+		// Too recent to be checked yet
+		makeEntry(t, 5, 5, ours, ourKey, true, fc.Now().Add(-time.Minute)),
+	}}
+
+	next, err := m.checkLog(context.Background(), "log", lc, 0)
+	test.AssertNotError(t, err, "checkLog failed")
+	test.AssertEquals(t, next, int64(5))
+	count := func(result string) int {
+		return test.CountCounter(m.entries.With(prometheus.Labels{"log": "log", "result": result}))
+	}
+	test.AssertEquals(t, count("ours"), 2)
+	test.AssertEquals(t, count("other"), 2)
+	test.AssertEquals(t, count("unknown"), 1)
+	test.AssertEquals(t, len(log.GetAllMatching(`CT log has a precertificate of ours that isn't in the database: log=\[log\] index=\[4\] serial=\[0+4\]`)), 1)
+
+	// Once the grace period has passed, the last entry is checked too
+	fc.Add(time.Hour)
+	next, err = m.checkLog(context.Background(), "log", lc, next)
+	test.AssertNotError(t, err, "checkLog failed")
+	test.AssertEquals(t, next, int64(6))
+	test.AssertEquals(t, count("unknown"), 2)
+}
+
+func TestState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ct-monitor")
+	test.AssertNotError(t, err, "creating temp dir")
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "state.json")
+
+	state, err := loadState(filename)
+	test.AssertNotError(t, err, "loading missing state file")
+	test.AssertEquals(t, len(state), 0)
+
+	state["https://ct.example.com/log"] = 1234
+	test.AssertNotError(t, saveState(filename, state), "saving state")
+	state, err = loadState(filename)
+	test.AssertNotError(t, err, "loading state")
+	test.AssertEquals(t, state["https://ct.example.com/log"], int64(1234))
+}
+
+func TestNewLogClient(t *testing.T) {
+	lc, err := newLogClient("https://ct.example.com/log", "", "", "")
+	test.AssertNotError(t, err, "creating RFC 6962 log client")
+	_, ok := lc.(*ctClient.LogClient)
+	test.Assert(t, ok, "RFC 6962 log client has the wrong type")
+
+	lc, err = newLogClient("https://ct.example.com/2025h1", "", cmd.LogTypeStatic, "https://mon.example.com/2025h1")
+	test.AssertNotError(t, err, "creating static log client")
+	_, ok = lc.(*staticct.Client)
+	test.Assert(t, ok, "static log client has the wrong type")
+
+	_, err = newLogClient("https://ct.example.com/2025h1", "", cmd.LogTypeStatic, "")
+	test.AssertError(t, err, "static log client without a monitoring URI was created")
+	_, err = newLogClient("https://ct.example.com/log", "", "tiled", "")
+	test.AssertError(t, err, "log client with an unknown type was created")
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/letsencrypt/boulder/cmd"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/sa"
+	"github.com/letsencrypt/boulder/sa/migrate"
+)
+
+const usageString = `
+usage:
+db-migrate status --config <path>
+db-migrate dry-run --config <path>
+db-migrate apply --config <path> [--allow-offline]
+
+command descriptions:
+  status   List every migration and whether it has been applied
+  dry-run  Print the SQL that applying the pending migrations would run
+  apply    Apply the pending migrations, in order
+
+args:
+  config         File path to the configuration file for this service
+  allow-offline  Also apply migrations that aren't annotated as safe to apply
+                 while Boulder is running. Without it, apply stops before the
+                 first such migration.
+`
+
+type config struct {
+	DBMigrate struct {
+		// The DB user must be allowed to change the schema, unlike the
+		// users of the Boulder services.
+		cmd.DBConfig
+
+		// Dirs are the directories whose migrations subdirectories hold the
+		// migrations to apply, e.g. "sa/_db".
+		Dirs []string
+	}
+
+	Syslog cmd.SyslogConfig
+}
+
+// status prints every migration, whether it's safe to apply online, and
+// whether it's been applied.
+func status(migrations, pending []migrate.Migration) error {
+	isPending := make(map[int64]bool, len(pending))
+	for _, m := range pending {
+		isPending[m.Version] = true
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "MIGRATION\tSAFE ONLINE\tSTATUS")
+	for _, m := range migrations {
+		state := "applied"
+		if isPending[m.Version] {
+			state = "pending"
+		}
+		fmt.Fprintf(w, "%s\t%t\t%s\n", m.Name, m.SafeOnline, state)
+	}
+	return w.Flush()
+}
+
+// apply applies the pending migrations in order, stopping at the first one
+// that isn't safe to apply online unless allowOffline is true.
+func apply(applyFunc func(migrate.Migration) error, log blog.Logger, pending []migrate.Migration, allowOffline bool) error {
+	for _, m := range pending {
+		if !m.SafeOnline && !allowOffline {
+			return fmt.Errorf("migration %s isn't annotated as safe to apply online, rerun with --allow-offline once Boulder is stopped", m.Name)
+		}
+		err := applyFunc(m)
+		if err != nil {
+			return err
+		}
+		log.AuditInfof("Applied migration %s", m.Name)
+	}
+	return nil
+}
+
+func main() {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, usageString)
+		os.Exit(1)
+	}
+	if len(os.Args) <= 2 {
+		usage()
+	}
+
+	command := os.Args[1]
+	flagSet := flag.NewFlagSet(command, flag.ContinueOnError)
+	configFile := flagSet.String("config", "", "File path to the configuration file for this service")
+	allowOffline := flagSet.Bool("allow-offline", false, "Also apply migrations that aren't safe to apply online")
+	err := flagSet.Parse(os.Args[2:])
+	cmd.FailOnError(err, "Error parsing flagset")
+
+	if *configFile == "" {
+		usage()
+	}
+
+	var c config
+	err = cmd.ReadConfigFile(*configFile, &c)
+	cmd.FailOnError(err, "Reading JSON config file into config structure")
+	logger := cmd.NewLogger(c.Syslog)
+
+	if len(c.DBMigrate.Dirs) == 0 {
+		cmd.Fail("No migration directories configured")
+	}
+	migrations, err := migrate.Load(c.DBMigrate.Dirs...)
+	cmd.FailOnError(err, "Failed to load migrations")
+
+	dbURL, err := c.DBMigrate.URL()
+	cmd.FailOnError(err, "Couldn't load DB URL")
+	dbMap, err := sa.NewDbMap(dbURL, 1)
+	cmd.FailOnError(err, "Couldn't connect to database")
+	pending, err := migrate.Pending(dbMap.Db, migrations)
+	cmd.FailOnError(err, "Failed to find pending migrations")
+
+	switch command {
+	case "status":
+		err = status(migrations, pending)
+		cmd.FailOnError(err, "Failed to print migration status")
+
+	case "dry-run":
+		err = migrate.WriteSQL(os.Stdout, pending)
+		cmd.FailOnError(err, "Failed to print migration SQL")
+
+	case "apply":
+		applyFunc := func(m migrate.Migration) error {
+			return migrate.Apply(dbMap.Db, m)
+		}
+		err = apply(applyFunc, logger, pending, *allowOffline)
+		cmd.FailOnError(err, "Failed to apply migrations")
+
+	default:
+		usage()
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/sa/migrate"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestApply(t *testing.T) {
+	pending := []migrate.Migration{
+		{Version: 1, Name: "1_Online.sql", SafeOnline: true},
+		{Version: 2, Name: "2_Offline.sql"},
+		{Version: 3, Name: "3_Online.sql", SafeOnline: true},
+	}
+	var applied []string
+	applyFunc := func(m migrate.Migration) error {
+		applied = append(applied, m.Name)
+		return nil
+	}
+
+	// Without --allow-offline, apply stops before the first migration that
+	// isn't safe online
+	err := apply(applyFunc, blog.NewMock(), pending, false)
+	test.AssertError(t, err, "apply applied a migration that isn't safe online")
+	test.AssertDeepEquals(t, applied, []string{"1_Online.sql"})
+
+	applied = nil
+	err = apply(applyFunc, blog.NewMock(), pending, true)
+	test.AssertNotError(t, err, "apply failed")
+	test.AssertDeepEquals(t, applied, []string{"1_Online.sql", "2_Offline.sql", "3_Online.sql"})
+}
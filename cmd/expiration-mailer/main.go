@@ -36,6 +36,7 @@ import (
 const (
 	defaultNagCheckInterval  = 24 * time.Hour
 	defaultExpirationSubject = "Let's Encrypt certificate expiration notice for domain {{.ExpirationSubject}}"
+	defaultClaimTimeout      = time.Hour
 )
 
 type regStore interface {
@@ -49,10 +50,70 @@ type mailer struct {
 	mailer          bmail.Mailer
 	emailTemplate   *template.Template
 	subjectTemplate *template.Template
-	nagTimes        []time.Duration
-	limit           int
-	clk             clock.Clock
-	stats           mailerStats
+	// localizedTemplates holds the templates for languages other than the
+	// default, indexed by lowercase language tag, e.g. "fr" or "pt-br".
+	localizedTemplates map[string]localizedTemplate
+	nagTimes           []time.Duration
+	limit              int
+	// batchSize is how many certificates are loaded and processed at a time.
+	// Each nag group is processed in batches until limit certificates have
+	// been found.
+	batchSize int
+	// workers is the number of mailers sharing the work of each run, and
+	// worker is the index of this one. Each mailer only processes the
+	// certificates whose serials hash to its index.
+	workers int
+	worker  int
+	// claimTimeout is how long a claim on a certificate whose nag hasn't
+	// been sent is honoured before another mailer takes it over, assuming
+	// the mailer that claimed it crashed.
+	claimTimeout time.Duration
+	clk          clock.Clock
+	stats        mailerStats
+	// When dryRun is true messages are rendered and logged but not sent, and
+	// certificate statuses are not updated.
+	dryRun bool
+	// webhooks, if not nil, delivers expiration events to webhook endpoints
+	// in addition to the nag emails.
+	webhooks *webhookSender
+}
+
+// localizedTemplate is a pair of subject and body templates for a single
+// language.
+type localizedTemplate struct {
+	subject *template.Template
+	email   *template.Template
+}
+
+// contactLanguage returns the language requested by a mailto contact URL, if
+// any. Subscribers request a language using the Content-Language header field
+// of the mailto URL (RFC 6068), e.g.
+// "mailto:admin@example.com?Content-Language=fr".
+func contactLanguage(contact *url.URL) string {
+	for name, values := range contact.Query() {
+		if strings.EqualFold(name, "Content-Language") && len(values) > 0 {
+			return strings.ToLower(strings.TrimSpace(values[0]))
+		}
+	}
+	return ""
+}
+
+// templatesFor returns the templates to use for the given language. If there
+// are no templates for a regional variant such as "pt-br" the templates for
+// the base language ("pt") are used, and if there are none of those either
+// the default templates are used.
+func (m *mailer) templatesFor(language string) localizedTemplate {
+	for language != "" {
+		if t, ok := m.localizedTemplates[language]; ok {
+			return t
+		}
+		i := strings.LastIndex(language, "-")
+		if i < 0 {
+			break
+		}
+		language = language[:i]
+	}
+	return localizedTemplate{subject: m.subjectTemplate, email: m.emailTemplate}
 }
 
 type mailerStats struct {
@@ -61,6 +122,7 @@ type mailerStats struct {
 	renewalCount      *prometheus.CounterVec
 	sendLatency       prometheus.Histogram
 	processingLatency prometheus.Histogram
+	claimCount        *prometheus.CounterVec
 }
 
 func (m *mailer) sendNags(contacts []string, certs []*x509.Certificate) error {
@@ -70,7 +132,10 @@ func (m *mailer) sendNags(contacts []string, certs []*x509.Certificate) error {
 	if len(certs) == 0 {
 		return errors.New("no certs given to send nags for")
 	}
-	emails := []string{}
+	// Group the email addresses by the templates they should be sent with,
+	// preserving the order of the contacts.
+	var groups []localizedTemplate
+	emailsByTemplate := make(map[localizedTemplate][]string)
 	for _, contact := range contacts {
 		parsed, err := url.Parse(contact)
 		if err != nil {
@@ -78,10 +143,14 @@ func (m *mailer) sendNags(contacts []string, certs []*x509.Certificate) error {
 			continue
 		}
 		if parsed.Scheme == "mailto" {
-			emails = append(emails, parsed.Opaque)
+			t := m.templatesFor(contactLanguage(parsed))
+			if _, ok := emailsByTemplate[t]; !ok {
+				groups = append(groups, t)
+			}
+			emailsByTemplate[t] = append(emailsByTemplate[t], parsed.Opaque)
 		}
 	}
-	if len(emails) == 0 {
+	if len(groups) == 0 {
 		return nil
 	}
 
@@ -111,18 +180,6 @@ func (m *mailer) sendNags(contacts []string, certs []*x509.Certificate) error {
 		expiringSubject += fmt.Sprintf(" (and %d more)", len(domains)-1)
 	}
 
-	// Execute the subjectTemplate by filling in the ExpirationSubject
-	subjBuf := new(bytes.Buffer)
-	err := m.subjectTemplate.Execute(subjBuf, struct {
-		ExpirationSubject string
-	}{
-		ExpirationSubject: expiringSubject,
-	})
-	if err != nil {
-		m.stats.errorCount.With(prometheus.Labels{"type": "SubjectTemplateFailure"}).Inc()
-		return err
-	}
-
 	email := struct {
 		ExpirationDate   string
 		DaysToExpiration int
@@ -132,24 +189,43 @@ func (m *mailer) sendNags(contacts []string, certs []*x509.Certificate) error {
 		DaysToExpiration: int(expiresIn.Hours() / 24),
 		DNSNames:         strings.Join(domains, "\n"),
 	}
-	msgBuf := new(bytes.Buffer)
-	err = m.emailTemplate.Execute(msgBuf, email)
-	if err != nil {
-		m.stats.errorCount.With(prometheus.Labels{"type": "TemplateFailure"}).Inc()
-		return err
-	}
-	startSending := m.clk.Now()
-	err = m.mailer.SendMail(emails, subjBuf.String(), msgBuf.String())
-	if err != nil {
-		return err
+
+	for _, t := range groups {
+		// Execute the subject template by filling in the ExpirationSubject
+		subjBuf := new(bytes.Buffer)
+		err := t.subject.Execute(subjBuf, struct {
+			ExpirationSubject string
+		}{
+			ExpirationSubject: expiringSubject,
+		})
+		if err != nil {
+			m.stats.errorCount.With(prometheus.Labels{"type": "SubjectTemplateFailure"}).Inc()
+			return err
+		}
+
+		msgBuf := new(bytes.Buffer)
+		err = t.email.Execute(msgBuf, email)
+		if err != nil {
+			m.stats.errorCount.With(prometheus.Labels{"type": "TemplateFailure"}).Inc()
+			return err
+		}
+		startSending := m.clk.Now()
+		err = m.mailer.SendMail(emailsByTemplate[t], subjBuf.String(), msgBuf.String())
+		if err != nil {
+			return err
+		}
+		finishSending := m.clk.Now()
+		elapsed := finishSending.Sub(startSending)
+		m.stats.sendLatency.Observe(elapsed.Seconds())
 	}
-	finishSending := m.clk.Now()
-	elapsed := finishSending.Sub(startSending)
-	m.stats.sendLatency.Observe(elapsed.Seconds())
 	return nil
 }
 
 func (m *mailer) updateCertStatus(serial string) error {
+	if m.dryRun {
+		// Leave the status alone so a real run still sends the nag.
+		return nil
+	}
 	_, err := m.dbMap.Exec(
 		"UPDATE certificateStatus SET lastExpirationNagSent = ?  WHERE serial = ?",
 		m.clk.Now(), serial)
@@ -224,6 +300,18 @@ func (m *mailer) processCerts(allCerts []core.Certificate) {
 				continue
 			}
 
+			if features.Enabled(features.ExpirationNagClaims) {
+				claimed, err := m.claimCert(cert.Serial)
+				if err != nil {
+					m.log.AuditErrf("Error claiming certificate %s: %s", cert.Serial, err)
+					m.stats.errorCount.With(prometheus.Labels{"type": "ClaimCertificate"}).Inc()
+					continue
+				}
+				if !claimed {
+					continue
+				}
+			}
+
 			parsedCerts = append(parsedCerts, parsedCert)
 		}
 
@@ -232,19 +320,44 @@ func (m *mailer) processCerts(allCerts []core.Certificate) {
 			continue
 		}
 
-		if reg.Contact == nil {
+		if reg.Contact == nil && (m.webhooks == nil || len(m.webhooks.endpoints) == 0) {
+			m.releaseClaims(parsedCerts)
 			continue
 		}
+		var contacts []string
+		if reg.Contact != nil {
+			contacts = *reg.Contact
+		}
 
-		err = m.sendNags(*reg.Contact, parsedCerts)
+		err = m.sendNags(contacts, parsedCerts)
 		if err != nil {
 			m.stats.errorCount.With(prometheus.Labels{"type": "SendNags"}).Inc()
 			m.log.AuditErrf("Error sending nag emails: %s", err)
+			// Release the claims so that the next run tries again.
+			m.releaseClaims(parsedCerts)
 			continue
 		}
+		if features.Enabled(features.ExpirationNagClaims) {
+			for _, cert := range parsedCerts {
+				serial := core.SerialToString(cert.SerialNumber)
+				if err := m.markClaimSent(serial); err != nil {
+					m.log.AuditErrf("Error recording nag sent for %s: %s", serial, err)
+					m.stats.errorCount.With(prometheus.Labels{"type": "ClaimCertificate"}).Inc()
+				}
+			}
+		}
+		if m.webhooks != nil {
+			// Webhook failures are counted by the sender. They
+			// don't stop the certificate status from being updated, since the
+			// nag emails were sent.
+			err = m.webhooks.notify(ctx, regID, contacts, parsedCerts)
+			if err != nil {
+				m.stats.errorCount.With(prometheus.Labels{"type": "SendWebhooks"}).Inc()
+			}
+		}
 		for _, cert := range parsedCerts {
 			serial := core.SerialToString(cert.SerialNumber)
-			err = m.updateCertStatus(serial)
+			err = m.finishNag(serial)
 			if err != nil {
 				m.log.AuditErrf("Error updating certificate status for %s: %s", serial, err)
 				m.stats.errorCount.With(prometheus.Labels{"type": "UpdateCertificateStatus"}).Inc()
@@ -255,6 +368,108 @@ func (m *mailer) processCerts(allCerts []core.Certificate) {
 	return
 }
 
+// claimCert records in the expirationNagClaims table that this mailer is
+// about to send a nag for serial, returning false if it shouldn't because
+// another mailer has claimed it. A claim whose nag was sent but whose
+// certificate status wasn't updated, because the mailer that held it
+// crashed, is finished instead of being claimed again. A claim whose nag
+// wasn't sent is taken over once it's older than m.claimTimeout.
+func (m *mailer) claimCert(serial string) (bool, error) {
+	if m.dryRun {
+		return true, nil
+	}
+	now := m.clk.Now()
+	_, err := m.dbMap.Exec(
+		"INSERT INTO expirationNagClaims (serial, claimedAt) VALUES (?, ?)",
+		serial, now)
+	if err == nil {
+		return true, nil
+	}
+	if !strings.HasPrefix(err.Error(), "Error 1062: Duplicate entry") {
+		return false, err
+	}
+
+	var existing nagClaim
+	err = m.dbMap.SelectOne(&existing,
+		"SELECT serial, claimedAt, sentAt FROM expirationNagClaims WHERE serial = ?",
+		serial)
+	if err != nil {
+		return false, err
+	}
+	if existing.SentAt != nil {
+		m.stats.claimCount.With(prometheus.Labels{"result": "alreadySent"}).Inc()
+		return false, m.finishNag(serial)
+	}
+	if now.Sub(existing.ClaimedAt) < m.claimTimeout {
+		m.stats.claimCount.With(prometheus.Labels{"result": "held"}).Inc()
+		return false, nil
+	}
+	// Only one of the mailers racing to take over a stale claim succeeds.
+	result, err := m.dbMap.Exec(
+		"UPDATE expirationNagClaims SET claimedAt = ? WHERE serial = ? AND claimedAt = ? AND sentAt IS NULL",
+		now, serial, existing.ClaimedAt)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		// Another mailer took the claim over first
+		m.stats.claimCount.With(prometheus.Labels{"result": "held"}).Inc()
+		return false, nil
+	}
+	m.stats.claimCount.With(prometheus.Labels{"result": "takenOver"}).Inc()
+	return true, nil
+}
+
+// markClaimSent records that the nag for a claimed certificate was sent, so
+// that it isn't sent again if the mailer crashes before finishing the nag.
+func (m *mailer) markClaimSent(serial string) error {
+	if m.dryRun {
+		return nil
+	}
+	_, err := m.dbMap.Exec(
+		"UPDATE expirationNagClaims SET sentAt = ? WHERE serial = ?",
+		m.clk.Now(), serial)
+	return err
+}
+
+// releaseClaims deletes the claims on certs, whose nags weren't sent, so that
+// the next run can send them.
+func (m *mailer) releaseClaims(certs []*x509.Certificate) {
+	if m.dryRun || !features.Enabled(features.ExpirationNagClaims) {
+		return
+	}
+	for _, cert := range certs {
+		serial := core.SerialToString(cert.SerialNumber)
+		_, err := m.dbMap.Exec("DELETE FROM expirationNagClaims WHERE serial = ?", serial)
+		if err != nil {
+			m.log.AuditErrf("Error releasing claim on %s: %s", serial, err)
+			m.stats.errorCount.With(prometheus.Labels{"type": "ClaimCertificate"}).Inc()
+		}
+	}
+}
+
+// finishNag records that the nag for serial was sent in its certificate
+// status, and then deletes its claim.
+func (m *mailer) finishNag(serial string) error {
+	err := m.updateCertStatus(serial)
+	if err != nil || m.dryRun || !features.Enabled(features.ExpirationNagClaims) {
+		return err
+	}
+	_, err = m.dbMap.Exec("DELETE FROM expirationNagClaims WHERE serial = ?", serial)
+	return err
+}
+
+// nagClaim is a row of the expirationNagClaims table.
+type nagClaim struct {
+	Serial    string     `db:"serial"`
+	ClaimedAt time.Time  `db:"claimedAt"`
+	SentAt    *time.Time `db:"sentAt"`
+}
+
 func (m *mailer) findExpiringCertificates() error {
 	now := m.clk.Now()
 	// E.g. m.nagTimes = [2, 4, 8, 15] days from expiration
@@ -268,77 +483,122 @@ func (m *mailer) findExpiringCertificates() error {
 		m.log.Infof("expiration-mailer: Searching for certificates that expire between %s and %s and had last nag >%s before expiry",
 			left.UTC(), right.UTC(), expiresIn)
 
-		// First we do a query on the certificateStatus table to find certificates
-		// nearing expiry meeting our criteria for email notification. We later
-		// sequentially fetch the certificate details. This avoids an expensive
-		// JOIN.
-		var serials []string
-		_, err := m.dbMap.Select(
-			&serials,
-			`SELECT
-				cs.serial
-				FROM certificateStatus AS cs
-				WHERE cs.notAfter > :cutoffA
-				AND cs.notAfter <= :cutoffB
-				AND cs.status != "revoked"
-				AND COALESCE(TIMESTAMPDIFF(SECOND, cs.lastExpirationNagSent, cs.notAfter) > :nagCutoff, 1)
-				ORDER BY cs.notAfter ASC
-				LIMIT :limit`,
-			map[string]interface{}{
-				"cutoffA":   left,
-				"cutoffB":   right,
-				"nagCutoff": expiresIn.Seconds(),
-				"limit":     m.limit,
-			},
-		)
-		if err != nil {
-			m.log.AuditErrf("expiration-mailer: Error loading certificate serials: %s", err)
-			return err
-		}
-
-		// Now we can sequentially retrieve the certificate details for each of the
-		// certificate status rows
-		var certs []core.Certificate
-		for _, serial := range serials {
-			var cert core.Certificate
-			cert, err := sa.SelectCertificate(m.dbMap, "WHERE serial = ?", serial)
+		// Process the group in batches, starting each batch after the last
+		// certificate of the previous one. Certificates that couldn't be
+		// nagged keep matching the query, so paginating by position rather
+		// than by offset means they can't be returned again and again.
+		found := 0
+		after := expiringCert{NotAfter: left}
+		for found < m.limit {
+			batchSize := m.batchSize
+			if batchSize <= 0 || batchSize > m.limit-found {
+				batchSize = m.limit - found
+			}
+			batch, err := m.findExpiringBatch(left, right, expiresIn, after, batchSize)
 			if err != nil {
-				m.log.AuditErrf("expiration-mailer: Error loading cert %q: %s", cert.Serial, err)
 				return err
 			}
-			certs = append(certs, cert)
-		}
+			if len(batch) == 0 {
+				break
+			}
+			found += len(batch)
+			after = batch[len(batch)-1]
+
+			// Now we can sequentially retrieve the certificate details for each
+			// of the certificate status rows
+			var certs []core.Certificate
+			for _, ec := range batch {
+				var cert core.Certificate
+				cert, err := sa.SelectCertificate(m.dbMap, "WHERE serial = ?", ec.Serial)
+				if err != nil {
+					m.log.AuditErrf("expiration-mailer: Error loading cert %q: %s", cert.Serial, err)
+					return err
+				}
+				certs = append(certs, cert)
+			}
 
-		m.log.Infof("Found %d certificates expiring between %s and %s", len(certs),
-			left.Format("2006-01-02 03:04"), right.Format("2006-01-02 03:04"))
+			processingStarted := m.clk.Now()
+			m.processCerts(certs)
+			processingEnded := m.clk.Now()
+			elapsed := processingEnded.Sub(processingStarted)
+			m.stats.processingLatency.Observe(elapsed.Seconds())
 
-		if len(certs) == 0 {
-			continue // nothing to do
+			if len(batch) < batchSize {
+				break
+			}
 		}
 
-		// If the `certs` result was exactly `m.limit` rows we need to increment
-		// a stat indicating that this nag group is at capacity based on the
+		m.log.Infof("Found %d certificates expiring between %s and %s", found,
+			left.Format("2006-01-02 03:04"), right.Format("2006-01-02 03:04"))
+
+		// If we found exactly `m.limit` certificates we need to increment a
+		// stat indicating that this nag group is at capacity based on the
 		// configured cert limit. If this condition continually occurs across mailer
 		// runs then we will not catch up, resulting in under-sending expiration
 		// mails. The effects of this were initially described in issue #2002[0].
 		//
 		// 0: https://github.com/letsencrypt/boulder/issues/2002
-		if len(certs) == m.limit {
+		if found == m.limit {
 			m.log.Infof("nag group %s expiring certificates at configured capacity (cert limit %d)",
 				expiresIn.String(), m.limit)
 			m.stats.nagsAtCapacity.With(prometheus.Labels{"nagGroup": expiresIn.String()}).Set(1)
 		}
-
-		processingStarted := m.clk.Now()
-		m.processCerts(certs)
-		processingEnded := m.clk.Now()
-		elapsed := processingEnded.Sub(processingStarted)
-		m.stats.processingLatency.Observe(elapsed.Seconds())
 	}
 
 	return nil
 }
 
+// expiringCert is a certificate found by findExpiringBatch.
+type expiringCert struct {
+	Serial   string    `db:"serial"`
+	NotAfter time.Time `db:"notAfter"`
+}
+
+// findExpiringBatch returns up to limit certificates that expire between left
+// and right, and haven't been nagged for the expiresIn nag group, ordered by
+// expiry and serial and starting after the certificate after. If the mailer
+// is one of several workers only the certificates in its shard are returned.
+//
+// We do a query on the certificateStatus table to find certificates nearing
+// expiry meeting our criteria for email notification, and the caller later
+// sequentially fetches the certificate details. This avoids an expensive JOIN.
+func (m *mailer) findExpiringBatch(left, right time.Time, expiresIn time.Duration, after expiringCert, limit int) ([]expiringCert, error) {
+	shard := ""
+	if m.workers > 1 {
+		shard = "AND CRC32(cs.serial) % :workers = :worker"
+	}
+	var batch []expiringCert
+	_, err := m.dbMap.Select(
+		&batch,
+		`SELECT
+			cs.serial, cs.notAfter
+			FROM certificateStatus AS cs
+			WHERE cs.notAfter > :cutoffA
+			AND cs.notAfter <= :cutoffB
+			AND (cs.notAfter > :afterNotAfter OR (cs.notAfter = :afterNotAfter AND cs.serial > :afterSerial))
+			AND cs.status != "revoked"
+			AND COALESCE(TIMESTAMPDIFF(SECOND, cs.lastExpirationNagSent, cs.notAfter) > :nagCutoff, 1)
+			`+shard+`
+			ORDER BY cs.notAfter ASC, cs.serial ASC
+			LIMIT :limit`,
+		map[string]interface{}{
+			"cutoffA":       left,
+			"cutoffB":       right,
+			"afterNotAfter": after.NotAfter,
+			"afterSerial":   after.Serial,
+			"nagCutoff":     expiresIn.Seconds(),
+			"workers":       m.workers,
+			"worker":        m.worker,
+			"limit":         limit,
+		},
+	)
+	if err != nil {
+		m.log.AuditErrf("expiration-mailer: Error loading certificate serials: %s", err)
+		return nil, err
+	}
+	return batch, nil
+}
+
 type durationSlice []time.Duration
 
 func (ds durationSlice) Len() int {
@@ -363,16 +623,54 @@ type config struct {
 		Subject string
 
 		CertLimit int
-		NagTimes  []string
+		// BatchSize is how many certificates are loaded and processed at a
+		// time, up to CertLimit for each nag group. It defaults to CertLimit.
+		BatchSize int
+		// Workers is the number of expiration-mailers sharing the
+		// certificates, and WorkerIndex is the index, from zero, of this one.
+		// Each needs the ExpirationNagClaims feature so that they don't send
+		// duplicate nags while the number of workers changes.
+		Workers     int
+		WorkerIndex int
+		// ClaimTimeout is how long a certificate claimed by a mailer that
+		// didn't send its nag waits before another mailer takes it over. It
+		// defaults to an hour.
+		ClaimTimeout cmd.ConfigDuration
+		NagTimes     []string
 		// How much earlier (than configured nag intervals) to
 		// send reminders, to account for the expected delay
 		// before the next expiration-mailer invocation.
 		NagCheckInterval string
 		// Path to a text/template email template
 		EmailTemplate string
+		// Templates for other languages, indexed by language tag (e.g. "fr" or
+		// "pt-BR"). These are used for contacts that request a language with
+		// the Content-Language field of their mailto URL. Contacts that don't,
+		// or that request a language with no templates, get EmailTemplate and
+		// Subject.
+		LocalizedTemplates map[string]struct {
+			// Path to a text/template email template
+			EmailTemplate string
+			Subject       string
+		}
 
 		Frequency cmd.ConfigDuration
 
+		// Webhooks configures delivery of expiration events to webhook
+		// endpoints. Events are sent to the https contact URLs of each
+		// registration and to the Endpoints configured here.
+		Webhooks *struct {
+			// Endpoints receive the expiration events of every registration.
+			Endpoints []string
+			// Path to a PEM encoded ECDSA P-256 private key used to sign
+			// events as JWS.
+			SigningKeyFile string
+			// How long to wait for each delivery attempt.
+			Timeout cmd.ConfigDuration
+			// How many times to attempt each delivery.
+			MaxAttempts int
+		}
+
 		TLS       cmd.TLSConfig
 		SAService *cmd.GRPCClientConfig
 
@@ -426,21 +724,32 @@ func initStats(scope metrics.Scope) mailerStats {
 		})
 	scope.MustRegister(processingLatency)
 
+	claimCount := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "claimConflicts",
+			Help: "Number of certificates already claimed by another mailer, by result",
+		},
+		[]string{"result"})
+	scope.MustRegister(claimCount)
+
 	return mailerStats{
 		nagsAtCapacity:    nagsAtCapacity,
 		errorCount:        errorCount,
 		renewalCount:      renewalCount,
 		sendLatency:       sendLatency,
 		processingLatency: processingLatency,
+		claimCount:        claimCount,
 	}
 }
 
 func main() {
 	configFile := flag.String("config", "", "File path to the configuration file for this service")
 	certLimit := flag.Int("cert_limit", 0, "Count of certificates to process per expiration period")
+	workerIndex := flag.Int("worker_index", -1, "Index of this mailer among the configured workers, overriding the config")
 	reconnBase := flag.Duration("reconnectBase", 1*time.Second, "Base sleep duration between reconnect attempts")
 	reconnMax := flag.Duration("reconnectMax", 5*60*time.Second, "Max sleep duration between reconnect attempts after exponential backoff")
 	daemon := flag.Bool("daemon", false, "Run in daemon mode")
+	dryRun := flag.Bool("dryRun", false, "Log rendered messages instead of sending them, and don't record nags as sent")
 
 	flag.Parse()
 
@@ -466,6 +775,15 @@ func main() {
 	if c.Mailer.CertLimit == 0 {
 		c.Mailer.CertLimit = 100
 	}
+	if *workerIndex >= 0 {
+		c.Mailer.WorkerIndex = *workerIndex
+	}
+	if c.Mailer.Workers > 1 && (c.Mailer.WorkerIndex < 0 || c.Mailer.WorkerIndex >= c.Mailer.Workers) {
+		cmd.Fail(fmt.Sprintf("WorkerIndex must be between 0 and %d", c.Mailer.Workers-1))
+	}
+	if c.Mailer.ClaimTimeout.Duration == 0 {
+		c.Mailer.ClaimTimeout.Duration = defaultClaimTimeout
+	}
 
 	// Configure DB
 	dbURL, err := c.Mailer.DBConfig.URL()
@@ -509,22 +827,39 @@ func main() {
 	subjTmpl, err := template.New("expiry-email-subject").Parse(c.Mailer.Subject)
 	cmd.FailOnError(err, fmt.Sprintf("Could not parse email subject template"))
 
+	localized := make(map[string]localizedTemplate, len(c.Mailer.LocalizedTemplates))
+	for language, lc := range c.Mailer.LocalizedTemplates {
+		emailTmpl, err := ioutil.ReadFile(lc.EmailTemplate)
+		cmd.FailOnError(err, fmt.Sprintf("Could not read %s email template file [%s]", language, lc.EmailTemplate))
+		tmpl, err := template.New("expiry-email-" + language).Parse(string(emailTmpl))
+		cmd.FailOnError(err, fmt.Sprintf("Could not parse %s email template", language))
+		subject := lc.Subject
+		if subject == "" {
+			subject = c.Mailer.Subject
+		}
+		subjTmpl, err := template.New("expiry-email-subject-" + language).Parse(subject)
+		cmd.FailOnError(err, fmt.Sprintf("Could not parse %s email subject template", language))
+		localized[strings.ToLower(language)] = localizedTemplate{subject: subjTmpl, email: tmpl}
+	}
+
 	fromAddress, err := netmail.ParseAddress(c.Mailer.From)
 	cmd.FailOnError(err, fmt.Sprintf("Could not parse from address: %s", c.Mailer.From))
 
-	smtpPassword, err := c.Mailer.PasswordConfig.Pass()
-	cmd.FailOnError(err, "Failed to load SMTP password")
-	mailClient := bmail.New(
-		c.Mailer.Server,
-		c.Mailer.Port,
-		c.Mailer.Username,
-		smtpPassword,
-		smtpRoots,
-		*fromAddress,
-		logger,
-		scope,
-		*reconnBase,
-		*reconnMax)
+	var mailClient bmail.Mailer
+	if *dryRun {
+		logger.Info("Doing a dry run: messages will be logged rather than sent")
+		mailClient = bmail.NewDryRun(*fromAddress, logger)
+	} else {
+		mailClient, err = cmd.NewMailer(
+			c.Mailer.SMTPConfig,
+			smtpRoots,
+			*fromAddress,
+			logger,
+			scope,
+			*reconnBase,
+			*reconnMax)
+		cmd.FailOnError(err, "Failed to set up mailer")
+	}
 
 	nagCheckInterval := defaultNagCheckInterval
 	if s := c.Mailer.NagCheckInterval; s != "" {
@@ -548,16 +883,35 @@ func main() {
 	sort.Sort(nags)
 
 	m := mailer{
-		log:             logger,
-		dbMap:           dbMap,
-		rs:              sac,
-		mailer:          mailClient,
-		subjectTemplate: subjTmpl,
-		emailTemplate:   tmpl,
-		nagTimes:        nags,
-		limit:           c.Mailer.CertLimit,
-		clk:             clk,
-		stats:           initStats(scope),
+		log:                logger,
+		dbMap:              dbMap,
+		rs:                 sac,
+		mailer:             mailClient,
+		subjectTemplate:    subjTmpl,
+		emailTemplate:      tmpl,
+		localizedTemplates: localized,
+		nagTimes:           nags,
+		limit:              c.Mailer.CertLimit,
+		batchSize:          c.Mailer.BatchSize,
+		workers:            c.Mailer.Workers,
+		worker:             c.Mailer.WorkerIndex,
+		claimTimeout:       c.Mailer.ClaimTimeout.Duration,
+		clk:                clk,
+		stats:              initStats(scope),
+		dryRun:             *dryRun,
+	}
+
+	if wc := c.Mailer.Webhooks; wc != nil {
+		signer, err := loadWebhookSigner(wc.SigningKeyFile)
+		cmd.FailOnError(err, "Could not load webhook signing key")
+		for _, endpoint := range wc.Endpoints {
+			parsed, err := url.Parse(endpoint)
+			if err != nil || parsed.Scheme != "https" {
+				cmd.Fail(fmt.Sprintf("Webhook endpoint %q is not an https URL", endpoint))
+			}
+		}
+		m.webhooks = newWebhookSender(signer, wc.Endpoints, wc.Timeout.Duration, wc.MaxAttempts, clk, logger, scope)
+		m.webhooks.dryRun = *dryRun
 	}
 
 	// Prefill this labelled stat with the possible label values, so each value is
@@ -25,6 +25,7 @@ import (
 
 	"github.com/letsencrypt/boulder/core"
 	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/features"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
 	"github.com/letsencrypt/boulder/mocks"
@@ -439,6 +440,79 @@ func TestFindCertsAtCapacity(t *testing.T) {
 	test.AssertEquals(t, len(testCtx.mc.Messages), 0)
 }
 
+func TestFindExpiringCertificatesInBatches(t *testing.T) {
+	testCtx := setup(t, []time.Duration{time.Hour * 24, time.Hour * 24 * 4, time.Hour * 24 * 7})
+
+	addExpiringCerts(t, testCtx)
+
+	testCtx.m.batchSize = 1
+	err := testCtx.m.findExpiringCertificates()
+	test.AssertNotError(t, err, "Failed to find expiring certs")
+	test.AssertEquals(t, len(testCtx.mc.Messages), 2)
+
+	testCtx.mc.Clear()
+	err = testCtx.m.findExpiringCertificates()
+	test.AssertNotError(t, err, "Failed to find expiring certs")
+	test.AssertEquals(t, len(testCtx.mc.Messages), 0)
+}
+
+func TestFindExpiringCertificatesSharded(t *testing.T) {
+	testCtx := setup(t, []time.Duration{time.Hour * 24, time.Hour * 24 * 4, time.Hour * 24 * 7})
+
+	addExpiringCerts(t, testCtx)
+
+	// Between them, the two workers send every nag once
+	testCtx.m.workers = 2
+	sent := 0
+	for worker := 0; worker < 2; worker++ {
+		testCtx.mc.Clear()
+		testCtx.m.worker = worker
+		err := testCtx.m.findExpiringCertificates()
+		test.AssertNotError(t, err, "Failed to find expiring certs")
+		sent += len(testCtx.mc.Messages)
+	}
+	test.AssertEquals(t, sent, 2)
+}
+
+func TestClaimCert(t *testing.T) {
+	testCtx := setup(t, []time.Duration{time.Hour * 24})
+	defer testCtx.cleanUp()
+	if _, err := testCtx.dbMap.Exec("SELECT 1 FROM expirationNagClaims LIMIT 1"); err != nil {
+		t.Skip("expirationNagClaims table requires the next database schema")
+	}
+	_ = features.Set(map[string]bool{"ExpirationNagClaims": true})
+	defer features.Reset()
+	testCtx.m.claimTimeout = time.Hour
+
+	claimed, err := testCtx.m.claimCert("serial")
+	test.AssertNotError(t, err, "claimCert failed")
+	test.Assert(t, claimed, "unclaimed certificate wasn't claimed")
+
+	// Another mailer can't claim it until the claim times out
+	claimed, err = testCtx.m.claimCert("serial")
+	test.AssertNotError(t, err, "claimCert failed")
+	test.Assert(t, !claimed, "claimed certificate was claimed again")
+	test.AssertEquals(t, test.CountCounterVec("result", "held", testCtx.m.stats.claimCount), 1)
+	test.AssertEquals(t, test.CountCounterVec("result", "takenOver", testCtx.m.stats.claimCount), 0)
+	testCtx.fc.Add(2 * time.Hour)
+	claimed, err = testCtx.m.claimCert("serial")
+	test.AssertNotError(t, err, "claimCert failed")
+	test.Assert(t, claimed, "stale claim wasn't taken over")
+	test.AssertEquals(t, test.CountCounterVec("result", "takenOver", testCtx.m.stats.claimCount), 1)
+
+	// Once the nag has been sent the claim is never taken over, even if it's
+	// stale, but is finished instead
+	err = testCtx.m.markClaimSent("serial")
+	test.AssertNotError(t, err, "markClaimSent failed")
+	testCtx.fc.Add(2 * time.Hour)
+	claimed, err = testCtx.m.claimCert("serial")
+	test.AssertNotError(t, err, "claimCert failed")
+	test.Assert(t, !claimed, "sent claim was taken over")
+	count, err := testCtx.dbMap.SelectInt("SELECT COUNT(*) FROM expirationNagClaims")
+	test.AssertNotError(t, err, "counting claims")
+	test.AssertEquals(t, count, int64(0))
+}
+
 func TestCertIsRenewed(t *testing.T) {
 	testCtx := setup(t, []time.Duration{time.Hour * 24, time.Hour * 24 * 4, time.Hour * 24 * 7})
 
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"math/big"
 	"testing"
+	"text/template"
 	"time"
 
+	"github.com/letsencrypt/boulder/metrics"
 	"github.com/letsencrypt/boulder/mocks"
 	"github.com/letsencrypt/boulder/test"
 )
@@ -67,3 +69,51 @@ func newX509Cert(commonName string, notAfter time.Time, dnsNames []string, seria
 	}
 
 }
+
+func TestSendLocalizedNags(t *testing.T) {
+	mc := &mocks.Mailer{}
+	fc := newFakeClock(t)
+	m := mailer{
+		log:             log,
+		mailer:          mc,
+		emailTemplate:   tmpl,
+		subjectTemplate: subjTmpl,
+		localizedTemplates: map[string]localizedTemplate{
+			"fr": {
+				subject: template.Must(template.New("fr-subject").Parse("Expiration de {{.ExpirationSubject}}")),
+				email:   template.Must(template.New("fr").Parse("certificat pour {{.DNSNames}} expire dans {{.DaysToExpiration}} jours")),
+			},
+		},
+		clk:   fc,
+		stats: initStats(metrics.NewNoopScope()),
+	}
+
+	rawCert := newX509Cert("happy",
+		fc.Now().AddDate(0, 0, 2),
+		[]string{"example.com"},
+		serial1,
+	)
+
+	contacts := []string{
+		"mailto:one@example.com?Content-Language=fr-CA",
+		email2,
+		"mailto:three@example.com?content-language=de",
+	}
+	err := m.sendNags(contacts, []*x509.Certificate{rawCert})
+	test.AssertNotError(t, err, "sendNags failed")
+	test.AssertEquals(t, len(mc.Messages), 3)
+
+	// "fr-CA" falls back to the "fr" templates
+	test.AssertEquals(t, mc.Messages[0], mocks.MailerMessage{
+		To:      "one@example.com",
+		Subject: "Expiration de \"example.com\"",
+		Body:    "certificat pour example.com expire dans 2 jours",
+	})
+	// No language, and a language with no templates, both get the default
+	// templates
+	defaultSubject := "Testing: Let's Encrypt certificate expiration notice for domain \"example.com\""
+	test.AssertEquals(t, mc.Messages[1].To, "two@example.com")
+	test.AssertEquals(t, mc.Messages[1].Subject, defaultSubject)
+	test.AssertEquals(t, mc.Messages[2].To, "three@example.com")
+	test.AssertEquals(t, mc.Messages[2].Subject, defaultSubject)
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	jose "gopkg.in/square/go-jose.v2"
+
+	"github.com/letsencrypt/boulder/core"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+)
+
+const (
+	webhookEventType       = "certificate-expiration"
+	webhookContentType     = "application/jose+json"
+	defaultWebhookAttempts = 3
+	defaultWebhookTimeout  = 10 * time.Second
+	webhookBackoffBase     = time.Second
+	webhookBackoffMax      = 30 * time.Second
+)
+
+// webhookEvent is the JSON payload delivered to webhook endpoints. It carries
+// the same information as an expiration email.
+type webhookEvent struct {
+	Type             string    `json:"type"`
+	RegistrationID   int64     `json:"registrationID"`
+	Expires          time.Time `json:"expires"`
+	DaysToExpiration int       `json:"daysToExpiration"`
+	DNSNames         []string  `json:"dnsNames"`
+	Serials          []string  `json:"serials"`
+}
+
+// nonPublicNetworks are the networks that webhook contacts mustn't reach:
+// loopback, private, shared, link-local, documentation, benchmarking,
+// multicast and reserved addresses.
+var nonPublicNetworks = mustParseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"224.0.0.0/3",
+	"::/128",
+	"::1/128",
+	"64:ff9b::/96",
+	"100::/64",
+	"2001:db8::/32",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// publicAddressOnly is a net.Dialer Control function that refuses to connect
+// to addresses in nonPublicNetworks. It sees the address after DNS
+// resolution, so a contact can't reach an internal address through a host
+// name that resolves to one.
+func publicAddressOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("refusing to connect to %q: not an IP address", host)
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return nonPublicAddressError{ip}
+		}
+	}
+	return nil
+}
+
+// nonPublicAddressError is returned when a webhook contact resolves to an
+// address in nonPublicNetworks. Deliveries that fail with it aren't retried.
+type nonPublicAddressError struct {
+	ip net.IP
+}
+
+func (e nonPublicAddressError) Error() string {
+	return fmt.Sprintf("refusing to connect to non-public address %s", e.ip)
+}
+
+// refused returns whether err is a nonPublicAddressError from dialing.
+func refused(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+	_, ok := err.(nonPublicAddressError)
+	return ok
+}
+
+// newContactClient returns the client that delivers events to registrations'
+// webhook contacts. It only connects to public addresses, and doesn't use a
+// proxy, which would make the connections on its behalf.
+func newContactClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: publicAddressOnly,
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: noRedirects,
+	}
+}
+
+// noRedirects makes webhook endpoints respond directly, so that a
+// registration can't use a redirect to point the mailer somewhere else.
+func noRedirects(*http.Request, []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// webhookSender delivers expiration events as signed JSON to webhook endpoints.
+// Endpoints are either configured globally by the operator, in which case they
+// receive events for every registration, or are https contact URLs of an
+// individual registration. Deliveries to contacts use contactClient, which
+// can't reach internal addresses.
+type webhookSender struct {
+	client        *http.Client
+	contactClient *http.Client
+	signer        jose.Signer
+	endpoints     []string
+	maxAttempts   int
+	clk           clock.Clock
+	log           blog.Logger
+	dryRun        bool
+
+	deliveries *prometheus.CounterVec
+	failures   *prometheus.CounterVec
+}
+
+func newWebhookSender(
+	signer jose.Signer,
+	endpoints []string,
+	timeout time.Duration,
+	maxAttempts int,
+	clk clock.Clock,
+	log blog.Logger,
+	scope metrics.Scope,
+) *webhookSender {
+	if timeout == 0 {
+		timeout = defaultWebhookTimeout
+	}
+	if maxAttempts == 0 {
+		maxAttempts = defaultWebhookAttempts
+	}
+
+	deliveries := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhookDeliveries",
+			Help: "Number of webhook events delivered, by whether the endpoint was configured by the operator or is a registration's contact",
+		},
+		[]string{"source"})
+	scope.MustRegister(deliveries)
+
+	failures := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhookFailures",
+			Help: "Number of webhook events that could not be delivered after all retries, by whether the endpoint was configured by the operator or is a registration's contact",
+		},
+		[]string{"source"})
+	scope.MustRegister(failures)
+
+	return &webhookSender{
+		client: &http.Client{
+			Timeout:       timeout,
+			CheckRedirect: noRedirects,
+		},
+		contactClient: newContactClient(timeout),
+		signer:        signer,
+		endpoints:     endpoints,
+		maxAttempts:   maxAttempts,
+		clk:           clk,
+		log:           log,
+		deliveries:    deliveries,
+		failures:      failures,
+	}
+}
+
+// loadWebhookSigner reads a PEM encoded ECDSA P-256 private key to sign
+// webhook events with.
+func loadWebhookSigner(filename string) (jose.Signer, error) {
+	keyPEM, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %q", filename)
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing webhook signing key %q: %s", filename, err)
+	}
+	return newWebhookSigner(key)
+}
+
+func newWebhookSigner(key *ecdsa.PrivateKey) (jose.Signer, error) {
+	if key.Curve.Params().BitSize != 256 {
+		return nil, fmt.Errorf("webhook signing key must use P-256")
+	}
+	return jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, nil)
+}
+
+// webhookContacts returns the contacts that are webhook endpoints.
+func webhookContacts(contacts []string) []string {
+	var endpoints []string
+	for _, contact := range contacts {
+		parsed, err := url.Parse(contact)
+		if err != nil || parsed.Scheme != "https" {
+			continue
+		}
+		endpoints = append(endpoints, contact)
+	}
+	return endpoints
+}
+
+// newWebhookEvent describes the expiration of certs, using the expiration date
+// that is closest to being hit.
+func newWebhookEvent(regID int64, certs []*x509.Certificate, now time.Time) webhookEvent {
+	event := webhookEvent{
+		Type:           webhookEventType,
+		RegistrationID: regID,
+	}
+	var domains []string
+	for i, cert := range certs {
+		domains = append(domains, cert.DNSNames...)
+		event.Serials = append(event.Serials, core.SerialToString(cert.SerialNumber))
+		if i == 0 || cert.NotAfter.Before(event.Expires) {
+			event.Expires = cert.NotAfter
+		}
+	}
+	event.Expires = event.Expires.UTC()
+	event.DaysToExpiration = int(event.Expires.Sub(now).Hours() / 24)
+	event.DNSNames = core.UniqueLowerNames(domains)
+	sort.Strings(event.DNSNames)
+	return event
+}
+
+// notify delivers an event for certs to the globally configured endpoints and
+// to any webhook endpoints among the registration's contacts. Delivery to every
+// endpoint is attempted; the returned error describes the last failure.
+func (ws *webhookSender) notify(ctx context.Context, regID int64, contacts []string, certs []*x509.Certificate) error {
+	contactEndpoints := webhookContacts(contacts)
+	endpoints := append(contactEndpoints, ws.endpoints...)
+	if len(endpoints) == 0 || len(certs) == 0 {
+		return nil
+	}
+
+	event := newWebhookEvent(regID, certs, ws.clk.Now())
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	jws, err := ws.signer.Sign(payload)
+	if err != nil {
+		return err
+	}
+	body := []byte(jws.FullSerialize())
+
+	var lastErr error
+	for i, endpoint := range endpoints {
+		if ws.dryRun {
+			ws.log.Infof("Dry run: would have sent webhook to %s: %s", endpoint, payload)
+			continue
+		}
+		client, source := ws.client, "operator"
+		if i < len(contactEndpoints) {
+			client, source = ws.contactClient, "contact"
+		}
+		err := ws.deliver(ctx, client, endpoint, body)
+		if err != nil {
+			ws.failures.With(prometheus.Labels{"source": source}).Inc()
+			ws.log.Warningf("Failed to deliver webhook for registration %d to %s: %s", regID, endpoint, err)
+			lastErr = err
+			continue
+		}
+		ws.deliveries.With(prometheus.Labels{"source": source}).Inc()
+	}
+	return lastErr
+}
+
+// deliver POSTs body to endpoint, retrying with backoff on network errors,
+// rate limiting and server errors.
+func (ws *webhookSender) deliver(ctx context.Context, client *http.Client, endpoint string, body []byte) error {
+	var err error
+	for attempt := 0; attempt < ws.maxAttempts; attempt++ {
+		ws.clk.Sleep(core.RetryBackoff(attempt, webhookBackoffBase, webhookBackoffMax, 2))
+
+		var retry bool
+		retry, err = ws.post(ctx, client, endpoint, body)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// post makes a single delivery attempt. It returns whether a failure is worth
+// retrying.
+func (ws *webhookSender) post(ctx context.Context, client *http.Client, endpoint string, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", webhookContentType)
+	req.Header.Set("User-Agent", "Boulder expiration-mailer")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return !refused(err), err
+	}
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("endpoint returned HTTP status %d", resp.StatusCode)
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, err
+}
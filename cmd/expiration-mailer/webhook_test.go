@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"golang.org/x/net/context"
+	jose "gopkg.in/square/go-jose.v2"
+
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+type webhookServer struct {
+	sync.Mutex
+	key      *ecdsa.PrivateKey
+	statuses []int
+	events   []webhookEvent
+	requests int
+}
+
+func (ws *webhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ws.Lock()
+	defer ws.Unlock()
+	ws.requests++
+	if len(ws.statuses) > 0 {
+		status := ws.statuses[0]
+		ws.statuses = ws.statuses[1:]
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil || r.Header.Get("Content-Type") != webhookContentType {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	jws, err := jose.ParseSigned(string(body))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	payload, err := jws.Verify(&ws.key.PublicKey)
+	if err != nil {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	var event webhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	ws.events = append(ws.events, event)
+}
+
+func setupWebhooks(t *testing.T, statuses ...int) (*webhookSender, *webhookServer, *httptest.Server) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "generating key")
+	signer, err := newWebhookSigner(key)
+	test.AssertNotError(t, err, "newWebhookSigner failed")
+
+	handler := &webhookServer{key: key, statuses: statuses}
+	srv := httptest.NewTLSServer(handler)
+	fc := clock.NewFake()
+	ws := newWebhookSender(signer, []string{srv.URL + "/global"}, time.Second, 3, fc, log, metrics.NewNoopScope())
+	ws.client = srv.Client()
+	// The test server is on a loopback address, which contacts can't reach
+	ws.contactClient = srv.Client()
+	return ws, handler, srv
+}
+
+func TestWebhookNotify(t *testing.T) {
+	ws, handler, srv := setupWebhooks(t)
+	defer srv.Close()
+
+	cert := &x509.Certificate{
+		NotAfter:     ws.clk.Now().Add(49 * time.Hour),
+		DNSNames:     []string{"www.Example.com", "example.com"},
+		SerialNumber: serial1,
+	}
+	contacts := []string{emailA, srv.URL + "/account"}
+	err := ws.notify(context.Background(), 7, contacts, []*x509.Certificate{cert})
+	test.AssertNotError(t, err, "notify failed")
+
+	// Both the account's webhook contact and the global endpoint should have
+	// received the event
+	test.AssertEquals(t, len(handler.events), 2)
+	test.AssertDeepEquals(t, handler.events[0], webhookEvent{
+		Type:             webhookEventType,
+		RegistrationID:   7,
+		Expires:          cert.NotAfter.UTC(),
+		DaysToExpiration: 2,
+		DNSNames:         []string{"example.com", "www.example.com"},
+		Serials:          []string{serial1String},
+	})
+	test.AssertEquals(t, test.CountCounterVec("source", "contact", ws.deliveries), 1)
+	test.AssertEquals(t, test.CountCounterVec("source", "operator", ws.deliveries), 1)
+}
+
+func TestWebhookRetries(t *testing.T) {
+	ws, handler, srv := setupWebhooks(t, http.StatusServiceUnavailable, http.StatusOK)
+	defer srv.Close()
+
+	cert := &x509.Certificate{NotAfter: ws.clk.Now().Add(time.Hour), SerialNumber: serial1}
+	err := ws.notify(context.Background(), 1, nil, []*x509.Certificate{cert})
+	test.AssertNotError(t, err, "notify failed")
+	test.AssertEquals(t, handler.requests, 2)
+	test.AssertEquals(t, len(handler.events), 1)
+
+	// Client errors aren't retried
+	handler.statuses = []int{http.StatusNotFound}
+	handler.requests = 0
+	err = ws.notify(context.Background(), 1, nil, []*x509.Certificate{cert})
+	test.AssertError(t, err, "notify didn't fail")
+	test.AssertEquals(t, handler.requests, 1)
+	test.AssertEquals(t, test.CountCounterVec("source", "operator", ws.failures), 1)
+
+	// Retries stop after maxAttempts
+	handler.statuses = []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError}
+	handler.requests = 0
+	err = ws.notify(context.Background(), 1, nil, []*x509.Certificate{cert})
+	test.AssertError(t, err, "notify didn't fail")
+	test.AssertEquals(t, handler.requests, 3)
+}
+
+func TestWebhookContactInternalAddress(t *testing.T) {
+	ws, handler, srv := setupWebhooks(t)
+	defer srv.Close()
+	ws.contactClient = newContactClient(time.Second)
+	ws.endpoints = nil
+
+	// A contact naming a host that resolves to a loopback address is refused
+	// when dialing, without retries
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	test.AssertNotError(t, err, "SplitHostPort failed")
+	cert := &x509.Certificate{NotAfter: ws.clk.Now().Add(time.Hour), SerialNumber: serial1}
+	err = ws.notify(context.Background(), 1, []string{"https://localhost:" + port + "/account"}, []*x509.Certificate{cert})
+	test.AssertError(t, err, "notify delivered to a loopback address")
+	test.Assert(t, refused(err), fmt.Sprintf("notify failed with %v, not a refusal", err))
+	test.AssertEquals(t, handler.requests, 0)
+	test.AssertEquals(t, test.CountCounterVec("source", "contact", ws.failures), 1)
+}
+
+func TestPublicAddressOnly(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1:443", "10.1.2.3:443", "172.20.0.1:443", "192.168.1.1:443",
+		"169.254.169.254:80", "100.64.0.1:443", "0.0.0.0:443", "[::1]:443", "[fe80::1]:443", "[fd00::1]:443",
+		"[::ffff:127.0.0.1]:443", "localhost:443"} {
+		test.AssertError(t, publicAddressOnly("tcp", addr, nil), fmt.Sprintf("%s was allowed", addr))
+	}
+	for _, addr := range []string{"8.8.8.8:443", "[2606:4700::1111]:443"} {
+		test.AssertNotError(t, publicAddressOnly("tcp", addr, nil), fmt.Sprintf("%s was refused", addr))
+	}
+}
+
+func TestWebhookDryRun(t *testing.T) {
+	ws, handler, srv := setupWebhooks(t)
+	defer srv.Close()
+	ws.dryRun = true
+
+	cert := &x509.Certificate{NotAfter: ws.clk.Now().Add(time.Hour), SerialNumber: serial1}
+	err := ws.notify(context.Background(), 1, nil, []*x509.Certificate{cert})
+	test.AssertNotError(t, err, "notify failed")
+	test.AssertEquals(t, handler.requests, 0)
+}
+
+func TestWebhookContacts(t *testing.T) {
+	test.AssertDeepEquals(t,
+		webhookContacts([]string{emailA, "https://example.com/hook", "http://example.com/hook"}),
+		[]string{"https://example.com/hook"})
+}
@@ -3,16 +3,20 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/jmhodges/clock"
 	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/core"
 	"github.com/letsencrypt/boulder/features"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
@@ -29,14 +33,39 @@ type eapConfig struct {
 		Syslog cmd.SyslogConfig
 
 		GracePeriod cmd.ConfigDuration
-		BatchSize   int
-		MaxAuthzs   int
-		Parallelism uint
+		// BatchSize is the largest number of authorizations that will be deleted
+		// by a single statement. The size of each batch is adjusted between
+		// MinBatchSize and BatchSize depending on how the database is coping
+		// with the deletes.
+		BatchSize int
+		// MinBatchSize is the smallest number of authorizations that will be
+		// deleted by a single statement. Defaults to 1.
+		MinBatchSize int
+		// MaxAuthzs is the maximum number of authorizations that will be deleted
+		// from each table when not running in daemon mode.
+		MaxAuthzs int
+		// UnlimitedAuthzs deletes every expired authorization when not running
+		// in daemon mode, instead of at most MaxAuthzs.
+		UnlimitedAuthzs bool
 		// MaxDPS controls the maximum number of deletes which will be performed
-		// per second in total from both the pendingAuthorizations and authz tables.
+		// per second from each of the pendingAuthorizations and authz tables.
 		// This can be used to reduce the replication lag caused by creating very
 		// large numbers of delete statements.
 		MaxDPS int
+		// ReplicaDB optionally configures a connection to a replica of the
+		// database. If it is set the replica's replication lag is checked after
+		// each batch and batches are shrunk when it exceeds MaxReplicaLag. The
+		// user needs the REPLICATION CLIENT privilege.
+		ReplicaDB     *cmd.DBConfig
+		MaxReplicaLag cmd.ConfigDuration
+		// MaxBatchLatency is how long deleting a batch may take before the
+		// database is considered to be under too much load and batches are
+		// shrunk.
+		MaxBatchLatency cmd.ConfigDuration
+		// PassInterval is how long to wait after purging everything that has
+		// expired before starting another pass through the tables in daemon
+		// mode.
+		PassInterval cmd.ConfigDuration
 		// PendingCheckpointFile is the path to a file which is used to store the
 		// last pending authorization ID which was deleted. If path is to a file
 		// which does not exist it will be created.
@@ -53,22 +82,122 @@ type eapConfig struct {
 type eapDB interface {
 	Exec(query string, args ...interface{}) (sql.Result, error)
 	Select(i interface{}, query string, args ...interface{}) ([]interface{}, error)
+	SelectInt(query string, args ...interface{}) (int64, error)
 }
 
-var deletedStat = prometheus.NewCounterVec(
-	prometheus.CounterOpts{
-		Name: "eap_authorizations_deleted",
-		Help: "Number of authorizations the EAP has deleted.",
-	},
-	[]string{"table"},
+var (
+	deletedStat = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eap_authorizations_deleted",
+			Help: "Number of authorizations the EAP has deleted.",
+		},
+		[]string{"table"},
+	)
+	backlogStat = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "eap_purge_backlog",
+			Help: "Number of expired authorizations left to delete in the current pass.",
+		},
+		[]string{"table"},
+	)
+	batchSizeStat = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "eap_batch_size",
+			Help: "Number of authorizations the EAP is currently deleting per batch.",
+		},
+		[]string{"table"},
+	)
+	replicaLagStat = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "eap_replica_lag_seconds",
+			Help: "Replication lag of the database replica, as last seen by the EAP.",
+		},
+	)
+)
+
+const (
+	// errorPause is how long to wait after a failed query before trying again.
+	// It's doubled after each consecutive failure, up to maxErrorPause.
+	errorPause    = 500 * time.Millisecond
+	maxErrorPause = time.Minute
+	// maxConsecutiveErrors is how many queries in a row can fail before the
+	// purge gives up.
+	maxConsecutiveErrors = 10
+	// strainPause is how long to wait after a batch that put the database
+	// under strain, to give it a chance to recover.
+	strainPause = time.Second
 )
 
 type expiredAuthzPurger struct {
 	log blog.Logger
 	clk clock.Clock
 	db  eapDB
+	// replicaLag reports the replication lag of a replica of db. It is nil if
+	// no replica has been configured.
+	replicaLag func() (time.Duration, error)
+
+	gracePeriod     time.Duration
+	minBatchSize    int64
+	batchSize       int64
+	maxReplicaLag   time.Duration
+	maxBatchLatency time.Duration
+	maxDPS          int
+	passInterval    time.Duration
+}
 
-	batchSize int64
+// batchController sizes batches of deletes using feedback from the database.
+// The batch size grows additively while replica lag and the time taken to
+// delete a batch stay within their limits, and is halved as soon as either
+// is exceeded.
+type batchController struct {
+	min, max   int64
+	step       int64
+	maxLag     time.Duration
+	maxLatency time.Duration
+
+	size int64
+}
+
+// newBatchController returns a batchController which starts with the smallest
+// batch size and can grow to the largest within roughly ten batches.
+func newBatchController(min, max int64, maxLag, maxLatency time.Duration) *batchController {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	return &batchController{
+		min:        min,
+		max:        max,
+		step:       (max-min)/10 + 1,
+		maxLag:     maxLag,
+		maxLatency: maxLatency,
+		size:       min,
+	}
+}
+
+// strained returns true if either the replica lag or batch latency exceeds its
+// limit. A zero limit is never exceeded.
+func (bc *batchController) strained(lag, latency time.Duration) bool {
+	return (bc.maxLag > 0 && lag > bc.maxLag) || (bc.maxLatency > 0 && latency > bc.maxLatency)
+}
+
+// update adjusts the batch size using the replica lag and latency observed
+// for the last batch and returns whether the database was strained.
+func (bc *batchController) update(lag, latency time.Duration) bool {
+	strained := bc.strained(lag, latency)
+	if strained {
+		bc.size /= 2
+	} else {
+		bc.size += bc.step
+	}
+	if bc.size < bc.min {
+		bc.size = bc.min
+	} else if bc.size > bc.max {
+		bc.size = bc.max
+	}
+	return strained
 }
 
 // loadCheckpoint reads a string (which is assumed to be an authorization ID)
@@ -106,11 +235,63 @@ func saveCheckpoint(checkpointFile, id string) error {
 	return os.Rename(tmp.Name(), checkpointFile)
 }
 
-// getWork selects a set of authorizations that expired before purgeBefore, bounded by batchSize,
-// that have IDs that are more than initialID from either the pendingAuthorizations or authz tables
-// and adds them to the work channel. It returns the last ID it selected and the number of IDs it
-// added to the work channel or an error.
-func (p *expiredAuthzPurger) getWork(work chan string, query string, initialID string, purgeBefore time.Time, batchSize int64) (string, int, error) {
+// replicaLagFunc returns a function that reports the replication lag of the
+// replica db is connected to, according to SHOW SLAVE STATUS.
+func replicaLagFunc(db *sql.DB) func() (time.Duration, error) {
+	return func() (time.Duration, error) {
+		rows, err := db.Query("SHOW SLAVE STATUS")
+		if err != nil {
+			return 0, err
+		}
+		defer func() { _ = rows.Close() }()
+		columns, err := rows.Columns()
+		if err != nil {
+			return 0, err
+		}
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return 0, err
+			}
+			return 0, errors.New("database is not a replica")
+		}
+		values := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		err = rows.Scan(dest...)
+		if err != nil {
+			return 0, err
+		}
+		for i, column := range columns {
+			if column != "Seconds_Behind_Master" {
+				continue
+			}
+			// Seconds_Behind_Master is NULL when replication isn't running.
+			if !values[i].Valid {
+				return 0, errors.New("replication is not running")
+			}
+			seconds, err := strconv.Atoi(values[i].String)
+			if err != nil {
+				return 0, err
+			}
+			return time.Duration(seconds) * time.Second, nil
+		}
+		return 0, errors.New("SHOW SLAVE STATUS has no Seconds_Behind_Master column")
+	}
+}
+
+// getWork selects up to batchSize IDs of authorizations that expired before
+// purgeBefore and have IDs that are more than initialID from either the
+// pendingAuthorizations or authz tables.
+func (p *expiredAuthzPurger) getWork(table string, initialID string, purgeBefore time.Time, batchSize int64) ([]string, error) {
+	var query string
+	switch table {
+	case "pendingAuthorizations":
+		query = "SELECT id FROM pendingAuthorizations WHERE id > :id AND expires <= :expires ORDER BY id LIMIT :limit"
+	case "authz":
+		query = "SELECT id FROM authz WHERE id > :id AND expires <= :expires ORDER BY id LIMIT :limit"
+	}
 	var idBatch []string
 	_, err := p.db.Select(
 		&idBatch,
@@ -122,94 +303,154 @@ func (p *expiredAuthzPurger) getWork(work chan string, query string, initialID s
 		},
 	)
 	if err != nil && err != sql.ErrNoRows {
-		return "", 0, fmt.Errorf("Getting a batch: %s", err)
+		return nil, fmt.Errorf("Getting a batch: %s", err)
+	}
+	return idBatch, nil
+}
+
+// countExpired returns the number of authorizations in table that expired
+// before purgeBefore. Neither table has an index on expires, so this scans the
+// whole table and is only done once per pass.
+func (p *expiredAuthzPurger) countExpired(table string, purgeBefore time.Time) (int64, error) {
+	var query string
+	switch table {
+	case "pendingAuthorizations":
+		query = "SELECT COUNT(1) FROM pendingAuthorizations WHERE expires <= ?"
+	case "authz":
+		query = "SELECT COUNT(1) FROM authz WHERE expires <= ?"
 	}
-	if len(idBatch) == 0 {
-		return initialID, 0, nil
+	return p.db.SelectInt(query, purgeBefore)
+}
+
+// lag returns the current replica lag, or zero if there is no replica. If the
+// lag can't be determined it is assumed to be as bad as it can be.
+func (p *expiredAuthzPurger) lag() time.Duration {
+	if p.replicaLag == nil {
+		return 0
 	}
-	var count int
-	var lastID string
-	for _, v := range idBatch {
-		work <- v
-		count++
-		lastID = v
+	lag, err := p.replicaLag()
+	if err != nil {
+		p.log.Warningf("Checking replica lag: %s", err)
+		return time.Duration(math.MaxInt64)
 	}
-	return lastID, count, nil
+	replicaLagStat.Set(lag.Seconds())
+	return lag
 }
 
-// deleteAuthorizations reads from the work channel and deletes each authorization
-// from either the pendingAuthorization or authz tables. If maxDPS is more than 0
-// it will throttle the number of DELETE statements it generates to the passed rate.
-func (p *expiredAuthzPurger) deleteAuthorizations(work chan string, maxDPS int, parallelism int, table string, checkpointFile string) {
-	wg := new(sync.WaitGroup)
-	deleted := int64(0)
-	var ticker *time.Ticker
-	if maxDPS > 0 {
-		ticker = time.NewTicker(time.Duration(float64(time.Second) / float64(maxDPS)))
-	}
-	for i := 0; i < parallelism; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for id := range work {
-				if ticker != nil {
-					<-ticker.C
-				}
-				err := deleteAuthorization(p.db, table, id)
+// throttle sleeps for long enough that deleting count authorizations, which
+// took elapsed, doesn't exceed maxDPS deletes per second.
+func (p *expiredAuthzPurger) throttle(count int, elapsed time.Duration) {
+	if p.maxDPS <= 0 {
+		return
+	}
+	target := time.Duration(float64(count) / float64(p.maxDPS) * float64(time.Second))
+	if target > elapsed {
+		p.clk.Sleep(target - elapsed)
+	}
+}
+
+// purgePass makes a single pass through table, starting after startID, deleting
+// authorizations that expired more than the grace period ago in batches sized
+// by bc. It stops once the end of the table is reached or, unless limit is
+// negative, once limit authorizations have been deleted. The last ID deleted is
+// saved to checkpointFile after every batch so that an interrupted pass can be
+// resumed, and the checkpoint is cleared once the pass is complete. Failed
+// queries are retried with backoff, and the pass gives up with an error after
+// maxConsecutiveErrors of them.
+func (p *expiredAuthzPurger) purgePass(table, startID string, limit int64, bc *batchController, checkpointFile string) (int64, error) {
+	purgeBefore := p.clk.Now().Add(-p.gracePeriod)
+	backlog, err := p.countExpired(table, purgeBefore)
+	if err != nil {
+		p.log.AuditErrf("Counting expired authorizations in %s: %s", table, err)
+	}
+	backlogStat.WithLabelValues(table).Set(float64(backlog))
+
+	id := startID
+	var deleted int64
+	var failures int
+	// failed records a failed query, returning an error if there have been
+	// too many in a row, and otherwise waiting before the query is retried.
+	failed := func(err error) error {
+		failures++
+		if failures >= maxConsecutiveErrors {
+			return fmt.Errorf("giving up on %s after %d consecutive errors: %s", table, failures, err)
+		}
+		p.clk.Sleep(core.RetryBackoff(failures, errorPause, maxErrorPause, 2))
+		return nil
+	}
+	for limit < 0 || deleted < limit {
+		size := bc.size
+		if limit >= 0 && limit-deleted < size {
+			size = limit - deleted
+		}
+		batchSizeStat.WithLabelValues(table).Set(float64(size))
+		ids, err := p.getWork(table, id, purgeBefore, size)
+		if err != nil {
+			p.log.AuditErr(err.Error())
+			if err := failed(err); err != nil {
+				return deleted, err
+			}
+			continue
+		}
+		if len(ids) == 0 {
+			if checkpointFile != "" {
+				err = saveCheckpoint(checkpointFile, "")
 				if err != nil {
-					p.log.AuditErrf("Deleting %s: %s", id, err)
-				}
-				numDeleted := atomic.AddInt64(&deleted, 1)
-				// Only checkpoint every 1000 IDs in order to prevent unnecessary churn
-				// in the checkpoint file
-				if checkpointFile != "" && numDeleted%1000 == 0 {
-					err = saveCheckpoint(checkpointFile, id)
-					if err != nil {
-						p.log.AuditErrf("failed to checkpoint %q table at ID %q: %s", table, id, err)
-					}
+					p.log.AuditErrf("failed to clear %q table checkpoint: %s", table, err)
 				}
 			}
-		}()
-	}
+			break
+		}
 
-	wg.Wait()
-	p.log.Infof("Deleted a total of %d expired authorizations from %s", deleted, table)
-}
+		start := time.Now()
+		err = deleteAuthorizations(p.db, table, ids)
+		latency := time.Since(start)
+		if err != nil {
+			p.log.AuditErrf("Deleting %d authorizations from %s, ending at %s: %s", len(ids), table, ids[len(ids)-1], err)
+			// The batch is retried, with a smaller size, from the same ID.
+			bc.update(0, time.Duration(math.MaxInt64))
+			if err := failed(err); err != nil {
+				return deleted, err
+			}
+			continue
+		}
+		failures = 0
+		id = ids[len(ids)-1]
+		deleted += int64(len(ids))
+		backlog -= int64(len(ids))
+		if backlog < 0 {
+			backlog = 0
+		}
+		backlogStat.WithLabelValues(table).Set(float64(backlog))
 
-// purge looks up pending or finalized authzs (depending on the value of
-// `table`) that expire before `purgeBefore`, using `parallelism`
-// goroutines. It will delete a maximum of `max` authzs if daemon is not true.
-// Neither table has an index on `expires` by itself, so we just iterate through
-// the table with LIMIT and OFFSET using the default ordering. Note that this
-// becomes expensive once the earliest set of authzs has been purged, since the
-// database will have to scan through many rows before it finds some that meet
-// the expiration criteria. When we move to better authz storage (#2620), we
-// will get an appropriate index that will make this cheaper.
-//
-// If daemon is true purge will run indefinitely looking for authorizations to
-// purge. If getWork returns the same ID that was passed to it then it will
-// sleep a minute before looking for more authorizations again, starting at the
-// same ID.
-//
-// If maxDPS is set the number of DELETE statements from both the pendingAuthorizations
-// and authz tables will be capped at the passed rate.
-func (p *expiredAuthzPurger) purge(
-	table string,
-	purgeBefore time.Time,
-	parallelism int,
-	max int,
-	daemon bool,
-	checkpointFile string,
-	maxDPS int,
-) error {
-	var query string
-	switch table {
-	case "pendingAuthorizations":
-		query = "SELECT id FROM pendingAuthorizations WHERE id > :id AND expires <= :expires ORDER BY id LIMIT :limit"
-	case "authz":
-		query = "SELECT id FROM authz WHERE id > :id AND expires <= :expires ORDER BY id LIMIT :limit"
+		if checkpointFile != "" {
+			err = saveCheckpoint(checkpointFile, id)
+			if err != nil {
+				p.log.AuditErrf("failed to checkpoint %q table at ID %q: %s", table, id, err)
+			}
+		}
+
+		if bc.update(p.lag(), latency) {
+			p.clk.Sleep(strainPause)
+		}
+		p.throttle(len(ids), latency)
 	}
+	return deleted, nil
+}
 
+// purge deletes pending or finalized authzs (depending on the value of
+// `table`) that expired more than the grace period ago. Neither table has an
+// index on `expires` by itself, so we iterate through the table in ID order,
+// deleting the expired authorizations we find in small batches. The size of
+// the batches adapts to the replica lag and the time taken to delete each
+// batch, so that the purger backs off when the database is under load.
+//
+// If daemon is false purge makes a single pass, deleting at most maxAuthzs
+// authorizations, or all of them if maxAuthzs is negative. If daemon is true
+// purge runs indefinitely, starting a new pass through the table every
+// passInterval so that authorizations are deleted continuously as they expire.
+// It returns an error if a pass gives up after repeated database errors.
+func (p *expiredAuthzPurger) purge(table string, daemon bool, maxAuthzs int64, checkpointFile string) error {
 	// id starts as "", which is smaller than all other ids.
 	var id string
 	if checkpointFile != "" {
@@ -220,59 +461,52 @@ func (p *expiredAuthzPurger) purge(
 		id = startID
 	}
 
-	work := make(chan string)
-	go func() {
-		var count int
-
-		var working func() bool
-		if daemon {
-			working = func() bool { return true }
-		} else {
-			working = func() bool { return count < max }
+	limit := int64(-1)
+	if !daemon {
+		limit = maxAuthzs
+	}
+	bc := newBatchController(p.minBatchSize, p.batchSize, p.maxReplicaLag, p.maxBatchLatency)
+	for {
+		deleted, err := p.purgePass(table, id, limit, bc, checkpointFile)
+		p.log.Infof("Deleted a total of %d expired authorizations from %s", deleted, table)
+		if err != nil {
+			return err
 		}
-
-		for working() {
-			lastID, added, err := p.getWork(work, query, id, purgeBefore, p.batchSize)
-			if err != nil {
-				p.log.AuditErr(err.Error())
-				time.Sleep(time.Millisecond * 500)
-				continue
-			} else if daemon && lastID == id {
-				time.Sleep(time.Minute)
-			} else if !daemon && added < int(p.batchSize) {
-				break
-			}
-			count += added
-			id = lastID
+		if !daemon {
+			return nil
 		}
-		close(work)
-	}()
-
-	p.deleteAuthorizations(work, maxDPS, parallelism, table, checkpointFile)
-
-	return nil
+		id = ""
+		p.clk.Sleep(p.passInterval)
+	}
 }
 
-func deleteAuthorization(db eapDB, table, id string) error {
-	// Delete challenges + authorization. We delete challenges first and fail out
-	// if that doesn't succeed so that we don't ever orphan challenges which would
-	// require a relatively expensive join to then find.
-	_, err := db.Exec("DELETE FROM challenges WHERE authorizationID = ?", id)
+// deleteAuthorizations deletes a batch of authorizations and their challenges
+// from either the pendingAuthorizations or authz table.
+func deleteAuthorizations(db eapDB, table string, ids []string) error {
+	// Delete challenges + authorizations. We delete challenges first and fail
+	// out if that doesn't succeed so that we don't ever orphan challenges which
+	// would require a relatively expensive join to then find.
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	_, err := db.Exec("DELETE FROM challenges WHERE authorizationID IN ("+placeholders+")", args...)
 	if err != nil {
 		return err
 	}
 	var query string
 	switch table {
 	case "pendingAuthorizations":
-		query = "DELETE FROM pendingAuthorizations WHERE id = ?"
+		query = "DELETE FROM pendingAuthorizations WHERE id IN (" + placeholders + ")"
 	case "authz":
-		query = "DELETE FROM authz WHERE id = ?"
+		query = "DELETE FROM authz WHERE id IN (" + placeholders + ")"
 	}
-	_, err = db.Exec(query, id)
+	_, err = db.Exec(query, args...)
 	if err != nil {
 		return err
 	}
-	deletedStat.WithLabelValues(table).Inc()
+	deletedStat.WithLabelValues(table).Add(float64(len(ids)))
 	return nil
 }
 
@@ -293,69 +527,79 @@ func main() {
 	err = features.Set(config.ExpiredAuthzPurger.Features)
 	cmd.FailOnError(err, "Failed to set feature flags")
 
+	c := config.ExpiredAuthzPurger
 	var logger blog.Logger
-	if config.ExpiredAuthzPurger.DebugAddr != "" {
+	if c.DebugAddr != "" {
 		var scope metrics.Scope
-		scope, logger = cmd.StatsAndLogging(config.ExpiredAuthzPurger.Syslog, config.ExpiredAuthzPurger.DebugAddr)
+		scope, logger = cmd.StatsAndLogging(c.Syslog, c.DebugAddr)
 		scope.MustRegister(deletedStat)
+		scope.MustRegister(backlogStat)
+		scope.MustRegister(batchSizeStat)
+		scope.MustRegister(replicaLagStat)
 	} else {
-		logger = cmd.NewLogger(config.ExpiredAuthzPurger.Syslog)
+		logger = cmd.NewLogger(c.Syslog)
 	}
 	defer logger.AuditPanic()
 	logger.Info(cmd.VersionString())
 
 	// Configure DB
-	dbURL, err := config.ExpiredAuthzPurger.DBConfig.URL()
+	dbURL, err := c.DBConfig.URL()
 	cmd.FailOnError(err, "Couldn't load DB URL")
-	dbMap, err := sa.NewDbMap(dbURL, config.ExpiredAuthzPurger.DBConfig.MaxDBConns)
+	dbMap, err := sa.NewDbMap(dbURL, c.DBConfig.MaxDBConns)
 	cmd.FailOnError(err, "Could not connect to database")
 	sa.SetSQLDebug(dbMap, logger)
 
 	purger := &expiredAuthzPurger{
-		log:       logger,
-		clk:       cmd.Clock(),
-		db:        dbMap,
-		batchSize: int64(config.ExpiredAuthzPurger.BatchSize),
+		log:             logger,
+		clk:             cmd.Clock(),
+		db:              dbMap,
+		gracePeriod:     c.GracePeriod.Duration,
+		minBatchSize:    int64(c.MinBatchSize),
+		batchSize:       int64(c.BatchSize),
+		maxReplicaLag:   c.MaxReplicaLag.Duration,
+		maxBatchLatency: c.MaxBatchLatency.Duration,
+		maxDPS:          c.MaxDPS,
+		passInterval:    c.PassInterval.Duration,
+	}
+	if purger.passInterval == 0 {
+		purger.passInterval = time.Minute
 	}
 
-	if config.ExpiredAuthzPurger.GracePeriod.Duration == 0 {
+	if c.ReplicaDB != nil {
+		replicaURL, err := c.ReplicaDB.URL()
+		cmd.FailOnError(err, "Couldn't load replica DB URL")
+		replicaMap, err := sa.NewDbMap(replicaURL, c.ReplicaDB.MaxDBConns)
+		cmd.FailOnError(err, "Could not connect to replica database")
+		purger.replicaLag = replicaLagFunc(replicaMap.Db)
+	}
+
+	if c.GracePeriod.Duration == 0 {
 		fmt.Fprintln(os.Stderr, "Grace period is 0, refusing to purge all pending authorizations")
 		os.Exit(1)
 	}
-	if config.ExpiredAuthzPurger.Parallelism == 0 {
-		fmt.Fprintln(os.Stderr, "Parallelism field in config must be set to non-zero")
+	if c.BatchSize == 0 {
+		fmt.Fprintln(os.Stderr, "BatchSize field in config must be set to non-zero")
 		os.Exit(1)
 	}
-	purgeBefore := purger.clk.Now().Add(-config.ExpiredAuthzPurger.GracePeriod.Duration)
+	maxAuthzs := int64(c.MaxAuthzs)
+	if c.UnlimitedAuthzs {
+		maxAuthzs = -1
+	} else if c.MaxAuthzs == 0 && !*daemon {
+		logger.Warning("MaxAuthzs is 0, so no authorizations will be purged. Set UnlimitedAuthzs to purge all of them")
+	}
 	logger.Info("Beginning purge")
 
 	wg := new(sync.WaitGroup)
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		err := purger.purge(
-			"authz",
-			purgeBefore,
-			int(config.ExpiredAuthzPurger.Parallelism),
-			int(config.ExpiredAuthzPurger.MaxAuthzs),
-			*daemon,
-			config.ExpiredAuthzPurger.FinalCheckpointFile,
-			config.ExpiredAuthzPurger.MaxDPS,
-		)
+		err := purger.purge("authz", *daemon, maxAuthzs, c.FinalCheckpointFile)
 		cmd.FailOnError(err, "Failed to purge authorizations")
 	}()
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		err := purger.purge(
-			"pendingAuthorizations",
-			purgeBefore,
-			int(config.ExpiredAuthzPurger.Parallelism),
-			int(config.ExpiredAuthzPurger.MaxAuthzs),
-			*daemon,
-			config.ExpiredAuthzPurger.PendingCheckpointFile,
-			config.ExpiredAuthzPurger.MaxDPS,
-		)
+		err := purger.purge("pendingAuthorizations", *daemon, maxAuthzs, c.PendingCheckpointFile)
 		cmd.FailOnError(err, "Failed to purge authorizations")
 	}()
 	wg.Wait()
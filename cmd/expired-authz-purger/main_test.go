@@ -4,6 +4,11 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -33,17 +38,9 @@ func TestPurgeAuthzs(t *testing.T) {
 	cleanUp := test.ResetSATestDatabase(t)
 	defer cleanUp()
 
-	p := expiredAuthzPurger{log, fc, dbMap, 1}
-
-	err = p.purge(
-		"pendingAuthorizations",
-		time.Time{},
-		10,
-		100,
-		false,
-		"",
-		0,
-	)
+	p := expiredAuthzPurger{log: log, clk: fc, db: dbMap, batchSize: 1}
+
+	err = p.purge("pendingAuthorizations", false, 100, "")
 	test.AssertNotError(t, err, "purgeAuthzs failed")
 
 	old, new := fc.Now().Add(-time.Hour), fc.Now().Add(time.Hour)
@@ -69,15 +66,7 @@ func TestPurgeAuthzs(t *testing.T) {
 	test.AssertNotError(t, err, "NewPendingAuthorization failed")
 
 	deletedStat.Reset()
-	err = p.purge(
-		"pendingAuthorizations",
-		fc.Now(),
-		10,
-		100,
-		false,
-		"",
-		0,
-	)
+	err = p.purge("pendingAuthorizations", false, 100, "")
 	test.AssertNotError(t, err, "purgeAuthzs failed")
 	count, err := dbMap.SelectInt("SELECT COUNT(1) FROM pendingAuthorizations")
 	test.AssertNotError(t, err, "dbMap.SelectInt failed")
@@ -88,15 +77,8 @@ func TestPurgeAuthzs(t *testing.T) {
 	test.AssertEquals(t, test.CountCounterVec("table", "pendingAuthorizations", deletedStat), 2)
 	test.AssertEquals(t, test.CountCounterVec("table", "authz", deletedStat), 0)
 
-	err = p.purge(
-		"pendingAuthorizations",
-		fc.Now().Add(time.Hour),
-		10,
-		100,
-		false,
-		"",
-		0,
-	)
+	p.gracePeriod = -time.Hour
+	err = p.purge("pendingAuthorizations", false, 100, "")
 	test.AssertNotError(t, err, "purgeAuthzs failed")
 	count, err = dbMap.SelectInt("SELECT COUNT(1) FROM pendingAuthorizations")
 	test.AssertNotError(t, err, "dbMap.SelectInt failed")
@@ -109,26 +91,178 @@ func TestPurgeAuthzs(t *testing.T) {
 
 }
 
-type mockDeleter struct{}
+// fakeDB holds the expiry of each authorization in a single table and
+// implements just enough of the queries made by the purger.
+// The next selectErrs selects and deleteErrs deletes of authorizations fail.
+type fakeDB struct {
+	authzs     map[string]time.Time
+	deletes    int
+	selectErrs int
+	deleteErrs int
+}
 
-func (md *mockDeleter) Exec(query string, args ...interface{}) (sql.Result, error) {
+func (db *fakeDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	if strings.HasPrefix(query, "DELETE FROM challenges") {
+		return nil, nil
+	}
+	if db.deleteErrs > 0 {
+		db.deleteErrs--
+		return nil, errors.New("deadlock")
+	}
+	db.deletes++
+	for _, id := range args {
+		delete(db.authzs, id.(string))
+	}
 	return nil, nil
 }
 
-func (md *mockDeleter) Select(i interface{}, query string, args ...interface{}) ([]interface{}, error) {
-	return nil, errors.New("not implemented")
+func (db *fakeDB) Select(i interface{}, query string, args ...interface{}) ([]interface{}, error) {
+	if db.selectErrs > 0 {
+		db.selectErrs--
+		return nil, errors.New("connection refused")
+	}
+	params := args[0].(map[string]interface{})
+	var ids []string
+	for id, expires := range db.authzs {
+		if id > params["id"].(string) && !expires.After(params["expires"].(time.Time)) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	if limit := int(params["limit"].(int64)); len(ids) > limit {
+		ids = ids[:limit]
+	}
+	*i.(*[]string) = ids
+	return nil, nil
 }
 
-func TestMaxDPS(t *testing.T) {
-	log := blog.UseMock()
-	md := &mockDeleter{}
-	p := &expiredAuthzPurger{db: md, log: log}
-	work := make(chan string, 2)
-	work <- "a"
-	work <- "b"
-	close(work)
-	start := time.Now()
-	p.deleteAuthorizations(work, 1, 1, "", "")
-	took := time.Since(start)
-	test.Assert(t, took >= time.Second*2, fmt.Sprintf("deleteAuthorizations was faster than expected. wanted: 2s, got: %s", took))
+func (db *fakeDB) SelectInt(query string, args ...interface{}) (int64, error) {
+	var count int64
+	for _, expires := range db.authzs {
+		if !expires.After(args[0].(time.Time)) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func TestPurgePass(t *testing.T) {
+	fc := clock.NewFake()
+	fc.Set(time.Date(2019, 2, 1, 0, 0, 0, 0, time.UTC))
+	db := &fakeDB{authzs: make(map[string]time.Time)}
+	for i := 0; i < 50; i++ {
+		db.authzs[fmt.Sprintf("expired-%02d", i)] = fc.Now().Add(-48 * time.Hour)
+		db.authzs[fmt.Sprintf("valid-%02d", i)] = fc.Now().Add(48 * time.Hour)
+	}
+	p := &expiredAuthzPurger{
+		log:          blog.NewMock(),
+		clk:          fc,
+		db:           db,
+		gracePeriod:  24 * time.Hour,
+		minBatchSize: 2,
+		batchSize:    10,
+	}
+	bc := newBatchController(p.minBatchSize, p.batchSize, 0, 0)
+
+	// A limited pass should stop early and leave a checkpoint to resume from
+	dir, err := ioutil.TempDir("", "eap")
+	test.AssertNotError(t, err, "creating temp dir")
+	defer func() { _ = os.RemoveAll(dir) }()
+	checkpoint := filepath.Join(dir, "checkpoint")
+	deleted, err := p.purgePass("authz", "", 5, bc, checkpoint)
+	test.AssertNotError(t, err, "purgePass failed")
+	test.AssertEquals(t, deleted, int64(5))
+	id, err := loadCheckpoint(checkpoint)
+	test.AssertNotError(t, err, "loadCheckpoint failed")
+	test.AssertEquals(t, id, "expired-04")
+
+	// A limit of zero deletes nothing
+	deleted, err = p.purgePass("authz", id, 0, bc, checkpoint)
+	test.AssertNotError(t, err, "purgePass failed")
+	test.AssertEquals(t, deleted, int64(0))
+
+	// Failed queries are retried, and a failed batch is retried from the
+	// same ID rather than skipped
+	db.selectErrs = 2
+	db.deleteErrs = 2
+	deleted, err = p.purgePass("authz", id, -1, bc, checkpoint)
+	test.AssertNotError(t, err, "purgePass failed")
+	test.AssertEquals(t, deleted, int64(45))
+	test.AssertEquals(t, len(db.authzs), 50)
+	id, err = loadCheckpoint(checkpoint)
+	test.AssertNotError(t, err, "loadCheckpoint failed")
+	test.AssertEquals(t, id, "")
+	// The batch size should have grown from the minimum to the maximum, so
+	// the deletes shouldn't have been done one batch of two at a time.
+	test.AssertEquals(t, bc.size, int64(10))
+	test.Assert(t, db.deletes < 25, fmt.Sprintf("expected fewer than 25 batches, got %d", db.deletes))
+}
+
+func TestPurgePassGivesUp(t *testing.T) {
+	fc := clock.NewFake()
+	db := &fakeDB{authzs: map[string]time.Time{"expired": fc.Now().Add(-48 * time.Hour)}}
+	p := &expiredAuthzPurger{log: blog.NewMock(), clk: fc, db: db, gracePeriod: 24 * time.Hour, batchSize: 10}
+	bc := newBatchController(1, p.batchSize, 0, 0)
+
+	db.selectErrs = maxConsecutiveErrors
+	_, err := p.purgePass("authz", "", -1, bc, "")
+	test.AssertError(t, err, "purgePass didn't give up on failing selects")
+
+	db.deleteErrs = maxConsecutiveErrors
+	_, err = p.purgePass("authz", "", -1, bc, "")
+	test.AssertError(t, err, "purgePass didn't give up on failing deletes")
+	test.AssertEquals(t, len(db.authzs), 1)
+
+	// Errors only count while they're consecutive
+	db.selectErrs = maxConsecutiveErrors - 1
+	deleted, err := p.purgePass("authz", "", -1, bc, "")
+	test.AssertNotError(t, err, "purgePass failed")
+	test.AssertEquals(t, deleted, int64(1))
+
+	err = p.purge("authz", false, -1, "")
+	test.AssertNotError(t, err, "purge failed")
+	db.selectErrs = maxConsecutiveErrors
+	err = p.purge("authz", true, -1, "")
+	test.AssertError(t, err, "purge in daemon mode didn't return the error of a pass")
+}
+
+func TestBatchController(t *testing.T) {
+	bc := newBatchController(10, 100, time.Second, time.Second)
+	test.AssertEquals(t, bc.size, int64(10))
+
+	// Healthy batches grow the size until it reaches the maximum
+	for i := 0; i < 20; i++ {
+		test.Assert(t, !bc.update(0, time.Millisecond), "healthy batch reported as strained")
+	}
+	test.AssertEquals(t, bc.size, int64(100))
+
+	// Replica lag or slow deletes halve it
+	test.Assert(t, bc.update(2*time.Second, time.Millisecond), "lagging replica not reported as strained")
+	test.AssertEquals(t, bc.size, int64(50))
+	test.Assert(t, bc.update(0, 2*time.Second), "slow batch not reported as strained")
+	test.AssertEquals(t, bc.size, int64(25))
+
+	// But never below the minimum
+	for i := 0; i < 5; i++ {
+		bc.update(2*time.Second, 0)
+	}
+	test.AssertEquals(t, bc.size, int64(10))
+
+	// Zero limits are never exceeded
+	bc = newBatchController(0, 0, 0, 0)
+	test.Assert(t, !bc.update(time.Hour, time.Hour), "zero limits reported as strained")
+	test.AssertEquals(t, bc.size, int64(1))
+}
+
+func TestThrottle(t *testing.T) {
+	fc := clock.NewFake()
+	p := &expiredAuthzPurger{clk: fc, maxDPS: 10}
+	start := fc.Now()
+	p.throttle(20, 500*time.Millisecond)
+	test.AssertEquals(t, fc.Now().Sub(start), 1500*time.Millisecond)
+
+	// Batches that were already slow enough aren't delayed
+	start = fc.Now()
+	p.throttle(20, 3*time.Second)
+	test.AssertEquals(t, fc.Now().Sub(start), time.Duration(0))
 }
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/letsencrypt/boulder/features"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/reloader"
+)
+
+// ReloadFeatures applies the feature flags in filename on top of the
+// configured ones, and does so again whenever the file changes. If filename is
+// empty the configured feature flags are left as they are.
+//
+// The file contains a JSON object mapping feature names to either a boolean,
+// enabling or disabling the feature for every account, or a
+// features.Rollout, enabling it only for some accounts, e.g.:
+//
+//	{"RevokeAtRA": true, "EarlyOrderRateLimit": {"accounts": [1], "percent": 5}}
+func ReloadFeatures(filename string, configured map[string]bool) error {
+	if filename == "" {
+		return nil
+	}
+	return reloader.Register(reloader.Section{
+		Name: "feature flags",
+		File: filename,
+		Load: func(contents []byte) error {
+			var fromFile map[string]json.RawMessage
+			err := json.Unmarshal(contents, &fromFile)
+			if err != nil {
+				return err
+			}
+			merged := make(map[string]bool, len(configured)+len(fromFile))
+			for name, enabled := range configured {
+				merged[name] = enabled
+			}
+			rollouts := make(map[string]features.Rollout)
+			for name, raw := range fromFile {
+				var enabled bool
+				if json.Unmarshal(raw, &enabled) == nil {
+					merged[name] = enabled
+					continue
+				}
+				var rollout features.Rollout
+				err := json.Unmarshal(raw, &rollout)
+				if err != nil {
+					return fmt.Errorf("feature '%s' is neither a boolean nor a rollout: %s", name, err)
+				}
+				rollouts[name] = rollout
+			}
+			return features.Replace(merged, rollouts)
+		},
+	})
+}
+
+// featureOperators holds the SHA-256 hashes of the passwords of the operators
+// allowed to override feature flags, by name.
+var featureOperators struct {
+	sync.RWMutex
+	hashes map[string][]byte
+}
+
+// LoadFeatureOperators loads the operators allowed to override feature flags
+// from filename, in the format described by ServiceConfig's
+// FeatureOperatorsFile. If filename is empty no one is allowed to.
+func LoadFeatureOperators(filename string) error {
+	hashes := make(map[string][]byte)
+	if filename != "" {
+		contents, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		var hexHashes map[string]string
+		err = json.Unmarshal(contents, &hexHashes)
+		if err != nil {
+			return fmt.Errorf("parsing feature operators from %q: %s", filename, err)
+		}
+		for name, hexHash := range hexHashes {
+			hash, err := hex.DecodeString(hexHash)
+			if err != nil || len(hash) != sha256.Size || name == "" {
+				return fmt.Errorf("feature operator %q in %q doesn't have a SHA-256 password hash", name, filename)
+			}
+			hashes[name] = hash
+		}
+	}
+	featureOperators.Lock()
+	defer featureOperators.Unlock()
+	featureOperators.hashes = hashes
+	return nil
+}
+
+// featureOperator returns the name of the operator who made r, if r was made
+// from the local host with the password of an operator loaded by
+// LoadFeatureOperators, as HTTP basic authentication.
+func featureOperator(r *http.Request) (string, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "", false
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return "", false
+	}
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		return "", false
+	}
+	featureOperators.RLock()
+	want, present := featureOperators.hashes[name]
+	featureOperators.RUnlock()
+	if !present {
+		return "", false
+	}
+	got := sha256.Sum256([]byte(password))
+	return name, subtle.ConstantTimeCompare(got[:], want) == 1
+}
+
+// featureOverride is the body of a POST to the /debug/features endpoint.
+type featureOverride struct {
+	Feature string `json:"feature"`
+	// Enabled is the value to override the feature with. If it is nil the
+	// existing override, if any, is removed instead.
+	Enabled *bool `json:"enabled"`
+	// Reason explains the change in the audit log.
+	Reason string `json:"reason"`
+}
+
+// featuresHandler serves the state of every feature flag on GET, and lets
+// operators override flags at runtime, without a restart, on POST. Overrides
+// are only accepted from the local host, authenticated as one of the
+// operators loaded by LoadFeatureOperators, and every change is audit logged
+// with the operator's name.
+type featuresHandler struct {
+	log blog.Logger
+}
+
+func (h featuresHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+	case "POST":
+		operator, ok := featureOperator(r)
+		if !ok {
+			h.log.AuditErrf("Refused unauthenticated feature override from %s", r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Basic realm="feature overrides"`)
+			http.Error(w, "feature overrides must be made locally by an operator", http.StatusUnauthorized)
+			return
+		}
+		var o featureOverride
+		err := json.NewDecoder(r.Body).Decode(&o)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid override: %s", err), http.StatusBadRequest)
+			return
+		}
+		if o.Reason == "" {
+			http.Error(w, "reason is required", http.StatusBadRequest)
+			return
+		}
+		if o.Enabled == nil {
+			err = features.ClearOverride(o.Feature)
+		} else {
+			err = features.Override(o.Feature, *o.Enabled)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if o.Enabled == nil {
+			h.log.AuditInfof("Feature %s override cleared by %q from %s: %s",
+				o.Feature, operator, r.RemoteAddr, o.Reason)
+		} else {
+			h.log.AuditInfof("Feature %s overridden to %t by %q from %s: %s",
+				o.Feature, *o.Enabled, operator, r.RemoteAddr, o.Reason)
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(features.States())
+}
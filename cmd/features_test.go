@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/letsencrypt/boulder/features"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestFeaturesHandler(t *testing.T) {
+	defer features.Reset()
+	log := blog.NewMock()
+	h := featuresHandler{log}
+
+	hash := sha256.Sum256([]byte("hunter2"))
+	f, err := ioutil.TempFile("", "feature-operators")
+	test.AssertNotError(t, err, "creating temp file")
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`{"alice": "` + hex.EncodeToString(hash[:]) + `"}`)
+	test.AssertNotError(t, err, "writing temp file")
+	f.Close()
+	test.AssertNotError(t, LoadFeatureOperators(f.Name()), "LoadFeatureOperators failed")
+	defer func() { _ = LoadFeatureOperators("") }()
+
+	request := func(remoteAddr, user, password, body string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/debug/features", strings.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		if user != "" {
+			req.SetBasicAuth(user, password)
+		}
+		h.ServeHTTP(rw, req)
+		return rw
+	}
+	post := func(body string) *httptest.ResponseRecorder {
+		return request("127.0.0.1:4321", "alice", "hunter2", body)
+	}
+
+	// Overrides must be made locally by an operator
+	enable := `{"feature": "RevokeAtRA", "enabled": true, "reason": "testing"}`
+	rw := request("127.0.0.1:4321", "", "", enable)
+	test.AssertEquals(t, rw.Code, http.StatusUnauthorized)
+	rw = request("127.0.0.1:4321", "alice", "wrong", enable)
+	test.AssertEquals(t, rw.Code, http.StatusUnauthorized)
+	rw = request("127.0.0.1:4321", "mallory", "hunter2", enable)
+	test.AssertEquals(t, rw.Code, http.StatusUnauthorized)
+	rw = request("10.0.0.1:4321", "alice", "hunter2", enable)
+	test.AssertEquals(t, rw.Code, http.StatusUnauthorized)
+	test.Assert(t, !features.Enabled(features.RevokeAtRA), "RevokeAtRA enabled by an unauthenticated request")
+	test.AssertEquals(t, len(log.GetAllMatching(`Refused unauthenticated feature override`)), 4)
+
+	rw = post(`{"feature": "RevokeAtRA", "enabled": true}`)
+	test.AssertEquals(t, rw.Code, http.StatusBadRequest)
+	rw = post(`{"feature": "NotAFeature", "enabled": true, "reason": "testing"}`)
+	test.AssertEquals(t, rw.Code, http.StatusBadRequest)
+	test.Assert(t, !features.Enabled(features.RevokeAtRA), "RevokeAtRA enabled by a rejected request")
+
+	rw = post(enable)
+	test.AssertEquals(t, rw.Code, http.StatusOK)
+	test.Assert(t, features.Enabled(features.RevokeAtRA), "RevokeAtRA not enabled")
+	test.AssertEquals(t, len(log.GetAllMatching(`\[AUDIT\] Feature RevokeAtRA overridden to true by "alice"`)), 1)
+	var states []features.State
+	test.AssertNotError(t, json.Unmarshal(rw.Body.Bytes(), &states), "unmarshaling response")
+	for _, s := range states {
+		if s.Name == "RevokeAtRA" {
+			test.Assert(t, s.Enabled && s.Overridden, "RevokeAtRA state doesn't reflect the override")
+		}
+	}
+
+	rw = post(`{"feature": "RevokeAtRA", "reason": "done testing"}`)
+	test.AssertEquals(t, rw.Code, http.StatusOK)
+	test.Assert(t, !features.Enabled(features.RevokeAtRA), "RevokeAtRA override not cleared")
+	test.AssertEquals(t, len(log.GetAllMatching(`\[AUDIT\] Feature RevokeAtRA override cleared by "alice"`)), 1)
+
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest("DELETE", "/debug/features", nil))
+	test.AssertEquals(t, rw.Code, http.StatusMethodNotAllowed)
+}
+
+func TestLoadFeatureOperators(t *testing.T) {
+	defer func() { _ = LoadFeatureOperators("") }()
+	f, err := ioutil.TempFile("", "feature-operators")
+	test.AssertNotError(t, err, "creating temp file")
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`{"alice": "not a hash"}`)
+	test.AssertNotError(t, err, "writing temp file")
+	f.Close()
+	test.AssertError(t, LoadFeatureOperators(f.Name()), "loaded an operator without a password hash")
+	test.AssertError(t, LoadFeatureOperators(f.Name()+".missing"), "loaded a missing file")
+}
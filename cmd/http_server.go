@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/http2"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+)
+
+// HTTPServerConfig tunes the HTTP servers of a frontend such as the WFE. The
+// zero value leaves Go's defaults in place, which don't hold up well against
+// large fleets of ACME clients that keep connections open.
+type HTTPServerConfig struct {
+	// DisableHTTP2 serves only HTTP/1.1 over TLS. HTTP/2 is otherwise
+	// negotiated with clients that support it.
+	DisableHTTP2 bool
+	// MaxConcurrentStreams is the most requests an HTTP/2 client can make at
+	// once on a connection. Defaults to 250.
+	MaxConcurrentStreams uint32
+
+	// ReadHeaderTimeout is how long a client has to send a request's headers,
+	// and ReadTimeout how long it has to send the whole request.
+	ReadHeaderTimeout ConfigDuration
+	ReadTimeout       ConfigDuration
+	// WriteTimeout is how long a response can take, from the end of reading
+	// the request headers.
+	WriteTimeout ConfigDuration
+	// IdleTimeout is how long a keep-alive connection is kept open while
+	// waiting for the next request.
+	IdleTimeout ConfigDuration
+	// MaxHeaderBytes is the largest request header accepted. Defaults to
+	// 1 MB.
+	MaxHeaderBytes int
+
+	// MaxRequestsPerConn, if non-zero, closes an HTTP/1.1 connection once it
+	// has served that many requests, so that clients are spread across the
+	// servers behind a load balancer rather than sticking to one forever.
+	MaxRequestsPerConn int
+
+	// ReusePort binds listening sockets with SO_REUSEPORT, so that during a
+	// deploy the new process can start listening before the old one stops.
+	// It isn't needed when systemd socket activation passes the sockets in,
+	// which Listen prefers. Linux drops the connections still queued on a
+	// SO_REUSEPORT socket when it's closed, so ShutdownDelay should be long
+	// enough for load balancers to stop sending new connections its way.
+	ReusePort bool
+	// ShutdownDelay is how long the servers keep accepting connections after
+	// the process is told to stop, and has stopped advertising readiness,
+	// before they're shut down. It gives load balancers and the process
+	// taking over the listening sockets time to pick up new connections.
+	ShutdownDelay ConfigDuration
+}
+
+// httpConnTracker counts the connections to one or more HTTP servers, and
+// caps the number of requests served on each HTTP/1.1 connection.
+type httpConnTracker struct {
+	maxRequests int
+
+	conns        *prometheus.CounterVec
+	open         prometheus.Gauge
+	capped       prometheus.Counter
+	requestsLock sync.Mutex
+	// requests counts the requests served on each open connection, by its
+	// remote address.
+	requests map[string]int
+}
+
+func newHTTPConnTracker(maxRequests int, stats metrics.Scope) *httpConnTracker {
+	conns := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_connections",
+		Help: "Number of HTTP connections that were opened, closed or hijacked",
+	}, []string{"event"})
+	stats.MustRegister(conns)
+	open := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_open_connections",
+		Help: "Number of open HTTP connections",
+	})
+	stats.MustRegister(open)
+	capped := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "http_connections_capped",
+		Help: "Number of HTTP connections closed because they reached the cap on requests per connection",
+	})
+	stats.MustRegister(capped)
+	return &httpConnTracker{
+		maxRequests: maxRequests,
+		conns:       conns,
+		open:        open,
+		capped:      capped,
+		requests:    make(map[string]int),
+	}
+}
+
+// connState is an http.Server ConnState hook.
+func (t *httpConnTracker) connState(conn net.Conn, state http.ConnState) {
+	var event string
+	switch state {
+	case http.StateNew:
+		event = "opened"
+		t.open.Inc()
+	case http.StateClosed:
+		event = "closed"
+		t.open.Dec()
+	case http.StateHijacked:
+		event = "hijacked"
+		t.open.Dec()
+	default:
+		return
+	}
+	t.conns.WithLabelValues(event).Inc()
+	if state != http.StateNew && t.maxRequests > 0 {
+		t.requestsLock.Lock()
+		delete(t.requests, conn.RemoteAddr().String())
+		t.requestsLock.Unlock()
+	}
+}
+
+// handler wraps h to ask HTTP/1.1 clients to close their connection once it
+// has served maxRequests requests.
+func (t *httpConnTracker) handler(h http.Handler) http.Handler {
+	if t.maxRequests <= 0 {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 1 {
+			t.requestsLock.Lock()
+			t.requests[r.RemoteAddr]++
+			last := t.requests[r.RemoteAddr] >= t.maxRequests
+			t.requestsLock.Unlock()
+			if last {
+				// The server closes the connection after this response.
+				w.Header().Set("Connection", "close")
+				t.capped.Inc()
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// HTTPServers builds http.Servers tuned by an HTTPServerConfig. All of the
+// servers built by one HTTPServers share its connection metrics.
+type HTTPServers struct {
+	config  HTTPServerConfig
+	tracker *httpConnTracker
+}
+
+// NewHTTPServers returns an HTTPServers for config, registering its
+// connection metrics with stats.
+func NewHTTPServers(config HTTPServerConfig, stats metrics.Scope) *HTTPServers {
+	return &HTTPServers{
+		config:  config,
+		tracker: newHTTPConnTracker(config.MaxRequestsPerConn, stats),
+	}
+}
+
+// New returns an http.Server listening on addr and serving handler.
+func (s *HTTPServers) New(addr string, handler http.Handler) (*http.Server, error) {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s.tracker.handler(handler),
+		ReadHeaderTimeout: s.config.ReadHeaderTimeout.Duration,
+		ReadTimeout:       s.config.ReadTimeout.Duration,
+		WriteTimeout:      s.config.WriteTimeout.Duration,
+		IdleTimeout:       s.config.IdleTimeout.Duration,
+		MaxHeaderBytes:    s.config.MaxHeaderBytes,
+		ConnState:         s.tracker.connState,
+	}
+	if s.config.DisableHTTP2 {
+		// A non-nil empty map stops the server from negotiating HTTP/2.
+		srv.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+		return srv, nil
+	}
+	err := http2.ConfigureServer(srv, &http2.Server{
+		MaxConcurrentStreams: s.config.MaxConcurrentStreams,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return srv, nil
+}
+
+// Shutdown waits for the ShutdownDelay, then stops the servers as
+// ShutdownHTTP does. It's meant for the callback of CatchSignals, which has
+// already stopped advertising readiness.
+func (s *HTTPServers) Shutdown(logger blog.Logger, timeout time.Duration, servers ...*http.Server) {
+	if s.config.ShutdownDelay.Duration > 0 {
+		logger.Infof("Draining connections for %s before shutting down", s.config.ShutdownDelay.Duration)
+		time.Sleep(s.config.ShutdownDelay.Duration)
+	}
+	ShutdownHTTP(logger, timeout, servers...)
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestHTTPServersNew(t *testing.T) {
+	config := HTTPServerConfig{
+		ReadTimeout:    ConfigDuration{time.Second},
+		IdleTimeout:    ConfigDuration{time.Minute},
+		MaxHeaderBytes: 4096,
+	}
+	servers := NewHTTPServers(config, metrics.NewNoopScope())
+	srv, err := servers.New(":0", http.NotFoundHandler())
+	test.AssertNotError(t, err, "New failed")
+	test.AssertEquals(t, srv.ReadTimeout, time.Second)
+	test.AssertEquals(t, srv.IdleTimeout, time.Minute)
+	test.AssertEquals(t, srv.MaxHeaderBytes, 4096)
+	test.Assert(t, srv.TLSNextProto["h2"] != nil, "HTTP/2 wasn't configured")
+
+	config.DisableHTTP2 = true
+	servers = NewHTTPServers(config, metrics.NewNoopScope())
+	srv, err = servers.New(":0", http.NotFoundHandler())
+	test.AssertNotError(t, err, "New failed")
+	test.AssertEquals(t, len(srv.TLSNextProto), 0)
+	test.Assert(t, srv.TLSNextProto != nil, "HTTP/2 wasn't disabled")
+}
+
+func TestHTTPMaxRequestsPerConn(t *testing.T) {
+	servers := NewHTTPServers(HTTPServerConfig{MaxRequestsPerConn: 3}, metrics.NewNoopScope())
+	srv, err := servers.New("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	test.AssertNotError(t, err, "New failed")
+	ts := httptest.NewUnstartedServer(srv.Handler)
+	ts.Config.ConnState = srv.ConnState
+	ts.Start()
+	defer ts.Close()
+
+	// The client reuses one connection until the server closes it after the
+	// third request
+	for i := 1; i <= 6; i++ {
+		resp, err := http.Get(ts.URL)
+		test.AssertNotError(t, err, "GET failed")
+		resp.Body.Close()
+		test.AssertEquals(t, resp.Close, i%3 == 0)
+	}
+	ts.Close()
+
+	tracker := servers.tracker
+	test.AssertEquals(t, test.CountCounter(tracker.capped), 2)
+	test.AssertEquals(t, test.CountCounter(tracker.conns.WithLabelValues("opened")), 2)
+	test.AssertEquals(t, test.CountCounter(tracker.conns.WithLabelValues("closed")), 2)
+	test.AssertEquals(t, len(tracker.requests), 0)
+}
+
+func TestHTTPServersShutdownDelay(t *testing.T) {
+	log := blog.NewMock()
+	servers := NewHTTPServers(HTTPServerConfig{ShutdownDelay: ConfigDuration{50 * time.Millisecond}}, metrics.NewNoopScope())
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	// The server keeps serving during the delay
+	go servers.Shutdown(log, time.Second, srv.Config)
+	time.Sleep(10 * time.Millisecond)
+	resp, err := http.Get(srv.URL)
+	test.AssertNotError(t, err, "Request during the shutdown delay failed")
+	resp.Body.Close()
+	test.AssertEquals(t, len(log.GetAllMatching("Draining connections for 50ms")), 1)
+}
@@ -0,0 +1,500 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/go-gorp/gorp.v2"
+
+	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/features"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/sa"
+)
+
+const usageString = `
+usage:
+issuance-stats aggregate --config <path> [--day <YYYY-MM-DD>] [--daemon]
+issuance-stats export --config <path> --from <YYYY-MM-DD> --to <YYYY-MM-DD> [--csv <path>]
+
+command descriptions:
+  aggregate  Count the names issued on a day, by TLD, key type, challenge type and
+             profile, and store the counts in the issuanceStats table
+  export     Write the stored counts for a range of days as CSV
+
+args:
+  config  File path to the configuration file for this service
+  day     Day to aggregate, in UTC. Defaults to yesterday
+  daemon  Keep running, aggregating each day shortly after it ends
+  from    First day to export
+  to      Last day to export
+  csv     File to write the CSV to. Defaults to stdout
+`
+
+const (
+	dayFormat        = "2006-01-02"
+	defaultBatchSize = 1000
+	defaultDelay     = time.Hour
+	// unknownChallenge is used for names with no record of how they were
+	// validated, e.g. those issued through the v1 API, which doesn't link
+	// certificates to authorizations.
+	unknownChallenge  = "unknown"
+	defaultProfile    = "default"
+	mustStapleProfile = "mustStaple"
+)
+
+var oidTLSFeature = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 24}
+
+type config struct {
+	IssuanceStats struct {
+		cmd.DBConfig
+
+		DebugAddr string
+
+		// BatchSize is the number of certificates read from the database at a
+		// time.
+		BatchSize int
+		// Delay is how long after the end of a day the daemon waits before
+		// aggregating it, to give any stragglers time to be stored.
+		Delay cmd.ConfigDuration
+
+		Features map[string]bool
+	}
+
+	Syslog cmd.SyslogConfig
+}
+
+// statsKey is the set of dimensions issuance is counted by.
+type statsKey struct {
+	TLD           string
+	KeyType       string
+	ChallengeType string
+	Profile       string
+}
+
+// statsRow is a single row of the issuanceStats table.
+type statsRow struct {
+	Day           time.Time `db:"day"`
+	TLD           string    `db:"tld"`
+	KeyType       string    `db:"keyType"`
+	ChallengeType string    `db:"challengeType"`
+	Profile       string    `db:"profile"`
+	NameCount     int64     `db:"nameCount"`
+}
+
+type certRow struct {
+	Serial string `db:"serial"`
+	DER    []byte `db:"der"`
+}
+
+type validatedName struct {
+	Identifier    string `db:"identifier"`
+	ChallengeType string `db:"type"`
+}
+
+// validatedAuthz2 is an authz2 style authorization, which records the type of
+// the challenge that was used to validate it in its attempted column.
+type validatedAuthz2 struct {
+	Identifier string `db:"identifierValue"`
+	Attempted  uint   `db:"attempted"`
+}
+
+type statsDB interface {
+	Select(i interface{}, query string, args ...interface{}) ([]interface{}, error)
+}
+
+type statsAggregator struct {
+	db        statsDB
+	log       blog.Logger
+	batchSize int
+
+	names *prometheus.GaugeVec
+	day   prometheus.Gauge
+}
+
+func newStatsAggregator(db statsDB, batchSize int, logger blog.Logger, scope metrics.Scope) *statsAggregator {
+	if batchSize == 0 {
+		batchSize = defaultBatchSize
+	}
+	names := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "issuance_stats_names",
+		Help: "Number of names issued on the last aggregated day, by key type, challenge type and profile",
+	}, []string{"keyType", "challengeType", "profile"})
+	scope.MustRegister(names)
+	day := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "issuance_stats_day",
+		Help: "Unix timestamp of the start of the last aggregated day",
+	})
+	scope.MustRegister(day)
+	return &statsAggregator{
+		db:        db,
+		log:       logger,
+		batchSize: batchSize,
+		names:     names,
+		day:       day,
+	}
+}
+
+// keyType describes the type and size of a certificate's public key, e.g.
+// "RSA 2048" or "ECDSA P-256".
+func keyType(cert *x509.Certificate) string {
+	switch k := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + k.Curve.Params().Name
+	default:
+		return "unknown"
+	}
+}
+
+// profile describes the kind of certificate requested. Today the only option
+// a subscriber has is whether to request the OCSP Must Staple extension.
+func profile(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidTLSFeature) {
+			return mustStapleProfile
+		}
+	}
+	return defaultProfile
+}
+
+// tld returns the last label of name.
+func tld(name string) string {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// challengeTypes returns the type of challenge used to validate each name of
+// the certificate with the given serial, keyed by the name that was validated.
+// When the NewAuthorizationSchema feature is enabled the order's authz2 style
+// authorizations are included.
+func (s *statsAggregator) challengeTypes(serial string) (map[string]string, error) {
+	var validated []validatedName
+	_, err := s.db.Select(
+		&validated,
+		`SELECT a.identifier, c.type
+		 FROM orders o
+		 JOIN orderToAuthz ota ON ota.orderID = o.id
+		 JOIN authz a ON a.id = ota.authzID
+		 JOIN challenges c ON c.authorizationID = a.id
+		 WHERE o.certificateSerial = ? AND c.status = ?`,
+		serial,
+		string(core.StatusValid),
+	)
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]string, len(validated))
+	for _, v := range validated {
+		var ident core.AcmeIdentifier
+		err := json.Unmarshal([]byte(v.Identifier), &ident)
+		if err != nil {
+			return nil, fmt.Errorf("parsing identifier %q: %s", v.Identifier, err)
+		}
+		types[strings.ToLower(ident.Value)] = v.ChallengeType
+	}
+	if !features.Enabled(features.NewAuthorizationSchema) {
+		return types, nil
+	}
+
+	var validated2 []validatedAuthz2
+	_, err = s.db.Select(
+		&validated2,
+		`SELECT a.identifierValue, a.attempted
+		 FROM orders o
+		 JOIN orderToAuthz2 ota ON ota.orderID = o.id
+		 JOIN authz2 a ON a.id = ota.authzID
+		 WHERE o.certificateSerial = ? AND a.status = ? AND a.attempted IS NOT NULL`,
+		serial,
+		sa.StatusUint(core.StatusValid),
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range validated2 {
+		challengeType, ok := sa.ChallengeTypeFromUint(v.Attempted)
+		if !ok {
+			return nil, fmt.Errorf("unknown challenge type %d attempted for %q", v.Attempted, v.Identifier)
+		}
+		types[strings.ToLower(v.Identifier)] = challengeType
+	}
+	return types, nil
+}
+
+// countCertificate adds each of the names in the certificate to counts.
+func countCertificate(counts map[statsKey]int64, cert *x509.Certificate, challenges map[string]string) {
+	kt, p := keyType(cert), profile(cert)
+	for _, name := range core.UniqueLowerNames(cert.DNSNames) {
+		// Wildcard names are validated by an authorization for the base
+		// domain.
+		challenge, present := challenges[strings.TrimPrefix(name, "*.")]
+		if !present {
+			challenge = unknownChallenge
+		}
+		counts[statsKey{
+			TLD:           tld(name),
+			KeyType:       kt,
+			ChallengeType: challenge,
+			Profile:       p,
+		}]++
+	}
+}
+
+// aggregate counts the names in all of the certificates issued on the day
+// starting at dayStart.
+func (s *statsAggregator) aggregate(dayStart time.Time) (map[statsKey]int64, error) {
+	dayEnd := dayStart.AddDate(0, 0, 1)
+	counts := make(map[statsKey]int64)
+	var lastSerial string
+	var total int
+	for {
+		var certs []certRow
+		_, err := s.db.Select(
+			&certs,
+			"SELECT serial, der FROM certificates WHERE issued >= ? AND issued < ? AND serial > ? ORDER BY serial LIMIT ?",
+			dayStart,
+			dayEnd,
+			lastSerial,
+			s.batchSize,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range certs {
+			cert, err := x509.ParseCertificate(c.DER)
+			if err != nil {
+				s.log.AuditErrf("Failed to parse certificate %s: %s", c.Serial, err)
+				continue
+			}
+			challenges, err := s.challengeTypes(c.Serial)
+			if err != nil {
+				return nil, err
+			}
+			countCertificate(counts, cert, challenges)
+		}
+		total += len(certs)
+		if len(certs) < s.batchSize {
+			break
+		}
+		lastSerial = certs[len(certs)-1].Serial
+	}
+	s.log.Infof("Counted %d certificates issued on %s", total, dayStart.Format(dayFormat))
+	return counts, nil
+}
+
+// store replaces the stored counts for a day with counts, so that
+// aggregating a day more than once doesn't count it twice.
+func store(tx gorp.SqlExecutor, dayStart time.Time, counts map[statsKey]int64) error {
+	_, err := tx.Exec("DELETE FROM issuanceStats WHERE day = ?", dayStart)
+	if err != nil {
+		return err
+	}
+	for k, count := range counts {
+		_, err := tx.Exec(
+			"INSERT INTO issuanceStats (day, tld, keyType, challengeType, profile, nameCount) VALUES (?, ?, ?, ?, ?, ?)",
+			dayStart,
+			k.TLD,
+			k.KeyType,
+			k.ChallengeType,
+			k.Profile,
+			count,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// export updates the metrics with counts. TLDs are left out to keep the
+// number of metric series manageable; they are only available in the table.
+func (s *statsAggregator) export(dayStart time.Time, counts map[statsKey]int64) {
+	s.names.Reset()
+	for k, count := range counts {
+		s.names.With(prometheus.Labels{
+			"keyType":       k.KeyType,
+			"challengeType": k.ChallengeType,
+			"profile":       k.Profile,
+		}).Add(float64(count))
+	}
+	s.day.Set(float64(dayStart.Unix()))
+}
+
+// writeCSV writes rows as CSV with a header line.
+func writeCSV(w io.Writer, rows []statsRow) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"day", "tld", "keyType", "challengeType", "profile", "names"})
+	if err != nil {
+		return err
+	}
+	for _, r := range rows {
+		err := cw.Write([]string{
+			r.Day.UTC().Format(dayFormat),
+			r.TLD,
+			r.KeyType,
+			r.ChallengeType,
+			r.Profile,
+			strconv.FormatInt(r.NameCount, 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func parseDay(day string) (time.Time, error) {
+	return time.ParseInLocation(dayFormat, day, time.UTC)
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func aggregateDay(s *statsAggregator, dbMap *gorp.DbMap, dayStart time.Time) error {
+	counts, err := s.aggregate(dayStart)
+	if err != nil {
+		return err
+	}
+	tx, err := dbMap.Begin()
+	if err != nil {
+		return err
+	}
+	err = store(tx, dayStart, counts)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	s.export(dayStart, counts)
+	s.log.AuditInfof("Stored issuance statistics for %s: %d rows", dayStart.Format(dayFormat), len(counts))
+	return nil
+}
+
+func main() {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, usageString)
+		os.Exit(1)
+	}
+	if len(os.Args) <= 2 {
+		usage()
+	}
+
+	command := os.Args[1]
+	flagSet := flag.NewFlagSet(command, flag.ContinueOnError)
+	configFile := flagSet.String("config", "", "File path to the configuration file for this service")
+	day := flagSet.String("day", "", "Day to aggregate, in UTC")
+	daemon := flagSet.Bool("daemon", false, "Aggregate each day shortly after it ends")
+	from := flagSet.String("from", "", "First day to export")
+	to := flagSet.String("to", "", "Last day to export")
+	csvFile := flagSet.String("csv", "", "File to write the CSV to")
+	err := flagSet.Parse(os.Args[2:])
+	cmd.FailOnError(err, "Error parsing flagset")
+
+	if *configFile == "" {
+		usage()
+	}
+
+	var c config
+	err = cmd.ReadConfigFile(*configFile, &c)
+	cmd.FailOnError(err, "Reading JSON config file into config structure")
+	err = features.Set(c.IssuanceStats.Features)
+	cmd.FailOnError(err, "Failed to set feature flags")
+
+	var scope metrics.Scope
+	var logger blog.Logger
+	if c.IssuanceStats.DebugAddr != "" {
+		scope, logger = cmd.StatsAndLogging(c.Syslog, c.IssuanceStats.DebugAddr)
+	} else {
+		scope, logger = metrics.NewNoopScope(), cmd.NewLogger(c.Syslog)
+	}
+	defer logger.AuditPanic()
+	logger.Info(cmd.VersionString())
+
+	dbURL, err := c.IssuanceStats.DBConfig.URL()
+	cmd.FailOnError(err, "Couldn't load DB URL")
+	dbMap, err := sa.NewDbMap(dbURL, c.IssuanceStats.DBConfig.MaxDBConns)
+	cmd.FailOnError(err, "Could not connect to database")
+	sa.SetSQLDebug(dbMap, logger)
+
+	clk := cmd.Clock()
+
+	switch command {
+	case "aggregate":
+		s := newStatsAggregator(dbMap, c.IssuanceStats.BatchSize, logger, scope)
+		dayStart := startOfDay(clk.Now()).AddDate(0, 0, -1)
+		if *day != "" {
+			dayStart, err = parseDay(*day)
+			cmd.FailOnError(err, "Failed to parse --day")
+		}
+		err = aggregateDay(s, dbMap, dayStart)
+		cmd.FailOnError(err, "Failed to aggregate issuance statistics")
+		if !*daemon {
+			return
+		}
+		delay := c.IssuanceStats.Delay.Duration
+		if delay == 0 {
+			delay = defaultDelay
+		}
+		for {
+			next := startOfDay(clk.Now().Add(-delay)).AddDate(0, 0, 1).Add(delay)
+			clk.Sleep(next.Sub(clk.Now()))
+			dayStart = startOfDay(clk.Now().Add(-delay)).AddDate(0, 0, -1)
+			err = aggregateDay(s, dbMap, dayStart)
+			if err != nil {
+				logger.AuditErrf("Failed to aggregate issuance statistics for %s: %s", dayStart.Format(dayFormat), err)
+			}
+		}
+
+	case "export":
+		if *from == "" || *to == "" {
+			usage()
+		}
+		fromDay, err := parseDay(*from)
+		cmd.FailOnError(err, "Failed to parse --from")
+		toDay, err := parseDay(*to)
+		cmd.FailOnError(err, "Failed to parse --to")
+		var rows []statsRow
+		_, err = dbMap.Select(
+			&rows,
+			"SELECT day, tld, keyType, challengeType, profile, nameCount FROM issuanceStats WHERE day >= ? AND day <= ? ORDER BY day, tld, keyType, challengeType, profile",
+			fromDay,
+			toDay,
+		)
+		cmd.FailOnError(err, "Failed to read issuance statistics")
+		var out bytes.Buffer
+		err = writeCSV(&out, rows)
+		cmd.FailOnError(err, "Failed to write CSV")
+		if *csvFile == "" {
+			_, err = os.Stdout.Write(out.Bytes())
+		} else {
+			err = ioutil.WriteFile(*csvFile, out.Bytes(), 0644)
+		}
+		cmd.FailOnError(err, "Failed to write CSV")
+
+	default:
+		usage()
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/features"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func makeCert(t *testing.T, key interface{}, serial int64, mustStaple bool, names ...string) []byte {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	if mustStaple {
+		template.ExtraExtensions = []pkix.Extension{{
+			Id:    oidTLSFeature,
+			Value: []byte{0x30, 0x03, 0x02, 0x01, 0x05},
+		}}
+	}
+	var pub interface{}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		pub = &k.PublicKey
+	case *ecdsa.PrivateKey:
+		pub = &k.PublicKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, key)
+	test.AssertNotError(t, err, "creating certificate")
+	return der
+}
+
+// fakeDB returns the configured certificates one batch at a time, and the
+// configured validated names for every certificate.
+type fakeDB struct {
+	certs      []certRow
+	validated  []validatedName
+	validated2 []validatedAuthz2
+	selects    int
+}
+
+func (db *fakeDB) Select(i interface{}, query string, args ...interface{}) ([]interface{}, error) {
+	switch out := i.(type) {
+	case *[]certRow:
+		db.selects++
+		lastSerial, limit := args[2].(string), args[3].(int)
+		for _, c := range db.certs {
+			if c.Serial > lastSerial && len(*out) < limit {
+				*out = append(*out, c)
+			}
+		}
+	case *[]validatedName:
+		*out = db.validated
+	case *[]validatedAuthz2:
+		*out = db.validated2
+	}
+	return nil, nil
+}
+
+func TestAggregate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	test.AssertNotError(t, err, "generating RSA key")
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "generating ECDSA key")
+
+	db := &fakeDB{
+		certs: []certRow{
+			{Serial: "01", DER: makeCert(t, rsaKey, 1, false, "example.com", "www.example.com")},
+			{Serial: "02", DER: makeCert(t, ecKey, 2, true, "*.example.org", "example.org")},
+			{Serial: "03", DER: []byte("not a certificate")},
+		},
+		validated: []validatedName{
+			{Identifier: `{"type":"dns","value":"example.com"}`, ChallengeType: "http-01"},
+			{Identifier: `{"type":"dns","value":"example.org"}`, ChallengeType: "dns-01"},
+		},
+	}
+	log := blog.NewMock()
+	s := newStatsAggregator(db, 2, log, metrics.NewNoopScope())
+	day := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	counts, err := s.aggregate(day)
+	test.AssertNotError(t, err, "aggregate failed")
+	test.AssertEquals(t, db.selects, 2)
+	test.AssertEquals(t, len(log.GetAllMatching("Failed to parse certificate 03")), 1)
+
+	test.AssertDeepEquals(t, counts, map[statsKey]int64{
+		{TLD: "com", KeyType: "RSA 2048", ChallengeType: "http-01", Profile: defaultProfile}:        1,
+		{TLD: "com", KeyType: "RSA 2048", ChallengeType: unknownChallenge, Profile: defaultProfile}: 1,
+		{TLD: "org", KeyType: "ECDSA P-256", ChallengeType: "dns-01", Profile: mustStapleProfile}:   2,
+	})
+
+	s.export(day, counts)
+	names, err := test.GaugeValueWithLabels(s.names, prometheus.Labels{
+		"keyType":       "ECDSA P-256",
+		"challengeType": "dns-01",
+		"profile":       mustStapleProfile,
+	})
+	test.AssertNotError(t, err, "reading names gauge")
+	test.AssertEquals(t, names, 2)
+}
+
+func TestChallengeTypesAuthz2(t *testing.T) {
+	db := &fakeDB{
+		validated: []validatedName{
+			{Identifier: `{"type":"dns","value":"example.com"}`, ChallengeType: "http-01"},
+		},
+		validated2: []validatedAuthz2{
+			{Identifier: "Example.org", Attempted: 2},
+			{Identifier: "example.net", Attempted: 3},
+		},
+	}
+	s := newStatsAggregator(db, 2, blog.NewMock(), metrics.NewNoopScope())
+
+	// authz2 style authorizations are only read with the new schema
+	types, err := s.challengeTypes("01")
+	test.AssertNotError(t, err, "challengeTypes failed")
+	test.AssertDeepEquals(t, types, map[string]string{"example.com": "http-01"})
+
+	err = features.Set(map[string]bool{"NewAuthorizationSchema": true})
+	test.AssertNotError(t, err, "Failed to enable NewAuthorizationSchema")
+	defer features.Reset()
+	types, err = s.challengeTypes("01")
+	test.AssertNotError(t, err, "challengeTypes failed")
+	test.AssertDeepEquals(t, types, map[string]string{
+		"example.com": "http-01",
+		"example.org": "dns-01",
+		"example.net": "tls-alpn-01",
+	})
+
+	db.validated2 = append(db.validated2, validatedAuthz2{Identifier: "example.edu", Attempted: 9})
+	_, err = s.challengeTypes("01")
+	test.AssertError(t, err, "challengeTypes accepted an unknown challenge type")
+}
+
+func TestTLD(t *testing.T) {
+	test.AssertEquals(t, tld("www.Example.COM"), "com")
+	test.AssertEquals(t, tld("example.co.uk."), "uk")
+	test.AssertEquals(t, tld("localhost"), "localhost")
+}
+
+func TestWriteCSV(t *testing.T) {
+	var out bytes.Buffer
+	err := writeCSV(&out, []statsRow{{
+		Day:           time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC),
+		TLD:           "com",
+		KeyType:       "RSA 2048",
+		ChallengeType: "http-01",
+		Profile:       defaultProfile,
+		NameCount:     42,
+	}})
+	test.AssertNotError(t, err, "writeCSV failed")
+	test.AssertEquals(t, out.String(), strings.Join([]string{
+		"day,tld,keyType,challengeType,profile,names",
+		"2019-03-01,com,RSA 2048,http-01,default,42",
+		"",
+	}, "\n"))
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	jose "gopkg.in/square/go-jose.v2"
+
+	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/features"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/sa"
+)
+
+const usageString = `
+usage:
+key-inventory --config <path> [--csv <path>] [--daemon]
+
+Counts the key types and sizes of valid accounts and of unexpired
+certificates, to measure how many subscribers a key type deprecation affects.
+
+args:
+  config  File path to the configuration file for this service
+  csv     File to write the counts to as CSV. Defaults to stdout
+  daemon  Keep running, taking an inventory every interval and exporting the
+          counts as metrics instead of writing CSV
+`
+
+const (
+	defaultBatchSize = 1000
+	defaultInterval  = 24 * time.Hour
+
+	kindAccount     = "account"
+	kindCertificate = "certificate"
+)
+
+type config struct {
+	KeyInventory struct {
+		cmd.DBConfig
+
+		DebugAddr string
+
+		// BatchSize is the number of accounts or certificates read from the
+		// database at a time.
+		BatchSize int
+		// Interval is how often the daemon takes an inventory.
+		Interval cmd.ConfigDuration
+
+		Features map[string]bool
+	}
+
+	Syslog cmd.SyslogConfig
+}
+
+// inventoryKey is the set of dimensions keys are counted by.
+type inventoryKey struct {
+	// Kind is what the key belongs to: an account or a certificate.
+	Kind    string
+	KeyType string
+}
+
+type accountRow struct {
+	ID  int64  `db:"id"`
+	JWK []byte `db:"jwk"`
+}
+
+type certRow struct {
+	Serial string `db:"serial"`
+	DER    []byte `db:"der"`
+}
+
+type inventoryDB interface {
+	Select(i interface{}, query string, args ...interface{}) ([]interface{}, error)
+}
+
+type inventory struct {
+	db        inventoryDB
+	log       blog.Logger
+	batchSize int
+
+	keys *prometheus.GaugeVec
+	last prometheus.Gauge
+}
+
+func newInventory(db inventoryDB, batchSize int, logger blog.Logger, scope metrics.Scope) *inventory {
+	if batchSize == 0 {
+		batchSize = defaultBatchSize
+	}
+	keys := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "key_inventory_keys",
+		Help: "Number of valid accounts and unexpired certificates in the last inventory, by key type",
+	}, []string{"kind", "keyType"})
+	scope.MustRegister(keys)
+	last := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "key_inventory_last_run",
+		Help: "Unix timestamp of the last completed key inventory",
+	})
+	scope.MustRegister(last)
+	return &inventory{
+		db:        db,
+		log:       logger,
+		batchSize: batchSize,
+		keys:      keys,
+		last:      last,
+	}
+}
+
+// keyType describes the type and size of a public key, e.g. "RSA 2048" or
+// "ECDSA P-256".
+func keyType(key interface{}) string {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + k.Curve.Params().Name
+	default:
+		return "unknown"
+	}
+}
+
+// countAccounts adds the key types of all valid accounts to counts.
+func (inv *inventory) countAccounts(counts map[inventoryKey]int64) error {
+	var lastID int64
+	var total int
+	for {
+		var accounts []accountRow
+		_, err := inv.db.Select(
+			&accounts,
+			"SELECT id, jwk FROM registrations WHERE status = ? AND id > ? ORDER BY id LIMIT ?",
+			string(core.StatusValid),
+			lastID,
+			inv.batchSize,
+		)
+		if err != nil {
+			return err
+		}
+		for _, a := range accounts {
+			var jwk jose.JSONWebKey
+			err := jwk.UnmarshalJSON(a.JWK)
+			if err != nil {
+				inv.log.AuditErrf("Failed to parse key of account %d: %s", a.ID, err)
+				continue
+			}
+			counts[inventoryKey{Kind: kindAccount, KeyType: keyType(jwk.Key)}]++
+		}
+		total += len(accounts)
+		if len(accounts) < inv.batchSize {
+			break
+		}
+		lastID = accounts[len(accounts)-1].ID
+	}
+	inv.log.Infof("Counted the keys of %d accounts", total)
+	return nil
+}
+
+// countCertificates adds the key types of all certificates unexpired at now
+// to counts.
+func (inv *inventory) countCertificates(counts map[inventoryKey]int64, now time.Time) error {
+	var lastSerial string
+	var total int
+	for {
+		var certs []certRow
+		_, err := inv.db.Select(
+			&certs,
+			"SELECT serial, der FROM certificates WHERE expires > ? AND serial > ? ORDER BY serial LIMIT ?",
+			now,
+			lastSerial,
+			inv.batchSize,
+		)
+		if err != nil {
+			return err
+		}
+		for _, c := range certs {
+			cert, err := x509.ParseCertificate(c.DER)
+			if err != nil {
+				inv.log.AuditErrf("Failed to parse certificate %s: %s", c.Serial, err)
+				continue
+			}
+			counts[inventoryKey{Kind: kindCertificate, KeyType: keyType(cert.PublicKey)}]++
+		}
+		total += len(certs)
+		if len(certs) < inv.batchSize {
+			break
+		}
+		lastSerial = certs[len(certs)-1].Serial
+	}
+	inv.log.Infof("Counted the keys of %d certificates", total)
+	return nil
+}
+
+// take counts the key types of all valid accounts and of all certificates
+// unexpired at now.
+func (inv *inventory) take(now time.Time) (map[inventoryKey]int64, error) {
+	counts := make(map[inventoryKey]int64)
+	err := inv.countAccounts(counts)
+	if err != nil {
+		return nil, err
+	}
+	err = inv.countCertificates(counts, now)
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// export updates the metrics with counts.
+func (inv *inventory) export(now time.Time, counts map[inventoryKey]int64) {
+	inv.keys.Reset()
+	for k, count := range counts {
+		inv.keys.With(prometheus.Labels{
+			"kind":    k.Kind,
+			"keyType": k.KeyType,
+		}).Set(float64(count))
+	}
+	inv.last.Set(float64(now.Unix()))
+}
+
+// writeCSV writes counts as CSV with a header line, sorted by kind and key
+// type.
+func writeCSV(w io.Writer, counts map[inventoryKey]int64) error {
+	keys := make([]inventoryKey, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Kind != keys[j].Kind {
+			return keys[i].Kind < keys[j].Kind
+		}
+		return keys[i].KeyType < keys[j].KeyType
+	})
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"kind", "keyType", "count"})
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		err := cw.Write([]string{k.Kind, k.KeyType, strconv.FormatInt(counts[k], 10)})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func main() {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, usageString)
+		os.Exit(1)
+	}
+
+	configFile := flag.String("config", "", "File path to the configuration file for this service")
+	csvFile := flag.String("csv", "", "File to write the counts to as CSV")
+	daemon := flag.Bool("daemon", false, "Take an inventory every interval and export the counts as metrics")
+	flag.Parse()
+	if *configFile == "" {
+		usage()
+	}
+
+	var c config
+	err := cmd.ReadConfigFile(*configFile, &c)
+	cmd.FailOnError(err, "Reading JSON config file into config structure")
+	err = features.Set(c.KeyInventory.Features)
+	cmd.FailOnError(err, "Failed to set feature flags")
+
+	var scope metrics.Scope
+	var logger blog.Logger
+	if c.KeyInventory.DebugAddr != "" {
+		scope, logger = cmd.StatsAndLogging(c.Syslog, c.KeyInventory.DebugAddr)
+	} else {
+		scope, logger = metrics.NewNoopScope(), cmd.NewLogger(c.Syslog)
+	}
+	defer logger.AuditPanic()
+	logger.Info(cmd.VersionString())
+
+	dbURL, err := c.KeyInventory.DBConfig.URL()
+	cmd.FailOnError(err, "Couldn't load DB URL")
+	dbMap, err := sa.NewDbMap(dbURL, c.KeyInventory.DBConfig.MaxDBConns)
+	cmd.FailOnError(err, "Could not connect to database")
+	sa.SetSQLDebug(dbMap, logger)
+
+	clk := cmd.Clock()
+	inv := newInventory(dbMap, c.KeyInventory.BatchSize, logger, scope)
+
+	if !*daemon {
+		counts, err := inv.take(clk.Now())
+		cmd.FailOnError(err, "Failed to take key inventory")
+		var out bytes.Buffer
+		err = writeCSV(&out, counts)
+		cmd.FailOnError(err, "Failed to write CSV")
+		if *csvFile == "" {
+			_, err = os.Stdout.Write(out.Bytes())
+		} else {
+			err = ioutil.WriteFile(*csvFile, out.Bytes(), 0644)
+		}
+		cmd.FailOnError(err, "Failed to write CSV")
+		return
+	}
+
+	interval := c.KeyInventory.Interval.Duration
+	if interval == 0 {
+		interval = defaultInterval
+	}
+	for {
+		now := clk.Now()
+		counts, err := inv.take(now)
+		if err != nil {
+			logger.AuditErrf("Failed to take key inventory: %s", err)
+		} else {
+			inv.export(now, counts)
+			logger.Infof("Took key inventory: %d key types", len(counts))
+		}
+		clk.Sleep(interval)
+	}
+}
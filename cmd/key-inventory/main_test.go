@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	jose "gopkg.in/square/go-jose.v2"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func makeCert(t *testing.T, key interface{}, serial int64) []byte {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	var pub interface{}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		pub = &k.PublicKey
+	case *ecdsa.PrivateKey:
+		pub = &k.PublicKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, pub, key)
+	test.AssertNotError(t, err, "creating certificate")
+	return der
+}
+
+func makeJWK(t *testing.T, key interface{}) []byte {
+	jwk, err := json.Marshal(jose.JSONWebKey{Key: key})
+	test.AssertNotError(t, err, "marshaling JWK")
+	return jwk
+}
+
+// fakeDB returns the configured accounts and certificates one batch at a
+// time.
+type fakeDB struct {
+	accounts []accountRow
+	certs    []certRow
+	selects  int
+}
+
+func (db *fakeDB) Select(i interface{}, query string, args ...interface{}) ([]interface{}, error) {
+	db.selects++
+	switch out := i.(type) {
+	case *[]accountRow:
+		lastID, limit := args[1].(int64), args[2].(int)
+		for _, a := range db.accounts {
+			if a.ID > lastID && len(*out) < limit {
+				*out = append(*out, a)
+			}
+		}
+	case *[]certRow:
+		lastSerial, limit := args[1].(string), args[2].(int)
+		for _, c := range db.certs {
+			if c.Serial > lastSerial && len(*out) < limit {
+				*out = append(*out, c)
+			}
+		}
+	}
+	return nil, nil
+}
+
+func TestInventory(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	test.AssertNotError(t, err, "generating RSA key")
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "generating ECDSA key")
+
+	db := &fakeDB{
+		accounts: []accountRow{
+			{ID: 1, JWK: makeJWK(t, &rsaKey.PublicKey)},
+			{ID: 2, JWK: makeJWK(t, &rsaKey.PublicKey)},
+			{ID: 3, JWK: makeJWK(t, &ecKey.PublicKey)},
+			{ID: 4, JWK: []byte("not a key")},
+		},
+		certs: []certRow{
+			{Serial: "01", DER: makeCert(t, rsaKey, 1)},
+			{Serial: "02", DER: makeCert(t, ecKey, 2)},
+			{Serial: "03", DER: makeCert(t, ecKey, 3)},
+			{Serial: "04", DER: []byte("not a certificate")},
+		},
+	}
+	log := blog.NewMock()
+	inv := newInventory(db, 2, log, metrics.NewNoopScope())
+	now := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	counts, err := inv.take(now)
+	test.AssertNotError(t, err, "take failed")
+	// Two full batches of accounts and certificates, and an empty one of each
+	test.AssertEquals(t, db.selects, 6)
+	test.AssertEquals(t, len(log.GetAllMatching("Failed to parse key of account 4")), 1)
+	test.AssertEquals(t, len(log.GetAllMatching("Failed to parse certificate 04")), 1)
+
+	test.AssertDeepEquals(t, counts, map[inventoryKey]int64{
+		{Kind: kindAccount, KeyType: "RSA 2048"}:        2,
+		{Kind: kindAccount, KeyType: "ECDSA P-256"}:     1,
+		{Kind: kindCertificate, KeyType: "RSA 2048"}:    1,
+		{Kind: kindCertificate, KeyType: "ECDSA P-256"}: 2,
+	})
+
+	inv.export(now, counts)
+	accounts, err := test.GaugeValueWithLabels(inv.keys, prometheus.Labels{
+		"kind":    kindAccount,
+		"keyType": "RSA 2048",
+	})
+	test.AssertNotError(t, err, "reading keys gauge")
+	test.AssertEquals(t, accounts, 2)
+
+	var out bytes.Buffer
+	err = writeCSV(&out, counts)
+	test.AssertNotError(t, err, "writeCSV failed")
+	test.AssertEquals(t, out.String(), strings.Join([]string{
+		"kind,keyType,count",
+		"account,ECDSA P-256,1",
+		"account,RSA 2048,2",
+		"certificate,ECDSA P-256,2",
+		"certificate,RSA 2048,1",
+		"",
+	}, "\n"))
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket
+// activation. Tests change it to hand over sockets of their own.
+var listenFDsStart = 3
+
+// activated holds the listening sockets passed to this process by systemd
+// socket activation that haven't been claimed by Listen yet.
+var activated struct {
+	sync.Mutex
+	loaded    bool
+	listeners []net.Listener
+}
+
+// activatedListener returns the listening socket for addr passed to this
+// process by systemd socket activation, or nil if there isn't one. Each
+// socket is returned at most once.
+func activatedListener(addr string) (net.Listener, error) {
+	activated.Lock()
+	defer activated.Unlock()
+	if !activated.loaded {
+		listeners, err := loadActivatedListeners()
+		if err != nil {
+			return nil, err
+		}
+		activated.listeners = listeners
+		activated.loaded = true
+	}
+	want, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	for i, l := range activated.listeners {
+		have, ok := l.Addr().(*net.TCPAddr)
+		if !ok || have.Port != want.Port {
+			continue
+		}
+		if have.IP.Equal(want.IP) || (have.IP.IsUnspecified() && (want.IP == nil || want.IP.IsUnspecified())) {
+			activated.listeners = append(activated.listeners[:i], activated.listeners[i+1:]...)
+			return l, nil
+		}
+	}
+	return nil, nil
+}
+
+// loadActivatedListeners returns the listening sockets described by the
+// LISTEN_PID and LISTEN_FDS environment variables, and unsets them so that
+// child processes don't mistake the sockets for their own.
+func loadActivatedListeners() ([]net.Listener, error) {
+	pid, fds := os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS")
+	if pid == "" || fds == "" || pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+	n, err := strconv.Atoi(fds)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %q", fds)
+	}
+	var listeners []net.Listener
+	for fd := listenFDsStart; fd < listenFDsStart+n; fd++ {
+		syscall.CloseOnExec(fd)
+		f := os.NewFile(uintptr(fd), fmt.Sprintf("LISTEN_FD_%d", fd))
+		// FileListener duplicates the descriptor, so f can be closed.
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("socket activation descriptor %d: %s", fd, err)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// tcpKeepAlivePeriod is the TCP keep-alive period of accepted connections,
+// the same as http.Server.ListenAndServe uses, so that the connections of
+// clients that went away without closing them are eventually dropped.
+const tcpKeepAlivePeriod = 3 * time.Minute
+
+// keepAliveListener sets TCP keep-alives on the connections it accepts.
+type keepAliveListener struct {
+	*net.TCPListener
+}
+
+func (l keepAliveListener) Accept() (net.Conn, error) {
+	conn, err := l.AcceptTCP()
+	if err != nil {
+		return nil, err
+	}
+	conn.SetKeepAlive(true)
+	conn.SetKeepAlivePeriod(tcpKeepAlivePeriod)
+	return conn, nil
+}
+
+// withKeepAlive returns l with TCP keep-alives set on the connections it
+// accepts, if it's a TCP listener.
+func withKeepAlive(l net.Listener) net.Listener {
+	if tl, ok := l.(*net.TCPListener); ok {
+		return keepAliveListener{tl}
+	}
+	return l
+}
+
+// reusePort is a net.ListenConfig Control function that sets SO_REUSEPORT on
+// a socket before it's bound.
+func reusePort(network, address string, c syscall.RawConn) error {
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// Listen returns a TCP listener for addr, to be passed to the Serve or
+// ServeTLS method of a server. If systemd passed this process a socket bound
+// to addr, that socket is used, so that a new process started with the same
+// sockets accepts the connections that the old one leaves. Otherwise a new
+// socket is bound, with SO_REUSEPORT if the ReusePort option is set, so that
+// a new process can bind addr before the old one has shut down. Either way,
+// TCP keep-alives are set on the accepted connections, like ListenAndServe
+// does.
+func (s *HTTPServers) Listen(addr string) (net.Listener, error) {
+	l, err := activatedListener(addr)
+	if err != nil {
+		return nil, err
+	}
+	if l != nil {
+		return withKeepAlive(l), nil
+	}
+	var lc net.ListenConfig
+	if s.config.ReusePort {
+		lc.Control = reusePort
+	}
+	l, err = lc.Listen(context.Background(), "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return withKeepAlive(l), nil
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestListenReusePort(t *testing.T) {
+	servers := NewHTTPServers(HTTPServerConfig{ReusePort: true}, metrics.NewNoopScope())
+	first, err := servers.Listen("127.0.0.1:0")
+	test.AssertNotError(t, err, "Listen failed")
+	defer first.Close()
+	addr := first.Addr().String()
+
+	// A new process taking over can bind the address while the old one is
+	// still serving
+	second, err := servers.Listen(addr)
+	test.AssertNotError(t, err, "Listen with SO_REUSEPORT on an address in use failed")
+	defer second.Close()
+
+	servers = NewHTTPServers(HTTPServerConfig{}, metrics.NewNoopScope())
+	_, err = servers.Listen(addr)
+	test.AssertError(t, err, "Listen without SO_REUSEPORT on an address in use succeeded")
+}
+
+func TestListenSocketActivation(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	test.AssertNotError(t, err, "Listen failed")
+	defer l.Close()
+	f, err := l.(*net.TCPListener).File()
+	test.AssertNotError(t, err, "File failed")
+	// The descriptor passed in is owned by the process it's passed to
+	fd, err := syscall.Dup(int(f.Fd()))
+	test.AssertNotError(t, err, "Dup failed")
+	f.Close()
+
+	defer func(start int) { listenFDsStart = start }(listenFDsStart)
+	listenFDsStart = fd
+	activated.loaded = false
+	activated.listeners = nil
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	os.Setenv("LISTEN_FDS", "1")
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+
+	servers := NewHTTPServers(HTTPServerConfig{}, metrics.NewNoopScope())
+	other, err := servers.Listen("127.0.0.1:0")
+	test.AssertNotError(t, err, "Listen failed")
+	defer other.Close()
+	test.Assert(t, other.Addr().String() != l.Addr().String(), "Listen used a passed socket bound to another address")
+	test.AssertEquals(t, os.Getenv("LISTEN_FDS"), "")
+
+	passed, err := servers.Listen(l.Addr().String())
+	test.AssertNotError(t, err, "Listen failed")
+	defer passed.Close()
+	test.AssertEquals(t, passed.Addr().String(), l.Addr().String())
+	_, ok := passed.(keepAliveListener)
+	test.Assert(t, ok, "Listen didn't set keep-alives on a passed socket")
+
+	// Each passed socket is only used once
+	_, err = servers.Listen(l.Addr().String())
+	test.AssertError(t, err, "Listen reused a passed socket")
+}
+
+func TestListenKeepAlive(t *testing.T) {
+	servers := NewHTTPServers(HTTPServerConfig{}, metrics.NewNoopScope())
+	l, err := servers.Listen("127.0.0.1:0")
+	test.AssertNotError(t, err, "Listen failed")
+	defer l.Close()
+	_, ok := l.(keepAliveListener)
+	test.Assert(t, ok, "Listen didn't return a keep-alive listener")
+
+	go func() {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err == nil {
+			conn.Close()
+		}
+	}()
+	conn, err := l.Accept()
+	test.AssertNotError(t, err, "Accept failed")
+	defer conn.Close()
+	raw, err := conn.(*net.TCPConn).SyscallConn()
+	test.AssertNotError(t, err, "SyscallConn failed")
+	var keepAlive, idle int
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		keepAlive, sockErr = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_KEEPALIVE)
+		if sockErr == nil {
+			idle, sockErr = unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_KEEPIDLE)
+		}
+	})
+	test.AssertNotError(t, err, "Control failed")
+	test.AssertNotError(t, sockErr, "GetsockoptInt failed")
+	test.AssertEquals(t, keepAlive, 1)
+	test.AssertEquals(t, idle, int(tcpKeepAlivePeriod/time.Second))
+}
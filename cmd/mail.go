@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	blog "github.com/letsencrypt/boulder/log"
+	bmail "github.com/letsencrypt/boulder/mail"
+	"github.com/letsencrypt/boulder/metrics"
+)
+
+// NewMailer returns the Mailer c configures: an APIMailer if c.API is set,
+// otherwise one that sends mail through c's SMTP server. If the APIMailer has
+// a bounce webhook configured, it is served in the background.
+func NewMailer(
+	c SMTPConfig,
+	smtpRoots *x509.CertPool,
+	from mail.Address,
+	logger blog.Logger,
+	scope metrics.Scope,
+	reconnectBase time.Duration,
+	reconnectMax time.Duration,
+) (bmail.Mailer, error) {
+	var dkim *bmail.DKIMSigner
+	if c.DKIM != nil {
+		var err error
+		dkim, err = bmail.NewDKIMSigner(c.DKIM.Domain, c.DKIM.Selector, c.DKIM.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.API == nil {
+		password, err := c.PasswordConfig.Pass()
+		if err != nil {
+			return nil, err
+		}
+		m := bmail.New(
+			c.Server,
+			c.Port,
+			c.Username,
+			password,
+			smtpRoots,
+			from,
+			logger,
+			scope,
+			reconnectBase,
+			reconnectMax)
+		m.SetDKIMSigner(dkim)
+		return m, nil
+	}
+
+	apiKey, err := c.API.PasswordConfig.Pass()
+	if err != nil {
+		return nil, err
+	}
+	suppressed, err := bmail.NewSuppressionList(c.API.SuppressionFile)
+	if err != nil {
+		return nil, err
+	}
+	if wc := c.API.BounceWebhook; wc != nil {
+		secret, err := ioutil.ReadFile(wc.SecretFile)
+		if err != nil {
+			return nil, err
+		}
+		handler, err := bmail.NewBounceHandler(c.API.Provider, strings.TrimSpace(string(secret)), suppressed, logger)
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			err := http.ListenAndServe(wc.ListenAddress, handler)
+			logger.Errf("Bounce webhook server stopped: %s", err)
+		}()
+	}
+	return bmail.NewAPIMailer(bmail.APIConfig{
+		Provider:     c.API.Provider,
+		BaseURL:      c.API.BaseURL,
+		APIKey:       apiKey,
+		AccessKeyID:  c.API.AccessKeyID,
+		Region:       c.API.Region,
+		Domain:       c.API.Domain,
+		MaxPerSecond: c.API.MaxPerSecond,
+	}, from, suppressed, dkim, logger, scope)
+}
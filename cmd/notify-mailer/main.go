@@ -464,19 +464,15 @@ func main() {
 		log.Infof("Doing a dry run.")
 		mailClient = bmail.NewDryRun(*address, log)
 	} else {
-		smtpPassword, err := cfg.NotifyMailer.PasswordConfig.Pass()
-		cmd.FailOnError(err, "Failed to load SMTP password")
-		mailClient = bmail.New(
-			cfg.NotifyMailer.Server,
-			cfg.NotifyMailer.Port,
-			cfg.NotifyMailer.Username,
-			smtpPassword,
+		mailClient, err = cmd.NewMailer(
+			cfg.NotifyMailer.SMTPConfig,
 			nil,
 			*address,
 			log,
 			metrics.NewNoopScope(),
 			*reconnBase,
 			*reconnMax)
+		cmd.FailOnError(err, "Failed to set up mailer")
 	}
 
 	m := mailer{
@@ -200,8 +200,6 @@ type config struct {
 		// upstream's timeout when making request to ocsp-responder.
 		Timeout cmd.ConfigDuration
 
-		ShutdownStopTimeout cmd.ConfigDuration
-
 		RequiredSerialPrefixes []string
 
 		Features map[string]bool
@@ -296,10 +294,7 @@ as generated by Boulder's single-ocsp command.
 
 	done := make(chan bool)
 	go cmd.CatchSignals(logger, func() {
-		ctx, cancel := context.WithTimeout(context.Background(),
-			c.OCSPResponder.ShutdownStopTimeout.Duration)
-		defer cancel()
-		_ = srv.Shutdown(ctx)
+		cmd.ShutdownHTTP(logger, c.OCSPResponder.ShutdownStopTimeout.Duration, srv)
 		done <- true
 	})
 
@@ -7,6 +7,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/jmhodges/clock"
@@ -54,6 +55,12 @@ type OCSPUpdater struct {
 	// Maximum number of individual OCSP updates to attempt in parallel. Making
 	// these requests in parallel allows us to get higher total throughput.
 	parallelGenerateOCSPRequests int
+	// How soon after a certificate is revoked its revoked response must be
+	// published. Zero means there's no deadline.
+	revokedSLA time.Duration
+	// Revoked certificates whose responses haven't been published yet, in the
+	// order they're due.
+	revocations *revocationQueue
 
 	loops []*looper
 
@@ -101,6 +108,8 @@ func newUpdater(
 		ocspMinTimeToExpiry:          config.OCSPMinTimeToExpiry.Duration,
 		ocspStaleMaxAge:              config.OCSPStaleMaxAge.Duration,
 		parallelGenerateOCSPRequests: config.ParallelGenerateOCSPRequests,
+		revokedSLA:                   config.RevokedCertificateSLA.Duration,
+		revocations:                  newRevocationQueue(),
 	}
 
 	// Setup loops
@@ -296,7 +305,7 @@ func (updater *OCSPUpdater) markExpired(status core.CertificateStatus) error {
 }
 
 func (updater *OCSPUpdater) findRevokedCertificatesToUpdate(batchSize int) ([]core.CertificateStatus, error) {
-	const query = "WHERE NOT isExpired AND status = ? AND ocspLastUpdated <= revokedDate LIMIT ?"
+	const query = "WHERE NOT isExpired AND status = ? AND ocspLastUpdated <= revokedDate ORDER BY revokedDate LIMIT ?"
 	statuses, err := sa.SelectCertificateStatuses(
 		updater.dbMap,
 		query,
@@ -306,6 +315,9 @@ func (updater *OCSPUpdater) findRevokedCertificatesToUpdate(batchSize int) ([]co
 	return statuses, err
 }
 
+// revokedCertificatesTick queues the revoked certificates whose revoked
+// responses haven't been generated yet and publishes the responses of up to
+// batchSize of the queued certificates, those closest to their deadline first.
 func (updater *OCSPUpdater) revokedCertificatesTick(ctx context.Context, batchSize int) error {
 	statuses, err := updater.findRevokedCertificatesToUpdate(batchSize)
 	if err != nil {
@@ -316,9 +328,20 @@ func (updater *OCSPUpdater) revokedCertificatesTick(ctx context.Context, batchSi
 	if len(statuses) == batchSize {
 		updater.stats.Inc("revokedCertificatesTick.FullTick", 1)
 	}
+	for _, status := range statuses {
+		updater.revocations.push(status, status.RevokedDate.Add(updater.revokedSLA))
+	}
+	defer func() {
+		updater.stats.Gauge("revokedCertificatesTick.QueueLength", int64(updater.revocations.len()))
+	}()
 
 	var allPurgeURLs []string
-	for _, status := range statuses {
+	for i := 0; i < batchSize; i++ {
+		item, ok := updater.revocations.pop()
+		if !ok {
+			break
+		}
+		status := item.status
 		// It's possible that, if our ticks are fast enough (mainly in tests), we
 		// will get a certificate status where the ocspLastUpdated == revokedDate
 		// and the certificate has already been revoked. In order to avoid
@@ -339,6 +362,7 @@ func (updater *OCSPUpdater) revokedCertificatesTick(ctx context.Context, batchSi
 		if err != nil {
 			updater.log.AuditErrf("Failed to generate revoked OCSP response: %s", err)
 			updater.stats.Inc("Errors.RevokedResponseGeneration", 1)
+			updater.revocations.push(status, item.deadline)
 			return err
 		}
 		allPurgeURLs = append(allPurgeURLs, purgeURLs...)
@@ -346,8 +370,10 @@ func (updater *OCSPUpdater) revokedCertificatesTick(ctx context.Context, batchSi
 		if err != nil {
 			updater.stats.Inc("Errors.StoreRevokedResponse", 1)
 			updater.log.AuditErrf("Failed to store OCSP response: %s", err)
+			updater.revocations.push(status, item.deadline)
 			continue
 		}
+		updater.published(item)
 	}
 
 	if len(allPurgeURLs) > 0 {
@@ -372,6 +398,18 @@ func (updater *OCSPUpdater) revokedCertificatesTick(ctx context.Context, batchSi
 	return nil
 }
 
+// published records how long it took to publish the revoked response of a
+// queued certificate, and whether that missed its deadline.
+func (updater *OCSPUpdater) published(item queuedRevocation) {
+	now := updater.clk.Now()
+	updater.stats.TimingDuration("revokedCertificatesTick.PublishLatency", now.Sub(item.status.RevokedDate))
+	if updater.revokedSLA > 0 && now.After(item.deadline) {
+		updater.stats.Inc("revokedCertificatesTick.SLABreaches", 1)
+		updater.log.Warningf("Revoked OCSP response for %s published %s after its deadline",
+			item.status.Serial, now.Sub(item.deadline))
+	}
+}
+
 func (updater *OCSPUpdater) generateOCSPResponses(ctx context.Context, statuses []core.CertificateStatus, stats metrics.Scope) error {
 	// Use the semaphore pattern from
 	// https://github.com/golang/go/wiki/BoundingResourceUse to send a number of
@@ -445,6 +483,33 @@ func (updater *OCSPUpdater) oldOCSPResponsesTick(ctx context.Context, batchSize
 	return updater.generateOCSPResponses(ctx, statuses, updater.stats.NewScope("oldOCSPResponsesTick"))
 }
 
+// stop stops every loop, waiting at most timeout (indefinitely if zero) for
+// the batches in progress to be finished.
+func (updater *OCSPUpdater) stop(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		for _, l := range updater.loops {
+			wg.Add(1)
+			go func(l *looper) {
+				defer wg.Done()
+				l.stop()
+			}(l)
+		}
+		wg.Wait()
+		close(done)
+	}()
+	if timeout <= 0 {
+		<-done
+		return
+	}
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		updater.log.Warningf("Batches in progress didn't finish within %s", timeout)
+	}
+}
+
 type looper struct {
 	clk                  clock.Clock
 	stats                metrics.Scope
@@ -455,12 +520,25 @@ type looper struct {
 	failureBackoffFactor float64
 	failureBackoffMax    time.Duration
 	failures             int
+
+	// tickMu is held while a batch is processed so that stop can wait for
+	// it to be finished.
+	tickMu   sync.Mutex
+	stopping bool
 }
 
-func (l *looper) tick() {
+// tick processes a batch and sleeps until the next one is due. It returns
+// false, without doing anything, if the looper has been stopped.
+func (l *looper) tick() bool {
 	tickStart := l.clk.Now()
 	ctx := context.TODO()
+	l.tickMu.Lock()
+	if l.stopping {
+		l.tickMu.Unlock()
+		return false
+	}
 	err := l.tickFunc(ctx, l.batchSize)
+	l.tickMu.Unlock()
 	l.stats.TimingDuration("TickDuration", time.Since(tickStart))
 	l.stats.Inc("Ticks", 1)
 	tickEnd := tickStart.Add(time.Since(tickStart))
@@ -483,15 +561,25 @@ func (l *looper) tick() {
 
 	// Sleep for the remaining tick period or for the backoff time
 	l.clk.Sleep(sleepDur)
+	return true
+}
+
+// stop prevents any further batches from being processed, waiting for the one
+// in progress, if any, to be finished. Since every batch leaves the database
+// consistent a stopped looper picks up where it left off when restarted.
+func (l *looper) stop() {
+	l.tickMu.Lock()
+	defer l.tickMu.Unlock()
+	l.stopping = true
 }
 
 func (l *looper) loop() error {
 	if l.batchSize == 0 || l.tickDur == 0 {
 		return fmt.Errorf("Both batch size and tick duration are required, not running '%s' loop", l.name)
 	}
-	for {
-		l.tick()
+	for l.tick() {
 	}
+	return nil
 }
 
 type config struct {
@@ -592,7 +680,9 @@ func main() {
 		}(l)
 	}
 
-	go cmd.CatchSignals(logger, nil)
+	go cmd.CatchSignals(logger, func() {
+		updater.stop(conf.ShutdownStopTimeout.Duration)
+	})
 
 	// Sleep forever (until signaled)
 	select {}
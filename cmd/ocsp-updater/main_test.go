@@ -14,6 +14,7 @@ import (
 	"github.com/letsencrypt/boulder/features"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/probs"
 	"github.com/letsencrypt/boulder/revocation"
 	"github.com/letsencrypt/boulder/sa"
 	"github.com/letsencrypt/boulder/sa/satest"
@@ -41,6 +42,10 @@ func (ca *mockCA) IssueCertificateForPrecertificate(_ context.Context, _ *caPB.I
 	return core.Certificate{}, errors.New("IssueCertificateForPrecertificate is not implemented by mockCA")
 }
 
+func (ca *mockCA) CheckCertificateRequest(_ context.Context, _ *caPB.IssueCertificateRequest) ([]*probs.ProblemDetails, error) {
+	return nil, errors.New("CheckCertificateRequest is not implemented by mockCA")
+}
+
 func (ca *mockCA) GenerateOCSP(_ context.Context, xferObj core.OCSPSigningRequest) (ocsp []byte, err error) {
 	ocsp = []byte{1, 2, 3}
 	time.Sleep(ca.sleepTime)
@@ -471,3 +476,88 @@ func TestLoopTickBackoff(t *testing.T) {
 	test.AssertEquals(t, l.failures, 0)
 	test.AssertEquals(t, l.clk.Now(), start)
 }
+
+func TestLoopStop(t *testing.T) {
+	fc := clock.NewFake()
+	started, release := make(chan struct{}), make(chan struct{})
+	ticks := 0
+	l := &looper{
+		clk:       fc,
+		stats:     metrics.NewNoopScope(),
+		batchSize: 1,
+		tickDur:   time.Minute,
+		tickFunc: func(context.Context, int) error {
+			ticks++
+			if ticks == 1 {
+				close(started)
+				<-release
+			}
+			return nil
+		},
+	}
+	loopDone := make(chan error)
+	go func() { loopDone <- l.loop() }()
+	<-started
+
+	stopped := make(chan struct{})
+	go func() {
+		l.stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("stop returned before the batch in progress was finished")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	<-stopped
+	test.AssertNotError(t, <-loopDone, "loop failed")
+	ticksBeforeStop := ticks
+	test.Assert(t, !l.tick(), "stopped looper ran another batch")
+	test.AssertEquals(t, ticks, ticksBeforeStop)
+}
+
+func TestRevocationQueue(t *testing.T) {
+	fc := clock.NewFake()
+	q := newRevocationQueue()
+	_, ok := q.pop()
+	test.Assert(t, !ok, "empty queue popped an item")
+
+	q.push(core.CertificateStatus{Serial: "b"}, fc.Now().Add(2*time.Minute))
+	q.push(core.CertificateStatus{Serial: "c"}, fc.Now().Add(3*time.Minute))
+	q.push(core.CertificateStatus{Serial: "a"}, fc.Now().Add(time.Minute))
+	// A certificate that's already queued isn't queued again
+	q.push(core.CertificateStatus{Serial: "b"}, fc.Now())
+	test.AssertEquals(t, q.len(), 3)
+
+	for _, serial := range []string{"a", "b", "c"} {
+		item, ok := q.pop()
+		test.Assert(t, ok, "queue was empty")
+		test.AssertEquals(t, item.status.Serial, serial)
+	}
+	_, ok = q.pop()
+	test.Assert(t, !ok, "queue wasn't empty")
+
+	// Once popped, a certificate can be queued again
+	q.push(core.CertificateStatus{Serial: "a"}, fc.Now())
+	test.AssertEquals(t, q.len(), 1)
+}
+
+func TestPublishedSLABreach(t *testing.T) {
+	fc := clock.NewFake()
+	mockLog := blog.NewMock()
+	updater := &OCSPUpdater{
+		stats:      metrics.NewNoopScope(),
+		log:        mockLog,
+		clk:        fc,
+		revokedSLA: time.Minute,
+	}
+	revoked := core.CertificateStatus{Serial: "a", RevokedDate: fc.Now()}
+
+	updater.published(queuedRevocation{status: revoked, deadline: fc.Now().Add(time.Minute)})
+	test.AssertEquals(t, len(mockLog.GetAllMatching("after its deadline")), 0)
+
+	fc.Add(2 * time.Minute)
+	updater.published(queuedRevocation{status: revoked, deadline: revoked.RevokedDate.Add(time.Minute)})
+	test.AssertEquals(t, len(mockLog.GetAllMatching("published 1m0s after its deadline")), 1)
+}
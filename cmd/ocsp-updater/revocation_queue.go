@@ -0,0 +1,71 @@
+package main
+
+import (
+	"container/heap"
+	"time"
+
+	"github.com/letsencrypt/boulder/core"
+)
+
+// queuedRevocation is a revoked certificate whose revoked OCSP response
+// must be published by deadline.
+type queuedRevocation struct {
+	status   core.CertificateStatus
+	deadline time.Time
+}
+
+// revocationHeap implements heap.Interface, with the earliest deadline first.
+type revocationHeap []queuedRevocation
+
+func (h revocationHeap) Len() int           { return len(h) }
+func (h revocationHeap) Less(i, j int) bool { return h[i].deadline.Before(h[j].deadline) }
+func (h revocationHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *revocationHeap) Push(x interface{}) {
+	*h = append(*h, x.(queuedRevocation))
+}
+
+func (h *revocationHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// revocationQueue is a priority queue of the revoked certificates whose
+// responses haven't been published yet, so that the responses closest to
+// missing their deadline are published first. A certificate is only queued
+// once at a time. It isn't safe for concurrent use.
+type revocationQueue struct {
+	heap   revocationHeap
+	queued map[string]bool
+}
+
+func newRevocationQueue() *revocationQueue {
+	return &revocationQueue{queued: make(map[string]bool)}
+}
+
+// push queues status with the given deadline, unless its certificate is
+// already queued.
+func (q *revocationQueue) push(status core.CertificateStatus, deadline time.Time) {
+	if q.queued[status.Serial] {
+		return
+	}
+	q.queued[status.Serial] = true
+	heap.Push(&q.heap, queuedRevocation{status: status, deadline: deadline})
+}
+
+// pop removes and returns the queued certificate with the earliest deadline.
+// It returns false if the queue is empty.
+func (q *revocationQueue) pop() (queuedRevocation, bool) {
+	if len(q.heap) == 0 {
+		return queuedRevocation{}, false
+	}
+	item := heap.Pop(&q.heap).(queuedRevocation)
+	delete(q.queued, item.status.Serial)
+	return item, true
+}
+
+func (q *revocationQueue) len() int {
+	return len(q.heap)
+}
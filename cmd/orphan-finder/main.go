@@ -31,10 +31,12 @@ name:
 
 usage:
   orphan-finder parse-ca-log --config <path> --log-file <path>
+  orphan-finder tail-ca-log --config <path> --log-file <path>
   orphan-finder parse-der --config <path> --der-file <path> --regID <registration-id>
 
 command descriptions:
   parse-ca-log    Parses boulder-ca logs to add multiple orphaned certificates
+  tail-ca-log     Runs as a service, following a boulder-ca log and adding orphaned certificates as they are logged
   parse-der       Parses a single orphaned DER certificate file and adds it to the database
 `
 
@@ -47,6 +49,14 @@ type config struct {
 	// `test/config/ca.json` for the CA "backdate" value.
 	Backdate cmd.ConfigDuration
 	Features map[string]bool
+
+	// DebugAddr, PollInterval and MaxAttempts are only used by tail-ca-log.
+	DebugAddr string
+	// PollInterval is how often the log file is checked for new lines.
+	PollInterval cmd.ConfigDuration
+	// MaxAttempts is how many times an orphan that fails to be stored because
+	// of an SA error is tried before it is reported as unresolvable.
+	MaxAttempts int
 }
 
 type certificateStorage interface {
@@ -63,55 +73,92 @@ var (
 var backdateDuration time.Duration
 
 func checkDER(sai certificateStorage, der []byte) (*x509.Certificate, error) {
-	ctx := context.Background()
 	cert, err := x509.ParseCertificate(der)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to parse DER: %s", err)
 	}
-	_, err = sai.GetCertificate(ctx, core.SerialToString(cert.SerialNumber))
+	err = checkStored(sai, cert)
+	if err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// checkStored returns nil if cert isn't in the database yet, errAlreadyExists
+// if it is, and any other error if the lookup failed.
+func checkStored(sai certificateStorage, cert *x509.Certificate) error {
+	_, err := sai.GetCertificate(context.Background(), core.SerialToString(cert.SerialNumber))
 	if err == nil {
-		return nil, errAlreadyExists
+		return errAlreadyExists
 	}
 	if berrors.Is(err, berrors.NotFound) {
-		return cert, nil
+		return nil
 	}
-	return nil, fmt.Errorf("Existing certificate lookup failed: %s", err)
+	return fmt.Errorf("Existing certificate lookup failed: %s", err)
 }
 
+// orphanStatus is the outcome of processing a single log line.
+type orphanStatus int
+
+const (
+	// notOrphan lines don't describe an orphaned certificate.
+	notOrphan orphanStatus = iota
+	// orphanAdded lines described an orphan that has now been stored.
+	orphanAdded
+	// orphanAlreadyStored lines described an orphan that was already in the
+	// database.
+	orphanAlreadyStored
+	// orphanRetryable lines described an orphan that couldn't be stored
+	// because of an SA error. Trying again later may succeed.
+	orphanRetryable
+	// orphanUnresolvable lines described an orphan that can never be stored
+	// because the log line or certificate is malformed.
+	orphanUnresolvable
+)
+
 func parseLogLine(sa certificateStorage, logger blog.Logger, line string) (found bool, added bool) {
+	status := processLogLine(sa, logger, line)
+	return status != notOrphan, status == orphanAdded
+}
+
+func processLogLine(sa certificateStorage, logger blog.Logger, line string) orphanStatus {
 	ctx := context.Background()
 	if !strings.Contains(line, "cert=") || !strings.Contains(line, "orphaning certificate") {
-		return false, false
+		return notOrphan
 	}
 	derStr := derOrphan.FindStringSubmatch(line)
 	if len(derStr) <= 1 {
 		logger.AuditErrf("Didn't match regex for cert: %s", line)
-		return true, false
+		return orphanUnresolvable
 	}
 	der, err := hex.DecodeString(derStr[1])
 	if err != nil {
 		logger.AuditErrf("Couldn't decode hex: %s, [%s]", err, line)
-		return true, false
+		return orphanUnresolvable
 	}
-	cert, err := checkDER(sa, der)
+	cert, err := x509.ParseCertificate(der)
 	if err != nil {
-		logFunc := logger.Errf
-		if err == errAlreadyExists {
-			logFunc = logger.Infof
-		}
-		logFunc("%s, [%s]", err, line)
-		return true, false
+		logger.AuditErrf("Failed to parse DER: %s, [%s]", err, line)
+		return orphanUnresolvable
+	}
+	err = checkStored(sa, cert)
+	if err == errAlreadyExists {
+		logger.Infof("%s, [%s]", err, line)
+		return orphanAlreadyStored
+	} else if err != nil {
+		logger.Errf("%s, [%s]", err, line)
+		return orphanRetryable
 	}
 	// extract the regID
 	regStr := regOrphan.FindStringSubmatch(line)
 	if len(regStr) <= 1 {
 		logger.AuditErrf("regID variable is empty, [%s]", line)
-		return true, false
+		return orphanUnresolvable
 	}
 	regID, err := strconv.Atoi(regStr[1])
 	if err != nil {
 		logger.AuditErrf("Couldn't parse regID: %s, [%s]", err, line)
-		return true, false
+		return orphanUnresolvable
 	}
 	// OCSP-Updater will do the first response generation for this cert so pass an
 	// empty OCSP response. We use `cert.NotBefore` as the issued date to avoid
@@ -123,12 +170,14 @@ func parseLogLine(sa certificateStorage, logger blog.Logger, line string) (found
 	_, err = sa.AddCertificate(ctx, der, int64(regID), nil, &issuedDate)
 	if err != nil {
 		logger.AuditErrf("Failed to store certificate: %s, [%s]", err, line)
-		return true, false
+		return orphanRetryable
 	}
-	return true, true
+	return orphanAdded
 }
 
-func setup(configFile string) (blog.Logger, core.StorageAuthority) {
+// setup reads the config and connects to the SA. If serve is true and a
+// DebugAddr is configured, metrics are served on it.
+func setup(configFile string, serve bool) (config, blog.Logger, metrics.Scope, core.StorageAuthority) {
 	configJSON, err := ioutil.ReadFile(configFile)
 	cmd.FailOnError(err, "Failed to read config file")
 	var conf config
@@ -136,7 +185,13 @@ func setup(configFile string) (blog.Logger, core.StorageAuthority) {
 	cmd.FailOnError(err, "Failed to parse config file")
 	err = features.Set(conf.Features)
 	cmd.FailOnError(err, "Failed to set feature flags")
-	logger := cmd.NewLogger(conf.Syslog)
+	var scope metrics.Scope
+	var logger blog.Logger
+	if serve && conf.DebugAddr != "" {
+		scope, logger = cmd.StatsAndLogging(conf.Syslog, conf.DebugAddr)
+	} else {
+		scope, logger = metrics.NewNoopScope(), cmd.NewLogger(conf.Syslog)
+	}
 
 	tlsConfig, err := conf.TLS.Load()
 	cmd.FailOnError(err, "TLS config")
@@ -147,7 +202,7 @@ func setup(configFile string) (blog.Logger, core.StorageAuthority) {
 	sac := bgrpc.NewStorageAuthorityClient(sapb.NewStorageAuthorityClient(conn))
 
 	backdateDuration = conf.Backdate.Duration
-	return logger, sac
+	return conf, logger, scope, sac
 }
 
 func main() {
@@ -177,7 +232,7 @@ func main() {
 
 	switch command {
 	case "parse-ca-log":
-		logger, sa := setup(*configFile)
+		_, logger, _, sa := setup(*configFile, false)
 		if *logPath == "" {
 			usage()
 		}
@@ -198,9 +253,26 @@ func main() {
 		}
 		logger.Infof("Found %d orphans and added %d to the database\n", orphansFound, orphansAdded)
 
+	case "tail-ca-log":
+		conf, logger, scope, sa := setup(*configFile, true)
+		if *logPath == "" {
+			usage()
+		}
+		lt := newLogTailer(sa, *logPath, conf.PollInterval.Duration, conf.MaxAttempts, cmd.Clock(), logger, scope)
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			lt.run(stop)
+			close(done)
+		}()
+		cmd.CatchSignals(logger, func() {
+			close(stop)
+			<-done
+		})
+
 	case "parse-der":
 		ctx := context.Background()
-		_, sa := setup(*configFile)
+		_, _, _, sa := setup(*configFile, false)
 		if *derPath == "" || *regID == 0 {
 			usage()
 		}
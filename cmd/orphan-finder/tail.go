@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+)
+
+const (
+	defaultPollInterval = 5 * time.Second
+	defaultMaxAttempts  = 10
+)
+
+// logTailer follows a boulder-ca log file, backfilling every orphaned
+// certificate it finds. It copes with the log being rotated or truncated
+// underneath it, and retries orphans that couldn't be stored because of SA
+// errors until maxAttempts is reached, at which point they are reported as
+// unresolvable.
+type logTailer struct {
+	sa           certificateStorage
+	log          blog.Logger
+	clk          clock.Clock
+	path         string
+	pollInterval time.Duration
+	maxAttempts  int
+
+	file    *os.File
+	reader  *bufio.Reader
+	offset  int64
+	partial string
+	// pending maps log lines describing orphans that failed to be stored to
+	// the number of attempts made so far.
+	pending map[string]int
+
+	orphans        *prometheus.CounterVec
+	pendingOrphans prometheus.Gauge
+}
+
+func newLogTailer(
+	sa certificateStorage,
+	path string,
+	pollInterval time.Duration,
+	maxAttempts int,
+	clk clock.Clock,
+	logger blog.Logger,
+	scope metrics.Scope,
+) *logTailer {
+	if pollInterval == 0 {
+		pollInterval = defaultPollInterval
+	}
+	if maxAttempts == 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	orphans := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "orphans",
+			Help: "Number of orphaned certificates found in the CA log, by result (added, alreadyStored, unresolvable)",
+		},
+		[]string{"result"})
+	scope.MustRegister(orphans)
+
+	pendingOrphans := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "pendingOrphans",
+		Help: "Number of orphaned certificates waiting to be retried after an SA error",
+	})
+	scope.MustRegister(pendingOrphans)
+
+	return &logTailer{
+		sa:             sa,
+		log:            logger,
+		clk:            clk,
+		path:           path,
+		pollInterval:   pollInterval,
+		maxAttempts:    maxAttempts,
+		pending:        make(map[string]int),
+		orphans:        orphans,
+		pendingOrphans: pendingOrphans,
+	}
+}
+
+// run polls the log file until stop is closed.
+func (lt *logTailer) run(stop <-chan struct{}) {
+	for {
+		err := lt.poll()
+		if err != nil {
+			lt.log.Errf("Failed to read %s: %s", lt.path, err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-lt.clk.After(lt.pollInterval):
+		}
+	}
+}
+
+// poll retries pending orphans and then processes every complete line written
+// to the log since the last call, reopening the file if it has been rotated or
+// truncated.
+func (lt *logTailer) poll() error {
+	lt.retryPending()
+
+	if lt.file == nil {
+		err := lt.open()
+		if err != nil {
+			return err
+		}
+	}
+	err := lt.readLines()
+	if err != nil {
+		return err
+	}
+
+	rotated, err := lt.rotated()
+	if err != nil {
+		return err
+	}
+	if rotated {
+		lt.log.Infof("%s was rotated, reopening", lt.path)
+		// Anything left in the buffer is the unterminated final line of the
+		// old file, which is as complete as it will ever be.
+		if lt.partial != "" {
+			lt.handle(lt.partial)
+			lt.partial = ""
+		}
+		_ = lt.file.Close()
+		lt.file = nil
+		err = lt.open()
+		if err != nil {
+			return err
+		}
+		return lt.readLines()
+	}
+	return nil
+}
+
+func (lt *logTailer) open() error {
+	f, err := os.Open(lt.path)
+	if err != nil {
+		return err
+	}
+	lt.file = f
+	lt.reader = bufio.NewReader(f)
+	lt.offset = 0
+	return nil
+}
+
+// rotated reports whether the path now refers to a different file than the
+// one being read, or whether the file has been truncated.
+func (lt *logTailer) rotated() (bool, error) {
+	current, err := lt.file.Stat()
+	if err != nil {
+		return false, err
+	}
+	latest, err := os.Stat(lt.path)
+	if os.IsNotExist(err) {
+		// The old file has been moved away but the new one hasn't been created
+		// yet. Keep reading the old one until it is.
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return !os.SameFile(current, latest) || latest.Size() < lt.offset, nil
+}
+
+func (lt *logTailer) readLines() error {
+	for {
+		chunk, err := lt.reader.ReadString('\n')
+		lt.offset += int64(len(chunk))
+		lt.partial += chunk
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		lt.handle(strings.TrimSuffix(lt.partial, "\n"))
+		lt.partial = ""
+	}
+}
+
+func (lt *logTailer) handle(line string) {
+	lt.record(line, processLogLine(lt.sa, lt.log, line), 1)
+}
+
+func (lt *logTailer) retryPending() {
+	for line, attempts := range lt.pending {
+		lt.record(line, processLogLine(lt.sa, lt.log, line), attempts+1)
+	}
+}
+
+// record updates the metrics and pending set with the outcome of an attempt
+// to store the orphan described by line.
+func (lt *logTailer) record(line string, status orphanStatus, attempts int) {
+	if status == orphanRetryable && attempts >= lt.maxAttempts {
+		lt.log.AuditErrf("Giving up on orphaned certificate after %d attempts, [%s]", attempts, line)
+		status = orphanUnresolvable
+	}
+	switch status {
+	case notOrphan:
+		return
+	case orphanRetryable:
+		lt.pending[line] = attempts
+	case orphanAdded:
+		delete(lt.pending, line)
+		lt.log.AuditInfof("Added orphaned certificate to the database, [%s]", line)
+		lt.orphans.With(prometheus.Labels{"result": "added"}).Inc()
+	case orphanAlreadyStored:
+		delete(lt.pending, line)
+		lt.orphans.With(prometheus.Labels{"result": "alreadyStored"}).Inc()
+	case orphanUnresolvable:
+		delete(lt.pending, line)
+		lt.orphans.With(prometheus.Labels{"result": "unresolvable"}).Inc()
+	}
+	lt.pendingOrphans.Set(float64(len(lt.pending)))
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+// flakySA fails the given number of GetCertificate calls before behaving
+// like mockSA.
+type flakySA struct {
+	mockSA
+	failures int
+}
+
+func (f *flakySA) GetCertificate(ctx context.Context, s string) (core.Certificate, error) {
+	if f.failures > 0 {
+		f.failures--
+		return core.Certificate{}, fmt.Errorf("SA unavailable")
+	}
+	return f.mockSA.GetCertificate(ctx, s)
+}
+
+func orphanLine(der string) string {
+	return fmt.Sprintf("0000-00-00T00:00:00+00:00 hostname boulder-ca[pid]: [AUDIT] Failed RPC to store at SA, orphaning certificate: cert=[%s] err=[context deadline exceeded], regID=[1001], orderID=[0]\n", der)
+}
+
+func appendToFile(t *testing.T, path, data string) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	test.AssertNotError(t, err, "opening log file")
+	_, err = f.WriteString(data)
+	test.AssertNotError(t, err, "writing log file")
+	test.AssertNotError(t, f.Close(), "closing log file")
+}
+
+func setupTailer(t *testing.T, sa certificateStorage, maxAttempts int) (*logTailer, string, func()) {
+	dir, err := ioutil.TempDir("", "orphan-finder")
+	test.AssertNotError(t, err, "creating temp dir")
+	path := filepath.Join(dir, "boulder-ca.log")
+	appendToFile(t, path, "")
+	lt := newLogTailer(sa, path, time.Second, maxAttempts, clock.NewFake(), log, metrics.NewNoopScope())
+	return lt, path, func() { _ = os.RemoveAll(dir) }
+}
+
+func TestTailerFollowsLog(t *testing.T) {
+	sa := &mockSA{}
+	lt, path, cleanup := setupTailer(t, sa, 3)
+	defer cleanup()
+	backdateDuration = time.Hour
+
+	// A line that hasn't been terminated yet shouldn't be processed until it is
+	line := orphanLine(testCertDER)
+	appendToFile(t, path, "unrelated line\n"+line[:100])
+	test.AssertNotError(t, lt.poll(), "poll failed")
+	test.AssertEquals(t, test.CountCounterVec("result", "added", lt.orphans), 0)
+
+	appendToFile(t, path, line[100:]+orphanLine("deadbeef"))
+	test.AssertNotError(t, lt.poll(), "poll failed")
+	test.AssertEquals(t, test.CountCounterVec("result", "added", lt.orphans), 1)
+	test.AssertEquals(t, test.CountCounterVec("result", "unresolvable", lt.orphans), 1)
+	test.AssertEquals(t, sa.certificate.RegistrationID, int64(1001))
+
+	// Nothing new has been written, so nothing should change
+	test.AssertNotError(t, lt.poll(), "poll failed")
+	test.AssertEquals(t, test.CountCounterVec("result", "added", lt.orphans), 1)
+
+	// After rotation the new file should be read from the start
+	test.AssertNotError(t, os.Rename(path, path+".1"), "rotating log file")
+	appendToFile(t, path, line)
+	test.AssertNotError(t, lt.poll(), "poll failed")
+	test.AssertEquals(t, test.CountCounterVec("result", "alreadyStored", lt.orphans), 1)
+
+	// As should a truncated file
+	test.AssertNotError(t, os.Truncate(path, 0), "truncating log file")
+	test.AssertNotError(t, lt.poll(), "poll failed")
+	appendToFile(t, path, line)
+	test.AssertNotError(t, lt.poll(), "poll failed")
+	test.AssertEquals(t, test.CountCounterVec("result", "alreadyStored", lt.orphans), 2)
+}
+
+func TestTailerRetries(t *testing.T) {
+	sa := &flakySA{failures: 1}
+	lt, path, cleanup := setupTailer(t, sa, 3)
+	defer cleanup()
+
+	// The first attempt fails and the orphan is retried on the next poll
+	appendToFile(t, path, orphanLine(testCertDER))
+	test.AssertNotError(t, lt.poll(), "poll failed")
+	test.AssertEquals(t, len(lt.pending), 1)
+	test.AssertNotError(t, lt.poll(), "poll failed")
+	test.AssertEquals(t, len(lt.pending), 0)
+	test.AssertEquals(t, test.CountCounterVec("result", "added", lt.orphans), 1)
+
+	// An orphan that keeps failing is eventually reported as unresolvable
+	sa.certificate = core.Certificate{}
+	sa.failures = 10
+	log.Clear()
+	appendToFile(t, path, orphanLine(testCertDER))
+	test.AssertNotError(t, lt.poll(), "poll failed")
+	test.AssertNotError(t, lt.poll(), "poll failed")
+	test.AssertEquals(t, len(lt.pending), 1)
+	test.AssertNotError(t, lt.poll(), "poll failed")
+	test.AssertEquals(t, len(lt.pending), 0)
+	test.AssertEquals(t, test.CountCounterVec("result", "unresolvable", lt.orphans), 1)
+	test.AssertEquals(t, len(log.GetAllMatching("Giving up on orphaned certificate after 3 attempts")), 1)
+}
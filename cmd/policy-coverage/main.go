@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/features"
+	"github.com/letsencrypt/boulder/policy"
+	"github.com/letsencrypt/boulder/sa"
+)
+
+const usageString = `
+usage:
+policy-coverage sa --config <path> --policy <path> [--since <duration>]
+policy-coverage ra-log --config <path> --policy <path> --log-file <path>
+
+command descriptions:
+  sa      Check the names of the certificates issued recently, read from the
+          issuedNames table, against a proposed hostname policy
+  ra-log  Check the names of the certificates in the successful certificate
+          requests in a boulder-ra log against a proposed hostname policy
+
+Both commands report which issued names the proposed policy would block, how
+many certificate requests each entry would have affected, and entries that
+duplicate or overlap other entries.
+
+args:
+  config    File path to the configuration file for this tool
+  policy    File path to the proposed hostname policy
+  since     How far back to read issued certificates. Defaults to 2160h (90 days)
+  log-file  File path to the boulder-ra log
+`
+
+const (
+	defaultBatchSize = 1000
+	defaultSince     = 90 * 24 * time.Hour
+	// requestLogPrefix starts the audit log line boulder-ra writes for each
+	// certificate it issues, followed by its certificateRequestEvent as JSON.
+	requestLogPrefix = "Certificate request - successful JSON="
+)
+
+type config struct {
+	PolicyCoverage struct {
+		cmd.DBConfig
+
+		// BatchSize is the number of issuedNames rows read from the database at
+		// a time.
+		BatchSize int
+
+		Features map[string]bool
+	}
+
+	Syslog cmd.SyslogConfig
+}
+
+// request is the names of one issued certificate.
+type request struct {
+	Serial string
+	Names  []string
+}
+
+// policyLists is the part of a hostname policy file that coverage is
+// computed for.
+type policyLists struct {
+	Blacklist      []string
+	ExactBlacklist []string
+}
+
+// entryCoverage is how much of the sample an entry of the policy blocks.
+type entryCoverage struct {
+	List  string
+	Entry string
+	// Names are the distinct names the entry blocks
+	Names map[string]bool
+	// Requests is the number of requests with at least one name the entry
+	// blocks
+	Requests int
+}
+
+// report is the result of checking a sample of requests against a policy.
+type report struct {
+	Requests        int
+	Names           int
+	BlockedRequests int
+	// Entries are the entries that block at least one name, ordered by the
+	// number of requests they affect, most first.
+	Entries []*entryCoverage
+	// Unused are the entries that block nothing in the sample.
+	Unused []string
+	// Overlaps describe entries that are duplicated or made redundant by
+	// other entries.
+	Overlaps []string
+}
+
+type blocker interface {
+	BlockingEntry(name string) (list, entry string)
+}
+
+// analyze checks the names of each request against the policy.
+func analyze(pa blocker, lists policyLists, requests []request) report {
+	r := report{Requests: len(requests)}
+	entries := make(map[string]*entryCoverage)
+	names := make(map[string]bool)
+	for _, req := range requests {
+		hit := make(map[*entryCoverage]bool)
+		for _, name := range core.UniqueLowerNames(req.Names) {
+			names[name] = true
+			list, entry := pa.BlockingEntry(name)
+			if entry == "" {
+				continue
+			}
+			key := list + " " + entry
+			ec, present := entries[key]
+			if !present {
+				ec = &entryCoverage{List: list, Entry: entry, Names: make(map[string]bool)}
+				entries[key] = ec
+				r.Entries = append(r.Entries, ec)
+			}
+			ec.Names[name] = true
+			hit[ec] = true
+		}
+		for ec := range hit {
+			ec.Requests++
+		}
+		if len(hit) > 0 {
+			r.BlockedRequests++
+		}
+	}
+	r.Names = len(names)
+	sort.Slice(r.Entries, func(i, j int) bool {
+		if r.Entries[i].Requests != r.Entries[j].Requests {
+			return r.Entries[i].Requests > r.Entries[j].Requests
+		}
+		return r.Entries[i].Entry < r.Entries[j].Entry
+	})
+
+	for _, l := range []struct {
+		name    string
+		entries []string
+	}{
+		{"blacklist", lists.Blacklist},
+		{"exactBlacklist", lists.ExactBlacklist},
+	} {
+		for _, entry := range uniqueSorted(l.entries) {
+			if entries[l.name+" "+entry] == nil {
+				r.Unused = append(r.Unused, fmt.Sprintf("%s entry %q", l.name, entry))
+			}
+		}
+	}
+	r.Overlaps = overlaps(lists)
+	return r
+}
+
+// overlaps describes the entries of lists that are listed more than once, or
+// that block nothing that a blacklist entry doesn't: a blacklist entry blocks
+// its own name and all of its subdomains.
+func overlaps(lists policyLists) []string {
+	var found []string
+	found = append(found, duplicates("blacklist", lists.Blacklist)...)
+	found = append(found, duplicates("exactBlacklist", lists.ExactBlacklist)...)
+
+	blacklist := make(map[string]bool)
+	for _, entry := range lists.Blacklist {
+		blacklist[entry] = true
+	}
+	for _, entry := range uniqueSorted(lists.Blacklist) {
+		if parent := coveringEntry(blacklist, entry, 1); parent != "" {
+			found = append(found, fmt.Sprintf("blacklist entry %q is redundant with blacklist entry %q", entry, parent))
+		}
+	}
+	for _, entry := range uniqueSorted(lists.ExactBlacklist) {
+		if parent := coveringEntry(blacklist, entry, 0); parent != "" {
+			found = append(found, fmt.Sprintf("exactBlacklist entry %q is redundant with blacklist entry %q", entry, parent))
+		}
+	}
+	return found
+}
+
+// duplicates describes the entries listed more than once on a list.
+func duplicates(list string, entries []string) []string {
+	counts := make(map[string]int)
+	for _, entry := range entries {
+		counts[entry]++
+	}
+	var found []string
+	for _, entry := range uniqueSorted(entries) {
+		if counts[entry] > 1 {
+			found = append(found, fmt.Sprintf("%s entry %q is listed %d times", list, entry, counts[entry]))
+		}
+	}
+	return found
+}
+
+// coveringEntry returns the blacklist entry for name or one of its parent
+// domains, skipping the first skip labels of name, or "" if there's none.
+func coveringEntry(blacklist map[string]bool, name string, skip int) string {
+	labels := strings.Split(name, ".")
+	for i := skip; i < len(labels); i++ {
+		if parent := strings.Join(labels[i:], "."); blacklist[parent] {
+			return parent
+		}
+	}
+	return ""
+}
+
+func uniqueSorted(entries []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, entry := range entries {
+		if !seen[entry] {
+			seen[entry] = true
+			unique = append(unique, entry)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+// writeReport writes r in a form meant to be read during a policy review.
+func writeReport(w io.Writer, r report) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "Checked %d names in %d certificate requests\n", r.Names, r.Requests)
+	fmt.Fprintf(bw, "%d requests would have been rejected\n", r.BlockedRequests)
+	if len(r.Entries) > 0 {
+		fmt.Fprintf(bw, "\nEntries blocking issued names:\n")
+		for _, ec := range r.Entries {
+			fmt.Fprintf(bw, "  %s entry %q: %d requests, %d names\n", ec.List, ec.Entry, ec.Requests, len(ec.Names))
+			var names []string
+			for name := range ec.Names {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Fprintf(bw, "    %s\n", name)
+			}
+		}
+	}
+	if len(r.Unused) > 0 {
+		fmt.Fprintf(bw, "\nEntries blocking no issued names:\n")
+		for _, u := range r.Unused {
+			fmt.Fprintf(bw, "  %s\n", u)
+		}
+	}
+	if len(r.Overlaps) > 0 {
+		fmt.Fprintf(bw, "\nDuplicate and overlapping entries:\n")
+		for _, o := range r.Overlaps {
+			fmt.Fprintf(bw, "  %s\n", o)
+		}
+	}
+	return bw.Flush()
+}
+
+type issuedNamesDB interface {
+	Select(i interface{}, query string, args ...interface{}) ([]interface{}, error)
+}
+
+type issuedNameRow struct {
+	ID           int64  `db:"id"`
+	ReversedName string `db:"reversedName"`
+	Serial       string `db:"serial"`
+}
+
+// requestsFromSA reads the names of the certificates issued since the given
+// time from the issuedNames table.
+func requestsFromSA(db issuedNamesDB, since time.Time, batchSize int) ([]request, error) {
+	bySerial := make(map[string]*request)
+	var serials []string
+	var lastID int64
+	for {
+		var rows []issuedNameRow
+		_, err := db.Select(
+			&rows,
+			"SELECT id, reversedName, serial FROM issuedNames WHERE notBefore >= ? AND id > ? ORDER BY id LIMIT ?",
+			since,
+			lastID,
+			batchSize,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			req, present := bySerial[row.Serial]
+			if !present {
+				req = &request{Serial: row.Serial}
+				bySerial[row.Serial] = req
+				serials = append(serials, row.Serial)
+			}
+			req.Names = append(req.Names, sa.ReverseName(row.ReversedName))
+		}
+		if len(rows) < batchSize {
+			break
+		}
+		lastID = rows[len(rows)-1].ID
+	}
+	requests := make([]request, len(serials))
+	for i, serial := range serials {
+		requests[i] = *bySerial[serial]
+	}
+	return requests, nil
+}
+
+// requestsFromRALog reads the names of the certificates in the successful
+// certificate requests logged by boulder-ra. Other lines are skipped.
+func requestsFromRALog(r io.Reader) ([]request, error) {
+	var requests []request
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		i := strings.Index(line, requestLogPrefix)
+		if i < 0 {
+			continue
+		}
+		var event struct {
+			SerialNumber string
+			Names        []string
+		}
+		err := json.Unmarshal([]byte(line[i+len(requestLogPrefix):]), &event)
+		if err != nil {
+			return nil, fmt.Errorf("parsing certificate request %q: %s", line, err)
+		}
+		requests = append(requests, request{Serial: event.SerialNumber, Names: event.Names})
+	}
+	return requests, scanner.Err()
+}
+
+func main() {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, usageString)
+		os.Exit(1)
+	}
+	if len(os.Args) <= 2 {
+		usage()
+	}
+
+	command := os.Args[1]
+	flagSet := flag.NewFlagSet(command, flag.ContinueOnError)
+	configFile := flagSet.String("config", "", "File path to the configuration file for this tool")
+	policyFile := flagSet.String("policy", "", "File path to the proposed hostname policy")
+	since := flagSet.Duration("since", defaultSince, "How far back to read issued certificates")
+	logFile := flagSet.String("log-file", "", "File path to the boulder-ra log")
+	err := flagSet.Parse(os.Args[2:])
+	cmd.FailOnError(err, "Error parsing flagset")
+
+	if *configFile == "" || *policyFile == "" {
+		usage()
+	}
+
+	var c config
+	err = cmd.ReadConfigFile(*configFile, &c)
+	cmd.FailOnError(err, "Reading JSON config file into config structure")
+	err = features.Set(c.PolicyCoverage.Features)
+	cmd.FailOnError(err, "Failed to set feature flags")
+
+	logger := cmd.NewLogger(c.Syslog)
+	defer logger.AuditPanic()
+	logger.Info(cmd.VersionString())
+
+	contents, err := ioutil.ReadFile(*policyFile)
+	cmd.FailOnError(err, "Failed to read hostname policy")
+	var lists policyLists
+	err = json.Unmarshal(contents, &lists)
+	cmd.FailOnError(err, "Failed to parse hostname policy")
+	pa, err := policy.New(nil)
+	cmd.FailOnError(err, "Failed to create PA")
+	err = pa.SetHostnamePolicyFile(*policyFile)
+	cmd.FailOnError(err, "Failed to load hostname policy")
+
+	var requests []request
+	switch command {
+	case "sa":
+		dbURL, err := c.PolicyCoverage.DBConfig.URL()
+		cmd.FailOnError(err, "Couldn't load DB URL")
+		dbMap, err := sa.NewDbMap(dbURL, c.PolicyCoverage.DBConfig.MaxDBConns)
+		cmd.FailOnError(err, "Could not connect to database")
+		sa.SetSQLDebug(dbMap, logger)
+		batchSize := c.PolicyCoverage.BatchSize
+		if batchSize == 0 {
+			batchSize = defaultBatchSize
+		}
+		requests, err = requestsFromSA(dbMap, cmd.Clock().Now().Add(-*since), batchSize)
+		cmd.FailOnError(err, "Failed to read issued names")
+
+	case "ra-log":
+		if *logFile == "" {
+			usage()
+		}
+		f, err := os.Open(*logFile)
+		cmd.FailOnError(err, "Failed to open boulder-ra log")
+		requests, err = requestsFromRALog(f)
+		cmd.FailOnError(err, "Failed to read boulder-ra log")
+		_ = f.Close()
+
+	default:
+		usage()
+	}
+
+	err = writeReport(os.Stdout, analyze(pa, lists, requests))
+	cmd.FailOnError(err, "Failed to write report")
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/letsencrypt/boulder/policy"
+	"github.com/letsencrypt/boulder/test"
+)
+
+// fakeDB returns the configured issuedNames rows one batch at a time.
+type fakeDB struct {
+	rows    []issuedNameRow
+	selects int
+}
+
+func (db *fakeDB) Select(i interface{}, query string, args ...interface{}) ([]interface{}, error) {
+	db.selects++
+	lastID, limit := args[1].(int64), args[2].(int)
+	out := i.(*[]issuedNameRow)
+	for _, row := range db.rows {
+		if row.ID > lastID && len(*out) < limit {
+			*out = append(*out, row)
+		}
+	}
+	return nil, nil
+}
+
+func TestRequestsFromSA(t *testing.T) {
+	db := &fakeDB{rows: []issuedNameRow{
+		{ID: 1, ReversedName: "com.example", Serial: "01"},
+		{ID: 2, ReversedName: "com.example.www", Serial: "01"},
+		{ID: 3, ReversedName: "org.example.*", Serial: "02"},
+	}}
+	requests, err := requestsFromSA(db, time.Now(), 2)
+	test.AssertNotError(t, err, "requestsFromSA failed")
+	test.AssertEquals(t, db.selects, 2)
+	test.AssertDeepEquals(t, requests, []request{
+		{Serial: "01", Names: []string{"example.com", "www.example.com"}},
+		{Serial: "02", Names: []string{"*.example.org"}},
+	})
+}
+
+func TestRequestsFromRALog(t *testing.T) {
+	log := strings.Join([]string{
+		`2019-03-01T00:00:00.000000+00:00 host boulder-ra[1]: 6 boulder-ra abcdefg [AUDIT] Certificate request - successful JSON={"ID":"x","SerialNumber":"01","Names":["example.com","www.example.com"]}`,
+		`2019-03-01T00:00:00.000000+00:00 host boulder-ra[1]: 3 boulder-ra abcdefg [AUDIT] Certificate request - error JSON={"ID":"y","Error":"oops"}`,
+		`2019-03-01T00:00:00.000000+00:00 host boulder-ra[1]: 6 boulder-ra abcdefg Something else`,
+		`2019-03-01T00:00:00.000000+00:00 host boulder-ra[1]: 6 boulder-ra abcdefg [AUDIT] Certificate request - successful JSON={"SerialNumber":"02","Names":["example.net"]}`,
+	}, "\n")
+	requests, err := requestsFromRALog(strings.NewReader(log))
+	test.AssertNotError(t, err, "requestsFromRALog failed")
+	test.AssertDeepEquals(t, requests, []request{
+		{Serial: "01", Names: []string{"example.com", "www.example.com"}},
+		{Serial: "02", Names: []string{"example.net"}},
+	})
+
+	_, err = requestsFromRALog(strings.NewReader(`[AUDIT] Certificate request - successful JSON={"Names":`))
+	test.AssertError(t, err, "truncated request was parsed")
+}
+
+func TestOverlaps(t *testing.T) {
+	found := overlaps(policyLists{
+		Blacklist:      []string{"example.com", "www.example.com", "example.net", "example.net"},
+		ExactBlacklist: []string{"example.com", "mail.example.org", "mail.example.org", "api.example.net"},
+	})
+	test.AssertDeepEquals(t, found, []string{
+		`blacklist entry "example.net" is listed 2 times`,
+		`exactBlacklist entry "mail.example.org" is listed 2 times`,
+		`blacklist entry "www.example.com" is redundant with blacklist entry "example.com"`,
+		`exactBlacklist entry "api.example.net" is redundant with blacklist entry "example.net"`,
+		`exactBlacklist entry "example.com" is redundant with blacklist entry "example.com"`,
+	})
+}
+
+type fakeBlocker map[string][2]string
+
+func (b fakeBlocker) BlockingEntry(name string) (string, string) {
+	return b[name][0], b[name][1]
+}
+
+func TestAnalyze(t *testing.T) {
+	pa := fakeBlocker{
+		"example.com":      {"blacklist", "example.com"},
+		"www.example.com":  {"blacklist", "example.com"},
+		"mail.example.org": {"exactBlacklist", "mail.example.org"},
+	}
+	lists := policyLists{
+		Blacklist:      []string{"example.com", "unused.example"},
+		ExactBlacklist: []string{"mail.example.org"},
+	}
+	r := analyze(pa, lists, []request{
+		{Serial: "01", Names: []string{"example.com", "WWW.example.com"}},
+		{Serial: "02", Names: []string{"www.example.com", "mail.example.org"}},
+		{Serial: "03", Names: []string{"example.net"}},
+	})
+	test.AssertEquals(t, r.Requests, 3)
+	test.AssertEquals(t, r.Names, 4)
+	test.AssertEquals(t, r.BlockedRequests, 2)
+	test.AssertEquals(t, len(r.Entries), 2)
+	test.AssertEquals(t, r.Entries[0].Entry, "example.com")
+	test.AssertEquals(t, r.Entries[0].Requests, 2)
+	test.AssertEquals(t, len(r.Entries[0].Names), 2)
+	test.AssertEquals(t, r.Entries[1].Entry, "mail.example.org")
+	test.AssertEquals(t, r.Entries[1].Requests, 1)
+	test.AssertDeepEquals(t, r.Unused, []string{`blacklist entry "unused.example"`})
+
+	var out bytes.Buffer
+	err := writeReport(&out, r)
+	test.AssertNotError(t, err, "writeReport failed")
+	test.AssertEquals(t, out.String(), `Checked 4 names in 3 certificate requests
+2 requests would have been rejected
+
+Entries blocking issued names:
+  blacklist entry "example.com": 2 requests, 2 names
+    example.com
+    www.example.com
+  exactBlacklist entry "mail.example.org": 1 requests, 1 names
+    mail.example.org
+
+Entries blocking no issued names:
+  blacklist entry "unused.example"
+`)
+}
+
+func TestAnalyzeWithPolicy(t *testing.T) {
+	pa, err := policy.New(nil)
+	test.AssertNotError(t, err, "Couldn't create PA")
+	err = pa.SetHostnamePolicyFile("../../test/hostname-policy.json")
+	test.AssertNotError(t, err, "Couldn't load hostname policy")
+	r := analyze(pa, policyLists{}, []request{
+		{Serial: "01", Names: []string{"letsencrypt.org", "www.example.org"}},
+		{Serial: "02", Names: []string{"*.letsencrypt.org"}},
+		{Serial: "03", Names: []string{"www.letsencrypt.org"}},
+	})
+	test.AssertEquals(t, r.BlockedRequests, 2)
+	test.AssertEquals(t, len(r.Entries), 2)
+	test.AssertEquals(t, r.Entries[0].List, "exactBlacklist")
+	test.AssertEquals(t, r.Entries[0].Entry, "exactblacklist.letsencrypt.org")
+	test.AssertEquals(t, r.Entries[1].Entry, "example.org")
+}
@@ -0,0 +1,8 @@
+// +build camode
+
+package cmd
+
+// nonstandardPortsBuildAllowed reports whether this build may validate on
+// ports other than 80 and 443. The 'camode' build flag is set for publicly
+// trusted CAs, which must validate on the ports required by RFC 8555.
+const nonstandardPortsBuildAllowed = false
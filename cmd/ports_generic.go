@@ -0,0 +1,8 @@
+// +build !camode
+
+package cmd
+
+// nonstandardPortsBuildAllowed reports whether this build may validate on
+// ports other than 80 and 443. Builds for publicly trusted CAs should set the
+// 'camode' build flag, which makes it false.
+const nonstandardPortsBuildAllowed = true
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/core"
+	bgrpc "github.com/letsencrypt/boulder/grpc"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/ratelimit"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+)
+
+const usageString = `
+usage:
+rate-limit-overrides add --config <path> --limit <name> (--key <key> | --regID <id>) --threshold <n> --for <duration> --reason <text>
+rate-limit-overrides expire --config <path> --id <override-id> --reason <text>
+rate-limit-overrides list --config <path>
+
+command descriptions:
+  add      Create an override, replacing any active override for the same limit and key or registration ID
+  expire   Immediately expire an active override
+  list     List all active overrides
+
+args:
+  config     File path to the configuration file for this service
+  limit      Name of the rate limit, as it appears in the rate limit policy file
+  key        Override key, e.g. the registered domain for certificatesPerName
+  regID      Registration ID the override applies to
+  threshold  New threshold for the key or registration ID
+  for        How long the override should stay in effect, e.g. 720h
+  id         ID of an override, as shown by the list command
+  reason     Why the override is being changed, e.g. a support ticket reference
+`
+
+type config struct {
+	RateLimitOverrides struct {
+		// The tool needs a TLSConfig to set up its gRPC client certs, but
+		// doesn't get the TLS field from ServiceConfig, so declares its own.
+		TLS cmd.TLSConfig
+
+		SAService *cmd.GRPCClientConfig
+	}
+
+	Syslog cmd.SyslogConfig
+}
+
+// overrideAdmin holds what each subcommand needs to talk to the SA. The
+// operator's username is recorded with every change as the "who" of the
+// audit trail.
+type overrideAdmin struct {
+	sac      core.StorageAuthority
+	clk      clock.Clock
+	log      blog.Logger
+	username string
+}
+
+func setupContext(c config) overrideAdmin {
+	logger := cmd.NewLogger(c.Syslog)
+
+	tlsConfig, err := c.RateLimitOverrides.TLS.Load()
+	cmd.FailOnError(err, "TLS config")
+
+	clk := cmd.Clock()
+
+	clientMetrics := bgrpc.NewClientMetrics(metrics.NewNoopScope())
+	saConn, err := bgrpc.ClientSetup(c.RateLimitOverrides.SAService, tlsConfig, clientMetrics, clk)
+	cmd.FailOnError(err, "Failed to load credentials and create gRPC connection to SA")
+	sac := bgrpc.NewStorageAuthorityClient(sapb.NewStorageAuthorityClient(saConn))
+
+	u, err := user.Current()
+	cmd.FailOnError(err, "Couldn't determine the current user")
+
+	return overrideAdmin{
+		sac:      sac,
+		clk:      clk,
+		log:      logger,
+		username: u.Username,
+	}
+}
+
+func (oa overrideAdmin) add(ctx context.Context, limit, key string, regID, threshold int64, duration time.Duration, reason string) error {
+	if !ratelimit.ValidLimitName(limit) {
+		return fmt.Errorf("unknown rate limit %q", limit)
+	}
+	if (key == "") == (regID == 0) {
+		return fmt.Errorf("exactly one of --key and --regID must be provided")
+	}
+	if threshold < 0 {
+		return fmt.Errorf("threshold must not be negative")
+	}
+	if duration <= 0 {
+		return fmt.Errorf("--for must be a positive duration")
+	}
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("--reason must be provided")
+	}
+
+	expires := oa.clk.Now().Add(duration).UnixNano()
+	override, err := oa.sac.AddRateLimitOverride(ctx, &sapb.RateLimitOverride{
+		LimitName:      &limit,
+		Key:            &key,
+		RegistrationID: &regID,
+		Threshold:      &threshold,
+		CreatedBy:      &oa.username,
+		Expires:        &expires,
+		Reason:         &reason,
+	})
+	if err != nil {
+		return err
+	}
+	oa.log.AuditInfof("Added rate limit override %d: limit=[%s] key=[%s] regID=[%d] threshold=[%d] expires=[%s] user=[%s] reason=[%s]",
+		*override.Id, limit, key, regID, threshold, time.Unix(0, expires).UTC(), oa.username, reason)
+	return nil
+}
+
+func (oa overrideAdmin) expire(ctx context.Context, id int64, reason string) error {
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("--reason must be provided")
+	}
+	err := oa.sac.ExpireRateLimitOverride(ctx, &sapb.ExpireRateLimitOverrideRequest{
+		Id:        &id,
+		ExpiredBy: &oa.username,
+		Reason:    &reason,
+	})
+	if err != nil {
+		return err
+	}
+	oa.log.AuditInfof("Expired rate limit override %d: user=[%s] reason=[%s]", id, oa.username, reason)
+	return nil
+}
+
+func (oa overrideAdmin) list(ctx context.Context) error {
+	now := oa.clk.Now().UnixNano()
+	resp, err := oa.sac.GetRateLimitOverrides(ctx, &sapb.GetRateLimitOverridesRequest{Now: &now})
+	if err != nil {
+		return err
+	}
+	overrides := resp.Overrides
+	sort.Slice(overrides, func(i, j int) bool {
+		return *overrides[i].Id < *overrides[j].Id
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tLIMIT\tKEY\tREG ID\tTHRESHOLD\tEXPIRES\tCREATED BY\tREASON")
+	for _, o := range overrides {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\t%s\t%s\t%s\n",
+			*o.Id,
+			*o.LimitName,
+			*o.Key,
+			*o.RegistrationID,
+			*o.Threshold,
+			time.Unix(0, *o.Expires).UTC().Format(time.RFC3339),
+			o.GetCreatedBy(),
+			o.GetReason())
+	}
+	return w.Flush()
+}
+
+func main() {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, usageString)
+		os.Exit(1)
+	}
+	if len(os.Args) <= 2 {
+		usage()
+	}
+
+	command := os.Args[1]
+	flagSet := flag.NewFlagSet(command, flag.ContinueOnError)
+	configFile := flagSet.String("config", "", "File path to the configuration file for this service")
+	limit := flagSet.String("limit", "", "Name of the rate limit")
+	key := flagSet.String("key", "", "Override key, e.g. a registered domain")
+	regID := flagSet.Int64("regID", 0, "Registration ID the override applies to")
+	threshold := flagSet.Int64("threshold", -1, "Threshold for the key or registration ID")
+	duration := flagSet.Duration("for", 0, "How long the override should stay in effect")
+	id := flagSet.Int64("id", 0, "ID of the override to expire")
+	reason := flagSet.String("reason", "", "Why the override is being changed")
+	err := flagSet.Parse(os.Args[2:])
+	cmd.FailOnError(err, "Error parsing flagset")
+
+	if *configFile == "" {
+		usage()
+	}
+
+	var c config
+	err = cmd.ReadConfigFile(*configFile, &c)
+	cmd.FailOnError(err, "Reading JSON config file into config structure")
+
+	ctx := context.Background()
+	switch command {
+	case "add":
+		oa := setupContext(c)
+		err = oa.add(ctx, *limit, *key, *regID, *threshold, *duration, *reason)
+		cmd.FailOnError(err, "Failed to add rate limit override")
+
+	case "expire":
+		if *id == 0 {
+			usage()
+		}
+		oa := setupContext(c)
+		err = oa.expire(ctx, *id, *reason)
+		cmd.FailOnError(err, "Failed to expire rate limit override")
+
+	case "list":
+		oa := setupContext(c)
+		err = oa.list(ctx)
+		cmd.FailOnError(err, "Failed to list rate limit overrides")
+
+	default:
+		usage()
+	}
+}
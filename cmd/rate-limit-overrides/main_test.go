@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"golang.org/x/net/context"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/mocks"
+	"github.com/letsencrypt/boulder/ratelimit"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+	"github.com/letsencrypt/boulder/test"
+)
+
+type mockSA struct {
+	mocks.StorageAuthority
+	added *sapb.RateLimitOverride
+}
+
+func (m *mockSA) AddRateLimitOverride(_ context.Context, req *sapb.RateLimitOverride) (*sapb.RateLimitOverride, error) {
+	m.added = req
+	id := int64(1)
+	req.Id = &id
+	return req, nil
+}
+
+func TestAdd(t *testing.T) {
+	fc := clock.NewFake()
+	sa := &mockSA{}
+	oa := overrideAdmin{
+		sac:      sa,
+		clk:      fc,
+		log:      blog.NewMock(),
+		username: "operator",
+	}
+	ctx := context.Background()
+
+	err := oa.add(ctx, "notARealLimit", "example.com", 0, 100, time.Hour, "ticket 1234")
+	test.AssertError(t, err, "add with unknown limit name should fail")
+
+	err = oa.add(ctx, ratelimit.CertificatesPerName, "", 0, 100, time.Hour, "ticket 1234")
+	test.AssertError(t, err, "add without key or registration ID should fail")
+
+	err = oa.add(ctx, ratelimit.CertificatesPerName, "example.com", 1, 100, time.Hour, "ticket 1234")
+	test.AssertError(t, err, "add with both key and registration ID should fail")
+
+	err = oa.add(ctx, ratelimit.CertificatesPerName, "example.com", 0, 100, 0, "ticket 1234")
+	test.AssertError(t, err, "add without a duration should fail")
+
+	err = oa.add(ctx, ratelimit.CertificatesPerName, "example.com", 0, 100, time.Hour, " ")
+	test.AssertError(t, err, "add without a reason should fail")
+	test.Assert(t, sa.added == nil, "invalid overrides should not be sent to the SA")
+
+	err = oa.add(ctx, ratelimit.CertificatesPerName, "example.com", 0, 100, time.Hour, "ticket 1234")
+	test.AssertNotError(t, err, "valid add failed")
+	test.AssertEquals(t, *sa.added.LimitName, ratelimit.CertificatesPerName)
+	test.AssertEquals(t, *sa.added.Key, "example.com")
+	test.AssertEquals(t, *sa.added.Threshold, int64(100))
+	test.AssertEquals(t, *sa.added.CreatedBy, "operator")
+	test.AssertEquals(t, *sa.added.Reason, "ticket 1234")
+	test.AssertEquals(t, *sa.added.Expires, fc.Now().Add(time.Hour).UnixNano())
+}
+
+func TestExpireRequiresReason(t *testing.T) {
+	oa := overrideAdmin{
+		sac:      &mockSA{},
+		clk:      clock.NewFake(),
+		log:      blog.NewMock(),
+		username: "operator",
+	}
+	err := oa.expire(context.Background(), 1, "")
+	test.AssertError(t, err, "expire without a reason should fail")
+	err = oa.expire(context.Background(), 1, "no longer needed")
+	test.AssertNotError(t, err, "valid expire failed")
+}
@@ -17,6 +17,7 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/pem"
 	"errors"
@@ -32,7 +33,9 @@ import (
 	"path"
 	"runtime"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"google.golang.org/grpc/grpclog"
 
@@ -42,8 +45,13 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/faults"
+	"github.com/letsencrypt/boulder/health"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/policy"
+	"github.com/letsencrypt/boulder/reloader"
+	"github.com/letsencrypt/boulder/trace"
 )
 
 // Because we don't know when this init will be called with respect to
@@ -153,23 +161,83 @@ func (log promLogger) Println(args ...interface{}) {
 func StatsAndLogging(logConf SyslogConfig, addr string) (metrics.Scope, blog.Logger) {
 	logger := NewLogger(logConf)
 	scope := newScope(addr, logger)
+	if auditSinks != nil {
+		scope.MustRegister(auditSinks.Collectors()...)
+	}
 	return scope, logger
 }
 
+// SetupTracing installs a global tracer exporting spans to the configured
+// OpenTelemetry collector. If config is nil or has no endpoint tracing stays
+// disabled. CatchSignals flushes the exported spans before exiting.
+func SetupTracing(config *TracingConfig, scope metrics.Scope, logger blog.Logger) {
+	if config == nil || config.Endpoint == "" {
+		return
+	}
+	if config.SampleRatio < 0 || config.SampleRatio > 1 {
+		Fail(fmt.Sprintf("Tracing sampleRatio must be between 0 and 1, not %g", config.SampleRatio))
+	}
+	service := config.ServiceName
+	if service == "" {
+		service = path.Base(os.Args[0])
+	}
+	exporter := trace.NewOTLPExporter(config.Endpoint, service, logger, scope)
+	trace.SetTracer(trace.NewTracer(exporter, config.SampleRatio))
+	logger.Infof("Exporting %g of traces to %s as %q", config.SampleRatio, config.Endpoint, service)
+}
+
+// SetupFaults configures the faults the service injects. It fails if rules
+// are invalid, or if any are given to a binary built without the
+// 'integration' build tag.
+func SetupFaults(rules []faults.Rule, logger blog.Logger) {
+	FailOnError(faults.Set(rules), "Couldn't set up fault injection")
+	if len(rules) > 0 {
+		logger.Warningf("Injecting %d configured faults", len(rules))
+	}
+}
+
 func NewLogger(logConf SyslogConfig) blog.Logger {
-	tag := path.Base(os.Args[0])
-	syslogger, err := syslog.Dial(
-		"",
-		"",
-		syslog.LOG_INFO, // default, not actually used
-		tag)
-	FailOnError(err, "Could not connect to Syslog")
+	var jsonFormat bool
+	switch logConf.StdoutFormat {
+	case "", "text":
+	case "json":
+		jsonFormat = true
+	default:
+		Fail(fmt.Sprintf("Unknown stdoutFormat %q, must be \"text\" or \"json\"", logConf.StdoutFormat))
+	}
 	syslogLevel := int(syslog.LOG_INFO)
 	if logConf.SyslogLevel != 0 {
 		syslogLevel = logConf.SyslogLevel
 	}
-	logger, err := blog.New(syslogger, logConf.StdoutLevel, syslogLevel)
-	FailOnError(err, "Could not connect to Syslog")
+
+	var syslogger *syslog.Writer
+	if syslogLevel >= 0 {
+		tag := path.Base(os.Args[0])
+		var err error
+		syslogger, err = syslog.Dial(
+			"",
+			"",
+			syslog.LOG_INFO, // default, not actually used
+			tag)
+		FailOnError(err, "Could not connect to Syslog")
+	} else if !jsonFormat {
+		Fail("Syslog can only be disabled when logging JSON to stdout")
+	}
+
+	var logger blog.Logger
+	if jsonFormat {
+		logger = blog.NewJSON(syslogger, logConf.StdoutLevel, syslogLevel)
+	} else {
+		var err error
+		logger, err = blog.New(syslogger, logConf.StdoutLevel, syslogLevel)
+		FailOnError(err, "Could not connect to Syslog")
+	}
+
+	if len(logConf.AuditSinks) > 0 {
+		var err error
+		logger, auditSinks, err = withAuditSinks(logger, logConf)
+		FailOnError(err, "Failed to set up audit sinks")
+	}
 
 	_ = blog.Set(logger)
 	cfsslLog.SetLogger(cfsslLogger{logger})
@@ -200,6 +268,11 @@ func newScope(addr string, logger blog.Logger) metrics.Scope {
 	mux.Handle("/debug/pprof/threadcreate", pprof.Handler("threadcreate"))
 
 	mux.Handle("/debug/vars", expvar.Handler())
+	mux.Handle("/health/live", health.LiveHandler())
+	mux.Handle("/health/ready", health.ReadyHandler(health.Default))
+	mux.Handle("/debug/reloadable-config", reloader.Default.Handler())
+	mux.Handle("/debug/features", featuresHandler{logger})
+	mux.Handle("/debug/hostname-policy-rejections", policy.DefaultRejections.Handler())
 	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{
 		ErrorLog: promLogger{logger},
 	}))
@@ -222,6 +295,9 @@ func Fail(msg string) {
 	logger := blog.Get()
 	logger.AuditErr(msg)
 	fmt.Fprintf(os.Stderr, msg)
+	if auditSinks != nil {
+		auditSinks.Close(auditSinkTimeout)
+	}
 	os.Exit(1)
 }
 
@@ -291,17 +367,91 @@ func CatchSignals(logger blog.Logger, callback func()) {
 	if logger != nil {
 		logger.Infof("Caught %s", signalToName[sig])
 	}
+	// Stop advertising readiness before shutting down so that load balancers
+	// stop sending new requests.
+	health.Default.SetServing(false)
 
 	if callback != nil {
 		callback()
 	}
+	trace.Shutdown()
 
 	if logger != nil {
 		logger.Info("Exiting")
 	}
+	if auditSinks != nil {
+		auditSinks.Close(auditSinkTimeout)
+	}
 	os.Exit(0)
 }
 
+// GracefulStopper is implemented by *grpc.Server.
+type GracefulStopper interface {
+	GracefulStop()
+	Stop()
+}
+
+// GracefulStop stops each server from accepting new RPCs and waits for the
+// in-flight ones to finish, as Shutdown does.
+func GracefulStop(logger blog.Logger, timeout time.Duration, servers ...GracefulStopper) {
+	Shutdown(logger, timeout, servers)
+}
+
+// ShutdownHTTP stops each server from accepting new connections and waits for
+// the in-flight requests to finish, as Shutdown does.
+func ShutdownHTTP(logger blog.Logger, timeout time.Duration, servers ...*http.Server) {
+	Shutdown(logger, timeout, nil, servers...)
+}
+
+// Shutdown stops every gRPC and HTTP server from accepting new requests and
+// waits for the in-flight ones to finish. The servers are stopped
+// concurrently, so that none of them keeps accepting requests while another
+// drains, and the timeout applies to all of them together. gRPC servers whose
+// RPCs haven't finished when it passes are stopped forcibly, cancelling the
+// remaining RPCs, and HTTP servers are left to be closed on exit. A zero
+// timeout waits indefinitely. Nil HTTP servers are ignored.
+func Shutdown(logger blog.Logger, timeout time.Duration, grpcServers []GracefulStopper, httpServers ...*http.Server) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var wg sync.WaitGroup
+	for _, srv := range grpcServers {
+		wg.Add(1)
+		go func(srv GracefulStopper) {
+			defer wg.Done()
+			done := make(chan struct{})
+			go func() {
+				srv.GracefulStop()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-ctx.Done():
+				logger.Warningf("In-flight RPCs didn't finish within %s, stopping forcibly", timeout)
+				srv.Stop()
+				<-done
+			}
+		}(srv)
+	}
+	for _, srv := range httpServers {
+		if srv == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(srv *http.Server) {
+			defer wg.Done()
+			err := srv.Shutdown(ctx)
+			if err != nil {
+				logger.Warningf("In-flight requests to %s didn't finish: %s", srv.Addr, err)
+			}
+		}(srv)
+	}
+	wg.Wait()
+}
+
 // FilterShutdownErrors returns the input error, with the exception of "use of
 // closed network connection," on which it returns nil
 // Per https://github.com/grpc/grpc-go/issues/1017, a gRPC server's `Serve()`
@@ -3,8 +3,11 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/letsencrypt/boulder/core"
 	blog "github.com/letsencrypt/boulder/log"
@@ -177,3 +180,101 @@ func TestReadConfigFile(t *testing.T) {
 	test.AssertNotError(t, err, "ReadConfigFile(../test/config/notify-mailer.json) errored")
 	test.AssertEquals(t, c.NotifyMailer.SMTPConfig.Server, "localhost")
 }
+
+// slowStopper is a GracefulStopper whose in-flight RPCs finish when release
+// is closed or the server is stopped forcibly.
+type slowStopper struct {
+	release chan struct{}
+	stopped bool
+}
+
+func (s *slowStopper) GracefulStop() {
+	<-s.release
+}
+
+func (s *slowStopper) Stop() {
+	s.stopped = true
+	close(s.release)
+}
+
+func TestGracefulStop(t *testing.T) {
+	log := blog.NewMock()
+	done := &slowStopper{release: make(chan struct{})}
+	close(done.release)
+	GracefulStop(log, time.Second, done)
+	test.Assert(t, !done.stopped, "server stopped forcibly despite draining in time")
+
+	slow := &slowStopper{release: make(chan struct{})}
+	GracefulStop(log, 10*time.Millisecond, slow)
+	test.Assert(t, slow.stopped, "server not stopped forcibly after the timeout")
+	test.AssertEquals(t, len(log.GetAllMatching("stopping forcibly")), 1)
+}
+
+// waitingStopper is a GracefulStopper whose in-flight RPCs only finish once
+// another server has started stopping.
+type waitingStopper struct {
+	slowStopper
+	other chan struct{}
+}
+
+func (s *waitingStopper) GracefulStop() {
+	select {
+	case <-s.other:
+	case <-s.release:
+	}
+}
+
+// signallingStopper is a GracefulStopper that closes started when it starts
+// stopping.
+type signallingStopper struct {
+	started chan struct{}
+}
+
+func (s *signallingStopper) GracefulStop() {
+	close(s.started)
+}
+
+func (s *signallingStopper) Stop() {}
+
+func TestShutdownConcurrently(t *testing.T) {
+	log := blog.NewMock()
+	second := &signallingStopper{started: make(chan struct{})}
+	first := &waitingStopper{slowStopper{release: make(chan struct{})}, second.started}
+	// The first server only drains if the second is stopped at the same time
+	Shutdown(log, 5*time.Second, []GracefulStopper{first, second})
+	test.Assert(t, !first.stopped, "servers not stopped concurrently")
+
+	// A single timeout applies to the gRPC and HTTP servers together
+	started, release := make(chan struct{}), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+	defer srv.Close()
+	go func() { _, _ = http.Get(srv.URL) }()
+	<-started
+	slow := &slowStopper{release: make(chan struct{})}
+	begin := time.Now()
+	Shutdown(log, 200*time.Millisecond, []GracefulStopper{slow}, srv.Config)
+	elapsed := time.Since(begin)
+	close(release)
+	test.Assert(t, slow.stopped, "gRPC server not stopped forcibly after the timeout")
+	test.AssertEquals(t, len(log.GetAllMatching("didn't finish")), 2)
+	test.Assert(t, elapsed < 400*time.Millisecond, fmt.Sprintf("shutdown took %s, more than twice the timeout", elapsed))
+}
+
+func TestShutdownHTTP(t *testing.T) {
+	log := blog.NewMock()
+	started, release := make(chan struct{}), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+	defer srv.Close()
+	go func() { _, _ = http.Get(srv.URL) }()
+	<-started
+
+	ShutdownHTTP(log, 10*time.Millisecond, srv.Config, nil)
+	test.AssertEquals(t, len(log.GetAllMatching("didn't finish")), 1)
+	close(release)
+}
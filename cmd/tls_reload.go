@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	blog "github.com/letsencrypt/boulder/log"
+)
+
+// tlsFiles holds the raw contents of the files named in a TLSConfig.
+type tlsFiles struct {
+	cert, key, ca []byte
+}
+
+func (f tlsFiles) equal(other tlsFiles) bool {
+	return bytes.Equal(f.cert, other.cert) &&
+		bytes.Equal(f.key, other.key) &&
+		bytes.Equal(f.ca, other.ca)
+}
+
+// retiredCA is a CA certificate that was removed from the CA bundle but is
+// trusted until the CARotationOverlap has passed.
+type retiredCA struct {
+	cert  *x509.Certificate
+	until time.Time
+}
+
+// tlsReloader holds the most recently loaded certificate, key and CA bundle
+// for a TLSConfig. If the files can't be loaded, e.g. because a new
+// certificate was written before its key, the previous ones stay in use.
+type tlsReloader struct {
+	config *TLSConfig
+	clk    clock.Clock
+
+	mu      sync.RWMutex
+	files   tlsFiles
+	cert    tls.Certificate
+	cas     []*x509.Certificate
+	retired []retiredCA
+	pool    *x509.CertPool
+}
+
+func newTLSReloader(config *TLSConfig, clk clock.Clock) *tlsReloader {
+	return &tlsReloader{config: config, clk: clk}
+}
+
+func (r *tlsReloader) read() (tlsFiles, error) {
+	var files tlsFiles
+	var err error
+	files.ca, err = ioutil.ReadFile(*r.config.CACertFile)
+	if err != nil {
+		return files, fmt.Errorf("reading CA cert from %q: %s", *r.config.CACertFile, err)
+	}
+	files.cert, err = ioutil.ReadFile(*r.config.CertFile)
+	if err == nil {
+		files.key, err = ioutil.ReadFile(*r.config.KeyFile)
+	}
+	if err != nil {
+		return files, fmt.Errorf("loading key pair from %q and %q: %s",
+			*r.config.CertFile, *r.config.KeyFile, err)
+	}
+	return files, nil
+}
+
+// parseCerts returns every certificate in a PEM bundle, skipping any that
+// can't be parsed, like x509.CertPool.AppendCertsFromPEM does.
+func parseCerts(bundle []byte) []*x509.Certificate {
+	var certs []*x509.Certificate
+	for len(bundle) > 0 {
+		var block *pem.Block
+		block, bundle = pem.Decode(bundle)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" || len(block.Headers) != 0 {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		certs = append(certs, cert)
+	}
+	return certs
+}
+
+func containsCert(certs []*x509.Certificate, cert *x509.Certificate) bool {
+	for _, c := range certs {
+		if c.Equal(cert) {
+			return true
+		}
+	}
+	return false
+}
+
+// check reads the files named in the TLSConfig and, if they changed, makes
+// their contents current. It also stops trusting retired CA certificates
+// whose overlap has passed. It returns true if the files changed.
+func (r *tlsReloader) check() (bool, error) {
+	files, err := r.read()
+	if err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.clk.Now()
+
+	var retired []retiredCA
+	for _, rc := range r.retired {
+		if now.Before(rc.until) {
+			retired = append(retired, rc)
+		}
+	}
+	if files.equal(r.files) {
+		if len(retired) != len(r.retired) {
+			r.retired = retired
+			r.pool = r.buildPool()
+		}
+		return false, nil
+	}
+
+	cas := parseCerts(files.ca)
+	if len(cas) == 0 {
+		return false, fmt.Errorf("parsing CA certs from %s failed", *r.config.CACertFile)
+	}
+	cert, err := tls.X509KeyPair(files.cert, files.key)
+	if err != nil {
+		return false, fmt.Errorf("loading key pair from %q and %q: %s",
+			*r.config.CertFile, *r.config.KeyFile, err)
+	}
+	if r.config.CARotationOverlap.Duration > 0 {
+		for _, ca := range r.cas {
+			if !containsCert(cas, ca) {
+				retired = append(retired, retiredCA{ca, now.Add(r.config.CARotationOverlap.Duration)})
+			}
+		}
+	}
+	// A CA that was retired and then added back is simply trusted again
+	r.retired = nil
+	for _, rc := range retired {
+		if !containsCert(cas, rc.cert) {
+			r.retired = append(r.retired, rc)
+		}
+	}
+	r.files, r.cert, r.cas = files, cert, cas
+	r.pool = r.buildPool()
+	return true, nil
+}
+
+// buildPool returns a pool of the current and retired CA certificates. The
+// caller must hold r.mu.
+func (r *tlsReloader) buildPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, ca := range r.cas {
+		pool.AddCert(ca)
+	}
+	for _, rc := range r.retired {
+		pool.AddCert(rc.cert)
+	}
+	return pool
+}
+
+// configFrom returns a copy of base using the current certificate and CA
+// bundle.
+func (r *tlsReloader) configFrom(base *tls.Config) *tls.Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	config := base.Clone()
+	config.GetConfigForClient = nil
+	config.Certificates = []tls.Certificate{r.cert}
+	config.RootCAs, config.ClientCAs = r.pool, r.pool
+	return config
+}
+
+// reloaderKey identifies the TLSConfigs that can share a tlsReloader.
+type reloaderKey struct {
+	cert, key, ca     string
+	interval, overlap time.Duration
+}
+
+// reloaders holds the tlsReloaders of every TLSConfig loaded with a
+// ReloadInterval, so that a process loading the same TLSConfig for several
+// servers and clients watches its files only once.
+var reloaders struct {
+	sync.Mutex
+	byKey map[reloaderKey]*tlsReloader
+}
+
+// loadTLSReloader returns a tlsReloader holding the contents of the files
+// named in config. If config has a ReloadInterval, the reloader is shared with
+// every other TLSConfig naming the same files, and the first call starts the
+// goroutine watching them.
+func loadTLSReloader(config *TLSConfig) (*tlsReloader, error) {
+	if config.ReloadInterval.Duration <= 0 {
+		r := newTLSReloader(config, clock.Default())
+		_, err := r.check()
+		return r, err
+	}
+	key := reloaderKey{
+		cert:     *config.CertFile,
+		key:      *config.KeyFile,
+		ca:       *config.CACertFile,
+		interval: config.ReloadInterval.Duration,
+		overlap:  config.CARotationOverlap.Duration,
+	}
+	reloaders.Lock()
+	defer reloaders.Unlock()
+	if r, ok := reloaders.byKey[key]; ok {
+		return r, nil
+	}
+	r := newTLSReloader(config, clock.Default())
+	if _, err := r.check(); err != nil {
+		return nil, err
+	}
+	if reloaders.byKey == nil {
+		reloaders.byKey = make(map[reloaderKey]*tlsReloader)
+	}
+	reloaders.byKey[key] = r
+	go r.watch(blog.Get(), key.interval)
+	return r, nil
+}
+
+// watch checks the files for changes every interval, forever.
+func (r *tlsReloader) watch(logger blog.Logger, interval time.Duration) {
+	for {
+		r.clk.Sleep(interval)
+		changed, err := r.check()
+		if err != nil {
+			logger.Errf("Failed to reload TLS certificates, continuing to use the previous ones: %s", err)
+			continue
+		}
+		if changed {
+			logger.Infof("Reloaded TLS certificate %q, key %q and CA certificates %q",
+				*r.config.CertFile, *r.config.KeyFile, *r.config.CACertFile)
+		}
+	}
+}
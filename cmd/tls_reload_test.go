@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+	contents, err := ioutil.ReadFile(src)
+	test.AssertNotError(t, err, "reading "+src)
+	test.AssertNotError(t, ioutil.WriteFile(dst, contents, 0600), "writing "+dst)
+}
+
+// verifies returns true if the leaf of cert chains to a root in pool.
+func verifies(t *testing.T, pool *x509.CertPool, cert tls.Certificate) bool {
+	t.Helper()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	test.AssertNotError(t, err, "parsing leaf certificate")
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:     pool,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err == nil
+}
+
+func TestTLSReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls-reload")
+	test.AssertNotError(t, err, "creating temp dir")
+	defer os.RemoveAll(dir)
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	caFile := filepath.Join(dir, "ca.pem")
+	copyFile(t, "testdata/cert.pem", certFile)
+	copyFile(t, "testdata/key.pem", keyFile)
+	copyFile(t, "testdata/minica.pem", caFile)
+
+	oldCert, err := tls.LoadX509KeyPair("testdata/cert.pem", "testdata/key.pem")
+	test.AssertNotError(t, err, "loading old key pair")
+	newCert, err := tls.LoadX509KeyPair("../grpc/creds/testdata/boulder-client/cert.pem", "../grpc/creds/testdata/boulder-client/key.pem")
+	test.AssertNotError(t, err, "loading new key pair")
+
+	fc := clock.NewFake()
+	r := newTLSReloader(&TLSConfig{
+		CertFile:          &certFile,
+		KeyFile:           &keyFile,
+		CACertFile:        &caFile,
+		CARotationOverlap: ConfigDuration{time.Hour},
+	}, fc)
+	changed, err := r.check()
+	test.AssertNotError(t, err, "initial load failed")
+	test.Assert(t, changed, "initial load reported no change")
+	changed, err = r.check()
+	test.AssertNotError(t, err, "check failed")
+	test.Assert(t, !changed, "unchanged files reported as changed")
+
+	base := &tls.Config{MinVersion: tls.VersionTLS12}
+	config := r.configFrom(base)
+	test.AssertEquals(t, config.MinVersion, uint16(tls.VersionTLS12))
+	test.AssertDeepEquals(t, config.Certificates[0].Certificate, oldCert.Certificate)
+	test.Assert(t, verifies(t, config.RootCAs, oldCert), "old cert not trusted")
+
+	// A certificate written without its key is an error, and the previous
+	// key pair stays in use
+	copyFile(t, "../grpc/creds/testdata/boulder-client/cert.pem", certFile)
+	_, err = r.check()
+	test.AssertError(t, err, "mismatched key pair loaded")
+	test.AssertDeepEquals(t, r.configFrom(base).Certificates[0].Certificate, oldCert.Certificate)
+
+	// Once the key and the new CA are written too everything is reloaded, and
+	// the old CA stays trusted during the overlap
+	copyFile(t, "../grpc/creds/testdata/boulder-client/key.pem", keyFile)
+	copyFile(t, "../grpc/creds/testdata/minica.pem", caFile)
+	changed, err = r.check()
+	test.AssertNotError(t, err, "reload failed")
+	test.Assert(t, changed, "reload reported no change")
+	config = r.configFrom(base)
+	test.AssertDeepEquals(t, config.Certificates[0].Certificate, newCert.Certificate)
+	test.Assert(t, verifies(t, config.ClientCAs, newCert), "new cert not trusted")
+	test.Assert(t, verifies(t, config.ClientCAs, oldCert), "old cert not trusted during overlap")
+
+	fc.Add(time.Hour)
+	changed, err = r.check()
+	test.AssertNotError(t, err, "check failed")
+	test.Assert(t, !changed, "unchanged files reported as changed")
+	config = r.configFrom(base)
+	test.Assert(t, verifies(t, config.ClientCAs, newCert), "new cert not trusted")
+	test.Assert(t, !verifies(t, config.ClientCAs, oldCert), "old cert trusted after overlap")
+}
+
+func TestTLSConfigLoadReloading(t *testing.T) {
+	cert := "testdata/cert.pem"
+	key := "testdata/key.pem"
+	caCert := "testdata/minica.pem"
+	static, err := (&TLSConfig{CertFile: &cert, KeyFile: &key, CACertFile: &caCert}).Load()
+	test.AssertNotError(t, err, "Load failed")
+	test.Assert(t, static.GetConfigForClient == nil, "static config has GetConfigForClient")
+
+	reloading, err := (&TLSConfig{
+		CertFile:       &cert,
+		KeyFile:        &key,
+		CACertFile:     &caCert,
+		ReloadInterval: ConfigDuration{time.Hour},
+	}).Load()
+	test.AssertNotError(t, err, "Load failed")
+	current, err := reloading.GetConfigForClient(nil)
+	test.AssertNotError(t, err, "GetConfigForClient failed")
+	test.AssertDeepEquals(t, current.Certificates, reloading.Certificates)
+	test.AssertEquals(t, current.ClientAuth, tls.RequireAndVerifyClientCert)
+}
+
+func TestTLSConfigLoadSharesReloader(t *testing.T) {
+	cert := "testdata/cert.pem"
+	key := "testdata/key.pem"
+	caCert := "testdata/minica.pem"
+	reloadingConfig := func(interval time.Duration) *TLSConfig {
+		return &TLSConfig{
+			CertFile:       &cert,
+			KeyFile:        &key,
+			CACertFile:     &caCert,
+			ReloadInterval: ConfigDuration{interval},
+		}
+	}
+	first, err := loadTLSReloader(reloadingConfig(time.Minute))
+	test.AssertNotError(t, err, "loadTLSReloader failed")
+	second, err := loadTLSReloader(reloadingConfig(time.Minute))
+	test.AssertNotError(t, err, "loadTLSReloader failed")
+	test.Assert(t, first == second, "loading the same files twice started a second watcher")
+	other, err := loadTLSReloader(reloadingConfig(2 * time.Minute))
+	test.AssertNotError(t, err, "loadTLSReloader failed")
+	test.Assert(t, first != other, "configs with different intervals share a watcher")
+
+	// Files that fail to load aren't watched
+	missing := "testdata/missing.pem"
+	broken := reloadingConfig(time.Minute)
+	broken.CertFile = &missing
+	_, err = loadTLSReloader(broken)
+	test.AssertError(t, err, "loading a missing certificate succeeded")
+	reloaders.Lock()
+	_, ok := reloaders.byKey[reloaderKey{missing, key, caCert, time.Minute, 0}]
+	reloaders.Unlock()
+	test.Assert(t, !ok, "missing certificate is being watched")
+}
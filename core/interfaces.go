@@ -11,6 +11,7 @@ import (
 
 	caPB "github.com/letsencrypt/boulder/ca/proto"
 	corepb "github.com/letsencrypt/boulder/core/proto"
+	"github.com/letsencrypt/boulder/probs"
 	pubpb "github.com/letsencrypt/boulder/publisher/proto"
 	rapb "github.com/letsencrypt/boulder/ra/proto"
 	"github.com/letsencrypt/boulder/revocation"
@@ -86,6 +87,12 @@ type RegistrationAuthority interface {
 	// [WebFrontEnd]
 	FinalizeOrder(ctx context.Context, req *rapb.FinalizeOrderRequest) (*corepb.Order, error)
 
+	// [WebFrontEnd]
+	CheckIssuance(ctx context.Context, req *rapb.CheckIssuanceRequest) ([]*probs.ProblemDetails, error)
+
+	// [WebFrontEnd]
+	RateLimitUsage(ctx context.Context, req *rapb.RateLimitUsageRequest) (*rapb.RateLimitUsageResponse, error)
+
 	// [AdminRevoker]
 	AdministrativelyRevokeCertificate(ctx context.Context, cert x509.Certificate, code revocation.Reason, adminName string) error
 }
@@ -101,6 +108,9 @@ type CertificateAuthority interface {
 	// [RegistrationAuthority]
 	IssueCertificateForPrecertificate(ctx context.Context, req *caPB.IssueCertificateForPrecertificateRequest) (Certificate, error)
 
+	// [RegistrationAuthority]
+	CheckCertificateRequest(ctx context.Context, issueReq *caPB.IssueCertificateRequest) ([]*probs.ProblemDetails, error)
+
 	GenerateOCSP(ctx context.Context, ocspReq OCSPSigningRequest) ([]byte, error)
 }
 
@@ -108,6 +118,8 @@ type CertificateAuthority interface {
 type PolicyAuthority interface {
 	WillingToIssue(domain AcmeIdentifier) error
 	WillingToIssueWildcard(domain AcmeIdentifier) error
+	WillingToIssueExempt(domain AcmeIdentifier, exemptEntry string) error
+	CheckOrderNames(names []string) error
 	ChallengesFor(domain AcmeIdentifier, registrationID int64, revalidation bool) (challenges []Challenge, validCombinations [][]int, err error)
 	ChallengeTypeEnabled(t string, registrationID int64) bool
 }
@@ -130,12 +142,21 @@ type StorageGetter interface {
 	CountFQDNSets(ctx context.Context, window time.Duration, domains []string) (count int64, err error)
 	FQDNSetExists(ctx context.Context, domains []string) (exists bool, err error)
 	PreviousCertificateExists(ctx context.Context, req *sapb.PreviousCertificateExistsRequest) (exists *sapb.Exists, err error)
+	CertificateReplaced(ctx context.Context, req *sapb.Serial) (replaced *sapb.Exists, err error)
 	GetOrder(ctx context.Context, req *sapb.OrderRequest) (*corepb.Order, error)
 	GetOrderForNames(ctx context.Context, req *sapb.GetOrderForNamesRequest) (*corepb.Order, error)
 	GetValidOrderAuthorizations(ctx context.Context, req *sapb.GetValidOrderAuthorizationsRequest) (map[string]*Authorization, error)
+	GetOrderAuthorizations(ctx context.Context, req *sapb.GetOrderAuthorizationsRequest) (map[string]*Authorization, error)
 	CountInvalidAuthorizations(ctx context.Context, req *sapb.CountInvalidAuthorizationsRequest) (count *sapb.Count, err error)
 	GetAuthorizations(ctx context.Context, req *sapb.GetAuthorizationsRequest) (*sapb.Authorizations, error)
 	GetAuthz2(ctx context.Context, req *sapb.AuthorizationID2) (*corepb.Authorization, error)
+	GetRateLimitOverrides(ctx context.Context, req *sapb.GetRateLimitOverridesRequest) (*sapb.RateLimitOverrides, error)
+	GetChallengeWhitelist(ctx context.Context, req *sapb.GetChallengeWhitelistRequest) (*sapb.ChallengeWhitelistEntries, error)
+	GetStaleDNS01Rechecks(ctx context.Context, req *sapb.GetStaleDNS01RechecksRequest) (*sapb.DNS01Rechecks, error)
+	SearchCertificates(ctx context.Context, req *sapb.SearchCertificatesRequest) (*sapb.CertificateSearchResults, error)
+	ListCertificatesForAccount(ctx context.Context, req *sapb.ListCertificatesForAccountRequest) (*sapb.CertificateSearchResults, error)
+	ListOrdersForAccount(ctx context.Context, req *sapb.ListOrdersForAccountRequest) (*sapb.OrderList, error)
+	ListInvalidAuthorizationsForAccount(ctx context.Context, req *sapb.ListInvalidAuthorizationsForAccountRequest) (*sapb.AuthorizationList, error)
 }
 
 // StorageAdder are the Boulder SA's write/update methods
@@ -150,12 +171,21 @@ type StorageAdder interface {
 	RevokeAuthorizationsByDomain(ctx context.Context, domain AcmeIdentifier) (finalized, pending int64, err error)
 	DeactivateRegistration(ctx context.Context, id int64) error
 	DeactivateAuthorization(ctx context.Context, id string) error
+	DeactivateAuthorization2(ctx context.Context, req *sapb.AuthorizationID2) (*corepb.Empty, error)
 	NewOrder(ctx context.Context, order *corepb.Order) (*corepb.Order, error)
 	SetOrderProcessing(ctx context.Context, order *corepb.Order) error
 	FinalizeOrder(ctx context.Context, order *corepb.Order) error
 	AddPendingAuthorizations(ctx context.Context, req *sapb.AddPendingAuthorizationsRequest) (*sapb.AuthorizationIDs, error)
 	SetOrderError(ctx context.Context, order *corepb.Order) error
 	RevokeCertificate(ctx context.Context, req *sapb.RevokeCertificateRequest) error
+	AddRateLimitOverride(ctx context.Context, req *sapb.RateLimitOverride) (*sapb.RateLimitOverride, error)
+	ExpireRateLimitOverride(ctx context.Context, req *sapb.ExpireRateLimitOverrideRequest) error
+	AddChallengeWhitelistEntry(ctx context.Context, req *sapb.ChallengeWhitelistEntry) (*sapb.ChallengeWhitelistEntry, error)
+	RemoveChallengeWhitelistEntry(ctx context.Context, req *sapb.RemoveChallengeWhitelistEntryRequest) error
+	AddDNS01Recheck(ctx context.Context, req *sapb.DNS01Recheck) error
+	ClaimDNS01Recheck(ctx context.Context, req *sapb.DNS01Recheck) (*sapb.Exists, error)
+	RemoveDNS01Recheck(ctx context.Context, req *sapb.AuthorizationID) error
+	RetryChallenge(ctx context.Context, req *sapb.RetryChallengeRequest) error
 }
 
 // StorageAuthority interface represents a simple key/value
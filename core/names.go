@@ -0,0 +1,96 @@
+package core
+
+import (
+	"crypto/sha256"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+
+	berrors "github.com/letsencrypt/boulder/errors"
+)
+
+// idnaProfile converts internationalized names to ASCII as RFC 5891 Section 5
+// describes for lookups, using the nontransitional UTS #46 mapping so that
+// e.g. "ß" isn't mapped to "ss".
+var idnaProfile = idna.New(idna.MapForLookup(), idna.BidiRule())
+
+// NormalizeName returns the canonical form of a DNS name, so that names can be
+// compared consistently by every component. The canonical form is lowercase,
+// has no trailing dot, has any internationalized labels converted to their
+// ASCII (punycode) form, and keeps a leading "*." wildcard label (see
+// WildcardBase).
+//
+// NormalizeName doesn't check that the name is one the CA is willing to issue
+// for; that's the policy authority's job. It only returns an error if an
+// internationalized name can't be converted to ASCII.
+func NormalizeName(name string) (string, error) {
+	name = strings.TrimSuffix(name, ".")
+	base, wildcard := WildcardBase(name)
+	if !isASCII(base) {
+		ascii, err := idnaProfile.ToASCII(base)
+		if err != nil {
+			return "", berrors.MalformedError("DNS name %q is not a valid internationalized domain name", name)
+		}
+		base = ascii
+	}
+	base = strings.ToLower(base)
+	if wildcard {
+		return "*." + base, nil
+	}
+	return base, nil
+}
+
+// NormalizeNames returns the set of the canonical forms of names, as returned
+// by NormalizeName, sorted alphabetically.
+func NormalizeNames(names []string) ([]string, error) {
+	nameMap := make(map[string]bool, len(names))
+	for _, name := range names {
+		normalized, err := NormalizeName(name)
+		if err != nil {
+			return nil, err
+		}
+		nameMap[normalized] = true
+	}
+	unique := make([]string, 0, len(nameMap))
+	for name := range nameMap {
+		unique = append(unique, name)
+	}
+	sort.Strings(unique)
+	return unique, nil
+}
+
+// HashNames returns the hash that identifies a set of names regardless of the
+// order, case or duplicates of the names, e.g. to find the orders and
+// certificates for the same set of names. It's the SHA-256 hash of the unique
+// names, lowercased without a trailing dot, sorted alphabetically and joined
+// with commas. The hashes of certificates and orders are stored, so this must
+// not change.
+func HashNames(names []string) []byte {
+	trimmed := make([]string, len(names))
+	for i, name := range names {
+		trimmed[i] = strings.TrimSuffix(name, ".")
+	}
+	hash := sha256.Sum256([]byte(strings.Join(UniqueLowerNames(trimmed), ",")))
+	return hash[:]
+}
+
+// WildcardBase returns the name a wildcard name covers the children of, e.g.
+// "example.com" for "*.example.com", and whether the name was a wildcard. A
+// name that isn't a wildcard is returned unchanged.
+func WildcardBase(name string) (string, bool) {
+	if strings.HasPrefix(name, "*.") {
+		return strings.TrimPrefix(name, "*."), true
+	}
+	return name, false
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestNormalizeName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		{"example.com", "example.com"},
+		{"EXAMPLE.com", "example.com"},
+		{"example.com.", "example.com"},
+		{"*.Example.COM.", "*.example.com"},
+		{"bücher.example", "xn--bcher-kva.example"},
+		{"BÜCHER.example", "xn--bcher-kva.example"},
+		{"*.bücher.example.", "*.xn--bcher-kva.example"},
+		{"faß.de", "xn--fa-hia.de"},
+		{"XN--BCHER-KVA.example", "xn--bcher-kva.example"},
+		// Names the PA rejects are left for it to reject
+		{"under_score.example.com", "under_score.example.com"},
+		{"a.*.example.com", "a.*.example.com"},
+	}
+	for _, tc := range testCases {
+		normalized, err := NormalizeName(tc.name)
+		test.AssertNotError(t, err, "NormalizeName failed for "+tc.name)
+		test.AssertEquals(t, normalized, tc.expected)
+	}
+
+	_, err := NormalizeName("bad\u200dname.example")
+	test.AssertError(t, err, "NormalizeName accepted an invalid internationalized name")
+}
+
+func TestNormalizeNames(t *testing.T) {
+	names, err := NormalizeNames([]string{"b.com", "B.COM.", "a.com", "*.a.com", "bücher.example"})
+	test.AssertNotError(t, err, "NormalizeNames failed")
+	test.AssertDeepEquals(t, names, []string{"*.a.com", "a.com", "b.com", "xn--bcher-kva.example"})
+
+	_, err = NormalizeNames([]string{"a.com", "bad\u200dname.example"})
+	test.AssertError(t, err, "NormalizeNames accepted an invalid internationalized name")
+}
+
+func TestWildcardBase(t *testing.T) {
+	base, wildcard := WildcardBase("*.example.com")
+	test.AssertEquals(t, base, "example.com")
+	test.Assert(t, wildcard, "*.example.com wasn't a wildcard")
+	base, wildcard = WildcardBase("www.example.com")
+	test.AssertEquals(t, base, "www.example.com")
+	test.Assert(t, !wildcard, "www.example.com was a wildcard")
+}
+
+func TestHashNames(t *testing.T) {
+	hash := HashNames([]string{"b.com", "a.com"})
+	// The hash of a set must not change, since hashes are stored
+	test.AssertEquals(t, fmt.Sprintf("%x", hash), fmt.Sprintf("%x", sha256.Sum256([]byte("a.com,b.com"))))
+	test.AssertByteEquals(t, HashNames([]string{"A.com.", "b.COM", "a.com"}), hash)
+	test.Assert(t, !bytes.Equal(HashNames([]string{"a.com"}), hash), "different sets had the same hash")
+}
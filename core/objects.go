@@ -42,11 +42,13 @@ const (
 	StatusInvalid     = AcmeStatus("invalid")     // Validation failed
 	StatusRevoked     = AcmeStatus("revoked")     // Object no longer valid
 	StatusDeactivated = AcmeStatus("deactivated") // Object has been deactivated
+	StatusPaused      = AcmeStatus("paused")      // Registration has been paused by an administrator
 )
 
 // These types are the available identification mechanisms
 const (
 	IdentifierDNS = IdentifierType("dns")
+	IdentifierIP  = IdentifierType("ip") // RFC 8738
 )
 
 // The types of ACME resources
@@ -202,9 +204,7 @@ func looksLikeKeyAuthorization(str string) error {
 		return fmt.Errorf("Invalid key authorization: does not look like a key authorization")
 	} else if !LooksLikeAToken(parts[0]) {
 		return fmt.Errorf("Invalid key authorization: malformed token")
-	} else if !LooksLikeAToken(parts[1]) {
-		// Thumbprints have the same syntax as tokens in boulder
-		// Both are base64-encoded and 32 octets
+	} else if !looksLikeAThumbprint(parts[1]) {
 		return fmt.Errorf("Invalid key authorization: malformed key thumbprint")
 	}
 	return nil
@@ -252,6 +252,30 @@ type Challenge struct {
 	// Contains information about URLs used or redirected to and IPs resolved and
 	// used
 	ValidationRecord []ValidationRecord `json:"validationRecord,omitempty"`
+
+	// The number of times validation of this challenge has been attempted.
+	// Only counted when the SA's StoreChallengeAttempts feature is enabled.
+	Attempts int64 `json:"attempts,omitempty"`
+}
+
+// A keyAuthorizer derives the key authorization of a challenge from its token
+// and the base64url encoded SHA-256 thumbprint of the account key.
+type keyAuthorizer func(token, thumbprint string) string
+
+// tokenDotThumbprint is the key authorization of RFC 8555 Section 8.1.
+func tokenDotThumbprint(token, thumbprint string) string {
+	return token + "." + thumbprint
+}
+
+// keyAuthorizers maps each challenge type to how its key authorization is
+// derived. With the new authorization schema every challenge of an
+// authorization shares one token, so a challenge type that gives its token its
+// own meaning must do so here rather than in how tokens are generated.
+var keyAuthorizers = map[string]keyAuthorizer{
+	ChallengeTypeHTTP01:    tokenDotThumbprint,
+	ChallengeTypeTLSSNI01:  tokenDotThumbprint,
+	ChallengeTypeDNS01:     tokenDotThumbprint,
+	ChallengeTypeTLSALPN01: tokenDotThumbprint,
 }
 
 // ExpectedKeyAuthorization computes the expected KeyAuthorization value for
@@ -266,7 +290,11 @@ func (ch Challenge) ExpectedKeyAuthorization(key *jose.JSONWebKey) (string, erro
 		return "", err
 	}
 
-	return ch.Token + "." + base64.RawURLEncoding.EncodeToString(thumbprint), nil
+	keyAuthorize, ok := keyAuthorizers[ch.Type]
+	if !ok {
+		keyAuthorize = tokenDotThumbprint
+	}
+	return keyAuthorize(ch.Token, base64.RawURLEncoding.EncodeToString(thumbprint)), nil
 }
 
 // RecordsSane checks the sanity of a ValidationRecord object before sending it
@@ -405,6 +433,12 @@ type Authorization struct {
 	// corresponds to a name `*.example.com` from an associated order.
 	Wildcard bool `json:"wildcard,omitempty" db:"-"`
 
+	// ValidationMethod is a Boulder-specific Authorization field holding the
+	// type of the challenge that was used to validate a valid authorization,
+	// e.g. "http-01". It is recorded for audits and so that CAA
+	// validationmethods can be enforced when the authorization is reused.
+	ValidationMethod string `json:"validationMethod,omitempty" db:"validationMethod"`
+
 	// v2 is used to indicate if the backing storage for this authorization is
 	// the new v2 style. It is not exposed to users.
 	V2 bool `json:"-" db:"-"`
@@ -44,6 +44,7 @@ type Challenge struct {
 	KeyAuthorization  *string             `protobuf:"bytes,5,opt,name=keyAuthorization" json:"keyAuthorization,omitempty"`
 	Validationrecords []*ValidationRecord `protobuf:"bytes,10,rep,name=validationrecords" json:"validationrecords,omitempty"`
 	Error             *ProblemDetails     `protobuf:"bytes,7,opt,name=error" json:"error,omitempty"`
+	Attempts          *int64              `protobuf:"varint,11,opt,name=attempts" json:"attempts,omitempty"`
 	XXX_unrecognized  []byte              `json:"-"`
 }
 
@@ -108,6 +109,13 @@ func (m *Challenge) GetError() *ProblemDetails {
 	return nil
 }
 
+func (m *Challenge) GetAttempts() int64 {
+	if m != nil && m.Attempts != nil {
+		return *m.Attempts
+	}
+	return 0
+}
+
 type ValidationRecord struct {
 	Hostname          *string  `protobuf:"bytes,1,opt,name=hostname" json:"hostname,omitempty"`
 	Port              *string  `protobuf:"bytes,2,opt,name=port" json:"port,omitempty"`
@@ -348,6 +356,7 @@ type Authorization struct {
 	Challenges       []*Challenge `protobuf:"bytes,6,rep,name=challenges" json:"challenges,omitempty"`
 	Combinations     []byte       `protobuf:"bytes,7,opt,name=combinations" json:"combinations,omitempty"`
 	V2               *bool        `protobuf:"varint,8,opt,name=v2" json:"v2,omitempty"`
+	ValidationMethod *string      `protobuf:"bytes,9,opt,name=validationMethod" json:"validationMethod,omitempty"`
 	XXX_unrecognized []byte       `json:"-"`
 }
 
@@ -412,6 +421,13 @@ func (m *Authorization) GetV2() bool {
 	return false
 }
 
+func (m *Authorization) GetValidationMethod() string {
+	if m != nil && m.ValidationMethod != nil {
+		return *m.ValidationMethod
+	}
+	return ""
+}
+
 type Order struct {
 	Id                *int64          `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
 	RegistrationID    *int64          `protobuf:"varint,2,opt,name=registrationID" json:"registrationID,omitempty"`
@@ -423,7 +439,13 @@ type Order struct {
 	Names             []string        `protobuf:"bytes,8,rep,name=names" json:"names,omitempty"`
 	BeganProcessing   *bool           `protobuf:"varint,9,opt,name=beganProcessing" json:"beganProcessing,omitempty"`
 	Created           *int64          `protobuf:"varint,10,opt,name=created" json:"created,omitempty"`
-	XXX_unrecognized  []byte          `json:"-"`
+	// replaces is the serial of the certificate the order replaces, if any
+	Replaces *string `protobuf:"bytes,11,opt,name=replaces" json:"replaces,omitempty"`
+	// exemptions are the policy exemption tokens the order uses
+	Exemptions []string `protobuf:"bytes,12,rep,name=exemptions" json:"exemptions,omitempty"`
+	// csrHash is the SHA-256 hash of the CSR the order was finalized with
+	CsrHash          []byte `protobuf:"bytes,13,opt,name=csrHash" json:"csrHash,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
 }
 
 func (m *Order) Reset()                    { *m = Order{} }
@@ -501,6 +523,27 @@ func (m *Order) GetCreated() int64 {
 	return 0
 }
 
+func (m *Order) GetReplaces() string {
+	if m != nil && m.Replaces != nil {
+		return *m.Replaces
+	}
+	return ""
+}
+
+func (m *Order) GetExemptions() []string {
+	if m != nil {
+		return m.Exemptions
+	}
+	return nil
+}
+
+func (m *Order) GetCsrHash() []byte {
+	if m != nil {
+		return m.CsrHash
+	}
+	return nil
+}
+
 type Empty struct {
 	XXX_unrecognized []byte `json:"-"`
 }
@@ -524,51 +567,55 @@ func init() {
 func init() { proto1.RegisterFile("core/proto/core.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 736 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x55, 0x41, 0x6e, 0xdb, 0x3a,
-	0x10, 0x85, 0x2c, 0x2b, 0xb6, 0xc6, 0xfe, 0x89, 0x43, 0xe4, 0x07, 0xc2, 0xc7, 0x47, 0x20, 0x68,
-	0xf1, 0x21, 0x04, 0x1f, 0x09, 0xe0, 0x1b, 0xa4, 0x49, 0x17, 0x59, 0xd5, 0x60, 0xd2, 0x2e, 0xba,
-	0x53, 0xa4, 0xa9, 0xcd, 0x46, 0x16, 0x0d, 0x92, 0x36, 0xea, 0xde, 0xa1, 0x07, 0xe9, 0x61, 0x7a,
-	0x8d, 0x2e, 0x7b, 0x84, 0xa2, 0xe0, 0x50, 0xb6, 0x65, 0x39, 0x45, 0x77, 0x33, 0x8f, 0x94, 0x39,
-	0xf3, 0xde, 0x9b, 0x31, 0xfc, 0x9d, 0x4b, 0x85, 0xd7, 0x0b, 0x25, 0x8d, 0xbc, 0xb6, 0xe1, 0x15,
-	0x85, 0xac, 0x6b, 0xe3, 0xe4, 0x4b, 0x07, 0xc2, 0xdb, 0x59, 0x56, 0x96, 0x58, 0x4d, 0x91, 0x1d,
-	0x43, 0x47, 0x14, 0x91, 0x17, 0x7b, 0xa9, 0xcf, 0x3b, 0xa2, 0x60, 0x0c, 0xba, 0x66, 0xbd, 0xc0,
-	0xa8, 0x13, 0x7b, 0x69, 0xc8, 0x29, 0x66, 0xe7, 0x70, 0xa4, 0x4d, 0x66, 0x96, 0x3a, 0x3a, 0x22,
-	0xb4, 0xce, 0xd8, 0x08, 0xfc, 0xa5, 0x12, 0x51, 0x48, 0xa0, 0x0d, 0xd9, 0x19, 0x04, 0x46, 0x3e,
-	0x63, 0x15, 0xf9, 0x84, 0xb9, 0x84, 0x5d, 0xc2, 0xe8, 0x19, 0xd7, 0x37, 0x4b, 0x33, 0x93, 0x4a,
-	0x7c, 0xce, 0x8c, 0x90, 0x55, 0x14, 0xd0, 0x85, 0x03, 0x9c, 0xdd, 0xc1, 0xe9, 0x2a, 0x2b, 0x45,
-	0x41, 0x99, 0xc2, 0x5c, 0xaa, 0x42, 0x47, 0x10, 0xfb, 0xe9, 0x60, 0x7c, 0x7e, 0x45, 0xbd, 0xbc,
-	0xdb, 0x1e, 0x73, 0x3a, 0xe6, 0x87, 0x1f, 0xb0, 0x4b, 0x08, 0x50, 0x29, 0xa9, 0xa2, 0x5e, 0xec,
-	0xa5, 0x83, 0xf1, 0x99, 0xfb, 0x72, 0xa2, 0xe4, 0x53, 0x89, 0xf3, 0x3b, 0x34, 0x99, 0x28, 0x35,
-	0x77, 0x57, 0x92, 0x1f, 0x1e, 0x8c, 0xda, 0xbf, 0xc9, 0xfe, 0x81, 0xfe, 0x4c, 0x6a, 0x53, 0x65,
-	0x73, 0x24, 0x72, 0x42, 0xbe, 0xcd, 0x2d, 0x45, 0x0b, 0xa9, 0xcc, 0x86, 0x22, 0x1b, 0xb3, 0xff,
-	0xe1, 0x34, 0x2b, 0x0a, 0x85, 0x5a, 0xa3, 0xe6, 0xa8, 0x65, 0xb9, 0xc2, 0x22, 0xf2, 0x63, 0x3f,
-	0x1d, 0xf2, 0xc3, 0x03, 0x16, 0xc3, 0xa0, 0x06, 0xdf, 0x6a, 0x2c, 0xa2, 0x6e, 0xec, 0xa5, 0x43,
-	0xde, 0x84, 0xe8, 0x86, 0xe3, 0xc5, 0x08, 0xd4, 0x51, 0x10, 0xfb, 0x69, 0xc8, 0x9b, 0x90, 0x23,
-	0xbf, 0xac, 0x15, 0xb1, 0x21, 0xfb, 0x0f, 0x8e, 0xb7, 0x4f, 0x3d, 0x2a, 0x81, 0x45, 0xd4, 0xa3,
-	0x02, 0x5a, 0x68, 0xf2, 0x11, 0x8e, 0xf7, 0x99, 0xb0, 0xaf, 0x2d, 0x1c, 0xf2, 0x68, 0xb5, 0x77,
-	0x0d, 0x37, 0x21, 0x6b, 0x81, 0x82, 0x2e, 0xd7, 0x5d, 0xd7, 0x19, 0xbb, 0x00, 0x98, 0x19, 0xb3,
-	0x78, 0x70, 0xf6, 0xb0, 0xaa, 0x07, 0xbc, 0x81, 0x24, 0x5f, 0x3d, 0x18, 0xdc, 0xa2, 0x32, 0xe2,
-	0x83, 0xc8, 0x33, 0x83, 0xb6, 0x46, 0x85, 0x53, 0xa1, 0x8d, 0x22, 0xb6, 0xef, 0xef, 0x6a, 0xeb,
-	0xb5, 0x50, 0xb2, 0x1c, 0x2a, 0x91, 0x6d, 0xdf, 0x73, 0x19, 0xd5, 0x21, 0xa6, 0xa8, 0x4d, 0xed,
-	0xb0, 0x3a, 0xb3, 0x6c, 0x14, 0xa8, 0x6a, 0x26, 0x6d, 0x68, 0x6f, 0x0a, 0xad, 0x97, 0x58, 0x90,
-	0xd5, 0x7c, 0x5e, 0x67, 0x2c, 0x82, 0x1e, 0x7e, 0x5a, 0x08, 0x85, 0xce, 0xcd, 0x3e, 0xdf, 0xa4,
-	0xc9, 0x77, 0x0f, 0x86, 0xbc, 0x51, 0xc6, 0xc1, 0x6c, 0x8c, 0xc0, 0x7f, 0xc6, 0x35, 0x55, 0x34,
-	0xe4, 0x36, 0xb4, 0x3f, 0x96, 0xcb, 0xca, 0x64, 0xb9, 0x21, 0xb1, 0x43, 0xbe, 0x49, 0x59, 0x0a,
-	0x27, 0x75, 0xa8, 0x27, 0x0a, 0x35, 0x56, 0x86, 0x8a, 0xeb, 0xf3, 0x36, 0xcc, 0xfe, 0x85, 0x30,
-	0x9b, 0x2a, 0xc4, 0xb9, 0xbd, 0xe3, 0xc6, 0x62, 0x07, 0xd8, 0x53, 0x51, 0x09, 0x23, 0xb2, 0xf2,
-	0x7e, 0x42, 0x05, 0x0f, 0xf9, 0x0e, 0xb0, 0xa7, 0xb9, 0xc2, 0xcc, 0x60, 0x71, 0x63, 0xc8, 0xeb,
-	0x3e, 0xdf, 0x01, 0x8d, 0xb9, 0xed, 0x37, 0xe7, 0x36, 0xf9, 0xe9, 0xc1, 0x5f, 0xfb, 0x53, 0xb7,
-	0xeb, 0x34, 0xa4, 0x4e, 0x2f, 0x00, 0x44, 0x81, 0x95, 0x95, 0x0d, 0x55, 0x2d, 0x41, 0x03, 0x79,
-	0x41, 0x46, 0xff, 0xb7, 0x32, 0xba, 0x0a, 0xba, 0x7b, 0x9b, 0xa3, 0x21, 0x42, 0xb0, 0x27, 0x02,
-	0xbb, 0x06, 0xc8, 0x37, 0xcb, 0xc9, 0x2a, 0x64, 0x07, 0xff, 0xc4, 0x8d, 0xef, 0x76, 0x69, 0xf1,
-	0xc6, 0x15, 0x96, 0xc0, 0x30, 0x97, 0xf3, 0x27, 0x51, 0xd1, 0x9b, 0x9a, 0x58, 0x18, 0xf2, 0x3d,
-	0xcc, 0xb6, 0xb7, 0x1a, 0x13, 0x09, 0x7d, 0xde, 0x59, 0x8d, 0x93, 0x6f, 0x1d, 0x08, 0xde, 0x28,
-	0xeb, 0x92, 0xb6, 0xc4, 0x87, 0x8d, 0x75, 0x5e, 0x6c, 0xac, 0xd1, 0x80, 0xbf, 0xdf, 0xc0, 0x76,
-	0xf5, 0x74, 0xff, 0xb8, 0x7a, 0xec, 0xd6, 0xc8, 0x77, 0xc3, 0xf1, 0xe0, 0x0c, 0xef, 0x2c, 0x70,
-	0x78, 0x40, 0xf3, 0xdd, 0x54, 0xcd, 0xd1, 0x13, 0xf2, 0x16, 0xda, 0x20, 0xbd, 0xb7, 0x47, 0xfa,
-	0x19, 0x04, 0x76, 0x7f, 0x59, 0x37, 0xd8, 0xcf, 0x5c, 0x62, 0x8d, 0xfa, 0x84, 0xd3, 0xac, 0x9a,
-	0x28, 0x99, 0xa3, 0xd6, 0xa2, 0x9a, 0xd2, 0x42, 0xef, 0xf3, 0x36, 0x4c, 0x66, 0x77, 0xde, 0x8a,
-	0xc0, 0xf5, 0x5c, 0xa7, 0x49, 0x0f, 0x82, 0xd7, 0xf3, 0x85, 0x59, 0xbf, 0xea, 0xbd, 0x0f, 0xe8,
-	0xaf, 0xe6, 0x57, 0x00, 0x00, 0x00, 0xff, 0xff, 0xbe, 0x8c, 0x5a, 0x8e, 0x82, 0x06, 0x00, 0x00,
+	// 794 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x55, 0xc1, 0x6e, 0xdb, 0x46,
+	0x10, 0x85, 0x44, 0x31, 0x12, 0x47, 0x8a, 0x63, 0x2f, 0xdc, 0x60, 0x51, 0x14, 0x81, 0xc0, 0x43,
+	0x41, 0x04, 0x41, 0x0c, 0xf8, 0x0f, 0xd2, 0xb8, 0x40, 0x73, 0x28, 0x6a, 0x6c, 0xd2, 0x1e, 0x7a,
+	0x5b, 0x93, 0x53, 0x69, 0x6b, 0x8a, 0x4b, 0xec, 0xae, 0x8d, 0xa8, 0xbf, 0xd3, 0x53, 0xfa, 0x61,
+	0x3d, 0xf6, 0x1b, 0x8a, 0x99, 0xa5, 0x28, 0x4a, 0x72, 0xd1, 0xdb, 0xbc, 0xb7, 0x4b, 0xed, 0xcc,
+	0x9b, 0x37, 0x23, 0xf8, 0xaa, 0xb4, 0x0e, 0xaf, 0x5a, 0x67, 0x83, 0xbd, 0xa2, 0xf0, 0x2d, 0x87,
+	0x62, 0x42, 0x71, 0xfe, 0x65, 0x0c, 0xd9, 0xfb, 0xb5, 0xae, 0x6b, 0x6c, 0x56, 0x28, 0xce, 0x60,
+	0x6c, 0x2a, 0x39, 0x5a, 0x8e, 0x8a, 0x44, 0x8d, 0x4d, 0x25, 0x04, 0x4c, 0xc2, 0xb6, 0x45, 0x39,
+	0x5e, 0x8e, 0x8a, 0x4c, 0x71, 0x2c, 0x5e, 0xc2, 0x33, 0x1f, 0x74, 0x78, 0xf0, 0xf2, 0x19, 0xb3,
+	0x1d, 0x12, 0xe7, 0x90, 0x3c, 0x38, 0x23, 0x33, 0x26, 0x29, 0x14, 0x97, 0x90, 0x06, 0x7b, 0x8f,
+	0x8d, 0x4c, 0x98, 0x8b, 0x40, 0xbc, 0x86, 0xf3, 0x7b, 0xdc, 0xbe, 0x7b, 0x08, 0x6b, 0xeb, 0xcc,
+	0x1f, 0x3a, 0x18, 0xdb, 0xc8, 0x94, 0x2f, 0x9c, 0xf0, 0xe2, 0x06, 0x2e, 0x1e, 0x75, 0x6d, 0x2a,
+	0x46, 0x0e, 0x4b, 0xeb, 0x2a, 0x2f, 0x61, 0x99, 0x14, 0xf3, 0xeb, 0x97, 0x6f, 0xb9, 0x96, 0x5f,
+	0xfa, 0x63, 0xc5, 0xc7, 0xea, 0xf4, 0x03, 0xf1, 0x1a, 0x52, 0x74, 0xce, 0x3a, 0x39, 0x5d, 0x8e,
+	0x8a, 0xf9, 0xf5, 0x65, 0xfc, 0xf2, 0xd6, 0xd9, 0xbb, 0x1a, 0x37, 0x37, 0x18, 0xb4, 0xa9, 0xbd,
+	0x8a, 0x57, 0xc4, 0xd7, 0x30, 0xd3, 0x21, 0xe0, 0xa6, 0x0d, 0x5e, 0xce, 0x59, 0x87, 0x1e, 0xe7,
+	0xff, 0x8c, 0xe0, 0xfc, 0xf8, 0x3d, 0xfa, 0x60, 0x6d, 0x7d, 0x68, 0xf4, 0x06, 0x59, 0xb8, 0x4c,
+	0xf5, 0x98, 0xe4, 0x6b, 0xad, 0x0b, 0x3b, 0xf9, 0x28, 0x16, 0x6f, 0xe0, 0x42, 0x57, 0x95, 0x43,
+	0xef, 0xd1, 0x2b, 0xf4, 0xb6, 0x7e, 0xc4, 0x4a, 0x26, 0xcb, 0xa4, 0x58, 0xa8, 0xd3, 0x03, 0xb1,
+	0x84, 0x79, 0x47, 0xfe, 0xec, 0xb1, 0x92, 0x93, 0xe5, 0xa8, 0x58, 0xa8, 0x21, 0xc5, 0x37, 0xa2,
+	0x66, 0xc1, 0xa0, 0x97, 0xe9, 0x32, 0x29, 0x32, 0x35, 0xa4, 0x62, 0x63, 0xea, 0xae, 0x5b, 0x14,
+	0x8a, 0x6f, 0xe1, 0xac, 0x7f, 0xea, 0x93, 0x33, 0x58, 0xc9, 0x29, 0x27, 0x70, 0xc4, 0xe6, 0xbf,
+	0xc3, 0xd9, 0xa1, 0x4a, 0xf4, 0x5a, 0x1b, 0x99, 0x4f, 0xdb, 0x76, 0x57, 0xf0, 0x90, 0x22, 0x7b,
+	0x54, 0x7c, 0xb9, 0xab, 0xba, 0x43, 0xe2, 0x15, 0xc0, 0x3a, 0x84, 0xf6, 0x63, 0xb4, 0x0e, 0x39,
+	0x22, 0x55, 0x03, 0x26, 0xff, 0x32, 0x82, 0xf9, 0x7b, 0x74, 0xc1, 0xfc, 0x66, 0x4a, 0x1d, 0x90,
+	0x72, 0x74, 0xb8, 0x32, 0x3e, 0x38, 0x56, 0xfb, 0xc3, 0x4d, 0x67, 0xcb, 0x23, 0x96, 0xed, 0x88,
+	0xce, 0xe8, 0xfe, 0xbd, 0x88, 0x38, 0x0f, 0xb3, 0x42, 0x1f, 0x3a, 0xf7, 0x75, 0x88, 0xd4, 0xa8,
+	0xd0, 0x75, 0x4a, 0x52, 0x48, 0x37, 0x8d, 0xf7, 0x0f, 0x58, 0xb1, 0x0d, 0x13, 0xd5, 0x21, 0x21,
+	0x61, 0x8a, 0x9f, 0x5b, 0xe3, 0x30, 0x3a, 0x3d, 0x51, 0x3b, 0x98, 0xff, 0x3d, 0x82, 0x85, 0x1a,
+	0xa4, 0x71, 0x32, 0x37, 0xe7, 0x90, 0xdc, 0xe3, 0x96, 0x33, 0x5a, 0x28, 0x0a, 0xe9, 0xc7, 0x4a,
+	0xdb, 0x04, 0x5d, 0x06, 0x6e, 0x76, 0xa6, 0x76, 0x50, 0x14, 0xf0, 0xa2, 0x0b, 0xfd, 0xad, 0x43,
+	0x8f, 0x4d, 0xe0, 0xe4, 0x66, 0xea, 0x98, 0x16, 0xdf, 0x40, 0xa6, 0x57, 0x0e, 0x71, 0x43, 0x77,
+	0xe2, 0xc8, 0xec, 0x09, 0x3a, 0x35, 0x8d, 0x09, 0x46, 0xd7, 0x1f, 0x6e, 0x39, 0xe1, 0x85, 0xda,
+	0x13, 0x74, 0x5a, 0x3a, 0xd4, 0x01, 0xab, 0x77, 0x81, 0xe7, 0x20, 0x51, 0x7b, 0x62, 0x30, 0xd3,
+	0xb3, 0xe1, 0x4c, 0xe7, 0x7f, 0x8d, 0xe1, 0xf9, 0xe1, 0x44, 0xee, 0x2b, 0xcd, 0xb8, 0xd2, 0x57,
+	0x00, 0xa6, 0xc2, 0x86, 0xda, 0x86, 0xae, 0x6b, 0xc1, 0x80, 0x79, 0xa2, 0x8d, 0xc9, 0x7f, 0xb6,
+	0x31, 0x66, 0x30, 0x39, 0xd8, 0x2a, 0x83, 0x26, 0xa4, 0x07, 0x4d, 0x10, 0x57, 0x00, 0xe5, 0x6e,
+	0x71, 0x51, 0x87, 0x68, 0x29, 0xbc, 0x88, 0xa3, 0xdd, 0x2f, 0x34, 0x35, 0xb8, 0x22, 0x72, 0x58,
+	0x94, 0x76, 0x73, 0x67, 0x1a, 0x7e, 0xd3, 0xb3, 0x0a, 0x0b, 0x75, 0xc0, 0x51, 0x79, 0x8f, 0xd7,
+	0x2c, 0xc2, 0x4c, 0x8d, 0x1f, 0xaf, 0x69, 0x59, 0xed, 0xf7, 0xc9, 0x8f, 0x18, 0xd6, 0xb6, 0xea,
+	0x36, 0xdc, 0x09, 0x9f, 0xff, 0x99, 0x40, 0xfa, 0x93, 0x23, 0x47, 0x1d, 0xdb, 0xe1, 0x54, 0x84,
+	0xf1, 0x93, 0x22, 0x0c, 0x8a, 0x4d, 0x0e, 0x8b, 0xed, 0x57, 0xd8, 0xe4, 0xff, 0x57, 0xd8, 0x1b,
+	0xb8, 0x28, 0xf7, 0x83, 0xf4, 0x31, 0x0e, 0x47, 0xb4, 0xcb, 0xe9, 0x01, 0xef, 0x82, 0x61, 0x87,
+	0xa3, 0x94, 0x99, 0x3a, 0x62, 0x07, 0x0d, 0x9a, 0x1e, 0x34, 0xe8, 0x12, 0x52, 0xda, 0x75, 0xe4,
+	0x1c, 0xfa, 0x2c, 0x02, 0x32, 0xf5, 0x1d, 0xae, 0x74, 0x73, 0xeb, 0x6c, 0x89, 0xde, 0x9b, 0x66,
+	0xc5, 0xb2, 0xcd, 0xd4, 0x31, 0xcd, 0x83, 0x11, 0x7d, 0x28, 0x21, 0xd6, 0xdc, 0x41, 0xda, 0xac,
+	0x0e, 0xdb, 0x5a, 0x97, 0x18, 0x57, 0x71, 0xa6, 0x7a, 0x4c, 0xb6, 0xc3, 0xcf, 0xb4, 0x95, 0x39,
+	0xe3, 0x05, 0x3f, 0x3d, 0x60, 0xf8, 0x57, 0xbd, 0xfb, 0x41, 0xfb, 0xb5, 0x7c, 0xce, 0x6d, 0xde,
+	0xc1, 0x7c, 0x0a, 0xe9, 0xf7, 0x9b, 0x36, 0x6c, 0xbf, 0x9b, 0xfe, 0x9a, 0xf2, 0x1f, 0xe1, 0xbf,
+	0x03, 0x00, 0x43, 0xa4, 0xc4, 0x48, 0x20, 0x07, 0x00, 0x00,
 }
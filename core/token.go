@@ -0,0 +1,117 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// base64URLAlphabet is the URL-safe base64 alphabet, the only characters ACME
+// allows in a token (RFC 8555 Section 8.1).
+const base64URLAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+
+const (
+	// minTokenEntropy is the fewest bits of entropy RFC 8555 Section 8.1
+	// allows a token to have.
+	minTokenEntropy = 128
+	// maxTokenLength is the longest token LooksLikeAToken accepts.
+	maxTokenLength = 128
+)
+
+// NewToken produces a random string for Challenges, etc.
+func NewToken() string {
+	return RandomString(32)
+}
+
+// TokenFormat describes the random tokens generated for challenges. The zero
+// TokenFormat generates tokens like NewToken: 32 random bytes, base64url
+// encoded.
+type TokenFormat struct {
+	// Length is the number of characters in a token.
+	Length int
+	// Alphabet is the characters each character of a token is chosen from.
+	// It may only contain characters from the URL-safe base64 alphabet, and
+	// defaults to all of them.
+	Alphabet string
+}
+
+func (f TokenFormat) alphabet() string {
+	if f.Alphabet == "" {
+		return base64URLAlphabet
+	}
+	return f.Alphabet
+}
+
+// Check returns an error if tokens in the format wouldn't be valid ACME
+// tokens, or wouldn't have the 128 bits of entropy RFC 8555 requires.
+func (f TokenFormat) Check() error {
+	if f.Length == 0 && f.Alphabet == "" {
+		return nil
+	}
+	if f.Length <= 0 || f.Length > maxTokenLength {
+		return fmt.Errorf("token length %d isn't between 1 and %d", f.Length, maxTokenLength)
+	}
+	alphabet := f.alphabet()
+	for i, c := range alphabet {
+		if !strings.ContainsRune(base64URLAlphabet, c) {
+			return fmt.Errorf("token alphabet contains %q, which isn't in the URL-safe base64 alphabet", c)
+		}
+		if strings.ContainsRune(alphabet[:i], c) {
+			return fmt.Errorf("token alphabet contains %q more than once", c)
+		}
+	}
+	entropy := float64(f.Length) * math.Log2(float64(len(alphabet)))
+	if entropy < minTokenEntropy {
+		return fmt.Errorf("tokens of %d characters from a %d character alphabet have %.0f bits of entropy, fewer than %d",
+			f.Length, len(alphabet), entropy, minTokenEntropy)
+	}
+	return nil
+}
+
+// NewToken produces a random token in the format, which must have passed
+// Check.
+func (f TokenFormat) NewToken() string {
+	if f.Length == 0 {
+		return NewToken()
+	}
+	alphabet := f.alphabet()
+	// Random bytes are mapped onto the alphabet by their remainder, so bytes
+	// at or above the largest multiple of the alphabet's size are discarded
+	// to keep every character equally likely.
+	limit := 256 - 256%len(alphabet)
+	token := make([]byte, 0, f.Length)
+	b := make([]byte, f.Length)
+	for len(token) < f.Length {
+		_, err := io.ReadFull(RandReader, b)
+		if err != nil {
+			panic(fmt.Sprintf("Error reading random bytes: %s", err))
+		}
+		for _, c := range b {
+			if int(c) < limit && len(token) < f.Length {
+				token = append(token, alphabet[int(c)%len(alphabet)])
+			}
+		}
+	}
+	return string(token)
+}
+
+var tokenPattern = regexp.MustCompile(fmt.Sprintf(`^[\w-]{%d,%d}$`,
+	int(math.Ceil(minTokenEntropy/6.0)), maxTokenLength))
+
+// LooksLikeAToken checks whether a string could be a token of any TokenFormat:
+// long enough to hold 128 bits in the URL-safe base64 alphabet, and no longer
+// than the longest format allows. Tokens issued under an earlier format stay
+// valid when the format changes.
+func LooksLikeAToken(token string) bool {
+	return tokenPattern.MatchString(token)
+}
+
+var thumbprintPattern = regexp.MustCompile(`^[\w-]{43}$`)
+
+// looksLikeAThumbprint checks whether a string represents a 32-octet SHA-256
+// JWK thumbprint in the URL-safe base64 alphabet.
+func looksLikeAThumbprint(thumbprint string) bool {
+	return thumbprintPattern.MatchString(thumbprint)
+}
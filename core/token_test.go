@@ -0,0 +1,76 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestNewToken(t *testing.T) {
+	token := NewToken()
+	fmt.Println(token)
+	tokenLength := int(math.Ceil(32 * 8 / 6.0)) // 32 bytes, b64 encoded
+	if len(token) != tokenLength {
+		t.Fatalf("Expected token of length %d, got %d", tokenLength, len(token))
+	}
+	collider := map[string]bool{}
+	// Test for very blatant RNG failures:
+	// Try 2^20 birthdays in a 2^72 search space...
+	// our naive collision probability here is  2^-32...
+	for i := 0; i < 1000000; i++ {
+		token = NewToken()[:12] // just sample a portion
+		test.Assert(t, !collider[token], "Token collision!")
+		collider[token] = true
+	}
+	return
+}
+
+func TestLooksLikeAToken(t *testing.T) {
+	test.Assert(t, !LooksLikeAToken("R-UL_7MrV3tUUjO9v5ym2"), "Accepted short token")
+	test.Assert(t, !LooksLikeAToken(strings.Repeat("R", 129)), "Accepted long token")
+	test.Assert(t, !LooksLikeAToken("R-UL_7MrV3tUUjO9v5ym2srK3dGGCwlxbVyKBdwLOS%"), "Accepted invalid token")
+	test.Assert(t, LooksLikeAToken("R-UL_7MrV3tUUjO9v5ym2srK3dGGCwlxbVyKBdwLOSU"), "Rejected valid token")
+	test.Assert(t, LooksLikeAToken("R-UL_7MrV3tUUjO9v5ym2srK3dGGCwlxbV"), "Rejected valid shorter token")
+}
+
+func TestTokenFormatCheck(t *testing.T) {
+	testCases := []struct {
+		format TokenFormat
+		valid  bool
+	}{
+		{TokenFormat{}, true},
+		{TokenFormat{Length: 22}, true},
+		{TokenFormat{Length: 21}, false},
+		{TokenFormat{Length: 129}, false},
+		{TokenFormat{Alphabet: "abcdef0123456789"}, false},
+		{TokenFormat{Length: 32, Alphabet: "abcdef0123456789"}, true},
+		{TokenFormat{Length: 31, Alphabet: "abcdef0123456789"}, false},
+		{TokenFormat{Length: 32, Alphabet: "abcdef012345678+"}, false},
+		{TokenFormat{Length: 32, Alphabet: "abcdef012345678a"}, false},
+		{TokenFormat{Length: 128, Alphabet: "a"}, false},
+	}
+	for _, tc := range testCases {
+		err := tc.format.Check()
+		if tc.valid {
+			test.AssertNotError(t, err, fmt.Sprintf("%+v was rejected", tc.format))
+		} else {
+			test.AssertError(t, err, fmt.Sprintf("%+v was accepted", tc.format))
+		}
+	}
+}
+
+func TestTokenFormatNewToken(t *testing.T) {
+	test.AssertEquals(t, len(TokenFormat{}.NewToken()), 43)
+
+	format := TokenFormat{Length: 40, Alphabet: "abcdefghijklmnopqrstuvwxyz0123456789"}
+	test.AssertNotError(t, format.Check(), "format rejected")
+	for i := 0; i < 100; i++ {
+		token := format.NewToken()
+		test.AssertEquals(t, len(token), 40)
+		test.Assert(t, LooksLikeAToken(token), "token doesn't look like a token")
+		test.AssertEquals(t, strings.Trim(token, format.Alphabet), "")
+	}
+}
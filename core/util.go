@@ -16,7 +16,6 @@ import (
 	"io/ioutil"
 	"math/big"
 	mrand "math/rand"
-	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -64,19 +63,6 @@ func RandomString(byteLength int) string {
 	return base64.RawURLEncoding.EncodeToString(b)
 }
 
-// NewToken produces a random string for Challenges, etc.
-func NewToken() string {
-	return RandomString(32)
-}
-
-var tokenFormat = regexp.MustCompile("^[\\w-]{43}$")
-
-// LooksLikeAToken checks whether a string represents a 32-octet value in
-// the URL-safe base64 alphabet.
-func LooksLikeAToken(token string) bool {
-	return tokenFormat.MatchString(token)
-}
-
 // Fingerprints
 
 // Fingerprint256 produces an unpadded, URL-safe Base64-encoded SHA256 digest
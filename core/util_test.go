@@ -3,7 +3,6 @@ package core
 import (
 	"encoding/json"
 	"fmt"
-	"math"
 	"math/big"
 	"sort"
 	"strings"
@@ -14,32 +13,6 @@ import (
 	"github.com/letsencrypt/boulder/test"
 )
 
-// challenges.go
-func TestNewToken(t *testing.T) {
-	token := NewToken()
-	fmt.Println(token)
-	tokenLength := int(math.Ceil(32 * 8 / 6.0)) // 32 bytes, b64 encoded
-	if len(token) != tokenLength {
-		t.Fatalf("Expected token of length %d, got %d", tokenLength, len(token))
-	}
-	collider := map[string]bool{}
-	// Test for very blatant RNG failures:
-	// Try 2^20 birthdays in a 2^72 search space...
-	// our naive collision probability here is  2^-32...
-	for i := 0; i < 1000000; i++ {
-		token = NewToken()[:12] // just sample a portion
-		test.Assert(t, !collider[token], "Token collision!")
-		collider[token] = true
-	}
-	return
-}
-
-func TestLooksLikeAToken(t *testing.T) {
-	test.Assert(t, !LooksLikeAToken("R-UL_7MrV3tUUjO9v5ym2srK3dGGCwlxbVyKBdwLOS"), "Accepted short token")
-	test.Assert(t, !LooksLikeAToken("R-UL_7MrV3tUUjO9v5ym2srK3dGGCwlxbVyKBdwLOS%"), "Accepted invalid token")
-	test.Assert(t, LooksLikeAToken("R-UL_7MrV3tUUjO9v5ym2srK3dGGCwlxbVyKBdwLOSU"), "Rejected valid token")
-}
-
 func TestSerialUtils(t *testing.T) {
 	serial := SerialToString(big.NewInt(100000000000000000))
 	test.AssertEquals(t, serial, "00000000000000000000016345785d8a0000")
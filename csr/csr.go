@@ -2,12 +2,15 @@ package csr
 
 import (
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
 	"crypto/x509"
 	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
 	"github.com/letsencrypt/boulder/goodkey"
 )
 
@@ -31,6 +34,41 @@ var goodSignatureAlgorithms = map[x509.SignatureAlgorithm]bool{
 	x509.ECDSAWithSHA512: true,
 }
 
+// NameLimits are the maximum numbers of DNS names allowed in a certificate.
+// Certificates for RSA and ECDSA keys are issued with different profiles (the
+// CA's RSAProfile and ECDSAProfile), and each profile may have its own limit.
+type NameLimits struct {
+	// Default is the limit for profiles that don't have their own.
+	Default int
+	RSA     int
+	ECDSA   int
+}
+
+// ForKey returns the limit for the profile used to issue certificates for key.
+func (nl NameLimits) ForKey(key crypto.PublicKey) int {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		if nl.RSA != 0 {
+			return nl.RSA
+		}
+	case *ecdsa.PublicKey:
+		if nl.ECDSA != 0 {
+			return nl.ECDSA
+		}
+	}
+	return nl.Default
+}
+
+// Max returns the largest limit of any profile. It applies before the key, and
+// so the profile, is known, e.g. to the identifiers in a new order.
+func (nl NameLimits) Max() int {
+	max := nl.ForKey(&rsa.PublicKey{})
+	if ecdsaMax := nl.ForKey(&ecdsa.PublicKey{}); ecdsaMax > max {
+		max = ecdsaMax
+	}
+	return max
+}
+
 var (
 	invalidPubKey       = errors.New("invalid public key in CSR")
 	unsupportedSigAlg   = errors.New("signature algorithm not supported")
@@ -42,36 +80,46 @@ var (
 
 // VerifyCSR checks the validity of a x509.CertificateRequest. Before doing checks it normalizes
 // the CSR which lowers the case of DNS names and subject CN, and if forceCNFromSAN is true it
-// will hoist a DNS name into the CN if it is empty.
-func VerifyCSR(csr *x509.CertificateRequest, maxNames int, keyPolicy *goodkey.KeyPolicy, pa core.PolicyAuthority, forceCNFromSAN bool, regID int64) error {
+// will hoist a DNS name into the CN if it is empty. Names in exemptions are checked against the
+// hostname policy ignoring the entry they map to, which a policy.Exemption allows. It returns
+// the first of the problems CheckCSR finds.
+func VerifyCSR(csr *x509.CertificateRequest, maxNames int, keyPolicy *goodkey.KeyPolicy, pa core.PolicyAuthority, forceCNFromSAN bool, regID int64, exemptions map[string]string) error {
+	if errs := CheckCSR(csr, maxNames, keyPolicy, pa, forceCNFromSAN, regID, exemptions); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// CheckCSR normalizes a x509.CertificateRequest like VerifyCSR does and returns every problem
+// with it rather than just the first, so that they can all be reported at once.
+func CheckCSR(csr *x509.CertificateRequest, maxNames int, keyPolicy *goodkey.KeyPolicy, pa core.PolicyAuthority, forceCNFromSAN bool, regID int64, exemptions map[string]string) []error {
+	var errs []error
 	normalizeCSR(csr, forceCNFromSAN)
 	key, ok := csr.PublicKey.(crypto.PublicKey)
 	if !ok {
-		return invalidPubKey
-	}
-	if err := keyPolicy.GoodKey(key); err != nil {
-		return fmt.Errorf("invalid public key in CSR: %s", err)
+		errs = append(errs, invalidPubKey)
+	} else if err := keyPolicy.GoodKey(key); err != nil {
+		errs = append(errs, berrors.BadPublicKeyError("invalid public key in CSR: %s", err))
 	}
 	if !goodSignatureAlgorithms[csr.SignatureAlgorithm] {
-		return unsupportedSigAlg
-	}
-	if err := csr.CheckSignature(); err != nil {
-		return invalidSig
+		errs = append(errs, unsupportedSigAlg)
+	} else if err := csr.CheckSignature(); err != nil {
+		errs = append(errs, invalidSig)
 	}
 	if len(csr.EmailAddresses) > 0 {
-		return invalidEmailPresent
+		errs = append(errs, invalidEmailPresent)
 	}
 	if len(csr.IPAddresses) > 0 {
-		return invalidIPPresent
+		errs = append(errs, invalidIPPresent)
 	}
 	if len(csr.DNSNames) == 0 && csr.Subject.CommonName == "" {
-		return invalidNoDNS
+		errs = append(errs, invalidNoDNS)
 	}
 	if len(csr.Subject.CommonName) > maxCNLength {
-		return fmt.Errorf("CN was longer than %d bytes", maxCNLength)
+		errs = append(errs, fmt.Errorf("CN was longer than %d bytes", maxCNLength))
 	}
 	if len(csr.DNSNames) > maxNames {
-		return fmt.Errorf("CSR contains more than %d DNS names", maxNames)
+		errs = append(errs, fmt.Errorf("CSR contains more than %d DNS names", maxNames))
 	}
 	badNames := []string{}
 	for _, name := range csr.DNSNames {
@@ -80,14 +128,19 @@ func VerifyCSR(csr *x509.CertificateRequest, maxNames int, keyPolicy *goodkey.Ke
 			Value: name,
 		}
 		var err error
-		if err = pa.WillingToIssueWildcard(ident); err != nil {
+		if entry, ok := exemptions[name]; ok {
+			err = pa.WillingToIssueExempt(ident, entry)
+		} else {
+			err = pa.WillingToIssueWildcard(ident)
+		}
+		if err != nil {
 			badNames = append(badNames, fmt.Sprintf("%q", name))
 		}
 	}
 	if len(badNames) > 0 {
-		return fmt.Errorf("policy forbids issuing for: %s", strings.Join(badNames, ", "))
+		errs = append(errs, fmt.Errorf("policy forbids issuing for: %s", strings.Join(badNames, ", ")))
 	}
-	return nil
+	return errs
 }
 
 // normalizeCSR deduplicates and lowers the case of dNSNames and the subject CN.
@@ -1,6 +1,7 @@
 package csr
 
 import (
+	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -38,6 +39,17 @@ func (pa *mockPA) WillingToIssueWildcard(id core.AcmeIdentifier) error {
 	return nil
 }
 
+func (pa *mockPA) WillingToIssueExempt(id core.AcmeIdentifier, exemptEntry string) error {
+	if id.Value == exemptEntry {
+		return nil
+	}
+	return pa.WillingToIssueWildcard(id)
+}
+
+func (pa *mockPA) CheckOrderNames(names []string) error {
+	return nil
+}
+
 func (pa *mockPA) ChallengeTypeEnabled(t string, registrationID int64) bool {
 	return true
 }
@@ -151,11 +163,43 @@ func TestVerifyCSR(t *testing.T) {
 	}
 
 	for _, c := range cases {
-		err := VerifyCSR(c.csr, c.maxNames, c.keyPolicy, c.pa, false, c.regID)
+		err := VerifyCSR(c.csr, c.maxNames, c.keyPolicy, c.pa, false, c.regID, nil)
 		test.AssertDeepEquals(t, c.expectedError, err)
 	}
 }
 
+func TestCheckCSR(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	test.AssertNotError(t, err, "error generating test key")
+	signedReqBytes, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{PublicKey: private.PublicKey, SignatureAlgorithm: x509.SHA256WithRSA}, private)
+	test.AssertNotError(t, err, "error generating test CSR")
+	signedReq, err := x509.ParseCertificateRequest(signedReqBytes)
+	test.AssertNotError(t, err, "error parsing test CSR")
+	signedReq.DNSNames = []string{"bad-name.com", "good-name.com"}
+	signedReq.EmailAddresses = []string{"foo@bar.com"}
+	signedReq.IPAddresses = []net.IP{net.IPv4(1, 2, 3, 4)}
+
+	errs := CheckCSR(signedReq, 1, testingPolicy, &mockPA{}, false, 0, nil)
+	test.AssertDeepEquals(t, errs, []error{
+		invalidEmailPresent,
+		invalidIPPresent,
+		errors.New("CSR contains more than 1 DNS names"),
+		errors.New("policy forbids issuing for: \"bad-name.com\""),
+	})
+	// VerifyCSR returns the first of them
+	test.AssertDeepEquals(t, VerifyCSR(signedReq, 1, testingPolicy, &mockPA{}, false, 0, nil), invalidEmailPresent)
+
+	signedReq.DNSNames = []string{"good-name.com"}
+	signedReq.EmailAddresses = nil
+	signedReq.IPAddresses = nil
+	test.AssertEquals(t, len(CheckCSR(signedReq, 1, testingPolicy, &mockPA{}, false, 0, nil)), 0)
+
+	// A name exempt from the policy entry that blocks it is allowed
+	signedReq.DNSNames = []string{"bad-name.com", "other-bad-name.com"}
+	errs = CheckCSR(signedReq, 2, testingPolicy, &mockPA{}, false, 0, map[string]string{"bad-name.com": "bad-name.com"})
+	test.AssertDeepEquals(t, errs, []error{errors.New("policy forbids issuing for: \"other-bad-name.com\"")})
+}
+
 func TestNormalizeCSR(t *testing.T) {
 	cases := []struct {
 		csr           *x509.CertificateRequest
@@ -200,3 +244,22 @@ func TestNormalizeCSR(t *testing.T) {
 		test.AssertDeepEquals(t, c.expectedNames, c.csr.DNSNames)
 	}
 }
+
+func TestNameLimits(t *testing.T) {
+	rsaKey := &rsa.PublicKey{}
+	ecdsaKey := &ecdsa.PublicKey{}
+
+	limits := NameLimits{Default: 100}
+	test.AssertEquals(t, limits.ForKey(rsaKey), 100)
+	test.AssertEquals(t, limits.ForKey(ecdsaKey), 100)
+	test.AssertEquals(t, limits.Max(), 100)
+
+	limits = NameLimits{Default: 100, RSA: 10}
+	test.AssertEquals(t, limits.ForKey(rsaKey), 10)
+	test.AssertEquals(t, limits.ForKey(ecdsaKey), 100)
+	test.AssertEquals(t, limits.Max(), 100)
+
+	limits = NameLimits{Default: 100, RSA: 10, ECDSA: 20}
+	test.AssertEquals(t, limits.ForKey(ecdsaKey), 20)
+	test.AssertEquals(t, limits.Max(), 20)
+}
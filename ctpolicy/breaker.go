@@ -0,0 +1,124 @@
+package ctpolicy
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// BreakerConfig configures a timeout and a circuit breaker for each CT log, so
+// that one slow or failing log doesn't delay every issuance.
+type BreakerConfig struct {
+	// SubmitTimeout, if non-zero, is how long a submission to one log may
+	// take before it counts as a failure.
+	SubmitTimeout time.Duration
+	// Failures is how many consecutive submissions to a log must fail before
+	// its breaker opens and the log is skipped.
+	Failures int
+	// Cooldown is how long a log is skipped once its breaker opens. After
+	// that, submissions to the log are tried again, and the breaker closes
+	// once one succeeds or opens again if one fails.
+	Cooldown time.Duration
+}
+
+// logBreaker is the state of one log's circuit breaker.
+type logBreaker struct {
+	failures  int
+	openUntil time.Time
+}
+
+// breakers are the circuit breakers of the CT logs, keyed by log URI. A nil
+// *breakers never skips a log.
+type breakers struct {
+	clk    clock.Clock
+	log    blog.Logger
+	config BreakerConfig
+	open   *prometheus.GaugeVec
+
+	mu   sync.Mutex
+	logs map[string]*logBreaker
+}
+
+func newBreakers(clk clock.Clock, log blog.Logger, open *prometheus.GaugeVec, config BreakerConfig) (*breakers, error) {
+	if config.SubmitTimeout < 0 {
+		return nil, errors.New("CT log submit timeout can't be negative")
+	}
+	if config.Failures <= 0 {
+		return nil, errors.New("CT log breaker failures must be positive")
+	}
+	if config.Cooldown <= 0 {
+		return nil, errors.New("CT log breaker cooldown must be positive")
+	}
+	return &breakers{
+		clk:    clk,
+		log:    log,
+		config: config,
+		open:   open,
+		logs:   make(map[string]*logBreaker),
+	}, nil
+}
+
+// allow returns false if the breaker of the log at uri is open.
+func (b *breakers) allow(uri string) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	lb, ok := b.logs[uri]
+	return !ok || !b.clk.Now().Before(lb.openUntil)
+}
+
+// submitTimeout returns how long a submission to one log may take, or zero if
+// there's no limit.
+func (b *breakers) submitTimeout() time.Duration {
+	if b == nil {
+		return 0
+	}
+	return b.config.SubmitTimeout
+}
+
+// record counts the result of a submission to the log at uri, opening its
+// breaker if too many submissions in a row have failed.
+func (b *breakers) record(uri string, err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	lb, ok := b.logs[uri]
+	if !ok {
+		lb = &logBreaker{}
+		b.logs[uri] = lb
+	}
+	if err == nil {
+		if lb.failures >= b.config.Failures {
+			b.log.Infof("Closing circuit breaker for CT log %q", uri)
+			b.open.With(prometheus.Labels{"log": uri}).Set(0)
+		}
+		lb.failures = 0
+		return
+	}
+	lb.failures++
+	if lb.failures >= b.config.Failures {
+		lb.openUntil = b.clk.Now().Add(b.config.Cooldown)
+		b.log.Warningf("Opening circuit breaker for CT log %q after %d failed submissions: %s",
+			uri, lb.failures, err)
+		b.open.With(prometheus.Labels{"log": uri}).Set(1)
+	}
+}
+
+// SetBreakers makes the CTPolicy time out slow submissions to each log and
+// skip logs that keep failing, as configured.
+func (ctp *CTPolicy) SetBreakers(config BreakerConfig) error {
+	b, err := newBreakers(clock.Default(), ctp.log, ctp.breakerOpen, config)
+	if err != nil {
+		return err
+	}
+	ctp.breakers = b
+	return nil
+}
@@ -2,8 +2,11 @@ package ctpolicy
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/letsencrypt/boulder/canceled"
@@ -19,22 +22,28 @@ import (
 // CTPolicy is used to hold information about SCTs required from various
 // groupings
 type CTPolicy struct {
-	pub           core.Publisher
+	pub core.Publisher
+	log blog.Logger
+
+	logsMu sync.RWMutex
+	logs   *logs
+
+	breakers *breakers
+
+	winnerCounter  *prometheus.CounterVec
+	breakerOpen    *prometheus.GaugeVec
+	skippedCounter *prometheus.CounterVec
+}
+
+// logs are the CT logs a CTPolicy submits to. They are replaced as a whole
+// when reloaded, and never modified.
+type logs struct {
 	groups        []cmd.CTGroup
 	informational []cmd.LogDescription
 	finalLogs     []cmd.LogDescription
-	log           blog.Logger
-
-	winnerCounter *prometheus.CounterVec
 }
 
-// New creates a new CTPolicy struct
-func New(pub core.Publisher,
-	groups []cmd.CTGroup,
-	informational []cmd.LogDescription,
-	log blog.Logger,
-	stats metrics.Scope,
-) *CTPolicy {
+func newLogs(groups []cmd.CTGroup, informational []cmd.LogDescription) *logs {
 	var finalLogs []cmd.LogDescription
 	for _, group := range groups {
 		for _, log := range group.Logs {
@@ -48,6 +57,89 @@ func New(pub core.Publisher,
 			finalLogs = append(finalLogs, log)
 		}
 	}
+	return &logs{
+		groups:        groups,
+		informational: informational,
+		finalLogs:     finalLogs,
+	}
+}
+
+// CheckGroups returns an error if the log groups can't be used to get SCTs:
+// there must be at least one group and every group must have logs. It also
+// sets up any temporal log sets.
+func CheckGroups(groups []cmd.CTGroup) error {
+	// Boulder's components assume that there will always be CT logs
+	// configured. Issuing a certificate without SCTs embedded is a
+	// miss-issuance event in the enviromnent Boulder is built for.
+	if len(groups) == 0 {
+		return errors.New("CTLogGroups2 must not be empty")
+	}
+	for i, g := range groups {
+		if len(g.Logs) == 0 {
+			return fmt.Errorf("CTLogGroups2 index %d specifies no logs", i)
+		}
+		for _, l := range g.Logs {
+			err := l.CheckType()
+			if err != nil {
+				return err
+			}
+			if l.TemporalSet != nil {
+				err := l.Setup()
+				if err != nil {
+					return fmt.Errorf("setting up temporal log set: %s", err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// logsFile is the format of the file loaded by LoadLogs, which matches the
+// CT sections of the RA config.
+type logsFile struct {
+	CTLogGroups2        []cmd.CTGroup
+	InformationalCTLogs []cmd.LogDescription
+}
+
+// LoadLogs replaces the logs the CTPolicy submits to with those in contents,
+// a JSON object with the CTLogGroups2 and InformationalCTLogs fields of the RA
+// config. It is suitable for use as the Load function of a reloadable config
+// section.
+func (ctp *CTPolicy) LoadLogs(contents []byte) error {
+	var f logsFile
+	err := json.Unmarshal(contents, &f)
+	if err != nil {
+		return err
+	}
+	err = CheckGroups(f.CTLogGroups2)
+	if err != nil {
+		return err
+	}
+	for _, l := range f.InformationalCTLogs {
+		err = l.CheckType()
+		if err != nil {
+			return err
+		}
+	}
+	ctp.logsMu.Lock()
+	ctp.logs = newLogs(f.CTLogGroups2, f.InformationalCTLogs)
+	ctp.logsMu.Unlock()
+	return nil
+}
+
+func (ctp *CTPolicy) currentLogs() *logs {
+	ctp.logsMu.RLock()
+	defer ctp.logsMu.RUnlock()
+	return ctp.logs
+}
+
+// New creates a new CTPolicy struct
+func New(pub core.Publisher,
+	groups []cmd.CTGroup,
+	informational []cmd.LogDescription,
+	log blog.Logger,
+	stats metrics.Scope,
+) *CTPolicy {
 
 	winnerCounter := prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -57,14 +149,30 @@ func New(pub core.Publisher,
 		[]string{"log", "group"},
 	)
 	stats.MustRegister(winnerCounter)
+	breakerOpen := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ct_log_breaker_open",
+			Help: "Whether the circuit breaker of a CT log is open (1) or closed (0).",
+		},
+		[]string{"log"},
+	)
+	stats.MustRegister(breakerOpen)
+	skippedCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ct_log_submissions_skipped",
+			Help: "Counter of SCT submissions skipped because the log's circuit breaker was open.",
+		},
+		[]string{"log"},
+	)
+	stats.MustRegister(skippedCounter)
 
 	return &CTPolicy{
-		pub:           pub,
-		groups:        groups,
-		informational: informational,
-		finalLogs:     finalLogs,
-		log:           log,
-		winnerCounter: winnerCounter,
+		pub:            pub,
+		log:            log,
+		logs:           newLogs(groups, informational),
+		winnerCounter:  winnerCounter,
+		breakerOpen:    breakerOpen,
+		skippedCounter: skippedCounter,
 	}
 }
 
@@ -77,15 +185,41 @@ type result struct {
 // race submits an SCT to each log in a group and waits for the first response back,
 // once it has the first SCT it cancels all of the other submissions and returns.
 // It allows up to len(group)-1 of the submissions to fail as we only care about
-// getting a single SCT.
+// getting a single SCT. Logs whose circuit breakers are open are skipped, unless
+// every log in the group is.
 func (ctp *CTPolicy) race(ctx context.Context, cert core.CertDER, group cmd.CTGroup, expiration time.Time) ([]byte, error) {
-	results := make(chan result, len(group.Logs))
-	isPrecert := true
+	type logInfo struct {
+		uri, key string
+		static   bool
+	}
+	var logs, skipped []logInfo
 	// Randomize the order in which we send requests to the logs in a group
 	// so we maximize the distribution of logs we get SCTs from.
-	for i, logNum := range rand.Perm(len(group.Logs)) {
-		ld := group.Logs[logNum]
-		go func(i int, ld cmd.LogDescription) {
+	for _, logNum := range rand.Perm(len(group.Logs)) {
+		uri, key, err := group.Logs[logNum].Info(expiration)
+		if err != nil {
+			ctp.log.Errf("unable to get log info: %s", err)
+			continue
+		}
+		info := logInfo{uri, key, group.Logs[logNum].Static()}
+		if ctp.breakers.allow(uri) {
+			logs = append(logs, info)
+		} else {
+			skipped = append(skipped, info)
+		}
+	}
+	if len(logs) == 0 {
+		logs = skipped
+	} else {
+		for _, l := range skipped {
+			ctp.skippedCounter.With(prometheus.Labels{"log": l.uri}).Inc()
+		}
+	}
+
+	results := make(chan result, len(logs))
+	isPrecert := true
+	for i, l := range logs {
+		go func(i int, l logInfo) {
 			// Each submission waits a bit longer than the previous one, to give the
 			// previous log a chance to reply. If the context is already done by the
 			// time we get here, don't bother submitting. That generally means the
@@ -94,30 +228,37 @@ func (ctp *CTPolicy) race(ctx context.Context, cert core.CertDER, group cmd.CTGr
 			if ctx.Err() != nil {
 				return
 			}
-			uri, key, err := ld.Info(expiration)
-			if err != nil {
-				ctp.log.Errf("unable to get log info: %s", err)
-				return
+			submitCtx := ctx
+			if timeout := ctp.breakers.submitTimeout(); timeout > 0 {
+				var cancel context.CancelFunc
+				submitCtx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
 			}
-			sct, err := ctp.pub.SubmitToSingleCTWithResult(ctx, &pubpb.Request{
-				LogURL:       &uri,
-				LogPublicKey: &key,
+			sct, err := ctp.pub.SubmitToSingleCTWithResult(submitCtx, &pubpb.Request{
+				LogURL:       &l.uri,
+				LogPublicKey: &l.key,
 				Der:          cert,
 				Precert:      &isPrecert,
+				StaticLog:    &l.static,
 			})
+			// Submissions canceled because the race is over say nothing about
+			// the log
+			if ctx.Err() == nil {
+				ctp.breakers.record(l.uri, err)
+			}
 			if err != nil {
 				// Only log the error if it is not a result of the context being canceled
 				if !canceled.Is(err) {
-					ctp.log.Warningf("ct submission to %q failed: %s", uri, err)
+					ctp.log.Warningf("ct submission to %q failed: %s", l.uri, err)
 				}
 				results <- result{err: err}
 				return
 			}
-			results <- result{sct: sct.Sct, log: uri}
-		}(i, ld)
+			results <- result{sct: sct.Sct, log: l.uri}
+		}(i, l)
 	}
 
-	for i := 0; i < len(group.Logs); i++ {
+	for i := 0; i < len(logs); i++ {
 		select {
 		case <-ctx.Done():
 			ctp.winnerCounter.With(prometheus.Labels{"log": "timeout", "group": group.Name}).Inc()
@@ -141,10 +282,11 @@ func (ctp *CTPolicy) race(ctx context.Context, cert core.CertDER, group cmd.CTGr
 // GetSCTs attempts to retrieve a SCT from each configured grouping of logs and returns
 // the set of SCTs to the caller.
 func (ctp *CTPolicy) GetSCTs(ctx context.Context, cert core.CertDER, expiration time.Time) (core.SCTDERs, error) {
-	results := make(chan result, len(ctp.groups))
+	logs := ctp.currentLogs()
+	results := make(chan result, len(logs.groups))
 	subCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	for i, g := range ctp.groups {
+	for i, g := range logs.groups {
 		go func(i int, g cmd.CTGroup) {
 			sct, err := ctp.race(subCtx, cert, g, expiration)
 			// Only one of these will be non-nil
@@ -155,7 +297,7 @@ func (ctp *CTPolicy) GetSCTs(ctx context.Context, cert core.CertDER, expiration
 		}(i, g)
 	}
 	isPrecert := true
-	for _, log := range ctp.informational {
+	for _, log := range logs.informational {
 		go func(l cmd.LogDescription) {
 			// We use a context.Background() here instead of subCtx because these
 			// submissions are running in a goroutine and we don't want them to be
@@ -166,11 +308,13 @@ func (ctp *CTPolicy) GetSCTs(ctx context.Context, cert core.CertDER, expiration
 				ctp.log.Errf("unable to get log info: %s", err)
 				return
 			}
+			static := l.Static()
 			_, err = ctp.pub.SubmitToSingleCTWithResult(context.Background(), &pubpb.Request{
 				LogURL:       &uri,
 				LogPublicKey: &key,
 				Der:          cert,
 				Precert:      &isPrecert,
+				StaticLog:    &static,
 			})
 			if err != nil {
 				ctp.log.Warningf("ct submission to informational log %q failed: %s", uri, err)
@@ -179,7 +323,7 @@ func (ctp *CTPolicy) GetSCTs(ctx context.Context, cert core.CertDER, expiration
 	}
 
 	var ret core.SCTDERs
-	for i := 0; i < len(ctp.groups); i++ {
+	for i := 0; i < len(logs.groups); i++ {
 		res := <-results
 		// If any one group fails to get a SCT then we fail out immediately
 		// cancel any other in progress work as we can't continue
@@ -196,19 +340,21 @@ func (ctp *CTPolicy) GetSCTs(ctx context.Context, cert core.CertDER, expiration
 // to any configured logs
 func (ctp *CTPolicy) SubmitFinalCert(cert []byte, expiration time.Time) {
 	falseVar := false
-	for _, log := range ctp.finalLogs {
+	for _, log := range ctp.currentLogs().finalLogs {
 		go func(l cmd.LogDescription) {
 			uri, key, err := l.Info(expiration)
 			if err != nil {
 				ctp.log.Errf("unable to get log info: %s", err)
 				return
 			}
+			static := l.Static()
 			_, err = ctp.pub.SubmitToSingleCTWithResult(context.Background(), &pubpb.Request{
 				LogURL:       &uri,
 				LogPublicKey: &key,
 				Der:          cert,
 				Precert:      &falseVar,
 				StoreSCT:     &falseVar,
+				StaticLog:    &static,
 			})
 			if err != nil {
 				ctp.log.Warningf("ct submission of final cert to log %q failed: %s", uri, err)
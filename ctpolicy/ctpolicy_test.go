@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"regexp"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/jmhodges/clock"
 	"github.com/letsencrypt/boulder/cmd"
 	"github.com/letsencrypt/boulder/core"
 	berrors "github.com/letsencrypt/boulder/errors"
@@ -241,3 +243,151 @@ func TestStagger(t *testing.T) {
 		t.Errorf("wrong number of requests to publisher. got %d, expected 1", countingPub.count)
 	}
 }
+
+func TestLoadLogs(t *testing.T) {
+	ctp := New(&mockPub{}, nil, nil, blog.NewMock(), metrics.NewNoopScope())
+	scts, err := ctp.GetSCTs(context.Background(), []byte{0}, time.Time{})
+	test.AssertNotError(t, err, "GetSCTs failed")
+	test.AssertEquals(t, len(scts), 0)
+
+	err = ctp.LoadLogs([]byte(`{"CTLogGroups2": []}`))
+	test.AssertError(t, err, "LoadLogs accepted an empty list of log groups")
+	err = ctp.LoadLogs([]byte(`{"CTLogGroups2": [{"Name": "a", "Logs": []}]}`))
+	test.AssertError(t, err, "LoadLogs accepted a log group without logs")
+	err = ctp.LoadLogs([]byte(`not json`))
+	test.AssertError(t, err, "LoadLogs accepted malformed JSON")
+	err = ctp.LoadLogs([]byte(`{"CTLogGroups2": [{"Name": "a", "Logs": [{"URI": "abc", "Key": "def", "Type": "tiled"}]}]}`))
+	test.AssertError(t, err, "LoadLogs accepted a log of unknown type")
+	err = ctp.LoadLogs([]byte(`{"CTLogGroups2": [{"Name": "a", "Logs": [{"URI": "abc", "Key": "def"}]}],
+		"InformationalCTLogs": [{"URI": "ghi", "Key": "jkl", "Type": "tiled"}]}`))
+	test.AssertError(t, err, "LoadLogs accepted an informational log of unknown type")
+
+	err = ctp.LoadLogs([]byte(`{"CTLogGroups2": [{"Name": "a", "Logs": [{"URI": "abc", "Key": "def"}]}]}`))
+	test.AssertNotError(t, err, "LoadLogs failed")
+	scts, err = ctp.GetSCTs(context.Background(), []byte{0}, time.Time{})
+	test.AssertNotError(t, err, "GetSCTs failed after loading logs")
+	test.AssertEquals(t, len(scts), 1)
+}
+
+// A mock publisher that records whether each log was submitted to as a
+// static log
+type staticPerLog struct {
+	mu     sync.Mutex
+	static map[string]bool
+}
+
+func (sp *staticPerLog) SubmitToSingleCTWithResult(_ context.Context, req *pubpb.Request) (*pubpb.Result, error) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.static[*req.LogURL] = *req.StaticLog
+	return &pubpb.Result{Sct: []byte{0}}, nil
+}
+
+func TestGetSCTsStaticLogs(t *testing.T) {
+	pub := &staticPerLog{static: make(map[string]bool)}
+	ctp := New(pub, []cmd.CTGroup{
+		{Name: "a", Logs: []cmd.LogDescription{{URI: "abc", Key: "def", Type: cmd.LogTypeStatic}}},
+		{Name: "b", Logs: []cmd.LogDescription{{URI: "ghi", Key: "jkl", Type: cmd.LogTypeRFC6962}}},
+	}, nil, blog.NewMock(), metrics.NewNoopScope())
+	_, err := ctp.GetSCTs(context.Background(), []byte{0}, time.Time{})
+	test.AssertNotError(t, err, "GetSCTs failed")
+	test.AssertDeepEquals(t, pub.static, map[string]bool{"abc": true, "ghi": false})
+}
+
+// A mock publisher that counts submissions to each log and fails those to
+// badURL
+type countPerLog struct {
+	mu     sync.Mutex
+	counts map[string]int
+	badURL string
+}
+
+func (cp *countPerLog) SubmitToSingleCTWithResult(_ context.Context, req *pubpb.Request) (*pubpb.Result, error) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.counts[*req.LogURL]++
+	if *req.LogURL == cp.badURL {
+		return nil, errors.New("BAD")
+	}
+	return &pubpb.Result{Sct: []byte{0}}, nil
+}
+
+func TestGetSCTsBreakers(t *testing.T) {
+	pub := &countPerLog{counts: make(map[string]int), badURL: "abc"}
+	ctp := New(pub, []cmd.CTGroup{
+		{Name: "a", Logs: []cmd.LogDescription{{URI: "abc", Key: "def"}}},
+	}, nil, blog.NewMock(), metrics.NewNoopScope())
+	err := ctp.SetBreakers(BreakerConfig{Failures: 1, Cooldown: time.Hour})
+	test.AssertNotError(t, err, "SetBreakers failed")
+
+	_, err = ctp.GetSCTs(context.Background(), []byte{0}, time.Time{})
+	test.AssertError(t, err, "GetSCTs didn't fail")
+	test.Assert(t, !ctp.breakers.allow("abc"), "breaker didn't open after a failure")
+	test.AssertEquals(t, test.CountGauge(ctp.breakerOpen.With(prometheus.Labels{"log": "abc"})), 1)
+
+	// Every log in the group has an open breaker, so they're all tried anyway
+	_, err = ctp.GetSCTs(context.Background(), []byte{0}, time.Time{})
+	test.AssertError(t, err, "GetSCTs didn't fail")
+	test.AssertEquals(t, pub.counts["abc"], 2)
+
+	err = ctp.LoadLogs([]byte(`{"CTLogGroups2": [{"Name": "a", "Logs": [{"URI": "abc", "Key": "def"}, {"URI": "ghi", "Key": "jkl"}]}]}`))
+	test.AssertNotError(t, err, "LoadLogs failed")
+	_, err = ctp.GetSCTs(context.Background(), []byte{0}, time.Time{})
+	test.AssertNotError(t, err, "GetSCTs failed")
+	test.AssertEquals(t, pub.counts["abc"], 2)
+	test.AssertEquals(t, pub.counts["ghi"], 1)
+	test.AssertEquals(t, test.CountCounter(ctp.skippedCounter.With(prometheus.Labels{"log": "abc"})), 1)
+}
+
+// A mock publisher whose submissions never finish before they're canceled
+type hangingPublisher struct{}
+
+func (hp *hangingPublisher) SubmitToSingleCTWithResult(ctx context.Context, _ *pubpb.Request) (*pubpb.Result, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestGetSCTsSubmitTimeout(t *testing.T) {
+	ctp := New(&hangingPublisher{}, []cmd.CTGroup{
+		{Name: "a", Logs: []cmd.LogDescription{{URI: "abc", Key: "def"}}},
+	}, nil, blog.NewMock(), metrics.NewNoopScope())
+	err := ctp.SetBreakers(BreakerConfig{SubmitTimeout: 10 * time.Millisecond, Failures: 1, Cooldown: time.Hour})
+	test.AssertNotError(t, err, "SetBreakers failed")
+
+	start := time.Now()
+	_, err = ctp.GetSCTs(context.Background(), []byte{0}, time.Time{})
+	test.AssertError(t, err, "GetSCTs didn't time out")
+	test.Assert(t, time.Since(start) < 500*time.Millisecond, "GetSCTs waited for the slow log")
+	test.Assert(t, !ctp.breakers.allow("abc"), "breaker didn't open after a timeout")
+}
+
+func TestBreakers(t *testing.T) {
+	fc := clock.NewFake()
+	open := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "open"}, []string{"log"})
+	b, err := newBreakers(fc, blog.NewMock(), open, BreakerConfig{Failures: 2, Cooldown: time.Minute})
+	test.AssertNotError(t, err, "newBreakers failed")
+
+	b.record("abc", errors.New("BAD"))
+	test.Assert(t, b.allow("abc"), "breaker opened before enough failures")
+	b.record("abc", nil)
+	b.record("abc", errors.New("BAD"))
+	test.Assert(t, b.allow("abc"), "breaker counted failures before a success")
+	b.record("abc", errors.New("BAD"))
+	test.Assert(t, !b.allow("abc"), "breaker didn't open after consecutive failures")
+	test.Assert(t, b.allow("ghi"), "breaker of another log opened")
+
+	fc.Add(time.Minute)
+	test.Assert(t, b.allow("abc"), "breaker didn't allow submissions after its cooldown")
+	b.record("abc", errors.New("BAD"))
+	test.Assert(t, !b.allow("abc"), "breaker didn't reopen after a failure following its cooldown")
+	fc.Add(time.Minute)
+	b.record("abc", nil)
+	test.AssertEquals(t, test.CountGauge(open.With(prometheus.Labels{"log": "abc"})), 0)
+	b.record("abc", errors.New("BAD"))
+	test.Assert(t, b.allow("abc"), "breaker didn't close after a success")
+
+	_, err = newBreakers(fc, blog.NewMock(), open, BreakerConfig{Cooldown: time.Minute})
+	test.AssertError(t, err, "newBreakers accepted zero failures")
+	_, err = newBreakers(fc, blog.NewMock(), open, BreakerConfig{Failures: 1})
+	test.AssertError(t, err, "newBreakers accepted zero cooldown")
+}
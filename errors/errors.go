@@ -1,6 +1,9 @@
 package errors
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // ErrorType provides a coarse category for BoulderErrors
 type ErrorType int
@@ -19,12 +22,36 @@ const (
 	CAA
 	MissingSCTs
 	Duplicate
+	BadPublicKey
+	BadCSR
+	IssuanceDisabled
+	AlreadyRevoked
+	BadRevocationReason
+	OrderNotReady
+	UnsupportedIdentifier
+	InvalidContact
 )
 
 // BoulderError represents internal Boulder errors
 type BoulderError struct {
 	Type   ErrorType
 	Detail string
+	// RetryAfter is how long the client should wait before retrying the
+	// request. It is only meaningful for RateLimit errors and is zero if
+	// unknown.
+	RetryAfter time.Duration
+	// SubErrors are the errors with individual names that make up this
+	// error, e.g. each of the names that cause an order to be rejected. They
+	// are sent to clients as subproblems.
+	SubErrors []SubBoulderError
+}
+
+// SubBoulderError is an error with a single DNS name that is part of a
+// BoulderError.
+type SubBoulderError struct {
+	Type   ErrorType
+	Detail string
+	Name   string
 }
 
 func (be *BoulderError) Error() string {
@@ -71,6 +98,16 @@ func RateLimitError(msg string, args ...interface{}) error {
 	}
 }
 
+// RateLimitErrorWithRetryAfter is like RateLimitError but also records how long
+// the client should wait before retrying.
+func RateLimitErrorWithRetryAfter(retryAfter time.Duration, msg string, args ...interface{}) error {
+	return &BoulderError{
+		Type:       RateLimit,
+		Detail:     fmt.Sprintf(msg+": see https://letsencrypt.org/docs/rate-limits/", args...),
+		RetryAfter: retryAfter,
+	}
+}
+
 func RejectedIdentifierError(msg string, args ...interface{}) error {
 	return New(RejectedIdentifier, msg, args...)
 }
@@ -98,3 +135,35 @@ func MissingSCTsError(msg string, args ...interface{}) error {
 func DuplicateError(msg string, args ...interface{}) error {
 	return New(Duplicate, msg, args...)
 }
+
+func BadPublicKeyError(msg string, args ...interface{}) error {
+	return New(BadPublicKey, msg, args...)
+}
+
+func BadCSRError(msg string, args ...interface{}) error {
+	return New(BadCSR, msg, args...)
+}
+
+func IssuanceDisabledError(msg string, args ...interface{}) error {
+	return New(IssuanceDisabled, msg, args...)
+}
+
+func AlreadyRevokedError(msg string, args ...interface{}) error {
+	return New(AlreadyRevoked, msg, args...)
+}
+
+func BadRevocationReasonError(msg string, args ...interface{}) error {
+	return New(BadRevocationReason, msg, args...)
+}
+
+func OrderNotReadyError(msg string, args ...interface{}) error {
+	return New(OrderNotReady, msg, args...)
+}
+
+func UnsupportedIdentifierError(msg string, args ...interface{}) error {
+	return New(UnsupportedIdentifier, msg, args...)
+}
+
+func InvalidContactError(msg string, args ...interface{}) error {
+	return New(InvalidContact, msg, args...)
+}
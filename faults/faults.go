@@ -0,0 +1,139 @@
+// Package faults injects delays and failures into gRPC methods, database
+// queries and HSM operations, so that integration tests can exercise timeout,
+// retry and orphan handling paths that a healthy test environment never
+// reaches.
+//
+// Faults are configured per service with the Faults field of its config, but
+// are only ever injected by binaries built with the 'integration' build tag.
+// Other builds refuse to start if any faults are configured, so a test config
+// can't slow down or break a production deployment.
+package faults
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Kind is the type of operation a Rule applies to.
+type Kind string
+
+const (
+	// GRPC faults are injected by gRPC servers before a method's handler
+	// runs. They are matched against the full method name, e.g.
+	// "/sa.StorageAuthority/AddCertificate".
+	GRPC = Kind("grpc")
+	// DB faults are injected before a SQL statement is sent to the database.
+	// They are matched against the statement, e.g. "INSERT INTO certificates".
+	DB = Kind("db")
+	// HSM faults are injected before an issuer key signs anything. They are
+	// matched against the operation, which is always "Sign".
+	HSM = Kind("hsm")
+)
+
+// Rule describes a fault to inject.
+type Rule struct {
+	Kind Kind
+	// Match selects the operations of Kind the fault applies to: those whose
+	// name contains it. If empty every operation of Kind is affected.
+	Match string
+	// Percent is the percentage of matching operations affected, between 0
+	// and 100.
+	Percent float64
+	// Delay, if set, is how long affected operations are held up, e.g. "5s".
+	// Operations with a deadline give up when it passes.
+	Delay string
+	// Error, if set, makes affected operations fail with this message after
+	// any Delay.
+	Error string
+}
+
+// rule is a validated Rule.
+type rule struct {
+	Rule
+	delay time.Duration
+}
+
+// Injector decides which operations to delay or fail.
+type Injector struct {
+	rules []rule
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// New returns an Injector applying rules, or an error if any of them are
+// invalid.
+func New(rules []Rule) (*Injector, error) {
+	inj := &Injector{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	for i, r := range rules {
+		switch r.Kind {
+		case GRPC, DB, HSM:
+		default:
+			return nil, fmt.Errorf("fault %d: unknown kind %q", i, r.Kind)
+		}
+		if r.Percent <= 0 || r.Percent > 100 {
+			return nil, fmt.Errorf("fault %d: percent must be greater than 0 and at most 100", i)
+		}
+		var delay time.Duration
+		if r.Delay != "" {
+			var err error
+			delay, err = time.ParseDuration(r.Delay)
+			if err != nil {
+				return nil, fmt.Errorf("fault %d: invalid delay: %s", i, err)
+			}
+		}
+		if delay <= 0 && r.Error == "" {
+			return nil, fmt.Errorf("fault %d: needs a positive delay, an error or both", i)
+		}
+		inj.rules = append(inj.rules, rule{Rule: r, delay: delay})
+	}
+	return inj, nil
+}
+
+// Inject applies the faults matching an operation: it waits for the longest
+// matching delay, or until ctx is done, and returns an error if any matching
+// fault has one. Each matching rule affects the operation with its own
+// probability. A nil Injector never injects anything.
+func (inj *Injector) Inject(ctx context.Context, kind Kind, name string) error {
+	if inj == nil {
+		return nil
+	}
+	var delay time.Duration
+	var failure string
+	for _, r := range inj.rules {
+		if r.Kind != kind || !strings.Contains(name, r.Match) || !inj.roll(r.Percent) {
+			continue
+		}
+		if r.delay > delay {
+			delay = r.delay
+		}
+		if r.Error != "" && failure == "" {
+			failure = r.Error
+		}
+	}
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+	if failure != "" {
+		return fmt.Errorf("injected %s fault in %s: %s", kind, name, failure)
+	}
+	return nil
+}
+
+// roll returns true with the given percent probability
+func (inj *Injector) roll(percent float64) bool {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	return inj.rand.Float64()*100 < percent
+}
@@ -0,0 +1,41 @@
+// +build !integration
+
+package faults
+
+import (
+	"crypto"
+	"errors"
+
+	"golang.org/x/net/context"
+)
+
+var errDisabled = errors.New("faults can only be injected by builds with the 'integration' build tag")
+
+// Set configures the faults this process injects. Faults are only injected by
+// builds with the 'integration' build tag, so this returns an error if any
+// rules are given.
+func Set(rules []Rule) error {
+	if len(rules) > 0 {
+		return errDisabled
+	}
+	return nil
+}
+
+// Inject applies the configured faults matching an operation. Faults are only
+// injected by builds with the 'integration' build tag, so this does nothing.
+func Inject(_ context.Context, _ Kind, _ string) error {
+	return nil
+}
+
+// Signer wraps s to inject HSM faults. Faults are only injected by builds with
+// the 'integration' build tag, so this returns s.
+func Signer(s crypto.Signer) crypto.Signer {
+	return s
+}
+
+// DriverName returns the name of the database/sql driver to use in place of
+// the named one, so that DB faults can be injected. Faults are only injected
+// by builds with the 'integration' build tag, so this returns name.
+func DriverName(name string) string {
+	return name
+}
@@ -0,0 +1,16 @@
+// +build !integration
+
+package faults
+
+import (
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestSetDisabled(t *testing.T) {
+	test.AssertNotError(t, Set(nil), "Set without rules failed")
+	err := Set([]Rule{{Kind: GRPC, Percent: 100, Error: "oops"}})
+	test.AssertEquals(t, err, errDisabled)
+	test.AssertEquals(t, DriverName("mysql"), "mysql")
+}
@@ -0,0 +1,57 @@
+// +build integration
+
+package faults
+
+import (
+	"crypto"
+	"io"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+var (
+	mu     sync.RWMutex
+	active *Injector
+)
+
+// Set configures the faults this process injects, replacing any set before.
+func Set(rules []Rule) error {
+	inj, err := New(rules)
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		inj = nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	active = inj
+	return nil
+}
+
+// Inject applies the configured faults matching an operation. See
+// Injector.Inject.
+func Inject(ctx context.Context, kind Kind, name string) error {
+	mu.RLock()
+	inj := active
+	mu.RUnlock()
+	return inj.Inject(ctx, kind, name)
+}
+
+// faultySigner injects HSM faults before signing.
+type faultySigner struct {
+	crypto.Signer
+}
+
+func (s faultySigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if err := Inject(context.Background(), HSM, "Sign"); err != nil {
+		return nil, err
+	}
+	return s.Signer.Sign(rand, digest, opts)
+}
+
+// Signer wraps s so that HSM faults are injected before it signs.
+func Signer(s crypto.Signer) crypto.Signer {
+	return faultySigner{s}
+}
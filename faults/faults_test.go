@@ -0,0 +1,77 @@
+package faults
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestNewInvalidRules(t *testing.T) {
+	testCases := []struct {
+		name string
+		rule Rule
+		err  string
+	}{
+		{"unknown kind", Rule{Kind: "dns", Percent: 100, Error: "oops"}, "unknown kind"},
+		{"zero percent", Rule{Kind: GRPC, Error: "oops"}, "percent"},
+		{"over 100 percent", Rule{Kind: GRPC, Percent: 101, Error: "oops"}, "percent"},
+		{"bad delay", Rule{Kind: DB, Percent: 50, Delay: "soon"}, "invalid delay"},
+		{"no effect", Rule{Kind: HSM, Percent: 50}, "delay, an error or both"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New([]Rule{tc.rule})
+			test.AssertError(t, err, "invalid rule was accepted")
+			test.Assert(t, strings.Contains(err.Error(), tc.err), "wrong error: "+err.Error())
+		})
+	}
+}
+
+func TestInject(t *testing.T) {
+	inj, err := New([]Rule{
+		{Kind: GRPC, Match: "/sa.StorageAuthority/AddCertificate", Percent: 100, Error: "database on fire"},
+		{Kind: DB, Match: "INSERT INTO certificates", Percent: 100, Delay: "10ms"},
+	})
+	test.AssertNotError(t, err, "New failed")
+	ctx := context.Background()
+
+	err = inj.Inject(ctx, GRPC, "/sa.StorageAuthority/AddCertificate")
+	test.AssertError(t, err, "matching gRPC method didn't fail")
+	test.Assert(t, strings.Contains(err.Error(), "database on fire"), "wrong error: "+err.Error())
+
+	test.AssertNotError(t, inj.Inject(ctx, GRPC, "/sa.StorageAuthority/GetCertificate"), "other gRPC method failed")
+	test.AssertNotError(t, inj.Inject(ctx, HSM, "Sign"), "other kind failed")
+
+	start := time.Now()
+	test.AssertNotError(t, inj.Inject(ctx, DB, "INSERT INTO certificates (serial) VALUES (?)"), "delayed query failed")
+	test.Assert(t, time.Since(start) >= 10*time.Millisecond, "matching query wasn't delayed")
+
+	var nilInjector *Injector
+	test.AssertNotError(t, nilInjector.Inject(ctx, GRPC, "/sa.StorageAuthority/AddCertificate"), "nil Injector failed")
+}
+
+func TestInjectDelayDeadline(t *testing.T) {
+	inj, err := New([]Rule{{Kind: GRPC, Percent: 100, Delay: "1h"}})
+	test.AssertNotError(t, err, "New failed")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = inj.Inject(ctx, GRPC, "/ra.RegistrationAuthority/NewOrder")
+	test.AssertEquals(t, err, context.DeadlineExceeded)
+}
+
+func TestInjectPercent(t *testing.T) {
+	inj, err := New([]Rule{{Kind: HSM, Percent: 50, Error: "HSM unplugged"}})
+	test.AssertNotError(t, err, "New failed")
+	failures := 0
+	for i := 0; i < 1000; i++ {
+		if inj.Inject(context.Background(), HSM, "Sign") != nil {
+			failures++
+		}
+	}
+	// The chance of this failing spuriously is far below 1 in a billion
+	test.Assert(t, failures > 300 && failures < 700, "roughly half of operations should fail")
+}
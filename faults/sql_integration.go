@@ -0,0 +1,106 @@
+// +build integration
+
+package faults
+
+import (
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/net/context"
+)
+
+const driverPrefix = "faults+"
+
+func init() {
+	sql.Register(driverPrefix+"mysql", faultyDriver{mysql.MySQLDriver{}})
+}
+
+// DriverName returns the name of the database/sql driver to use in place of
+// the named one, so that DB faults can be injected. Only the "mysql" driver
+// can be wrapped; other names are returned unchanged.
+func DriverName(name string) string {
+	if name == "mysql" {
+		return driverPrefix + name
+	}
+	return name
+}
+
+// faultyDriver wraps a driver so that DB faults are injected before every
+// statement is executed.
+type faultyDriver struct {
+	driver.Driver
+}
+
+func (d faultyDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return faultyConn{conn}, nil
+}
+
+type faultyConn struct {
+	driver.Conn
+}
+
+func (c faultyConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return faultyStmt{Stmt: stmt, query: query}, nil
+}
+
+// Exec and Query only handle statements without arguments, which are sent
+// as is. Statements with arguments are prepared, so that faults are injected
+// once for every statement however the driver would have sent it.
+func (c faultyConn) Exec(query string, args []driver.Value) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.Execer)
+	if !ok || len(args) != 0 {
+		return nil, driver.ErrSkip
+	}
+	if err := Inject(context.Background(), DB, query); err != nil {
+		return nil, err
+	}
+	return execer.Exec(query, args)
+}
+
+func (c faultyConn) Query(query string, args []driver.Value) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.Queryer)
+	if !ok || len(args) != 0 {
+		return nil, driver.ErrSkip
+	}
+	if err := Inject(context.Background(), DB, query); err != nil {
+		return nil, err
+	}
+	return queryer.Query(query, args)
+}
+
+type faultyStmt struct {
+	driver.Stmt
+	query string
+}
+
+func (s faultyStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if err := Inject(context.Background(), DB, s.query); err != nil {
+		return nil, err
+	}
+	return s.Stmt.Exec(args)
+}
+
+func (s faultyStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if err := Inject(context.Background(), DB, s.query); err != nil {
+		return nil, err
+	}
+	return s.Stmt.Query(args)
+}
+
+// ColumnConverter uses the wrapped driver's conversion of arguments, if it
+// has one.
+func (s faultyStmt) ColumnConverter(idx int) driver.ValueConverter {
+	if cc, ok := s.Stmt.(driver.ColumnConverter); ok {
+		return cc.ColumnConverter(idx)
+	}
+	return driver.DefaultParameterConverter
+}
@@ -3,7 +3,10 @@
 package features
 
 import (
+	"encoding/binary"
 	"fmt"
+	"hash/fnv"
+	"sort"
 	"sync"
 )
 
@@ -41,6 +44,38 @@ const (
 	// EarlyOrderRateLimit enables the RA applying certificate per name/per FQDN
 	// set rate limits in NewOrder in addition to FinalizeOrder.
 	EarlyOrderRateLimit
+	// WebhookContacts allows registrations to use https URLs as contacts. The
+	// expiration-mailer delivers expiration events to them as webhooks.
+	WebhookContacts
+	// ExpirationNagClaims makes the expiration-mailer record each certificate
+	// it is sending a nag for in the expirationNagClaims table, so that
+	// concurrent or restarted mailers don't send the same nag twice.
+	ExpirationNagClaims
+	// StoreKeyHashes makes the SA record the SHA-256 hash of each new
+	// certificate's SubjectPublicKeyInfo in the keyHashToSerial table, so
+	// that certificates can be searched for by key.
+	StoreKeyHashes
+	// StoreValidationMethod makes the SA record the challenge type each
+	// authorization was validated with in the authz table's validationMethod
+	// column, and read it back with the authorization.
+	StoreValidationMethod
+	// StoreReplacementOrders makes the SA record the certificate each new
+	// order replaces in the replacementOrders table, and read it back with
+	// the order.
+	StoreReplacementOrders
+	// StoreChallengeAttempts makes the SA record how many times each challenge
+	// has been attempted in the challenges table's attempts column, and allows
+	// failed challenges to be retried.
+	StoreChallengeAttempts
+	// StoreOrderExemptions makes the SA record the policy exemption tokens
+	// each new order uses in the orderExemptions table, refusing tokens
+	// already used by another order, and read them back with the order.
+	StoreOrderExemptions
+	// StoreOrderCSRHash makes the SA record the SHA-256 hash of the CSR each
+	// order was finalized with in the orders table's csrHash column, and
+	// makes the RA answer a repeated finalization with the same CSR with the
+	// order instead of an error.
+	StoreOrderCSRHash
 )
 
 // List of features and their default value, protected by fMu
@@ -59,6 +94,14 @@ var features = map[FeatureFlag]bool{
 	RevokeAtRA:               false,
 	SetIssuedNamesRenewalBit: false,
 	EarlyOrderRateLimit:      false,
+	WebhookContacts:          false,
+	ExpirationNagClaims:      false,
+	StoreKeyHashes:           false,
+	StoreValidationMethod:    false,
+	StoreReplacementOrders:   false,
+	StoreChallengeAttempts:   false,
+	StoreOrderExemptions:     false,
+	StoreOrderCSRHash:        false,
 }
 
 var fMu = new(sync.RWMutex)
@@ -90,24 +133,181 @@ func Set(featureSet map[string]bool) error {
 	return nil
 }
 
+// Replace resets every feature to its default value and then applies
+// featureSet and rolloutSet. Unlike Set, nothing is changed if featureSet or
+// rolloutSet names a feature that doesn't exist, or a rollout is invalid.
+//
+// Each call to Enabled or EnabledForAccount sees either every change made by
+// Replace or none of them, and never a feature reset to its default but not
+// yet set again. Code that checks several features while handling a single
+// request can still see some of them before a Replace and the others after
+// it, so it mustn't rely on them changing together.
+func Replace(featureSet map[string]bool, rolloutSet map[string]Rollout) error {
+	for n := range featureSet {
+		if _, present := nameToFeature[n]; !present {
+			return fmt.Errorf("feature '%s' doesn't exist", n)
+		}
+	}
+	newRollouts := make(map[FeatureFlag]Rollout, len(rolloutSet))
+	for n, r := range rolloutSet {
+		f, present := nameToFeature[n]
+		if !present {
+			return fmt.Errorf("feature '%s' doesn't exist", n)
+		}
+		if r.Percent < 0 || r.Percent > 100 {
+			return fmt.Errorf("rollout of feature '%s' has invalid percent %d", n, r.Percent)
+		}
+		newRollouts[f] = r
+	}
+	fMu.Lock()
+	defer fMu.Unlock()
+	for k, v := range initial {
+		features[k] = v
+	}
+	for n, v := range featureSet {
+		features[nameToFeature[n]] = v
+	}
+	rollouts = newRollouts
+	return nil
+}
+
 // Enabled returns true if the feature is enabled or false
 // if it isn't, it will panic if passed a feature that it
-// doesn't know.
+// doesn't know. An override set with Override takes precedence
+// over the configured value.
 func Enabled(n FeatureFlag) bool {
 	fMu.RLock()
 	defer fMu.RUnlock()
+	return enabled(n)
+}
+
+// enabled must be called with fMu held
+func enabled(n FeatureFlag) bool {
 	v, present := features[n]
 	if !present {
 		panic(fmt.Sprintf("feature '%s' doesn't exist", n.String()))
 	}
+	if o, overridden := overrides[n]; overridden {
+		return o
+	}
 	return v
 }
 
-// Reset resets the features to their initial state
+// EnabledForAccount returns true if the feature is enabled, either for every
+// account or, by its rollout, for the account with the provided registration
+// ID.
+func EnabledForAccount(n FeatureFlag, regID int64) bool {
+	fMu.RLock()
+	defer fMu.RUnlock()
+	if enabled(n) {
+		return true
+	}
+	if _, overridden := overrides[n]; overridden {
+		return false
+	}
+	r, present := rollouts[n]
+	return present && r.includes(n, regID)
+}
+
+// Reset resets the features to their initial state, removing any rollouts
+// and overrides
 func Reset() {
 	fMu.Lock()
 	defer fMu.Unlock()
 	for k, v := range initial {
 		features[k] = v
 	}
+	rollouts = map[FeatureFlag]Rollout{}
+	overrides = map[FeatureFlag]bool{}
+}
+
+// Rollout enables a feature for some accounts only, so that it can be rolled
+// out in stages.
+type Rollout struct {
+	// Accounts lists the registration IDs of accounts the feature is enabled
+	// for.
+	Accounts []int64 `json:"accounts,omitempty"`
+	// Percent is the percentage of all accounts, between 0 and 100, that the
+	// feature is enabled for. Accounts are chosen by a hash of their ID and the
+	// feature's name, so the same accounts stay enabled as Percent grows, but
+	// different features are enabled for different accounts.
+	Percent int `json:"percent,omitempty"`
+}
+
+func (r Rollout) includes(n FeatureFlag, regID int64) bool {
+	for _, id := range r.Accounts {
+		if id == regID {
+			return true
+		}
+	}
+	if r.Percent <= 0 {
+		return false
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(n.String()))
+	_ = binary.Write(h, binary.BigEndian, regID)
+	return int(h.Sum32()%100) < r.Percent
+}
+
+// Rollouts of features to some accounts, and overrides of the configured
+// value of features, both protected by fMu
+var (
+	rollouts  = map[FeatureFlag]Rollout{}
+	overrides = map[FeatureFlag]bool{}
+)
+
+// Override enables or disables a feature, for every account, regardless of
+// its configured value and rollout, until ClearOverride is called. Overrides
+// are kept when the configured values are replaced.
+func Override(name string, enabled bool) error {
+	f, present := nameToFeature[name]
+	if !present {
+		return fmt.Errorf("feature '%s' doesn't exist", name)
+	}
+	fMu.Lock()
+	defer fMu.Unlock()
+	overrides[f] = enabled
+	return nil
+}
+
+// ClearOverride removes the override of a feature, if any, restoring its
+// configured value and rollout.
+func ClearOverride(name string) error {
+	f, present := nameToFeature[name]
+	if !present {
+		return fmt.Errorf("feature '%s' doesn't exist", name)
+	}
+	fMu.Lock()
+	defer fMu.Unlock()
+	delete(overrides, f)
+	return nil
+}
+
+// State describes the current value of a feature.
+type State struct {
+	Name       string   `json:"name"`
+	Enabled    bool     `json:"enabled"`
+	Configured bool     `json:"configured"`
+	Overridden bool     `json:"overridden"`
+	Rollout    *Rollout `json:"rollout,omitempty"`
+}
+
+// States returns the state of every feature, sorted by name.
+func States() []State {
+	fMu.RLock()
+	defer fMu.RUnlock()
+	var states []State
+	for f, v := range features {
+		if f == unused {
+			continue
+		}
+		s := State{Name: f.String(), Enabled: enabled(f), Configured: v}
+		_, s.Overridden = overrides[f]
+		if r, present := rollouts[f]; present {
+			s.Rollout = &r
+		}
+		states = append(states, s)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
 }
@@ -1,6 +1,7 @@
 package features
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/letsencrypt/boulder/test"
@@ -30,3 +31,73 @@ func TestFeatures(t *testing.T) {
 	features = map[FeatureFlag]bool{}
 	Enabled(unused)
 }
+
+func TestReplace(t *testing.T) {
+	defer Reset()
+	err := Set(map[string]bool{"RevokeAtRA": true})
+	test.AssertNotError(t, err, "Set shouldn't have failed setting existing features")
+
+	err = Replace(map[string]bool{"unused": true}, nil)
+	test.AssertNotError(t, err, "Replace shouldn't have failed setting existing features")
+	test.Assert(t, Enabled(unused), "'unused' should be enabled")
+	test.Assert(t, !Enabled(RevokeAtRA), "'RevokeAtRA' should have been reset")
+
+	err = Replace(map[string]bool{"unused": false, "non-existent": true}, nil)
+	test.AssertError(t, err, "Replace should've failed trying to enable a non-existent feature")
+	test.Assert(t, Enabled(unused), "'unused' shouldn't have been changed by a failed Replace")
+}
+
+func TestEnabledForAccount(t *testing.T) {
+	defer Reset()
+	test.Assert(t, !EnabledForAccount(unused, 1), "'unused' shouldn't be enabled for account 1")
+
+	err := Replace(nil, map[string]Rollout{"unused": {Accounts: []int64{1}}})
+	test.AssertNotError(t, err, "Replace failed")
+	test.Assert(t, EnabledForAccount(unused, 1), "'unused' should be enabled for account 1")
+	test.Assert(t, !EnabledForAccount(unused, 2), "'unused' shouldn't be enabled for account 2")
+	test.Assert(t, !Enabled(unused), "'unused' shouldn't be enabled for every account")
+
+	err = Replace(nil, map[string]Rollout{"unused": {Percent: 50}})
+	test.AssertNotError(t, err, "Replace failed")
+	enabled := 0
+	for id := int64(0); id < 1000; id++ {
+		if EnabledForAccount(unused, id) {
+			enabled++
+		}
+	}
+	test.Assert(t, enabled > 400 && enabled < 600, fmt.Sprintf("'unused' enabled for %d of 1000 accounts", enabled))
+
+	err = Replace(nil, map[string]Rollout{"unused": {Percent: 101}})
+	test.AssertError(t, err, "Replace accepted a rollout to more than 100% of accounts")
+	err = Replace(nil, map[string]Rollout{"non-existent": {Percent: 1}})
+	test.AssertError(t, err, "Replace accepted a rollout of a non-existent feature")
+}
+
+func TestOverride(t *testing.T) {
+	defer Reset()
+	err := Replace(nil, map[string]Rollout{"unused": {Accounts: []int64{1}}})
+	test.AssertNotError(t, err, "Replace failed")
+
+	err = Override("unused", true)
+	test.AssertNotError(t, err, "Override failed")
+	test.Assert(t, Enabled(unused), "'unused' should be enabled by the override")
+	// Overrides survive replacing the configured values
+	err = Replace(map[string]bool{"unused": false}, nil)
+	test.AssertNotError(t, err, "Replace failed")
+	test.Assert(t, Enabled(unused), "'unused' override was lost by Replace")
+
+	err = Override("unused", false)
+	test.AssertNotError(t, err, "Override failed")
+	err = Replace(nil, map[string]Rollout{"unused": {Accounts: []int64{1}}})
+	test.AssertNotError(t, err, "Replace failed")
+	test.Assert(t, !EnabledForAccount(unused, 1), "disabling override didn't apply to the rollout")
+
+	for _, s := range States() {
+		test.Assert(t, s.Name != "unused", "States included 'unused'")
+	}
+
+	err = ClearOverride("unused")
+	test.AssertNotError(t, err, "ClearOverride failed")
+	test.Assert(t, EnabledForAccount(unused, 1), "rollout not restored after clearing the override")
+	test.AssertError(t, Override("non-existent", true), "Override accepted a non-existent feature")
+}
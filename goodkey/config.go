@@ -0,0 +1,74 @@
+package goodkey
+
+import (
+	"fmt"
+)
+
+// Config is the JSON configuration of the types, sizes and exponents of the
+// keys a KeyPolicy accepts. Its zero value leaves a KeyPolicy unchanged, so
+// deployments only need to configure what differs from Boulder's defaults.
+type Config struct {
+	// RSAKeySizes lists the allowed RSA modulus sizes in bits, e.g.
+	// [3072, 4096]. They must be multiples of 8 bits and at least 2048 bits,
+	// the minimum allowed by the Baseline Requirements. If empty, any multiple
+	// of 8 bits from 2048 to 4096 is allowed.
+	RSAKeySizes []int
+	// ECDSACurves lists the names of the allowed ECDSA curves, out of "P-256",
+	// "P-384" and "P-521". If empty, P-256 and P-384 are allowed.
+	ECDSACurves []string
+	// RSAMinExponent and RSAMaxExponent bound the public exponent of RSA keys.
+	// If zero, they default to 2^16+1 and no maximum.
+	RSAMinExponent int
+	RSAMaxExponent int
+}
+
+// Apply sets the key types, sizes and exponents that policy accepts to those
+// configured in c.
+func (c Config) Apply(policy *KeyPolicy) error {
+	for _, size := range c.RSAKeySizes {
+		if size < minRSAKeySize || size%8 != 0 {
+			return fmt.Errorf("invalid RSA key size %d", size)
+		}
+	}
+	if c.RSAMinExponent < 0 || c.RSAMaxExponent < 0 {
+		return fmt.Errorf("RSA exponent bounds must not be negative")
+	}
+	if c.RSAMinExponent != 0 && c.RSAMinExponent%2 == 0 {
+		return fmt.Errorf("RSAMinExponent must be odd, got %d", c.RSAMinExponent)
+	}
+	minExponent := c.RSAMinExponent
+	if minExponent == 0 {
+		minExponent = (1 << 16) + 1
+	}
+	if c.RSAMaxExponent != 0 && c.RSAMaxExponent < minExponent {
+		return fmt.Errorf("RSAMaxExponent %d is less than the minimum exponent %d", c.RSAMaxExponent, minExponent)
+	}
+	if len(c.ECDSACurves) > 0 {
+		var p256, p384, p521 bool
+		for _, name := range c.ECDSACurves {
+			switch name {
+			case "P-256":
+				p256 = true
+			case "P-384":
+				p384 = true
+			case "P-521":
+				p521 = true
+			default:
+				return fmt.Errorf("unsupported ECDSA curve %q", name)
+			}
+		}
+		policy.AllowECDSANISTP256 = p256
+		policy.AllowECDSANISTP384 = p384
+		policy.AllowECDSANISTP521 = p521
+	}
+	if len(c.RSAKeySizes) > 0 {
+		policy.RSAKeySizes = c.RSAKeySizes
+	}
+	if c.RSAMinExponent != 0 {
+		policy.RSAMinExponent = c.RSAMinExponent
+	}
+	if c.RSAMaxExponent != 0 {
+		policy.RSAMaxExponent = c.RSAMaxExponent
+	}
+	return nil
+}
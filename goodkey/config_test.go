@@ -0,0 +1,70 @@
+package goodkey
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestConfigApply(t *testing.T) {
+	policy := *testingPolicy
+	err := Config{}.Apply(&policy)
+	test.AssertNotError(t, err, "Apply failed for empty config")
+	test.AssertDeepEquals(t, policy, *testingPolicy)
+
+	err = Config{
+		RSAKeySizes: []int{3072, 4096},
+		ECDSACurves: []string{"P-384", "P-521"},
+	}.Apply(&policy)
+	test.AssertNotError(t, err, "Apply failed")
+
+	rsa2048, err := rsa.GenerateKey(rand.Reader, 2048)
+	test.AssertNotError(t, err, "Error generating key")
+	test.AssertError(t, policy.GoodKey(&rsa2048.PublicKey), "Accepted RSA key of a size that isn't allowed")
+	rsa3072, err := rsa.GenerateKey(rand.Reader, 3072)
+	test.AssertNotError(t, err, "Error generating key")
+	test.AssertNotError(t, policy.GoodKey(&rsa3072.PublicKey), "Rejected RSA key of an allowed size")
+
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "Error generating key")
+	test.AssertError(t, policy.GoodKey(&p256.PublicKey), "Accepted ECDSA key on a curve that isn't allowed")
+	p521, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	test.AssertNotError(t, err, "Error generating key")
+	test.AssertNotError(t, policy.GoodKey(&p521.PublicKey), "Rejected ECDSA key on an allowed curve")
+}
+
+func TestConfigApplyExponent(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	test.AssertNotError(t, err, "Error generating key")
+	key := private.PublicKey
+
+	policy := *testingPolicy
+	test.AssertNotError(t, Config{RSAMaxExponent: 65537}.Apply(&policy), "Apply failed")
+	test.AssertNotError(t, policy.GoodKey(&key), "Rejected key with exponent 65537")
+	key.E = 65539
+	test.AssertError(t, policy.GoodKey(&key), "Accepted key with exponent above the maximum")
+
+	policy = *testingPolicy
+	key.E = 3
+	test.AssertError(t, policy.GoodKey(&key), "Accepted key with exponent 3 by default")
+	test.AssertNotError(t, Config{RSAMinExponent: 3}.Apply(&policy), "Apply failed")
+	test.AssertNotError(t, policy.GoodKey(&key), "Rejected key with exponent 3 when allowed")
+}
+
+func TestConfigApplyInvalid(t *testing.T) {
+	for _, c := range []Config{
+		{RSAKeySizes: []int{2047}},
+		{RSAKeySizes: []int{1024}},
+		{ECDSACurves: []string{"P-224"}},
+		{RSAMinExponent: 4},
+		{RSAMinExponent: -1},
+		{RSAMaxExponent: 3},
+	} {
+		policy := *testingPolicy
+		test.AssertError(t, c.Apply(&policy), "Apply accepted an invalid config")
+	}
+}
@@ -29,6 +29,10 @@ var smallPrimeInts = []int64{
 	719, 727, 733, 739, 743, 751,
 }
 
+// minRSAKeySize is the smallest RSA modulus, in bits, that any KeyPolicy
+// accepts, as required by the Baseline Requirements.
+const minRSAKeySize = 2048
+
 // singleton defines the object of a Singleton pattern
 var (
 	smallPrimesSingleton sync.Once
@@ -41,7 +45,20 @@ type KeyPolicy struct {
 	AllowRSA           bool // Whether RSA keys should be allowed.
 	AllowECDSANISTP256 bool // Whether ECDSA NISTP256 keys should be allowed.
 	AllowECDSANISTP384 bool // Whether ECDSA NISTP384 keys should be allowed.
-	weakRSAList        *WeakRSAKeys
+	AllowECDSANISTP521 bool // Whether ECDSA NISTP521 keys should be allowed.
+	// RSAKeySizes lists the allowed RSA modulus sizes in bits. Sizes below
+	// 2048 bits are never allowed. If it is empty any multiple of 8 bits from
+	// 2048 to 4096 is allowed.
+	RSAKeySizes []int
+	// RSAMinExponent and RSAMaxExponent bound the public exponent of RSA keys,
+	// which must always be odd. They default to 2^16+1 and no maximum.
+	RSAMinExponent int
+	RSAMaxExponent int
+	// FermatRounds is the number of rounds of Fermat's factorization method
+	// to attempt on RSA moduli, to detect keys whose primes are too close
+	// together. Zero disables the check.
+	FermatRounds int
+	weakRSAList  *WeakRSAKeys
 }
 
 // NewKeyPolicy returns a KeyPolicy that allows RSA, ECDSA256 and ECDSA384.
@@ -79,7 +96,7 @@ func (policy *KeyPolicy) GoodKey(key crypto.PublicKey) error {
 	case *ecdsa.PublicKey:
 		return policy.goodKeyECDSA(*t)
 	default:
-		return berrors.MalformedError("unknown key type %s", reflect.TypeOf(key))
+		return berrors.BadPublicKeyError("unknown key type %s", reflect.TypeOf(key))
 	}
 }
 
@@ -109,7 +126,7 @@ func (policy *KeyPolicy) goodKeyECDSA(key ecdsa.PublicKey) (err error) {
 	// This code assumes that the point at infinity is (0,0), which is the
 	// case for all supported curves.
 	if isPointAtInfinityNISTP(key.X, key.Y) {
-		return berrors.MalformedError("key x, y must not be the point at infinity")
+		return berrors.BadPublicKeyError("key x, y must not be the point at infinity")
 	}
 
 	// SP800-56A § 5.6.2.3.2 Step 2.
@@ -126,11 +143,11 @@ func (policy *KeyPolicy) goodKeyECDSA(key ecdsa.PublicKey) (err error) {
 	// correct representation of an element in the underlying field by verifying
 	// that x and y are integers in [0, p-1].
 	if key.X.Sign() < 0 || key.Y.Sign() < 0 {
-		return berrors.MalformedError("key x, y must not be negative")
+		return berrors.BadPublicKeyError("key x, y must not be negative")
 	}
 
 	if key.X.Cmp(params.P) >= 0 || key.Y.Cmp(params.P) >= 0 {
-		return berrors.MalformedError("key x, y must not exceed P-1")
+		return berrors.BadPublicKeyError("key x, y must not exceed P-1")
 	}
 
 	// SP800-56A § 5.6.2.3.2 Step 3.
@@ -148,7 +165,7 @@ func (policy *KeyPolicy) goodKeyECDSA(key ecdsa.PublicKey) (err error) {
 	// This proves that the public key is on the correct elliptic curve.
 	// But in practice, this test is provided by crypto/elliptic, so use that.
 	if !key.Curve.IsOnCurve(key.X, key.Y) {
-		return berrors.MalformedError("key point is not on the curve")
+		return berrors.BadPublicKeyError("key point is not on the curve")
 	}
 
 	// SP800-56A § 5.6.2.3.2 Step 4.
@@ -164,7 +181,7 @@ func (policy *KeyPolicy) goodKeyECDSA(key ecdsa.PublicKey) (err error) {
 	// n*Q = O iff n*Q is the point at infinity (see step 1).
 	ox, oy := key.Curve.ScalarMult(key.X, key.Y, params.N.Bytes())
 	if !isPointAtInfinityNISTP(ox, oy) {
-		return berrors.MalformedError("public key does not have correct order")
+		return berrors.BadPublicKeyError("public key does not have correct order")
 	}
 
 	// End of SP800-56A § 5.6.2.3.2 Public Key Validation Routine.
@@ -189,60 +206,90 @@ func (policy *KeyPolicy) goodCurve(c elliptic.Curve) (err error) {
 		return nil
 	case policy.AllowECDSANISTP384 && params == elliptic.P384().Params():
 		return nil
+	case policy.AllowECDSANISTP521 && params == elliptic.P521().Params():
+		return nil
 	default:
-		return berrors.MalformedError("ECDSA curve %v not allowed", params.Name)
+		return berrors.BadPublicKeyError("ECDSA curve %v not allowed", params.Name)
 	}
 }
 
 // GoodKeyRSA determines if a RSA pubkey meets our requirements
 func (policy *KeyPolicy) goodKeyRSA(key rsa.PublicKey) (err error) {
 	if !policy.AllowRSA {
-		return berrors.MalformedError("RSA keys are not allowed")
+		return berrors.BadPublicKeyError("RSA keys are not allowed")
 	}
 	if policy.weakRSAList != nil && policy.weakRSAList.Known(&key) {
-		return berrors.MalformedError("key is on a known weak RSA key list")
+		return berrors.BadPublicKeyError("key is on a known weak RSA key list")
 	}
 
-	// Baseline Requirements Appendix A
-	// Modulus must be >= 2048 bits and <= 4096 bits
 	modulus := key.N
 	modulusBitLen := modulus.BitLen()
-	const maxKeySize = 4096
-	if modulusBitLen < 2048 {
-		return berrors.MalformedError("key too small: %d", modulusBitLen)
-	}
-	if modulusBitLen > maxKeySize {
-		return berrors.MalformedError("key too large: %d > %d", modulusBitLen, maxKeySize)
+	// Baseline Requirements Appendix A
+	// Modulus must be >= 2048 bits and <= 4096 bits
+	if modulusBitLen < minRSAKeySize {
+		return berrors.BadPublicKeyError("key too small: %d", modulusBitLen)
 	}
-	// Bit lengths that are not a multiple of 8 may cause problems on some
-	// client implementations.
-	if modulusBitLen%8 != 0 {
-		return berrors.MalformedError("key length wasn't a multiple of 8: %d", modulusBitLen)
+	if len(policy.RSAKeySizes) > 0 {
+		if !policy.allowedRSAKeySize(modulusBitLen) {
+			return berrors.BadPublicKeyError("key size not allowed: %d, allowed sizes are %v", modulusBitLen, policy.RSAKeySizes)
+		}
+	} else {
+		const maxKeySize = 4096
+		if modulusBitLen > maxKeySize {
+			return berrors.BadPublicKeyError("key too large: %d > %d", modulusBitLen, maxKeySize)
+		}
+		// Bit lengths that are not a multiple of 8 may cause problems on some
+		// client implementations.
+		if modulusBitLen%8 != 0 {
+			return berrors.BadPublicKeyError("key length wasn't a multiple of 8: %d", modulusBitLen)
+		}
 	}
 	// The CA SHALL confirm that the value of the public exponent is an
 	// odd number equal to 3 or more. Additionally, the public exponent
 	// SHOULD be in the range between 2^16 + 1 and 2^256-1.
 	// NOTE: rsa.PublicKey cannot represent an exponent part greater than
 	// 2^32 - 1 or 2^64 - 1, because it stores E as an integer. So we
-	// don't need to check the upper bound.
-	if (key.E%2) == 0 || key.E < ((1<<16)+1) {
-		return berrors.MalformedError("key exponent should be odd and >2^16: %d", key.E)
+	// don't need to check the upper bound unless one is configured.
+	minExponent := policy.RSAMinExponent
+	if minExponent == 0 {
+		minExponent = (1 << 16) + 1
+	}
+	if (key.E%2) == 0 || key.E < minExponent {
+		return berrors.BadPublicKeyError("key exponent should be odd and >= %d: %d", minExponent, key.E)
+	}
+	if policy.RSAMaxExponent != 0 && key.E > policy.RSAMaxExponent {
+		return berrors.BadPublicKeyError("key exponent too large: %d > %d", key.E, policy.RSAMaxExponent)
 	}
 	// The modulus SHOULD also have the following characteristics: an odd
 	// number, not the power of a prime, and have no factors smaller than 752.
 	// TODO: We don't yet check for "power of a prime."
 	if checkSmallPrimes(modulus) {
-		return berrors.MalformedError("key divisible by small prime")
+		return berrors.BadPublicKeyError("key divisible by small prime")
 	}
 	// Check for weak keys generated by Infineon hardware
 	// (see https://crocs.fi.muni.cz/public/papers/rsa_ccs17)
 	if rocacheck.IsWeak(&key) {
-		return berrors.MalformedError("key generated by vulnerable Infineon-based hardware")
+		return berrors.BadPublicKeyError("key generated by vulnerable Infineon-based hardware")
+	}
+	// Check for keys whose primes are so close together that the modulus is
+	// quickly factored, as produced by some buggy key generators.
+	if policy.FermatRounds > 0 && checkFermat(modulus, policy.FermatRounds) {
+		return berrors.BadPublicKeyError("key modulus factored by Fermat's method: primes are too close together")
 	}
 
 	return nil
 }
 
+// allowedRSAKeySize returns true iff bits is one of policy.RSAKeySizes.
+func (policy *KeyPolicy) allowedRSAKeySize(bits int) bool {
+	for _, size := range policy.RSAKeySizes {
+		if bits == size {
+			return true
+		}
+	}
+	return false
+}
+
 // Returns true iff integer i is divisible by any of the primes in smallPrimes.
 //
 // Short circuits; execution time is dependent on i. Do not use this on secret
@@ -264,3 +311,33 @@ func checkSmallPrimes(i *big.Int) bool {
 
 	return false
 }
+
+// checkFermat returns true iff Fermat's factorization method factors n within
+// the given number of rounds. Any odd n = pq can be written as a^2 - b^2 with
+// a = (p+q)/2 and b = (p-q)/2, so starting from a = ceil(sqrt(n)) and stepping
+// a upwards finds the factors quickly when p and q are close together.
+//
+// Execution time is dependent on n. Do not use this on secret values.
+func checkFermat(n *big.Int, rounds int) bool {
+	one := big.NewInt(1)
+	a := new(big.Int).Sqrt(n)
+	// a^2 - n, which is a perfect square b^2 once a has reached (p+q)/2
+	b2 := new(big.Int).Mul(a, a)
+	if b2.Cmp(n) < 0 {
+		a.Add(a, one)
+		b2.Mul(a, a)
+	}
+	b2.Sub(b2, n)
+	b := new(big.Int)
+	bb := new(big.Int)
+	for i := 0; i < rounds; i++ {
+		b.Sqrt(b2)
+		if bb.Mul(b, b).Cmp(b2) == 0 {
+			return true
+		}
+		// (a+1)^2 - n = a^2 - n + 2a + 1
+		b2.Add(b2, a).Add(b2, a).Add(b2, one)
+		a.Add(a, one)
+	}
+	return false
+}
@@ -59,6 +59,14 @@ func TestModulusModulo8(t *testing.T) {
 	test.AssertError(t, testingPolicy.GoodKey(&key), "Should have rejected modulus with length not divisible by 8.")
 }
 
+func TestSmallModulusWithAllowedSizes(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, 1024)
+	test.AssertNotError(t, err, "Error generating key")
+	policy := *testingPolicy
+	policy.RSAKeySizes = []int{1024, 2048}
+	test.AssertError(t, policy.GoodKey(&private.PublicKey), "Should have rejected 1024 bit key even though its size is listed.")
+}
+
 func TestSmallExponent(t *testing.T) {
 	bigOne := big.NewInt(1)
 	key := rsa.PublicKey{
@@ -224,3 +232,26 @@ func TestECDSAIdentity(t *testing.T) {
 		test.AssertError(t, testingPolicy.GoodKey(public), "Should not have accepted key with point at infinity.")
 	}
 }
+
+func TestFermat(t *testing.T) {
+	// Build a 2048 bit modulus from two primes that differ only in their low
+	// bits, as a buggy key generator might.
+	p, err := rand.Prime(rand.Reader, 1024)
+	test.AssertNotError(t, err, "Error generating prime")
+	q := new(big.Int).Add(p, big.NewInt(2))
+	for !q.ProbablyPrime(20) {
+		q.Add(q, big.NewInt(2))
+	}
+	key := rsa.PublicKey{N: new(big.Int).Mul(p, q), E: 65537}
+
+	policy := *testingPolicy
+	test.AssertNotError(t, policy.GoodKey(&key), "Rejected close-prime key with Fermat checking disabled")
+	policy.FermatRounds = 100
+	err = policy.GoodKey(&key)
+	test.AssertError(t, err, "Should have rejected key with close primes")
+	test.AssertContains(t, err.Error(), "Fermat")
+
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	test.AssertNotError(t, err, "Error generating key")
+	test.AssertNotError(t, policy.GoodKey(&private.PublicKey), "Rejected a good key with Fermat checking enabled")
+}
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	berrors "github.com/letsencrypt/boulder/errors"
 	"github.com/letsencrypt/boulder/test"
 )
 
@@ -44,3 +45,25 @@ func TestLoadKeys(t *testing.T) {
 	test.AssertNotError(t, err, "Failed to load suffixes from directory")
 	test.Assert(t, wk.Known(&testKey), "WeakRSAKeys.Known failed to find suffix that has been added")
 }
+
+func TestGoodKeyWeakList(t *testing.T) {
+	modBytes, err := hex.DecodeString("D673252AF6723C3F72529403EAB7C30DEF3C52F97E799825F4A70191C616ADCF1ECE1113F1625971074C492C592025FDEADBDB146A081826BDF0D77C3C913DCF1B6F0B3B78F5108D2E493AD0EEE8CA5C021711ADC13D358E61133870FCD19C8E5C22403959782AA82E72AEE53A3D491E3912CE27B27E1A85EA69C19A527D28F7934C9823B7E56FDD657DAC83FDC65BB22A98D843DF73238919781B714C81A5E2AFEC71F5C54AA2A27C590AD94C03C1062D50EFCFFAC743E3C8A3AE056846A1D756EB862BF4224169D467C35215ADE0AFCC11E85FE629AFB802C4786FF2E9C929BCCF502B3D3B8876C6A11785CC398B389F1D86BDD9CB0BD4EC13956EC3FA270D")
+	test.AssertNotError(t, err, "Failed to decode modulus bytes")
+	testKey := &rsa.PublicKey{N: new(big.Int).SetBytes(modBytes), E: 65537}
+	tempDir, err := ioutil.TempDir("", "weak-keys")
+	test.AssertNotError(t, err, "Failed to create temporary directory")
+	defer os.RemoveAll(tempDir)
+	tempPath := filepath.Join(tempDir, "a.json")
+	err = ioutil.WriteFile(tempPath, []byte("[\"8df20e6961a16398b85a\"]"), os.ModePerm)
+	test.AssertNotError(t, err, "Failed to create temporary file")
+
+	policy, err := NewKeyPolicy("")
+	test.AssertNotError(t, err, "NewKeyPolicy failed")
+	test.AssertNotError(t, policy.GoodKey(testKey), "Rejected key without a weak key list")
+
+	policy, err = NewKeyPolicy(tempPath)
+	test.AssertNotError(t, err, "NewKeyPolicy failed")
+	err = policy.GoodKey(testKey)
+	test.AssertError(t, err, "Accepted key on the weak key list")
+	test.Assert(t, berrors.Is(err, berrors.BadPublicKey), "Weak key wasn't rejected with a BadPublicKey error")
+}
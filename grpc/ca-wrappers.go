@@ -16,6 +16,7 @@ import (
 	caPB "github.com/letsencrypt/boulder/ca/proto"
 	"github.com/letsencrypt/boulder/core"
 	corepb "github.com/letsencrypt/boulder/core/proto"
+	"github.com/letsencrypt/boulder/probs"
 	"github.com/letsencrypt/boulder/revocation"
 )
 
@@ -70,6 +71,17 @@ func (cac CertificateAuthorityClientWrapper) IssueCertificateForPrecertificate(c
 	return pbToCert(res)
 }
 
+func (cac CertificateAuthorityClientWrapper) CheckCertificateRequest(ctx context.Context, issueReq *caPB.IssueCertificateRequest) ([]*probs.ProblemDetails, error) {
+	if cac.inner == nil {
+		return nil, errors.New("this CA client does not support checking certificate requests")
+	}
+	resp, err := cac.inner.CheckCertificateRequest(ctx, issueReq)
+	if err != nil {
+		return nil, err
+	}
+	return pbToProblems(resp.Problems)
+}
+
 func (cac CertificateAuthorityClientWrapper) GenerateOCSP(ctx context.Context, ocspReq core.OCSPSigningRequest) ([]byte, error) {
 	var inner interface {
 		GenerateOCSP(context.Context, *caPB.GenerateOCSPRequest, ...grpc.CallOption) (*caPB.OCSPResponse, error)
@@ -135,6 +147,21 @@ func (cas *CertificateAuthorityServerWrapper) IssueCertificateForPrecertificate(
 	return certToPB(cert), nil
 }
 
+func (cas *CertificateAuthorityServerWrapper) CheckCertificateRequest(ctx context.Context, request *caPB.IssueCertificateRequest) (*caPB.CheckCertificateRequestResponse, error) {
+	if request == nil || request.Csr == nil || request.RegistrationID == nil {
+		return nil, errIncompleteRequest
+	}
+	problems, err := cas.inner.CheckCertificateRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	pbProblems, err := problemsToPB(problems)
+	if err != nil {
+		return nil, err
+	}
+	return &caPB.CheckCertificateRequestResponse{Problems: pbProblems}, nil
+}
+
 func (cas *CertificateAuthorityServerWrapper) GenerateOCSP(ctx context.Context, request *caPB.GenerateOCSPRequest) (*caPB.OCSPResponse, error) {
 	res, err := cas.inner.GenerateOCSP(ctx, core.OCSPSigningRequest{
 		CertDER:   request.CertDER,
@@ -3,42 +3,93 @@ package grpc
 import (
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"net"
+	"strings"
 
 	"github.com/grpc-ecosystem/go-grpc-prometheus"
 	"github.com/jmhodges/clock"
 	"github.com/letsencrypt/boulder/cmd"
 	bcreds "github.com/letsencrypt/boulder/grpc/creds"
+	"github.com/letsencrypt/boulder/health"
 	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 )
 
+// ConnCheck returns a health check that fails while the provided connection
+// is unable to reach any backend. Idle connections, which haven't been used
+// yet, pass.
+func ConnCheck(conn *grpc.ClientConn) health.Check {
+	return func(_ context.Context) error {
+		state := conn.GetState()
+		if state == connectivity.TransientFailure || state == connectivity.Shutdown {
+			return fmt.Errorf("connection to %s is in state %s", conn.Target(), state)
+		}
+		return nil
+	}
+}
+
 // ClientSetup creates a gRPC TransportCredentials that presents
 // a client certificate and validates the the server certificate based
 // on the provided *tls.Config.
 // It dials the remote service and returns a grpc.ClientConn if successful.
 func ClientSetup(c *cmd.GRPCClientConfig, tlsConfig *tls.Config, metrics clientMetrics, clk clock.Clock) (*grpc.ClientConn, error) {
-	if c.ServerAddress == "" {
-		return nil, errors.New("ServerAddress must not be empty")
+	if c.ServerAddress == "" && len(c.ServerAddresses) == 0 {
+		return nil, errors.New("ServerAddress or ServerAddresses must not be empty")
 	}
 	if tlsConfig == nil {
 		return nil, errNilTLS
 	}
+	retries, err := newRetryPolicies(c.RetryPolicies)
+	if err != nil {
+		return nil, err
+	}
 
 	// Set the only acceptable TLS version to 1.2 and the only acceptable cipher suite
 	// to ECDHE-RSA-CHACHA20-POLY1305.
 	tlsConfig.MinVersion, tlsConfig.MaxVersion = tls.VersionTLS12, tls.VersionTLS12
 	tlsConfig.CipherSuites = []uint16{tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305}
 
-	ci := clientInterceptor{c.Timeout.Duration, metrics, clk}
-	host, _, err := net.SplitHostPort(c.ServerAddress)
+	ci := clientInterceptor{
+		timeout: c.Timeout.Duration,
+		metrics: metrics,
+		clk:     clk,
+		retries: retries,
+	}
+	target := "dns:///" + c.ServerAddress
+	host := c.HostOverride
+	if len(c.ServerAddresses) > 0 {
+		target = staticScheme + ":///" + strings.Join(c.ServerAddresses, ",")
+		if host == "" {
+			host, _, err = net.SplitHostPort(c.ServerAddresses[0])
+		}
+	} else if host == "" {
+		host, _, err = net.SplitHostPort(c.ServerAddress)
+	}
 	if err != nil {
 		return nil, err
 	}
+	balancerName := "round_robin"
+	if c.SubsetSize > 0 {
+		balancerName = subsetBalancerName(c.SubsetSize)
+	}
 	creds := bcreds.NewClientCredentials(tlsConfig.RootCAs, tlsConfig.Certificates, host)
+	if tlsConfig.GetConfigForClient != nil {
+		// The certificates are reloaded from disk (see cmd.TLSConfig), so use
+		// the current ones for each new connection.
+		creds = bcreds.NewReloadingClientCredentials(func() *tls.Config {
+			current, err := tlsConfig.GetConfigForClient(nil)
+			if err != nil || current == nil {
+				return tlsConfig
+			}
+			return current
+		}, host)
+	}
 	return grpc.Dial(
-		"dns:///"+c.ServerAddress,
-		grpc.WithBalancerName("round_robin"),
+		target,
+		grpc.WithBalancerName(balancerName),
 		grpc.WithTransportCredentials(creds),
 		grpc.WithUnaryInterceptor(ci.intercept),
 	)
@@ -55,6 +106,13 @@ type clientMetrics struct {
 	// inFlightRPCs is a labelled gauge that slices by service/method the number
 	// of outstanding/in-flight RPCs.
 	inFlightRPCs *prometheus.GaugeVec
+	// retries counts, by service/method, the attempts made after the first
+	// for RPCs with a retry policy.
+	retries *prometheus.CounterVec
+	// deadlineExceeded counts, by service/method, RPCs that didn't complete
+	// before their deadline, and whether that deadline was set by this
+	// client's timeout ("client") or inherited from the caller ("inherited").
+	deadlineExceeded *prometheus.CounterVec
 }
 
 // NewClientMetrics constructs a *grpc_prometheus.ClientMetrics, registered with
@@ -73,8 +131,22 @@ func NewClientMetrics(stats registry) clientMetrics {
 	}, []string{"method", "service"})
 	stats.MustRegister(inFlightGauge)
 
+	retries := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_client_retries",
+		Help: "Number of RPC attempts made after the first, including hedged attempts",
+	}, []string{"method", "service"})
+	stats.MustRegister(retries)
+
+	deadlineExceeded := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_client_deadline_exceeded",
+		Help: "Number of RPCs that exceeded their deadline, by whose budget (client or inherited) set it",
+	}, []string{"method", "service", "budget"})
+	stats.MustRegister(deadlineExceeded)
+
 	return clientMetrics{
-		grpcMetrics:  grpcMetrics,
-		inFlightRPCs: inFlightGauge,
+		grpcMetrics:      grpcMetrics,
+		inFlightRPCs:     inFlightGauge,
+		retries:          retries,
+		deadlineExceeded: deadlineExceeded,
 	}
 }
@@ -43,11 +43,22 @@ type clientTransportCredentials struct {
 	// If set, this is used as the hostname to validate on certificates, instead
 	// of the value passed to ClientHandshake by grpc.
 	hostOverride string
+	// If set, this is called for every handshake and the roots and client
+	// certificates of the config it returns are used instead of the ones above.
+	getConfig func() *tls.Config
 }
 
 // NewClientCredentials returns a new initialized grpc/credentials.TransportCredentials for client usage
 func NewClientCredentials(rootCAs *x509.CertPool, clientCerts []tls.Certificate, hostOverride string) credentials.TransportCredentials {
-	return &clientTransportCredentials{rootCAs, clientCerts, hostOverride}
+	return &clientTransportCredentials{rootCAs, clientCerts, hostOverride, nil}
+}
+
+// NewReloadingClientCredentials returns a new initialized
+// grpc/credentials.TransportCredentials for client usage that calls getConfig
+// for the root CAs and client certificates to use for each new connection, so
+// that they can be rotated without recreating the credentials.
+func NewReloadingClientCredentials(getConfig func() *tls.Config, hostOverride string) credentials.TransportCredentials {
+	return &clientTransportCredentials{hostOverride: hostOverride, getConfig: getConfig}
 }
 
 // ClientHandshake does the authentication handshake specified by the corresponding
@@ -65,10 +76,15 @@ func (tc *clientTransportCredentials) ClientHandshake(ctx context.Context, addr
 			return nil, nil, err
 		}
 	}
+	roots, clients := tc.roots, tc.clients
+	if tc.getConfig != nil {
+		config := tc.getConfig()
+		roots, clients = config.RootCAs, config.Certificates
+	}
 	conn := tls.Client(rawConn, &tls.Config{
 		ServerName:   host,
-		RootCAs:      tc.roots,
-		Certificates: tc.clients,
+		RootCAs:      roots,
+		Certificates: clients,
 		MinVersion:   tls.VersionTLS12, // Override default of tls.VersionTLS10
 		MaxVersion:   tls.VersionTLS12, // Same as default in golang <= 1.6
 	})
@@ -114,7 +130,7 @@ func (tc *clientTransportCredentials) RequireTransportSecurity() bool {
 
 // Clone returns a copy of the clientTransportCredentials
 func (tc *clientTransportCredentials) Clone() credentials.TransportCredentials {
-	return NewClientCredentials(tc.roots, tc.clients, tc.hostOverride)
+	return &clientTransportCredentials{tc.roots, tc.clients, tc.hostOverride, tc.getConfig}
 }
 
 // OverrideServerName is not implemented and here only to satisfy the interface
@@ -203,3 +203,48 @@ func TestClientReset(t *testing.T) {
 	})
 	test.Assert(t, ok, "returned error doesn't have a Temporary method")
 }
+
+func TestReloadingClientCredentials(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	test.AssertNotError(t, err, "rsa.GenerateKey failed")
+	temp := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			CommonName: "A",
+		},
+		DNSNames:              []string{"A"},
+		NotBefore:             time.Unix(1000, 0),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, temp, temp, priv.Public(), priv)
+	test.AssertNotError(t, err, "x509.CreateCertificate failed")
+	cert, err := x509.ParseCertificate(der)
+	test.AssertNotError(t, err, "x509.ParserCertificate failed")
+
+	server := httptest.NewUnstartedServer(nil)
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: priv}}}
+	server.StartTLS()
+	defer server.Close()
+
+	current := &tls.Config{RootCAs: x509.NewCertPool()}
+	tc := NewReloadingClientCredentials(func() *tls.Config { return current }, "A")
+	handshake := func() error {
+		rawConn, err := net.Dial("tcp", server.Listener.Addr().String())
+		test.AssertNotError(t, err, "net.Dial failed")
+		defer func() {
+			_ = rawConn.Close()
+		}()
+		_, _, err = tc.ClientHandshake(context.Background(), "ignored:443", rawConn)
+		return err
+	}
+
+	test.AssertError(t, handshake(), "tc.ClientHandshake succeeded without the server's root")
+
+	// Adding the root to the current config is picked up by new connections
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	current = &tls.Config{RootCAs: roots}
+	test.AssertNotError(t, handshake(), "tc.ClientHandshake failed after the root was added")
+}
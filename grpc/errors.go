@@ -1,8 +1,10 @@
 package grpc
 
 import (
+	"encoding/json"
 	"errors"
 	"strconv"
+	"time"
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
@@ -30,7 +32,19 @@ func wrapError(ctx context.Context, err error) error {
 		// Ignoring the error return here is safe because if setting the metadata
 		// fails, we'll still return an error, but it will be interpreted on the
 		// other side as an InternalServerError instead of a more specific one.
-		_ = grpc.SetTrailer(ctx, metadata.Pairs("errortype", strconv.Itoa(int(berr.Type))))
+		pairs := []string{"errortype", strconv.Itoa(int(berr.Type))}
+		if berr.RetryAfter > 0 {
+			pairs = append(pairs, "retryafter", strconv.FormatInt(int64(berr.RetryAfter), 10))
+		}
+		if len(berr.SubErrors) > 0 {
+			// If the suberrors can't be marshaled the error is still returned,
+			// just without them.
+			subErrs, jsonErr := json.Marshal(berr.SubErrors)
+			if jsonErr == nil {
+				pairs = append(pairs, "suberrors", string(subErrs))
+			}
+		}
+		_ = grpc.SetTrailer(ctx, metadata.Pairs(pairs...))
 		return grpc.Errorf(codes.Unknown, err.Error())
 	}
 	return grpc.Errorf(codes.Unknown, err.Error())
@@ -39,7 +53,8 @@ func wrapError(ctx context.Context, err error) error {
 // unwrapError unwraps errors returned from gRPC client calls which were wrapped
 // with wrapError to their proper internal error type. If the provided metadata
 // object has an "errortype" field, that will be used to set the type of the
-// error.
+// error. "retryafter" and "suberrors" fields, if present, set the error's
+// RetryAfter and SubErrors.
 func unwrapError(err error, md metadata.MD) error {
 	if err == nil {
 		return nil
@@ -60,7 +75,23 @@ func unwrapError(err error, md metadata.MD) error {
 				unwrappedErr,
 			)
 		}
-		return berrors.New(berrors.ErrorType(errType), unwrappedErr)
+		berr := &berrors.BoulderError{
+			Type:   berrors.ErrorType(errType),
+			Detail: unwrappedErr,
+		}
+		if retryAfterStrs, ok := md["retryafter"]; ok && len(retryAfterStrs) == 1 {
+			retryAfter, decErr := strconv.ParseInt(retryAfterStrs[0], 10, 64)
+			if decErr == nil {
+				berr.RetryAfter = time.Duration(retryAfter)
+			}
+		}
+		if subErrStrs, ok := md["suberrors"]; ok && len(subErrStrs) == 1 {
+			var subErrs []berrors.SubBoulderError
+			if decErr := json.Unmarshal([]byte(subErrStrs[0]), &subErrs); decErr == nil {
+				berr.SubErrors = subErrs
+			}
+		}
+		return berr
 	}
 	return err
 }
@@ -27,7 +27,7 @@ func (s *errorServer) Chill(_ context.Context, _ *testproto.Time) (*testproto.Ti
 func TestErrorWrapping(t *testing.T) {
 	serverMetrics := NewServerMetrics(metrics.NewNoopScope())
 	si := newServerInterceptor(serverMetrics, clock.NewFake())
-	ci := clientInterceptor{time.Second, NewClientMetrics(metrics.NewNoopScope()), clock.NewFake()}
+	ci := clientInterceptor{timeout: time.Second, metrics: NewClientMetrics(metrics.NewNoopScope()), clk: clock.NewFake()}
 	srv := grpc.NewServer(grpc.UnaryInterceptor(si.intercept))
 	es := &errorServer{}
 	testproto.RegisterChillerServer(srv, es)
@@ -49,6 +49,23 @@ func TestErrorWrapping(t *testing.T) {
 	test.Assert(t, err != nil, fmt.Sprintf("nil error returned, expected: %s", err))
 	test.AssertDeepEquals(t, err, es.err)
 
+	es.err = berrors.RateLimitErrorWithRetryAfter(time.Hour, "slow down")
+	_, err = client.Chill(context.Background(), &testproto.Time{})
+	test.Assert(t, err != nil, fmt.Sprintf("nil error returned, expected: %s", err))
+	test.AssertDeepEquals(t, err, es.err)
+
+	es.err = &berrors.BoulderError{
+		Type:   berrors.Malformed,
+		Detail: "bad names",
+		SubErrors: []berrors.SubBoulderError{
+			{Type: berrors.Malformed, Detail: "bad name", Name: "example.com"},
+			{Type: berrors.RejectedIdentifier, Detail: "forbidden name", Name: "example.net"},
+		},
+	}
+	_, err = client.Chill(context.Background(), &testproto.Time{})
+	test.Assert(t, err != nil, fmt.Sprintf("nil error returned, expected: %s", err))
+	test.AssertDeepEquals(t, err, es.err)
+
 	test.AssertEquals(t, wrapError(nil, nil), nil)
 	test.AssertEquals(t, unwrapError(nil, nil), nil)
 }
@@ -13,6 +13,8 @@ import (
 	"google.golang.org/grpc/metadata"
 
 	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/faults"
+	"github.com/letsencrypt/boulder/trace"
 )
 
 const (
@@ -45,12 +47,23 @@ func (si *serverInterceptor) intercept(ctx context.Context, req interface{}, inf
 	// Extract the grpc metadata from the context. If the context has
 	// a `clientRequestTimeKey` field, and it has a value, then observe the RPC
 	// latency with Prometheus.
-	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md[clientRequestTimeKey]) > 0 {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok && len(md[clientRequestTimeKey]) > 0 {
 		if err := si.observeLatency(md[clientRequestTimeKey][0]); err != nil {
 			return nil, err
 		}
 	}
 
+	// Continue the caller's trace, if it sent one, with a span covering the
+	// handling of this RPC.
+	if ok && len(md[trace.TraceparentKey]) > 0 {
+		if parent, err := trace.ParseTraceparent(md[trace.TraceparentKey][0]); err == nil {
+			ctx = trace.NewContext(ctx, parent)
+		}
+	}
+	ctx, span := trace.Start(ctx, info.FullMethod, trace.KindServer)
+	defer span.End()
+
 	// Shave 20 milliseconds off the deadline to ensure that if the RPC server times
 	// out any sub-calls it makes (like DNS lookups, or onwards RPCs), it has a
 	// chance to report that timeout to the client. This allows for more specific
@@ -64,22 +77,47 @@ func (si *serverInterceptor) intercept(ctx context.Context, req interface{}, inf
 	if !ok {
 		deadline = time.Now().Add(100 * time.Second)
 	}
+	service, method := splitMethodName(info.FullMethod)
+	si.metrics.deadlineBudget.With(prometheus.Labels{
+		"method":  method,
+		"service": service,
+	}).Observe(deadline.Sub(time.Now()).Seconds())
 	deadline = deadline.Add(-returnOverhead)
 	remaining := deadline.Sub(time.Now())
 	if remaining < meaningfulWorkOverhead {
+		si.deadlineExceeded(service, method, "arrival")
 		return nil, grpc.Errorf(codes.DeadlineExceeded, "not enough time left on clock: %s", remaining)
 	}
 	var cancel func()
 	ctx, cancel = context.WithDeadline(ctx, deadline)
 	defer cancel()
 
+	// In integration builds, faults configured for this method delay or fail
+	// it here, before the handler runs.
+	if err := faults.Inject(ctx, faults.GRPC, info.FullMethod); err != nil {
+		span.SetError(err)
+		return nil, wrapError(ctx, err)
+	}
+
 	resp, err := si.metrics.grpcMetrics.UnaryServerInterceptor()(ctx, req, info, handler)
 	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			si.deadlineExceeded(service, method, "handler")
+		}
+		span.SetError(err)
 		err = wrapError(ctx, err)
 	}
 	return resp, err
 }
 
+func (si *serverInterceptor) deadlineExceeded(service, method, stage string) {
+	si.metrics.deadlineExceeded.With(prometheus.Labels{
+		"method":  method,
+		"service": service,
+		"stage":   stage,
+	}).Inc()
+}
+
 // splitMethodName is borrowed directly from
 // `grpc-ecosystem/go-grpc-prometheus/util.go` and is used to extract the
 // service and method name from the `method` argument to
@@ -118,11 +156,13 @@ func (si *serverInterceptor) observeLatency(clientReqTime string) error {
 // makes a request while all backends are briefly down (e.g. for a restart), the
 // request doesn't necessarily fail. A backend can service the request if it
 // comes back up within the timeout. Under gRPC the same effect is achieved by
-// retries up to the Context deadline.
+// retries up to the Context deadline. RPCs that fail can additionally be
+// retried according to the retry policy configured for their method.
 type clientInterceptor struct {
 	timeout time.Duration
 	metrics clientMetrics
 	clk     clock.Clock
+	retries retryPolicies
 }
 
 // intercept fulfils the grpc.UnaryClientInterceptor interface, it should be noted that while this API
@@ -141,6 +181,13 @@ func (ci *clientInterceptor) intercept(
 		return berrors.InternalServerError("clientInterceptor has nil inFlightRPCs gauge")
 	}
 
+	// The RPC's deadline is the earlier of the caller's deadline, if any, and
+	// this client's timeout. Remember which one applies so that RPCs that
+	// exceed it can be attributed to this hop or an earlier one.
+	budget := "client"
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < ci.timeout {
+		budget = "inherited"
+	}
 	localCtx, cancel := context.WithTimeout(ctx, ci.timeout)
 	defer cancel()
 	// Disable fail-fast so RPCs will retry until deadline, even if all backends
@@ -150,18 +197,20 @@ func (ci *clientInterceptor) intercept(
 	// Convert the current unix nano timestamp to a string for embedding in the grpc metadata
 	nowTS := strconv.FormatInt(ci.clk.Now().UnixNano(), 10)
 
+	// Start a span covering the RPC from the client's point of view
+	localCtx, span := trace.Start(localCtx, fullMethod, trace.KindClient)
+	defer span.End()
+
 	// Create a grpc/metadata.Metadata instance for the request metadata.
-	// Initialize it with the request time.
+	// Initialize it with the request time and, if tracing, the span to
+	// continue the trace from.
 	reqMD := metadata.New(map[string]string{clientRequestTimeKey: nowTS})
+	if sc := span.Context(); sc.IsValid() {
+		reqMD.Set(trace.TraceparentKey, sc.Traceparent())
+	}
 	// Configure the localCtx with the metadata so it gets sent along in the request
 	localCtx = metadata.NewOutgoingContext(localCtx, reqMD)
 
-	// Create a grpc/metadata.Metadata instance for a grpc.Trailer.
-	respMD := metadata.New(nil)
-	// Configure a grpc Trailer with respMD. This allows us to wrap error
-	// types in the server interceptor later on.
-	opts = append(opts, grpc.Trailer(&respMD))
-
 	// Split the method and service name from the fullMethod.
 	// UnaryClientInterceptor's receive a `method` arg of the form
 	// "/ServiceName/MethodName"
@@ -177,9 +226,37 @@ func (ci *clientInterceptor) intercept(
 	// And defer decrementing it when we're done
 	defer ci.metrics.inFlightRPCs.With(labels).Dec()
 	// Handle the RPC
-	err := ci.metrics.grpcMetrics.UnaryClientInterceptor()(localCtx, fullMethod, req, reply, cc, invoker, opts...)
+	attempt := func(ctx context.Context, reply interface{}) (metadata.MD, error) {
+		// Create a grpc/metadata.Metadata instance for a grpc.Trailer.
+		respMD := metadata.New(nil)
+		// Configure a grpc Trailer with respMD. This allows us to wrap error
+		// types in the server interceptor later on.
+		attemptOpts := append(opts[:len(opts):len(opts)], grpc.Trailer(&respMD))
+		err := ci.metrics.grpcMetrics.UnaryClientInterceptor()(ctx, fullMethod, req, reply, cc, invoker, attemptOpts...)
+		return respMD, err
+	}
+	var respMD metadata.MD
+	var err error
+	if policy := ci.retries.forMethod(fullMethod); policy != nil {
+		var attempts int
+		respMD, attempts, err = policy.invoke(localCtx, reply, attempt)
+		span.SetAttribute("attempts", strconv.Itoa(attempts))
+		if attempts > 1 && ci.metrics.retries != nil {
+			ci.metrics.retries.With(labels).Add(float64(attempts - 1))
+		}
+	} else {
+		respMD, err = attempt(localCtx, reply)
+	}
+	if err != nil && localCtx.Err() == context.DeadlineExceeded && ci.metrics.deadlineExceeded != nil {
+		ci.metrics.deadlineExceeded.With(prometheus.Labels{
+			"method":  method,
+			"service": service,
+			"budget":  budget,
+		}).Inc()
+	}
 	if err != nil {
 		err = unwrapError(err, respMD)
+		span.SetError(err)
 	}
 	return err
 }
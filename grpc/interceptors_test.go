@@ -343,3 +343,47 @@ func TestInFlightRPCStat(t *testing.T) {
 	// What a ~ ~ Chill Sitch ~ ~
 	test.AssertEquals(t, inFlightCount, 0)
 }
+
+func TestDeadlineBudgets(t *testing.T) {
+	ci := clientInterceptor{
+		timeout: time.Second,
+		metrics: NewClientMetrics(metrics.NewNoopScope()),
+		clk:     clock.NewFake(),
+	}
+	slowInvoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		<-ctx.Done()
+		return grpc.Errorf(codes.DeadlineExceeded, "too slow")
+	}
+
+	// The caller's deadline is shorter than the client's timeout, so the
+	// deadline is inherited
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := ci.intercept(ctx, "/service/inherited", nil, nil, nil, slowInvoker)
+	test.AssertError(t, err, "slow RPC didn't fail")
+	test.AssertEquals(t, test.CountCounter(ci.metrics.deadlineExceeded.With(prometheus.Labels{"service": "service", "method": "inherited", "budget": "inherited"})), 1)
+
+	ci.timeout = 10 * time.Millisecond
+	err = ci.intercept(context.Background(), "/service/client", nil, nil, nil, slowInvoker)
+	test.AssertError(t, err, "slow RPC didn't fail")
+	test.AssertEquals(t, test.CountCounter(ci.metrics.deadlineExceeded.With(prometheus.Labels{"service": "service", "method": "client", "budget": "client"})), 1)
+
+	// A server receiving an RPC with too little time left rejects it
+	si := newServerInterceptor(NewServerMetrics(metrics.NewNoopScope()), clock.NewFake())
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = si.intercept(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/service/method"}, testHandler)
+	test.AssertError(t, err, "RPC without enough time left wasn't rejected")
+	test.AssertEquals(t, test.CountCounter(si.metrics.deadlineExceeded.With(prometheus.Labels{"service": "service", "method": "method", "stage": "arrival"})), 1)
+
+	// A handler that runs out of time is counted too
+	slowHandler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = si.intercept(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/service/method"}, slowHandler)
+	test.AssertError(t, err, "slow handler didn't fail")
+	test.AssertEquals(t, test.CountCounter(si.metrics.deadlineExceeded.With(prometheus.Labels{"service": "service", "method": "method", "stage": "handler"})), 1)
+}
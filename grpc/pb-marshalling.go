@@ -16,6 +16,7 @@ import (
 	"github.com/letsencrypt/boulder/core"
 	corepb "github.com/letsencrypt/boulder/core/proto"
 	"github.com/letsencrypt/boulder/probs"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
 	vapb "github.com/letsencrypt/boulder/va/proto"
 )
 
@@ -108,6 +109,7 @@ func ChallengeToPB(challenge core.Challenge) (*corepb.Challenge, error) {
 		KeyAuthorization:  &challenge.ProvidedKeyAuthorization,
 		Error:             prob,
 		Validationrecords: recordAry,
+		Attempts:          &challenge.Attempts,
 	}, nil
 }
 
@@ -140,6 +142,7 @@ func pbToChallenge(in *corepb.Challenge) (challenge core.Challenge, err error) {
 		ProvidedKeyAuthorization: *in.KeyAuthorization,
 		Error:                    prob,
 		ValidationRecord:         recordAry,
+		Attempts:                 in.GetAttempts(),
 	}, nil
 }
 
@@ -199,6 +202,33 @@ func PBToValidationRecord(in *corepb.ValidationRecord) (record core.ValidationRe
 	}, nil
 }
 
+func problemsToPB(problems []*probs.ProblemDetails) ([]*corepb.ProblemDetails, error) {
+	pbProblems := make([]*corepb.ProblemDetails, 0, len(problems))
+	for _, prob := range problems {
+		pbProb, err := ProblemDetailsToPB(prob)
+		if err != nil {
+			return nil, err
+		}
+		pbProblems = append(pbProblems, pbProb)
+	}
+	return pbProblems, nil
+}
+
+func pbToProblems(in []*corepb.ProblemDetails) ([]*probs.ProblemDetails, error) {
+	problems := make([]*probs.ProblemDetails, 0, len(in))
+	for _, pbProb := range in {
+		prob, err := PBToProblemDetails(pbProb)
+		if err != nil {
+			return nil, err
+		}
+		if prob == nil {
+			return nil, ErrMissingParameters
+		}
+		problems = append(problems, prob)
+	}
+	return problems, nil
+}
+
 func ValidationResultToPB(records []core.ValidationRecord, prob *probs.ProblemDetails) (*vapb.ValidationResult, error) {
 	recordAry := make([]*corepb.ValidationRecord, len(records))
 	var err error
@@ -362,14 +392,15 @@ func AuthzToPB(authz core.Authorization) (*corepb.Authorization, error) {
 		expires = authz.Expires.UTC().UnixNano()
 	}
 	return &corepb.Authorization{
-		Id:             &authz.ID,
-		Identifier:     &authz.Identifier.Value,
-		RegistrationID: &authz.RegistrationID,
-		Status:         &status,
-		Expires:        &expires,
-		Challenges:     challs,
-		Combinations:   comboBytes,
-		V2:             &authz.V2,
+		Id:               &authz.ID,
+		Identifier:       &authz.Identifier.Value,
+		RegistrationID:   &authz.RegistrationID,
+		Status:           &status,
+		Expires:          &expires,
+		Challenges:       challs,
+		Combinations:     comboBytes,
+		V2:               &authz.V2,
+		ValidationMethod: &authz.ValidationMethod,
 	}, nil
 }
 
@@ -404,6 +435,9 @@ func PBToAuthz(pb *corepb.Authorization) (core.Authorization, error) {
 	if pb.Id != nil {
 		authz.ID = *pb.Id
 	}
+	if pb.ValidationMethod != nil {
+		authz.ValidationMethod = *pb.ValidationMethod
+	}
 	return authz, nil
 }
 
@@ -433,6 +467,25 @@ func authorizationValid(authz *corepb.Authorization) bool {
 	return !(authz.Id == nil || authz.Identifier == nil || authz.RegistrationID == nil || authz.Status == nil || authz.Expires == nil)
 }
 
+// rateLimitOverrideValid checks that the fields needed to apply an override
+// are present.
+func rateLimitOverrideValid(o *sapb.RateLimitOverride) bool {
+	return !(o.Id == nil || o.LimitName == nil || o.Key == nil || o.RegistrationID == nil ||
+		o.Threshold == nil || o.Expires == nil)
+}
+
+// challengeWhitelistEntryValid checks that the fields needed to apply a
+// challenge whitelist entry are present.
+func challengeWhitelistEntryValid(e *sapb.ChallengeWhitelistEntry) bool {
+	return !(e.Id == nil || e.ChallengeType == nil || e.RegistrationID == nil || e.Expires == nil)
+}
+
+// dns01RecheckValid checks that the fields needed to resume a DNS-01 recheck
+// are present.
+func dns01RecheckValid(r *sapb.DNS01Recheck) bool {
+	return !(r.AuthzID == nil || r.ChallengeID == nil || r.Deadline == nil || r.LeaseExpires == nil || r.Claim == nil)
+}
+
 func certToPB(cert core.Certificate) *corepb.Certificate {
 	issued, expires := cert.Issued.UnixNano(), cert.Expires.UnixNano()
 	return &corepb.Certificate{
@@ -104,6 +104,7 @@ func TestChallenge(t *testing.T) {
 		Status:                   core.StatusPending,
 		Token:                    "asd",
 		ProvidedKeyAuthorization: "keyauth",
+		Attempts:                 2,
 	}
 
 	pb, err := ChallengeToPB(chall)
@@ -299,6 +300,14 @@ func TestAuthz(t *testing.T) {
 	outAuthz, err := PBToAuthz(pbAuthz)
 	test.AssertNotError(t, err, "pbToAuthz failed")
 	test.AssertDeepEquals(t, inAuthz, outAuthz)
+
+	inAuthz.Status = core.StatusValid
+	inAuthz.ValidationMethod = core.ChallengeTypeDNS01
+	pbAuthz, err = AuthzToPB(inAuthz)
+	test.AssertNotError(t, err, "AuthzToPB failed")
+	outAuthz, err = PBToAuthz(pbAuthz)
+	test.AssertNotError(t, err, "pbToAuthz failed")
+	test.AssertDeepEquals(t, inAuthz, outAuthz)
 }
 
 func TestCert(t *testing.T) {
@@ -13,6 +13,7 @@ import (
 
 	"github.com/letsencrypt/boulder/core"
 	corepb "github.com/letsencrypt/boulder/core/proto"
+	"github.com/letsencrypt/boulder/probs"
 	rapb "github.com/letsencrypt/boulder/ra/proto"
 	"github.com/letsencrypt/boulder/revocation"
 )
@@ -187,6 +188,30 @@ func (ras *RegistrationAuthorityClientWrapper) FinalizeOrder(ctx context.Context
 	return resp, nil
 }
 
+func (ras *RegistrationAuthorityClientWrapper) CheckIssuance(ctx context.Context, request *rapb.CheckIssuanceRequest) ([]*probs.ProblemDetails, error) {
+	resp, err := ras.inner.CheckIssuance(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return pbToProblems(resp.Problems)
+}
+
+func (ras *RegistrationAuthorityClientWrapper) RateLimitUsage(ctx context.Context, request *rapb.RateLimitUsageRequest) (*rapb.RateLimitUsageResponse, error) {
+	resp, err := ras.inner.RateLimitUsage(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errIncompleteResponse
+	}
+	for _, u := range resp.Usages {
+		if u.Limit == nil || u.Count == nil || u.Threshold == nil || u.Window == nil {
+			return nil, errIncompleteResponse
+		}
+	}
+	return resp, nil
+}
+
 // RegistrationAuthorityServerWrapper is the gRPC version of a core.RegistrationAuthority server
 type RegistrationAuthorityServerWrapper struct {
 	inner core.RegistrationAuthority
@@ -343,3 +368,25 @@ func (ras *RegistrationAuthorityServerWrapper) FinalizeOrder(ctx context.Context
 
 	return ras.inner.FinalizeOrder(ctx, request)
 }
+
+func (ras *RegistrationAuthorityServerWrapper) CheckIssuance(ctx context.Context, request *rapb.CheckIssuanceRequest) (*rapb.CheckIssuanceResponse, error) {
+	if request == nil || request.RegistrationID == nil || request.Csr == nil {
+		return nil, errIncompleteRequest
+	}
+	problems, err := ras.inner.CheckIssuance(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	pbProblems, err := problemsToPB(problems)
+	if err != nil {
+		return nil, err
+	}
+	return &rapb.CheckIssuanceResponse{Problems: pbProblems}, nil
+}
+
+func (ras *RegistrationAuthorityServerWrapper) RateLimitUsage(ctx context.Context, request *rapb.RateLimitUsageRequest) (*rapb.RateLimitUsageResponse, error) {
+	if request == nil || request.RegistrationID == nil {
+		return nil, errIncompleteRequest
+	}
+	return ras.inner.RateLimitUsage(ctx, request)
+}
@@ -0,0 +1,41 @@
+package grpc
+
+import (
+	"strings"
+
+	"google.golang.org/grpc/resolver"
+)
+
+// staticScheme is the target scheme of clients configured with a static list
+// of backends. The endpoint of such a target is the comma separated list of
+// their host:port addresses, e.g. "static:///ra1:9094,ra2:9094".
+const staticScheme = "static"
+
+func init() {
+	resolver.Register(staticBuilder{})
+}
+
+// staticBuilder builds resolvers that always resolve a target to the list of
+// addresses it contains.
+type staticBuilder struct{}
+
+func (staticBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOption) (resolver.Resolver, error) {
+	var addrs []resolver.Address
+	for _, addr := range strings.Split(target.Endpoint, ",") {
+		if addr != "" {
+			addrs = append(addrs, resolver.Address{Addr: addr})
+		}
+	}
+	cc.NewAddress(addrs)
+	return staticTargetResolver{}, nil
+}
+
+func (staticBuilder) Scheme() string {
+	return staticScheme
+}
+
+type staticTargetResolver struct{}
+
+func (staticTargetResolver) ResolveNow(resolver.ResolveNowOption) {}
+
+func (staticTargetResolver) Close() {}
@@ -0,0 +1,49 @@
+package grpc
+
+import (
+	"net"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"github.com/letsencrypt/boulder/grpc/test_proto"
+	"github.com/letsencrypt/boulder/test"
+)
+
+// idServer responds to every Chill with its ID.
+type idServer struct {
+	id int64
+}
+
+func (s *idServer) Chill(context.Context, *test_proto.Time) (*test_proto.Time, error) {
+	return &test_proto.Time{Time: &s.id}, nil
+}
+
+func TestStaticTarget(t *testing.T) {
+	var addrs []string
+	for i := int64(0); i < 2; i++ {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		test.AssertNotError(t, err, "listening")
+		s := grpc.NewServer()
+		test_proto.RegisterChillerServer(s, &idServer{id: i})
+		go func() { _ = s.Serve(lis) }()
+		defer s.Stop()
+		addrs = append(addrs, lis.Addr().String())
+	}
+
+	conn, err := grpc.Dial(staticScheme+":///"+addrs[0]+","+addrs[1],
+		grpc.WithInsecure(),
+		grpc.WithBalancerName("round_robin"))
+	test.AssertNotError(t, err, "dialing")
+	defer func() { _ = conn.Close() }()
+	c := test_proto.NewChillerClient(conn)
+
+	seen := make(map[int64]bool)
+	for i := 0; i < 1000 && len(seen) < 2; i++ {
+		resp, err := c.Chill(context.Background(), &test_proto.Time{}, grpc.FailFast(false))
+		test.AssertNotError(t, err, "Chill failed")
+		seen[resp.GetTime()] = true
+	}
+	test.AssertEquals(t, len(seen), 2)
+}
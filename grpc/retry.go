@@ -0,0 +1,182 @@
+package grpc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/core"
+)
+
+// retryPolicy is the parsed form of a cmd.GRPCRetryPolicy.
+type retryPolicy struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	hedgingDelay   time.Duration
+	retryable      map[codes.Code]bool
+}
+
+// parseCode parses the name of a gRPC status code, in either the form used by
+// the gRPC specification (e.g. "DEADLINE_EXCEEDED") or by grpc-go (e.g.
+// "DeadlineExceeded").
+func parseCode(name string) (codes.Code, error) {
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		if strings.EqualFold(strings.Replace(name, "_", "", -1), c.String()) {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown gRPC status code %q", name)
+}
+
+func newRetryPolicy(c cmd.GRPCRetryPolicy) (*retryPolicy, error) {
+	if c.MaxAttempts < 1 {
+		return nil, fmt.Errorf("MaxAttempts must be at least 1, got %d", c.MaxAttempts)
+	}
+	p := &retryPolicy{
+		maxAttempts:    c.MaxAttempts,
+		initialBackoff: c.InitialBackoff.Duration,
+		maxBackoff:     c.MaxBackoff.Duration,
+		hedgingDelay:   c.HedgingDelay.Duration,
+		retryable:      map[codes.Code]bool{codes.Unavailable: true},
+	}
+	if p.maxBackoff < p.initialBackoff {
+		p.maxBackoff = p.initialBackoff
+	}
+	if len(c.RetryableStatusCodes) > 0 {
+		p.retryable = make(map[codes.Code]bool)
+		for _, name := range c.RetryableStatusCodes {
+			code, err := parseCode(name)
+			if err != nil {
+				return nil, err
+			}
+			p.retryable[code] = true
+		}
+	}
+	return p, nil
+}
+
+// retryPolicies maps service and method names, without leading slash, to
+// their retry policies.
+type retryPolicies map[string]*retryPolicy
+
+func newRetryPolicies(c map[string]cmd.GRPCRetryPolicy) (retryPolicies, error) {
+	policies := make(retryPolicies, len(c))
+	for name, pc := range c {
+		p, err := newRetryPolicy(pc)
+		if err != nil {
+			return nil, fmt.Errorf("retry policy for %q: %s", name, err)
+		}
+		policies[strings.TrimPrefix(name, "/")] = p
+	}
+	return policies, nil
+}
+
+// forMethod returns the retry policy for a full method name of the form
+// "/service/method", or nil if it shouldn't be retried.
+func (rp retryPolicies) forMethod(fullMethod string) *retryPolicy {
+	name := strings.TrimPrefix(fullMethod, "/")
+	if p, ok := rp[name]; ok {
+		return p
+	}
+	service, _ := splitMethodName(fullMethod)
+	return rp[service]
+}
+
+// attemptFunc makes a single attempt at an RPC, storing the response in reply
+// and returning the trailer metadata received along with any error.
+type attemptFunc func(ctx context.Context, reply interface{}) (metadata.MD, error)
+
+type attemptResult struct {
+	reply interface{}
+	md    metadata.MD
+	err   error
+}
+
+// shouldRetry returns true if another attempt is allowed after one failed
+// with err.
+func (p *retryPolicy) shouldRetry(err error) bool {
+	return err != nil && p.retryable[status.Code(err)]
+}
+
+// invoke calls attempt until it succeeds, fails with a status code that isn't
+// retryable, or the policy's attempts are exhausted, returning the trailer
+// metadata and error of the last attempt and the number of attempts made.
+func (p *retryPolicy) invoke(ctx context.Context, reply interface{}, attempt attemptFunc) (metadata.MD, int, error) {
+	if p.hedgingDelay > 0 {
+		return p.hedge(ctx, reply, attempt)
+	}
+	for attempts := 1; ; attempts++ {
+		md, err := attempt(ctx, reply)
+		if attempts >= p.maxAttempts || !p.shouldRetry(err) {
+			return md, attempts, err
+		}
+		backoff := core.RetryBackoff(attempts, p.initialBackoff, p.maxBackoff, 2)
+		select {
+		case <-ctx.Done():
+			return md, attempts, err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// hedge starts a new attempt every hedgingDelay, or as soon as an attempt
+// fails with a retryable status code, until one succeeds or fails with a
+// status code that isn't retryable. Each attempt receives its response in a
+// copy of reply, so reply must be a proto.Message.
+func (p *retryPolicy) hedge(ctx context.Context, reply interface{}, attempt attemptFunc) (metadata.MD, int, error) {
+	msg, ok := reply.(proto.Message)
+	if !ok {
+		md, err := attempt(ctx, reply)
+		return md, 1, err
+	}
+	// Cancel the attempts still in flight once a result has been chosen.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan attemptResult, p.maxAttempts)
+	start := func() {
+		attemptReply := proto.Clone(msg)
+		go func() {
+			md, err := attempt(ctx, attemptReply)
+			results <- attemptResult{attemptReply, md, err}
+		}()
+	}
+
+	started, finished := 1, 0
+	start()
+	timer := time.NewTimer(p.hedgingDelay)
+	defer timer.Stop()
+	var last attemptResult
+	for {
+		select {
+		case <-timer.C:
+			if started < p.maxAttempts {
+				started++
+				start()
+				timer.Reset(p.hedgingDelay)
+			}
+		case last = <-results:
+			finished++
+			if !p.shouldRetry(last.err) {
+				if last.err == nil {
+					msg.Reset()
+					proto.Merge(msg, last.reply.(proto.Message))
+				}
+				return last.md, started, last.err
+			}
+			if started < p.maxAttempts {
+				started++
+				start()
+			} else if finished == started {
+				return last.md, started, last.err
+			}
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package grpc
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/status"
+
+	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/grpc/test_proto"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestNewRetryPolicies(t *testing.T) {
+	policies, err := newRetryPolicies(map[string]cmd.GRPCRetryPolicy{
+		"sa.StorageAuthority":                  {MaxAttempts: 2},
+		"/sa.StorageAuthority/GetRegistration": {MaxAttempts: 3, RetryableStatusCodes: []string{"DEADLINE_EXCEEDED", "Unavailable"}},
+	})
+	test.AssertNotError(t, err, "newRetryPolicies failed")
+	test.AssertEquals(t, policies.forMethod("/sa.StorageAuthority/GetRegistration").maxAttempts, 3)
+	test.AssertEquals(t, policies.forMethod("/sa.StorageAuthority/GetOrder").maxAttempts, 2)
+	test.Assert(t, policies.forMethod("/ra.RegistrationAuthority/NewOrder") == nil, "unconfigured method has a retry policy")
+	p := policies.forMethod("/sa.StorageAuthority/GetRegistration")
+	test.Assert(t, p.retryable[codes.DeadlineExceeded] && p.retryable[codes.Unavailable], "retryable codes not parsed")
+
+	_, err = newRetryPolicies(map[string]cmd.GRPCRetryPolicy{"sa.StorageAuthority": {}})
+	test.AssertError(t, err, "newRetryPolicies accepted a policy without attempts")
+	_, err = newRetryPolicies(map[string]cmd.GRPCRetryPolicy{
+		"sa.StorageAuthority": {MaxAttempts: 2, RetryableStatusCodes: []string{"NOPE"}},
+	})
+	test.AssertError(t, err, "newRetryPolicies accepted an unknown status code")
+}
+
+// failingAttempts returns an attemptFunc whose first failures attempts fail
+// with code, and whose later attempts succeed, setting the reply's time to
+// the attempt number.
+func failingAttempts(failures int, code codes.Code) (attemptFunc, *int) {
+	var mu sync.Mutex
+	attempts := 0
+	return func(ctx context.Context, reply interface{}) (metadata.MD, error) {
+		mu.Lock()
+		attempts++
+		n := attempts
+		mu.Unlock()
+		if n <= failures {
+			return nil, status.Error(code, fmt.Sprintf("attempt %d failed", n))
+		}
+		reply.(*test_proto.Time).Time = proto.Int64(int64(n))
+		return nil, nil
+	}, &attempts
+}
+
+func TestRetryPolicyInvoke(t *testing.T) {
+	p, err := newRetryPolicy(cmd.GRPCRetryPolicy{MaxAttempts: 3, InitialBackoff: cmd.ConfigDuration{Duration: time.Millisecond}})
+	test.AssertNotError(t, err, "newRetryPolicy failed")
+
+	attempt, _ := failingAttempts(2, codes.Unavailable)
+	reply := &test_proto.Time{}
+	_, attempts, err := p.invoke(context.Background(), reply, attempt)
+	test.AssertNotError(t, err, "invoke failed")
+	test.AssertEquals(t, attempts, 3)
+	test.AssertEquals(t, reply.GetTime(), int64(3))
+
+	attempt, _ = failingAttempts(3, codes.Unavailable)
+	_, attempts, err = p.invoke(context.Background(), &test_proto.Time{}, attempt)
+	test.AssertError(t, err, "invoke succeeded after all attempts failed")
+	test.AssertEquals(t, attempts, 3)
+
+	attempt, _ = failingAttempts(1, codes.InvalidArgument)
+	_, attempts, err = p.invoke(context.Background(), &test_proto.Time{}, attempt)
+	test.AssertError(t, err, "invoke retried a status code that isn't retryable")
+	test.AssertEquals(t, attempts, 1)
+}
+
+func TestRetryPolicyHedge(t *testing.T) {
+	p, err := newRetryPolicy(cmd.GRPCRetryPolicy{MaxAttempts: 3, HedgingDelay: cmd.ConfigDuration{Duration: 10 * time.Millisecond}})
+	test.AssertNotError(t, err, "newRetryPolicy failed")
+
+	// The first attempt hangs until cancelled, so the hedged second attempt
+	// provides the response.
+	release := make(chan struct{})
+	var mu sync.Mutex
+	calls := 0
+	attempt := func(ctx context.Context, reply interface{}) (metadata.MD, error) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+		if n == 1 {
+			<-ctx.Done()
+			close(release)
+			return nil, ctx.Err()
+		}
+		reply.(*test_proto.Time).Time = proto.Int64(int64(n))
+		return nil, nil
+	}
+	reply := &test_proto.Time{}
+	_, attempts, err := p.invoke(context.Background(), reply, attempt)
+	test.AssertNotError(t, err, "invoke failed")
+	test.AssertEquals(t, attempts, 2)
+	test.AssertEquals(t, reply.GetTime(), int64(2))
+	// The hanging attempt is cancelled once the hedged one succeeds
+	<-release
+
+	// Attempts that fail with a retryable code are hedged immediately
+	failing, count := failingAttempts(3, codes.Unavailable)
+	_, attempts, err = p.invoke(context.Background(), &test_proto.Time{}, failing)
+	test.AssertError(t, err, "invoke succeeded after all attempts failed")
+	test.AssertEquals(t, attempts, 3)
+	test.AssertEquals(t, *count, 3)
+}
+
+type fakeSubConn struct {
+	addr string
+}
+
+func (*fakeSubConn) UpdateAddresses([]resolver.Address) {}
+func (*fakeSubConn) Connect()                           {}
+
+func TestSubsetPicker(t *testing.T) {
+	ready := make(map[resolver.Address]balancer.SubConn)
+	for i := 0; i < 5; i++ {
+		addr := resolver.Address{Addr: fmt.Sprintf("10.0.0.%d:9090", i)}
+		ready[addr] = &fakeSubConn{addr.Addr}
+	}
+	pb := subsetPickerBuilder{size: 2, seed: 1}
+
+	picked := func(p balancer.Picker) map[string]bool {
+		seen := make(map[string]bool)
+		for i := 0; i < 10; i++ {
+			sc, _, err := p.Pick(context.Background(), balancer.PickOptions{})
+			test.AssertNotError(t, err, "Pick failed")
+			seen[sc.(*fakeSubConn).addr] = true
+		}
+		return seen
+	}
+	subset := picked(pb.Build(ready))
+	test.AssertEquals(t, len(subset), 2)
+
+	// When a backend in the subset isn't ready any more, only it is replaced
+	var gone string
+	for addr := range subset {
+		gone = addr
+		delete(ready, resolver.Address{Addr: addr})
+		break
+	}
+	newSubset := picked(pb.Build(ready))
+	test.AssertEquals(t, len(newSubset), 2)
+	test.Assert(t, !newSubset[gone], "subset contains a backend that isn't ready")
+	for addr := range subset {
+		if addr != gone {
+			test.Assert(t, newSubset[addr], "ready backend was removed from the subset")
+		}
+	}
+
+	_, _, err := pb.Build(nil).Pick(context.Background(), balancer.PickOptions{})
+	test.AssertEquals(t, err, balancer.ErrNoSubConnAvailable)
+	test.AssertEquals(t, subsetBalancerName(2), subsetBalancerName(2))
+}
+
+// fakeClientConn is a balancer.ClientConn that keeps track of the SubConns
+// created by a balancer.
+type fakeClientConn struct {
+	balancer.ClientConn
+	subConns map[string]balancer.SubConn
+	created  int
+}
+
+func (cc *fakeClientConn) NewSubConn(addrs []resolver.Address, _ balancer.NewSubConnOptions) (balancer.SubConn, error) {
+	sc := &fakeSubConn{addrs[0].Addr}
+	cc.subConns[sc.addr] = sc
+	cc.created++
+	return sc, nil
+}
+
+func (cc *fakeClientConn) RemoveSubConn(sc balancer.SubConn) {
+	delete(cc.subConns, sc.(*fakeSubConn).addr)
+}
+
+func (cc *fakeClientConn) UpdateBalancerState(connectivity.State, balancer.Picker) {}
+
+func TestSubsetBalancer(t *testing.T) {
+	pb := subsetPickerBuilder{size: 2, seed: 1}
+	cc := &fakeClientConn{subConns: make(map[string]balancer.SubConn)}
+	b := subsetBuilder{name: "test", pb: pb, base: base.NewBalancerBuilder("test", pb)}.Build(cc, balancer.BuildOptions{})
+	var addrs []resolver.Address
+	for i := 0; i < 5; i++ {
+		addrs = append(addrs, resolver.Address{Addr: fmt.Sprintf("10.0.0.%d:9090", i)})
+	}
+	b.HandleResolvedAddrs(addrs, nil)
+	// Only the subset is connected to
+	test.AssertEquals(t, cc.created, 2)
+	test.AssertEquals(t, len(cc.subConns), 2)
+	subset := make(map[string]bool)
+	for addr, sc := range cc.subConns {
+		subset[addr] = true
+		b.HandleSubConnStateChange(sc, connectivity.Ready)
+	}
+
+	// When the connection to a backend in the subset fails, only it is
+	// replaced by another backend
+	var gone string
+	for addr := range subset {
+		gone = addr
+		break
+	}
+	b.HandleSubConnStateChange(cc.subConns[gone], connectivity.TransientFailure)
+	test.AssertEquals(t, cc.created, 3)
+	test.AssertEquals(t, len(cc.subConns), 2)
+	test.Assert(t, cc.subConns[gone] == nil, "failed backend is still connected to")
+	for addr := range subset {
+		if addr != gone {
+			test.Assert(t, cc.subConns[addr] != nil, "backend still in the subset was disconnected")
+		}
+	}
+
+	// Failed backends are still used when there aren't enough other ones
+	b.HandleResolvedAddrs(addrs[:2], nil)
+	test.AssertEquals(t, len(cc.subConns), 2)
+	created := cc.created
+	b.HandleSubConnStateChange(cc.subConns[addrs[0].Addr], connectivity.TransientFailure)
+	test.AssertEquals(t, len(cc.subConns), 2)
+	test.AssertEquals(t, cc.created, created)
+}
@@ -305,6 +305,17 @@ func (sac StorageAuthorityClientWrapper) PreviousCertificateExists(
 	return exists, err
 }
 
+func (sac StorageAuthorityClientWrapper) CertificateReplaced(ctx context.Context, req *sapb.Serial) (*sapb.Exists, error) {
+	replaced, err := sac.inner.CertificateReplaced(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if replaced == nil || replaced.Exists == nil {
+		return nil, errIncompleteResponse
+	}
+	return replaced, nil
+}
+
 func (sac StorageAuthorityClientWrapper) FQDNSetExists(ctx context.Context, domains []string) (bool, error) {
 	response, err := sac.inner.FQDNSetExists(ctx, &sapb.FQDNSetExistsRequest{Domains: domains})
 	if err != nil {
@@ -468,6 +479,10 @@ func (sac StorageAuthorityClientWrapper) DeactivateAuthorization(ctx context.Con
 	return nil
 }
 
+func (sac StorageAuthorityClientWrapper) DeactivateAuthorization2(ctx context.Context, req *sapb.AuthorizationID2) (*corepb.Empty, error) {
+	return sac.inner.DeactivateAuthorization2(ctx, req)
+}
+
 func (sas StorageAuthorityClientWrapper) NewOrder(ctx context.Context, request *corepb.Order) (*corepb.Order, error) {
 	resp, err := sas.inner.NewOrder(ctx, request)
 	if err != nil {
@@ -555,6 +570,31 @@ func (sas StorageAuthorityClientWrapper) GetValidOrderAuthorizations(
 	return auths, nil
 }
 
+func (sas StorageAuthorityClientWrapper) GetOrderAuthorizations(
+	ctx context.Context,
+	request *sapb.GetOrderAuthorizationsRequest) (map[string]*core.Authorization, error) {
+	resp, err := sas.inner.GetOrderAuthorizations(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errIncompleteResponse
+	}
+
+	auths := make(map[string]*core.Authorization, len(resp.Authz))
+	for _, element := range resp.Authz {
+		if element == nil || element.Domain == nil || !authorizationValid(element.Authz) {
+			return nil, errIncompleteResponse
+		}
+		authz, err := PBToAuthz(element.Authz)
+		if err != nil {
+			return nil, err
+		}
+		auths[*element.Domain] = &authz
+	}
+	return auths, nil
+}
+
 func (sas StorageAuthorityClientWrapper) GetAuthorizations(ctx context.Context, req *sapb.GetAuthorizationsRequest) (*sapb.Authorizations, error) {
 	resp, err := sas.inner.GetAuthorizations(ctx, req)
 	if err != nil {
@@ -598,6 +638,176 @@ func (sas StorageAuthorityClientWrapper) RevokeCertificate(ctx context.Context,
 	return err
 }
 
+func (sas StorageAuthorityClientWrapper) GetRateLimitOverrides(ctx context.Context, req *sapb.GetRateLimitOverridesRequest) (*sapb.RateLimitOverrides, error) {
+	resp, err := sas.inner.GetRateLimitOverrides(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errIncompleteResponse
+	}
+	for _, o := range resp.Overrides {
+		if !rateLimitOverrideValid(o) {
+			return nil, errIncompleteResponse
+		}
+	}
+	return resp, nil
+}
+
+func (sas StorageAuthorityClientWrapper) SearchCertificates(ctx context.Context, req *sapb.SearchCertificatesRequest) (*sapb.CertificateSearchResults, error) {
+	resp, err := sas.inner.SearchCertificates(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errIncompleteResponse
+	}
+	for _, c := range resp.Certificates {
+		if c.Serial == nil || c.RegistrationID == nil || c.Issued == nil || c.Expires == nil || c.Status == nil {
+			return nil, errIncompleteResponse
+		}
+	}
+	return resp, nil
+}
+
+func (sas StorageAuthorityClientWrapper) ListCertificatesForAccount(ctx context.Context, req *sapb.ListCertificatesForAccountRequest) (*sapb.CertificateSearchResults, error) {
+	resp, err := sas.inner.ListCertificatesForAccount(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errIncompleteResponse
+	}
+	for _, c := range resp.Certificates {
+		if c.Serial == nil || c.RegistrationID == nil || c.Issued == nil || c.Expires == nil || c.Status == nil {
+			return nil, errIncompleteResponse
+		}
+	}
+	return resp, nil
+}
+
+func (sas StorageAuthorityClientWrapper) ListOrdersForAccount(ctx context.Context, req *sapb.ListOrdersForAccountRequest) (*sapb.OrderList, error) {
+	resp, err := sas.inner.ListOrdersForAccount(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errIncompleteResponse
+	}
+	for _, order := range resp.Orders {
+		if !orderValid(order) {
+			return nil, errIncompleteResponse
+		}
+	}
+	return resp, nil
+}
+
+func (sas StorageAuthorityClientWrapper) ListInvalidAuthorizationsForAccount(ctx context.Context, req *sapb.ListInvalidAuthorizationsForAccountRequest) (*sapb.AuthorizationList, error) {
+	resp, err := sas.inner.ListInvalidAuthorizationsForAccount(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errIncompleteResponse
+	}
+	for _, authz := range resp.Authorizations {
+		if !authorizationValid(authz) {
+			return nil, errIncompleteResponse
+		}
+	}
+	return resp, nil
+}
+
+func (sas StorageAuthorityClientWrapper) AddRateLimitOverride(ctx context.Context, req *sapb.RateLimitOverride) (*sapb.RateLimitOverride, error) {
+	resp, err := sas.inner.AddRateLimitOverride(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || !rateLimitOverrideValid(resp) {
+		return nil, errIncompleteResponse
+	}
+	return resp, nil
+}
+
+func (sas StorageAuthorityClientWrapper) ExpireRateLimitOverride(ctx context.Context, req *sapb.ExpireRateLimitOverrideRequest) error {
+	_, err := sas.inner.ExpireRateLimitOverride(ctx, req)
+	return err
+}
+
+func (sas StorageAuthorityClientWrapper) GetChallengeWhitelist(ctx context.Context, req *sapb.GetChallengeWhitelistRequest) (*sapb.ChallengeWhitelistEntries, error) {
+	resp, err := sas.inner.GetChallengeWhitelist(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errIncompleteResponse
+	}
+	for _, e := range resp.Entries {
+		if !challengeWhitelistEntryValid(e) {
+			return nil, errIncompleteResponse
+		}
+	}
+	return resp, nil
+}
+
+func (sas StorageAuthorityClientWrapper) AddChallengeWhitelistEntry(ctx context.Context, req *sapb.ChallengeWhitelistEntry) (*sapb.ChallengeWhitelistEntry, error) {
+	resp, err := sas.inner.AddChallengeWhitelistEntry(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || !challengeWhitelistEntryValid(resp) {
+		return nil, errIncompleteResponse
+	}
+	return resp, nil
+}
+
+func (sas StorageAuthorityClientWrapper) RemoveChallengeWhitelistEntry(ctx context.Context, req *sapb.RemoveChallengeWhitelistEntryRequest) error {
+	_, err := sas.inner.RemoveChallengeWhitelistEntry(ctx, req)
+	return err
+}
+
+func (sas StorageAuthorityClientWrapper) GetStaleDNS01Rechecks(ctx context.Context, req *sapb.GetStaleDNS01RechecksRequest) (*sapb.DNS01Rechecks, error) {
+	resp, err := sas.inner.GetStaleDNS01Rechecks(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errIncompleteResponse
+	}
+	for _, r := range resp.Rechecks {
+		if !dns01RecheckValid(r) {
+			return nil, errIncompleteResponse
+		}
+	}
+	return resp, nil
+}
+
+func (sas StorageAuthorityClientWrapper) AddDNS01Recheck(ctx context.Context, req *sapb.DNS01Recheck) error {
+	_, err := sas.inner.AddDNS01Recheck(ctx, req)
+	return err
+}
+
+func (sas StorageAuthorityClientWrapper) ClaimDNS01Recheck(ctx context.Context, req *sapb.DNS01Recheck) (*sapb.Exists, error) {
+	resp, err := sas.inner.ClaimDNS01Recheck(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Exists == nil {
+		return nil, errIncompleteResponse
+	}
+	return resp, nil
+}
+
+func (sas StorageAuthorityClientWrapper) RemoveDNS01Recheck(ctx context.Context, req *sapb.AuthorizationID) error {
+	_, err := sas.inner.RemoveDNS01Recheck(ctx, req)
+	return err
+}
+
+func (sas StorageAuthorityClientWrapper) RetryChallenge(ctx context.Context, req *sapb.RetryChallengeRequest) error {
+	_, err := sas.inner.RetryChallenge(ctx, req)
+	return err
+}
+
 // StorageAuthorityServerWrapper is the gRPC version of a core.ServerAuthority server
 type StorageAuthorityServerWrapper struct {
 	// TODO(#3119): Don't use core.StorageAuthority
@@ -869,6 +1079,13 @@ func (sac StorageAuthorityServerWrapper) PreviousCertificateExists(
 	return sac.inner.PreviousCertificateExists(ctx, req)
 }
 
+func (sac StorageAuthorityServerWrapper) CertificateReplaced(ctx context.Context, req *sapb.Serial) (*sapb.Exists, error) {
+	if req == nil || req.Serial == nil {
+		return nil, errIncompleteRequest
+	}
+	return sac.inner.CertificateReplaced(ctx, req)
+}
+
 func (sas StorageAuthorityServerWrapper) NewRegistration(ctx context.Context, request *corepb.Registration) (*corepb.Registration, error) {
 	if request == nil || !registrationValid(request) {
 		return nil, errIncompleteRequest
@@ -1025,6 +1242,14 @@ func (sas StorageAuthorityServerWrapper) DeactivateAuthorization(ctx context.Con
 	return &corepb.Empty{}, nil
 }
 
+func (sas StorageAuthorityServerWrapper) DeactivateAuthorization2(ctx context.Context, request *sapb.AuthorizationID2) (*corepb.Empty, error) {
+	if request == nil || request.Id == nil {
+		return nil, errIncompleteRequest
+	}
+
+	return sas.inner.DeactivateAuthorization2(ctx, request)
+}
+
 func (sas StorageAuthorityServerWrapper) NewOrder(ctx context.Context, request *corepb.Order) (*corepb.Order, error) {
 	if request == nil || !newOrderValid(request) {
 		return nil, errIncompleteRequest
@@ -1112,6 +1337,32 @@ func (sas StorageAuthorityServerWrapper) GetValidOrderAuthorizations(
 	return resp, nil
 }
 
+func (sas StorageAuthorityServerWrapper) GetOrderAuthorizations(
+	ctx context.Context,
+	request *sapb.GetOrderAuthorizationsRequest) (*sapb.Authorizations, error) {
+	if request == nil || request.Id == nil || request.AcctID == nil {
+		return nil, errIncompleteRequest
+	}
+
+	authzs, err := sas.inner.GetOrderAuthorizations(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &sapb.Authorizations{}
+	for k, v := range authzs {
+		authzPB, err := AuthzToPB(*v)
+		if err != nil {
+			return nil, err
+		}
+		// Make a copy of k because it will be reassigned with each loop.
+		kCopy := k
+		resp.Authz = append(resp.Authz, &sapb.Authorizations_MapElement{Domain: &kCopy, Authz: authzPB})
+	}
+
+	return resp, nil
+}
+
 func (sas StorageAuthorityServerWrapper) GetAuthorizations(ctx context.Context, request *sapb.GetAuthorizationsRequest) (*sapb.Authorizations, error) {
 	if request == nil || request.RegistrationID == nil || request.Domains == nil || request.Now == nil || request.RequireV2Authzs == nil {
 		return nil, errIncompleteRequest
@@ -1142,3 +1393,108 @@ func (sas StorageAuthorityServerWrapper) RevokeCertificate(ctx context.Context,
 	}
 	return &corepb.Empty{}, sas.inner.RevokeCertificate(ctx, req)
 }
+
+func (sas StorageAuthorityServerWrapper) GetRateLimitOverrides(ctx context.Context, req *sapb.GetRateLimitOverridesRequest) (*sapb.RateLimitOverrides, error) {
+	if req == nil || req.Now == nil {
+		return nil, errIncompleteRequest
+	}
+	return sas.inner.GetRateLimitOverrides(ctx, req)
+}
+
+func (sas StorageAuthorityServerWrapper) SearchCertificates(ctx context.Context, req *sapb.SearchCertificatesRequest) (*sapb.CertificateSearchResults, error) {
+	if req == nil {
+		return nil, errIncompleteRequest
+	}
+	return sas.inner.SearchCertificates(ctx, req)
+}
+
+func (sas StorageAuthorityServerWrapper) ListCertificatesForAccount(ctx context.Context, req *sapb.ListCertificatesForAccountRequest) (*sapb.CertificateSearchResults, error) {
+	if req == nil || req.RegistrationID == nil {
+		return nil, errIncompleteRequest
+	}
+	return sas.inner.ListCertificatesForAccount(ctx, req)
+}
+
+func (sas StorageAuthorityServerWrapper) ListOrdersForAccount(ctx context.Context, req *sapb.ListOrdersForAccountRequest) (*sapb.OrderList, error) {
+	if req == nil || req.RegistrationID == nil {
+		return nil, errIncompleteRequest
+	}
+	return sas.inner.ListOrdersForAccount(ctx, req)
+}
+
+func (sas StorageAuthorityServerWrapper) ListInvalidAuthorizationsForAccount(ctx context.Context, req *sapb.ListInvalidAuthorizationsForAccountRequest) (*sapb.AuthorizationList, error) {
+	if req == nil || req.RegistrationID == nil || req.ExpiresAfter == nil {
+		return nil, errIncompleteRequest
+	}
+	return sas.inner.ListInvalidAuthorizationsForAccount(ctx, req)
+}
+
+func (sas StorageAuthorityServerWrapper) AddRateLimitOverride(ctx context.Context, req *sapb.RateLimitOverride) (*sapb.RateLimitOverride, error) {
+	if req == nil || req.LimitName == nil || req.Threshold == nil || req.CreatedBy == nil || req.Expires == nil || req.Reason == nil {
+		return nil, errIncompleteRequest
+	}
+	return sas.inner.AddRateLimitOverride(ctx, req)
+}
+
+func (sas StorageAuthorityServerWrapper) ExpireRateLimitOverride(ctx context.Context, req *sapb.ExpireRateLimitOverrideRequest) (*corepb.Empty, error) {
+	if req == nil || req.Id == nil || req.ExpiredBy == nil || req.Reason == nil {
+		return nil, errIncompleteRequest
+	}
+	return &corepb.Empty{}, sas.inner.ExpireRateLimitOverride(ctx, req)
+}
+
+func (sas StorageAuthorityServerWrapper) GetChallengeWhitelist(ctx context.Context, req *sapb.GetChallengeWhitelistRequest) (*sapb.ChallengeWhitelistEntries, error) {
+	if req == nil || req.Now == nil {
+		return nil, errIncompleteRequest
+	}
+	return sas.inner.GetChallengeWhitelist(ctx, req)
+}
+
+func (sas StorageAuthorityServerWrapper) AddChallengeWhitelistEntry(ctx context.Context, req *sapb.ChallengeWhitelistEntry) (*sapb.ChallengeWhitelistEntry, error) {
+	if req == nil || req.ChallengeType == nil || req.RegistrationID == nil || req.CreatedBy == nil || req.Reason == nil {
+		return nil, errIncompleteRequest
+	}
+	return sas.inner.AddChallengeWhitelistEntry(ctx, req)
+}
+
+func (sas StorageAuthorityServerWrapper) RemoveChallengeWhitelistEntry(ctx context.Context, req *sapb.RemoveChallengeWhitelistEntryRequest) (*corepb.Empty, error) {
+	if req == nil || req.ChallengeType == nil || req.RegistrationID == nil || req.RemovedBy == nil || req.Reason == nil {
+		return nil, errIncompleteRequest
+	}
+	return &corepb.Empty{}, sas.inner.RemoveChallengeWhitelistEntry(ctx, req)
+}
+
+func (sas StorageAuthorityServerWrapper) GetStaleDNS01Rechecks(ctx context.Context, req *sapb.GetStaleDNS01RechecksRequest) (*sapb.DNS01Rechecks, error) {
+	if req == nil || req.Now == nil {
+		return nil, errIncompleteRequest
+	}
+	return sas.inner.GetStaleDNS01Rechecks(ctx, req)
+}
+
+func (sas StorageAuthorityServerWrapper) AddDNS01Recheck(ctx context.Context, req *sapb.DNS01Recheck) (*corepb.Empty, error) {
+	if req == nil || !dns01RecheckValid(req) {
+		return nil, errIncompleteRequest
+	}
+	return &corepb.Empty{}, sas.inner.AddDNS01Recheck(ctx, req)
+}
+
+func (sas StorageAuthorityServerWrapper) ClaimDNS01Recheck(ctx context.Context, req *sapb.DNS01Recheck) (*sapb.Exists, error) {
+	if req == nil || req.AuthzID == nil || req.LeaseExpires == nil || req.Claim == nil {
+		return nil, errIncompleteRequest
+	}
+	return sas.inner.ClaimDNS01Recheck(ctx, req)
+}
+
+func (sas StorageAuthorityServerWrapper) RemoveDNS01Recheck(ctx context.Context, req *sapb.AuthorizationID) (*corepb.Empty, error) {
+	if req == nil || req.Id == nil {
+		return nil, errIncompleteRequest
+	}
+	return &corepb.Empty{}, sas.inner.RemoveDNS01Recheck(ctx, req)
+}
+
+func (sas StorageAuthorityServerWrapper) RetryChallenge(ctx context.Context, req *sapb.RetryChallengeRequest) (*corepb.Empty, error) {
+	if req == nil || req.AuthorizationID == nil || req.ChallengeID == nil {
+		return nil, errIncompleteRequest
+	}
+	return &corepb.Empty{}, sas.inner.RetryChallenge(ctx, req)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/jmhodges/clock"
 	"github.com/letsencrypt/boulder/cmd"
 	bcreds "github.com/letsencrypt/boulder/grpc/creds"
+	"github.com/letsencrypt/boulder/health"
 	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/grpc"
 )
@@ -51,11 +52,15 @@ func NewServer(c *cmd.GRPCServerConfig, tlsConfig *tls.Config, metrics serverMet
 		maxConcurrentStreams = 250
 	}
 	si := newServerInterceptor(metrics, clk)
-	return grpc.NewServer(
+	server := grpc.NewServer(
 		grpc.Creds(creds),
 		grpc.UnaryInterceptor(si.intercept),
 		grpc.MaxConcurrentStreams(uint32(maxConcurrentStreams)),
-	), l, nil
+	)
+	// Every server answers the standard gRPC health checks with the readiness
+	// of the process (see health.Default).
+	health.RegisterGRPC(server, health.Default)
+	return server, l, nil
 }
 
 // serverMetrics is a struct type used to return a few registered metrics from
@@ -63,6 +68,13 @@ func NewServer(c *cmd.GRPCServerConfig, tlsConfig *tls.Config, metrics serverMet
 type serverMetrics struct {
 	grpcMetrics *grpc_prometheus.ServerMetrics
 	rpcLag      prometheus.Histogram
+	// deadlineBudget tracks, by service/method, how much of the caller's
+	// deadline is left when an RPC is received.
+	deadlineBudget *prometheus.HistogramVec
+	// deadlineExceeded counts, by service/method and stage, RPCs that ran out
+	// of time: either on arrival, with too little time left to do meaningful
+	// work, or while being handled.
+	deadlineExceeded *prometheus.CounterVec
 }
 
 // NewServerMetrics registers metrics with a registry. It must be called a
@@ -85,8 +97,25 @@ func NewServerMetrics(stats registry) serverMetrics {
 		})
 	stats.MustRegister(rpcLag)
 
+	deadlineBudget := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "grpc_server_deadline_budget_seconds",
+			Help:    "Time left before the caller's deadline when an RPC is received",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+		}, []string{"method", "service"})
+	stats.MustRegister(deadlineBudget)
+
+	deadlineExceeded := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_server_deadline_exceeded",
+			Help: "Number of RPCs that exceeded their deadline, by stage (arrival or handler)",
+		}, []string{"method", "service", "stage"})
+	stats.MustRegister(deadlineExceeded)
+
 	return serverMetrics{
-		grpcMetrics: grpcMetrics,
-		rpcLag:      rpcLag,
+		grpcMetrics:      grpcMetrics,
+		rpcLag:           rpcLag,
+		deadlineBudget:   deadlineBudget,
+		deadlineExceeded: deadlineExceeded,
 	}
 }
@@ -0,0 +1,205 @@
+package grpc
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/resolver"
+)
+
+// subsetSeed makes each process prefer a different subset of backends.
+var subsetSeed = func() uint64 {
+	var seed uint64
+	_ = binary.Read(rand.Reader, binary.LittleEndian, &seed)
+	return seed
+}()
+
+var (
+	subsetBalancersMu sync.Mutex
+	subsetBalancers   = make(map[int]string)
+)
+
+// subsetBalancerName returns the name of a balancer that connects to at most
+// size backends and round-robins RPCs across them, registering it if
+// necessary. gRPC doesn't support per-client balancer configuration, so there
+// is one balancer per subset size.
+func subsetBalancerName(size int) string {
+	subsetBalancersMu.Lock()
+	defer subsetBalancersMu.Unlock()
+	name, ok := subsetBalancers[size]
+	if !ok {
+		name = fmt.Sprintf("boulder_subset_%d", size)
+		pb := subsetPickerBuilder{size: size, seed: subsetSeed}
+		balancer.Register(subsetBuilder{name: name, pb: pb, base: base.NewBalancerBuilder(name, pb)})
+		subsetBalancers[size] = name
+	}
+	return name
+}
+
+// subsetBuilder builds subsetBalancers wrapping the balancers built by base.
+type subsetBuilder struct {
+	name string
+	pb   subsetPickerBuilder
+	base balancer.Builder
+}
+
+func (b subsetBuilder) Name() string {
+	return b.name
+}
+
+func (b subsetBuilder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	sb := &subsetBalancer{
+		pb:       b.pb,
+		subConns: make(map[balancer.SubConn]string),
+		failed:   make(map[string]bool),
+	}
+	sb.Balancer = b.base.Build(subsetClientConn{cc, sb}, opts)
+	return sb
+}
+
+// subsetBalancer only passes the first size of the resolved backends, ranked
+// as by subsetPickerBuilder, to the balancer it wraps, so that only they are
+// connected to. When the connection to one of them fails it is replaced by
+// the next ranked backend, and the rest of the subset is unchanged. Backends
+// whose connection failed are only used again when there aren't enough other
+// ones, or once the backends are resolved again. gRPC calls balancers from a
+// single goroutine, so there's no locking.
+type subsetBalancer struct {
+	balancer.Balancer
+	pb subsetPickerBuilder
+
+	// resolved holds the resolved backends, in order of rank.
+	resolved []resolver.Address
+	// subConns holds the address of each SubConn created by the wrapped
+	// balancer.
+	subConns map[balancer.SubConn]string
+	// failed holds the addresses of the backends whose connection failed.
+	failed map[string]bool
+}
+
+func (sb *subsetBalancer) HandleResolvedAddrs(addrs []resolver.Address, err error) {
+	if err != nil {
+		sb.Balancer.HandleResolvedAddrs(addrs, err)
+		return
+	}
+	sb.resolved = append([]resolver.Address(nil), addrs...)
+	sort.SliceStable(sb.resolved, func(i, j int) bool {
+		return sb.pb.rank(sb.resolved[i].Addr) < sb.pb.rank(sb.resolved[j].Addr)
+	})
+	sb.failed = make(map[string]bool)
+	sb.update()
+}
+
+func (sb *subsetBalancer) HandleSubConnStateChange(sc balancer.SubConn, s connectivity.State) {
+	sb.Balancer.HandleSubConnStateChange(sc, s)
+	addr, ok := sb.subConns[sc]
+	if !ok {
+		return
+	}
+	switch s {
+	case connectivity.Ready:
+		delete(sb.failed, addr)
+	case connectivity.TransientFailure:
+		if !sb.failed[addr] {
+			sb.failed[addr] = true
+			sb.update()
+		}
+	case connectivity.Shutdown:
+		delete(sb.subConns, sc)
+	}
+}
+
+// update passes the first size backends whose connection hasn't failed to the
+// wrapped balancer, followed by failed ones if there aren't enough.
+func (sb *subsetBalancer) update() {
+	var subset, failed []resolver.Address
+	for _, addr := range sb.resolved {
+		if sb.failed[addr.Addr] {
+			failed = append(failed, addr)
+		} else {
+			subset = append(subset, addr)
+		}
+	}
+	subset = append(subset, failed...)
+	if len(subset) > sb.pb.size {
+		subset = subset[:sb.pb.size]
+	}
+	sb.Balancer.HandleResolvedAddrs(subset, nil)
+}
+
+// subsetClientConn records the address of each SubConn created by the
+// balancer wrapped by a subsetBalancer.
+type subsetClientConn struct {
+	balancer.ClientConn
+	sb *subsetBalancer
+}
+
+func (cc subsetClientConn) NewSubConn(addrs []resolver.Address, opts balancer.NewSubConnOptions) (balancer.SubConn, error) {
+	sc, err := cc.ClientConn.NewSubConn(addrs, opts)
+	if err == nil && len(addrs) > 0 {
+		cc.sb.subConns[sc] = addrs[0].Addr
+	}
+	return sc, err
+}
+
+// subsetPickerBuilder builds pickers that use the first size ready backends,
+// ranked by a hash of the seed and their address (i.e. rendezvous hashing).
+// Only backends with a ready connection are considered, so while the
+// subsetBalancer replaces a backend that became unreachable the rest of the
+// subset is used.
+type subsetPickerBuilder struct {
+	size int
+	seed uint64
+}
+
+func (pb subsetPickerBuilder) rank(addr string) uint64 {
+	h := fnv.New64a()
+	_ = binary.Write(h, binary.LittleEndian, pb.seed)
+	_, _ = h.Write([]byte(addr))
+	return h.Sum64()
+}
+
+func (pb subsetPickerBuilder) Build(readySCs map[resolver.Address]balancer.SubConn) balancer.Picker {
+	addrs := make([]resolver.Address, 0, len(readySCs))
+	for addr := range readySCs {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return pb.rank(addrs[i].Addr) < pb.rank(addrs[j].Addr)
+	})
+	if len(addrs) > pb.size {
+		addrs = addrs[:pb.size]
+	}
+	p := &subsetPicker{}
+	for _, addr := range addrs {
+		p.subConns = append(p.subConns, readySCs[addr])
+	}
+	return p
+}
+
+type subsetPicker struct {
+	// subConns is immutable
+	subConns []balancer.SubConn
+
+	mu   sync.Mutex
+	next int
+}
+
+func (p *subsetPicker) Pick(context.Context, balancer.PickOptions) (balancer.SubConn, func(balancer.DoneInfo), error) {
+	if len(p.subConns) == 0 {
+		return nil, nil, balancer.ErrNoSubConnAvailable
+	}
+	p.mu.Lock()
+	sc := p.subConns[p.next]
+	p.next = (p.next + 1) % len(p.subConns)
+	p.mu.Unlock()
+	return sc, nil, nil
+}
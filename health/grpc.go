@@ -0,0 +1,73 @@
+package health
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	healthpb "github.com/letsencrypt/boulder/health/proto"
+)
+
+// grpcServer implements the standard gRPC health checking protocol on top of
+// a Checker. The empty service name refers to the server as a whole, any other
+// name must be one of the services registered on the gRPC server.
+type grpcServer struct {
+	checker       *Checker
+	server        *grpc.Server
+	watchInterval time.Duration
+}
+
+// RegisterGRPC registers the gRPC health service on server, reporting the
+// readiness of checker.
+func RegisterGRPC(server *grpc.Server, checker *Checker) {
+	healthpb.RegisterHealthServer(server, &grpcServer{
+		checker:       checker,
+		server:        server,
+		watchInterval: time.Second,
+	})
+}
+
+func (s *grpcServer) status(ctx context.Context, service string) healthpb.HealthCheckResponse_ServingStatus {
+	if service != "" {
+		if _, ok := s.server.GetServiceInfo()[service]; !ok {
+			return healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+	}
+	if s.checker.Status(ctx).Ready() {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
+
+// Check returns the current status of the requested service.
+func (s *grpcServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	status := s.status(ctx, req.Service)
+	if status == healthpb.HealthCheckResponse_SERVICE_UNKNOWN {
+		return nil, grpc.Errorf(codes.NotFound, "unknown service %q", req.Service)
+	}
+	return &healthpb.HealthCheckResponse{Status: status}, nil
+}
+
+// Watch sends the status of the requested service immediately and again
+// every time it changes, until the client goes away.
+func (s *grpcServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ctx := stream.Context()
+	last := healthpb.HealthCheckResponse_ServingStatus(-1)
+	for {
+		status := s.status(ctx, req.Service)
+		if status != last {
+			err := stream.Send(&healthpb.HealthCheckResponse{Status: status})
+			if err != nil {
+				return err
+			}
+			last = status
+		}
+		select {
+		case <-ctx.Done():
+			return grpc.Errorf(codes.Canceled, "watch canceled: %s", ctx.Err())
+		case <-s.checker.clk.After(s.watchInterval):
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Package health tracks whether a Boulder service is ready to receive traffic.
+// A service is ready once it has finished initializing and every dependency
+// check registered with AddCheck (e.g. "the database is reachable") passes.
+// Readiness is exposed over the standard gRPC health checking protocol (see
+// grpc.go) and as HTTP readiness and liveness endpoints on the debug port (see
+// http.go).
+package health
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"golang.org/x/net/context"
+)
+
+const (
+	// defaultInterval is how long the results of dependency checks are reused
+	// for, so that frequent probes from load balancers don't translate into
+	// frequent database pings or HSM operations.
+	defaultInterval = 5 * time.Second
+	// defaultTimeout bounds how long a single dependency check can take.
+	defaultTimeout = 2 * time.Second
+)
+
+// Check reports whether a dependency is usable by returning nil.
+type Check func(ctx context.Context) error
+
+// Checker combines a service's serving state with the results of its
+// dependency checks.
+type Checker struct {
+	clk      clock.Clock
+	interval time.Duration
+	timeout  time.Duration
+
+	// checkMu serializes runs of the checks so that concurrent probes share
+	// a single run.
+	checkMu sync.Mutex
+
+	mu      sync.RWMutex
+	serving bool
+	checks  map[string]Check
+	results map[string]error
+	lastRun time.Time
+}
+
+// New returns a Checker that isn't serving and has no checks.
+func New(clk clock.Clock) *Checker {
+	return &Checker{
+		clk:      clk,
+		interval: defaultInterval,
+		timeout:  defaultTimeout,
+		checks:   make(map[string]Check),
+		results:  make(map[string]error),
+	}
+}
+
+// Default is the Checker used by Boulder's service binaries. It is served on
+// the debug port by the cmd package and registered on every gRPC server
+// created by the grpc package.
+var Default = New(clock.Default())
+
+// AddCheck registers a dependency check. Adding a check with the name of an
+// existing one replaces it.
+func (c *Checker) AddCheck(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+	// Make sure the new check runs before readiness is next reported
+	c.lastRun = time.Time{}
+}
+
+// SetServing marks the service as done initializing (true) or as shutting
+// down (false). A service that isn't serving is never ready.
+func (c *Checker) SetServing(serving bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.serving = serving
+}
+
+// Status describes the readiness of a service.
+type Status struct {
+	Serving bool `json:"serving"`
+	// Checks maps the name of each dependency check to "ok" or the error it
+	// returned.
+	Checks map[string]string `json:"checks"`
+}
+
+// Ready reports whether the service is serving and all its checks pass.
+func (s Status) Ready() bool {
+	if !s.Serving {
+		return false
+	}
+	for _, result := range s.Checks {
+		if result != "ok" {
+			return false
+		}
+	}
+	return true
+}
+
+// Status returns the current readiness of the service, running the dependency
+// checks if their last results are older than the check interval.
+func (c *Checker) Status(ctx context.Context) Status {
+	c.mu.RLock()
+	stale := c.clk.Now().Sub(c.lastRun) >= c.interval
+	c.mu.RUnlock()
+	if stale {
+		c.runChecks(ctx)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	status := Status{Serving: c.serving, Checks: make(map[string]string, len(c.results))}
+	for name, err := range c.results {
+		status.Checks[name] = "ok"
+		if err != nil {
+			status.Checks[name] = err.Error()
+		}
+	}
+	return status
+}
+
+// runChecks runs every check concurrently and stores the results.
+func (c *Checker) runChecks(ctx context.Context) {
+	c.checkMu.Lock()
+	defer c.checkMu.Unlock()
+
+	c.mu.RLock()
+	// Another caller may have run the checks while we waited for checkMu
+	if c.clk.Now().Sub(c.lastRun) < c.interval {
+		c.mu.RUnlock()
+		return
+	}
+	names := make([]string, 0, len(c.checks))
+	checks := make([]Check, 0, len(c.checks))
+	for name, check := range c.checks {
+		names = append(names, name)
+		checks = append(checks, check)
+	}
+	c.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	errs := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			errs[i] = check(ctx)
+		}(i, check)
+	}
+	wg.Wait()
+
+	results := make(map[string]error, len(names))
+	for i, name := range names {
+		results[name] = errs[i]
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results = results
+	c.lastRun = c.clk.Now()
+}
@@ -0,0 +1,112 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	healthpb "github.com/letsencrypt/boulder/health/proto"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestStatus(t *testing.T) {
+	fc := clock.NewFake()
+	checker := New(fc)
+	ctx := context.Background()
+
+	// A service without checks is ready once it's serving
+	test.Assert(t, !checker.Status(ctx).Ready(), "ready before serving")
+	checker.SetServing(true)
+	test.Assert(t, checker.Status(ctx).Ready(), "not ready while serving")
+
+	var calls int
+	var dbErr error
+	checker.AddCheck("db", func(_ context.Context) error {
+		calls++
+		return dbErr
+	})
+	status := checker.Status(ctx)
+	test.Assert(t, status.Ready(), "not ready with passing check")
+	test.AssertEquals(t, status.Checks["db"], "ok")
+	test.AssertEquals(t, calls, 1)
+
+	// Results are cached until the interval has passed
+	dbErr = errors.New("connection refused")
+	test.Assert(t, checker.Status(ctx).Ready(), "cached result not used")
+	test.AssertEquals(t, calls, 1)
+	fc.Add(defaultInterval)
+	status = checker.Status(ctx)
+	test.Assert(t, !status.Ready(), "ready with failing check")
+	test.AssertEquals(t, status.Checks["db"], "connection refused")
+	test.AssertEquals(t, calls, 2)
+
+	dbErr = nil
+	fc.Add(defaultInterval)
+	test.Assert(t, checker.Status(ctx).Ready(), "not ready after check recovered")
+	checker.SetServing(false)
+	test.Assert(t, !checker.Status(ctx).Ready(), "ready while shutting down")
+}
+
+func TestCheckTimeout(t *testing.T) {
+	checker := New(clock.NewFake())
+	checker.timeout = time.Millisecond
+	checker.SetServing(true)
+	checker.AddCheck("hsm", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	status := checker.Status(context.Background())
+	test.Assert(t, !status.Ready(), "ready with hung check")
+	test.AssertEquals(t, status.Checks["hsm"], context.DeadlineExceeded.Error())
+}
+
+func TestHTTPHandlers(t *testing.T) {
+	checker := New(clock.NewFake())
+	checker.AddCheck("db", func(_ context.Context) error { return nil })
+
+	rw := httptest.NewRecorder()
+	ReadyHandler(checker).ServeHTTP(rw, httptest.NewRequest("GET", "/health/ready", nil))
+	test.AssertEquals(t, rw.Code, http.StatusServiceUnavailable)
+	var status Status
+	test.AssertNotError(t, json.Unmarshal(rw.Body.Bytes(), &status), "unmarshaling readiness")
+	test.Assert(t, !status.Serving, "serving before SetServing")
+	test.AssertEquals(t, status.Checks["db"], "ok")
+
+	checker.SetServing(true)
+	rw = httptest.NewRecorder()
+	ReadyHandler(checker).ServeHTTP(rw, httptest.NewRequest("GET", "/health/ready", nil))
+	test.AssertEquals(t, rw.Code, http.StatusOK)
+
+	rw = httptest.NewRecorder()
+	LiveHandler().ServeHTTP(rw, httptest.NewRequest("GET", "/health/live", nil))
+	test.AssertEquals(t, rw.Code, http.StatusOK)
+}
+
+func TestGRPCCheck(t *testing.T) {
+	checker := New(clock.NewFake())
+	server := grpc.NewServer()
+	hs := &grpcServer{checker: checker, server: server, watchInterval: time.Second}
+	healthpb.RegisterHealthServer(server, hs)
+	ctx := context.Background()
+
+	resp, err := hs.Check(ctx, &healthpb.HealthCheckRequest{})
+	test.AssertNotError(t, err, "Check failed")
+	test.AssertEquals(t, resp.Status, healthpb.HealthCheckResponse_NOT_SERVING)
+
+	checker.SetServing(true)
+	resp, err = hs.Check(ctx, &healthpb.HealthCheckRequest{Service: "grpc.health.v1.Health"})
+	test.AssertNotError(t, err, "Check failed")
+	test.AssertEquals(t, resp.Status, healthpb.HealthCheckResponse_SERVING)
+
+	_, err = hs.Check(ctx, &healthpb.HealthCheckRequest{Service: "nonexistent.Service"})
+	test.AssertError(t, err, "Check succeeded for unknown service")
+	test.AssertEquals(t, grpc.Code(err), codes.NotFound)
+}
@@ -0,0 +1,32 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ReadyHandler serves the readiness of checker as JSON, with a 200 status
+// code if the service is ready and 503 otherwise.
+func ReadyHandler(checker *Checker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := checker.Status(r.Context())
+		body, err := json.Marshal(status)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if !status.Ready() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_, _ = w.Write(body)
+	})
+}
+
+// LiveHandler always responds with a 200 status code, so that orchestrators
+// can tell a process that is up but not ready from one that is hung.
+func LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok\n"))
+	})
+}
@@ -0,0 +1,3 @@
+package proto
+
+//go:generate sh -c "cd ../.. && protoc --go_out=plugins=grpc:. health/proto/health.proto"
@@ -0,0 +1,117 @@
+package killswitch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/reloader"
+)
+
+// challengeSwitch disables a challenge type until its sunset, which every
+// entry must have so that a forgotten switch can't disable a challenge type
+// forever. DisabledBy identifies the operator who flipped it.
+type challengeSwitch struct {
+	DisabledBy string    `json:"disabledBy"`
+	Reason     string    `json:"reason"`
+	Ticket     string    `json:"ticket,omitempty"`
+	Sunset     time.Time `json:"sunset"`
+}
+
+// Challenges records which challenge types have been disabled, e.g. during
+// an exploit against their validation method. It's checked when challenges
+// are offered, when the RA is asked to validate one, which it refuses without
+// changing the authorization, and by the VA for validations that were
+// already under way. A nil *Challenges disables nothing.
+type Challenges struct {
+	mu       sync.RWMutex
+	switches map[string]challengeSwitch
+	clk      clock.Clock
+	log      blog.Logger
+}
+
+// NewChallenges returns a Challenges that disables no challenge types.
+func NewChallenges(clk clock.Clock, logger blog.Logger) *Challenges {
+	return &Challenges{clk: clk, log: logger}
+}
+
+// WatchFile loads the disabled challenge types from file and reloads them
+// whenever the file changes. The file is a JSON object mapping challenge
+// types to objects like {"disabledBy": "jsmith", "reason": "exploit",
+// "ticket": "OPS-123", "sunset": "2019-05-01T00:00:00Z"}; removing a
+// challenge type from it enables it again before its sunset.
+func (c *Challenges) WatchFile(file string) error {
+	return reloader.Register(reloader.Section{
+		Name: "challenge kill switch",
+		File: file,
+		Load: c.load,
+	})
+}
+
+func (c *Challenges) load(contents []byte) error {
+	var switches map[string]challengeSwitch
+	dec := json.NewDecoder(bytes.NewReader(contents))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&switches); err != nil {
+		return err
+	}
+	if switches == nil {
+		return errors.New("challenge kill switch file doesn't contain an object")
+	}
+	for t, s := range switches {
+		if !core.ValidChallenge(t) {
+			return fmt.Errorf("invalid challenge type %q", t)
+		}
+		if s.DisabledBy == "" {
+			return fmt.Errorf("kill switch for %s doesn't say who flipped it", t)
+		}
+		if s.Sunset.IsZero() {
+			return fmt.Errorf("kill switch for %s has no sunset", t)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for t, s := range switches {
+		if c.switches[t] != s {
+			c.log.AuditInfof("Challenge type %s disabled by kill switch: operator=[%s] reason=[%s] ticket=[%s] sunset=[%s]",
+				t, s.DisabledBy, s.Reason, s.Ticket, s.Sunset.Format(time.RFC3339))
+		}
+	}
+	for t, s := range c.switches {
+		if _, ok := switches[t]; !ok {
+			c.log.AuditInfof("Challenge type %s re-enabled by kill switch: operator=[%s]", t, s.DisabledBy)
+		}
+	}
+	c.switches = switches
+	return nil
+}
+
+// Check returns an IssuanceDisabled error, which clients see as the service
+// being unavailable, if challenge type t is disabled.
+func (c *Challenges) Check(t string) error {
+	if c.Disabled(t) {
+		return berrors.IssuanceDisabledError("challenge type %s is temporarily disabled", t)
+	}
+	return nil
+}
+
+// Disabled returns whether challenge type t is disabled by a switch whose
+// sunset hasn't passed.
+func (c *Challenges) Disabled(t string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	s, ok := c.switches[t]
+	return ok && c.clk.Now().Before(s.Sunset)
+}
@@ -0,0 +1,74 @@
+package killswitch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestChallengesDisabled(t *testing.T) {
+	var nilChallenges *Challenges
+	test.Assert(t, !nilChallenges.Disabled("http-01"), "nil Challenges disabled a challenge type")
+
+	fc := clock.NewFake()
+	fc.Set(time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC))
+	log := blog.NewMock()
+	c := NewChallenges(fc, log)
+	test.Assert(t, !c.Disabled("http-01"), "new Challenges disabled a challenge type")
+
+	flipped := `{"http-01": {"disabledBy": "jsmith", "reason": "exploit", "ticket": "OPS-1", "sunset": "2019-04-02T00:00:00Z"}}`
+	test.AssertNotError(t, c.load([]byte(flipped)), "load failed")
+	test.Assert(t, c.Disabled("http-01"), "loaded Challenges didn't disable HTTP-01")
+	test.Assert(t, !c.Disabled("dns-01"), "loaded Challenges disabled DNS-01")
+	test.AssertError(t, c.Check("http-01"), "Check allowed a disabled challenge type")
+	test.AssertNotError(t, c.Check("dns-01"), "Check refused an enabled challenge type")
+	test.AssertNotError(t, nilChallenges.Check("http-01"), "nil Challenges refused a challenge type")
+	test.AssertEquals(t, len(log.GetAllMatching(`Challenge type http-01 disabled by kill switch: operator=\[jsmith\] reason=\[exploit\] ticket=\[OPS-1\] sunset=\[2019-04-02T00:00:00Z\]`)), 1)
+
+	// Reloading an unchanged switch isn't logged again
+	log.Clear()
+	test.AssertNotError(t, c.load([]byte(flipped)), "load failed")
+	test.AssertEquals(t, len(log.GetAllMatching(`kill switch`)), 0)
+
+	// Invalid contents leave the current state in effect
+	for _, invalid := range []string{
+		`{"bogus-01": {"disabledBy": "jsmith", "sunset": "2019-04-02T00:00:00Z"}}`,
+		`{"http-01": {"sunset": "2019-04-02T00:00:00Z"}}`,
+		`{"http-01": {"disabledBy": "jsmith"}}`,
+		`{"http-01": {"disabledBy": "jsmith", "sunset": "2019-04-02T00:00:00Z", "until": "never"}}`,
+		`null`,
+	} {
+		test.AssertError(t, c.load([]byte(invalid)), "load accepted "+invalid)
+	}
+	test.Assert(t, c.Disabled("http-01"), "invalid contents changed the Challenges")
+
+	// A switch lapses at its sunset
+	fc.Set(time.Date(2019, 4, 2, 0, 0, 0, 0, time.UTC))
+	test.Assert(t, !c.Disabled("http-01"), "HTTP-01 still disabled after the sunset")
+
+	// Removing a switch re-enables the challenge type early
+	fc.Set(time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC))
+	test.AssertNotError(t, c.load([]byte(`{}`)), "load failed")
+	test.Assert(t, !c.Disabled("http-01"), "HTTP-01 still disabled after its switch was removed")
+	test.AssertEquals(t, len(log.GetAllMatching(`Challenge type http-01 re-enabled by kill switch: operator=\[jsmith\]`)), 1)
+}
+
+func TestChallengesWatchFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "killswitch")
+	test.AssertNotError(t, err, "TempDir failed")
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "challenges.json")
+	fc := clock.NewFake()
+	c := NewChallenges(fc, blog.NewMock())
+	test.AssertError(t, c.WatchFile(file), "WatchFile accepted a missing file")
+	test.AssertNotError(t, ioutil.WriteFile(file, []byte(`{"dns-01": {"disabledBy": "jsmith", "sunset": "2030-01-01T00:00:00Z"}}`), 0644), "WriteFile failed")
+	test.AssertNotError(t, c.WatchFile(file), "WatchFile failed")
+	test.Assert(t, c.Disabled("dns-01"), "Challenges loaded from file didn't disable DNS-01")
+}
@@ -0,0 +1,87 @@
+// Package killswitch provides a switch that operators can flip to stop all
+// certificate issuance immediately, without stopping the services that issue
+// certificates. OCSP signing, revocation and read-only requests are not
+// affected. It also provides switches that disable individual challenge
+// types.
+package killswitch
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	berrors "github.com/letsencrypt/boulder/errors"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/reloader"
+)
+
+// state is the contents of a kill switch file, which is a JSON object like
+// {"stopIssuance": true, "reason": "scheduled maintenance"}. The reason is
+// included in the errors returned to subscribers.
+type state struct {
+	StopIssuance bool   `json:"stopIssuance"`
+	Reason       string `json:"reason"`
+}
+
+// Switch records whether issuance has been stopped. A nil *Switch never stops
+// issuance.
+type Switch struct {
+	mu    sync.RWMutex
+	state state
+	log   blog.Logger
+}
+
+// New returns a Switch that allows issuance until it is stopped.
+func New(logger blog.Logger) *Switch {
+	return &Switch{log: logger}
+}
+
+// WatchFile loads the switch's state from file and reloads it whenever the
+// file changes. The file must exist, so that a typo in its path can't leave
+// operators unable to stop issuance.
+func (s *Switch) WatchFile(file string) error {
+	return reloader.Register(reloader.Section{
+		Name: "issuance kill switch",
+		File: file,
+		Load: s.load,
+	})
+}
+
+func (s *Switch) load(contents []byte) error {
+	var st state
+	dec := json.NewDecoder(bytes.NewReader(contents))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&st); err != nil {
+		return err
+	}
+	s.Set(st.StopIssuance, st.Reason)
+	return nil
+}
+
+// Set stops issuance, giving reason as the cause, or allows it again.
+func (s *Switch) Set(stopIssuance bool, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stopIssuance && !s.state.StopIssuance {
+		s.log.AuditInfof("Issuance stopped by kill switch: %s", reason)
+	} else if !stopIssuance && s.state.StopIssuance {
+		s.log.AuditInfo("Issuance resumed by kill switch")
+	}
+	s.state = state{StopIssuance: stopIssuance, Reason: reason}
+}
+
+// Check returns an IssuanceDisabled error if issuance has been stopped.
+func (s *Switch) Check() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.state.StopIssuance {
+		return nil
+	}
+	if s.state.Reason == "" {
+		return berrors.IssuanceDisabledError("certificate issuance is temporarily disabled")
+	}
+	return berrors.IssuanceDisabledError("certificate issuance is temporarily disabled: %s", s.state.Reason)
+}
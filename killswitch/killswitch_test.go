@@ -0,0 +1,56 @@
+package killswitch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	berrors "github.com/letsencrypt/boulder/errors"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestCheck(t *testing.T) {
+	var nilSwitch *Switch
+	test.AssertNotError(t, nilSwitch.Check(), "nil Switch stopped issuance")
+
+	s := New(blog.NewMock())
+	test.AssertNotError(t, s.Check(), "new Switch stopped issuance")
+
+	s.Set(true, "scheduled maintenance")
+	err := s.Check()
+	test.Assert(t, berrors.Is(err, berrors.IssuanceDisabled), "Check didn't return IssuanceDisabled")
+	test.AssertContains(t, err.Error(), "scheduled maintenance")
+
+	s.Set(false, "")
+	test.AssertNotError(t, s.Check(), "Switch still stopped issuance after being reset")
+}
+
+func TestLoad(t *testing.T) {
+	s := New(blog.NewMock())
+	test.AssertNotError(t, s.load([]byte(`{"stopIssuance": true, "reason": "incident"}`)), "load failed")
+	test.Assert(t, berrors.Is(s.Check(), berrors.IssuanceDisabled), "loaded Switch didn't stop issuance")
+
+	// Invalid contents leave the current state in effect
+	test.AssertError(t, s.load([]byte(`{"stopIssuance": "no"}`)), "load accepted a string")
+	test.AssertError(t, s.load([]byte(`{"stopissuing": false}`)), "load accepted an unknown field")
+	test.Assert(t, berrors.Is(s.Check(), berrors.IssuanceDisabled), "invalid contents changed the Switch")
+
+	test.AssertNotError(t, s.load([]byte(`{"stopIssuance": false}`)), "load failed")
+	test.AssertNotError(t, s.Check(), "loaded Switch stopped issuance")
+}
+
+func TestWatchFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "killswitch")
+	test.AssertNotError(t, err, "TempDir failed")
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "killswitch.json")
+
+	s := New(blog.NewMock())
+	test.AssertError(t, s.WatchFile(file), "WatchFile accepted a missing file")
+
+	test.AssertNotError(t, ioutil.WriteFile(file, []byte(`{"stopIssuance": true}`), 0644), "WriteFile failed")
+	test.AssertNotError(t, s.WatchFile(file), "WatchFile failed")
+	test.Assert(t, berrors.Is(s.Check(), berrors.IssuanceDisabled), "Switch loaded from file didn't stop issuance")
+}
@@ -1,15 +1,19 @@
 package log
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/syslog"
 	"os"
 	"path"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/jmhodges/clock"
 )
@@ -32,11 +36,19 @@ type Logger interface {
 	AuditObject(string, interface{})
 	AuditErr(string)
 	AuditErrf(format string, a ...interface{})
+	With(fields Fields) Logger
+	WithRequestID(id string) Logger
 }
 
+// Fields are key/value pairs attached to every message logged by a Logger
+// returned from With. Values must be serializable to JSON.
+type Fields map[string]interface{}
+
 // impl implements Logger.
 type impl struct {
-	w writer
+	w         writer
+	fields    Fields
+	requestID string
 }
 
 // singleton defines the object of a Singleton pattern
@@ -57,10 +69,33 @@ func New(log *syslog.Writer, stdoutLogLevel int, syslogLogLevel int) (Logger, er
 		return nil, errors.New("Attempted to use a nil System Logger.")
 	}
 	return &impl{
-		&bothWriter{log, stdoutLogLevel, syslogLogLevel, clock.Default()},
+		w: &bothWriter{
+			Writer:      log,
+			stdoutLevel: stdoutLogLevel,
+			syslogLevel: syslogLogLevel,
+			clk:         clock.Default(),
+			stdout:      os.Stdout,
+		},
 	}, nil
 }
 
+// NewJSON returns a new Logger that writes one JSON object per message to
+// stdout, for log pipelines that need structured input. If log is not nil the
+// messages are also sent to syslog in the usual text format; otherwise syslog
+// isn't used at all.
+func NewJSON(log *syslog.Writer, stdoutLogLevel int, syslogLogLevel int) Logger {
+	return &impl{
+		w: &bothWriter{
+			Writer:      log,
+			stdoutLevel: stdoutLogLevel,
+			syslogLevel: syslogLogLevel,
+			clk:         clock.Default(),
+			stdout:      os.Stdout,
+			json:        true,
+		},
+	}
+}
+
 // initialize should only be used in unit tests.
 func initialize() {
 	// defaultPriority is never used because we always use specific priority-based
@@ -106,27 +141,89 @@ func Get() Logger {
 }
 
 type writer interface {
-	logAtLevel(syslog.Priority, string)
+	logAtLevel(syslog.Priority, entry)
+}
+
+// entry is a single message along with the context it was logged in.
+type entry struct {
+	msg       string
+	audit     bool
+	fields    Fields
+	requestID string
+}
+
+// text formats the entry as a single line: the message, prefixed with the
+// audit tag if it's an audit message, followed by the request ID and fields
+// as key=value pairs with JSON encoded values.
+func (e entry) text() string {
+	var buf bytes.Buffer
+	if e.audit {
+		buf.WriteString(auditTag)
+		buf.WriteString(" ")
+	}
+	buf.WriteString(e.msg)
+	if e.requestID != "" {
+		fmt.Fprintf(&buf, " requestID=%s", e.requestID)
+	}
+	keys := make([]string, 0, len(e.fields))
+	for k := range e.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		value, err := json.Marshal(e.fields[k])
+		if err != nil {
+			value = []byte(fmt.Sprintf("%q", fmt.Sprintf("%+v", e.fields[k])))
+		}
+		fmt.Fprintf(&buf, " %s=%s", k, value)
+	}
+	return buf.String()
+}
+
+// jsonLine is the structure of the lines written to stdout by a Logger created
+// with NewJSON.
+type jsonLine struct {
+	Time      string `json:"time"`
+	Level     string `json:"level"`
+	Component string `json:"component"`
+	// Audit is set for audit messages, which are prefixed with the audit tag
+	// in the text format.
+	Audit     bool   `json:"audit,omitempty"`
+	RequestID string `json:"requestID,omitempty"`
+	Message   string `json:"msg"`
+	Fields    Fields `json:"fields,omitempty"`
+}
+
+var jsonLevelName = map[syslog.Priority]string{
+	syslog.LOG_ERR:     "error",
+	syslog.LOG_WARNING: "warning",
+	syslog.LOG_INFO:    "info",
+	syslog.LOG_DEBUG:   "debug",
 }
 
 // bothWriter implements writer and writes to both syslog and stdout.
 type bothWriter struct {
+	// Writer may be nil for JSON loggers, in which case syslog isn't used.
 	*syslog.Writer
 	stdoutLevel int
 	syslogLevel int
 	clk         clock.Clock
+	stdout      io.Writer
+	// json selects the JSON format, rather than colored text, for stdout.
+	json bool
 }
 
 // Log the provided message at the appropriate level, writing to
 // both stdout and the Logger
-func (w *bothWriter) logAtLevel(level syslog.Priority, msg string) {
+func (w *bothWriter) logAtLevel(level syslog.Priority, e entry) {
 	var prefix string
 	var err error
+	msg := e.text()
 
 	const red = "\033[31m\033[1m"
 	const yellow = "\033[33m"
 
-	switch syslogAllowed := int(level) <= w.syslogLevel; level {
+	switch syslogAllowed := w.Writer != nil && int(level) <= w.syslogLevel; level {
 	case syslog.LOG_ERR:
 		if syslogAllowed {
 			err = w.Err(msg)
@@ -148,7 +245,9 @@ func (w *bothWriter) logAtLevel(level syslog.Priority, msg string) {
 		}
 		prefix = "D"
 	default:
-		err = w.Err(fmt.Sprintf("%s (unknown logging level: %d)", msg, int(level)))
+		if w.Writer != nil {
+			err = w.Err(fmt.Sprintf("%s (unknown logging level: %d)", msg, int(level)))
+		}
 	}
 
 	if err != nil {
@@ -160,19 +259,74 @@ func (w *bothWriter) logAtLevel(level syslog.Priority, msg string) {
 		reset = "\033[0m"
 	}
 
-	if int(level) <= w.stdoutLevel {
-		fmt.Printf("%s%s %s %s%s\n",
-			prefix,
-			w.clk.Now().Format("150405"),
-			path.Base(os.Args[0]),
-			msg,
-			reset)
+	if int(level) > w.stdoutLevel {
+		return
+	}
+	if w.json {
+		w.writeJSON(level, e)
+		return
+	}
+	fmt.Fprintf(w.stdout, "%s%s %s %s%s\n",
+		prefix,
+		w.clk.Now().Format("150405"),
+		path.Base(os.Args[0]),
+		msg,
+		reset)
+}
+
+func (w *bothWriter) writeJSON(level syslog.Priority, e entry) {
+	_, _ = w.stdout.Write(append(encodeJSON(w.clk.Now(), level, e), '\n'))
+}
+
+// encodeJSON encodes e, logged at now, as a jsonLine.
+func encodeJSON(now time.Time, level syslog.Priority, e entry) []byte {
+	line := jsonLine{
+		Time:      now.UTC().Format(time.RFC3339Nano),
+		Level:     jsonLevelName[level],
+		Component: path.Base(os.Args[0]),
+		Audit:     e.audit,
+		RequestID: e.requestID,
+		Message:   e.msg,
+		Fields:    e.fields,
+	}
+	encoded, err := json.Marshal(line)
+	if err != nil {
+		// One of the fields can't be serialized, so include them in the
+		// message in the text format instead of losing the message.
+		line.Message, line.Fields = e.text(), nil
+		encoded, _ = json.Marshal(line)
 	}
+	return encoded
+}
+
+func (log *impl) logAtLevel(level syslog.Priority, msg string) {
+	log.w.logAtLevel(level, entry{msg: msg, fields: log.fields, requestID: log.requestID})
 }
 
 func (log *impl) auditAtLevel(level syslog.Priority, msg string) {
-	text := fmt.Sprintf("%s %s", auditTag, msg)
-	log.w.logAtLevel(level, text)
+	log.w.logAtLevel(level, entry{msg: msg, audit: true, fields: log.fields, requestID: log.requestID})
+}
+
+// With returns a Logger that adds the provided fields, in addition to any
+// fields already attached to log, to every message it logs. In the JSON format
+// they're rendered as an object, in the text format as key=value pairs after
+// the message.
+func (log *impl) With(fields Fields) Logger {
+	merged := make(Fields, len(log.fields)+len(fields))
+	for k, v := range log.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &impl{w: log.w, fields: merged, requestID: log.requestID}
+}
+
+// WithRequestID returns a Logger that marks every message it logs with the ID
+// of the request being handled, so that all the messages about one request can
+// be found together.
+func (log *impl) WithRequestID(id string) Logger {
+	return &impl{w: log.w, fields: log.fields, requestID: id}
 }
 
 // Return short format caller info for panic events, skipping to before the
@@ -213,7 +367,7 @@ func (log *impl) Errf(format string, a ...interface{}) {
 
 // Warning level messages pass through normally.
 func (log *impl) Warning(msg string) {
-	log.w.logAtLevel(syslog.LOG_WARNING, msg)
+	log.logAtLevel(syslog.LOG_WARNING, msg)
 }
 
 // Warningf level messages pass through normally.
@@ -223,7 +377,7 @@ func (log *impl) Warningf(format string, a ...interface{}) {
 
 // Info level messages pass through normally.
 func (log *impl) Info(msg string) {
-	log.w.logAtLevel(syslog.LOG_INFO, msg)
+	log.logAtLevel(syslog.LOG_INFO, msg)
 }
 
 // Infof level messages pass through normally.
@@ -233,7 +387,7 @@ func (log *impl) Infof(format string, a ...interface{}) {
 
 // Debug level messages pass through normally.
 func (log *impl) Debug(msg string) {
-	log.w.logAtLevel(syslog.LOG_DEBUG, msg)
+	log.logAtLevel(syslog.LOG_DEBUG, msg)
 }
 
 // Debugf level messages pass through normally.
@@ -1,6 +1,8 @@
 package log
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"log"
 	"log/syslog"
@@ -285,6 +287,56 @@ func TestSyslogLevels(t *testing.T) {
 	}
 }
 
+func TestWithFields(t *testing.T) {
+	t.Parallel()
+	log := NewMock()
+	fielded := log.With(Fields{"regID": 1234, "names": []string{"example.com"}}).WithRequestID("abcd")
+	fielded.Info("issuing")
+	fielded.With(Fields{"regID": 5678}).AuditInfo("issued")
+	log.Info("unrelated")
+	test.AssertDeepEquals(t, log.GetAll(), []string{
+		`INFO: issuing requestID=abcd names=["example.com"] regID=1234`,
+		`INFO: [AUDIT] issued requestID=abcd names=["example.com"] regID=5678`,
+		`INFO: unrelated`,
+	})
+}
+
+func TestJSONFormat(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	fc := clock.NewFake()
+	fc.Set(time.Date(2019, 3, 1, 12, 30, 0, 0, time.UTC))
+	log := &impl{w: &bothWriter{
+		stdoutLevel: int(syslog.LOG_INFO),
+		syslogLevel: int(syslog.LOG_DEBUG),
+		clk:         fc,
+		stdout:      &buf,
+		json:        true,
+	}}
+
+	log.WithRequestID("abcd").With(Fields{"serial": "ff00"}).AuditErrf("revoking %d certificates", 2)
+	log.Debug("not logged")
+	log.With(Fields{"unserializable": make(chan int)}).Warning("still logged")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	test.AssertEquals(t, len(lines), 2)
+	var line jsonLine
+	test.AssertNotError(t, json.Unmarshal([]byte(lines[0]), &line), "unmarshaling first line")
+	test.AssertEquals(t, line.Time, "2019-03-01T12:30:00Z")
+	test.AssertEquals(t, line.Level, "error")
+	test.Assert(t, line.Audit, "audit message not marked as such")
+	test.AssertEquals(t, line.RequestID, "abcd")
+	test.AssertEquals(t, line.Message, "revoking 2 certificates")
+	test.AssertEquals(t, line.Fields["serial"], "ff00")
+
+	line = jsonLine{}
+	test.AssertNotError(t, json.Unmarshal([]byte(lines[1]), &line), "unmarshaling second line")
+	test.AssertEquals(t, line.Level, "warning")
+	test.Assert(t, !line.Audit, "non-audit message marked as audit")
+	test.Assert(t, strings.HasPrefix(line.Message, "still logged unserializable="), "fields not added to message")
+	test.Assert(t, line.Fields == nil, "unserializable fields included")
+}
+
 func newUDPListener(addr string) (*net.UDPConn, error) {
 	l, err := net.ListenPacket("udp", addr)
 	if err != nil {
@@ -0,0 +1,353 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/syslog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultAuditQueueSize = 1000
+	auditRetryBaseDelay   = 100 * time.Millisecond
+	auditRetryMaxDelay    = 30 * time.Second
+)
+
+// An AuditSink receives a copy of every audit message, in addition to
+// syslog, so that audit messages are captured even if the local syslog
+// daemon loses them.
+type AuditSink interface {
+	// Send delivers one audit message, encoded as a JSON object in the same
+	// format as the JSON stdout logs.
+	Send(msg []byte) error
+}
+
+// fileAuditSink appends audit messages to a file, one per line, rotating it
+// when it grows too large.
+type fileAuditSink struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// NewFileAuditSink returns an AuditSink that appends messages to the file at
+// path, syncing it after every message. If maxBytes is non-zero the file is
+// rotated before it would grow larger: path is renamed to path.1, path.1 to
+// path.2 and so on, keeping maxBackups old files.
+func NewFileAuditSink(path string, maxBytes int64, maxBackups int) (AuditSink, error) {
+	if maxBytes > 0 && maxBackups < 1 {
+		return nil, errors.New("a rotated audit log file must keep at least one backup")
+	}
+	s := &fileAuditSink{path: path, maxBytes: maxBytes, maxBackups: maxBackups}
+	err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileAuditSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	s.f, s.size = f, info.Size()
+	return nil
+}
+
+func (s *fileAuditSink) rotate() error {
+	err := s.f.Close()
+	if err != nil {
+		return err
+	}
+	for i := s.maxBackups - 1; i >= 1; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	err = os.Rename(s.path, s.path+".1")
+	if err != nil {
+		return err
+	}
+	return s.open()
+}
+
+func (s *fileAuditSink) Send(msg []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// msg is shared with the other sinks, so it mustn't be appended to.
+	line := make([]byte, 0, len(msg)+1)
+	line = append(append(line, msg...), '\n')
+	if s.maxBytes > 0 && s.size > 0 && s.size+int64(len(line)) > s.maxBytes {
+		err := s.rotate()
+		if err != nil {
+			return err
+		}
+	}
+	n, err := s.f.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+// httpAuditSink POSTs each audit message to an HTTPS collector.
+type httpAuditSink struct {
+	url         string
+	contentType string
+	client      *http.Client
+	// body, if set, wraps a message in the request body the collector
+	// expects.
+	body func(msg []byte) ([]byte, error)
+}
+
+// NewHTTPAuditSink returns an AuditSink that POSTs each message to url as
+// application/json. Any response other than a 2xx is a delivery failure.
+func NewHTTPAuditSink(url string, client *http.Client) AuditSink {
+	return &httpAuditSink{url: url, contentType: "application/json", client: client}
+}
+
+type kafkaRESTRecord struct {
+	Value json.RawMessage `json:"value"`
+}
+
+type kafkaRESTRequest struct {
+	Records []kafkaRESTRecord `json:"records"`
+}
+
+// NewKafkaRESTAuditSink returns an AuditSink that produces each message to a
+// Kafka topic through the Kafka REST Proxy at proxyURL, using its v2 JSON
+// embedded format.
+func NewKafkaRESTAuditSink(proxyURL, topic string, client *http.Client) AuditSink {
+	return &httpAuditSink{
+		url:         strings.TrimSuffix(proxyURL, "/") + "/topics/" + url.PathEscape(topic),
+		contentType: "application/vnd.kafka.json.v2+json",
+		client:      client,
+		body: func(msg []byte) ([]byte, error) {
+			return json.Marshal(kafkaRESTRequest{Records: []kafkaRESTRecord{{Value: msg}}})
+		},
+	}
+}
+
+func (s *httpAuditSink) Send(msg []byte) error {
+	body := msg
+	if s.body != nil {
+		var err error
+		body, err = s.body(msg)
+		if err != nil {
+			return err
+		}
+	}
+	resp, err := s.client.Post(s.url, s.contentType, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("collector returned %d: %s", resp.StatusCode, msg)
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+// queuedAuditSink delivers messages to an AuditSink from a queue, in order,
+// retrying each until it's delivered.
+type queuedAuditSink struct {
+	name  string
+	sink  AuditSink
+	queue chan []byte
+}
+
+// AuditSinks delivers copies of audit messages to a set of AuditSinks. Each
+// sink has its own queue, so a slow or failing sink doesn't hold up logging
+// or the other sinks. Messages that fail to be delivered are retried with
+// backoff until they are. If a sink's queue fills up, new messages for it
+// are dropped. Every failed or dropped delivery is counted in the
+// audit_sink_messages metric, which should be alerted on, and reported on
+// stderr.
+type AuditSinks struct {
+	clk       clock.Clock
+	queueSize int
+	sinks     []*queuedAuditSink
+
+	// mu guards closed, so that no message is queued once the queues are
+	// closed.
+	mu       sync.RWMutex
+	closed   bool
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+
+	messages *prometheus.CounterVec
+	queued   *prometheus.GaugeVec
+}
+
+// NewAuditSinks returns an empty AuditSinks that queues up to queueSize
+// messages for each sink. A queueSize of zero means 1000.
+func NewAuditSinks(queueSize int) *AuditSinks {
+	if queueSize == 0 {
+		queueSize = defaultAuditQueueSize
+	}
+	return &AuditSinks{
+		clk:       clock.Default(),
+		queueSize: queueSize,
+		stop:      make(chan struct{}),
+		messages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "audit_sink_messages",
+			Help: "Number of audit messages sent to each audit sink, by result (delivered, failed, dropped). Failed deliveries are retried",
+		}, []string{"sink", "result"}),
+		queued: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "audit_sink_queued_messages",
+			Help: "Number of audit messages waiting to be delivered to each audit sink",
+		}, []string{"sink"}),
+	}
+}
+
+// Collectors returns the metrics of the sinks, for registering once the
+// logger they belong to has been created.
+func (s *AuditSinks) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{s.messages, s.queued}
+}
+
+// Add starts delivering audit messages to sink, identified in metrics and
+// errors by name. It must not be called after the sinks are in use.
+func (s *AuditSinks) Add(name string, sink AuditSink) {
+	qs := &queuedAuditSink{
+		name:  name,
+		sink:  sink,
+		queue: make(chan []byte, s.queueSize),
+	}
+	s.sinks = append(s.sinks, qs)
+	s.wg.Add(1)
+	go s.run(qs)
+}
+
+func (s *AuditSinks) run(qs *queuedAuditSink) {
+	defer s.wg.Done()
+	for msg := range qs.queue {
+		s.queued.WithLabelValues(qs.name).Set(float64(len(qs.queue)))
+		s.deliver(qs, msg)
+	}
+}
+
+// deliver sends msg to the sink, retrying with backoff until it succeeds or
+// the sinks are closed and their deadline for delivering has passed.
+func (s *AuditSinks) deliver(qs *queuedAuditSink, msg []byte) {
+	delay := auditRetryBaseDelay
+	for {
+		err := qs.sink.Send(msg)
+		if err == nil {
+			s.messages.WithLabelValues(qs.name, "delivered").Inc()
+			return
+		}
+		s.messages.WithLabelValues(qs.name, "failed").Inc()
+		fmt.Fprintf(os.Stderr, "Failed to deliver audit message to sink %s, retrying in %s: %s\n", qs.name, delay, err)
+		select {
+		case <-s.clk.After(delay):
+		case <-s.stop:
+			s.messages.WithLabelValues(qs.name, "dropped").Inc()
+			fmt.Fprintf(os.Stderr, "Gave up delivering audit message to sink %s: %s\n", qs.name, msg)
+			return
+		}
+		delay *= 2
+		if delay > auditRetryMaxDelay {
+			delay = auditRetryMaxDelay
+		}
+	}
+}
+
+// send queues msg for delivery to every sink.
+func (s *AuditSinks) send(msg []byte) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	for _, qs := range s.sinks {
+		select {
+		case qs.queue <- msg:
+			s.queued.WithLabelValues(qs.name).Set(float64(len(qs.queue)))
+		default:
+			s.messages.WithLabelValues(qs.name, "dropped").Inc()
+			fmt.Fprintf(os.Stderr, "Audit sink %s queue is full, dropped audit message: %s\n", qs.name, msg)
+		}
+	}
+}
+
+// Close stops accepting audit messages and waits for the queued ones to be
+// delivered. Once timeout has passed, messages that are still failing are
+// dropped. Close returns once every sink's queue is empty.
+func (s *AuditSinks) Close(timeout time.Duration) {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		for _, qs := range s.sinks {
+			close(qs.queue)
+		}
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-s.clk.After(timeout):
+		s.stopOnce.Do(func() { close(s.stop) })
+		<-done
+	}
+}
+
+// sinkWriter is a writer that also sends audit messages to AuditSinks.
+type sinkWriter struct {
+	writer
+	sinks *AuditSinks
+	clk   clock.Clock
+}
+
+func (w *sinkWriter) logAtLevel(level syslog.Priority, e entry) {
+	w.writer.logAtLevel(level, e)
+	if e.audit {
+		w.sinks.send(encodeJSON(w.clk.Now(), level, e))
+	}
+}
+
+// WithAuditSinks returns a Logger that logs like logger, which must have been
+// returned by New or NewJSON, and also sends every audit message to sinks.
+func WithAuditSinks(logger Logger, sinks *AuditSinks) (Logger, error) {
+	l, ok := logger.(*impl)
+	if !ok {
+		return nil, errors.New("audit sinks can only be added to a Logger returned by New or NewJSON")
+	}
+	return &impl{
+		w:         &sinkWriter{writer: l.w, sinks: sinks, clk: clock.Default()},
+		fields:    l.fields,
+		requestID: l.requestID,
+	}, nil
+}
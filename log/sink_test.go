@@ -0,0 +1,132 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log/syslog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestFileAuditSinkRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit-sink")
+	test.AssertNotError(t, err, "creating temp dir")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	_, err = NewFileAuditSink(path, 100, 0)
+	test.AssertError(t, err, "rotated file without backups was accepted")
+
+	sink, err := NewFileAuditSink(path, 30, 1)
+	test.AssertNotError(t, err, "NewFileAuditSink failed")
+	for _, msg := range []string{`{"msg":"one"}`, `{"msg":"two"}`, `{"msg":"three"}`} {
+		test.AssertNotError(t, sink.Send([]byte(msg)), "Send failed")
+	}
+
+	current, err := ioutil.ReadFile(path)
+	test.AssertNotError(t, err, "reading current file")
+	test.AssertEquals(t, string(current), "{\"msg\":\"three\"}\n")
+	backup, err := ioutil.ReadFile(path + ".1")
+	test.AssertNotError(t, err, "reading backup")
+	test.AssertEquals(t, string(backup), "{\"msg\":\"one\"}\n{\"msg\":\"two\"}\n")
+	_, err = os.Stat(path + ".2")
+	test.Assert(t, os.IsNotExist(err), "more backups than configured were kept")
+}
+
+func TestHTTPAuditSinks(t *testing.T) {
+	var gotPath, gotType, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		gotPath, gotType, gotBody = r.URL.Path, r.Header.Get("Content-Type"), string(body)
+		if strings.Contains(gotBody, "reject") {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	sink := NewHTTPAuditSink(srv.URL+"/audit", srv.Client())
+	test.AssertNotError(t, sink.Send([]byte(`{"msg":"hi"}`)), "Send failed")
+	test.AssertEquals(t, gotPath, "/audit")
+	test.AssertEquals(t, gotType, "application/json")
+	test.AssertEquals(t, gotBody, `{"msg":"hi"}`)
+	test.AssertError(t, sink.Send([]byte(`{"msg":"reject"}`)), "non-2xx response wasn't a failure")
+
+	sink = NewKafkaRESTAuditSink(srv.URL+"/", "boulder audit", srv.Client())
+	test.AssertNotError(t, sink.Send([]byte(`{"msg":"hi"}`)), "Send failed")
+	test.AssertEquals(t, gotPath, "/topics/boulder audit")
+	test.AssertEquals(t, gotType, "application/vnd.kafka.json.v2+json")
+	test.AssertEquals(t, gotBody, `{"records":[{"value":{"msg":"hi"}}]}`)
+}
+
+// flakySink fails the first failures sends, and records the rest.
+type flakySink struct {
+	mu       sync.Mutex
+	failures int
+	sent     []string
+}
+
+func (s *flakySink) Send(msg []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("collector unavailable")
+	}
+	s.sent = append(s.sent, string(msg))
+	return nil
+}
+
+func TestWithAuditSinks(t *testing.T) {
+	_, err := WithAuditSinks(NewMock(), NewAuditSinks(0))
+	test.AssertError(t, err, "audit sinks were added to a mock logger")
+
+	logger := &impl{w: &bothWriter{clk: clock.NewFake(), stdout: ioutil.Discard}}
+	sinks := NewAuditSinks(0)
+	flaky := &flakySink{failures: 1}
+	sinks.Add("flaky", flaky)
+	withSinks, err := WithAuditSinks(logger, sinks)
+	test.AssertNotError(t, err, "WithAuditSinks failed")
+
+	withSinks.WithRequestID("abcd").AuditInfof("revoking %d certificates", 2)
+	withSinks.Info("not audited")
+	sinks.Close(time.Minute)
+
+	test.AssertEquals(t, len(flaky.sent), 1)
+	var line jsonLine
+	test.AssertNotError(t, json.Unmarshal([]byte(flaky.sent[0]), &line), "unmarshaling message")
+	test.AssertEquals(t, line.Level, jsonLevelName[syslog.LOG_INFO])
+	test.Assert(t, line.Audit, "audit message not marked as such")
+	test.AssertEquals(t, line.RequestID, "abcd")
+	test.AssertEquals(t, line.Message, "revoking 2 certificates")
+	test.AssertEquals(t, test.CountCounter(sinks.messages.WithLabelValues("flaky", "failed")), 1)
+	test.AssertEquals(t, test.CountCounter(sinks.messages.WithLabelValues("flaky", "delivered")), 1)
+
+	// Messages logged after the sinks are closed aren't queued
+	withSinks.AuditInfo("too late")
+	test.AssertEquals(t, len(flaky.sent), 1)
+}
+
+func TestAuditSinksGiveUp(t *testing.T) {
+	sinks := NewAuditSinks(1)
+	broken := &flakySink{failures: 1000}
+	sinks.Add("broken", broken)
+	sinks.send([]byte(`{"msg":"one"}`))
+	sinks.send([]byte(`{"msg":"two"}`))
+	sinks.send([]byte(`{"msg":"three"}`))
+	sinks.Close(50 * time.Millisecond)
+
+	test.AssertEquals(t, len(broken.sent), 0)
+	// Whichever messages didn't fit in the queue were dropped straight away,
+	// and the rest were given up on when Close timed out.
+	test.AssertEquals(t, test.CountCounter(sinks.messages.WithLabelValues("broken", "dropped")), 3)
+}
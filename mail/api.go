@@ -0,0 +1,401 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	"github.com/letsencrypt/boulder/core"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+)
+
+// APIConfig configures an APIMailer.
+type APIConfig struct {
+	// Provider is "ses", "sendgrid" or "mailgun".
+	Provider string
+	// BaseURL overrides the provider's default API endpoint.
+	BaseURL string
+	// APIKey is the SES secret access key, SendGrid API key or Mailgun API
+	// key.
+	APIKey string
+	// AccessKeyID and Region are used by SES.
+	AccessKeyID string
+	Region      string
+	// Domain is the Mailgun sending domain.
+	Domain string
+	// MaxPerSecond limits the rate at which messages are sent. Zero means no
+	// limit.
+	MaxPerSecond float64
+}
+
+// apiProvider sends a single message through a mail provider's HTTP API.
+// Providers that accept a complete MIME message send raw, which carries any
+// DKIM signature; the others build the message from subject and body.
+type apiProvider interface {
+	send(from mail.Address, to []string, subject, body string, raw []byte) error
+}
+
+// apiError is returned by an apiProvider when the API responds with an
+// unexpected status code.
+type apiError struct {
+	status int
+	body   string
+}
+
+func (e apiError) Error() string {
+	return fmt.Sprintf("%d: %s", e.status, e.body)
+}
+
+// retryable returns true if the request might succeed if it's sent again.
+func (e apiError) retryable() bool {
+	return e.status == http.StatusTooManyRequests || e.status >= 500
+}
+
+// APIMailer is a Mailer that sends mail through a provider's HTTP API rather
+// than SMTP. It skips recipients on its SuppressionList and limits the rate
+// at which it sends. It is not safe for concurrent access.
+type APIMailer struct {
+	provider    apiProvider
+	from        mail.Address
+	suppressed  *SuppressionList
+	dkim        *DKIMSigner
+	interval    time.Duration
+	lastSent    time.Time
+	maxAttempts int
+	retryBase   time.Duration
+	clk         clock.Clock
+	csprgSource idGenerator
+	log         blog.Logger
+	stats       metrics.Scope
+}
+
+// NewAPIMailer returns an APIMailer for the provider c configures. suppressed
+// and dkim may be nil.
+func NewAPIMailer(
+	c APIConfig,
+	from mail.Address,
+	suppressed *SuppressionList,
+	dkim *DKIMSigner,
+	logger blog.Logger,
+	stats metrics.Scope,
+) (*APIMailer, error) {
+	if c.APIKey == "" {
+		return nil, fmt.Errorf("%s API key is required", c.Provider)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	var provider apiProvider
+	switch c.Provider {
+	case "ses":
+		if c.AccessKeyID == "" || c.Region == "" {
+			return nil, fmt.Errorf("SES access key ID and region are required")
+		}
+		endpoint := c.BaseURL
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://email.%s.amazonaws.com", c.Region)
+		}
+		provider = &sesProvider{client, endpoint, c.AccessKeyID, c.APIKey, c.Region, clock.Default()}
+	case "sendgrid":
+		endpoint := c.BaseURL
+		if endpoint == "" {
+			endpoint = "https://api.sendgrid.com"
+		}
+		provider = &sendGridProvider{client, endpoint, c.APIKey}
+	case "mailgun":
+		if c.Domain == "" {
+			return nil, fmt.Errorf("Mailgun domain is required")
+		}
+		endpoint := c.BaseURL
+		if endpoint == "" {
+			endpoint = "https://api.mailgun.net"
+		}
+		provider = &mailgunProvider{client, endpoint, c.Domain, c.APIKey}
+	default:
+		return nil, fmt.Errorf("unknown mail provider %q", c.Provider)
+	}
+	m := &APIMailer{
+		provider:    provider,
+		from:        from,
+		suppressed:  suppressed,
+		dkim:        dkim,
+		maxAttempts: 3,
+		retryBase:   time.Second,
+		clk:         clock.Default(),
+		csprgSource: realSource{},
+		log:         logger,
+		stats:       stats.NewScope("Mailer"),
+	}
+	if c.MaxPerSecond > 0 {
+		m.interval = time.Duration(float64(time.Second) / c.MaxPerSecond)
+	}
+	return m, nil
+}
+
+// Connect does nothing, as each message is sent with its own request.
+func (m *APIMailer) Connect() error {
+	return nil
+}
+
+// Close does nothing.
+func (m *APIMailer) Close() error {
+	return nil
+}
+
+// SendMail sends an email to the recipients in to that aren't suppressed. If
+// the provider rejects the message it returns a RecoverableSMTPError, so that
+// callers can carry on sending mail as they would for an SMTP rejection.
+func (m *APIMailer) SendMail(to []string, subject, body string) error {
+	m.stats.Inc("SendMail.Attempts", 1)
+
+	var recipients []string
+	for _, addr := range to {
+		if m.suppressed != nil && m.suppressed.Suppressed(addr) {
+			m.stats.Inc("SendMail.Suppressed", 1)
+			m.log.Infof("Not sending mail to suppressed address %q", addr)
+			continue
+		}
+		recipients = append(recipients, addr)
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	raw, err := generateMessage(m.from, m.clk, m.csprgSource, recipients, subject, body)
+	if err != nil {
+		return err
+	}
+	if m.dkim != nil {
+		raw, err = m.dkim.Sign(raw, m.clk.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		m.wait()
+		err = m.provider.send(m.from, recipients, subject, body, raw)
+		if err == nil {
+			break
+		}
+		apiErr, ok := err.(apiError)
+		if ok && !apiErr.retryable() {
+			m.stats.Inc(fmt.Sprintf("SendMail.Errors.API.%d", apiErr.status), 1)
+			return RecoverableSMTPError{apiErr.Error()}
+		}
+		if attempt >= m.maxAttempts {
+			m.stats.Inc("SendMail.Errors", 1)
+			return err
+		}
+		m.log.Warningf("sending mail failed, retrying: %s", err)
+		m.clk.Sleep(core.RetryBackoff(attempt, m.retryBase, time.Minute, 2))
+	}
+
+	m.stats.Inc("SendMail.Successes", 1)
+	return nil
+}
+
+// wait sleeps until the next message may be sent without exceeding the rate
+// limit.
+func (m *APIMailer) wait() {
+	if m.interval == 0 {
+		return
+	}
+	next := m.lastSent.Add(m.interval)
+	if now := m.clk.Now(); now.Before(next) {
+		m.clk.Sleep(next.Sub(now))
+	}
+	m.lastSent = m.clk.Now()
+}
+
+// doAPIRequest sends req, returning an apiError unless the response has the
+// expected status code.
+func doAPIRequest(client *http.Client, req *http.Request, expected int) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != expected {
+		return apiError{resp.StatusCode, string(body)}
+	}
+	return nil
+}
+
+// sesProvider sends raw messages with the SES SendRawEmail action.
+type sesProvider struct {
+	client          *http.Client
+	endpoint        string
+	accessKeyID     string
+	secretAccessKey string
+	region          string
+	clk             clock.Clock
+}
+
+func (p *sesProvider) send(from mail.Address, to []string, _, _ string, raw []byte) error {
+	form := url.Values{
+		"Action":          {"SendRawEmail"},
+		"Version":         {"2010-12-01"},
+		"Source":          {from.Address},
+		"RawMessage.Data": {base64.StdEncoding.EncodeToString(raw)},
+	}
+	for i, addr := range to {
+		form.Set(fmt.Sprintf("Destinations.member.%d", i+1), addr)
+	}
+	body := form.Encode()
+	req, err := http.NewRequest("POST", p.endpoint+"/", strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	p.sign(req, []byte(body), p.clk.Now().UTC())
+	return doAPIRequest(p.client, req, http.StatusOK)
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req.
+func (p *sesProvider) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	bodyHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		"/",
+		"",
+		"content-type:" + req.Header.Get("Content-Type"),
+		"host:" + req.URL.Host,
+		"x-amz-date:" + amzDate,
+		"",
+		"content-type;host;x-amz-date",
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+	scope := fmt.Sprintf("%s/%s/ses/aws4_request", date, p.region)
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := []byte("AWS4" + p.secretAccessKey)
+	for _, part := range []string{date, p.region, "ses", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=content-type;host;x-amz-date, Signature=%s",
+		p.accessKeyID,
+		scope,
+		hex.EncodeToString(hmacSHA256(key, stringToSign)),
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// sendGridProvider sends messages with the SendGrid v3 mail send API. It
+// doesn't accept raw messages, so DKIM signing has to be configured in
+// SendGrid's domain authentication settings instead.
+type sendGridProvider struct {
+	client   *http.Client
+	endpoint string
+	apiKey   string
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+func (p *sendGridProvider) send(from mail.Address, to []string, subject, body string, _ []byte) error {
+	var msg struct {
+		Personalizations []struct {
+			To []sendGridAddress `json:"to"`
+		} `json:"personalizations"`
+		From    sendGridAddress `json:"from"`
+		Subject string          `json:"subject"`
+		Content []struct {
+			Type  string `json:"type"`
+			Value string `json:"value"`
+		} `json:"content"`
+	}
+	msg.Personalizations = make([]struct {
+		To []sendGridAddress `json:"to"`
+	}, 1)
+	for _, addr := range to {
+		msg.Personalizations[0].To = append(msg.Personalizations[0].To, sendGridAddress{Email: addr})
+	}
+	msg.From = sendGridAddress{Email: from.Address, Name: from.Name}
+	msg.Subject = subject
+	msg.Content = []struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}{{"text/plain", body}}
+	reqJSON, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", p.endpoint+"/v3/mail/send", bytes.NewReader(reqJSON))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	return doAPIRequest(p.client, req, http.StatusAccepted)
+}
+
+// mailgunProvider sends raw messages with the Mailgun MIME message API.
+type mailgunProvider struct {
+	client   *http.Client
+	endpoint string
+	domain   string
+	apiKey   string
+}
+
+func (p *mailgunProvider) send(_ mail.Address, to []string, _, _ string, raw []byte) error {
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
+	for _, addr := range to {
+		if err := w.WriteField("to", addr); err != nil {
+			return err
+		}
+	}
+	part, err := w.CreateFormFile("message", "message.mime")
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(raw); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/v3/%s/messages.mime", p.endpoint, url.PathEscape(p.domain))
+	req, err := http.NewRequest("POST", endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth("api", p.apiKey)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return doAPIRequest(p.client, req, http.StatusOK)
+}
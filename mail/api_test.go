@@ -0,0 +1,235 @@
+package mail
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func newTestAPIMailer(t *testing.T, c APIConfig, suppressed *SuppressionList) (*APIMailer, clock.FakeClock) {
+	from, _ := mail.ParseAddress("sender <send@email.com>")
+	m, err := NewAPIMailer(c, *from, suppressed, nil, blog.NewMock(), metrics.NewNoopScope())
+	test.AssertNotError(t, err, "NewAPIMailer failed")
+	fc := clock.NewFake()
+	m.clk = fc
+	m.csprgSource = fakeSource{}
+	if p, ok := m.provider.(*sesProvider); ok {
+		p.clk = fc
+	}
+	return m, fc
+}
+
+func TestNewAPIMailer(t *testing.T) {
+	from, _ := mail.ParseAddress("send@email.com")
+	for _, c := range []APIConfig{
+		{Provider: "sendgrid"},
+		{Provider: "carrier-pigeon", APIKey: "key"},
+		{Provider: "ses", APIKey: "key"},
+		{Provider: "mailgun", APIKey: "key"},
+	} {
+		_, err := NewAPIMailer(c, *from, nil, nil, blog.NewMock(), metrics.NewNoopScope())
+		test.AssertError(t, err, "NewAPIMailer accepted an invalid config")
+	}
+}
+
+func TestAPIMailerSendGrid(t *testing.T) {
+	var requests []map[string]interface{}
+	status := http.StatusAccepted
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		test.AssertEquals(t, r.URL.Path, "/v3/mail/send")
+		test.AssertEquals(t, r.Header.Get("Authorization"), "Bearer key")
+		var req map[string]interface{}
+		test.AssertNotError(t, json.NewDecoder(r.Body).Decode(&req), "decoding request")
+		requests = append(requests, req)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	suppressed, err := NewSuppressionList("")
+	test.AssertNotError(t, err, "NewSuppressionList failed")
+	test.AssertNotError(t, suppressed.Add("Bounced@email.com"), "Add failed")
+	m, fc := newTestAPIMailer(t, APIConfig{Provider: "sendgrid", BaseURL: srv.URL, APIKey: "key", MaxPerSecond: 1}, suppressed)
+
+	err = m.SendMail([]string{"recv@email.com", "bounced@email.com"}, "subject", "body")
+	test.AssertNotError(t, err, "SendMail failed")
+	test.AssertEquals(t, len(requests), 1)
+	test.AssertEquals(t, requests[0]["subject"], "subject")
+	to := requests[0]["personalizations"].([]interface{})[0].(map[string]interface{})["to"].([]interface{})
+	test.AssertEquals(t, len(to), 1)
+
+	// Mail only to suppressed addresses isn't sent
+	err = m.SendMail([]string{"bounced@email.com"}, "subject", "body")
+	test.AssertNotError(t, err, "SendMail failed")
+	test.AssertEquals(t, len(requests), 1)
+
+	// The second message waits for the rate limit
+	started := fc.Now()
+	err = m.SendMail([]string{"recv@email.com"}, "subject", "body")
+	test.AssertNotError(t, err, "SendMail failed")
+	test.AssertEquals(t, fc.Since(started), time.Second)
+
+	// Rate limited requests are retried, and then fail
+	status = http.StatusTooManyRequests
+	err = m.SendMail([]string{"recv@email.com"}, "subject", "body")
+	test.AssertError(t, err, "SendMail succeeded with a 429 response")
+	test.AssertEquals(t, len(requests), 5)
+
+	// Rejected messages are recoverable
+	status = http.StatusBadRequest
+	err = m.SendMail([]string{"recv@email.com"}, "subject", "body")
+	_, ok := err.(RecoverableSMTPError)
+	test.Assert(t, ok, "400 response wasn't a RecoverableSMTPError")
+}
+
+func TestAPIMailerSES(t *testing.T) {
+	var form map[string][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		test.Assert(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/19700101/us-east-1/ses/aws4_request, "), "bad Authorization "+auth)
+		test.AssertEquals(t, r.Header.Get("X-Amz-Date"), "19700101T000000Z")
+		test.AssertNotError(t, r.ParseForm(), "parsing form")
+		form = r.PostForm
+	}))
+	defer srv.Close()
+
+	m, _ := newTestAPIMailer(t, APIConfig{Provider: "ses", BaseURL: srv.URL, APIKey: "secret", AccessKeyID: "AKID", Region: "us-east-1"}, nil)
+	err := m.SendMail([]string{"recv@email.com"}, "subject", "body")
+	test.AssertNotError(t, err, "SendMail failed")
+	test.AssertEquals(t, form["Action"][0], "SendRawEmail")
+	test.AssertEquals(t, form["Destinations.member.1"][0], "recv@email.com")
+	raw, err := base64.StdEncoding.DecodeString(form["RawMessage.Data"][0])
+	test.AssertNotError(t, err, "decoding raw message")
+	test.Assert(t, bytes.Contains(raw, []byte("Subject: subject\r\n")), "raw message is missing its subject")
+}
+
+func TestAPIMailerMailgun(t *testing.T) {
+	var to []string
+	var raw string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		test.AssertEquals(t, r.URL.Path, "/v3/mg.email.com/messages.mime")
+		user, pass, _ := r.BasicAuth()
+		test.AssertEquals(t, user+":"+pass, "api:key")
+		test.AssertNotError(t, r.ParseMultipartForm(1<<20), "parsing form")
+		to = r.MultipartForm.Value["to"]
+		f, _, err := r.FormFile("message")
+		test.AssertNotError(t, err, "reading message")
+		contents, _ := ioutil.ReadAll(f)
+		raw = string(contents)
+	}))
+	defer srv.Close()
+
+	m, _ := newTestAPIMailer(t, APIConfig{Provider: "mailgun", BaseURL: srv.URL, APIKey: "key", Domain: "mg.email.com"}, nil)
+	err := m.SendMail([]string{"a@email.com", "b@email.com"}, "subject", "body")
+	test.AssertNotError(t, err, "SendMail failed")
+	test.AssertDeepEquals(t, to, []string{"a@email.com", "b@email.com"})
+	test.Assert(t, strings.Contains(raw, "Subject: subject\r\n"), "raw message is missing its subject")
+}
+
+func TestDKIMSign(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	test.AssertNotError(t, err, "generating key")
+	dir, err := ioutil.TempDir("", "dkim")
+	test.AssertNotError(t, err, "TempDir failed")
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "dkim.pem")
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	test.AssertNotError(t, ioutil.WriteFile(keyFile, keyPEM, 0600), "writing key")
+
+	_, err = NewDKIMSigner("", "s1", keyFile)
+	test.AssertError(t, err, "NewDKIMSigner accepted an empty domain")
+	signer, err := NewDKIMSigner("email.com", "s1", keyFile)
+	test.AssertNotError(t, err, "NewDKIMSigner failed")
+
+	msg := "From: send@email.com\r\nTo: recv@email.com\r\nSubject:  a   subject \r\n\r\nbody  text \r\n\r\n"
+	signed, err := signer.Sign([]byte(msg), time.Unix(1000, 0))
+	test.AssertNotError(t, err, "Sign failed")
+	lines := strings.SplitN(string(signed), "\r\n", 2)
+	test.AssertEquals(t, lines[1], msg)
+	sigHeader := lines[0]
+	test.Assert(t, strings.HasPrefix(sigHeader, "DKIM-Signature: v=1; a=rsa-sha256; c=relaxed/relaxed; d=email.com; s=s1; t=1000; "), "unexpected header "+sigHeader)
+
+	bodyHash := sha256.Sum256([]byte("body text\r\n"))
+	test.Assert(t, strings.Contains(sigHeader, "bh="+base64.StdEncoding.EncodeToString(bodyHash[:])+";"), "wrong body hash")
+
+	// Verify the signature over the canonicalized headers
+	b := strings.LastIndex(sigHeader, "b=")
+	sig, err := base64.StdEncoding.DecodeString(sigHeader[b+2:])
+	test.AssertNotError(t, err, "decoding signature")
+	_, canonSig := relaxedHeader(sigHeader[:b+2])
+	h := sha256.Sum256([]byte("from:send@email.com\r\nto:recv@email.com\r\nsubject:a subject\r\n" + canonSig))
+	err = rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, h[:], sig)
+	test.AssertNotError(t, err, "DKIM signature didn't verify")
+}
+
+func TestBounceHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "suppress")
+	test.AssertNotError(t, err, "TempDir failed")
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "suppressed")
+	list, err := NewSuppressionList(file)
+	test.AssertNotError(t, err, "NewSuppressionList failed")
+
+	_, err = NewBounceHandler("sendgrid", "", list, blog.NewMock())
+	test.AssertError(t, err, "NewBounceHandler accepted an empty secret")
+
+	post := func(provider, secret, body string) int {
+		h, err := NewBounceHandler(provider, "hunter2", list, blog.NewMock())
+		test.AssertNotError(t, err, "NewBounceHandler failed")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/bounces?secret="+secret, strings.NewReader(body)))
+		return w.Code
+	}
+
+	test.AssertEquals(t, post("sendgrid", "wrong", `[{"email": "a@email.com", "event": "bounce"}]`), http.StatusForbidden)
+	test.Assert(t, !list.Suppressed("a@email.com"), "address suppressed without the secret")
+
+	test.AssertEquals(t, post("sendgrid", "hunter2", `[{"email": "a@email.com", "event": "bounce"}, {"email": "b@email.com", "event": "delivered"}]`), http.StatusOK)
+	test.AssertEquals(t, post("mailgun", "hunter2", `{"event-data": {"event": "failed", "severity": "permanent", "recipient": "c@email.com"}}`), http.StatusOK)
+	test.AssertEquals(t, post("mailgun", "hunter2", `{"event-data": {"event": "failed", "severity": "temporary", "recipient": "d@email.com"}}`), http.StatusOK)
+	sesMsg, _ := json.Marshal(map[string]interface{}{
+		"notificationType": "Bounce",
+		"bounce": map[string]interface{}{
+			"bounceType":        "Permanent",
+			"bouncedRecipients": []map[string]string{{"emailAddress": "E@email.com"}},
+		},
+	})
+	sesBody, _ := json.Marshal(map[string]string{"Type": "Notification", "Message": string(sesMsg)})
+	test.AssertEquals(t, post("ses", "hunter2", string(sesBody)), http.StatusOK)
+	test.AssertEquals(t, post("ses", "hunter2", "not json"), http.StatusBadRequest)
+
+	for addr, suppressed := range map[string]bool{
+		"a@email.com": true,
+		"b@email.com": false,
+		"c@email.com": true,
+		"d@email.com": false,
+		"e@email.com": true,
+	} {
+		test.AssertEquals(t, list.Suppressed(addr), suppressed)
+	}
+
+	// The suppressed addresses are loaded by the next list
+	list, err = NewSuppressionList(file)
+	test.AssertNotError(t, err, "NewSuppressionList failed")
+	test.Assert(t, list.Suppressed("E@email.com"), "suppressed address wasn't saved")
+}
@@ -0,0 +1,143 @@
+package mail
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// dkimSignedHeaders are the headers covered by DKIM signatures, in the order
+// they are signed.
+var dkimSignedHeaders = []string{"from", "to", "subject", "date", "message-id"}
+
+// DKIMSigner adds DKIM signatures (RFC 6376) to messages, using the "relaxed"
+// canonicalization for both headers and body.
+type DKIMSigner struct {
+	domain   string
+	selector string
+	key      *rsa.PrivateKey
+}
+
+// NewDKIMSigner returns a DKIMSigner that signs for domain using the RSA key
+// in keyFile, which is published in DNS under selector.
+func NewDKIMSigner(domain, selector, keyFile string) (*DKIMSigner, error) {
+	if domain == "" || selector == "" {
+		return nil, errors.New("DKIM domain and selector are required")
+	}
+	keyPEM, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in DKIM key file %q", keyFile)
+	}
+	var key interface{}
+	key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing DKIM key: %s", err)
+		}
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("DKIM key must be an RSA key")
+	}
+	return &DKIMSigner{domain: domain, selector: selector, key: rsaKey}, nil
+}
+
+// Sign returns msg, which must use CRLF line endings, with a DKIM-Signature
+// header prepended.
+func (d *DKIMSigner) Sign(msg []byte, now time.Time) ([]byte, error) {
+	parts := bytes.SplitN(msg, []byte("\r\n\r\n"), 2)
+	if len(parts) != 2 {
+		return nil, errors.New("message has no body")
+	}
+	headers := make(map[string]string)
+	for _, h := range strings.Split(string(parts[0]), "\r\n") {
+		name, canon := relaxedHeader(h)
+		headers[name] = canon
+	}
+
+	bodyHash := sha256.Sum256(relaxedBody(parts[1]))
+	sigHeader := fmt.Sprintf(
+		"DKIM-Signature: v=1; a=rsa-sha256; c=relaxed/relaxed; d=%s; s=%s; t=%d; h=%s; bh=%s; b=",
+		d.domain,
+		d.selector,
+		now.Unix(),
+		strings.Join(dkimSignedHeaders, ":"),
+		base64.StdEncoding.EncodeToString(bodyHash[:]),
+	)
+
+	h := sha256.New()
+	for _, name := range dkimSignedHeaders {
+		if canon, ok := headers[name]; ok {
+			h.Write([]byte(canon + "\r\n"))
+		}
+	}
+	// The signature header itself is signed with an empty b= tag and no
+	// trailing CRLF.
+	_, canonSig := relaxedHeader(sigHeader)
+	h.Write([]byte(canonSig))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, d.key, crypto.SHA256, h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	signed := new(bytes.Buffer)
+	signed.WriteString(sigHeader)
+	signed.WriteString(base64.StdEncoding.EncodeToString(sig))
+	signed.WriteString("\r\n")
+	signed.Write(msg)
+	return signed.Bytes(), nil
+}
+
+// relaxedHeader returns the lowercased name of a header line and the line in
+// the "relaxed" header canonicalization.
+func relaxedHeader(line string) (string, string) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return "", ""
+	}
+	name := strings.ToLower(strings.TrimSpace(line[:colon]))
+	value := strings.Join(strings.Fields(line[colon+1:]), " ")
+	return name, name + ":" + value
+}
+
+// relaxedBody returns body in the "relaxed" body canonicalization.
+func relaxedBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	for i, line := range lines {
+		// Collapse runs of whitespace and remove whitespace at the end of
+		// lines.
+		fields := strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == '\t' })
+		if len(fields) == 0 {
+			lines[i] = ""
+			continue
+		}
+		sep := ""
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			sep = " "
+		}
+		lines[i] = sep + strings.Join(fields, " ")
+	}
+	// Remove empty lines at the end of the body.
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
@@ -61,6 +61,7 @@ type MailerImpl struct {
 	stats         metrics.Scope
 	reconnectBase time.Duration
 	reconnectMax  time.Duration
+	dkim          *DKIMSigner
 }
 
 type dialer interface {
@@ -155,9 +156,25 @@ func NewDryRun(from mail.Address, logger blog.Logger) *MailerImpl {
 	}
 }
 
+// SetDKIMSigner makes the mailer add a DKIM signature from signer to every
+// message it sends.
+func (m *MailerImpl) SetDKIMSigner(signer *DKIMSigner) {
+	m.dkim = signer
+}
+
 func (m *MailerImpl) generateMessage(to []string, subject, body string) ([]byte, error) {
-	mid := m.csprgSource.generate()
-	now := m.clk.Now().UTC()
+	msg, err := generateMessage(m.from, m.clk, m.csprgSource, to, subject, body)
+	if err != nil || m.dkim == nil {
+		return msg, err
+	}
+	return m.dkim.Sign(msg, m.clk.Now())
+}
+
+// generateMessage returns a plain text message from from to the addresses in
+// to, with a Message-Id made from a random number from csprgSource.
+func generateMessage(from mail.Address, clk clock.Clock, csprgSource idGenerator, to []string, subject, body string) ([]byte, error) {
+	mid := csprgSource.generate()
+	now := clk.Now().UTC()
 	addrs := []string{}
 	for _, a := range to {
 		if !core.IsASCII(a) {
@@ -167,10 +184,10 @@ func (m *MailerImpl) generateMessage(to []string, subject, body string) ([]byte,
 	}
 	headers := []string{
 		fmt.Sprintf("To: %s", strings.Join(addrs, ", ")),
-		fmt.Sprintf("From: %s", m.from.String()),
+		fmt.Sprintf("From: %s", from.String()),
 		fmt.Sprintf("Subject: %s", subject),
 		fmt.Sprintf("Date: %s", now.Format(time.RFC822)),
-		fmt.Sprintf("Message-Id: <%s.%s.%s>", now.Format("20060102T150405"), mid.String(), m.from.Address),
+		fmt.Sprintf("Message-Id: <%s.%s.%s>", now.Format("20060102T150405"), mid.String(), from.Address),
 		"MIME-Version: 1.0",
 		"Content-Type: text/plain; charset=UTF-8",
 		"Content-Transfer-Encoding: quoted-printable",
@@ -0,0 +1,223 @@
+package mail
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	blog "github.com/letsencrypt/boulder/log"
+)
+
+// SuppressionList is a set of addresses that mail should no longer be sent
+// to, because mail to them has bounced. If it has a file, the list is loaded
+// from it and addresses added to the list are appended to it, one per line.
+type SuppressionList struct {
+	mu        sync.RWMutex
+	addresses map[string]bool
+	file      string
+}
+
+// NewSuppressionList returns a SuppressionList backed by file, or held only
+// in memory if file is empty.
+func NewSuppressionList(file string) (*SuppressionList, error) {
+	sl := &SuppressionList{
+		addresses: make(map[string]bool),
+		file:      file,
+	}
+	if file == "" {
+		return sl, nil
+	}
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return sl, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if addr := strings.TrimSpace(scanner.Text()); addr != "" {
+			sl.addresses[strings.ToLower(addr)] = true
+		}
+	}
+	return sl, scanner.Err()
+}
+
+// Suppressed returns true if mail shouldn't be sent to address.
+func (sl *SuppressionList) Suppressed(address string) bool {
+	sl.mu.RLock()
+	defer sl.mu.RUnlock()
+	return sl.addresses[strings.ToLower(address)]
+}
+
+// Add adds address to the list, saving it to the list's file if it has one.
+func (sl *SuppressionList) Add(address string) error {
+	address = strings.ToLower(strings.TrimSpace(address))
+	if address == "" || strings.ContainsAny(address, "\r\n") {
+		return fmt.Errorf("invalid address to suppress %q", address)
+	}
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	if sl.addresses[address] {
+		return nil
+	}
+	if sl.file != "" {
+		f, err := os.OpenFile(sl.file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+		if err != nil {
+			return err
+		}
+		if _, err := f.WriteString(address + "\n"); err != nil {
+			_ = f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	sl.addresses[address] = true
+	return nil
+}
+
+// bounceHandler receives a mail provider's bounce notifications and adds the
+// addresses that bounced permanently to a SuppressionList.
+type bounceHandler struct {
+	provider string
+	secret   string
+	list     *SuppressionList
+	log      blog.Logger
+}
+
+// NewBounceHandler returns an http.Handler for the bounce webhooks of
+// provider, which is "ses", "sendgrid" or "mailgun". Requests must include
+// secret in a "secret" query parameter, so the webhook URL configured with
+// the provider should include it.
+func NewBounceHandler(provider, secret string, list *SuppressionList, logger blog.Logger) (http.Handler, error) {
+	switch provider {
+	case "ses", "sendgrid", "mailgun":
+	default:
+		return nil, fmt.Errorf("unknown mail provider %q", provider)
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("bounce webhook secret is required")
+	}
+	return &bounceHandler{provider: provider, secret: secret, list: list, log: logger}, nil
+}
+
+func (bh *bounceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("secret")), []byte(bh.secret)) != 1 {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var bounced []string
+	switch bh.provider {
+	case "ses":
+		bounced, err = sesBounces(body)
+	case "sendgrid":
+		bounced, err = sendGridBounces(body)
+	case "mailgun":
+		bounced, err = mailgunBounces(body)
+	}
+	if err != nil {
+		bh.log.Warningf("Failed to parse %s bounce notification: %s", bh.provider, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for _, addr := range bounced {
+		if err := bh.list.Add(addr); err != nil {
+			bh.log.Errf("Failed to suppress bounced address %q: %s", addr, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		bh.log.Infof("Suppressing mail to %q after a permanent bounce", addr)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// sesBounces returns the addresses that bounced permanently in an SNS
+// notification of an SES bounce.
+func sesBounces(body []byte) ([]string, error) {
+	var notification struct {
+		Type    string
+		Message string
+	}
+	if err := json.Unmarshal(body, &notification); err != nil {
+		return nil, err
+	}
+	if notification.Type != "Notification" {
+		// Subscription confirmations have to be confirmed by hand.
+		return nil, nil
+	}
+	var msg struct {
+		NotificationType string `json:"notificationType"`
+		Bounce           struct {
+			BounceType        string `json:"bounceType"`
+			BouncedRecipients []struct {
+				EmailAddress string `json:"emailAddress"`
+			} `json:"bouncedRecipients"`
+		} `json:"bounce"`
+	}
+	if err := json.Unmarshal([]byte(notification.Message), &msg); err != nil {
+		return nil, err
+	}
+	if msg.NotificationType != "Bounce" || msg.Bounce.BounceType != "Permanent" {
+		return nil, nil
+	}
+	var bounced []string
+	for _, r := range msg.Bounce.BouncedRecipients {
+		bounced = append(bounced, r.EmailAddress)
+	}
+	return bounced, nil
+}
+
+// sendGridBounces returns the addresses that bounced in a batch of SendGrid
+// events.
+func sendGridBounces(body []byte) ([]string, error) {
+	var events []struct {
+		Email string `json:"email"`
+		Event string `json:"event"`
+	}
+	if err := json.Unmarshal(body, &events); err != nil {
+		return nil, err
+	}
+	var bounced []string
+	for _, e := range events {
+		if e.Event == "bounce" {
+			bounced = append(bounced, e.Email)
+		}
+	}
+	return bounced, nil
+}
+
+// mailgunBounces returns the address that bounced permanently in a Mailgun
+// event, if any.
+func mailgunBounces(body []byte) ([]string, error) {
+	var event struct {
+		EventData struct {
+			Event     string `json:"event"`
+			Severity  string `json:"severity"`
+			Recipient string `json:"recipient"`
+		} `json:"event-data"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+	if event.EventData.Event != "failed" || event.EventData.Severity != "permanent" {
+		return nil, nil
+	}
+	return []string{event.EventData.Recipient}, nil
+}
@@ -10,10 +10,11 @@ import (
 )
 
 // responseWriterWithStatus satisfies http.ResponseWriter, but keeps track of the
-// status code for gathering stats.
+// status code and body size for gathering stats.
 type responseWriterWithStatus struct {
 	http.ResponseWriter
 	code int
+	size int
 }
 
 // WriteHeader stores a status code for generating stats.
@@ -28,7 +29,22 @@ func (r *responseWriterWithStatus) Write(body []byte) (int, error) {
 	if r.code == 0 {
 		r.code = http.StatusOK
 	}
-	return r.ResponseWriter.Write(body)
+	n, err := r.ResponseWriter.Write(body)
+	r.size += n
+	return n, err
+}
+
+// statusClass returns the class ("1xx" to "5xx") of a status code, which is
+// a better label than the code itself for alerting on error rates.
+func statusClass(code int) string {
+	if code == 0 {
+		// Nothing was written, so net/http responds 200 OK
+		code = http.StatusOK
+	}
+	if code < 100 || code > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(code/100) + "xx"
 }
 
 // serveMux is a partial interface wrapper for the method http.ServeMux
@@ -45,6 +61,10 @@ type MeasuredHandler struct {
 	clk clock.Clock
 	// Normally this is always responseTime, but we override it for testing.
 	stat *prometheus.HistogramVec
+	// size, statusClass and inFlight are only recorded if non-nil.
+	size        *prometheus.HistogramVec
+	statusClass *prometheus.CounterVec
+	inFlight    *prometheus.GaugeVec
 }
 
 func New(m serveMux, clk clock.Clock, scope metrics.Scope) *MeasuredHandler {
@@ -55,16 +75,41 @@ func New(m serveMux, clk clock.Clock, scope metrics.Scope) *MeasuredHandler {
 		},
 		[]string{"endpoint", "method", "code"})
 	scope.MustRegister(responseTime)
+	responseSize := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "response_size_bytes",
+			Help:    "Size of response bodies",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+		},
+		[]string{"endpoint", "method"})
+	scope.MustRegister(responseSize)
+	responses := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "responses",
+			Help: "Number of responses, by status class (e.g. 4xx)",
+		},
+		[]string{"endpoint", "method", "class"})
+	scope.MustRegister(responses)
+	inFlight := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "in_flight_requests",
+			Help: "Number of requests being handled",
+		},
+		[]string{"endpoint", "method"})
+	scope.MustRegister(inFlight)
 	return &MeasuredHandler{
-		serveMux: m,
-		clk:      clk,
-		stat:     responseTime,
+		serveMux:    m,
+		clk:         clk,
+		stat:        responseTime,
+		size:        responseSize,
+		statusClass: responses,
+		inFlight:    inFlight,
 	}
 }
 
 func (h *MeasuredHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	begin := h.clk.Now()
-	rwws := &responseWriterWithStatus{w, 0}
+	rwws := &responseWriterWithStatus{ResponseWriter: w}
 
 	// Use the method string only if it's a recognized HTTP method. This avoids
 	// ballooning timeseries with invalid methods from public input.
@@ -79,12 +124,32 @@ func (h *MeasuredHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	subHandler, pattern := h.Handler(r)
+	endpointLabels := prometheus.Labels{
+		"endpoint": pattern,
+		"method":   method,
+	}
+	if h.inFlight != nil {
+		h.inFlight.With(endpointLabels).Inc()
+	}
 	defer func() {
 		h.stat.With(prometheus.Labels{
 			"endpoint": pattern,
 			"method":   method,
 			"code":     strconv.Itoa(rwws.code),
 		}).Observe(h.clk.Since(begin).Seconds())
+		if h.inFlight != nil {
+			h.inFlight.With(endpointLabels).Dec()
+		}
+		if h.size != nil {
+			h.size.With(endpointLabels).Observe(float64(rwws.size))
+		}
+		if h.statusClass != nil {
+			h.statusClass.With(prometheus.Labels{
+				"endpoint": pattern,
+				"method":   method,
+				"class":    statusClass(rwws.code),
+			}).Inc()
+		}
 	}()
 
 	subHandler.ServeHTTP(rwws, r)
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/jmhodges/clock"
+	"github.com/letsencrypt/boulder/metrics"
 	"github.com/prometheus/client_golang/prometheus"
 	io_prometheus_client "github.com/prometheus/client_model/go"
 )
@@ -208,3 +209,47 @@ func TestWrite(t *testing.T) {
 		t.Errorf("Some labels were expected, but not observed: %v", expectedLabels)
 	}
 }
+
+func TestSizeAndStatusClass(t *testing.T) {
+	clk := clock.NewFake()
+	scope := metrics.NewNoopScope()
+	mux := http.NewServeMux()
+	var inFlightDuringRequest float64
+	var mh *MeasuredHandler
+	mux.HandleFunc("/foo", func(w http.ResponseWriter, r *http.Request) {
+		inFlightDuringRequest = collect(mh.inFlight).Gauge.GetValue()
+		w.WriteHeader(404)
+		_, _ = w.Write([]byte("not found"))
+	})
+	mh = New(mux, clk, scope)
+	mh.ServeHTTP(httptest.NewRecorder(), &http.Request{
+		URL:    &url.URL{Path: "/foo"},
+		Method: "GET",
+	})
+
+	if inFlightDuringRequest != 1 {
+		t.Errorf("in-flight requests during the request = %g (expected 1)", inFlightDuringRequest)
+	}
+	if v := collect(mh.inFlight).Gauge.GetValue(); v != 0 {
+		t.Errorf("in-flight requests after the request = %g (expected 0)", v)
+	}
+	hist := collect(mh.size).Histogram
+	if hist.GetSampleCount() != 1 || hist.GetSampleSum() != 9 {
+		t.Errorf("size histogram count = %d, sum = %g (expected 1, 9)", hist.GetSampleCount(), hist.GetSampleSum())
+	}
+	iom := collect(mh.statusClass)
+	for _, labelPair := range iom.Label {
+		if labelPair.GetName() == "class" && labelPair.GetValue() != "4xx" {
+			t.Errorf("class = %q (expected 4xx)", labelPair.GetValue())
+		}
+	}
+	if iom.Counter.GetValue() != 1 {
+		t.Errorf("responses = %g (expected 1)", iom.Counter.GetValue())
+	}
+
+	for code, class := range map[int]string{0: "2xx", 200: "2xx", 302: "3xx", 503: "5xx", 42: "unknown"} {
+		if statusClass(code) != class {
+			t.Errorf("statusClass(%d) = %q (expected %q)", code, statusClass(code), class)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	caPB "github.com/letsencrypt/boulder/ca/proto"
 	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/probs"
 	"github.com/letsencrypt/boulder/revocation"
 )
 
@@ -53,6 +54,11 @@ func (ca *MockCA) IssueCertificateForPrecertificate(ctx context.Context, req *ca
 	return core.Certificate{DER: req.DER}, nil
 }
 
+// CheckCertificateRequest is a mock
+func (ca *MockCA) CheckCertificateRequest(ctx context.Context, _ *caPB.IssueCertificateRequest) ([]*probs.ProblemDetails, error) {
+	return nil, nil
+}
+
 // GenerateOCSP is a mock
 func (ca *MockCA) GenerateOCSP(ctx context.Context, xferObj core.OCSPSigningRequest) (ocsp []byte, err error) {
 	return
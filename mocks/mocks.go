@@ -379,6 +379,12 @@ func (sa *StorageAuthority) PreviousCertificateExists(
 	}, nil
 }
 
+// CertificateReplaced is a mock
+func (sa *StorageAuthority) CertificateReplaced(_ context.Context, _ *sapb.Serial) (*sapb.Exists, error) {
+	f := false
+	return &sapb.Exists{Exists: &f}, nil
+}
+
 func (sa *StorageAuthority) GetPendingAuthorization(ctx context.Context, req *sapb.GetPendingAuthorizationRequest) (*core.Authorization, error) {
 	return nil, nil
 }
@@ -452,6 +458,11 @@ func (sa *StorageAuthority) DeactivateAuthorization(_ context.Context, _ string)
 	return nil
 }
 
+// DeactivateAuthorization2 is a mock
+func (sa *StorageAuthority) DeactivateAuthorization2(_ context.Context, _ *sapb.AuthorizationID2) (*corepb.Empty, error) {
+	return &corepb.Empty{}, nil
+}
+
 // DeactivateRegistration is a mock
 func (sa *StorageAuthority) DeactivateRegistration(_ context.Context, _ int64) error {
 	return nil
@@ -541,6 +552,11 @@ func (sa *StorageAuthority) GetValidOrderAuthorizations(_ context.Context, _ *sa
 	return nil, nil
 }
 
+// GetOrderAuthorizations is a mock
+func (sa *StorageAuthority) GetOrderAuthorizations(_ context.Context, _ *sapb.GetOrderAuthorizationsRequest) (map[string]*core.Authorization, error) {
+	return nil, nil
+}
+
 // GetAuthorizations is a mock
 func (sa *StorageAuthority) GetAuthorizations(ctx context.Context, req *sapb.GetAuthorizationsRequest) (*sapb.Authorizations, error) {
 	return &sapb.Authorizations{}, nil
@@ -615,6 +631,82 @@ func (sa *StorageAuthority) RevokeCertificate(ctx context.Context, req *sapb.Rev
 	return nil
 }
 
+// GetRateLimitOverrides is a mock
+func (sa *StorageAuthority) GetRateLimitOverrides(ctx context.Context, req *sapb.GetRateLimitOverridesRequest) (*sapb.RateLimitOverrides, error) {
+	return &sapb.RateLimitOverrides{}, nil
+}
+
+// SearchCertificates is a mock
+func (sa *StorageAuthority) SearchCertificates(ctx context.Context, req *sapb.SearchCertificatesRequest) (*sapb.CertificateSearchResults, error) {
+	return &sapb.CertificateSearchResults{}, nil
+}
+
+// ListCertificatesForAccount is a mock
+func (sa *StorageAuthority) ListCertificatesForAccount(ctx context.Context, req *sapb.ListCertificatesForAccountRequest) (*sapb.CertificateSearchResults, error) {
+	return &sapb.CertificateSearchResults{}, nil
+}
+
+// ListOrdersForAccount is a mock
+func (sa *StorageAuthority) ListOrdersForAccount(ctx context.Context, req *sapb.ListOrdersForAccountRequest) (*sapb.OrderList, error) {
+	return &sapb.OrderList{}, nil
+}
+
+// ListInvalidAuthorizationsForAccount is a mock
+func (sa *StorageAuthority) ListInvalidAuthorizationsForAccount(ctx context.Context, req *sapb.ListInvalidAuthorizationsForAccountRequest) (*sapb.AuthorizationList, error) {
+	return &sapb.AuthorizationList{}, nil
+}
+
+// AddRateLimitOverride is a mock
+func (sa *StorageAuthority) AddRateLimitOverride(ctx context.Context, req *sapb.RateLimitOverride) (*sapb.RateLimitOverride, error) {
+	return req, nil
+}
+
+// ExpireRateLimitOverride is a mock
+func (sa *StorageAuthority) ExpireRateLimitOverride(ctx context.Context, req *sapb.ExpireRateLimitOverrideRequest) error {
+	return nil
+}
+
+// GetChallengeWhitelist is a mock
+func (sa *StorageAuthority) GetChallengeWhitelist(ctx context.Context, req *sapb.GetChallengeWhitelistRequest) (*sapb.ChallengeWhitelistEntries, error) {
+	return &sapb.ChallengeWhitelistEntries{}, nil
+}
+
+// AddChallengeWhitelistEntry is a mock
+func (sa *StorageAuthority) AddChallengeWhitelistEntry(ctx context.Context, req *sapb.ChallengeWhitelistEntry) (*sapb.ChallengeWhitelistEntry, error) {
+	return req, nil
+}
+
+// RemoveChallengeWhitelistEntry is a mock
+func (sa *StorageAuthority) RemoveChallengeWhitelistEntry(ctx context.Context, req *sapb.RemoveChallengeWhitelistEntryRequest) error {
+	return nil
+}
+
+// GetStaleDNS01Rechecks is a mock
+func (sa *StorageAuthority) GetStaleDNS01Rechecks(ctx context.Context, req *sapb.GetStaleDNS01RechecksRequest) (*sapb.DNS01Rechecks, error) {
+	return &sapb.DNS01Rechecks{}, nil
+}
+
+// AddDNS01Recheck is a mock
+func (sa *StorageAuthority) AddDNS01Recheck(ctx context.Context, req *sapb.DNS01Recheck) error {
+	return nil
+}
+
+// ClaimDNS01Recheck is a mock
+func (sa *StorageAuthority) ClaimDNS01Recheck(ctx context.Context, req *sapb.DNS01Recheck) (*sapb.Exists, error) {
+	claimed := true
+	return &sapb.Exists{Exists: &claimed}, nil
+}
+
+// RemoveDNS01Recheck is a mock
+func (sa *StorageAuthority) RemoveDNS01Recheck(ctx context.Context, req *sapb.AuthorizationID) error {
+	return nil
+}
+
+// RetryChallenge is a mock
+func (sa *StorageAuthority) RetryChallenge(ctx context.Context, req *sapb.RetryChallengeRequest) error {
+	return nil
+}
+
 // Publisher is a mock
 type Publisher struct {
 	// empty
@@ -11,28 +11,81 @@
 // The MaxUsed value determines how long a generated nonce can be used before it
 // is forgotten. To calculate that period, divide the MaxUsed value by average
 // redemption rate (valid POSTs per second).
+// Each nonce also carries, in the clear, a random prefix identifying the nonce
+// service that issued it, and encrypted alongside the counter, the time it was
+// issued. They let a nonce that can't be redeemed be diagnosed: whether it was
+// issued by another instance, was forgotten, or was never issued at all.
 package nonce
 
 import (
+	"bytes"
 	"container/heap"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/base64"
-	"errors"
-	"math/big"
+	"encoding/binary"
+	"fmt"
 	"sync"
 	"time"
 
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/letsencrypt/boulder/metrics"
 )
 
 // MaxUsed defines the maximum number of Nonces we're willing to hold in
 // memory.
 const MaxUsed = 65536
-const nonceLen = 32
 
-var errInvalidNonceLength = errors.New("invalid nonce length")
+const (
+	// prefixLen is the length of the prefix identifying the nonce service
+	// that issued a nonce.
+	prefixLen = 4
+	// randLen is the length of the random part of the GCM nonce, which
+	// follows the prefix.
+	randLen = 8
+	// plaintextLen is the length of the encrypted counter and issuance time.
+	plaintextLen = 16
+	nonceLen     = prefixLen + randLen + plaintextLen + 16
+)
+
+// The causes of a BadNonceError
+const (
+	// BadNonceMalformed is a nonce that isn't in the format of a nonce.
+	BadNonceMalformed = "malformed"
+	// BadNonceWrongInstance is a nonce issued by a different nonce service,
+	// usually another WFE instance or an earlier run of this one. Many of them
+	// suggest clients aren't sticking to one WFE, or WFEs are restarting.
+	BadNonceWrongInstance = "wrong_instance"
+	// BadNonceNeverIssued is a nonce that looks like it was issued by this
+	// nonce service, but wasn't: it's forged or corrupted.
+	BadNonceNeverIssued = "never_issued"
+	// BadNonceExpired is a nonce that was issued by this nonce service, but
+	// so long ago that it has been forgotten. Many of them suggest MaxUsed is
+	// too low for the redemption rate.
+	BadNonceExpired = "expired"
+	// BadNonceAlreadyUsed is a nonce that has already been redeemed.
+	BadNonceAlreadyUsed = "already_used"
+)
+
+// BadNonceError is the error Redeem returns for a nonce that can't be
+// redeemed.
+type BadNonceError struct {
+	// Cause is one of the BadNonce constants.
+	Cause string
+	// Age is how long ago the nonce was issued, if it was issued by this
+	// nonce service, or otherwise 0.
+	Age time.Duration
+}
+
+func (e *BadNonceError) Error() string {
+	if e.Age > 0 {
+		return fmt.Sprintf("bad nonce: %s (issued %s ago)", e.Cause, e.Age)
+	}
+	return fmt.Sprintf("bad nonce: %s", e.Cause)
+}
 
 // NonceService generates, cancels, and tracks Nonces.
 type NonceService struct {
@@ -42,8 +95,14 @@ type NonceService struct {
 	used     map[int64]bool
 	usedHeap *int64Heap
 	gcm      cipher.AEAD
+	prefix   []byte
 	maxUsed  int
+	clk      clock.Clock
 	stats    metrics.Scope
+
+	issued      prometheus.Counter
+	redemptions *prometheus.CounterVec
+	age         *prometheus.HistogramVec
 }
 
 type int64Heap []int64
@@ -71,6 +130,10 @@ func NewNonceService(scope metrics.Scope) (*NonceService, error) {
 	if _, err := rand.Read(key); err != nil {
 		return nil, err
 	}
+	prefix := make([]byte, prefixLen)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
 
 	c, err := aes.NewCipher(key)
 	if err != nil {
@@ -81,64 +144,78 @@ func NewNonceService(scope metrics.Scope) (*NonceService, error) {
 		panic("Failure in NewGCM: " + err.Error())
 	}
 
+	issued := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "nonces_issued",
+		Help: "Number of nonces issued",
+	})
+	redemptions := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "nonce_redemptions",
+		Help: "Number of nonces redeemed, by result: valid, or the cause of a bad nonce",
+	}, []string{"result"})
+	age := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "nonce_redemption_age_seconds",
+		Help:    "Time between a nonce being issued and redeemed, by result, for nonces issued by this instance",
+		Buckets: []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 3600, 14400, 86400},
+	}, []string{"result"})
+	scope.MustRegister(issued, redemptions, age)
+
 	return &NonceService{
-		earliest: 0,
-		latest:   0,
-		used:     make(map[int64]bool, MaxUsed),
-		usedHeap: &int64Heap{},
-		gcm:      gcm,
-		maxUsed:  MaxUsed,
-		stats:    scope,
+		earliest:    0,
+		latest:      0,
+		used:        make(map[int64]bool, MaxUsed),
+		usedHeap:    &int64Heap{},
+		gcm:         gcm,
+		prefix:      prefix,
+		maxUsed:     MaxUsed,
+		clk:         clock.Default(),
+		stats:       scope,
+		issued:      issued,
+		redemptions: redemptions,
+		age:         age,
 	}, nil
 }
 
-func (ns *NonceService) encrypt(counter int64) (string, error) {
-	// Generate a nonce with upper 4 bytes zero
-	nonce := make([]byte, 12)
-	for i := 0; i < 4; i++ {
-		nonce[i] = 0
-	}
-	if _, err := rand.Read(nonce[4:]); err != nil {
+func (ns *NonceService) encrypt(counter int64, issued time.Time) (string, error) {
+	// The GCM nonce is the prefix followed by random bytes
+	nonce := make([]byte, prefixLen+randLen)
+	copy(nonce, ns.prefix)
+	if _, err := rand.Read(nonce[prefixLen:]); err != nil {
 		return "", err
 	}
 
-	// Encode counter to plaintext
-	pt := make([]byte, 8)
-	ctr := big.NewInt(counter)
-	pad := 8 - len(ctr.Bytes())
-	copy(pt[pad:], ctr.Bytes())
+	// Encode counter and issuance time to plaintext
+	pt := make([]byte, plaintextLen)
+	binary.BigEndian.PutUint64(pt, uint64(counter))
+	binary.BigEndian.PutUint64(pt[8:], uint64(issued.UnixNano()))
 
 	// Encrypt
 	ret := make([]byte, nonceLen)
 	ct := ns.gcm.Seal(nil, nonce, pt, nil)
-	copy(ret, nonce[4:])
-	copy(ret[8:], ct)
+	copy(ret, nonce)
+	copy(ret[len(nonce):], ct)
 	return base64.RawURLEncoding.EncodeToString(ret), nil
 }
 
-func (ns *NonceService) decrypt(nonce string) (int64, error) {
+// decrypt returns the counter and issuance time of a nonce, or a
+// BadNonceError if it wasn't issued by this nonce service.
+func (ns *NonceService) decrypt(nonce string) (int64, time.Time, *BadNonceError) {
 	decoded, err := base64.RawURLEncoding.DecodeString(nonce)
-	if err != nil {
-		return 0, err
-	}
-	if len(decoded) != nonceLen {
-		return 0, errInvalidNonceLength
+	if err != nil || len(decoded) != nonceLen {
+		return 0, time.Time{}, &BadNonceError{Cause: BadNonceMalformed}
 	}
-
-	n := make([]byte, 12)
-	for i := 0; i < 4; i++ {
-		n[i] = 0
+	if !bytes.Equal(decoded[:prefixLen], ns.prefix) {
+		return 0, time.Time{}, &BadNonceError{Cause: BadNonceWrongInstance}
 	}
-	copy(n[4:], decoded[:8])
 
-	pt, err := ns.gcm.Open(nil, n, decoded[8:], nil)
+	n := decoded[:prefixLen+randLen]
+	pt, err := ns.gcm.Open(nil, n, decoded[len(n):], nil)
 	if err != nil {
-		return 0, err
+		return 0, time.Time{}, &BadNonceError{Cause: BadNonceNeverIssued}
 	}
 
-	ctr := big.NewInt(0)
-	ctr.SetBytes(pt)
-	return ctr.Int64(), nil
+	counter := int64(binary.BigEndian.Uint64(pt))
+	issued := time.Unix(0, int64(binary.BigEndian.Uint64(pt[8:])))
+	return counter, issued, nil
 }
 
 // Nonce provides a new Nonce.
@@ -147,34 +224,55 @@ func (ns *NonceService) Nonce() (string, error) {
 	ns.latest++
 	latest := ns.latest
 	ns.mu.Unlock()
-	defer ns.stats.Inc("Generated", 1)
-	return ns.encrypt(latest)
+	defer ns.issued.Inc()
+	return ns.encrypt(latest, ns.clk.Now())
 }
 
 // Valid determines whether the provided Nonce string is valid, returning
 // true if so.
 func (ns *NonceService) Valid(nonce string) bool {
-	c, err := ns.decrypt(nonce)
+	return ns.Redeem(nonce) == nil
+}
+
+// Redeem redeems the provided Nonce string, returning a *BadNonceError
+// saying why if it isn't valid.
+func (ns *NonceService) Redeem(nonce string) error {
+	age, err := ns.redeem(nonce)
+	result := "valid"
+	if err != nil {
+		result = err.Cause
+	}
+	ns.redemptions.With(prometheus.Labels{"result": result}).Inc()
+	if age > 0 {
+		ns.age.With(prometheus.Labels{"result": result}).Observe(age.Seconds())
+	}
 	if err != nil {
-		ns.stats.Inc("Invalid.Decrypt", 1)
-		return false
+		return err
+	}
+	return nil
+}
+
+// redeem implements Redeem, also returning how long ago the nonce was issued
+// if it was issued by this nonce service.
+func (ns *NonceService) redeem(nonce string) (time.Duration, *BadNonceError) {
+	c, issued, err := ns.decrypt(nonce)
+	if err != nil {
+		return 0, err
 	}
+	age := ns.clk.Since(issued)
 
 	ns.mu.Lock()
 	defer ns.mu.Unlock()
 	if c > ns.latest {
-		ns.stats.Inc("Invalid.TooHigh", 1)
-		return false
+		return 0, &BadNonceError{Cause: BadNonceNeverIssued}
 	}
 
 	if c <= ns.earliest {
-		ns.stats.Inc("Invalid.TooLow", 1)
-		return false
+		return age, &BadNonceError{Cause: BadNonceExpired, Age: age}
 	}
 
 	if ns.used[c] {
-		ns.stats.Inc("Invalid.AlreadyUsed", 1)
-		return false
+		return age, &BadNonceError{Cause: BadNonceAlreadyUsed, Age: age}
 	}
 
 	ns.used[c] = true
@@ -186,6 +284,5 @@ func (ns *NonceService) Valid(nonce string) bool {
 		delete(ns.used, ns.earliest)
 	}
 
-	ns.stats.Inc("Valid", 1)
-	return true
+	return age, nil
 }
@@ -1,8 +1,13 @@
 package nonce
 
 import (
+	"encoding/base64"
 	"fmt"
 	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/letsencrypt/boulder/metrics"
 	"github.com/letsencrypt/boulder/test"
@@ -89,6 +94,57 @@ func TestRejectTooEarly(t *testing.T) {
 	test.Assert(t, !ns.Valid(n0), "Accepted a nonce that we should have forgotten")
 }
 
+func TestRedeemCauses(t *testing.T) {
+	ns, err := NewNonceService(metrics.NewNoopScope())
+	test.AssertNotError(t, err, "Could not create nonce service")
+	fc := clock.NewFake()
+	ns.clk = fc
+
+	cause := func(n string) string {
+		err := ns.Redeem(n)
+		test.AssertError(t, err, "Redeemed a bad nonce")
+		return err.(*BadNonceError).Cause
+	}
+
+	n, err := ns.Nonce()
+	test.AssertNotError(t, err, "Could not create nonce")
+	fc.Add(time.Minute)
+	test.AssertNotError(t, ns.Redeem(n), "Did not redeem fresh nonce")
+	err = ns.Redeem(n)
+	test.AssertDeepEquals(t, err, &BadNonceError{Cause: BadNonceAlreadyUsed, Age: time.Minute})
+
+	test.AssertEquals(t, cause("aGkK"), BadNonceMalformed)
+
+	other, err := NewNonceService(metrics.NewNoopScope())
+	test.AssertNotError(t, err, "Could not create nonce service")
+	n, err = other.Nonce()
+	test.AssertNotError(t, err, "Could not create nonce")
+	test.AssertEquals(t, cause(n), BadNonceWrongInstance)
+
+	n, err = ns.Nonce()
+	test.AssertNotError(t, err, "Could not create nonce")
+	decoded, err := base64.RawURLEncoding.DecodeString(n)
+	test.AssertNotError(t, err, "Could not decode nonce")
+	decoded[len(decoded)-1] ^= 1
+	test.AssertEquals(t, cause(base64.RawURLEncoding.EncodeToString(decoded)), BadNonceNeverIssued)
+
+	ns.earliest = ns.latest
+	test.AssertEquals(t, cause(n), BadNonceExpired)
+
+	count := func(result string) int {
+		return test.CountCounter(ns.redemptions.With(prometheus.Labels{"result": result}))
+	}
+	test.AssertEquals(t, test.CountCounter(ns.issued), 2)
+	test.AssertEquals(t, count("valid"), 1)
+	for _, result := range []string{BadNonceAlreadyUsed, BadNonceMalformed, BadNonceWrongInstance, BadNonceNeverIssued, BadNonceExpired} {
+		test.AssertEquals(t, count(result), 1)
+	}
+	// The expired nonce was issued at the current time, so only the valid and
+	// already used nonces have an age
+	test.AssertEquals(t, test.CountHistogramSamples(ns.age.With(prometheus.Labels{"result": "valid"}).(prometheus.Histogram)), 1)
+	test.AssertEquals(t, test.CountHistogramSamples(ns.age.With(prometheus.Labels{"result": BadNonceAlreadyUsed}).(prometheus.Histogram)), 1)
+}
+
 func BenchmarkNonces(b *testing.B) {
 	ns, err := NewNonceService(metrics.NewNoopScope())
 	if err != nil {
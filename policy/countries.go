@@ -0,0 +1,193 @@
+package policy
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// CountryPolicyMode says what happens when a name or validation target maps
+// to a denied country.
+type CountryPolicyMode string
+
+const (
+	// CountryPolicyLog only logs names and validation targets in denied
+	// countries, so the effect of a policy can be seen before it's enforced.
+	CountryPolicyLog = CountryPolicyMode("log")
+	// CountryPolicyEnforce rejects names and validations in denied countries.
+	CountryPolicyEnforce = CountryPolicyMode("enforce")
+)
+
+// countryPolicyYAML is the format of a country policy file.
+type countryPolicyYAML struct {
+	// Mode is "log" or "enforce".
+	Mode CountryPolicyMode `yaml:"mode"`
+	// DeniedCountries are the ISO 3166-1 alpha-2 codes of the countries
+	// certificates can't be issued for.
+	DeniedCountries []string `yaml:"deniedCountries"`
+	// TLDCountries maps TLDs, e.g. country code TLDs, to a country.
+	TLDCountries map[string]string `yaml:"tldCountries"`
+	// RegistrantCountries maps registered domains to the country of their
+	// registrant, as given by a data feed. Subdomains map to the same country.
+	RegistrantCountries map[string]string `yaml:"registrantCountries"`
+	// NetworkCountries maps CIDR networks to the country they're geolocated
+	// in, and is checked against the addresses used for validation.
+	NetworkCountries map[string]string `yaml:"networkCountries"`
+}
+
+// CountryMatch describes how a name or address maps to a denied country.
+type CountryMatch struct {
+	// Country is the denied country's code.
+	Country string
+	// Source is what mapped to the country: "tld", "registrant" or "network".
+	Source string
+	// Entry is the TLD, registered domain or network that matched.
+	Entry string
+}
+
+func (m CountryMatch) String() string {
+	return fmt.Sprintf("%s %s entry %q", m.Country, m.Source, m.Entry)
+}
+
+type countryNetwork struct {
+	network *net.IPNet
+	country string
+}
+
+// CountryPolicy maps names and validation target addresses to countries and
+// reports those in denied countries, for operators subject to sanctions
+// regimes. The zero value denies nothing until a policy is loaded.
+type CountryPolicy struct {
+	mu          sync.RWMutex
+	mode        CountryPolicyMode
+	denied      map[string]bool
+	tlds        map[string]string
+	registrants map[string]string
+	networks    []countryNetwork
+}
+
+func normalizeCountry(country string) (string, error) {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if len(country) != 2 || country[0] < 'A' || country[0] > 'Z' || country[1] < 'A' || country[1] > 'Z' {
+		return "", fmt.Errorf("invalid country code %q", country)
+	}
+	return country, nil
+}
+
+func loadCountryMap(entries map[string]string, what string) (map[string]string, error) {
+	m := make(map[string]string, len(entries))
+	for entry, country := range entries {
+		country, err := normalizeCountry(country)
+		if err != nil {
+			return nil, fmt.Errorf("%s %q: %s", what, entry, err)
+		}
+		entry = strings.Trim(strings.ToLower(strings.TrimSpace(entry)), ".")
+		if entry == "" {
+			return nil, fmt.Errorf("empty %s", what)
+		}
+		m[entry] = country
+	}
+	return m, nil
+}
+
+// LoadCountryPolicy replaces the policy with the contents of a YAML country
+// policy file.
+func (cp *CountryPolicy) LoadCountryPolicy(contents []byte) error {
+	var p countryPolicyYAML
+	err := yaml.Unmarshal(contents, &p)
+	if err != nil {
+		return err
+	}
+	if p.Mode != CountryPolicyLog && p.Mode != CountryPolicyEnforce {
+		return fmt.Errorf("country policy mode must be %q or %q, not %q",
+			CountryPolicyLog, CountryPolicyEnforce, p.Mode)
+	}
+	denied := make(map[string]bool, len(p.DeniedCountries))
+	for _, country := range p.DeniedCountries {
+		country, err := normalizeCountry(country)
+		if err != nil {
+			return fmt.Errorf("denied country: %s", err)
+		}
+		denied[country] = true
+	}
+	tlds, err := loadCountryMap(p.TLDCountries, "TLD")
+	if err != nil {
+		return err
+	}
+	registrants, err := loadCountryMap(p.RegistrantCountries, "registered domain")
+	if err != nil {
+		return err
+	}
+	var networks []countryNetwork
+	for cidr, country := range p.NetworkCountries {
+		country, err := normalizeCountry(country)
+		if err != nil {
+			return fmt.Errorf("network %q: %s", cidr, err)
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return err
+		}
+		networks = append(networks, countryNetwork{network, country})
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.mode = p.Mode
+	cp.denied = denied
+	cp.tlds = tlds
+	cp.registrants = registrants
+	cp.networks = networks
+	return nil
+}
+
+// Enforced returns true if names and validations in denied countries should
+// be rejected rather than only logged.
+func (cp *CountryPolicy) Enforced() bool {
+	cp.mu.RLock()
+	defer cp.mu.RUnlock()
+	return cp.mode == CountryPolicyEnforce
+}
+
+// DeniedName returns how name maps to a denied country, either by its TLD or
+// by the registrant of it or one of its parent domains, or nil if it doesn't.
+func (cp *CountryPolicy) DeniedName(name string) *CountryMatch {
+	cp.mu.RLock()
+	defer cp.mu.RUnlock()
+	labels := strings.Split(strings.TrimPrefix(strings.ToLower(name), "*."), ".")
+	for i := range labels {
+		parent := strings.Join(labels[i:], ".")
+		if country, ok := cp.registrants[parent]; ok && cp.denied[country] {
+			return &CountryMatch{Country: country, Source: "registrant", Entry: parent}
+		}
+	}
+	tld := labels[len(labels)-1]
+	if country, ok := cp.tlds[tld]; ok && cp.denied[country] {
+		return &CountryMatch{Country: country, Source: "tld", Entry: tld}
+	}
+	return nil
+}
+
+// DeniedAddress returns how an address maps to a denied country by the most
+// specific network containing it, or nil if it doesn't.
+func (cp *CountryPolicy) DeniedAddress(ip net.IP) *CountryMatch {
+	cp.mu.RLock()
+	defer cp.mu.RUnlock()
+	var best *countryNetwork
+	bestSize := -1
+	for i, n := range cp.networks {
+		if !n.network.Contains(ip) {
+			continue
+		}
+		if size, _ := n.network.Mask.Size(); size > bestSize {
+			best, bestSize = &cp.networks[i], size
+		}
+	}
+	if best == nil || !cp.denied[best.country] {
+		return nil
+	}
+	return &CountryMatch{Country: best.country, Source: "network", Entry: best.network.String()}
+}
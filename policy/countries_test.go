@@ -0,0 +1,92 @@
+package policy
+
+import (
+	"net"
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+const testCountryPolicy = `
+mode: enforce
+deniedCountries: [xx, YY]
+tldCountries:
+  xx: XX
+  zz: ZZ
+registrantCountries:
+  Sanctioned.example: YY
+  allowed.xx: ZZ
+networkCountries:
+  192.0.2.0/24: XX
+  192.0.2.128/25: ZZ
+  2001:db8::/32: YY
+`
+
+func TestCountryPolicyNames(t *testing.T) {
+	var cp CountryPolicy
+	test.Assert(t, cp.DeniedName("example.xx") == nil, "name denied without a policy")
+	err := cp.LoadCountryPolicy([]byte(testCountryPolicy))
+	test.AssertNotError(t, err, "loading country policy failed")
+	test.Assert(t, cp.Enforced(), "enforce mode wasn't enforced")
+
+	testCases := []struct {
+		name  string
+		match *CountryMatch
+	}{
+		{"example.com", nil},
+		{"example.zz", nil},
+		{"example.xx", &CountryMatch{"XX", "tld", "xx"}},
+		{"*.WWW.example.XX", &CountryMatch{"XX", "tld", "xx"}},
+		{"sanctioned.example", &CountryMatch{"YY", "registrant", "sanctioned.example"}},
+		{"*.www.sanctioned.example", &CountryMatch{"YY", "registrant", "sanctioned.example"}},
+		{"notsanctioned.example", nil},
+		// A registrant in an allowed country doesn't override a denied TLD
+		{"www.allowed.xx", &CountryMatch{"XX", "tld", "xx"}},
+	}
+	for _, tc := range testCases {
+		test.AssertDeepEquals(t, cp.DeniedName(tc.name), tc.match)
+	}
+}
+
+func TestCountryPolicyAddresses(t *testing.T) {
+	var cp CountryPolicy
+	err := cp.LoadCountryPolicy([]byte(testCountryPolicy))
+	test.AssertNotError(t, err, "loading country policy failed")
+
+	testCases := []struct {
+		ip    string
+		match *CountryMatch
+	}{
+		{"192.0.2.1", &CountryMatch{"XX", "network", "192.0.2.0/24"}},
+		// The most specific network is used
+		{"192.0.2.200", nil},
+		{"2001:db8::1", &CountryMatch{"YY", "network", "2001:db8::/32"}},
+		{"198.51.100.1", nil},
+	}
+	for _, tc := range testCases {
+		test.AssertDeepEquals(t, cp.DeniedAddress(net.ParseIP(tc.ip)), tc.match)
+	}
+}
+
+func TestLoadCountryPolicy(t *testing.T) {
+	var cp CountryPolicy
+	err := cp.LoadCountryPolicy([]byte("mode: log\ndeniedCountries: [XX]\ntldCountries: {xx: XX}\n"))
+	test.AssertNotError(t, err, "loading country policy failed")
+	test.Assert(t, !cp.Enforced(), "log mode was enforced")
+	test.Assert(t, cp.DeniedName("example.xx") != nil, "log mode didn't match")
+
+	// An invalid file leaves the loaded policy in effect
+	for _, contents := range []string{
+		"deniedCountries: [XX]\n",
+		"mode: block\n",
+		"mode: log\ndeniedCountries: [XXX]\n",
+		"mode: log\ntldCountries: {xx: X1}\n",
+		"mode: log\nregistrantCountries: {'': XX}\n",
+		"mode: log\nnetworkCountries: {192.0.2.0: XX}\n",
+		"mode: [\n",
+	} {
+		err = cp.LoadCountryPolicy([]byte(contents))
+		test.AssertError(t, err, "invalid country policy accepted: "+contents)
+	}
+	test.Assert(t, cp.DeniedName("example.xx") != nil, "invalid policy replaced the loaded one")
+}
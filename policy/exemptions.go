@@ -0,0 +1,128 @@
+package policy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
+)
+
+// Exemption lets one account issue for one identifier despite one hostname
+// policy entry that blocks it, e.g. so that the owner of a high-value domain
+// can get a certificate for it. Operators give exemptions to accounts as
+// tokens, signed with a key shared by the RA and CA, which the account
+// presents in a new order. Each token can be used by only one order.
+type Exemption struct {
+	// ID is random and unique to each token, so that its use can be
+	// recorded.
+	ID             string    `json:"id"`
+	RegistrationID int64     `json:"registrationID"`
+	Identifier     string    `json:"identifier"`
+	Entry          string    `json:"entry"`
+	Expires        time.Time `json:"expires"`
+}
+
+// SignExemption returns a token for e, signed with key: the JSON encoding of
+// e and its HMAC-SHA256, each base64url encoded without padding, joined by a
+// ".".
+func SignExemption(key []byte, e Exemption) (string, error) {
+	if len(key) == 0 {
+		return "", berrors.InternalServerError("no policy exemption key")
+	}
+	if e.ID == "" || e.RegistrationID == 0 || e.Identifier == "" || e.Entry == "" || e.Expires.IsZero() {
+		return "", berrors.InternalServerError("incomplete policy exemption")
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(exemptionMAC(key, payload)), nil
+}
+
+// ParseExemption checks the signature of an exemption token against key and
+// returns the exemption. It doesn't check whether the exemption has expired,
+// since orders keep using their exemptions until they're finalized.
+func ParseExemption(key []byte, token string) (*Exemption, error) {
+	if len(key) == 0 {
+		return nil, berrors.MalformedError("Policy exemptions aren't supported")
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, berrors.MalformedError("Malformed policy exemption token")
+	}
+	// Strict decoding makes each exemption's token unique, so the SA can
+	// record its use by the token alone.
+	payload, err := base64.RawURLEncoding.Strict().DecodeString(parts[0])
+	if err != nil {
+		return nil, berrors.MalformedError("Malformed policy exemption token")
+	}
+	mac, err := base64.RawURLEncoding.Strict().DecodeString(parts[1])
+	if err != nil {
+		return nil, berrors.MalformedError("Malformed policy exemption token")
+	}
+	if !hmac.Equal(mac, exemptionMAC(key, payload)) {
+		return nil, berrors.UnauthorizedError("Invalid policy exemption token signature")
+	}
+	var e Exemption
+	err = json.Unmarshal(payload, &e)
+	if err != nil {
+		return nil, berrors.MalformedError("Malformed policy exemption token")
+	}
+	return &e, nil
+}
+
+func exemptionMAC(key, payload []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write(payload)
+	return h.Sum(nil)
+}
+
+// ParseExemptions parses the exemption tokens presented by the account regID,
+// checking that each is validly signed, for the account, and the only one
+// for its identifier. It returns the exemptions keyed by identifier.
+func ParseExemptions(key []byte, regID int64, tokens []string) (map[string]*Exemption, error) {
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	exemptions := make(map[string]*Exemption, len(tokens))
+	for _, token := range tokens {
+		e, err := ParseExemption(key, token)
+		if err != nil {
+			return nil, err
+		}
+		if e.RegistrationID != regID {
+			return nil, berrors.UnauthorizedError("Policy exemption %s isn't for this account", e.ID)
+		}
+		// Normalize the identifier the same way as order names, so that an
+		// exemption matches its name however it was written when signed
+		identifier, err := core.NormalizeName(e.Identifier)
+		if err != nil {
+			return nil, err
+		}
+		e.Identifier = identifier
+		if _, ok := exemptions[e.Identifier]; ok {
+			return nil, berrors.MalformedError("More than one policy exemption for %q", e.Identifier)
+		}
+		exemptions[e.Identifier] = e
+	}
+	return exemptions, nil
+}
+
+// ExemptEntries returns the hostname policy entry each identifier of
+// exemptions is exempt from, as csr.VerifyCSR takes them.
+func ExemptEntries(exemptions map[string]*Exemption) map[string]string {
+	if len(exemptions) == 0 {
+		return nil
+	}
+	entries := make(map[string]string, len(exemptions))
+	for identifier, e := range exemptions {
+		entries[identifier] = e.Entry
+	}
+	return entries
+}
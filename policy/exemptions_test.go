@@ -0,0 +1,122 @@
+package policy
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/test"
+)
+
+var testExemptionKey = []byte("policy exemption test key")
+
+func testExemption() Exemption {
+	return Exemption{
+		ID:             "abc",
+		RegistrationID: 1,
+		Identifier:     "www.example.com",
+		Entry:          "example.com",
+		Expires:        time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func mustDecode(t *testing.T, s string) []byte {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	test.AssertNotError(t, err, "decoding base64")
+	return b
+}
+
+func TestSignAndParseExemption(t *testing.T) {
+	e := testExemption()
+	token, err := SignExemption(testExemptionKey, e)
+	test.AssertNotError(t, err, "SignExemption failed")
+
+	parsed, err := ParseExemption(testExemptionKey, token)
+	test.AssertNotError(t, err, "ParseExemption failed")
+	test.AssertDeepEquals(t, *parsed, e)
+
+	_, err = ParseExemption([]byte("other key"), token)
+	test.Assert(t, berrors.Is(err, berrors.Unauthorized), "token was accepted with the wrong key")
+	_, err = ParseExemption(nil, token)
+	test.Assert(t, berrors.Is(err, berrors.Malformed), "token was accepted without a key")
+
+	// Changing the exemption invalidates the signature
+	parts := strings.Split(token, ".")
+	e.Entry = "com"
+	other, err := SignExemption(testExemptionKey, e)
+	test.AssertNotError(t, err, "SignExemption failed")
+	_, err = ParseExemption(testExemptionKey, strings.Split(other, ".")[0]+"."+parts[1])
+	test.Assert(t, berrors.Is(err, berrors.Unauthorized), "modified exemption was accepted")
+
+	// Each exemption has only one valid encoding. The last character of the
+	// 32 byte MAC holds two bits of padding, which lenient decoding ignores.
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+	last := strings.IndexByte(alphabet, parts[1][len(parts[1])-1])
+	nonCanonical := parts[1][:len(parts[1])-1] + string(alphabet[last^1])
+	lenient, err := base64.RawURLEncoding.DecodeString(nonCanonical)
+	test.AssertNotError(t, err, "decoding non-canonical MAC")
+	test.AssertByteEquals(t, lenient, exemptionMAC(testExemptionKey, mustDecode(t, parts[0])))
+	_, err = ParseExemption(testExemptionKey, parts[0]+"."+nonCanonical)
+	test.Assert(t, berrors.Is(err, berrors.Malformed), "non-canonical token was accepted")
+
+	for _, token := range []string{"", "abc", "a.b.c", parts[0] + ".!", "!." + parts[1]} {
+		_, err := ParseExemption(testExemptionKey, token)
+		test.Assert(t, berrors.Is(err, berrors.Malformed), "malformed token "+token+" was accepted")
+	}
+
+	_, err = SignExemption(testExemptionKey, Exemption{ID: "abc", RegistrationID: 1})
+	test.AssertError(t, err, "incomplete exemption was signed")
+	_, err = SignExemption(nil, testExemption())
+	test.AssertError(t, err, "exemption was signed without a key")
+}
+
+func TestParseExemptions(t *testing.T) {
+	e := testExemption()
+	e.Identifier = "WWW.example.com."
+	first, err := SignExemption(testExemptionKey, e)
+	test.AssertNotError(t, err, "SignExemption failed")
+	e.ID = "def"
+	e.Identifier = "*.example.com"
+	second, err := SignExemption(testExemptionKey, e)
+	test.AssertNotError(t, err, "SignExemption failed")
+
+	exemptions, err := ParseExemptions(testExemptionKey, 1, []string{first, second})
+	test.AssertNotError(t, err, "ParseExemptions failed")
+	test.AssertEquals(t, len(exemptions), 2)
+	test.AssertEquals(t, exemptions["www.example.com"].ID, "abc")
+	test.AssertEquals(t, exemptions["*.example.com"].ID, "def")
+	test.AssertDeepEquals(t, ExemptEntries(exemptions), map[string]string{
+		"www.example.com": "example.com",
+		"*.example.com":   "example.com",
+	})
+
+	_, err = ParseExemptions(testExemptionKey, 2, []string{first})
+	test.Assert(t, berrors.Is(err, berrors.Unauthorized), "another account's exemption was accepted")
+
+	e.ID = "jkl"
+	e.Identifier = "xn--bcher-kva.example"
+	idn, err := SignExemption(testExemptionKey, e)
+	test.AssertNotError(t, err, "SignExemption failed")
+	e.Identifier = "bücher.example"
+	unicode, err := SignExemption(testExemptionKey, e)
+	test.AssertNotError(t, err, "SignExemption failed")
+	exemptions, err = ParseExemptions(testExemptionKey, 1, []string{unicode})
+	test.AssertNotError(t, err, "ParseExemptions failed")
+	test.AssertEquals(t, exemptions["xn--bcher-kva.example"].ID, "jkl")
+	_, err = ParseExemptions(testExemptionKey, 1, []string{idn, unicode})
+	test.Assert(t, berrors.Is(err, berrors.Malformed), "two exemptions for one identifier in different forms were accepted")
+
+	e.ID = "ghi"
+	e.Identifier = "*.example.com"
+	third, err := SignExemption(testExemptionKey, e)
+	test.AssertNotError(t, err, "SignExemption failed")
+	_, err = ParseExemptions(testExemptionKey, 1, []string{second, third})
+	test.Assert(t, berrors.Is(err, berrors.Malformed), "two exemptions for one identifier were accepted")
+
+	exemptions, err = ParseExemptions(nil, 1, nil)
+	test.AssertNotError(t, err, "ParseExemptions failed without exemptions")
+	test.AssertEquals(t, len(exemptions), 0)
+	test.Assert(t, ExemptEntries(exemptions) == nil, "ExemptEntries of no exemptions wasn't nil")
+}
@@ -1,16 +1,19 @@
 package policy
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net"
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/jmhodges/clock"
+	"golang.org/x/net/context"
 	"golang.org/x/net/idna"
 	"golang.org/x/text/unicode/norm"
 
@@ -18,6 +21,7 @@ import (
 	berrors "github.com/letsencrypt/boulder/errors"
 	"github.com/letsencrypt/boulder/features"
 	"github.com/letsencrypt/boulder/iana"
+	"github.com/letsencrypt/boulder/killswitch"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/reloader"
 )
@@ -26,13 +30,39 @@ import (
 type AuthorityImpl struct {
 	log blog.Logger
 
-	blacklist              map[string]bool
-	exactBlacklist         map[string]bool
-	wildcardExactBlacklist map[string]bool
-	blacklistMu            sync.RWMutex
-
-	enabledChallenges          map[string]bool
-	enabledChallengesWhitelist map[string]map[int64]bool
+	blacklist      map[string]bool
+	exactBlacklist map[string]bool
+	// wildcardExactBlacklist maps the base domains of the wildcards that would
+	// cover an exact blacklist entry to that entry
+	wildcardExactBlacklist map[string]string
+	// entryMetadata describes why entries are on the blacklists
+	entryMetadata map[string]EntryMetadata
+	blacklistMu   sync.RWMutex
+
+	// rejections records the names rejected because of a blacklist entry
+	rejections *RejectionLog
+
+	// wildcardOrders decides which names may be in an order with a wildcard
+	wildcardOrders WildcardOrderPolicy
+
+	enabledChallenges map[string]bool
+	// enabledChallengesWhitelist and challengesWhitelistEnrollments map
+	// challenge types to the accounts they're enabled for, from the challenges
+	// whitelist file and the SA respectively, and when that ends. A zero time
+	// means never.
+	enabledChallengesWhitelist     map[string]map[int64]time.Time
+	challengesWhitelistEnrollments map[string]map[int64]time.Time
+	// challengeWindows holds when the challenge types scheduled in a
+	// challenges file are enabled
+	challengeWindows map[string]ChallengeWindow
+	// killSwitch disables challenge types in an emergency, overriding
+	// everything else
+	killSwitch *killswitch.Challenges
+	// tokens is the format of the tokens generated for challenges
+	tokens core.TokenFormat
+	// allowReservedNames allows names under reservedTLDs
+	allowReservedNames bool
+	clk              clock.Clock
 	pseudoRNG                  *rand.Rand
 	rngMu                      sync.Mutex
 }
@@ -43,6 +73,8 @@ func New(challengeTypes map[string]bool) (*AuthorityImpl, error) {
 	pa := AuthorityImpl{
 		log:               blog.Get(),
 		enabledChallenges: challengeTypes,
+		rejections:        DefaultRejections,
+		clk:               clock.Default(),
 		// We don't need real randomness for this.
 		pseudoRNG: rand.New(rand.NewSource(99)),
 	}
@@ -53,22 +85,57 @@ func New(challengeTypes map[string]bool) (*AuthorityImpl, error) {
 type blacklistJSON struct {
 	Blacklist      []string
 	ExactBlacklist []string
+	// Metadata describes why entries in Blacklist and ExactBlacklist exist,
+	// keyed by entry. It's optional, but every key must be an entry.
+	Metadata map[string]EntryMetadata `json:",omitempty"`
+}
+
+// EntryMetadata describes why a hostname policy entry exists. It's included in
+// the audit log and the rejection log when the entry causes a name to be
+// rejected, so operators can trace why the name is blocked.
+type EntryMetadata struct {
+	Reason string `json:"reason,omitempty"`
+	// Ticket refers to the ticket or issue tracking the entry
+	Ticket  string `json:"ticket,omitempty"`
+	AddedBy string `json:"addedBy,omitempty"`
+	// Severity is one of "low", "medium", "high" or "critical"
+	Severity string `json:"severity,omitempty"`
 }
 
+var validSeverities = map[string]bool{"": true, "low": true, "medium": true, "high": true, "critical": true}
+
 // SetHostnamePolicyFile will load the given policy file, returning error if it
 // fails. It will also start a reloader in case the file changes.
 func (pa *AuthorityImpl) SetHostnamePolicyFile(f string) error {
-	_, err := reloader.New(f, pa.loadHostnamePolicy, pa.hostnamePolicyLoadError)
-	return err
+	return reloader.Register(reloader.Section{
+		Name: "hostname policy",
+		File: f,
+		Load: pa.loadHostnamePolicy,
+	})
 }
 
-func (pa *AuthorityImpl) hostnamePolicyLoadError(err error) {
-	pa.log.AuditErrf("error loading hostname policy: %s", err)
+// SetHostnamePolicyRemote is like SetHostnamePolicyFile, but fetches the
+// hostname policy from a remote HTTPS source and polls it for changes.
+func (pa *AuthorityImpl) SetHostnamePolicyRemote(source *reloader.RemoteSource) error {
+	return reloader.Register(reloader.Section{
+		Name:   "hostname policy",
+		Remote: source,
+		Load:   pa.loadHostnamePolicy,
+	})
+}
+
+// CheckHostnamePolicy is a health check that fails until the hostname policy
+// has been loaded.
+func (pa *AuthorityImpl) CheckHostnamePolicy(_ context.Context) error {
+	pa.blacklistMu.RLock()
+	defer pa.blacklistMu.RUnlock()
+	if pa.blacklist == nil {
+		return errors.New("hostname policy not yet loaded")
+	}
+	return nil
 }
 
 func (pa *AuthorityImpl) loadHostnamePolicy(b []byte) error {
-	hash := sha256.Sum256(b)
-	pa.log.Infof("loading hostname policy, sha256: %s", hex.EncodeToString(hash[:]))
 	var bl blacklistJSON
 	err := json.Unmarshal(b, &bl)
 	if err != nil {
@@ -82,7 +149,7 @@ func (pa *AuthorityImpl) loadHostnamePolicy(b []byte) error {
 		nameMap[v] = true
 	}
 	exactNameMap := make(map[string]bool)
-	wildcardNameMap := make(map[string]bool)
+	wildcardNameMap := make(map[string]string)
 	for _, v := range bl.ExactBlacklist {
 		exactNameMap[v] = true
 		// Remove the leftmost label of the exact blacklist entry to make an exact
@@ -101,12 +168,21 @@ func (pa *AuthorityImpl) loadHostnamePolicy(b []byte) error {
 		}
 		// Add the second part, the domain minus the first label, to the
 		// wildcardNameMap to block issuance for `*.`+parts[1]
-		wildcardNameMap[parts[1]] = true
+		wildcardNameMap[parts[1]] = v
+	}
+	for entry, metadata := range bl.Metadata {
+		if !nameMap[entry] && !exactNameMap[entry] {
+			return fmt.Errorf("Metadata for %q, which isn't a blacklist entry", entry)
+		}
+		if !validSeverities[metadata.Severity] {
+			return fmt.Errorf("Invalid severity %q for blacklist entry %q", metadata.Severity, entry)
+		}
 	}
 	pa.blacklistMu.Lock()
 	pa.blacklist = nameMap
 	pa.exactBlacklist = exactNameMap
 	pa.wildcardExactBlacklist = wildcardNameMap
+	pa.entryMetadata = bl.Metadata
 	pa.blacklistMu.Unlock()
 	return nil
 }
@@ -114,29 +190,45 @@ func (pa *AuthorityImpl) loadHostnamePolicy(b []byte) error {
 // SetChallengesWhitelistFile will load the given whitelist file, returning error if it
 // fails. It will also start a reloader in case the file changes.
 func (pa *AuthorityImpl) SetChallengesWhitelistFile(f string) error {
-	_, err := reloader.New(f, pa.loadChallengesWhitelist, pa.challengesWhitelistLoadError)
-	return err
+	return reloader.Register(reloader.Section{
+		Name: "challenges whitelist",
+		File: f,
+		Load: pa.loadChallengesWhitelist,
+	})
+}
+
+// challengesWhitelistFileEntry is an entry of the challenges whitelist file:
+// either a registration ID, or an object with a registration ID and an
+// optional expiry, e.g. {"regID": 1000, "expires": "2019-06-01T00:00:00Z"}.
+type challengesWhitelistFileEntry struct {
+	RegID   int64     `json:"regID"`
+	Expires time.Time `json:"expires"`
 }
 
-func (pa *AuthorityImpl) challengesWhitelistLoadError(err error) {
-	pa.log.AuditErrf("error loading challenges whitelist: %s", err)
+func (e *challengesWhitelistFileEntry) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &e.RegID); err == nil {
+		return nil
+	}
+	type entry challengesWhitelistFileEntry
+	return json.Unmarshal(b, (*entry)(e))
 }
 
 func (pa *AuthorityImpl) loadChallengesWhitelist(b []byte) error {
-	hash := sha256.Sum256(b)
-	pa.log.Infof("loading challenges whitelist, sha256: %s", hex.EncodeToString(hash[:]))
-	var wl map[string][]int64
+	var wl map[string][]challengesWhitelistFileEntry
 	err := json.Unmarshal(b, &wl)
 	if err != nil {
 		return err
 	}
 
-	chalWl := make(map[string]map[int64]bool)
+	chalWl := make(map[string]map[int64]time.Time)
 
 	for k, v := range wl {
-		chalWl[k] = make(map[int64]bool)
-		for _, i := range v {
-			chalWl[k][i] = true
+		chalWl[k] = make(map[int64]time.Time)
+		for _, e := range v {
+			if e.RegID == 0 {
+				return fmt.Errorf("%s whitelist entry without a registration ID", k)
+			}
+			chalWl[k][e.RegID] = e.Expires
 		}
 	}
 
@@ -147,6 +239,80 @@ func (pa *AuthorityImpl) loadChallengesWhitelist(b []byte) error {
 	return nil
 }
 
+// SetChallengesFile will load the challenge types to enable from the given
+// file, replacing those passed to New, returning error if it fails. It will
+// also start a reloader in case the file changes. The file contains a JSON
+// object in the same format as the Challenges section of the PA config,
+// except that a challenge type may map to a ChallengeWindow instead of a
+// bool, to enable or disable it at a scheduled time.
+func (pa *AuthorityImpl) SetChallengesFile(f string) error {
+	return reloader.Register(reloader.Section{
+		Name: "enabled challenges",
+		File: f,
+		Load: pa.loadChallenges,
+	})
+}
+
+// ChallengeWindow schedules when a challenge type in a challenges file is
+// enabled, so that challenge types are enabled or disabled at the same moment
+// across every instance without a synchronized restart. A zero time leaves
+// that end of the window open.
+type ChallengeWindow struct {
+	// EnableAt is when the challenge type is enabled.
+	EnableAt time.Time `json:"enableAt"`
+	// DisableAt is when the challenge type is disabled.
+	DisableAt time.Time `json:"disableAt"`
+}
+
+// Contains returns true if the challenge type is enabled at t.
+func (w ChallengeWindow) Contains(t time.Time) bool {
+	return !t.Before(w.EnableAt) && (w.DisableAt.IsZero() || t.Before(w.DisableAt))
+}
+
+func (pa *AuthorityImpl) loadChallenges(b []byte) error {
+	var raw map[string]json.RawMessage
+	err := json.Unmarshal(b, &raw)
+	if err != nil {
+		return err
+	}
+	if len(raw) == 0 {
+		return errors.New("No challenges in challenges file.")
+	}
+	challenges := make(map[string]bool, len(raw))
+	windows := make(map[string]ChallengeWindow)
+	for name, value := range raw {
+		if !core.ValidChallenge(name) {
+			return fmt.Errorf("Invalid challenge in challenges file: %s", name)
+		}
+		var enabled bool
+		if err := json.Unmarshal(value, &enabled); err == nil {
+			challenges[name] = enabled
+			continue
+		}
+		var window ChallengeWindow
+		dec := json.NewDecoder(bytes.NewReader(value))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&window); err != nil {
+			return fmt.Errorf("Invalid schedule for %s in challenges file: %s", name, err)
+		}
+		if window.EnableAt.IsZero() && window.DisableAt.IsZero() {
+			return fmt.Errorf("Empty schedule for %s in challenges file", name)
+		}
+		if !window.DisableAt.IsZero() && !window.DisableAt.After(window.EnableAt) {
+			return fmt.Errorf("Schedule for %s in challenges file disables it before enabling it", name)
+		}
+		challenges[name] = true
+		windows[name] = window
+	}
+
+	pa.blacklistMu.Lock()
+	pa.enabledChallenges = challenges
+	pa.challengeWindows = windows
+	pa.blacklistMu.Unlock()
+
+	return nil
+}
+
 const (
 	maxLabels = 10
 
@@ -163,6 +329,31 @@ const (
 	maxDNSIdentifierLength = 230
 )
 
+// reservedTLDs are TLDs that will never be delegated in the public DNS: those
+// reserved for testing and documentation by RFC 2606 and RFC 6761, and the
+// strings ICANN indefinitely deferred delegating because of name collisions
+// with private networks. They aren't public suffixes, so names under them are
+// always rejected unless allowed with SetAllowReservedNames.
+var reservedTLDs = map[string]bool{
+	"test":      true,
+	"example":   true,
+	"invalid":   true,
+	"localhost": true,
+	"corp":      true,
+	"home":      true,
+	"mail":      true,
+}
+
+// reservedTLD returns the reserved TLD that domain is under, or "" if it isn't
+// under one.
+func reservedTLD(domain string) string {
+	tld := domain[strings.LastIndex(domain, ".")+1:]
+	if reservedTLDs[tld] {
+		return tld
+	}
+	return ""
+}
+
 var dnsLabelRegexp = regexp.MustCompile("^[a-z0-9][a-z0-9-]{0,62}$")
 var punycodeRegexp = regexp.MustCompile("^xn--")
 var idnReservedRegexp = regexp.MustCompile("^[a-z0-9]{2}--")
@@ -178,6 +369,7 @@ var (
 	errInvalidIdentifier    = berrors.MalformedError("Invalid identifier type")
 	errNonPublic            = berrors.MalformedError("Name does not end in a public suffix")
 	errICANNTLD             = berrors.MalformedError("Name is an ICANN TLD")
+	errReservedTLD          = berrors.RejectedIdentifierError("Name is under a reserved or test TLD")
 	errBlacklisted          = berrors.RejectedIdentifierError("Policy forbids issuing for name")
 	errInvalidDNSCharacter  = berrors.MalformedError("Invalid character in DNS name")
 	errNameTooLong          = berrors.MalformedError("DNS name too long")
@@ -209,7 +401,8 @@ var (
 //    In particular:
 //    * MUST NOT contain underscores
 //  * MUST NOT match the syntax of an IP address
-//  * MUST end in a public suffix
+//  * MUST NOT end in a reserved or test TLD, unless the PA allows them
+//  * MUST end in a public suffix, or an allowed reserved TLD
 //  * MUST have at least one label in addition to the public suffix
 //  * MUST NOT be a label-wise suffix match for a name on the black list,
 //    where comparison is case-independent (normalized to lower case)
@@ -217,6 +410,12 @@ var (
 // If WillingToIssue returns an error, it will be of type MalformedRequestError
 // or RejectedIdentifierError
 func (pa *AuthorityImpl) WillingToIssue(id core.AcmeIdentifier) error {
+	return pa.willingToIssue(id, "")
+}
+
+// willingToIssue implements WillingToIssue, ignoring the hostname policy
+// entry exempt if it's not "".
+func (pa *AuthorityImpl) willingToIssue(id core.AcmeIdentifier, exempt string) error {
 	if id.Type != core.IdentifierDNS {
 		return errInvalidIdentifier
 	}
@@ -226,7 +425,7 @@ func (pa *AuthorityImpl) WillingToIssue(id core.AcmeIdentifier) error {
 		return errEmptyName
 	}
 
-	if strings.HasPrefix(domain, "*.") {
+	if _, wildcard := core.WildcardBase(domain); wildcard {
 		return errWildcardNotSupported
 	}
 
@@ -289,16 +488,26 @@ func (pa *AuthorityImpl) WillingToIssue(id core.AcmeIdentifier) error {
 	}
 
 	// Names must end in an ICANN TLD, but they must not be equal to an ICANN TLD.
-	icannTLD, err := iana.ExtractSuffix(domain)
-	if err != nil {
-		return errNonPublic
-	}
-	if icannTLD == domain {
-		return errICANNTLD
+	// Names under a reserved TLD never do, so they're rejected with a clearer
+	// error unless the PA allows them, in which case the reserved TLD stands
+	// in for the public suffix. There are at least two labels, so the name
+	// can't be equal to it.
+	if tld := reservedTLD(domain); tld != "" {
+		if !pa.allowReservedNames {
+			return errReservedTLD
+		}
+	} else {
+		icannTLD, err := iana.ExtractSuffix(domain)
+		if err != nil {
+			return errNonPublic
+		}
+		if icannTLD == domain {
+			return errICANNTLD
+		}
 	}
 
 	// Require no match against blacklist
-	if err := pa.checkHostLists(domain); err != nil {
+	if err := pa.checkHostLists(domain, exempt); err != nil {
 		return err
 	}
 
@@ -319,6 +528,18 @@ func (pa *AuthorityImpl) WillingToIssue(id core.AcmeIdentifier) error {
 // If all of the above is true then the base domain (e.g. without the *.) is run
 // through WillingToIssue to catch other illegal things (blocked hosts, etc).
 func (pa *AuthorityImpl) WillingToIssueWildcard(ident core.AcmeIdentifier) error {
+	return pa.willingToIssueWildcard(ident, "")
+}
+
+// WillingToIssueExempt is like WillingToIssueWildcard, but ignores the
+// hostname policy entry exempt, as allowed by an Exemption.
+func (pa *AuthorityImpl) WillingToIssueExempt(ident core.AcmeIdentifier, exempt string) error {
+	return pa.willingToIssueWildcard(ident, exempt)
+}
+
+// willingToIssueWildcard implements WillingToIssueWildcard, ignoring the
+// hostname policy entry exempt if it's not "".
+func (pa *AuthorityImpl) willingToIssueWildcard(ident core.AcmeIdentifier, exempt string) error {
 	// We're only willing to process DNS identifiers
 	if ident.Type != core.IdentifierDNS {
 		return errInvalidIdentifier
@@ -334,17 +555,25 @@ func (pa *AuthorityImpl) WillingToIssueWildcard(ident core.AcmeIdentifier) error
 	// processing to ensure that it is a well formed wildcard request and to
 	// translate the identifer to its base domain for use with WillingToIssue
 	if strings.Count(rawDomain, "*") == 1 {
+		// The base domain is the wildcard request with the `*.` prefix removed.
 		// If the rawDomain has a wildcard character, but it isn't the first most
 		// label of the domain name then the wildcard domain is malformed
-		if !strings.HasPrefix(rawDomain, "*.") {
+		baseDomain, wildcard := core.WildcardBase(rawDomain)
+		if !wildcard {
 			return errMalformedWildcard
 		}
-		// The base domain is the wildcard request with the `*.` prefix removed
-		baseDomain := strings.TrimPrefix(rawDomain, "*.")
 		// Names must end in an ICANN TLD, but they must not be equal to an ICANN TLD.
-		icannTLD, err := iana.ExtractSuffix(baseDomain)
-		if err != nil {
-			return errNonPublic
+		icannTLD := reservedTLD(baseDomain)
+		if icannTLD != "" {
+			if !pa.allowReservedNames {
+				return errReservedTLD
+			}
+		} else {
+			var err error
+			icannTLD, err = iana.ExtractSuffix(baseDomain)
+			if err != nil {
+				return errNonPublic
+			}
 		}
 		// Names must have a non-wildcard label immediately adjacent to the ICANN
 		// TLD. No `*.com`!
@@ -352,7 +581,7 @@ func (pa *AuthorityImpl) WillingToIssueWildcard(ident core.AcmeIdentifier) error
 			return errICANNTLDWildcard
 		}
 		// The base domain can't be in the wildcard exact blacklist
-		if err := pa.checkWildcardHostList(baseDomain); err != nil {
+		if err := pa.checkWildcardHostList(baseDomain, exempt); err != nil {
 			return err
 		}
 		// Check that the PA is willing to issue for the base domain
@@ -364,19 +593,94 @@ func (pa *AuthorityImpl) WillingToIssueWildcard(ident core.AcmeIdentifier) error
 		// NOTE(@cpu): This is pretty hackish! Boulder issue #3323[0] describes
 		// a better follow-up that we should land to replace this code.
 		// [0] https://github.com/letsencrypt/boulder/issues/3323
-		return pa.WillingToIssue(core.AcmeIdentifier{
+		return pa.willingToIssue(core.AcmeIdentifier{
 			Type:  core.IdentifierDNS,
 			Value: "x." + baseDomain,
-		})
+		}, exempt)
 	}
 
-	return pa.WillingToIssue(ident)
+	return pa.willingToIssue(ident, exempt)
+}
+
+// WildcardOrderPolicy decides which other names may be in the same order as a
+// wildcard name. The zero value allows the wildcard's base domain, e.g.
+// "example.com" with "*.example.com", and the names beneath the base domain
+// that the wildcard doesn't cover, e.g. "www.shop.example.com". Names the
+// wildcard covers, e.g. "www.example.com", are always rejected as redundant.
+type WildcardOrderPolicy struct {
+	RejectBaseDomain bool
+	RejectSubdomains bool
+}
+
+// SetWildcardOrderPolicy sets the policy CheckOrderNames enforces. It must be
+// called before the PA is used.
+func (pa *AuthorityImpl) SetWildcardOrderPolicy(p WildcardOrderPolicy) {
+	pa.wildcardOrders = p
+}
+
+// CheckOrderNames checks that the names of an order, which must already be
+// normalized, can be in the same order according to the wildcard order
+// policy. If they can't, it returns a Malformed error with a suberror for each
+// name that conflicts with a wildcard.
+func (pa *AuthorityImpl) CheckOrderNames(names []string) error {
+	wildcards := make(map[string]bool)
+	for _, name := range names {
+		if base, wildcard := core.WildcardBase(name); wildcard {
+			wildcards[base] = true
+		}
+	}
+	if len(wildcards) == 0 {
+		return nil
+	}
+
+	var subErrs []berrors.SubBoulderError
+	for _, name := range names {
+		// Check the name against each wildcard whose base domain is, or is a
+		// parent of, the name, other than the name itself, until one
+		// conflicts with it.
+		labels := strings.Split(name, ".")
+		for i := range labels {
+			base := strings.Join(labels[i:], ".")
+			if !wildcards[base] || "*."+base == name {
+				continue
+			}
+			var detail string
+			switch {
+			case i == 0 && pa.wildcardOrders.RejectBaseDomain:
+				detail = fmt.Sprintf("Domain name %q can't be in the same order as the wildcard %q for it", name, "*."+base)
+			case i == 1:
+				detail = fmt.Sprintf("Domain name %q is redundant with the wildcard %q in the same order. Remove one or the other from the order.", name, "*."+base)
+			case i > 1 && pa.wildcardOrders.RejectSubdomains:
+				detail = fmt.Sprintf("Domain name %q can't be in the same order as the wildcard %q for its parent domain", name, "*."+base)
+			}
+			if detail != "" {
+				subErrs = append(subErrs, berrors.SubBoulderError{
+					Type:   berrors.Malformed,
+					Detail: detail,
+					Name:   name,
+				})
+				break
+			}
+		}
+	}
+	if len(subErrs) == 0 {
+		return nil
+	}
+	detail := "Order contains names that conflict with its wildcard names"
+	if len(subErrs) == 1 {
+		detail = subErrs[0].Detail
+	}
+	return &berrors.BoulderError{
+		Type:      berrors.Malformed,
+		Detail:    detail,
+		SubErrors: subErrs,
+	}
 }
 
 // checkWildcardHostList checks the wildcardExactBlacklist for a given domain.
-// If the domain is not present on the list nil is returned, otherwise
-// errBlacklisted is returned.
-func (pa *AuthorityImpl) checkWildcardHostList(domain string) error {
+// If the domain is not present on the list, or its entry is exempt, nil is
+// returned, otherwise errBlacklisted is returned.
+func (pa *AuthorityImpl) checkWildcardHostList(domain, exempt string) error {
 	pa.blacklistMu.RLock()
 	defer pa.blacklistMu.RUnlock()
 
@@ -384,14 +688,19 @@ func (pa *AuthorityImpl) checkWildcardHostList(domain string) error {
 		return fmt.Errorf("Hostname policy not yet loaded.")
 	}
 
-	if pa.wildcardExactBlacklist[domain] {
+	if entry, ok := pa.wildcardExactBlacklist[domain]; ok {
+		if entry == exempt {
+			pa.exempted("*."+domain, "exactBlacklist", entry)
+			return nil
+		}
+		pa.rejected("*."+domain, "exactBlacklist", entry)
 		return errBlacklisted
 	}
 
 	return nil
 }
 
-func (pa *AuthorityImpl) checkHostLists(domain string) error {
+func (pa *AuthorityImpl) checkHostLists(domain, exempt string) error {
 	pa.blacklistMu.RLock()
 	defer pa.blacklistMu.RUnlock()
 
@@ -399,17 +708,90 @@ func (pa *AuthorityImpl) checkHostLists(domain string) error {
 		return fmt.Errorf("Hostname policy not yet loaded.")
 	}
 
+	if list, entry := pa.blockingEntry(domain, exempt); entry != "" {
+		pa.rejected(domain, list, entry)
+		return errBlacklisted
+	}
+	if exempt != "" {
+		if list, entry := pa.blockingEntry(domain, ""); entry == exempt {
+			pa.exempted(domain, list, entry)
+		}
+	}
+	return nil
+}
+
+// blockingEntry returns the list and entry of the hostname policy, other than
+// exempt, that block domain, or "" for both if it isn't blocked.
+// pa.blacklistMu must be held.
+func (pa *AuthorityImpl) blockingEntry(domain, exempt string) (string, string) {
 	labels := strings.Split(domain, ".")
 	for i := range labels {
 		joined := strings.Join(labels[i:], ".")
-		if pa.blacklist[joined] {
-			return errBlacklisted
+		if pa.blacklist[joined] && joined != exempt {
+			return "blacklist", joined
 		}
 	}
 
-	if pa.exactBlacklist[domain] {
-		return errBlacklisted
+	if pa.exactBlacklist[domain] && domain != exempt {
+		return "exactBlacklist", domain
 	}
+	return "", ""
+}
+
+// BlockingEntry returns the list ("blacklist" or "exactBlacklist") and entry
+// of the hostname policy that would cause name, which may be a wildcard, to be
+// rejected, or "" for both if none would. Unlike WillingToIssue it neither
+// audit logs nor records a rejection, so it can be used to analyze a policy.
+func (pa *AuthorityImpl) BlockingEntry(name string) (list, entry string) {
+	pa.blacklistMu.RLock()
+	defer pa.blacklistMu.RUnlock()
+
+	if base, wildcard := core.WildcardBase(name); wildcard {
+		if entry, ok := pa.wildcardExactBlacklist[base]; ok {
+			return "exactBlacklist", entry
+		}
+		// As in WillingToIssueWildcard, the base domain is checked with an "x"
+		// label standing in for the wildcard.
+		name = "x." + base
+	}
+	return pa.blockingEntry(name, "")
+}
+
+// rejected audit logs and records that name was rejected because of entry on
+// list, along with the entry's metadata. pa.blacklistMu must be held.
+func (pa *AuthorityImpl) rejected(name, list, entry string) {
+	metadata := pa.entryMetadata[entry]
+	pa.log.AuditInfof("Hostname policy rejected %q: %s entry %q reason=[%s] ticket=[%s] addedBy=[%s] severity=[%s]",
+		name, list, entry, metadata.Reason, metadata.Ticket, metadata.AddedBy, metadata.Severity)
+	pa.rejections.add(Rejection{
+		Name:     name,
+		List:     list,
+		Entry:    entry,
+		Metadata: metadata,
+	})
+}
+
+// exempted audit logs that name wasn't rejected because of entry on list
+// because of an Exemption.
+func (pa *AuthorityImpl) exempted(name, list, entry string) {
+	pa.log.AuditInfof("Hostname policy exemption allowed %q despite %s entry %q", name, list, entry)
+}
+
+// SetAllowReservedNames sets whether names under reserved and test TLDs, like
+// "example.test" or "server.corp", are allowed. Public CAs must never allow
+// them, but a private CA may intentionally issue for them. It must be called
+// before the PA is used.
+func (pa *AuthorityImpl) SetAllowReservedNames(allow bool) {
+	pa.allowReservedNames = allow
+}
+
+// SetTokenFormat sets the format of the tokens generated for challenges,
+// returning an error if it doesn't make valid ACME tokens.
+func (pa *AuthorityImpl) SetTokenFormat(format core.TokenFormat) error {
+	if err := format.Check(); err != nil {
+		return err
+	}
+	pa.tokens = format
 	return nil
 }
 
@@ -421,15 +803,34 @@ func (pa *AuthorityImpl) ChallengesFor(identifier core.AcmeIdentifier, regID int
 	challenges := []core.Challenge{}
 
 	// If we are using the new authorization storage schema we only use a single
-	// token for all challenges rather than a unique token per challenge.
-	var token string
+	// token for all challenges rather than a unique token per challenge. It's
+	// stored as the 32 bytes it decodes to, so it's always in the default
+	// format.
+	newToken := pa.tokens.NewToken
 	if features.Enabled(features.NewAuthorizationSchema) {
-		token = core.NewToken()
+		token := core.NewToken()
+		newToken = func() string { return token }
 	}
 
-	// If the identifier is for a DNS wildcard name we only
-	// provide a DNS-01 challenge as a matter of CA policy.
-	if strings.HasPrefix(identifier.Value, "*.") {
+	// IP identifiers can't be validated with DNS-01 or TLS-SNI-01 (RFC 8738
+	// Section 7) so we only offer the enabled challenges that support them.
+	if identifier.Type == core.IdentifierIP {
+		if pa.ChallengeTypeEnabled(core.ChallengeTypeHTTP01, regID) {
+			challenges = append(challenges, core.HTTPChallenge01(newToken()))
+		}
+
+		if pa.ChallengeTypeEnabled(core.ChallengeTypeTLSALPN01, regID) {
+			challenges = append(challenges, core.TLSALPNChallenge01(newToken()))
+		}
+
+		if len(challenges) == 0 {
+			return nil, nil, fmt.Errorf(
+				"Challenges requested for IP identifier but neither HTTP-01 " +
+					"nor TLS-ALPN-01 challenge type is enabled")
+		}
+	} else if _, wildcard := core.WildcardBase(identifier.Value); wildcard {
+		// If the identifier is for a DNS wildcard name we only
+		// provide a DNS-01 challenge as a matter of CA policy.
 		// We must have the DNS-01 challenge type enabled to create challenges for
 		// a wildcard identifier per LE policy.
 		if !pa.ChallengeTypeEnabled(core.ChallengeTypeDNS01, regID) {
@@ -438,26 +839,26 @@ func (pa *AuthorityImpl) ChallengesFor(identifier core.AcmeIdentifier, regID int
 					"challenge type is not enabled")
 		}
 		// Only provide a DNS-01-Wildcard challenge
-		challenges = []core.Challenge{core.DNSChallenge01(token)}
+		challenges = []core.Challenge{core.DNSChallenge01(newToken())}
 	} else {
 		// Otherwise we collect up challenges based on what is enabled.
 		if pa.ChallengeTypeEnabled(core.ChallengeTypeHTTP01, regID) {
-			challenges = append(challenges, core.HTTPChallenge01(token))
+			challenges = append(challenges, core.HTTPChallenge01(newToken()))
 		}
 
 		// Add a TLS-SNI challenge, if either (a) the challenge is enabled, or (b)
 		// the TLSSNIRevalidation feature flag is on and this is a revalidation.
 		if pa.ChallengeTypeEnabled(core.ChallengeTypeTLSSNI01, regID) ||
-			(features.Enabled(features.TLSSNIRevalidation) && revalidation) {
-			challenges = append(challenges, core.TLSSNIChallenge01(token))
+			(features.Enabled(features.TLSSNIRevalidation) && revalidation && !pa.killed(core.ChallengeTypeTLSSNI01)) {
+			challenges = append(challenges, core.TLSSNIChallenge01(newToken()))
 		}
 
 		if pa.ChallengeTypeEnabled(core.ChallengeTypeTLSALPN01, regID) {
-			challenges = append(challenges, core.TLSALPNChallenge01(token))
+			challenges = append(challenges, core.TLSALPNChallenge01(newToken()))
 		}
 
 		if pa.ChallengeTypeEnabled(core.ChallengeTypeDNS01, regID) {
-			challenges = append(challenges, core.DNSChallenge01(token))
+			challenges = append(challenges, core.DNSChallenge01(newToken()))
 		}
 	}
 
@@ -481,10 +882,66 @@ func (pa *AuthorityImpl) ChallengesFor(identifier core.AcmeIdentifier, regID int
 	return shuffled, shuffledCombos, nil
 }
 
+// ChallengeWhitelistEntry enables a challenge type for an account until
+// Expires, or forever if it's zero.
+type ChallengeWhitelistEntry struct {
+	ChallengeType  string
+	RegistrationID int64
+	Expires        time.Time
+}
+
+// SetChallengesWhitelistEnrollments replaces the challenge whitelist entries
+// enrolled in the SA, which apply along with those in the challenges whitelist
+// file.
+func (pa *AuthorityImpl) SetChallengesWhitelistEnrollments(entries []ChallengeWhitelistEntry) {
+	enrollments := make(map[string]map[int64]time.Time)
+	for _, e := range entries {
+		if enrollments[e.ChallengeType] == nil {
+			enrollments[e.ChallengeType] = make(map[int64]time.Time)
+		}
+		enrollments[e.ChallengeType][e.RegistrationID] = e.Expires
+	}
+	pa.blacklistMu.Lock()
+	pa.challengesWhitelistEnrollments = enrollments
+	pa.blacklistMu.Unlock()
+}
+
+// whitelisted returns whether wl has an unexpired entry for the challenge type
+// and account.
+func whitelisted(wl map[string]map[int64]time.Time, t string, regID int64, now time.Time) bool {
+	expires, ok := wl[t][regID]
+	return ok && (expires.IsZero() || now.Before(expires))
+}
+
+// SetChallengeKillSwitch makes the PA stop offering the challenge types
+// disabled by ks, even to whitelisted accounts.
+func (pa *AuthorityImpl) SetChallengeKillSwitch(ks *killswitch.Challenges) {
+	pa.blacklistMu.Lock()
+	pa.killSwitch = ks
+	pa.blacklistMu.Unlock()
+}
+
+// killed returns whether challenge type t is disabled by the kill switch.
+func (pa *AuthorityImpl) killed(t string) bool {
+	pa.blacklistMu.RLock()
+	ks := pa.killSwitch
+	pa.blacklistMu.RUnlock()
+	return ks.Disabled(t)
+}
+
 // ChallengeTypeEnabled returns whether the specified challenge type is enabled
 func (pa *AuthorityImpl) ChallengeTypeEnabled(t string, regID int64) bool {
+	if pa.killed(t) {
+		return false
+	}
 	pa.blacklistMu.RLock()
 	defer pa.blacklistMu.RUnlock()
-	return pa.enabledChallenges[t] ||
-		(pa.enabledChallengesWhitelist[t] != nil && pa.enabledChallengesWhitelist[t][regID])
+	now := pa.clk.Now()
+	enabled := pa.enabledChallenges[t]
+	if window, ok := pa.challengeWindows[t]; ok {
+		enabled = window.Contains(now)
+	}
+	return enabled ||
+		whitelisted(pa.enabledChallengesWhitelist, t, regID, now) ||
+		whitelisted(pa.challengesWhitelistEnrollments, t, regID, now)
 }
@@ -2,12 +2,18 @@ package policy
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/jmhodges/clock"
 	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
 	"github.com/letsencrypt/boulder/features"
+	"github.com/letsencrypt/boulder/killswitch"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/test"
 )
@@ -96,6 +102,10 @@ func TestWillingToIssue(t *testing.T) {
 
 		{`example.acting`, errNonPublic},
 		{`example.internal`, errNonPublic},
+		{`example.test`, errReservedTLD},
+		{`www.example.example`, errReservedTLD},
+		{`server.corp`, errReservedTLD},
+		{`app.localhost`, errReservedTLD},
 		// All-numeric final label not okay.
 		{`www.zombo.163`, errNonPublic},
 		{`xn--109-3veba6djs1bfxlfmx6c9g.xn--f1awi.xn--p1ai`, errMalformedIDN}, // Not in Unicode NFC
@@ -333,6 +343,62 @@ func TestChallengesFor(t *testing.T) {
 
 }
 
+func TestAllowReservedNames(t *testing.T) {
+	pa := paImpl(t)
+	err := pa.loadHostnamePolicy([]byte(`{"Blacklist": ["blocked.test"], "ExactBlacklist": []}`))
+	test.AssertNotError(t, err, "loading hostname policy")
+	dns := func(name string) core.AcmeIdentifier {
+		return core.AcmeIdentifier{Type: core.IdentifierDNS, Value: name}
+	}
+	for _, name := range []string{"example.test", "*.example.test", "nas.home"} {
+		err := pa.WillingToIssueWildcard(dns(name))
+		test.AssertEquals(t, err, errReservedTLD)
+	}
+
+	pa.SetAllowReservedNames(true)
+	for _, name := range []string{"example.test", "*.example.test", "nas.home", "www.example.com"} {
+		err := pa.WillingToIssueWildcard(dns(name))
+		test.AssertNotError(t, err, fmt.Sprintf("allowed reserved name %q was rejected", name))
+	}
+	test.AssertEquals(t, pa.WillingToIssueWildcard(dns("*.test")), errICANNTLDWildcard)
+	test.AssertEquals(t, pa.WillingToIssueWildcard(dns("test")), errTooFewLabels)
+
+	// Allowed reserved names are still subject to the hostname policy
+	test.AssertEquals(t, pa.WillingToIssueWildcard(dns("www.blocked.test")), errBlacklisted)
+}
+
+func TestChallengesForTokenFormat(t *testing.T) {
+	pa := paImpl(t)
+	err := pa.SetTokenFormat(core.TokenFormat{Length: 8})
+	test.AssertError(t, err, "token format with too little entropy was accepted")
+
+	format := core.TokenFormat{Length: 32, Alphabet: "0123456789abcdef"}
+	err = pa.SetTokenFormat(format)
+	test.AssertNotError(t, err, "SetTokenFormat failed")
+
+	challenges, _, err := pa.ChallengesFor(core.AcmeIdentifier{}, testRegID, false)
+	test.AssertNotError(t, err, "ChallengesFor failed")
+	tokens := make(map[string]bool)
+	for _, challenge := range challenges {
+		test.AssertEquals(t, len(challenge.Token), 32)
+		test.AssertEquals(t, strings.Trim(challenge.Token, format.Alphabet), "")
+		tokens[challenge.Token] = true
+	}
+	test.AssertEquals(t, len(tokens), len(challenges))
+
+	// The new authorization schema's single token is always in the default
+	// format
+	err = features.Set(map[string]bool{"NewAuthorizationSchema": true})
+	test.AssertNotError(t, err, "setting NewAuthorizationSchema feature")
+	defer features.Reset()
+	challenges, _, err = pa.ChallengesFor(core.AcmeIdentifier{}, testRegID, false)
+	test.AssertNotError(t, err, "ChallengesFor failed")
+	for _, challenge := range challenges {
+		test.AssertEquals(t, challenge.Token, challenges[0].Token)
+	}
+	test.AssertEquals(t, len(challenges[0].Token), 43)
+}
+
 func TestChallengesForWhitelist(t *testing.T) {
 	enabledChallenges[core.ChallengeTypeTLSSNI01] = false
 
@@ -362,6 +428,150 @@ func TestChallengesForWhitelist(t *testing.T) {
 	test.Assert(t, len(challenges) == len(enabledChallenges), "Wrong number of challenges returned")
 }
 
+func TestChallengesWhitelistExpiry(t *testing.T) {
+	enabledChallenges[core.ChallengeTypeTLSSNI01] = false
+	pa := paImpl(t)
+	fc := clock.NewFake()
+	fc.Set(time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC))
+	pa.clk = fc
+
+	err := pa.loadChallengesWhitelist([]byte(`{
+		"tls-sni-01": [1001, {"regID": 1002}, {"regID": 1003, "expires": "2019-04-10T00:00:00Z"}]
+	}`))
+	test.AssertNotError(t, err, "Couldn't load challenges whitelist")
+	pa.SetChallengesWhitelistEnrollments([]ChallengeWhitelistEntry{
+		{ChallengeType: core.ChallengeTypeTLSSNI01, RegistrationID: 1004},
+		{ChallengeType: core.ChallengeTypeTLSSNI01, RegistrationID: 1005, Expires: time.Date(2019, 4, 5, 0, 0, 0, 0, time.UTC)},
+	})
+
+	enabled := func(regID int64) bool {
+		return pa.ChallengeTypeEnabled(core.ChallengeTypeTLSSNI01, regID)
+	}
+	for _, regID := range []int64{1001, 1002, 1003, 1004, 1005} {
+		test.Assert(t, enabled(regID), fmt.Sprintf("TLS-SNI-01 disabled for %d", regID))
+	}
+	test.Assert(t, !enabled(testRegID), "TLS-SNI-01 enabled for a non-whitelisted account")
+
+	fc.Set(time.Date(2019, 4, 5, 0, 0, 0, 0, time.UTC))
+	test.Assert(t, !enabled(1005), "TLS-SNI-01 enabled after the enrollment expired")
+	test.Assert(t, enabled(1003), "TLS-SNI-01 disabled before the whitelist entry expired")
+	fc.Set(time.Date(2019, 4, 10, 0, 0, 0, 0, time.UTC))
+	test.Assert(t, !enabled(1003), "TLS-SNI-01 enabled after the whitelist entry expired")
+	test.Assert(t, enabled(1001) && enabled(1004), "TLS-SNI-01 disabled for entries that never expire")
+
+	// Replacing the enrollments drops the old ones
+	pa.SetChallengesWhitelistEnrollments(nil)
+	test.Assert(t, !enabled(1004), "TLS-SNI-01 enabled after the enrollment was removed")
+
+	for _, invalid := range []string{
+		`{"tls-sni-01": [{"expires": "2019-04-10T00:00:00Z"}]}`,
+		`{"tls-sni-01": [{"regID": 1001, "expires": "soon"}]}`,
+		`{"tls-sni-01": ["1001"]}`,
+	} {
+		err = pa.loadChallengesWhitelist([]byte(invalid))
+		test.AssertError(t, err, "Invalid challenges whitelist loaded: "+invalid)
+	}
+}
+
+func TestChallengeKillSwitch(t *testing.T) {
+	pa := paImpl(t)
+	pa.SetChallengesWhitelistEnrollments([]ChallengeWhitelistEntry{
+		{ChallengeType: core.ChallengeTypeHTTP01, RegistrationID: testRegIDWhitelisted},
+	})
+
+	f, _ := ioutil.TempFile("", "test-challenge-kill-switch.json")
+	defer os.Remove(f.Name())
+	err := ioutil.WriteFile(f.Name(), []byte(`{"http-01": {"disabledBy": "jsmith", "sunset": "2030-01-01T00:00:00Z"}}`), 0640)
+	test.AssertNotError(t, err, "Couldn't write kill switch file")
+	ks := killswitch.NewChallenges(clock.NewFake(), blog.NewMock())
+	test.AssertNotError(t, ks.WatchFile(f.Name()), "Couldn't load kill switch file")
+	pa.SetChallengeKillSwitch(ks)
+
+	// The kill switch overrides the whitelists
+	test.Assert(t, !pa.ChallengeTypeEnabled(core.ChallengeTypeHTTP01, testRegID), "HTTP-01 enabled")
+	test.Assert(t, !pa.ChallengeTypeEnabled(core.ChallengeTypeHTTP01, testRegIDWhitelisted), "HTTP-01 enabled for a whitelisted account")
+	test.Assert(t, pa.ChallengeTypeEnabled(core.ChallengeTypeDNS01, testRegID), "DNS-01 disabled")
+
+	challenges, _, err := pa.ChallengesFor(core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "example.com"}, testRegIDWhitelisted, false)
+	test.AssertNotError(t, err, "ChallengesFor failed")
+	for _, chall := range challenges {
+		test.Assert(t, chall.Type != core.ChallengeTypeHTTP01, "HTTP-01 challenge offered")
+	}
+}
+
+func TestSetChallengesFile(t *testing.T) {
+	pa := paImpl(t)
+
+	f, _ := ioutil.TempFile("", "test-challenges.json")
+	defer os.Remove(f.Name())
+	err := ioutil.WriteFile(f.Name(), []byte(`{"http-01": true, "dns-01": false}`), 0640)
+	test.AssertNotError(t, err, "Couldn't write challenges file")
+	err = pa.SetChallengesFile(f.Name())
+	test.AssertNotError(t, err, "Couldn't load challenges file")
+	test.Assert(t, pa.ChallengeTypeEnabled(core.ChallengeTypeHTTP01, testRegID), "HTTP-01 disabled")
+	test.Assert(t, !pa.ChallengeTypeEnabled(core.ChallengeTypeDNS01, testRegID), "DNS-01 enabled")
+	test.Assert(t, !pa.ChallengeTypeEnabled(core.ChallengeTypeTLSSNI01, testRegID), "TLS-SNI-01 enabled")
+
+	// Invalid contents are rejected and leave the previous challenges enabled
+	for _, invalid := range []string{`{}`, `{"http-01": true, "bogus-01": true}`, `not json`} {
+		err = pa.loadChallenges([]byte(invalid))
+		test.AssertError(t, err, "Invalid challenges loaded: "+invalid)
+	}
+	test.Assert(t, pa.ChallengeTypeEnabled(core.ChallengeTypeHTTP01, testRegID), "HTTP-01 disabled")
+	test.Assert(t, !pa.ChallengeTypeEnabled(core.ChallengeTypeDNS01, testRegID), "DNS-01 enabled")
+}
+
+func TestScheduledChallenges(t *testing.T) {
+	pa := paImpl(t)
+	fc := clock.NewFake()
+	fc.Set(time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC))
+	pa.clk = fc
+
+	err := pa.loadChallenges([]byte(`{
+		"http-01": true,
+		"tls-sni-01": {"disableAt": "2019-03-13T00:00:00Z"},
+		"tls-alpn-01": {"enableAt": "2019-03-05T00:00:00Z"},
+		"dns-01": {"enableAt": "2019-03-02T00:00:00Z", "disableAt": "2019-03-10T00:00:00Z"}
+	}`))
+	test.AssertNotError(t, err, "Couldn't load scheduled challenges")
+
+	enabled := func(when string, types ...string) {
+		t.Helper()
+		now, err := time.Parse(time.RFC3339, when)
+		test.AssertNotError(t, err, "parsing time")
+		fc.Set(now)
+		want := map[string]bool{}
+		for _, typ := range types {
+			want[typ] = true
+		}
+		for _, typ := range []string{core.ChallengeTypeHTTP01, core.ChallengeTypeTLSSNI01, core.ChallengeTypeTLSALPN01, core.ChallengeTypeDNS01} {
+			test.AssertEquals(t, pa.ChallengeTypeEnabled(typ, testRegID), want[typ])
+		}
+	}
+	enabled("2019-03-01T00:00:00Z", core.ChallengeTypeHTTP01, core.ChallengeTypeTLSSNI01)
+	enabled("2019-03-02T00:00:00Z", core.ChallengeTypeHTTP01, core.ChallengeTypeTLSSNI01, core.ChallengeTypeDNS01)
+	enabled("2019-03-05T00:00:00Z", core.ChallengeTypeHTTP01, core.ChallengeTypeTLSSNI01, core.ChallengeTypeDNS01, core.ChallengeTypeTLSALPN01)
+	enabled("2019-03-10T00:00:00Z", core.ChallengeTypeHTTP01, core.ChallengeTypeTLSSNI01, core.ChallengeTypeTLSALPN01)
+	enabled("2019-03-13T00:00:00Z", core.ChallengeTypeHTTP01, core.ChallengeTypeTLSALPN01)
+
+	// A whitelisted account can still use a challenge type outside its window
+	pa.enabledChallengesWhitelist = map[string]map[int64]time.Time{
+		core.ChallengeTypeDNS01: {testRegIDWhitelisted: time.Time{}},
+	}
+	test.Assert(t, pa.ChallengeTypeEnabled(core.ChallengeTypeDNS01, testRegIDWhitelisted), "whitelisted DNS-01 disabled")
+
+	for _, invalid := range []string{
+		`{"http-01": {}}`,
+		`{"http-01": {"enableAt": "soon"}}`,
+		`{"http-01": {"enableAt": "2019-03-02T00:00:00Z", "disable": "2019-03-10T00:00:00Z"}}`,
+		`{"http-01": {"enableAt": "2019-03-10T00:00:00Z", "disableAt": "2019-03-02T00:00:00Z"}}`,
+		`{"http-01": "yes"}`,
+	} {
+		err = pa.loadChallenges([]byte(invalid))
+		test.AssertError(t, err, "Invalid challenges loaded: "+invalid)
+	}
+}
+
 func TestChallengesForWildcard(t *testing.T) {
 	// wildcardIdent is an identifier for a wildcard domain name
 	wildcardIdent := core.AcmeIdentifier{
@@ -401,6 +611,43 @@ func TestChallengesForWildcard(t *testing.T) {
 	test.AssertEquals(t, challenges[0].Type, core.ChallengeTypeDNS01)
 }
 
+func TestChallengesForIP(t *testing.T) {
+	ipIdent := core.AcmeIdentifier{
+		Type:  core.IdentifierIP,
+		Value: "10.0.0.1",
+	}
+
+	pa, err := New(map[string]bool{
+		core.ChallengeTypeHTTP01:    true,
+		core.ChallengeTypeTLSSNI01:  true,
+		core.ChallengeTypeTLSALPN01: true,
+		core.ChallengeTypeDNS01:     true,
+	})
+	test.AssertNotError(t, err, "Couldn't create policy implementation")
+
+	// Only HTTP-01 and TLS-ALPN-01 can validate an IP address
+	challenges, combinations, err := pa.ChallengesFor(ipIdent, testRegID, false)
+	test.AssertNotError(t, err, "ChallengesFor errored for an IP ident")
+	test.AssertEquals(t, len(combinations), 2)
+	test.AssertEquals(t, len(challenges), 2)
+	seenChalls := make(map[string]bool)
+	for _, challenge := range challenges {
+		seenChalls[challenge.Type] = true
+	}
+	test.Assert(t, seenChalls[core.ChallengeTypeHTTP01], "HTTP-01 not offered")
+	test.Assert(t, seenChalls[core.ChallengeTypeTLSALPN01], "TLS-ALPN-01 not offered")
+
+	// With neither enabled there are no challenges to offer
+	pa, err = New(map[string]bool{
+		core.ChallengeTypeTLSSNI01: true,
+		core.ChallengeTypeDNS01:    true,
+	})
+	test.AssertNotError(t, err, "Couldn't create policy implementation")
+	_, _, err = pa.ChallengesFor(ipIdent, testRegID, false)
+	test.AssertError(t, err, "ChallengesFor did not error for an IP ident "+
+		"when HTTP-01 and TLS-ALPN-01 were disabled")
+}
+
 // TestMalformedExactBlacklist tests that loading a JSON policy file with an
 // invalid exact blacklist entry will fail as expected.
 func TestMalformedExactBlacklist(t *testing.T) {
@@ -434,3 +681,190 @@ func TestMalformedExactBlacklist(t *testing.T) {
 	test.AssertError(t, err, "Loaded invalid exact blacklist content without error")
 	test.AssertEquals(t, err.Error(), "Malformed exact blacklist entry, only one label: \"com\"")
 }
+
+func TestHostnamePolicyMetadata(t *testing.T) {
+	pa := paImpl(t)
+	pa.rejections = NewRejectionLog(clock.NewFake(), 10)
+
+	policy := `{
+		"Blacklist": ["website2.com", "website3.com"],
+		"ExactBlacklist": ["highvalue.website1.org"],
+		"Metadata": {
+			"website2.com": {"reason": "phishing", "ticket": "OPS-1", "addedBy": "jsha", "severity": "high"},
+			"highvalue.website1.org": {"reason": "requested by owner", "severity": "low"}
+		}
+	}`
+	err := pa.loadHostnamePolicy([]byte(policy))
+	test.AssertNotError(t, err, "Couldn't load hostname policy")
+
+	log.Clear()
+	err = pa.WillingToIssue(core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "www.website2.com"})
+	test.AssertEquals(t, err, errBlacklisted)
+	test.AssertEquals(t, len(log.GetAllMatching(`Hostname policy rejected "www.website2.com": blacklist entry "website2.com" reason=\[phishing\] ticket=\[OPS-1\] addedBy=\[jsha\] severity=\[high\]`)), 1)
+
+	// Entries don't need metadata
+	err = pa.WillingToIssue(core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "website3.com"})
+	test.AssertEquals(t, err, errBlacklisted)
+
+	// A wildcard covering an exact entry is rejected because of that entry
+	err = pa.WillingToIssueWildcard(core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "*.website1.org"})
+	test.AssertEquals(t, err, errBlacklisted)
+
+	rejections := pa.rejections.Rejections()
+	test.AssertEquals(t, len(rejections), 3)
+	test.AssertEquals(t, rejections[0].Name, "*.website1.org")
+	test.AssertEquals(t, rejections[0].List, "exactBlacklist")
+	test.AssertEquals(t, rejections[0].Entry, "highvalue.website1.org")
+	test.AssertEquals(t, rejections[0].Metadata.Reason, "requested by owner")
+	test.AssertEquals(t, rejections[1].Entry, "website3.com")
+	test.AssertEquals(t, rejections[1].Metadata, EntryMetadata{})
+	test.AssertEquals(t, rejections[2].Name, "www.website2.com")
+	test.AssertEquals(t, rejections[2].Metadata.Ticket, "OPS-1")
+
+	err = pa.loadHostnamePolicy([]byte(`{"Blacklist": ["website2.com"], "Metadata": {"website3.com": {"reason": "?"}}}`))
+	test.AssertError(t, err, "Loaded metadata for a name that isn't an entry")
+	err = pa.loadHostnamePolicy([]byte(`{"Blacklist": ["website2.com"], "Metadata": {"website2.com": {"severity": "extreme"}}}`))
+	test.AssertError(t, err, "Loaded metadata with an invalid severity")
+}
+
+func TestBlockingEntry(t *testing.T) {
+	pa := paImpl(t)
+	pa.rejections = NewRejectionLog(clock.NewFake(), 10)
+	err := pa.loadHostnamePolicy([]byte(`{
+		"Blacklist": ["website2.com"],
+		"ExactBlacklist": ["highvalue.website1.org"]
+	}`))
+	test.AssertNotError(t, err, "Couldn't load hostname policy")
+
+	testCases := []struct {
+		name  string
+		list  string
+		entry string
+	}{
+		{"website2.com", "blacklist", "website2.com"},
+		{"www.website2.com", "blacklist", "website2.com"},
+		{"*.website2.com", "blacklist", "website2.com"},
+		{"highvalue.website1.org", "exactBlacklist", "highvalue.website1.org"},
+		{"*.website1.org", "exactBlacklist", "highvalue.website1.org"},
+		{"www.highvalue.website1.org", "", ""},
+		{"website1.org", "", ""},
+		{"*.example.com", "", ""},
+	}
+	log.Clear()
+	for _, tc := range testCases {
+		list, entry := pa.BlockingEntry(tc.name)
+		test.AssertEquals(t, list, tc.list)
+		test.AssertEquals(t, entry, tc.entry)
+	}
+	// Nothing is logged or recorded
+	test.AssertEquals(t, len(log.GetAllMatching("Hostname policy rejected")), 0)
+	test.AssertEquals(t, len(pa.rejections.Rejections()), 0)
+}
+
+func TestWillingToIssueExempt(t *testing.T) {
+	pa := paImpl(t)
+	err := pa.loadHostnamePolicy([]byte(`{
+		"Blacklist": ["website2.com", "shop.website2.com"],
+		"ExactBlacklist": ["highvalue.website1.org"]
+	}`))
+	test.AssertNotError(t, err, "Couldn't load hostname policy")
+
+	testCases := []struct {
+		name    string
+		exempt  string
+		allowed bool
+	}{
+		{"website2.com", "website2.com", true},
+		{"www.website2.com", "website2.com", true},
+		{"*.website2.com", "website2.com", true},
+		// Other entries still apply
+		{"www.shop.website2.com", "website2.com", false},
+		{"www.shop.website2.com", "shop.website2.com", false},
+		{"website2.com", "website3.com", false},
+		{"highvalue.website1.org", "highvalue.website1.org", true},
+		{"*.website1.org", "highvalue.website1.org", true},
+		{"*.website1.org", "website1.org", false},
+		// Exemptions don't relax the other checks
+		{"website2.com.", "website2.com", false},
+	}
+	log.Clear()
+	for _, tc := range testCases {
+		err := pa.WillingToIssueExempt(core.AcmeIdentifier{Type: core.IdentifierDNS, Value: tc.name}, tc.exempt)
+		if tc.allowed {
+			test.AssertNotError(t, err, fmt.Sprintf("%q exempt from %q was rejected", tc.name, tc.exempt))
+		} else {
+			test.AssertError(t, err, fmt.Sprintf("%q exempt from %q was allowed", tc.name, tc.exempt))
+		}
+	}
+	test.AssertEquals(t, len(log.GetAllMatching(`Hostname policy exemption allowed "www.website2.com" despite blacklist entry "website2.com"`)), 1)
+	test.AssertEquals(t, len(log.GetAllMatching(`Hostname policy exemption allowed "\*.website1.org" despite exactBlacklist entry "highvalue.website1.org"`)), 1)
+
+	// Without an exemption, WillingToIssueExempt is WillingToIssueWildcard
+	err = pa.WillingToIssueExempt(core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "website2.com"}, "")
+	test.AssertEquals(t, err, errBlacklisted)
+	err = pa.WillingToIssueExempt(core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "*.example.com"}, "")
+	test.AssertNotError(t, err, "Rejected a name without an exemption")
+}
+
+func TestCheckOrderNames(t *testing.T) {
+	pa := paImpl(t)
+
+	// Orders without wildcards are never rejected
+	test.AssertNotError(t, pa.CheckOrderNames([]string{"example.com", "www.example.com"}), "Rejected an order without wildcards")
+
+	testCases := []struct {
+		name     string
+		policy   WildcardOrderPolicy
+		names    []string
+		rejected []string
+	}{
+		{
+			name:  "default policy",
+			names: []string{"*.example.com", "example.com", "www.shop.example.com", "*.other.example.com", "example.net"},
+		},
+		{
+			name:     "covered names are redundant",
+			names:    []string{"*.example.com", "example.com", "www.example.com", "*.www.example.com"},
+			rejected: []string{"www.example.com"},
+		},
+		{
+			name:     "reject base domain",
+			policy:   WildcardOrderPolicy{RejectBaseDomain: true},
+			names:    []string{"*.example.com", "example.com", "www.shop.example.com", "example.net"},
+			rejected: []string{"example.com"},
+		},
+		{
+			name:     "reject subdomains",
+			policy:   WildcardOrderPolicy{RejectSubdomains: true},
+			names:    []string{"*.example.com", "example.com", "www.example.com", "www.shop.example.com", "*.other.example.com"},
+			rejected: []string{"www.example.com", "www.shop.example.com", "*.other.example.com"},
+		},
+		{
+			name:     "reject both",
+			policy:   WildcardOrderPolicy{RejectBaseDomain: true, RejectSubdomains: true},
+			names:    []string{"*.example.com", "example.com", "a.b.example.com", "example.net"},
+			rejected: []string{"example.com", "a.b.example.com"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pa.SetWildcardOrderPolicy(tc.policy)
+			err := pa.CheckOrderNames(tc.names)
+			if len(tc.rejected) == 0 {
+				test.AssertNotError(t, err, "Rejected an allowed order")
+				return
+			}
+			test.Assert(t, berrors.Is(err, berrors.Malformed), "Wrong error type")
+			subErrs := err.(*berrors.BoulderError).SubErrors
+			var rejected []string
+			for _, subErr := range subErrs {
+				test.AssertEquals(t, subErr.Type, berrors.Malformed)
+				rejected = append(rejected, subErr.Name)
+			}
+			test.AssertDeepEquals(t, rejected, tc.rejected)
+			if len(subErrs) == 1 {
+				test.AssertEquals(t, err.Error(), subErrs[0].Detail)
+			}
+		})
+	}
+}
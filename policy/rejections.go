@@ -0,0 +1,74 @@
+package policy
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+)
+
+// maxRejections is how many rejections the DefaultRejections log keeps.
+const maxRejections = 100
+
+// Rejection is a name the policy authority rejected because of a hostname
+// policy entry.
+type Rejection struct {
+	Time time.Time `json:"time"`
+	Name string    `json:"name"`
+	// List is the list the entry is on, "blacklist" or "exactBlacklist"
+	List     string        `json:"list"`
+	Entry    string        `json:"entry"`
+	Metadata EntryMetadata `json:"metadata"`
+}
+
+// RejectionLog keeps the most recent hostname policy rejections, so that
+// operators can see which entries are blocking names and why they exist.
+type RejectionLog struct {
+	clk clock.Clock
+	max int
+
+	mu         sync.Mutex
+	rejections []Rejection
+}
+
+// NewRejectionLog returns an empty RejectionLog that keeps the max most recent
+// rejections.
+func NewRejectionLog(clk clock.Clock, max int) *RejectionLog {
+	return &RejectionLog{clk: clk, max: max}
+}
+
+// DefaultRejections is the RejectionLog that policy authorities record their
+// rejections in, served on the debug server.
+var DefaultRejections = NewRejectionLog(clock.Default(), maxRejections)
+
+func (l *RejectionLog) add(r Rejection) {
+	r.Time = l.clk.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rejections = append(l.rejections, r)
+	if len(l.rejections) > l.max {
+		l.rejections = l.rejections[len(l.rejections)-l.max:]
+	}
+}
+
+// Rejections returns the rejections in the log, most recent first.
+func (l *RejectionLog) Rejections() []Rejection {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rejections := make([]Rejection, len(l.rejections))
+	for i, r := range l.rejections {
+		rejections[len(l.rejections)-1-i] = r
+	}
+	return rejections
+}
+
+// Handler returns an http.Handler that serves the rejections in the log as
+// JSON, most recent first.
+func (l *RejectionLog) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(l.Rejections())
+	})
+}
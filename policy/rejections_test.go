@@ -0,0 +1,38 @@
+package policy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestRejectionLog(t *testing.T) {
+	clk := clock.NewFake()
+	l := NewRejectionLog(clk, 2)
+	l.add(Rejection{Name: "a.com"})
+	clk.Add(time.Second)
+	l.add(Rejection{Name: "b.com"})
+	clk.Add(time.Second)
+	l.add(Rejection{Name: "c.com", Entry: "c.com", Metadata: EntryMetadata{Reason: "abuse"}})
+
+	// Only the most recent two are kept, most recent first
+	rejections := l.Rejections()
+	test.AssertEquals(t, len(rejections), 2)
+	test.AssertEquals(t, rejections[0].Name, "c.com")
+	test.AssertEquals(t, rejections[0].Time, clk.Now())
+	test.AssertEquals(t, rejections[1].Name, "b.com")
+
+	w := httptest.NewRecorder()
+	l.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/debug/hostname-policy-rejections", nil))
+	test.AssertEquals(t, w.Code, http.StatusOK)
+	var served []Rejection
+	err := json.Unmarshal(w.Body.Bytes(), &served)
+	test.AssertNotError(t, err, "Couldn't unmarshal rejections")
+	test.AssertEquals(t, len(served), 2)
+	test.AssertEquals(t, served[0].Metadata.Reason, "abuse")
+}
@@ -3,6 +3,7 @@ package probs
 import (
 	"fmt"
 	"net/http"
+	"time"
 )
 
 // Error types that can be used in ACME payloads
@@ -22,6 +23,19 @@ const (
 	DNSProblem                 = ProblemType("dns")
 	AlreadyRevokedProblem      = ProblemType("alreadyRevoked")
 	OrderNotReadyProblem       = ProblemType("orderNotReady")
+	BadPublicKeyProblem        = ProblemType("badPublicKey")
+	BadCSRProblem              = ProblemType("badCSR")
+	// The remaining error types are only defined by RFC 8555 and should only be
+	// used by the ACME v2 WFE.
+	BadRevocationReasonProblem     = ProblemType("badRevocationReason")
+	BadSignatureAlgorithmProblem   = ProblemType("badSignatureAlgorithm")
+	CompoundProblem                = ProblemType("compound")
+	ExternalAccountRequiredProblem = ProblemType("externalAccountRequired")
+	IncorrectResponseProblem       = ProblemType("incorrectResponse")
+	InvalidContactProblem          = ProblemType("invalidContact")
+	UnsupportedContactProblem      = ProblemType("unsupportedContact")
+	UnsupportedIdentifierProblem   = ProblemType("unsupportedIdentifier")
+	UserActionRequiredProblem      = ProblemType("userActionRequired")
 
 	V1ErrorNS = "urn:acme:error:"
 	V2ErrorNS = "urn:ietf:params:acme:error:"
@@ -38,12 +52,51 @@ type ProblemDetails struct {
 	// HTTPStatus is the HTTP status code the ProblemDetails should probably be sent
 	// as.
 	HTTPStatus int `json:"status,omitempty"`
+	// RetryAfter is how long the client should wait before retrying. If
+	// non-zero it is sent to the client in a Retry-After header.
+	RetryAfter time.Duration `json:"-"`
+	// Identifier is the identifier the problem relates to, if any. It is
+	// usually only set on subproblems.
+	Identifier *Identifier `json:"identifier,omitempty"`
+	// SubProblems are the problems with individual identifiers that make up
+	// this problem (RFC 8555 section 6.7.1).
+	SubProblems []*ProblemDetails `json:"subproblems,omitempty"`
+}
+
+// Identifier is an ACME identifier included in a problem document. It has the
+// same JSON encoding as core.AcmeIdentifier, which can't be used here because
+// the core package depends on this one.
+type Identifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
 }
 
 func (pd *ProblemDetails) Error() string {
 	return fmt.Sprintf("%s :: %s", pd.Type, pd.Detail)
 }
 
+// WithIdentifier sets the identifier the problem relates to and returns the
+// problem.
+func (pd *ProblemDetails) WithIdentifier(identType, value string) *ProblemDetails {
+	pd.Identifier = &Identifier{Type: identType, Value: value}
+	return pd
+}
+
+// WithSubProblems appends subproblems to the problem and returns the problem.
+func (pd *ProblemDetails) WithSubProblems(subProblems ...*ProblemDetails) *ProblemDetails {
+	pd.SubProblems = append(pd.SubProblems, subProblems...)
+	return pd
+}
+
+// AddNamespace prefixes the type of the problem and each of its subproblems
+// with namespace.
+func (pd *ProblemDetails) AddNamespace(namespace string) {
+	pd.Type = ProblemType(namespace) + pd.Type
+	for _, sub := range pd.SubProblems {
+		sub.AddNamespace(namespace)
+	}
+}
+
 // statusTooManyRequests is the HTTP status code meant for rate limiting
 // errors. It's not currently in the net/http library so we add it here.
 const statusTooManyRequests = 429
@@ -64,13 +117,27 @@ func ProblemDetailsToStatusCode(prob *ProblemDetails) int {
 		BadNonceProblem,
 		InvalidEmailProblem,
 		RejectedIdentifierProblem,
-		AccountDoesNotExistProblem:
+		AccountDoesNotExistProblem,
+		BadPublicKeyProblem,
+		BadCSRProblem,
+		DNSProblem,
+		AlreadyRevokedProblem,
+		BadRevocationReasonProblem,
+		BadSignatureAlgorithmProblem,
+		CompoundProblem,
+		InvalidContactProblem,
+		UnsupportedContactProblem,
+		UnsupportedIdentifierProblem:
 		return http.StatusBadRequest
 	case ServerInternalProblem:
 		return http.StatusInternalServerError
 	case
 		UnauthorizedProblem,
-		CAAProblem:
+		CAAProblem,
+		OrderNotReadyProblem,
+		ExternalAccountRequiredProblem,
+		IncorrectResponseProblem,
+		UserActionRequiredProblem:
 		return http.StatusForbidden
 	case RateLimitedProblem:
 		return statusTooManyRequests
@@ -272,3 +339,122 @@ func OrderNotReady(detail string, a ...interface{}) *ProblemDetails {
 		HTTPStatus: http.StatusForbidden,
 	}
 }
+
+// BadPublicKey returns a ProblemDetails representing a BadPublicKeyProblem
+func BadPublicKey(detail string, a ...interface{}) *ProblemDetails {
+	return &ProblemDetails{
+		Type:       BadPublicKeyProblem,
+		Detail:     fmt.Sprintf(detail, a...),
+		HTTPStatus: http.StatusBadRequest,
+	}
+}
+
+// BadCSR returns a ProblemDetails representing a BadCSRProblem
+func BadCSR(detail string, a ...interface{}) *ProblemDetails {
+	return &ProblemDetails{
+		Type:       BadCSRProblem,
+		Detail:     fmt.Sprintf(detail, a...),
+		HTTPStatus: http.StatusBadRequest,
+	}
+}
+
+// BadRevocationReason returns a ProblemDetails representing a
+// BadRevocationReasonProblem
+func BadRevocationReason(detail string, a ...interface{}) *ProblemDetails {
+	return &ProblemDetails{
+		Type:       BadRevocationReasonProblem,
+		Detail:     fmt.Sprintf(detail, a...),
+		HTTPStatus: http.StatusBadRequest,
+	}
+}
+
+// BadSignatureAlgorithm returns a ProblemDetails representing a
+// BadSignatureAlgorithmProblem
+func BadSignatureAlgorithm(detail string, a ...interface{}) *ProblemDetails {
+	return &ProblemDetails{
+		Type:       BadSignatureAlgorithmProblem,
+		Detail:     fmt.Sprintf(detail, a...),
+		HTTPStatus: http.StatusBadRequest,
+	}
+}
+
+// Compound returns a ProblemDetails representing a CompoundProblem made up of
+// the given subproblems. Its HTTP status code is the one shared by all of the
+// subproblems, or 400 Bad Request if they differ.
+func Compound(subProblems []*ProblemDetails, detail string, a ...interface{}) *ProblemDetails {
+	status := http.StatusBadRequest
+	for i, sub := range subProblems {
+		subStatus := ProblemDetailsToStatusCode(sub)
+		if i == 0 {
+			status = subStatus
+		} else if subStatus != status {
+			status = http.StatusBadRequest
+			break
+		}
+	}
+	return &ProblemDetails{
+		Type:        CompoundProblem,
+		Detail:      fmt.Sprintf(detail, a...),
+		HTTPStatus:  status,
+		SubProblems: subProblems,
+	}
+}
+
+// ExternalAccountRequired returns a ProblemDetails representing an
+// ExternalAccountRequiredProblem
+func ExternalAccountRequired(detail string, a ...interface{}) *ProblemDetails {
+	return &ProblemDetails{
+		Type:       ExternalAccountRequiredProblem,
+		Detail:     fmt.Sprintf(detail, a...),
+		HTTPStatus: http.StatusForbidden,
+	}
+}
+
+// IncorrectResponse returns a ProblemDetails representing an
+// IncorrectResponseProblem
+func IncorrectResponse(detail string, a ...interface{}) *ProblemDetails {
+	return &ProblemDetails{
+		Type:       IncorrectResponseProblem,
+		Detail:     fmt.Sprintf(detail, a...),
+		HTTPStatus: http.StatusForbidden,
+	}
+}
+
+// InvalidContact returns a ProblemDetails representing an InvalidContactProblem
+func InvalidContact(detail string, a ...interface{}) *ProblemDetails {
+	return &ProblemDetails{
+		Type:       InvalidContactProblem,
+		Detail:     fmt.Sprintf(detail, a...),
+		HTTPStatus: http.StatusBadRequest,
+	}
+}
+
+// UnsupportedContact returns a ProblemDetails representing an
+// UnsupportedContactProblem
+func UnsupportedContact(detail string, a ...interface{}) *ProblemDetails {
+	return &ProblemDetails{
+		Type:       UnsupportedContactProblem,
+		Detail:     fmt.Sprintf(detail, a...),
+		HTTPStatus: http.StatusBadRequest,
+	}
+}
+
+// UnsupportedIdentifier returns a ProblemDetails representing an
+// UnsupportedIdentifierProblem
+func UnsupportedIdentifier(detail string, a ...interface{}) *ProblemDetails {
+	return &ProblemDetails{
+		Type:       UnsupportedIdentifierProblem,
+		Detail:     fmt.Sprintf(detail, a...),
+		HTTPStatus: http.StatusBadRequest,
+	}
+}
+
+// UserActionRequired returns a ProblemDetails representing a
+// UserActionRequiredProblem
+func UserActionRequired(detail string, a ...interface{}) *ProblemDetails {
+	return &ProblemDetails{
+		Type:       UserActionRequiredProblem,
+		Detail:     fmt.Sprintf(detail, a...),
+		HTTPStatus: http.StatusForbidden,
+	}
+}
@@ -1,6 +1,7 @@
 package probs
 
 import (
+	"encoding/json"
 	"testing"
 
 	"net/http"
@@ -35,6 +36,12 @@ func TestProblemDetailsToStatusCode(t *testing.T) {
 		{&ProblemDetails{Type: "foo", HTTPStatus: 200}, 200},
 		{&ProblemDetails{Type: ConnectionProblem, HTTPStatus: 200}, 200},
 		{&ProblemDetails{Type: AccountDoesNotExistProblem}, http.StatusBadRequest},
+		{&ProblemDetails{Type: BadPublicKeyProblem}, http.StatusBadRequest},
+		{&ProblemDetails{Type: BadCSRProblem}, http.StatusBadRequest},
+		{&ProblemDetails{Type: OrderNotReadyProblem}, http.StatusForbidden},
+		{&ProblemDetails{Type: BadRevocationReasonProblem}, http.StatusBadRequest},
+		{&ProblemDetails{Type: UnsupportedIdentifierProblem}, http.StatusBadRequest},
+		{&ProblemDetails{Type: UserActionRequiredProblem}, http.StatusForbidden},
 	}
 
 	for _, c := range testCases {
@@ -63,6 +70,16 @@ func TestProblemDetailsConvenience(t *testing.T) {
 		{TLSError("TLS error detail"), TLSProblem, http.StatusBadRequest, "TLS error detail"},
 		{RejectedIdentifier("rejected identifier detail"), RejectedIdentifierProblem, http.StatusBadRequest, "rejected identifier detail"},
 		{AccountDoesNotExist("no account detail"), AccountDoesNotExistProblem, http.StatusBadRequest, "no account detail"},
+		{BadPublicKey("bad public key detail"), BadPublicKeyProblem, http.StatusBadRequest, "bad public key detail"},
+		{BadCSR("bad CSR detail"), BadCSRProblem, http.StatusBadRequest, "bad CSR detail"},
+		{BadRevocationReason("bad reason detail"), BadRevocationReasonProblem, http.StatusBadRequest, "bad reason detail"},
+		{BadSignatureAlgorithm("bad alg detail"), BadSignatureAlgorithmProblem, http.StatusBadRequest, "bad alg detail"},
+		{ExternalAccountRequired("EAB detail"), ExternalAccountRequiredProblem, http.StatusForbidden, "EAB detail"},
+		{IncorrectResponse("incorrect detail"), IncorrectResponseProblem, http.StatusForbidden, "incorrect detail"},
+		{InvalidContact("invalid contact detail"), InvalidContactProblem, http.StatusBadRequest, "invalid contact detail"},
+		{UnsupportedContact("unsupported contact detail"), UnsupportedContactProblem, http.StatusBadRequest, "unsupported contact detail"},
+		{UnsupportedIdentifier("unsupported identifier detail"), UnsupportedIdentifierProblem, http.StatusBadRequest, "unsupported identifier detail"},
+		{UserActionRequired("action detail"), UserActionRequiredProblem, http.StatusForbidden, "action detail"},
 	}
 
 	for _, c := range testCases {
@@ -79,3 +96,38 @@ func TestProblemDetailsConvenience(t *testing.T) {
 		}
 	}
 }
+
+func TestSubProblems(t *testing.T) {
+	prob := Compound([]*ProblemDetails{
+		RejectedIdentifier("forbidden").WithIdentifier("dns", "example.net"),
+		UnsupportedIdentifier("not DNS").WithIdentifier("ip", "10.0.0.1"),
+	}, "Error creating new order")
+	test.AssertEquals(t, prob.HTTPStatus, http.StatusBadRequest)
+	prob.AddNamespace(V2ErrorNS)
+
+	doc, err := json.Marshal(prob)
+	test.AssertNotError(t, err, "marshalling problem")
+	test.AssertUnmarshaledEquals(t, string(doc), `{
+		"type": "urn:ietf:params:acme:error:compound",
+		"detail": "Error creating new order",
+		"status": 400,
+		"subproblems": [
+			{
+				"type": "urn:ietf:params:acme:error:rejectedIdentifier",
+				"detail": "forbidden",
+				"status": 400,
+				"identifier": {"type": "dns", "value": "example.net"}
+			},
+			{
+				"type": "urn:ietf:params:acme:error:unsupportedIdentifier",
+				"detail": "not DNS",
+				"status": 400,
+				"identifier": {"type": "ip", "value": "10.0.0.1"}
+			}
+		]
+	}`)
+
+	// A compound problem takes its status from its subproblems when they agree
+	prob = Compound([]*ProblemDetails{CAA("a"), Unauthorized("b")}, "forbidden")
+	test.AssertEquals(t, prob.HTTPStatus, http.StatusForbidden)
+}
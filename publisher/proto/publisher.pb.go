@@ -1,12 +1,12 @@
 // Code generated by protoc-gen-go.
-// source: publisher.proto
+// source: publisher/proto/publisher.proto
 // DO NOT EDIT!
 
 /*
 Package publisher is a generated protocol buffer package.
 
 It is generated from these files:
-	publisher.proto
+	publisher/proto/publisher.proto
 
 It has these top-level messages:
 	Request
@@ -35,12 +35,15 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
 type Request struct {
-	Der              []byte  `protobuf:"bytes,1,opt,name=der" json:"der,omitempty"`
-	LogURL           *string `protobuf:"bytes,2,opt,name=LogURL,json=logURL" json:"LogURL,omitempty"`
-	LogPublicKey     *string `protobuf:"bytes,3,opt,name=LogPublicKey,json=logPublicKey" json:"LogPublicKey,omitempty"`
-	Precert          *bool   `protobuf:"varint,4,opt,name=precert" json:"precert,omitempty"`
-	StoreSCT         *bool   `protobuf:"varint,5,opt,name=storeSCT" json:"storeSCT,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+	Der          []byte  `protobuf:"bytes,1,opt,name=der" json:"der,omitempty"`
+	LogURL       *string `protobuf:"bytes,2,opt,name=LogURL" json:"LogURL,omitempty"`
+	LogPublicKey *string `protobuf:"bytes,3,opt,name=LogPublicKey" json:"LogPublicKey,omitempty"`
+	Precert      *bool   `protobuf:"varint,4,opt,name=precert" json:"precert,omitempty"`
+	StoreSCT     *bool   `protobuf:"varint,5,opt,name=storeSCT" json:"storeSCT,omitempty"`
+	// staticLog is set if the log implements static-ct-api rather than
+	// RFC 6962.
+	StaticLog        *bool  `protobuf:"varint,6,opt,name=staticLog" json:"staticLog,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
 }
 
 func (m *Request) Reset()                    { *m = Request{} }
@@ -83,6 +86,13 @@ func (m *Request) GetStoreSCT() bool {
 	return false
 }
 
+func (m *Request) GetStaticLog() bool {
+	if m != nil && m.StaticLog != nil {
+		return *m.StaticLog
+	}
+	return false
+}
+
 type Result struct {
 	Sct              []byte `protobuf:"bytes,1,opt,name=sct" json:"sct,omitempty"`
 	XXX_unrecognized []byte `json:"-"`
@@ -174,25 +184,25 @@ var _Publisher_serviceDesc = grpc.ServiceDesc{
 		},
 	},
 	Streams:  []grpc.StreamDesc{},
-	Metadata: "publisher.proto",
+	Metadata: "publisher/proto/publisher.proto",
 }
 
-func init() { proto.RegisterFile("publisher.proto", fileDescriptor0) }
+func init() { proto.RegisterFile("publisher/proto/publisher.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 210 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4c, 0x8f, 0xc1, 0x4a, 0xc4, 0x30,
-	0x10, 0x86, 0x8d, 0xab, 0x6d, 0x77, 0x28, 0x28, 0x39, 0x48, 0xc8, 0xa9, 0xe4, 0xd4, 0x53, 0x41,
-	0x1f, 0xc0, 0xcb, 0x1e, 0xed, 0x61, 0x49, 0x2b, 0xde, 0x37, 0x0e, 0xdd, 0x40, 0x34, 0x35, 0x99,
-	0x1c, 0x7c, 0x08, 0xdf, 0x59, 0x1a, 0x5b, 0xd9, 0xdb, 0x7c, 0xff, 0xcf, 0x0c, 0xdf, 0xc0, 0xdd,
-	0x9c, 0x4e, 0xce, 0xc6, 0x33, 0x86, 0x6e, 0x0e, 0x9e, 0xbc, 0xfa, 0x61, 0x50, 0x6a, 0xfc, 0x4a,
-	0x18, 0x89, 0xdf, 0xc3, 0xee, 0x1d, 0x83, 0x60, 0x0d, 0x6b, 0x6b, 0xbd, 0x8c, 0xfc, 0x01, 0x8a,
-	0xde, 0x4f, 0xaf, 0xba, 0x17, 0xd7, 0x0d, 0x6b, 0xf7, 0xba, 0x70, 0x99, 0xb8, 0x82, 0xba, 0xf7,
-	0xd3, 0x71, 0xb9, 0x65, 0x5e, 0xf0, 0x5b, 0xec, 0x72, 0x5b, 0xbb, 0x8b, 0x8c, 0x0b, 0x28, 0xe7,
-	0x80, 0x06, 0x03, 0x89, 0x9b, 0x86, 0xb5, 0x95, 0xde, 0x90, 0x4b, 0xa8, 0x22, 0xf9, 0x80, 0xc3,
-	0x61, 0x14, 0xb7, 0xb9, 0xfa, 0x67, 0x25, 0xa1, 0xd0, 0x18, 0x93, 0xcb, 0x36, 0xd1, 0xd0, 0x66,
-	0x13, 0x0d, 0x3d, 0x3d, 0xc3, 0xfe, 0xb8, 0xe9, 0xf3, 0x47, 0x90, 0x43, 0x3a, 0x7d, 0x58, 0x1a,
-	0xfd, 0x60, 0x3f, 0x27, 0x87, 0x87, 0xf1, 0xcd, 0xd2, 0x79, 0x5d, 0xae, 0xba, 0xf5, 0x29, 0x59,
-	0x76, 0x7f, 0x91, 0xba, 0xfa, 0x0d, 0x00, 0x00, 0xff, 0xff, 0x55, 0x8b, 0xd8, 0x2b, 0xfd, 0x00,
-	0x00, 0x00,
+	// 224 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x54, 0x8f, 0xbd, 0x4e, 0xc3, 0x30,
+	0x14, 0x85, 0x31, 0x85, 0xfc, 0x5c, 0x75, 0x40, 0x1e, 0x90, 0x15, 0x21, 0x11, 0x65, 0xca, 0x94,
+	0x0a, 0x1e, 0x80, 0xa5, 0x23, 0x1e, 0x2a, 0x27, 0x88, 0x99, 0x9a, 0x2b, 0xd7, 0x52, 0xc0, 0xc1,
+	0xbe, 0x1e, 0x78, 0x26, 0x5e, 0x12, 0xd5, 0x24, 0x45, 0xdd, 0xfc, 0x9d, 0x4f, 0xbe, 0x3a, 0x07,
+	0xee, 0xa7, 0xb8, 0x1f, 0x6d, 0x38, 0xa0, 0xdf, 0x4c, 0xde, 0x91, 0xdb, 0x9c, 0xb8, 0x4b, 0xdc,
+	0xfc, 0x30, 0xc8, 0x15, 0x7e, 0x45, 0x0c, 0xc4, 0x6f, 0x60, 0xf5, 0x8e, 0x5e, 0xb0, 0x9a, 0xb5,
+	0x6b, 0x75, 0x7c, 0xf2, 0x5b, 0xc8, 0xa4, 0x33, 0x2f, 0x4a, 0x8a, 0xcb, 0x9a, 0xb5, 0xa5, 0x9a,
+	0x89, 0x37, 0xb0, 0x96, 0xce, 0xec, 0x8e, 0xb7, 0xf4, 0x33, 0x7e, 0x8b, 0x55, 0xb2, 0x67, 0x19,
+	0x17, 0x90, 0x4f, 0x1e, 0x35, 0x7a, 0x12, 0x57, 0x35, 0x6b, 0x0b, 0xb5, 0x20, 0xaf, 0xa0, 0x08,
+	0xe4, 0x3c, 0xf6, 0xdb, 0x41, 0x5c, 0x27, 0x75, 0x62, 0x7e, 0x07, 0x65, 0xa0, 0x37, 0xb2, 0x5a,
+	0x3a, 0x23, 0xb2, 0x24, 0xff, 0x83, 0xa6, 0x82, 0x4c, 0x61, 0x88, 0x63, 0xea, 0x1a, 0x34, 0x2d,
+	0x5d, 0x83, 0xa6, 0xc7, 0x27, 0x28, 0x77, 0xcb, 0x38, 0xfe, 0x00, 0x55, 0x1f, 0xf7, 0x1f, 0x96,
+	0x06, 0xd7, 0xdb, 0x4f, 0x33, 0xe2, 0x76, 0x78, 0xb5, 0x74, 0x98, 0x3f, 0x17, 0xdd, 0x3c, 0xb9,
+	0xca, 0xbb, 0xbf, 0xa8, 0xb9, 0xf8, 0x1d, 0x00, 0xe1, 0xb3, 0x78, 0x6f, 0x2b, 0x01, 0x00, 0x00,
 }
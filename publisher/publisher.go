@@ -29,6 +29,8 @@ import (
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
 	pubpb "github.com/letsencrypt/boulder/publisher/proto"
+	"github.com/letsencrypt/boulder/staticct"
+	"github.com/letsencrypt/boulder/trace"
 )
 
 // Log contains the CT client and signature verifier for a particular CT log
@@ -37,6 +39,9 @@ type Log struct {
 	uri      string
 	client   *ctClient.LogClient
 	verifier *ct.SignatureVerifier
+	// static is true if the log implements static-ct-api, in which case uri is
+	// its submission prefix.
+	static bool
 }
 
 // logCache contains a cache of *Log's that are constructed as required by
@@ -48,7 +53,7 @@ type logCache struct {
 
 // AddLog adds a *Log to the cache by constructing the statName, client and
 // verifier for the given uri & base64 public key.
-func (c *logCache) AddLog(uri, b64PK string, logger blog.Logger) (*Log, error) {
+func (c *logCache) AddLog(uri, b64PK string, static bool, logger blog.Logger) (*Log, error) {
 	// Lock the mutex for reading to check the cache
 	c.RLock()
 	log, present := c.logs[b64PK]
@@ -64,7 +69,7 @@ func (c *logCache) AddLog(uri, b64PK string, logger blog.Logger) (*Log, error) {
 	defer c.Unlock()
 
 	// Construct a Log, add it to the cache, and return it to the caller
-	log, err := NewLog(uri, b64PK, logger)
+	log, err := NewLog(uri, b64PK, static, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -79,15 +84,15 @@ func (c *logCache) Len() int {
 	return len(c.logs)
 }
 
-// LogURIs returns the URIs of all logs currently in the logCache
-func (c *logCache) LogURIs() []string {
+// Logs returns all logs currently in the logCache
+func (c *logCache) Logs() []*Log {
 	c.RLock()
 	defer c.RUnlock()
-	var uris []string
+	var logs []*Log
 	for _, l := range c.logs {
-		uris = append(uris, l.uri)
+		logs = append(logs, l)
 	}
-	return uris
+	return logs
 }
 
 type logAdaptor struct {
@@ -98,8 +103,11 @@ func (la logAdaptor) Printf(s string, args ...interface{}) {
 	la.Logger.Infof(s, args...)
 }
 
-// NewLog returns an initialized Log struct
-func NewLog(uri, b64PK string, logger blog.Logger) (*Log, error) {
+// NewLog returns an initialized Log struct. A static log, one implementing
+// static-ct-api, is submitted to with the same API as RFC 6962 logs, at uri,
+// its submission prefix, but its SCTs must say where their entry is in the
+// log.
+func NewLog(uri, b64PK string, static bool, logger blog.Logger) (*Log, error) {
 	url, err := url.Parse(uri)
 	if err != nil {
 		return nil, err
@@ -158,6 +166,7 @@ func NewLog(uri, b64PK string, logger blog.Logger) (*Log, error) {
 		uri:      url.String(),
 		client:   client,
 		verifier: verifier,
+		static:   static,
 	}, nil
 }
 
@@ -236,7 +245,11 @@ func (pub *Impl) SubmitToSingleCTWithResult(ctx context.Context, req *pubpb.Requ
 	// Add a log URL/pubkey to the cache, if already present the
 	// existing *Log will be returned, otherwise one will be constructed, added
 	// and returned.
-	ctLog, err := pub.ctLogsCache.AddLog(*req.LogURL, *req.LogPublicKey, pub.log)
+	static := false
+	if req.StaticLog != nil {
+		static = *req.StaticLog
+	}
+	ctLog, err := pub.ctLogsCache.AddLog(*req.LogURL, *req.LogPublicKey, static, pub.log)
 	if err != nil {
 		pub.log.AuditErrf("Making Log: %s", err)
 		return nil, err
@@ -286,9 +299,13 @@ func (pub *Impl) singleLogSubmit(
 		submissionMethod = ctLog.client.AddPreChain
 	}
 
+	_, span := trace.Start(ctx, "CT submit", trace.KindClient)
+	span.SetAttribute("log", ctLog.uri)
 	start := time.Now()
 	sct, err := submissionMethod(ctx, chain)
 	took := time.Since(start).Seconds()
+	span.SetError(err)
+	span.End()
 	if err != nil {
 		status := "error"
 		if canceled.Is(err) {
@@ -329,6 +346,14 @@ func (pub *Impl) singleLogSubmit(
 	if isPrecert && timestamp.Sub(time.Now()) < -10*time.Minute {
 		return nil, fmt.Errorf("SCT Timestamp was too far in the past (%s)", timestamp)
 	}
+	// Static logs must include the index of the entry in their SCTs, which is
+	// what lets it be found in the log's tiles.
+	if ctLog.static {
+		_, err := staticct.LeafIndex(sct.Extensions)
+		if err != nil {
+			return nil, fmt.Errorf("SCT from static log: %s", err)
+		}
+	}
 
 	return sct, nil
 }
@@ -347,6 +372,12 @@ func sctToInternal(sct *ct.SignedCertificateTimestamp, serial string) core.Signe
 // CreateTestingSignedSCT is used by both the publisher tests and ct-test-serv, which is
 // why it is exported. It creates a signed SCT based on the provided chain.
 func CreateTestingSignedSCT(req []string, k *ecdsa.PrivateKey, precert bool, timestamp time.Time) []byte {
+	return createTestingSignedSCTWithExtensions(req, k, precert, timestamp, nil)
+}
+
+// createTestingSignedSCTWithExtensions is CreateTestingSignedSCT for an SCT
+// with the given extensions, which are signed along with the rest of it.
+func createTestingSignedSCTWithExtensions(req []string, k *ecdsa.PrivateKey, precert bool, timestamp time.Time, extensions ct.CTExtensions) []byte {
 	chain := make([]ct.ASN1Cert, len(req))
 	for i, str := range req {
 		b, err := base64.StdEncoding.DecodeString(str)
@@ -374,6 +405,7 @@ func CreateTestingSignedSCT(req []string, k *ecdsa.PrivateKey, precert bool, tim
 		SCTVersion: ct.V1,
 		LogID:      ct.LogID{KeyID: logID},
 		Timestamp:  timestampMillis,
+		Extensions: extensions,
 	}, ct.LogEntry{Leaf: *leaf})
 	hashed := sha256.Sum256(serialized)
 	var ecdsaSig struct {
@@ -395,6 +427,7 @@ func CreateTestingSignedSCT(req []string, k *ecdsa.PrivateKey, precert bool, tim
 	jsonSCTObj.SCTVersion = ct.V1
 	jsonSCTObj.ID = base64.StdEncoding.EncodeToString(logID[:])
 	jsonSCTObj.Timestamp = timestampMillis
+	jsonSCTObj.Extensions = base64.StdEncoding.EncodeToString(extensions)
 	ds := ct.DigitallySigned{
 		Algorithm: cttls.SignatureAndHashAlgorithm{
 			Hash:      cttls.SHA256,
@@ -410,12 +443,17 @@ func CreateTestingSignedSCT(req []string, k *ecdsa.PrivateKey, precert bool, tim
 
 // ProbeLogs sends a HTTP GET request to each of the logs in the
 // publisher logCache and records the latency and status of the
-// response.
+// response. Static logs don't serve get-sth at their submission prefix, so
+// get-roots is requested from them instead.
 func (pub *Impl) ProbeLogs() {
 	wg := new(sync.WaitGroup)
-	for _, log := range pub.ctLogsCache.LogURIs() {
+	for _, log := range pub.ctLogsCache.Logs() {
+		path := ct.GetSTHPath
+		if log.static {
+			path = ct.GetRootsPath
+		}
 		wg.Add(1)
-		go func(uri string) {
+		go func(uri, path string) {
 			defer wg.Done()
 			c := http.Client{
 				Timeout: time.Minute*2 + time.Second*30,
@@ -424,7 +462,7 @@ func (pub *Impl) ProbeLogs() {
 			if err != nil {
 				pub.log.Errf("failed to parse log URI: %s", err)
 			}
-			url.Path = ct.GetSTHPath
+			url.Path += path
 			s := time.Now()
 			resp, err := c.Get(url.String())
 			took := time.Since(s).Seconds()
@@ -439,7 +477,7 @@ func (pub *Impl) ProbeLogs() {
 				"log":    uri,
 				"status": status,
 			}).Observe(took)
-		}(log)
+		}(log.uri, path)
 	}
 	wg.Wait()
 }
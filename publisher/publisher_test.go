@@ -23,12 +23,14 @@ import (
 	"time"
 
 	ct "github.com/google/certificate-transparency-go"
+	cttls "github.com/google/certificate-transparency-go/tls"
 	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/net/context"
 
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
 	pubpb "github.com/letsencrypt/boulder/publisher/proto"
+	"github.com/letsencrypt/boulder/staticct"
 	"github.com/letsencrypt/boulder/test"
 )
 
@@ -281,7 +283,7 @@ func addLog(t *testing.T, pub *Impl, port int, pubKey *ecdsa.PublicKey) *Log {
 	uri := fmt.Sprintf("http://localhost:%d", port)
 	der, err := x509.MarshalPKIXPublicKey(pubKey)
 	test.AssertNotError(t, err, "Failed to marshal key")
-	newLog, err := NewLog(uri, base64.StdEncoding.EncodeToString(der), log)
+	newLog, err := NewLog(uri, base64.StdEncoding.EncodeToString(der), false, log)
 	test.AssertNotError(t, err, "Couldn't create log")
 	test.AssertEquals(t, newLog.uri, fmt.Sprintf("http://localhost:%d", port))
 	return newLog
@@ -363,17 +365,70 @@ func TestTimestampVerificationPast(t *testing.T) {
 	}
 }
 
+// staticLogSrv signs SCTs with the given extensions.
+func staticLogSrv(k *ecdsa.PrivateKey, extensions ct.CTExtensions) *httptest.Server {
+	m := http.NewServeMux()
+	m.HandleFunc("/ct/", func(w http.ResponseWriter, r *http.Request) {
+		decoder := json.NewDecoder(r.Body)
+		var jsonReq ctSubmissionRequest
+		err := decoder.Decode(&jsonReq)
+		if err != nil {
+			return
+		}
+		precert := r.URL.Path == "/ct/v1/add-pre-chain"
+		sct := createTestingSignedSCTWithExtensions(jsonReq.Chain, k, precert, time.Now(), extensions)
+		fmt.Fprint(w, string(sct))
+	})
+	return httptest.NewServer(m)
+}
+
+func TestStaticLog(t *testing.T) {
+	pub, _, k := setup(t)
+	der, err := x509.MarshalPKIXPublicKey(&k.PublicKey)
+	test.AssertNotError(t, err, "Failed to marshal key")
+	b64PK := base64.StdEncoding.EncodeToString(der)
+	issuerBundle, precert, err := makePrecert(k)
+	test.AssertNotError(t, err, "Failed to create test leaf")
+	pub.issuerBundle = issuerBundle
+	trueBool := true
+
+	// An SCT with a leaf_index is accepted from a static log
+	server := staticLogSrv(k, ct.CTExtensions{0, 0, 5, 0, 0, 0, 0, 42})
+	defer server.Close()
+	resp, err := pub.SubmitToSingleCTWithResult(ctx, &pubpb.Request{LogURL: &server.URL, LogPublicKey: &b64PK, Der: precert, Precert: &trueBool, StaticLog: &trueBool})
+	test.AssertNotError(t, err, "SCT with a leaf_index was rejected")
+	var sct ct.SignedCertificateTimestamp
+	_, err = cttls.Unmarshal(resp.Sct, &sct)
+	test.AssertNotError(t, err, "Failed to unmarshal SCT")
+	index, err := staticct.LeafIndex(sct.Extensions)
+	test.AssertNotError(t, err, "SCT has no leaf_index")
+	test.AssertEquals(t, index, uint64(42))
+
+	// Without one it's rejected. Logs are cached by key, so this one needs a
+	// key of its own.
+	k2, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "Couldn't generate test key")
+	der, err = x509.MarshalPKIXPublicKey(&k2.PublicKey)
+	test.AssertNotError(t, err, "Failed to marshal key")
+	b64PK = base64.StdEncoding.EncodeToString(der)
+	noIndexServer := staticLogSrv(k2, nil)
+	defer noIndexServer.Close()
+	_, err = pub.SubmitToSingleCTWithResult(ctx, &pubpb.Request{LogURL: &noIndexServer.URL, LogPublicKey: &b64PK, Der: precert, Precert: &trueBool, StaticLog: &trueBool})
+	test.AssertError(t, err, "SCT without a leaf_index was accepted from a static log")
+	test.AssertContains(t, err.Error(), "no leaf_index extension")
+}
+
 func TestLogCache(t *testing.T) {
 	cache := logCache{
 		logs: make(map[string]*Log),
 	}
 
 	// Adding a log with an invalid base64 public key should error
-	_, err := cache.AddLog("www.test.com", "1234", log)
+	_, err := cache.AddLog("www.test.com", "1234", false, log)
 	test.AssertError(t, err, "AddLog() with invalid base64 pk didn't error")
 
 	// Adding a log with an invalid URI should error
-	_, err = cache.AddLog(":", "", log)
+	_, err = cache.AddLog(":", "", false, log)
 	test.AssertError(t, err, "AddLog() with an invalid log URI didn't error")
 
 	// Create one keypair & base 64 public key
@@ -391,21 +446,21 @@ func TestLogCache(t *testing.T) {
 	k2b64 := base64.StdEncoding.EncodeToString(der2)
 
 	// Adding the first log should not produce an error
-	l1, err := cache.AddLog("http://log.one.example.com", k1b64, log)
+	l1, err := cache.AddLog("http://log.one.example.com", k1b64, false, log)
 	test.AssertNotError(t, err, "cache.AddLog() failed for log 1")
 	test.AssertEquals(t, cache.Len(), 1)
 	test.AssertEquals(t, l1.uri, "http://log.one.example.com")
 	test.AssertEquals(t, l1.logID, k1b64)
 
 	// Adding it again should not produce any errors, or increase the Len()
-	l1, err = cache.AddLog("http://log.one.example.com", k1b64, log)
+	l1, err = cache.AddLog("http://log.one.example.com", k1b64, false, log)
 	test.AssertNotError(t, err, "cache.AddLog() failed for second add of log 1")
 	test.AssertEquals(t, cache.Len(), 1)
 	test.AssertEquals(t, l1.uri, "http://log.one.example.com")
 	test.AssertEquals(t, l1.logID, k1b64)
 
 	// Adding a second log should not error and should increase the Len()
-	l2, err := cache.AddLog("http://log.two.example.com", k2b64, log)
+	l2, err := cache.AddLog("http://log.two.example.com", k2b64, false, log)
 	test.AssertNotError(t, err, "cache.AddLog() failed for log 2")
 	test.AssertEquals(t, cache.Len(), 2)
 	test.AssertEquals(t, l2.uri, "http://log.two.example.com")
@@ -445,20 +500,28 @@ func TestProbeLogs(t *testing.T) {
 	defer srvB.Close()
 	portB, err := getPort(srvB.URL)
 	test.AssertNotError(t, err, "Failed to get test server port")
+	// srvC only serves get-roots, below the log's path
+	srvC := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/static"+ct.GetRootsPath {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srvC.Close()
 
-	addLog := func(uri string) {
+	addLog := func(uri string, static bool) {
 		k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 		test.AssertNotError(t, err, "ecdsa.GenerateKey() failed for k")
 		der, err := x509.MarshalPKIXPublicKey(&k.PublicKey)
 		test.AssertNotError(t, err, "x509.MarshalPKIXPublicKey(der) failed")
 		kb64 := base64.StdEncoding.EncodeToString(der)
-		_, err = pub.ctLogsCache.AddLog(uri, kb64, pub.log)
+		_, err = pub.ctLogsCache.AddLog(uri, kb64, static, pub.log)
 		test.AssertNotError(t, err, "Failed to add log to logCache")
 	}
 
-	addLog(fmt.Sprintf("http://localhost:%d", portA))
-	addLog(fmt.Sprintf("http://localhost:%d", portB))
-	addLog("http://blackhole:9999")
+	addLog(fmt.Sprintf("http://localhost:%d", portA), false)
+	addLog(fmt.Sprintf("http://localhost:%d", portB), false)
+	addLog("http://blackhole:9999", false)
+	addLog(srvC.URL+"/static", true)
 
 	pub.ProbeLogs()
 
@@ -474,4 +537,8 @@ func TestProbeLogs(t *testing.T) {
 		"log":    "http://blackhole:9999",
 		"status": "error",
 	})), 1)
+	test.AssertEquals(t, test.CountHistogramSamples(pub.metrics.probeLatency.With(prometheus.Labels{
+		"log":    srvC.URL + "/static",
+		"status": "200 OK",
+	})), 1)
 }
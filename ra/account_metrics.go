@@ -0,0 +1,132 @@
+package ra
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// defaultTopAccounts is how many accounts get their own account label in
+	// the per-account issuance metrics unless SetTopAccounts is called.
+	defaultTopAccounts = 20
+	// topAccountsPeriod is how often the accounts with their own label are
+	// chosen again, from the most active accounts of the previous period.
+	topAccountsPeriod = time.Hour
+	// otherAccounts is the account label shared by all the accounts without
+	// their own label.
+	otherAccounts = "other"
+)
+
+// accountMetrics counts certificate issuance requests by account, so that a
+// single account requesting far more certificates than usual, or failing far
+// more often, stands out. To keep the number of time series bounded only the
+// most active accounts get their own account label and the rest are counted
+// together as "other". An account is given its own label while there are
+// fewer than the maximum number of labelled accounts. At the end of each
+// period the labelled accounts are replaced by the period's most active
+// accounts, and the series of accounts that drop out are removed.
+type accountMetrics struct {
+	clk      clock.Clock
+	requests *prometheus.CounterVec
+
+	mu          sync.Mutex
+	topAccounts int
+	periodEnd   time.Time
+	// activity is the number of issuance requests of each account this period
+	activity map[int64]int64
+	// labelled is the set of accounts with their own account label
+	labelled map[int64]bool
+}
+
+func newAccountMetrics(clk clock.Clock, stats metrics.Scope) *accountMetrics {
+	requests := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "account_issuance_requests",
+			Help: "Number of certificate issuance requests by the most active accounts, with all other accounts counted as \"other\", and result",
+		},
+		[]string{"account", "result"},
+	)
+	stats.MustRegister(requests)
+	return &accountMetrics{
+		clk:         clk,
+		requests:    requests,
+		topAccounts: defaultTopAccounts,
+		periodEnd:   clk.Now().Add(topAccountsPeriod),
+		activity:    make(map[int64]int64),
+		labelled:    make(map[int64]bool),
+	}
+}
+
+// observe counts an issuance request by regID that succeeded if err is nil.
+func (am *accountMetrics) observe(regID int64, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	am.requests.With(prometheus.Labels{"account": am.label(regID), "result": result}).Inc()
+}
+
+// label returns the account label for regID's requests.
+func (am *accountMetrics) label(regID int64) string {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	if now := am.clk.Now(); !now.Before(am.periodEnd) {
+		am.rotate()
+		am.periodEnd = now.Add(topAccountsPeriod)
+	}
+	am.activity[regID]++
+	if !am.labelled[regID] {
+		if len(am.labelled) >= am.topAccounts {
+			return otherAccounts
+		}
+		am.labelled[regID] = true
+	}
+	return strconv.FormatInt(regID, 10)
+}
+
+// rotate replaces the labelled accounts with the most active accounts of the
+// period that just ended and starts a new one. am.mu must be held.
+func (am *accountMetrics) rotate() {
+	regIDs := make([]int64, 0, len(am.activity))
+	for regID := range am.activity {
+		regIDs = append(regIDs, regID)
+	}
+	sort.Slice(regIDs, func(i, j int) bool {
+		if am.activity[regIDs[i]] != am.activity[regIDs[j]] {
+			return am.activity[regIDs[i]] > am.activity[regIDs[j]]
+		}
+		return regIDs[i] < regIDs[j]
+	})
+	if len(regIDs) > am.topAccounts {
+		regIDs = regIDs[:am.topAccounts]
+	}
+
+	labelled := make(map[int64]bool, len(regIDs))
+	for _, regID := range regIDs {
+		labelled[regID] = true
+	}
+	for regID := range am.labelled {
+		if !labelled[regID] {
+			account := strconv.FormatInt(regID, 10)
+			am.requests.DeleteLabelValues(account, "success")
+			am.requests.DeleteLabelValues(account, "failure")
+		}
+	}
+	am.labelled = labelled
+	am.activity = make(map[int64]int64)
+}
+
+// SetTopAccounts sets how many of the most active accounts get their own
+// account label in the per-account issuance metrics. Accounts that already
+// have a label keep it until the end of the current period.
+func (ra *RegistrationAuthorityImpl) SetTopAccounts(n int) {
+	ra.accountMetrics.mu.Lock()
+	defer ra.accountMetrics.mu.Unlock()
+	ra.accountMetrics.topAccounts = n
+}
@@ -0,0 +1,46 @@
+package ra
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmhodges/clock"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestAccountMetrics(t *testing.T) {
+	clk := clock.NewFake()
+	am := newAccountMetrics(clk, metrics.NewNoopScope())
+	am.topAccounts = 2
+
+	count := func(account, result string) int {
+		return test.CountCounter(am.requests.With(prometheus.Labels{"account": account, "result": result}))
+	}
+
+	// The first two accounts get their own label, the rest are "other"
+	am.observe(1, nil)
+	am.observe(2, errors.New("broken"))
+	am.observe(3, nil)
+	am.observe(4, nil)
+	test.AssertEquals(t, count("1", "success"), 1)
+	test.AssertEquals(t, count("2", "failure"), 1)
+	test.AssertEquals(t, count("other", "success"), 2)
+
+	// Account 3 is the most active this period, followed by 4
+	am.observe(3, nil)
+	am.observe(3, nil)
+	am.observe(4, nil)
+	test.AssertEquals(t, count("other", "success"), 5)
+
+	// So they replace 1 and 2 in the next period, and 1 and 2's series are
+	// removed
+	clk.Add(topAccountsPeriod)
+	am.observe(1, nil)
+	am.observe(3, errors.New("broken"))
+	test.AssertEquals(t, count("other", "success"), 6)
+	test.AssertEquals(t, count("3", "failure"), 1)
+	test.AssertEquals(t, count("1", "success"), 0)
+	test.AssertEquals(t, count("2", "failure"), 0)
+}
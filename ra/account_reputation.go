@@ -0,0 +1,202 @@
+package ra
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ReputationConfig configures the restrictions the RA places on accounts
+// whose validations mostly fail, e.g. because they're requesting certificates
+// for names they don't control.
+type ReputationConfig struct {
+	// Window is how long validation results count towards an account's
+	// failure ratio. Results are counted per window, and an account's ratio
+	// is taken over the current and previous windows.
+	Window time.Duration
+	// MinValidations is how many validations an account must have finished
+	// before its failure ratio is considered.
+	MinValidations int64
+	// MaxFailureRatio is the fraction of an account's validations that may
+	// fail before it's restricted.
+	MaxFailureRatio float64
+	// RestrictedValidations, if non-zero, is how many validations a
+	// restricted account may start per window.
+	RestrictedValidations int64
+	// RequireDNS01 restricts accounts to DNS-01 challenges, which can't be
+	// pointed at an unsuspecting web server.
+	RequireDNS01 bool
+}
+
+// validationCounts are an account's validations in one window.
+type validationCounts struct {
+	started  int64
+	finished int64
+	failures int64
+}
+
+// accountReputation tracks the ratio of failed validations of each account and
+// decides which accounts are restricted. It only remembers the accounts that
+// validated something in the current or previous window. A nil
+// *accountReputation restricts no one.
+type accountReputation struct {
+	clk     clock.Clock
+	log     blog.Logger
+	config  ReputationConfig
+	refused *prometheus.CounterVec
+
+	mu        sync.Mutex
+	periodEnd time.Time
+	current   map[int64]*validationCounts
+	previous  map[int64]*validationCounts
+	// restricted is the set of accounts that are currently restricted, so
+	// that changes can be audit logged
+	restricted map[int64]bool
+}
+
+func newAccountReputation(clk clock.Clock, log blog.Logger, stats metrics.Scope, config ReputationConfig) (*accountReputation, error) {
+	if config.Window <= 0 {
+		return nil, fmt.Errorf("account reputation window must be positive")
+	}
+	if config.MaxFailureRatio <= 0 || config.MaxFailureRatio >= 1 {
+		return nil, fmt.Errorf("account reputation maxFailureRatio must be between 0 and 1")
+	}
+	if config.RestrictedValidations == 0 && !config.RequireDNS01 {
+		return nil, fmt.Errorf("account reputation must set restrictedValidations or requireDNS01")
+	}
+	refused := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "account_reputation_refusals",
+			Help: "Number of validations refused because the account has a high validation failure ratio, by reason",
+		},
+		[]string{"reason"},
+	)
+	stats.MustRegister(refused)
+	return &accountReputation{
+		clk:        clk,
+		log:        log,
+		config:     config,
+		refused:    refused,
+		periodEnd:  clk.Now().Add(config.Window),
+		current:    make(map[int64]*validationCounts),
+		previous:   make(map[int64]*validationCounts),
+		restricted: make(map[int64]bool),
+	}, nil
+}
+
+// counts returns regID's counts in the current window. ar.mu must be held.
+func (ar *accountReputation) counts(regID int64) *validationCounts {
+	if now := ar.clk.Now(); !now.Before(ar.periodEnd) {
+		ar.rotate(now)
+	}
+	c, ok := ar.current[regID]
+	if !ok {
+		c = &validationCounts{}
+		ar.current[regID] = c
+	}
+	return c
+}
+
+// rotate starts a new window. If more than a window has passed since the
+// current one ended, both windows are forgotten. ar.mu must be held.
+func (ar *accountReputation) rotate(now time.Time) {
+	if now.Before(ar.periodEnd.Add(ar.config.Window)) {
+		ar.previous = ar.current
+	} else {
+		ar.previous = make(map[int64]*validationCounts)
+	}
+	ar.current = make(map[int64]*validationCounts)
+	ar.periodEnd = now.Add(ar.config.Window)
+	for regID := range ar.restricted {
+		ar.update(regID)
+	}
+}
+
+// update re-evaluates whether regID is restricted, audit logging any change.
+// ar.mu must be held.
+func (ar *accountReputation) update(regID int64) {
+	var finished, failures int64
+	for _, window := range []map[int64]*validationCounts{ar.previous, ar.current} {
+		if c, ok := window[regID]; ok {
+			finished += c.finished
+			failures += c.failures
+		}
+	}
+	restricted := finished >= ar.config.MinValidations &&
+		float64(failures) > ar.config.MaxFailureRatio*float64(finished)
+	if restricted == ar.restricted[regID] {
+		return
+	}
+	if restricted {
+		ar.restricted[regID] = true
+		ar.log.AuditInfof("Restricting validations for account with a high failure ratio: regID=[%d] validations=[%d] failures=[%d]",
+			regID, finished, failures)
+	} else {
+		delete(ar.restricted, regID)
+		ar.log.AuditInfof("Lifting validation restrictions for account: regID=[%d] validations=[%d] failures=[%d]",
+			regID, finished, failures)
+	}
+}
+
+// observe records the result of one of regID's validations.
+func (ar *accountReputation) observe(regID int64, valid bool) {
+	if ar == nil {
+		return
+	}
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	c := ar.counts(regID)
+	c.finished++
+	if !valid {
+		c.failures++
+	}
+	ar.update(regID)
+}
+
+// startValidation returns an error if regID is restricted from validating a
+// challenge of challType, and otherwise counts the validation as started.
+func (ar *accountReputation) startValidation(regID int64, challType string) error {
+	if ar == nil {
+		return nil
+	}
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	c := ar.counts(regID)
+	if ar.restricted[regID] {
+		if ar.config.RequireDNS01 && challType != core.ChallengeTypeDNS01 {
+			ar.refused.With(prometheus.Labels{"reason": "challenge_type"}).Inc()
+			ar.log.AuditInfof("Refusing %s validation for restricted account: regID=[%d]", challType, regID)
+			return berrors.UnauthorizedError(
+				"too many recent validations for this account failed, only %s challenges are allowed",
+				core.ChallengeTypeDNS01)
+		}
+		if ar.config.RestrictedValidations > 0 && c.started >= ar.config.RestrictedValidations {
+			ar.refused.With(prometheus.Labels{"reason": "rate_limit"}).Inc()
+			ar.log.AuditInfof("Refusing validation for restricted account over its limit: regID=[%d] started=[%d]",
+				regID, c.started)
+			return berrors.RateLimitError(
+				"too many recent validations for this account failed, it may only start %d validations per %s",
+				ar.config.RestrictedValidations, ar.config.Window)
+		}
+	}
+	c.started++
+	return nil
+}
+
+// SetAccountReputation makes the RA restrict the validations of accounts
+// whose validations mostly fail, as configured.
+func (ra *RegistrationAuthorityImpl) SetAccountReputation(config ReputationConfig) error {
+	ar, err := newAccountReputation(ra.clk, ra.log, ra.stats, config)
+	if err != nil {
+		return err
+	}
+	ra.reputation = ar
+	return nil
+}
@@ -0,0 +1,105 @@
+package ra
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestNewAccountReputation(t *testing.T) {
+	clk := clock.NewFake()
+	for _, config := range []ReputationConfig{
+		{MaxFailureRatio: 0.5, RequireDNS01: true},
+		{Window: time.Hour, MaxFailureRatio: 1, RequireDNS01: true},
+		{Window: time.Hour, MaxFailureRatio: 0.5},
+	} {
+		_, err := newAccountReputation(clk, blog.NewMock(), metrics.NewNoopScope(), config)
+		test.AssertError(t, err, "invalid config was accepted")
+	}
+}
+
+func TestAccountReputation(t *testing.T) {
+	clk := clock.NewFake()
+	log := blog.NewMock()
+	ar, err := newAccountReputation(clk, log, metrics.NewNoopScope(), ReputationConfig{
+		Window:                time.Hour,
+		MinValidations:        4,
+		MaxFailureRatio:       0.5,
+		RestrictedValidations: 2,
+		RequireDNS01:          true,
+	})
+	test.AssertNotError(t, err, "newAccountReputation failed")
+
+	// Three failures out of three isn't enough validations to restrict
+	for i := 0; i < 3; i++ {
+		test.AssertNotError(t, ar.startValidation(1, core.ChallengeTypeHTTP01), "validation refused")
+		ar.observe(1, false)
+	}
+	test.AssertNotError(t, ar.startValidation(1, core.ChallengeTypeHTTP01), "validation refused")
+
+	// But a fourth is, and the account can then only use DNS-01, and only
+	// two more times this window
+	ar.observe(1, false)
+	test.AssertEquals(t, len(log.GetAllMatching("Restricting validations for account.*regID=\\[1\\]")), 1)
+	err = ar.startValidation(1, core.ChallengeTypeHTTP01)
+	test.AssertError(t, err, "restricted account started an HTTP-01 validation")
+	test.Assert(t, berrors.Is(err, berrors.Unauthorized), "wrong error type")
+
+	// Other accounts are unaffected
+	test.AssertNotError(t, ar.startValidation(2, core.ChallengeTypeHTTP01), "validation refused")
+
+	// The account already started 4 validations this window
+	err = ar.startValidation(1, core.ChallengeTypeDNS01)
+	test.AssertError(t, err, "restricted account started too many validations")
+	test.Assert(t, berrors.Is(err, berrors.RateLimit), "wrong error type")
+
+	// In the next window it can start two DNS-01 validations. The previous
+	// window still counts towards its failure ratio.
+	clk.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		test.AssertNotError(t, ar.startValidation(1, core.ChallengeTypeDNS01), "validation refused")
+		ar.observe(1, true)
+	}
+	test.AssertError(t, ar.startValidation(1, core.ChallengeTypeDNS01), "restricted account started too many validations")
+
+	// Two more successes bring its failure ratio down to a half, which lifts
+	// the restriction
+	ar.observe(1, true)
+	test.Assert(t, ar.restricted[1], "account restriction lifted too early")
+	ar.observe(1, true)
+	test.Assert(t, !ar.restricted[1], "account restriction wasn't lifted")
+	test.AssertEquals(t, len(log.GetAllMatching("Lifting validation restrictions for account.*regID=\\[1\\]")), 1)
+	test.AssertNotError(t, ar.startValidation(1, core.ChallengeTypeHTTP01), "validation refused")
+
+	// A nil accountReputation restricts no one
+	var none *accountReputation
+	none.observe(1, false)
+	test.AssertNotError(t, none.startValidation(1, core.ChallengeTypeHTTP01), "nil accountReputation refused a validation")
+}
+
+func TestAccountReputationExpiry(t *testing.T) {
+	clk := clock.NewFake()
+	log := blog.NewMock()
+	ar, err := newAccountReputation(clk, log, metrics.NewNoopScope(), ReputationConfig{
+		Window:          time.Hour,
+		MinValidations:  1,
+		MaxFailureRatio: 0.5,
+		RequireDNS01:    true,
+	})
+	test.AssertNotError(t, err, "newAccountReputation failed")
+
+	ar.observe(1, false)
+	test.Assert(t, ar.restricted[1], "account wasn't restricted")
+
+	// Failures are forgotten after two windows without validations
+	clk.Add(2 * time.Hour)
+	test.AssertNotError(t, ar.startValidation(1, core.ChallengeTypeHTTP01), "validation refused")
+	test.Assert(t, !ar.restricted[1], "account restriction wasn't lifted")
+	test.AssertEquals(t, len(ar.previous), 0)
+}
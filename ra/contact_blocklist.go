@@ -0,0 +1,116 @@
+package ra
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"sync"
+
+	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/reloader"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v2"
+)
+
+// contactBlocklistYAML is the format of a blocked contact domains file.
+type contactBlocklistYAML struct {
+	// BlockedDomains are the domains, e.g. of disposable mail providers,
+	// whose email addresses can't be account contacts. Their subdomains are
+	// blocked too.
+	BlockedDomains []string `yaml:"blockedDomains"`
+}
+
+// contactBlocklist holds the contact email domains accounts may not use. A
+// nil *contactBlocklist blocks nothing.
+type contactBlocklist struct {
+	rejections prometheus.Counter
+
+	mu      sync.RWMutex
+	domains map[string]bool
+}
+
+func newContactBlocklist(stats metrics.Scope) *contactBlocklist {
+	rejections := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "blocked_contact_rejections",
+		Help: "Number of account contacts rejected because their email domain is blocked",
+	})
+	stats.MustRegister(rejections)
+	return &contactBlocklist{rejections: rejections}
+}
+
+// load replaces the blocked domains with those in a blocked contact domains
+// file.
+func (b *contactBlocklist) load(contents []byte) error {
+	var list contactBlocklistYAML
+	err := yaml.Unmarshal(contents, &list)
+	if err != nil {
+		return err
+	}
+	domains := make(map[string]bool, len(list.BlockedDomains))
+	for _, domain := range list.BlockedDomains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain == "" || strings.Contains(domain, "@") {
+			return fmt.Errorf("invalid blocked contact domain %q", domain)
+		}
+		domains[domain] = true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.domains = domains
+	return nil
+}
+
+// blocked returns the blocked domain that domain is, or is a subdomain of, or
+// "" if it isn't blocked.
+func (b *contactBlocklist) blocked(domain string) string {
+	if b == nil {
+		return ""
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	labels := strings.Split(strings.ToLower(domain), ".")
+	for i := range labels {
+		if parent := strings.Join(labels[i:], "."); b.domains[parent] {
+			return parent
+		}
+	}
+	return ""
+}
+
+// checkEmail returns an InvalidContact error if the domain of an email address
+// is blocked. The address must already have been validated by validateEmail.
+func (b *contactBlocklist) checkEmail(address string) error {
+	if b == nil {
+		return nil
+	}
+	email, err := mail.ParseAddress(address)
+	if err != nil {
+		return unparseableEmailError
+	}
+	domain := email.Address[strings.LastIndex(email.Address, "@")+1:]
+	if blocked := b.blocked(domain); blocked != "" {
+		b.rejections.Inc()
+		return berrors.InvalidContactError(
+			"contact emails @%s can't be used for accounts, please use another email address",
+			blocked)
+	}
+	return nil
+}
+
+// SetBlockedContactDomainsFile makes the RA reject new accounts and contact
+// updates with email contacts whose domain is listed in the given YAML file.
+// The file is reloaded whenever it changes.
+func (ra *RegistrationAuthorityImpl) SetBlockedContactDomainsFile(filename string) error {
+	b := newContactBlocklist(ra.stats)
+	err := reloader.Register(reloader.Section{
+		Name: "blocked contact domains",
+		File: filename,
+		Load: b.load,
+	})
+	if err != nil {
+		return err
+	}
+	ra.contactBlocklist = b
+	return nil
+}
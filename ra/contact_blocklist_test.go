@@ -0,0 +1,56 @@
+package ra
+
+import (
+	"context"
+	"testing"
+
+	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestContactBlocklistLoad(t *testing.T) {
+	b := newContactBlocklist(metrics.NewNoopScope())
+	err := b.load([]byte("blockedDomains:\n  - Mailinator.com\n  - throwaway.example\n"))
+	test.AssertNotError(t, err, "loading blocked contact domains failed")
+	test.AssertEquals(t, b.blocked("mailinator.com"), "mailinator.com")
+	test.AssertEquals(t, b.blocked("MAIL.Mailinator.com"), "mailinator.com")
+	test.AssertEquals(t, b.blocked("notmailinator.com"), "")
+	test.AssertEquals(t, b.blocked("email.com"), "")
+
+	// An invalid file leaves the loaded domains in effect
+	err = b.load([]byte("blockedDomains:\n  - user@mailinator.com\n"))
+	test.AssertError(t, err, "email address accepted as a blocked domain")
+	err = b.load([]byte("blockedDomains: [\n"))
+	test.AssertError(t, err, "invalid YAML accepted")
+	test.AssertEquals(t, b.blocked("mailinator.com"), "mailinator.com")
+
+	err = b.load([]byte("blockedDomains: []\n"))
+	test.AssertNotError(t, err, "loading an empty list failed")
+	test.AssertEquals(t, b.blocked("mailinator.com"), "")
+}
+
+func TestValidateContactsBlocked(t *testing.T) {
+	b := newContactBlocklist(metrics.NewNoopScope())
+	err := b.load([]byte("blockedDomains:\n  - mailinator.com\n"))
+	test.AssertNotError(t, err, "loading blocked contact domains failed")
+	ra := &RegistrationAuthorityImpl{contactBlocklist: b}
+
+	err = ra.validateContacts(context.Background(), &[]string{"mailto:admin@email.com"})
+	test.AssertNotError(t, err, "contact with an unblocked domain was rejected")
+
+	for _, contact := range []string{
+		"mailto:someone@mailinator.com",
+		"mailto:someone@eu.MAILINATOR.com",
+	} {
+		err = ra.validateContacts(context.Background(), &[]string{"mailto:admin@email.com", contact})
+		test.Assert(t, berrors.Is(err, berrors.InvalidContact), "contact with a blocked domain wasn't rejected")
+		test.AssertEquals(t, err.Error(), "contact emails @mailinator.com can't be used for accounts, please use another email address")
+	}
+	test.AssertEquals(t, test.CountCounter(b.rejections), 2)
+
+	// A nil blocklist blocks nothing
+	ra.contactBlocklist = nil
+	err = ra.validateContacts(context.Background(), &[]string{"mailto:someone@mailinator.com"})
+	test.AssertNotError(t, err, "contact was rejected without a blocklist")
+}
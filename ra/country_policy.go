@@ -0,0 +1,100 @@
+package ra
+
+import (
+	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/policy"
+	"github.com/letsencrypt/boulder/probs"
+	"github.com/letsencrypt/boulder/reloader"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// countryPolicy checks names and validation targets against a
+// policy.CountryPolicy. A nil *countryPolicy allows everything.
+type countryPolicy struct {
+	policy  policy.CountryPolicy
+	matches *prometheus.CounterVec
+}
+
+func newCountryPolicy(stats metrics.Scope) *countryPolicy {
+	matches := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "country_policy_matches",
+		Help: "Number of names and validation targets that map to a denied country, by what mapped them and whether they were rejected",
+	}, []string{"source", "enforced"})
+	stats.MustRegister(matches)
+	return &countryPolicy{matches: matches}
+}
+
+// SetCountryPolicyFile makes the RA check the names of new authorizations and
+// orders, and the addresses their challenges are validated against, with the
+// country policy in the given YAML file. Depending on the file's mode, names
+// and validations in denied countries are either logged or rejected. The file
+// is reloaded whenever it changes.
+func (ra *RegistrationAuthorityImpl) SetCountryPolicyFile(filename string) error {
+	cp := newCountryPolicy(ra.stats)
+	err := reloader.Register(reloader.Section{
+		Name: "country policy",
+		File: filename,
+		Load: cp.policy.LoadCountryPolicy,
+	})
+	if err != nil {
+		return err
+	}
+	ra.countryPolicy = cp
+	return nil
+}
+
+// countryPolicyMatched records that something maps to a denied country, and
+// returns true if it should be rejected.
+func (ra *RegistrationAuthorityImpl) countryPolicyMatched(match *policy.CountryMatch, what string, regID int64) bool {
+	enforced := ra.countryPolicy.policy.Enforced()
+	label := "false"
+	if enforced {
+		label = "true"
+	}
+	ra.countryPolicy.matches.WithLabelValues(match.Source, label).Inc()
+	ra.log.AuditInfof("Country policy matched %s: denied=[%s] regID=[%d] enforced=[%t]",
+		what, match, regID, enforced)
+	return enforced
+}
+
+// checkCountryPolicyName returns a RejectedIdentifier error if the country
+// policy is enforced and name maps to a denied country.
+func (ra *RegistrationAuthorityImpl) checkCountryPolicyName(name string, regID int64) error {
+	if ra.countryPolicy == nil {
+		return nil
+	}
+	match := ra.countryPolicy.policy.DeniedName(name)
+	if match == nil {
+		return nil
+	}
+	if ra.countryPolicyMatched(match, "name "+name, regID) {
+		return berrors.RejectedIdentifierError("Policy forbids issuing for %q", name)
+	}
+	return nil
+}
+
+// checkCountryPolicyRecords returns a RejectedIdentifier problem if the country
+// policy is enforced and any address used for a validation maps to a denied
+// country.
+func (ra *RegistrationAuthorityImpl) checkCountryPolicyRecords(authz core.Authorization, records []core.ValidationRecord) *probs.ProblemDetails {
+	if ra.countryPolicy == nil {
+		return nil
+	}
+	for _, record := range records {
+		if record.AddressUsed == nil {
+			continue
+		}
+		match := ra.countryPolicy.policy.DeniedAddress(record.AddressUsed)
+		if match == nil {
+			continue
+		}
+		what := "validation target " + record.AddressUsed.String() + " of " + authz.Identifier.Value
+		if ra.countryPolicyMatched(match, what, authz.RegistrationID) {
+			return probs.RejectedIdentifier("Policy forbids validating %q against %s",
+				authz.Identifier.Value, record.AddressUsed)
+		}
+	}
+	return nil
+}
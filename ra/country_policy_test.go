@@ -0,0 +1,61 @@
+package ra
+
+import (
+	"net"
+	"testing"
+
+	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/probs"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestCountryPolicy(t *testing.T) {
+	cp := newCountryPolicy(metrics.NewNoopScope())
+	ra := &RegistrationAuthorityImpl{log: log, countryPolicy: cp}
+	authz := core.Authorization{
+		Identifier:     core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "example.com"},
+		RegistrationID: 1,
+	}
+	records := []core.ValidationRecord{
+		{AddressUsed: net.ParseIP("192.0.2.1")},
+		{AddressUsed: net.ParseIP("198.51.100.1")},
+	}
+
+	for _, mode := range []string{"log", "enforce"} {
+		err := cp.policy.LoadCountryPolicy([]byte("mode: " + mode + `
+deniedCountries: [XX]
+tldCountries: {xx: XX}
+networkCountries: {198.51.100.0/24: XX}
+`))
+		test.AssertNotError(t, err, "loading country policy failed")
+		log.Clear()
+
+		test.AssertNotError(t, ra.checkCountryPolicyName("example.com", 1), "allowed name was rejected")
+		test.Assert(t, ra.checkCountryPolicyRecords(authz, records[:1]) == nil, "allowed validation target was rejected")
+		test.AssertEquals(t, len(log.GetAllMatching("Country policy matched")), 0)
+
+		err = ra.checkCountryPolicyName("example.xx", 1)
+		prob := ra.checkCountryPolicyRecords(authz, records)
+		test.AssertEquals(t, len(log.GetAllMatching(`Country policy matched name example\.xx: denied=\[XX tld entry "xx"\]`)), 1)
+		test.AssertEquals(t, len(log.GetAllMatching(`Country policy matched validation target 198\.51\.100\.1 of example\.com`)), 1)
+		if mode == "log" {
+			test.AssertNotError(t, err, "name was rejected in log mode")
+			test.Assert(t, prob == nil, "validation target was rejected in log mode")
+		} else {
+			test.Assert(t, berrors.Is(err, berrors.RejectedIdentifier), "denied name wasn't rejected")
+			test.Assert(t, prob != nil && prob.Type == probs.RejectedIdentifierProblem, "denied validation target wasn't rejected")
+		}
+	}
+	for _, source := range []string{"tld", "network"} {
+		for _, enforced := range []string{"false", "true"} {
+			test.AssertEquals(t, test.CountCounter(cp.matches.WithLabelValues(source, enforced)), 1)
+		}
+	}
+
+	// Without a country policy nothing is checked
+	ra.countryPolicy = nil
+	test.AssertNotError(t, ra.checkCountryPolicyName("example.xx", 1), "name was rejected without a policy")
+	test.Assert(t, ra.checkCountryPolicyRecords(authz, records) == nil, "validation target was rejected without a policy")
+}
@@ -0,0 +1,238 @@
+package ra
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/probs"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+)
+
+// dns01RecheckLeaseSlack is how long an RA's lease on a DNS-01 recheck lasts
+// beyond the interval before its next recheck, to cover the validation. Once
+// a lease expires without being renewed, e.g. because the RA restarted, the
+// recheck is taken over by ResumeDNS01Rechecks.
+const dns01RecheckLeaseSlack = time.Minute
+
+// DNS01RecheckConfig configures the rechecking of failed DNS-01 validations,
+// for subscribers whose DNS providers are slow to propagate TXT records.
+type DNS01RecheckConfig struct {
+	// Deadline is how long after the first attempt a failed validation is
+	// rechecked for.
+	Deadline time.Duration
+	// Interval is how long the RA waits before each recheck.
+	Interval time.Duration
+}
+
+// SetDNS01Rechecks makes the RA recheck DNS-01 validations whose TXT record
+// couldn't be looked up every Interval until Deadline after the first
+// attempt, instead of failing the challenge straight away. While it's being
+// rechecked the challenge is processing, and holds the error and validation
+// records of the latest attempt. The rechecks are recorded in the SA, so that
+// ResumeDNS01Rechecks can take over those of an RA that stopped.
+func (ra *RegistrationAuthorityImpl) SetDNS01Rechecks(config DNS01RecheckConfig) error {
+	if config.Interval <= 0 || config.Deadline <= config.Interval {
+		return fmt.Errorf("DNS-01 recheck interval must be positive and less than the deadline")
+	}
+	if config.Deadline >= ra.pendingAuthorizationLifetime {
+		return fmt.Errorf("DNS-01 recheck deadline must be less than the pending authorization lifetime")
+	}
+	ra.dns01Rechecks = &config
+	return nil
+}
+
+// recheckable returns whether a validation of ch that failed with prob may
+// succeed if it's tried again later. Only lookup failures are rechecked: a
+// TXT record that was found to be missing or wrong fails the challenge.
+func recheckable(ch core.Challenge, prob *probs.ProblemDetails) bool {
+	return ch.Type == core.ChallengeTypeDNS01 && prob != nil && prob.Type == probs.DNSProblem
+}
+
+// dns01RecheckLease returns when a lease on a DNS-01 recheck taken now
+// expires.
+func (ra *RegistrationAuthorityImpl) dns01RecheckLease() int64 {
+	return ra.clk.Now().Add(ra.dns01Rechecks.Interval + dns01RecheckLeaseSlack).UnixNano()
+}
+
+// addDNS01Recheck records in the SA that this RA is rechecking the failed
+// validation of the challenge at challIndex, first attempted at started. It
+// returns nil if rechecks aren't enabled, and a Duplicate error if the
+// challenge is already being rechecked.
+func (ra *RegistrationAuthorityImpl) addDNS01Recheck(ctx context.Context, authz core.Authorization, challIndex int, started time.Time) (*sapb.DNS01Recheck, error) {
+	if ra.dns01Rechecks == nil {
+		return nil, nil
+	}
+	deadline := started.Add(ra.dns01Rechecks.Deadline).UnixNano()
+	leaseExpires := ra.dns01RecheckLease()
+	var claim int64
+	recheck := &sapb.DNS01Recheck{
+		AuthzID:      &authz.ID,
+		ChallengeID:  &authz.Challenges[challIndex].ID,
+		Deadline:     &deadline,
+		LeaseExpires: &leaseExpires,
+		Claim:        &claim,
+	}
+	if err := ra.SA.AddDNS01Recheck(ctx, recheck); err != nil {
+		return nil, err
+	}
+	return recheck, nil
+}
+
+// claimDNS01Recheck takes or renews this RA's lease on a DNS-01 recheck,
+// returning false if another RA claimed it first or the claim couldn't be
+// made. Only the RA holding the lease may update the challenge.
+func (ra *RegistrationAuthorityImpl) claimDNS01Recheck(ctx context.Context, recheck *sapb.DNS01Recheck) bool {
+	leaseExpires := ra.dns01RecheckLease()
+	claimed, err := ra.SA.ClaimDNS01Recheck(ctx, &sapb.DNS01Recheck{
+		AuthzID:      recheck.AuthzID,
+		LeaseExpires: &leaseExpires,
+		Claim:        recheck.Claim,
+	})
+	if err != nil {
+		ra.log.AuditErrf("Could not claim DNS-01 recheck: err=[%s] authzID=[%s]", err, *recheck.AuthzID)
+		return false
+	}
+	if !*claimed.Exists {
+		ra.log.Infof("DNS-01 recheck claimed by another RA: authzID=[%s]", *recheck.AuthzID)
+		return false
+	}
+	claim := *recheck.Claim + 1
+	recheck.Claim = &claim
+	recheck.LeaseExpires = &leaseExpires
+	return true
+}
+
+// removeDNS01Recheck removes the DNS-01 recheck of authzID from the SA once
+// its challenge has been finalized.
+func (ra *RegistrationAuthorityImpl) removeDNS01Recheck(ctx context.Context, authzID string) {
+	err := ra.SA.RemoveDNS01Recheck(ctx, &sapb.AuthorizationID{Id: &authzID})
+	if err != nil {
+		ra.log.AuditErrf("Could not remove DNS-01 recheck: err=[%s] authzID=[%s]", err, authzID)
+	}
+}
+
+// recheckDNS01 rechecks the failed DNS-01 validation of the challenge at
+// challIndex until it succeeds, fails with a problem that waiting won't fix,
+// or the recheck's deadline passes, returning the last problem. Before each
+// recheck the lease on it is renewed and the challenge is stored as
+// processing, with the result of the attempt before it, so that subscribers
+// can follow its progress. If the lease is lost to another RA, false is
+// returned, and the challenge must be left to that RA.
+func (ra *RegistrationAuthorityImpl) recheckDNS01(ctx context.Context, authz *core.Authorization, challIndex int, recheck *sapb.DNS01Recheck, prob *probs.ProblemDetails) (*probs.ProblemDetails, bool) {
+	deadline := time.Unix(0, *recheck.Deadline)
+	challenge := &authz.Challenges[challIndex]
+	for recheckable(*challenge, prob) && !ra.clk.Now().Add(ra.dns01Rechecks.Interval).After(deadline) {
+		if !ra.claimDNS01Recheck(ctx, recheck) {
+			return prob, false
+		}
+		challenge.Status = core.StatusProcessing
+		challenge.Error = prob
+		err := ra.SA.UpdatePendingAuthorization(ctx, *authz)
+		if err != nil {
+			ra.log.AuditErrf("Could not record DNS-01 recheck: err=[%s] regID=[%d] authzID=[%s]",
+				err, authz.RegistrationID, authz.ID)
+		}
+
+		ra.clk.Sleep(ra.dns01Rechecks.Interval)
+		// The VA only validates pending challenges
+		challenge.Status = core.StatusPending
+		challenge.Error = nil
+		prob = ra.validate(ctx, authz, challIndex)
+		ra.stats.Inc("DNS01Rechecks", 1)
+	}
+	return prob, ra.claimDNS01Recheck(ctx, recheck)
+}
+
+// ResumeDNS01Rechecks takes over the DNS-01 rechecks whose lease has expired,
+// because the RA rechecking them stopped. Those whose deadline has passed
+// fail with the problem of their latest attempt, and the rest are rechecked
+// in the background until their deadline. A recheck whose challenge can't be
+// loaded is logged and skipped, and taken over again once its new lease
+// expires.
+func (ra *RegistrationAuthorityImpl) ResumeDNS01Rechecks(ctx context.Context) error {
+	now := ra.clk.Now().UnixNano()
+	stale, err := ra.SA.GetStaleDNS01Rechecks(ctx, &sapb.GetStaleDNS01RechecksRequest{Now: &now})
+	if err != nil {
+		return err
+	}
+	for _, recheck := range stale.Rechecks {
+		if !ra.claimDNS01Recheck(ctx, recheck) {
+			continue
+		}
+		authz, challIndex, err := ra.dns01RecheckChallenge(ctx, recheck)
+		if err != nil {
+			ra.log.AuditErrf("Could not resume DNS-01 recheck: err=[%s] authzID=[%s]", err, *recheck.AuthzID)
+			ra.stats.Inc("DNS01RecheckResumeErrors", 1)
+			continue
+		}
+		if challIndex < 0 {
+			// The challenge was finalized, or its authorization deactivated
+			// or removed, before the recheck was.
+			ra.removeDNS01Recheck(ctx, *recheck.AuthzID)
+			continue
+		}
+		ra.stats.Inc("DNS01RechecksResumed", 1)
+		go func(authz core.Authorization, recheck *sapb.DNS01Recheck) {
+			ctx := context.Background()
+			prob, claimed := ra.recheckDNS01(ctx, &authz, challIndex, recheck, authz.Challenges[challIndex].Error)
+			if !claimed {
+				return
+			}
+			ra.finalizeValidation(ctx, authz, challIndex, prob)
+			ra.removeDNS01Recheck(ctx, authz.ID)
+		}(authz, recheck)
+	}
+	return nil
+}
+
+// dns01RecheckChallenge returns the authorization of a DNS-01 recheck and the
+// index of its challenge, ready to be sent to the VA, or an index of -1 if the
+// challenge is no longer being rechecked.
+func (ra *RegistrationAuthorityImpl) dns01RecheckChallenge(ctx context.Context, recheck *sapb.DNS01Recheck) (core.Authorization, int, error) {
+	authz, err := ra.SA.GetAuthorization(ctx, *recheck.AuthzID)
+	if berrors.Is(err, berrors.NotFound) {
+		return authz, -1, nil
+	} else if err != nil {
+		return authz, -1, err
+	}
+	for i, ch := range authz.Challenges {
+		if ch.ID != *recheck.ChallengeID {
+			continue
+		}
+		if authz.Status != core.StatusPending || ch.Status != core.StatusProcessing {
+			return authz, -1, nil
+		}
+		if ch.Error == nil {
+			authz.Challenges[i].Error = probs.ServerInternal("DNS-01 recheck was interrupted")
+		}
+		reg, err := ra.SA.GetRegistration(ctx, authz.RegistrationID)
+		if err != nil {
+			return authz, -1, err
+		}
+		authz.Challenges[i].ProvidedKeyAuthorization, err = ch.ExpectedKeyAuthorization(reg.Key)
+		if err != nil {
+			return authz, -1, err
+		}
+		return authz, i, nil
+	}
+	return authz, -1, nil
+}
+
+// ResumeDNS01RechecksLoop calls ResumeDNS01Rechecks once per recheck
+// interval, forever. Errors are logged, and the rechecks that weren't resumed
+// are tried again next time.
+func (ra *RegistrationAuthorityImpl) ResumeDNS01RechecksLoop() {
+	interval := ra.dns01Rechecks.Interval
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		if err := ra.ResumeDNS01Rechecks(ctx); err != nil {
+			ra.log.Errf("error resuming DNS-01 rechecks: %s", err)
+		}
+		cancel()
+		ra.clk.Sleep(interval)
+	}
+}
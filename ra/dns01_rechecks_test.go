@@ -0,0 +1,405 @@
+package ra
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/core"
+	csrlib "github.com/letsencrypt/boulder/csr"
+	berrors "github.com/letsencrypt/boulder/errors"
+	bgrpc "github.com/letsencrypt/boulder/grpc"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/policy"
+	"github.com/letsencrypt/boulder/probs"
+	rapb "github.com/letsencrypt/boulder/ra/proto"
+	"github.com/letsencrypt/boulder/sa/memsa"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+	"github.com/letsencrypt/boulder/sa/satest"
+	"github.com/letsencrypt/boulder/test"
+)
+
+// recheckVA fails validations with each of problems in turn, then succeeds.
+// Before each validation it records the challenge it was given in validated,
+// sends the stored state of the challenge on stored, and waits for proceed.
+type recheckVA struct {
+	DummyValidationAuthority
+	sa        core.StorageGetter
+	problems  []*probs.ProblemDetails
+	validated core.Challenge
+	stored    chan core.Challenge
+	proceed   chan bool
+}
+
+func (va *recheckVA) PerformValidation(ctx context.Context, domain string, challenge core.Challenge, authz core.Authorization) ([]core.ValidationRecord, error) {
+	stored, err := va.sa.GetAuthorization(ctx, authz.ID)
+	if err != nil {
+		return nil, err
+	}
+	va.validated = challenge
+	va.stored <- stored.Challenges[0]
+	<-va.proceed
+	records := []core.ValidationRecord{{Hostname: domain}}
+	if len(va.problems) == 0 {
+		return records, nil
+	}
+	prob := va.problems[0]
+	va.problems = va.problems[1:]
+	return records, prob
+}
+
+func TestPerformValidationDNS01Recheck(t *testing.T) {
+	fc := clock.NewFake()
+	fc.Set(time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC))
+	ssa := memsa.New(fc, log)
+	pa, err := policy.New(SupportedChallenges)
+	test.AssertNotError(t, err, "Couldn't create PA")
+	ra := NewRegistrationAuthorityImpl(fc,
+		log,
+		metrics.NewNoopScope(),
+		1, testKeyPolicy, csrlib.NameLimits{Default: 100}, true, false, 300*24*time.Hour, 7*24*time.Hour, nil, noopCAA{}, 0, nil, nil, nil)
+	ra.SA = ssa
+	ra.PA = pa
+	err = ra.SetDNS01Rechecks(DNS01RecheckConfig{Deadline: 8 * 24 * time.Hour, Interval: time.Minute})
+	test.AssertError(t, err, "recheck deadline beyond the pending authorization lifetime was accepted")
+	err = ra.SetDNS01Rechecks(DNS01RecheckConfig{Deadline: time.Minute, Interval: time.Minute})
+	test.AssertError(t, err, "recheck interval as long as the deadline was accepted")
+	err = ra.SetDNS01Rechecks(DNS01RecheckConfig{Deadline: 8 * time.Minute, Interval: 3 * time.Minute})
+	test.AssertNotError(t, err, "SetDNS01Rechecks failed")
+
+	reg := satest.CreateWorkingRegistration(t, ssa)
+	validate := func(problems ...*probs.ProblemDetails) (*recheckVA, string) {
+		va := &recheckVA{
+			sa:       ssa,
+			problems: problems,
+			stored:   make(chan core.Challenge),
+			proceed:  make(chan bool),
+		}
+		ra.VA = va
+		exp := fc.Now().Add(time.Hour)
+		authz, err := ssa.NewPendingAuthorization(ctx, core.Authorization{
+			Identifier:     core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "example.com"},
+			RegistrationID: reg.ID,
+			Status:         core.StatusPending,
+			Expires:        &exp,
+			Challenges: []core.Challenge{
+				{Type: core.ChallengeTypeDNS01, Status: core.StatusPending, Token: core.NewToken()},
+			},
+			Combinations: [][]int{{0}},
+		})
+		test.AssertNotError(t, err, "Couldn't create pending authorization")
+		authzPB, err := bgrpc.AuthzToPB(authz)
+		test.AssertNotError(t, err, "AuthzToPB failed")
+		challIndex := int64(0)
+		_, err = ra.PerformValidation(ctx, &rapb.PerformValidationRequest{
+			Authz:          authzPB,
+			ChallengeIndex: &challIndex,
+		})
+		test.AssertNotError(t, err, "PerformValidation failed")
+		return va, authz.ID
+	}
+	// attempt lets the VA make its next attempt, returning the stored state
+	// of the challenge before it.
+	attempt := func(va *recheckVA) core.Challenge {
+		t.Helper()
+		select {
+		case stored := <-va.stored:
+			va.proceed <- true
+			return stored
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for the VA")
+		}
+		return core.Challenge{}
+	}
+	final := func(id string) core.Authorization {
+		t.Helper()
+		for i := 0; i < 100; i++ {
+			authz, err := ssa.GetAuthorization(ctx, id)
+			test.AssertNotError(t, err, "GetAuthorization failed")
+			if authz.Status != core.StatusPending {
+				return authz
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatal("Timed out waiting for the authorization to be finalized")
+		return core.Authorization{}
+	}
+
+	// Failed lookups are rechecked until they succeed, and the result of each
+	// attempt is stored in the meantime
+	va, id := validate(probs.DNS("DNS query timed out"), probs.DNS("SERVFAIL"))
+	test.AssertEquals(t, attempt(va).Status, core.StatusPending)
+	stored := attempt(va)
+	test.AssertEquals(t, stored.Status, core.StatusProcessing)
+	test.AssertEquals(t, stored.Error.Detail, "DNS query timed out")
+
+	// Responding to the challenge while it's rechecked doesn't start another
+	// validation
+	processing, err := ssa.GetAuthorization(ctx, id)
+	test.AssertNotError(t, err, "GetAuthorization failed")
+	authzPB, err := bgrpc.AuthzToPB(processing)
+	test.AssertNotError(t, err, "AuthzToPB failed")
+	challIndex := int64(0)
+	resp, err := ra.PerformValidation(ctx, &rapb.PerformValidationRequest{Authz: authzPB, ChallengeIndex: &challIndex})
+	test.AssertNotError(t, err, "PerformValidation of a processing challenge failed")
+	test.AssertEquals(t, *resp.Challenges[0].Status, string(core.StatusProcessing))
+
+	stored = attempt(va)
+	test.AssertEquals(t, stored.Status, core.StatusProcessing)
+	test.AssertEquals(t, stored.Error.Detail, "SERVFAIL")
+	validated := final(id)
+	test.AssertEquals(t, validated.Status, core.StatusValid)
+	test.AssertEquals(t, validated.Challenges[0].Status, core.StatusValid)
+	test.Assert(t, validated.Challenges[0].Error == nil, "Validated challenge kept the error of a recheck")
+
+	// Rechecks stop at the deadline. The attempts are at 0, 3 and 6 minutes,
+	// and one at 9 minutes would be after the 8 minute deadline.
+	va, id = validate(probs.DNS("SERVFAIL"), probs.DNS("SERVFAIL"),
+		probs.DNS("DNS query timed out"), probs.DNS("SERVFAIL"))
+	for i := 0; i < 3; i++ {
+		attempt(va)
+	}
+	failed := final(id)
+	test.AssertEquals(t, failed.Status, core.StatusInvalid)
+	test.AssertEquals(t, failed.Challenges[0].Error.Detail, "DNS query timed out")
+
+	// Problems that waiting won't fix aren't rechecked, including a TXT
+	// record that was looked up and found to be missing
+	for _, prob := range []*probs.ProblemDetails{
+		probs.CAA("CAA record forbids issuance"),
+		probs.Unauthorized("No TXT record found"),
+	} {
+		va, id = validate(prob)
+		attempt(va)
+		failed = final(id)
+		test.AssertEquals(t, failed.Status, core.StatusInvalid)
+		test.AssertEquals(t, failed.Challenges[0].Error.Type, prob.Type)
+	}
+
+	// Finished rechecks are removed from the SA
+	farFuture := fc.Now().Add(time.Hour).UnixNano()
+	stale, err := ssa.GetStaleDNS01Rechecks(ctx, &sapb.GetStaleDNS01RechecksRequest{Now: &farFuture})
+	test.AssertNotError(t, err, "GetStaleDNS01Rechecks failed")
+	test.AssertEquals(t, len(stale.Rechecks), 0)
+}
+
+func TestResumeDNS01Rechecks(t *testing.T) {
+	fc := clock.NewFake()
+	fc.Set(time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC))
+	ssa := memsa.New(fc, log)
+	ra := NewRegistrationAuthorityImpl(fc,
+		log,
+		metrics.NewNoopScope(),
+		1, testKeyPolicy, csrlib.NameLimits{Default: 100}, true, false, 300*24*time.Hour, 7*24*time.Hour, nil, noopCAA{}, 0, nil, nil, nil)
+	ra.SA = ssa
+	err := ra.SetDNS01Rechecks(DNS01RecheckConfig{Deadline: 8 * time.Minute, Interval: 3 * time.Minute})
+	test.AssertNotError(t, err, "SetDNS01Rechecks failed")
+	va := &recheckVA{
+		sa:      ssa,
+		stored:  make(chan core.Challenge),
+		proceed: make(chan bool),
+	}
+	ra.VA = va
+
+	reg := satest.CreateWorkingRegistration(t, ssa)
+	// recheck stores an authorization whose DNS-01 challenge was left
+	// processing by an RA that stopped, and the recheck that RA recorded.
+	recheck := func(status core.AcmeStatus, deadline, leaseExpires time.Time) string {
+		exp := fc.Now().Add(time.Hour)
+		authz, err := ssa.NewPendingAuthorization(ctx, core.Authorization{
+			Identifier:     core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "example.com"},
+			RegistrationID: reg.ID,
+			Status:         core.StatusPending,
+			Expires:        &exp,
+			Challenges: []core.Challenge{
+				{Type: core.ChallengeTypeDNS01, Status: core.StatusPending, Token: core.NewToken()},
+			},
+			Combinations: [][]int{{0}},
+		})
+		test.AssertNotError(t, err, "Couldn't create pending authorization")
+		authz.Challenges[0].Status = status
+		authz.Challenges[0].Error = probs.DNS("SERVFAIL")
+		err = ssa.UpdatePendingAuthorization(ctx, authz)
+		test.AssertNotError(t, err, "UpdatePendingAuthorization failed")
+		deadlineNS, leaseNS := deadline.UnixNano(), leaseExpires.UnixNano()
+		var claim int64
+		err = ssa.AddDNS01Recheck(ctx, &sapb.DNS01Recheck{
+			AuthzID:      &authz.ID,
+			ChallengeID:  &authz.Challenges[0].ID,
+			Deadline:     &deadlineNS,
+			LeaseExpires: &leaseNS,
+			Claim:        &claim,
+		})
+		test.AssertNotError(t, err, "AddDNS01Recheck failed")
+		return authz.ID
+	}
+	get := func(id string) core.Authorization {
+		t.Helper()
+		authz, err := ssa.GetAuthorization(ctx, id)
+		test.AssertNotError(t, err, "GetAuthorization failed")
+		return authz
+	}
+	// final waits for a resumed recheck to finalize its authorization
+	final := func(id string) core.Authorization {
+		t.Helper()
+		for i := 0; i < 100; i++ {
+			if authz := get(id); authz.Status != core.StatusPending {
+				return authz
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatal("Timed out waiting for the authorization to be finalized")
+		return core.Authorization{}
+	}
+
+	now := fc.Now()
+	resumed := recheck(core.StatusProcessing, now.Add(5*time.Minute), now.Add(-time.Second))
+	expired := recheck(core.StatusProcessing, now.Add(time.Minute), now.Add(-time.Second))
+	leased := recheck(core.StatusProcessing, now.Add(5*time.Minute), now.Add(time.Minute))
+	finalized := recheck(core.StatusPending, now.Add(5*time.Minute), now.Add(-time.Second))
+
+	err = ra.ResumeDNS01Rechecks(ctx)
+	test.AssertNotError(t, err, "ResumeDNS01Rechecks failed")
+
+	// A recheck whose deadline passed fails with the problem of its latest
+	// attempt
+	failed := final(expired)
+	test.AssertEquals(t, failed.Status, core.StatusInvalid)
+	test.AssertEquals(t, failed.Challenges[0].Error.Detail, "SERVFAIL")
+
+	// Other stale rechecks carry on where they left off
+	select {
+	case stored := <-va.stored:
+		test.AssertEquals(t, stored.Status, core.StatusProcessing)
+		va.proceed <- true
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the VA")
+	}
+	test.Assert(t, va.validated.ProvidedKeyAuthorization != "", "Resumed recheck didn't send the key authorization")
+	test.AssertEquals(t, final(resumed).Status, core.StatusValid)
+
+	// A recheck whose lease hasn't expired is left to the RA holding it, and
+	// the others are removed once they're finished, or straight away if their
+	// challenge isn't processing any more
+	test.AssertEquals(t, get(leased).Challenges[0].Status, core.StatusProcessing)
+	test.AssertEquals(t, get(finalized).Status, core.StatusPending)
+	farFuture := fc.Now().Add(time.Hour).UnixNano()
+	var stale *sapb.DNS01Rechecks
+	for i := 0; i < 100; i++ {
+		stale, err = ssa.GetStaleDNS01Rechecks(ctx, &sapb.GetStaleDNS01RechecksRequest{Now: &farFuture})
+		test.AssertNotError(t, err, "GetStaleDNS01Rechecks failed")
+		if len(stale.Rechecks) == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	test.AssertEquals(t, len(stale.Rechecks), 1)
+	test.AssertEquals(t, *stale.Rechecks[0].AuthzID, leased)
+}
+
+// failingAuthzSA fails to get the authorization with ID failID, whose DNS-01
+// recheck it returns first of the stale ones.
+type failingAuthzSA struct {
+	*memsa.StorageAuthority
+	failID string
+}
+
+func (sa *failingAuthzSA) GetAuthorization(ctx context.Context, id string) (core.Authorization, error) {
+	if id == sa.failID {
+		return core.Authorization{}, berrors.InternalServerError("database unavailable")
+	}
+	return sa.StorageAuthority.GetAuthorization(ctx, id)
+}
+
+func (sa *failingAuthzSA) GetStaleDNS01Rechecks(ctx context.Context, req *sapb.GetStaleDNS01RechecksRequest) (*sapb.DNS01Rechecks, error) {
+	stale, err := sa.StorageAuthority.GetStaleDNS01Rechecks(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(stale.Rechecks, func(i, j int) bool {
+		return *stale.Rechecks[i].AuthzID == sa.failID && *stale.Rechecks[j].AuthzID != sa.failID
+	})
+	return stale, nil
+}
+
+func TestResumeDNS01RechecksError(t *testing.T) {
+	fc := clock.NewFake()
+	fc.Set(time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC))
+	ssa := &failingAuthzSA{StorageAuthority: memsa.New(fc, log)}
+	ra := NewRegistrationAuthorityImpl(fc,
+		log,
+		metrics.NewNoopScope(),
+		1, testKeyPolicy, csrlib.NameLimits{Default: 100}, true, false, 300*24*time.Hour, 7*24*time.Hour, nil, noopCAA{}, 0, nil, nil, nil)
+	ra.SA = ssa
+	err := ra.SetDNS01Rechecks(DNS01RecheckConfig{Deadline: 8 * time.Minute, Interval: 3 * time.Minute})
+	test.AssertNotError(t, err, "SetDNS01Rechecks failed")
+
+	reg := satest.CreateWorkingRegistration(t, ssa)
+	// recheck stores a processing DNS-01 challenge and a stale recheck of it
+	// whose deadline has passed, so that resuming it fails the challenge.
+	recheck := func() string {
+		exp := fc.Now().Add(time.Hour)
+		authz, err := ssa.NewPendingAuthorization(ctx, core.Authorization{
+			Identifier:     core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "example.com"},
+			RegistrationID: reg.ID,
+			Status:         core.StatusPending,
+			Expires:        &exp,
+			Challenges: []core.Challenge{
+				{Type: core.ChallengeTypeDNS01, Status: core.StatusPending, Token: core.NewToken()},
+			},
+			Combinations: [][]int{{0}},
+		})
+		test.AssertNotError(t, err, "Couldn't create pending authorization")
+		authz.Challenges[0].Status = core.StatusProcessing
+		authz.Challenges[0].Error = probs.DNS("SERVFAIL")
+		err = ssa.UpdatePendingAuthorization(ctx, authz)
+		test.AssertNotError(t, err, "UpdatePendingAuthorization failed")
+		past := fc.Now().Add(-time.Second).UnixNano()
+		var claim int64
+		err = ssa.AddDNS01Recheck(ctx, &sapb.DNS01Recheck{
+			AuthzID:      &authz.ID,
+			ChallengeID:  &authz.Challenges[0].ID,
+			Deadline:     &past,
+			LeaseExpires: &past,
+			Claim:        &claim,
+		})
+		test.AssertNotError(t, err, "AddDNS01Recheck failed")
+		return authz.ID
+	}
+	ssa.failID = recheck()
+	resumed := recheck()
+
+	// The recheck whose authorization couldn't be loaded doesn't stop the
+	// next one being resumed
+	err = ra.ResumeDNS01Rechecks(ctx)
+	test.AssertNotError(t, err, "ResumeDNS01Rechecks failed")
+	var authz core.Authorization
+	for i := 0; i < 100; i++ {
+		authz, err = ssa.GetAuthorization(ctx, resumed)
+		test.AssertNotError(t, err, "GetAuthorization failed")
+		if authz.Status != core.StatusPending {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	test.AssertEquals(t, authz.Status, core.StatusInvalid)
+
+	// The other is resumed once its new lease expires
+	failed := ssa.failID
+	ssa.failID = ""
+	fc.Add(3*time.Minute + dns01RecheckLeaseSlack)
+	err = ra.ResumeDNS01Rechecks(ctx)
+	test.AssertNotError(t, err, "ResumeDNS01Rechecks failed")
+	for i := 0; i < 100; i++ {
+		authz, err = ssa.GetAuthorization(ctx, failed)
+		test.AssertNotError(t, err, "GetAuthorization failed")
+		if authz.Status != core.StatusPending {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	test.AssertEquals(t, authz.Status, core.StatusInvalid)
+}
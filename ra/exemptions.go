@@ -0,0 +1,39 @@
+package ra
+
+import (
+	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/policy"
+)
+
+// SetPolicyExemptionKey sets the key policy exemption tokens are signed with,
+// which must be the same as the CA's. Without it, new orders with policy
+// exemptions are rejected.
+func (ra *RegistrationAuthorityImpl) SetPolicyExemptionKey(key []byte) {
+	ra.exemptionKey = key
+}
+
+// checkNewOrderExemptions checks the policy exemption tokens presented with a
+// new order for names by the account regID: each must be validly signed,
+// unexpired, for the account, and for one of the names. It returns the
+// hostname policy entry each exempt name is exempt from. The SA makes sure
+// that each token is only used by one order.
+func (ra *RegistrationAuthorityImpl) checkNewOrderExemptions(regID int64, names []string, tokens []string) (map[string]string, error) {
+	exemptions, err := policy.ParseExemptions(ra.exemptionKey, regID, tokens)
+	if err != nil {
+		return nil, err
+	}
+	inOrder := make(map[string]bool, len(names))
+	for _, name := range names {
+		inOrder[name] = true
+	}
+	now := ra.clk.Now()
+	for identifier, e := range exemptions {
+		if !now.Before(e.Expires) {
+			return nil, berrors.UnauthorizedError("Policy exemption %s has expired", e.ID)
+		}
+		if !inOrder[identifier] {
+			return nil, berrors.MalformedError("Policy exemption %s is for %q, which isn't in the order", e.ID, identifier)
+		}
+	}
+	return policy.ExemptEntries(exemptions), nil
+}
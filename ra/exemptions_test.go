@@ -0,0 +1,102 @@
+package ra
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	csrlib "github.com/letsencrypt/boulder/csr"
+	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/policy"
+	rapb "github.com/letsencrypt/boulder/ra/proto"
+	"github.com/letsencrypt/boulder/sa/memsa"
+	"github.com/letsencrypt/boulder/sa/satest"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestNewOrderPolicyExemption(t *testing.T) {
+	fc := clock.NewFake()
+	fc.Set(time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC))
+	ssa := memsa.New(fc, log)
+	pa, err := policy.New(SupportedChallenges)
+	test.AssertNotError(t, err, "Couldn't create PA")
+	err = pa.SetHostnamePolicyFile("../test/hostname-policy.json")
+	test.AssertNotError(t, err, "Couldn't set hostname policy")
+	ra := NewRegistrationAuthorityImpl(fc,
+		log,
+		metrics.NewNoopScope(),
+		1, testKeyPolicy, csrlib.NameLimits{Default: 100}, true, false, 300*24*time.Hour, 7*24*time.Hour, nil, noopCAA{}, 7*24*time.Hour, nil, nil, nil)
+	ra.SA = ssa
+	ra.PA = pa
+	key := []byte("policy exemption test key")
+	ra.SetPolicyExemptionKey(key)
+
+	reg := satest.CreateWorkingRegistration(t, ssa)
+	exemption := policy.Exemption{
+		ID:             "abc",
+		RegistrationID: reg.ID,
+		Identifier:     "www.example.net",
+		Entry:          "example.net",
+		Expires:        fc.Now().Add(time.Hour),
+	}
+	sign := func(e policy.Exemption) string {
+		token, err := policy.SignExemption(key, e)
+		test.AssertNotError(t, err, "SignExemption failed")
+		return token
+	}
+	token := sign(exemption)
+	newOrder := func(names []string, tokens ...string) error {
+		_, err := ra.NewOrder(ctx, &rapb.NewOrderRequest{
+			RegistrationID: &reg.ID,
+			Names:          names,
+			Exemptions:     tokens,
+		})
+		return err
+	}
+
+	// The blocked name is rejected without the exemption
+	err = newOrder([]string{"www.example.net"})
+	test.Assert(t, berrors.Is(err, berrors.RejectedIdentifier), "blocked name was allowed")
+
+	order, err := ra.NewOrder(ctx, &rapb.NewOrderRequest{
+		RegistrationID: &reg.ID,
+		Names:          []string{"www.example.net", "www.example.com"},
+		Exemptions:     []string{token},
+	})
+	test.AssertNotError(t, err, "NewOrder with an exemption failed")
+	test.AssertDeepEquals(t, order.Exemptions, []string{token})
+
+	// Each exemption can be used once
+	err = newOrder([]string{"www.example.net"}, token)
+	test.Assert(t, berrors.Is(err, berrors.Duplicate), "exemption was used twice")
+
+	testCases := []struct {
+		name    string
+		change  func(*policy.Exemption)
+		names   []string
+		errType berrors.ErrorType
+	}{
+		{"expired", func(e *policy.Exemption) { e.Expires = fc.Now() }, []string{"www.example.net"}, berrors.Unauthorized},
+		{"other account", func(e *policy.Exemption) { e.RegistrationID++ }, []string{"www.example.net"}, berrors.Unauthorized},
+		{"name not in order", func(e *policy.Exemption) {}, []string{"www.example.com"}, berrors.Malformed},
+		{"other entry", func(e *policy.Exemption) { e.Entry = "example.org" }, []string{"www.example.net"}, berrors.RejectedIdentifier},
+		{"other name", func(e *policy.Exemption) { e.Identifier = "example.org" }, []string{"www.example.net", "example.org"}, berrors.RejectedIdentifier},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := exemption
+			e.ID = tc.name
+			tc.change(&e)
+			err := newOrder(tc.names, sign(e))
+			test.Assert(t, berrors.Is(err, tc.errType), "wrong error: "+err.Error())
+		})
+	}
+
+	// Without a key, exemptions aren't supported
+	ra.SetPolicyExemptionKey(nil)
+	exemption.ID = "no key"
+	err = newOrder([]string{"www.example.net"}, sign(exemption))
+	test.Assert(t, berrors.Is(err, berrors.Malformed), "exemption was accepted without a key")
+}
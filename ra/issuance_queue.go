@@ -0,0 +1,230 @@
+package ra
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+
+	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/metrics"
+)
+
+// IssuanceQueueConfig configures the queue between the RA accepting a request
+// for a certificate and the CA signing it, which keeps an overloaded CA from
+// piling up waiting requests until the RA runs out of memory.
+type IssuanceQueueConfig struct {
+	// MaxConcurrent is how many certificates may be being signed at once.
+	MaxConcurrent int
+	// MaxQueued is how many requests may wait for their turn to be signed.
+	// Once it's reached, further requests are refused with a rate limit
+	// error.
+	MaxQueued int
+	// RenewalReserve is how many of MaxQueued are kept for renewals, so that
+	// during overload new issuance is refused first. Renewals are signed
+	// ahead of new issuance regardless.
+	RenewalReserve int
+	// MaxWait is how long a request may wait for its turn before it's
+	// refused with a rate limit error.
+	MaxWait time.Duration
+}
+
+// Issuance priority tiers, highest priority first
+const (
+	tierRenewal = iota
+	tierNew
+	numTiers
+)
+
+var tierNames = [numTiers]string{"renewal", "new"}
+
+// issuanceWaiter is a request waiting in the issuance queue. ready is closed
+// once it has been given a signing slot.
+type issuanceWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// issuanceQueue limits how many certificates the RA asks the CA to sign at
+// once, queueing the rest by priority tier and shedding them once the queue
+// is full or they've waited too long.
+type issuanceQueue struct {
+	clk    clock.Clock
+	config IssuanceQueueConfig
+
+	mu      sync.Mutex
+	active  int
+	waiting [numTiers]*list.List
+
+	queued   *prometheus.GaugeVec
+	shed     *prometheus.CounterVec
+	waitTime *prometheus.HistogramVec
+}
+
+func newIssuanceQueue(clk clock.Clock, stats metrics.Scope, config IssuanceQueueConfig) (*issuanceQueue, error) {
+	if config.MaxConcurrent <= 0 {
+		return nil, fmt.Errorf("issuance queue maxConcurrent must be positive")
+	}
+	if config.MaxQueued < 0 || config.RenewalReserve < 0 || config.RenewalReserve > config.MaxQueued {
+		return nil, fmt.Errorf("issuance queue renewalReserve must be between 0 and maxQueued")
+	}
+	if config.MaxWait <= 0 {
+		return nil, fmt.Errorf("issuance queue maxWait must be positive")
+	}
+	q := &issuanceQueue{
+		clk:    clk,
+		config: config,
+		queued: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "issuance_queue_waiting",
+			Help: "Number of certificate requests waiting to be signed, by priority tier",
+		}, []string{"tier"}),
+		shed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "issuance_queue_shed",
+			Help: "Number of certificate requests refused because the CA is overloaded, by priority tier and reason (full, timeout, canceled)",
+		}, []string{"tier", "reason"}),
+		waitTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "issuance_queue_wait_seconds",
+			Help:    "Time certificate requests waited to be signed, by priority tier",
+			Buckets: metrics.InternetFacingBuckets,
+		}, []string{"tier"}),
+	}
+	for i := range q.waiting {
+		q.waiting[i] = list.New()
+	}
+	stats.MustRegister(q.queued, q.shed, q.waitTime)
+	return q, nil
+}
+
+// numWaiting returns how many requests are queued across every tier. q.mu must
+// be held.
+func (q *issuanceQueue) numWaiting() int {
+	n := 0
+	for _, l := range q.waiting {
+		n += l.Len()
+	}
+	return n
+}
+
+// overloaded returns the error a shed request gets, which asks the client to
+// retry after MaxWait.
+func (q *issuanceQueue) overloaded(tier int, reason string) error {
+	q.shed.With(prometheus.Labels{"tier": tierNames[tier], "reason": reason}).Inc()
+	return berrors.RateLimitErrorWithRetryAfter(q.config.MaxWait, "the CA is currently overloaded, please retry later")
+}
+
+// acquire waits for a signing slot for a renewal or new issuance. It returns a
+// function that must be called to give up the slot once the certificate has
+// been signed, or a rate limit error if the request was shed.
+func (q *issuanceQueue) acquire(ctx context.Context, renewal bool) (func(), error) {
+	tier := tierNew
+	if renewal {
+		tier = tierRenewal
+	}
+
+	q.mu.Lock()
+	if q.active < q.config.MaxConcurrent && q.numWaiting() == 0 {
+		q.active++
+		q.mu.Unlock()
+		q.waitTime.With(prometheus.Labels{"tier": tierNames[tier]}).Observe(0)
+		return q.release, nil
+	}
+	limit := q.config.MaxQueued
+	if tier != tierRenewal {
+		limit -= q.config.RenewalReserve
+	}
+	if q.numWaiting() >= limit {
+		q.mu.Unlock()
+		return nil, q.overloaded(tier, "full")
+	}
+	w := &issuanceWaiter{ready: make(chan struct{})}
+	elem := q.waiting[tier].PushBack(w)
+	q.queued.With(prometheus.Labels{"tier": tierNames[tier]}).Set(float64(q.waiting[tier].Len()))
+	q.mu.Unlock()
+
+	start := q.clk.Now()
+	var reason string
+	select {
+	case <-w.ready:
+	case <-q.clk.After(q.config.MaxWait):
+		reason = "timeout"
+	case <-ctx.Done():
+		reason = "canceled"
+	}
+	q.waitTime.With(prometheus.Labels{"tier": tierNames[tier]}).Observe(q.clk.Since(start).Seconds())
+	if reason == "" {
+		return q.release, nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	// The request may have been given a slot as it gave up waiting, in which
+	// case it may as well use it.
+	if w.granted {
+		return q.release, nil
+	}
+	q.waiting[tier].Remove(elem)
+	q.queued.With(prometheus.Labels{"tier": tierNames[tier]}).Set(float64(q.waiting[tier].Len()))
+	return nil, q.overloaded(tier, reason)
+}
+
+// release gives up a signing slot, handing it to the first request waiting in
+// the highest priority tier.
+func (q *issuanceQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for tier, l := range q.waiting {
+		front := l.Front()
+		if front == nil {
+			continue
+		}
+		l.Remove(front)
+		q.queued.With(prometheus.Labels{"tier": tierNames[tier]}).Set(float64(l.Len()))
+		w := front.Value.(*issuanceWaiter)
+		w.granted = true
+		close(w.ready)
+		return
+	}
+	q.active--
+}
+
+// waitForIssuance waits for a turn to sign a certificate for names for the
+// account regID, if the RA has an issuance queue. replaces is the serial of
+// the certificate the order replaces, if any. The request is a renewal, and
+// waits ahead of new issuance, if renewsReplaced says it renews that
+// certificate or a certificate for exactly names has been issued before. It
+// returns a function that must be called once the certificate has been
+// signed, or a rate limit error if the request was shed. It must be called
+// before anything that a shed request would leave behind, like setting an
+// order processing, so that the request can be retried.
+func (ra *RegistrationAuthorityImpl) waitForIssuance(ctx context.Context, regID int64, names []string, replaces string) (func(), error) {
+	if ra.issuanceQueue == nil {
+		return func() {}, nil
+	}
+	renewal, err := ra.renewsReplaced(ctx, regID, names, replaces)
+	if err != nil {
+		return nil, err
+	}
+	if !renewal {
+		renewal, err = ra.SA.FQDNSetExists(ctx, names)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ra.issuanceQueue.acquire(ctx, renewal)
+}
+
+// SetIssuanceQueue makes the RA limit how many certificates it asks the CA to
+// sign at once, queueing renewals ahead of new issuance and refusing requests
+// with rate limit errors when the queue is full.
+func (ra *RegistrationAuthorityImpl) SetIssuanceQueue(config IssuanceQueueConfig) error {
+	q, err := newIssuanceQueue(ra.clk, ra.stats, config)
+	if err != nil {
+		return err
+	}
+	ra.issuanceQueue = q
+	return nil
+}
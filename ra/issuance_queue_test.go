@@ -0,0 +1,165 @@
+package ra
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+
+	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/features"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestNewIssuanceQueue(t *testing.T) {
+	for _, config := range []IssuanceQueueConfig{
+		{MaxQueued: 10, MaxWait: time.Second},
+		{MaxConcurrent: 1, MaxQueued: 10, RenewalReserve: 11, MaxWait: time.Second},
+		{MaxConcurrent: 1, MaxQueued: 10},
+	} {
+		_, err := newIssuanceQueue(clock.NewFake(), metrics.NewNoopScope(), config)
+		test.AssertError(t, err, "invalid config was accepted")
+	}
+}
+
+// waitQueued waits until n requests are waiting in q.
+func waitQueued(t *testing.T, q *issuanceQueue, n int) {
+	for i := 0; i < 1000; i++ {
+		q.mu.Lock()
+		waiting := q.numWaiting()
+		q.mu.Unlock()
+		if waiting == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("%d requests never queued", n)
+}
+
+type acquired struct {
+	name    string
+	release func()
+}
+
+func TestIssuanceQueuePriority(t *testing.T) {
+	q, err := newIssuanceQueue(clock.NewFake(), metrics.NewNoopScope(), IssuanceQueueConfig{
+		MaxConcurrent:  1,
+		MaxQueued:      3,
+		RenewalReserve: 1,
+		MaxWait:        time.Hour,
+	})
+	test.AssertNotError(t, err, "newIssuanceQueue failed")
+
+	release, err := q.acquire(context.Background(), false)
+	test.AssertNotError(t, err, "first request wasn't signed straight away")
+
+	results := make(chan acquired)
+	wait := func(name string, renewal bool) {
+		go func() {
+			release, err := q.acquire(context.Background(), renewal)
+			test.AssertNotError(t, err, "queued request was shed")
+			results <- acquired{name, release}
+		}()
+	}
+	wait("new 1", false)
+	waitQueued(t, q, 1)
+	wait("new 2", false)
+	waitQueued(t, q, 2)
+
+	// The rest of the queue is reserved for renewals
+	_, err = q.acquire(context.Background(), false)
+	test.Assert(t, berrors.Is(err, berrors.RateLimit), "new issuance wasn't shed from a full queue")
+	wait("renewal", true)
+	waitQueued(t, q, 3)
+	_, err = q.acquire(context.Background(), true)
+	test.Assert(t, berrors.Is(err, berrors.RateLimit), "renewal wasn't shed from a full queue")
+	test.AssertEquals(t, test.CountCounter(q.shed.With(prometheus.Labels{"tier": "new", "reason": "full"})), 1)
+	test.AssertEquals(t, test.CountCounter(q.shed.With(prometheus.Labels{"tier": "renewal", "reason": "full"})), 1)
+
+	// The renewal is signed first, then the new issuance in order
+	for _, name := range []string{"renewal", "new 1", "new 2"} {
+		release()
+		next := <-results
+		test.AssertEquals(t, next.name, name)
+		release = next.release
+	}
+	release()
+	test.AssertEquals(t, q.active, 0)
+}
+
+func TestIssuanceQueueShedding(t *testing.T) {
+	q, err := newIssuanceQueue(clock.Default(), metrics.NewNoopScope(), IssuanceQueueConfig{
+		MaxConcurrent: 1,
+		MaxQueued:     10,
+		MaxWait:       10 * time.Millisecond,
+	})
+	test.AssertNotError(t, err, "newIssuanceQueue failed")
+	release, err := q.acquire(context.Background(), false)
+	test.AssertNotError(t, err, "first request wasn't signed straight away")
+
+	// A request that waits too long is shed
+	_, err = q.acquire(context.Background(), false)
+	test.Assert(t, berrors.Is(err, berrors.RateLimit), "request wasn't shed after waiting too long")
+	test.AssertEquals(t, err.(*berrors.BoulderError).RetryAfter, 10*time.Millisecond)
+	test.AssertEquals(t, test.CountCounter(q.shed.With(prometheus.Labels{"tier": "new", "reason": "timeout"})), 1)
+
+	// As is one whose client gives up
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = q.acquire(ctx, true)
+	test.Assert(t, berrors.Is(err, berrors.RateLimit), "request wasn't shed when canceled")
+	test.AssertEquals(t, test.CountCounter(q.shed.With(prometheus.Labels{"tier": "renewal", "reason": "canceled"})), 1)
+
+	// Shed requests leave the queue, so the slot is free once it's released
+	waitQueued(t, q, 0)
+	release()
+	release, err = q.acquire(context.Background(), false)
+	test.AssertNotError(t, err, "request wasn't signed straight away")
+	release()
+}
+
+func TestWaitForIssuanceTier(t *testing.T) {
+	fc := clock.NewFake()
+	fc.Set(time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC))
+	sa, ra := newReplacedCertSA(t, fc)
+	ra.stats = metrics.NewNoopScope()
+	// With the only slot taken and no room in the queue except for renewals,
+	// new issuance is shed straight away and renewals wait
+	err := ra.SetIssuanceQueue(IssuanceQueueConfig{
+		MaxConcurrent:  1,
+		MaxQueued:      1,
+		RenewalReserve: 1,
+		MaxWait:        time.Hour,
+	})
+	test.AssertNotError(t, err, "SetIssuanceQueue failed")
+	release, err := ra.issuanceQueue.acquire(context.Background(), false)
+	test.AssertNotError(t, err, "first request wasn't signed straight away")
+	defer release()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tierOf := func(names []string, replaces string) string {
+		t.Helper()
+		before := test.CountCounter(ra.issuanceQueue.shed.With(prometheus.Labels{"tier": "renewal", "reason": "canceled"}))
+		_, err := ra.waitForIssuance(canceled, 1, names, replaces)
+		test.Assert(t, berrors.Is(err, berrors.RateLimit), "request wasn't shed")
+		if test.CountCounter(ra.issuanceQueue.shed.With(prometheus.Labels{"tier": "renewal", "reason": "canceled"})) > before {
+			return "renewal"
+		}
+		return "new"
+	}
+	names := []string{"example.com", "www.example.com"}
+
+	// The client's replaces field alone doesn't make a request a renewal
+	test.AssertEquals(t, tierOf(names, sa.cert.Serial), "new")
+
+	err = features.Set(map[string]bool{"StoreReplacementOrders": true})
+	test.AssertNotError(t, err, "setting feature")
+	defer features.Reset()
+	test.AssertEquals(t, tierOf(names, sa.cert.Serial), "renewal")
+	test.AssertEquals(t, tierOf([]string{"example.net"}, sa.cert.Serial), "new")
+	sa.replaced = true
+	test.AssertEquals(t, tierOf(names, sa.cert.Serial), "new")
+}
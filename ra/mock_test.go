@@ -95,6 +95,11 @@ func (sa *mockInvalidAuthorizationsAuthority) PreviousCertificateExists(
 	}, nil
 }
 
+func (sa *mockInvalidAuthorizationsAuthority) CertificateReplaced(_ context.Context, _ *sapb.Serial, _ ...grpc.CallOption) (*sapb.Exists, error) {
+	f := false
+	return &sapb.Exists{Exists: &f}, nil
+}
+
 func (sa *mockInvalidAuthorizationsAuthority) FQDNSetExists(ctx context.Context, in *sapb.FQDNSetExistsRequest, opts ...grpc.CallOption) (*sapb.Exists, error) {
 	return nil, nil
 }
@@ -139,6 +144,10 @@ func (sa *mockInvalidAuthorizationsAuthority) DeactivateAuthorization(ctx contex
 	return nil, nil
 }
 
+func (sa *mockInvalidAuthorizationsAuthority) DeactivateAuthorization2(ctx context.Context, in *sapb.AuthorizationID2, opts ...grpc.CallOption) (*core.Empty, error) {
+	return nil, nil
+}
+
 func (sa *mockInvalidAuthorizationsAuthority) NewOrder(ctx context.Context, in *core.Order, opts ...grpc.CallOption) (*core.Order, error) {
 	return nil, nil
 }
@@ -163,6 +172,10 @@ func (sa *mockInvalidAuthorizationsAuthority) GetValidOrderAuthorizations(_ cont
 	return nil, nil
 }
 
+func (sa *mockInvalidAuthorizationsAuthority) GetOrderAuthorizations(_ context.Context, _ *sapb.GetOrderAuthorizationsRequest, opts ...grpc.CallOption) (*sapb.Authorizations, error) {
+	return nil, nil
+}
+
 func (sa *mockInvalidAuthorizationsAuthority) SetOrderProcessing(ctx context.Context, in *core.Order, opts ...grpc.CallOption) (*core.Empty, error) {
 	return nil, nil
 }
@@ -182,3 +195,63 @@ func (sa *mockInvalidAuthorizationsAuthority) RevokeCertificate(_ context.Contex
 func (sa *mockInvalidAuthorizationsAuthority) GetAuthz2(_ context.Context, _ *sapb.AuthorizationID2, opts ...grpc.CallOption) (*corepb.Authorization, error) {
 	return nil, nil
 }
+
+func (sa *mockInvalidAuthorizationsAuthority) GetRateLimitOverrides(_ context.Context, _ *sapb.GetRateLimitOverridesRequest, opts ...grpc.CallOption) (*sapb.RateLimitOverrides, error) {
+	return nil, nil
+}
+
+func (sa *mockInvalidAuthorizationsAuthority) AddRateLimitOverride(_ context.Context, _ *sapb.RateLimitOverride, opts ...grpc.CallOption) (*sapb.RateLimitOverride, error) {
+	return nil, nil
+}
+
+func (sa *mockInvalidAuthorizationsAuthority) ExpireRateLimitOverride(_ context.Context, _ *sapb.ExpireRateLimitOverrideRequest, opts ...grpc.CallOption) (*core.Empty, error) {
+	return nil, nil
+}
+
+func (sa *mockInvalidAuthorizationsAuthority) GetChallengeWhitelist(_ context.Context, _ *sapb.GetChallengeWhitelistRequest, opts ...grpc.CallOption) (*sapb.ChallengeWhitelistEntries, error) {
+	return nil, nil
+}
+
+func (sa *mockInvalidAuthorizationsAuthority) AddChallengeWhitelistEntry(_ context.Context, _ *sapb.ChallengeWhitelistEntry, opts ...grpc.CallOption) (*sapb.ChallengeWhitelistEntry, error) {
+	return nil, nil
+}
+
+func (sa *mockInvalidAuthorizationsAuthority) RemoveChallengeWhitelistEntry(_ context.Context, _ *sapb.RemoveChallengeWhitelistEntryRequest, opts ...grpc.CallOption) (*core.Empty, error) {
+	return nil, nil
+}
+
+func (sa *mockInvalidAuthorizationsAuthority) GetStaleDNS01Rechecks(_ context.Context, _ *sapb.GetStaleDNS01RechecksRequest, opts ...grpc.CallOption) (*sapb.DNS01Rechecks, error) {
+	return nil, nil
+}
+
+func (sa *mockInvalidAuthorizationsAuthority) AddDNS01Recheck(_ context.Context, _ *sapb.DNS01Recheck, opts ...grpc.CallOption) (*core.Empty, error) {
+	return nil, nil
+}
+
+func (sa *mockInvalidAuthorizationsAuthority) ClaimDNS01Recheck(_ context.Context, _ *sapb.DNS01Recheck, opts ...grpc.CallOption) (*sapb.Exists, error) {
+	return nil, nil
+}
+
+func (sa *mockInvalidAuthorizationsAuthority) RemoveDNS01Recheck(_ context.Context, _ *sapb.AuthorizationID, opts ...grpc.CallOption) (*core.Empty, error) {
+	return nil, nil
+}
+
+func (sa *mockInvalidAuthorizationsAuthority) RetryChallenge(_ context.Context, _ *sapb.RetryChallengeRequest, opts ...grpc.CallOption) (*core.Empty, error) {
+	return nil, nil
+}
+
+func (sa *mockInvalidAuthorizationsAuthority) SearchCertificates(_ context.Context, _ *sapb.SearchCertificatesRequest, opts ...grpc.CallOption) (*sapb.CertificateSearchResults, error) {
+	return nil, nil
+}
+
+func (sa *mockInvalidAuthorizationsAuthority) ListCertificatesForAccount(_ context.Context, _ *sapb.ListCertificatesForAccountRequest, opts ...grpc.CallOption) (*sapb.CertificateSearchResults, error) {
+	return nil, nil
+}
+
+func (sa *mockInvalidAuthorizationsAuthority) ListOrdersForAccount(_ context.Context, _ *sapb.ListOrdersForAccountRequest, opts ...grpc.CallOption) (*sapb.OrderList, error) {
+	return nil, nil
+}
+
+func (sa *mockInvalidAuthorizationsAuthority) ListInvalidAuthorizationsForAccount(_ context.Context, _ *sapb.ListInvalidAuthorizationsForAccountRequest, opts ...grpc.CallOption) (*sapb.AuthorizationList, error) {
+	return nil, nil
+}
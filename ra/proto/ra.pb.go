@@ -18,6 +18,11 @@ It has these top-level messages:
 	AdministrativelyRevokeCertificateRequest
 	NewOrderRequest
 	FinalizeOrderRequest
+	CheckIssuanceRequest
+	CheckIssuanceResponse
+	RateLimitUsageRequest
+	RateLimitUsage
+	RateLimitUsageResponse
 */
 package proto
 
@@ -182,10 +187,12 @@ type RevokeCertificateWithRegRequest struct {
 	XXX_unrecognized []byte `json:"-"`
 }
 
-func (m *RevokeCertificateWithRegRequest) Reset()                    { *m = RevokeCertificateWithRegRequest{} }
-func (m *RevokeCertificateWithRegRequest) String() string            { return proto1.CompactTextString(m) }
-func (*RevokeCertificateWithRegRequest) ProtoMessage()               {}
-func (*RevokeCertificateWithRegRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+func (m *RevokeCertificateWithRegRequest) Reset()         { *m = RevokeCertificateWithRegRequest{} }
+func (m *RevokeCertificateWithRegRequest) String() string { return proto1.CompactTextString(m) }
+func (*RevokeCertificateWithRegRequest) ProtoMessage()    {}
+func (*RevokeCertificateWithRegRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor0, []int{5}
+}
 
 func (m *RevokeCertificateWithRegRequest) GetCert() []byte {
 	if m != nil {
@@ -218,8 +225,10 @@ type AdministrativelyRevokeCertificateRequest struct {
 func (m *AdministrativelyRevokeCertificateRequest) Reset() {
 	*m = AdministrativelyRevokeCertificateRequest{}
 }
-func (m *AdministrativelyRevokeCertificateRequest) String() string { return proto1.CompactTextString(m) }
-func (*AdministrativelyRevokeCertificateRequest) ProtoMessage()    {}
+func (m *AdministrativelyRevokeCertificateRequest) String() string {
+	return proto1.CompactTextString(m)
+}
+func (*AdministrativelyRevokeCertificateRequest) ProtoMessage() {}
 func (*AdministrativelyRevokeCertificateRequest) Descriptor() ([]byte, []int) {
 	return fileDescriptor0, []int{6}
 }
@@ -246,8 +255,13 @@ func (m *AdministrativelyRevokeCertificateRequest) GetAdminName() string {
 }
 
 type NewOrderRequest struct {
-	RegistrationID   *int64   `protobuf:"varint,1,opt,name=registrationID" json:"registrationID,omitempty"`
-	Names            []string `protobuf:"bytes,2,rep,name=names" json:"names,omitempty"`
+	RegistrationID *int64   `protobuf:"varint,1,opt,name=registrationID" json:"registrationID,omitempty"`
+	Names          []string `protobuf:"bytes,2,rep,name=names" json:"names,omitempty"`
+	// replaces is the ARI certificate ID of the certificate the order
+	// replaces, if any
+	Replaces *string `protobuf:"bytes,3,opt,name=replaces" json:"replaces,omitempty"`
+	// exemptions are policy exemption tokens for names in the order
+	Exemptions       []string `protobuf:"bytes,4,rep,name=exemptions" json:"exemptions,omitempty"`
 	XXX_unrecognized []byte   `json:"-"`
 }
 
@@ -270,6 +284,20 @@ func (m *NewOrderRequest) GetNames() []string {
 	return nil
 }
 
+func (m *NewOrderRequest) GetReplaces() string {
+	if m != nil && m.Replaces != nil {
+		return *m.Replaces
+	}
+	return ""
+}
+
+func (m *NewOrderRequest) GetExemptions() []string {
+	if m != nil {
+		return m.Exemptions
+	}
+	return nil
+}
+
 type FinalizeOrderRequest struct {
 	Order            *core.Order `protobuf:"bytes,1,opt,name=order" json:"order,omitempty"`
 	Csr              []byte      `protobuf:"bytes,2,opt,name=csr" json:"csr,omitempty"`
@@ -295,6 +323,154 @@ func (m *FinalizeOrderRequest) GetCsr() []byte {
 	return nil
 }
 
+type CheckIssuanceRequest struct {
+	RegistrationID   *int64   `protobuf:"varint,1,opt,name=registrationID" json:"registrationID,omitempty"`
+	Names            []string `protobuf:"bytes,2,rep,name=names" json:"names,omitempty"`
+	Csr              []byte   `protobuf:"bytes,3,opt,name=csr" json:"csr,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *CheckIssuanceRequest) Reset()                    { *m = CheckIssuanceRequest{} }
+func (m *CheckIssuanceRequest) String() string            { return proto1.CompactTextString(m) }
+func (*CheckIssuanceRequest) ProtoMessage()               {}
+func (*CheckIssuanceRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *CheckIssuanceRequest) GetRegistrationID() int64 {
+	if m != nil && m.RegistrationID != nil {
+		return *m.RegistrationID
+	}
+	return 0
+}
+
+func (m *CheckIssuanceRequest) GetNames() []string {
+	if m != nil {
+		return m.Names
+	}
+	return nil
+}
+
+func (m *CheckIssuanceRequest) GetCsr() []byte {
+	if m != nil {
+		return m.Csr
+	}
+	return nil
+}
+
+type CheckIssuanceResponse struct {
+	Problems         []*core.ProblemDetails `protobuf:"bytes,1,rep,name=problems" json:"problems,omitempty"`
+	XXX_unrecognized []byte                 `json:"-"`
+}
+
+func (m *CheckIssuanceResponse) Reset()                    { *m = CheckIssuanceResponse{} }
+func (m *CheckIssuanceResponse) String() string            { return proto1.CompactTextString(m) }
+func (*CheckIssuanceResponse) ProtoMessage()               {}
+func (*CheckIssuanceResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *CheckIssuanceResponse) GetProblems() []*core.ProblemDetails {
+	if m != nil {
+		return m.Problems
+	}
+	return nil
+}
+
+type RateLimitUsageRequest struct {
+	RegistrationID *int64 `protobuf:"varint,1,opt,name=registrationID" json:"registrationID,omitempty"`
+	// names, if set, adds the usage of the limits on issuing a certificate
+	// for them.
+	Names            []string `protobuf:"bytes,2,rep,name=names" json:"names,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *RateLimitUsageRequest) Reset()                    { *m = RateLimitUsageRequest{} }
+func (m *RateLimitUsageRequest) String() string            { return proto1.CompactTextString(m) }
+func (*RateLimitUsageRequest) ProtoMessage()               {}
+func (*RateLimitUsageRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *RateLimitUsageRequest) GetRegistrationID() int64 {
+	if m != nil && m.RegistrationID != nil {
+		return *m.RegistrationID
+	}
+	return 0
+}
+
+func (m *RateLimitUsageRequest) GetNames() []string {
+	if m != nil {
+		return m.Names
+	}
+	return nil
+}
+
+type RateLimitUsage struct {
+	// limit is the name of the rate limit in the policy file.
+	Limit *string `protobuf:"bytes,1,opt,name=limit" json:"limit,omitempty"`
+	// key is what the limit is counted for, e.g. a registered domain, or
+	// empty for limits counted only per account.
+	Key       *string `protobuf:"bytes,2,opt,name=key" json:"key,omitempty"`
+	Count     *int64  `protobuf:"varint,3,opt,name=count" json:"count,omitempty"`
+	Threshold *int64  `protobuf:"varint,4,opt,name=threshold" json:"threshold,omitempty"`
+	// window is in nanoseconds, or zero for limits on what's currently
+	// pending.
+	Window           *int64 `protobuf:"varint,5,opt,name=window" json:"window,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *RateLimitUsage) Reset()                    { *m = RateLimitUsage{} }
+func (m *RateLimitUsage) String() string            { return proto1.CompactTextString(m) }
+func (*RateLimitUsage) ProtoMessage()               {}
+func (*RateLimitUsage) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *RateLimitUsage) GetLimit() string {
+	if m != nil && m.Limit != nil {
+		return *m.Limit
+	}
+	return ""
+}
+
+func (m *RateLimitUsage) GetKey() string {
+	if m != nil && m.Key != nil {
+		return *m.Key
+	}
+	return ""
+}
+
+func (m *RateLimitUsage) GetCount() int64 {
+	if m != nil && m.Count != nil {
+		return *m.Count
+	}
+	return 0
+}
+
+func (m *RateLimitUsage) GetThreshold() int64 {
+	if m != nil && m.Threshold != nil {
+		return *m.Threshold
+	}
+	return 0
+}
+
+func (m *RateLimitUsage) GetWindow() int64 {
+	if m != nil && m.Window != nil {
+		return *m.Window
+	}
+	return 0
+}
+
+type RateLimitUsageResponse struct {
+	Usages           []*RateLimitUsage `protobuf:"bytes,1,rep,name=usages" json:"usages,omitempty"`
+	XXX_unrecognized []byte            `json:"-"`
+}
+
+func (m *RateLimitUsageResponse) Reset()                    { *m = RateLimitUsageResponse{} }
+func (m *RateLimitUsageResponse) String() string            { return proto1.CompactTextString(m) }
+func (*RateLimitUsageResponse) ProtoMessage()               {}
+func (*RateLimitUsageResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *RateLimitUsageResponse) GetUsages() []*RateLimitUsage {
+	if m != nil {
+		return m.Usages
+	}
+	return nil
+}
+
 func init() {
 	proto1.RegisterType((*NewAuthorizationRequest)(nil), "ra.NewAuthorizationRequest")
 	proto1.RegisterType((*NewCertificateRequest)(nil), "ra.NewCertificateRequest")
@@ -305,6 +481,11 @@ func init() {
 	proto1.RegisterType((*AdministrativelyRevokeCertificateRequest)(nil), "ra.AdministrativelyRevokeCertificateRequest")
 	proto1.RegisterType((*NewOrderRequest)(nil), "ra.NewOrderRequest")
 	proto1.RegisterType((*FinalizeOrderRequest)(nil), "ra.FinalizeOrderRequest")
+	proto1.RegisterType((*CheckIssuanceRequest)(nil), "ra.CheckIssuanceRequest")
+	proto1.RegisterType((*CheckIssuanceResponse)(nil), "ra.CheckIssuanceResponse")
+	proto1.RegisterType((*RateLimitUsageRequest)(nil), "ra.RateLimitUsageRequest")
+	proto1.RegisterType((*RateLimitUsage)(nil), "ra.RateLimitUsage")
+	proto1.RegisterType((*RateLimitUsageResponse)(nil), "ra.RateLimitUsageResponse")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -329,6 +510,8 @@ type RegistrationAuthorityClient interface {
 	AdministrativelyRevokeCertificate(ctx context.Context, in *AdministrativelyRevokeCertificateRequest, opts ...grpc.CallOption) (*core.Empty, error)
 	NewOrder(ctx context.Context, in *NewOrderRequest, opts ...grpc.CallOption) (*core.Order, error)
 	FinalizeOrder(ctx context.Context, in *FinalizeOrderRequest, opts ...grpc.CallOption) (*core.Order, error)
+	CheckIssuance(ctx context.Context, in *CheckIssuanceRequest, opts ...grpc.CallOption) (*CheckIssuanceResponse, error)
+	RateLimitUsage(ctx context.Context, in *RateLimitUsageRequest, opts ...grpc.CallOption) (*RateLimitUsageResponse, error)
 }
 
 type registrationAuthorityClient struct {
@@ -438,6 +621,24 @@ func (c *registrationAuthorityClient) FinalizeOrder(ctx context.Context, in *Fin
 	return out, nil
 }
 
+func (c *registrationAuthorityClient) CheckIssuance(ctx context.Context, in *CheckIssuanceRequest, opts ...grpc.CallOption) (*CheckIssuanceResponse, error) {
+	out := new(CheckIssuanceResponse)
+	err := grpc.Invoke(ctx, "/ra.RegistrationAuthority/CheckIssuance", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registrationAuthorityClient) RateLimitUsage(ctx context.Context, in *RateLimitUsageRequest, opts ...grpc.CallOption) (*RateLimitUsageResponse, error) {
+	out := new(RateLimitUsageResponse)
+	err := grpc.Invoke(ctx, "/ra.RegistrationAuthority/RateLimitUsage", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for RegistrationAuthority service
 
 type RegistrationAuthorityServer interface {
@@ -452,6 +653,8 @@ type RegistrationAuthorityServer interface {
 	AdministrativelyRevokeCertificate(context.Context, *AdministrativelyRevokeCertificateRequest) (*core.Empty, error)
 	NewOrder(context.Context, *NewOrderRequest) (*core.Order, error)
 	FinalizeOrder(context.Context, *FinalizeOrderRequest) (*core.Order, error)
+	CheckIssuance(context.Context, *CheckIssuanceRequest) (*CheckIssuanceResponse, error)
+	RateLimitUsage(context.Context, *RateLimitUsageRequest) (*RateLimitUsageResponse, error)
 }
 
 func RegisterRegistrationAuthorityServer(s *grpc.Server, srv RegistrationAuthorityServer) {
@@ -656,6 +859,42 @@ func _RegistrationAuthority_FinalizeOrder_Handler(srv interface{}, ctx context.C
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RegistrationAuthority_CheckIssuance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckIssuanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistrationAuthorityServer).CheckIssuance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ra.RegistrationAuthority/CheckIssuance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistrationAuthorityServer).CheckIssuance(ctx, req.(*CheckIssuanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RegistrationAuthority_RateLimitUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RateLimitUsageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistrationAuthorityServer).RateLimitUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ra.RegistrationAuthority/RateLimitUsage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistrationAuthorityServer).RateLimitUsage(ctx, req.(*RateLimitUsageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _RegistrationAuthority_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "ra.RegistrationAuthority",
 	HandlerType: (*RegistrationAuthorityServer)(nil),
@@ -704,6 +943,14 @@ var _RegistrationAuthority_serviceDesc = grpc.ServiceDesc{
 			MethodName: "FinalizeOrder",
 			Handler:    _RegistrationAuthority_FinalizeOrder_Handler,
 		},
+		{
+			MethodName: "CheckIssuance",
+			Handler:    _RegistrationAuthority_CheckIssuance_Handler,
+		},
+		{
+			MethodName: "RateLimitUsage",
+			Handler:    _RegistrationAuthority_RateLimitUsage_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "ra/proto/ra.proto",
@@ -712,43 +959,57 @@ var _RegistrationAuthority_serviceDesc = grpc.ServiceDesc{
 func init() { proto1.RegisterFile("ra/proto/ra.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 605 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x55, 0xdb, 0x6e, 0xd3, 0x4c,
-	0x10, 0x4e, 0xe2, 0xa6, 0x7f, 0x3b, 0xfd, 0xe9, 0x61, 0xda, 0x52, 0xd7, 0x14, 0xd1, 0x2e, 0x52,
-	0x15, 0x0e, 0x4a, 0xa5, 0x5e, 0x21, 0x55, 0x08, 0x4a, 0x4b, 0x45, 0x84, 0x94, 0x22, 0x4b, 0x80,
-	0xd4, 0x1b, 0x58, 0xec, 0x69, 0x62, 0x11, 0xdb, 0x61, 0xbd, 0x49, 0x48, 0x5e, 0x81, 0x57, 0xe0,
-	0x61, 0x91, 0xd7, 0x9b, 0xc6, 0x76, 0x6c, 0x15, 0x84, 0xb8, 0x9b, 0x9d, 0xc3, 0x37, 0x33, 0xfe,
-	0xe6, 0x93, 0x61, 0x43, 0xf0, 0xa3, 0xbe, 0x08, 0x65, 0x78, 0x24, 0x78, 0x53, 0x19, 0x58, 0x13,
-	0xdc, 0xda, 0x76, 0x42, 0x41, 0x3a, 0x10, 0x9b, 0x49, 0x88, 0x5d, 0xc1, 0x4e, 0x9b, 0x46, 0xa7,
-	0x03, 0xd9, 0x0d, 0x85, 0x37, 0xe1, 0xd2, 0x0b, 0x03, 0x9b, 0xbe, 0x0d, 0x28, 0x92, 0xf8, 0x08,
-	0xea, 0x7c, 0x20, 0xbb, 0x13, 0xb3, 0xba, 0x5f, 0x6d, 0xac, 0x1c, 0x6f, 0x36, 0x55, 0x59, 0x36,
-	0x35, 0xc9, 0xc0, 0x2d, 0xa8, 0x0b, 0xea, 0xb4, 0xce, 0xcd, 0xda, 0x7e, 0xb5, 0x61, 0xd8, 0xc9,
-	0x83, 0xbd, 0x80, 0xed, 0x36, 0x8d, 0xce, 0x48, 0x48, 0xef, 0xda, 0x73, 0xb8, 0xa4, 0x29, 0xf2,
-	0x3a, 0x18, 0x4e, 0x24, 0x14, 0xee, 0xff, 0x76, 0x6c, 0x96, 0x00, 0x84, 0xb0, 0xfb, 0xbe, 0xef,
-	0xaa, 0xc2, 0x8e, 0x17, 0x49, 0x91, 0x19, 0xef, 0x10, 0x16, 0xbe, 0xf0, 0x88, 0xf4, 0x74, 0x98,
-	0x4c, 0x97, 0x49, 0x54, 0x71, 0x7c, 0x0c, 0x8b, 0x03, 0x05, 0xa2, 0xb0, 0x8b, 0x33, 0x75, 0x06,
-	0xfb, 0x59, 0x05, 0x2b, 0xe9, 0xf8, 0xb7, 0x5f, 0xe4, 0x10, 0x56, 0x9d, 0x2e, 0xef, 0xf5, 0x28,
-	0xe8, 0x50, 0x2b, 0x70, 0xe9, 0xbb, 0xde, 0x2c, 0xe7, 0xc5, 0x27, 0xb0, 0x24, 0x28, 0xea, 0x87,
-	0x41, 0x44, 0xa6, 0xa1, 0x50, 0xd7, 0x12, 0xd4, 0xb3, 0x69, 0x9e, 0x7d, 0x93, 0xc0, 0x7c, 0x30,
-	0xdf, 0x91, 0xb8, 0x0e, 0x85, 0xff, 0x81, 0xf7, 0x3c, 0xf7, 0x1f, 0xcf, 0xc6, 0x3e, 0xc1, 0x03,
-	0x9b, 0x86, 0xe1, 0x57, 0x4a, 0x51, 0xf8, 0xd1, 0x93, 0x5d, 0x9b, 0x3a, 0xd3, 0xae, 0x08, 0x0b,
-	0x0e, 0x09, 0xa9, 0xa9, 0x54, 0xb6, 0xf2, 0x85, 0x2e, 0x69, 0x50, 0x65, 0xcf, 0xf8, 0x35, 0xd2,
-	0xfc, 0xf6, 0xa1, 0x71, 0xea, 0xfa, 0x5e, 0xa0, 0x89, 0x18, 0x52, 0x6f, 0x3c, 0xd7, 0xf0, 0x4f,
-	0x3b, 0xed, 0xc1, 0x32, 0x8f, 0x31, 0xdb, 0xdc, 0x4f, 0xbe, 0xe8, 0xb2, 0x3d, 0x73, 0xb0, 0x4b,
-	0x58, 0x6b, 0xd3, 0xe8, 0x52, 0xb8, 0x24, 0x66, 0x77, 0xb4, 0x2a, 0x52, 0xb7, 0xd0, 0x3a, 0x57,
-	0x2d, 0x0c, 0x3b, 0xe7, 0x8d, 0x57, 0x08, 0xb8, 0x4f, 0x91, 0x59, 0xdb, 0x37, 0x1a, 0xcb, 0x76,
-	0xf2, 0x60, 0x6f, 0x61, 0xeb, 0xc2, 0x0b, 0x78, 0xcf, 0x9b, 0x50, 0x06, 0xf5, 0x00, 0xea, 0x61,
-	0xfc, 0xd6, 0x74, 0xac, 0x24, 0x74, 0x24, 0x29, 0x49, 0x64, 0xaa, 0x82, 0xda, 0x8d, 0x0a, 0x8e,
-	0x7f, 0x2c, 0xc2, 0x76, 0xfa, 0x2e, 0x35, 0x7b, 0x72, 0x8c, 0x27, 0x6a, 0xee, 0x74, 0x0c, 0x0b,
-	0xee, 0xd8, 0x2a, 0xf0, 0xb1, 0x0a, 0x5e, 0xc0, 0x7a, 0x5e, 0xe3, 0x78, 0xaf, 0x29, 0x78, 0xb3,
-	0x44, 0xf9, 0x56, 0xd1, 0xf1, 0xb0, 0x0a, 0xbe, 0x84, 0xd5, 0xac, 0x9e, 0x71, 0x57, 0xa3, 0xcc,
-	0xf3, 0x65, 0x6d, 0xe8, 0x33, 0x9e, 0x45, 0x58, 0x05, 0x5b, 0x80, 0xf3, 0x82, 0xc6, 0xfb, 0x31,
-	0x4a, 0xa9, 0xd0, 0x4b, 0x96, 0x7a, 0x03, 0x1b, 0x73, 0x5a, 0xc0, 0xbd, 0x18, 0xa9, 0x4c, 0x22,
-	0x65, 0x6b, 0xb5, 0xc1, 0x2c, 0x3b, 0x73, 0x7c, 0x18, 0x03, 0xde, 0x22, 0x02, 0x4b, 0x93, 0xfb,
-	0xda, 0xef, 0xcb, 0x31, 0xab, 0xe0, 0x09, 0xdc, 0x3d, 0x27, 0xee, 0x48, 0x6f, 0x98, 0x5f, 0xb4,
-	0x88, 0xb2, 0x5c, 0xf1, 0x73, 0xd8, 0x99, 0x15, 0x67, 0x29, 0x2b, 0x1a, 0x3f, 0x5f, 0xfe, 0x19,
-	0x0e, 0x6e, 0x55, 0x14, 0x3e, 0x8d, 0x97, 0xfa, 0x5d, 0xe1, 0xe5, 0x3b, 0x34, 0x61, 0x69, 0xaa,
-	0x20, 0xdc, 0xd4, 0xf4, 0xa7, 0x2f, 0xdf, 0x4a, 0x9f, 0x3a, 0xab, 0xe0, 0x33, 0xb8, 0x93, 0x11,
-	0x08, 0x9a, 0x71, 0x51, 0x91, 0x66, 0x72, 0x95, 0xaf, 0xfe, 0xbb, 0xaa, 0xab, 0x7f, 0xd4, 0xaf,
-	0x00, 0x00, 0x00, 0xff, 0xff, 0x3a, 0x20, 0x77, 0x28, 0xd2, 0x06, 0x00, 0x00,
+	// 819 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x56, 0x5b, 0x8f, 0xdb, 0x44,
+	0x14, 0x4e, 0xe2, 0x4d, 0xbb, 0x39, 0x4b, 0xb7, 0xdd, 0xe9, 0x66, 0xeb, 0x98, 0x02, 0xe9, 0x20,
+	0x55, 0xe1, 0xa2, 0x14, 0xed, 0x13, 0x52, 0x85, 0xa0, 0x6c, 0x58, 0x11, 0x81, 0x42, 0x65, 0x69,
+	0x41, 0xea, 0x0b, 0x4c, 0xed, 0xb3, 0x89, 0xb5, 0xbe, 0x31, 0x9e, 0x6c, 0x9a, 0x7d, 0xe6, 0x8d,
+	0xbf, 0x80, 0xf8, 0xad, 0x68, 0x2e, 0x4e, 0x6c, 0xc7, 0xe6, 0x22, 0xb6, 0x6f, 0x73, 0x6e, 0xdf,
+	0x39, 0x67, 0xce, 0x99, 0xcf, 0x86, 0x23, 0xce, 0x9e, 0xa5, 0x3c, 0x11, 0xc9, 0x33, 0xce, 0xc6,
+	0xea, 0x40, 0x3a, 0x9c, 0x39, 0x7d, 0x2f, 0xe1, 0x68, 0x0c, 0xf2, 0xa8, 0x4d, 0xf4, 0x15, 0x3c,
+	0x9a, 0xe1, 0xea, 0xc5, 0x52, 0x2c, 0x12, 0x1e, 0xdc, 0x30, 0x11, 0x24, 0xb1, 0x8b, 0xbf, 0x2e,
+	0x31, 0x13, 0xe4, 0x23, 0xe8, 0xb2, 0xa5, 0x58, 0xdc, 0xd8, 0xed, 0x61, 0x7b, 0x74, 0x70, 0xfa,
+	0x70, 0xac, 0xc2, 0xca, 0xae, 0xda, 0x83, 0x1c, 0x43, 0x97, 0xe3, 0x7c, 0x3a, 0xb1, 0x3b, 0xc3,
+	0xf6, 0xc8, 0x72, 0xb5, 0x40, 0xbf, 0x84, 0xfe, 0x0c, 0x57, 0x67, 0xc8, 0x45, 0x70, 0x19, 0x78,
+	0x4c, 0x60, 0x8e, 0xfc, 0x00, 0x2c, 0x2f, 0xe3, 0x0a, 0xf7, 0x1d, 0x57, 0x1e, 0x1b, 0x00, 0x12,
+	0x18, 0x5c, 0xa4, 0xbe, 0x0a, 0x9c, 0x07, 0x99, 0xe0, 0xa5, 0xf2, 0x9e, 0xc2, 0xde, 0x6b, 0x96,
+	0xa1, 0xa9, 0x8e, 0xe8, 0xea, 0x4a, 0x8e, 0xca, 0x4e, 0x3e, 0x86, 0x3b, 0x4b, 0x05, 0x62, 0x77,
+	0x1a, 0x3d, 0x8d, 0x07, 0xfd, 0xa3, 0x0d, 0x8e, 0xce, 0xf8, 0x7f, 0x6f, 0xe4, 0x29, 0x1c, 0x7a,
+	0x0b, 0x16, 0x86, 0x18, 0xcf, 0x71, 0x1a, 0xfb, 0xf8, 0xc6, 0x74, 0x56, 0xd1, 0x92, 0x4f, 0x60,
+	0x9f, 0x63, 0x96, 0x26, 0x71, 0x86, 0xb6, 0xa5, 0x50, 0xef, 0x6b, 0xd4, 0xb3, 0xdc, 0xcf, 0xdd,
+	0x38, 0xd0, 0x08, 0xec, 0x97, 0xc8, 0x2f, 0x13, 0x1e, 0xfd, 0xc8, 0xc2, 0xc0, 0x7f, 0xcb, 0xb5,
+	0xd1, 0x9f, 0xe1, 0x03, 0x17, 0xaf, 0x93, 0x2b, 0x2c, 0x8c, 0xf0, 0xa7, 0x40, 0x2c, 0x5c, 0x9c,
+	0xe7, 0x59, 0x09, 0xec, 0x79, 0xc8, 0x85, 0x19, 0xa5, 0x3a, 0x2b, 0x5d, 0xe2, 0xa3, 0x01, 0x55,
+	0xe7, 0xed, 0x7c, 0xad, 0xe2, 0x7c, 0x53, 0x18, 0xbd, 0xf0, 0xa3, 0x20, 0x36, 0x83, 0xb8, 0xc6,
+	0x70, 0xbd, 0x93, 0xf0, 0xbf, 0x66, 0x7a, 0x0c, 0x3d, 0x26, 0x31, 0x67, 0x2c, 0xd2, 0x37, 0xda,
+	0x73, 0xb7, 0x0a, 0xfa, 0x7b, 0x1b, 0xee, 0xcf, 0x70, 0xf5, 0x03, 0xf7, 0x91, 0x6f, 0x17, 0xe9,
+	0x90, 0x17, 0x96, 0x61, 0x3a, 0x51, 0x39, 0x2c, 0xb7, 0xa2, 0x95, 0x3d, 0xc4, 0x2c, 0xc2, 0xcc,
+	0xee, 0x0c, 0xad, 0x51, 0xcf, 0xd5, 0x02, 0x71, 0xe4, 0x00, 0xd3, 0x90, 0x79, 0x98, 0x99, 0x74,
+	0x1b, 0x99, 0xbc, 0x0f, 0x80, 0x6f, 0x30, 0x4a, 0x25, 0x40, 0x66, 0xef, 0xa9, 0xb0, 0x82, 0x86,
+	0x7e, 0x07, 0xc7, 0xe7, 0x41, 0xcc, 0xc2, 0xe0, 0x06, 0x4b, 0x15, 0x3d, 0x81, 0x6e, 0x22, 0x65,
+	0x33, 0xcb, 0x03, 0x3d, 0x4b, 0xed, 0xa2, 0x2d, 0xf9, 0x13, 0xea, 0x6c, 0x9e, 0x10, 0xbd, 0x84,
+	0xe3, 0xb3, 0x05, 0x7a, 0x57, 0xd3, 0x2c, 0x5b, 0xb2, 0xd8, 0xc3, 0xdb, 0x69, 0xcf, 0xe4, 0xb1,
+	0xb6, 0x79, 0xa6, 0xd0, 0xaf, 0xe4, 0xd1, 0xdb, 0x49, 0x3e, 0x83, 0xfd, 0x94, 0x27, 0xaf, 0x43,
+	0x8c, 0x32, 0xbb, 0x3d, 0xb4, 0x46, 0x07, 0xa7, 0xc7, 0xba, 0xf0, 0x97, 0x5a, 0x3b, 0x41, 0xc1,
+	0x82, 0x30, 0x73, 0x37, 0x5e, 0xf4, 0x02, 0xfa, 0x2e, 0x13, 0xf8, 0x7d, 0x10, 0x05, 0xe2, 0x22,
+	0x63, 0xf3, 0xdb, 0xa9, 0x99, 0xfe, 0xd6, 0x86, 0xc3, 0x32, 0xae, 0x74, 0x0c, 0xa5, 0xa4, 0x70,
+	0x7a, 0xae, 0x16, 0x64, 0x73, 0x57, 0xb8, 0x56, 0x97, 0xd8, 0x73, 0xe5, 0x51, 0xfa, 0x79, 0xc9,
+	0x32, 0x16, 0xf9, 0x9e, 0x2a, 0x41, 0xee, 0x94, 0x58, 0x70, 0xcc, 0x16, 0x49, 0xe8, 0xdb, 0x7b,
+	0xca, 0xb2, 0x55, 0x90, 0x13, 0xb8, 0xb3, 0x0a, 0x62, 0x3f, 0x59, 0xd9, 0x5d, 0x65, 0x32, 0x12,
+	0x9d, 0xc0, 0x49, 0xb5, 0x3b, 0x73, 0x53, 0x92, 0x92, 0xa4, 0x22, 0xbf, 0x27, 0x32, 0xe6, 0x6c,
+	0x5c, 0xf1, 0x35, 0x1e, 0xa7, 0x7f, 0xde, 0x85, 0x7e, 0x91, 0xab, 0xcc, 0x8b, 0x16, 0x6b, 0xf2,
+	0x5c, 0xad, 0x72, 0xd1, 0x46, 0x6a, 0xb8, 0xcd, 0xa9, 0xd1, 0xd1, 0x16, 0x39, 0x87, 0x07, 0x55,
+	0xde, 0x27, 0xef, 0xca, 0x32, 0x1a, 0xbe, 0x06, 0x4e, 0x1d, 0xa1, 0xd0, 0x16, 0xf9, 0x0a, 0x0e,
+	0xcb, 0x1c, 0x4f, 0x06, 0x06, 0x65, 0xf7, 0x0d, 0x3b, 0x47, 0x86, 0xda, 0xb6, 0x16, 0xda, 0x22,
+	0x53, 0x20, 0xbb, 0x24, 0x4f, 0xde, 0x93, 0x28, 0x8d, 0xe4, 0xdf, 0xd0, 0xd4, 0xb7, 0x70, 0xb4,
+	0xc3, 0x8f, 0xe4, 0xb1, 0x44, 0x6a, 0xa2, 0xcd, 0xa6, 0xb6, 0x66, 0x60, 0x37, 0x51, 0x1f, 0xf9,
+	0x50, 0x4d, 0xeb, 0xef, 0x89, 0xd1, 0x31, 0x6f, 0xf6, 0x9b, 0x28, 0x15, 0x6b, 0xda, 0x22, 0xcf,
+	0xe1, 0x64, 0x82, 0xcc, 0x13, 0xc1, 0x75, 0xb5, 0xd1, 0xba, 0x91, 0x55, 0x82, 0xbf, 0x80, 0x47,
+	0xdb, 0xe0, 0xf2, 0xc8, 0xea, 0xca, 0xaf, 0x86, 0xff, 0x02, 0x4f, 0xfe, 0x91, 0x65, 0xc9, 0xa7,
+	0xb2, 0xa9, 0x7f, 0x4b, 0xc6, 0xd5, 0x0c, 0x63, 0xd8, 0xcf, 0x49, 0x95, 0x3c, 0x34, 0xe3, 0x2f,
+	0x12, 0x9a, 0x53, 0x64, 0x30, 0xda, 0x22, 0x9f, 0xc3, 0xbd, 0x12, 0xef, 0x11, 0x5b, 0x06, 0xd5,
+	0x51, 0x61, 0x35, 0xf2, 0x1c, 0xee, 0x95, 0xc8, 0x47, 0x47, 0xd6, 0xf1, 0x9e, 0x33, 0xa8, 0xb1,
+	0x98, 0xef, 0xa8, 0x5c, 0xba, 0x2a, 0x43, 0x0c, 0x6a, 0xde, 0xa0, 0x41, 0x72, 0xea, 0x4c, 0x39,
+	0xd4, 0xd7, 0x77, 0x5f, 0x75, 0xd5, 0xaf, 0xd4, 0x5f, 0x03, 0x00, 0x9b, 0xac, 0xf2, 0x26, 0x79,
+	0x09, 0x00, 0x00,
 }
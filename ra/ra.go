@@ -1,6 +1,8 @@
 package ra
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/json"
 	"fmt"
@@ -9,6 +11,7 @@ import (
 	"net/url"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,8 +28,10 @@ import (
 	"github.com/letsencrypt/boulder/goodkey"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
 	"github.com/letsencrypt/boulder/iana"
+	"github.com/letsencrypt/boulder/killswitch"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/policy"
 	"github.com/letsencrypt/boulder/probs"
 	rapb "github.com/letsencrypt/boulder/ra/proto"
 	"github.com/letsencrypt/boulder/ratelimit"
@@ -39,6 +44,7 @@ import (
 	"github.com/weppos/publicsuffix-go/publicsuffix"
 	"golang.org/x/net/context"
 	grpc "google.golang.org/grpc"
+	jose "gopkg.in/square/go-jose.v2"
 )
 
 type caaChecker interface {
@@ -70,23 +76,58 @@ type RegistrationAuthorityImpl struct {
 	pendingAuthorizationLifetime time.Duration
 	rlPolicies                   ratelimit.Limits
 	maxContactsPerReg            int
-	maxNames                     int
+	nameLimits                   csrlib.NameLimits
 	forceCNFromSAN               bool
 	reuseValidAuthz              bool
 	orderLifetime                time.Duration
+	// challengeRetries is how many times a failed challenge may be retried,
+	// reopening its invalid authorization. See SetChallengeRetries.
+	challengeRetries int
 
 	issuer *x509.Certificate
 	purger akamaipb.AkamaiPurgerClient
 
+	// KillSwitch, if set, stops the RA from requesting new certificates from
+	// the CA while it is stopped.
+	KillSwitch *killswitch.Switch
+	// ChallengeKillSwitch, if set, stops the RA from starting the validation
+	// of disabled challenge types.
+	ChallengeKillSwitch *killswitch.Challenges
+
 	regByIPStats           metrics.Scope
 	regByIPRangeStats      metrics.Scope
 	pendAuthByRegIDStats   metrics.Scope
+	invalidAuthzStats      metrics.Scope
 	pendOrdersByRegIDStats metrics.Scope
 	newOrderByRegIDStats   metrics.Scope
 	certsForDomainStats    metrics.Scope
+	certsForFQDNSetStats   metrics.Scope
 
 	ctpolicy        *ctpolicy.CTPolicy
 	ctpolicyResults *prometheus.HistogramVec
+	// replacementOrders counts new orders that replace a certificate, by why
+	// it's replaced
+	replacementOrders *prometheus.CounterVec
+
+	accountMetrics *accountMetrics
+	// reputation, if set, restricts the validations of accounts whose
+	// validations mostly fail. See SetAccountReputation.
+	reputation *accountReputation
+	// issuanceQueue, if set, limits how many certificates are being signed
+	// at once. See SetIssuanceQueue.
+	issuanceQueue *issuanceQueue
+	// contactBlocklist, if set, holds the email domains that can't be used
+	// as account contacts. See SetBlockedContactDomainsFile.
+	contactBlocklist *contactBlocklist
+	// exemptionKey, if set, is the key policy exemption tokens are signed
+	// with. See SetPolicyExemptionKey.
+	exemptionKey []byte
+	// countryPolicy, if set, logs or rejects names and validation targets in
+	// denied countries. See SetCountryPolicyFile.
+	countryPolicy *countryPolicy
+	// dns01Rechecks, if set, rechecks failed DNS-01 validations. See
+	// SetDNS01Rechecks.
+	dns01Rechecks *DNS01RecheckConfig
 }
 
 // NewRegistrationAuthorityImpl constructs a new RA object.
@@ -96,7 +137,7 @@ func NewRegistrationAuthorityImpl(
 	stats metrics.Scope,
 	maxContactsPerReg int,
 	keyPolicy goodkey.KeyPolicy,
-	maxNames int,
+	nameLimits csrlib.NameLimits,
 	forceCNFromSAN bool,
 	reuseValidAuthz bool,
 	authorizationLifetime time.Duration,
@@ -118,6 +159,12 @@ func NewRegistrationAuthorityImpl(
 	)
 	stats.MustRegister(ctpolicyResults)
 
+	replacementOrders := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "replacement_orders",
+		Help: "Number of new orders that replace a certificate, by why it's replaced (renewal, early or revoked)",
+	}, []string{"reason"})
+	stats.MustRegister(replacementOrders)
+
 	ra := &RegistrationAuthorityImpl{
 		stats: stats,
 		clk:   clk,
@@ -127,37 +174,121 @@ func NewRegistrationAuthorityImpl(
 		rlPolicies:                   ratelimit.New(),
 		maxContactsPerReg:            maxContactsPerReg,
 		keyPolicy:                    keyPolicy,
-		maxNames:                     maxNames,
+		nameLimits:                   nameLimits,
 		forceCNFromSAN:               forceCNFromSAN,
 		reuseValidAuthz:              reuseValidAuthz,
 		regByIPStats:                 stats.NewScope("RateLimit", "RegistrationsByIP"),
 		regByIPRangeStats:            stats.NewScope("RateLimit", "RegistrationsByIPRange"),
 		pendAuthByRegIDStats:         stats.NewScope("RateLimit", "PendingAuthorizationsByRegID"),
+		invalidAuthzStats:            stats.NewScope("RateLimit", "InvalidAuthorizationsByRegID"),
 		pendOrdersByRegIDStats:       stats.NewScope("RateLimit", "PendingOrdersByRegID"),
 		newOrderByRegIDStats:         stats.NewScope("RateLimit", "NewOrdersByRegID"),
 		certsForDomainStats:          stats.NewScope("RateLimit", "CertificatesForDomain"),
+		certsForFQDNSetStats:         stats.NewScope("RateLimit", "CertificatesForFQDNSet"),
 		publisher:                    pubc,
 		caa:                          caaClient,
 		orderLifetime:                orderLifetime,
 		ctpolicy:                     ctp,
 		ctpolicyResults:              ctpolicyResults,
+		replacementOrders:            replacementOrders,
 		purger:                       purger,
 		issuer:                       issuer,
+		accountMetrics:               newAccountMetrics(clk, stats),
 	}
 	return ra
 }
 
 func (ra *RegistrationAuthorityImpl) SetRateLimitPoliciesFile(filename string) error {
-	_, err := reloader.New(filename, ra.rlPolicies.LoadPolicies, ra.rateLimitPoliciesLoadError)
+	return reloader.Register(reloader.Section{
+		Name: "rate limit policies",
+		File: filename,
+		Load: ra.rlPolicies.LoadPolicies,
+	})
+}
+
+// UpdateRateLimitOverrides fetches the currently active rate limit overrides
+// from the SA and loads them into the RA's rate limit policies, replacing any
+// previously loaded overrides.
+func (ra *RegistrationAuthorityImpl) UpdateRateLimitOverrides(ctx context.Context) error {
+	now := ra.clk.Now().UnixNano()
+	resp, err := ra.SA.GetRateLimitOverrides(ctx, &sapb.GetRateLimitOverridesRequest{Now: &now})
 	if err != nil {
 		return err
 	}
+	overrides := make([]ratelimit.Override, 0, len(resp.Overrides))
+	for _, o := range resp.Overrides {
+		overrides = append(overrides, ratelimit.Override{
+			LimitName:      *o.LimitName,
+			Key:            *o.Key,
+			RegistrationID: *o.RegistrationID,
+			Threshold:      int(*o.Threshold),
+		})
+	}
+	ra.rlPolicies.LoadOverrides(overrides)
+	return nil
+}
+
+// UpdateRateLimitOverridesLoop calls UpdateRateLimitOverrides once per
+// interval, forever. Errors are logged and the previously loaded overrides
+// stay in effect until the next successful update.
+func (ra *RegistrationAuthorityImpl) UpdateRateLimitOverridesLoop(interval time.Duration) {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		if err := ra.UpdateRateLimitOverrides(ctx); err != nil {
+			ra.log.Errf("error updating rate limit overrides: %s", err)
+		}
+		cancel()
+		ra.clk.Sleep(interval)
+	}
+}
+
+// challengesWhitelistEnroller is implemented by policy authorities that can
+// enable challenge types for the accounts enrolled in the SA's challenge
+// whitelist.
+type challengesWhitelistEnroller interface {
+	SetChallengesWhitelistEnrollments([]policy.ChallengeWhitelistEntry)
+}
 
+// UpdateChallengeWhitelist fetches the unexpired challenge whitelist entries
+// from the SA and loads them into the RA's policy authority, replacing any
+// previously loaded entries.
+func (ra *RegistrationAuthorityImpl) UpdateChallengeWhitelist(ctx context.Context) error {
+	pa, ok := ra.PA.(challengesWhitelistEnroller)
+	if !ok {
+		return fmt.Errorf("policy authority %T doesn't support challenge whitelist enrollments", ra.PA)
+	}
+	now := ra.clk.Now().UnixNano()
+	resp, err := ra.SA.GetChallengeWhitelist(ctx, &sapb.GetChallengeWhitelistRequest{Now: &now})
+	if err != nil {
+		return err
+	}
+	entries := make([]policy.ChallengeWhitelistEntry, 0, len(resp.Entries))
+	for _, e := range resp.Entries {
+		entry := policy.ChallengeWhitelistEntry{
+			ChallengeType:  *e.ChallengeType,
+			RegistrationID: *e.RegistrationID,
+		}
+		if e.Expires != nil && *e.Expires != 0 {
+			entry.Expires = time.Unix(0, *e.Expires)
+		}
+		entries = append(entries, entry)
+	}
+	pa.SetChallengesWhitelistEnrollments(entries)
 	return nil
 }
 
-func (ra *RegistrationAuthorityImpl) rateLimitPoliciesLoadError(err error) {
-	ra.log.Errf("error reloading rate limit policy: %s", err)
+// UpdateChallengeWhitelistLoop calls UpdateChallengeWhitelist once per
+// interval, forever. Errors are logged and the previously loaded entries stay
+// in effect until the next successful update.
+func (ra *RegistrationAuthorityImpl) UpdateChallengeWhitelistLoop(interval time.Duration) {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		if err := ra.UpdateChallengeWhitelist(ctx); err != nil {
+			ra.log.Errf("error updating challenge whitelist: %s", err)
+		}
+		cancel()
+		ra.clk.Sleep(interval)
+	}
 }
 
 // certificateRequestAuthz is a struct for holding information about a valid
@@ -197,6 +328,11 @@ type certificateRequestEvent struct {
 	ResponseTime time.Time `json:",omitempty"`
 	// Error contains any encountered errors
 	Error string `json:",omitempty"`
+	// Replaces is the serial of the certificate this one replaces, if any
+	Replaces string `json:",omitempty"`
+	// Exemptions are the policy exemption tokens the certificate is issued
+	// with, if any
+	Exemptions []string `json:",omitempty"`
 	// Authorizations is a map of identifier names to certificateRequestAuthz
 	// objects. It can be used to understand how the names in a certificate
 	// request were authorized.
@@ -238,19 +374,44 @@ func (ra *RegistrationAuthorityImpl) checkRegistrationIPLimit(
 	return nil
 }
 
+// rateLimitExceeded records that limit was exceeded in stats and returns err.
+// If the limit is in log only mode the request is allowed to proceed: the
+// "LogOnlyExceeded" stat is incremented instead and nil is returned. This lets
+// new or tightened limits be evaluated against real traffic before they are
+// enforced.
+func (ra *RegistrationAuthorityImpl) rateLimitExceeded(limit ratelimit.RateLimitPolicy, stats metrics.Scope, err error) error {
+	if limit.LogOnly {
+		stats.Inc("LogOnlyExceeded", 1)
+		ra.log.Infof("Rate limit is log only, not enforcing: %s", err)
+		return nil
+	}
+	stats.Inc("Exceeded", 1)
+	return err
+}
+
 // checkRegistrationLimits enforces the RegistrationsPerIP and
-// RegistrationsPerIPRange limits
+// RegistrationsPerIPRange limits, unless the IP is exempt from both
 func (ra *RegistrationAuthorityImpl) checkRegistrationLimits(ctx context.Context, ip net.IP) error {
+	if ra.rlPolicies.RegistrationIPExempt(ip) {
+		ra.regByIPStats.Inc("Exempt", 1)
+		return nil
+	}
+
 	// Check the registrations per IP limit using the CountRegistrationsByIP SA
 	// function that matches IP addresses exactly
 	exactRegLimit := ra.rlPolicies.RegistrationsPerIP()
 	err := ra.checkRegistrationIPLimit(ctx, exactRegLimit, ip, ra.SA.CountRegistrationsByIP)
-	if err != nil {
-		ra.regByIPStats.Inc("Exceeded", 1)
+	switch {
+	case berrors.Is(err, berrors.RateLimit):
 		ra.log.Infof("Rate limit exceeded, RegistrationsByIP, IP: %s", ip)
+		if err := ra.rateLimitExceeded(exactRegLimit, ra.regByIPStats, err); err != nil {
+			return err
+		}
+	case err != nil:
 		return err
+	default:
+		ra.regByIPStats.Inc("Pass", 1)
 	}
-	ra.regByIPStats.Inc("Pass", 1)
 
 	// We only apply the fuzzy reg limit to IPv6 addresses.
 	// Per https://golang.org/pkg/net/#IP.To4 "If ip is not an IPv4 address, To4
@@ -264,12 +425,15 @@ func (ra *RegistrationAuthorityImpl) checkRegistrationLimits(ctx context.Context
 	// within a larger address range
 	fuzzyRegLimit := ra.rlPolicies.RegistrationsPerIPRange()
 	err = ra.checkRegistrationIPLimit(ctx, fuzzyRegLimit, ip, ra.SA.CountRegistrationsByIPRange)
-	if err != nil {
-		ra.regByIPRangeStats.Inc("Exceeded", 1)
+	if berrors.Is(err, berrors.RateLimit) {
 		ra.log.Infof("Rate limit exceeded, RegistrationsByIPRange, IP: %s", ip)
 		// For the fuzzyRegLimit we use a new error message that specifically
 		// mentions that the limit being exceeded is applied to a *range* of IPs
-		return berrors.RateLimitError("too many registrations for this IP range")
+		return ra.rateLimitExceeded(fuzzyRegLimit, ra.regByIPRangeStats,
+			berrors.RateLimitError("too many registrations for this IP range"))
+	}
+	if err != nil {
+		return err
 	}
 	ra.regByIPRangeStats.Inc("Pass", 1)
 
@@ -279,7 +443,7 @@ func (ra *RegistrationAuthorityImpl) checkRegistrationLimits(ctx context.Context
 // NewRegistration constructs a new Registration from a request.
 func (ra *RegistrationAuthorityImpl) NewRegistration(ctx context.Context, init core.Registration) (core.Registration, error) {
 	if err := ra.keyPolicy.GoodKey(init.Key.Key); err != nil {
-		return core.Registration{}, berrors.MalformedError("invalid public key: %s", err.Error())
+		return core.Registration{}, berrors.BadPublicKeyError("invalid public key: %s", err.Error())
 	}
 	if err := ra.checkRegistrationLimits(ctx, init.InitialIP); err != nil {
 		return core.Registration{}, err
@@ -338,6 +502,12 @@ func (ra *RegistrationAuthorityImpl) validateContacts(ctx context.Context, conta
 		if err != nil {
 			return berrors.MalformedError("invalid contact")
 		}
+		if parsed.Scheme == "https" && features.Enabled(features.WebhookContacts) {
+			if err := validateWebhookContact(parsed); err != nil {
+				return err
+			}
+			continue
+		}
 		if parsed.Scheme != "mailto" {
 			return berrors.MalformedError("contact method %s is not supported", parsed.Scheme)
 		}
@@ -350,6 +520,9 @@ func (ra *RegistrationAuthorityImpl) validateContacts(ctx context.Context, conta
 		if err := validateEmail(parsed.Opaque); err != nil {
 			return err
 		}
+		if err := ra.contactBlocklist.checkEmail(parsed.Opaque); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -394,6 +567,33 @@ func validateEmail(address string) error {
 	return nil
 }
 
+// validateWebhookContact returns an error if the given https contact URL isn't
+// suitable for webhook delivery. Only URLs naming a host by a public DNS name
+// are allowed. The name can still resolve to an internal address, so the
+// expiration-mailer also refuses to connect to those.
+func validateWebhookContact(contact *url.URL) error {
+	if contact.User != nil {
+		return berrors.MalformedError("webhook contact must not contain credentials")
+	}
+	if contact.Fragment != "" {
+		return berrors.MalformedError("webhook contact must not contain a fragment")
+	}
+	if contact.Port() != "" && contact.Port() != "443" {
+		return berrors.MalformedError("webhook contact must use port 443")
+	}
+	hostname := contact.Hostname()
+	if hostname == "" || net.ParseIP(hostname) != nil {
+		return berrors.MalformedError("webhook contact must name a host by DNS name")
+	}
+	if !core.IsASCII(contact.String()) {
+		return berrors.MalformedError("webhook contact contains non-ASCII characters")
+	}
+	if _, err := iana.ExtractSuffix(strings.ToLower(hostname)); err != nil {
+		return berrors.MalformedError("webhook contact host name does not end in a IANA suffix")
+	}
+	return nil
+}
+
 func (ra *RegistrationAuthorityImpl) checkPendingAuthorizationLimit(ctx context.Context, regID int64) error {
 	limit := ra.rlPolicies.PendingAuthorizationsPerAccount()
 	if limit.Enabled() {
@@ -405,9 +605,9 @@ func (ra *RegistrationAuthorityImpl) checkPendingAuthorizationLimit(ctx context.
 		// here.
 		noKey := ""
 		if count >= limit.GetThreshold(noKey, regID) {
-			ra.pendAuthByRegIDStats.Inc("Exceeded", 1)
 			ra.log.Infof("Rate limit exceeded, PendingAuthorizationsByRegID, regID: %d", regID)
-			return berrors.RateLimitError("too many currently pending authorizations")
+			return ra.rateLimitExceeded(limit, ra.pendAuthByRegIDStats,
+				berrors.RateLimitError("too many currently pending authorizations"))
 		}
 		ra.pendAuthByRegIDStats.Inc("Pass", 1)
 	}
@@ -445,9 +645,16 @@ func (ra *RegistrationAuthorityImpl) checkInvalidAuthorizationLimit(ctx context.
 	// here.
 	noKey := ""
 	if *count.Count >= int64(limit.GetThreshold(noKey, regID)) {
-		ra.log.Infof("Rate limit exceeded, InvalidAuthorizationsByRegID, regID: %d", regID)
-		return berrors.RateLimitError("too many failed authorizations recently")
-	}
+		ra.log.Infof("Rate limit exceeded, InvalidAuthorizationsByRegID, regID: %d, hostname: %s", regID, hostname)
+		// We don't know exactly when the oldest failure in the window will age
+		// out, so suggest waiting the full window. That is conservative but
+		// guaranteed to be long enough.
+		return ra.rateLimitExceeded(limit, ra.invalidAuthzStats,
+			berrors.RateLimitErrorWithRetryAfter(
+				limit.Window.Duration,
+				"too many failed authorizations recently"))
+	}
+	ra.invalidAuthzStats.Inc("Pass", 1)
 	return nil
 }
 
@@ -468,24 +675,33 @@ func (ra *RegistrationAuthorityImpl) checkNewOrdersPerAccountLimit(ctx context.C
 	// There is no meaningful override key to use for this rate limit
 	noKey := ""
 	if count >= limit.GetThreshold(noKey, acctID) {
-		ra.newOrderByRegIDStats.Inc("Exceeded", 1)
-		return berrors.RateLimitError("too many new orders recently")
+		ra.log.Infof("Rate limit exceeded, NewOrdersByRegID, regID: %d", acctID)
+		return ra.rateLimitExceeded(limit, ra.newOrderByRegIDStats,
+			berrors.RateLimitError("too many new orders recently"))
 	}
 	ra.newOrderByRegIDStats.Inc("Pass", 1)
 	return nil
 }
 
 // NewAuthorization constructs a new Authz from a request. Values (domains) in
-// request.Identifier will be lowercased before storage.
+// request.Identifier will be normalized (see core.NormalizeName) before storage.
 func (ra *RegistrationAuthorityImpl) NewAuthorization(ctx context.Context, request core.Authorization, regID int64) (core.Authorization, error) {
 	identifier := request.Identifier
-	identifier.Value = strings.ToLower(identifier.Value)
+	value, err := core.NormalizeName(identifier.Value)
+	if err != nil {
+		return core.Authorization{}, err
+	}
+	identifier.Value = value
 
 	// Check that the identifier is present and appropriate
 	if err := ra.PA.WillingToIssue(identifier); err != nil {
 		return core.Authorization{}, err
 	}
 
+	if err := ra.checkCountryPolicyName(identifier.Value, regID); err != nil {
+		return core.Authorization{}, err
+	}
+
 	if err := ra.checkPendingAuthorizationLimit(ctx, regID); err != nil {
 		return core.Authorization{}, err
 	}
@@ -751,14 +967,16 @@ func (ra *RegistrationAuthorityImpl) recheckCAA(ctx context.Context, authzs []*c
 		go func(authz *core.Authorization) {
 			name := authz.Identifier.Value
 
-			// If an authorization has multiple valid challenges,
-			// the type of the first valid challenge is used for
-			// the purposes of CAA rechecking.
-			var method string
-			for _, challenge := range authz.Challenges {
-				if challenge.Status == core.StatusValid {
-					method = challenge.Type
-					break
+			// Use the validation method recorded when the authorization
+			// was validated. Authorizations validated before it was recorded
+			// fall back to the type of their first valid challenge.
+			method := authz.ValidationMethod
+			if method == "" {
+				for _, challenge := range authz.Challenges {
+					if challenge.Status == core.StatusValid {
+						method = challenge.Type
+						break
+					}
 				}
 			}
 			if method == "" {
@@ -824,6 +1042,16 @@ func (ra *RegistrationAuthorityImpl) failOrder(
 	return order
 }
 
+// repeatedFinalize returns true if order has already been finalized with the
+// CSR hashing to csrHash, and so is processing or valid.
+func repeatedFinalize(order *corepb.Order, csrHash []byte) bool {
+	status := order.GetStatus()
+	if status != string(core.StatusProcessing) && status != string(core.StatusValid) {
+		return false
+	}
+	return len(order.CsrHash) > 0 && bytes.Equal(order.CsrHash, csrHash)
+}
+
 // FinalizeOrder accepts a request to finalize an order object and, if possible,
 // issues a certificate to satisfy the order. If an order does not have valid,
 // unexpired authorizations for all of its associated names an error is
@@ -832,8 +1060,26 @@ func (ra *RegistrationAuthorityImpl) failOrder(
 // If successful the order will be returned in processing status for the client
 // to poll while awaiting finalization to occur.
 func (ra *RegistrationAuthorityImpl) FinalizeOrder(ctx context.Context, req *rapb.FinalizeOrderRequest) (*corepb.Order, error) {
+	// Refuse to finalize while issuance is stopped, rather than leaving the
+	// order stuck in the processing state.
+	if err := ra.KillSwitch.Check(); err != nil {
+		return nil, err
+	}
+
 	order := req.Order
 
+	// A client that retries finalizing with the same CSR, e.g. after timing
+	// out waiting for the first attempt, gets the order that attempt
+	// finalized instead of an error.
+	var csrHash []byte
+	if features.Enabled(features.StoreOrderCSRHash) {
+		hash := sha256.Sum256(req.Csr)
+		csrHash = hash[:]
+		if repeatedFinalize(order, csrHash) {
+			return order, nil
+		}
+	}
+
 	// Prior to ACME draft-10 the "ready" status did not exist and orders in
 	// a pending status with valid authzs were finalizable. We accept both states
 	// here for deployability ease. In the future we will only allow ready orders
@@ -841,7 +1087,7 @@ func (ra *RegistrationAuthorityImpl) FinalizeOrder(ctx context.Context, req *rap
 	// TODO(@cpu): Forbid finalizing "Pending" orders
 	if *order.Status != string(core.StatusPending) &&
 		*order.Status != string(core.StatusReady) {
-		return nil, berrors.MalformedError(
+		return nil, berrors.OrderNotReadyError(
 			"Order's status (%q) is not acceptable for finalization",
 			*order.Status)
 	}
@@ -853,13 +1099,25 @@ func (ra *RegistrationAuthorityImpl) FinalizeOrder(ctx context.Context, req *rap
 		return nil, berrors.InternalServerError("Order has no associated names")
 	}
 
+	// The order's policy exemptions were checked when it was created, and
+	// still apply although they may have since expired.
+	exemptions, err := policy.ParseExemptions(ra.exemptionKey, *order.RegistrationID, order.Exemptions)
+	if err != nil {
+		return nil, err
+	}
+
 	// Parse the CSR from the request
 	csrOb, err := x509.ParseCertificateRequest(req.Csr)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := csrlib.VerifyCSR(csrOb, ra.maxNames, &ra.keyPolicy, ra.PA, ra.forceCNFromSAN, *req.Order.RegistrationID); err != nil {
+	if err := csrlib.VerifyCSR(csrOb, ra.nameLimits.ForKey(csrOb.PublicKey), &ra.keyPolicy, ra.PA, ra.forceCNFromSAN, *req.Order.RegistrationID, policy.ExemptEntries(exemptions)); err != nil {
+		// VerifyCSR returns BadPublicKey errors for unacceptable keys, every
+		// other problem with the CSR makes it malformed.
+		if berrors.Is(err, berrors.BadPublicKey) {
+			return nil, err
+		}
 		return nil, berrors.MalformedError(err.Error())
 	}
 
@@ -880,6 +1138,15 @@ func (ra *RegistrationAuthorityImpl) FinalizeOrder(ctx context.Context, req *rap
 		}
 	}
 
+	// Wait for a turn to be signed before the order is processing, so that if
+	// the request is shed because the CA is overloaded the order can still be
+	// finalized again later.
+	release, err := ra.waitForIssuance(ctx, *order.RegistrationID, orderNames, order.GetReplaces())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	// Update the order to be status processing - we issue synchronously at the
 	// present time so this is somewhat artificial/unnecessary but allows planning
 	// for the future.
@@ -889,7 +1156,16 @@ func (ra *RegistrationAuthorityImpl) FinalizeOrder(ctx context.Context, req *rap
 	// Otherwise the order will be "stuck" in processing state. It can not be
 	// finalized because it isn't pending, but we aren't going to process it
 	// further because we already did and encountered an error.
+	order.CsrHash = csrHash
 	if err := ra.SA.SetOrderProcessing(ctx, order); err != nil {
+		// If a concurrent request with the same CSR began processing the order
+		// first, it's that request's to finish.
+		if csrHash != nil {
+			current, getErr := ra.SA.GetOrder(ctx, &sapb.OrderRequest{Id: order.Id})
+			if getErr == nil && repeatedFinalize(current, csrHash) {
+				return current, nil
+			}
+		}
 		// Fail the order with a server internal error - we weren't able to set the
 		// status to processing and that's unexpected & weird.
 		ra.failOrder(ctx, order, probs.ServerInternal("Error setting order processing"))
@@ -902,7 +1178,7 @@ func (ra *RegistrationAuthorityImpl) FinalizeOrder(ctx context.Context, req *rap
 		Bytes: req.Csr,
 		CSR:   csrOb,
 	}
-	cert, err := ra.issueCertificate(ctx, issueReq, accountID(*order.RegistrationID), orderID(*order.Id))
+	cert, err := ra.issueCertificate(ctx, issueReq, accountID(*order.RegistrationID), orderID(*order.Id), order.GetReplaces(), order.Exemptions)
 	if err != nil {
 		// Fail the order. The problem is computed using
 		// `web.ProblemDetailsForError`, the same function the WFE uses to convert
@@ -940,16 +1216,146 @@ func (ra *RegistrationAuthorityImpl) FinalizeOrder(ctx context.Context, req *rap
 	return order, nil
 }
 
+// CheckIssuance runs the policy, key, CSR and profile checks that ordering a
+// certificate for req.Names and finalizing the order with req.Csr would make,
+// including the CA's, without creating an order or signing anything. It
+// returns every problem the checks find, with the details a client would see
+// if the order failed because of it. Authorizations and rate limits aren't
+// checked, since they depend on what the account does before finalizing.
+func (ra *RegistrationAuthorityImpl) CheckIssuance(ctx context.Context, req *rapb.CheckIssuanceRequest) ([]*probs.ProblemDetails, error) {
+	const (
+		newOrderMsg = "Error creating new order"
+		finalizeMsg = "Error finalizing order"
+	)
+	account, err := ra.SA.GetRegistration(ctx, *req.RegistrationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []*probs.ProblemDetails
+	seen := make(map[string]bool)
+	addProblem := func(prob *probs.ProblemDetails) {
+		key := string(prob.Type) + " " + prob.Detail
+		if !seen[key] {
+			seen[key] = true
+			problems = append(problems, prob)
+		}
+	}
+
+	// The checks NewOrder makes of the names
+	names, err := core.NormalizeNames(req.Names)
+	if err != nil {
+		addProblem(web.ProblemDetailsForError(err, newOrderMsg))
+	} else {
+		if len(names) == 0 {
+			addProblem(probs.Malformed("%s :: no identifiers were provided", newOrderMsg))
+		}
+		if maxNames := ra.nameLimits.Max(); len(names) > maxNames {
+			addProblem(web.ProblemDetailsForError(
+				berrors.MalformedError("Order cannot contain more than %d DNS names", maxNames), newOrderMsg))
+		}
+		for _, name := range names {
+			id := core.AcmeIdentifier{Value: name, Type: core.IdentifierDNS}
+			if err := ra.PA.WillingToIssueWildcard(id); err != nil {
+				addProblem(web.ProblemDetailsForError(err, newOrderMsg).WithIdentifier(string(id.Type), id.Value))
+			}
+		}
+		if err := wildcardOverlap(names); err != nil {
+			addProblem(web.ProblemDetailsForError(err, newOrderMsg))
+		}
+	}
+
+	// The checks FinalizeOrder makes of the CSR
+	csrOb, err := x509.ParseCertificateRequest(req.Csr)
+	if err != nil {
+		addProblem(probs.BadCSR("%s :: CSR could not be parsed", finalizeMsg))
+		return problems, nil
+	}
+	csrErrs := csrlib.CheckCSR(csrOb, ra.nameLimits.ForKey(csrOb.PublicKey), &ra.keyPolicy, ra.PA, ra.forceCNFromSAN, *req.RegistrationID, nil)
+	for _, err := range csrErrs {
+		if !berrors.Is(err, berrors.BadPublicKey) {
+			err = berrors.MalformedError("%s", err)
+		}
+		addProblem(web.ProblemDetailsForError(err, finalizeMsg))
+	}
+	if len(names) > 0 {
+		csrNames := core.UniqueLowerNames(csrOb.DNSNames)
+		if len(csrNames) != len(names) {
+			addProblem(probs.Unauthorized("%s :: Order includes different number of names than CSR specifies", finalizeMsg))
+		} else {
+			for i, name := range names {
+				if name != csrNames[i] {
+					addProblem(probs.Unauthorized("%s :: CSR is missing Order domain %q", finalizeMsg, name))
+				}
+			}
+		}
+	}
+	if err := ra.checkCertificateKeyNotAccountKey(ctx, csrOb, account); err != nil {
+		if !berrors.Is(err, berrors.BadCSR) {
+			return nil, err
+		}
+		addProblem(web.ProblemDetailsForError(err, finalizeMsg))
+	}
+
+	// The checks the CA makes before signing, e.g. of the key policy of the
+	// profile the certificate would be issued with. Many of them repeat the
+	// RA's, and so are only reported once.
+	caProblems, err := ra.CA.CheckCertificateRequest(ctx, &caPB.IssueCertificateRequest{
+		Csr:            req.Csr,
+		RegistrationID: req.RegistrationID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, prob := range caProblems {
+		prob.Detail = fmt.Sprintf("%s :: %s", finalizeMsg, prob.Detail)
+		addProblem(prob)
+	}
+	return problems, nil
+}
+
+// checkCertificateKeyNotAccountKey returns a BadCSR error if the CSR's public
+// key is the account key of the requesting account or of any other account.
+// Using one key for both roles is a common client misconfiguration that
+// weakens both of them.
+func (ra *RegistrationAuthorityImpl) checkCertificateKeyNotAccountKey(ctx context.Context, csr *x509.CertificateRequest, account core.Registration) error {
+	const detail = "certificate public key must be different than account key"
+	if core.KeyDigestEquals(csr.PublicKey, account.Key) {
+		return berrors.BadCSRError(detail)
+	}
+	// The SA looks up accounts by the SHA-256 hash of their key's SPKI, which
+	// is the same for the CSR's public key.
+	_, err := ra.SA.GetRegistrationByKey(ctx, &jose.JSONWebKey{Key: csr.PublicKey})
+	if err == nil {
+		return berrors.BadCSRError(detail)
+	}
+	if !berrors.Is(err, berrors.NotFound) {
+		return err
+	}
+	return nil
+}
+
 // NewCertificate requests the issuance of a certificate.
 func (ra *RegistrationAuthorityImpl) NewCertificate(ctx context.Context, req core.CertificateRequest, regID int64) (core.Certificate, error) {
+	if err := ra.KillSwitch.Check(); err != nil {
+		return core.Certificate{}, err
+	}
 	// Verify the CSR
-	if err := csrlib.VerifyCSR(req.CSR, ra.maxNames, &ra.keyPolicy, ra.PA, ra.forceCNFromSAN, regID); err != nil {
+	if err := csrlib.VerifyCSR(req.CSR, ra.nameLimits.ForKey(req.CSR.PublicKey), &ra.keyPolicy, ra.PA, ra.forceCNFromSAN, regID, nil); err != nil {
+		if berrors.Is(err, berrors.BadPublicKey) {
+			return core.Certificate{}, err
+		}
 		return core.Certificate{}, berrors.MalformedError(err.Error())
 	}
+	release, err := ra.waitForIssuance(ctx, regID, req.CSR.DNSNames, "")
+	if err != nil {
+		return core.Certificate{}, err
+	}
+	defer release()
 	// NewCertificate provides an order ID of 0, indicating this is a classic ACME
 	// v1 issuance request from the new certificate endpoint that is not
 	// associated with an ACME v2 order.
-	return ra.issueCertificate(ctx, req, accountID(regID), orderID(0))
+	return ra.issueCertificate(ctx, req, accountID(regID), orderID(0), "", nil)
 }
 
 // To help minimize the chance that an accountID would be used as an order ID
@@ -959,21 +1365,28 @@ type accountID int64
 type orderID int64
 
 // issueCertificate sets up a log event structure and captures any errors
-// encountered during issuance, then calls issueCertificateInner.
+// encountered during issuance, then calls issueCertificateInner. replaces is
+// the serial of the certificate the order being finalized replaces, if any,
+// and exemptions are the order's policy exemption tokens.
 func (ra *RegistrationAuthorityImpl) issueCertificate(
 	ctx context.Context,
 	req core.CertificateRequest,
 	acctID accountID,
-	oID orderID) (core.Certificate, error) {
+	oID orderID,
+	replaces string,
+	exemptions []string) (core.Certificate, error) {
 	// Construct the log event
 	logEvent := certificateRequestEvent{
 		ID:          core.NewToken(),
 		OrderID:     int64(oID),
 		Requester:   int64(acctID),
 		RequestTime: ra.clk.Now(),
+		Replaces:    replaces,
+		Exemptions:  exemptions,
 	}
 	var result string
 	cert, err := ra.issueCertificateInner(ctx, req, acctID, oID, &logEvent)
+	ra.accountMetrics.observe(int64(acctID), err)
 	if err != nil {
 		logEvent.Error = err.Error()
 		result = "error"
@@ -1018,14 +1431,18 @@ func (ra *RegistrationAuthorityImpl) issueCertificateInner(
 	names := make([]string, len(csr.DNSNames))
 	copy(names, csr.DNSNames)
 
-	if core.KeyDigestEquals(csr.PublicKey, account.Key) {
-		return emptyCert, berrors.MalformedError("certificate public key must be different than account key")
+	if err := ra.checkCertificateKeyNotAccountKey(ctx, csr, account); err != nil {
+		return emptyCert, err
 	}
 
 	// Check rate limits before checking authorizations. If someone is unable to
 	// issue a cert due to rate limiting, we don't want to tell them to go get the
 	// necessary authorizations, only to later fail the rate limit check.
-	err = ra.checkLimits(ctx, names, account.ID)
+	renewal, err := ra.renewsReplaced(ctx, account.ID, names, logEvent.Replaces)
+	if err != nil {
+		return emptyCert, err
+	}
+	err = ra.checkLimits(ctx, names, account.ID, renewal)
 	if err != nil {
 		return emptyCert, err
 	}
@@ -1075,6 +1492,7 @@ func (ra *RegistrationAuthorityImpl) issueCertificateInner(
 		Csr:            csr.Raw,
 		RegistrationID: &acctIDInt,
 		OrderID:        &orderIDInt,
+		Exemptions:     logEvent.Exemptions,
 	}
 
 	// wrapError adds a prefix to an error. If the error is a boulder error then
@@ -1277,12 +1695,12 @@ func (ra *RegistrationAuthorityImpl) checkCertificatesPerNameLimit(ctx context.C
 			return nil
 		}
 		domains := strings.Join(badNames, ", ")
-		ra.certsForDomainStats.Inc("Exceeded", 1)
 		ra.log.Infof("Rate limit exceeded, CertificatesForDomain, regID: %d, domains: %s", regID, domains)
-		return berrors.RateLimitError(
-			"too many certificates already issued for: %s",
-			domains,
-		)
+		return ra.rateLimitExceeded(limit, ra.certsForDomainStats,
+			berrors.RateLimitError(
+				"too many certificates already issued for: %s",
+				domains,
+			))
 	}
 	ra.certsForDomainStats.Inc("Pass", 1)
 
@@ -1296,20 +1714,31 @@ func (ra *RegistrationAuthorityImpl) checkCertificatesPerFQDNSetLimit(ctx contex
 	}
 	names = core.UniqueLowerNames(names)
 	if int(count) >= limit.GetThreshold(strings.Join(names, ","), regID) {
-		return berrors.RateLimitError(
-			"too many certificates already issued for exact set of domains: %s",
-			strings.Join(names, ","),
-		)
-	}
+		ra.log.Infof("Rate limit exceeded, CertificatesForFQDNSet, regID: %d, names: %s", regID, strings.Join(names, ","))
+		return ra.rateLimitExceeded(limit, ra.certsForFQDNSetStats,
+			berrors.RateLimitError(
+				"too many certificates already issued for exact set of domains: %s",
+				strings.Join(names, ","),
+			))
+	}
+	ra.certsForFQDNSetStats.Inc("Pass", 1)
 	return nil
 }
 
-func (ra *RegistrationAuthorityImpl) checkLimits(ctx context.Context, names []string, regID int64) error {
+// checkLimits checks the rate limits on issuing a certificate for names to the
+// account regID. If the certificate is a renewal of one it replaces, as
+// determined by renewsReplaced, it's exempt from the certificates per name
+// limit.
+func (ra *RegistrationAuthorityImpl) checkLimits(ctx context.Context, names []string, regID int64, renewal bool) error {
 	certNameLimits := ra.rlPolicies.CertificatesPerName()
 	if certNameLimits.Enabled() {
-		err := ra.checkCertificatesPerNameLimit(ctx, names, certNameLimits, regID)
-		if err != nil {
-			return err
+		if renewal {
+			ra.certsForDomainStats.Inc("ReplacementBypass", 1)
+		} else {
+			err := ra.checkCertificatesPerNameLimit(ctx, names, certNameLimits, regID)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -1414,6 +1843,20 @@ func mergeUpdate(r *core.Registration, input core.Registration) bool {
 	return changed
 }
 
+// SetChallengeRetries sets how many times a failed challenge may be retried
+// by responding to it again, which reopens its invalid authorization instead
+// of requiring a new one. Zero, the default, disables retries. Retries need
+// the SA's StoreChallengeAttempts feature, which counts the attempts.
+func (ra *RegistrationAuthorityImpl) SetChallengeRetries(n int) {
+	ra.challengeRetries = n
+}
+
+// canRetryChallenge returns whether ch has failed and may be retried. Only
+// challenges whose attempts were counted can be retried.
+func (ra *RegistrationAuthorityImpl) canRetryChallenge(ch core.Challenge) bool {
+	return ch.Status == core.StatusInvalid && ch.Attempts > 0 && ch.Attempts <= int64(ra.challengeRetries)
+}
+
 // PerformValidation initiates validation for a specific challenge associated
 // with the given base authorization. The authorization and challenge are
 // updated based on the results.
@@ -1439,6 +1882,12 @@ func (ra *RegistrationAuthorityImpl) PerformValidation(
 
 	ch := &authz.Challenges[challIndex]
 
+	// Refuse to validate a disabled challenge type before anything changes,
+	// so that the authorization stays pending rather than failing.
+	if err := ra.ChallengeKillSwitch.Check(ch.Type); err != nil {
+		return nil, err
+	}
+
 	// If TLSSNIRevalidation is enabled, find out whether this was a revalidation
 	// (previous certificate existed) or not. If it is a revalidation, we can
 	// proceed with validation even though the challenge type is currently
@@ -1457,6 +1906,13 @@ func (ra *RegistrationAuthorityImpl) PerformValidation(
 		}
 	}
 
+	// A processing challenge's validation is being rechecked, by this RA or
+	// another, or by whichever RA's ResumeDNS01Rechecks takes it over if that
+	// one stopped, so responding to it again doesn't start another one.
+	if ch.Status == core.StatusProcessing {
+		return req.Authz, nil
+	}
+
 	// When configured with `reuseValidAuthz` we can expect some clients to try
 	// and update a challenge for an authorization that is already valid. In this
 	// case we don't need to process the challenge update. It wouldn't be helpful,
@@ -1467,10 +1923,40 @@ func (ra *RegistrationAuthorityImpl) PerformValidation(
 		return req.Authz, nil
 	}
 
-	if authz.Status != core.StatusPending {
+	retry := authz.Status == core.StatusInvalid && ra.canRetryChallenge(*ch)
+	if authz.Status != core.StatusPending && !retry {
 		return nil, berrors.WrongAuthorizationStateError("authorization must be pending")
 	}
 
+	// Refuse to send another validation attempt to the VA if the account has
+	// already failed validation for this hostname too many times recently.
+	if err := ra.checkInvalidAuthorizationLimit(ctx, authz.RegistrationID, authz.Identifier.Value); err != nil {
+		return nil, err
+	}
+
+	// Apply any restrictions for accounts with a high validation failure ratio
+	if err := ra.reputation.startValidation(authz.RegistrationID, ch.Type); err != nil {
+		return nil, err
+	}
+
+	// Reopen the authorization of a failed challenge that's being retried,
+	// so that it can be validated again like a pending one.
+	if retry {
+		err := ra.SA.RetryChallenge(ctx, &sapb.RetryChallengeRequest{
+			AuthorizationID: &authz.ID,
+			ChallengeID:     &ch.ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		authz.Status = core.StatusPending
+		ch.Status = core.StatusPending
+		ch.Error = nil
+		ch.ValidationRecord = nil
+		ra.stats.Inc("RetriedChallenges", 1)
+	}
+	ch.Attempts++
+
 	// Look up the account key for this authorization
 	reg, err := ra.SA.GetRegistration(ctx, authz.RegistrationID)
 	if err != nil {
@@ -1508,41 +1994,81 @@ func (ra *RegistrationAuthorityImpl) PerformValidation(
 		copy(challenges, authz.Challenges)
 		authz.Challenges = challenges
 
-		records, err := ra.VA.PerformValidation(vaCtx, authz.Identifier.Value, authz.Challenges[challIndex], authz)
-		var prob *probs.ProblemDetails
-		if p, ok := err.(*probs.ProblemDetails); ok {
-			prob = p
+		started := ra.clk.Now()
+		prob := ra.validate(vaCtx, &authz, challIndex)
+		if !recheckable(authz.Challenges[challIndex], prob) {
+			ra.finalizeValidation(vaCtx, authz, challIndex, prob)
+			return
+		}
+		recheck, err := ra.addDNS01Recheck(vaCtx, authz, challIndex, started)
+		if berrors.Is(err, berrors.Duplicate) {
+			// Another validation of the challenge is already being rechecked
+			return
 		} else if err != nil {
-			prob = probs.ServerInternal("Could not communicate with VA")
-			ra.log.AuditErrf("Could not communicate with VA: %s", err)
+			ra.log.AuditErrf("Could not start DNS-01 recheck: err=[%s] regID=[%d] authzID=[%s]",
+				err, authz.RegistrationID, authz.ID)
 		}
-
-		// Save the updated records
-		challenge := &authz.Challenges[challIndex]
-		challenge.ValidationRecord = records
-
-		if !challenge.RecordsSane() && prob == nil {
-			prob = probs.ServerInternal("Records for validation failed sanity check")
+		if recheck == nil {
+			ra.finalizeValidation(vaCtx, authz, challIndex, prob)
+			return
 		}
-
-		if prob != nil {
-			challenge.Status = core.StatusInvalid
-			challenge.Error = prob
-		} else {
-			challenge.Status = core.StatusValid
-		}
-		authz.Challenges[challIndex] = *challenge
-
-		err = ra.onValidationUpdate(vaCtx, authz)
-		if err != nil {
-			ra.log.AuditErrf("Could not record updated validation: err=[%s] regID=[%d] authzID=[%s]",
-				err, authz.RegistrationID, authz.ID)
+		prob, claimed := ra.recheckDNS01(vaCtx, &authz, challIndex, recheck, prob)
+		if !claimed {
+			return
 		}
+		ra.finalizeValidation(vaCtx, authz, challIndex, prob)
+		ra.removeDNS01Recheck(vaCtx, authz.ID)
 	}(authz)
 	ra.stats.Inc("UpdatedPendingAuthorizations", 1)
 	return bgrpc.AuthzToPB(authz)
 }
 
+// finalizeValidation stores the result of the validation of the challenge at
+// challIndex, which failed if prob isn't nil, and finalizes the authorization.
+func (ra *RegistrationAuthorityImpl) finalizeValidation(ctx context.Context, authz core.Authorization, challIndex int, prob *probs.ProblemDetails) {
+	challenge := &authz.Challenges[challIndex]
+	if prob != nil {
+		challenge.Status = core.StatusInvalid
+		challenge.Error = prob
+	} else {
+		challenge.Status = core.StatusValid
+		challenge.Error = nil
+	}
+
+	err := ra.onValidationUpdate(ctx, authz)
+	if err != nil {
+		ra.log.AuditErrf("Could not record updated validation: err=[%s] regID=[%d] authzID=[%s]",
+			err, authz.RegistrationID, authz.ID)
+	}
+}
+
+// validate has the VA validate the challenge at challIndex, saving its
+// validation records in the challenge, and returns the problem that made the
+// validation fail, if it did.
+func (ra *RegistrationAuthorityImpl) validate(ctx context.Context, authz *core.Authorization, challIndex int) *probs.ProblemDetails {
+	records, err := ra.VA.PerformValidation(ctx, authz.Identifier.Value, authz.Challenges[challIndex], *authz)
+	var prob *probs.ProblemDetails
+	if p, ok := err.(*probs.ProblemDetails); ok {
+		prob = p
+	} else if err != nil {
+		prob = probs.ServerInternal("Could not communicate with VA")
+		ra.log.AuditErrf("Could not communicate with VA: %s", err)
+	}
+
+	// Save the updated records
+	challenge := &authz.Challenges[challIndex]
+	challenge.ValidationRecord = records
+
+	if !challenge.RecordsSane() && prob == nil {
+		prob = probs.ServerInternal("Records for validation failed sanity check")
+	}
+
+	if prob == nil {
+		prob = ra.checkCountryPolicyRecords(*authz, records)
+	}
+	return prob
+}
+
 func revokeEvent(state, serial, cn string, names []string, revocationCode revocation.Reason) string {
 	return fmt.Sprintf(
 		"Revocation - State: %s, Serial: %s, CN: %s, DNS Names: %s, Reason: %s",
@@ -1683,6 +2209,9 @@ func (ra *RegistrationAuthorityImpl) onValidationUpdate(ctx context.Context, aut
 		}
 		if comboValid {
 			authz.Status = core.StatusValid
+			if len(combo) > 0 {
+				authz.ValidationMethod = authz.Challenges[combo[0]].Type
+			}
 		}
 	}
 
@@ -1694,6 +2223,7 @@ func (ra *RegistrationAuthorityImpl) onValidationUpdate(ctx context.Context, aut
 		exp := ra.clk.Now().Add(ra.authorizationLifetime)
 		authz.Expires = &exp
 	}
+	ra.reputation.observe(authz.RegistrationID, authz.Status == core.StatusValid)
 
 	// Finalize the authorization
 	err := ra.SA.FinalizeAuthorization(ctx, authz)
@@ -1717,12 +2247,22 @@ func (ra *RegistrationAuthorityImpl) DeactivateRegistration(ctx context.Context,
 	return nil
 }
 
-// DeactivateAuthorization deactivates a currently valid authorization
+// DeactivateAuthorization deactivates a currently valid or pending
+// authorization, so that it can no longer be used or reused for issuance.
 func (ra *RegistrationAuthorityImpl) DeactivateAuthorization(ctx context.Context, auth core.Authorization) error {
 	if auth.Status != core.StatusValid && auth.Status != core.StatusPending {
 		return berrors.MalformedError("only valid and pending authorizations can be deactivated")
 	}
-	err := ra.SA.DeactivateAuthorization(ctx, auth.ID)
+	var err error
+	if auth.V2 {
+		id, parseErr := strconv.ParseInt(auth.ID, 10, 64)
+		if parseErr != nil {
+			return berrors.InternalServerError("invalid authorization ID %q", auth.ID)
+		}
+		_, err = ra.SA.DeactivateAuthorization2(ctx, &sapb.AuthorizationID2{Id: &id})
+	} else {
+		err = ra.SA.DeactivateAuthorization(ctx, auth.ID)
+	}
 	if err != nil {
 		return berrors.InternalServerError(err.Error())
 	}
@@ -1731,37 +2271,79 @@ func (ra *RegistrationAuthorityImpl) DeactivateAuthorization(ctx context.Context
 
 // NewOrder creates a new order object
 func (ra *RegistrationAuthorityImpl) NewOrder(ctx context.Context, req *rapb.NewOrderRequest) (*corepb.Order, error) {
+	names, err := core.NormalizeNames(req.Names)
+	if err != nil {
+		return nil, err
+	}
 	order := &corepb.Order{
 		RegistrationID: req.RegistrationID,
-		Names:          core.UniqueLowerNames(req.Names),
+		Names:          names,
 	}
 
-	// Validate that our policy allows issuing for each of the names in the order
+	// The key, and so the profile the certificate will be issued with, isn't
+	// known until the order is finalized, so only the largest limit of any
+	// profile can be enforced here.
+	if maxNames := ra.nameLimits.Max(); len(order.Names) > maxNames {
+		return nil, berrors.MalformedError("Order cannot contain more than %d DNS names", maxNames)
+	}
+
+	exemptions, err := ra.checkNewOrderExemptions(*order.RegistrationID, order.Names, req.Exemptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate that our policy allows issuing for each of the names in the
+	// order, other than any entries they're exempt from
 	for _, name := range order.Names {
 		id := core.AcmeIdentifier{Value: name, Type: core.IdentifierDNS}
-		if err := ra.PA.WillingToIssueWildcard(id); err != nil {
+		if entry, ok := exemptions[name]; ok {
+			err = ra.PA.WillingToIssueExempt(id, entry)
+		} else {
+			err = ra.PA.WillingToIssueWildcard(id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := ra.checkCountryPolicyName(name, *order.RegistrationID); err != nil {
 			return nil, err
 		}
 	}
+	order.Exemptions = req.Exemptions
 
-	if err := wildcardOverlap(order.Names); err != nil {
+	if err := ra.PA.CheckOrderNames(order.Names); err != nil {
 		return nil, err
 	}
 
-	// See if there is an existing, pending, unexpired order that can be reused
-	// for this account
-	existingOrder, err := ra.SA.GetOrderForNames(ctx, &sapb.GetOrderForNamesRequest{
-		AcctID: order.RegistrationID,
-		Names:  order.Names,
-	})
-	// If there was an error and it wasn't an acceptable "NotFound" error, return
-	// immediately
-	if err != nil && !berrors.Is(err, berrors.NotFound) {
-		return nil, err
+	// The certificate an order replaces is only recorded, and so only trusted
+	// to exempt the order from rate limits, when the StoreReplacementOrders
+	// feature is enabled.
+	var replacementReason string
+	if req.GetReplaces() != "" && features.Enabled(features.StoreReplacementOrders) {
+		replaces, reason, err := ra.checkReplaces(ctx, *order.RegistrationID, order.Names, req.GetReplaces())
+		if err != nil {
+			return nil, err
+		}
+		order.Replaces = &replaces
+		replacementReason = reason
 	}
-	// If there was an order, return it
-	if existingOrder != nil {
-		return existingOrder, nil
+
+	// See if there is an existing, pending, unexpired order that can be reused
+	// for this account. An order replacing a certificate or using policy
+	// exemptions isn't reused, since the existing order doesn't record them.
+	if order.Replaces == nil && len(order.Exemptions) == 0 {
+		existingOrder, err := ra.SA.GetOrderForNames(ctx, &sapb.GetOrderForNamesRequest{
+			AcctID: order.RegistrationID,
+			Names:  order.Names,
+		})
+		// If there was an error and it wasn't an acceptable "NotFound" error, return
+		// immediately
+		if err != nil && !berrors.Is(err, berrors.NotFound) {
+			return nil, err
+		}
+		// If there was an order, return it
+		if existingOrder != nil {
+			return existingOrder, nil
+		}
 	}
 	// Otherwise we were unable to find an order to reuse, continue creating a new
 	// order
@@ -1771,11 +2353,19 @@ func (ra *RegistrationAuthorityImpl) NewOrder(ctx context.Context, req *rapb.New
 		return nil, err
 	}
 
-	if features.Enabled(features.EarlyOrderRateLimit) {
+	if features.EnabledForAccount(features.EarlyOrderRateLimit, *order.RegistrationID) {
 		// Check if there is rate limit space for issuing a certificate for the new
 		// order's names. If there isn't then it doesn't make sense to allow creating
 		// an order - it will just fail when finalization checks the same limits.
-		if err := ra.checkLimits(ctx, order.Names, *order.RegistrationID); err != nil {
+		var replaces string
+		if order.Replaces != nil {
+			replaces = *order.Replaces
+		}
+		renewal, err := ra.renewsReplaced(ctx, *order.RegistrationID, order.Names, replaces)
+		if err != nil {
+			return nil, err
+		}
+		if err := ra.checkLimits(ctx, order.Names, *order.RegistrationID, renewal); err != nil {
 			return nil, err
 		}
 	}
@@ -1830,11 +2420,12 @@ func (ra *RegistrationAuthorityImpl) NewOrder(ctx context.Context, req *rapb.New
 		// never get back an authorization for a domain with a wildcard prefix
 		// that doesn't meet this criteria from SA.GetAuthorizations but we verify
 		// again to be safe.
-		if strings.HasPrefix(name, "*.") &&
+		_, wildcard := core.WildcardBase(name)
+		if wildcard &&
 			len(authz.Challenges) == 1 && *authz.Challenges[0].Type == core.ChallengeTypeDNS01 {
 			order.Authorizations = append(order.Authorizations, *authz.Id)
 			continue
-		} else if !strings.HasPrefix(name, "*.") {
+		} else if !wildcard {
 			// If the identifier isn't a wildcard, we can reuse any authz
 			order.Authorizations = append(order.Authorizations, *authz.Id)
 			continue
@@ -1918,6 +2509,9 @@ func (ra *RegistrationAuthorityImpl) NewOrder(ctx context.Context, req *rapb.New
 	if err != nil {
 		return nil, err
 	}
+	if replacementReason != "" {
+		ra.replacementOrders.WithLabelValues(replacementReason).Inc()
+	}
 
 	return storedOrder, nil
 }
@@ -0,0 +1,99 @@
+package ra
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/core"
+	rapb "github.com/letsencrypt/boulder/ra/proto"
+	"github.com/letsencrypt/boulder/ratelimit"
+)
+
+// RateLimitUsage returns how much of each rate limit an account has used, so
+// that subscribers can see how close they are to them: the orders it created
+// within the newOrdersPerAccount window and its pending authorizations. If
+// req.Names is set, the usage of the limits on issuing a certificate for them
+// is included too: certificatesPerName for each of their registered domains
+// (or each name that is itself a public suffix), and certificatesPerFQDNSet
+// for the set of them. Thresholds include the overrides for the account and
+// key. Limits that aren't enabled are left out.
+func (ra *RegistrationAuthorityImpl) RateLimitUsage(ctx context.Context, req *rapb.RateLimitUsageRequest) (*rapb.RateLimitUsageResponse, error) {
+	regID := *req.RegistrationID
+	now := ra.clk.Now()
+	resp := &rapb.RateLimitUsageResponse{}
+	add := func(name, key string, limit ratelimit.RateLimitPolicy, count int64, window time.Duration) {
+		threshold := int64(limit.GetThreshold(key, regID))
+		windowNS := window.Nanoseconds()
+		resp.Usages = append(resp.Usages, &rapb.RateLimitUsage{
+			Limit:     &name,
+			Key:       &key,
+			Count:     &count,
+			Threshold: &threshold,
+			Window:    &windowNS,
+		})
+	}
+
+	if limit := ra.rlPolicies.NewOrdersPerAccount(); limit.Enabled() {
+		count, err := ra.SA.CountOrders(ctx, regID, limit.WindowBegin(now), now)
+		if err != nil {
+			return nil, err
+		}
+		add(ratelimit.NewOrdersPerAccount, "", limit, int64(count), limit.Window.Duration)
+	}
+	if limit := ra.rlPolicies.PendingAuthorizationsPerAccount(); limit.Enabled() {
+		count, err := ra.SA.CountPendingAuthorizations(ctx, regID)
+		if err != nil {
+			return nil, err
+		}
+		add(ratelimit.PendingAuthorizationsPerAccount, "", limit, int64(count), 0)
+	}
+	if len(req.Names) == 0 {
+		return resp, nil
+	}
+
+	names, err := core.NormalizeNames(req.Names)
+	if err != nil {
+		return nil, err
+	}
+	if limit := ra.rlPolicies.CertificatesPerName(); limit.Enabled() {
+		domains, err := domainsForRateLimiting(names)
+		if err != nil {
+			return nil, err
+		}
+		suffixes, err := suffixesForRateLimiting(names)
+		if err != nil {
+			return nil, err
+		}
+		// As in checkCertificatesPerNameLimit, certificates are counted for
+		// registered domains and their subdomains, but only exactly for
+		// public suffixes.
+		for _, q := range []struct {
+			names []string
+			count certCountRPC
+		}{
+			{domains, ra.SA.CountCertificatesByNames},
+			{suffixes, ra.SA.CountCertificatesByExactNames},
+		} {
+			if len(q.names) == 0 {
+				continue
+			}
+			counts, err := q.count(ctx, q.names, limit.WindowBegin(now), now)
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range counts {
+				add(ratelimit.CertificatesPerName, *entry.Name, limit, *entry.Count, limit.Window.Duration)
+			}
+		}
+	}
+	if limit := ra.rlPolicies.CertificatesPerFQDNSet(); limit.Enabled() {
+		count, err := ra.SA.CountFQDNSets(ctx, limit.Window.Duration, names)
+		if err != nil {
+			return nil, err
+		}
+		add(ratelimit.CertificatesPerFQDNSet, strings.Join(names, ","), limit, count, limit.Window.Duration)
+	}
+	return resp, nil
+}
@@ -0,0 +1,92 @@
+package ra
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/cmd"
+	rapb "github.com/letsencrypt/boulder/ra/proto"
+	"github.com/letsencrypt/boulder/ratelimit"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+	"github.com/letsencrypt/boulder/test"
+)
+
+type mockSARateLimitUsage struct {
+	mockSAWithNameCounts
+}
+
+func (m *mockSARateLimitUsage) CountOrders(_ context.Context, _ int64, _, _ time.Time) (int, error) {
+	return 4, nil
+}
+
+func (m *mockSARateLimitUsage) CountFQDNSets(_ context.Context, _ time.Duration, _ []string) (int64, error) {
+	return 1, nil
+}
+
+func TestRateLimitUsage(t *testing.T) {
+	fc := clock.NewFake()
+	window := cmd.ConfigDuration{Duration: 23 * time.Hour}
+	ra := &RegistrationAuthorityImpl{
+		clk: fc,
+		SA: &mockSARateLimitUsage{mockSAWithNameCounts{
+			nameCounts: map[string]*sapb.CountByNames_MapElement{
+				"example.com": nameCount("example.com", 2),
+			},
+			exactCounts: map[string]*sapb.CountByNames_MapElement{
+				"co.uk": nameCount("co.uk", 1),
+			},
+			clk: fc,
+			t:   t,
+		}},
+		rlPolicies: &dummyRateLimitConfig{
+			NewOrdersPerAccountPolicy: ratelimit.RateLimitPolicy{
+				Threshold:             10,
+				Window:                window,
+				RegistrationOverrides: map[int64]int{1: 20},
+			},
+			CertificatesPerNamePolicy: ratelimit.RateLimitPolicy{
+				Threshold: 5,
+				Window:    window,
+				Overrides: map[string]int{"example.com": 50},
+			},
+			CertificatesPerFQDNSetPolicy: ratelimit.RateLimitPolicy{
+				Threshold: 3,
+				Window:    window,
+			},
+		},
+	}
+	regID := int64(1)
+
+	// Without names, only the enabled account limits are reported
+	resp, err := ra.RateLimitUsage(ctx, &rapb.RateLimitUsageRequest{RegistrationID: &regID})
+	test.AssertNotError(t, err, "RateLimitUsage failed")
+	test.AssertEquals(t, len(resp.Usages), 1)
+	usage := resp.Usages[0]
+	test.AssertEquals(t, *usage.Limit, ratelimit.NewOrdersPerAccount)
+	test.AssertEquals(t, *usage.Count, int64(4))
+	test.AssertEquals(t, *usage.Threshold, int64(20))
+	test.AssertEquals(t, *usage.Window, window.Nanoseconds())
+
+	resp, err = ra.RateLimitUsage(ctx, &rapb.RateLimitUsageRequest{
+		RegistrationID: &regID,
+		Names:          []string{"WWW.example.com", "co.uk"},
+	})
+	test.AssertNotError(t, err, "RateLimitUsage failed")
+	type usageKey struct{ limit, key string }
+	usages := map[usageKey][2]int64{}
+	for _, u := range resp.Usages {
+		usages[usageKey{*u.Limit, *u.Key}] = [2]int64{*u.Count, *u.Threshold}
+	}
+	test.AssertDeepEquals(t, usages, map[usageKey][2]int64{
+		{ratelimit.NewOrdersPerAccount, ""}:                         {4, 20},
+		{ratelimit.CertificatesPerName, "example.com"}:              {2, 50},
+		{ratelimit.CertificatesPerName, "co.uk"}:                    {1, 5},
+		{ratelimit.CertificatesPerFQDNSet, "co.uk,www.example.com"}: {1, 3},
+	})
+
+	_, err = ra.RateLimitUsage(ctx, &rapb.RateLimitUsageRequest{RegistrationID: &regID, Names: []string{"\u05d0a.example.com"}})
+	test.AssertError(t, err, "invalid name was accepted")
+}
@@ -0,0 +1,167 @@
+package ra
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"math/big"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/features"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+)
+
+// Reasons a certificate is replaced, the label values of the
+// replacement_orders metric.
+const (
+	// replacedRevoked certificates were revoked, e.g. because of an incident.
+	replacedRevoked = "revoked"
+	// replacedEarly certificates had more than a third of their lifetime
+	// left, so are being renewed early.
+	replacedEarly = "early"
+	// replacedRenewal certificates are being renewed on schedule.
+	replacedRenewal = "renewal"
+)
+
+// parseARICertID parses an ARI certificate ID, as sent in the replaces field of
+// a new order: the certificate's authority key identifier and the DER
+// encoding of its serial number's value, each base64url encoded without
+// padding, joined by a ".". It returns the authority key identifier and the
+// serial as a string.
+func parseARICertID(id string) ([]byte, string, error) {
+	parts := strings.Split(id, ".")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, "", berrors.MalformedError("Invalid certificate ID %q in replaces", id)
+	}
+	aki, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, "", berrors.MalformedError("Invalid authority key identifier in replaces certificate ID %q", id)
+	}
+	serial, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, "", berrors.MalformedError("Invalid serial in replaces certificate ID %q", id)
+	}
+	return aki, core.SerialToString(new(big.Int).SetBytes(serial)), nil
+}
+
+// checkReplaces checks that the certificate a new order for names by the
+// account regID replaces, identified by its ARI certificate ID, was issued to
+// the same account for at least one of the same names and hasn't already been
+// replaced by another order. It returns the serial of the certificate and the
+// reason it's being replaced.
+func (ra *RegistrationAuthorityImpl) checkReplaces(ctx context.Context, regID int64, names []string, id string) (string, string, error) {
+	aki, serial, err := parseARICertID(id)
+	if err != nil {
+		return "", "", err
+	}
+	cert, err := ra.SA.GetCertificate(ctx, serial)
+	if berrors.Is(err, berrors.NotFound) {
+		return "", "", berrors.MalformedError("Replaced certificate %s not found", serial)
+	} else if err != nil {
+		return "", "", err
+	}
+	if cert.RegistrationID != regID {
+		return "", "", berrors.UnauthorizedError("Replaced certificate %s wasn't issued to this account", serial)
+	}
+	parsed, err := x509.ParseCertificate(cert.DER)
+	if err != nil {
+		return "", "", berrors.InternalServerError("parsing replaced certificate %s: %s", serial, err)
+	}
+	if !bytes.Equal(parsed.AuthorityKeyId, aki) {
+		return "", "", berrors.MalformedError("Replaced certificate %s wasn't issued by the issuer in certificate ID %q", serial, id)
+	}
+	if !namesOverlap(parsed.DNSNames, names) {
+		return "", "", berrors.MalformedError("Replaced certificate %s has none of the order's identifiers", serial)
+	}
+	replaced, err := ra.SA.CertificateReplaced(ctx, &sapb.Serial{Serial: &serial})
+	if err != nil {
+		return "", "", err
+	}
+	if *replaced.Exists {
+		return "", "", berrors.MalformedError("Replaced certificate %s has already been replaced", serial)
+	}
+
+	status, err := ra.SA.GetCertificateStatus(ctx, serial)
+	if err != nil {
+		return "", "", err
+	}
+	return serial, replacementReason(status.Status, parsed.NotBefore, parsed.NotAfter, ra.clk.Now()), nil
+}
+
+// renewsReplaced returns whether a certificate for names issued to the
+// account regID, for an order that replaces the certificate with serial, is a
+// renewal of it: every one of names is in the replaced certificate, and the
+// replaced certificate hasn't already been replaced by another order. Renewals
+// are exempt from the certificates per name limit. Since only the SA can tell
+// whether a certificate has already been replaced, nothing is a renewal unless
+// the StoreReplacementOrders feature is enabled.
+func (ra *RegistrationAuthorityImpl) renewsReplaced(ctx context.Context, regID int64, names []string, serial string) (bool, error) {
+	if serial == "" || !features.Enabled(features.StoreReplacementOrders) {
+		return false, nil
+	}
+	cert, err := ra.SA.GetCertificate(ctx, serial)
+	if berrors.Is(err, berrors.NotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if cert.RegistrationID != regID {
+		return false, nil
+	}
+	parsed, err := x509.ParseCertificate(cert.DER)
+	if err != nil {
+		return false, berrors.InternalServerError("parsing replaced certificate %s: %s", serial, err)
+	}
+	if !namesSubset(names, parsed.DNSNames) {
+		return false, nil
+	}
+	replaced, err := ra.SA.CertificateReplaced(ctx, &sapb.Serial{Serial: &serial})
+	if err != nil {
+		return false, err
+	}
+	return !*replaced.Exists, nil
+}
+
+// replacementReason classifies why a certificate is being replaced at now.
+func replacementReason(status core.OCSPStatus, notBefore, notAfter, now time.Time) string {
+	if status == core.OCSPStatusRevoked {
+		return replacedRevoked
+	}
+	if now.Before(notBefore.Add(notAfter.Sub(notBefore) * 2 / 3)) {
+		return replacedEarly
+	}
+	return replacedRenewal
+}
+
+// namesSubset returns whether every name in a is also in b.
+func namesSubset(a, b []string) bool {
+	names := make(map[string]bool, len(b))
+	for _, name := range b {
+		names[strings.ToLower(name)] = true
+	}
+	for _, name := range a {
+		if !names[strings.ToLower(name)] {
+			return false
+		}
+	}
+	return true
+}
+
+// namesOverlap returns whether a and b have any name in common.
+func namesOverlap(a, b []string) bool {
+	names := make(map[string]bool, len(a))
+	for _, name := range a {
+		names[strings.ToLower(name)] = true
+	}
+	for _, name := range b {
+		if names[strings.ToLower(name)] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package ra
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/features"
+	"github.com/letsencrypt/boulder/mocks"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestParseARICertID(t *testing.T) {
+	// The example from draft-ietf-acme-ari: an AKI of
+	// 69:88:5B:6B:87:46:40:41:E1:B3:7B:84:7B:A0:AE:2C:DE:01:C8:D4 and serial
+	// 00:87:65:43:21
+	aki, serial, err := parseARICertID("aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdlQyE")
+	test.AssertNotError(t, err, "parseARICertID failed")
+	test.AssertByteEquals(t, aki, []byte{0x69, 0x88, 0x5b, 0x6b, 0x87, 0x46, 0x40, 0x41, 0xe1, 0xb3, 0x7b, 0x84, 0x7b, 0xa0, 0xae, 0x2c, 0xde, 0x01, 0xc8, 0xd4})
+	test.AssertEquals(t, serial, core.SerialToString(big.NewInt(0x87654321)))
+
+	for _, id := range []string{"", "aYhba4dGQEHhs3uEe6CuLN4ByNQ", ".AIdlQyE", "aYhba4dGQEHhs3uEe6CuLN4ByNQ.", "a.b.c", "aYhb!.AIdlQyE", "aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdl=QyE"} {
+		_, _, err := parseARICertID(id)
+		test.Assert(t, berrors.Is(err, berrors.Malformed), "invalid certificate ID "+id+" was parsed")
+	}
+}
+
+func TestReplacementReason(t *testing.T) {
+	notBefore := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := notBefore.Add(90 * 24 * time.Hour)
+	test.AssertEquals(t, replacementReason(core.OCSPStatusGood, notBefore, notAfter, notBefore.Add(24*time.Hour)), replacedEarly)
+	test.AssertEquals(t, replacementReason(core.OCSPStatusGood, notBefore, notAfter, notBefore.Add(60*24*time.Hour)), replacedRenewal)
+	test.AssertEquals(t, replacementReason(core.OCSPStatusRevoked, notBefore, notAfter, notBefore.Add(24*time.Hour)), replacedRevoked)
+}
+
+// replacedCertSA is a mock SA with one certificate, with serial "00000000000000000000000000000000c0de".
+type replacedCertSA struct {
+	mocks.StorageAuthority
+	cert     core.Certificate
+	status   core.OCSPStatus
+	replaced bool
+}
+
+func (sa *replacedCertSA) GetCertificate(_ context.Context, serial string) (core.Certificate, error) {
+	if serial != sa.cert.Serial {
+		return core.Certificate{}, berrors.NotFoundError("no certificate with serial %s", serial)
+	}
+	return sa.cert, nil
+}
+
+func (sa *replacedCertSA) GetCertificateStatus(_ context.Context, serial string) (core.CertificateStatus, error) {
+	return core.CertificateStatus{Serial: serial, Status: sa.status}, nil
+}
+
+func (sa *replacedCertSA) CertificateReplaced(_ context.Context, req *sapb.Serial) (*sapb.Exists, error) {
+	replaced := sa.replaced && *req.Serial == sa.cert.Serial
+	return &sapb.Exists{Exists: &replaced}, nil
+}
+
+// newReplacedCertSA returns a replacedCertSA with a certificate for
+// example.com and www.example.com issued to account 1, at fc's time 80 days
+// into its 90 day lifetime, and an RA using it. The certificate's ARI
+// certificate ID is "AQID.wN4": "AQID" is the AKI, "wN4" the serial.
+func newReplacedCertSA(t *testing.T, fc clock.FakeClock) (*replacedCertSA, *RegistrationAuthorityImpl) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "generating key")
+	serial := big.NewInt(0xc0de)
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber:   serial,
+		DNSNames:       []string{"example.com", "www.example.com"},
+		NotBefore:      fc.Now().Add(-80 * 24 * time.Hour),
+		NotAfter:       fc.Now().Add(10 * 24 * time.Hour),
+		AuthorityKeyId: []byte{1, 2, 3},
+	}, &x509.Certificate{SerialNumber: big.NewInt(1)}, key.Public(), key)
+	test.AssertNotError(t, err, "creating certificate")
+	sa := &replacedCertSA{
+		cert: core.Certificate{
+			RegistrationID: 1,
+			Serial:         core.SerialToString(serial),
+			DER:            der,
+		},
+		status: core.OCSPStatusGood,
+	}
+	return sa, &RegistrationAuthorityImpl{SA: sa, clk: fc}
+}
+
+func TestCheckReplaces(t *testing.T) {
+	fc := clock.NewFake()
+	fc.Set(time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC))
+	sa, ra := newReplacedCertSA(t, fc)
+	id := "AQID.wN4"
+
+	replaced, reason, err := ra.checkReplaces(context.Background(), 1, []string{"example.com", "example.net"}, id)
+	test.AssertNotError(t, err, "checkReplaces failed")
+	test.AssertEquals(t, replaced, sa.cert.Serial)
+	test.AssertEquals(t, reason, replacedRenewal)
+
+	sa.status = core.OCSPStatusRevoked
+	_, reason, err = ra.checkReplaces(context.Background(), 1, []string{"www.example.com"}, id)
+	test.AssertNotError(t, err, "checkReplaces failed for a revoked certificate")
+	test.AssertEquals(t, reason, replacedRevoked)
+
+	testCases := []struct {
+		name    string
+		regID   int64
+		names   []string
+		id      string
+		errType berrors.ErrorType
+	}{
+		{"unknown certificate", 1, []string{"example.com"}, "AQID.wN8", berrors.Malformed},
+		{"other account", 2, []string{"example.com"}, id, berrors.Unauthorized},
+		{"other issuer", 1, []string{"example.com"}, "AQIE.wN4", berrors.Malformed},
+		{"no common names", 1, []string{"example.net"}, id, berrors.Malformed},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := ra.checkReplaces(context.Background(), tc.regID, tc.names, tc.id)
+			test.Assert(t, berrors.Is(err, tc.errType), "wrong error: "+err.Error())
+		})
+	}
+
+	// A certificate can only be replaced once
+	sa.replaced = true
+	_, _, err = ra.checkReplaces(context.Background(), 1, []string{"example.com"}, id)
+	test.Assert(t, berrors.Is(err, berrors.Malformed), "checkReplaces accepted an already replaced certificate")
+}
+
+func TestRenewsReplaced(t *testing.T) {
+	fc := clock.NewFake()
+	fc.Set(time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC))
+	sa, ra := newReplacedCertSA(t, fc)
+	ctx := context.Background()
+	names := []string{"example.com", "www.example.com"}
+
+	renewal, err := ra.renewsReplaced(ctx, 1, names, sa.cert.Serial)
+	test.AssertNotError(t, err, "renewsReplaced failed")
+	test.Assert(t, !renewal, "order was a renewal without StoreReplacementOrders")
+
+	err = features.Set(map[string]bool{"StoreReplacementOrders": true})
+	test.AssertNotError(t, err, "setting feature")
+	defer features.Reset()
+
+	renewal, err = ra.renewsReplaced(ctx, 1, names, sa.cert.Serial)
+	test.AssertNotError(t, err, "renewsReplaced failed")
+	test.Assert(t, renewal, "order for the replaced certificate's names wasn't a renewal")
+
+	renewal, err = ra.renewsReplaced(ctx, 1, []string{"www.example.com"}, sa.cert.Serial)
+	test.AssertNotError(t, err, "renewsReplaced failed")
+	test.Assert(t, renewal, "order for some of the replaced certificate's names wasn't a renewal")
+
+	testCases := []struct {
+		name   string
+		regID  int64
+		names  []string
+		serial string
+	}{
+		{"no replaced certificate", 1, names, ""},
+		{"unknown certificate", 1, names, "00000000000000000000000000000000c0df"},
+		{"other account", 2, names, sa.cert.Serial},
+		{"names not in the replaced certificate", 1, []string{"example.net"}, sa.cert.Serial},
+		{"some names not in the replaced certificate", 1, []string{"example.com", "example.net"}, sa.cert.Serial},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			renewal, err := ra.renewsReplaced(ctx, tc.regID, tc.names, tc.serial)
+			test.AssertNotError(t, err, "renewsReplaced failed")
+			test.Assert(t, !renewal, "order was a renewal")
+		})
+	}
+
+	// Once another order has replaced the certificate, it can't be renewed
+	// again
+	sa.replaced = true
+	renewal, err = ra.renewsReplaced(ctx, 1, names, sa.cert.Serial)
+	test.AssertNotError(t, err, "renewsReplaced failed")
+	test.Assert(t, !renewal, "order replacing an already replaced certificate was a renewal")
+}
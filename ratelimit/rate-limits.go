@@ -1,6 +1,9 @@
 package ratelimit
 
 import (
+	"fmt"
+	"net"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,7 +23,53 @@ type Limits interface {
 	CertificatesPerFQDNSet() RateLimitPolicy
 	PendingOrdersPerAccount() RateLimitPolicy
 	NewOrdersPerAccount() RateLimitPolicy
+	RegistrationIPExempt(ip net.IP) bool
 	LoadPolicies(contents []byte) error
+	LoadOverrides(overrides []Override)
+}
+
+// The names of the individual rate limits, as they appear in the YAML policy
+// file. These are also used to identify the limit an Override applies to.
+const (
+	CertificatesPerName             = "certificatesPerName"
+	RegistrationsPerIP              = "registrationsPerIP"
+	RegistrationsPerIPRange         = "registrationsPerIPRange"
+	PendingAuthorizationsPerAccount = "pendingAuthorizationsPerAccount"
+	InvalidAuthorizationsPerAccount = "invalidAuthorizationsPerAccount"
+	PendingOrdersPerAccount         = "pendingOrdersPerAccount"
+	NewOrdersPerAccount             = "newOrdersPerAccount"
+	CertificatesPerFQDNSet          = "certificatesPerFQDNSet"
+)
+
+var limitNames = map[string]bool{
+	CertificatesPerName:             true,
+	RegistrationsPerIP:              true,
+	RegistrationsPerIPRange:         true,
+	PendingAuthorizationsPerAccount: true,
+	InvalidAuthorizationsPerAccount: true,
+	PendingOrdersPerAccount:         true,
+	NewOrdersPerAccount:             true,
+	CertificatesPerFQDNSet:          true,
+}
+
+// ValidLimitName returns true if name is the name of a known rate limit.
+func ValidLimitName(name string) bool {
+	return limitNames[name]
+}
+
+// Override is a threshold for a single rate limit that applies to a single
+// key (e.g. a registered domain) or a single registration ID. Unlike the
+// overrides in the policy file, these are managed at runtime by operators
+// and are stored by the SA.
+type Override struct {
+	LimitName string
+	// Key is the override key, e.g. the registered domain for the
+	// CertificatesPerName limit. It is empty for registration overrides.
+	Key string
+	// RegistrationID is the ID of the registration the override applies to. It
+	// is zero for key overrides.
+	RegistrationID int64
+	Threshold      int
 }
 
 // limitsImpl is an unexported implementation of the Limits interface. It acts
@@ -30,6 +79,48 @@ type Limits interface {
 type limitsImpl struct {
 	sync.RWMutex
 	rlPolicy *rateLimitConfig
+	// registrationExemptions holds the parsed form of
+	// rlPolicy.RegistrationIPExemptions.
+	registrationExemptions []*net.IPNet
+	// overrides holds the overrides loaded with LoadOverrides, indexed by
+	// limit name. They take precedence over the overrides from the policy
+	// file.
+	overrides map[string]*overrideSet
+}
+
+type overrideSet struct {
+	keys          map[string]int
+	registrations map[int64]int
+}
+
+// withOverrides returns a copy of policy with the dynamic overrides for the
+// named limit merged in. The caller must hold the read lock.
+func (r *limitsImpl) withOverrides(name string, policy RateLimitPolicy) RateLimitPolicy {
+	set, present := r.overrides[name]
+	if !present {
+		return policy
+	}
+	if len(set.keys) > 0 {
+		keys := make(map[string]int, len(policy.Overrides)+len(set.keys))
+		for k, v := range policy.Overrides {
+			keys[k] = v
+		}
+		for k, v := range set.keys {
+			keys[k] = v
+		}
+		policy.Overrides = keys
+	}
+	if len(set.registrations) > 0 {
+		regs := make(map[int64]int, len(policy.RegistrationOverrides)+len(set.registrations))
+		for k, v := range policy.RegistrationOverrides {
+			regs[k] = v
+		}
+		for k, v := range set.registrations {
+			regs[k] = v
+		}
+		policy.RegistrationOverrides = regs
+	}
+	return policy
 }
 
 func (r *limitsImpl) CertificatesPerName() RateLimitPolicy {
@@ -38,7 +129,7 @@ func (r *limitsImpl) CertificatesPerName() RateLimitPolicy {
 	if r.rlPolicy == nil {
 		return RateLimitPolicy{}
 	}
-	return r.rlPolicy.CertificatesPerName
+	return r.withOverrides(CertificatesPerName, r.rlPolicy.CertificatesPerName)
 }
 
 func (r *limitsImpl) RegistrationsPerIP() RateLimitPolicy {
@@ -47,7 +138,7 @@ func (r *limitsImpl) RegistrationsPerIP() RateLimitPolicy {
 	if r.rlPolicy == nil {
 		return RateLimitPolicy{}
 	}
-	return r.rlPolicy.RegistrationsPerIP
+	return r.withOverrides(RegistrationsPerIP, r.rlPolicy.RegistrationsPerIP)
 }
 
 func (r *limitsImpl) RegistrationsPerIPRange() RateLimitPolicy {
@@ -56,7 +147,7 @@ func (r *limitsImpl) RegistrationsPerIPRange() RateLimitPolicy {
 	if r.rlPolicy == nil {
 		return RateLimitPolicy{}
 	}
-	return r.rlPolicy.RegistrationsPerIPRange
+	return r.withOverrides(RegistrationsPerIPRange, r.rlPolicy.RegistrationsPerIPRange)
 }
 
 func (r *limitsImpl) PendingAuthorizationsPerAccount() RateLimitPolicy {
@@ -65,7 +156,7 @@ func (r *limitsImpl) PendingAuthorizationsPerAccount() RateLimitPolicy {
 	if r.rlPolicy == nil {
 		return RateLimitPolicy{}
 	}
-	return r.rlPolicy.PendingAuthorizationsPerAccount
+	return r.withOverrides(PendingAuthorizationsPerAccount, r.rlPolicy.PendingAuthorizationsPerAccount)
 }
 
 func (r *limitsImpl) InvalidAuthorizationsPerAccount() RateLimitPolicy {
@@ -74,7 +165,7 @@ func (r *limitsImpl) InvalidAuthorizationsPerAccount() RateLimitPolicy {
 	if r.rlPolicy == nil {
 		return RateLimitPolicy{}
 	}
-	return r.rlPolicy.InvalidAuthorizationsPerAccount
+	return r.withOverrides(InvalidAuthorizationsPerAccount, r.rlPolicy.InvalidAuthorizationsPerAccount)
 }
 
 func (r *limitsImpl) CertificatesPerFQDNSet() RateLimitPolicy {
@@ -83,7 +174,7 @@ func (r *limitsImpl) CertificatesPerFQDNSet() RateLimitPolicy {
 	if r.rlPolicy == nil {
 		return RateLimitPolicy{}
 	}
-	return r.rlPolicy.CertificatesPerFQDNSet
+	return r.withOverrides(CertificatesPerFQDNSet, r.rlPolicy.CertificatesPerFQDNSet)
 }
 
 func (r *limitsImpl) PendingOrdersPerAccount() RateLimitPolicy {
@@ -92,7 +183,7 @@ func (r *limitsImpl) PendingOrdersPerAccount() RateLimitPolicy {
 	if r.rlPolicy == nil {
 		return RateLimitPolicy{}
 	}
-	return r.rlPolicy.PendingOrdersPerAccount
+	return r.withOverrides(PendingOrdersPerAccount, r.rlPolicy.PendingOrdersPerAccount)
 }
 
 func (r *limitsImpl) NewOrdersPerAccount() RateLimitPolicy {
@@ -101,7 +192,47 @@ func (r *limitsImpl) NewOrdersPerAccount() RateLimitPolicy {
 	if r.rlPolicy == nil {
 		return RateLimitPolicy{}
 	}
-	return r.rlPolicy.NewOrdersPerAccount
+	return r.withOverrides(NewOrdersPerAccount, r.rlPolicy.NewOrdersPerAccount)
+}
+
+// RegistrationIPExempt returns true if ip is within one of the networks
+// exempted from the RegistrationsPerIP and RegistrationsPerIPRange limits.
+func (r *limitsImpl) RegistrationIPExempt(ip net.IP) bool {
+	r.RLock()
+	defer r.RUnlock()
+	for _, network := range r.registrationExemptions {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseExemptions parses a list of IP addresses and CIDR ranges. A bare IP
+// address is treated as a network containing only that address.
+func parseExemptions(exemptions []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, e := range exemptions {
+		if !strings.Contains(e, "/") {
+			ip := net.ParseIP(e)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid registration IP exemption %q", e)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(e)
+		if err != nil {
+			return nil, fmt.Errorf("invalid registration IP exemption %q: %s", e, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
 }
 
 // LoadPolicies loads various rate limiting policies from a byte array of
@@ -112,13 +243,46 @@ func (r *limitsImpl) LoadPolicies(contents []byte) error {
 	if err != nil {
 		return err
 	}
+	exemptions, err := parseExemptions(newPolicy.RegistrationIPExemptions)
+	if err != nil {
+		return err
+	}
 
 	r.Lock()
 	r.rlPolicy = &newPolicy
+	r.registrationExemptions = exemptions
 	r.Unlock()
 	return nil
 }
 
+// LoadOverrides replaces the set of dynamic overrides with the provided
+// overrides. Overrides for unknown limits are ignored.
+func (r *limitsImpl) LoadOverrides(overrides []Override) {
+	newOverrides := make(map[string]*overrideSet)
+	for _, o := range overrides {
+		if !ValidLimitName(o.LimitName) {
+			continue
+		}
+		set, present := newOverrides[o.LimitName]
+		if !present {
+			set = &overrideSet{
+				keys:          make(map[string]int),
+				registrations: make(map[int64]int),
+			}
+			newOverrides[o.LimitName] = set
+		}
+		if o.RegistrationID != 0 {
+			set.registrations[o.RegistrationID] = o.Threshold
+		} else {
+			set.keys[o.Key] = o.Threshold
+		}
+	}
+
+	r.Lock()
+	r.overrides = newOverrides
+	r.Unlock()
+}
+
 func New() Limits {
 	return &limitsImpl{}
 }
@@ -140,6 +304,10 @@ type rateLimitConfig struct {
 	// Note: Like RegistrationsPerIP, setting a RegistrationOverride has no
 	// effect here.
 	RegistrationsPerIPRange RateLimitPolicy `yaml:"registrationsPerIPRange"`
+	// IP addresses and CIDR ranges that are exempt from both
+	// RegistrationsPerIP and RegistrationsPerIPRange, e.g. the egress points
+	// of large NATs that legitimately create many registrations.
+	RegistrationIPExemptions []string `yaml:"registrationIPExemptions"`
 	// Number of pending authorizations that can exist per account. Overrides by
 	// key are not applied, but overrides by registration are.
 	PendingAuthorizationsPerAccount RateLimitPolicy `yaml:"pendingAuthorizationsPerAccount"`
@@ -179,6 +347,11 @@ type RateLimitPolicy struct {
 	// than the default. If both key-based and registration-based overrides are
 	// available, the registration-based on takes priority.
 	RegistrationOverrides map[int64]int `yaml:"registrationOverrides"`
+	// When LogOnly is true the limit is evaluated and requests that would
+	// exceed it are logged and counted, but are not rejected. This allows a new
+	// limit or a tightened threshold to run in shadow mode before it is
+	// enforced.
+	LogOnly bool `yaml:"logOnly"`
 }
 
 // Enabled returns true iff the RateLimitPolicy is enabled.
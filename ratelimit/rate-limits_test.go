@@ -2,6 +2,7 @@ package ratelimit
 
 import (
 	"io/ioutil"
+	"net"
 	"testing"
 	"time"
 
@@ -137,6 +138,12 @@ func TestLoadPolicies(t *testing.T) {
 	})
 	test.AssertEquals(t, len(regsPerIP.RegistrationOverrides), 0)
 
+	// Test that the registration IP exemptions parsed correctly
+	test.Assert(t, policy.RegistrationIPExempt(net.ParseIP("192.0.2.7")), "192.0.2.7 should be exempt")
+	test.Assert(t, policy.RegistrationIPExempt(net.ParseIP("2001:db8:0:1::1")), "2001:db8:0:1::1 should be exempt")
+	test.Assert(t, !policy.RegistrationIPExempt(net.ParseIP("2001:db8:1::1")), "2001:db8:1::1 should not be exempt")
+	test.Assert(t, !policy.RegistrationIPExempt(net.ParseIP("127.0.0.1")), "127.0.0.1 should not be exempt")
+
 	// Test that the PendingAuthorizationsPerAccount section parsed correctly
 	pendingAuthsPerAcct := policy.PendingAuthorizationsPerAccount()
 	test.AssertEquals(t, pendingAuthsPerAcct.Threshold, 150)
@@ -159,6 +166,19 @@ func TestLoadPolicies(t *testing.T) {
 	})
 	test.AssertEquals(t, len(certsPerFQDN.RegistrationOverrides), 0)
 
+	// Test that the log only flag parses
+	err = policy.LoadPolicies([]byte("newOrdersPerAccount:\n  threshold: 10\n  logOnly: true\n"))
+	test.AssertNotError(t, err, "Failed to parse logOnly policy")
+	test.Assert(t, policy.NewOrdersPerAccount().LogOnly, "NewOrdersPerAccount should be log only")
+	test.Assert(t, !policy.CertificatesPerName().LogOnly, "CertificatesPerName should not be log only")
+	err = policy.LoadPolicies(policyContent)
+	test.AssertNotError(t, err, "Failed to re-parse rate-limit-policies.yml")
+
+	// Test that loading an invalid exemption generates an error
+	err = policy.LoadPolicies([]byte("registrationIPExemptions: [\"10.0.0.0/99\"]"))
+	test.AssertError(t, err, "Failed to generate error loading invalid registration IP exemption")
+	test.Assert(t, policy.RegistrationIPExempt(net.ParseIP("192.0.2.7")), "LoadPolicies error changed the exemptions")
+
 	// Test that loading invalid YAML generates an error
 	err = policy.LoadPolicies([]byte("err"))
 	test.AssertError(t, err, "Failed to generate error loading invalid yaml policy file")
@@ -178,3 +198,56 @@ func TestLoadPolicies(t *testing.T) {
 	test.AssertEquals(t, emptyPolicy.PendingAuthorizationsPerAccount().Threshold, 0)
 	test.AssertEquals(t, emptyPolicy.CertificatesPerFQDNSet().Threshold, 0)
 }
+
+func TestLoadOverrides(t *testing.T) {
+	policy := New()
+	err := policy.LoadPolicies([]byte(`
+certificatesPerName:
+  window: 2160h
+  threshold: 2
+  overrides:
+    le.wtf: 10000
+    example.com: 5
+  registrationOverrides:
+    101: 1000
+`))
+	test.AssertNotError(t, err, "Failed to load policies")
+
+	policy.LoadOverrides([]Override{
+		{LimitName: CertificatesPerName, Key: "example.com", Threshold: 50},
+		{LimitName: CertificatesPerName, Key: "example.net", Threshold: 20},
+		{LimitName: CertificatesPerName, RegistrationID: 202, Threshold: 300},
+		{LimitName: "notARealLimit", Key: "example.com", Threshold: 1},
+	})
+
+	certsPerName := policy.CertificatesPerName()
+	test.AssertDeepEquals(t, certsPerName.Overrides, map[string]int{
+		"le.wtf":      10000,
+		"example.com": 50,
+		"example.net": 20,
+	})
+	test.AssertDeepEquals(t, certsPerName.RegistrationOverrides, map[int64]int{
+		101: 1000,
+		202: 300,
+	})
+	// Limits without any dynamic overrides should be unchanged
+	test.AssertEquals(t, len(policy.CertificatesPerFQDNSet().Overrides), 0)
+
+	// Loading a new set of overrides should replace the previous set entirely
+	policy.LoadOverrides(nil)
+	certsPerName = policy.CertificatesPerName()
+	test.AssertDeepEquals(t, certsPerName.Overrides, map[string]int{
+		"le.wtf":      10000,
+		"example.com": 5,
+	})
+	test.AssertDeepEquals(t, certsPerName.RegistrationOverrides, map[int64]int{
+		101: 1000,
+	})
+}
+
+func TestValidLimitName(t *testing.T) {
+	test.Assert(t, ValidLimitName(CertificatesPerName), "CertificatesPerName should be valid")
+	test.Assert(t, ValidLimitName("newOrdersPerAccount"), "newOrdersPerAccount should be valid")
+	test.Assert(t, !ValidLimitName("NewOrdersPerAccount"), "limit names should be case sensitive")
+	test.Assert(t, !ValidLimitName(""), "empty limit name should be invalid")
+}
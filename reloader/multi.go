@@ -0,0 +1,166 @@
+package reloader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// File is one of the files loaded by a reloader that watches several files.
+type File struct {
+	// Name is the path of the file.
+	Name     string
+	Contents []byte
+}
+
+// listFiles returns the sorted paths of the regular files matched by pattern,
+// which is either a directory, matching every file in it whose name doesn't
+// start with a dot, or a glob pattern as understood by filepath.Glob.
+func listFiles(pattern string) ([]string, error) {
+	var names []string
+	info, err := os.Stat(pattern)
+	if err == nil && info.IsDir() {
+		entries, err := ioutil.ReadDir(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Mode().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+				names = append(names, filepath.Join(pattern, entry.Name()))
+			}
+		}
+	} else {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if info.Mode().IsRegular() {
+				names = append(names, match)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// statFiles returns the modification times of the files matched by pattern.
+func statFiles(pattern string) (map[string]time.Time, error) {
+	names, err := listFiles(pattern)
+	if err != nil {
+		return nil, err
+	}
+	modTimes := make(map[string]time.Time, len(names))
+	for _, name := range names {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[name] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// changed returns true if a file was added, removed or modified.
+func changed(old, current map[string]time.Time) bool {
+	if len(old) != len(current) {
+		return true
+	}
+	for name, modTime := range current {
+		oldModTime, ok := old[name]
+		if !ok || !modTime.Equal(oldModTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// readFiles reads the files in modTimes, sorted by name.
+func readFiles(modTimes map[string]time.Time) ([]File, error) {
+	names := make([]string, 0, len(modTimes))
+	for name := range modTimes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	files := make([]File, len(names))
+	for i, name := range names {
+		b, err := readFile(name)
+		if err != nil {
+			return nil, err
+		}
+		files[i] = File{Name: name, Contents: b}
+	}
+	return files, nil
+}
+
+// NewMulti is like New, but loads every file matched by pattern, which is
+// either a directory or a glob pattern such as "/etc/boulder/policies/*.yaml".
+// When pattern is a directory, every regular file in it is loaded, except
+// those whose name starts with a dot (e.g. editor swap files). dataCallback
+// receives the contents of all the files sorted by name, and is called again
+// with the complete new set whenever a file is modified, added or removed.
+// A glob pattern that matches no files on the first load is an error, but
+// files may all be removed later.
+func NewMulti(pattern string, dataCallback func([]File) error, errorCallback func(error)) (*Reloader, error) {
+	if errorCallback == nil {
+		errorCallback = func(e error) {}
+	}
+	modTimes, err := statFiles(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(modTimes) == 0 {
+		if _, err := os.Stat(pattern); err != nil {
+			return nil, fmt.Errorf("%q matched no files", pattern)
+		}
+	}
+	files, err := readFiles(modTimes)
+	if err != nil {
+		return nil, err
+	}
+	stopChan := make(chan struct{})
+	tickerStop, tickChan := makeTicker(fileCheckInterval)
+	loop := func() {
+		for {
+			select {
+			case <-stopChan:
+				tickerStop()
+				return
+			case <-tickChan:
+				currentModTimes, err := statFiles(pattern)
+				if err != nil {
+					errorCallback(err)
+					continue
+				}
+				if !changed(modTimes, currentModTimes) {
+					continue
+				}
+				files, err := readFiles(currentModTimes)
+				if err != nil {
+					errorCallback(err)
+					continue
+				}
+				modTimes = currentModTimes
+				err = dataCallback(files)
+				if err != nil {
+					errorCallback(err)
+				}
+			}
+		}
+	}
+	err = dataCallback(files)
+	if err != nil {
+		tickerStop()
+		return nil, err
+	}
+	go loop()
+	return &Reloader{stopChan}, nil
+}
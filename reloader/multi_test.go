@@ -0,0 +1,112 @@
+package reloader
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestNewMulti(t *testing.T) {
+	fakeTick, restoreMakeTicker := makeFakeMakeTicker()
+	defer restoreMakeTicker()
+
+	dir, err := ioutil.TempDir("", "test-multi")
+	test.AssertNotError(t, err, "creating temporary directory")
+	defer os.RemoveAll(dir)
+
+	// writeFile writes contents to name in dir with a modification time newer
+	// than any before.
+	mtime := time.Now()
+	writeFile := func(name, contents string) {
+		path := filepath.Join(dir, name)
+		err := ioutil.WriteFile(path, []byte(contents), 0644)
+		test.AssertNotError(t, err, "writing file")
+		mtime = mtime.Add(time.Minute)
+		test.AssertNotError(t, os.Chtimes(path, mtime, mtime), "setting modification time")
+	}
+	writeFile("b.yaml", "b")
+	writeFile("a.yaml", "a")
+	writeFile(".a.yaml.swp", "swap")
+	writeFile("c.txt", "c")
+
+	// contents returns the names, relative to dir, and contents of files
+	contents := func(files []File) map[string]string {
+		m := make(map[string]string)
+		for i, f := range files {
+			if i > 0 && files[i-1].Name >= f.Name {
+				t.Errorf("files not sorted by name: %q before %q", files[i-1].Name, f.Name)
+			}
+			m[filepath.Base(f.Name)] = string(f.Contents)
+		}
+		return m
+	}
+
+	_, err = NewMulti(filepath.Join(dir, "*.json"), func([]File) error { return nil }, testErrCb(t))
+	test.AssertError(t, err, "NewMulti accepted a glob that matches no files")
+
+	// Each reloader gets its own fake ticker. The loop handles one tick at a
+	// time, so once the second of two ticks has been received the first has
+	// been fully processed.
+	loads := make(chan map[string]string, 10)
+	r, err := NewMulti(dir, func(files []File) error {
+		loads <- contents(files)
+		return nil
+	}, testErrCb(t))
+	test.AssertNotError(t, err, "NewMulti failed for a directory")
+	defer r.Stop()
+	test.AssertDeepEquals(t, <-loads, map[string]string{"a.yaml": "a", "b.yaml": "b", "c.txt": "c"})
+
+	globTick, restoreGlobTicker := makeFakeMakeTicker()
+	defer restoreGlobTicker()
+	globLoads := make(chan map[string]string, 10)
+	gr, err := NewMulti(filepath.Join(dir, "*.yaml"), func(files []File) error {
+		globLoads <- contents(files)
+		return nil
+	}, testErrCb(t))
+	test.AssertNotError(t, err, "NewMulti failed for a glob")
+	defer gr.Stop()
+	test.AssertDeepEquals(t, <-globLoads, map[string]string{"a.yaml": "a", "b.yaml": "b"})
+
+	tick := func() {
+		fakeTick <- time.Now()
+		fakeTick <- time.Now()
+		globTick <- time.Now()
+		globTick <- time.Now()
+	}
+	// noLoad checks that a reloader hasn't loaded its files again.
+	noLoad := func(name string, loads chan map[string]string) {
+		select {
+		case l := <-loads:
+			t.Errorf("%s reloader reloaded unexpectedly: %v", name, l)
+		default:
+		}
+	}
+
+	tick()
+	noLoad("directory", loads)
+	noLoad("glob", globLoads)
+
+	writeFile("a.yaml", "a2")
+	tick()
+	test.AssertDeepEquals(t, <-loads, map[string]string{"a.yaml": "a2", "b.yaml": "b", "c.txt": "c"})
+	test.AssertDeepEquals(t, <-globLoads, map[string]string{"a.yaml": "a2", "b.yaml": "b"})
+
+	writeFile("d.yaml", "d")
+	tick()
+	test.AssertDeepEquals(t, <-loads, map[string]string{"a.yaml": "a2", "b.yaml": "b", "c.txt": "c", "d.yaml": "d"})
+	test.AssertDeepEquals(t, <-globLoads, map[string]string{"a.yaml": "a2", "b.yaml": "b", "d.yaml": "d"})
+
+	test.AssertNotError(t, os.Remove(filepath.Join(dir, "b.yaml")), "removing file")
+	tick()
+	test.AssertDeepEquals(t, <-loads, map[string]string{"a.yaml": "a2", "c.txt": "c", "d.yaml": "d"})
+	test.AssertDeepEquals(t, <-globLoads, map[string]string{"a.yaml": "a2", "d.yaml": "d"})
+
+	writeFile("c.txt", "c2")
+	tick()
+	test.AssertDeepEquals(t, <-loads, map[string]string{"a.yaml": "a2", "c.txt": "c2", "d.yaml": "d"})
+	noLoad("glob", globLoads)
+}
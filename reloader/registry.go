@@ -0,0 +1,176 @@
+package reloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	blog "github.com/letsencrypt/boulder/log"
+)
+
+// Section is a part of a service's configuration that lives in its own file
+// and is reloaded whenever that file changes, e.g. rate limit policies or
+// feature flags.
+type Section struct {
+	// Name describes the section in logs and status reports.
+	Name string
+	// File is the path of the file holding the section. If LoadFiles is set
+	// instead of Load, it is a directory or glob pattern matching the files
+	// holding the section, as for NewMulti.
+	File string
+	// Remote, if set instead of File, fetches the section from an HTTPS URL
+	// as for NewRemote. It can only be used with Load.
+	Remote *RemoteSource
+	// Load validates the contents of the file and, only if they are valid,
+	// replaces the section's current config with them. Each read of the
+	// config must see either all of the new contents or none of them, but
+	// reads made at different times while handling a single request may see
+	// different versions. If Load returns an error the current config must
+	// stay in effect.
+	Load func(contents []byte) error
+	// LoadFiles is like Load, for sections split across several files. It
+	// receives the contents of every file, sorted by name.
+	LoadFiles func(files []File) error
+}
+
+// SectionStatus reports the state of a registered Section.
+type SectionStatus struct {
+	Name string `json:"name"`
+	File string `json:"file"`
+	// SHA256 is the hex encoded hash of the contents in effect.
+	SHA256 string    `json:"sha256"`
+	Loaded time.Time `json:"loaded"`
+	// LastError is the error from the last attempt to load the file, if it
+	// failed. It is cleared by the next successful load.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// Registry keeps track of the Sections registered by a service.
+type Registry struct {
+	mu        sync.Mutex
+	sections  []*SectionStatus
+	reloaders []*Reloader
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Default is the Registry used by Register. Its status is served on the
+// debug port by the cmd package.
+var Default = NewRegistry()
+
+// Register loads a Section using Default.
+func Register(s Section) error {
+	return Default.Register(s)
+}
+
+// Register loads the Section's file and reloads it whenever it changes. The
+// first load happens synchronously, and Register returns its error so that
+// services can fail fast on an invalid config. Errors from later loads are
+// logged and recorded in the section's status, and the previous config stays
+// in effect.
+func (r *Registry) Register(s Section) error {
+	if s.Name == "" || (s.File == "") == (s.Remote == nil) || (s.Load == nil) == (s.LoadFiles == nil) {
+		return errors.New("reloadable config sections need a name, a file or remote source and exactly one load function")
+	}
+	if s.Remote != nil && s.LoadFiles != nil {
+		return errors.New("remote config sections must be loaded from a single file")
+	}
+	if s.Remote != nil {
+		s.File = s.Remote.URL
+	}
+	status := &SectionStatus{Name: s.Name, File: s.File}
+	// loaded records the result of loading contents whose hash is sum.
+	loaded := func(err error, sum []byte) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if err != nil {
+			status.LastError = err.Error()
+			return err
+		}
+		status.SHA256 = hex.EncodeToString(sum)
+		status.Loaded = time.Now()
+		status.LastError = ""
+		blog.Get().Infof("Loaded %s from %q, sha256: %s", s.Name, s.File, status.SHA256)
+		return nil
+	}
+	onError := func(err error) {
+		r.mu.Lock()
+		status.LastError = err.Error()
+		r.mu.Unlock()
+		blog.Get().AuditErrf("Error reloading %s from %q, continuing to use the previous version: %s",
+			s.Name, s.File, err)
+	}
+	var reloader *Reloader
+	var err error
+	load := func(contents []byte) error {
+		hash := sha256.Sum256(contents)
+		return loaded(s.Load(contents), hash[:])
+	}
+	if s.Remote != nil {
+		reloader, err = NewRemote(*s.Remote, load, onError)
+	} else if s.Load != nil {
+		reloader, err = New(s.File, load, onError)
+	} else {
+		reloader, err = NewMulti(s.File, func(files []File) error {
+			// Hash the names of the files as well as their contents, so that
+			// renaming a file changes the hash.
+			hash := sha256.New()
+			for _, f := range files {
+				fmt.Fprintf(hash, "%s\x00%d\x00", f.Name, len(f.Contents))
+				_, _ = hash.Write(f.Contents)
+			}
+			return loaded(s.LoadFiles(files), hash.Sum(nil))
+		}, onError)
+	}
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sections = append(r.sections, status)
+	r.reloaders = append(r.reloaders, reloader)
+	return nil
+}
+
+// Status returns the status of every registered Section, in the order they
+// were registered.
+func (r *Registry) Status() []SectionStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	statuses := make([]SectionStatus, len(r.sections))
+	for i, s := range r.sections {
+		statuses[i] = *s
+	}
+	return statuses
+}
+
+// Stop stops reloading every registered Section.
+func (r *Registry) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, reloader := range r.reloaders {
+		reloader.Stop()
+	}
+	r.sections, r.reloaders = nil, nil
+}
+
+// Handler serves the status of the registered Sections as JSON.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := json.Marshal(r.Status())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	})
+}
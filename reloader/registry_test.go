@@ -0,0 +1,119 @@
+package reloader
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/test"
+)
+
+var log = blog.UseMock()
+
+// loadGood accepts contents starting with "good".
+func loadGood(contents []byte) error {
+	if !strings.HasPrefix(string(contents), "good") {
+		return errors.New("contents aren't good")
+	}
+	return nil
+}
+
+func TestRegistry(t *testing.T) {
+	fakeTick, restoreMakeTicker := makeFakeMakeTicker()
+	defer restoreMakeTicker()
+
+	f, _ := ioutil.TempFile("", "test-registry.txt")
+	filename := f.Name()
+	defer os.Remove(filename)
+	_, _ = f.Write([]byte("good 1"))
+	_ = f.Close()
+
+	r := NewRegistry()
+	defer r.Stop()
+	err := r.Register(Section{Name: "test", File: filename})
+	test.AssertError(t, err, "Register accepted a section without a load function")
+	err = r.Register(Section{Name: "test", File: filename, Load: func([]byte) error { return errors.New("invalid") }})
+	test.AssertError(t, err, "Register accepted a section that failed to load")
+	test.AssertEquals(t, len(r.Status()), 0)
+
+	err = r.Register(Section{Name: "test", File: filename, Load: loadGood})
+	test.AssertNotError(t, err, "Register failed")
+	status := r.Status()
+	test.AssertEquals(t, len(status), 1)
+	test.AssertEquals(t, status[0].Name, "test")
+	test.AssertEquals(t, status[0].SHA256, fmt.Sprintf("%x", sha256.Sum256([]byte("good 1"))))
+	firstHash := status[0].SHA256
+	test.AssertEquals(t, status[0].LastError, "")
+
+	// reloadWith writes contents to the file with a newer modification time
+	// and waits until the registry has tried to load them.
+	mtime := time.Now()
+	reloadWith := func(contents string) SectionStatus {
+		err := ioutil.WriteFile(filename, []byte(contents), 0644)
+		test.AssertNotError(t, err, "writing file")
+		mtime = mtime.Add(time.Minute)
+		test.AssertNotError(t, os.Chtimes(filename, mtime, mtime), "setting modification time")
+		// The loop handles one tick at a time, so once the second tick has
+		// been received the first has been fully processed.
+		fakeTick <- time.Now()
+		fakeTick <- time.Now()
+		return r.Status()[0]
+	}
+
+	s := reloadWith("bad")
+	test.AssertEquals(t, s.LastError, "contents aren't good")
+	test.AssertEquals(t, s.SHA256, firstHash)
+
+	s = reloadWith("good 2")
+	test.AssertEquals(t, s.LastError, "")
+	test.Assert(t, s.SHA256 != firstHash, "hash not updated after reload")
+
+	rw := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rw, httptest.NewRequest("GET", "/debug/reloadable-config", nil))
+	var served []SectionStatus
+	test.AssertNotError(t, json.Unmarshal(rw.Body.Bytes(), &served), "unmarshaling status")
+	test.AssertEquals(t, len(served), 1)
+	test.AssertEquals(t, served[0].SHA256, s.SHA256)
+}
+
+func TestRegistryLoadFiles(t *testing.T) {
+	_, restoreMakeTicker := makeFakeMakeTicker()
+	defer restoreMakeTicker()
+
+	dir, err := ioutil.TempDir("", "test-registry")
+	test.AssertNotError(t, err, "creating temporary directory")
+	defer os.RemoveAll(dir)
+	test.AssertNotError(t, ioutil.WriteFile(filepath.Join(dir, "a"), []byte("good a"), 0644), "writing file")
+	test.AssertNotError(t, ioutil.WriteFile(filepath.Join(dir, "b"), []byte("good b"), 0644), "writing file")
+
+	r := NewRegistry()
+	defer r.Stop()
+	err = r.Register(Section{Name: "test", File: dir, Load: loadGood, LoadFiles: func([]File) error { return nil }})
+	test.AssertError(t, err, "Register accepted a section with two load functions")
+
+	var loaded []File
+	err = r.Register(Section{Name: "test", File: dir, LoadFiles: func(files []File) error {
+		for _, f := range files {
+			if err := loadGood(f.Contents); err != nil {
+				return err
+			}
+		}
+		loaded = files
+		return nil
+	}})
+	test.AssertNotError(t, err, "Register failed")
+	test.AssertEquals(t, len(loaded), 2)
+	status := r.Status()
+	test.AssertEquals(t, len(status), 1)
+	test.AssertEquals(t, status[0].LastError, "")
+	test.Assert(t, status[0].SHA256 != "", "hash not set")
+}
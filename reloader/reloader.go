@@ -8,11 +8,14 @@ import (
 )
 
 // Wrap time.Tick so we can override it in tests.
-var makeTicker = func() (func(), <-chan time.Time) {
-	t := time.NewTicker(1 * time.Second)
+var makeTicker = func(interval time.Duration) (func(), <-chan time.Time) {
+	t := time.NewTicker(interval)
 	return t.Stop, t.C
 }
 
+// fileCheckInterval is how often local files are checked for changes.
+const fileCheckInterval = time.Second
+
 // Reloader represents an ongoing reloader task.
 type Reloader struct {
 	stopChan chan<- struct{}
@@ -45,7 +48,7 @@ func New(filename string, dataCallback func([]byte) error, errorCallback func(er
 		return nil, err
 	}
 	stopChan := make(chan struct{})
-	tickerStop, tickChan := makeTicker()
+	tickerStop, tickChan := makeTicker(fileCheckInterval)
 	loop := func() {
 		for {
 			select {
@@ -77,7 +77,7 @@ func TestFirstSuccess(t *testing.T) {
 func makeFakeMakeTicker() (chan<- time.Time, func()) {
 	origMakeTicker := makeTicker
 	fakeTickChan := make(chan time.Time)
-	makeTicker = func() (func(), <-chan time.Time) {
+	makeTicker = func(time.Duration) (func(), <-chan time.Time) {
 		return func() {}, fakeTickChan
 	}
 	return fakeTickChan, func() {
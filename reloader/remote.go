@@ -0,0 +1,187 @@
+package reloader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	defaultPollInterval  = time.Minute
+	defaultRemoteTimeout = 10 * time.Second
+	// maxRemoteSize limits how much of a response body is read, so that a
+	// misbehaving server can't exhaust our memory.
+	maxRemoteSize = 64 << 20
+)
+
+// RemoteSource describes a file served over HTTPS.
+type RemoteSource struct {
+	// URL is the HTTPS URL the file is fetched from.
+	URL string
+	// RootCAs verifies the server's certificate. If nil, the system roots
+	// are used.
+	RootCAs *x509.CertPool
+	// PinnedSPKIHashes are base64 encoded SHA-256 hashes of
+	// SubjectPublicKeyInfos. If any are given, the server's verified chain
+	// must contain a certificate with one of them.
+	PinnedSPKIHashes []string
+	// PollInterval is how often the URL is polled for changes. It defaults
+	// to one minute.
+	PollInterval time.Duration
+	// Timeout limits each request. It defaults to ten seconds.
+	Timeout time.Duration
+}
+
+// remoteFetcher fetches a RemoteSource, using the ETag of the last response
+// so that the server only needs to send the file again once it changes.
+type remoteFetcher struct {
+	url    string
+	client *http.Client
+	etag   string
+	last   []byte
+}
+
+func newRemoteFetcher(source RemoteSource) (*remoteFetcher, error) {
+	u, err := url.Parse(source.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("remote source URL %q isn't an https URL", source.URL)
+	}
+	tlsConfig := &tls.Config{RootCAs: source.RootCAs}
+	if len(source.PinnedSPKIHashes) > 0 {
+		pins := make(map[string]bool, len(source.PinnedSPKIHashes))
+		for _, pin := range source.PinnedSPKIHashes {
+			if b, err := base64.StdEncoding.DecodeString(pin); err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("invalid SPKI pin %q: must be a base64 encoded SHA-256 hash", pin)
+			}
+			pins[pin] = true
+		}
+		tlsConfig.VerifyPeerCertificate = func(_ [][]byte, chains [][]*x509.Certificate) error {
+			for _, chain := range chains {
+				for _, cert := range chain {
+					hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+					if pins[base64.StdEncoding.EncodeToString(hash[:])] {
+						return nil
+					}
+				}
+			}
+			return errors.New("no certificate in the server's chain matches a pinned SPKI hash")
+		}
+	}
+	timeout := source.Timeout
+	if timeout == 0 {
+		timeout = defaultRemoteTimeout
+	}
+	return &remoteFetcher{
+		url: source.URL,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}, nil
+}
+
+// fetch returns the file's contents and true if they changed since the last
+// successful fetch, or false if they didn't.
+func (f *remoteFetcher) fetch() ([]byte, bool, error) {
+	req, err := http.NewRequest("GET", f.url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if f.etag != "" {
+		req.Header.Set("If-None-Match", f.etag)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil, false, nil
+	case http.StatusOK:
+	default:
+		return nil, false, fmt.Errorf("fetching %q: unexpected status %q", f.url, resp.Status)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxRemoteSize+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(b) > maxRemoteSize {
+		return nil, false, fmt.Errorf("fetching %q: response larger than %d bytes", f.url, maxRemoteSize)
+	}
+	f.etag = resp.Header.Get("ETag")
+	// Servers that don't send ETags, or that change them without changing
+	// the file, shouldn't cause a reload.
+	if f.last != nil && bytes.Equal(b, f.last) {
+		return nil, false, nil
+	}
+	f.last = b
+	return b, true, nil
+}
+
+// NewRemote is like New, but fetches the file from an HTTPS URL, polling it
+// for changes every PollInterval. Requests after the first send the ETag of
+// the last response in an If-None-Match header, so that the server can
+// respond with 304 Not Modified.
+func NewRemote(source RemoteSource, dataCallback func([]byte) error, errorCallback func(error)) (*Reloader, error) {
+	if errorCallback == nil {
+		errorCallback = func(e error) {}
+	}
+	fetcher, err := newRemoteFetcher(source)
+	if err != nil {
+		return nil, err
+	}
+	b, _, err := fetcher.fetch()
+	if err != nil {
+		return nil, err
+	}
+	interval := source.PollInterval
+	if interval == 0 {
+		interval = defaultPollInterval
+	}
+	stopChan := make(chan struct{})
+	tickerStop, tickChan := makeTicker(interval)
+	loop := func() {
+		for {
+			select {
+			case <-stopChan:
+				tickerStop()
+				return
+			case <-tickChan:
+				b, changed, err := fetcher.fetch()
+				if err != nil {
+					errorCallback(err)
+					continue
+				}
+				if !changed {
+					continue
+				}
+				err = dataCallback(b)
+				if err != nil {
+					errorCallback(err)
+				}
+			}
+		}
+	}
+	err = dataCallback(b)
+	if err != nil {
+		tickerStop()
+		return nil, err
+	}
+	go loop()
+	return &Reloader{stopChan}, nil
+}
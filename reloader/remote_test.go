@@ -0,0 +1,97 @@
+package reloader
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+// etagServer serves body with an ETag derived from its version, honouring
+// If-None-Match.
+type etagServer struct {
+	sync.Mutex
+	version     int
+	body        string
+	notModified int
+}
+
+func (s *etagServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	etag := fmt.Sprintf(`"v%d"`, s.version)
+	if r.Header.Get("If-None-Match") == etag {
+		s.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	_, _ = w.Write([]byte(s.body))
+}
+
+func (s *etagServer) set(body string) {
+	s.Lock()
+	defer s.Unlock()
+	s.version++
+	s.body = body
+}
+
+func TestNewRemote(t *testing.T) {
+	fakeTick, restoreMakeTicker := makeFakeMakeTicker()
+	defer restoreMakeTicker()
+
+	handler := &etagServer{body: "first body"}
+	srv := httptest.NewTLSServer(handler)
+	defer srv.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	spkiHash := sha256.Sum256(srv.Certificate().RawSubjectPublicKeyInfo)
+	pin := base64.StdEncoding.EncodeToString(spkiHash[:])
+	otherHash := sha256.Sum256([]byte("some other key"))
+	otherPin := base64.StdEncoding.EncodeToString(otherHash[:])
+
+	_, err := NewRemote(RemoteSource{URL: "http" + srv.URL[len("https"):], RootCAs: roots}, noop, testErrCb(t))
+	test.AssertError(t, err, "NewRemote accepted an http URL")
+	_, err = NewRemote(RemoteSource{URL: srv.URL}, noop, testErrCb(t))
+	test.AssertError(t, err, "NewRemote accepted a server certificate from an untrusted root")
+	_, err = NewRemote(RemoteSource{URL: srv.URL, RootCAs: roots, PinnedSPKIHashes: []string{otherPin}}, noop, testErrCb(t))
+	test.AssertError(t, err, "NewRemote accepted a server certificate that doesn't match the pins")
+	_, err = NewRemote(RemoteSource{URL: srv.URL, RootCAs: roots, PinnedSPKIHashes: []string{"not a pin"}}, noop, testErrCb(t))
+	test.AssertError(t, err, "NewRemote accepted an invalid pin")
+
+	bodies := make(chan string, 10)
+	r, err := NewRemote(RemoteSource{URL: srv.URL, RootCAs: roots, PinnedSPKIHashes: []string{otherPin, pin}}, func(b []byte) error {
+		bodies <- string(b)
+		return nil
+	}, testErrCb(t))
+	test.AssertNotError(t, err, "NewRemote failed")
+	defer r.Stop()
+	test.AssertEquals(t, <-bodies, "first body")
+
+	// The loop handles one tick at a time, so once the second of two ticks
+	// has been received the first has been fully processed.
+	tick := func() {
+		fakeTick <- time.Now()
+		fakeTick <- time.Now()
+	}
+	tick()
+	select {
+	case b := <-bodies:
+		t.Errorf("reloaded unchanged body %q", b)
+	default:
+	}
+	handler.Lock()
+	test.Assert(t, handler.notModified > 0, "If-None-Match wasn't sent with the previous ETag")
+	handler.Unlock()
+
+	handler.set("second body")
+	tick()
+	test.AssertEquals(t, <-bodies, "second body")
+}
@@ -0,0 +1,26 @@
+
+-- +goose Up
+-- +boulder SafeOnline
+-- SQL in section 'Up' is executed when this migration is applied
+
+CREATE TABLE `rateLimitOverrides` (
+  `id` BIGINT(20) NOT NULL AUTO_INCREMENT,
+  `limitName` VARCHAR(255) NOT NULL,
+  `overrideKey` VARCHAR(255) NOT NULL,
+  `registrationID` BIGINT(20) NOT NULL,
+  `threshold` BIGINT(20) NOT NULL,
+  `createdBy` VARCHAR(255) NOT NULL,
+  `created` DATETIME NOT NULL,
+  `expires` DATETIME NOT NULL,
+  `reason` VARCHAR(1024) NOT NULL,
+  `expiredBy` VARCHAR(255) NOT NULL DEFAULT '',
+  `expiredReason` VARCHAR(1024) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  KEY `expires_idx` (`expires`),
+  KEY `limitName_overrideKey_registrationID_expires_idx` (`limitName`, `overrideKey`, `registrationID`, `expires`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+-- +goose Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+DROP TABLE `rateLimitOverrides`;
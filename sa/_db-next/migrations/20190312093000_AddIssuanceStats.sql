@@ -0,0 +1,21 @@
+
+-- +goose Up
+-- +boulder SafeOnline
+-- SQL in section 'Up' is executed when this migration is applied
+
+CREATE TABLE `issuanceStats` (
+  `id` BIGINT(20) NOT NULL AUTO_INCREMENT,
+  `day` DATE NOT NULL,
+  `tld` VARCHAR(255) NOT NULL,
+  `keyType` VARCHAR(32) NOT NULL,
+  `challengeType` VARCHAR(32) NOT NULL,
+  `profile` VARCHAR(32) NOT NULL,
+  `nameCount` BIGINT(20) NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `day_tld_keyType_challengeType_profile_idx` (`day`, `tld`, `keyType`, `challengeType`, `profile`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+-- +goose Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+DROP TABLE `issuanceStats`;
@@ -0,0 +1,16 @@
+
+-- +goose Up
+-- +boulder SafeOnline
+-- SQL in section 'Up' is executed when this migration is applied
+
+CREATE TABLE `expirationNagClaims` (
+  `serial` VARCHAR(255) NOT NULL,
+  `claimedAt` DATETIME NOT NULL,
+  `sentAt` DATETIME DEFAULT NULL,
+  PRIMARY KEY (`serial`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+-- +goose Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+DROP TABLE `expirationNagClaims`;
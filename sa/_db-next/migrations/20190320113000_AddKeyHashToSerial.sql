@@ -0,0 +1,20 @@
+
+-- +goose Up
+-- +boulder SafeOnline
+-- SQL in section 'Up' is executed when this migration is applied
+
+CREATE TABLE `keyHashToSerial` (
+  `id` BIGINT(20) NOT NULL AUTO_INCREMENT,
+  -- SHA-256 hash of the certificate's SubjectPublicKeyInfo.
+  `keyHash` BINARY(32) NOT NULL,
+  `certNotAfter` DATETIME NOT NULL,
+  `certSerial` VARCHAR(255) NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `keyHash_certNotAfter_Idx` (`keyHash`, `certNotAfter`),
+  UNIQUE KEY `keyHash_certSerial_Idx` (`keyHash`, `certSerial`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+-- +goose Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+DROP TABLE `keyHashToSerial`;
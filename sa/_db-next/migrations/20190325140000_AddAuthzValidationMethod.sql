@@ -0,0 +1,11 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+ALTER TABLE `authz` ADD COLUMN `validationMethod` VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE `authz` ADD INDEX `validationMethod_expires_idx` (`validationMethod`, `expires`);
+
+-- +goose Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+ALTER TABLE `authz` DROP INDEX `validationMethod_expires_idx`;
+ALTER TABLE `authz` DROP COLUMN `validationMethod`;
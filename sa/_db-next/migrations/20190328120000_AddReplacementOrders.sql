@@ -0,0 +1,23 @@
+
+-- +goose Up
+-- +boulder SafeOnline
+-- SQL in section 'Up' is executed when this migration is applied
+
+CREATE TABLE `replacementOrders` (
+  `id` BIGINT(20) NOT NULL AUTO_INCREMENT,
+  -- Serial of the certificate the order replaces.
+  `serial` VARCHAR(255) NOT NULL,
+  `orderID` BIGINT(20) NOT NULL,
+  `orderExpires` DATETIME NOT NULL,
+  -- Whether the order has been finalized, so that the certificate has been
+  -- replaced.
+  `replaced` TINYINT(1) NOT NULL DEFAULT 0,
+  PRIMARY KEY (`id`),
+  KEY `serial_idx` (`serial`),
+  UNIQUE KEY `orderID_idx` (`orderID`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+-- +goose Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+DROP TABLE `replacementOrders`;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +boulder SafeOnline
+-- SQL in section 'Up' is executed when this migration is applied
+
+CREATE TABLE `orderExemptions` (
+  -- Hex SHA-256 hash of the token, so that each token can be used by only
+  -- one order.
+  `tokenHash` CHAR(64) NOT NULL,
+  `orderID` BIGINT(20) NOT NULL,
+  `token` TEXT NOT NULL,
+  PRIMARY KEY (`tokenHash`),
+  KEY `orderID_idx` (`orderID`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+-- +goose Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+DROP TABLE `orderExemptions`;
@@ -0,0 +1,24 @@
+
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+CREATE TABLE `challengeWhitelist` (
+  `id` BIGINT(20) NOT NULL AUTO_INCREMENT,
+  `challengeType` VARCHAR(255) NOT NULL,
+  `registrationID` BIGINT(20) NOT NULL,
+  `createdBy` VARCHAR(255) NOT NULL,
+  `created` DATETIME NOT NULL,
+  -- NULL if the entry never expires.
+  `expires` DATETIME DEFAULT NULL,
+  `reason` VARCHAR(1024) NOT NULL,
+  `removedBy` VARCHAR(255) NOT NULL DEFAULT '',
+  `removedReason` VARCHAR(1024) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  KEY `expires_idx` (`expires`),
+  KEY `challengeType_registrationID_expires_idx` (`challengeType`, `registrationID`, `expires`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+-- +goose Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+DROP TABLE `challengeWhitelist`;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+CREATE TABLE `orderToAuthz2` (
+  `orderID` BIGINT(20) NOT NULL,
+  `authzID` BIGINT(20) NOT NULL,
+  PRIMARY KEY (`orderID`, `authzID`),
+  KEY `authzID` (`authzID`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+-- +goose Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+DROP TABLE `orderToAuthz2`;
@@ -0,0 +1,18 @@
+
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+CREATE TABLE `dns01Rechecks` (
+  `authzID` VARCHAR(255) NOT NULL,
+  `challengeID` BIGINT(20) NOT NULL,
+  `deadline` DATETIME NOT NULL,
+  `leaseExpires` DATETIME NOT NULL,
+  `claim` BIGINT(20) NOT NULL,
+  PRIMARY KEY (`authzID`),
+  KEY `leaseExpires_idx` (`leaseExpires`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+-- +goose Down
+-- SQL section 'Down' is executed when this migration is rolled back
+
+DROP TABLE `dns01Rechecks`;
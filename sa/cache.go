@@ -0,0 +1,281 @@
+package sa
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/letsencrypt/boulder/core"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ReadCacheConfig configures the SA's in-process cache of reads whose results
+// don't change, so that e.g. repeated downloads of a certificate don't each
+// cost a DB read.
+type ReadCacheConfig struct {
+	// Certificates is the maximum number of issued certificates to cache.
+	// Zero disables caching them.
+	Certificates int
+	// Authorizations is the maximum number of finalized authorizations to
+	// cache. Zero disables caching them. Invalid authorizations aren't
+	// cached, since RetryChallenge can make them pending again.
+	Authorizations int
+	// AuthorizationMaxAge is how long a finalized authorization is cached.
+	// A cached authorization is removed when it's deactivated or revoked
+	// through this SA, but not when that happens through another SA
+	// instance, so this bounds how stale it can be. It must be positive if
+	// Authorizations is.
+	AuthorizationMaxAge time.Duration
+}
+
+// lruEntry is an entry of an lru
+type lruEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// lru is a size limited cache that evicts the least recently used entry when
+// it's full. It's safe for concurrent use.
+type lru struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	ll      *list.List
+	entries map[string]*list.Element
+}
+
+func newLRU(maxEntries int) *lru {
+	return &lru{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// get returns the value cached for key, unless there's none or it expired
+// before now.
+func (c *lru) get(key string, now time.Time) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if !entry.expires.IsZero() && !now.Before(entry.expires) {
+		c.ll.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return entry.value, true
+}
+
+// add caches value for key until expires, or indefinitely if expires is zero.
+func (c *lru) add(key string, value interface{}, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.ll.MoveToFront(elem)
+		entry := elem.Value.(*lruEntry)
+		entry.value, entry.expires = value, expires
+		return
+	}
+	c.entries[key] = c.ll.PushFront(&lruEntry{key: key, value: value, expires: expires})
+	if c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// remove removes the value cached for key, if any.
+func (c *lru) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.ll.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+// removeIf removes every cached value that match returns true for.
+func (c *lru) removeIf(match func(value interface{}) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, elem := range c.entries {
+		if match(elem.Value.(*lruEntry).value) {
+			c.ll.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// readCache caches issued certificates by serial and finalized authorizations
+// by ID. A nil *readCache caches nothing, and neither certs nor authzs are
+// cached if their lru is nil.
+type readCache struct {
+	certs       *lru
+	authzs      *lru
+	authzMaxAge time.Duration
+	lookups     *prometheus.CounterVec
+}
+
+func newReadCache(config ReadCacheConfig, lookups *prometheus.CounterVec) (*readCache, error) {
+	if config.Certificates < 0 || config.Authorizations < 0 {
+		return nil, errors.New("read cache sizes can't be negative")
+	}
+	if config.Authorizations > 0 && config.AuthorizationMaxAge <= 0 {
+		return nil, errors.New("read cache authorizationMaxAge must be positive")
+	}
+	rc := &readCache{
+		authzMaxAge: config.AuthorizationMaxAge,
+		lookups:     lookups,
+	}
+	if config.Certificates > 0 {
+		rc.certs = newLRU(config.Certificates)
+	}
+	if config.Authorizations > 0 {
+		rc.authzs = newLRU(config.Authorizations)
+	}
+	return rc, nil
+}
+
+func (rc *readCache) observe(kind string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	rc.lookups.With(prometheus.Labels{"type": kind, "result": result}).Inc()
+}
+
+// getCertificate returns the cached certificate with serial, if any.
+func (rc *readCache) getCertificate(serial string, now time.Time) (core.Certificate, bool) {
+	if rc == nil || rc.certs == nil {
+		return core.Certificate{}, false
+	}
+	value, ok := rc.certs.get(serial, now)
+	rc.observe("certificate", ok)
+	if !ok {
+		return core.Certificate{}, false
+	}
+	return copyCertificate(value.(core.Certificate)), true
+}
+
+// addCertificate caches an issued certificate. Its contents never change, so
+// it's cached until it's evicted.
+func (rc *readCache) addCertificate(cert core.Certificate) {
+	if rc == nil || rc.certs == nil {
+		return
+	}
+	rc.certs.add(cert.Serial, copyCertificate(cert), time.Time{})
+}
+
+// removeCertificate removes the cached certificate with serial, if any.
+func (rc *readCache) removeCertificate(serial string) {
+	if rc == nil || rc.certs == nil {
+		return
+	}
+	rc.certs.remove(serial)
+}
+
+// getAuthorization returns the cached finalized authorization with id, if
+// any.
+func (rc *readCache) getAuthorization(id string, now time.Time) (core.Authorization, bool) {
+	if rc == nil || rc.authzs == nil {
+		return core.Authorization{}, false
+	}
+	value, ok := rc.authzs.get(id, now)
+	rc.observe("authorization", ok)
+	if !ok {
+		return core.Authorization{}, false
+	}
+	return copyAuthorization(value.(core.Authorization)), true
+}
+
+// addAuthorization caches a finalized authorization for authzMaxAge, or until
+// it expires if that's sooner. Invalid authorizations aren't cached: when
+// RetryChallenge reopens one through another SA instance, nothing removes it
+// from this one's cache, and a stale copy would hide the retry.
+func (rc *readCache) addAuthorization(authz core.Authorization, now time.Time) {
+	if rc == nil || rc.authzs == nil || authz.Status == core.StatusInvalid {
+		return
+	}
+	expires := now.Add(rc.authzMaxAge)
+	if authz.Expires != nil && authz.Expires.Before(expires) {
+		expires = *authz.Expires
+	}
+	rc.authzs.add(authz.ID, copyAuthorization(authz), expires)
+}
+
+// removeAuthorization removes the cached authorization with id, if any.
+func (rc *readCache) removeAuthorization(id string) {
+	if rc == nil || rc.authzs == nil {
+		return
+	}
+	rc.authzs.remove(id)
+}
+
+// removeAuthorizationsFor removes every cached authorization for ident.
+func (rc *readCache) removeAuthorizationsFor(ident core.AcmeIdentifier) {
+	if rc == nil || rc.authzs == nil {
+		return
+	}
+	rc.authzs.removeIf(func(value interface{}) bool {
+		return value.(core.Authorization).Identifier == ident
+	})
+}
+
+// copyCertificate returns a copy of cert that shares no memory with it, so
+// that callers can't change what's cached.
+func copyCertificate(cert core.Certificate) core.Certificate {
+	cert.DER = append([]byte(nil), cert.DER...)
+	return cert
+}
+
+// copyAuthorization returns a copy of authz that shares no memory with it, so
+// that callers can't change what's cached.
+func copyAuthorization(authz core.Authorization) core.Authorization {
+	if authz.Expires != nil {
+		expires := *authz.Expires
+		authz.Expires = &expires
+	}
+	if authz.Challenges != nil {
+		challenges := make([]core.Challenge, len(authz.Challenges))
+		for i, chall := range authz.Challenges {
+			chall.ValidationRecord = append([]core.ValidationRecord(nil), chall.ValidationRecord...)
+			if chall.Error != nil {
+				prob := *chall.Error
+				chall.Error = &prob
+			}
+			challenges[i] = chall
+		}
+		authz.Challenges = challenges
+	}
+	if authz.Combinations != nil {
+		combinations := make([][]int, len(authz.Combinations))
+		for i, combination := range authz.Combinations {
+			combinations[i] = append([]int(nil), combination...)
+		}
+		authz.Combinations = combinations
+	}
+	return authz
+}
+
+// SetReadCache makes the SA cache issued certificates and finalized
+// authorizations in memory, as configured.
+func (ssa *SQLStorageAuthority) SetReadCache(config ReadCacheConfig) error {
+	lookups := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sa_read_cache_lookups",
+		Help: "Lookups in the SA's read cache, by type and result (hit or miss)",
+	}, []string{"type", "result"})
+	rc, err := newReadCache(config, lookups)
+	if err != nil {
+		return err
+	}
+	ssa.scope.MustRegister(lookups)
+	ssa.readCache = rc
+	return nil
+}
@@ -0,0 +1,165 @@
+package sa
+
+import (
+	"testing"
+	"time"
+
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/test"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestLRU(t *testing.T) {
+	now := time.Now()
+	c := newLRU(2)
+	c.add("a", 1, time.Time{})
+	c.add("b", 2, time.Time{})
+	// Using "a" makes "b" the least recently used, so it's evicted
+	_, ok := c.get("a", now)
+	test.Assert(t, ok, "a wasn't cached")
+	c.add("c", 3, time.Time{})
+	_, ok = c.get("b", now)
+	test.Assert(t, !ok, "least recently used entry wasn't evicted")
+	value, ok := c.get("a", now)
+	test.Assert(t, ok, "a was evicted")
+	test.AssertEquals(t, value.(int), 1)
+
+	c.add("d", 4, now.Add(time.Minute))
+	_, ok = c.get("d", now)
+	test.Assert(t, ok, "d wasn't cached")
+	_, ok = c.get("d", now.Add(time.Minute))
+	test.Assert(t, !ok, "expired entry was returned")
+
+	c.remove("a")
+	_, ok = c.get("a", now)
+	test.Assert(t, !ok, "removed entry was returned")
+
+	c.add("e", 5, time.Time{})
+	c.add("f", 6, time.Time{})
+	c.removeIf(func(value interface{}) bool { return value.(int) == 5 })
+	_, ok = c.get("e", now)
+	test.Assert(t, !ok, "entry matching removeIf was returned")
+	_, ok = c.get("f", now)
+	test.Assert(t, ok, "entry not matching removeIf was removed")
+}
+
+func TestNewReadCache(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config ReadCacheConfig
+		err    string
+	}{
+		{
+			name:   "certificates only",
+			config: ReadCacheConfig{Certificates: 10},
+		},
+		{
+			name:   "authorizations",
+			config: ReadCacheConfig{Authorizations: 10, AuthorizationMaxAge: time.Minute},
+		},
+		{
+			name:   "negative size",
+			config: ReadCacheConfig{Certificates: -1},
+			err:    "read cache sizes can't be negative",
+		},
+		{
+			name:   "no authorization max age",
+			config: ReadCacheConfig{Authorizations: 10},
+			err:    "read cache authorizationMaxAge must be positive",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newReadCache(tc.config, nil)
+			if tc.err == "" {
+				test.AssertNotError(t, err, "newReadCache failed")
+			} else {
+				test.AssertError(t, err, "newReadCache accepted an invalid config")
+				test.AssertEquals(t, err.Error(), tc.err)
+			}
+		})
+	}
+}
+
+func TestReadCache(t *testing.T) {
+	lookups := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "lookups"}, []string{"type", "result"})
+	rc, err := newReadCache(ReadCacheConfig{
+		Certificates:        10,
+		Authorizations:      10,
+		AuthorizationMaxAge: time.Hour,
+	}, lookups)
+	test.AssertNotError(t, err, "newReadCache failed")
+	now := time.Now()
+
+	cert := core.Certificate{Serial: "00", DER: []byte{1, 2, 3}}
+	rc.addCertificate(cert)
+	cached, ok := rc.getCertificate("00", now)
+	test.Assert(t, ok, "certificate wasn't cached")
+	test.AssertDeepEquals(t, cached, cert)
+	// Changing what's returned doesn't change what's cached
+	cached.DER[0] = 9
+	cached, _ = rc.getCertificate("00", now)
+	test.AssertEquals(t, cached.DER[0], byte(1))
+	rc.removeCertificate("00")
+	_, ok = rc.getCertificate("00", now)
+	test.Assert(t, !ok, "certificate was returned after it was removed")
+	test.AssertEquals(t, test.CountCounter(lookups.With(prometheus.Labels{"type": "certificate", "result": "hit"})), 2)
+	test.AssertEquals(t, test.CountCounter(lookups.With(prometheus.Labels{"type": "certificate", "result": "miss"})), 1)
+
+	// An authorization is cached until it expires if that's sooner than the
+	// max age
+	expires := now.Add(time.Minute)
+	ident := core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "example.com"}
+	authz := core.Authorization{
+		ID:         "a",
+		Identifier: ident,
+		Status:     core.StatusValid,
+		Expires:    &expires,
+		Challenges: []core.Challenge{{Type: core.ChallengeTypeHTTP01, Status: core.StatusValid}},
+	}
+	rc.addAuthorization(authz, now)
+	cachedAuthz, ok := rc.getAuthorization("a", now)
+	test.Assert(t, ok, "authorization wasn't cached")
+	test.AssertDeepEquals(t, cachedAuthz, authz)
+	cachedAuthz.Challenges[0].Status = core.StatusInvalid
+	cachedAuthz, _ = rc.getAuthorization("a", now)
+	test.AssertEquals(t, cachedAuthz.Challenges[0].Status, core.StatusValid)
+	_, ok = rc.getAuthorization("a", expires)
+	test.Assert(t, !ok, "expired authorization was returned")
+
+	// Revoking the authorizations for an identifier removes them
+	rc.addAuthorization(authz, now)
+	other := authz
+	other.ID = "b"
+	other.Identifier.Value = "example.net"
+	rc.addAuthorization(other, now)
+	rc.removeAuthorizationsFor(ident)
+	_, ok = rc.getAuthorization("a", now)
+	test.Assert(t, !ok, "revoked authorization was returned")
+	_, ok = rc.getAuthorization("b", now)
+	test.Assert(t, ok, "authorization for another identifier was removed")
+	rc.removeAuthorization("b")
+	_, ok = rc.getAuthorization("b", now)
+	test.Assert(t, !ok, "deactivated authorization was returned")
+
+	// Invalid authorizations can be retried through any SA, so aren't cached
+	invalid := authz
+	invalid.ID = "c"
+	invalid.Status = core.StatusInvalid
+	rc.addAuthorization(invalid, now)
+	_, ok = rc.getAuthorization("c", now)
+	test.Assert(t, !ok, "invalid authorization was cached")
+}
+
+func TestNilReadCache(t *testing.T) {
+	var rc *readCache
+	rc.addCertificate(core.Certificate{Serial: "00"})
+	_, ok := rc.getCertificate("00", time.Now())
+	test.Assert(t, !ok, "nil read cache returned a certificate")
+	rc.addAuthorization(core.Authorization{ID: "a"}, time.Now())
+	_, ok = rc.getAuthorization("a", time.Now())
+	test.Assert(t, !ok, "nil read cache returned an authorization")
+	rc.removeCertificate("00")
+	rc.removeAuthorization("a")
+	rc.removeAuthorizationsFor(core.AcmeIdentifier{})
+}
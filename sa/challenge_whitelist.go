@@ -0,0 +1,171 @@
+package sa
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+
+	berrors "github.com/letsencrypt/boulder/errors"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+)
+
+// challengeWhitelistModel represents one row in the challengeWhitelist table,
+// which enables a challenge type for an account even if it's disabled for
+// everyone else. Like rate limit overrides, rows are never deleted: removing
+// an entry only sets its expiry.
+type challengeWhitelistModel struct {
+	ID             int64      `db:"id"`
+	ChallengeType  string     `db:"challengeType"`
+	RegistrationID int64      `db:"registrationID"`
+	CreatedBy      string     `db:"createdBy"`
+	Created        time.Time  `db:"created"`
+	Expires        *time.Time `db:"expires"`
+	Reason         string     `db:"reason"`
+	RemovedBy      string     `db:"removedBy"`
+	RemovedReason  string     `db:"removedReason"`
+}
+
+func modelToChallengeWhitelistEntry(m *challengeWhitelistModel) *sapb.ChallengeWhitelistEntry {
+	created := m.Created.UnixNano()
+	var expires int64
+	if m.Expires != nil {
+		expires = m.Expires.UnixNano()
+	}
+	return &sapb.ChallengeWhitelistEntry{
+		Id:             &m.ID,
+		ChallengeType:  &m.ChallengeType,
+		RegistrationID: &m.RegistrationID,
+		CreatedBy:      &m.CreatedBy,
+		Created:        &created,
+		Expires:        &expires,
+		Reason:         &m.Reason,
+	}
+}
+
+// whitelistExpiry formats the expiry of a challenge whitelist entry for the
+// audit log.
+func whitelistExpiry(expires *time.Time) string {
+	if expires == nil {
+		return "never"
+	}
+	return expires.UTC().Format(time.RFC3339)
+}
+
+// AddChallengeWhitelistEntry enables a challenge type for an account until the
+// entry's expiry, or forever if it has none. Any unexpired entry for the same
+// challenge type and account is expired as part of the same transaction. The
+// stored entry, including its ID, is returned.
+func (ssa *SQLStorageAuthority) AddChallengeWhitelistEntry(ctx context.Context, req *sapb.ChallengeWhitelistEntry) (*sapb.ChallengeWhitelistEntry, error) {
+	if *req.ChallengeType == "" || *req.RegistrationID == 0 {
+		return nil, berrors.MalformedError("a challenge type and registration ID must be provided")
+	}
+	if *req.CreatedBy == "" || *req.Reason == "" {
+		return nil, berrors.MalformedError("a challenge whitelist entry must record who created it and why")
+	}
+	now := ssa.clk.Now()
+	var expires *time.Time
+	if req.Expires != nil && *req.Expires != 0 {
+		t := time.Unix(0, *req.Expires)
+		if !t.After(now) {
+			return nil, berrors.MalformedError("challenge whitelist entry expiry must be in the future")
+		}
+		expires = &t
+	}
+
+	tx, err := ssa.dbMap.Begin()
+	if err != nil {
+		return nil, err
+	}
+	txWithCtx := tx.WithContext(ctx)
+
+	_, err = txWithCtx.Exec(
+		`UPDATE challengeWhitelist
+		SET expires = ?, removedBy = ?, removedReason = ?
+		WHERE challengeType = ? AND registrationID = ? AND (expires IS NULL OR expires > ?)`,
+		now,
+		*req.CreatedBy,
+		"replaced by a new entry: "+*req.Reason,
+		*req.ChallengeType,
+		*req.RegistrationID,
+		now,
+	)
+	if err != nil {
+		return nil, Rollback(tx, err)
+	}
+
+	m := &challengeWhitelistModel{
+		ChallengeType:  *req.ChallengeType,
+		RegistrationID: *req.RegistrationID,
+		CreatedBy:      *req.CreatedBy,
+		Created:        now,
+		Expires:        expires,
+		Reason:         *req.Reason,
+	}
+	if err := txWithCtx.Insert(m); err != nil {
+		return nil, Rollback(tx, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	ssa.log.AuditInfof("Added challenge whitelist entry: id=[%d] challenge=[%s] regID=[%d] expires=[%s] by=[%s] reason=[%s]",
+		m.ID, m.ChallengeType, m.RegistrationID, whitelistExpiry(m.Expires), m.CreatedBy, m.Reason)
+	return modelToChallengeWhitelistEntry(m), nil
+}
+
+// RemoveChallengeWhitelistEntry immediately expires the challenge whitelist
+// entry for a challenge type and account, recording who removed it and why. A
+// NotFound error is returned if there is no unexpired entry for them.
+func (ssa *SQLStorageAuthority) RemoveChallengeWhitelistEntry(ctx context.Context, req *sapb.RemoveChallengeWhitelistEntryRequest) error {
+	if *req.RemovedBy == "" || *req.Reason == "" {
+		return berrors.MalformedError("removing a challenge whitelist entry must record who removed it and why")
+	}
+	now := ssa.clk.Now()
+	result, err := ssa.db(ctx).Exec(
+		`UPDATE challengeWhitelist
+		SET expires = ?, removedBy = ?, removedReason = ?
+		WHERE challengeType = ? AND registrationID = ? AND (expires IS NULL OR expires > ?)`,
+		now,
+		*req.RemovedBy,
+		*req.Reason,
+		*req.ChallengeType,
+		*req.RegistrationID,
+		now,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return berrors.NotFoundError("no unexpired %s whitelist entry for registration ID %d",
+			*req.ChallengeType, *req.RegistrationID)
+	}
+
+	ssa.log.AuditInfof("Removed challenge whitelist entry: challenge=[%s] regID=[%d] by=[%s] reason=[%s]",
+		*req.ChallengeType, *req.RegistrationID, *req.RemovedBy, *req.Reason)
+	return nil
+}
+
+// GetChallengeWhitelist returns all of the challenge whitelist entries that
+// have not expired as of the request's timestamp.
+func (ssa *SQLStorageAuthority) GetChallengeWhitelist(ctx context.Context, req *sapb.GetChallengeWhitelistRequest) (*sapb.ChallengeWhitelistEntries, error) {
+	var models []*challengeWhitelistModel
+	_, err := ssa.db(ctx).Select(
+		&models,
+		`SELECT id, challengeType, registrationID, createdBy, created, expires, reason, removedBy, removedReason
+		FROM challengeWhitelist
+		WHERE expires IS NULL OR expires > ?`,
+		time.Unix(0, *req.Now),
+	)
+	if err != nil {
+		return nil, err
+	}
+	entries := &sapb.ChallengeWhitelistEntries{}
+	for _, m := range models {
+		entries.Entries = append(entries.Entries, modelToChallengeWhitelistEntry(m))
+	}
+	return entries, nil
+}
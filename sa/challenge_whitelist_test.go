@@ -0,0 +1,125 @@
+package sa
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	berrors "github.com/letsencrypt/boulder/errors"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+	"github.com/letsencrypt/boulder/sa/satest"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func whitelistEntry(challengeType string, regID int64, expires time.Time) *sapb.ChallengeWhitelistEntry {
+	var expiresNS int64
+	if !expires.IsZero() {
+		expiresNS = expires.UnixNano()
+	}
+	createdBy, reason := "operator", "testing"
+	return &sapb.ChallengeWhitelistEntry{
+		ChallengeType:  &challengeType,
+		RegistrationID: &regID,
+		CreatedBy:      &createdBy,
+		Expires:        &expiresNS,
+		Reason:         &reason,
+	}
+}
+
+func TestAddChallengeWhitelistEntryMalformed(t *testing.T) {
+	fc := clock.NewFake()
+	fc.Set(time.Date(2019, 4, 8, 0, 0, 0, 0, time.UTC))
+	ssa := &SQLStorageAuthority{clk: fc}
+
+	empty := ""
+	noType := whitelistEntry("", 1, time.Time{})
+	noReg := whitelistEntry("dns-01", 0, time.Time{})
+	noCreator := whitelistEntry("dns-01", 1, time.Time{})
+	noCreator.CreatedBy = &empty
+	noReason := whitelistEntry("dns-01", 1, time.Time{})
+	noReason.Reason = &empty
+	expired := whitelistEntry("dns-01", 1, fc.Now())
+	for _, req := range []*sapb.ChallengeWhitelistEntry{noType, noReg, noCreator, noReason, expired} {
+		_, err := ssa.AddChallengeWhitelistEntry(ctx, req)
+		test.Assert(t, berrors.Is(err, berrors.Malformed), "invalid challenge whitelist entry was accepted")
+	}
+
+	challengeType, regID := "dns-01", int64(1)
+	err := ssa.RemoveChallengeWhitelistEntry(ctx, &sapb.RemoveChallengeWhitelistEntryRequest{
+		ChallengeType:  &challengeType,
+		RegistrationID: &regID,
+		RemovedBy:      &empty,
+		Reason:         &empty,
+	})
+	test.Assert(t, berrors.Is(err, berrors.Malformed), "removal without who and why was accepted")
+}
+
+func TestChallengeWhitelist(t *testing.T) {
+	sa, fc, cleanUp := initSA(t)
+	defer cleanUp()
+	if _, err := sa.dbMap.Exec("SELECT 1 FROM challengeWhitelist LIMIT 1"); err != nil {
+		t.Skip("challengeWhitelist table not present")
+	}
+	reg := satest.CreateWorkingRegistration(t, sa)
+	other := satest.CreateWorkingRegistration(t, sa)
+
+	list := func(at time.Time) map[int64]*sapb.ChallengeWhitelistEntry {
+		now := at.UnixNano()
+		entries, err := sa.GetChallengeWhitelist(ctx, &sapb.GetChallengeWhitelistRequest{Now: &now})
+		test.AssertNotError(t, err, "GetChallengeWhitelist failed")
+		byID := make(map[int64]*sapb.ChallengeWhitelistEntry)
+		for _, e := range entries.Entries {
+			byID[*e.Id] = e
+		}
+		return byID
+	}
+
+	forever, err := sa.AddChallengeWhitelistEntry(ctx, whitelistEntry("tls-sni-01", reg.ID, time.Time{}))
+	test.AssertNotError(t, err, "AddChallengeWhitelistEntry failed")
+	test.AssertEquals(t, *forever.ChallengeType, "tls-sni-01")
+	test.AssertEquals(t, *forever.RegistrationID, reg.ID)
+	test.AssertEquals(t, *forever.Expires, int64(0))
+	test.AssertEquals(t, *forever.Created, fc.Now().UnixNano())
+
+	expires := fc.Now().Add(time.Hour)
+	expiring, err := sa.AddChallengeWhitelistEntry(ctx, whitelistEntry("dns-01", other.ID, expires))
+	test.AssertNotError(t, err, "AddChallengeWhitelistEntry failed")
+	test.AssertEquals(t, *expiring.Expires, expires.UnixNano())
+
+	entries := list(fc.Now())
+	test.AssertEquals(t, len(entries), 2)
+	test.AssertDeepEquals(t, entries[*forever.Id], forever)
+	test.AssertDeepEquals(t, entries[*expiring.Id], expiring)
+
+	// Entries that have expired aren't listed
+	entries = list(expires)
+	test.AssertEquals(t, len(entries), 1)
+	test.Assert(t, entries[*forever.Id] != nil, "entry without an expiry wasn't listed")
+
+	// A new entry for the same challenge type and account replaces the old
+	fc.Add(time.Minute)
+	replacement, err := sa.AddChallengeWhitelistEntry(ctx, whitelistEntry("tls-sni-01", reg.ID, expires))
+	test.AssertNotError(t, err, "AddChallengeWhitelistEntry failed")
+	entries = list(fc.Now())
+	test.AssertEquals(t, len(entries), 2)
+	test.Assert(t, entries[*forever.Id] == nil, "replaced entry was listed")
+	test.Assert(t, entries[*replacement.Id] != nil, "replacement entry wasn't listed")
+
+	// Removing an entry expires it, and only it
+	challengeType, removedBy, reason := "tls-sni-01", "operator", "done testing"
+	remove := &sapb.RemoveChallengeWhitelistEntryRequest{
+		ChallengeType:  &challengeType,
+		RegistrationID: &reg.ID,
+		RemovedBy:      &removedBy,
+		Reason:         &reason,
+	}
+	err = sa.RemoveChallengeWhitelistEntry(ctx, remove)
+	test.AssertNotError(t, err, "RemoveChallengeWhitelistEntry failed")
+	entries = list(fc.Now())
+	test.AssertEquals(t, len(entries), 1)
+	test.Assert(t, entries[*expiring.Id] != nil, "another account's entry was removed")
+
+	err = sa.RemoveChallengeWhitelistEntry(ctx, remove)
+	test.Assert(t, berrors.Is(err, berrors.NotFound), "removing an already removed entry didn't return NotFound")
+}
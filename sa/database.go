@@ -9,6 +9,7 @@ import (
 	"gopkg.in/go-gorp/gorp.v2"
 
 	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/faults"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
 )
@@ -45,7 +46,7 @@ var setMaxOpenConns = func(db *sql.DB, maxOpenConns int) {
 func NewDbMapFromConfig(config *mysql.Config, maxOpenConns int) (*gorp.DbMap, error) {
 	adjustMySQLConfig(config)
 
-	db, err := sqlOpen("mysql", config.FormatDSN())
+	db, err := sqlOpen(faults.DriverName("mysql"), config.FormatDSN())
 	if err != nil {
 		return nil, err
 	}
@@ -134,8 +135,16 @@ func initTables(dbMap *gorp.DbMap) {
 	regTable.ColMap("KeySHA256").SetNotNull(true).SetUnique(true)
 	pendingAuthzTable := dbMap.AddTableWithName(pendingauthzModel{}, "pendingAuthorizations").SetKeys(false, "ID")
 	pendingAuthzTable.SetVersionCol("LockCol")
-	dbMap.AddTableWithName(authzModel{}, "authz").SetKeys(false, "ID")
-	dbMap.AddTableWithName(challModel{}, "challenges").SetKeys(true, "ID")
+	// The validationMethod column only exists in the authz table, and is only
+	// written when the StoreValidationMethod feature is enabled, by
+	// FinalizeAuthorization.
+	pendingAuthzTable.ColMap("ValidationMethod").SetTransient(true)
+	authzTable := dbMap.AddTableWithName(authzModel{}, "authz").SetKeys(false, "ID")
+	authzTable.ColMap("ValidationMethod").SetTransient(true)
+	challTable := dbMap.AddTableWithName(challModel{}, "challenges").SetKeys(true, "ID")
+	// The attempts column is only written when the StoreChallengeAttempts
+	// feature is enabled, by updateChallenges and RetryChallenge.
+	challTable.ColMap("Attempts").SetTransient(true)
 	dbMap.AddTableWithName(issuedNameModel{}, "issuedNames").SetKeys(true, "ID")
 	dbMap.AddTableWithName(core.Certificate{}, "certificates").SetKeys(false, "Serial")
 	dbMap.AddTableWithName(core.CertificateStatus{}, "certificateStatus").SetKeys(false, "Serial")
@@ -147,4 +156,10 @@ func initTables(dbMap *gorp.DbMap) {
 	dbMap.AddTableWithName(orderToAuthzModel{}, "orderToAuthz").SetKeys(false, "OrderID", "AuthzID")
 	dbMap.AddTableWithName(requestedNameModel{}, "requestedNames").SetKeys(false, "OrderID")
 	dbMap.AddTableWithName(orderFQDNSet{}, "orderFqdnSets").SetKeys(true, "ID")
+	dbMap.AddTableWithName(rateLimitOverrideModel{}, "rateLimitOverrides").SetKeys(true, "ID")
+	dbMap.AddTableWithName(challengeWhitelistModel{}, "challengeWhitelist").SetKeys(true, "ID")
+	dbMap.AddTableWithName(dns01RecheckModel{}, "dns01Rechecks").SetKeys(false, "AuthzID")
+	dbMap.AddTableWithName(keyHashModel{}, "keyHashToSerial").SetKeys(true, "ID")
+	dbMap.AddTableWithName(replacementOrderModel{}, "replacementOrders").SetKeys(true, "ID")
+	dbMap.AddTableWithName(orderExemptionModel{}, "orderExemptions").SetKeys(false, "TokenHash")
 }
@@ -0,0 +1,105 @@
+package sa
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	berrors "github.com/letsencrypt/boulder/errors"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+)
+
+// dns01RecheckModel represents one row in the dns01Rechecks table, which
+// records a failed DNS-01 validation that an RA is rechecking, so that
+// another RA can take the recheck over if the lease on it isn't renewed,
+// e.g. because the RA restarted.
+type dns01RecheckModel struct {
+	AuthzID      string    `db:"authzID"`
+	ChallengeID  int64     `db:"challengeID"`
+	Deadline     time.Time `db:"deadline"`
+	LeaseExpires time.Time `db:"leaseExpires"`
+	Claim        int64     `db:"claim"`
+}
+
+func modelToDNS01Recheck(m *dns01RecheckModel) *sapb.DNS01Recheck {
+	deadline, leaseExpires := m.Deadline.UnixNano(), m.LeaseExpires.UnixNano()
+	return &sapb.DNS01Recheck{
+		AuthzID:      &m.AuthzID,
+		ChallengeID:  &m.ChallengeID,
+		Deadline:     &deadline,
+		LeaseExpires: &leaseExpires,
+		Claim:        &m.Claim,
+	}
+}
+
+// AddDNS01Recheck records that the DNS-01 validation of an authorization is
+// being rechecked, with the request's claim. A Duplicate error is returned if
+// it already is.
+func (ssa *SQLStorageAuthority) AddDNS01Recheck(ctx context.Context, req *sapb.DNS01Recheck) error {
+	err := ssa.db(ctx).Insert(&dns01RecheckModel{
+		AuthzID:      *req.AuthzID,
+		ChallengeID:  *req.ChallengeID,
+		Deadline:     time.Unix(0, *req.Deadline),
+		LeaseExpires: time.Unix(0, *req.LeaseExpires),
+		Claim:        *req.Claim,
+	})
+	if err != nil && strings.HasPrefix(err.Error(), "Error 1062: Duplicate entry") {
+		return berrors.DuplicateError("DNS-01 validation of authorization %q is already being rechecked", *req.AuthzID)
+	}
+	return err
+}
+
+// ClaimDNS01Recheck increments the claim on a DNS-01 recheck and sets its
+// lease expiry, if the request's claim is the current one. It returns whether
+// it did, which is false if another RA claimed the recheck first, or it has
+// been removed.
+func (ssa *SQLStorageAuthority) ClaimDNS01Recheck(ctx context.Context, req *sapb.DNS01Recheck) (*sapb.Exists, error) {
+	result, err := ssa.db(ctx).Exec(
+		`UPDATE dns01Rechecks
+		SET claim = claim + 1, leaseExpires = ?
+		WHERE authzID = ? AND claim = ?`,
+		time.Unix(0, *req.LeaseExpires),
+		*req.AuthzID,
+		*req.Claim,
+	)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	claimed := rows == 1
+	return &sapb.Exists{Exists: &claimed}, nil
+}
+
+// RemoveDNS01Recheck removes the DNS-01 recheck of an authorization once its
+// challenge has been finalized. Removing one that doesn't exist isn't an
+// error.
+func (ssa *SQLStorageAuthority) RemoveDNS01Recheck(ctx context.Context, req *sapb.AuthorizationID) error {
+	_, err := ssa.db(ctx).Exec(`DELETE FROM dns01Rechecks WHERE authzID = ?`, *req.Id)
+	return err
+}
+
+// GetStaleDNS01Rechecks returns the DNS-01 rechecks whose lease expired by
+// the request's timestamp, which the RAs that claimed them have stopped
+// rechecking.
+func (ssa *SQLStorageAuthority) GetStaleDNS01Rechecks(ctx context.Context, req *sapb.GetStaleDNS01RechecksRequest) (*sapb.DNS01Rechecks, error) {
+	var models []*dns01RecheckModel
+	_, err := ssa.db(ctx).Select(
+		&models,
+		`SELECT authzID, challengeID, deadline, leaseExpires, claim
+		FROM dns01Rechecks
+		WHERE leaseExpires <= ?`,
+		time.Unix(0, *req.Now),
+	)
+	if err != nil {
+		return nil, err
+	}
+	rechecks := &sapb.DNS01Rechecks{}
+	for _, m := range models {
+		rechecks.Rechecks = append(rechecks.Rechecks, modelToDNS01Recheck(m))
+	}
+	return rechecks, nil
+}
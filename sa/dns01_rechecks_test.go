@@ -0,0 +1,71 @@
+package sa
+
+import (
+	"testing"
+	"time"
+
+	berrors "github.com/letsencrypt/boulder/errors"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestDNS01Rechecks(t *testing.T) {
+	sa, fc, cleanUp := initSA(t)
+	defer cleanUp()
+	if _, err := sa.dbMap.Exec("SELECT 1 FROM dns01Rechecks LIMIT 1"); err != nil {
+		t.Skip("dns01Rechecks table not present")
+	}
+
+	authzID, challID := "authz", int64(1)
+	deadline := fc.Now().Add(time.Hour).UnixNano()
+	leaseExpires := fc.Now().Add(time.Minute).UnixNano()
+	var claim int64
+	recheck := &sapb.DNS01Recheck{
+		AuthzID:      &authzID,
+		ChallengeID:  &challID,
+		Deadline:     &deadline,
+		LeaseExpires: &leaseExpires,
+		Claim:        &claim,
+	}
+	err := sa.AddDNS01Recheck(ctx, recheck)
+	test.AssertNotError(t, err, "AddDNS01Recheck failed")
+	err = sa.AddDNS01Recheck(ctx, recheck)
+	test.Assert(t, berrors.Is(err, berrors.Duplicate), "Adding a recheck twice didn't return Duplicate")
+
+	stale := func(at time.Time) []*sapb.DNS01Recheck {
+		t.Helper()
+		now := at.UnixNano()
+		rechecks, err := sa.GetStaleDNS01Rechecks(ctx, &sapb.GetStaleDNS01RechecksRequest{Now: &now})
+		test.AssertNotError(t, err, "GetStaleDNS01Rechecks failed")
+		return rechecks.Rechecks
+	}
+	test.AssertEquals(t, len(stale(fc.Now())), 0)
+	expired := stale(fc.Now().Add(time.Minute))
+	test.AssertEquals(t, len(expired), 1)
+	test.AssertEquals(t, *expired[0].AuthzID, authzID)
+	test.AssertEquals(t, *expired[0].ChallengeID, challID)
+	test.AssertEquals(t, *expired[0].Deadline, deadline)
+	test.AssertEquals(t, *expired[0].Claim, claim)
+
+	// Only the current claim can be renewed, so two RAs can't both take over
+	// a stale recheck
+	renewed := fc.Now().Add(2 * time.Minute).UnixNano()
+	claimed, err := sa.ClaimDNS01Recheck(ctx, &sapb.DNS01Recheck{AuthzID: &authzID, LeaseExpires: &renewed, Claim: &claim})
+	test.AssertNotError(t, err, "ClaimDNS01Recheck failed")
+	test.Assert(t, *claimed.Exists, "Current claim on a recheck couldn't be renewed")
+	claimed, err = sa.ClaimDNS01Recheck(ctx, &sapb.DNS01Recheck{AuthzID: &authzID, LeaseExpires: &renewed, Claim: &claim})
+	test.AssertNotError(t, err, "ClaimDNS01Recheck failed")
+	test.Assert(t, !*claimed.Exists, "Outdated claim on a recheck was renewed")
+	test.AssertEquals(t, len(stale(fc.Now().Add(time.Minute))), 0)
+	test.AssertEquals(t, *stale(fc.Now().Add(2 * time.Minute))[0].Claim, claim+1)
+
+	err = sa.RemoveDNS01Recheck(ctx, &sapb.AuthorizationID{Id: &authzID})
+	test.AssertNotError(t, err, "RemoveDNS01Recheck failed")
+	test.AssertEquals(t, len(stale(fc.Now().Add(time.Hour))), 0)
+	next := claim + 1
+	claimed, err = sa.ClaimDNS01Recheck(ctx, &sapb.DNS01Recheck{AuthzID: &authzID, LeaseExpires: &renewed, Claim: &next})
+	test.AssertNotError(t, err, "ClaimDNS01Recheck failed")
+	test.Assert(t, !*claimed.Exists, "Removed recheck was claimed")
+	err = sa.RemoveDNS01Recheck(ctx, &sapb.AuthorizationID{Id: &authzID})
+	test.AssertNotError(t, err, "Removing a removed recheck failed")
+}
@@ -0,0 +1,1685 @@
+// Package memsa provides a storage authority that keeps everything in memory.
+// It is meant for development and tests that need a working SA without a
+// database, such as boulder-dev. Nothing it stores survives a restart.
+package memsa
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/jmhodges/clock"
+	"github.com/weppos/publicsuffix-go/publicsuffix"
+	"golang.org/x/net/context"
+	jose "gopkg.in/square/go-jose.v2"
+
+	"github.com/letsencrypt/boulder/core"
+	corepb "github.com/letsencrypt/boulder/core/proto"
+	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/features"
+	bgrpc "github.com/letsencrypt/boulder/grpc"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/ratelimit"
+	"github.com/letsencrypt/boulder/revocation"
+	"github.com/letsencrypt/boulder/sa"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+)
+
+const (
+	// defaultSearchLimit and maxSearchLimit match the SQL SA's limits on pages
+	// of SearchCertificates results.
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+	// maxInvalidAuthorizations matches the SQL SA's limit on the results of
+	// ListInvalidAuthorizationsForAccount.
+	maxInvalidAuthorizations = 100
+)
+
+// authzEntry is a stored authorization. Like the SQL SA, pending
+// authorizations become final once they are finalized or deactivated, after
+// which only their status can change.
+type authzEntry struct {
+	authz core.Authorization
+	final bool
+}
+
+// issuedName is a name from an issued certificate, used for rate limits and
+// certificate search.
+type issuedName struct {
+	reversedName string
+	serial       string
+	notBefore    time.Time
+	renewal      bool
+}
+
+// fqdnSet is the set of names in an issued certificate.
+type fqdnSet struct {
+	hash    string
+	serial  string
+	issued  time.Time
+	expires time.Time
+}
+
+// orderFQDNSet is the set of names in an order that hasn't been finalized,
+// used to reuse pending orders.
+type orderFQDNSet struct {
+	hash    string
+	regID   int64
+	expires time.Time
+}
+
+// StorageAuthority is an in-memory implementation of core.StorageAuthority.
+// It is safe for concurrent use.
+type StorageAuthority struct {
+	clk clock.Clock
+	log blog.Logger
+
+	mu sync.Mutex
+
+	lastRegID   int64
+	regs        map[int64]core.Registration
+	lastChallID int64
+	authzs      map[string]*authzEntry
+
+	certs       map[string]core.Certificate
+	certStatus  map[string]core.CertificateStatus
+	issuedNames []issuedName
+	fqdnSets    []fqdnSet
+	// keyHashes maps the SHA-256 of certificates' SubjectPublicKeyInfo
+	// to their serials, in the order they were added.
+	keyHashes map[string][]string
+
+	lastOrderID   int64
+	orders        map[int64]*corepb.Order
+	orderFQDNSets map[int64]orderFQDNSet
+	// exemptionTokens are the policy exemption tokens used by orders
+	exemptionTokens map[string]bool
+
+	lastOverrideID int64
+	overrides      []*sapb.RateLimitOverride
+
+	lastWhitelistID int64
+	whitelist       []*sapb.ChallengeWhitelistEntry
+
+	// dns01Rechecks maps authorization IDs to their DNS-01 rechecks
+	dns01Rechecks map[string]*sapb.DNS01Recheck
+}
+
+// New returns an empty StorageAuthority.
+func New(clk clock.Clock, logger blog.Logger) *StorageAuthority {
+	return &StorageAuthority{
+		clk:           clk,
+		log:           logger,
+		regs:          make(map[int64]core.Registration),
+		authzs:        make(map[string]*authzEntry),
+		certs:         make(map[string]core.Certificate),
+		certStatus:    make(map[string]core.CertificateStatus),
+		keyHashes:     make(map[string][]string),
+		orders:        make(map[int64]*corepb.Order),
+		orderFQDNSets: make(map[int64]orderFQDNSet),
+
+		exemptionTokens: make(map[string]bool),
+		dns01Rechecks:   make(map[string]*sapb.DNS01Recheck),
+	}
+}
+
+func hashNames(names []string) string {
+	return string(core.HashNames(names))
+}
+
+// copyRegistration returns a copy of reg that doesn't share its contacts, so
+// that callers can't modify stored registrations.
+func copyRegistration(reg core.Registration) core.Registration {
+	if reg.Contact != nil {
+		contact := append([]string(nil), *reg.Contact...)
+		reg.Contact = &contact
+	}
+	return reg
+}
+
+// copyAuthz returns a copy of authz that doesn't share its challenges or
+// expiry, so that callers can't modify stored authorizations.
+func copyAuthz(authz core.Authorization) core.Authorization {
+	if authz.Expires != nil {
+		expires := *authz.Expires
+		authz.Expires = &expires
+	}
+	authz.Challenges = append([]core.Challenge(nil), authz.Challenges...)
+	return authz
+}
+
+// GetRegistration obtains a Registration by ID
+func (ssa *StorageAuthority) GetRegistration(_ context.Context, id int64) (core.Registration, error) {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	reg, ok := ssa.regs[id]
+	if !ok {
+		return core.Registration{}, berrors.NotFoundError("registration with ID '%d' not found", id)
+	}
+	return copyRegistration(reg), nil
+}
+
+// GetRegistrationByKey obtains a Registration by JWK
+func (ssa *StorageAuthority) GetRegistrationByKey(_ context.Context, key *jose.JSONWebKey) (core.Registration, error) {
+	if key == nil {
+		return core.Registration{}, fmt.Errorf("key argument to GetRegistrationByKey must not be nil")
+	}
+	sha, err := core.KeyDigest(key.Key)
+	if err != nil {
+		return core.Registration{}, err
+	}
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	reg, ok := ssa.regByKeyDigest(sha)
+	if !ok {
+		return core.Registration{}, berrors.NotFoundError("no registrations with public key sha256 %q", sha)
+	}
+	return copyRegistration(reg), nil
+}
+
+// regByKeyDigest must be called with ssa.mu held.
+func (ssa *StorageAuthority) regByKeyDigest(sha string) (core.Registration, bool) {
+	for _, reg := range ssa.regs {
+		if regSHA, err := core.KeyDigest(reg.Key.Key); err == nil && regSHA == sha {
+			return reg, true
+		}
+	}
+	return core.Registration{}, false
+}
+
+// NewRegistration stores a new Registration
+func (ssa *StorageAuthority) NewRegistration(_ context.Context, reg core.Registration) (core.Registration, error) {
+	if reg.Key == nil {
+		return reg, fmt.Errorf("registration must have a key")
+	}
+	sha, err := core.KeyDigest(reg.Key.Key)
+	if err != nil {
+		return reg, err
+	}
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	if _, exists := ssa.regByKeyDigest(sha); exists {
+		return reg, berrors.DuplicateError("a registration with this key already exists")
+	}
+	ssa.lastRegID++
+	reg.ID = ssa.lastRegID
+	reg.CreatedAt = ssa.clk.Now()
+	ssa.regs[reg.ID] = copyRegistration(reg)
+	return copyRegistration(reg), nil
+}
+
+// UpdateRegistration stores an updated Registration
+func (ssa *StorageAuthority) UpdateRegistration(_ context.Context, reg core.Registration) error {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	existing, ok := ssa.regs[reg.ID]
+	if !ok {
+		return berrors.NotFoundError("registration with ID '%d' not found", reg.ID)
+	}
+	// The creation time and initial IP never change.
+	reg.CreatedAt = existing.CreatedAt
+	reg.InitialIP = existing.InitialIP
+	ssa.regs[reg.ID] = copyRegistration(reg)
+	return nil
+}
+
+// DeactivateRegistration deactivates a currently valid registration
+func (ssa *StorageAuthority) DeactivateRegistration(_ context.Context, id int64) error {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	reg, ok := ssa.regs[id]
+	if ok && reg.Status == core.StatusValid {
+		reg.Status = core.StatusDeactivated
+		ssa.regs[id] = reg
+	}
+	return nil
+}
+
+// CountRegistrationsByIP returns the number of registrations created in the
+// time range for a single IP address.
+func (ssa *StorageAuthority) CountRegistrationsByIP(_ context.Context, ip net.IP, earliest, latest time.Time) (int, error) {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	count := 0
+	for _, reg := range ssa.regs {
+		if reg.InitialIP.Equal(ip) && reg.CreatedAt.After(earliest) && !reg.CreatedAt.After(latest) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CountRegistrationsByIPRange returns the number of registrations created in
+// the time range in an IP range. For IPv4 addresses, that range is limited to
+// the single IP. For IPv6 addresses, that range is a /48.
+func (ssa *StorageAuthority) CountRegistrationsByIPRange(_ context.Context, ip net.IP, earliest, latest time.Time) (int, error) {
+	ipNet := &net.IPNet{IP: ip.To16(), Mask: net.CIDRMask(48, 128)}
+	if ip.To4() != nil {
+		ipNet.Mask = net.CIDRMask(128, 128)
+	}
+	ipNet.IP = ipNet.IP.Mask(ipNet.Mask)
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	count := 0
+	for _, reg := range ssa.regs {
+		if ipNet.Contains(reg.InitialIP) && reg.CreatedAt.After(earliest) && !reg.CreatedAt.After(latest) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetAuthorization obtains an Authorization by ID
+func (ssa *StorageAuthority) GetAuthorization(_ context.Context, id string) (core.Authorization, error) {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	entry, ok := ssa.authzs[id]
+	if !ok {
+		return core.Authorization{}, berrors.NotFoundError("no authorization found with id %q", id)
+	}
+	return copyAuthz(entry.authz), nil
+}
+
+// NewPendingAuthorization stores a new pending authorization, assigning IDs
+// to it and its challenges.
+func (ssa *StorageAuthority) NewPendingAuthorization(_ context.Context, authz core.Authorization) (core.Authorization, error) {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	return ssa.newPendingAuthorization(authz), nil
+}
+
+// newPendingAuthorization must be called with ssa.mu held.
+func (ssa *StorageAuthority) newPendingAuthorization(authz core.Authorization) core.Authorization {
+	authz = copyAuthz(authz)
+	authz.ID = core.NewToken()
+	for _, exists := ssa.authzs[authz.ID]; exists; _, exists = ssa.authzs[authz.ID] {
+		authz.ID = core.NewToken()
+	}
+	for i := range authz.Challenges {
+		ssa.lastChallID++
+		authz.Challenges[i].ID = ssa.lastChallID
+	}
+	ssa.authzs[authz.ID] = &authzEntry{authz: authz}
+	return copyAuthz(authz)
+}
+
+// AddPendingAuthorizations creates a batch of pending authorizations and returns their IDs
+func (ssa *StorageAuthority) AddPendingAuthorizations(_ context.Context, req *sapb.AddPendingAuthorizationsRequest) (*sapb.AuthorizationIDs, error) {
+	var authzs []core.Authorization
+	for _, authPB := range req.Authz {
+		authz, err := bgrpc.PBToAuthz(authPB)
+		if err != nil {
+			return nil, err
+		}
+		authzs = append(authzs, authz)
+	}
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	ids := []string{}
+	for _, authz := range authzs {
+		ids = append(ids, ssa.newPendingAuthorization(authz).ID)
+	}
+	return &sapb.AuthorizationIDs{Ids: ids}, nil
+}
+
+// GetPendingAuthorization returns the pending authorization with the given
+// identifier that expires soonest after the request's ValidUntil, if any.
+func (ssa *StorageAuthority) GetPendingAuthorization(_ context.Context, req *sapb.GetPendingAuthorizationRequest) (*core.Authorization, error) {
+	ident := core.AcmeIdentifier{
+		Type:  core.IdentifierType(*req.IdentifierType),
+		Value: *req.IdentifierValue,
+	}
+	validUntil := time.Unix(0, *req.ValidUntil)
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	var found *core.Authorization
+	for _, entry := range ssa.authzs {
+		a := entry.authz
+		if entry.final || a.RegistrationID != *req.RegistrationID || a.Identifier != ident ||
+			a.Status != core.StatusPending || a.Expires == nil || !a.Expires.After(validUntil) {
+			continue
+		}
+		if found == nil || a.Expires.Before(*found.Expires) {
+			authz := copyAuthz(a)
+			found = &authz
+		}
+	}
+	if found == nil {
+		return nil, berrors.NotFoundError("pending authz not found")
+	}
+	return found, nil
+}
+
+// updateChallenges copies the status, error and validation records of
+// challenges to those of a stored authorization that are still pending, as
+// the SQL SA does. It must be called with ssa.mu held.
+func updateChallenges(stored *core.Authorization, challenges []core.Challenge) error {
+	if len(stored.Challenges) != len(challenges) {
+		return fmt.Errorf("Invalid number of challenges provided")
+	}
+	for i, chall := range challenges {
+		status := stored.Challenges[i].Status
+		if status != core.StatusPending && status != core.StatusProcessing {
+			continue
+		}
+		stored.Challenges[i].Status = chall.Status
+		stored.Challenges[i].Error = chall.Error
+		stored.Challenges[i].ValidationRecord = chall.ValidationRecord
+		if features.Enabled(features.StoreChallengeAttempts) {
+			stored.Challenges[i].Attempts = chall.Attempts
+		}
+	}
+	return nil
+}
+
+func statusIsPending(status core.AcmeStatus) bool {
+	return status == core.StatusPending || status == core.StatusProcessing || status == core.StatusUnknown
+}
+
+// UpdatePendingAuthorization updates a Pending Authorization's Challenges.
+func (ssa *StorageAuthority) UpdatePendingAuthorization(_ context.Context, authz core.Authorization) error {
+	if !statusIsPending(authz.Status) {
+		return berrors.WrongAuthorizationStateError("authorization is not pending")
+	}
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	entry, ok := ssa.authzs[authz.ID]
+	if !ok {
+		return berrors.InternalServerError("authorization with ID '%s' not found", authz.ID)
+	}
+	if entry.final {
+		return berrors.WrongAuthorizationStateError("cannot update a finalized authorization")
+	}
+	return updateChallenges(&entry.authz, authz.Challenges)
+}
+
+// FinalizeAuthorization converts a Pending Authorization to a final one. If the
+// Authorization is not found a berrors.NotFound result is returned. If the
+// Authorization is status pending a berrors.InternalServer error is returned.
+func (ssa *StorageAuthority) FinalizeAuthorization(_ context.Context, authz core.Authorization) error {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	entry, ok := ssa.authzs[authz.ID]
+	if !ok || entry.final {
+		return berrors.NotFoundError("authorization with ID %q not found", authz.ID)
+	}
+	if statusIsPending(authz.Status) {
+		return berrors.InternalServerError("authorization to finalize is pending (ID %q)", authz.ID)
+	}
+	final := copyAuthz(authz)
+	final.Challenges = entry.authz.Challenges
+	if err := updateChallenges(&final, authz.Challenges); err != nil {
+		return err
+	}
+	ssa.authzs[authz.ID] = &authzEntry{authz: final, final: true}
+	return nil
+}
+
+// DeactivateAuthorization deactivates a currently valid or pending authorization
+func (ssa *StorageAuthority) DeactivateAuthorization(_ context.Context, id string) error {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	entry, ok := ssa.authzs[id]
+	if !ok {
+		return nil
+	}
+	if !entry.final {
+		if entry.authz.Status != core.StatusPending {
+			return berrors.WrongAuthorizationStateError("authorization not pending")
+		}
+		entry.final = true
+		entry.authz.Status = core.StatusDeactivated
+	} else if entry.authz.Status == core.StatusValid {
+		entry.authz.Status = core.StatusDeactivated
+	}
+	return nil
+}
+
+// RetryChallenge reopens an unexpired invalid authorization so that its
+// failed challenge can be attempted again, resetting the challenge but
+// keeping its attempts. Like the SQL SA it requires the StoreChallengeAttempts
+// feature.
+func (ssa *StorageAuthority) RetryChallenge(_ context.Context, req *sapb.RetryChallengeRequest) error {
+	if !features.Enabled(features.StoreChallengeAttempts) {
+		return berrors.InternalServerError("retrying challenges requires the StoreChallengeAttempts feature")
+	}
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	entry, ok := ssa.authzs[*req.AuthorizationID]
+	if !ok || !entry.final {
+		return berrors.NotFoundError("no finalized authorization found with id %q", *req.AuthorizationID)
+	}
+	if entry.authz.Status != core.StatusInvalid {
+		return berrors.WrongAuthorizationStateError("authorization is not invalid")
+	}
+	if entry.authz.Expires == nil || entry.authz.Expires.Before(ssa.clk.Now()) {
+		return berrors.WrongAuthorizationStateError("authorization has expired")
+	}
+	for i, chall := range entry.authz.Challenges {
+		if chall.ID != *req.ChallengeID || chall.Status != core.StatusInvalid {
+			continue
+		}
+		chall.Status = core.StatusPending
+		chall.Error = nil
+		chall.ValidationRecord = nil
+		authz := copyAuthz(entry.authz)
+		authz.Status = core.StatusPending
+		authz.ValidationMethod = ""
+		authz.Challenges[i] = chall
+		ssa.authzs[authz.ID] = &authzEntry{authz: authz}
+		return nil
+	}
+	return berrors.WrongAuthorizationStateError("challenge %d is not an invalid challenge of authorization %q", *req.ChallengeID, *req.AuthorizationID)
+}
+
+// RevokeAuthorizationsByDomain invalidates all unexpired pending or
+// finalized authorizations for a specific domain
+func (ssa *StorageAuthority) RevokeAuthorizationsByDomain(_ context.Context, ident core.AcmeIdentifier) (int64, int64, error) {
+	now := ssa.clk.Now()
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	var finalized, pending int64
+	for _, entry := range ssa.authzs {
+		a := &entry.authz
+		if a.Identifier != ident || a.Status == core.StatusRevoked || a.Expires == nil || !a.Expires.After(now) {
+			continue
+		}
+		a.Status = core.StatusRevoked
+		if entry.final {
+			finalized++
+		} else {
+			pending++
+		}
+	}
+	return finalized, pending, nil
+}
+
+// CountPendingAuthorizations returns the number of pending, unexpired
+// authorizations for the given registration.
+func (ssa *StorageAuthority) CountPendingAuthorizations(_ context.Context, regID int64) (int, error) {
+	now := ssa.clk.Now()
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	count := 0
+	for _, entry := range ssa.authzs {
+		a := entry.authz
+		if !entry.final && a.RegistrationID == regID && a.Status == core.StatusPending &&
+			a.Expires != nil && a.Expires.After(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CountInvalidAuthorizations counts invalid authorizations for a user expiring
+// in a given time range.
+func (ssa *StorageAuthority) CountInvalidAuthorizations(_ context.Context, req *sapb.CountInvalidAuthorizationsRequest) (*sapb.Count, error) {
+	ident := core.AcmeIdentifier{Type: core.IdentifierDNS, Value: *req.Hostname}
+	earliest, latest := time.Unix(0, *req.Range.Earliest), time.Unix(0, *req.Range.Latest)
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	var count int64
+	for _, entry := range ssa.authzs {
+		a := entry.authz
+		if entry.final && a.RegistrationID == *req.RegistrationID && a.Identifier == ident &&
+			a.Status == core.StatusInvalid && a.Expires != nil &&
+			a.Expires.After(earliest) && !a.Expires.After(latest) {
+			count++
+		}
+	}
+	return &sapb.Count{Count: &count}, nil
+}
+
+// getAuthorizations returns the unexpired authorizations with the given
+// status for as many of names as possible, preferring those that expire last.
+// If requireV2Authzs is true only authorizations that belong to an order are
+// returned. It must be called with ssa.mu held.
+func (ssa *StorageAuthority) getAuthorizations(
+	status core.AcmeStatus,
+	regID int64,
+	names []string,
+	now time.Time,
+	requireV2Authzs bool,
+) (map[string]*core.Authorization, error) {
+	if len(names) == 0 {
+		return nil, berrors.InternalServerError("no names received")
+	}
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+	var v2IDs map[string]bool
+	if requireV2Authzs {
+		v2IDs = make(map[string]bool)
+		for _, order := range ssa.orders {
+			for _, id := range order.Authorizations {
+				v2IDs[id] = true
+			}
+		}
+	}
+	byName := make(map[string]*core.Authorization)
+	for id, entry := range ssa.authzs {
+		a := entry.authz
+		if a.RegistrationID != regID || a.Status != status || a.Expires == nil || !a.Expires.After(now) ||
+			a.Identifier.Type != core.IdentifierDNS || !wanted[a.Identifier.Value] {
+			continue
+		}
+		if requireV2Authzs && !v2IDs[id] {
+			continue
+		}
+		existing, present := byName[a.Identifier.Value]
+		if !present || a.Expires.After(*existing.Expires) {
+			authz := copyAuthz(a)
+			byName[a.Identifier.Value] = &authz
+		}
+	}
+	return byName, nil
+}
+
+// GetValidAuthorizations returns the latest authorization object for all
+// domain names from the parameters that the account has authorizations for.
+func (ssa *StorageAuthority) GetValidAuthorizations(_ context.Context, regID int64, names []string, now time.Time) (map[string]*core.Authorization, error) {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	return ssa.getAuthorizations(core.StatusValid, regID, names, now, false)
+}
+
+// GetAuthorizations returns a map of valid or pending authorizations for as many names as possible
+func (ssa *StorageAuthority) GetAuthorizations(_ context.Context, req *sapb.GetAuthorizationsRequest) (*sapb.Authorizations, error) {
+	now := time.Unix(0, *req.Now)
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	authzMap, err := ssa.getAuthorizations(core.StatusValid, *req.RegistrationID, req.Domains, now, *req.RequireV2Authzs)
+	if err != nil {
+		return nil, err
+	}
+	var remainingNames []string
+	for _, name := range req.Domains {
+		if _, present := authzMap[name]; !present {
+			remainingNames = append(remainingNames, name)
+		}
+	}
+	if len(remainingNames) > 0 {
+		pending, err := ssa.getAuthorizations(core.StatusPending, *req.RegistrationID, remainingNames, now, *req.RequireV2Authzs)
+		if err != nil {
+			return nil, err
+		}
+		for name, a := range pending {
+			authzMap[name] = a
+		}
+	}
+
+	resp := &sapb.Authorizations{}
+	for name, authz := range authzMap {
+		authzPB, err := bgrpc.AuthzToPB(*authz)
+		if err != nil {
+			return nil, err
+		}
+		domain := name
+		resp.Authz = append(resp.Authz, &sapb.Authorizations_MapElement{Domain: &domain, Authz: authzPB})
+	}
+	return resp, nil
+}
+
+// GetAuthz2 always returns a NotFound error, because authz2 style
+// authorizations aren't supported.
+func (ssa *StorageAuthority) GetAuthz2(_ context.Context, id *sapb.AuthorizationID2) (*corepb.Authorization, error) {
+	return nil, berrors.NotFoundError("authorization %d not found", *id.Id)
+}
+
+// DeactivateAuthorization2 always returns a NotFound error, because authz2
+// style authorizations aren't supported.
+func (ssa *StorageAuthority) DeactivateAuthorization2(_ context.Context, id *sapb.AuthorizationID2) (*corepb.Empty, error) {
+	return nil, berrors.NotFoundError("authorization %d not found", *id.Id)
+}
+
+// AddCertificate stores an issued certificate and returns the digest as
+// a string, or an error if any occurred.
+func (ssa *StorageAuthority) AddCertificate(_ context.Context, der []byte, regID int64, ocspResponse []byte, issued *time.Time) (string, error) {
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		return "", err
+	}
+	digest := core.Fingerprint256(der)
+	serial := core.SerialToString(parsed.SerialNumber)
+	if issued == nil {
+		now := ssa.clk.Now()
+		issued = &now
+	}
+
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	if _, exists := ssa.certs[serial]; exists {
+		return "", berrors.DuplicateError("cannot add a duplicate cert")
+	}
+	ssa.certs[serial] = core.Certificate{
+		RegistrationID: regID,
+		Serial:         serial,
+		Digest:         digest,
+		DER:            der,
+		Issued:         *issued,
+		Expires:        parsed.NotAfter,
+	}
+	status := core.CertificateStatus{
+		Serial:       serial,
+		Status:       core.OCSPStatusGood,
+		OCSPResponse: []byte{},
+		NotAfter:     parsed.NotAfter,
+	}
+	if len(ocspResponse) != 0 {
+		status.OCSPResponse = ocspResponse
+		status.OCSPLastUpdated = ssa.clk.Now()
+	}
+	ssa.certStatus[serial] = status
+
+	var isRenewal bool
+	if features.Enabled(features.SetIssuedNamesRenewalBit) {
+		isRenewal = ssa.fqdnSetExists(parsed.DNSNames)
+	}
+	for _, name := range parsed.DNSNames {
+		ssa.issuedNames = append(ssa.issuedNames, issuedName{
+			reversedName: sa.ReverseName(name),
+			serial:       serial,
+			notBefore:    parsed.NotBefore,
+			renewal:      isRenewal,
+		})
+	}
+	if features.Enabled(features.StoreKeyHashes) {
+		keyHash := sha256.Sum256(parsed.RawSubjectPublicKeyInfo)
+		key := string(keyHash[:])
+		ssa.keyHashes[key] = append(ssa.keyHashes[key], serial)
+	}
+	ssa.fqdnSets = append(ssa.fqdnSets, fqdnSet{
+		hash:    hashNames(parsed.DNSNames),
+		serial:  serial,
+		issued:  parsed.NotBefore,
+		expires: parsed.NotAfter,
+	})
+	return digest, nil
+}
+
+// GetCertificate takes a serial number and returns the corresponding
+// certificate, or error if it does not exist.
+func (ssa *StorageAuthority) GetCertificate(_ context.Context, serial string) (core.Certificate, error) {
+	if !core.ValidSerial(serial) {
+		return core.Certificate{}, fmt.Errorf("Invalid certificate serial %s", serial)
+	}
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	cert, ok := ssa.certs[serial]
+	if !ok {
+		return core.Certificate{}, berrors.NotFoundError("certificate with serial %q not found", serial)
+	}
+	return cert, nil
+}
+
+// GetCertificateStatus takes a hexadecimal string representing the full 128-bit serial
+// number of a certificate and returns data about that certificate's current
+// validity.
+func (ssa *StorageAuthority) GetCertificateStatus(_ context.Context, serial string) (core.CertificateStatus, error) {
+	if !core.ValidSerial(serial) {
+		return core.CertificateStatus{}, fmt.Errorf("Invalid certificate serial %s", serial)
+	}
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	return ssa.certStatus[serial], nil
+}
+
+// MarkCertificateRevoked stores the fact that a certificate is revoked, along
+// with a timestamp and a reason.
+func (ssa *StorageAuthority) MarkCertificateRevoked(_ context.Context, serial string, reasonCode revocation.Reason) error {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	status, ok := ssa.certStatus[serial]
+	if !ok {
+		return fmt.Errorf("Unable to mark certificate %s revoked: cert not found.", serial)
+	}
+	status.Status = core.OCSPStatusRevoked
+	status.RevokedDate = ssa.clk.Now()
+	status.RevokedReason = reasonCode
+	ssa.certStatus[serial] = status
+	return nil
+}
+
+// RevokeCertificate stores revocation information about a certificate, if it
+// isn't already revoked.
+func (ssa *StorageAuthority) RevokeCertificate(_ context.Context, req *sapb.RevokeCertificateRequest) error {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	status, ok := ssa.certStatus[*req.Serial]
+	if !ok || status.Status == core.OCSPStatusRevoked {
+		return berrors.InternalServerError("no certificate with serial %s and status %s", *req.Serial, string(core.OCSPStatusRevoked))
+	}
+	revokedDate := time.Unix(0, *req.Date)
+	status.Status = core.OCSPStatusRevoked
+	status.RevokedReason = revocation.Reason(*req.Reason)
+	status.RevokedDate = revokedDate
+	status.OCSPLastUpdated = revokedDate
+	status.OCSPResponse = req.Response
+	ssa.certStatus[*req.Serial] = status
+	return nil
+}
+
+// countCertificates returns the number of distinct certificates issued in the
+// time range for domain, and if includeSubdomains is true its subdomains. If
+// the AllowRenewalFirstRL feature is enabled renewals aren't counted. It must
+// be called with ssa.mu held.
+func (ssa *StorageAuthority) countCertificates(domain string, includeSubdomains bool, earliest, latest time.Time) int64 {
+	reversed := sa.ReverseName(domain)
+	serials := make(map[string]bool)
+	for _, in := range ssa.issuedNames {
+		if in.reversedName != reversed && !(includeSubdomains && strings.HasPrefix(in.reversedName, reversed+".")) {
+			continue
+		}
+		if features.Enabled(features.AllowRenewalFirstRL) && in.renewal {
+			continue
+		}
+		if in.notBefore.After(earliest) && !in.notBefore.After(latest) {
+			serials[in.serial] = true
+		}
+	}
+	return int64(len(serials))
+}
+
+func (ssa *StorageAuthority) countCertificatesByNames(domains []string, includeSubdomains bool, earliest, latest time.Time) []*sapb.CountByNames_MapElement {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	var ret []*sapb.CountByNames_MapElement
+	for _, domain := range domains {
+		name := domain
+		count := ssa.countCertificates(domain, includeSubdomains, earliest, latest)
+		ret = append(ret, &sapb.CountByNames_MapElement{Name: &name, Count: &count})
+	}
+	return ret
+}
+
+// CountCertificatesByNames counts, for each input domain, the number of
+// certificates issued in the given time range for that domain and its
+// subdomains.
+func (ssa *StorageAuthority) CountCertificatesByNames(_ context.Context, domains []string, earliest, latest time.Time) ([]*sapb.CountByNames_MapElement, error) {
+	return ssa.countCertificatesByNames(domains, true, earliest, latest), nil
+}
+
+// CountCertificatesByExactNames counts, for each input domain, the number of
+// certificates issued in the given time range for exactly that domain.
+func (ssa *StorageAuthority) CountCertificatesByExactNames(_ context.Context, domains []string, earliest, latest time.Time) ([]*sapb.CountByNames_MapElement, error) {
+	return ssa.countCertificatesByNames(domains, false, earliest, latest), nil
+}
+
+// fqdnSetExists must be called with ssa.mu held.
+func (ssa *StorageAuthority) fqdnSetExists(names []string) bool {
+	hash := hashNames(names)
+	for _, set := range ssa.fqdnSets {
+		if set.hash == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// CountFQDNSets returns the number of certificates issued for exactly names
+// within window.
+func (ssa *StorageAuthority) CountFQDNSets(_ context.Context, window time.Duration, names []string) (int64, error) {
+	hash := hashNames(names)
+	earliest := ssa.clk.Now().Add(-window)
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	var count int64
+	for _, set := range ssa.fqdnSets {
+		if set.hash == hash && set.issued.After(earliest) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// FQDNSetExists returns a bool indicating if a certificate has been issued
+// for exactly names.
+func (ssa *StorageAuthority) FQDNSetExists(_ context.Context, names []string) (bool, error) {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	return ssa.fqdnSetExists(names), nil
+}
+
+// PreviousCertificateExists returns true iff there was at least one certificate
+// issued with the provided domain name, and the most recent such certificate
+// was issued by the provided registration ID.
+func (ssa *StorageAuthority) PreviousCertificateExists(_ context.Context, req *sapb.PreviousCertificateExistsRequest) (*sapb.Exists, error) {
+	reversed := sa.ReverseName(*req.Domain)
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	var latest *issuedName
+	for i, in := range ssa.issuedNames {
+		if in.reversedName == reversed && (latest == nil || in.notBefore.After(latest.notBefore)) {
+			latest = &ssa.issuedNames[i]
+		}
+	}
+	exists := latest != nil && ssa.certs[latest.serial].RegistrationID == *req.RegID
+	return &sapb.Exists{Exists: &exists}, nil
+}
+
+// CertificateReplaced always returns false, since orders here don't record
+// the certificate they replace.
+func (ssa *StorageAuthority) CertificateReplaced(_ context.Context, _ *sapb.Serial) (*sapb.Exists, error) {
+	f := false
+	return &sapb.Exists{Exists: &f}, nil
+}
+
+// NewOrder adds a new v2 style order
+func (ssa *StorageAuthority) NewOrder(_ context.Context, req *corepb.Order) (*corepb.Order, error) {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	for _, token := range req.Exemptions {
+		if ssa.exemptionTokens[token] {
+			return nil, berrors.DuplicateError("policy exemption has already been used")
+		}
+	}
+	for _, token := range req.Exemptions {
+		ssa.exemptionTokens[token] = true
+	}
+	ssa.lastOrderID++
+	id := ssa.lastOrderID
+	created := ssa.clk.Now().UnixNano()
+	beganProcessing := false
+	order := proto.Clone(req).(*corepb.Order)
+	order.Id = &id
+	order.Created = &created
+	order.BeganProcessing = &beganProcessing
+	order.Status = nil
+	ssa.orders[id] = order
+	ssa.orderFQDNSets[id] = orderFQDNSet{
+		hash:    hashNames(req.Names),
+		regID:   *req.RegistrationID,
+		expires: time.Unix(0, *req.Expires),
+	}
+
+	req.Id = &id
+	req.Created = &created
+	req.BeganProcessing = &beganProcessing
+	status, err := ssa.statusForOrder(order)
+	if err != nil {
+		return nil, err
+	}
+	req.Status = &status
+	return req, nil
+}
+
+// SetOrderProcessing updates a provided *corepb.Order in pending status to be
+// in processing status.
+func (ssa *StorageAuthority) SetOrderProcessing(_ context.Context, req *corepb.Order) error {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	order, ok := ssa.orders[*req.Id]
+	if !ok || *order.BeganProcessing {
+		return berrors.InternalServerError("no order updated to beganProcessing status")
+	}
+	beganProcessing := true
+	order.BeganProcessing = &beganProcessing
+	return nil
+}
+
+// SetOrderError updates a provided Order's error field.
+func (ssa *StorageAuthority) SetOrderError(_ context.Context, req *corepb.Order) error {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	order, ok := ssa.orders[*req.Id]
+	if !ok {
+		return berrors.InternalServerError("no order updated with new error field")
+	}
+	order.Error = proto.Clone(req.Error).(*corepb.ProblemDetails)
+	return nil
+}
+
+// FinalizeOrder finalizes a provided *corepb.Order by persisting the
+// CertificateSerial.
+func (ssa *StorageAuthority) FinalizeOrder(_ context.Context, req *corepb.Order) error {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	order, ok := ssa.orders[*req.Id]
+	if !ok || !*order.BeganProcessing {
+		return berrors.InternalServerError("no order updated for finalization")
+	}
+	if _, ok := ssa.orderFQDNSets[*req.Id]; !ok {
+		return berrors.InternalServerError("No orderFQDNSet exists to delete")
+	}
+	serial := *req.CertificateSerial
+	order.CertificateSerial = &serial
+	// Finalized orders can't be reused.
+	delete(ssa.orderFQDNSets, *req.Id)
+	return nil
+}
+
+// GetOrder is used to retrieve an already existing order object
+func (ssa *StorageAuthority) GetOrder(_ context.Context, req *sapb.OrderRequest) (*corepb.Order, error) {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	return ssa.getOrder(*req.Id)
+}
+
+// getOrder must be called with ssa.mu held.
+func (ssa *StorageAuthority) getOrder(id int64) (*corepb.Order, error) {
+	stored, ok := ssa.orders[id]
+	if !ok {
+		return nil, berrors.NotFoundError("no order found for ID %d", id)
+	}
+	order := proto.Clone(stored).(*corepb.Order)
+	status, err := ssa.statusForOrder(order)
+	if err != nil {
+		return nil, err
+	}
+	order.Status = &status
+	return order, nil
+}
+
+// statusForOrder computes the status of an order from its error, expiry,
+// certificate serial and authorizations, in the same way as the SQL SA. It
+// must be called with ssa.mu held.
+func (ssa *StorageAuthority) statusForOrder(order *corepb.Order) (string, error) {
+	if order.Error != nil {
+		return string(core.StatusInvalid), nil
+	}
+	now := ssa.clk.Now()
+	if time.Unix(0, *order.Expires).Before(now) {
+		return string(core.StatusInvalid), nil
+	}
+
+	counts := make(map[core.AcmeStatus]int)
+	for _, id := range order.Authorizations {
+		entry, ok := ssa.authzs[id]
+		if !ok || entry.authz.RegistrationID != *order.RegistrationID {
+			return "", berrors.InternalServerError(
+				"authorization %s of order %d is missing", id, *order.Id)
+		}
+		authz := entry.authz
+		switch authz.Status {
+		case core.StatusInvalid, core.StatusDeactivated, core.StatusPending, core.StatusValid:
+			counts[authz.Status]++
+		default:
+			return "", berrors.InternalServerError(
+				"Order is in an invalid state. Authz %s has invalid status %q",
+				authz.ID, authz.Status)
+		}
+		if authz.Expires.Before(now) {
+			return string(core.StatusInvalid), nil
+		}
+	}
+
+	switch {
+	case counts[core.StatusInvalid] > 0:
+		return string(core.StatusInvalid), nil
+	case counts[core.StatusDeactivated] > 0:
+		return string(core.StatusDeactivated), nil
+	case counts[core.StatusPending] > 0:
+		return string(core.StatusPending), nil
+	case len(order.Names) != counts[core.StatusValid]:
+		return "", berrors.InternalServerError(
+			"Order has the incorrect number of valid authorizations & no pending, " +
+				"deactivated or invalid authorizations")
+	case order.CertificateSerial != nil && *order.CertificateSerial != "":
+		return string(core.StatusValid), nil
+	case order.BeganProcessing != nil && *order.BeganProcessing:
+		return string(core.StatusProcessing), nil
+	default:
+		return string(core.StatusReady), nil
+	}
+}
+
+// CountOrders returns the number of orders created by an account in the time
+// range, including the start but not the end.
+func (ssa *StorageAuthority) CountOrders(_ context.Context, acctID int64, earliest, latest time.Time) (int, error) {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	count := 0
+	for _, order := range ssa.orders {
+		created := time.Unix(0, *order.Created)
+		if *order.RegistrationID == acctID && !created.Before(earliest) && created.Before(latest) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetOrderForNames tries to find a **pending** order with the exact set of
+// names requested, associated with the given accountID. Only unexpired orders
+// with status pending are considered.
+func (ssa *StorageAuthority) GetOrderForNames(_ context.Context, req *sapb.GetOrderForNamesRequest) (*corepb.Order, error) {
+	hash := hashNames(req.Names)
+	now := ssa.clk.Now()
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	for id, set := range ssa.orderFQDNSets {
+		if set.hash != hash || set.regID != *req.AcctID || !set.expires.After(now) {
+			continue
+		}
+		order, err := ssa.getOrder(id)
+		if err != nil {
+			return nil, err
+		}
+		if *order.Status == string(core.StatusPending) {
+			return order, nil
+		}
+	}
+	return nil, berrors.NotFoundError("no order matching request found")
+}
+
+// GetValidOrderAuthorizations is used to find the valid, unexpired authorizations
+// associated with a specific order and account ID.
+func (ssa *StorageAuthority) GetValidOrderAuthorizations(_ context.Context, req *sapb.GetValidOrderAuthorizationsRequest) (map[string]*core.Authorization, error) {
+	now := ssa.clk.Now()
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	byName := make(map[string]*core.Authorization)
+	order, ok := ssa.orders[*req.Id]
+	if !ok {
+		return byName, nil
+	}
+	for _, id := range order.Authorizations {
+		entry, ok := ssa.authzs[id]
+		if !ok {
+			continue
+		}
+		a := entry.authz
+		if a.RegistrationID != *req.AcctID || a.Status != core.StatusValid || a.Expires == nil || !a.Expires.After(now) {
+			continue
+		}
+		if a.Identifier.Type != core.IdentifierDNS {
+			return nil, fmt.Errorf("unknown identifier type: %q on authz id %q", a.Identifier.Type, a.ID)
+		}
+		existing, present := byName[a.Identifier.Value]
+		if !present || a.Expires.After(*existing.Expires) {
+			authz := copyAuthz(a)
+			byName[a.Identifier.Value] = &authz
+		}
+	}
+	return byName, nil
+}
+
+// GetOrderAuthorizations is used to find all of the authorizations, whatever
+// their status, associated with a specific order and account ID.
+func (ssa *StorageAuthority) GetOrderAuthorizations(_ context.Context, req *sapb.GetOrderAuthorizationsRequest) (map[string]*core.Authorization, error) {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	byName := make(map[string]*core.Authorization)
+	order, ok := ssa.orders[*req.Id]
+	if !ok {
+		return byName, nil
+	}
+	for _, id := range order.Authorizations {
+		entry, ok := ssa.authzs[id]
+		if !ok || entry.authz.RegistrationID != *req.AcctID {
+			continue
+		}
+		a := entry.authz
+		if a.Identifier.Type != core.IdentifierDNS {
+			return nil, fmt.Errorf("unknown identifier type: %q on authz id %q", a.Identifier.Type, a.ID)
+		}
+		if _, present := byName[a.Identifier.Value]; present {
+			return nil, berrors.InternalServerError(
+				"Found multiple authorizations within one order for identifier %q",
+				a.Identifier.Value)
+		}
+		authz := copyAuthz(a)
+		authz.Challenges = nil
+		byName[a.Identifier.Value] = &authz
+	}
+	return byName, nil
+}
+
+// AddRateLimitOverride stores a new rate limit override, expiring any
+// unexpired override for the same limit and key or registration ID.
+func (ssa *StorageAuthority) AddRateLimitOverride(_ context.Context, req *sapb.RateLimitOverride) (*sapb.RateLimitOverride, error) {
+	if !ratelimit.ValidLimitName(*req.LimitName) {
+		return nil, berrors.MalformedError("unknown rate limit %q", *req.LimitName)
+	}
+	var key string
+	var regID int64
+	if req.Key != nil {
+		key = *req.Key
+	}
+	if req.RegistrationID != nil {
+		regID = *req.RegistrationID
+	}
+	if (key == "") == (regID == 0) {
+		return nil, berrors.MalformedError("exactly one of key and registration ID must be provided")
+	}
+	if *req.Threshold < 0 {
+		return nil, berrors.MalformedError("threshold must not be negative")
+	}
+	if *req.CreatedBy == "" || *req.Reason == "" {
+		return nil, berrors.MalformedError("an override must record who created it and why")
+	}
+	now := ssa.clk.Now()
+	if !time.Unix(0, *req.Expires).After(now) {
+		return nil, berrors.MalformedError("override expiry must be in the future")
+	}
+
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	nowNS := now.UnixNano()
+	for _, o := range ssa.overrides {
+		if *o.LimitName == *req.LimitName && *o.Key == key && *o.RegistrationID == regID && *o.Expires > nowNS {
+			expires := nowNS
+			o.Expires = &expires
+		}
+	}
+	ssa.lastOverrideID++
+	id := ssa.lastOverrideID
+	override := &sapb.RateLimitOverride{
+		Id:             &id,
+		LimitName:      proto.String(*req.LimitName),
+		Key:            &key,
+		RegistrationID: &regID,
+		Threshold:      proto.Int64(*req.Threshold),
+		CreatedBy:      proto.String(*req.CreatedBy),
+		Created:        &nowNS,
+		Expires:        proto.Int64(*req.Expires),
+		Reason:         proto.String(*req.Reason),
+	}
+	ssa.overrides = append(ssa.overrides, override)
+	ssa.log.AuditInfof("Added rate limit override: id=[%d] limit=[%s] key=[%s] regID=[%d] threshold=[%d] by=[%s] reason=[%s]",
+		id, *req.LimitName, key, regID, *req.Threshold, *req.CreatedBy, *req.Reason)
+	return proto.Clone(override).(*sapb.RateLimitOverride), nil
+}
+
+// ExpireRateLimitOverride immediately expires the rate limit override with the
+// given ID. A NotFound error is returned if there is no unexpired override
+// with that ID.
+func (ssa *StorageAuthority) ExpireRateLimitOverride(_ context.Context, req *sapb.ExpireRateLimitOverrideRequest) error {
+	if *req.ExpiredBy == "" || *req.Reason == "" {
+		return berrors.MalformedError("expiring an override must record who expired it and why")
+	}
+	now := ssa.clk.Now().UnixNano()
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	for _, o := range ssa.overrides {
+		if *o.Id == *req.Id && *o.Expires > now {
+			o.Expires = &now
+			ssa.log.AuditInfof("Expired rate limit override: id=[%d] by=[%s] reason=[%s]",
+				*req.Id, *req.ExpiredBy, *req.Reason)
+			return nil
+		}
+	}
+	return berrors.NotFoundError("no unexpired rate limit override with ID %d", *req.Id)
+}
+
+// GetRateLimitOverrides returns all of the rate limit overrides that have not
+// expired as of the request's timestamp.
+func (ssa *StorageAuthority) GetRateLimitOverrides(_ context.Context, req *sapb.GetRateLimitOverridesRequest) (*sapb.RateLimitOverrides, error) {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	overrides := &sapb.RateLimitOverrides{}
+	for _, o := range ssa.overrides {
+		if *o.Expires > *req.Now {
+			overrides.Overrides = append(overrides.Overrides, proto.Clone(o).(*sapb.RateLimitOverride))
+		}
+	}
+	return overrides, nil
+}
+
+// whitelistEntryActive returns whether a challenge whitelist entry hasn't
+// expired as of now, in Unix nanoseconds.
+func whitelistEntryActive(e *sapb.ChallengeWhitelistEntry, now int64) bool {
+	return *e.Expires == 0 || *e.Expires > now
+}
+
+// AddChallengeWhitelistEntry enables a challenge type for an account, expiring
+// any unexpired entry for the same challenge type and account.
+func (ssa *StorageAuthority) AddChallengeWhitelistEntry(_ context.Context, req *sapb.ChallengeWhitelistEntry) (*sapb.ChallengeWhitelistEntry, error) {
+	if *req.ChallengeType == "" || *req.RegistrationID == 0 {
+		return nil, berrors.MalformedError("a challenge type and registration ID must be provided")
+	}
+	if *req.CreatedBy == "" || *req.Reason == "" {
+		return nil, berrors.MalformedError("a challenge whitelist entry must record who created it and why")
+	}
+	now := ssa.clk.Now().UnixNano()
+	var expires int64
+	if req.Expires != nil {
+		expires = *req.Expires
+	}
+	if expires != 0 && expires <= now {
+		return nil, berrors.MalformedError("challenge whitelist entry expiry must be in the future")
+	}
+
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	for _, e := range ssa.whitelist {
+		if *e.ChallengeType == *req.ChallengeType && *e.RegistrationID == *req.RegistrationID && whitelistEntryActive(e, now) {
+			e.Expires = proto.Int64(now)
+		}
+	}
+	ssa.lastWhitelistID++
+	entry := &sapb.ChallengeWhitelistEntry{
+		Id:             proto.Int64(ssa.lastWhitelistID),
+		ChallengeType:  proto.String(*req.ChallengeType),
+		RegistrationID: proto.Int64(*req.RegistrationID),
+		CreatedBy:      proto.String(*req.CreatedBy),
+		Created:        proto.Int64(now),
+		Expires:        proto.Int64(expires),
+		Reason:         proto.String(*req.Reason),
+	}
+	ssa.whitelist = append(ssa.whitelist, entry)
+	ssa.log.AuditInfof("Added challenge whitelist entry: id=[%d] challenge=[%s] regID=[%d] by=[%s] reason=[%s]",
+		*entry.Id, *req.ChallengeType, *req.RegistrationID, *req.CreatedBy, *req.Reason)
+	return proto.Clone(entry).(*sapb.ChallengeWhitelistEntry), nil
+}
+
+// RemoveChallengeWhitelistEntry immediately expires the challenge whitelist
+// entry for a challenge type and account. A NotFound error is returned if
+// there is no unexpired entry for them.
+func (ssa *StorageAuthority) RemoveChallengeWhitelistEntry(_ context.Context, req *sapb.RemoveChallengeWhitelistEntryRequest) error {
+	if *req.RemovedBy == "" || *req.Reason == "" {
+		return berrors.MalformedError("removing a challenge whitelist entry must record who removed it and why")
+	}
+	now := ssa.clk.Now().UnixNano()
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	for _, e := range ssa.whitelist {
+		if *e.ChallengeType == *req.ChallengeType && *e.RegistrationID == *req.RegistrationID && whitelistEntryActive(e, now) {
+			e.Expires = proto.Int64(now)
+			ssa.log.AuditInfof("Removed challenge whitelist entry: challenge=[%s] regID=[%d] by=[%s] reason=[%s]",
+				*req.ChallengeType, *req.RegistrationID, *req.RemovedBy, *req.Reason)
+			return nil
+		}
+	}
+	return berrors.NotFoundError("no unexpired %s whitelist entry for registration ID %d",
+		*req.ChallengeType, *req.RegistrationID)
+}
+
+// GetChallengeWhitelist returns all of the challenge whitelist entries that
+// have not expired as of the request's timestamp.
+func (ssa *StorageAuthority) GetChallengeWhitelist(_ context.Context, req *sapb.GetChallengeWhitelistRequest) (*sapb.ChallengeWhitelistEntries, error) {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	entries := &sapb.ChallengeWhitelistEntries{}
+	for _, e := range ssa.whitelist {
+		if whitelistEntryActive(e, *req.Now) {
+			entries.Entries = append(entries.Entries, proto.Clone(e).(*sapb.ChallengeWhitelistEntry))
+		}
+	}
+	return entries, nil
+}
+
+// AddDNS01Recheck records that the DNS-01 validation of an authorization is
+// being rechecked, with the request's claim. A Duplicate error is returned if
+// it already is.
+func (ssa *StorageAuthority) AddDNS01Recheck(_ context.Context, req *sapb.DNS01Recheck) error {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	if _, present := ssa.dns01Rechecks[*req.AuthzID]; present {
+		return berrors.DuplicateError("DNS-01 validation of authorization %q is already being rechecked", *req.AuthzID)
+	}
+	ssa.dns01Rechecks[*req.AuthzID] = proto.Clone(req).(*sapb.DNS01Recheck)
+	return nil
+}
+
+// ClaimDNS01Recheck increments the claim on a DNS-01 recheck and sets its
+// lease expiry, if the request's claim is the current one. It returns whether
+// it did.
+func (ssa *StorageAuthority) ClaimDNS01Recheck(_ context.Context, req *sapb.DNS01Recheck) (*sapb.Exists, error) {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	r, present := ssa.dns01Rechecks[*req.AuthzID]
+	claimed := present && *r.Claim == *req.Claim
+	if claimed {
+		r.Claim = proto.Int64(*r.Claim + 1)
+		r.LeaseExpires = proto.Int64(*req.LeaseExpires)
+	}
+	return &sapb.Exists{Exists: &claimed}, nil
+}
+
+// RemoveDNS01Recheck removes the DNS-01 recheck of an authorization, if there
+// is one.
+func (ssa *StorageAuthority) RemoveDNS01Recheck(_ context.Context, req *sapb.AuthorizationID) error {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	delete(ssa.dns01Rechecks, *req.Id)
+	return nil
+}
+
+// GetStaleDNS01Rechecks returns the DNS-01 rechecks whose lease expired by
+// the request's timestamp.
+func (ssa *StorageAuthority) GetStaleDNS01Rechecks(_ context.Context, req *sapb.GetStaleDNS01RechecksRequest) (*sapb.DNS01Rechecks, error) {
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	rechecks := &sapb.DNS01Rechecks{}
+	for _, r := range ssa.dns01Rechecks {
+		if *r.LeaseExpires <= *req.Now {
+			rechecks.Rechecks = append(rechecks.Rechecks, proto.Clone(r).(*sapb.DNS01Recheck))
+		}
+	}
+	return rechecks, nil
+}
+
+// SearchCertificates returns a page of the certificates with a given serial,
+// DNS name, registered domain or key, ordered like the SQL SA's results. The
+// cursor is the offset of the next page.
+func (ssa *StorageAuthority) SearchCertificates(_ context.Context, req *sapb.SearchCertificatesRequest) (*sapb.CertificateSearchResults, error) {
+	var set int
+	for _, isSet := range []bool{
+		req.Serial != nil && *req.Serial != "",
+		req.Fqdn != nil && *req.Fqdn != "",
+		req.RegisteredDomain != nil && *req.RegisteredDomain != "",
+		len(req.SpkiHash) > 0,
+	} {
+		if isSet {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, berrors.MalformedError("exactly one of serial, fqdn, registeredDomain or spkiHash must be given")
+	}
+	limit := int64(defaultSearchLimit)
+	if req.Limit != nil && *req.Limit > 0 {
+		limit = *req.Limit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	offset, err := decodeOffsetCursor(req.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	var serials []string
+	switch {
+	case req.Serial != nil && *req.Serial != "":
+		if !core.ValidSerial(*req.Serial) {
+			return nil, berrors.MalformedError("invalid serial %q", *req.Serial)
+		}
+		serials = []string{*req.Serial}
+	case req.Fqdn != nil && *req.Fqdn != "":
+		name, err := core.NormalizeName(*req.Fqdn)
+		if err != nil {
+			return nil, err
+		}
+		serials = ssa.searchIssuedNames(sa.ReverseName(name), false)
+	case req.RegisteredDomain != nil && *req.RegisteredDomain != "":
+		domain, err := core.NormalizeName(*req.RegisteredDomain)
+		if err != nil {
+			return nil, err
+		}
+		if registered, err := publicsuffix.Domain(domain); err != nil || registered != domain {
+			return nil, berrors.MalformedError("%q is not a registered domain", domain)
+		}
+		serials = ssa.searchIssuedNames(sa.ReverseName(domain), true)
+	default:
+		if len(req.SpkiHash) != 32 {
+			return nil, berrors.MalformedError("spkiHash must be a SHA-256 hash")
+		}
+		found := ssa.keyHashes[string(req.SpkiHash)]
+		for i := len(found) - 1; i >= 0; i-- {
+			serials = append(serials, found[i])
+		}
+	}
+
+	results := &sapb.CertificateSearchResults{}
+	if offset < int64(len(serials)) {
+		serials = serials[offset:]
+	} else {
+		serials = nil
+	}
+	if int64(len(serials)) > limit {
+		serials = serials[:limit]
+		results.NextCursor = encodeOffsetCursor(offset + limit)
+	}
+	for _, serial := range serials {
+		cert, ok := ssa.certs[serial]
+		if !ok {
+			continue
+		}
+		result, err := ssa.searchResult(cert)
+		if err != nil {
+			return nil, err
+		}
+		results.Certificates = append(results.Certificates, result)
+	}
+	return results, nil
+}
+
+// searchResult returns the summary of cert that is included in search
+// results. It must be called with ssa.mu held.
+func (ssa *StorageAuthority) searchResult(cert core.Certificate) (*sapb.CertificateSearchResult, error) {
+	parsed, err := x509.ParseCertificate(cert.DER)
+	if err != nil {
+		return nil, err
+	}
+	status := ssa.certStatus[cert.Serial]
+	issued := cert.Issued.UnixNano()
+	expires := cert.Expires.UnixNano()
+	statusStr := string(status.Status)
+	result := &sapb.CertificateSearchResult{
+		Serial:         proto.String(cert.Serial),
+		RegistrationID: proto.Int64(cert.RegistrationID),
+		Issued:         &issued,
+		Expires:        &expires,
+		DnsNames:       parsed.DNSNames,
+		Status:         &statusStr,
+	}
+	if status.Status == core.OCSPStatusRevoked {
+		result.RevokedDate = proto.Int64(status.RevokedDate.UnixNano())
+		result.RevokedReason = proto.Int64(int64(status.RevokedReason))
+	}
+	return result, nil
+}
+
+// ListCertificatesForAccount returns a page of an account's certificates,
+// newest first, filtered like the SQL SA's. The cursor is the offset of the
+// next page.
+func (ssa *StorageAuthority) ListCertificatesForAccount(_ context.Context, req *sapb.ListCertificatesForAccountRequest) (*sapb.CertificateSearchResults, error) {
+	if req.RegistrationID == nil || *req.RegistrationID == 0 {
+		return nil, berrors.MalformedError("a registration ID must be given")
+	}
+	var status core.OCSPStatus
+	if req.Status != nil && *req.Status != "" {
+		status = core.OCSPStatus(*req.Status)
+		if status != core.OCSPStatusGood && status != core.OCSPStatusRevoked {
+			return nil, berrors.MalformedError("status must be %q or %q", core.OCSPStatusGood, core.OCSPStatusRevoked)
+		}
+	}
+	limit := int64(defaultSearchLimit)
+	if req.Limit != nil && *req.Limit > 0 {
+		limit = *req.Limit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	offset, err := decodeOffsetCursor(req.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	var nameContains string
+	if req.NameContains != nil {
+		nameContains = strings.ToLower(*req.NameContains)
+	}
+
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	var matches []*sapb.CertificateSearchResult
+	for _, cert := range ssa.certs {
+		if cert.RegistrationID != *req.RegistrationID ||
+			(status != "" && ssa.certStatus[cert.Serial].Status != status) ||
+			(req.ExpiresAfter != nil && *req.ExpiresAfter != 0 && cert.Expires.UnixNano() <= *req.ExpiresAfter) ||
+			(req.ExpiresBefore != nil && *req.ExpiresBefore != 0 && cert.Expires.UnixNano() >= *req.ExpiresBefore) {
+			continue
+		}
+		result, err := ssa.searchResult(cert)
+		if err != nil {
+			return nil, err
+		}
+		if nameContains != "" && !anyContains(result.DnsNames, nameContains) {
+			continue
+		}
+		matches = append(matches, result)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if *matches[i].Issued != *matches[j].Issued {
+			return *matches[i].Issued > *matches[j].Issued
+		}
+		return *matches[i].Serial > *matches[j].Serial
+	})
+
+	results := &sapb.CertificateSearchResults{}
+	if offset < int64(len(matches)) {
+		matches = matches[offset:]
+	} else {
+		matches = nil
+	}
+	if int64(len(matches)) > limit {
+		matches = matches[:limit]
+		results.NextCursor = encodeOffsetCursor(offset + limit)
+	}
+	results.Certificates = matches
+	return results, nil
+}
+
+// ListOrdersForAccount returns a page of an account's orders, newest first.
+// The cursor is the offset of the next page.
+func (ssa *StorageAuthority) ListOrdersForAccount(_ context.Context, req *sapb.ListOrdersForAccountRequest) (*sapb.OrderList, error) {
+	if req.RegistrationID == nil || *req.RegistrationID == 0 {
+		return nil, berrors.MalformedError("a registration ID must be given")
+	}
+	limit := int64(defaultSearchLimit)
+	if req.Limit != nil && *req.Limit > 0 {
+		limit = *req.Limit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	offset, err := decodeOffsetCursor(req.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	var ids []int64
+	for id, order := range ssa.orders {
+		if *order.RegistrationID == *req.RegistrationID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+	list := &sapb.OrderList{}
+	if offset < int64(len(ids)) {
+		ids = ids[offset:]
+	} else {
+		ids = nil
+	}
+	if int64(len(ids)) > limit {
+		ids = ids[:limit]
+		list.NextCursor = encodeOffsetCursor(offset + limit)
+	}
+	for _, id := range ids {
+		order, err := ssa.getOrder(id)
+		if err != nil {
+			return nil, err
+		}
+		list.Orders = append(list.Orders, order)
+	}
+	return list, nil
+}
+
+// ListInvalidAuthorizationsForAccount returns an account's invalid
+// authorizations that expire after the request's expiresAfter, latest expiry
+// first.
+func (ssa *StorageAuthority) ListInvalidAuthorizationsForAccount(_ context.Context, req *sapb.ListInvalidAuthorizationsForAccountRequest) (*sapb.AuthorizationList, error) {
+	if req.RegistrationID == nil || *req.RegistrationID == 0 {
+		return nil, berrors.MalformedError("a registration ID must be given")
+	}
+	limit := maxInvalidAuthorizations
+	if req.Limit != nil && *req.Limit > 0 && *req.Limit < int64(limit) {
+		limit = int(*req.Limit)
+	}
+	expiresAfter := time.Unix(0, *req.ExpiresAfter)
+
+	ssa.mu.Lock()
+	defer ssa.mu.Unlock()
+	var authzs []core.Authorization
+	for _, entry := range ssa.authzs {
+		a := entry.authz
+		if entry.final && a.RegistrationID == *req.RegistrationID && a.Status == core.StatusInvalid &&
+			a.Expires != nil && a.Expires.After(expiresAfter) {
+			authzs = append(authzs, a)
+		}
+	}
+	sort.Slice(authzs, func(i, j int) bool { return authzs[i].Expires.After(*authzs[j].Expires) })
+	if len(authzs) > limit {
+		authzs = authzs[:limit]
+	}
+	list := &sapb.AuthorizationList{}
+	for _, authz := range authzs {
+		authzPB, err := bgrpc.AuthzToPB(authz)
+		if err != nil {
+			return nil, err
+		}
+		list.Authorizations = append(list.Authorizations, authzPB)
+	}
+	return list, nil
+}
+
+// anyContains returns true if any of names contains substr.
+func anyContains(names []string, substr string) bool {
+	for _, name := range names {
+		if strings.Contains(name, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeOffsetCursor returns the cursor for the page of results starting at
+// offset.
+func encodeOffsetCursor(offset int64) *string {
+	cursor := base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(offset, 10)))
+	return &cursor
+}
+
+// decodeOffsetCursor returns the offset of the page of results that cursor
+// refers to, or 0 if it's unset.
+func decodeOffsetCursor(cursor *string) (int64, error) {
+	if cursor == nil || *cursor == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(*cursor)
+	var offset int64
+	if err == nil {
+		offset, err = strconv.ParseInt(string(b), 10, 64)
+	}
+	if err != nil || offset < 0 {
+		return 0, berrors.MalformedError("invalid search cursor")
+	}
+	return offset, nil
+}
+
+// searchIssuedNames returns the serials of certificates for reversedName, and
+// if includeSubdomains is true its subdomains, ordered by name and then newest
+// first. It must be called with ssa.mu held.
+func (ssa *StorageAuthority) searchIssuedNames(reversedName string, includeSubdomains bool) []string {
+	var matches []issuedName
+	for _, in := range ssa.issuedNames {
+		if in.reversedName == reversedName || (includeSubdomains && strings.HasPrefix(in.reversedName, reversedName+".")) {
+			matches = append(matches, in)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].reversedName != matches[j].reversedName {
+			return matches[i].reversedName > matches[j].reversedName
+		}
+		return matches[i].notBefore.After(matches[j].notBefore)
+	})
+	serials := make([]string, len(matches))
+	for i, in := range matches {
+		serials[i] = in.serial
+	}
+	return serials
+}
@@ -0,0 +1,485 @@
+package memsa
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/jmhodges/clock"
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/core"
+	corepb "github.com/letsencrypt/boulder/core/proto"
+	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/features"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/probs"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+	"github.com/letsencrypt/boulder/sa/satest"
+	"github.com/letsencrypt/boulder/test"
+)
+
+var ctx = context.Background()
+
+// Compile-time check that StorageAuthority can stand in for the SQL SA
+var _ core.StorageAuthority = &StorageAuthority{}
+
+func setup() (*StorageAuthority, clock.FakeClock) {
+	fc := clock.NewFake()
+	fc.Set(time.Date(2015, 10, 1, 0, 0, 0, 0, time.UTC))
+	return New(fc, blog.NewMock()), fc
+}
+
+func TestRegistrations(t *testing.T) {
+	ssa, _ := setup()
+	reg := satest.CreateWorkingRegistration(t, ssa)
+	test.AssertEquals(t, reg.ID, int64(1))
+
+	byKey, err := ssa.GetRegistrationByKey(ctx, satest.GoodJWK())
+	test.AssertNotError(t, err, "GetRegistrationByKey failed")
+	test.AssertEquals(t, byKey.ID, reg.ID)
+
+	_, err = ssa.NewRegistration(ctx, core.Registration{Key: satest.GoodJWK()})
+	test.Assert(t, berrors.Is(err, berrors.Duplicate), "NewRegistration didn't reject a duplicate key")
+
+	// Registrations returned can't be used to modify the stored registration
+	(*reg.Contact)[0] = "mailto:changed@example.com"
+	stored, err := ssa.GetRegistration(ctx, reg.ID)
+	test.AssertNotError(t, err, "GetRegistration failed")
+	test.AssertEquals(t, (*stored.Contact)[0], "mailto:foo@example.com")
+
+	test.AssertNotError(t, ssa.DeactivateRegistration(ctx, reg.ID), "DeactivateRegistration failed")
+	stored, _ = ssa.GetRegistration(ctx, reg.ID)
+	test.AssertEquals(t, stored.Status, core.StatusDeactivated)
+
+	_, err = ssa.GetRegistration(ctx, 100)
+	test.Assert(t, berrors.Is(err, berrors.NotFound), "GetRegistration didn't return NotFound")
+}
+
+func TestOrderLifecycle(t *testing.T) {
+	ssa, fc := setup()
+	reg := satest.CreateWorkingRegistration(t, ssa)
+	expires := fc.Now().Add(time.Hour)
+
+	authz, err := ssa.NewPendingAuthorization(ctx, core.Authorization{
+		Identifier:     core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "example.com"},
+		RegistrationID: reg.ID,
+		Status:         core.StatusPending,
+		Expires:        &expires,
+		Challenges:     []core.Challenge{{Type: core.ChallengeTypeHTTP01, Status: core.StatusPending}},
+	})
+	test.AssertNotError(t, err, "NewPendingAuthorization failed")
+	test.Assert(t, authz.ID != "", "authorization wasn't given an ID")
+	test.AssertEquals(t, authz.Challenges[0].ID, int64(1))
+
+	expiresNS := expires.UnixNano()
+	order, err := ssa.NewOrder(ctx, &corepb.Order{
+		RegistrationID: &reg.ID,
+		Expires:        &expiresNS,
+		Names:          []string{"example.com"},
+		Authorizations: []string{authz.ID},
+	})
+	test.AssertNotError(t, err, "NewOrder failed")
+	test.AssertEquals(t, *order.Status, string(core.StatusPending))
+
+	reused, err := ssa.GetOrderForNames(ctx, &sapb.GetOrderForNamesRequest{AcctID: &reg.ID, Names: []string{"EXAMPLE.com"}})
+	test.AssertNotError(t, err, "GetOrderForNames failed")
+	test.AssertEquals(t, *reused.Id, *order.Id)
+
+	all, err := ssa.GetOrderAuthorizations(ctx, &sapb.GetOrderAuthorizationsRequest{Id: order.Id, AcctID: &reg.ID})
+	test.AssertNotError(t, err, "GetOrderAuthorizations failed")
+	test.AssertEquals(t, len(all), 1)
+	test.AssertEquals(t, all["example.com"].Status, core.StatusPending)
+	wrongAcctID := reg.ID + 1
+	all, err = ssa.GetOrderAuthorizations(ctx, &sapb.GetOrderAuthorizationsRequest{Id: order.Id, AcctID: &wrongAcctID})
+	test.AssertNotError(t, err, "GetOrderAuthorizations for the wrong account failed")
+	test.AssertEquals(t, len(all), 0)
+
+	authz.Status = core.StatusValid
+	authz.Challenges[0].Status = core.StatusValid
+	test.AssertNotError(t, ssa.FinalizeAuthorization(ctx, authz), "FinalizeAuthorization failed")
+	err = ssa.UpdatePendingAuthorization(ctx, core.Authorization{ID: authz.ID, Status: core.StatusPending})
+	test.Assert(t, berrors.Is(err, berrors.WrongAuthorizationState), "finalized authorization was updated")
+
+	order, err = ssa.GetOrder(ctx, &sapb.OrderRequest{Id: order.Id})
+	test.AssertNotError(t, err, "GetOrder failed")
+	test.AssertEquals(t, *order.Status, string(core.StatusReady))
+
+	valid, err := ssa.GetValidOrderAuthorizations(ctx, &sapb.GetValidOrderAuthorizationsRequest{Id: order.Id, AcctID: &reg.ID})
+	test.AssertNotError(t, err, "GetValidOrderAuthorizations failed")
+	test.AssertEquals(t, len(valid), 1)
+	test.AssertEquals(t, valid["example.com"].Challenges[0].Status, core.StatusValid)
+
+	test.AssertNotError(t, ssa.SetOrderProcessing(ctx, order), "SetOrderProcessing failed")
+	test.AssertError(t, ssa.SetOrderProcessing(ctx, order), "SetOrderProcessing succeeded twice")
+	order, _ = ssa.GetOrder(ctx, &sapb.OrderRequest{Id: order.Id})
+	test.AssertEquals(t, *order.Status, string(core.StatusProcessing))
+
+	serial := "000000000000000000000000000000000001"
+	order.CertificateSerial = &serial
+	test.AssertNotError(t, ssa.FinalizeOrder(ctx, order), "FinalizeOrder failed")
+	order, _ = ssa.GetOrder(ctx, &sapb.OrderRequest{Id: order.Id})
+	test.AssertEquals(t, *order.Status, string(core.StatusValid))
+
+	// Finalized orders aren't reused
+	_, err = ssa.GetOrderForNames(ctx, &sapb.GetOrderForNamesRequest{AcctID: &reg.ID, Names: []string{"example.com"}})
+	test.Assert(t, berrors.Is(err, berrors.NotFound), "GetOrderForNames returned a finalized order")
+
+	// Orders with expired authorizations are invalid
+	fc.Add(2 * time.Hour)
+	order, _ = ssa.GetOrder(ctx, &sapb.OrderRequest{Id: order.Id})
+	test.AssertEquals(t, *order.Status, string(core.StatusInvalid))
+}
+
+func TestRetryChallenge(t *testing.T) {
+	ssa, fc := setup()
+	err := features.Set(map[string]bool{"StoreChallengeAttempts": true})
+	test.AssertNotError(t, err, "Failed to enable StoreChallengeAttempts feature flag")
+	defer features.Reset()
+	reg := satest.CreateWorkingRegistration(t, ssa)
+	expires := fc.Now().Add(time.Hour)
+
+	authz, err := ssa.NewPendingAuthorization(ctx, core.Authorization{
+		Identifier:     core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "example.com"},
+		RegistrationID: reg.ID,
+		Status:         core.StatusPending,
+		Expires:        &expires,
+		Challenges:     []core.Challenge{{Type: core.ChallengeTypeHTTP01, Status: core.StatusPending}},
+	})
+	test.AssertNotError(t, err, "NewPendingAuthorization failed")
+	req := &sapb.RetryChallengeRequest{AuthorizationID: &authz.ID, ChallengeID: &authz.Challenges[0].ID}
+	err = ssa.RetryChallenge(ctx, req)
+	test.Assert(t, berrors.Is(err, berrors.NotFound), "pending authorization was retried")
+
+	authz.Status = core.StatusInvalid
+	authz.Challenges[0].Status = core.StatusInvalid
+	authz.Challenges[0].Error = probs.ConnectionFailure("Connection refused")
+	authz.Challenges[0].Attempts = 1
+	test.AssertNotError(t, ssa.FinalizeAuthorization(ctx, authz), "FinalizeAuthorization failed")
+
+	test.AssertNotError(t, ssa.RetryChallenge(ctx, req), "RetryChallenge failed")
+	retried, err := ssa.GetAuthorization(ctx, authz.ID)
+	test.AssertNotError(t, err, "GetAuthorization failed")
+	test.AssertEquals(t, retried.Status, core.StatusPending)
+	test.AssertEquals(t, retried.Challenges[0].Status, core.StatusPending)
+	test.Assert(t, retried.Challenges[0].Error == nil, "retried challenge kept its error")
+	test.AssertEquals(t, retried.Challenges[0].Attempts, int64(1))
+	err = ssa.RetryChallenge(ctx, req)
+	test.Assert(t, berrors.Is(err, berrors.NotFound), "reopened authorization was retried")
+
+	retried.Status = core.StatusInvalid
+	retried.Challenges[0].Status = core.StatusInvalid
+	retried.Challenges[0].Attempts = 2
+	test.AssertNotError(t, ssa.FinalizeAuthorization(ctx, retried), "FinalizeAuthorization failed")
+	final, _ := ssa.GetAuthorization(ctx, authz.ID)
+	test.AssertEquals(t, final.Challenges[0].Attempts, int64(2))
+
+	fc.Add(2 * time.Hour)
+	err = ssa.RetryChallenge(ctx, req)
+	test.Assert(t, berrors.Is(err, berrors.WrongAuthorizationState), "expired authorization was retried")
+}
+
+func TestCertificates(t *testing.T) {
+	ssa, fc := setup()
+	reg := satest.CreateWorkingRegistration(t, ssa)
+	// names [example.com, www.example.com, admin.example.com], issued
+	// 2015-09-28
+	certDER, err := ioutil.ReadFile("../test-cert.der")
+	test.AssertNotError(t, err, "Couldn't read example cert DER")
+	serial := "ffdd9b8a82126d96f61d378d5ba99a0474f0"
+
+	_, err = ssa.AddCertificate(ctx, certDER, reg.ID, nil, nil)
+	test.AssertNotError(t, err, "AddCertificate failed")
+	_, err = ssa.AddCertificate(ctx, certDER, reg.ID, nil, nil)
+	test.Assert(t, berrors.Is(err, berrors.Duplicate), "AddCertificate didn't reject a duplicate")
+
+	cert, err := ssa.GetCertificate(ctx, serial)
+	test.AssertNotError(t, err, "GetCertificate failed")
+	test.AssertEquals(t, cert.Issued, fc.Now())
+	status, err := ssa.GetCertificateStatus(ctx, serial)
+	test.AssertNotError(t, err, "GetCertificateStatus failed")
+	test.AssertEquals(t, status.Status, core.OCSPStatusGood)
+
+	earliest := fc.Now().Add(-7 * 24 * time.Hour)
+	counts, err := ssa.CountCertificatesByNames(ctx, []string{"example.com", "www.example.com", "other.com"}, earliest, fc.Now())
+	test.AssertNotError(t, err, "CountCertificatesByNames failed")
+	test.AssertEquals(t, *counts[0].Count, int64(1))
+	test.AssertEquals(t, *counts[1].Count, int64(1))
+	test.AssertEquals(t, *counts[2].Count, int64(0))
+
+	exists, err := ssa.FQDNSetExists(ctx, []string{"www.example.com", "admin.example.com", "example.com"})
+	test.AssertNotError(t, err, "FQDNSetExists failed")
+	test.Assert(t, exists, "FQDN set wasn't found")
+	domain := "www.example.com"
+	previous, err := ssa.PreviousCertificateExists(ctx, &sapb.PreviousCertificateExistsRequest{Domain: &domain, RegID: &reg.ID})
+	test.AssertNotError(t, err, "PreviousCertificateExists failed")
+	test.Assert(t, *previous.Exists, "previous certificate wasn't found")
+
+	registered := "example.com"
+	results, err := ssa.SearchCertificates(ctx, &sapb.SearchCertificatesRequest{RegisteredDomain: &registered})
+	test.AssertNotError(t, err, "SearchCertificates failed")
+	test.AssertEquals(t, len(results.Certificates), 3)
+	test.AssertEquals(t, *results.Certificates[0].Serial, serial)
+	limit := int64(2)
+	results, _ = ssa.SearchCertificates(ctx, &sapb.SearchCertificatesRequest{RegisteredDomain: &registered, Limit: &limit})
+	test.AssertEquals(t, len(results.Certificates), 2)
+	results, _ = ssa.SearchCertificates(ctx, &sapb.SearchCertificatesRequest{RegisteredDomain: &registered, Cursor: results.NextCursor})
+	test.AssertEquals(t, len(results.Certificates), 1)
+	test.Assert(t, results.NextCursor == nil, "last page has a next cursor")
+
+	reason := int64(1)
+	date := fc.Now().UnixNano()
+	err = ssa.RevokeCertificate(ctx, &sapb.RevokeCertificateRequest{Serial: &serial, Reason: &reason, Date: &date})
+	test.AssertNotError(t, err, "RevokeCertificate failed")
+	err = ssa.RevokeCertificate(ctx, &sapb.RevokeCertificateRequest{Serial: &serial, Reason: &reason, Date: &date})
+	test.AssertError(t, err, "RevokeCertificate succeeded for a revoked certificate")
+	status, _ = ssa.GetCertificateStatus(ctx, serial)
+	test.AssertEquals(t, status.Status, core.OCSPStatusRevoked)
+}
+
+func TestListCertificatesForAccount(t *testing.T) {
+	ssa, fc := setup()
+	reg := satest.CreateWorkingRegistration(t, ssa)
+	// names [example.com, www.example.com, admin.example.com], expires
+	// 2015-12-27
+	certDER, err := ioutil.ReadFile("../test-cert.der")
+	test.AssertNotError(t, err, "Couldn't read example cert DER")
+	_, err = ssa.AddCertificate(ctx, certDER, reg.ID, nil, nil)
+	test.AssertNotError(t, err, "AddCertificate failed")
+	// names [example.co.bn], expires 2016-01-01
+	certDER2, err := ioutil.ReadFile("../test-cert2.der")
+	test.AssertNotError(t, err, "Couldn't read example cert DER")
+	fc.Add(time.Hour)
+	_, err = ssa.AddCertificate(ctx, certDER2, reg.ID, nil, nil)
+	test.AssertNotError(t, err, "AddCertificate failed")
+	serial, serial2 := "ffdd9b8a82126d96f61d378d5ba99a0474f0", "ffa0160630d618b2eb5c0510824b14274856"
+
+	list := func(req *sapb.ListCertificatesForAccountRequest) []string {
+		if req.RegistrationID == nil {
+			req.RegistrationID = &reg.ID
+		}
+		results, err := ssa.ListCertificatesForAccount(ctx, req)
+		test.AssertNotError(t, err, "ListCertificatesForAccount failed")
+		var serials []string
+		for _, c := range results.Certificates {
+			serials = append(serials, *c.Serial)
+		}
+		return serials
+	}
+
+	test.AssertDeepEquals(t, list(&sapb.ListCertificatesForAccountRequest{}), []string{serial2, serial})
+	limit := int64(1)
+	results, err := ssa.ListCertificatesForAccount(ctx, &sapb.ListCertificatesForAccountRequest{RegistrationID: &reg.ID, Limit: &limit})
+	test.AssertNotError(t, err, "ListCertificatesForAccount failed")
+	test.AssertEquals(t, len(results.Certificates), 1)
+	test.Assert(t, results.NextCursor != nil, "first page had no next cursor")
+	test.AssertDeepEquals(t, list(&sapb.ListCertificatesForAccountRequest{Cursor: results.NextCursor}), []string{serial})
+
+	revoked := string(core.OCSPStatusRevoked)
+	test.AssertEquals(t, len(list(&sapb.ListCertificatesForAccountRequest{Status: &revoked})), 0)
+	before := time.Date(2015, 12, 31, 0, 0, 0, 0, time.UTC).UnixNano()
+	test.AssertDeepEquals(t, list(&sapb.ListCertificatesForAccountRequest{ExpiresBefore: &before}), []string{serial})
+	test.AssertDeepEquals(t, list(&sapb.ListCertificatesForAccountRequest{ExpiresAfter: &before}), []string{serial2})
+	name := "ADMIN.example"
+	test.AssertDeepEquals(t, list(&sapb.ListCertificatesForAccountRequest{NameContains: &name}), []string{serial})
+	otherReg := reg.ID + 1
+	test.AssertEquals(t, len(list(&sapb.ListCertificatesForAccountRequest{RegistrationID: &otherReg})), 0)
+
+	badStatus, badCursor := "unknown", "!"
+	for _, req := range []*sapb.ListCertificatesForAccountRequest{
+		{},
+		{RegistrationID: &reg.ID, Status: &badStatus},
+		{RegistrationID: &reg.ID, Cursor: &badCursor},
+	} {
+		_, err := ssa.ListCertificatesForAccount(ctx, req)
+		test.Assert(t, berrors.Is(err, berrors.Malformed), "invalid request wasn't malformed")
+	}
+}
+
+func TestListOrdersForAccount(t *testing.T) {
+	ssa, fc := setup()
+	reg := satest.CreateWorkingRegistration(t, ssa)
+	expires := fc.Now().Add(time.Hour)
+	expiresNS := expires.UnixNano()
+	var ids []int64
+	for _, name := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		authz, err := ssa.NewPendingAuthorization(ctx, core.Authorization{
+			Identifier:     core.AcmeIdentifier{Type: core.IdentifierDNS, Value: name},
+			RegistrationID: reg.ID,
+			Status:         core.StatusPending,
+			Expires:        &expires,
+		})
+		test.AssertNotError(t, err, "NewPendingAuthorization failed")
+		order, err := ssa.NewOrder(ctx, &corepb.Order{
+			RegistrationID: &reg.ID,
+			Expires:        &expiresNS,
+			Names:          []string{name},
+			Authorizations: []string{authz.ID},
+		})
+		test.AssertNotError(t, err, "NewOrder failed")
+		ids = append(ids, *order.Id)
+	}
+
+	limit := int64(2)
+	list, err := ssa.ListOrdersForAccount(ctx, &sapb.ListOrdersForAccountRequest{RegistrationID: &reg.ID, Limit: &limit})
+	test.AssertNotError(t, err, "ListOrdersForAccount failed")
+	test.AssertEquals(t, len(list.Orders), 2)
+	test.AssertEquals(t, *list.Orders[0].Id, ids[2])
+	test.AssertEquals(t, *list.Orders[1].Id, ids[1])
+	test.Assert(t, list.NextCursor != nil, "first page had no next cursor")
+	list, err = ssa.ListOrdersForAccount(ctx, &sapb.ListOrdersForAccountRequest{RegistrationID: &reg.ID, Limit: &limit, Cursor: list.NextCursor})
+	test.AssertNotError(t, err, "ListOrdersForAccount failed")
+	test.AssertEquals(t, len(list.Orders), 1)
+	test.AssertEquals(t, *list.Orders[0].Id, ids[0])
+	test.Assert(t, list.NextCursor == nil, "last page had a next cursor")
+
+	otherReg := reg.ID + 1
+	list, err = ssa.ListOrdersForAccount(ctx, &sapb.ListOrdersForAccountRequest{RegistrationID: &otherReg})
+	test.AssertNotError(t, err, "ListOrdersForAccount failed")
+	test.AssertEquals(t, len(list.Orders), 0)
+
+	_, err = ssa.ListOrdersForAccount(ctx, &sapb.ListOrdersForAccountRequest{})
+	test.Assert(t, berrors.Is(err, berrors.Malformed), "request without a registration ID wasn't malformed")
+}
+
+func TestListInvalidAuthorizationsForAccount(t *testing.T) {
+	ssa, fc := setup()
+	reg := satest.CreateWorkingRegistration(t, ssa)
+	newAuthz := func(name string, status core.AcmeStatus, expires time.Time) {
+		authz, err := ssa.NewPendingAuthorization(ctx, core.Authorization{
+			Identifier:     core.AcmeIdentifier{Type: core.IdentifierDNS, Value: name},
+			RegistrationID: reg.ID,
+			Status:         core.StatusPending,
+			Expires:        &expires,
+			Challenges:     []core.Challenge{{Type: core.ChallengeTypeHTTP01, Status: core.StatusPending}},
+		})
+		test.AssertNotError(t, err, "NewPendingAuthorization failed")
+		if status == core.StatusPending {
+			return
+		}
+		authz.Status = status
+		authz.Challenges[0].Status = status
+		if status == core.StatusInvalid {
+			authz.Challenges[0].Error = probs.ConnectionFailure("timeout")
+		}
+		test.AssertNotError(t, ssa.FinalizeAuthorization(ctx, authz), "FinalizeAuthorization failed")
+	}
+	newAuthz("old.example.com", core.StatusInvalid, fc.Now().Add(time.Hour))
+	newAuthz("new.example.com", core.StatusInvalid, fc.Now().Add(2*time.Hour))
+	newAuthz("valid.example.com", core.StatusValid, fc.Now().Add(3*time.Hour))
+	newAuthz("pending.example.com", core.StatusPending, fc.Now().Add(3*time.Hour))
+
+	list := func(expiresAfter time.Time, limit int64) []string {
+		expiresAfterNS := expiresAfter.UnixNano()
+		req := &sapb.ListInvalidAuthorizationsForAccountRequest{RegistrationID: &reg.ID, ExpiresAfter: &expiresAfterNS}
+		if limit != 0 {
+			req.Limit = &limit
+		}
+		result, err := ssa.ListInvalidAuthorizationsForAccount(ctx, req)
+		test.AssertNotError(t, err, "ListInvalidAuthorizationsForAccount failed")
+		var names []string
+		for _, authz := range result.Authorizations {
+			names = append(names, *authz.Identifier)
+			test.AssertEquals(t, *authz.Challenges[0].Status, string(core.StatusInvalid))
+		}
+		return names
+	}
+	test.AssertDeepEquals(t, list(fc.Now(), 0), []string{"new.example.com", "old.example.com"})
+	test.AssertDeepEquals(t, list(fc.Now(), 1), []string{"new.example.com"})
+	test.AssertDeepEquals(t, list(fc.Now().Add(90*time.Minute), 0), []string{"new.example.com"})
+}
+
+func TestChallengeWhitelist(t *testing.T) {
+	ssa, fc := setup()
+	by, reason := "operator", "ticket 1234"
+	entry := func(regID int64, expires time.Time) *sapb.ChallengeWhitelistEntry {
+		var expiresNS int64
+		if !expires.IsZero() {
+			expiresNS = expires.UnixNano()
+		}
+		return &sapb.ChallengeWhitelistEntry{
+			ChallengeType:  proto.String(core.ChallengeTypeTLSSNI01),
+			RegistrationID: &regID,
+			CreatedBy:      &by,
+			Expires:        &expiresNS,
+			Reason:         &reason,
+		}
+	}
+	active := func() map[int64]int64 {
+		now := fc.Now().UnixNano()
+		entries, err := ssa.GetChallengeWhitelist(ctx, &sapb.GetChallengeWhitelistRequest{Now: &now})
+		test.AssertNotError(t, err, "GetChallengeWhitelist failed")
+		regIDs := map[int64]int64{}
+		for _, e := range entries.Entries {
+			regIDs[*e.RegistrationID] = *e.Id
+		}
+		return regIDs
+	}
+
+	_, err := ssa.AddChallengeWhitelistEntry(ctx, entry(1, time.Time{}))
+	test.AssertNotError(t, err, "AddChallengeWhitelistEntry failed")
+	_, err = ssa.AddChallengeWhitelistEntry(ctx, entry(2, fc.Now().Add(time.Hour)))
+	test.AssertNotError(t, err, "AddChallengeWhitelistEntry failed")
+	_, err = ssa.AddChallengeWhitelistEntry(ctx, entry(3, fc.Now()))
+	test.AssertError(t, err, "entry that already expired was added")
+	test.AssertEquals(t, len(active()), 2)
+
+	// Adding an entry for an account replaces its existing entry
+	replacement, err := ssa.AddChallengeWhitelistEntry(ctx, entry(2, time.Time{}))
+	test.AssertNotError(t, err, "AddChallengeWhitelistEntry failed")
+	fc.Add(2 * time.Hour)
+	test.AssertDeepEquals(t, active(), map[int64]int64{1: 1, 2: *replacement.Id})
+
+	remove := &sapb.RemoveChallengeWhitelistEntryRequest{
+		ChallengeType:  proto.String(core.ChallengeTypeTLSSNI01),
+		RegistrationID: proto.Int64(1),
+		RemovedBy:      &by,
+		Reason:         &reason,
+	}
+	test.AssertNotError(t, ssa.RemoveChallengeWhitelistEntry(ctx, remove), "RemoveChallengeWhitelistEntry failed")
+	test.AssertDeepEquals(t, active(), map[int64]int64{2: *replacement.Id})
+	err = ssa.RemoveChallengeWhitelistEntry(ctx, remove)
+	test.Assert(t, berrors.Is(err, berrors.NotFound), "removing a removed entry didn't return NotFound")
+}
+
+func TestDNS01Rechecks(t *testing.T) {
+	ssa, fc := setup()
+	deadline := fc.Now().Add(time.Hour).UnixNano()
+	leaseExpires := fc.Now().Add(time.Minute).UnixNano()
+	recheck := &sapb.DNS01Recheck{
+		AuthzID:      proto.String("authz"),
+		ChallengeID:  proto.Int64(1),
+		Deadline:     &deadline,
+		LeaseExpires: &leaseExpires,
+		Claim:        proto.Int64(0),
+	}
+	err := ssa.AddDNS01Recheck(ctx, recheck)
+	test.AssertNotError(t, err, "AddDNS01Recheck failed")
+	err = ssa.AddDNS01Recheck(ctx, recheck)
+	test.Assert(t, berrors.Is(err, berrors.Duplicate), "Adding a recheck twice didn't return Duplicate")
+
+	stale := func(at time.Time) []*sapb.DNS01Recheck {
+		now := at.UnixNano()
+		rechecks, err := ssa.GetStaleDNS01Rechecks(ctx, &sapb.GetStaleDNS01RechecksRequest{Now: &now})
+		test.AssertNotError(t, err, "GetStaleDNS01Rechecks failed")
+		return rechecks.Rechecks
+	}
+	test.AssertEquals(t, len(stale(fc.Now())), 0)
+	test.AssertEquals(t, len(stale(fc.Now().Add(time.Minute))), 1)
+
+	renewed := fc.Now().Add(2 * time.Minute).UnixNano()
+	claim := &sapb.DNS01Recheck{AuthzID: proto.String("authz"), LeaseExpires: &renewed, Claim: proto.Int64(0)}
+	claimed, err := ssa.ClaimDNS01Recheck(ctx, claim)
+	test.AssertNotError(t, err, "ClaimDNS01Recheck failed")
+	test.Assert(t, *claimed.Exists, "Current claim on a recheck couldn't be renewed")
+	claimed, err = ssa.ClaimDNS01Recheck(ctx, claim)
+	test.AssertNotError(t, err, "ClaimDNS01Recheck failed")
+	test.Assert(t, !*claimed.Exists, "Outdated claim on a recheck was renewed")
+	test.AssertEquals(t, len(stale(fc.Now().Add(time.Minute))), 0)
+	test.AssertEquals(t, *stale(fc.Now().Add(2 * time.Minute))[0].Claim, int64(1))
+
+	err = ssa.RemoveDNS01Recheck(ctx, &sapb.AuthorizationID{Id: proto.String("authz")})
+	test.AssertNotError(t, err, "RemoveDNS01Recheck failed")
+	test.AssertEquals(t, len(stale(fc.Now().Add(time.Hour))), 0)
+}
@@ -0,0 +1,284 @@
+// Package migrate applies the SA's schema migrations, the goose migration
+// files in sa/_db/migrations and sa/_db-next/migrations. The versions applied
+// are tracked in goose's goose_db_version table, so databases migrated with
+// goose can be migrated with this package and vice versa.
+package migrate
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	upAnnotation             = "-- +goose Up"
+	downAnnotation           = "-- +goose Down"
+	statementBeginAnnotation = "-- +goose StatementBegin"
+	statementEndAnnotation   = "-- +goose StatementEnd"
+	// safeOnlineAnnotation marks a migration as safe to apply while Boulder
+	// is serving from the database, e.g. because it only creates new tables
+	// and doesn't lock or rewrite existing ones.
+	safeOnlineAnnotation = "-- +boulder SafeOnline"
+)
+
+// Migration is one schema migration.
+type Migration struct {
+	// Version is the timestamp the migration's file name starts with.
+	Version int64
+	// Name is the migration's file name.
+	Name string
+	// Statements are the SQL statements of the migration's Up section.
+	Statements []string
+	// SafeOnline is true if the migration is annotated as safe to apply
+	// without taking Boulder offline.
+	SafeOnline bool
+}
+
+// Load reads the migrations in the migrations directory of each of dirs (e.g.
+// "sa/_db"), returning them in the order they must be applied. A migration
+// that is in more than one directory is only returned once.
+func Load(dirs ...string) ([]Migration, error) {
+	byVersion := make(map[int64]Migration)
+	for _, dir := range dirs {
+		files, err := filepath.Glob(filepath.Join(dir, "migrations", "*.sql"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			m, err := loadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			if existing, ok := byVersion[m.Version]; ok {
+				if existing.Name != m.Name {
+					return nil, fmt.Errorf("migrations %q and %q have the same version", existing.Name, m.Name)
+				}
+				continue
+			}
+			byVersion[m.Version] = m
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func loadFile(file string) (Migration, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return Migration{}, err
+	}
+	defer func() { _ = f.Close() }()
+	return parse(filepath.Base(file), f)
+}
+
+// parse parses a migration file the way goose does: statements end with a
+// semicolon at the end of a line, unless they're between StatementBegin and
+// StatementEnd annotations.
+func parse(name string, r io.Reader) (Migration, error) {
+	underscore := strings.Index(name, "_")
+	if underscore < 0 {
+		return Migration{}, fmt.Errorf("migration %q has no version", name)
+	}
+	version, err := strconv.ParseInt(name[:underscore], 10, 64)
+	if err != nil {
+		return Migration{}, fmt.Errorf("migration %q has an invalid version: %s", name, err)
+	}
+	m := Migration{Version: version, Name: name}
+
+	var inUp, seenUp, inStatement bool
+	var buf strings.Builder
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		switch trimmed {
+		case upAnnotation:
+			inUp, seenUp = true, true
+			continue
+		case downAnnotation:
+			inUp = false
+			continue
+		case safeOnlineAnnotation:
+			m.SafeOnline = true
+			continue
+		case statementBeginAnnotation:
+			inStatement = true
+			continue
+		case statementEndAnnotation:
+			inStatement = false
+		}
+		if !inUp {
+			continue
+		}
+		if trimmed != statementEndAnnotation {
+			if strings.HasPrefix(trimmed, "--") && buf.Len() == 0 {
+				continue
+			}
+			buf.WriteString(line)
+			buf.WriteString("\n")
+		}
+		if !inStatement && strings.HasSuffix(trimmed, ";") || trimmed == statementEndAnnotation {
+			if statement := strings.TrimSpace(buf.String()); statement != "" {
+				m.Statements = append(m.Statements, statement)
+			}
+			buf.Reset()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Migration{}, err
+	}
+	if !seenUp {
+		return Migration{}, fmt.Errorf("migration %q has no %q section", name, upAnnotation)
+	}
+	if rest := strings.TrimSpace(buf.String()); rest != "" {
+		return Migration{}, fmt.Errorf("migration %q has an unterminated statement: %q", name, rest)
+	}
+	return m, nil
+}
+
+// ensureVersionTable creates goose's version table if it doesn't exist yet,
+// the same way goose does.
+func ensureVersionTable(db *sql.DB) error {
+	exists, err := versionTableExists(db)
+	if err != nil || exists {
+		return err
+	}
+	_, err = db.Exec(`CREATE TABLE goose_db_version (
+		id serial NOT NULL,
+		version_id bigint NOT NULL,
+		is_applied boolean NOT NULL,
+		tstamp timestamp NULL default now(),
+		PRIMARY KEY(id)
+	)`)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("INSERT INTO goose_db_version (version_id, is_applied) VALUES (0, true)")
+	return err
+}
+
+func versionTableExists(db *sql.DB) (bool, error) {
+	rows, err := db.Query("SHOW TABLES LIKE 'goose_db_version'")
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = rows.Close() }()
+	exists := rows.Next()
+	return exists, rows.Err()
+}
+
+// applied returns the versions of the migrations that have been applied to
+// db. As for goose, the most recent row of a version says whether it's
+// applied, since rolling a migration back adds a row. It only reads from db,
+// so that services without permission to change the schema can check it.
+func applied(db *sql.DB) (map[int64]bool, error) {
+	versions := make(map[int64]bool)
+	exists, err := versionTableExists(db)
+	if err != nil || !exists {
+		return versions, err
+	}
+	rows, err := db.Query("SELECT version_id, is_applied FROM goose_db_version ORDER BY id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	seen := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		err := rows.Scan(&version, &isApplied)
+		if err != nil {
+			return nil, err
+		}
+		if seen[version] {
+			continue
+		}
+		seen[version] = true
+		if isApplied {
+			versions[version] = true
+		}
+	}
+	return versions, rows.Err()
+}
+
+// Pending returns the migrations that haven't been applied to db, in the
+// order they must be applied.
+func Pending(db *sql.DB, migrations []Migration) ([]Migration, error) {
+	versions, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, m := range migrations {
+		if !versions[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Apply applies a migration and records its version in a single
+// transaction. Note that MySQL implicitly commits schema changes like
+// CREATE and ALTER TABLE, so a migration with those statements that fails
+// partway through must be cleaned up by hand before it's applied again.
+func Apply(db *sql.DB, m Migration) error {
+	err := ensureVersionTable(db)
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, statement := range m.Statements {
+		_, err = tx.Exec(statement)
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("applying migration %q: %s", m.Name, err)
+		}
+	}
+	_, err = tx.Exec("INSERT INTO goose_db_version (version_id, is_applied) VALUES (?, true)", m.Version)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("recording migration %q: %s", m.Name, err)
+	}
+	return tx.Commit()
+}
+
+// WriteSQL writes the SQL that applying migrations would run to w, for a
+// dry run.
+func WriteSQL(w io.Writer, migrations []Migration) error {
+	for _, m := range migrations {
+		online := "requires downtime"
+		if m.SafeOnline {
+			online = "safe online"
+		}
+		_, err := fmt.Fprintf(w, "-- %s (%s)\n", m.Name, online)
+		if err != nil {
+			return err
+		}
+		for _, statement := range m.Statements {
+			_, err = fmt.Fprintf(w, "%s\n", statement)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = fmt.Fprintf(w, "INSERT INTO goose_db_version (version_id, is_applied) VALUES (%d, true);\n\n", m.Version)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
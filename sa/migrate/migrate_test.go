@@ -0,0 +1,124 @@
+package migrate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestParse(t *testing.T) {
+	m, err := parse("20190101000000_Example.sql", strings.NewReader(`
+-- +goose Up
+-- +boulder SafeOnline
+-- SQL in section 'Up' is executed when this migration is applied
+
+CREATE TABLE example (
+  id BIGINT(20) NOT NULL,
+  -- The example's name.
+  name VARCHAR(255) NOT NULL
+);
+ALTER TABLE example ADD INDEX name_idx (name);
+
+-- +goose StatementBegin
+CREATE PROCEDURE example_proc()
+BEGIN
+  SELECT 1;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE example;
+`))
+	test.AssertNotError(t, err, "parse failed")
+	test.AssertEquals(t, m.Version, int64(20190101000000))
+	test.AssertEquals(t, m.Name, "20190101000000_Example.sql")
+	test.Assert(t, m.SafeOnline, "SafeOnline annotation wasn't parsed")
+	test.AssertEquals(t, len(m.Statements), 3)
+	test.AssertEquals(t, m.Statements[0], `CREATE TABLE example (
+  id BIGINT(20) NOT NULL,
+  -- The example's name.
+  name VARCHAR(255) NOT NULL
+);`)
+	test.AssertEquals(t, m.Statements[1], "ALTER TABLE example ADD INDEX name_idx (name);")
+	test.AssertEquals(t, m.Statements[2], `CREATE PROCEDURE example_proc()
+BEGIN
+  SELECT 1;
+END;`)
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		contents string
+		err      string
+	}{
+		{
+			name:     "Example.sql",
+			contents: "-- +goose Up\n",
+			err:      `migration "Example.sql" has no version`,
+		},
+		{
+			name:     "2019_Example.sql",
+			contents: "DROP TABLE example;\n",
+			err:      `migration "2019_Example.sql" has no "-- +goose Up" section`,
+		},
+		{
+			name:     "2019_Example.sql",
+			contents: "-- +goose Up\nDROP TABLE example\n",
+			err:      `migration "2019_Example.sql" has an unterminated statement: "DROP TABLE example"`,
+		},
+	}
+	for _, tc := range testCases {
+		_, err := parse(tc.name, strings.NewReader(tc.contents))
+		test.AssertError(t, err, "parse accepted an invalid migration")
+		test.AssertEquals(t, err.Error(), tc.err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load("../_db", "../_db-next")
+	test.AssertNotError(t, err, "Load failed")
+	test.Assert(t, len(migrations) > 0, "no migrations loaded")
+	for i, m := range migrations {
+		test.Assert(t, len(m.Statements) > 0, "migration "+m.Name+" has no statements")
+		if i > 0 {
+			test.Assert(t, migrations[i-1].Version < m.Version, "migrations aren't in order")
+		}
+	}
+
+	// Loading a directory twice doesn't duplicate its migrations
+	again, err := Load("../_db", "../_db")
+	test.AssertNotError(t, err, "Load failed")
+	only, err := Load("../_db")
+	test.AssertNotError(t, err, "Load failed")
+	test.AssertEquals(t, len(again), len(only))
+}
+
+func TestWriteSQL(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteSQL(&buf, []Migration{
+		{
+			Version:    1,
+			Name:       "1_Online.sql",
+			Statements: []string{"CREATE TABLE a (id INT);"},
+			SafeOnline: true,
+		},
+		{
+			Version:    2,
+			Name:       "2_Offline.sql",
+			Statements: []string{"ALTER TABLE a ADD COLUMN b INT;"},
+		},
+	})
+	test.AssertNotError(t, err, "WriteSQL failed")
+	test.AssertEquals(t, buf.String(), `-- 1_Online.sql (safe online)
+CREATE TABLE a (id INT);
+INSERT INTO goose_db_version (version_id, is_applied) VALUES (1, true);
+
+-- 2_Offline.sql (requires downtime)
+ALTER TABLE a ADD COLUMN b INT;
+INSERT INTO goose_db_version (version_id, is_applied) VALUES (2, true);
+
+`)
+}
@@ -15,9 +15,11 @@ import (
 
 	"github.com/letsencrypt/boulder/core"
 	corepb "github.com/letsencrypt/boulder/core/proto"
+	"github.com/letsencrypt/boulder/features"
 	"github.com/letsencrypt/boulder/grpc"
 	"github.com/letsencrypt/boulder/probs"
 	"github.com/letsencrypt/boulder/revocation"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
 )
 
 // By convention, any function that takes a dbOneSelector, dbSelector,
@@ -78,12 +80,22 @@ func selectPendingAuthz(s dbOneSelector, q string, args ...interface{}) (*pendin
 
 const authzFields = "id, identifier, registrationID, status, expires, combinations"
 
+// authzFieldsFor returns the fields to select for authorizations from table.
+// Finalized authorizations also have the validationMethod they were validated
+// with if the StoreValidationMethod feature is enabled.
+func authzFieldsFor(table string) string {
+	if table == authorizationTable && features.Enabled(features.StoreValidationMethod) {
+		return authzFields + ", validationMethod"
+	}
+	return authzFields
+}
+
 // selectAuthz selects all fields of one authorization model
 func selectAuthz(s dbOneSelector, q string, args ...interface{}) (*authzModel, error) {
 	var model authzModel
 	err := s.SelectOne(
 		&model,
-		"SELECT "+authzFields+" FROM authz "+q,
+		"SELECT "+authzFieldsFor(authorizationTable)+" FROM authz "+q,
 		args...,
 	)
 	return &model, err
@@ -204,15 +216,26 @@ type challModel struct {
 	// TODO(#1818): Remove, this field is unused, but is kept temporarily to avoid a database migration.
 	Validated bool `db:"validated"`
 
+	Attempts int64 `db:"attempts"`
+
 	LockCol int64
 }
 
-// getChallengesQuery fetches exactly the fields in challModel from the
-// challenges table.
-const getChallengesQuery = `
-	SELECT id, authorizationID, type, status, error, token,
-		keyAuthorization, validationRecord
-	FROM challenges WHERE authorizationID = :authID ORDER BY id ASC`
+const challFields = `id, authorizationID, type, status, error, token,
+		keyAuthorization, validationRecord`
+
+// getChallengesQuery returns a query that fetches exactly the fields in
+// challModel from the challenges table. The attempts column is only selected
+// if the StoreChallengeAttempts feature is enabled.
+func getChallengesQuery() string {
+	fields := challFields
+	if features.Enabled(features.StoreChallengeAttempts) {
+		fields += ", attempts"
+	}
+	return fmt.Sprintf(`
+	SELECT %s
+	FROM challenges WHERE authorizationID = :authID ORDER BY id ASC`, fields)
+}
 
 // newReg creates a reg model object from a core.Registration
 func registrationToModel(r *core.Registration) (*regModel, error) {
@@ -282,6 +305,7 @@ func challengeToModel(c *core.Challenge, authID string) (*challModel, error) {
 		Status:           c.Status,
 		Token:            c.Token,
 		KeyAuthorization: c.ProvidedKeyAuthorization,
+		Attempts:         c.Attempts,
 	}
 	if c.Error != nil {
 		errJSON, err := json.Marshal(c.Error)
@@ -313,6 +337,7 @@ func modelToChallenge(cm *challModel) (core.Challenge, error) {
 		Status:                   cm.Status,
 		Token:                    cm.Token,
 		ProvidedKeyAuthorization: cm.KeyAuthorization,
+		Attempts:                 cm.Attempts,
 	}
 	if len(cm.Error) > 0 {
 		var problem probs.ProblemDetails
@@ -343,6 +368,25 @@ type orderModel struct {
 	BeganProcessing   bool
 }
 
+// orderExemptionModel represents one row in the orderExemptions table, which
+// records the policy exemption tokens used by orders. It's keyed by the
+// token's hash so that each token can only be used by one order.
+type orderExemptionModel struct {
+	TokenHash string `db:"tokenHash"`
+	OrderID   int64  `db:"orderID"`
+	Token     string `db:"token"`
+}
+
+// replacementOrderModel represents one row in the replacementOrders table,
+// which records the certificate an order replaces.
+type replacementOrderModel struct {
+	ID           int64     `db:"id"`
+	Serial       string    `db:"serial"`
+	OrderID      int64     `db:"orderID"`
+	OrderExpires time.Time `db:"orderExpires"`
+	Replaced     bool      `db:"replaced"`
+}
+
 type requestedNameModel struct {
 	ID           int64
 	OrderID      int64
@@ -415,6 +459,13 @@ var uintToChallType = map[uint]string{
 	3: "tls-alpn-01",
 }
 
+// ChallengeTypeFromUint returns the challenge type stored as n in the
+// attempted column of the authz2 table, or false if n isn't a known type.
+func ChallengeTypeFromUint(n uint) (string, bool) {
+	t, ok := uintToChallType[n]
+	return t, ok
+}
+
 var identifierTypeToUint = map[string]uint{
 	"dns": 0,
 }
@@ -430,6 +481,11 @@ var statusToUint = map[string]uint{
 	"deactivated": 3,
 }
 
+// StatusUint returns the value that status is stored as in the authz2 table.
+func StatusUint(status core.AcmeStatus) uint {
+	return statusToUint[string(status)]
+}
+
 var uintToStatus = map[uint]string{
 	0: "pending",
 	1: "valid",
@@ -593,6 +649,12 @@ func modelToAuthzPB(am *authz2Model) (*corepb.Authorization, error) {
 		RegistrationID: &am.RegistrationID,
 		Expires:        &expires,
 	}
+	// The attempted challenge of a valid authz2 authorization is the one it
+	// was validated with
+	if am.Attempted != nil && status == string(core.StatusValid) {
+		validationMethod := uintToChallType[*am.Attempted]
+		pb.ValidationMethod = &validationMethod
+	}
 	// Populate authorization challenge array. We do this by iterating through
 	// the challenge type bitmap and creating a challenge of each type if its
 	// bit is set. Each of these challenges has the token from the authorization
@@ -623,3 +685,36 @@ func modelToAuthzPB(am *authz2Model) (*corepb.Authorization, error) {
 	}
 	return pb, nil
 }
+
+// rateLimitOverrideModel represents one row in the rateLimitOverrides table.
+// Rows are never deleted: replacing or expiring an override only sets its
+// expiry, leaving a record of who changed what, when, and why.
+type rateLimitOverrideModel struct {
+	ID             int64     `db:"id"`
+	LimitName      string    `db:"limitName"`
+	Key            string    `db:"overrideKey"`
+	RegistrationID int64     `db:"registrationID"`
+	Threshold      int64     `db:"threshold"`
+	CreatedBy      string    `db:"createdBy"`
+	Created        time.Time `db:"created"`
+	Expires        time.Time `db:"expires"`
+	Reason         string    `db:"reason"`
+	ExpiredBy      string    `db:"expiredBy"`
+	ExpiredReason  string    `db:"expiredReason"`
+}
+
+func modelToRateLimitOverride(rlom *rateLimitOverrideModel) *sapb.RateLimitOverride {
+	created := rlom.Created.UnixNano()
+	expires := rlom.Expires.UnixNano()
+	return &sapb.RateLimitOverride{
+		Id:             &rlom.ID,
+		LimitName:      &rlom.LimitName,
+		Key:            &rlom.Key,
+		RegistrationID: &rlom.RegistrationID,
+		Threshold:      &rlom.Threshold,
+		CreatedBy:      &rlom.CreatedBy,
+		Created:        &created,
+		Expires:        &expires,
+		Reason:         &rlom.Reason,
+	}
+}
@@ -35,13 +35,34 @@ It has these top-level messages:
 	RevokeAuthorizationsByDomainResponse
 	OrderRequest
 	GetValidOrderAuthorizationsRequest
+	GetOrderAuthorizationsRequest
 	GetOrderForNamesRequest
 	GetAuthorizationsRequest
 	Authorizations
 	AddPendingAuthorizationsRequest
 	AuthorizationIDs
 	AuthorizationID2
+	RetryChallengeRequest
 	RevokeCertificateRequest
+	RateLimitOverride
+	RateLimitOverrides
+	GetRateLimitOverridesRequest
+	ExpireRateLimitOverrideRequest
+	SearchCertificatesRequest
+	ListCertificatesForAccountRequest
+	CertificateSearchResult
+	CertificateSearchResults
+	ListOrdersForAccountRequest
+	OrderList
+	ListInvalidAuthorizationsForAccountRequest
+	AuthorizationList
+	ChallengeWhitelistEntry
+	ChallengeWhitelistEntries
+	GetChallengeWhitelistRequest
+	RemoveChallengeWhitelistEntryRequest
+	DNS01Recheck
+	DNS01Rechecks
+	GetStaleDNS01RechecksRequest
 */
 package proto
 
@@ -803,6 +824,31 @@ func (m *GetValidOrderAuthorizationsRequest) GetAcctID() int64 {
 	return 0
 }
 
+type GetOrderAuthorizationsRequest struct {
+	Id               *int64 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	AcctID           *int64 `protobuf:"varint,2,opt,name=acctID" json:"acctID,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *GetOrderAuthorizationsRequest) Reset()                    { *m = GetOrderAuthorizationsRequest{} }
+func (m *GetOrderAuthorizationsRequest) String() string            { return proto1.CompactTextString(m) }
+func (*GetOrderAuthorizationsRequest) ProtoMessage()               {}
+func (*GetOrderAuthorizationsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
+
+func (m *GetOrderAuthorizationsRequest) GetId() int64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
+	}
+	return 0
+}
+
+func (m *GetOrderAuthorizationsRequest) GetAcctID() int64 {
+	if m != nil && m.AcctID != nil {
+		return *m.AcctID
+	}
+	return 0
+}
+
 type GetOrderForNamesRequest struct {
 	AcctID           *int64   `protobuf:"varint,1,opt,name=acctID" json:"acctID,omitempty"`
 	Names            []string `protobuf:"bytes,2,rep,name=names" json:"names,omitempty"`
@@ -812,7 +858,7 @@ type GetOrderForNamesRequest struct {
 func (m *GetOrderForNamesRequest) Reset()                    { *m = GetOrderForNamesRequest{} }
 func (m *GetOrderForNamesRequest) String() string            { return proto1.CompactTextString(m) }
 func (*GetOrderForNamesRequest) ProtoMessage()               {}
-func (*GetOrderForNamesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{26} }
+func (*GetOrderForNamesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{27} }
 
 func (m *GetOrderForNamesRequest) GetAcctID() int64 {
 	if m != nil && m.AcctID != nil {
@@ -839,7 +885,7 @@ type GetAuthorizationsRequest struct {
 func (m *GetAuthorizationsRequest) Reset()                    { *m = GetAuthorizationsRequest{} }
 func (m *GetAuthorizationsRequest) String() string            { return proto1.CompactTextString(m) }
 func (*GetAuthorizationsRequest) ProtoMessage()               {}
-func (*GetAuthorizationsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{27} }
+func (*GetAuthorizationsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{28} }
 
 func (m *GetAuthorizationsRequest) GetRegistrationID() int64 {
 	if m != nil && m.RegistrationID != nil {
@@ -877,7 +923,7 @@ type Authorizations struct {
 func (m *Authorizations) Reset()                    { *m = Authorizations{} }
 func (m *Authorizations) String() string            { return proto1.CompactTextString(m) }
 func (*Authorizations) ProtoMessage()               {}
-func (*Authorizations) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{28} }
+func (*Authorizations) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{29} }
 
 func (m *Authorizations) GetAuthz() []*Authorizations_MapElement {
 	if m != nil {
@@ -895,7 +941,7 @@ type Authorizations_MapElement struct {
 func (m *Authorizations_MapElement) Reset()                    { *m = Authorizations_MapElement{} }
 func (m *Authorizations_MapElement) String() string            { return proto1.CompactTextString(m) }
 func (*Authorizations_MapElement) ProtoMessage()               {}
-func (*Authorizations_MapElement) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{28, 0} }
+func (*Authorizations_MapElement) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{29, 0} }
 
 func (m *Authorizations_MapElement) GetDomain() string {
 	if m != nil && m.Domain != nil {
@@ -920,7 +966,7 @@ func (m *AddPendingAuthorizationsRequest) Reset()         { *m = AddPendingAutho
 func (m *AddPendingAuthorizationsRequest) String() string { return proto1.CompactTextString(m) }
 func (*AddPendingAuthorizationsRequest) ProtoMessage()    {}
 func (*AddPendingAuthorizationsRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor0, []int{29}
+	return fileDescriptor0, []int{30}
 }
 
 func (m *AddPendingAuthorizationsRequest) GetAuthz() []*core.Authorization {
@@ -938,7 +984,7 @@ type AuthorizationIDs struct {
 func (m *AuthorizationIDs) Reset()                    { *m = AuthorizationIDs{} }
 func (m *AuthorizationIDs) String() string            { return proto1.CompactTextString(m) }
 func (*AuthorizationIDs) ProtoMessage()               {}
-func (*AuthorizationIDs) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{30} }
+func (*AuthorizationIDs) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{31} }
 
 func (m *AuthorizationIDs) GetIds() []string {
 	if m != nil {
@@ -955,7 +1001,7 @@ type AuthorizationID2 struct {
 func (m *AuthorizationID2) Reset()                    { *m = AuthorizationID2{} }
 func (m *AuthorizationID2) String() string            { return proto1.CompactTextString(m) }
 func (*AuthorizationID2) ProtoMessage()               {}
-func (*AuthorizationID2) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{31} }
+func (*AuthorizationID2) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{32} }
 
 func (m *AuthorizationID2) GetId() int64 {
 	if m != nil && m.Id != nil {
@@ -964,6 +1010,31 @@ func (m *AuthorizationID2) GetId() int64 {
 	return 0
 }
 
+type RetryChallengeRequest struct {
+	AuthorizationID  *string `protobuf:"bytes,1,opt,name=authorizationID" json:"authorizationID,omitempty"`
+	ChallengeID      *int64  `protobuf:"varint,2,opt,name=challengeID" json:"challengeID,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *RetryChallengeRequest) Reset()                    { *m = RetryChallengeRequest{} }
+func (m *RetryChallengeRequest) String() string            { return proto1.CompactTextString(m) }
+func (*RetryChallengeRequest) ProtoMessage()               {}
+func (*RetryChallengeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{33} }
+
+func (m *RetryChallengeRequest) GetAuthorizationID() string {
+	if m != nil && m.AuthorizationID != nil {
+		return *m.AuthorizationID
+	}
+	return ""
+}
+
+func (m *RetryChallengeRequest) GetChallengeID() int64 {
+	if m != nil && m.ChallengeID != nil {
+		return *m.ChallengeID
+	}
+	return 0
+}
+
 type RevokeCertificateRequest struct {
 	Serial           *string `protobuf:"bytes,1,opt,name=serial" json:"serial,omitempty"`
 	Reason           *int64  `protobuf:"varint,2,opt,name=reason" json:"reason,omitempty"`
@@ -975,7 +1046,7 @@ type RevokeCertificateRequest struct {
 func (m *RevokeCertificateRequest) Reset()                    { *m = RevokeCertificateRequest{} }
 func (m *RevokeCertificateRequest) String() string            { return proto1.CompactTextString(m) }
 func (*RevokeCertificateRequest) ProtoMessage()               {}
-func (*RevokeCertificateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{32} }
+func (*RevokeCertificateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{34} }
 
 func (m *RevokeCertificateRequest) GetSerial() string {
 	if m != nil && m.Serial != nil {
@@ -1005,6 +1076,739 @@ func (m *RevokeCertificateRequest) GetResponse() []byte {
 	return nil
 }
 
+type RateLimitOverride struct {
+	Id        *int64  `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	LimitName *string `protobuf:"bytes,2,opt,name=limitName" json:"limitName,omitempty"`
+	// Exactly one of key or registrationID must be set.
+	Key              *string `protobuf:"bytes,3,opt,name=key" json:"key,omitempty"`
+	RegistrationID   *int64  `protobuf:"varint,4,opt,name=registrationID" json:"registrationID,omitempty"`
+	Threshold        *int64  `protobuf:"varint,5,opt,name=threshold" json:"threshold,omitempty"`
+	CreatedBy        *string `protobuf:"bytes,6,opt,name=createdBy" json:"createdBy,omitempty"`
+	Created          *int64  `protobuf:"varint,7,opt,name=created" json:"created,omitempty"`
+	Expires          *int64  `protobuf:"varint,8,opt,name=expires" json:"expires,omitempty"`
+	Reason           *string `protobuf:"bytes,9,opt,name=reason" json:"reason,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *RateLimitOverride) Reset()                    { *m = RateLimitOverride{} }
+func (m *RateLimitOverride) String() string            { return proto1.CompactTextString(m) }
+func (*RateLimitOverride) ProtoMessage()               {}
+func (*RateLimitOverride) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{35} }
+
+func (m *RateLimitOverride) GetId() int64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
+	}
+	return 0
+}
+
+func (m *RateLimitOverride) GetLimitName() string {
+	if m != nil && m.LimitName != nil {
+		return *m.LimitName
+	}
+	return ""
+}
+
+func (m *RateLimitOverride) GetKey() string {
+	if m != nil && m.Key != nil {
+		return *m.Key
+	}
+	return ""
+}
+
+func (m *RateLimitOverride) GetRegistrationID() int64 {
+	if m != nil && m.RegistrationID != nil {
+		return *m.RegistrationID
+	}
+	return 0
+}
+
+func (m *RateLimitOverride) GetThreshold() int64 {
+	if m != nil && m.Threshold != nil {
+		return *m.Threshold
+	}
+	return 0
+}
+
+func (m *RateLimitOverride) GetCreatedBy() string {
+	if m != nil && m.CreatedBy != nil {
+		return *m.CreatedBy
+	}
+	return ""
+}
+
+func (m *RateLimitOverride) GetCreated() int64 {
+	if m != nil && m.Created != nil {
+		return *m.Created
+	}
+	return 0
+}
+
+func (m *RateLimitOverride) GetExpires() int64 {
+	if m != nil && m.Expires != nil {
+		return *m.Expires
+	}
+	return 0
+}
+
+func (m *RateLimitOverride) GetReason() string {
+	if m != nil && m.Reason != nil {
+		return *m.Reason
+	}
+	return ""
+}
+
+type RateLimitOverrides struct {
+	Overrides        []*RateLimitOverride `protobuf:"bytes,1,rep,name=overrides" json:"overrides,omitempty"`
+	XXX_unrecognized []byte               `json:"-"`
+}
+
+func (m *RateLimitOverrides) Reset()                    { *m = RateLimitOverrides{} }
+func (m *RateLimitOverrides) String() string            { return proto1.CompactTextString(m) }
+func (*RateLimitOverrides) ProtoMessage()               {}
+func (*RateLimitOverrides) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{36} }
+
+func (m *RateLimitOverrides) GetOverrides() []*RateLimitOverride {
+	if m != nil {
+		return m.Overrides
+	}
+	return nil
+}
+
+type GetRateLimitOverridesRequest struct {
+	Now              *int64 `protobuf:"varint,1,opt,name=now" json:"now,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *GetRateLimitOverridesRequest) Reset()                    { *m = GetRateLimitOverridesRequest{} }
+func (m *GetRateLimitOverridesRequest) String() string            { return proto1.CompactTextString(m) }
+func (*GetRateLimitOverridesRequest) ProtoMessage()               {}
+func (*GetRateLimitOverridesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{37} }
+
+func (m *GetRateLimitOverridesRequest) GetNow() int64 {
+	if m != nil && m.Now != nil {
+		return *m.Now
+	}
+	return 0
+}
+
+type ExpireRateLimitOverrideRequest struct {
+	Id               *int64  `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	ExpiredBy        *string `protobuf:"bytes,2,opt,name=expiredBy" json:"expiredBy,omitempty"`
+	Reason           *string `protobuf:"bytes,3,opt,name=reason" json:"reason,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ExpireRateLimitOverrideRequest) Reset()         { *m = ExpireRateLimitOverrideRequest{} }
+func (m *ExpireRateLimitOverrideRequest) String() string { return proto1.CompactTextString(m) }
+func (*ExpireRateLimitOverrideRequest) ProtoMessage()    {}
+func (*ExpireRateLimitOverrideRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor0, []int{38}
+}
+
+func (m *ExpireRateLimitOverrideRequest) GetId() int64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
+	}
+	return 0
+}
+
+func (m *ExpireRateLimitOverrideRequest) GetExpiredBy() string {
+	if m != nil && m.ExpiredBy != nil {
+		return *m.ExpiredBy
+	}
+	return ""
+}
+
+func (m *ExpireRateLimitOverrideRequest) GetReason() string {
+	if m != nil && m.Reason != nil {
+		return *m.Reason
+	}
+	return ""
+}
+
+type SearchCertificatesRequest struct {
+	// Exactly one of serial, fqdn, registeredDomain or spkiHash must be set.
+	Serial *string `protobuf:"bytes,1,opt,name=serial" json:"serial,omitempty"`
+	Fqdn   *string `protobuf:"bytes,2,opt,name=fqdn" json:"fqdn,omitempty"`
+	// registeredDomain matches certificates for the domain or any of its
+	// subdomains.
+	RegisteredDomain *string `protobuf:"bytes,3,opt,name=registeredDomain" json:"registeredDomain,omitempty"`
+	SpkiHash         []byte  `protobuf:"bytes,4,opt,name=spkiHash" json:"spkiHash,omitempty"`
+	Limit            *int64  `protobuf:"varint,5,opt,name=limit" json:"limit,omitempty"`
+	// cursor is the nextCursor of the previous page, or empty for the first
+	// page.
+	Cursor           *string `protobuf:"bytes,6,opt,name=cursor" json:"cursor,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SearchCertificatesRequest) Reset()                    { *m = SearchCertificatesRequest{} }
+func (m *SearchCertificatesRequest) String() string            { return proto1.CompactTextString(m) }
+func (*SearchCertificatesRequest) ProtoMessage()               {}
+func (*SearchCertificatesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{39} }
+
+func (m *SearchCertificatesRequest) GetSerial() string {
+	if m != nil && m.Serial != nil {
+		return *m.Serial
+	}
+	return ""
+}
+
+func (m *SearchCertificatesRequest) GetFqdn() string {
+	if m != nil && m.Fqdn != nil {
+		return *m.Fqdn
+	}
+	return ""
+}
+
+func (m *SearchCertificatesRequest) GetRegisteredDomain() string {
+	if m != nil && m.RegisteredDomain != nil {
+		return *m.RegisteredDomain
+	}
+	return ""
+}
+
+func (m *SearchCertificatesRequest) GetSpkiHash() []byte {
+	if m != nil {
+		return m.SpkiHash
+	}
+	return nil
+}
+
+func (m *SearchCertificatesRequest) GetLimit() int64 {
+	if m != nil && m.Limit != nil {
+		return *m.Limit
+	}
+	return 0
+}
+
+func (m *SearchCertificatesRequest) GetCursor() string {
+	if m != nil && m.Cursor != nil {
+		return *m.Cursor
+	}
+	return ""
+}
+
+type ListCertificatesForAccountRequest struct {
+	RegistrationID *int64 `protobuf:"varint,1,opt,name=registrationID" json:"registrationID,omitempty"`
+	// status, if set, is "good" or "revoked".
+	Status        *string `protobuf:"bytes,2,opt,name=status" json:"status,omitempty"`
+	ExpiresAfter  *int64  `protobuf:"varint,3,opt,name=expiresAfter" json:"expiresAfter,omitempty"`
+	ExpiresBefore *int64  `protobuf:"varint,4,opt,name=expiresBefore" json:"expiresBefore,omitempty"`
+	// nameContains, if set, matches certificates with a DNS name that
+	// contains it.
+	NameContains *string `protobuf:"bytes,5,opt,name=nameContains" json:"nameContains,omitempty"`
+	Limit        *int64  `protobuf:"varint,6,opt,name=limit" json:"limit,omitempty"`
+	// cursor is the nextCursor of the previous page, or empty for the first
+	// page.
+	Cursor           *string `protobuf:"bytes,7,opt,name=cursor" json:"cursor,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ListCertificatesForAccountRequest) Reset()         { *m = ListCertificatesForAccountRequest{} }
+func (m *ListCertificatesForAccountRequest) String() string { return proto1.CompactTextString(m) }
+func (*ListCertificatesForAccountRequest) ProtoMessage()    {}
+func (*ListCertificatesForAccountRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor0, []int{40}
+}
+
+func (m *ListCertificatesForAccountRequest) GetRegistrationID() int64 {
+	if m != nil && m.RegistrationID != nil {
+		return *m.RegistrationID
+	}
+	return 0
+}
+
+func (m *ListCertificatesForAccountRequest) GetStatus() string {
+	if m != nil && m.Status != nil {
+		return *m.Status
+	}
+	return ""
+}
+
+func (m *ListCertificatesForAccountRequest) GetExpiresAfter() int64 {
+	if m != nil && m.ExpiresAfter != nil {
+		return *m.ExpiresAfter
+	}
+	return 0
+}
+
+func (m *ListCertificatesForAccountRequest) GetExpiresBefore() int64 {
+	if m != nil && m.ExpiresBefore != nil {
+		return *m.ExpiresBefore
+	}
+	return 0
+}
+
+func (m *ListCertificatesForAccountRequest) GetNameContains() string {
+	if m != nil && m.NameContains != nil {
+		return *m.NameContains
+	}
+	return ""
+}
+
+func (m *ListCertificatesForAccountRequest) GetLimit() int64 {
+	if m != nil && m.Limit != nil {
+		return *m.Limit
+	}
+	return 0
+}
+
+func (m *ListCertificatesForAccountRequest) GetCursor() string {
+	if m != nil && m.Cursor != nil {
+		return *m.Cursor
+	}
+	return ""
+}
+
+type CertificateSearchResult struct {
+	Serial           *string  `protobuf:"bytes,1,opt,name=serial" json:"serial,omitempty"`
+	RegistrationID   *int64   `protobuf:"varint,2,opt,name=registrationID" json:"registrationID,omitempty"`
+	Issued           *int64   `protobuf:"varint,3,opt,name=issued" json:"issued,omitempty"`
+	Expires          *int64   `protobuf:"varint,4,opt,name=expires" json:"expires,omitempty"`
+	DnsNames         []string `protobuf:"bytes,5,rep,name=dnsNames" json:"dnsNames,omitempty"`
+	Status           *string  `protobuf:"bytes,6,opt,name=status" json:"status,omitempty"`
+	RevokedDate      *int64   `protobuf:"varint,7,opt,name=revokedDate" json:"revokedDate,omitempty"`
+	RevokedReason    *int64   `protobuf:"varint,8,opt,name=revokedReason" json:"revokedReason,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *CertificateSearchResult) Reset()                    { *m = CertificateSearchResult{} }
+func (m *CertificateSearchResult) String() string            { return proto1.CompactTextString(m) }
+func (*CertificateSearchResult) ProtoMessage()               {}
+func (*CertificateSearchResult) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{41} }
+
+func (m *CertificateSearchResult) GetSerial() string {
+	if m != nil && m.Serial != nil {
+		return *m.Serial
+	}
+	return ""
+}
+
+func (m *CertificateSearchResult) GetRegistrationID() int64 {
+	if m != nil && m.RegistrationID != nil {
+		return *m.RegistrationID
+	}
+	return 0
+}
+
+func (m *CertificateSearchResult) GetIssued() int64 {
+	if m != nil && m.Issued != nil {
+		return *m.Issued
+	}
+	return 0
+}
+
+func (m *CertificateSearchResult) GetExpires() int64 {
+	if m != nil && m.Expires != nil {
+		return *m.Expires
+	}
+	return 0
+}
+
+func (m *CertificateSearchResult) GetDnsNames() []string {
+	if m != nil {
+		return m.DnsNames
+	}
+	return nil
+}
+
+func (m *CertificateSearchResult) GetStatus() string {
+	if m != nil && m.Status != nil {
+		return *m.Status
+	}
+	return ""
+}
+
+func (m *CertificateSearchResult) GetRevokedDate() int64 {
+	if m != nil && m.RevokedDate != nil {
+		return *m.RevokedDate
+	}
+	return 0
+}
+
+func (m *CertificateSearchResult) GetRevokedReason() int64 {
+	if m != nil && m.RevokedReason != nil {
+		return *m.RevokedReason
+	}
+	return 0
+}
+
+type CertificateSearchResults struct {
+	Certificates []*CertificateSearchResult `protobuf:"bytes,1,rep,name=certificates" json:"certificates,omitempty"`
+	// nextCursor is empty if there are no more results.
+	NextCursor       *string `protobuf:"bytes,2,opt,name=nextCursor" json:"nextCursor,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *CertificateSearchResults) Reset()                    { *m = CertificateSearchResults{} }
+func (m *CertificateSearchResults) String() string            { return proto1.CompactTextString(m) }
+func (*CertificateSearchResults) ProtoMessage()               {}
+func (*CertificateSearchResults) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{42} }
+
+func (m *CertificateSearchResults) GetCertificates() []*CertificateSearchResult {
+	if m != nil {
+		return m.Certificates
+	}
+	return nil
+}
+
+func (m *CertificateSearchResults) GetNextCursor() string {
+	if m != nil && m.NextCursor != nil {
+		return *m.NextCursor
+	}
+	return ""
+}
+
+type ListOrdersForAccountRequest struct {
+	RegistrationID *int64 `protobuf:"varint,1,opt,name=registrationID" json:"registrationID,omitempty"`
+	Limit          *int64 `protobuf:"varint,2,opt,name=limit" json:"limit,omitempty"`
+	// cursor is the nextCursor of the previous page, or empty for the first
+	// page.
+	Cursor           *string `protobuf:"bytes,3,opt,name=cursor" json:"cursor,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ListOrdersForAccountRequest) Reset()                    { *m = ListOrdersForAccountRequest{} }
+func (m *ListOrdersForAccountRequest) String() string            { return proto1.CompactTextString(m) }
+func (*ListOrdersForAccountRequest) ProtoMessage()               {}
+func (*ListOrdersForAccountRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{43} }
+
+func (m *ListOrdersForAccountRequest) GetRegistrationID() int64 {
+	if m != nil && m.RegistrationID != nil {
+		return *m.RegistrationID
+	}
+	return 0
+}
+
+func (m *ListOrdersForAccountRequest) GetLimit() int64 {
+	if m != nil && m.Limit != nil {
+		return *m.Limit
+	}
+	return 0
+}
+
+func (m *ListOrdersForAccountRequest) GetCursor() string {
+	if m != nil && m.Cursor != nil {
+		return *m.Cursor
+	}
+	return ""
+}
+
+type OrderList struct {
+	Orders []*core.Order `protobuf:"bytes,1,rep,name=orders" json:"orders,omitempty"`
+	// nextCursor is empty if there are no more orders.
+	NextCursor       *string `protobuf:"bytes,2,opt,name=nextCursor" json:"nextCursor,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *OrderList) Reset()                    { *m = OrderList{} }
+func (m *OrderList) String() string            { return proto1.CompactTextString(m) }
+func (*OrderList) ProtoMessage()               {}
+func (*OrderList) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{44} }
+
+func (m *OrderList) GetOrders() []*core.Order {
+	if m != nil {
+		return m.Orders
+	}
+	return nil
+}
+
+func (m *OrderList) GetNextCursor() string {
+	if m != nil && m.NextCursor != nil {
+		return *m.NextCursor
+	}
+	return ""
+}
+
+type ListInvalidAuthorizationsForAccountRequest struct {
+	RegistrationID   *int64 `protobuf:"varint,1,opt,name=registrationID" json:"registrationID,omitempty"`
+	ExpiresAfter     *int64 `protobuf:"varint,2,opt,name=expiresAfter" json:"expiresAfter,omitempty"`
+	Limit            *int64 `protobuf:"varint,3,opt,name=limit" json:"limit,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *ListInvalidAuthorizationsForAccountRequest) Reset() {
+	*m = ListInvalidAuthorizationsForAccountRequest{}
+}
+func (m *ListInvalidAuthorizationsForAccountRequest) String() string {
+	return proto1.CompactTextString(m)
+}
+func (*ListInvalidAuthorizationsForAccountRequest) ProtoMessage() {}
+func (*ListInvalidAuthorizationsForAccountRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor0, []int{45}
+}
+
+func (m *ListInvalidAuthorizationsForAccountRequest) GetRegistrationID() int64 {
+	if m != nil && m.RegistrationID != nil {
+		return *m.RegistrationID
+	}
+	return 0
+}
+
+func (m *ListInvalidAuthorizationsForAccountRequest) GetExpiresAfter() int64 {
+	if m != nil && m.ExpiresAfter != nil {
+		return *m.ExpiresAfter
+	}
+	return 0
+}
+
+func (m *ListInvalidAuthorizationsForAccountRequest) GetLimit() int64 {
+	if m != nil && m.Limit != nil {
+		return *m.Limit
+	}
+	return 0
+}
+
+type AuthorizationList struct {
+	Authorizations   []*core.Authorization `protobuf:"bytes,1,rep,name=authorizations" json:"authorizations,omitempty"`
+	XXX_unrecognized []byte                `json:"-"`
+}
+
+func (m *AuthorizationList) Reset()                    { *m = AuthorizationList{} }
+func (m *AuthorizationList) String() string            { return proto1.CompactTextString(m) }
+func (*AuthorizationList) ProtoMessage()               {}
+func (*AuthorizationList) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{46} }
+
+func (m *AuthorizationList) GetAuthorizations() []*core.Authorization {
+	if m != nil {
+		return m.Authorizations
+	}
+	return nil
+}
+
+type ChallengeWhitelistEntry struct {
+	Id             *int64  `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	ChallengeType  *string `protobuf:"bytes,2,opt,name=challengeType" json:"challengeType,omitempty"`
+	RegistrationID *int64  `protobuf:"varint,3,opt,name=registrationID" json:"registrationID,omitempty"`
+	CreatedBy      *string `protobuf:"bytes,4,opt,name=createdBy" json:"createdBy,omitempty"`
+	Created        *int64  `protobuf:"varint,5,opt,name=created" json:"created,omitempty"`
+	// expires is a Unix timestamp (nanoseconds), or zero if the entry
+	// never expires.
+	Expires          *int64  `protobuf:"varint,6,opt,name=expires" json:"expires,omitempty"`
+	Reason           *string `protobuf:"bytes,7,opt,name=reason" json:"reason,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ChallengeWhitelistEntry) Reset()                    { *m = ChallengeWhitelistEntry{} }
+func (m *ChallengeWhitelistEntry) String() string            { return proto1.CompactTextString(m) }
+func (*ChallengeWhitelistEntry) ProtoMessage()               {}
+func (*ChallengeWhitelistEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{47} }
+
+func (m *ChallengeWhitelistEntry) GetId() int64 {
+	if m != nil && m.Id != nil {
+		return *m.Id
+	}
+	return 0
+}
+
+func (m *ChallengeWhitelistEntry) GetChallengeType() string {
+	if m != nil && m.ChallengeType != nil {
+		return *m.ChallengeType
+	}
+	return ""
+}
+
+func (m *ChallengeWhitelistEntry) GetRegistrationID() int64 {
+	if m != nil && m.RegistrationID != nil {
+		return *m.RegistrationID
+	}
+	return 0
+}
+
+func (m *ChallengeWhitelistEntry) GetCreatedBy() string {
+	if m != nil && m.CreatedBy != nil {
+		return *m.CreatedBy
+	}
+	return ""
+}
+
+func (m *ChallengeWhitelistEntry) GetCreated() int64 {
+	if m != nil && m.Created != nil {
+		return *m.Created
+	}
+	return 0
+}
+
+func (m *ChallengeWhitelistEntry) GetExpires() int64 {
+	if m != nil && m.Expires != nil {
+		return *m.Expires
+	}
+	return 0
+}
+
+func (m *ChallengeWhitelistEntry) GetReason() string {
+	if m != nil && m.Reason != nil {
+		return *m.Reason
+	}
+	return ""
+}
+
+type ChallengeWhitelistEntries struct {
+	Entries          []*ChallengeWhitelistEntry `protobuf:"bytes,1,rep,name=entries" json:"entries,omitempty"`
+	XXX_unrecognized []byte                     `json:"-"`
+}
+
+func (m *ChallengeWhitelistEntries) Reset()                    { *m = ChallengeWhitelistEntries{} }
+func (m *ChallengeWhitelistEntries) String() string            { return proto1.CompactTextString(m) }
+func (*ChallengeWhitelistEntries) ProtoMessage()               {}
+func (*ChallengeWhitelistEntries) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{48} }
+
+func (m *ChallengeWhitelistEntries) GetEntries() []*ChallengeWhitelistEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+type GetChallengeWhitelistRequest struct {
+	Now              *int64 `protobuf:"varint,1,opt,name=now" json:"now,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *GetChallengeWhitelistRequest) Reset()                    { *m = GetChallengeWhitelistRequest{} }
+func (m *GetChallengeWhitelistRequest) String() string            { return proto1.CompactTextString(m) }
+func (*GetChallengeWhitelistRequest) ProtoMessage()               {}
+func (*GetChallengeWhitelistRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{49} }
+
+func (m *GetChallengeWhitelistRequest) GetNow() int64 {
+	if m != nil && m.Now != nil {
+		return *m.Now
+	}
+	return 0
+}
+
+type RemoveChallengeWhitelistEntryRequest struct {
+	ChallengeType    *string `protobuf:"bytes,1,opt,name=challengeType" json:"challengeType,omitempty"`
+	RegistrationID   *int64  `protobuf:"varint,2,opt,name=registrationID" json:"registrationID,omitempty"`
+	RemovedBy        *string `protobuf:"bytes,3,opt,name=removedBy" json:"removedBy,omitempty"`
+	Reason           *string `protobuf:"bytes,4,opt,name=reason" json:"reason,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *RemoveChallengeWhitelistEntryRequest) Reset()         { *m = RemoveChallengeWhitelistEntryRequest{} }
+func (m *RemoveChallengeWhitelistEntryRequest) String() string { return proto1.CompactTextString(m) }
+func (*RemoveChallengeWhitelistEntryRequest) ProtoMessage()    {}
+func (*RemoveChallengeWhitelistEntryRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor0, []int{50}
+}
+
+func (m *RemoveChallengeWhitelistEntryRequest) GetChallengeType() string {
+	if m != nil && m.ChallengeType != nil {
+		return *m.ChallengeType
+	}
+	return ""
+}
+
+func (m *RemoveChallengeWhitelistEntryRequest) GetRegistrationID() int64 {
+	if m != nil && m.RegistrationID != nil {
+		return *m.RegistrationID
+	}
+	return 0
+}
+
+func (m *RemoveChallengeWhitelistEntryRequest) GetRemovedBy() string {
+	if m != nil && m.RemovedBy != nil {
+		return *m.RemovedBy
+	}
+	return ""
+}
+
+func (m *RemoveChallengeWhitelistEntryRequest) GetReason() string {
+	if m != nil && m.Reason != nil {
+		return *m.Reason
+	}
+	return ""
+}
+
+type DNS01Recheck struct {
+	AuthzID     *string `protobuf:"bytes,1,opt,name=authzID" json:"authzID,omitempty"`
+	ChallengeID *int64  `protobuf:"varint,2,opt,name=challengeID" json:"challengeID,omitempty"`
+	Deadline    *int64  `protobuf:"varint,3,opt,name=deadline" json:"deadline,omitempty"`
+	// leaseExpires is a Unix timestamp (nanoseconds) by which the RA
+	// rechecking the validation must renew its claim, after which another
+	// RA may take the recheck over.
+	LeaseExpires *int64 `protobuf:"varint,4,opt,name=leaseExpires" json:"leaseExpires,omitempty"`
+	// claim is incremented each time the recheck is claimed or its lease
+	// renewed. ClaimDNS01Recheck only succeeds for the current claim.
+	Claim            *int64 `protobuf:"varint,5,opt,name=claim" json:"claim,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *DNS01Recheck) Reset()                    { *m = DNS01Recheck{} }
+func (m *DNS01Recheck) String() string            { return proto1.CompactTextString(m) }
+func (*DNS01Recheck) ProtoMessage()               {}
+func (*DNS01Recheck) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{51} }
+
+func (m *DNS01Recheck) GetAuthzID() string {
+	if m != nil && m.AuthzID != nil {
+		return *m.AuthzID
+	}
+	return ""
+}
+
+func (m *DNS01Recheck) GetChallengeID() int64 {
+	if m != nil && m.ChallengeID != nil {
+		return *m.ChallengeID
+	}
+	return 0
+}
+
+func (m *DNS01Recheck) GetDeadline() int64 {
+	if m != nil && m.Deadline != nil {
+		return *m.Deadline
+	}
+	return 0
+}
+
+func (m *DNS01Recheck) GetLeaseExpires() int64 {
+	if m != nil && m.LeaseExpires != nil {
+		return *m.LeaseExpires
+	}
+	return 0
+}
+
+func (m *DNS01Recheck) GetClaim() int64 {
+	if m != nil && m.Claim != nil {
+		return *m.Claim
+	}
+	return 0
+}
+
+type DNS01Rechecks struct {
+	Rechecks         []*DNS01Recheck `protobuf:"bytes,1,rep,name=rechecks" json:"rechecks,omitempty"`
+	XXX_unrecognized []byte          `json:"-"`
+}
+
+func (m *DNS01Rechecks) Reset()                    { *m = DNS01Rechecks{} }
+func (m *DNS01Rechecks) String() string            { return proto1.CompactTextString(m) }
+func (*DNS01Rechecks) ProtoMessage()               {}
+func (*DNS01Rechecks) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{52} }
+
+func (m *DNS01Rechecks) GetRechecks() []*DNS01Recheck {
+	if m != nil {
+		return m.Rechecks
+	}
+	return nil
+}
+
+type GetStaleDNS01RechecksRequest struct {
+	Now              *int64 `protobuf:"varint,1,opt,name=now" json:"now,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *GetStaleDNS01RechecksRequest) Reset()                    { *m = GetStaleDNS01RechecksRequest{} }
+func (m *GetStaleDNS01RechecksRequest) String() string            { return proto1.CompactTextString(m) }
+func (*GetStaleDNS01RechecksRequest) ProtoMessage()               {}
+func (*GetStaleDNS01RechecksRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{53} }
+
+func (m *GetStaleDNS01RechecksRequest) GetNow() int64 {
+	if m != nil && m.Now != nil {
+		return *m.Now
+	}
+	return 0
+}
+
 func init() {
 	proto1.RegisterType((*RegistrationID)(nil), "sa.RegistrationID")
 	proto1.RegisterType((*JSONWebKey)(nil), "sa.JSONWebKey")
@@ -1034,6 +1838,7 @@ func init() {
 	proto1.RegisterType((*RevokeAuthorizationsByDomainResponse)(nil), "sa.RevokeAuthorizationsByDomainResponse")
 	proto1.RegisterType((*OrderRequest)(nil), "sa.OrderRequest")
 	proto1.RegisterType((*GetValidOrderAuthorizationsRequest)(nil), "sa.GetValidOrderAuthorizationsRequest")
+	proto1.RegisterType((*GetOrderAuthorizationsRequest)(nil), "sa.GetOrderAuthorizationsRequest")
 	proto1.RegisterType((*GetOrderForNamesRequest)(nil), "sa.GetOrderForNamesRequest")
 	proto1.RegisterType((*GetAuthorizationsRequest)(nil), "sa.GetAuthorizationsRequest")
 	proto1.RegisterType((*Authorizations)(nil), "sa.Authorizations")
@@ -1041,7 +1846,27 @@ func init() {
 	proto1.RegisterType((*AddPendingAuthorizationsRequest)(nil), "sa.AddPendingAuthorizationsRequest")
 	proto1.RegisterType((*AuthorizationIDs)(nil), "sa.AuthorizationIDs")
 	proto1.RegisterType((*AuthorizationID2)(nil), "sa.AuthorizationID2")
+	proto1.RegisterType((*RetryChallengeRequest)(nil), "sa.RetryChallengeRequest")
 	proto1.RegisterType((*RevokeCertificateRequest)(nil), "sa.RevokeCertificateRequest")
+	proto1.RegisterType((*RateLimitOverride)(nil), "sa.RateLimitOverride")
+	proto1.RegisterType((*RateLimitOverrides)(nil), "sa.RateLimitOverrides")
+	proto1.RegisterType((*GetRateLimitOverridesRequest)(nil), "sa.GetRateLimitOverridesRequest")
+	proto1.RegisterType((*ExpireRateLimitOverrideRequest)(nil), "sa.ExpireRateLimitOverrideRequest")
+	proto1.RegisterType((*SearchCertificatesRequest)(nil), "sa.SearchCertificatesRequest")
+	proto1.RegisterType((*ListCertificatesForAccountRequest)(nil), "sa.ListCertificatesForAccountRequest")
+	proto1.RegisterType((*CertificateSearchResult)(nil), "sa.CertificateSearchResult")
+	proto1.RegisterType((*CertificateSearchResults)(nil), "sa.CertificateSearchResults")
+	proto1.RegisterType((*ListOrdersForAccountRequest)(nil), "sa.ListOrdersForAccountRequest")
+	proto1.RegisterType((*OrderList)(nil), "sa.OrderList")
+	proto1.RegisterType((*ListInvalidAuthorizationsForAccountRequest)(nil), "sa.ListInvalidAuthorizationsForAccountRequest")
+	proto1.RegisterType((*AuthorizationList)(nil), "sa.AuthorizationList")
+	proto1.RegisterType((*ChallengeWhitelistEntry)(nil), "sa.ChallengeWhitelistEntry")
+	proto1.RegisterType((*ChallengeWhitelistEntries)(nil), "sa.ChallengeWhitelistEntries")
+	proto1.RegisterType((*GetChallengeWhitelistRequest)(nil), "sa.GetChallengeWhitelistRequest")
+	proto1.RegisterType((*RemoveChallengeWhitelistEntryRequest)(nil), "sa.RemoveChallengeWhitelistEntryRequest")
+	proto1.RegisterType((*DNS01Recheck)(nil), "sa.DNS01Recheck")
+	proto1.RegisterType((*DNS01Rechecks)(nil), "sa.DNS01Rechecks")
+	proto1.RegisterType((*GetStaleDNS01RechecksRequest)(nil), "sa.GetStaleDNS01RechecksRequest")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1075,7 +1900,25 @@ type StorageAuthorityClient interface {
 	CountFQDNSets(ctx context.Context, in *CountFQDNSetsRequest, opts ...grpc.CallOption) (*Count, error)
 	FQDNSetExists(ctx context.Context, in *FQDNSetExistsRequest, opts ...grpc.CallOption) (*Exists, error)
 	PreviousCertificateExists(ctx context.Context, in *PreviousCertificateExistsRequest, opts ...grpc.CallOption) (*Exists, error)
+	CertificateReplaced(ctx context.Context, in *Serial, opts ...grpc.CallOption) (*Exists, error)
 	GetAuthz2(ctx context.Context, in *AuthorizationID2, opts ...grpc.CallOption) (*core.Authorization, error)
+	// Return the rate limit overrides that have not expired as of the given
+	// time.
+	GetRateLimitOverrides(ctx context.Context, in *GetRateLimitOverridesRequest, opts ...grpc.CallOption) (*RateLimitOverrides, error)
+	// Return a page of the certificates matching a serial, name or key,
+	// for operators investigating issuance.
+	SearchCertificates(ctx context.Context, in *SearchCertificatesRequest, opts ...grpc.CallOption) (*CertificateSearchResults, error)
+	ListCertificatesForAccount(ctx context.Context, in *ListCertificatesForAccountRequest, opts ...grpc.CallOption) (*CertificateSearchResults, error)
+	// Return a page of an account's orders, newest first.
+	ListOrdersForAccount(ctx context.Context, in *ListOrdersForAccountRequest, opts ...grpc.CallOption) (*OrderList, error)
+	// Return an account's most recent invalid authorizations that expire
+	// after the given time, with their challenges.
+	ListInvalidAuthorizationsForAccount(ctx context.Context, in *ListInvalidAuthorizationsForAccountRequest, opts ...grpc.CallOption) (*AuthorizationList, error)
+	// Return the challenge whitelist entries that have not expired as of
+	// the given time.
+	GetChallengeWhitelist(ctx context.Context, in *GetChallengeWhitelistRequest, opts ...grpc.CallOption) (*ChallengeWhitelistEntries, error)
+	// Return the DNS-01 rechecks whose lease expired by the given time.
+	GetStaleDNS01Rechecks(ctx context.Context, in *GetStaleDNS01RechecksRequest, opts ...grpc.CallOption) (*DNS01Rechecks, error)
 	// Adders
 	NewRegistration(ctx context.Context, in *core.Registration, opts ...grpc.CallOption) (*core.Registration, error)
 	UpdateRegistration(ctx context.Context, in *core.Registration, opts ...grpc.CallOption) (*core.Empty, error)
@@ -1087,16 +1930,26 @@ type StorageAuthorityClient interface {
 	RevokeAuthorizationsByDomain(ctx context.Context, in *RevokeAuthorizationsByDomainRequest, opts ...grpc.CallOption) (*RevokeAuthorizationsByDomainResponse, error)
 	DeactivateRegistration(ctx context.Context, in *RegistrationID, opts ...grpc.CallOption) (*core.Empty, error)
 	DeactivateAuthorization(ctx context.Context, in *AuthorizationID, opts ...grpc.CallOption) (*core.Empty, error)
+	DeactivateAuthorization2(ctx context.Context, in *AuthorizationID2, opts ...grpc.CallOption) (*core.Empty, error)
 	NewOrder(ctx context.Context, in *core.Order, opts ...grpc.CallOption) (*core.Order, error)
 	SetOrderProcessing(ctx context.Context, in *core.Order, opts ...grpc.CallOption) (*core.Empty, error)
 	SetOrderError(ctx context.Context, in *core.Order, opts ...grpc.CallOption) (*core.Empty, error)
 	FinalizeOrder(ctx context.Context, in *core.Order, opts ...grpc.CallOption) (*core.Empty, error)
 	GetOrder(ctx context.Context, in *OrderRequest, opts ...grpc.CallOption) (*core.Order, error)
 	GetValidOrderAuthorizations(ctx context.Context, in *GetValidOrderAuthorizationsRequest, opts ...grpc.CallOption) (*Authorizations, error)
+	GetOrderAuthorizations(ctx context.Context, in *GetOrderAuthorizationsRequest, opts ...grpc.CallOption) (*Authorizations, error)
 	GetOrderForNames(ctx context.Context, in *GetOrderForNamesRequest, opts ...grpc.CallOption) (*core.Order, error)
 	GetAuthorizations(ctx context.Context, in *GetAuthorizationsRequest, opts ...grpc.CallOption) (*Authorizations, error)
 	AddPendingAuthorizations(ctx context.Context, in *AddPendingAuthorizationsRequest, opts ...grpc.CallOption) (*AuthorizationIDs, error)
 	RevokeCertificate(ctx context.Context, in *RevokeCertificateRequest, opts ...grpc.CallOption) (*core.Empty, error)
+	AddRateLimitOverride(ctx context.Context, in *RateLimitOverride, opts ...grpc.CallOption) (*RateLimitOverride, error)
+	ExpireRateLimitOverride(ctx context.Context, in *ExpireRateLimitOverrideRequest, opts ...grpc.CallOption) (*core.Empty, error)
+	RetryChallenge(ctx context.Context, in *RetryChallengeRequest, opts ...grpc.CallOption) (*core.Empty, error)
+	AddChallengeWhitelistEntry(ctx context.Context, in *ChallengeWhitelistEntry, opts ...grpc.CallOption) (*ChallengeWhitelistEntry, error)
+	RemoveChallengeWhitelistEntry(ctx context.Context, in *RemoveChallengeWhitelistEntryRequest, opts ...grpc.CallOption) (*core.Empty, error)
+	AddDNS01Recheck(ctx context.Context, in *DNS01Recheck, opts ...grpc.CallOption) (*core.Empty, error)
+	ClaimDNS01Recheck(ctx context.Context, in *DNS01Recheck, opts ...grpc.CallOption) (*Exists, error)
+	RemoveDNS01Recheck(ctx context.Context, in *AuthorizationID, opts ...grpc.CallOption) (*core.Empty, error)
 }
 
 type storageAuthorityClient struct {
@@ -1170,99 +2023,171 @@ func (c *storageAuthorityClient) GetCertificateStatus(ctx context.Context, in *S
 	return out, nil
 }
 
-func (c *storageAuthorityClient) CountCertificatesByNames(ctx context.Context, in *CountCertificatesByNamesRequest, opts ...grpc.CallOption) (*CountByNames, error) {
-	out := new(CountByNames)
-	err := grpc.Invoke(ctx, "/sa.StorageAuthority/CountCertificatesByNames", in, out, c.cc, opts...)
+func (c *storageAuthorityClient) CountCertificatesByNames(ctx context.Context, in *CountCertificatesByNamesRequest, opts ...grpc.CallOption) (*CountByNames, error) {
+	out := new(CountByNames)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/CountCertificatesByNames", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) CountCertificatesByExactNames(ctx context.Context, in *CountCertificatesByNamesRequest, opts ...grpc.CallOption) (*CountByNames, error) {
+	out := new(CountByNames)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/CountCertificatesByExactNames", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) CountRegistrationsByIP(ctx context.Context, in *CountRegistrationsByIPRequest, opts ...grpc.CallOption) (*Count, error) {
+	out := new(Count)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/CountRegistrationsByIP", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) CountRegistrationsByIPRange(ctx context.Context, in *CountRegistrationsByIPRequest, opts ...grpc.CallOption) (*Count, error) {
+	out := new(Count)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/CountRegistrationsByIPRange", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) CountPendingAuthorizations(ctx context.Context, in *RegistrationID, opts ...grpc.CallOption) (*Count, error) {
+	out := new(Count)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/CountPendingAuthorizations", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) CountOrders(ctx context.Context, in *CountOrdersRequest, opts ...grpc.CallOption) (*Count, error) {
+	out := new(Count)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/CountOrders", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) CountInvalidAuthorizations(ctx context.Context, in *CountInvalidAuthorizationsRequest, opts ...grpc.CallOption) (*Count, error) {
+	out := new(Count)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/CountInvalidAuthorizations", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) CountFQDNSets(ctx context.Context, in *CountFQDNSetsRequest, opts ...grpc.CallOption) (*Count, error) {
+	out := new(Count)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/CountFQDNSets", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) FQDNSetExists(ctx context.Context, in *FQDNSetExistsRequest, opts ...grpc.CallOption) (*Exists, error) {
+	out := new(Exists)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/FQDNSetExists", in, out, c.cc, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *storageAuthorityClient) CountCertificatesByExactNames(ctx context.Context, in *CountCertificatesByNamesRequest, opts ...grpc.CallOption) (*CountByNames, error) {
-	out := new(CountByNames)
-	err := grpc.Invoke(ctx, "/sa.StorageAuthority/CountCertificatesByExactNames", in, out, c.cc, opts...)
+func (c *storageAuthorityClient) PreviousCertificateExists(ctx context.Context, in *PreviousCertificateExistsRequest, opts ...grpc.CallOption) (*Exists, error) {
+	out := new(Exists)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/PreviousCertificateExists", in, out, c.cc, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *storageAuthorityClient) CountRegistrationsByIP(ctx context.Context, in *CountRegistrationsByIPRequest, opts ...grpc.CallOption) (*Count, error) {
-	out := new(Count)
-	err := grpc.Invoke(ctx, "/sa.StorageAuthority/CountRegistrationsByIP", in, out, c.cc, opts...)
+func (c *storageAuthorityClient) CertificateReplaced(ctx context.Context, in *Serial, opts ...grpc.CallOption) (*Exists, error) {
+	out := new(Exists)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/CertificateReplaced", in, out, c.cc, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *storageAuthorityClient) CountRegistrationsByIPRange(ctx context.Context, in *CountRegistrationsByIPRequest, opts ...grpc.CallOption) (*Count, error) {
-	out := new(Count)
-	err := grpc.Invoke(ctx, "/sa.StorageAuthority/CountRegistrationsByIPRange", in, out, c.cc, opts...)
+func (c *storageAuthorityClient) GetAuthz2(ctx context.Context, in *AuthorizationID2, opts ...grpc.CallOption) (*core.Authorization, error) {
+	out := new(core.Authorization)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/GetAuthz2", in, out, c.cc, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *storageAuthorityClient) CountPendingAuthorizations(ctx context.Context, in *RegistrationID, opts ...grpc.CallOption) (*Count, error) {
-	out := new(Count)
-	err := grpc.Invoke(ctx, "/sa.StorageAuthority/CountPendingAuthorizations", in, out, c.cc, opts...)
+func (c *storageAuthorityClient) GetRateLimitOverrides(ctx context.Context, in *GetRateLimitOverridesRequest, opts ...grpc.CallOption) (*RateLimitOverrides, error) {
+	out := new(RateLimitOverrides)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/GetRateLimitOverrides", in, out, c.cc, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *storageAuthorityClient) CountOrders(ctx context.Context, in *CountOrdersRequest, opts ...grpc.CallOption) (*Count, error) {
-	out := new(Count)
-	err := grpc.Invoke(ctx, "/sa.StorageAuthority/CountOrders", in, out, c.cc, opts...)
+func (c *storageAuthorityClient) SearchCertificates(ctx context.Context, in *SearchCertificatesRequest, opts ...grpc.CallOption) (*CertificateSearchResults, error) {
+	out := new(CertificateSearchResults)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/SearchCertificates", in, out, c.cc, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *storageAuthorityClient) CountInvalidAuthorizations(ctx context.Context, in *CountInvalidAuthorizationsRequest, opts ...grpc.CallOption) (*Count, error) {
-	out := new(Count)
-	err := grpc.Invoke(ctx, "/sa.StorageAuthority/CountInvalidAuthorizations", in, out, c.cc, opts...)
+func (c *storageAuthorityClient) ListCertificatesForAccount(ctx context.Context, in *ListCertificatesForAccountRequest, opts ...grpc.CallOption) (*CertificateSearchResults, error) {
+	out := new(CertificateSearchResults)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/ListCertificatesForAccount", in, out, c.cc, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *storageAuthorityClient) CountFQDNSets(ctx context.Context, in *CountFQDNSetsRequest, opts ...grpc.CallOption) (*Count, error) {
-	out := new(Count)
-	err := grpc.Invoke(ctx, "/sa.StorageAuthority/CountFQDNSets", in, out, c.cc, opts...)
+func (c *storageAuthorityClient) ListOrdersForAccount(ctx context.Context, in *ListOrdersForAccountRequest, opts ...grpc.CallOption) (*OrderList, error) {
+	out := new(OrderList)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/ListOrdersForAccount", in, out, c.cc, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *storageAuthorityClient) FQDNSetExists(ctx context.Context, in *FQDNSetExistsRequest, opts ...grpc.CallOption) (*Exists, error) {
-	out := new(Exists)
-	err := grpc.Invoke(ctx, "/sa.StorageAuthority/FQDNSetExists", in, out, c.cc, opts...)
+func (c *storageAuthorityClient) ListInvalidAuthorizationsForAccount(ctx context.Context, in *ListInvalidAuthorizationsForAccountRequest, opts ...grpc.CallOption) (*AuthorizationList, error) {
+	out := new(AuthorizationList)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/ListInvalidAuthorizationsForAccount", in, out, c.cc, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *storageAuthorityClient) PreviousCertificateExists(ctx context.Context, in *PreviousCertificateExistsRequest, opts ...grpc.CallOption) (*Exists, error) {
-	out := new(Exists)
-	err := grpc.Invoke(ctx, "/sa.StorageAuthority/PreviousCertificateExists", in, out, c.cc, opts...)
+func (c *storageAuthorityClient) GetChallengeWhitelist(ctx context.Context, in *GetChallengeWhitelistRequest, opts ...grpc.CallOption) (*ChallengeWhitelistEntries, error) {
+	out := new(ChallengeWhitelistEntries)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/GetChallengeWhitelist", in, out, c.cc, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *storageAuthorityClient) GetAuthz2(ctx context.Context, in *AuthorizationID2, opts ...grpc.CallOption) (*core.Authorization, error) {
-	out := new(core.Authorization)
-	err := grpc.Invoke(ctx, "/sa.StorageAuthority/GetAuthz2", in, out, c.cc, opts...)
+func (c *storageAuthorityClient) GetStaleDNS01Rechecks(ctx context.Context, in *GetStaleDNS01RechecksRequest, opts ...grpc.CallOption) (*DNS01Rechecks, error) {
+	out := new(DNS01Rechecks)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/GetStaleDNS01Rechecks", in, out, c.cc, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -1359,6 +2284,15 @@ func (c *storageAuthorityClient) DeactivateAuthorization(ctx context.Context, in
 	return out, nil
 }
 
+func (c *storageAuthorityClient) DeactivateAuthorization2(ctx context.Context, in *AuthorizationID2, opts ...grpc.CallOption) (*core.Empty, error) {
+	out := new(core.Empty)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/DeactivateAuthorization2", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *storageAuthorityClient) NewOrder(ctx context.Context, in *core.Order, opts ...grpc.CallOption) (*core.Order, error) {
 	out := new(core.Order)
 	err := grpc.Invoke(ctx, "/sa.StorageAuthority/NewOrder", in, out, c.cc, opts...)
@@ -1413,6 +2347,15 @@ func (c *storageAuthorityClient) GetValidOrderAuthorizations(ctx context.Context
 	return out, nil
 }
 
+func (c *storageAuthorityClient) GetOrderAuthorizations(ctx context.Context, in *GetOrderAuthorizationsRequest, opts ...grpc.CallOption) (*Authorizations, error) {
+	out := new(Authorizations)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/GetOrderAuthorizations", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *storageAuthorityClient) GetOrderForNames(ctx context.Context, in *GetOrderForNamesRequest, opts ...grpc.CallOption) (*core.Order, error) {
 	out := new(core.Order)
 	err := grpc.Invoke(ctx, "/sa.StorageAuthority/GetOrderForNames", in, out, c.cc, opts...)
@@ -1449,6 +2392,78 @@ func (c *storageAuthorityClient) RevokeCertificate(ctx context.Context, in *Revo
 	return out, nil
 }
 
+func (c *storageAuthorityClient) AddRateLimitOverride(ctx context.Context, in *RateLimitOverride, opts ...grpc.CallOption) (*RateLimitOverride, error) {
+	out := new(RateLimitOverride)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/AddRateLimitOverride", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) ExpireRateLimitOverride(ctx context.Context, in *ExpireRateLimitOverrideRequest, opts ...grpc.CallOption) (*core.Empty, error) {
+	out := new(core.Empty)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/ExpireRateLimitOverride", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) RetryChallenge(ctx context.Context, in *RetryChallengeRequest, opts ...grpc.CallOption) (*core.Empty, error) {
+	out := new(core.Empty)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/RetryChallenge", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) AddChallengeWhitelistEntry(ctx context.Context, in *ChallengeWhitelistEntry, opts ...grpc.CallOption) (*ChallengeWhitelistEntry, error) {
+	out := new(ChallengeWhitelistEntry)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/AddChallengeWhitelistEntry", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) RemoveChallengeWhitelistEntry(ctx context.Context, in *RemoveChallengeWhitelistEntryRequest, opts ...grpc.CallOption) (*core.Empty, error) {
+	out := new(core.Empty)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/RemoveChallengeWhitelistEntry", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) AddDNS01Recheck(ctx context.Context, in *DNS01Recheck, opts ...grpc.CallOption) (*core.Empty, error) {
+	out := new(core.Empty)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/AddDNS01Recheck", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) ClaimDNS01Recheck(ctx context.Context, in *DNS01Recheck, opts ...grpc.CallOption) (*Exists, error) {
+	out := new(Exists)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/ClaimDNS01Recheck", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAuthorityClient) RemoveDNS01Recheck(ctx context.Context, in *AuthorizationID, opts ...grpc.CallOption) (*core.Empty, error) {
+	out := new(core.Empty)
+	err := grpc.Invoke(ctx, "/sa.StorageAuthority/RemoveDNS01Recheck", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for StorageAuthority service
 
 type StorageAuthorityServer interface {
@@ -1472,7 +2487,25 @@ type StorageAuthorityServer interface {
 	CountFQDNSets(context.Context, *CountFQDNSetsRequest) (*Count, error)
 	FQDNSetExists(context.Context, *FQDNSetExistsRequest) (*Exists, error)
 	PreviousCertificateExists(context.Context, *PreviousCertificateExistsRequest) (*Exists, error)
+	CertificateReplaced(context.Context, *Serial) (*Exists, error)
 	GetAuthz2(context.Context, *AuthorizationID2) (*core.Authorization, error)
+	// Return the rate limit overrides that have not expired as of the given
+	// time.
+	GetRateLimitOverrides(context.Context, *GetRateLimitOverridesRequest) (*RateLimitOverrides, error)
+	// Return a page of the certificates matching a serial, name or key,
+	// for operators investigating issuance.
+	SearchCertificates(context.Context, *SearchCertificatesRequest) (*CertificateSearchResults, error)
+	ListCertificatesForAccount(context.Context, *ListCertificatesForAccountRequest) (*CertificateSearchResults, error)
+	// Return a page of an account's orders, newest first.
+	ListOrdersForAccount(context.Context, *ListOrdersForAccountRequest) (*OrderList, error)
+	// Return an account's most recent invalid authorizations that expire
+	// after the given time, with their challenges.
+	ListInvalidAuthorizationsForAccount(context.Context, *ListInvalidAuthorizationsForAccountRequest) (*AuthorizationList, error)
+	// Return the challenge whitelist entries that have not expired as of
+	// the given time.
+	GetChallengeWhitelist(context.Context, *GetChallengeWhitelistRequest) (*ChallengeWhitelistEntries, error)
+	// Return the DNS-01 rechecks whose lease expired by the given time.
+	GetStaleDNS01Rechecks(context.Context, *GetStaleDNS01RechecksRequest) (*DNS01Rechecks, error)
 	// Adders
 	NewRegistration(context.Context, *core.Registration) (*core.Registration, error)
 	UpdateRegistration(context.Context, *core.Registration) (*core.Empty, error)
@@ -1484,16 +2517,26 @@ type StorageAuthorityServer interface {
 	RevokeAuthorizationsByDomain(context.Context, *RevokeAuthorizationsByDomainRequest) (*RevokeAuthorizationsByDomainResponse, error)
 	DeactivateRegistration(context.Context, *RegistrationID) (*core.Empty, error)
 	DeactivateAuthorization(context.Context, *AuthorizationID) (*core.Empty, error)
+	DeactivateAuthorization2(context.Context, *AuthorizationID2) (*core.Empty, error)
 	NewOrder(context.Context, *core.Order) (*core.Order, error)
 	SetOrderProcessing(context.Context, *core.Order) (*core.Empty, error)
 	SetOrderError(context.Context, *core.Order) (*core.Empty, error)
 	FinalizeOrder(context.Context, *core.Order) (*core.Empty, error)
 	GetOrder(context.Context, *OrderRequest) (*core.Order, error)
 	GetValidOrderAuthorizations(context.Context, *GetValidOrderAuthorizationsRequest) (*Authorizations, error)
+	GetOrderAuthorizations(context.Context, *GetOrderAuthorizationsRequest) (*Authorizations, error)
 	GetOrderForNames(context.Context, *GetOrderForNamesRequest) (*core.Order, error)
 	GetAuthorizations(context.Context, *GetAuthorizationsRequest) (*Authorizations, error)
 	AddPendingAuthorizations(context.Context, *AddPendingAuthorizationsRequest) (*AuthorizationIDs, error)
 	RevokeCertificate(context.Context, *RevokeCertificateRequest) (*core.Empty, error)
+	AddRateLimitOverride(context.Context, *RateLimitOverride) (*RateLimitOverride, error)
+	ExpireRateLimitOverride(context.Context, *ExpireRateLimitOverrideRequest) (*core.Empty, error)
+	RetryChallenge(context.Context, *RetryChallengeRequest) (*core.Empty, error)
+	AddChallengeWhitelistEntry(context.Context, *ChallengeWhitelistEntry) (*ChallengeWhitelistEntry, error)
+	RemoveChallengeWhitelistEntry(context.Context, *RemoveChallengeWhitelistEntryRequest) (*core.Empty, error)
+	AddDNS01Recheck(context.Context, *DNS01Recheck) (*core.Empty, error)
+	ClaimDNS01Recheck(context.Context, *DNS01Recheck) (*Exists, error)
+	RemoveDNS01Recheck(context.Context, *AuthorizationID) (*core.Empty, error)
 }
 
 func RegisterStorageAuthorityServer(s *grpc.Server, srv StorageAuthorityServer) {
@@ -1596,230 +2639,374 @@ func _StorageAuthority_GetCertificate_Handler(srv interface{}, ctx context.Conte
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(StorageAuthorityServer).GetCertificate(ctx, in)
+		return srv.(StorageAuthorityServer).GetCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/GetCertificate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).GetCertificate(ctx, req.(*Serial))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_GetCertificateStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Serial)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).GetCertificateStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/GetCertificateStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).GetCertificateStatus(ctx, req.(*Serial))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_CountCertificatesByNames_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CountCertificatesByNamesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).CountCertificatesByNames(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/CountCertificatesByNames",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).CountCertificatesByNames(ctx, req.(*CountCertificatesByNamesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_CountCertificatesByExactNames_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CountCertificatesByNamesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).CountCertificatesByExactNames(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/CountCertificatesByExactNames",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).CountCertificatesByExactNames(ctx, req.(*CountCertificatesByNamesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_CountRegistrationsByIP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CountRegistrationsByIPRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).CountRegistrationsByIP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/CountRegistrationsByIP",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).CountRegistrationsByIP(ctx, req.(*CountRegistrationsByIPRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_CountRegistrationsByIPRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CountRegistrationsByIPRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).CountRegistrationsByIPRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/CountRegistrationsByIPRange",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).CountRegistrationsByIPRange(ctx, req.(*CountRegistrationsByIPRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_CountPendingAuthorizations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegistrationID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).CountPendingAuthorizations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/CountPendingAuthorizations",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).CountPendingAuthorizations(ctx, req.(*RegistrationID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_CountOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CountOrdersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).CountOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/CountOrders",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).CountOrders(ctx, req.(*CountOrdersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_CountInvalidAuthorizations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CountInvalidAuthorizationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).CountInvalidAuthorizations(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/sa.StorageAuthority/GetCertificate",
+		FullMethod: "/sa.StorageAuthority/CountInvalidAuthorizations",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(StorageAuthorityServer).GetCertificate(ctx, req.(*Serial))
+		return srv.(StorageAuthorityServer).CountInvalidAuthorizations(ctx, req.(*CountInvalidAuthorizationsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _StorageAuthority_GetCertificateStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Serial)
+func _StorageAuthority_CountFQDNSets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CountFQDNSetsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(StorageAuthorityServer).GetCertificateStatus(ctx, in)
+		return srv.(StorageAuthorityServer).CountFQDNSets(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/sa.StorageAuthority/GetCertificateStatus",
+		FullMethod: "/sa.StorageAuthority/CountFQDNSets",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(StorageAuthorityServer).GetCertificateStatus(ctx, req.(*Serial))
+		return srv.(StorageAuthorityServer).CountFQDNSets(ctx, req.(*CountFQDNSetsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _StorageAuthority_CountCertificatesByNames_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CountCertificatesByNamesRequest)
+func _StorageAuthority_FQDNSetExists_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FQDNSetExistsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(StorageAuthorityServer).CountCertificatesByNames(ctx, in)
+		return srv.(StorageAuthorityServer).FQDNSetExists(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/sa.StorageAuthority/CountCertificatesByNames",
+		FullMethod: "/sa.StorageAuthority/FQDNSetExists",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(StorageAuthorityServer).CountCertificatesByNames(ctx, req.(*CountCertificatesByNamesRequest))
+		return srv.(StorageAuthorityServer).FQDNSetExists(ctx, req.(*FQDNSetExistsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _StorageAuthority_CountCertificatesByExactNames_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CountCertificatesByNamesRequest)
+func _StorageAuthority_PreviousCertificateExists_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PreviousCertificateExistsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(StorageAuthorityServer).CountCertificatesByExactNames(ctx, in)
+		return srv.(StorageAuthorityServer).PreviousCertificateExists(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/sa.StorageAuthority/CountCertificatesByExactNames",
+		FullMethod: "/sa.StorageAuthority/PreviousCertificateExists",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(StorageAuthorityServer).CountCertificatesByExactNames(ctx, req.(*CountCertificatesByNamesRequest))
+		return srv.(StorageAuthorityServer).PreviousCertificateExists(ctx, req.(*PreviousCertificateExistsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _StorageAuthority_CountRegistrationsByIP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CountRegistrationsByIPRequest)
+func _StorageAuthority_CertificateReplaced_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Serial)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(StorageAuthorityServer).CountRegistrationsByIP(ctx, in)
+		return srv.(StorageAuthorityServer).CertificateReplaced(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/sa.StorageAuthority/CountRegistrationsByIP",
+		FullMethod: "/sa.StorageAuthority/CertificateReplaced",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(StorageAuthorityServer).CountRegistrationsByIP(ctx, req.(*CountRegistrationsByIPRequest))
+		return srv.(StorageAuthorityServer).CertificateReplaced(ctx, req.(*Serial))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _StorageAuthority_CountRegistrationsByIPRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CountRegistrationsByIPRequest)
+func _StorageAuthority_GetAuthz2_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthorizationID2)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(StorageAuthorityServer).CountRegistrationsByIPRange(ctx, in)
+		return srv.(StorageAuthorityServer).GetAuthz2(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/sa.StorageAuthority/CountRegistrationsByIPRange",
+		FullMethod: "/sa.StorageAuthority/GetAuthz2",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(StorageAuthorityServer).CountRegistrationsByIPRange(ctx, req.(*CountRegistrationsByIPRequest))
+		return srv.(StorageAuthorityServer).GetAuthz2(ctx, req.(*AuthorizationID2))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _StorageAuthority_CountPendingAuthorizations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(RegistrationID)
+func _StorageAuthority_GetRateLimitOverrides_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRateLimitOverridesRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(StorageAuthorityServer).CountPendingAuthorizations(ctx, in)
+		return srv.(StorageAuthorityServer).GetRateLimitOverrides(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/sa.StorageAuthority/CountPendingAuthorizations",
+		FullMethod: "/sa.StorageAuthority/GetRateLimitOverrides",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(StorageAuthorityServer).CountPendingAuthorizations(ctx, req.(*RegistrationID))
+		return srv.(StorageAuthorityServer).GetRateLimitOverrides(ctx, req.(*GetRateLimitOverridesRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _StorageAuthority_CountOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CountOrdersRequest)
+func _StorageAuthority_SearchCertificates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchCertificatesRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(StorageAuthorityServer).CountOrders(ctx, in)
+		return srv.(StorageAuthorityServer).SearchCertificates(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/sa.StorageAuthority/CountOrders",
+		FullMethod: "/sa.StorageAuthority/SearchCertificates",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(StorageAuthorityServer).CountOrders(ctx, req.(*CountOrdersRequest))
+		return srv.(StorageAuthorityServer).SearchCertificates(ctx, req.(*SearchCertificatesRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _StorageAuthority_CountInvalidAuthorizations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CountInvalidAuthorizationsRequest)
+func _StorageAuthority_ListCertificatesForAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCertificatesForAccountRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(StorageAuthorityServer).CountInvalidAuthorizations(ctx, in)
+		return srv.(StorageAuthorityServer).ListCertificatesForAccount(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/sa.StorageAuthority/CountInvalidAuthorizations",
+		FullMethod: "/sa.StorageAuthority/ListCertificatesForAccount",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(StorageAuthorityServer).CountInvalidAuthorizations(ctx, req.(*CountInvalidAuthorizationsRequest))
+		return srv.(StorageAuthorityServer).ListCertificatesForAccount(ctx, req.(*ListCertificatesForAccountRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _StorageAuthority_CountFQDNSets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CountFQDNSetsRequest)
+func _StorageAuthority_ListOrdersForAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListOrdersForAccountRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(StorageAuthorityServer).CountFQDNSets(ctx, in)
+		return srv.(StorageAuthorityServer).ListOrdersForAccount(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/sa.StorageAuthority/CountFQDNSets",
+		FullMethod: "/sa.StorageAuthority/ListOrdersForAccount",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(StorageAuthorityServer).CountFQDNSets(ctx, req.(*CountFQDNSetsRequest))
+		return srv.(StorageAuthorityServer).ListOrdersForAccount(ctx, req.(*ListOrdersForAccountRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _StorageAuthority_FQDNSetExists_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(FQDNSetExistsRequest)
+func _StorageAuthority_ListInvalidAuthorizationsForAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListInvalidAuthorizationsForAccountRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(StorageAuthorityServer).FQDNSetExists(ctx, in)
+		return srv.(StorageAuthorityServer).ListInvalidAuthorizationsForAccount(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/sa.StorageAuthority/FQDNSetExists",
+		FullMethod: "/sa.StorageAuthority/ListInvalidAuthorizationsForAccount",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(StorageAuthorityServer).FQDNSetExists(ctx, req.(*FQDNSetExistsRequest))
+		return srv.(StorageAuthorityServer).ListInvalidAuthorizationsForAccount(ctx, req.(*ListInvalidAuthorizationsForAccountRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _StorageAuthority_PreviousCertificateExists_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(PreviousCertificateExistsRequest)
+func _StorageAuthority_GetChallengeWhitelist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetChallengeWhitelistRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(StorageAuthorityServer).PreviousCertificateExists(ctx, in)
+		return srv.(StorageAuthorityServer).GetChallengeWhitelist(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/sa.StorageAuthority/PreviousCertificateExists",
+		FullMethod: "/sa.StorageAuthority/GetChallengeWhitelist",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(StorageAuthorityServer).PreviousCertificateExists(ctx, req.(*PreviousCertificateExistsRequest))
+		return srv.(StorageAuthorityServer).GetChallengeWhitelist(ctx, req.(*GetChallengeWhitelistRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _StorageAuthority_GetAuthz2_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(AuthorizationID2)
+func _StorageAuthority_GetStaleDNS01Rechecks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStaleDNS01RechecksRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(StorageAuthorityServer).GetAuthz2(ctx, in)
+		return srv.(StorageAuthorityServer).GetStaleDNS01Rechecks(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/sa.StorageAuthority/GetAuthz2",
+		FullMethod: "/sa.StorageAuthority/GetStaleDNS01Rechecks",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(StorageAuthorityServer).GetAuthz2(ctx, req.(*AuthorizationID2))
+		return srv.(StorageAuthorityServer).GetStaleDNS01Rechecks(ctx, req.(*GetStaleDNS01RechecksRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -2004,6 +3191,24 @@ func _StorageAuthority_DeactivateAuthorization_Handler(srv interface{}, ctx cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StorageAuthority_DeactivateAuthorization2_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthorizationID2)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).DeactivateAuthorization2(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/DeactivateAuthorization2",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).DeactivateAuthorization2(ctx, req.(*AuthorizationID2))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _StorageAuthority_NewOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(core.Order)
 	if err := dec(in); err != nil {
@@ -2112,6 +3317,24 @@ func _StorageAuthority_GetValidOrderAuthorizations_Handler(srv interface{}, ctx
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StorageAuthority_GetOrderAuthorizations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderAuthorizationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).GetOrderAuthorizations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/GetOrderAuthorizations",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).GetOrderAuthorizations(ctx, req.(*GetOrderAuthorizationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _StorageAuthority_GetOrderForNames_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetOrderForNamesRequest)
 	if err := dec(in); err != nil {
@@ -2184,6 +3407,150 @@ func _StorageAuthority_RevokeCertificate_Handler(srv interface{}, ctx context.Co
 	return interceptor(ctx, in, info, handler)
 }
 
+func _StorageAuthority_AddRateLimitOverride_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RateLimitOverride)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).AddRateLimitOverride(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/AddRateLimitOverride",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).AddRateLimitOverride(ctx, req.(*RateLimitOverride))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_ExpireRateLimitOverride_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExpireRateLimitOverrideRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).ExpireRateLimitOverride(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/ExpireRateLimitOverride",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).ExpireRateLimitOverride(ctx, req.(*ExpireRateLimitOverrideRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_RetryChallenge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RetryChallengeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).RetryChallenge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/RetryChallenge",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).RetryChallenge(ctx, req.(*RetryChallengeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_AddChallengeWhitelistEntry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChallengeWhitelistEntry)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).AddChallengeWhitelistEntry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/AddChallengeWhitelistEntry",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).AddChallengeWhitelistEntry(ctx, req.(*ChallengeWhitelistEntry))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_RemoveChallengeWhitelistEntry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveChallengeWhitelistEntryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).RemoveChallengeWhitelistEntry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/RemoveChallengeWhitelistEntry",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).RemoveChallengeWhitelistEntry(ctx, req.(*RemoveChallengeWhitelistEntryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_AddDNS01Recheck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DNS01Recheck)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).AddDNS01Recheck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/AddDNS01Recheck",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).AddDNS01Recheck(ctx, req.(*DNS01Recheck))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_ClaimDNS01Recheck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DNS01Recheck)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).ClaimDNS01Recheck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/ClaimDNS01Recheck",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).ClaimDNS01Recheck(ctx, req.(*DNS01Recheck))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAuthority_RemoveDNS01Recheck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthorizationID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAuthorityServer).RemoveDNS01Recheck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/sa.StorageAuthority/RemoveDNS01Recheck",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAuthorityServer).RemoveDNS01Recheck(ctx, req.(*AuthorizationID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _StorageAuthority_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "sa.StorageAuthority",
 	HandlerType: (*StorageAuthorityServer)(nil),
@@ -2256,10 +3623,42 @@ var _StorageAuthority_serviceDesc = grpc.ServiceDesc{
 			MethodName: "PreviousCertificateExists",
 			Handler:    _StorageAuthority_PreviousCertificateExists_Handler,
 		},
+		{
+			MethodName: "CertificateReplaced",
+			Handler:    _StorageAuthority_CertificateReplaced_Handler,
+		},
 		{
 			MethodName: "GetAuthz2",
 			Handler:    _StorageAuthority_GetAuthz2_Handler,
 		},
+		{
+			MethodName: "GetRateLimitOverrides",
+			Handler:    _StorageAuthority_GetRateLimitOverrides_Handler,
+		},
+		{
+			MethodName: "SearchCertificates",
+			Handler:    _StorageAuthority_SearchCertificates_Handler,
+		},
+		{
+			MethodName: "ListCertificatesForAccount",
+			Handler:    _StorageAuthority_ListCertificatesForAccount_Handler,
+		},
+		{
+			MethodName: "ListOrdersForAccount",
+			Handler:    _StorageAuthority_ListOrdersForAccount_Handler,
+		},
+		{
+			MethodName: "ListInvalidAuthorizationsForAccount",
+			Handler:    _StorageAuthority_ListInvalidAuthorizationsForAccount_Handler,
+		},
+		{
+			MethodName: "GetChallengeWhitelist",
+			Handler:    _StorageAuthority_GetChallengeWhitelist_Handler,
+		},
+		{
+			MethodName: "GetStaleDNS01Rechecks",
+			Handler:    _StorageAuthority_GetStaleDNS01Rechecks_Handler,
+		},
 		{
 			MethodName: "NewRegistration",
 			Handler:    _StorageAuthority_NewRegistration_Handler,
@@ -2300,6 +3699,10 @@ var _StorageAuthority_serviceDesc = grpc.ServiceDesc{
 			MethodName: "DeactivateAuthorization",
 			Handler:    _StorageAuthority_DeactivateAuthorization_Handler,
 		},
+		{
+			MethodName: "DeactivateAuthorization2",
+			Handler:    _StorageAuthority_DeactivateAuthorization2_Handler,
+		},
 		{
 			MethodName: "NewOrder",
 			Handler:    _StorageAuthority_NewOrder_Handler,
@@ -2324,6 +3727,10 @@ var _StorageAuthority_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetValidOrderAuthorizations",
 			Handler:    _StorageAuthority_GetValidOrderAuthorizations_Handler,
 		},
+		{
+			MethodName: "GetOrderAuthorizations",
+			Handler:    _StorageAuthority_GetOrderAuthorizations_Handler,
+		},
 		{
 			MethodName: "GetOrderForNames",
 			Handler:    _StorageAuthority_GetOrderForNames_Handler,
@@ -2340,6 +3747,38 @@ var _StorageAuthority_serviceDesc = grpc.ServiceDesc{
 			MethodName: "RevokeCertificate",
 			Handler:    _StorageAuthority_RevokeCertificate_Handler,
 		},
+		{
+			MethodName: "AddRateLimitOverride",
+			Handler:    _StorageAuthority_AddRateLimitOverride_Handler,
+		},
+		{
+			MethodName: "ExpireRateLimitOverride",
+			Handler:    _StorageAuthority_ExpireRateLimitOverride_Handler,
+		},
+		{
+			MethodName: "RetryChallenge",
+			Handler:    _StorageAuthority_RetryChallenge_Handler,
+		},
+		{
+			MethodName: "AddChallengeWhitelistEntry",
+			Handler:    _StorageAuthority_AddChallengeWhitelistEntry_Handler,
+		},
+		{
+			MethodName: "RemoveChallengeWhitelistEntry",
+			Handler:    _StorageAuthority_RemoveChallengeWhitelistEntry_Handler,
+		},
+		{
+			MethodName: "AddDNS01Recheck",
+			Handler:    _StorageAuthority_AddDNS01Recheck_Handler,
+		},
+		{
+			MethodName: "ClaimDNS01Recheck",
+			Handler:    _StorageAuthority_ClaimDNS01Recheck_Handler,
+		},
+		{
+			MethodName: "RemoveDNS01Recheck",
+			Handler:    _StorageAuthority_RemoveDNS01Recheck_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "sa/proto/sa.proto",
@@ -2348,110 +3787,172 @@ var _StorageAuthority_serviceDesc = grpc.ServiceDesc{
 func init() { proto1.RegisterFile("sa/proto/sa.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 1673 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x58, 0xeb, 0x6e, 0xdb, 0xc6,
-	0x12, 0xd6, 0xc5, 0x72, 0xa4, 0xf1, 0x7d, 0x63, 0xcb, 0x0c, 0x7d, 0x89, 0xb3, 0xf1, 0xc9, 0x71,
-	0x70, 0x00, 0x27, 0x47, 0x2d, 0x92, 0x00, 0x6e, 0xda, 0xda, 0xb1, 0xa3, 0x38, 0x17, 0xdb, 0xa5,
-	0x12, 0x27, 0x68, 0x81, 0x02, 0x8c, 0xb8, 0x51, 0x58, 0xcb, 0xa4, 0xb2, 0xbb, 0xb2, 0x23, 0xbf,
-	0x40, 0xfb, 0x04, 0x45, 0xd1, 0x5f, 0x7d, 0x8e, 0xbe, 0x59, 0xff, 0x15, 0x7b, 0x21, 0x45, 0x52,
-	0xa4, 0x14, 0x23, 0x45, 0xff, 0xed, 0xcc, 0xce, 0x7c, 0x33, 0x3b, 0x3b, 0x3b, 0xfc, 0x24, 0x98,
-	0x63, 0xf6, 0x9d, 0x0e, 0xf5, 0xb9, 0x7f, 0x87, 0xd9, 0x9b, 0x72, 0x81, 0x0a, 0xcc, 0x36, 0x17,
-	0x9a, 0x3e, 0x25, 0x7a, 0x43, 0x2c, 0xd5, 0x16, 0x5e, 0x83, 0x69, 0x8b, 0xb4, 0x5c, 0xc6, 0xa9,
-	0xcd, 0x5d, 0xdf, 0xdb, 0xdf, 0x45, 0xd3, 0x50, 0x70, 0x1d, 0x23, 0xbf, 0x96, 0xdf, 0x28, 0x5a,
-	0x05, 0xd7, 0xc1, 0xab, 0x00, 0x4f, 0x1b, 0x87, 0x07, 0xaf, 0xc9, 0xdb, 0x67, 0xa4, 0x87, 0x66,
-	0xa1, 0xf8, 0xd3, 0xf9, 0x89, 0xdc, 0x9e, 0xb4, 0xc4, 0x12, 0xdf, 0x80, 0x99, 0xed, 0x2e, 0x7f,
-	0xef, 0x53, 0xf7, 0x62, 0x10, 0xa2, 0x22, 0x21, 0xfe, 0xcc, 0xc3, 0x6a, 0x9d, 0xf0, 0x23, 0xe2,
-	0x39, 0xae, 0xd7, 0x8a, 0x59, 0x5b, 0xe4, 0x43, 0x97, 0x30, 0x8e, 0x6e, 0xc1, 0x34, 0x8d, 0xe5,
-	0xa1, 0x33, 0x48, 0x68, 0x85, 0x9d, 0xeb, 0x10, 0x8f, 0xbb, 0xef, 0x5c, 0x42, 0x5f, 0xf6, 0x3a,
-	0xc4, 0x28, 0xc8, 0x30, 0x09, 0x2d, 0xda, 0x80, 0x99, 0xbe, 0xe6, 0xd8, 0x6e, 0x77, 0x89, 0x51,
-	0x94, 0x86, 0x49, 0x35, 0x5a, 0x05, 0x38, 0xb3, 0xdb, 0xae, 0xf3, 0xca, 0xe3, 0x6e, 0xdb, 0x18,
-	0x93, 0x51, 0x23, 0x1a, 0xcc, 0x60, 0xa5, 0x4e, 0xf8, 0xb1, 0x50, 0xc4, 0x32, 0x67, 0x97, 0x4d,
-	0xdd, 0x80, 0x2b, 0x8e, 0x7f, 0x6a, 0xbb, 0x1e, 0x33, 0x0a, 0x6b, 0xc5, 0x8d, 0x8a, 0x15, 0x88,
-	0xa2, 0xa8, 0x9e, 0x7f, 0x2e, 0x13, 0x2c, 0x5a, 0x62, 0x89, 0xff, 0xc8, 0xc3, 0xd5, 0x94, 0x90,
-	0xe8, 0x01, 0x94, 0x64, 0x6a, 0x46, 0x7e, 0xad, 0xb8, 0x31, 0x51, 0xc3, 0x9b, 0xcc, 0xde, 0x4c,
-	0xb1, 0xdb, 0x7c, 0x61, 0x77, 0xf6, 0xda, 0xe4, 0x94, 0x78, 0xdc, 0x52, 0x0e, 0xe6, 0x21, 0x40,
-	0x5f, 0x89, 0xaa, 0x30, 0xae, 0x82, 0xeb, 0x5b, 0xd2, 0x12, 0xba, 0x0d, 0x25, 0xbb, 0xcb, 0xdf,
-	0x5f, 0xc8, 0xaa, 0x4e, 0xd4, 0xae, 0x6e, 0xca, 0x56, 0x89, 0xdf, 0x98, 0xb2, 0xc0, 0x7f, 0x15,
-	0x60, 0xee, 0x11, 0xa1, 0xa2, 0x94, 0x4d, 0x9b, 0x93, 0x06, 0xb7, 0x79, 0x97, 0x09, 0x60, 0x46,
-	0xa8, 0x6b, 0xb7, 0x03, 0x60, 0x25, 0xa1, 0x4d, 0x40, 0xac, 0xfb, 0x96, 0x35, 0xa9, 0xfb, 0x96,
-	0xd0, 0xed, 0x4e, 0x87, 0xfa, 0x67, 0xc4, 0x91, 0x51, 0xca, 0x56, 0xca, 0x8e, 0xc4, 0x91, 0x88,
-	0xfa, 0xda, 0xb4, 0x24, 0xee, 0xd5, 0x6f, 0xb2, 0xce, 0x73, 0x9b, 0xf1, 0x57, 0x1d, 0xc7, 0xe6,
-	0xc4, 0xd1, 0x57, 0x96, 0x54, 0xa3, 0x35, 0x98, 0xa0, 0xe4, 0xcc, 0x3f, 0x21, 0xce, 0xae, 0xcd,
-	0x89, 0x51, 0x92, 0x56, 0x51, 0x15, 0x5a, 0x87, 0x29, 0x2d, 0x5a, 0xc4, 0x66, 0xbe, 0x67, 0x8c,
-	0x4b, 0x9b, 0xb8, 0x12, 0x7d, 0x09, 0x0b, 0x6d, 0x9b, 0xf1, 0xbd, 0x8f, 0x1d, 0x57, 0x5d, 0xe5,
-	0x81, 0xdd, 0x6a, 0x10, 0x8f, 0x1b, 0x57, 0xa4, 0x75, 0xfa, 0x26, 0xc2, 0x30, 0x29, 0x12, 0xb2,
-	0x08, 0xeb, 0xf8, 0x1e, 0x23, 0x46, 0x59, 0x3e, 0x98, 0x98, 0x0e, 0x99, 0x50, 0xf6, 0x7c, 0xbe,
-	0xfd, 0x8e, 0x13, 0x6a, 0x54, 0x24, 0x58, 0x28, 0xa3, 0x65, 0xa8, 0xb8, 0x4c, 0xc2, 0x12, 0xc7,
-	0x00, 0x59, 0xa6, 0xbe, 0x02, 0xaf, 0xc1, 0x78, 0x43, 0xd5, 0x35, 0xa3, 0xde, 0x78, 0x0b, 0x4a,
-	0x96, 0xed, 0xb5, 0x64, 0x10, 0x62, 0xd3, 0xb6, 0x4b, 0x18, 0xd7, 0x7d, 0x19, 0xca, 0xc2, 0xb9,
-	0x6d, 0x73, 0xb1, 0x53, 0x90, 0x3b, 0x5a, 0xc2, 0x2b, 0x50, 0x7a, 0xe4, 0x77, 0x3d, 0x8e, 0xe6,
-	0xa1, 0xd4, 0x14, 0x0b, 0xed, 0xa9, 0x04, 0xfc, 0x06, 0xae, 0xcb, 0xed, 0xc8, 0xed, 0xb3, 0x9d,
-	0xde, 0x81, 0x7d, 0x4a, 0xc2, 0x37, 0x71, 0x1d, 0x4a, 0x54, 0x84, 0x97, 0x8e, 0x13, 0xb5, 0x8a,
-	0xe8, 0x53, 0x99, 0x8f, 0xa5, 0xf4, 0x02, 0xd9, 0x13, 0x0e, 0xfa, 0x29, 0x28, 0x01, 0xff, 0x9c,
-	0x87, 0x49, 0x09, 0xad, 0xe1, 0xd0, 0x37, 0x30, 0xd9, 0x8c, 0xc8, 0xba, 0xed, 0x97, 0x04, 0x5c,
-	0xd4, 0x2e, 0xda, 0xef, 0x31, 0x07, 0xf3, 0x5e, 0xac, 0xed, 0x11, 0x8c, 0x89, 0x40, 0xba, 0x56,
-	0x72, 0xdd, 0x3f, 0x63, 0x21, 0x7a, 0xc6, 0x23, 0x58, 0x91, 0x01, 0xa2, 0xc3, 0x91, 0xed, 0xf4,
-	0xf6, 0x8f, 0x82, 0x13, 0x8a, 0x19, 0xd7, 0xd1, 0x73, 0xb0, 0xe0, 0x76, 0xfa, 0x27, 0x2e, 0xa4,
-	0x9f, 0x18, 0xff, 0x92, 0x87, 0x1b, 0x12, 0x72, 0xdf, 0x3b, 0xfb, 0xfc, 0x61, 0x62, 0x42, 0xf9,
-	0xbd, 0xcf, 0xb8, 0x3c, 0x8d, 0x9a, 0x80, 0xa1, 0xdc, 0x4f, 0xa5, 0x98, 0x91, 0x4a, 0x03, 0x90,
-	0xcc, 0xe4, 0x90, 0x3a, 0x84, 0x86, 0xa1, 0x97, 0xa1, 0x62, 0x37, 0xe5, 0xe9, 0xc3, 0xa8, 0x7d,
-	0xc5, 0xe8, 0xf3, 0x3d, 0x81, 0x79, 0x09, 0xfa, 0xf8, 0xbb, 0xdd, 0x83, 0x06, 0xe1, 0x21, 0x6c,
-	0x15, 0xc6, 0xcf, 0x5d, 0xcf, 0xf1, 0xcf, 0x35, 0xa6, 0x96, 0xb2, 0xc7, 0x21, 0xbe, 0x0b, 0xf3,
-	0x1a, 0x64, 0xef, 0xa3, 0xcb, 0xfa, 0x48, 0x11, 0x8f, 0x7c, 0xdc, 0xe3, 0x08, 0xd6, 0x8e, 0x28,
-	0x39, 0x73, 0xfd, 0x2e, 0x8b, 0x34, 0x65, 0xdc, 0x3b, 0x6b, 0xe4, 0xcd, 0x43, 0x89, 0x92, 0xd6,
-	0xfe, 0x6e, 0x70, 0xff, 0x52, 0x10, 0x2f, 0x4c, 0xb9, 0x0b, 0x3f, 0x22, 0x57, 0xd2, 0xaf, 0x6c,
-	0x69, 0x09, 0x3f, 0x83, 0x95, 0x17, 0x36, 0x3d, 0x89, 0xc4, 0xb3, 0x82, 0xb9, 0x11, 0x06, 0x4c,
-	0x1d, 0x85, 0x08, 0xc6, 0x9a, 0xbe, 0x43, 0x74, 0x3c, 0xb9, 0xc6, 0x27, 0xb0, 0xb0, 0xed, 0x38,
-	0x31, 0x2c, 0x05, 0x32, 0x0b, 0x45, 0x87, 0xd0, 0xe0, 0x7b, 0xeb, 0x10, 0x9a, 0x9e, 0xaf, 0x00,
-	0x15, 0xb3, 0x45, 0x5e, 0xf9, 0xa4, 0x25, 0xd7, 0x22, 0x01, 0x97, 0xb1, 0x6e, 0x38, 0x22, 0xb5,
-	0x84, 0xef, 0x42, 0x35, 0x19, 0x4c, 0x4f, 0x24, 0x51, 0x23, 0xb7, 0x15, 0x8c, 0x0a, 0x51, 0x23,
-	0x29, 0xe1, 0x87, 0x70, 0x53, 0x1d, 0x2e, 0xde, 0xb4, 0x3b, 0xbd, 0x5d, 0x59, 0xc3, 0x11, 0x25,
-	0xc6, 0x3f, 0xc2, 0xfa, 0x70, 0x77, 0x1d, 0x7e, 0x19, 0x2a, 0xef, 0x5c, 0xcf, 0x6e, 0xbb, 0x17,
-	0x24, 0x60, 0x20, 0x7d, 0x85, 0xb8, 0xfe, 0x8e, 0x62, 0x10, 0xfa, 0xe8, 0x81, 0x88, 0x57, 0x61,
-	0x52, 0xb6, 0x72, 0xf4, 0x6d, 0x46, 0x29, 0xcc, 0x73, 0xc0, 0xc1, 0x27, 0x5c, 0xda, 0xa5, 0x3f,
-	0xbd, 0x84, 0x97, 0x38, 0x8d, 0xdd, 0x6c, 0xf2, 0xb0, 0xd2, 0x5a, 0xc2, 0x75, 0x58, 0xac, 0x13,
-	0xf5, 0x76, 0x1e, 0xfb, 0x34, 0x36, 0xf6, 0xfa, 0x2e, 0xf9, 0xa8, 0x4b, 0xc6, 0xb4, 0xfb, 0x2d,
-	0x0f, 0x46, 0x9d, 0xf0, 0x7f, 0x8d, 0x55, 0x88, 0x8f, 0x27, 0x25, 0x1f, 0xba, 0x2e, 0x25, 0xc7,
-	0x35, 0x11, 0xf5, 0x82, 0xc9, 0xce, 0x28, 0x5b, 0x49, 0x35, 0xfe, 0x35, 0x0f, 0xd3, 0x09, 0xea,
-	0xf1, 0x45, 0x40, 0x0d, 0xd4, 0x0c, 0x5e, 0x11, 0x03, 0x60, 0x08, 0xeb, 0x90, 0xb6, 0xff, 0x3c,
-	0xeb, 0x78, 0x0e, 0xd7, 0xb7, 0x1d, 0x27, 0x8d, 0x49, 0x86, 0x95, 0xbb, 0x1d, 0x4f, 0x74, 0x18,
-	0xda, 0x3a, 0xcc, 0x26, 0xb8, 0xab, 0x2c, 0x9b, 0xeb, 0x04, 0x13, 0x46, 0x2c, 0x31, 0x1e, 0xb0,
-	0xaa, 0x0d, 0xb4, 0xd8, 0x05, 0x18, 0xaa, 0xc5, 0x53, 0xde, 0x70, 0xd6, 0x20, 0xa8, 0xc2, 0x38,
-	0x55, 0xc4, 0x43, 0x37, 0x98, 0x92, 0xc4, 0x5b, 0x16, 0x14, 0x46, 0xdf, 0x9c, 0x5c, 0x8b, 0x79,
-	0x4f, 0x03, 0x2e, 0x31, 0x26, 0xdf, 0x78, 0x28, 0xd7, 0x7e, 0x9f, 0x87, 0xd9, 0x06, 0xf7, 0xa9,
-	0xdd, 0x0a, 0x1e, 0x18, 0xef, 0xa1, 0x2d, 0x98, 0xa9, 0x93, 0xd8, 0xe7, 0x0b, 0x21, 0x39, 0xb3,
-	0x63, 0xed, 0x63, 0x22, 0x55, 0x9d, 0xa8, 0x16, 0xe7, 0xd0, 0x57, 0x30, 0x9f, 0x70, 0xde, 0xe9,
-	0x09, 0xf6, 0x3f, 0x2d, 0x10, 0xfa, 0xbf, 0x06, 0x32, 0xbc, 0xbf, 0x86, 0xd9, 0x64, 0x5b, 0xa3,
-	0xab, 0x03, 0xed, 0xb2, 0xbf, 0x6b, 0xa6, 0x5d, 0x0d, 0xce, 0xa1, 0x97, 0xf2, 0x81, 0xa5, 0xdd,
-	0x31, 0x92, 0x84, 0x77, 0xf8, 0x4f, 0x89, 0x2c, 0xd4, 0x63, 0xa8, 0xa6, 0xf3, 0x78, 0x74, 0x43,
-	0x83, 0x66, 0x73, 0x7c, 0x73, 0x31, 0x83, 0x68, 0xe3, 0x1c, 0xfa, 0x3f, 0x4c, 0xd7, 0x49, 0x94,
-	0x0b, 0x21, 0x10, 0xc6, 0x8a, 0x9f, 0x99, 0x73, 0x2a, 0x99, 0xc8, 0x36, 0xce, 0xa1, 0x2d, 0x59,
-	0xde, 0x41, 0xf2, 0x1c, 0x75, 0x5c, 0x90, 0x1c, 0x27, 0x69, 0x82, 0x73, 0xa8, 0x01, 0x46, 0x16,
-	0xfb, 0x42, 0x37, 0x43, 0x62, 0x94, 0xcd, 0xcd, 0xcc, 0xd9, 0x24, 0x7b, 0xc2, 0x39, 0xf4, 0x46,
-	0xd3, 0x9d, 0xb8, 0xdb, 0xde, 0x47, 0xbb, 0xc9, 0x3f, 0x13, 0xf9, 0x09, 0x54, 0xd3, 0x89, 0x94,
-	0x2a, 0xfb, 0x50, 0x92, 0x65, 0x56, 0x42, 0x13, 0x9c, 0x43, 0x2f, 0x60, 0x29, 0xc3, 0x5a, 0x32,
-	0xca, 0xcb, 0xc2, 0x3d, 0x04, 0x53, 0x2e, 0x53, 0x67, 0x49, 0xea, 0x5b, 0x89, 0xb9, 0xd7, 0x60,
-	0x22, 0xc2, 0xa1, 0x50, 0x35, 0xdc, 0x8b, 0x91, 0xaa, 0xb8, 0xcf, 0x91, 0x0e, 0x99, 0xca, 0x00,
-	0xd1, 0x7f, 0x42, 0xd3, 0x61, 0x0c, 0x31, 0x8e, 0x78, 0x0f, 0xa6, 0x62, 0xa4, 0x0b, 0x19, 0xe1,
-	0x6e, 0x82, 0x87, 0xc5, 0xfd, 0xee, 0xc3, 0x54, 0x8c, 0x62, 0x29, 0xbf, 0x34, 0xd6, 0x65, 0xca,
-	0xa6, 0x54, 0x2a, 0x9c, 0x43, 0x87, 0x70, 0x2d, 0x93, 0x69, 0xa1, 0x75, 0x61, 0x3a, 0x8a, 0x88,
-	0x25, 0x00, 0x1f, 0x40, 0x45, 0x0f, 0x8b, 0x8b, 0x1a, 0x9a, 0x4f, 0x99, 0x12, 0xb5, 0xac, 0x07,
-	0xbd, 0x05, 0x33, 0x07, 0xe4, 0x3c, 0x31, 0xe1, 0x06, 0xe6, 0x51, 0xc6, 0x8c, 0xba, 0x0f, 0x48,
-	0xfd, 0x50, 0x1c, 0xe9, 0x3f, 0xa1, 0x74, 0x7b, 0xa7, 0x1d, 0xde, 0xc3, 0x39, 0xb4, 0x07, 0x8b,
-	0x07, 0xe4, 0x3c, 0x75, 0x38, 0xa5, 0xe5, 0x99, 0x95, 0xfc, 0xb7, 0x60, 0xaa, 0xf8, 0x9f, 0x8e,
-	0x94, 0x48, 0x64, 0x0b, 0x16, 0x1e, 0x6b, 0x6e, 0x74, 0x79, 0xe7, 0xa7, 0x50, 0x4d, 0x27, 0xaf,
-	0xea, 0x19, 0x0d, 0x25, 0xb6, 0x49, 0xac, 0x7d, 0x98, 0x8e, 0xd3, 0x49, 0x74, 0x4d, 0x5e, 0x63,
-	0x1a, 0x9f, 0x35, 0xcd, 0xb4, 0x2d, 0xf5, 0x1d, 0xc3, 0x39, 0xc4, 0x60, 0x79, 0x18, 0x51, 0x44,
-	0xff, 0x55, 0xaf, 0x72, 0x24, 0x13, 0x35, 0x37, 0x46, 0x1b, 0x86, 0x41, 0xb7, 0xa0, 0xba, 0x4b,
-	0xec, 0x26, 0x77, 0xcf, 0x06, 0xdb, 0x61, 0x70, 0x08, 0x24, 0x0e, 0xff, 0x10, 0x16, 0xfb, 0xce,
-	0x9f, 0xf0, 0xc9, 0x4b, 0xb8, 0xdf, 0x82, 0xf2, 0x01, 0x39, 0x97, 0x23, 0x03, 0xe9, 0x2d, 0x29,
-	0x98, 0x51, 0x01, 0xe7, 0xd0, 0x5d, 0x40, 0x0d, 0xcd, 0x39, 0x8f, 0xa8, 0xdf, 0x24, 0x8c, 0xb9,
-	0x5e, 0x2b, 0xd5, 0x23, 0x40, 0xfe, 0x1f, 0x4c, 0x05, 0x1e, 0x7b, 0x94, 0xfa, 0x74, 0x94, 0x71,
-	0xd0, 0x4b, 0xd9, 0xb9, 0xf4, 0x8d, 0xcb, 0x01, 0xff, 0x45, 0x72, 0xe2, 0x47, 0xb9, 0x77, 0x32,
-	0xf1, 0x1f, 0x60, 0x69, 0x08, 0xf5, 0x46, 0xb7, 0xa2, 0x9f, 0xde, 0x6c, 0x6e, 0x6e, 0xa2, 0x41,
-	0xb6, 0x19, 0x12, 0x8d, 0x18, 0x13, 0x47, 0x4b, 0x1a, 0x31, 0x8d, 0x9f, 0x27, 0x93, 0xab, 0xc3,
-	0xdc, 0x00, 0xff, 0x46, 0xcb, 0x1a, 0xe0, 0x32, 0x89, 0xbc, 0x06, 0x23, 0x8b, 0x95, 0xaa, 0x2f,
-	0xe7, 0x08, 0xce, 0x6a, 0xa6, 0x0d, 0x3e, 0x26, 0xc7, 0xc4, 0xdc, 0x00, 0xad, 0x54, 0x19, 0x66,
-	0xb1, 0xcd, 0xc4, 0x6d, 0xed, 0x5c, 0xf9, 0xbe, 0x24, 0xff, 0xe9, 0xfd, 0x3b, 0x00, 0x00, 0xff,
-	0xff, 0x38, 0x31, 0xe4, 0x87, 0x18, 0x16, 0x00, 0x00,
+	// 2660 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x1a, 0xcb, 0x72, 0x1c, 0xb7,
+	0x71, 0x1f, 0x5c, 0x92, 0xdb, 0x7c, 0x43, 0x7c, 0xac, 0x46, 0x24, 0x45, 0x41, 0x8a, 0x43, 0x3b,
+	0x29, 0x4a, 0xa6, 0x13, 0xdb, 0x15, 0x46, 0x71, 0xf8, 0x12, 0x45, 0x9b, 0x22, 0xe9, 0x59, 0x5b,
+	0x52, 0x39, 0x55, 0xa9, 0x1a, 0xed, 0x80, 0xe4, 0x84, 0xcb, 0x99, 0x15, 0x30, 0x4b, 0x6a, 0x99,
+	0x63, 0x0e, 0xce, 0x29, 0xc7, 0x54, 0x8e, 0x3e, 0xe7, 0x13, 0x52, 0x95, 0x8f, 0xc9, 0x67, 0xe4,
+	0x90, 0x4a, 0x0a, 0x8f, 0x79, 0x60, 0x06, 0xb3, 0x4b, 0xda, 0xae, 0xdc, 0xd0, 0x8d, 0xee, 0x06,
+	0xd0, 0x68, 0xf4, 0x6b, 0x06, 0x66, 0x98, 0xf3, 0xb8, 0x43, 0x83, 0x30, 0x78, 0xcc, 0x9c, 0x35,
+	0x31, 0x40, 0x15, 0xe6, 0x58, 0x73, 0xad, 0x80, 0x12, 0x35, 0xc1, 0x87, 0x72, 0x0a, 0xaf, 0xc0,
+	0xa4, 0x4d, 0x4e, 0x3d, 0x16, 0x52, 0x27, 0xf4, 0x02, 0x7f, 0x7f, 0x07, 0x4d, 0x42, 0xc5, 0x73,
+	0x1b, 0xe5, 0x95, 0xf2, 0x6a, 0xd5, 0xae, 0x78, 0x2e, 0x5e, 0x06, 0xf8, 0xbc, 0x79, 0x74, 0xf8,
+	0x8a, 0xbc, 0xf9, 0x82, 0xf4, 0xd0, 0x34, 0x54, 0xff, 0x70, 0x75, 0x2e, 0xa6, 0xc7, 0x6d, 0x3e,
+	0xc4, 0x0f, 0x60, 0x6a, 0xb3, 0x1b, 0x9e, 0x05, 0xd4, 0xbb, 0xce, 0x8b, 0xa8, 0x0b, 0x11, 0xff,
+	0x28, 0xc3, 0xf2, 0x1e, 0x09, 0x8f, 0x89, 0xef, 0x7a, 0xfe, 0xa9, 0x46, 0x6d, 0x93, 0xb7, 0x5d,
+	0xc2, 0x42, 0xf4, 0x1e, 0x4c, 0x52, 0x6d, 0x1f, 0x6a, 0x07, 0x19, 0x2c, 0xa7, 0xf3, 0x5c, 0xe2,
+	0x87, 0xde, 0x89, 0x47, 0xe8, 0x57, 0xbd, 0x0e, 0x69, 0x54, 0xc4, 0x32, 0x19, 0x2c, 0x5a, 0x85,
+	0xa9, 0x04, 0xf3, 0xd2, 0x69, 0x77, 0x49, 0xa3, 0x2a, 0x08, 0xb3, 0x68, 0xb4, 0x0c, 0x70, 0xe9,
+	0xb4, 0x3d, 0xf7, 0x6b, 0x3f, 0xf4, 0xda, 0x8d, 0x21, 0xb1, 0x6a, 0x0a, 0x83, 0x19, 0x2c, 0xed,
+	0x91, 0xf0, 0x25, 0x47, 0x68, 0x3b, 0x67, 0xb7, 0xdd, 0x7a, 0x03, 0x46, 0xdc, 0xe0, 0xc2, 0xf1,
+	0x7c, 0xd6, 0xa8, 0xac, 0x54, 0x57, 0xeb, 0x76, 0x04, 0x72, 0xa5, 0xfa, 0xc1, 0x95, 0xd8, 0x60,
+	0xd5, 0xe6, 0x43, 0xfc, 0x5d, 0x19, 0xee, 0x18, 0x96, 0x44, 0x9f, 0x42, 0x4d, 0x6c, 0xad, 0x51,
+	0x5e, 0xa9, 0xae, 0x8e, 0xad, 0xe3, 0x35, 0xe6, 0xac, 0x19, 0xe8, 0xd6, 0x5e, 0x38, 0x9d, 0xdd,
+	0x36, 0xb9, 0x20, 0x7e, 0x68, 0x4b, 0x06, 0xeb, 0x08, 0x20, 0x41, 0xa2, 0x79, 0x18, 0x96, 0x8b,
+	0xab, 0x5b, 0x52, 0x10, 0x7a, 0x1f, 0x6a, 0x4e, 0x37, 0x3c, 0xbb, 0x16, 0x5a, 0x1d, 0x5b, 0xbf,
+	0xb3, 0x26, 0x4c, 0x45, 0xbf, 0x31, 0x49, 0x81, 0xff, 0x5d, 0x81, 0x99, 0x6d, 0x42, 0xb9, 0x2a,
+	0x5b, 0x4e, 0x48, 0x9a, 0xa1, 0x13, 0x76, 0x19, 0x17, 0xcc, 0x08, 0xf5, 0x9c, 0x76, 0x24, 0x58,
+	0x42, 0x68, 0x0d, 0x10, 0xeb, 0xbe, 0x61, 0x2d, 0xea, 0xbd, 0x21, 0x74, 0xb3, 0xd3, 0xa1, 0xc1,
+	0x25, 0x71, 0xc5, 0x2a, 0xa3, 0xb6, 0x61, 0x46, 0xc8, 0x11, 0x12, 0xd5, 0xb5, 0x29, 0x88, 0xdf,
+	0x6b, 0xd0, 0x62, 0x9d, 0x03, 0x87, 0x85, 0x5f, 0x77, 0x5c, 0x27, 0x24, 0xae, 0xba, 0xb2, 0x2c,
+	0x1a, 0xad, 0xc0, 0x18, 0x25, 0x97, 0xc1, 0x39, 0x71, 0x77, 0x9c, 0x90, 0x34, 0x6a, 0x82, 0x2a,
+	0x8d, 0x42, 0x8f, 0x60, 0x42, 0x81, 0x36, 0x71, 0x58, 0xe0, 0x37, 0x86, 0x05, 0x8d, 0x8e, 0x44,
+	0xbf, 0x80, 0xb9, 0xb6, 0xc3, 0xc2, 0xdd, 0x77, 0x1d, 0x4f, 0x5e, 0xe5, 0xa1, 0x73, 0xda, 0x24,
+	0x7e, 0xd8, 0x18, 0x11, 0xd4, 0xe6, 0x49, 0x84, 0x61, 0x9c, 0x6f, 0xc8, 0x26, 0xac, 0x13, 0xf8,
+	0x8c, 0x34, 0x46, 0xc5, 0x83, 0xd1, 0x70, 0xc8, 0x82, 0x51, 0x3f, 0x08, 0x37, 0x4f, 0x42, 0x42,
+	0x1b, 0x75, 0x21, 0x2c, 0x86, 0xd1, 0x22, 0xd4, 0x3d, 0x26, 0xc4, 0x12, 0xb7, 0x01, 0x42, 0x4d,
+	0x09, 0x02, 0xaf, 0xc0, 0x70, 0x53, 0xea, 0xb5, 0x40, 0xdf, 0x78, 0x03, 0x6a, 0xb6, 0xe3, 0x9f,
+	0x8a, 0x45, 0x88, 0x43, 0xdb, 0x1e, 0x61, 0xa1, 0xb2, 0xcb, 0x18, 0xe6, 0xcc, 0x6d, 0x27, 0xe4,
+	0x33, 0x15, 0x31, 0xa3, 0x20, 0xbc, 0x04, 0xb5, 0xed, 0xa0, 0xeb, 0x87, 0x68, 0x16, 0x6a, 0x2d,
+	0x3e, 0x50, 0x9c, 0x12, 0xc0, 0xaf, 0xe1, 0xbe, 0x98, 0x4e, 0xdd, 0x3e, 0xdb, 0xea, 0x1d, 0x3a,
+	0x17, 0x24, 0x7e, 0x13, 0xf7, 0xa1, 0x46, 0xf9, 0xf2, 0x82, 0x71, 0x6c, 0xbd, 0xce, 0xed, 0x54,
+	0xec, 0xc7, 0x96, 0x78, 0x2e, 0xd9, 0xe7, 0x0c, 0xea, 0x29, 0x48, 0x00, 0x7f, 0x5b, 0x86, 0x71,
+	0x21, 0x5a, 0x89, 0x43, 0x9f, 0xc1, 0x78, 0x2b, 0x05, 0x2b, 0xb3, 0xbf, 0xc7, 0xc5, 0xa5, 0xe9,
+	0xd2, 0xf6, 0xae, 0x31, 0x58, 0x1f, 0x6b, 0x66, 0x8f, 0x60, 0x88, 0x2f, 0xa4, 0x74, 0x25, 0xc6,
+	0xc9, 0x19, 0x2b, 0xe9, 0x33, 0x1e, 0xc3, 0x92, 0x58, 0x20, 0xed, 0x1c, 0xd9, 0x56, 0x6f, 0xff,
+	0x38, 0x3a, 0x21, 0xf7, 0x71, 0x1d, 0xe5, 0x07, 0x2b, 0x5e, 0x27, 0x39, 0x71, 0xc5, 0x7c, 0x62,
+	0xfc, 0xe7, 0x32, 0x3c, 0x10, 0x22, 0xf7, 0xfd, 0xcb, 0x1f, 0xee, 0x4c, 0x2c, 0x18, 0x3d, 0x0b,
+	0x58, 0x28, 0x4e, 0x23, 0x3d, 0x60, 0x0c, 0x27, 0x5b, 0xa9, 0x16, 0x6c, 0xa5, 0x09, 0x48, 0xec,
+	0xe4, 0x88, 0xba, 0x84, 0xc6, 0x4b, 0x2f, 0x42, 0xdd, 0x69, 0x89, 0xd3, 0xc7, 0xab, 0x26, 0x88,
+	0xc1, 0xe7, 0x7b, 0x0e, 0xb3, 0x42, 0xe8, 0xb3, 0x2f, 0x77, 0x0e, 0x9b, 0x24, 0x8c, 0xc5, 0xce,
+	0xc3, 0xf0, 0x95, 0xe7, 0xbb, 0xc1, 0x95, 0x92, 0xa9, 0xa0, 0x62, 0x77, 0x88, 0x9f, 0xc0, 0xac,
+	0x12, 0xb2, 0xfb, 0xce, 0x63, 0x89, 0xa4, 0x14, 0x47, 0x59, 0xe7, 0x38, 0x86, 0x95, 0x63, 0x4a,
+	0x2e, 0xbd, 0xa0, 0xcb, 0x52, 0x46, 0xa9, 0x73, 0x17, 0xb9, 0xbc, 0x59, 0xa8, 0x51, 0x72, 0xba,
+	0xbf, 0x13, 0xdd, 0xbf, 0x00, 0xf8, 0x0b, 0x93, 0xec, 0x9c, 0x8f, 0x88, 0x91, 0xe0, 0x1b, 0xb5,
+	0x15, 0x84, 0xbf, 0x80, 0xa5, 0x17, 0x0e, 0x3d, 0x4f, 0xad, 0x67, 0x47, 0x7e, 0x23, 0x5e, 0xd0,
+	0xe8, 0x0a, 0x11, 0x0c, 0xb5, 0x02, 0x97, 0xa8, 0xf5, 0xc4, 0x18, 0x9f, 0xc3, 0xdc, 0xa6, 0xeb,
+	0x6a, 0xb2, 0xa4, 0x90, 0x69, 0xa8, 0xba, 0x84, 0x46, 0xf1, 0xd6, 0x25, 0xd4, 0xbc, 0x5f, 0x2e,
+	0x94, 0xfb, 0x16, 0x71, 0xe5, 0xe3, 0xb6, 0x18, 0xf3, 0x0d, 0x78, 0x8c, 0x75, 0x63, 0x17, 0xa9,
+	0x20, 0xfc, 0x04, 0xe6, 0xb3, 0x8b, 0x29, 0x8f, 0xc4, 0x75, 0xe4, 0x9d, 0x46, 0xae, 0xa2, 0x6e,
+	0x2b, 0x08, 0x3f, 0x85, 0x87, 0xf2, 0x70, 0xba, 0xd1, 0x6e, 0xf5, 0x76, 0x84, 0x0e, 0x07, 0xa8,
+	0x18, 0xff, 0x1e, 0x1e, 0xf5, 0x67, 0x57, 0xcb, 0x2f, 0x42, 0xfd, 0xc4, 0xf3, 0x9d, 0xb6, 0x77,
+	0x4d, 0xa2, 0x0c, 0x24, 0x41, 0xf0, 0xeb, 0xef, 0xc8, 0x0c, 0x42, 0x1d, 0x3d, 0x02, 0xf1, 0x32,
+	0x8c, 0x0b, 0x53, 0x4e, 0xbf, 0xcd, 0x74, 0x0a, 0x73, 0x00, 0x38, 0x0a, 0xe1, 0x82, 0xce, 0xfc,
+	0xf4, 0x32, 0x5c, 0xfc, 0x34, 0x4e, 0xab, 0x15, 0xc6, 0x9a, 0x56, 0x10, 0xde, 0x13, 0x09, 0xc1,
+	0x8f, 0x22, 0x68, 0x21, 0x12, 0xf4, 0x2c, 0xa0, 0x9a, 0xff, 0x4c, 0x58, 0xca, 0x69, 0x96, 0x02,
+	0xb7, 0xf9, 0xb7, 0x32, 0x34, 0xf6, 0x48, 0xf8, 0x7f, 0x4b, 0x4f, 0x78, 0x14, 0xa6, 0xe4, 0x6d,
+	0xd7, 0xa3, 0xe4, 0xe5, 0x3a, 0x5f, 0xf5, 0x9a, 0x09, 0x13, 0x1b, 0xb5, 0xb3, 0x68, 0xfc, 0xd7,
+	0x32, 0x4c, 0x66, 0x72, 0x98, 0x8f, 0xa2, 0x1c, 0x43, 0x3a, 0xf3, 0x25, 0xee, 0x49, 0xfa, 0xa4,
+	0x2f, 0x82, 0xf6, 0xc7, 0x4f, 0x5f, 0x0e, 0xe0, 0xfe, 0xa6, 0xeb, 0x9a, 0x52, 0xd2, 0x58, 0x73,
+	0xef, 0xeb, 0x1b, 0xed, 0x27, 0xed, 0x11, 0x4c, 0x67, 0x92, 0x60, 0xa1, 0x36, 0xcf, 0x8d, 0x5c,
+	0x15, 0x1f, 0x62, 0x9c, 0xa3, 0x5a, 0xcf, 0xd9, 0x6a, 0x0b, 0xe6, 0x6c, 0x12, 0xd2, 0xde, 0xf6,
+	0x99, 0xd3, 0x6e, 0x13, 0xee, 0x5f, 0xd5, 0x6e, 0x56, 0x61, 0xca, 0xd1, 0x99, 0xd5, 0xe1, 0xb3,
+	0x68, 0x9e, 0xf9, 0xb4, 0x22, 0xee, 0xd8, 0xe8, 0xd2, 0x28, 0x7c, 0x0d, 0x0d, 0xf9, 0x20, 0x0d,
+	0x1e, 0xa7, 0xc8, 0x6d, 0xcd, 0xc3, 0x30, 0x95, 0x69, 0x92, 0xb2, 0x62, 0x09, 0x71, 0xcf, 0xc3,
+	0x13, 0x2e, 0x65, 0x1e, 0x62, 0xcc, 0xa3, 0x13, 0x8d, 0x32, 0x9f, 0x21, 0xe1, 0x91, 0x62, 0x18,
+	0xff, 0xa9, 0x02, 0x33, 0xb6, 0x13, 0x92, 0x03, 0xef, 0xc2, 0x0b, 0x8f, 0x2e, 0x09, 0xa5, 0x9e,
+	0x4b, 0x72, 0x6f, 0x66, 0x11, 0xea, 0x6d, 0x4e, 0x70, 0x98, 0x04, 0xb8, 0x04, 0xc1, 0x55, 0x7b,
+	0x4e, 0x7a, 0x2a, 0x35, 0xe4, 0x43, 0x83, 0x95, 0x0f, 0x19, 0xad, 0x7c, 0x11, 0xea, 0xe1, 0x19,
+	0x25, 0xec, 0x2c, 0x68, 0xbb, 0x2a, 0x27, 0x4c, 0x10, 0x7c, 0xb6, 0x45, 0x09, 0x4f, 0x1f, 0xb7,
+	0x7a, 0x22, 0x1b, 0xac, 0xdb, 0x09, 0x82, 0xbf, 0x10, 0x05, 0xa8, 0xdc, 0x2f, 0x02, 0xf9, 0x0c,
+	0x11, 0xa9, 0x19, 0x13, 0x89, 0x5e, 0xd5, 0x8e, 0xc0, 0x94, 0xd6, 0xea, 0x52, 0x9b, 0x12, 0xc2,
+	0xfb, 0x80, 0x72, 0x4a, 0xe0, 0x4f, 0xa3, 0x1e, 0x44, 0x80, 0xb2, 0xba, 0x39, 0x19, 0x68, 0x33,
+	0xa4, 0x76, 0x42, 0x87, 0x9f, 0xc0, 0xe2, 0x1e, 0x09, 0xf3, 0xd2, 0x52, 0x21, 0xc4, 0x8f, 0xa3,
+	0x2f, 0x1f, 0xe2, 0x13, 0x58, 0x96, 0x99, 0x64, 0x5e, 0x6e, 0x81, 0x0b, 0x5b, 0x84, 0xba, 0x3c,
+	0x11, 0x57, 0x8c, 0xba, 0x8e, 0x18, 0x91, 0x3a, 0x64, 0x55, 0x3b, 0xe4, 0x3f, 0xcb, 0x70, 0xb7,
+	0x49, 0x1c, 0xda, 0x3a, 0x4b, 0xa7, 0x8a, 0x37, 0x88, 0x8f, 0x27, 0x6f, 0x5d, 0x5f, 0x2d, 0x23,
+	0xc6, 0xe8, 0x03, 0x98, 0x96, 0x17, 0x49, 0x28, 0x71, 0x65, 0xd4, 0x50, 0x6b, 0xe5, 0xf0, 0xdc,
+	0xf8, 0x58, 0xe7, 0xdc, 0x7b, 0xee, 0xb0, 0xb3, 0xc8, 0xf8, 0x22, 0x98, 0xfb, 0x4f, 0x61, 0x45,
+	0xea, 0xea, 0x25, 0xc0, 0x77, 0xd2, 0xea, 0x52, 0x16, 0x50, 0x75, 0xe7, 0x0a, 0xc2, 0xff, 0x2d,
+	0xc3, 0x83, 0x03, 0x8f, 0x69, 0x89, 0xee, 0xb3, 0x80, 0x6e, 0xca, 0xa4, 0xe8, 0xb6, 0x0e, 0x36,
+	0x29, 0x69, 0x2a, 0x5a, 0x49, 0x83, 0x61, 0x5c, 0x59, 0x8b, 0x2c, 0x05, 0xe4, 0x43, 0xd2, 0x70,
+	0xbc, 0x54, 0x51, 0xf0, 0x16, 0x39, 0x09, 0x28, 0x51, 0xd6, 0xad, 0x23, 0xb9, 0x24, 0x1e, 0x10,
+	0xb6, 0x03, 0x3f, 0x14, 0x7e, 0xbc, 0x26, 0xd6, 0xd1, 0x70, 0x89, 0x06, 0x86, 0xcd, 0x1a, 0x18,
+	0xd1, 0x34, 0xf0, 0x6d, 0x05, 0x16, 0xd2, 0x45, 0x9e, 0xb8, 0x4c, 0x9b, 0xb0, 0x6e, 0xbb, 0xf8,
+	0xfe, 0xf2, 0xfa, 0xa8, 0x14, 0xe9, 0x43, 0xa5, 0x27, 0xd5, 0x74, 0x7a, 0x92, 0x7e, 0x4c, 0x43,
+	0xfa, 0x63, 0xb2, 0x60, 0xd4, 0xf5, 0x99, 0xac, 0x04, 0x6a, 0xc2, 0xad, 0xc6, 0x70, 0x4a, 0xbb,
+	0xc3, 0x9a, 0x76, 0x33, 0x65, 0xe0, 0xc8, 0x0d, 0xca, 0xc0, 0x51, 0x43, 0x19, 0x88, 0xff, 0x08,
+	0x8d, 0x02, 0x45, 0xc8, 0x2a, 0x25, 0x99, 0xd3, 0xab, 0x14, 0x33, 0x8f, 0xad, 0x31, 0xf0, 0x1e,
+	0x84, 0x4f, 0xde, 0x85, 0xdb, 0xf2, 0x0a, 0xa4, 0x79, 0xa4, 0x30, 0x98, 0xc1, 0x3d, 0x6e, 0x87,
+	0x32, 0x5f, 0xff, 0xfe, 0x16, 0x18, 0xdf, 0x7d, 0xc5, 0x7c, 0xf7, 0x55, 0xed, 0xee, 0x8f, 0xa1,
+	0x2e, 0x16, 0xe4, 0x2b, 0xa3, 0x87, 0x30, 0x1c, 0x88, 0xd5, 0xd5, 0xe1, 0xc6, 0x64, 0x30, 0x14,
+	0x04, 0xb6, 0x9a, 0x1a, 0x78, 0x8c, 0xbf, 0x94, 0xe1, 0x03, 0x2e, 0xcd, 0x58, 0x01, 0x7d, 0xff,
+	0x63, 0x65, 0x1f, 0x50, 0xc5, 0xf0, 0x80, 0xe2, 0xa3, 0x57, 0x53, 0x47, 0xc7, 0xc7, 0x30, 0xa3,
+	0x6d, 0x42, 0x1c, 0x75, 0x03, 0x26, 0xb5, 0x88, 0xca, 0xfa, 0xc5, 0xff, 0x0c, 0x29, 0xfe, 0x57,
+	0x19, 0x16, 0xe2, 0xd0, 0xfd, 0xea, 0xcc, 0x0b, 0x49, 0xdb, 0x63, 0xe1, 0xae, 0x1f, 0xd2, 0x5e,
+	0xce, 0xa9, 0x3e, 0x82, 0x89, 0x38, 0x28, 0xa7, 0x5a, 0x59, 0x3a, 0xd2, 0xa0, 0x85, 0x6a, 0x51,
+	0x64, 0x4b, 0x62, 0xd7, 0x50, 0x9f, 0xd8, 0x55, 0x2b, 0x8c, 0x5d, 0xc3, 0x45, 0xb1, 0x6b, 0x44,
+	0x73, 0xeb, 0x36, 0xdc, 0x35, 0x1f, 0xd1, 0x23, 0x0c, 0xfd, 0x12, 0x46, 0x88, 0x1c, 0x6a, 0xcf,
+	0xc0, 0xac, 0x12, 0x3b, 0xa2, 0x55, 0x41, 0x2c, 0x4f, 0x56, 0x1c, 0xc4, 0xfe, 0x5e, 0xe6, 0x55,
+	0xc5, 0x45, 0x70, 0x49, 0x8a, 0x84, 0x2b, 0xd6, 0x9c, 0x9a, 0xcb, 0x37, 0x53, 0x73, 0xa5, 0x48,
+	0xcd, 0x54, 0xac, 0xca, 0xd5, 0x2c, 0x1f, 0x4c, 0x82, 0x48, 0xa9, 0x6c, 0x48, 0x53, 0xd9, 0x77,
+	0x65, 0x18, 0xdf, 0x39, 0x6c, 0x3e, 0xf9, 0xd0, 0x26, 0xad, 0x33, 0xd2, 0x3a, 0xe7, 0x5a, 0x17,
+	0x99, 0x63, 0x9c, 0xc5, 0x45, 0xe0, 0xe0, 0xec, 0x4d, 0xb8, 0x41, 0xe2, 0xb8, 0x6d, 0xcf, 0x8f,
+	0xb2, 0xae, 0x18, 0xe6, 0x6f, 0xa1, 0x4d, 0x1c, 0x46, 0x76, 0x35, 0x0f, 0xaa, 0xe1, 0x44, 0xc7,
+	0xa3, 0xed, 0x78, 0x17, 0x51, 0x10, 0x14, 0x00, 0x7e, 0x0a, 0x13, 0xe9, 0x1d, 0x32, 0xf4, 0x73,
+	0x9e, 0xc4, 0xc9, 0xb1, 0xba, 0xca, 0x69, 0x7e, 0x95, 0x69, 0x22, 0x3b, 0xa6, 0x50, 0x17, 0xd8,
+	0x0c, 0x9d, 0x36, 0xd1, 0xc4, 0x14, 0x5e, 0xe0, 0xfa, 0x7f, 0x96, 0x61, 0xba, 0x19, 0x06, 0xd4,
+	0x39, 0x8d, 0xea, 0xc2, 0xb0, 0x87, 0x36, 0x60, 0x8a, 0x27, 0x33, 0x29, 0x9d, 0x23, 0xc4, 0x57,
+	0xd5, 0x9b, 0xd4, 0x16, 0x92, 0x6f, 0x31, 0x8d, 0xc5, 0x25, 0xf4, 0x6b, 0x98, 0xcd, 0x30, 0x6f,
+	0xf5, 0x78, 0xd3, 0x7a, 0x92, 0x4b, 0x48, 0x9a, 0xd8, 0x05, 0xdc, 0xbf, 0x81, 0xe9, 0x6c, 0x11,
+	0x85, 0xee, 0xe4, 0x8a, 0x93, 0xfd, 0x1d, 0xcb, 0xe4, 0x08, 0x70, 0x09, 0x7d, 0x25, 0xca, 0x39,
+	0x53, 0x45, 0x81, 0x44, 0x9f, 0xb6, 0x7f, 0x07, 0xbc, 0x48, 0xea, 0x4b, 0x98, 0x37, 0xb7, 0x9f,
+	0xd1, 0x03, 0x25, 0xb4, 0xb8, 0x35, 0x6d, 0x2d, 0x14, 0xf4, 0x87, 0x71, 0x09, 0x7d, 0x08, 0x93,
+	0xfc, 0xc1, 0x25, 0x51, 0x08, 0x01, 0x27, 0x96, 0x6d, 0x45, 0x6b, 0x46, 0x6e, 0x26, 0x35, 0x8d,
+	0x4b, 0x68, 0x43, 0xa8, 0x37, 0xdf, 0xf3, 0x4d, 0x33, 0xce, 0x65, 0x83, 0x9e, 0x20, 0xc1, 0x25,
+	0xd4, 0x84, 0x46, 0x51, 0xd3, 0x10, 0x3d, 0x8c, 0xfb, 0x79, 0xc5, 0x2d, 0x45, 0x6b, 0x3a, 0xdb,
+	0xf4, 0xc3, 0x25, 0xf4, 0x1a, 0x96, 0x0c, 0x6c, 0xbb, 0xef, 0x9c, 0x56, 0xf8, 0x03, 0x25, 0x3f,
+	0x87, 0x79, 0x73, 0xff, 0x4f, 0xaa, 0xbd, 0x6f, 0x6f, 0xd0, 0xaa, 0xc7, 0x24, 0xb8, 0x84, 0x5e,
+	0xc0, 0xbd, 0x02, 0x6a, 0xd1, 0x08, 0xbd, 0xad, 0xb8, 0xa7, 0x60, 0x89, 0xa1, 0xb1, 0x72, 0x35,
+	0xbe, 0x15, 0x8d, 0x7d, 0x1d, 0xc6, 0x52, 0xad, 0x3f, 0x34, 0x1f, 0xcf, 0x69, 0xbd, 0x40, 0x9d,
+	0xe7, 0x58, 0x2d, 0x69, 0x0c, 0xdb, 0xe8, 0x27, 0x31, 0x69, 0xbf, 0xc6, 0xa6, 0x2e, 0xf1, 0x63,
+	0x98, 0xd0, 0x7a, 0x85, 0xa8, 0x11, 0xcf, 0x66, 0xda, 0x87, 0x3a, 0xdf, 0x27, 0x30, 0xa1, 0x75,
+	0x06, 0x25, 0x9f, 0xa9, 0x59, 0x68, 0x09, 0xa3, 0x94, 0x28, 0x5c, 0x42, 0x47, 0x70, 0xb7, 0xb0,
+	0x41, 0x88, 0x1e, 0x71, 0xd2, 0x41, 0xfd, 0xc3, 0x8c, 0xc0, 0xc7, 0x70, 0x47, 0xab, 0x9d, 0x3b,
+	0x6d, 0xa7, 0x45, 0x5c, 0xed, 0x29, 0xe8, 0x0c, 0x9f, 0x42, 0x5d, 0x79, 0x97, 0xeb, 0x75, 0x34,
+	0x6b, 0x70, 0x2b, 0xeb, 0x45, 0x1e, 0xa0, 0x09, 0x73, 0xc6, 0xfa, 0x0e, 0xad, 0x28, 0x07, 0x50,
+	0x58, 0xfa, 0x59, 0xf3, 0xc6, 0xe2, 0x51, 0x3e, 0x47, 0x94, 0xaf, 0xcc, 0xd0, 0x92, 0xdc, 0x7e,
+	0x41, 0xc5, 0x66, 0x2d, 0xf6, 0xc9, 0x68, 0xb9, 0xd0, 0x16, 0x58, 0xc5, 0xe5, 0x92, 0x34, 0x94,
+	0x81, 0xe5, 0xd4, 0xc0, 0x45, 0x9e, 0xc3, 0xac, 0x29, 0x17, 0x46, 0xf7, 0x23, 0xf1, 0x05, 0x59,
+	0xb2, 0x35, 0xc1, 0x09, 0xe2, 0x8c, 0x16, 0x97, 0x90, 0x0f, 0x0f, 0x6f, 0x90, 0x8d, 0xa2, 0xb5,
+	0x48, 0xf0, 0xcd, 0xd2, 0x56, 0xe9, 0x02, 0x73, 0x69, 0x25, 0x2e, 0xa1, 0x6f, 0xc4, 0x45, 0xe6,
+	0xb3, 0x95, 0xf8, 0x22, 0x0b, 0xd3, 0x1f, 0x6b, 0xa9, 0x38, 0x89, 0xf2, 0xc4, 0x7d, 0x1e, 0xc2,
+	0x9c, 0x31, 0xfc, 0xc6, 0xb2, 0x0b, 0x23, 0xb3, 0x35, 0x93, 0x8d, 0xea, 0x4c, 0xf8, 0xfa, 0xa9,
+	0x43, 0x72, 0x95, 0x89, 0xc3, 0xb9, 0xa8, 0x59, 0x10, 0x49, 0x3f, 0x01, 0x24, 0xbf, 0xc2, 0x0d,
+	0xe4, 0x57, 0x65, 0xc4, 0xee, 0x45, 0x27, 0xec, 0xe1, 0x12, 0xda, 0x85, 0x85, 0x43, 0x72, 0x65,
+	0x0c, 0xa1, 0xa6, 0xc7, 0x51, 0xf4, 0x62, 0x7e, 0x0b, 0x96, 0x5c, 0xff, 0xe6, 0x92, 0x32, 0x1b,
+	0xd9, 0x80, 0xb9, 0x67, 0xaa, 0xf1, 0x7c, 0x7b, 0xe6, 0xcf, 0x61, 0xde, 0xfc, 0x65, 0x40, 0x3a,
+	0xfb, 0xbe, 0x5f, 0x0d, 0xb2, 0xb2, 0xf6, 0x61, 0x52, 0xef, 0xd5, 0xa3, 0xbb, 0xc2, 0xbc, 0x4c,
+	0x1f, 0x0b, 0x2c, 0xcb, 0x34, 0xa5, 0xda, 0x6e, 0x25, 0xc4, 0x60, 0xb1, 0x5f, 0x17, 0x1e, 0xfd,
+	0x54, 0xc6, 0x8e, 0x81, 0x6d, 0x7e, 0x6b, 0x75, 0x30, 0x61, 0xbc, 0xe8, 0x06, 0xcc, 0xef, 0x10,
+	0xa7, 0x15, 0x7a, 0x97, 0x79, 0x73, 0xc8, 0x87, 0xaa, 0xcc, 0xe1, 0x9f, 0xc2, 0x42, 0xc2, 0x7c,
+	0x83, 0xc4, 0x2c, 0xc3, 0xfe, 0x19, 0x34, 0x0a, 0xd8, 0x8b, 0x3c, 0x70, 0x46, 0xc0, 0x7b, 0x30,
+	0x7a, 0x48, 0xae, 0x84, 0xcb, 0x40, 0xe9, 0x82, 0xd7, 0x4a, 0x03, 0xb8, 0x84, 0x9e, 0x70, 0x67,
+	0x2a, 0xfd, 0xce, 0x31, 0x0d, 0x5a, 0x84, 0x31, 0xcf, 0x3f, 0x35, 0x72, 0x44, 0x92, 0x7f, 0x06,
+	0x13, 0x11, 0xc7, 0x2e, 0xa5, 0x01, 0x1d, 0x44, 0x1c, 0x19, 0x63, 0xf1, 0x5e, 0x12, 0xe2, 0xd1,
+	0xe8, 0xa3, 0x02, 0x9a, 0x8e, 0x3d, 0x5e, 0xc6, 0xba, 0xa2, 0x8d, 0xff, 0x0e, 0xee, 0xf5, 0xf9,
+	0x30, 0x82, 0xde, 0x4b, 0x67, 0x98, 0xc5, 0x1f, 0x3c, 0x2c, 0x94, 0x53, 0x26, 0x77, 0x21, 0x5f,
+	0x8a, 0xcc, 0xd5, 0x24, 0x37, 0xca, 0x5c, 0x6f, 0x2d, 0x52, 0xa6, 0xe8, 0xda, 0x17, 0x13, 0x74,
+	0x2f, 0x2d, 0x2c, 0xf3, 0x1d, 0x25, 0x7b, 0xde, 0x3d, 0x98, 0xc9, 0x7d, 0x27, 0x41, 0x8b, 0x4a,
+	0xc0, 0x6d, 0x36, 0xf2, 0x0a, 0x1a, 0x45, 0x5f, 0x0f, 0x64, 0xce, 0x39, 0xe0, 0xdb, 0x82, 0x65,
+	0xb2, 0x3f, 0x26, 0x5c, 0xd7, 0x4c, 0xae, 0x33, 0x2f, 0x77, 0x58, 0xd4, 0xb0, 0xcf, 0x1a, 0xc0,
+	0x33, 0x98, 0xdd, 0x74, 0xdd, 0x7c, 0x87, 0xdd, 0xdc, 0x48, 0xb6, 0xcc, 0x68, 0x5c, 0x42, 0x07,
+	0xb0, 0x50, 0xd0, 0x24, 0x96, 0xe5, 0x4c, 0xff, 0x0e, 0x72, 0x76, 0x57, 0xbf, 0x82, 0x49, 0xfd,
+	0xb3, 0x86, 0xf4, 0x63, 0xc6, 0x4f, 0x1d, 0x59, 0xde, 0xd7, 0x60, 0x71, 0xa7, 0x56, 0xd0, 0x55,
+	0xe9, 0xd7, 0x5f, 0xb0, 0xfa, 0x4d, 0xca, 0xfa, 0xa1, 0x6f, 0x0b, 0x01, 0x29, 0x57, 0x37, 0xb8,
+	0xcb, 0x90, 0xdd, 0xf3, 0x3a, 0x4c, 0x6d, 0xba, 0xae, 0x56, 0xf2, 0xe7, 0xaa, 0xe7, 0x2c, 0xcf,
+	0x47, 0x30, 0xb3, 0xcd, 0x4b, 0xf1, 0x01, 0x5c, 0xd9, 0xbc, 0x12, 0xc9, 0xfd, 0x69, 0x5c, 0x37,
+	0x70, 0x8f, 0x5b, 0x23, 0xdf, 0xd4, 0xc4, 0x3f, 0x60, 0xff, 0x1b, 0x00, 0x61, 0xa1, 0x98, 0x4e,
+	0x32, 0x26, 0x00, 0x00,
 }
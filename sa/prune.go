@@ -0,0 +1,223 @@
+package sa
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/ratelimit"
+	"github.com/letsencrypt/boulder/reloader"
+)
+
+// PruneConfig configures deleting rows of the fqdnSets and issuedNames tables
+// that no rate limit counts any more. Those tables get a row per issued
+// certificate (and per name, for issuedNames), so without pruning they grow
+// forever.
+type PruneConfig struct {
+	// RateLimitPoliciesFile is the rate limit policies file the RA enforces.
+	// issuedNames rows are kept for the certificatesPerName window, and
+	// fqdnSets rows for the certificatesPerFQDNSet window. The file is
+	// reloaded whenever it changes, so that a longer window takes effect
+	// before rows it would count are deleted. A table whose limit has no
+	// window isn't pruned.
+	RateLimitPoliciesFile string
+	// MinRetention, if longer than a table's window, is how long its rows are
+	// kept instead, e.g. so that certificate searches by name reach further
+	// back. It's required, and must be at least MaxCertificateLifetime, so
+	// that the rows of every unexpired certificate are kept for the renewal
+	// checks of PreviousCertificateExists and FQDNSetExists.
+	MinRetention time.Duration
+	// MaxCertificateLifetime is the longest lifetime of any certificate the
+	// CA issues.
+	MaxCertificateLifetime time.Duration
+	// Interval is how long to wait between pruning passes.
+	Interval time.Duration
+	// BatchSize is the most rows read or deleted by a single query, bounding
+	// how long each query holds locks.
+	BatchSize int
+}
+
+// pruneTable is a table the pruner deletes rows from.
+type pruneTable struct {
+	name string
+	// column is the time the retention is measured from. fqdnSets rows are
+	// pruned by expiry rather than issuance, so that renewals of unexpired
+	// certificates are still recognized.
+	column string
+	// window returns the window of the rate limit counting the table's rows.
+	window func(ratelimit.Limits) time.Duration
+}
+
+var pruneTables = []pruneTable{
+	{
+		name:   "issuedNames",
+		column: "notBefore",
+		window: func(l ratelimit.Limits) time.Duration { return l.CertificatesPerName().Window.Duration },
+	},
+	{
+		name:   "fqdnSets",
+		column: "expires",
+		window: func(l ratelimit.Limits) time.Duration { return l.CertificatesPerFQDNSet().Window.Duration },
+	},
+}
+
+// pruneRow is the ID and retention time of a row considered for pruning.
+type pruneRow struct {
+	ID   int64     `db:"id"`
+	Time time.Time `db:"t"`
+}
+
+// pruner deletes rows of pruneTables older than their retention.
+type pruner struct {
+	dbMap  dbSelectExecer
+	clk    clock.Clock
+	log    blog.Logger
+	limits ratelimit.Limits
+	config PruneConfig
+
+	pruned           *prometheus.CounterVec
+	retentionSeconds *prometheus.GaugeVec
+}
+
+// retention returns how long the rows of t are kept, or zero if they aren't
+// pruned.
+func (p *pruner) retention(t pruneTable) time.Duration {
+	window := t.window(p.limits)
+	if window <= 0 {
+		return 0
+	}
+	if p.config.MinRetention > window {
+		return p.config.MinRetention
+	}
+	return window
+}
+
+// prune deletes the rows of t older than its retention and returns how many
+// were deleted. IDs increase with insertion time, so the table is walked in ID
+// order from its oldest row, a batch at a time, stopping at the first batch
+// with nothing to delete. That keeps each pass from scanning the rows that are
+// still needed, at the cost of leaving the odd old row inserted out of order
+// for a later pass.
+func (p *pruner) prune(t pruneTable) (int64, error) {
+	retention := p.retention(t)
+	p.retentionSeconds.WithLabelValues(t.name).Set(retention.Seconds())
+	if retention == 0 {
+		return 0, nil
+	}
+	cutoff := p.clk.Now().Add(-retention)
+	query := fmt.Sprintf("SELECT id, %s AS t FROM %s WHERE id > ? ORDER BY id LIMIT ?", t.column, t.name)
+
+	var total int64
+	var lastID int64
+	for {
+		var rows []pruneRow
+		_, err := p.dbMap.Select(&rows, query, lastID, p.config.BatchSize)
+		if err != nil {
+			return total, err
+		}
+		var ids []interface{}
+		for _, r := range rows {
+			if r.Time.Before(cutoff) {
+				ids = append(ids, r.ID)
+			}
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+		qmarks := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+		result, err := p.dbMap.Exec(fmt.Sprintf("DELETE FROM %s WHERE id IN (%s)", t.name, qmarks), ids...)
+		if err != nil {
+			return total, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+		p.pruned.WithLabelValues(t.name).Add(float64(n))
+		if len(rows) < p.config.BatchSize {
+			return total, nil
+		}
+		lastID = rows[len(rows)-1].ID
+	}
+}
+
+// loop prunes every table once per interval, forever.
+func (p *pruner) loop() {
+	for {
+		for _, t := range pruneTables {
+			n, err := p.prune(t)
+			if err != nil {
+				p.log.Errf("Pruning %s failed after deleting %d rows: %s", t.name, n, err)
+				continue
+			}
+			p.log.Infof("Pruned %d rows from %s", n, t.name)
+		}
+		p.clk.Sleep(p.config.Interval)
+	}
+}
+
+// validate checks that c is a usable pruning config.
+func (c PruneConfig) validate() error {
+	if c.RateLimitPoliciesFile == "" {
+		return errors.New("pruning needs a RateLimitPoliciesFile")
+	}
+	if c.Interval <= 0 || c.BatchSize <= 0 {
+		return errors.New("pruning needs a positive Interval and BatchSize")
+	}
+	if c.MaxCertificateLifetime <= 0 {
+		return errors.New("pruning needs a positive MaxCertificateLifetime")
+	}
+	if c.MinRetention < c.MaxCertificateLifetime {
+		return fmt.Errorf("pruning MinRetention (%s) must be at least MaxCertificateLifetime (%s)",
+			c.MinRetention, c.MaxCertificateLifetime)
+	}
+	return nil
+}
+
+// StartPruning loads the rate limit policies and starts deleting the rows of
+// the fqdnSets and issuedNames tables that fall outside the windows of the
+// limits counting them, as configured. Once pruned, rows are also gone from
+// certificate searches by name and from the renewal checks of
+// PreviousCertificateExists and FQDNSetExists, which is what MinRetention is
+// for. Pruning is idempotent, but it's only worth running on one SA instance.
+func (ssa *SQLStorageAuthority) StartPruning(config PruneConfig) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+	limits := ratelimit.New()
+	err := reloader.Register(reloader.Section{
+		Name: "SA pruning rate limit policies",
+		File: config.RateLimitPoliciesFile,
+		Load: limits.LoadPolicies,
+	})
+	if err != nil {
+		return err
+	}
+	pruned := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sa_pruned_rows",
+		Help: "Number of rows deleted from tables only needed for rate limits once they fall outside the window, by table",
+	}, []string{"table"})
+	retention := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sa_prune_retention_seconds",
+		Help: "How long rows of tables only needed for rate limits are kept before they're pruned, by table. Zero if the table isn't pruned",
+	}, []string{"table"})
+	ssa.scope.MustRegister(pruned)
+	ssa.scope.MustRegister(retention)
+	p := &pruner{
+		dbMap:            ssa.dbMap,
+		clk:              ssa.clk,
+		log:              ssa.log,
+		limits:           limits,
+		config:           config,
+		pruned:           pruned,
+		retentionSeconds: retention,
+	}
+	go p.loop()
+	return nil
+}
@@ -0,0 +1,153 @@
+package sa
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/ratelimit"
+	"github.com/letsencrypt/boulder/test"
+)
+
+// fakePruneDB is a table of pruneRows supporting the queries the pruner
+// makes, counting the rows it's asked to read.
+type fakePruneDB struct {
+	rows []pruneRow
+	read int
+}
+
+func (db *fakePruneDB) Select(holder interface{}, query string, args ...interface{}) ([]interface{}, error) {
+	after, limit := args[0].(int64), args[1].(int)
+	rows := holder.(*[]pruneRow)
+	for _, r := range db.rows {
+		if r.ID > after && len(*rows) < limit {
+			*rows = append(*rows, r)
+		}
+	}
+	db.read += len(*rows)
+	return nil, nil
+}
+
+func (db *fakePruneDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	if !strings.HasPrefix(query, "DELETE FROM issuedNames WHERE id IN (") {
+		return nil, sql.ErrNoRows
+	}
+	deleted := map[int64]bool{}
+	for _, arg := range args {
+		deleted[arg.(int64)] = true
+	}
+	var kept []pruneRow
+	for _, r := range db.rows {
+		if !deleted[r.ID] {
+			kept = append(kept, r)
+		}
+	}
+	n := int64(len(db.rows) - len(kept))
+	db.rows = kept
+	return driverResult(n), nil
+}
+
+type driverResult int64
+
+func (r driverResult) LastInsertId() (int64, error) { return 0, nil }
+func (r driverResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+func newTestPruner(t *testing.T, db dbSelectExecer, clk clock.Clock, policies string) *pruner {
+	limits := ratelimit.New()
+	err := limits.LoadPolicies([]byte(policies))
+	test.AssertNotError(t, err, "Couldn't load rate limit policies")
+	return &pruner{
+		dbMap:  db,
+		clk:    clk,
+		limits: limits,
+		config: PruneConfig{MinRetention: 48 * time.Hour, BatchSize: 3},
+		pruned: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "pruned"}, []string{"table"}),
+		retentionSeconds: prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "retention"},
+			[]string{"table"}),
+	}
+}
+
+func TestPruneRetention(t *testing.T) {
+	p := newTestPruner(t, nil, clock.NewFake(), `
+certificatesPerName:
+  window: 2160h
+certificatesPerFQDNSet:
+  window: 24h
+`)
+	issuedNames, fqdnSets := pruneTables[0], pruneTables[1]
+	test.AssertEquals(t, p.retention(issuedNames), 2160*time.Hour)
+	// MinRetention applies to windows shorter than it
+	test.AssertEquals(t, p.retention(fqdnSets), 48*time.Hour)
+
+	// A table whose limit has no window isn't pruned
+	p = newTestPruner(t, nil, clock.NewFake(), "certificatesPerName:\n  window: 24h\n")
+	test.AssertEquals(t, p.retention(fqdnSets), time.Duration(0))
+	n, err := p.prune(fqdnSets)
+	test.AssertNotError(t, err, "prune failed")
+	test.AssertEquals(t, n, int64(0))
+}
+
+func TestPruneConfigValidate(t *testing.T) {
+	valid := PruneConfig{
+		RateLimitPoliciesFile:  "../test/rate-limit-policies.yml",
+		MinRetention:           2160 * time.Hour,
+		MaxCertificateLifetime: 2160 * time.Hour,
+		Interval:               time.Hour,
+		BatchSize:              1000,
+	}
+	test.AssertNotError(t, valid.validate(), "valid config was rejected")
+
+	noMinRetention := valid
+	noMinRetention.MinRetention = 0
+	shortRetention := valid
+	shortRetention.MinRetention = 2159 * time.Hour
+	noLifetime := valid
+	noLifetime.MaxCertificateLifetime = 0
+	noPolicies := valid
+	noPolicies.RateLimitPoliciesFile = ""
+	noBatchSize := valid
+	noBatchSize.BatchSize = 0
+	for _, c := range []PruneConfig{noMinRetention, shortRetention, noLifetime, noPolicies, noBatchSize} {
+		test.AssertError(t, c.validate(), "invalid config was accepted")
+	}
+
+	// StartPruning refuses an invalid config before doing anything
+	ssa := &SQLStorageAuthority{}
+	test.AssertError(t, ssa.StartPruning(shortRetention), "StartPruning accepted MinRetention shorter than MaxCertificateLifetime")
+}
+
+func TestPrune(t *testing.T) {
+	fc := clock.NewFake()
+	fc.Set(time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC))
+	db := &fakePruneDB{}
+	// Ten rows a day apart, except row 3, which was inserted out of order.
+	for i := 1; i <= 10; i++ {
+		db.rows = append(db.rows, pruneRow{ID: int64(i), Time: fc.Now().Add(time.Duration(i-11) * 24 * time.Hour)})
+	}
+	db.rows[2].Time = fc.Now()
+	p := newTestPruner(t, db, fc, "certificatesPerName:\n  window: 72h\n")
+
+	n, err := p.prune(pruneTables[0])
+	test.AssertNotError(t, err, "prune failed")
+	// Rows 1, 2 and 4 to 7 are older than three days. The walk stops at the
+	// first batch with none of those, which only has row 10.
+	test.AssertEquals(t, n, int64(6))
+	var ids []int64
+	for _, r := range db.rows {
+		ids = append(ids, r.ID)
+	}
+	test.AssertDeepEquals(t, ids, []int64{3, 8, 9, 10})
+	test.AssertEquals(t, db.read, 10)
+	test.AssertEquals(t, test.CountCounterVec("table", "issuedNames", p.pruned), 6)
+
+	// A pass with nothing to prune reads a single batch
+	db.read = 0
+	n, err = p.prune(pruneTables[0])
+	test.AssertNotError(t, err, "prune failed")
+	test.AssertEquals(t, n, int64(0))
+	test.AssertEquals(t, db.read, 3)
+}
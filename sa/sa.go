@@ -24,6 +24,7 @@ import (
 	bgrpc "github.com/letsencrypt/boulder/grpc"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/ratelimit"
 	"github.com/letsencrypt/boulder/revocation"
 	sapb "github.com/letsencrypt/boulder/sa/proto"
 )
@@ -47,6 +48,14 @@ type SQLStorageAuthority struct {
 	// unittests.
 	countCertificatesByName certCountFunc
 	getChallenges           getChallengesFunc
+
+	// readCache, if set, caches issued certificates and finalized
+	// authorizations. See SetReadCache.
+	readCache *readCache
+
+	// txRetrier, if set, runs write transactions again when they deadlock.
+	// See SetTransactionRetries.
+	txRetrier *txRetrier
 }
 
 func digest256(data []byte) []byte {
@@ -139,7 +148,7 @@ func updateChallenges(db dbSelectExecer, authID string, challenges []core.Challe
 	var challs []challModel
 	_, err := db.Select(
 		&challs,
-		getChallengesQuery,
+		getChallengesQuery(),
 		map[string]interface{}{"authID": authID},
 	)
 	if err != nil {
@@ -157,17 +166,17 @@ func updateChallenges(db dbSelectExecer, authID string, challenges []core.Challe
 			return err
 		}
 		chall.ID = challs[i].ID
-		_, err = db.Exec(
-			`UPDATE challenges SET
-				status = ?,
-				error = ?,
-				validationRecord = ?
-			WHERE status = ? AND id = ?`,
-			string(chall.Status),
-			chall.Error,
-			chall.ValidationRecord,
-			string(core.StatusPending),
-			chall.ID)
+		query := "UPDATE challenges SET status = ?, error = ?, validationRecord = ?"
+		args := []interface{}{string(chall.Status), chall.Error, chall.ValidationRecord}
+		if features.Enabled(features.StoreChallengeAttempts) {
+			query += ", attempts = ?"
+			args = append(args, chall.Attempts)
+		}
+		// A challenge whose validation is being rechecked is processing, and
+		// can still be updated until it's final.
+		query += " WHERE status IN (?, ?) AND id = ?"
+		args = append(args, string(core.StatusPending), string(core.StatusProcessing), chall.ID)
+		_, err = db.Exec(query, args...)
 		if err != nil {
 			return err
 		}
@@ -178,7 +187,7 @@ func updateChallenges(db dbSelectExecer, authID string, challenges []core.Challe
 // GetRegistration obtains a Registration by ID
 func (ssa *SQLStorageAuthority) GetRegistration(ctx context.Context, id int64) (core.Registration, error) {
 	const query = "WHERE id = ?"
-	model, err := selectRegistration(ssa.dbMap.WithContext(ctx), query, id)
+	model, err := selectRegistration(ssa.db(ctx), query, id)
 	if err == sql.ErrNoRows {
 		return core.Registration{}, berrors.NotFoundError("registration with ID '%d' not found", id)
 	}
@@ -198,7 +207,7 @@ func (ssa *SQLStorageAuthority) GetRegistrationByKey(ctx context.Context, key *j
 	if err != nil {
 		return core.Registration{}, err
 	}
-	model, err := selectRegistration(ssa.dbMap.WithContext(ctx), query, sha)
+	model, err := selectRegistration(ssa.db(ctx), query, sha)
 	if err == sql.ErrNoRows {
 		return core.Registration{}, berrors.NotFoundError("no registrations with public key sha256 %q", sha)
 	}
@@ -211,7 +220,11 @@ func (ssa *SQLStorageAuthority) GetRegistrationByKey(ctx context.Context, key *j
 
 // GetAuthorization obtains an Authorization by ID
 func (ssa *SQLStorageAuthority) GetAuthorization(ctx context.Context, id string) (core.Authorization, error) {
+	if authz, ok := ssa.readCache.getAuthorization(id, ssa.clk.Now()); ok {
+		return authz, nil
+	}
 	authz := core.Authorization{}
+	final := false
 	tx, err := ssa.dbMap.Begin()
 	if err != nil {
 		return authz, err
@@ -224,7 +237,7 @@ func (ssa *SQLStorageAuthority) GetAuthorization(ctx context.Context, id string)
 	}
 	if err == sql.ErrNoRows {
 		var fa authzModel
-		err := txWithCtx.SelectOne(&fa, fmt.Sprintf("SELECT %s FROM authz WHERE id = ?", authzFields), id)
+		err := txWithCtx.SelectOne(&fa, fmt.Sprintf("SELECT %s FROM authz WHERE id = ?", authzFieldsFor(authorizationTable)), id)
 		if err != nil && err != sql.ErrNoRows {
 			return authz, Rollback(tx, err)
 		} else if err == sql.ErrNoRows {
@@ -236,6 +249,7 @@ func (ssa *SQLStorageAuthority) GetAuthorization(ctx context.Context, id string)
 				berrors.NotFoundError("no authorization found with id %q", id))
 		}
 		authz = fa.Authorization
+		final = true
 	} else {
 		authz = pa.Authorization
 	}
@@ -245,7 +259,17 @@ func (ssa *SQLStorageAuthority) GetAuthorization(ctx context.Context, id string)
 		return authz, Rollback(tx, err)
 	}
 
-	return authz, tx.Commit()
+	err = tx.Commit()
+	if err != nil {
+		return authz, err
+	}
+	// Finalized authorizations only change if they're deactivated or revoked,
+	// which removes them from the cache, or retried, which only invalid ones
+	// can be and which addAuthorization doesn't cache
+	if final {
+		ssa.readCache.addAuthorization(authz, ssa.clk.Now())
+	}
+	return authz, nil
 }
 
 // GetValidAuthorizations returns the latest authorization object for all
@@ -315,7 +339,7 @@ func ipRange(ip net.IP) (net.IP, net.IP) {
 // time range for a single IP address.
 func (ssa *SQLStorageAuthority) CountRegistrationsByIP(ctx context.Context, ip net.IP, earliest time.Time, latest time.Time) (int, error) {
 	var count int64
-	err := ssa.dbMap.WithContext(ctx).SelectOne(
+	err := ssa.db(ctx).SelectOne(
 		&count,
 		`SELECT COUNT(1) FROM registrations
 		 WHERE
@@ -340,7 +364,7 @@ func (ssa *SQLStorageAuthority) CountRegistrationsByIP(ctx context.Context, ip n
 func (ssa *SQLStorageAuthority) CountRegistrationsByIPRange(ctx context.Context, ip net.IP, earliest time.Time, latest time.Time) (int, error) {
 	var count int64
 	beginIP, endIP := ipRange(ip)
-	err := ssa.dbMap.WithContext(ctx).SelectOne(
+	err := ssa.db(ctx).SelectOne(
 		&count,
 		`SELECT COUNT(1) FROM registrations
 		 WHERE
@@ -396,7 +420,7 @@ func (ssa *SQLStorageAuthority) CountCertificatesByNames(ctx context.Context, do
 				default:
 				}
 				currentCount, err := ssa.countCertificatesByName(
-					ssa.dbMap.WithContext(ctx), domain, earliest, latest)
+					ssa.db(ctx), domain, earliest, latest)
 				if err != nil {
 					results <- result{err: err}
 					// Skip any further work
@@ -431,7 +455,7 @@ func (ssa *SQLStorageAuthority) CountCertificatesByExactNames(ctx context.Contex
 	var ret []*sapb.CountByNames_MapElement
 	for _, domain := range domains {
 		currentCount, err := ssa.countCertificatesByExactName(
-			ssa.dbMap.WithContext(ctx), domain, earliest, latest)
+			ssa.db(ctx), domain, earliest, latest)
 		if err != nil {
 			return ret, err
 		}
@@ -551,13 +575,17 @@ func (ssa *SQLStorageAuthority) GetCertificate(ctx context.Context, serial strin
 		return core.Certificate{}, err
 	}
 
-	cert, err := SelectCertificate(ssa.dbMap.WithContext(ctx), "WHERE serial = ?", serial)
+	if cert, ok := ssa.readCache.getCertificate(serial, ssa.clk.Now()); ok {
+		return cert, nil
+	}
+	cert, err := SelectCertificate(ssa.db(ctx), "WHERE serial = ?", serial)
 	if err == sql.ErrNoRows {
 		return core.Certificate{}, berrors.NotFoundError("certificate with serial %q not found", serial)
 	}
 	if err != nil {
 		return core.Certificate{}, err
 	}
+	ssa.readCache.addCertificate(cert)
 	return cert, err
 }
 
@@ -571,7 +599,7 @@ func (ssa *SQLStorageAuthority) GetCertificateStatus(ctx context.Context, serial
 	}
 
 	var status core.CertificateStatus
-	statusObj, err := ssa.dbMap.WithContext(ctx).Get(certStatusModel{}, serial)
+	statusObj, err := ssa.db(ctx).Get(certStatusModel{}, serial)
 	if err != nil {
 		return status, err
 	}
@@ -601,7 +629,7 @@ func (ssa *SQLStorageAuthority) NewRegistration(ctx context.Context, reg core.Re
 	if err != nil {
 		return reg, err
 	}
-	err = ssa.dbMap.WithContext(ctx).Insert(rm)
+	err = ssa.db(ctx).Insert(rm)
 	if err != nil {
 		return reg, err
 	}
@@ -612,6 +640,7 @@ func (ssa *SQLStorageAuthority) NewRegistration(ctx context.Context, reg core.Re
 // with a timestamp and a reason.
 // TODO(#4048): This method has been deprecated and replaced by RevokeCertificate.
 func (ssa *SQLStorageAuthority) MarkCertificateRevoked(ctx context.Context, serial string, reasonCode revocation.Reason) error {
+	defer ssa.readCache.removeCertificate(serial)
 	var err error
 	if _, err = ssa.GetCertificate(ctx, serial); err != nil {
 		return fmt.Errorf(
@@ -623,47 +652,37 @@ func (ssa *SQLStorageAuthority) MarkCertificateRevoked(ctx context.Context, seri
 			"Unable to mark certificate %s revoked: cert status not found.", serial)
 	}
 
-	tx, err := ssa.dbMap.Begin()
-	if err != nil {
-		return err
-	}
-	txWithCtx := tx.WithContext(ctx)
-
-	const statusQuery = "WHERE serial = ?"
-	statusObj, err := SelectCertificateStatus(txWithCtx, statusQuery, serial)
-	if err == sql.ErrNoRows {
-		err = fmt.Errorf("No certificate with serial %s", serial)
-		err = Rollback(tx, err)
-		return err
-	}
-	if err != nil {
-		err = Rollback(tx, err)
-		return err
-	}
+	return ssa.withTransaction(ctx, "MarkCertificateRevoked", func(txWithCtx gorp.SqlExecutor) error {
+		const statusQuery = "WHERE serial = ?"
+		statusObj, err := SelectCertificateStatus(txWithCtx, statusQuery, serial)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("No certificate with serial %s", serial)
+		}
+		if err != nil {
+			return err
+		}
 
-	var n int64
-	now := ssa.clk.Now()
-	statusObj.Status = core.OCSPStatusRevoked
-	statusObj.RevokedDate = now
-	statusObj.RevokedReason = reasonCode
-	n, err = tx.Update(&statusObj)
-	if err != nil {
-		err = Rollback(tx, err)
-		return err
-	}
-	if n == 0 {
-		err = berrors.InternalServerError("no certificate updated")
-		err = Rollback(tx, err)
-		return err
-	}
+		var n int64
+		now := ssa.clk.Now()
+		statusObj.Status = core.OCSPStatusRevoked
+		statusObj.RevokedDate = now
+		statusObj.RevokedReason = reasonCode
+		n, err = txWithCtx.Update(&statusObj)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return berrors.InternalServerError("no certificate updated")
+		}
 
-	return tx.Commit()
+		return nil
+	})
 }
 
 // UpdateRegistration stores an updated Registration
 func (ssa *SQLStorageAuthority) UpdateRegistration(ctx context.Context, reg core.Registration) error {
 	const query = "WHERE id = ?"
-	model, err := selectRegistration(ssa.dbMap.WithContext(ctx), query, reg.ID)
+	model, err := selectRegistration(ssa.db(ctx), query, reg.ID)
 	if err == sql.ErrNoRows {
 		return berrors.NotFoundError("registration with ID '%d' not found", reg.ID)
 	}
@@ -676,7 +695,7 @@ func (ssa *SQLStorageAuthority) UpdateRegistration(ctx context.Context, reg core
 	// Copy the existing registration model's LockCol to the new updated
 	// registration model's LockCol
 	updatedRegModel.LockCol = model.LockCol
-	n, err := ssa.dbMap.WithContext(ctx).Update(updatedRegModel)
+	n, err := ssa.db(ctx).Update(updatedRegModel)
 	if err != nil {
 		return err
 	}
@@ -691,56 +710,51 @@ func (ssa *SQLStorageAuthority) UpdateRegistration(ctx context.Context, reg core
 // authz.Identifier if one exists, or creates a new one otherwise.
 func (ssa *SQLStorageAuthority) NewPendingAuthorization(ctx context.Context, authz core.Authorization) (core.Authorization, error) {
 	var output core.Authorization
-
-	tx, err := ssa.dbMap.Begin()
-	if err != nil {
-		return output, err
-	}
-	txWithCtx := tx.WithContext(ctx)
-
-	// Create a random ID and check that it doesn't exist already
-	authz.ID = core.NewToken()
-	for existingPending(txWithCtx, authz.ID) ||
-		existingFinal(txWithCtx, authz.ID) {
+	var pendingAuthz pendingauthzModel
+	err := ssa.withTransaction(ctx, "NewPendingAuthorization", func(txWithCtx gorp.SqlExecutor) error {
+		// Create a random ID and check that it doesn't exist already
 		authz.ID = core.NewToken()
-	}
-
-	// Insert a stub row in pending
-	pendingAuthz := pendingauthzModel{Authorization: authz}
-	err = txWithCtx.Insert(&pendingAuthz)
-	if err != nil {
-		err = Rollback(tx, err)
-		return output, err
-	}
+		for existingPending(txWithCtx, authz.ID) ||
+			existingFinal(txWithCtx, authz.ID) {
+			authz.ID = core.NewToken()
+		}
 
-	for i, c := range authz.Challenges {
-		challModel, err := challengeToModel(&c, pendingAuthz.ID)
-		if err != nil {
-			err = Rollback(tx, err)
-			return output, err
-		}
-		// Magic happens here: Gorp will modify challModel, setting challModel.ID
-		// to the auto-increment primary key. This is important because we want
-		// the challenge objects inside the Authorization we return to know their
-		// IDs, so they can have proper URLs.
-		// See https://godoc.org/github.com/coopernurse/gorp#DbMap.Insert
-		err = txWithCtx.Insert(challModel)
+		// Insert a stub row in pending
+		pendingAuthz = pendingauthzModel{Authorization: authz}
+		err := txWithCtx.Insert(&pendingAuthz)
 		if err != nil {
-			err = Rollback(tx, err)
-			return output, err
+			return err
 		}
-		challenge, err := modelToChallenge(challModel)
-		if err != nil {
-			err = Rollback(tx, err)
-			return output, err
+
+		for i, c := range authz.Challenges {
+			challModel, err := challengeToModel(&c, pendingAuthz.ID)
+			if err != nil {
+				return err
+			}
+			// Magic happens here: Gorp will modify challModel, setting challModel.ID
+			// to the auto-increment primary key. This is important because we want
+			// the challenge objects inside the Authorization we return to know their
+			// IDs, so they can have proper URLs.
+			// See https://godoc.org/github.com/coopernurse/gorp#DbMap.Insert
+			err = txWithCtx.Insert(challModel)
+			if err != nil {
+				return err
+			}
+			challenge, err := modelToChallenge(challModel)
+			if err != nil {
+				return err
+			}
+			authz.Challenges[i] = challenge
 		}
-		authz.Challenges[i] = challenge
-	}
 
-	err = tx.Commit()
+		return nil
+	})
+	if err != nil {
+		return output, err
+	}
 	output = pendingAuthz.Authorization
 	output.Challenges = authz.Challenges
-	return output, err
+	return output, nil
 }
 
 // GetPendingAuthorization returns the most recent Pending authorization
@@ -761,7 +775,7 @@ func (ssa *SQLStorageAuthority) GetPendingAuthorization(
 	// keep the amount of scanning to a minimum. That index does not include the
 	// identifier, so accounts with huge numbers of pending authzs may result in
 	// slow queries here.
-	pa, err := selectPendingAuthz(ssa.dbMap.WithContext(ctx),
+	pa, err := selectPendingAuthz(ssa.db(ctx),
 		`WHERE registrationID = :regID
 			 AND identifier = :identifierJSON
 			 AND status = :status
@@ -794,94 +808,90 @@ func (ssa *SQLStorageAuthority) GetPendingAuthorization(
 // reasons) may indicate, the pending authorization table row is not changed,
 // only the associated challenges by way of `sa.updateChallenges`.
 func (ssa *SQLStorageAuthority) UpdatePendingAuthorization(ctx context.Context, authz core.Authorization) error {
-	tx, err := ssa.dbMap.Begin()
-	if err != nil {
-		return err
-	}
-	txWithCtx := tx.WithContext(ctx)
-
-	if !statusIsPending(authz.Status) {
-		err = berrors.WrongAuthorizationStateError("authorization is not pending")
-		return Rollback(tx, err)
-	}
+	return ssa.withTransaction(ctx, "UpdatePendingAuthorization", func(txWithCtx gorp.SqlExecutor) error {
+		if !statusIsPending(authz.Status) {
+			return berrors.WrongAuthorizationStateError("authorization is not pending")
+		}
 
-	if existingFinal(txWithCtx, authz.ID) {
-		err = berrors.WrongAuthorizationStateError("cannot update a finalized authorization")
-		return Rollback(tx, err)
-	}
+		if existingFinal(txWithCtx, authz.ID) {
+			return berrors.WrongAuthorizationStateError("cannot update a finalized authorization")
+		}
 
-	if !existingPending(txWithCtx, authz.ID) {
-		err = berrors.InternalServerError("authorization with ID '%s' not found", authz.ID)
-		return Rollback(tx, err)
-	}
+		if !existingPending(txWithCtx, authz.ID) {
+			return berrors.InternalServerError("authorization with ID '%s' not found", authz.ID)
+		}
 
-	_, err = selectPendingAuthz(txWithCtx, "WHERE id = ?", authz.ID)
-	if err == sql.ErrNoRows {
-		err = berrors.InternalServerError("authorization with ID '%s' not found", authz.ID)
-		return Rollback(tx, err)
-	}
-	if err != nil {
-		return Rollback(tx, err)
-	}
+		_, err := selectPendingAuthz(txWithCtx, "WHERE id = ?", authz.ID)
+		if err == sql.ErrNoRows {
+			return berrors.InternalServerError("authorization with ID '%s' not found", authz.ID)
+		}
+		if err != nil {
+			return err
+		}
 
-	err = updateChallenges(txWithCtx, authz.ID, authz.Challenges)
-	if err != nil {
-		return Rollback(tx, err)
-	}
+		err = updateChallenges(txWithCtx, authz.ID, authz.Challenges)
+		if err != nil {
+			return err
+		}
 
-	return tx.Commit()
+		return nil
+	})
 }
 
 // FinalizeAuthorization converts a Pending Authorization to a final one. If the
 // Authorization is not found a berrors.NotFound result is returned. If the
 // Authorization is status pending a berrors.InternalServer error is returned.
 func (ssa *SQLStorageAuthority) FinalizeAuthorization(ctx context.Context, authz core.Authorization) error {
-	tx, err := ssa.dbMap.Begin()
-	if err != nil {
-		return err
-	}
-	txWithCtx := tx.WithContext(ctx)
+	return ssa.withTransaction(ctx, "FinalizeAuthorization", func(txWithCtx gorp.SqlExecutor) error {
+		// Check that a pending authz exists
+		if !existingPending(txWithCtx, authz.ID) {
+			return berrors.NotFoundError("authorization with ID %q not found", authz.ID)
+		}
+		if statusIsPending(authz.Status) {
+			return berrors.InternalServerError("authorization to finalize is pending (ID %q)", authz.ID)
+		}
 
-	// Check that a pending authz exists
-	if !existingPending(txWithCtx, authz.ID) {
-		err = berrors.NotFoundError("authorization with ID %q not found", authz.ID)
-		return Rollback(tx, err)
-	}
-	if statusIsPending(authz.Status) {
-		err = berrors.InternalServerError("authorization to finalize is pending (ID %q)", authz.ID)
-		return Rollback(tx, err)
-	}
+		auth := &authzModel{authz}
+		pa, err := selectPendingAuthz(txWithCtx, "WHERE id = ?", authz.ID)
+		if err == sql.ErrNoRows {
+			return berrors.NotFoundError("authorization with ID %q not found", authz.ID)
+		}
+		if err != nil {
+			return err
+		}
 
-	auth := &authzModel{authz}
-	pa, err := selectPendingAuthz(txWithCtx, "WHERE id = ?", authz.ID)
-	if err == sql.ErrNoRows {
-		return Rollback(tx, berrors.NotFoundError("authorization with ID %q not found", authz.ID))
-	}
-	if err != nil {
-		return Rollback(tx, err)
-	}
+		err = txWithCtx.Insert(auth)
+		if err != nil {
+			return err
+		}
 
-	err = txWithCtx.Insert(auth)
-	if err != nil {
-		return Rollback(tx, err)
-	}
+		if features.Enabled(features.StoreValidationMethod) && authz.ValidationMethod != "" {
+			_, err = txWithCtx.Exec(
+				"UPDATE authz SET validationMethod = ? WHERE id = ?",
+				authz.ValidationMethod, authz.ID)
+			if err != nil {
+				return err
+			}
+		}
 
-	_, err = txWithCtx.Delete(pa)
-	if err != nil {
-		return Rollback(tx, err)
-	}
+		_, err = txWithCtx.Delete(pa)
+		if err != nil {
+			return err
+		}
 
-	err = updateChallenges(txWithCtx, authz.ID, authz.Challenges)
-	if err != nil {
-		return Rollback(tx, err)
-	}
+		err = updateChallenges(txWithCtx, authz.ID, authz.Challenges)
+		if err != nil {
+			return err
+		}
 
-	return tx.Commit()
+		return nil
+	})
 }
 
 // RevokeAuthorizationsByDomain invalidates all pending or finalized authorizations
 // for a specific domain
 func (ssa *SQLStorageAuthority) RevokeAuthorizationsByDomain(ctx context.Context, ident core.AcmeIdentifier) (int64, int64, error) {
+	defer ssa.readCache.removeAuthorizationsFor(ident)
 	identifierJSON, err := json.Marshal(ident)
 	if err != nil {
 		return 0, 0, err
@@ -892,7 +902,7 @@ func (ssa *SQLStorageAuthority) RevokeAuthorizationsByDomain(ctx context.Context
 	now := ssa.clk.Now()
 	for i, table := range authorizationTables {
 		for {
-			authz, err := getAuthorizationIDsByDomain(ssa.dbMap.WithContext(ctx), table, identifier, now)
+			authz, err := getAuthorizationIDsByDomain(ssa.db(ctx), table, identifier, now)
 			if err != nil {
 				return results[0], results[1], err
 			}
@@ -901,7 +911,7 @@ func (ssa *SQLStorageAuthority) RevokeAuthorizationsByDomain(ctx context.Context
 				break
 			}
 
-			numRevoked, err := revokeAuthorizations(ssa.dbMap.WithContext(ctx), table, authz)
+			numRevoked, err := revokeAuthorizations(ssa.db(ctx), table, authz)
 			if err != nil {
 				return results[0], results[1], err
 			}
@@ -953,75 +963,82 @@ func (ssa *SQLStorageAuthority) AddCertificate(
 		certStatus.OCSPLastUpdated = ssa.clk.Now()
 	}
 
-	tx, err := ssa.dbMap.Begin()
-	if err != nil {
-		return "", err
-	}
-	txWithCtx := tx.WithContext(ctx)
+	err = ssa.withTransaction(ctx, "AddCertificate", func(txWithCtx gorp.SqlExecutor) error {
+		// Note: will fail on duplicate serials. Extremely unlikely to happen and soon
+		// to be fixed by redesign. Reference issue
+		// https://github.com/letsencrypt/boulder/issues/2265 for more
+		err := txWithCtx.Insert(cert)
+		if err != nil {
+			if strings.HasPrefix(err.Error(), "Error 1062: Duplicate entry") {
+				err = berrors.DuplicateError("cannot add a duplicate cert")
+			}
+			return err
+		}
 
-	// Note: will fail on duplicate serials. Extremely unlikely to happen and soon
-	// to be fixed by redesign. Reference issue
-	// https://github.com/letsencrypt/boulder/issues/2265 for more
-	err = txWithCtx.Insert(cert)
-	if err != nil {
-		if strings.HasPrefix(err.Error(), "Error 1062: Duplicate entry") {
-			err = berrors.DuplicateError("cannot add a duplicate cert")
+		err = txWithCtx.Insert(certStatus)
+		if err != nil {
+			if strings.HasPrefix(err.Error(), "Error 1062: Duplicate entry") {
+				err = berrors.DuplicateError("cannot add a duplicate cert status")
+			}
+			return err
 		}
-		return "", Rollback(tx, err)
-	}
 
-	err = txWithCtx.Insert(certStatus)
-	if err != nil {
-		if strings.HasPrefix(err.Error(), "Error 1062: Duplicate entry") {
-			err = berrors.DuplicateError("cannot add a duplicate cert status")
-		}
-		return "", Rollback(tx, err)
-	}
-
-	// If the SetIssuedNamesRenewalBit feature flag is enabled then we need to
-	// determine if the certificate being added is a renewal of a previously
-	// issued certificate in order to set the renewal bit of the issued names rows
-	// correctly with `addIssuedNames`.
-	var isRenewal bool
-	if features.Enabled(features.SetIssuedNamesRenewalBit) {
-		// NOTE(@cpu): When we collect up names to check if an FQDN set exists (e.g.
-		// that it is a renewal) we use just the DNSNames from the certificate and
-		// ignore the Subject Common Name (if any). This is a safe assumption because
-		// if a certificate we issued were to have a Subj. CN not present as a SAN it
-		// would be a misissuance and miscalculating whether the cert is a renewal or
-		// not for the purpose of rate limiting is the least of our troubles.
-		prevCertExists, err := ssa.checkFQDNSetExists(
-			txWithCtx.SelectOne,
-			parsedCertificate.DNSNames)
+		// If the SetIssuedNamesRenewalBit feature flag is enabled then we need to
+		// determine if the certificate being added is a renewal of a previously
+		// issued certificate in order to set the renewal bit of the issued names rows
+		// correctly with `addIssuedNames`.
+		var isRenewal bool
+		if features.Enabled(features.SetIssuedNamesRenewalBit) {
+			// NOTE(@cpu): When we collect up names to check if an FQDN set exists (e.g.
+			// that it is a renewal) we use just the DNSNames from the certificate and
+			// ignore the Subject Common Name (if any). This is a safe assumption because
+			// if a certificate we issued were to have a Subj. CN not present as a SAN it
+			// would be a misissuance and miscalculating whether the cert is a renewal or
+			// not for the purpose of rate limiting is the least of our troubles.
+			prevCertExists, err := ssa.checkFQDNSetExists(
+				txWithCtx.SelectOne,
+				parsedCertificate.DNSNames)
+			if err != nil {
+				return err
+			}
+			isRenewal = prevCertExists
+		}
+
+		err = addIssuedNames(txWithCtx, parsedCertificate, isRenewal)
 		if err != nil {
-			return "", Rollback(tx, err)
+			return err
 		}
-		isRenewal = prevCertExists
-	}
 
-	err = addIssuedNames(txWithCtx, parsedCertificate, isRenewal)
-	if err != nil {
-		return "", Rollback(tx, err)
-	}
+		if features.Enabled(features.StoreKeyHashes) {
+			err = addKeyHash(txWithCtx, parsedCertificate)
+			if err != nil {
+				return err
+			}
+		}
 
-	err = addFQDNSet(
-		txWithCtx,
-		parsedCertificate.DNSNames,
-		serial,
-		parsedCertificate.NotBefore,
-		parsedCertificate.NotAfter,
-	)
+		err = addFQDNSet(
+			txWithCtx,
+			parsedCertificate.DNSNames,
+			serial,
+			parsedCertificate.NotBefore,
+			parsedCertificate.NotAfter,
+		)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
 	if err != nil {
-		return "", Rollback(tx, err)
+		return "", err
 	}
-
-	return digest, tx.Commit()
+	return digest, nil
 }
 
 // CountPendingAuthorizations returns the number of pending, unexpired
 // authorizations for the given registration.
 func (ssa *SQLStorageAuthority) CountPendingAuthorizations(ctx context.Context, regID int64) (count int, err error) {
-	err = ssa.dbMap.WithContext(ctx).SelectOne(&count,
+	err = ssa.db(ctx).SelectOne(&count,
 		`SELECT count(1) FROM pendingAuthorizations
 		WHERE registrationID = :regID AND
 		expires > :now AND
@@ -1036,7 +1053,7 @@ func (ssa *SQLStorageAuthority) CountPendingAuthorizations(ctx context.Context,
 
 func (ssa *SQLStorageAuthority) CountOrders(ctx context.Context, acctID int64, earliest, latest time.Time) (int, error) {
 	var count int
-	err := ssa.dbMap.WithContext(ctx).SelectOne(&count,
+	err := ssa.db(ctx).SelectOne(&count,
 		`SELECT count(1) FROM orders
 		WHERE registrationID = :acctID AND
 		created >= :windowLeft AND
@@ -1072,7 +1089,7 @@ func (ssa *SQLStorageAuthority) CountInvalidAuthorizations(
 	count = &sapb.Count{
 		Count: new(int64),
 	}
-	err = ssa.dbMap.WithContext(ctx).SelectOne(count.Count,
+	err = ssa.db(ctx).SelectOne(count.Count,
 		`SELECT COUNT(1) FROM authz
 		WHERE registrationID = :regID AND
 		identifier = :identifier AND
@@ -1089,15 +1106,9 @@ func (ssa *SQLStorageAuthority) CountInvalidAuthorizations(
 	return
 }
 
-func hashNames(names []string) []byte {
-	names = core.UniqueLowerNames(names)
-	hash := sha256.Sum256([]byte(strings.Join(names, ",")))
-	return hash[:]
-}
-
 func addFQDNSet(db dbInserter, names []string, serial string, issued time.Time, expires time.Time) error {
 	return db.Insert(&core.FQDNSet{
-		SetHash: hashNames(names),
+		SetHash: core.HashNames(names),
 		Serial:  serial,
 		Issued:  issued,
 		Expires: expires,
@@ -1115,7 +1126,7 @@ func addOrderFQDNSet(
 	regID int64,
 	expires time.Time) error {
 	return db.Insert(&orderFQDNSet{
-		SetHash:        hashNames(names),
+		SetHash:        core.HashNames(names),
 		OrderID:        orderID,
 		RegistrationID: regID,
 		Expires:        expires,
@@ -1170,12 +1181,12 @@ func addIssuedNames(db dbExecer, cert *x509.Certificate, isRenewal bool) error {
 // |window|
 func (ssa *SQLStorageAuthority) CountFQDNSets(ctx context.Context, window time.Duration, names []string) (int64, error) {
 	var count int64
-	err := ssa.dbMap.WithContext(ctx).SelectOne(
+	err := ssa.db(ctx).SelectOne(
 		&count,
 		`SELECT COUNT(1) FROM fqdnSets
 		WHERE setHash = ?
 		AND issued > ?`,
-		hashNames(names),
+		core.HashNames(names),
 		ssa.clk.Now().Add(-window),
 	)
 	return count, err
@@ -1302,7 +1313,7 @@ func (ssa *SQLStorageAuthority) getNewIssuancesByFQDNSet(
 // exists in the database
 func (ssa *SQLStorageAuthority) FQDNSetExists(ctx context.Context, names []string) (bool, error) {
 	exists, err := ssa.checkFQDNSetExists(
-		ssa.dbMap.WithContext(ctx).SelectOne,
+		ssa.db(ctx).SelectOne,
 		names)
 	if err != nil {
 		return false, err
@@ -1323,7 +1334,7 @@ func (ssa *SQLStorageAuthority) checkFQDNSetExists(selector oneSelectorFunc, nam
 		`SELECT COUNT(1) FROM fqdnSets
 		WHERE setHash = ?
 		LIMIT 1`,
-		hashNames(names),
+		core.HashNames(names),
 	)
 	return count > 0, err
 }
@@ -1346,7 +1357,7 @@ func (ssa *SQLStorageAuthority) PreviousCertificateExists(
 
 	// Find the most recently issued certificate containing this domain name.
 	var serial string
-	err := ssa.dbMap.WithContext(ctx).SelectOne(
+	err := ssa.db(ctx).SelectOne(
 		&serial,
 		`SELECT serial FROM issuedNames
 		WHERE reversedName = ?
@@ -1363,7 +1374,7 @@ func (ssa *SQLStorageAuthority) PreviousCertificateExists(
 
 	// Check whether that certificate was issued to the specified account.
 	var count int
-	err = ssa.dbMap.WithContext(ctx).SelectOne(
+	err = ssa.db(ctx).SelectOne(
 		&count,
 		`SELECT COUNT(1) FROM certificates
 		WHERE serial = ?
@@ -1386,9 +1397,32 @@ func (ssa *SQLStorageAuthority) PreviousCertificateExists(
 	return notExists, nil
 }
 
+// CertificateReplaced returns true iff an order that replaces the certificate
+// with the provided serial has been finalized. Replacement orders are only
+// recorded when the StoreReplacementOrders feature is enabled, so until then
+// no certificate has been replaced.
+func (ssa *SQLStorageAuthority) CertificateReplaced(ctx context.Context, req *sapb.Serial) (*sapb.Exists, error) {
+	replaced := false
+	if features.Enabled(features.StoreReplacementOrders) {
+		var count int64
+		err := ssa.db(ctx).SelectOne(
+			&count,
+			`SELECT COUNT(1) FROM replacementOrders
+			WHERE serial = ?
+			AND replaced = true`,
+			*req.Serial,
+		)
+		if err != nil {
+			return nil, err
+		}
+		replaced = count > 0
+	}
+	return &sapb.Exists{Exists: &replaced}, nil
+}
+
 // DeactivateRegistration deactivates a currently valid registration
 func (ssa *SQLStorageAuthority) DeactivateRegistration(ctx context.Context, id int64) error {
-	_, err := ssa.dbMap.WithContext(ctx).Exec(
+	_, err := ssa.db(ctx).Exec(
 		"UPDATE registrations SET status = ? WHERE status = ? AND id = ?",
 		string(core.StatusDeactivated),
 		string(core.StatusValid),
@@ -1399,50 +1433,110 @@ func (ssa *SQLStorageAuthority) DeactivateRegistration(ctx context.Context, id i
 
 // DeactivateAuthorization deactivates a currently valid or pending authorization
 func (ssa *SQLStorageAuthority) DeactivateAuthorization(ctx context.Context, id string) error {
-	tx, err := ssa.dbMap.Begin()
-	if err != nil {
-		return err
-	}
-	txWithCtx := tx.WithContext(ctx)
+	defer ssa.readCache.removeAuthorization(id)
+	return ssa.withTransaction(ctx, "DeactivateAuthorization", func(txWithCtx gorp.SqlExecutor) error {
+		if existingPending(txWithCtx, id) {
+			authzObj, err := txWithCtx.Get(&pendingauthzModel{}, id)
+			if err != nil {
+				return err
+			}
+			if authzObj == nil {
+				// InternalServerError because existingPending already told us it existed
+				return berrors.InternalServerError("failure retrieving pending authorization")
+			}
+			authz := authzObj.(*pendingauthzModel)
+			if authz.Status != core.StatusPending {
+				return berrors.WrongAuthorizationStateError("authorization not pending")
+			}
+			result, err := txWithCtx.Delete(authzObj)
+			if err != nil {
+				return err
+			}
+			if result != 1 {
+				return berrors.InternalServerError("wrong number of rows deleted: expected 1, got %d", result)
+			}
+			authz.Status = core.StatusDeactivated
+			err = txWithCtx.Insert(&authzModel{authz.Authorization})
+			if err != nil {
+				return err
+			}
+		} else {
+			_, err := txWithCtx.Exec(
+				`UPDATE authz SET status = ? WHERE id = ? and status = ?`,
+				string(core.StatusDeactivated),
+				id,
+				string(core.StatusValid),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
 
-	if existingPending(txWithCtx, id) {
-		authzObj, err := txWithCtx.Get(&pendingauthzModel{}, id)
+// RetryChallenge reopens an invalid authorization so that its failed
+// challenge can be attempted again: the authorization is moved back to the
+// pendingAuthorizations table and the challenge's status, error and
+// validation record are reset, keeping its attempts. It requires the
+// StoreChallengeAttempts feature, without which attempts aren't counted.
+func (ssa *SQLStorageAuthority) RetryChallenge(ctx context.Context, req *sapb.RetryChallengeRequest) error {
+	if !features.Enabled(features.StoreChallengeAttempts) {
+		return berrors.InternalServerError("retrying challenges requires the StoreChallengeAttempts feature")
+	}
+	// Invalid authorizations aren't cached (see addAuthorization), so no SA
+	// instance, this one or another, serves a stale copy of the authorization
+	// once it's reopened.
+	return ssa.withTransaction(ctx, "RetryChallenge", func(txWithCtx gorp.SqlExecutor) error {
+		authz, err := selectAuthz(txWithCtx, "WHERE id = ?", *req.AuthorizationID)
+		if err == sql.ErrNoRows {
+			return berrors.NotFoundError("no finalized authorization found with id %q", *req.AuthorizationID)
+		}
 		if err != nil {
-			return Rollback(tx, err)
+			return err
 		}
-		if authzObj == nil {
-			// InternalServerError because existingPending already told us it existed
-			return Rollback(tx, berrors.InternalServerError("failure retrieving pending authorization"))
+		if authz.Status != core.StatusInvalid {
+			return berrors.WrongAuthorizationStateError("authorization is not invalid")
 		}
-		authz := authzObj.(*pendingauthzModel)
-		if authz.Status != core.StatusPending {
-			return Rollback(tx, berrors.WrongAuthorizationStateError("authorization not pending"))
+		if authz.Expires == nil || authz.Expires.Before(ssa.clk.Now()) {
+			return berrors.WrongAuthorizationStateError("authorization has expired")
 		}
-		result, err := txWithCtx.Delete(authzObj)
+
+		result, err := txWithCtx.Exec(
+			`UPDATE challenges SET status = ?, error = NULL, validationRecord = NULL
+			WHERE id = ? AND authorizationID = ? AND status = ?`,
+			string(core.StatusPending),
+			*req.ChallengeID,
+			*req.AuthorizationID,
+			string(core.StatusInvalid),
+		)
 		if err != nil {
-			return Rollback(tx, err)
+			return err
 		}
-		if result != 1 {
-			return Rollback(tx, berrors.InternalServerError("wrong number of rows deleted: expected 1, got %d", result))
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n != 1 {
+			return berrors.WrongAuthorizationStateError("challenge %d is not an invalid challenge of authorization %q", *req.ChallengeID, *req.AuthorizationID)
 		}
-		authz.Status = core.StatusDeactivated
-		err = txWithCtx.Insert(&authzModel{authz.Authorization})
+
+		n, err := txWithCtx.Delete(authz)
 		if err != nil {
-			return Rollback(tx, err)
+			return err
 		}
-	} else {
-		_, err = txWithCtx.Exec(
-			`UPDATE authz SET status = ? WHERE id = ? and status = ?`,
-			string(core.StatusDeactivated),
-			id,
-			string(core.StatusValid),
-		)
+		if n != 1 {
+			return berrors.InternalServerError("wrong number of rows deleted: expected 1, got %d", n)
+		}
+		pending := &pendingauthzModel{Authorization: authz.Authorization}
+		pending.Status = core.StatusPending
+		pending.ValidationMethod = ""
+		err = txWithCtx.Insert(pending)
 		if err != nil {
-			return Rollback(tx, err)
+			return err
 		}
-	}
 
-	return tx.Commit()
+		return nil
+	})
 }
 
 // NewOrder adds a new v2 style order to the database
@@ -1453,43 +1547,69 @@ func (ssa *SQLStorageAuthority) NewOrder(ctx context.Context, req *corepb.Order)
 		Created:        ssa.clk.Now(),
 	}
 
-	tx, err := ssa.dbMap.Begin()
-	if err != nil {
-		return nil, err
-	}
-	txWithCtx := tx.WithContext(ctx)
-
-	if err := txWithCtx.Insert(order); err != nil {
-		return nil, Rollback(tx, err)
-	}
+	err := ssa.withTransaction(ctx, "NewOrder", func(txWithCtx gorp.SqlExecutor) error {
+		if err := txWithCtx.Insert(order); err != nil {
+			return err
+		}
 
-	for _, id := range req.Authorizations {
-		otoa := &orderToAuthzModel{
-			OrderID: order.ID,
-			AuthzID: id,
+		for _, id := range req.Authorizations {
+			otoa := &orderToAuthzModel{
+				OrderID: order.ID,
+				AuthzID: id,
+			}
+			if err := txWithCtx.Insert(otoa); err != nil {
+				return err
+			}
 		}
-		if err := txWithCtx.Insert(otoa); err != nil {
-			return nil, Rollback(tx, err)
+
+		for _, name := range req.Names {
+			reqdName := &requestedNameModel{
+				OrderID:      order.ID,
+				ReversedName: ReverseName(name),
+			}
+			if err := txWithCtx.Insert(reqdName); err != nil {
+				return err
+			}
 		}
-	}
 
-	for _, name := range req.Names {
-		reqdName := &requestedNameModel{
-			OrderID:      order.ID,
-			ReversedName: ReverseName(name),
+		// Add an FQDNSet entry for the order
+		if err := addOrderFQDNSet(
+			txWithCtx, req.Names, order.ID, order.RegistrationID, order.Expires); err != nil {
+			return err
 		}
-		if err := txWithCtx.Insert(reqdName); err != nil {
-			return nil, Rollback(tx, err)
+
+		if features.Enabled(features.StoreReplacementOrders) && req.Replaces != nil && *req.Replaces != "" {
+			if err := txWithCtx.Insert(&replacementOrderModel{
+				Serial:       *req.Replaces,
+				OrderID:      order.ID,
+				OrderExpires: order.Expires,
+			}); err != nil {
+				return err
+			}
 		}
-	}
 
-	// Add an FQDNSet entry for the order
-	if err := addOrderFQDNSet(
-		txWithCtx, req.Names, order.ID, order.RegistrationID, order.Expires); err != nil {
-		return nil, Rollback(tx, err)
-	}
+		if len(req.Exemptions) > 0 {
+			if !features.Enabled(features.StoreOrderExemptions) {
+				return berrors.InternalServerError("policy exemptions can't be stored")
+			}
+			for _, token := range req.Exemptions {
+				err := txWithCtx.Insert(&orderExemptionModel{
+					TokenHash: fmt.Sprintf("%x", sha256.Sum256([]byte(token))),
+					OrderID:   order.ID,
+					Token:     token,
+				})
+				if err != nil {
+					if strings.HasPrefix(err.Error(), "Error 1062: Duplicate entry") {
+						err = berrors.DuplicateError("policy exemption has already been used")
+					}
+					return err
+				}
+			}
+		}
 
-	if err := tx.Commit(); err != nil {
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -1514,67 +1634,63 @@ func (ssa *SQLStorageAuthority) NewOrder(ctx context.Context, req *corepb.Order)
 
 // SetOrderProcessing updates a provided *corepb.Order in pending status to be
 // in processing status by updating the `beganProcessing` field of the
-// corresponding Order table row in the DB.
+// corresponding Order table row in the DB. If the StoreOrderCSRHash feature is
+// enabled the order's CsrHash is stored along with it.
 func (ssa *SQLStorageAuthority) SetOrderProcessing(ctx context.Context, req *corepb.Order) error {
-	tx, err := ssa.dbMap.Begin()
-	if err != nil {
-		return err
-	}
-	txWithCtx := tx.WithContext(ctx)
-
-	result, err := txWithCtx.Exec(`
-		UPDATE orders
-		SET beganProcessing = ?
-		WHERE id = ?
-		AND beganProcessing = ?`,
-		true,
-		*req.Id,
-		false)
-	if err != nil {
-		err = berrors.InternalServerError("error updating order to beganProcessing status")
-		return Rollback(tx, err)
-	}
+	return ssa.withTransaction(ctx, "SetOrderProcessing", func(txWithCtx gorp.SqlExecutor) error {
+		query := "UPDATE orders SET beganProcessing = ?"
+		args := []interface{}{true}
+		if features.Enabled(features.StoreOrderCSRHash) && len(req.CsrHash) > 0 {
+			query += ", csrHash = ?"
+			args = append(args, req.CsrHash)
+		}
+		result, err := txWithCtx.Exec(
+			query+" WHERE id = ? AND beganProcessing = ?",
+			append(args, *req.Id, false)...)
+		if err != nil {
+			if isRetryableTxError(err) {
+				return err
+			}
+			return berrors.InternalServerError("error updating order to beganProcessing status")
+		}
 
-	n, err := result.RowsAffected()
-	if err != nil || n == 0 {
-		err = berrors.InternalServerError("no order updated to beganProcessing status")
-		return Rollback(tx, err)
-	}
+		n, err := result.RowsAffected()
+		if err != nil || n == 0 {
+			return berrors.InternalServerError("no order updated to beganProcessing status")
+		}
 
-	return tx.Commit()
+		return nil
+	})
 }
 
 // SetOrderError updates a provided Order's error field.
 func (ssa *SQLStorageAuthority) SetOrderError(ctx context.Context, order *corepb.Order) error {
-	tx, err := ssa.dbMap.Begin()
-	if err != nil {
-		return err
-	}
-	txWithCtx := tx.WithContext(ctx)
-
-	om, err := orderToModel(order)
-	if err != nil {
-		return Rollback(tx, err)
-	}
+	return ssa.withTransaction(ctx, "SetOrderError", func(txWithCtx gorp.SqlExecutor) error {
+		om, err := orderToModel(order)
+		if err != nil {
+			return err
+		}
 
-	result, err := txWithCtx.Exec(`
-		UPDATE orders
-		SET error = ?
-		WHERE id = ?`,
-		om.Error,
-		om.ID)
-	if err != nil {
-		err = berrors.InternalServerError("error updating order error field")
-		return Rollback(tx, err)
-	}
+		result, err := txWithCtx.Exec(`
+			UPDATE orders
+			SET error = ?
+			WHERE id = ?`,
+			om.Error,
+			om.ID)
+		if err != nil {
+			if isRetryableTxError(err) {
+				return err
+			}
+			return berrors.InternalServerError("error updating order error field")
+		}
 
-	n, err := result.RowsAffected()
-	if err != nil || n == 0 {
-		err = berrors.InternalServerError("no order updated with new error field")
-		return Rollback(tx, err)
-	}
+		n, err := result.RowsAffected()
+		if err != nil || n == 0 {
+			return berrors.InternalServerError("no order updated with new error field")
+		}
 
-	return tx.Commit()
+		return nil
+	})
 }
 
 // FinalizeOrder finalizes a provided *corepb.Order by persisting the
@@ -1582,42 +1698,50 @@ func (ssa *SQLStorageAuthority) SetOrderError(ctx context.Context, order *corepb
 // CertificateSerial and the order ID on the provided order are processed (e.g.
 // this is not a generic update RPC).
 func (ssa *SQLStorageAuthority) FinalizeOrder(ctx context.Context, req *corepb.Order) error {
-	tx, err := ssa.dbMap.Begin()
-	if err != nil {
-		return err
-	}
-	txWithCtx := tx.WithContext(ctx)
+	return ssa.withTransaction(ctx, "FinalizeOrder", func(txWithCtx gorp.SqlExecutor) error {
+		result, err := txWithCtx.Exec(`
+			UPDATE orders
+			SET certificateSerial = ?
+			WHERE id = ? AND
+			beganProcessing = true`,
+			*req.CertificateSerial,
+			*req.Id)
+		if err != nil {
+			if isRetryableTxError(err) {
+				return err
+			}
+			return berrors.InternalServerError("error updating order for finalization")
+		}
 
-	result, err := txWithCtx.Exec(`
-		UPDATE orders
-		SET certificateSerial = ?
-		WHERE id = ? AND
-		beganProcessing = true`,
-		*req.CertificateSerial,
-		*req.Id)
-	if err != nil {
-		err = berrors.InternalServerError("error updating order for finalization")
-		return Rollback(tx, err)
-	}
+		n, err := result.RowsAffected()
+		if err != nil || n == 0 {
+			return berrors.InternalServerError("no order updated for finalization")
+		}
 
-	n, err := result.RowsAffected()
-	if err != nil || n == 0 {
-		err = berrors.InternalServerError("no order updated for finalization")
-		return Rollback(tx, err)
-	}
+		// Delete the orderFQDNSet row for the order now that it has been finalized.
+		// We use this table for order reuse and should not reuse a finalized order.
+		if err := deleteOrderFQDNSet(txWithCtx, *req.Id); err != nil {
+			return err
+		}
 
-	// Delete the orderFQDNSet row for the order now that it has been finalized.
-	// We use this table for order reuse and should not reuse a finalized order.
-	if err := deleteOrderFQDNSet(txWithCtx, *req.Id); err != nil {
-		return Rollback(tx, err)
-	}
+		// If the order replaces a certificate, that certificate has now been
+		// replaced.
+		if features.Enabled(features.StoreReplacementOrders) {
+			_, err := txWithCtx.Exec(
+				"UPDATE replacementOrders SET replaced = true WHERE orderID = ?",
+				*req.Id)
+			if err != nil {
+				return err
+			}
+		}
 
-	return tx.Commit()
+		return nil
+	})
 }
 
 func (ssa *SQLStorageAuthority) authzForOrder(ctx context.Context, orderID int64) ([]string, error) {
 	var ids []string
-	_, err := ssa.dbMap.WithContext(ctx).Select(
+	_, err := ssa.db(ctx).Select(
 		&ids, "SELECT authzID FROM orderToAuthz WHERE orderID = ?", orderID)
 	if err != nil {
 		return nil, err
@@ -1629,7 +1753,7 @@ func (ssa *SQLStorageAuthority) authzForOrder(ctx context.Context, orderID int64
 // names are returned in their reversed form (see `sa.ReverseName`).
 func (ssa *SQLStorageAuthority) namesForOrder(ctx context.Context, orderID int64) ([]string, error) {
 	var reversedNames []string
-	_, err := ssa.dbMap.WithContext(ctx).Select(
+	_, err := ssa.db(ctx).Select(
 		&reversedNames,
 		`SELECT reversedName
 	   FROM requestedNames
@@ -1641,9 +1765,50 @@ func (ssa *SQLStorageAuthority) namesForOrder(ctx context.Context, orderID int64
 	return reversedNames, nil
 }
 
+// replacedForOrder returns the serial of the certificate an order replaces, or
+// "" if it doesn't replace one.
+func (ssa *SQLStorageAuthority) replacedForOrder(ctx context.Context, orderID int64) (string, error) {
+	var serials []string
+	_, err := ssa.db(ctx).Select(
+		&serials,
+		"SELECT serial FROM replacementOrders WHERE orderID = ?",
+		orderID)
+	if err != nil || len(serials) == 0 {
+		return "", err
+	}
+	return serials[0], nil
+}
+
+// csrHashForOrder returns the hash of the CSR an order was finalized with, or
+// nil if it hasn't been finalized or was finalized without one being stored.
+func (ssa *SQLStorageAuthority) csrHashForOrder(ctx context.Context, orderID int64) ([]byte, error) {
+	var hashes [][]byte
+	_, err := ssa.db(ctx).Select(
+		&hashes,
+		"SELECT csrHash FROM orders WHERE id = ? AND csrHash IS NOT NULL",
+		orderID)
+	if err != nil || len(hashes) == 0 {
+		return nil, err
+	}
+	return hashes[0], nil
+}
+
+// exemptionsForOrder returns the policy exemption tokens an order uses.
+func (ssa *SQLStorageAuthority) exemptionsForOrder(ctx context.Context, orderID int64) ([]string, error) {
+	var tokens []string
+	_, err := ssa.db(ctx).Select(
+		&tokens,
+		"SELECT token FROM orderExemptions WHERE orderID = ?",
+		orderID)
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
 // GetOrder is used to retrieve an already existing order object
 func (ssa *SQLStorageAuthority) GetOrder(ctx context.Context, req *sapb.OrderRequest) (*corepb.Order, error) {
-	omObj, err := ssa.dbMap.WithContext(ctx).Get(orderModel{}, *req.Id)
+	omObj, err := ssa.db(ctx).Get(orderModel{}, *req.Id)
 	if err == sql.ErrNoRows || omObj == nil {
 		return nil, berrors.NotFoundError("no order found for ID %d", *req.Id)
 	}
@@ -1675,6 +1840,30 @@ func (ssa *SQLStorageAuthority) GetOrder(ctx context.Context, req *sapb.OrderReq
 	}
 	order.Names = reversedNames
 
+	if features.Enabled(features.StoreReplacementOrders) {
+		replaces, err := ssa.replacedForOrder(ctx, *order.Id)
+		if err != nil {
+			return nil, err
+		}
+		if replaces != "" {
+			order.Replaces = &replaces
+		}
+	}
+
+	if features.Enabled(features.StoreOrderExemptions) {
+		order.Exemptions, err = ssa.exemptionsForOrder(ctx, *order.Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if features.Enabled(features.StoreOrderCSRHash) {
+		order.CsrHash, err = ssa.csrHashForOrder(ctx, *order.Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Calculate the status for the order
 	status, err := ssa.statusForOrder(ctx, order)
 	if err != nil {
@@ -1821,13 +2010,13 @@ func (ssa *SQLStorageAuthority) getAllOrderAuthorizations(
 
 	for _, table := range authorizationTables {
 		var authzs []*core.Authorization
-		_, err := ssa.dbMap.WithContext(ctx).Select(
+		_, err := ssa.db(ctx).Select(
 			&authzs,
 			fmt.Sprintf(`SELECT %s from %s AS authz
 		INNER JOIN orderToAuthz
 		ON authz.ID = orderToAuthz.authzID
 		WHERE authz.registrationID = ? AND
-		orderToAuthz.orderID = ?`, authzFields, table),
+		orderToAuthz.orderID = ?`, authzFieldsFor(table), table),
 			acctID,
 			orderID)
 		if err != nil {
@@ -1867,7 +2056,7 @@ func (ssa *SQLStorageAuthority) GetValidOrderAuthorizations(
 	// authorizations that are owned by the correct account ID and associated with
 	// the given order ID
 	var auths []*core.Authorization
-	_, err := ssa.dbMap.WithContext(ctx).Select(
+	_, err := ssa.db(ctx).Select(
 		&auths,
 		fmt.Sprintf(`SELECT %s FROM %s AS authz
 	LEFT JOIN orderToAuthz
@@ -1875,7 +2064,7 @@ func (ssa *SQLStorageAuthority) GetValidOrderAuthorizations(
 	WHERE authz.registrationID = ? AND
 	authz.expires > ? AND
 	authz.status = ? AND
-	orderToAuthz.orderID = ?`, authzFields, authorizationTable),
+	orderToAuthz.orderID = ?`, authzFieldsFor(authorizationTable), authorizationTable),
 		*req.AcctID,
 		now,
 		string(core.StatusValid),
@@ -1895,7 +2084,7 @@ func (ssa *SQLStorageAuthority) GetValidOrderAuthorizations(
 		existing, present := byName[auth.Identifier.Value]
 		if !present || auth.Expires.After(*existing.Expires) {
 			// Retrieve challenges for the authz
-			auth.Challenges, err = ssa.getChallenges(ssa.dbMap.WithContext(ctx), auth.ID)
+			auth.Challenges, err = ssa.getChallenges(ssa.db(ctx), auth.ID)
 			if err != nil {
 				return nil, err
 			}
@@ -1906,6 +2095,16 @@ func (ssa *SQLStorageAuthority) GetValidOrderAuthorizations(
 	return byName, nil
 }
 
+// GetOrderAuthorizations is used to find all of the authorizations, whatever
+// their status, associated with a specific order and account ID. It lets a
+// client watching an order see the state of each of its authorizations
+// without fetching them one at a time. Challenges aren't included.
+func (ssa *SQLStorageAuthority) GetOrderAuthorizations(
+	ctx context.Context,
+	req *sapb.GetOrderAuthorizationsRequest) (map[string]*core.Authorization, error) {
+	return ssa.getAllOrderAuthorizations(ctx, *req.Id, *req.AcctID)
+}
+
 // GetOrderForNames tries to find a **pending** order with the exact set of
 // names requested, associated with the given accountID. Only unexpired orders
 // with status pending are considered. If no order meeting these requirements is
@@ -1915,10 +2114,10 @@ func (ssa *SQLStorageAuthority) GetOrderForNames(
 	req *sapb.GetOrderForNamesRequest) (*corepb.Order, error) {
 
 	// Hash the names requested for lookup in the orderFqdnSets table
-	fqdnHash := hashNames(req.Names)
+	fqdnHash := core.HashNames(req.Names)
 
 	var orderID int64
-	err := ssa.dbMap.WithContext(ctx).SelectOne(&orderID, `
+	err := ssa.db(ctx).SelectOne(&orderID, `
 	SELECT orderID
 	FROM orderFqdnSets
 	WHERE setHash = ?
@@ -1974,7 +2173,7 @@ func (ssa *SQLStorageAuthority) getAuthorizations(
 	// If requested, filter out V1 authorizations by doing a JOIN on the
 	// orderToAuthz table, ensuring that all authorization IDs returned correspond
 	// to a V2 order.
-	queryPrefix := fmt.Sprintf(`SELECT %s FROM %s`, authzFields, table)
+	queryPrefix := fmt.Sprintf(`SELECT %s FROM %s`, authzFieldsFor(table), table)
 	if requireV2Authzs {
 		queryPrefix = queryPrefix + `
 		JOIN orderToAuthz
@@ -1982,7 +2181,7 @@ func (ssa *SQLStorageAuthority) getAuthorizations(
 	}
 
 	var auths []*core.Authorization
-	_, err := ssa.dbMap.WithContext(ctx).Select(
+	_, err := ssa.db(ctx).Select(
 		&auths,
 		fmt.Sprintf(`%s
 		WHERE registrationID = ? AND
@@ -2014,7 +2213,7 @@ func (ssa *SQLStorageAuthority) getAuthorizations(
 
 	for _, auth := range byName {
 		// Retrieve challenges for the authz
-		if auth.Challenges, err = ssa.getChallenges(ssa.dbMap.WithContext(ctx), auth.ID); err != nil {
+		if auth.Challenges, err = ssa.getChallenges(ssa.db(ctx), auth.ID); err != nil {
 			return nil, err
 		}
 	}
@@ -2099,7 +2298,7 @@ func (ssa *SQLStorageAuthority) GetAuthorizations(
 	// challenge).
 	// Fetch each of the authorizations' associated challenges
 	for _, authz := range authzMap {
-		authz.Challenges, err = ssa.getChallenges(ssa.dbMap.WithContext(ctx), authz.ID)
+		authz.Challenges, err = ssa.getChallenges(ssa.db(ctx), authz.ID)
 		if err != nil {
 			return nil, err
 		}
@@ -2128,7 +2327,7 @@ func (ssa *SQLStorageAuthority) getChallengesImpl(db dbSelector, authID string)
 	var challObjs []challModel
 	_, err := db.Select(
 		&challObjs,
-		getChallengesQuery,
+		getChallengesQuery(),
 		map[string]interface{}{"authID": authID},
 	)
 	if err != nil {
@@ -2163,7 +2362,7 @@ func (ssa *SQLStorageAuthority) NewAuthorization(authz *corepb.Authorization) (i
 // GetAuthz2 returns the authz2 style authorization identified by the provided ID or an error.
 // If no authorization is found matching the ID a berrors.NotFound type error is returned.
 func (ssa *SQLStorageAuthority) GetAuthz2(ctx context.Context, id *sapb.AuthorizationID2) (*corepb.Authorization, error) {
-	obj, err := ssa.dbMap.Get(authz2Model{}, id.Id)
+	obj, err := ssa.db(ctx).Get(authz2Model{}, id.Id)
 	if err != nil {
 		return nil, err
 	}
@@ -2173,45 +2372,204 @@ func (ssa *SQLStorageAuthority) GetAuthz2(ctx context.Context, id *sapb.Authoriz
 	return modelToAuthzPB(obj.(*authz2Model))
 }
 
+// DeactivateAuthorization2 deactivates the currently valid or pending authz2
+// style authorization identified by the provided ID. If there is no such
+// authorization a berrors.NotFound type error is returned, and if it is in
+// any other state it is left alone and a berrors.WrongAuthorizationState type
+// error is returned.
+func (ssa *SQLStorageAuthority) DeactivateAuthorization2(ctx context.Context, req *sapb.AuthorizationID2) (*corepb.Empty, error) {
+	result, err := ssa.db(ctx).Exec(
+		`UPDATE authz2 SET status = ? WHERE id = ? AND status IN (?, ?)`,
+		statusToUint[string(core.StatusDeactivated)],
+		*req.Id,
+		statusToUint[string(core.StatusValid)],
+		statusToUint[string(core.StatusPending)],
+	)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		status, err := ssa.db(ctx).SelectNullInt(`SELECT status FROM authz2 WHERE id = ?`, *req.Id)
+		if err != nil {
+			return nil, err
+		}
+		if !status.Valid {
+			return nil, berrors.NotFoundError("authorization %d not found", *req.Id)
+		}
+		return nil, berrors.WrongAuthorizationStateError("authorization %d is %s, not valid or pending",
+			*req.Id, uintToStatus[uint(status.Int64)])
+	}
+	return &corepb.Empty{}, nil
+}
+
 // RevokeCertificate stores revocation information about a certificate. It will only store this
 // information if the certificate is not alreay marked as revoked. This method is meant as a
 // replacement for MarkCertificateRevoked and the ocsp-updater database methods.
 func (ssa *SQLStorageAuthority) RevokeCertificate(ctx context.Context, req *sapb.RevokeCertificateRequest) error {
-	tx, err := ssa.dbMap.Begin()
+	defer ssa.readCache.removeCertificate(*req.Serial)
+	return ssa.withTransaction(ctx, "RevokeCertificate", func(txWithCtx gorp.SqlExecutor) error {
+		status, err := SelectCertificateStatus(
+			txWithCtx,
+			"WHERE serial = ? AND status != ?",
+			*req.Serial,
+			string(core.OCSPStatusRevoked),
+		)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				// InternalServerError because we expected this certificate status to exist and
+				// not be revoked.
+				return berrors.InternalServerError("no certificate with serial %s and status %s", *req.Serial, string(core.OCSPStatusRevoked))
+			}
+			return err
+		}
+
+		revokedDate := time.Unix(0, *req.Date)
+		status.Status = core.OCSPStatusRevoked
+		status.RevokedReason = revocation.Reason(*req.Reason)
+		status.RevokedDate = revokedDate
+		status.OCSPLastUpdated = revokedDate
+		status.OCSPResponse = req.Response
+
+		n, err := txWithCtx.Update(&status)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return berrors.InternalServerError("no certificate updated")
+		}
+
+		return nil
+	})
+}
+
+// AddRateLimitOverride stores a new rate limit override. Any unexpired
+// override for the same limit and key or registration ID is expired as part of
+// the same transaction, so at most one override is in effect for each
+// combination. The stored override, including its ID, is returned.
+func (ssa *SQLStorageAuthority) AddRateLimitOverride(ctx context.Context, req *sapb.RateLimitOverride) (*sapb.RateLimitOverride, error) {
+	if !ratelimit.ValidLimitName(*req.LimitName) {
+		return nil, berrors.MalformedError("unknown rate limit %q", *req.LimitName)
+	}
+	var key string
+	var regID int64
+	if req.Key != nil {
+		key = *req.Key
+	}
+	if req.RegistrationID != nil {
+		regID = *req.RegistrationID
+	}
+	if (key == "") == (regID == 0) {
+		return nil, berrors.MalformedError("exactly one of key and registration ID must be provided")
+	}
+	if *req.Threshold < 0 {
+		return nil, berrors.MalformedError("threshold must not be negative")
+	}
+	if *req.CreatedBy == "" || *req.Reason == "" {
+		return nil, berrors.MalformedError("an override must record who created it and why")
+	}
+	now := ssa.clk.Now()
+	expires := time.Unix(0, *req.Expires)
+	if !expires.After(now) {
+		return nil, berrors.MalformedError("override expiry must be in the future")
+	}
+
+	rlom := &rateLimitOverrideModel{
+		LimitName:      *req.LimitName,
+		Key:            key,
+		RegistrationID: regID,
+		Threshold:      *req.Threshold,
+		CreatedBy:      *req.CreatedBy,
+		Created:        now,
+		Expires:        expires,
+		Reason:         *req.Reason,
+	}
+	err := ssa.withTransaction(ctx, "AddRateLimitOverride", func(txWithCtx gorp.SqlExecutor) error {
+		_, err := txWithCtx.Exec(
+			`UPDATE rateLimitOverrides
+			SET expires = ?, expiredBy = ?, expiredReason = ?
+			WHERE limitName = ? AND overrideKey = ? AND registrationID = ? AND expires > ?`,
+			now,
+			*req.CreatedBy,
+			fmt.Sprintf("replaced by a new override: %s", *req.Reason),
+			*req.LimitName,
+			key,
+			regID,
+			now,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := txWithCtx.Insert(rlom); err != nil {
+			return err
+		}
+
+		return nil
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	txWithCtx := tx.WithContext(ctx)
 
-	status, err := SelectCertificateStatus(
-		txWithCtx,
-		"WHERE serial = ? AND status != ?",
-		*req.Serial,
-		string(core.OCSPStatusRevoked),
+	ssa.log.AuditInfof("Added rate limit override: id=[%d] limit=[%s] key=[%s] regID=[%d] threshold=[%d] expires=[%s] by=[%s] reason=[%s]",
+		rlom.ID, rlom.LimitName, rlom.Key, rlom.RegistrationID, rlom.Threshold, rlom.Expires, rlom.CreatedBy, rlom.Reason)
+	return modelToRateLimitOverride(rlom), nil
+}
+
+// ExpireRateLimitOverride immediately expires the rate limit override with the
+// given ID, recording who expired it and why. A NotFound error is returned if
+// there is no unexpired override with that ID.
+func (ssa *SQLStorageAuthority) ExpireRateLimitOverride(ctx context.Context, req *sapb.ExpireRateLimitOverrideRequest) error {
+	if *req.ExpiredBy == "" || *req.Reason == "" {
+		return berrors.MalformedError("expiring an override must record who expired it and why")
+	}
+	now := ssa.clk.Now()
+	result, err := ssa.db(ctx).Exec(
+		`UPDATE rateLimitOverrides
+		SET expires = ?, expiredBy = ?, expiredReason = ?
+		WHERE id = ? AND expires > ?`,
+		now,
+		*req.ExpiredBy,
+		*req.Reason,
+		*req.Id,
+		now,
 	)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			// InternalServerError because we expected this certificate status to exist and
-			// not be revoked.
-			return Rollback(tx, berrors.InternalServerError("no certificate with serial %s and status %s", *req.Serial, string(core.OCSPStatusRevoked)))
-		}
-		return Rollback(tx, err)
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return berrors.NotFoundError("no unexpired rate limit override with ID %d", *req.Id)
 	}
 
-	revokedDate := time.Unix(0, *req.Date)
-	status.Status = core.OCSPStatusRevoked
-	status.RevokedReason = revocation.Reason(*req.Reason)
-	status.RevokedDate = revokedDate
-	status.OCSPLastUpdated = revokedDate
-	status.OCSPResponse = req.Response
+	ssa.log.AuditInfof("Expired rate limit override: id=[%d] by=[%s] reason=[%s]",
+		*req.Id, *req.ExpiredBy, *req.Reason)
+	return nil
+}
 
-	n, err := txWithCtx.Update(&status)
+// GetRateLimitOverrides returns all of the rate limit overrides that have not
+// expired as of the request's timestamp.
+func (ssa *SQLStorageAuthority) GetRateLimitOverrides(ctx context.Context, req *sapb.GetRateLimitOverridesRequest) (*sapb.RateLimitOverrides, error) {
+	var models []*rateLimitOverrideModel
+	_, err := ssa.db(ctx).Select(
+		&models,
+		`SELECT id, limitName, overrideKey, registrationID, threshold, createdBy, created, expires, reason, expiredBy, expiredReason
+		FROM rateLimitOverrides
+		WHERE expires > ?`,
+		time.Unix(0, *req.Now),
+	)
 	if err != nil {
-		return Rollback(tx, err)
+		return nil, err
 	}
-	if n == 0 {
-		return Rollback(tx, berrors.InternalServerError("no certificate updated"))
+	overrides := &sapb.RateLimitOverrides{}
+	for _, m := range models {
+		overrides.Overrides = append(overrides.Overrides, modelToRateLimitOverride(m))
 	}
-
-	return tx.Commit()
+	return overrides, nil
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"database/sql"
 	"encoding/json"
@@ -28,6 +29,7 @@ import (
 	"github.com/letsencrypt/boulder/features"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/probs"
 	"github.com/letsencrypt/boulder/revocation"
 	sapb "github.com/letsencrypt/boulder/sa/proto"
 	"github.com/letsencrypt/boulder/sa/satest"
@@ -1120,6 +1122,121 @@ func TestDeactivateAuthorization(t *testing.T) {
 	test.Assert(t, pendingObj == nil, "Deactivated authorization still in pending table")
 }
 
+func TestDeactivateAuthorization2(t *testing.T) {
+	sa, fc, cleanUp := initSA(t)
+	defer cleanUp()
+	if _, err := sa.dbMap.Exec("SELECT 1 FROM authz2 LIMIT 1"); err != nil {
+		t.Skip("authz2 table requires the next database schema")
+	}
+
+	reg := satest.CreateWorkingRegistration(t, sa)
+	newAuthz2 := func(status core.AcmeStatus) int64 {
+		token := make([]byte, 32)
+		_, err := rand.Read(token)
+		test.AssertNotError(t, err, "generating token")
+		result, err := sa.dbMap.Exec(
+			`INSERT INTO authz2
+			(identifierType, identifierValue, registrationID, status, expires, challenges, token, validationError, validationRecord)
+			VALUES (0, ?, ?, ?, ?, ?, ?, "", "")`,
+			"example.com", reg.ID, statusToUint[string(status)], fc.Now().Add(time.Hour),
+			1<<challTypeToUint[core.ChallengeTypeHTTP01], token)
+		test.AssertNotError(t, err, "inserting authz2")
+		id, err := result.LastInsertId()
+		test.AssertNotError(t, err, "getting authz2 ID")
+		return id
+	}
+	statusOf := func(id int64) string {
+		status, err := sa.dbMap.SelectInt("SELECT status FROM authz2 WHERE id = ?", id)
+		test.AssertNotError(t, err, "selecting authz2 status")
+		return uintToStatus[uint(status)]
+	}
+
+	for _, status := range []core.AcmeStatus{core.StatusValid, core.StatusPending} {
+		id := newAuthz2(status)
+		_, err := sa.DeactivateAuthorization2(ctx, &sapb.AuthorizationID2{Id: &id})
+		test.AssertNotError(t, err, fmt.Sprintf("deactivating %s authz2", status))
+		test.AssertEquals(t, statusOf(id), string(core.StatusDeactivated))
+
+		_, err = sa.DeactivateAuthorization2(ctx, &sapb.AuthorizationID2{Id: &id})
+		test.Assert(t, berrors.Is(err, berrors.WrongAuthorizationState), "deactivated an already deactivated authz2")
+	}
+
+	invalid := newAuthz2(core.StatusInvalid)
+	_, err := sa.DeactivateAuthorization2(ctx, &sapb.AuthorizationID2{Id: &invalid})
+	test.Assert(t, berrors.Is(err, berrors.WrongAuthorizationState), "deactivated an invalid authz2")
+	test.AssertEquals(t, statusOf(invalid), string(core.StatusInvalid))
+
+	missing := invalid + 1000
+	_, err = sa.DeactivateAuthorization2(ctx, &sapb.AuthorizationID2{Id: &missing})
+	test.Assert(t, berrors.Is(err, berrors.NotFound), "deactivated a nonexistent authz2")
+}
+
+func TestRetryChallenge(t *testing.T) {
+	sa, fc, cleanUp := initSA(t)
+	defer cleanUp()
+
+	// The challenges table's attempts column is only in the next database
+	// schema.
+	if _, err := sa.dbMap.Exec("SELECT attempts FROM challenges LIMIT 1"); err != nil {
+		t.Skip("challenges table has no attempts column")
+	}
+	err := features.Set(map[string]bool{"StoreChallengeAttempts": true})
+	test.AssertNotError(t, err, "Failed to enable StoreChallengeAttempts feature flag")
+	defer features.Reset()
+
+	reg := satest.CreateWorkingRegistration(t, sa)
+	exp := fc.Now().Add(time.Hour)
+	authz, err := sa.NewPendingAuthorization(ctx, core.Authorization{
+		Identifier:     core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "example.com"},
+		RegistrationID: reg.ID,
+		Status:         core.StatusPending,
+		Expires:        &exp,
+		Challenges: []core.Challenge{
+			{Type: core.ChallengeTypeHTTP01, Status: core.StatusPending, Token: core.NewToken()},
+		},
+	})
+	test.AssertNotError(t, err, "Couldn't create pending authorization")
+	req := &sapb.RetryChallengeRequest{AuthorizationID: &authz.ID, ChallengeID: &authz.Challenges[0].ID}
+
+	err = sa.RetryChallenge(ctx, req)
+	test.Assert(t, berrors.Is(err, berrors.NotFound), "Retried a challenge of a pending authorization")
+
+	authz.Status = core.StatusInvalid
+	authz.Challenges[0].Status = core.StatusInvalid
+	authz.Challenges[0].Error = probs.ConnectionFailure("Connection refused")
+	authz.Challenges[0].Attempts = 1
+	err = sa.FinalizeAuthorization(ctx, authz)
+	test.AssertNotError(t, err, "Couldn't finalize authorization")
+
+	otherChall := authz.Challenges[0].ID + 1
+	err = sa.RetryChallenge(ctx, &sapb.RetryChallengeRequest{AuthorizationID: &authz.ID, ChallengeID: &otherChall})
+	test.Assert(t, berrors.Is(err, berrors.WrongAuthorizationState), "Retried a challenge of another authorization")
+
+	err = sa.RetryChallenge(ctx, req)
+	test.AssertNotError(t, err, "RetryChallenge failed")
+	retried, err := sa.GetAuthorization(ctx, authz.ID)
+	test.AssertNotError(t, err, "Couldn't get retried authorization")
+	test.AssertEquals(t, retried.Status, core.StatusPending)
+	test.AssertEquals(t, retried.Challenges[0].Status, core.StatusPending)
+	test.Assert(t, retried.Challenges[0].Error == nil, "Retried challenge kept its error")
+	test.AssertEquals(t, retried.Challenges[0].Attempts, int64(1))
+
+	// The reopened authorization is finalized as usual
+	retried.Status = core.StatusInvalid
+	retried.Challenges[0].Status = core.StatusInvalid
+	retried.Challenges[0].Attempts = 2
+	err = sa.FinalizeAuthorization(ctx, retried)
+	test.AssertNotError(t, err, "Couldn't finalize retried authorization")
+	final, err := sa.GetAuthorization(ctx, authz.ID)
+	test.AssertNotError(t, err, "Couldn't get finalized authorization")
+	test.AssertEquals(t, final.Status, core.StatusInvalid)
+	test.AssertEquals(t, final.Challenges[0].Attempts, int64(2))
+
+	fc.Add(2 * time.Hour)
+	err = sa.RetryChallenge(ctx, req)
+	test.Assert(t, berrors.Is(err, berrors.WrongAuthorizationState), "Retried a challenge of an expired authorization")
+}
+
 func TestDeactivateAccount(t *testing.T) {
 	sa, _, cleanUp := initSA(t)
 	defer cleanUp()
@@ -1373,6 +1490,53 @@ func TestNewOrder(t *testing.T) {
 	test.AssertDeepEquals(t, names, []string{"com.example", "com.example.another.just"})
 }
 
+func TestNewOrderExemptions(t *testing.T) {
+	sa, _, cleanup := initSA(t)
+	defer cleanup()
+
+	// The orderExemptions table is only in the next database schema.
+	if _, err := sa.dbMap.Exec("SELECT 1 FROM orderExemptions LIMIT 1"); err != nil {
+		t.Skip("no orderExemptions table")
+	}
+	reg, err := sa.NewRegistration(ctx, core.Registration{
+		Key:       &jose.JSONWebKey{Key: &rsa.PublicKey{N: big.NewInt(1), E: 1}},
+		InitialIP: net.ParseIP("42.42.42.42"),
+	})
+	test.AssertNotError(t, err, "Couldn't create test registration")
+	newOrder := func(tokens ...string) (*corepb.Order, error) {
+		expires := sa.clk.Now().Add(time.Hour).UnixNano()
+		return sa.NewOrder(ctx, &corepb.Order{
+			RegistrationID: &reg.ID,
+			Expires:        &expires,
+			Names:          []string{"www.example.com"},
+			Authorizations: []string{"a"},
+			Exemptions:     tokens,
+		})
+	}
+
+	// Exemptions can't be stored without the feature
+	_, err = newOrder("token-a")
+	test.AssertError(t, err, "exemptions were stored without StoreOrderExemptions")
+
+	err = features.Set(map[string]bool{"StoreOrderExemptions": true})
+	test.AssertNotError(t, err, "Failed to enable StoreOrderExemptions feature flag")
+	defer features.Reset()
+
+	order, err := newOrder("token-a")
+	test.AssertNotError(t, err, "sa.NewOrder failed")
+	stored, err := sa.GetOrder(ctx, &sapb.OrderRequest{Id: order.Id})
+	test.AssertNotError(t, err, "sa.GetOrder failed")
+	test.AssertDeepEquals(t, stored.Exemptions, []string{"token-a"})
+
+	// Neither token is stored if one has been used
+	_, err = newOrder("token-b", "token-a")
+	test.Assert(t, berrors.Is(err, berrors.Duplicate), "exemption was stored twice")
+	_, err = newOrder("token-b")
+	test.AssertNotError(t, err, "exemption of a failed order couldn't be used")
+	_, err = newOrder("token-b")
+	test.Assert(t, berrors.Is(err, berrors.Duplicate), "exemption was stored twice")
+}
+
 func TestSetOrderProcessing(t *testing.T) {
 	sa, fc, cleanup := initSA(t)
 	defer cleanup()
@@ -1485,6 +1649,123 @@ func TestFinalizeOrder(t *testing.T) {
 	test.AssertEquals(t, *updatedOrder.Status, string(core.StatusValid))
 }
 
+func TestOrderCSRHash(t *testing.T) {
+	err := features.Set(map[string]bool{"StoreOrderCSRHash": true})
+	test.AssertNotError(t, err, "setting StoreOrderCSRHash feature")
+	defer features.Reset()
+	sa, fc, cleanup := initSA(t)
+	defer cleanup()
+
+	reg := satest.CreateWorkingRegistration(t, sa)
+	authzExpires := fc.Now().Add(time.Hour)
+	authz, err := sa.NewPendingAuthorization(ctx, core.Authorization{
+		Identifier:     core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "example.com"},
+		RegistrationID: reg.ID,
+		Status:         core.StatusPending,
+		Expires:        &authzExpires,
+	})
+	test.AssertNotError(t, err, "Couldn't create new pending authorization")
+	authz.Status = core.StatusValid
+	err = sa.FinalizeAuthorization(ctx, authz)
+	test.AssertNotError(t, err, "Couldn't finalize pending authorization")
+
+	orderExpiry := fc.Now().Add(365 * 24 * time.Hour).UnixNano()
+	order, err := sa.NewOrder(ctx, &corepb.Order{
+		RegistrationID: &reg.ID,
+		Expires:        &orderExpiry,
+		Names:          []string{"example.com"},
+		Authorizations: []string{authz.ID},
+	})
+	test.AssertNotError(t, err, "NewOrder failed")
+
+	// A new order hasn't been finalized with a CSR
+	got, err := sa.GetOrder(ctx, &sapb.OrderRequest{Id: order.Id})
+	test.AssertNotError(t, err, "GetOrder failed")
+	test.AssertEquals(t, len(got.CsrHash), 0)
+
+	csrHash := sha256.Sum256([]byte("a CSR"))
+	order.CsrHash = csrHash[:]
+	err = sa.SetOrderProcessing(ctx, order)
+	test.AssertNotError(t, err, "SetOrderProcessing failed")
+
+	got, err = sa.GetOrder(ctx, &sapb.OrderRequest{Id: order.Id})
+	test.AssertNotError(t, err, "GetOrder failed")
+	test.AssertByteEquals(t, got.CsrHash, csrHash[:])
+	test.AssertEquals(t, *got.Status, string(core.StatusProcessing))
+}
+
+func TestReplacementOrders(t *testing.T) {
+	sa, fc, cleanup := initSA(t)
+	defer cleanup()
+	if _, err := sa.dbMap.Exec("SELECT 1 FROM replacementOrders LIMIT 1"); err != nil {
+		t.Skip("replacementOrders table not present")
+	}
+
+	reg := satest.CreateWorkingRegistration(t, sa)
+	authzExpires := fc.Now().Add(time.Hour)
+	authz, err := sa.NewPendingAuthorization(ctx, core.Authorization{
+		Identifier:     core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "example.com"},
+		RegistrationID: reg.ID,
+		Status:         core.StatusPending,
+		Expires:        &authzExpires,
+	})
+	test.AssertNotError(t, err, "Couldn't create new pending authorization")
+	authz.Status = core.StatusValid
+	err = sa.FinalizeAuthorization(ctx, authz)
+	test.AssertNotError(t, err, "Couldn't finalize pending authorization")
+
+	replacedSerial := "000000000000000000000000000000000001"
+	newOrder := func() *corepb.Order {
+		orderExpiry := fc.Now().Add(365 * 24 * time.Hour).UnixNano()
+		order, err := sa.NewOrder(ctx, &corepb.Order{
+			RegistrationID: &reg.ID,
+			Expires:        &orderExpiry,
+			Names:          []string{"example.com"},
+			Authorizations: []string{authz.ID},
+			Replaces:       &replacedSerial,
+		})
+		test.AssertNotError(t, err, "NewOrder failed")
+		return order
+	}
+	replaced := func() bool {
+		exists, err := sa.CertificateReplaced(ctx, &sapb.Serial{Serial: &replacedSerial})
+		test.AssertNotError(t, err, "CertificateReplaced failed")
+		return *exists.Exists
+	}
+
+	// Without the feature the replaced certificate isn't recorded
+	order := newOrder()
+	got, err := sa.GetOrder(ctx, &sapb.OrderRequest{Id: order.Id})
+	test.AssertNotError(t, err, "GetOrder failed")
+	test.Assert(t, got.Replaces == nil, "GetOrder returned a replaced certificate without StoreReplacementOrders")
+	test.Assert(t, !replaced(), "certificate was replaced without StoreReplacementOrders")
+
+	err = features.Set(map[string]bool{"StoreReplacementOrders": true})
+	test.AssertNotError(t, err, "setting StoreReplacementOrders feature")
+	defer features.Reset()
+
+	order = newOrder()
+	got, err = sa.GetOrder(ctx, &sapb.OrderRequest{Id: order.Id})
+	test.AssertNotError(t, err, "GetOrder failed")
+	test.AssertEquals(t, got.GetReplaces(), replacedSerial)
+
+	// The certificate isn't replaced until the order replacing it is finalized
+	test.Assert(t, !replaced(), "certificate was replaced by a pending order")
+	err = sa.SetOrderProcessing(ctx, order)
+	test.AssertNotError(t, err, "SetOrderProcessing failed")
+	test.Assert(t, !replaced(), "certificate was replaced by a processing order")
+	serial := "000000000000000000000000000000000002"
+	order.CertificateSerial = &serial
+	err = sa.FinalizeOrder(ctx, order)
+	test.AssertNotError(t, err, "FinalizeOrder failed")
+	test.Assert(t, replaced(), "certificate wasn't replaced by a finalized order")
+
+	otherSerial := "000000000000000000000000000000000003"
+	exists, err := sa.CertificateReplaced(ctx, &sapb.Serial{Serial: &otherSerial})
+	test.AssertNotError(t, err, "CertificateReplaced failed")
+	test.Assert(t, !*exists.Exists, "certificate no order replaces was replaced")
+}
+
 func TestOrder(t *testing.T) {
 	sa, fc, cleanup := initSA(t)
 	defer cleanup()
@@ -1620,6 +1901,68 @@ func TestGetValidOrderAuthorizations(t *testing.T) {
 	test.AssertEquals(t, len(authzMap), 0)
 }
 
+func TestGetOrderAuthorizations(t *testing.T) {
+	sa, _, cleanup := initSA(t)
+	defer cleanup()
+
+	reg := satest.CreateWorkingRegistration(t, sa)
+
+	// One of the order's authorizations is valid and the other is pending
+	validAuthz := CreateDomainAuthWithRegID(t, "example.com", sa, reg.ID)
+	exp := sa.clk.Now().Add(time.Hour * 24 * 7)
+	validAuthz.Expires = &exp
+	validAuthz.Status = "valid"
+	err := sa.FinalizeAuthorization(ctx, validAuthz)
+	test.AssertNotError(t, err, "Couldn't create final authz with ID "+validAuthz.ID)
+	pendingAuthz := CreateDomainAuthWithRegID(t, "www.example.com", sa, reg.ID)
+
+	i := time.Now().Truncate(time.Second).UnixNano()
+	status := string(core.StatusPending)
+	order, err := sa.NewOrder(context.Background(), &corepb.Order{
+		RegistrationID: &reg.ID,
+		Expires:        &i,
+		Names:          []string{"example.com", "www.example.com"},
+		Authorizations: []string{validAuthz.ID, pendingAuthz.ID},
+		Status:         &status,
+	})
+	test.AssertNotError(t, err, "NewOrder failed")
+
+	authzMap, err := sa.GetOrderAuthorizations(
+		context.Background(),
+		&sapb.GetOrderAuthorizationsRequest{
+			Id:     order.Id,
+			AcctID: &reg.ID,
+		})
+	test.AssertNotError(t, err, "GetOrderAuthorizations failed")
+	test.AssertEquals(t, len(authzMap), 2)
+	test.AssertEquals(t, authzMap["example.com"].ID, validAuthz.ID)
+	test.AssertEquals(t, authzMap["example.com"].Status, core.StatusValid)
+	test.AssertEquals(t, authzMap["www.example.com"].ID, pendingAuthz.ID)
+	test.AssertEquals(t, authzMap["www.example.com"].Status, core.StatusPending)
+
+	// An order that doesn't exist, or belongs to another account, has no
+	// authorizations
+	missingID := int64(0xC0FFEEEEEEE)
+	authzMap, err = sa.GetOrderAuthorizations(
+		context.Background(),
+		&sapb.GetOrderAuthorizationsRequest{
+			Id:     &missingID,
+			AcctID: &reg.ID,
+		})
+	test.AssertNotError(t, err, "GetOrderAuthorizations for non-existent order errored")
+	test.AssertEquals(t, len(authzMap), 0)
+
+	wrongAcctID := int64(0xDEADDA7ABA5E)
+	authzMap, err = sa.GetOrderAuthorizations(
+		context.Background(),
+		&sapb.GetOrderAuthorizationsRequest{
+			Id:     order.Id,
+			AcctID: &wrongAcctID,
+		})
+	test.AssertNotError(t, err, "GetOrderAuthorizations for existent order, wrong acctID errored")
+	test.AssertEquals(t, len(authzMap), 0)
+}
+
 // TestGetAuthorizationNoRows ensures that the GetAuthorization function returns
 // the correct error when there are no results for the provided ID.
 func TestGetAuthorizationNoRows(t *testing.T) {
@@ -2573,3 +2916,402 @@ func TestCountCertificatesRenewalBit(t *testing.T) {
 	// CertB is not ignored as a renewal because the feature flag is disabled.
 	test.AssertEquals(t, countNameExact(t, "not-example.com"), int64(2))
 }
+
+func TestSearchCertificates(t *testing.T) {
+	sa, _, cleanUp := initSA(t)
+	defer cleanUp()
+
+	// The keyHashToSerial table is only in the next database schema.
+	_, err := sa.dbMap.Exec("SELECT 1 FROM keyHashToSerial LIMIT 1")
+	storeKeyHashes := err == nil
+	if storeKeyHashes {
+		err = features.Set(map[string]bool{"StoreKeyHashes": true})
+		test.AssertNotError(t, err, "Failed to enable StoreKeyHashes feature flag")
+		defer features.Reset()
+	}
+
+	reg := satest.CreateWorkingRegistration(t, sa)
+	// names [example.com, www.example.com, admin.example.com]
+	certDER, err := ioutil.ReadFile("test-cert.der")
+	test.AssertNotError(t, err, "Couldn't read example cert DER")
+	issued := sa.clk.Now()
+	_, err = sa.AddCertificate(ctx, certDER, reg.ID, nil, &issued)
+	test.AssertNotError(t, err, "Couldn't add test-cert.der")
+	serial := "ffdd9b8a82126d96f61d378d5ba99a0474f0"
+
+	search := func(req *sapb.SearchCertificatesRequest) *sapb.CertificateSearchResults {
+		results, err := sa.SearchCertificates(ctx, req)
+		test.AssertNotError(t, err, "SearchCertificates failed")
+		return results
+	}
+
+	results := search(&sapb.SearchCertificatesRequest{Serial: &serial})
+	test.AssertEquals(t, len(results.Certificates), 1)
+	test.AssertEquals(t, *results.Certificates[0].RegistrationID, reg.ID)
+	test.AssertEquals(t, *results.Certificates[0].Status, string(core.OCSPStatusGood))
+	test.AssertEquals(t, len(results.Certificates[0].DnsNames), 3)
+	test.Assert(t, results.NextCursor == nil, "single result had a next cursor")
+
+	missing := "000000000000000000000000000000000001"
+	results = search(&sapb.SearchCertificatesRequest{Serial: &missing})
+	test.AssertEquals(t, len(results.Certificates), 0)
+
+	fqdn := "WWW.example.com"
+	results = search(&sapb.SearchCertificatesRequest{Fqdn: &fqdn})
+	test.AssertEquals(t, len(results.Certificates), 1)
+
+	// Each of the certificate's three names is a result, one page at a time.
+	domain := "example.com"
+	limit := int64(2)
+	results = search(&sapb.SearchCertificatesRequest{RegisteredDomain: &domain, Limit: &limit})
+	test.AssertEquals(t, len(results.Certificates), 2)
+	test.Assert(t, results.NextCursor != nil, "first page had no next cursor")
+	results = search(&sapb.SearchCertificatesRequest{RegisteredDomain: &domain, Limit: &limit, Cursor: results.NextCursor})
+	test.AssertEquals(t, len(results.Certificates), 1)
+	test.Assert(t, results.NextCursor == nil, "last page had a next cursor")
+
+	// Wildcard characters in the domain only match themselves
+	wildcard := "exampl_.com"
+	results = search(&sapb.SearchCertificatesRequest{RegisteredDomain: &wildcard})
+	test.AssertEquals(t, len(results.Certificates), 0)
+
+	if storeKeyHashes {
+		parsed, err := x509.ParseCertificate(certDER)
+		test.AssertNotError(t, err, "Couldn't parse test-cert.der")
+		keyHash := sha256.Sum256(parsed.RawSubjectPublicKeyInfo)
+		results = search(&sapb.SearchCertificatesRequest{SpkiHash: keyHash[:]})
+		test.AssertEquals(t, len(results.Certificates), 1)
+		test.AssertEquals(t, *results.Certificates[0].Serial, serial)
+	}
+
+	subdomain := "www.example.com"
+	badCursor := "!"
+	for _, req := range []*sapb.SearchCertificatesRequest{
+		{},
+		{Serial: &serial, Fqdn: &fqdn},
+		{RegisteredDomain: &subdomain},
+		{SpkiHash: []byte{1}},
+		{Fqdn: &fqdn, Cursor: &badCursor},
+	} {
+		_, err := sa.SearchCertificates(ctx, req)
+		test.Assert(t, berrors.Is(err, berrors.Malformed), fmt.Sprintf("%v wasn't malformed: %v", req, err))
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	test.AssertEquals(t, escapeLike(`com.example`), `com.example`)
+	test.AssertEquals(t, escapeLike(`com.exa_ple`), `com.exa\_ple`)
+	test.AssertEquals(t, escapeLike(`com.%`), `com.\%`)
+	test.AssertEquals(t, escapeLike(`com.a\_`), `com.a\\\_`)
+}
+
+func TestListCertificatesForAccount(t *testing.T) {
+	sa, fc, cleanUp := initSA(t)
+	defer cleanUp()
+
+	reg := satest.CreateWorkingRegistration(t, sa)
+	// names [example.com, www.example.com, admin.example.com], expires
+	// 2015-12-27
+	certDER, err := ioutil.ReadFile("test-cert.der")
+	test.AssertNotError(t, err, "Couldn't read example cert DER")
+	issued := fc.Now()
+	_, err = sa.AddCertificate(ctx, certDER, reg.ID, nil, &issued)
+	test.AssertNotError(t, err, "Couldn't add test-cert.der")
+	// names [example.co.bn], expires 2016-01-01
+	certDER2, err := ioutil.ReadFile("test-cert2.der")
+	test.AssertNotError(t, err, "Couldn't read test-cert2.der")
+	_, err = sa.AddCertificate(ctx, certDER2, reg.ID, nil, &issued)
+	test.AssertNotError(t, err, "Couldn't add test-cert2.der")
+	serial, serial2 := "ffdd9b8a82126d96f61d378d5ba99a0474f0", "ffa0160630d618b2eb5c0510824b14274856"
+
+	list := func(req *sapb.ListCertificatesForAccountRequest) *sapb.CertificateSearchResults {
+		if req.RegistrationID == nil {
+			req.RegistrationID = &reg.ID
+		}
+		results, err := sa.ListCertificatesForAccount(ctx, req)
+		test.AssertNotError(t, err, "ListCertificatesForAccount failed")
+		return results
+	}
+	serials := func(results *sapb.CertificateSearchResults) []string {
+		var serials []string
+		for _, c := range results.Certificates {
+			serials = append(serials, *c.Serial)
+		}
+		return serials
+	}
+
+	// The most recently added certificate is first, one page at a time.
+	results := list(&sapb.ListCertificatesForAccountRequest{})
+	test.AssertDeepEquals(t, serials(results), []string{serial2, serial})
+	test.Assert(t, results.NextCursor == nil, "only page had a next cursor")
+	limit := int64(1)
+	results = list(&sapb.ListCertificatesForAccountRequest{Limit: &limit})
+	test.AssertDeepEquals(t, serials(results), []string{serial2})
+	test.Assert(t, results.NextCursor != nil, "first page had no next cursor")
+	results = list(&sapb.ListCertificatesForAccountRequest{Limit: &limit, Cursor: results.NextCursor})
+	test.AssertDeepEquals(t, serials(results), []string{serial})
+	test.Assert(t, results.NextCursor == nil, "last page had a next cursor")
+
+	good, revoked := string(core.OCSPStatusGood), string(core.OCSPStatusRevoked)
+	test.AssertEquals(t, len(list(&sapb.ListCertificatesForAccountRequest{Status: &good}).Certificates), 2)
+	test.AssertEquals(t, len(list(&sapb.ListCertificatesForAccountRequest{Status: &revoked}).Certificates), 0)
+	before := time.Date(2015, 12, 31, 0, 0, 0, 0, time.UTC).UnixNano()
+	test.AssertDeepEquals(t, serials(list(&sapb.ListCertificatesForAccountRequest{ExpiresBefore: &before})), []string{serial})
+	test.AssertDeepEquals(t, serials(list(&sapb.ListCertificatesForAccountRequest{ExpiresAfter: &before})), []string{serial2})
+
+	// Filtering by name skips certificates without a match, even across pages.
+	name := "Admin.Example"
+	results = list(&sapb.ListCertificatesForAccountRequest{NameContains: &name, Limit: &limit})
+	test.AssertDeepEquals(t, serials(results), []string{serial})
+	test.Assert(t, results.NextCursor == nil, "last page had a next cursor")
+
+	otherReg := reg.ID + 1
+	test.AssertEquals(t, len(list(&sapb.ListCertificatesForAccountRequest{RegistrationID: &otherReg}).Certificates), 0)
+
+	badStatus, badCursor := "unknown", "!"
+	for _, req := range []*sapb.ListCertificatesForAccountRequest{
+		{},
+		{RegistrationID: &reg.ID, Status: &badStatus},
+		{RegistrationID: &reg.ID, Cursor: &badCursor},
+	} {
+		_, err := sa.ListCertificatesForAccount(ctx, req)
+		test.Assert(t, berrors.Is(err, berrors.Malformed), fmt.Sprintf("%v wasn't malformed: %v", req, err))
+	}
+}
+
+func TestListOrdersForAccount(t *testing.T) {
+	sa, fc, cleanUp := initSA(t)
+	defer cleanUp()
+
+	reg := satest.CreateWorkingRegistration(t, sa)
+	otherReg := satest.CreateWorkingRegistration(t, sa)
+	newOrder := func(regID int64) int64 {
+		authzExpires := fc.Now().Add(time.Hour)
+		authz, err := sa.NewPendingAuthorization(ctx, core.Authorization{
+			Identifier:     core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "example.com"},
+			RegistrationID: regID,
+			Status:         core.StatusPending,
+			Expires:        &authzExpires,
+		})
+		test.AssertNotError(t, err, "Couldn't create new pending authorization")
+		orderExpiry := fc.Now().Add(24 * time.Hour).UnixNano()
+		order, err := sa.NewOrder(ctx, &corepb.Order{
+			RegistrationID: &regID,
+			Expires:        &orderExpiry,
+			Names:          []string{"example.com"},
+			Authorizations: []string{authz.ID},
+		})
+		test.AssertNotError(t, err, "NewOrder failed")
+		return *order.Id
+	}
+	first, second, third := newOrder(reg.ID), newOrder(reg.ID), newOrder(reg.ID)
+	other := newOrder(otherReg.ID)
+
+	list := func(req *sapb.ListOrdersForAccountRequest) *sapb.OrderList {
+		orders, err := sa.ListOrdersForAccount(ctx, req)
+		test.AssertNotError(t, err, "ListOrdersForAccount failed")
+		return orders
+	}
+	ids := func(orders *sapb.OrderList) []int64 {
+		var ids []int64
+		for _, o := range orders.Orders {
+			ids = append(ids, *o.Id)
+		}
+		return ids
+	}
+
+	// The most recent order is first, and only the account's own orders are
+	// listed
+	orders := list(&sapb.ListOrdersForAccountRequest{RegistrationID: &reg.ID})
+	test.AssertDeepEquals(t, ids(orders), []int64{third, second, first})
+	test.Assert(t, orders.NextCursor == nil, "only page had a next cursor")
+	orders = list(&sapb.ListOrdersForAccountRequest{RegistrationID: &otherReg.ID})
+	test.AssertDeepEquals(t, ids(orders), []int64{other})
+
+	// One page at a time
+	limit := int64(2)
+	orders = list(&sapb.ListOrdersForAccountRequest{RegistrationID: &reg.ID, Limit: &limit})
+	test.AssertDeepEquals(t, ids(orders), []int64{third, second})
+	test.Assert(t, orders.NextCursor != nil, "first page had no next cursor")
+	orders = list(&sapb.ListOrdersForAccountRequest{RegistrationID: &reg.ID, Limit: &limit, Cursor: orders.NextCursor})
+	test.AssertDeepEquals(t, ids(orders), []int64{first})
+	test.Assert(t, orders.NextCursor == nil, "last page had a next cursor")
+
+	// A page that ends exactly at the last order has no next cursor
+	limit = 3
+	orders = list(&sapb.ListOrdersForAccountRequest{RegistrationID: &reg.ID, Limit: &limit})
+	test.AssertEquals(t, len(orders.Orders), 3)
+	test.Assert(t, orders.NextCursor == nil, "full last page had a next cursor")
+
+	// A cursor from one account's listing doesn't reach another account's
+	// orders
+	limit = 1
+	orders = list(&sapb.ListOrdersForAccountRequest{RegistrationID: &reg.ID, Limit: &limit})
+	orders = list(&sapb.ListOrdersForAccountRequest{RegistrationID: &otherReg.ID, Cursor: orders.NextCursor})
+	test.AssertDeepEquals(t, ids(orders), []int64(nil))
+
+	badCursor := "!"
+	for _, req := range []*sapb.ListOrdersForAccountRequest{
+		{},
+		{RegistrationID: &reg.ID, Cursor: &badCursor},
+	} {
+		_, err := sa.ListOrdersForAccount(ctx, req)
+		test.Assert(t, berrors.Is(err, berrors.Malformed), fmt.Sprintf("%v wasn't malformed: %v", req, err))
+	}
+}
+
+func TestListInvalidAuthorizationsForAccount(t *testing.T) {
+	sa, fc, cleanUp := initSA(t)
+	defer cleanUp()
+
+	reg := satest.CreateWorkingRegistration(t, sa)
+	otherReg := satest.CreateWorkingRegistration(t, sa)
+	newAuthz := func(regID int64, status core.AcmeStatus, expiresIn time.Duration) string {
+		expires := fc.Now().Add(expiresIn)
+		authz, err := sa.NewPendingAuthorization(ctx, core.Authorization{
+			Identifier:     core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "example.com"},
+			RegistrationID: regID,
+			Status:         core.StatusPending,
+			Expires:        &expires,
+			Challenges:     []core.Challenge{{Type: core.ChallengeTypeHTTP01, Status: core.StatusPending, Token: core.NewToken()}},
+		})
+		test.AssertNotError(t, err, "Couldn't create new pending authorization")
+		authz.Status = status
+		authz.Challenges[0].Status = status
+		if status == core.StatusInvalid {
+			authz.Challenges[0].Error = probs.Unauthorized("no key authorization")
+		}
+		err = sa.FinalizeAuthorization(ctx, authz)
+		test.AssertNotError(t, err, "Couldn't finalize authorization")
+		return authz.ID
+	}
+	soon := newAuthz(reg.ID, core.StatusInvalid, time.Hour)
+	later := newAuthz(reg.ID, core.StatusInvalid, 2*time.Hour)
+	_ = newAuthz(reg.ID, core.StatusValid, 3*time.Hour)
+	other := newAuthz(otherReg.ID, core.StatusInvalid, time.Hour)
+
+	list := func(regID int64, expiresAfter time.Time, limit int64) []string {
+		expiresAfterNS := expiresAfter.UnixNano()
+		authzs, err := sa.ListInvalidAuthorizationsForAccount(ctx, &sapb.ListInvalidAuthorizationsForAccountRequest{
+			RegistrationID: &regID,
+			ExpiresAfter:   &expiresAfterNS,
+			Limit:          &limit,
+		})
+		test.AssertNotError(t, err, "ListInvalidAuthorizationsForAccount failed")
+		var ids []string
+		for _, authz := range authzs.Authorizations {
+			test.AssertEquals(t, *authz.RegistrationID, regID)
+			test.AssertEquals(t, *authz.Status, string(core.StatusInvalid))
+			test.AssertEquals(t, len(authz.Challenges), 1)
+			test.Assert(t, authz.Challenges[0].Error != nil, "invalid challenge had no error")
+			ids = append(ids, *authz.Id)
+		}
+		return ids
+	}
+
+	// Only the account's own invalid authorizations are listed, latest expiry
+	// first
+	test.AssertDeepEquals(t, list(reg.ID, fc.Now(), 0), []string{later, soon})
+	test.AssertDeepEquals(t, list(otherReg.ID, fc.Now(), 0), []string{other})
+
+	// The limit cuts the list short from its end, and expiresAfter from its
+	// start
+	test.AssertDeepEquals(t, list(reg.ID, fc.Now(), 1), []string{later})
+	test.AssertDeepEquals(t, list(reg.ID, fc.Now().Add(90*time.Minute), 0), []string{later})
+	test.AssertDeepEquals(t, list(reg.ID, fc.Now().Add(2*time.Hour), 0), []string(nil))
+
+	_, err := sa.ListInvalidAuthorizationsForAccount(ctx, &sapb.ListInvalidAuthorizationsForAccountRequest{})
+	test.Assert(t, berrors.Is(err, berrors.Malformed), "request without a registration ID wasn't malformed")
+}
+
+func TestRateLimitOverrides(t *testing.T) {
+	sa, fc, cleanUp := initSA(t)
+	defer cleanUp()
+
+	// The rateLimitOverrides table is only in the next database schema.
+	if _, err := sa.dbMap.Exec("SELECT id FROM rateLimitOverrides LIMIT 1"); err != nil {
+		t.Skip("no rateLimitOverrides table")
+	}
+
+	active := func() []*sapb.RateLimitOverride {
+		t.Helper()
+		now := fc.Now().UnixNano()
+		resp, err := sa.GetRateLimitOverrides(ctx, &sapb.GetRateLimitOverridesRequest{Now: &now})
+		test.AssertNotError(t, err, "GetRateLimitOverrides failed")
+		return resp.Overrides
+	}
+	override := func(limit, key string, regID, threshold int64, expires time.Time) *sapb.RateLimitOverride {
+		createdBy, reason := "jsmith", "hosting provider"
+		exp := expires.UnixNano()
+		return &sapb.RateLimitOverride{
+			LimitName:      &limit,
+			Key:            &key,
+			RegistrationID: &regID,
+			Threshold:      &threshold,
+			CreatedBy:      &createdBy,
+			Expires:        &exp,
+			Reason:         &reason,
+		}
+	}
+	test.AssertEquals(t, len(active()), 0)
+
+	added, err := sa.AddRateLimitOverride(ctx, override("certificatesPerName", "example.com", 0, 1000, fc.Now().Add(time.Hour)))
+	test.AssertNotError(t, err, "AddRateLimitOverride failed")
+	test.Assert(t, *added.Id != 0, "added override has no ID")
+	test.AssertEquals(t, *added.Created, fc.Now().UnixNano())
+	_, err = sa.AddRateLimitOverride(ctx, override("newOrdersPerAccount", "", 5, 50, fc.Now().Add(2*time.Hour)))
+	test.AssertNotError(t, err, "AddRateLimitOverride failed")
+
+	overrides := active()
+	test.AssertEquals(t, len(overrides), 2)
+	for _, o := range overrides {
+		switch *o.LimitName {
+		case "certificatesPerName":
+			test.AssertEquals(t, *o.Id, *added.Id)
+			test.AssertEquals(t, *o.Key, "example.com")
+			test.AssertEquals(t, *o.Threshold, int64(1000))
+		case "newOrdersPerAccount":
+			test.AssertEquals(t, *o.RegistrationID, int64(5))
+			test.AssertEquals(t, *o.Key, "")
+		default:
+			t.Errorf("unexpected override for %s", *o.LimitName)
+		}
+	}
+
+	// A new override for the same limit and key replaces the old one
+	replacement, err := sa.AddRateLimitOverride(ctx, override("certificatesPerName", "example.com", 0, 2000, fc.Now().Add(time.Hour)))
+	test.AssertNotError(t, err, "AddRateLimitOverride failed")
+	overrides = active()
+	test.AssertEquals(t, len(overrides), 2)
+	for _, o := range overrides {
+		if *o.LimitName == "certificatesPerName" {
+			test.AssertEquals(t, *o.Id, *replacement.Id)
+			test.AssertEquals(t, *o.Threshold, int64(2000))
+		}
+	}
+
+	// Expired overrides are ignored
+	expiredBy, reason := "jsmith", "no longer needed"
+	err = sa.ExpireRateLimitOverride(ctx, &sapb.ExpireRateLimitOverrideRequest{Id: replacement.Id, ExpiredBy: &expiredBy, Reason: &reason})
+	test.AssertNotError(t, err, "ExpireRateLimitOverride failed")
+	overrides = active()
+	test.AssertEquals(t, len(overrides), 1)
+	test.AssertEquals(t, *overrides[0].LimitName, "newOrdersPerAccount")
+	err = sa.ExpireRateLimitOverride(ctx, &sapb.ExpireRateLimitOverrideRequest{Id: replacement.Id, ExpiredBy: &expiredBy, Reason: &reason})
+	test.Assert(t, berrors.Is(err, berrors.NotFound), "Expired an override twice")
+
+	// So are overrides whose expiry has passed
+	fc.Add(2 * time.Hour)
+	test.AssertEquals(t, len(active()), 0)
+
+	for _, invalid := range []*sapb.RateLimitOverride{
+		override("bogusLimit", "example.com", 0, 10, fc.Now().Add(time.Hour)),
+		override("certificatesPerName", "example.com", 5, 10, fc.Now().Add(time.Hour)),
+		override("certificatesPerName", "", 0, 10, fc.Now().Add(time.Hour)),
+		override("certificatesPerName", "example.com", 0, -1, fc.Now().Add(time.Hour)),
+		override("certificatesPerName", "example.com", 0, 10, fc.Now()),
+	} {
+		_, err := sa.AddRateLimitOverride(ctx, invalid)
+		test.Assert(t, berrors.Is(err, berrors.Malformed), fmt.Sprintf("%v wasn't malformed: %v", invalid, err))
+	}
+}
@@ -0,0 +1,571 @@
+package sa
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/weppos/publicsuffix-go/publicsuffix"
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
+	bgrpc "github.com/letsencrypt/boulder/grpc"
+	"github.com/letsencrypt/boulder/revocation"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+)
+
+const (
+	// defaultSearchLimit is the number of results in a page of
+	// SearchCertificates results if the request doesn't set a limit.
+	defaultSearchLimit = 100
+	// maxSearchLimit is the largest page of results SearchCertificates returns.
+	maxSearchLimit = 1000
+)
+
+// keyHashModel represents one row in the keyHashToSerial table.
+type keyHashModel struct {
+	ID           int64     `db:"id"`
+	KeyHash      []byte    `db:"keyHash"`
+	CertNotAfter time.Time `db:"certNotAfter"`
+	CertSerial   string    `db:"certSerial"`
+}
+
+// addKeyHash records the hash of cert's SubjectPublicKeyInfo so that the
+// certificate can be found by SearchCertificates.
+func addKeyHash(db dbInserter, cert *x509.Certificate) error {
+	keyHash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return db.Insert(&keyHashModel{
+		KeyHash:      keyHash[:],
+		CertNotAfter: cert.NotAfter,
+		CertSerial:   core.SerialToString(cert.SerialNumber),
+	})
+}
+
+// searchCursor is the position of the last result on a page of search
+// results. It is handed to the caller as an opaque string, and the next page
+// starts after it. The results are ordered by the indexes used to find them,
+// so each page is a single range scan of an index.
+type searchCursor struct {
+	ReversedName string `json:"name,omitempty"`
+	Time         int64  `json:"time"`
+	ID           int64  `json:"id"`
+}
+
+func (sc searchCursor) encode() string {
+	// Marshalling a struct of strings and ints can't fail.
+	b, _ := json.Marshal(sc)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSearchCursor(s string) (*searchCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, berrors.MalformedError("invalid search cursor")
+	}
+	var sc searchCursor
+	if err := json.Unmarshal(b, &sc); err != nil {
+		return nil, berrors.MalformedError("invalid search cursor")
+	}
+	return &sc, nil
+}
+
+// SearchCertificates returns a page of the certificates with a given serial,
+// DNS name, registered domain or key. Certificates found by name are ordered
+// by name and then newest first, and a certificate appears once for each of
+// its names that matches. Certificates found by key are ordered newest first,
+// and can only be found if they were added with the StoreKeyHashes feature
+// enabled.
+func (ssa *SQLStorageAuthority) SearchCertificates(ctx context.Context, req *sapb.SearchCertificatesRequest) (*sapb.CertificateSearchResults, error) {
+	var set int
+	for _, isSet := range []bool{
+		req.Serial != nil && *req.Serial != "",
+		req.Fqdn != nil && *req.Fqdn != "",
+		req.RegisteredDomain != nil && *req.RegisteredDomain != "",
+		len(req.SpkiHash) > 0,
+	} {
+		if isSet {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, berrors.MalformedError("exactly one of serial, fqdn, registeredDomain or spkiHash must be given")
+	}
+
+	limit := int64(defaultSearchLimit)
+	if req.Limit != nil && *req.Limit > 0 {
+		limit = *req.Limit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	var cursor *searchCursor
+	if req.Cursor != nil && *req.Cursor != "" {
+		var err error
+		cursor, err = decodeSearchCursor(*req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var serials []string
+	var next *searchCursor
+	var err error
+	switch {
+	case req.Serial != nil && *req.Serial != "":
+		if !core.ValidSerial(*req.Serial) {
+			return nil, berrors.MalformedError("invalid serial %q", *req.Serial)
+		}
+		serials = []string{*req.Serial}
+	case req.Fqdn != nil && *req.Fqdn != "":
+		var name string
+		name, err = core.NormalizeName(*req.Fqdn)
+		if err != nil {
+			return nil, err
+		}
+		serials, next, err = ssa.searchIssuedNames(ctx, ReverseName(name), false, cursor, limit)
+	case req.RegisteredDomain != nil && *req.RegisteredDomain != "":
+		var domain string
+		domain, err = core.NormalizeName(*req.RegisteredDomain)
+		if err != nil {
+			return nil, err
+		}
+		if registered, err := publicsuffix.Domain(domain); err != nil || registered != domain {
+			return nil, berrors.MalformedError("%q is not a registered domain", domain)
+		}
+		serials, next, err = ssa.searchIssuedNames(ctx, ReverseName(domain), true, cursor, limit)
+	default:
+		if len(req.SpkiHash) != 32 {
+			return nil, berrors.MalformedError("spkiHash must be a SHA-256 hash")
+		}
+		serials, next, err = ssa.searchKeyHashes(ctx, req.SpkiHash, cursor, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results := &sapb.CertificateSearchResults{}
+	for _, serial := range serials {
+		result, err := ssa.certificateSearchResult(ctx, serial)
+		if berrors.Is(err, berrors.NotFound) {
+			// Names and key hashes are only added in the same transaction as
+			// their certificate, so only a serial search finds nothing.
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		results.Certificates = append(results.Certificates, result)
+	}
+	if next != nil {
+		nextCursor := next.encode()
+		results.NextCursor = &nextCursor
+	}
+	return results, nil
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern, and the
+// backslash used to escape them.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// searchIssuedNames returns the serials of up to limit certificates for
+// reversedName, or if includeSubdomains is true for reversedName and all of
+// its subdomains, starting after cursor. If there are more, it also returns
+// the cursor for the next page.
+func (ssa *SQLStorageAuthority) searchIssuedNames(
+	ctx context.Context,
+	reversedName string,
+	includeSubdomains bool,
+	cursor *searchCursor,
+	limit int64,
+) ([]string, *searchCursor, error) {
+	query := `SELECT id, reversedName, notBefore, serial FROM issuedNames WHERE `
+	args := map[string]interface{}{
+		"reversedName": reversedName,
+		// One more row than the limit tells whether there is another page.
+		"limit": limit + 1,
+	}
+	if includeSubdomains {
+		query += `(reversedName = :reversedName OR reversedName LIKE :subdomains ESCAPE '\\')`
+		args["subdomains"] = escapeLike(reversedName) + ".%"
+	} else {
+		query += `reversedName = :reversedName`
+	}
+	if cursor != nil {
+		query += ` AND (reversedName, notBefore, id) < (:cursorName, :cursorTime, :cursorID)`
+		args["cursorName"] = cursor.ReversedName
+		args["cursorTime"] = time.Unix(0, cursor.Time)
+		args["cursorID"] = cursor.ID
+	}
+	query += ` ORDER BY reversedName DESC, notBefore DESC, id DESC LIMIT :limit`
+
+	var rows []issuedNameModel
+	_, err := ssa.db(ctx).Select(&rows, query, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	var next *searchCursor
+	if int64(len(rows)) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		next = &searchCursor{ReversedName: last.ReversedName, Time: last.NotBefore.UnixNano(), ID: last.ID}
+	}
+	serials := make([]string, len(rows))
+	for i, row := range rows {
+		serials[i] = row.Serial
+	}
+	return serials, next, nil
+}
+
+// searchKeyHashes returns the serials of up to limit certificates for the key
+// with the given hash, starting after cursor. If there are more, it also
+// returns the cursor for the next page.
+func (ssa *SQLStorageAuthority) searchKeyHashes(
+	ctx context.Context,
+	keyHash []byte,
+	cursor *searchCursor,
+	limit int64,
+) ([]string, *searchCursor, error) {
+	query := `SELECT id, keyHash, certNotAfter, certSerial FROM keyHashToSerial WHERE keyHash = :keyHash`
+	args := map[string]interface{}{
+		"keyHash": keyHash,
+		// One more row than the limit tells whether there is another page.
+		"limit": limit + 1,
+	}
+	if cursor != nil {
+		query += ` AND (certNotAfter, id) < (:cursorTime, :cursorID)`
+		args["cursorTime"] = time.Unix(0, cursor.Time)
+		args["cursorID"] = cursor.ID
+	}
+	query += ` ORDER BY certNotAfter DESC, id DESC LIMIT :limit`
+
+	var rows []keyHashModel
+	_, err := ssa.db(ctx).Select(&rows, query, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	var next *searchCursor
+	if int64(len(rows)) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		next = &searchCursor{Time: last.CertNotAfter.UnixNano(), ID: last.ID}
+	}
+	serials := make([]string, len(rows))
+	for i, row := range rows {
+		serials[i] = row.CertSerial
+	}
+	return serials, next, nil
+}
+
+// certificateSearchResult returns the summary of the certificate with the
+// given serial that is included in search results.
+func (ssa *SQLStorageAuthority) certificateSearchResult(ctx context.Context, serial string) (*sapb.CertificateSearchResult, error) {
+	cert, err := SelectCertificate(ssa.db(ctx), "WHERE serial = ?", serial)
+	if err == sql.ErrNoRows {
+		return nil, berrors.NotFoundError("certificate with serial %q not found", serial)
+	} else if err != nil {
+		return nil, err
+	}
+	parsed, err := x509.ParseCertificate(cert.DER)
+	if err != nil {
+		return nil, err
+	}
+	var status struct {
+		Status        core.OCSPStatus   `db:"status"`
+		RevokedDate   time.Time         `db:"revokedDate"`
+		RevokedReason revocation.Reason `db:"revokedReason"`
+	}
+	err = ssa.db(ctx).SelectOne(
+		&status,
+		`SELECT status, revokedDate, revokedReason FROM certificateStatus WHERE serial = ?`,
+		serial,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	issued := cert.Issued.UnixNano()
+	expires := cert.Expires.UnixNano()
+	statusStr := string(status.Status)
+	result := &sapb.CertificateSearchResult{
+		Serial:         &cert.Serial,
+		RegistrationID: &cert.RegistrationID,
+		Issued:         &issued,
+		Expires:        &expires,
+		DnsNames:       parsed.DNSNames,
+		Status:         &statusStr,
+	}
+	if status.Status == core.OCSPStatusRevoked {
+		revokedDate := status.RevokedDate.UnixNano()
+		revokedReason := int64(status.RevokedReason)
+		result.RevokedDate = &revokedDate
+		result.RevokedReason = &revokedReason
+	}
+	return result, nil
+}
+
+// maxListScan is the most certificates ListCertificatesForAccount examines
+// for a single page when filtering by name. If it examines that many without
+// filling the page, it returns a short page with a cursor to carry on from.
+const maxListScan = 5000
+
+// accountCertificateModel is a certificate and its status, as listed by
+// ListCertificatesForAccount.
+type accountCertificateModel struct {
+	ID             int64             `db:"id"`
+	Serial         string            `db:"serial"`
+	RegistrationID int64             `db:"registrationID"`
+	DER            []byte            `db:"der"`
+	Issued         time.Time         `db:"issued"`
+	Expires        time.Time         `db:"expires"`
+	Status         core.OCSPStatus   `db:"status"`
+	RevokedDate    time.Time         `db:"revokedDate"`
+	RevokedReason  revocation.Reason `db:"revokedReason"`
+}
+
+// ListCertificatesForAccount returns a page of an account's certificates,
+// newest first, optionally filtered by status, by an expiry window and by a
+// substring of their DNS names. The name filter can't use an index, so a page
+// of results filtered by name may be short, or even empty, while still having
+// a cursor for the next page.
+func (ssa *SQLStorageAuthority) ListCertificatesForAccount(ctx context.Context, req *sapb.ListCertificatesForAccountRequest) (*sapb.CertificateSearchResults, error) {
+	if req.RegistrationID == nil || *req.RegistrationID == 0 {
+		return nil, berrors.MalformedError("a registration ID must be given")
+	}
+	limit := int64(defaultSearchLimit)
+	if req.Limit != nil && *req.Limit > 0 {
+		limit = *req.Limit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	query := `SELECT c.id, c.serial, c.registrationID, c.der, c.issued, c.expires,
+		cs.status, cs.revokedDate, cs.revokedReason
+		FROM certificates AS c
+		JOIN certificateStatus AS cs ON cs.serial = c.serial
+		WHERE c.registrationID = :regID`
+	args := map[string]interface{}{"regID": *req.RegistrationID}
+	if req.Status != nil && *req.Status != "" {
+		status := core.OCSPStatus(*req.Status)
+		if status != core.OCSPStatusGood && status != core.OCSPStatusRevoked {
+			return nil, berrors.MalformedError("status must be %q or %q", core.OCSPStatusGood, core.OCSPStatusRevoked)
+		}
+		query += ` AND cs.status = :status`
+		args["status"] = string(status)
+	}
+	if req.ExpiresAfter != nil && *req.ExpiresAfter != 0 {
+		query += ` AND c.expires > :expiresAfter`
+		args["expiresAfter"] = time.Unix(0, *req.ExpiresAfter)
+	}
+	if req.ExpiresBefore != nil && *req.ExpiresBefore != 0 {
+		query += ` AND c.expires < :expiresBefore`
+		args["expiresBefore"] = time.Unix(0, *req.ExpiresBefore)
+	}
+	cursorID := int64(math.MaxInt64)
+	if req.Cursor != nil && *req.Cursor != "" {
+		cursor, err := decodeSearchCursor(*req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursorID = cursor.ID
+	}
+	query += ` AND c.id < :cursorID ORDER BY c.id DESC LIMIT :batch`
+	// Without a name filter every row is a result, so one batch fills the
+	// page. With one, rows are read in larger batches until the page is full.
+	batch := limit
+	var nameContains string
+	if req.NameContains != nil && *req.NameContains != "" {
+		nameContains = strings.ToLower(*req.NameContains)
+		batch = maxSearchLimit
+	}
+	// One more row than the batch tells whether there are more.
+	args["batch"] = batch + 1
+
+	results := &sapb.CertificateSearchResults{}
+	var scanned int64
+	for {
+		args["cursorID"] = cursorID
+		var rows []accountCertificateModel
+		_, err := ssa.db(ctx).Select(&rows, query, args)
+		if err != nil {
+			return nil, err
+		}
+		more := int64(len(rows)) > batch
+		if more {
+			rows = rows[:batch]
+		}
+		for i, row := range rows {
+			scanned++
+			cursorID = row.ID
+			result, err := row.searchResult()
+			if err != nil {
+				return nil, err
+			}
+			if nameContains != "" && !anyContains(result.DnsNames, nameContains) {
+				continue
+			}
+			results.Certificates = append(results.Certificates, result)
+			if int64(len(results.Certificates)) == limit {
+				more = more || i < len(rows)-1
+				break
+			}
+		}
+		if !more {
+			return results, nil
+		}
+		if int64(len(results.Certificates)) == limit || scanned >= maxListScan {
+			break
+		}
+	}
+	next := searchCursor{ID: cursorID}.encode()
+	results.NextCursor = &next
+	return results, nil
+}
+
+// anyContains returns true if any of names contains substr.
+func anyContains(names []string, substr string) bool {
+	for _, name := range names {
+		if strings.Contains(name, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// searchResult returns the summary of the certificate that is included in
+// search results.
+func (m accountCertificateModel) searchResult() (*sapb.CertificateSearchResult, error) {
+	parsed, err := x509.ParseCertificate(m.DER)
+	if err != nil {
+		return nil, err
+	}
+	issued := m.Issued.UnixNano()
+	expires := m.Expires.UnixNano()
+	status := string(m.Status)
+	result := &sapb.CertificateSearchResult{
+		Serial:         &m.Serial,
+		RegistrationID: &m.RegistrationID,
+		Issued:         &issued,
+		Expires:        &expires,
+		DnsNames:       parsed.DNSNames,
+		Status:         &status,
+	}
+	if m.Status == core.OCSPStatusRevoked {
+		revokedDate := m.RevokedDate.UnixNano()
+		revokedReason := int64(m.RevokedReason)
+		result.RevokedDate = &revokedDate
+		result.RevokedReason = &revokedReason
+	}
+	return result, nil
+}
+
+// maxInvalidAuthorizations is the most invalid authorizations
+// ListInvalidAuthorizationsForAccount returns.
+const maxInvalidAuthorizations = 100
+
+// ListOrdersForAccount returns a page of an account's orders, newest first,
+// with the same contents as GetOrder returns.
+func (ssa *SQLStorageAuthority) ListOrdersForAccount(ctx context.Context, req *sapb.ListOrdersForAccountRequest) (*sapb.OrderList, error) {
+	if req.RegistrationID == nil || *req.RegistrationID == 0 {
+		return nil, berrors.MalformedError("a registration ID must be given")
+	}
+	limit := int64(defaultSearchLimit)
+	if req.Limit != nil && *req.Limit > 0 {
+		limit = *req.Limit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	cursorID := int64(math.MaxInt64)
+	if req.Cursor != nil && *req.Cursor != "" {
+		cursor, err := decodeSearchCursor(*req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursorID = cursor.ID
+	}
+
+	var ids []int64
+	_, err := ssa.db(ctx).Select(
+		&ids,
+		`SELECT id FROM orders
+		WHERE registrationID = ? AND id < ?
+		ORDER BY id DESC LIMIT ?`,
+		*req.RegistrationID,
+		cursorID,
+		// One more row than the limit tells whether there is another page.
+		limit+1,
+	)
+	if err != nil {
+		return nil, err
+	}
+	list := &sapb.OrderList{}
+	more := int64(len(ids)) > limit
+	if more {
+		ids = ids[:limit]
+	}
+	for i := range ids {
+		order, err := ssa.GetOrder(ctx, &sapb.OrderRequest{Id: &ids[i]})
+		if err != nil {
+			return nil, err
+		}
+		list.Orders = append(list.Orders, order)
+	}
+	if more {
+		next := searchCursor{ID: ids[len(ids)-1]}.encode()
+		list.NextCursor = &next
+	}
+	return list, nil
+}
+
+// ListInvalidAuthorizationsForAccount returns an account's invalid
+// authorizations that expire after the request's expiresAfter, latest expiry
+// first, with their challenges and the errors they failed with. At most
+// maxInvalidAuthorizations are returned.
+func (ssa *SQLStorageAuthority) ListInvalidAuthorizationsForAccount(ctx context.Context, req *sapb.ListInvalidAuthorizationsForAccountRequest) (*sapb.AuthorizationList, error) {
+	if req.RegistrationID == nil || *req.RegistrationID == 0 {
+		return nil, berrors.MalformedError("a registration ID must be given")
+	}
+	limit := int64(maxInvalidAuthorizations)
+	if req.Limit != nil && *req.Limit > 0 && *req.Limit < limit {
+		limit = *req.Limit
+	}
+
+	db := ssa.db(ctx)
+	var models []authzModel
+	_, err := db.Select(
+		&models,
+		fmt.Sprintf(`SELECT %s FROM authz
+		WHERE registrationID = ? AND status = ? AND expires > ?
+		ORDER BY expires DESC LIMIT ?`, authzFieldsFor(authorizationTable)),
+		*req.RegistrationID,
+		string(core.StatusInvalid),
+		time.Unix(0, *req.ExpiresAfter),
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	list := &sapb.AuthorizationList{}
+	for _, m := range models {
+		authz := m.Authorization
+		authz.Challenges, err = ssa.getChallenges(db, authz.ID)
+		if err != nil {
+			return nil, err
+		}
+		authzPB, err := bgrpc.AuthzToPB(authz)
+		if err != nil {
+			return nil, err
+		}
+		list.Authorizations = append(list.Authorizations, authzPB)
+	}
+	return list, nil
+}
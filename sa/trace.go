@@ -0,0 +1,168 @@
+package sa
+
+import (
+	"database/sql"
+	"fmt"
+
+	"golang.org/x/net/context"
+	"gopkg.in/go-gorp/gorp.v2"
+
+	"github.com/letsencrypt/boulder/trace"
+)
+
+// tracedExecutor records each statement run through it as a client span of
+// the trace in ctx, so that the time spent in the database shows up in
+// traces. Spans of Query only cover running the query, not reading its rows.
+type tracedExecutor struct {
+	gorp.SqlExecutor
+	ctx context.Context
+}
+
+// traced returns exec with ctx, recording its statements in ctx's trace.
+func traced(ctx context.Context, exec gorp.SqlExecutor) gorp.SqlExecutor {
+	return &tracedExecutor{SqlExecutor: exec.WithContext(ctx), ctx: ctx}
+}
+
+// db returns the SA's database map with ctx, recording its statements in
+// ctx's trace.
+func (ssa *SQLStorageAuthority) db(ctx context.Context) gorp.SqlExecutor {
+	return traced(ctx, ssa.dbMap)
+}
+
+// start starts the span of a statement. For statements that gorp builds from
+// a model, statement is the model's type.
+func (te *tracedExecutor) start(op, statement string) *trace.Span {
+	_, span := trace.Start(te.ctx, "DB "+op, trace.KindClient)
+	span.SetAttribute("db.system", "mysql")
+	span.SetAttribute("db.statement", statement)
+	return span
+}
+
+func modelType(list []interface{}) string {
+	if len(list) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%T", list[0])
+}
+
+func (te *tracedExecutor) WithContext(ctx context.Context) gorp.SqlExecutor {
+	return traced(ctx, te.SqlExecutor)
+}
+
+func (te *tracedExecutor) Get(i interface{}, keys ...interface{}) (interface{}, error) {
+	span := te.start("Get", fmt.Sprintf("%T", i))
+	defer span.End()
+	obj, err := te.SqlExecutor.Get(i, keys...)
+	span.SetError(err)
+	return obj, err
+}
+
+func (te *tracedExecutor) Insert(list ...interface{}) error {
+	span := te.start("Insert", modelType(list))
+	defer span.End()
+	err := te.SqlExecutor.Insert(list...)
+	span.SetError(err)
+	return err
+}
+
+func (te *tracedExecutor) Update(list ...interface{}) (int64, error) {
+	span := te.start("Update", modelType(list))
+	defer span.End()
+	n, err := te.SqlExecutor.Update(list...)
+	span.SetError(err)
+	return n, err
+}
+
+func (te *tracedExecutor) Delete(list ...interface{}) (int64, error) {
+	span := te.start("Delete", modelType(list))
+	defer span.End()
+	n, err := te.SqlExecutor.Delete(list...)
+	span.SetError(err)
+	return n, err
+}
+
+func (te *tracedExecutor) Exec(query string, args ...interface{}) (sql.Result, error) {
+	span := te.start("Exec", query)
+	defer span.End()
+	result, err := te.SqlExecutor.Exec(query, args...)
+	span.SetError(err)
+	return result, err
+}
+
+func (te *tracedExecutor) Select(i interface{}, query string, args ...interface{}) ([]interface{}, error) {
+	span := te.start("Select", query)
+	defer span.End()
+	list, err := te.SqlExecutor.Select(i, query, args...)
+	span.SetError(err)
+	return list, err
+}
+
+func (te *tracedExecutor) SelectInt(query string, args ...interface{}) (int64, error) {
+	span := te.start("Select", query)
+	defer span.End()
+	n, err := te.SqlExecutor.SelectInt(query, args...)
+	span.SetError(err)
+	return n, err
+}
+
+func (te *tracedExecutor) SelectNullInt(query string, args ...interface{}) (sql.NullInt64, error) {
+	span := te.start("Select", query)
+	defer span.End()
+	n, err := te.SqlExecutor.SelectNullInt(query, args...)
+	span.SetError(err)
+	return n, err
+}
+
+func (te *tracedExecutor) SelectFloat(query string, args ...interface{}) (float64, error) {
+	span := te.start("Select", query)
+	defer span.End()
+	f, err := te.SqlExecutor.SelectFloat(query, args...)
+	span.SetError(err)
+	return f, err
+}
+
+func (te *tracedExecutor) SelectNullFloat(query string, args ...interface{}) (sql.NullFloat64, error) {
+	span := te.start("Select", query)
+	defer span.End()
+	f, err := te.SqlExecutor.SelectNullFloat(query, args...)
+	span.SetError(err)
+	return f, err
+}
+
+func (te *tracedExecutor) SelectStr(query string, args ...interface{}) (string, error) {
+	span := te.start("Select", query)
+	defer span.End()
+	s, err := te.SqlExecutor.SelectStr(query, args...)
+	span.SetError(err)
+	return s, err
+}
+
+func (te *tracedExecutor) SelectNullStr(query string, args ...interface{}) (sql.NullString, error) {
+	span := te.start("Select", query)
+	defer span.End()
+	s, err := te.SqlExecutor.SelectNullStr(query, args...)
+	span.SetError(err)
+	return s, err
+}
+
+func (te *tracedExecutor) SelectOne(holder interface{}, query string, args ...interface{}) error {
+	span := te.start("Select", query)
+	defer span.End()
+	err := te.SqlExecutor.SelectOne(holder, query, args...)
+	span.SetError(err)
+	return err
+}
+
+func (te *tracedExecutor) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	span := te.start("Query", query)
+	defer span.End()
+	rows, err := te.SqlExecutor.Query(query, args...)
+	span.SetError(err)
+	return rows, err
+}
+
+func (te *tracedExecutor) QueryRow(query string, args ...interface{}) *sql.Row {
+	span := te.start("Query", query)
+	defer span.End()
+	return te.SqlExecutor.QueryRow(query, args...)
+}
@@ -0,0 +1,67 @@
+package sa
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/net/context"
+	"gopkg.in/go-gorp/gorp.v2"
+
+	"github.com/letsencrypt/boulder/test"
+	"github.com/letsencrypt/boulder/trace"
+)
+
+type recordingExporter struct {
+	spans []*trace.SpanData
+}
+
+func (re *recordingExporter) Export(s *trace.SpanData) {
+	re.spans = append(re.spans, s)
+}
+
+func (re *recordingExporter) Shutdown() {}
+
+// fakeExecutor fails every SelectInt, succeeds at every Insert, and panics on
+// anything else.
+type fakeExecutor struct {
+	gorp.SqlExecutor
+}
+
+func (fe fakeExecutor) WithContext(ctx context.Context) gorp.SqlExecutor {
+	return fe
+}
+
+func (fe fakeExecutor) SelectInt(query string, args ...interface{}) (int64, error) {
+	return 0, errors.New("broken")
+}
+
+func (fe fakeExecutor) Insert(list ...interface{}) error {
+	return nil
+}
+
+func TestTracedExecutor(t *testing.T) {
+	exporter := &recordingExporter{}
+	trace.SetTracer(trace.NewTracer(exporter, 1))
+	defer trace.SetTracer(nil)
+
+	ctx, parent := trace.Start(context.Background(), "parent", trace.KindServer)
+	db := traced(ctx, fakeExecutor{})
+	_, err := db.SelectInt("SELECT COUNT(1) FROM certificates WHERE serial = ?", "00")
+	test.AssertError(t, err, "SelectInt didn't fail")
+	err = db.WithContext(ctx).Insert(&regModel{})
+	test.AssertNotError(t, err, "Insert failed")
+	parent.End()
+
+	test.AssertEquals(t, len(exporter.spans), 3)
+	sel, ins := exporter.spans[0], exporter.spans[1]
+	test.AssertEquals(t, sel.Name, "DB Select")
+	test.AssertEquals(t, sel.Kind, trace.KindClient)
+	test.AssertEquals(t, sel.Attributes["db.statement"], "SELECT COUNT(1) FROM certificates WHERE serial = ?")
+	test.AssertError(t, sel.Err, "failed statement's span has no error")
+	test.AssertEquals(t, sel.Parent, parent.Context().SpanID)
+	test.AssertEquals(t, sel.TraceID, parent.Context().TraceID)
+	test.AssertEquals(t, ins.Name, "DB Insert")
+	test.AssertEquals(t, ins.Attributes["db.statement"], "*sa.regModel")
+	test.AssertNotError(t, ins.Err, "successful statement's span has an error")
+	test.AssertEquals(t, ins.Parent, parent.Context().SpanID)
+}
@@ -0,0 +1,165 @@
+package sa
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"gopkg.in/go-gorp/gorp.v2"
+
+	"github.com/letsencrypt/boulder/trace"
+)
+
+const (
+	// MySQL error numbers of transactions that failed only because they
+	// contended with another, and can be run again.
+	// https://dev.mysql.com/doc/refman/5.7/en/server-error-reference.html
+	errLockWaitTimeout = 1205
+	errLockDeadlock    = 1213
+)
+
+// TransactionRetryConfig configures running write transactions again when
+// the database aborted them to break a deadlock or lock wait timeout, which
+// happen when e.g. an order is finalized while certificates for its names are
+// being counted for rate limiting.
+type TransactionRetryConfig struct {
+	// MaxAttempts is the most times a transaction is run, including the
+	// first. It must be at least 2.
+	MaxAttempts int
+	// BaseDelay is how long to wait before the first retry. Each retry waits
+	// twice as long as the last, up to MaxDelay, and the wait is jittered by
+	// up to half of it.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// txRetrier decides whether and when failed transactions are run again. A
+// nil *txRetrier runs every transaction once.
+type txRetrier struct {
+	config TransactionRetryConfig
+	// retries counts transactions that were run again, and exhausted those
+	// that still failed retryably on their last attempt, by RPC name.
+	retries   *prometheus.CounterVec
+	exhausted *prometheus.CounterVec
+}
+
+// isRetryableTxError returns true if err means the transaction was aborted
+// because of contention with another transaction.
+func isRetryableTxError(err error) bool {
+	if rbErr, ok := err.(*RollbackError); ok {
+		err = rbErr.Err
+	}
+	mysqlErr, ok := err.(*mysql.MySQLError)
+	if !ok {
+		return false
+	}
+	return mysqlErr.Number == errLockDeadlock || mysqlErr.Number == errLockWaitTimeout
+}
+
+// backoff returns how long to wait before retry number retry, counting from
+// 1.
+func (r *txRetrier) backoff(retry int) time.Duration {
+	delay := r.config.BaseDelay
+	for i := 1; i < retry && delay < r.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > r.config.MaxDelay {
+		delay = r.config.MaxDelay
+	}
+	// Jitter the delay to within [delay/2, delay) so that the transactions
+	// that deadlocked don't collide again when they retry.
+	half := int64(delay / 2)
+	if half <= 0 {
+		return delay
+	}
+	return time.Duration(half + rand.Int63n(half))
+}
+
+// withTransaction runs f in a transaction, committing it if f returns nil
+// and rolling it back otherwise. If the transaction is aborted because of a
+// deadlock or lock wait timeout, whether in f or on commit, it's run again
+// as configured by SetTransactionRetries, so f must not have side effects
+// outside the transaction. If ctx is done while waiting to retry, the last
+// attempt's error is returned. name identifies the transaction in metrics.
+func (ssa *SQLStorageAuthority) withTransaction(
+	ctx context.Context,
+	name string,
+	f func(txWithCtx gorp.SqlExecutor) error,
+) error {
+	attempts := 1
+	if ssa.txRetrier != nil {
+		attempts = ssa.txRetrier.config.MaxAttempts
+	}
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			ssa.txRetrier.retries.WithLabelValues(name).Inc()
+			timer := time.NewTimer(ssa.txRetrier.backoff(attempt - 1))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+		err = ssa.runTransaction(ctx, name, f)
+		if err == nil || !isRetryableTxError(err) {
+			return err
+		}
+		ssa.log.Warningf("Transaction %s aborted on attempt %d of %d: %s", name, attempt, attempts, err)
+	}
+	if ssa.txRetrier != nil {
+		ssa.txRetrier.exhausted.WithLabelValues(name).Inc()
+	}
+	return err
+}
+
+// runTransaction runs f in a transaction once. The transaction is a span of
+// the trace in ctx, with a child span for each of its statements.
+func (ssa *SQLStorageAuthority) runTransaction(ctx context.Context, name string, f func(txWithCtx gorp.SqlExecutor) error) (err error) {
+	ctx, span := trace.Start(ctx, "DB transaction "+name, trace.KindInternal)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	tx, err := ssa.dbMap.Begin()
+	if err != nil {
+		return err
+	}
+	err = f(traced(ctx, tx))
+	if err != nil {
+		return Rollback(tx, err)
+	}
+	return tx.Commit()
+}
+
+// SetTransactionRetries makes the SA run write transactions again, as
+// configured, when the database aborts them to break a deadlock or lock wait
+// timeout, rather than failing the RPC.
+func (ssa *SQLStorageAuthority) SetTransactionRetries(config TransactionRetryConfig) error {
+	if config.MaxAttempts < 2 {
+		return errors.New("transaction retries need a MaxAttempts of at least 2")
+	}
+	if config.BaseDelay <= 0 || config.MaxDelay < config.BaseDelay {
+		return errors.New("transaction retries need a positive BaseDelay no greater than MaxDelay")
+	}
+	retries := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sa_transaction_retries",
+		Help: "Number of times a write transaction was run again after a deadlock or lock wait timeout, by RPC",
+	}, []string{"name"})
+	exhausted := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sa_transaction_retries_exhausted",
+		Help: "Number of write transactions that still deadlocked or timed out waiting for a lock on their last attempt, by RPC",
+	}, []string{"name"})
+	ssa.scope.MustRegister(retries)
+	ssa.scope.MustRegister(exhausted)
+	ssa.txRetrier = &txRetrier{
+		config:    config,
+		retries:   retries,
+		exhausted: exhausted,
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package sa
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/net/context"
+	"gopkg.in/go-gorp/gorp.v2"
+
+	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestIsRetryableTxError(t *testing.T) {
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+	testCases := []struct {
+		err       error
+		retryable bool
+	}{
+		{deadlock, true},
+		{&mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}, true},
+		{&RollbackError{Err: deadlock, RollbackErr: errors.New("oops")}, true},
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}, false},
+		{berrors.InternalServerError("no order updated"), false},
+		{errors.New("Error 1213: Deadlock found when trying to get lock"), false},
+	}
+	for _, tc := range testCases {
+		test.AssertEquals(t, isRetryableTxError(tc.err), tc.retryable)
+	}
+}
+
+func TestTxRetrierBackoff(t *testing.T) {
+	r := &txRetrier{config: TransactionRetryConfig{
+		MaxAttempts: 5,
+		BaseDelay:   20 * time.Millisecond,
+		MaxDelay:    50 * time.Millisecond,
+	}}
+	testCases := []struct {
+		retry int
+		max   time.Duration
+	}{
+		{1, 20 * time.Millisecond},
+		{2, 40 * time.Millisecond},
+		{3, 50 * time.Millisecond},
+		{10, 50 * time.Millisecond},
+	}
+	for _, tc := range testCases {
+		for i := 0; i < 100; i++ {
+			delay := r.backoff(tc.retry)
+			test.Assert(t, delay >= tc.max/2 && delay < tc.max,
+				"backoff of retry outside of jittered bounds")
+		}
+	}
+}
+
+func TestSetTransactionRetriesInvalid(t *testing.T) {
+	ssa := &SQLStorageAuthority{scope: metrics.NewNoopScope()}
+	for _, config := range []TransactionRetryConfig{
+		{MaxAttempts: 1, BaseDelay: time.Millisecond, MaxDelay: time.Second},
+		{MaxAttempts: 3, MaxDelay: time.Second},
+		{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Millisecond},
+	} {
+		err := ssa.SetTransactionRetries(config)
+		test.AssertError(t, err, "invalid transaction retries config was accepted")
+	}
+	test.Assert(t, ssa.txRetrier == nil, "invalid config enabled retries")
+}
+
+func TestWithTransactionRetries(t *testing.T) {
+	sa, _, cleanUp := initSA(t)
+	defer cleanUp()
+
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+	var calls int
+	deadlockOnce := func(txWithCtx gorp.SqlExecutor) error {
+		calls++
+		if calls == 1 {
+			return deadlock
+		}
+		return nil
+	}
+
+	// Without retries, the first deadlock is returned
+	err := sa.withTransaction(context.Background(), "test", deadlockOnce)
+	test.AssertEquals(t, err, error(deadlock))
+	test.AssertEquals(t, calls, 1)
+
+	err = sa.SetTransactionRetries(TransactionRetryConfig{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    10 * time.Millisecond,
+	})
+	test.AssertNotError(t, err, "SetTransactionRetries failed")
+
+	calls = 0
+	err = sa.withTransaction(context.Background(), "test", deadlockOnce)
+	test.AssertNotError(t, err, "transaction wasn't retried")
+	test.AssertEquals(t, calls, 2)
+	test.AssertEquals(t, test.CountCounter(sa.txRetrier.retries.WithLabelValues("test")), 1)
+
+	// Other errors aren't retried
+	calls = 0
+	notFound := berrors.NotFoundError("gone")
+	err = sa.withTransaction(context.Background(), "test", func(gorp.SqlExecutor) error {
+		calls++
+		return notFound
+	})
+	test.AssertEquals(t, err, notFound)
+	test.AssertEquals(t, calls, 1)
+
+	// A transaction that keeps deadlocking gives up after MaxAttempts
+	calls = 0
+	err = sa.withTransaction(context.Background(), "test", func(gorp.SqlExecutor) error {
+		calls++
+		return deadlock
+	})
+	test.AssertEquals(t, err, error(deadlock))
+	test.AssertEquals(t, calls, 3)
+	test.AssertEquals(t, test.CountCounter(sa.txRetrier.exhausted.WithLabelValues("test")), 1)
+
+	// Waiting to retry stops as soon as the context is done
+	sa.txRetrier.config.BaseDelay = time.Hour
+	sa.txRetrier.config.MaxDelay = time.Hour
+	calls = 0
+	cancelCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- sa.withTransaction(cancelCtx, "test", func(gorp.SqlExecutor) error {
+			calls++
+			return deadlock
+		})
+	}()
+	cancel()
+	select {
+	case err = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("withTransaction kept waiting to retry after its context was canceled")
+	}
+	test.AssertEquals(t, err, error(deadlock))
+	test.AssertEquals(t, calls, 1)
+}
@@ -0,0 +1,122 @@
+package staticct
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	ct "github.com/google/certificate-transparency-go"
+	"golang.org/x/net/context"
+)
+
+// Client reads a static log from its monitoring prefix. It has the GetSTH and
+// GetEntries methods of the RFC 6962 client, so that code reading logs can
+// take either.
+type Client struct {
+	monitoringURL string
+	verifier      *Verifier
+	httpClient    *http.Client
+
+	mu sync.Mutex
+	// treeSize is the size of the log in the last checkpoint, which says
+	// which tiles are full.
+	treeSize uint64
+}
+
+// NewClient returns a Client for the static log with the given submission and
+// monitoring prefixes. If b64PK, the log's base64 DER encoded public key, is
+// set, checkpoints are verified with it.
+func NewClient(submissionURL, monitoringURL, b64PK string, httpClient *http.Client) (*Client, error) {
+	c := &Client{
+		monitoringURL: strings.TrimSuffix(monitoringURL, "/") + "/",
+		httpClient:    httpClient,
+	}
+	if b64PK != "" {
+		der, err := base64.StdEncoding.DecodeString(b64PK)
+		if err != nil {
+			return nil, fmt.Errorf("decoding log public key: %s", err)
+		}
+		c.verifier, err = NewVerifier(Origin(submissionURL), der)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *Client) get(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequest("GET", c.monitoringURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", req.URL, resp.Status)
+	}
+	return body, nil
+}
+
+// GetSTH fetches and verifies the log's latest checkpoint, returning it as a
+// signed tree head.
+func (c *Client) GetSTH(ctx context.Context) (*ct.SignedTreeHead, error) {
+	note, err := c.get(ctx, "checkpoint")
+	if err != nil {
+		return nil, err
+	}
+	checkpoint, err := ParseCheckpoint(note, c.verifier)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if checkpoint.TreeSize > c.treeSize {
+		c.treeSize = checkpoint.TreeSize
+	}
+	c.mu.Unlock()
+	return checkpoint.STH(), nil
+}
+
+// GetEntries returns entries of the log from start to end inclusive, which
+// must be within the tree size of the last checkpoint fetched by GetSTH. As
+// entries are fetched a tile at a time, fewer than requested may be
+// returned.
+func (c *Client) GetEntries(ctx context.Context, start, end int64) ([]ct.LogEntry, error) {
+	c.mu.Lock()
+	size := c.treeSize
+	c.mu.Unlock()
+	if start < 0 || end < start || uint64(end) >= size {
+		return nil, fmt.Errorf("entries %d to %d are outside the tree of size %d", start, end, size)
+	}
+	n := uint64(start) / TileWidth
+	width := TileWidth
+	if remaining := size - n*TileWidth; remaining < TileWidth {
+		width = int(remaining)
+	}
+	tile, err := c.get(ctx, DataTilePath(n, width))
+	if err != nil {
+		return nil, err
+	}
+	entries, err := ParseDataTile(tile, n)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != width {
+		return nil, fmt.Errorf("tile %d has %d entries, not %d", n, len(entries), width)
+	}
+	first := uint64(start) - n*TileWidth
+	last := uint64(end) - n*TileWidth
+	if last >= uint64(width) {
+		last = uint64(width) - 1
+	}
+	return entries[first : last+1], nil
+}
@@ -0,0 +1,244 @@
+// Package staticct implements the parts of static-ct-api
+// (https://c2sp.org/static-ct-api) that differ from RFC 6962. Static, or
+// tiled, CT logs accept submissions with the RFC 6962 add-chain and
+// add-pre-chain endpoints, but their SCTs carry the index of the entry in the
+// log, and they're read by fetching a signed checkpoint and tiles of entries
+// from a separate monitoring prefix rather than with get-sth and get-entries.
+package staticct
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	ct "github.com/google/certificate-transparency-go"
+	cttls "github.com/google/certificate-transparency-go/tls"
+)
+
+// TileWidth is the number of entries in a full data tile.
+const TileWidth = 256
+
+// leafIndexExtension is the type of the SCT extension holding the index of
+// the entry in the log.
+const leafIndexExtension = 0
+
+// LeafIndex returns the index of the log entry that extensions, the
+// extensions of an SCT or of a TimestampedEntry from a static log, belong to.
+// Static logs must include it, so an error is returned if it's missing.
+func LeafIndex(extensions ct.CTExtensions) (uint64, error) {
+	b := []byte(extensions)
+	for len(b) > 0 {
+		if len(b) < 3 {
+			return 0, errors.New("truncated CT extension")
+		}
+		extType, length := b[0], int(binary.BigEndian.Uint16(b[1:3]))
+		b = b[3:]
+		if len(b) < length {
+			return 0, errors.New("truncated CT extension")
+		}
+		data := b[:length]
+		b = b[length:]
+		if extType != leafIndexExtension {
+			continue
+		}
+		if length != 5 {
+			return 0, fmt.Errorf("leaf_index extension has %d bytes, not 5", length)
+		}
+		return uint64(data[0])<<32 | uint64(binary.BigEndian.Uint32(data[1:])), nil
+	}
+	return 0, errors.New("no leaf_index extension")
+}
+
+// Origin returns the checkpoint origin of the static log with the given
+// submission prefix: the prefix without its scheme and trailing slashes.
+func Origin(submissionURL string) string {
+	origin := submissionURL
+	if i := strings.Index(origin, "://"); i >= 0 {
+		origin = origin[i+3:]
+	}
+	return strings.TrimRight(origin, "/")
+}
+
+// Checkpoint is a static log's signed tree head.
+type Checkpoint struct {
+	Origin   string
+	TreeSize uint64
+	RootHash ct.SHA256Hash
+	// Timestamp and Signature are from the log's RFC 6962 note signature, and
+	// are zero if the checkpoint wasn't verified.
+	Timestamp uint64
+	Signature ct.DigitallySigned
+}
+
+// STH returns the checkpoint as an RFC 6962 signed tree head.
+func (c Checkpoint) STH() *ct.SignedTreeHead {
+	return &ct.SignedTreeHead{
+		Version:           ct.V1,
+		TreeSize:          c.TreeSize,
+		Timestamp:         c.Timestamp,
+		SHA256RootHash:    c.RootHash,
+		TreeHeadSignature: c.Signature,
+	}
+}
+
+// Verifier verifies the checkpoints of a static log.
+type Verifier struct {
+	origin   string
+	keyID    [4]byte
+	verifier *ct.SignatureVerifier
+}
+
+// NewVerifier returns a Verifier for the checkpoints of the static log with
+// the given origin and DER encoded public key.
+func NewVerifier(origin string, der []byte) (*Verifier, error) {
+	pk, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("parsing log public key: %s", err)
+	}
+	verifier, err := ct.NewSignatureVerifier(pk)
+	if err != nil {
+		return nil, err
+	}
+	// The key ID of an RFC 6962 note signature hashes the key name, which is
+	// the origin, with the signature type 0x05 and the key.
+	h := sha256.New()
+	h.Write([]byte(origin + "\n\x05"))
+	h.Write(der)
+	v := &Verifier{origin: origin, verifier: verifier}
+	copy(v.keyID[:], h.Sum(nil))
+	return v, nil
+}
+
+// ParseCheckpoint parses a checkpoint, verifying its signature by the log if
+// v isn't nil. Lines of the note other than the origin, tree size and root
+// hash, and signatures by other keys, are ignored.
+func ParseCheckpoint(note []byte, v *Verifier) (*Checkpoint, error) {
+	sep := bytes.LastIndex(note, []byte("\n\n"))
+	if sep < 0 {
+		return nil, errors.New("checkpoint has no signatures")
+	}
+	text, sigs := string(note[:sep+1]), string(note[sep+2:])
+	lines := strings.Split(text, "\n")
+	if len(lines) < 4 {
+		return nil, errors.New("checkpoint is too short")
+	}
+	c := &Checkpoint{Origin: lines[0]}
+	size, err := strconv.ParseUint(lines[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint tree size %q", lines[1])
+	}
+	c.TreeSize = size
+	hash, err := base64.StdEncoding.DecodeString(lines[2])
+	if err != nil || len(hash) != sha256.Size {
+		return nil, fmt.Errorf("invalid checkpoint root hash %q", lines[2])
+	}
+	copy(c.RootHash[:], hash)
+	if v == nil {
+		return c, nil
+	}
+
+	if c.Origin != v.origin {
+		return nil, fmt.Errorf("checkpoint origin is %q, not %q", c.Origin, v.origin)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(sigs, "\n"), "\n") {
+		fields := strings.Fields(strings.TrimPrefix(line, "— "))
+		if !strings.HasPrefix(line, "— ") || len(fields) != 2 || fields[0] != v.origin {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(sig) < 4+8 || !bytes.Equal(sig[:4], v.keyID[:]) {
+			continue
+		}
+		c.Timestamp = binary.BigEndian.Uint64(sig[4:12])
+		rest, err := cttls.Unmarshal(sig[12:], &c.Signature)
+		if err != nil || len(rest) > 0 {
+			return nil, errors.New("malformed checkpoint signature")
+		}
+		err = v.verifier.VerifySTHSignature(*c.STH())
+		if err != nil {
+			return nil, fmt.Errorf("invalid checkpoint signature: %s", err)
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("checkpoint has no signature by %q", v.origin)
+}
+
+// DataTilePath returns the path, relative to the monitoring prefix, of data
+// tile n. width is the number of entries in the tile, which is less than
+// TileWidth for the partial tile at the end of the log.
+func DataTilePath(n uint64, width int) string {
+	digits := fmt.Sprintf("%d", n)
+	if pad := len(digits) % 3; pad != 0 {
+		digits = strings.Repeat("0", 3-pad) + digits
+	}
+	var elems []string
+	for len(digits) > 3 {
+		elems = append(elems, "x"+digits[:3])
+		digits = digits[3:]
+	}
+	path := "tile/data/" + strings.Join(append(elems, digits), "/")
+	if width < TileWidth {
+		path += fmt.Sprintf(".p/%d", width)
+	}
+	return path
+}
+
+// ParseDataTile parses the entries of data tile n, checking that each is for
+// the index in the log its position in the tile gives it. The certificate
+// chains of entries are only included in tiles as fingerprints, so the
+// entries returned have none.
+func ParseDataTile(tile []byte, n uint64) ([]ct.LogEntry, error) {
+	var entries []ct.LogEntry
+	index := n * TileWidth
+	for len(tile) > 0 {
+		var rle ct.RawLogEntry
+		rle.Index = int64(index)
+		rle.Leaf = ct.MerkleTreeLeaf{Version: ct.V1, LeafType: ct.TimestampedEntryLeafType}
+		var te ct.TimestampedEntry
+		rest, err := cttls.Unmarshal(tile, &te)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %s", index, err)
+		}
+		rle.Leaf.TimestampedEntry = &te
+		switch te.EntryType {
+		case ct.X509LogEntryType:
+			rle.Cert = *te.X509Entry
+		case ct.PrecertLogEntryType:
+			rest, err = cttls.Unmarshal(rest, &rle.Cert)
+			if err != nil {
+				return nil, fmt.Errorf("entry %d: precertificate: %s", index, err)
+			}
+		default:
+			return nil, fmt.Errorf("entry %d: unknown entry type %d", index, te.EntryType)
+		}
+		var fingerprints struct {
+			Chain []byte `tls:"minlen:0,maxlen:65535"`
+		}
+		tile, err = cttls.Unmarshal(rest, &fingerprints)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: chain: %s", index, err)
+		}
+		leafIndex, err := LeafIndex(te.Extensions)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %s", index, err)
+		}
+		if leafIndex != index {
+			return nil, fmt.Errorf("entry %d has leaf_index %d", index, leafIndex)
+		}
+		// An entry is returned along with non-fatal certificate parsing
+		// errors, which aren't the log's fault.
+		entry, err := rle.ToLogEntry()
+		if entry == nil {
+			return nil, fmt.Errorf("entry %d: %s", index, err)
+		}
+		entries = append(entries, *entry)
+		index++
+	}
+	return entries, nil
+}
@@ -0,0 +1,251 @@
+package staticct
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ct "github.com/google/certificate-transparency-go"
+	cttls "github.com/google/certificate-transparency-go/tls"
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+const testOrigin = "log.example.com/2025h1"
+
+// leafIndexExt returns the extensions of an entry or SCT with the given
+// leaf_index.
+func leafIndexExt(index uint64) ct.CTExtensions {
+	return ct.CTExtensions{0, 0, 5, byte(index >> 32), byte(index >> 24), byte(index >> 16), byte(index >> 8), byte(index)}
+}
+
+func TestLeafIndex(t *testing.T) {
+	index, err := LeafIndex(leafIndexExt(1<<32 + 7))
+	test.AssertNotError(t, err, "LeafIndex failed")
+	test.AssertEquals(t, index, uint64(1<<32+7))
+
+	// Other extensions are skipped
+	index, err = LeafIndex(append(ct.CTExtensions{9, 0, 1, 42}, leafIndexExt(3)...))
+	test.AssertNotError(t, err, "LeafIndex failed")
+	test.AssertEquals(t, index, uint64(3))
+
+	for _, invalid := range []ct.CTExtensions{
+		nil,
+		{9, 0, 1, 42},
+		{0, 0, 4, 0, 0, 0, 1},
+		{0, 0, 5, 0, 0},
+	} {
+		_, err := LeafIndex(invalid)
+		test.AssertError(t, err, fmt.Sprintf("invalid extensions %x were accepted", invalid))
+	}
+}
+
+func TestOrigin(t *testing.T) {
+	test.AssertEquals(t, Origin("https://log.example.com/2025h1/"), "log.example.com/2025h1")
+	test.AssertEquals(t, Origin("log.example.com"), "log.example.com")
+}
+
+func TestDataTilePath(t *testing.T) {
+	test.AssertEquals(t, DataTilePath(0, TileWidth), "tile/data/000")
+	test.AssertEquals(t, DataTilePath(5, 17), "tile/data/005.p/17")
+	test.AssertEquals(t, DataTilePath(1234067, TileWidth), "tile/data/x001/x234/067")
+}
+
+// testLog is a static log serving a checkpoint and tiles of its entries.
+type testLog struct {
+	t       *testing.T
+	key     *ecdsa.PrivateKey
+	b64PK   string
+	entries [][]byte
+	// timestamp is the time of the checkpoint's signature, in milliseconds.
+	timestamp uint64
+}
+
+func newTestLog(t *testing.T) *testLog {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "generating log key")
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	test.AssertNotError(t, err, "marshaling log key")
+	return &testLog{t: t, key: key, b64PK: base64.StdEncoding.EncodeToString(der), timestamp: 1000}
+}
+
+// add appends an entry for cert, as a precertificate if precert is set,
+// returning its index.
+func (l *testLog) add(t *testing.T, cert *x509.Certificate, precert bool) uint64 {
+	index := uint64(len(l.entries))
+	te := ct.TimestampedEntry{Timestamp: 1000 + index, Extensions: leafIndexExt(index)}
+	if precert {
+		te.EntryType = ct.PrecertLogEntryType
+		te.PrecertEntry = &ct.PreCert{IssuerKeyHash: sha256.Sum256([]byte("issuer")), TBSCertificate: cert.RawTBSCertificate}
+	} else {
+		te.EntryType = ct.X509LogEntryType
+		te.X509Entry = &ct.ASN1Cert{Data: cert.Raw}
+	}
+	entry, err := cttls.Marshal(te)
+	test.AssertNotError(t, err, "marshaling entry")
+	if precert {
+		submitted, err := cttls.Marshal(ct.ASN1Cert{Data: cert.Raw})
+		test.AssertNotError(t, err, "marshaling precertificate")
+		entry = append(entry, submitted...)
+	}
+	// One issuer fingerprint
+	entry = append(entry, 0, 32)
+	entry = append(entry, make([]byte, 32)...)
+	l.entries = append(l.entries, entry)
+	return index
+}
+
+// checkpoint returns a checkpoint of the log signed by key.
+func (l *testLog) checkpoint(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	var rootHash ct.SHA256Hash
+	rootHash[0] = byte(len(l.entries))
+	input, err := ct.SerializeSTHSignatureInput(ct.SignedTreeHead{
+		Version:        ct.V1,
+		TreeSize:       uint64(len(l.entries)),
+		Timestamp:      l.timestamp,
+		SHA256RootHash: rootHash,
+	})
+	test.AssertNotError(t, err, "serializing tree head")
+	digest := sha256.Sum256(input)
+	sig, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	test.AssertNotError(t, err, "signing tree head")
+	ds, err := cttls.Marshal(cttls.DigitallySigned{
+		Algorithm: cttls.SignatureAndHashAlgorithm{Hash: cttls.SHA256, Signature: cttls.ECDSA},
+		Signature: sig,
+	})
+	test.AssertNotError(t, err, "marshaling signature")
+
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	keyID := sha256.Sum256(append([]byte(testOrigin+"\n\x05"), der...))
+	noteSig := append(keyID[:4:4], make([]byte, 8)...)
+	binary.BigEndian.PutUint64(noteSig[4:], l.timestamp)
+	noteSig = append(noteSig, ds...)
+	return []byte(fmt.Sprintf("%s\n%d\n%s\n\n— other.example.com AAAAAAAA\n— %s %s\n",
+		testOrigin, len(l.entries), base64.StdEncoding.EncodeToString(rootHash[:]),
+		testOrigin, base64.StdEncoding.EncodeToString(noteSig)))
+}
+
+func (l *testLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/checkpoint" {
+		_, _ = w.Write(l.checkpoint(l.t, l.key))
+		return
+	}
+	for n := 0; n*TileWidth < len(l.entries); n++ {
+		end := (n + 1) * TileWidth
+		if end > len(l.entries) {
+			end = len(l.entries)
+		}
+		if r.URL.Path == "/"+DataTilePath(uint64(n), end-n*TileWidth) {
+			for _, entry := range l.entries[n*TileWidth : end] {
+				_, _ = w.Write(entry)
+			}
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+func testCert(t *testing.T, serial int64) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.AssertNotError(t, err, "generating key")
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	test.AssertNotError(t, err, "creating certificate")
+	cert, err := x509.ParseCertificate(der)
+	test.AssertNotError(t, err, "parsing certificate")
+	return cert
+}
+
+func TestParseCheckpoint(t *testing.T) {
+	l := newTestLog(t)
+	l.add(t, testCert(t, 1), false)
+	der, _ := base64.StdEncoding.DecodeString(l.b64PK)
+	v, err := NewVerifier(testOrigin, der)
+	test.AssertNotError(t, err, "NewVerifier failed")
+
+	c, err := ParseCheckpoint(l.checkpoint(t, l.key), v)
+	test.AssertNotError(t, err, "ParseCheckpoint failed")
+	test.AssertEquals(t, c.Origin, testOrigin)
+	test.AssertEquals(t, c.TreeSize, uint64(1))
+	test.AssertEquals(t, c.RootHash[0], byte(1))
+	test.AssertEquals(t, c.Timestamp, uint64(1000))
+
+	// Without a verifier, signatures aren't needed
+	c, err = ParseCheckpoint([]byte(testOrigin+"\n5\n"+base64.StdEncoding.EncodeToString(make([]byte, 32))+"\n\n"), nil)
+	test.AssertNotError(t, err, "ParseCheckpoint failed")
+	test.AssertEquals(t, c.TreeSize, uint64(5))
+
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, err = ParseCheckpoint(l.checkpoint(t, otherKey), v)
+	test.AssertError(t, err, "checkpoint signed by another key was accepted")
+
+	otherV, _ := NewVerifier("log.example.com/2025h2", der)
+	_, err = ParseCheckpoint(l.checkpoint(t, l.key), otherV)
+	test.AssertError(t, err, "checkpoint with the wrong origin was accepted")
+
+	// A signature over a different tree head doesn't verify
+	tampered := l.checkpoint(t, l.key)
+	tampered[len(testOrigin)+1] = '2'
+	_, err = ParseCheckpoint(tampered, v)
+	test.AssertError(t, err, "tampered checkpoint was accepted")
+}
+
+func TestClient(t *testing.T) {
+	l := newTestLog(t)
+	for i := 0; i < TileWidth+2; i++ {
+		l.add(t, testCert(t, int64(i+1)), i%2 == 1)
+	}
+	srv := httptest.NewServer(l)
+	defer srv.Close()
+	ctx := context.Background()
+
+	c, err := NewClient("https://"+testOrigin+"/", srv.URL, l.b64PK, http.DefaultClient)
+	test.AssertNotError(t, err, "NewClient failed")
+	_, err = c.GetEntries(ctx, 0, 1)
+	test.AssertError(t, err, "GetEntries succeeded before GetSTH")
+
+	sth, err := c.GetSTH(ctx)
+	test.AssertNotError(t, err, "GetSTH failed")
+	test.AssertEquals(t, sth.TreeSize, uint64(TileWidth+2))
+
+	// Entries come a tile at a time
+	entries, err := c.GetEntries(ctx, 250, 257)
+	test.AssertNotError(t, err, "GetEntries failed")
+	test.AssertEquals(t, len(entries), 6)
+	test.AssertEquals(t, entries[0].Index, int64(250))
+	test.AssertEquals(t, entries[0].X509Cert.SerialNumber.Int64(), int64(251))
+	test.AssertEquals(t, entries[0].Leaf.TimestampedEntry.Timestamp, uint64(1250))
+	test.AssertEquals(t, entries[1].Precert.TBSCertificate.SerialNumber.Int64(), int64(252))
+
+	// The last tile is partial
+	entries, err = c.GetEntries(ctx, 256, 257)
+	test.AssertNotError(t, err, "GetEntries failed")
+	test.AssertEquals(t, len(entries), 2)
+	test.AssertEquals(t, entries[1].Index, int64(257))
+
+	_, err = c.GetEntries(ctx, 256, 258)
+	test.AssertError(t, err, "GetEntries beyond the tree size succeeded")
+
+	// Entries must have the leaf_index of their position
+	l.entries[256], l.entries[257] = l.entries[257], l.entries[256]
+	_, err = c.GetEntries(ctx, 256, 257)
+	test.AssertError(t, err, "entries with the wrong leaf_index were accepted")
+}
@@ -24,6 +24,8 @@ GRANT USAGE ON *.* TO 'cert_checker'@'localhost';
 DROP USER 'cert_checker'@'localhost';
 GRANT USAGE ON *.* TO 'purger'@'localhost';
 DROP USER 'purger'@'localhost';
+GRANT USAGE ON *.* TO 'stats'@'localhost';
+DROP USER 'stats'@'localhost';
 GRANT USAGE ON *.* TO 'backfiller'@'localhost';
 DROP USER 'backfiller'@'localhost';
 GRANT USAGE ON *.* TO 'test_setup'@'localhost';
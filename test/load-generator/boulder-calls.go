@@ -7,10 +7,12 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -39,11 +41,14 @@ var (
 		"revokeCertificate": revokeCertificate,
 
 		/* ACME v2 Operations */
-		"newAccount":    newAccount,
-		"getAccount":    getAccount,
-		"newOrder":      newOrder,
-		"fulfillOrder":  fulfillOrder,
-		"finalizeOrder": finalizeOrder,
+		"newAccount":          newAccount,
+		"getAccount":          getAccount,
+		"newOrder":            newOrder,
+		"newWildcardOrder":    newWildcardOrder,
+		"fulfillOrder":        fulfillOrder,
+		"failOrder":           failOrder,
+		"finalizeOrder":       finalizeOrder,
+		"revokeCertificateV2": revokeCertificateV2,
 	}
 )
 
@@ -324,7 +329,22 @@ func newOrder(s *State, ctx *context) error {
 			Value: randDomain(s.domainBase),
 		})
 	}
+	return submitOrder(s, ctx, dnsNames)
+}
+
+// newWildcardOrder creates a new pending order object for a single random
+// wildcard name using the context's account. The order's authorization can only
+// be fulfilled with a DNS-01 challenge.
+func newWildcardOrder(s *State, ctx *context) error {
+	return submitOrder(s, ctx, []core.AcmeIdentifier{{
+		Type:  core.IdentifierDNS,
+		Value: fmt.Sprintf("*.%s", randDomain(s.domainBase)),
+	}})
+}
 
+// submitOrder POSTs a new-order request for the provided identifiers using the
+// context's account, storing the resulting pending order in the context.
+func submitOrder(s *State, ctx *context, dnsNames []core.AcmeIdentifier) error {
 	// create the new order request object
 	initOrder := struct {
 		Identifiers []core.AcmeIdentifier
@@ -489,46 +509,40 @@ func getAuthorization(s *State, url string) (*core.Authorization, error) {
 	return &authz, nil
 }
 
-// completeAuthorization processes a provided authorization by solving its
-// HTTP-01 challenge using the context's account and the state's challenge
-// server. Aftering POSTing the authorization's HTTP-01 challenge the
-// authorization will be polled waiting for a state change.
-func completeAuthorization(authz *core.Authorization, s *State, ctx *context) error {
-	// Skip if the authz isn't pending
-	if authz.Status != core.StatusPending {
-		return nil
+// challengeToSolve picks the challenge of a pending authorization that the load
+// generator will solve. Wildcard authorizations only offer DNS-01 challenges,
+// everything else is solved with HTTP-01.
+func challengeToSolve(authz *core.Authorization) (*core.Challenge, error) {
+	chalType := core.ChallengeTypeHTTP01
+	if authz.Wildcard {
+		chalType = core.ChallengeTypeDNS01
 	}
-
-	// Find a challenge to solve from the pending authorization. For now, we only
-	// process HTTP-01 challenges and must error if there isn't a HTTP-01
-	// challenge to solve.
-	var chalToSolve *core.Challenge
 	for _, challenge := range authz.Challenges {
-		if challenge.Type == core.ChallengeTypeHTTP01 {
-			chalToSolve = &challenge
-			break
+		if challenge.Type == chalType {
+			return &challenge, nil
 		}
 	}
-	if chalToSolve == nil {
-		return errors.New("no http-01 challenges to complete")
-	}
+	return nil, fmt.Errorf("no %s challenges to complete", chalType)
+}
 
-	// Compute the key authorization from the context account's key
+// keyAuthorization computes the key authorization for the provided challenge
+// token from the context account's key.
+func keyAuthorization(ctx *context, token string) (string, error) {
 	jwk := &jose.JSONWebKey{Key: &ctx.acct.key.PublicKey}
 	thumbprint, err := jwk.Thumbprint(crypto.SHA256)
 	if err != nil {
-		return err
+		return "", err
 	}
-	authStr := fmt.Sprintf("%s.%s", chalToSolve.Token, base64.RawURLEncoding.EncodeToString(thumbprint))
-
-	// Add the challenge response to the state's test server
-	s.challSrv.AddHTTPOneChallenge(chalToSolve.Token, authStr)
-	// Clean up after we're done
-	defer s.challSrv.DeleteHTTPOneChallenge(chalToSolve.Token)
+	return fmt.Sprintf("%s.%s", token, base64.RawURLEncoding.EncodeToString(thumbprint)), nil
+}
 
+// postChallenge POSTs the provided key authorization to a challenge URL to
+// begin the validation process, recording the latency and result under the
+// provided action name.
+func postChallenge(s *State, ctx *context, chal *core.Challenge, authStr, action string) error {
 	// Prepare the Challenge POST body
 	update := fmt.Sprintf(`{"keyAuthorization":"%s"}`, authStr)
-	jws, err := ctx.signKeyIDV2Request([]byte(update), chalToSolve.URL)
+	jws, err := ctx.signKeyIDV2Request([]byte(update), chal.URL)
 	if err != nil {
 		return err
 	}
@@ -536,12 +550,12 @@ func completeAuthorization(authz *core.Authorization, s *State, ctx *context) er
 
 	// POST the challenge update to begin the challenge process
 	cStarted := time.Now()
-	resp, err := s.post(chalToSolve.URL, requestPayload, ctx.ns)
+	resp, err := s.post(chal.URL, requestPayload, ctx.ns)
 	cFinished := time.Now()
 	cState := "error"
 	// Record the final latency and state when finished
 	defer func() {
-		s.callLatency.Add("POST /acme/challenge/{ID}", cStarted, cFinished, cState)
+		s.callLatency.Add(action, cStarted, cFinished, cState)
 	}()
 	if err != nil {
 		return err
@@ -557,17 +571,53 @@ func completeAuthorization(authz *core.Authorization, s *State, ctx *context) er
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("Unexpected HTTP response code: %d", resp.StatusCode)
 	}
+	cState = "good"
+	return nil
+}
 
-	// Poll the authorization waiting for the challenge response to be recorded in
-	// a change of state. The polling may sleep and retry a few times if required
-	pollAuthorization(authz, s, ctx)
+// completeAuthorization processes a provided authorization by solving its
+// HTTP-01 challenge (or DNS-01 challenge for wildcard authorizations) using the
+// context's account and the state's challenge server. Aftering POSTing the
+// challenge the authorization will be polled waiting for a state change.
+func completeAuthorization(authz *core.Authorization, s *State, ctx *context) error {
+	// Skip if the authz isn't pending
+	if authz.Status != core.StatusPending {
+		return nil
+	}
+
+	chalToSolve, err := challengeToSolve(authz)
+	if err != nil {
+		return err
+	}
+	authStr, err := keyAuthorization(ctx, chalToSolve.Token)
 	if err != nil {
 		return err
 	}
 
-	// The challenge is completed, the authz is valid
-	cState = "good"
-	return nil
+	// Add the challenge response to the state's test server, cleaning up after
+	// we're done
+	switch chalToSolve.Type {
+	case core.ChallengeTypeHTTP01:
+		s.challSrv.AddHTTPOneChallenge(chalToSolve.Token, authStr)
+		defer s.challSrv.DeleteHTTPOneChallenge(chalToSolve.Token)
+	case core.ChallengeTypeDNS01:
+		if !s.dnsOne {
+			return errors.New("no DNS-01 challenge server configured for wildcard authorization")
+		}
+		host := fmt.Sprintf("_acme-challenge.%s.", authz.Identifier.Value)
+		h := sha256.Sum256([]byte(authStr))
+		s.challSrv.AddDNSOneChallenge(host, base64.RawURLEncoding.EncodeToString(h[:]))
+		defer s.challSrv.DeleteDNSOneChallenge(host)
+	}
+
+	err = postChallenge(s, ctx, chalToSolve, authStr, "POST /acme/challenge/{ID}")
+	if err != nil {
+		return err
+	}
+
+	// Poll the authorization waiting for the challenge response to be recorded in
+	// a change of state. The polling may sleep and retry a few times if required
+	return pollAuthorization(authz, s, ctx)
 }
 
 // pollAuthorization GETs a provided authorization up to three times, sleeping
@@ -628,6 +678,64 @@ func fulfillOrder(s *State, ctx *context) error {
 	return nil
 }
 
+// failOrder processes a pending order from the context, POSTing the HTTP-01
+// challenge of its first pending authorization without adding a response to
+// the state's challenge server. The authorization is then polled waiting for
+// the failed validation to make it invalid. The order is discarded afterwards.
+func failOrder(s *State, ctx *context) error {
+	// There must be at least one pending order in the context to fail
+	if len(ctx.pendingOrders) == 0 {
+		return errors.New("no pending orders to fail")
+	}
+
+	// Get an order to fail from the context
+	order := popPendingOrder(ctx)
+
+	for _, url := range order.Authorizations {
+		// Fetch the authz by its URL
+		authz, err := getAuthorization(s, url)
+		if err != nil {
+			return err
+		}
+		// Authorizations that are already valid (e.g. reused from an earlier
+		// order) can't be failed, so look for a pending one
+		if authz.Status != core.StatusPending {
+			continue
+		}
+
+		var chal *core.Challenge
+		for _, challenge := range authz.Challenges {
+			if challenge.Type == core.ChallengeTypeHTTP01 {
+				chal = &challenge
+				break
+			}
+		}
+		if chal == nil {
+			return errors.New("no http-01 challenges to fail")
+		}
+		authStr, err := keyAuthorization(ctx, chal.Token)
+		if err != nil {
+			return err
+		}
+		err = postChallenge(s, ctx, chal, authStr, "POST /acme/challenge/{ID} (failed validation)")
+		if err != nil {
+			return err
+		}
+
+		// The authz is expected to become invalid, a valid authz means the VA
+		// accepted a response nobody served
+		err = pollAuthorization(authz, s, ctx)
+		if err == nil {
+			return fmt.Errorf("Authorization %q is valid without a challenge response", url)
+		}
+		if !strings.Contains(err.Error(), "status invalid") {
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("Order %q has no pending authorizations to fail", order.URL)
+}
+
 // popPending **removes** a random pending authorization from the context,
 // returning it.
 func popPending(ctx *context) *core.Authorization {
@@ -960,10 +1068,7 @@ func newCertificate(s *State, ctx *context) error {
 // revokeCertificate revokes a random certificate from the context's list of
 // certificates. Presently it always uses the context's registration and the V1
 // style of revocation. The certificate is removed from the context's `certs`
-// list.
-//
-// TODO(@cpu): Write a V2 version of `revokeCertificate` that uses the context's
-// account and a key ID JWS.
+// list. See `revokeCertificateV2` for the V2 equivalent.
 func revokeCertificate(s *State, ctx *context) error {
 	// randomly select a cert to revoke
 	if len(ctx.certs) == 0 {
@@ -1009,3 +1114,66 @@ func revokeCertificate(s *State, ctx *context) error {
 	ctx.certs = append(ctx.certs[:index], ctx.certs[index+1:]...)
 	return nil
 }
+
+// revokeCertificateV2 revokes a random certificate from the context's list of
+// certificates using the context's account and a key ID JWS. The certificate is
+// removed from the context's `certs` list.
+func revokeCertificateV2(s *State, ctx *context) error {
+	// randomly select a cert to revoke
+	if len(ctx.certs) == 0 {
+		return errors.New("no certificates to revoke")
+	}
+
+	index := mrand.Intn(len(ctx.certs))
+	gStarted := time.Now()
+	resp, err := s.get(ctx.certs[index])
+	gFinished := time.Now()
+	gState := "error"
+	defer func() { s.callLatency.Add("GET /acme/cert/{ID}", gStarted, gFinished, gState) }()
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("bad response, status %d: %s", resp.StatusCode, body)
+	}
+	// The V2 certificate endpoint returns a PEM chain, the leaf comes first
+	block, _ := pem.Decode(body)
+	if block == nil {
+		return fmt.Errorf("%s, no PEM certificate in response", ctx.certs[index])
+	}
+	gState = "good"
+
+	request := fmt.Sprintf(`{"certificate":"%s"}`, base64.RawURLEncoding.EncodeToString(block.Bytes))
+	url := fmt.Sprintf("%s%s", s.apiBase, revokeCertPath)
+	jws, err := ctx.signKeyIDV2Request([]byte(request), url)
+	if err != nil {
+		return err
+	}
+	requestPayload := []byte(jws.FullSerialize())
+
+	started := time.Now()
+	resp, err = s.post(url, requestPayload, ctx.ns)
+	finished := time.Now()
+	state := "error"
+	defer func() { s.callLatency.Add("POST /acme/revoke-cert", started, finished, state) }()
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("bad response, status %d: %s", resp.StatusCode, body)
+	}
+
+	ctx.certs = append(ctx.certs[:index], ctx.certs[index+1:]...)
+	state = "good"
+	return nil
+}
@@ -16,20 +16,27 @@ import (
 type Config struct {
 	// Execution plan parameters
 	Plan struct {
-		Actions   []string // things to do
-		Rate      int64    // requests / s
-		RateDelta string   // requests / s^2
-		Runtime   string   // how long to run for
+		Actions   []string         // things to do
+		Scenarios []ScenarioConfig // weighted sets of things to do, instead of Actions
+		Rate      int64            // requests / s
+		RateDelta string           // requests / s^2
+		Ramp      []struct {       // rate profile to follow, instead of Rate and RateDelta
+			For  string // how long to hold the rate for
+			Rate int64  // requests / s
+		}
+		Runtime string // how long to run for, defaults to the length of Ramp
 	}
 	ExternalState   string // path to file to load/save registrations etc to/from
 	DontSaveState   bool   // don't save changes to external state
 	APIBase         string // ACME API address to send requests to
 	DomainBase      string // base domain name to create authorizations for
 	HTTPOneAddr     string // address to listen for http-01 validation requests on
+	DNSOneAddr      string // address to listen for dns-01 validation requests on
 	RealIP          string // value of the Real-IP header to use when bypassing CDN
 	CertKeySize     int    // size of the key to use when creating CSRs
 	RegEmail        string // email to use in registrations
 	Results         string // path to save metrics to
+	Report          string // path to save the per-operation latency/error report to
 	MaxRegs         int    // maximum number of registrations to create
 	MaxNamesPerCert int    // maximum number of names on one certificate/order
 }
@@ -37,6 +44,7 @@ type Config struct {
 func main() {
 	configPath := flag.String("config", "", "Path to configuration file for load-generator")
 	resultsPath := flag.String("results", "", "Path to latency results file")
+	reportPath := flag.String("report", "", "Path to per-operation latency/error report file")
 	rateArg := flag.Int("rate", 0, "")
 	runtimeArg := flag.String("runtime", "", "")
 	deltaArg := flag.String("delta", "", "")
@@ -57,6 +65,9 @@ func main() {
 	if *resultsPath != "" {
 		config.Results = *resultsPath
 	}
+	if *reportPath != "" {
+		config.Report = *reportPath
+	}
 	if *rateArg != 0 {
 		config.Plan.Rate = int64(*rateArg)
 	}
@@ -67,6 +78,15 @@ func main() {
 		config.Plan.RateDelta = *deltaArg
 	}
 
+	scenarios := config.Plan.Scenarios
+	if len(config.Plan.Actions) > 0 {
+		if len(scenarios) > 0 {
+			fmt.Fprintf(os.Stderr, "Plan can't contain both actions and scenarios\n")
+			os.Exit(1)
+		}
+		scenarios = []ScenarioConfig{{Name: "default", Ratio: 1, Actions: config.Plan.Actions}}
+	}
+
 	s, err := New(
 		config.APIBase,
 		config.CertKeySize,
@@ -76,7 +96,7 @@ func main() {
 		config.MaxNamesPerCert,
 		config.Results,
 		config.RegEmail,
-		config.Plan.Actions,
+		scenarios,
 	)
 	cmd.FailOnError(err, "Failed to create load generator")
 
@@ -85,8 +105,28 @@ func main() {
 		cmd.FailOnError(err, "Failed to load registration snapshot")
 	}
 
-	runtime, err := time.ParseDuration(config.Plan.Runtime)
-	cmd.FailOnError(err, "Failed to parse plan runtime")
+	var ramp []RatePeriod
+	var rampLength time.Duration
+	for _, step := range config.Plan.Ramp {
+		period, err := time.ParseDuration(step.For)
+		cmd.FailOnError(err, "Failed to parse period of Ramp step")
+		if step.Rate <= 0 {
+			fmt.Fprintf(os.Stderr, "Ramp step rates must be positive\n")
+			os.Exit(1)
+		}
+		ramp = append(ramp, RatePeriod{For: period, Rate: step.Rate})
+		rampLength += period
+	}
+	if len(ramp) > 0 && config.Plan.RateDelta != "" {
+		fmt.Fprintf(os.Stderr, "Plan can't contain both a Ramp and a RateDelta\n")
+		os.Exit(1)
+	}
+
+	runtime := rampLength
+	if config.Plan.Runtime != "" {
+		runtime, err = time.ParseDuration(config.Plan.Runtime)
+		cmd.FailOnError(err, "Failed to parse plan runtime")
+	}
 
 	var delta *RateDelta
 	if config.Plan.RateDelta != "" {
@@ -104,13 +144,19 @@ func main() {
 
 	go cmd.CatchSignals(nil, nil)
 
-	err = s.Run(config.HTTPOneAddr, Plan{
+	err = s.Run(config.HTTPOneAddr, config.DNSOneAddr, Plan{
 		Runtime: runtime,
 		Rate:    config.Plan.Rate,
 		Delta:   delta,
+		Ramp:    ramp,
 	})
 	cmd.FailOnError(err, "Failed to run load generator")
 
+	if config.Report != "" {
+		err = s.SaveReport(config.Report)
+		cmd.FailOnError(err, "Failed to save report")
+	}
+
 	if config.ExternalState != "" && !config.DontSaveState {
 		err = s.Snapshot(config.ExternalState)
 		cmd.FailOnError(err, "Failed to save registration snapshot")
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// opStats holds the latency of every call made for one ACME operation and how
+// many of those calls failed
+type opStats struct {
+	latencies []time.Duration
+	errors    int64
+}
+
+// flowStats counts the flows run for one scenario
+type flowStats struct {
+	completed int64
+	failed    int64
+}
+
+// report is a latencyWriter that aggregates per-operation latency and error
+// statistics in memory, passing every point on to another latencyWriter (e.g.
+// the results file). It also counts completed and failed flows per scenario.
+type report struct {
+	next latencyWriter
+
+	mu    sync.Mutex
+	ops   map[string]*opStats
+	flows map[string]*flowStats
+}
+
+type opSummary struct {
+	Action    string  `json:"action"`
+	Count     int     `json:"count"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"errorRate"`
+	P50       float64 `json:"p50Ms"`
+	P90       float64 `json:"p90Ms"`
+	P99       float64 `json:"p99Ms"`
+	Max       float64 `json:"maxMs"`
+}
+
+type scenarioSummary struct {
+	Scenario  string `json:"scenario"`
+	Completed int64  `json:"completed"`
+	Failed    int64  `json:"failed"`
+}
+
+type reportSummary struct {
+	Operations []opSummary       `json:"operations"`
+	Scenarios  []scenarioSummary `json:"scenarios"`
+}
+
+func newReport(next latencyWriter) *report {
+	return &report{
+		next:  next,
+		ops:   make(map[string]*opStats),
+		flows: make(map[string]*flowStats),
+	}
+}
+
+// Add records a point and passes it on
+func (r *report) Add(action string, sent, finished time.Time, pType string) {
+	r.mu.Lock()
+	stats, ok := r.ops[action]
+	if !ok {
+		stats = &opStats{}
+		r.ops[action] = stats
+	}
+	stats.latencies = append(stats.latencies, finished.Sub(sent))
+	if pType != "good" {
+		stats.errors++
+	}
+	r.mu.Unlock()
+	r.next.Add(action, sent, finished, pType)
+}
+
+// Close closes the wrapped latencyWriter, the aggregated statistics remain
+// available
+func (r *report) Close() {
+	r.next.Close()
+}
+
+// addFlow records the outcome of running a scenario
+func (r *report) addFlow(scenario string, failed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats, ok := r.flows[scenario]
+	if !ok {
+		stats = &flowStats{}
+		r.flows[scenario] = stats
+	}
+	if failed {
+		stats.failed++
+	} else {
+		stats.completed++
+	}
+}
+
+// percentile returns the nearest-rank percentile p (0-100) of a sorted list of
+// latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p / 100 * float64(len(sorted)))
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// summary computes the statistics for each operation and scenario, sorted by
+// name
+func (r *report) summary() reportSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sum reportSummary
+	for action, stats := range r.ops {
+		sorted := make([]time.Duration, len(stats.latencies))
+		copy(sorted, stats.latencies)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		sum.Operations = append(sum.Operations, opSummary{
+			Action:    action,
+			Count:     len(sorted),
+			Errors:    stats.errors,
+			ErrorRate: float64(stats.errors) / float64(len(sorted)),
+			P50:       millis(percentile(sorted, 50)),
+			P90:       millis(percentile(sorted, 90)),
+			P99:       millis(percentile(sorted, 99)),
+			Max:       millis(sorted[len(sorted)-1]),
+		})
+	}
+	sort.Slice(sum.Operations, func(i, j int) bool {
+		return sum.Operations[i].Action < sum.Operations[j].Action
+	})
+	for name, stats := range r.flows {
+		sum.Scenarios = append(sum.Scenarios, scenarioSummary{
+			Scenario:  name,
+			Completed: stats.completed,
+			Failed:    stats.failed,
+		})
+	}
+	sort.Slice(sum.Scenarios, func(i, j int) bool {
+		return sum.Scenarios[i].Scenario < sum.Scenarios[j].Scenario
+	})
+	return sum
+}
+
+// print writes the report as a pair of tables
+func (r *report) print(out io.Writer) {
+	sum := r.summary()
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Operation\tCalls\tErrors\tError rate\tp50 (ms)\tp90 (ms)\tp99 (ms)\tMax (ms)")
+	for _, op := range sum.Operations {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.2f%%\t%.1f\t%.1f\t%.1f\t%.1f\n",
+			op.Action, op.Count, op.Errors, op.ErrorRate*100, op.P50, op.P90, op.P99, op.Max)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Scenario\tCompleted\tFailed")
+	for _, sc := range sum.Scenarios {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", sc.Scenario, sc.Completed, sc.Failed)
+	}
+	_ = w.Flush()
+}
+
+// save writes the report to a file as JSON
+func (r *report) save(filename string) error {
+	cont, err := json.MarshalIndent(r.summary(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, cont, os.ModePerm)
+}
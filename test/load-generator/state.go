@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	mrand "math/rand"
 	"net"
 	"net/http"
 	"os"
@@ -187,6 +188,25 @@ type Plan struct {
 	Runtime time.Duration
 	Rate    int64
 	Delta   *RateDelta
+	// Ramp, if not empty, replaces Rate and Delta with a sequence of rates that
+	// are each maintained for a given period
+	Ramp []RatePeriod
+}
+
+// ScenarioConfig describes a named sequence of actions that makes up one flow.
+// Each flow runs a scenario picked at random, weighted by its Ratio relative to
+// the Ratio of the other scenarios in the plan.
+type ScenarioConfig struct {
+	Name    string
+	Ratio   int
+	Actions []string
+}
+
+// scenario is a ScenarioConfig with its actions converted to operations
+type scenario struct {
+	name       string
+	ratio      int
+	operations []func(*State, *context) error
 }
 
 type respCode struct {
@@ -204,7 +224,8 @@ type State struct {
 	realIP          string
 	certKey         *ecdsa.PrivateKey
 
-	operations []func(*State, *context) error
+	scenarios  []scenario
+	totalRatio int
 
 	rMu sync.RWMutex
 
@@ -214,7 +235,9 @@ type State struct {
 	accts []*account
 
 	challSrv    *challtestsrv.ChallSrv
+	dnsOne      bool
 	callLatency latencyWriter
+	report      *report
 	client      *http.Client
 
 	getTotal  int64
@@ -350,7 +373,7 @@ func New(
 	maxRegs, maxNamesPerCert int,
 	latencyPath string,
 	userEmail string,
-	operations []string) (*State, error) {
+	scenarios []ScenarioConfig) (*State, error) {
 	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
 		return nil, err
@@ -374,12 +397,14 @@ func New(
 	if err != nil {
 		return nil, err
 	}
+	report := newReport(latencyFile)
 	s := &State{
 		client:          client,
 		apiBase:         apiBase,
 		certKey:         certKey,
 		domainBase:      domainBase,
-		callLatency:     latencyFile,
+		callLatency:     report,
+		report:          report,
 		wg:              new(sync.WaitGroup),
 		realIP:          realIP,
 		maxRegs:         maxRegs,
@@ -389,25 +414,46 @@ func New(
 	}
 
 	// convert operations strings to methods
-	for _, opName := range operations {
-		op, present := stringToOperation[opName]
-		if !present {
-			return nil, fmt.Errorf("unknown operation %q", opName)
+	for _, sc := range scenarios {
+		if sc.Ratio <= 0 {
+			return nil, fmt.Errorf("scenario %q must have a positive ratio", sc.Name)
 		}
-		s.operations = append(s.operations, op)
+		if len(sc.Actions) == 0 {
+			return nil, fmt.Errorf("scenario %q has no actions", sc.Name)
+		}
+		parsed := scenario{name: sc.Name, ratio: sc.Ratio}
+		for _, opName := range sc.Actions {
+			op, present := stringToOperation[opName]
+			if !present {
+				return nil, fmt.Errorf("unknown operation %q in scenario %q", opName, sc.Name)
+			}
+			parsed.operations = append(parsed.operations, op)
+		}
+		s.scenarios = append(s.scenarios, parsed)
+		s.totalRatio += sc.Ratio
+	}
+	if len(s.scenarios) == 0 {
+		return nil, errors.New("no actions or scenarios to run")
 	}
 
 	return s, nil
 }
 
-// Run runs the WFE load-generator
-func (s *State) Run(httpOneAddr string, p Plan) error {
-	// Create a new challenge server for HTTP-01 challenges
-	challSrv, err := challtestsrv.New(challtestsrv.Config{
+// Run runs the WFE load-generator. If dnsOneAddr is not empty a DNS-01
+// challenge server is started alongside the HTTP-01 challenge server, which is
+// required to fulfill wildcard orders.
+func (s *State) Run(httpOneAddr, dnsOneAddr string, p Plan) error {
+	// Create a new challenge server for HTTP-01 and DNS-01 challenges
+	config := challtestsrv.Config{
 		HTTPOneAddrs: []string{httpOneAddr},
 		// Use a logger that has a load-generator prefix
 		Log: log.New(os.Stdout, "load-generator challsrv - ", log.LstdFlags),
-	})
+	}
+	if dnsOneAddr != "" {
+		config.DNSOneAddrs = []string{dnsOneAddr}
+		s.dnsOne = true
+	}
+	challSrv, err := challtestsrv.New(config)
 	if err != nil {
 		return err
 	}
@@ -417,6 +463,9 @@ func (s *State) Run(httpOneAddr string, p Plan) error {
 	// Start the Challenge server in its own Go routine
 	go s.challSrv.Run()
 	fmt.Printf("[+] Started http-01 challenge server: %q\n", httpOneAddr)
+	if s.dnsOne {
+		fmt.Printf("[+] Started dns-01 challenge server: %q\n", dnsOneAddr)
+	}
 
 	if p.Delta != nil {
 		go func() {
@@ -426,6 +475,16 @@ func (s *State) Run(httpOneAddr string, p Plan) error {
 			}
 		}()
 	}
+	if len(p.Ramp) > 0 {
+		p.Rate = p.Ramp[0].Rate
+		go func() {
+			for _, period := range p.Ramp {
+				atomic.StoreInt64(&p.Rate, period.Rate)
+				fmt.Printf("[+] Ramping to %d/s for %s\n", period.Rate, period.For)
+				time.Sleep(period.For)
+			}
+		}()
+	}
 
 	// Run sending loop
 	stop := make(chan bool, 1)
@@ -484,9 +543,16 @@ func (s *State) Run(httpOneAddr string, p Plan) error {
 	s.wg.Wait()
 	fmt.Println("[+] Shutting down challenge server")
 	s.challSrv.Shutdown()
+	s.report.print(os.Stdout)
 	return nil
 }
 
+// SaveReport writes the per-operation latency/error report to a file as JSON
+func (s *State) SaveReport(filename string) error {
+	fmt.Printf("[+] Saving report to %s\n", filename)
+	return s.report.save(filename)
+}
+
 // HTTP utils
 
 func (s *State) addRespCode(code int) {
@@ -647,18 +713,34 @@ func (s *State) addRegistration(reg *registration) {
 	s.regs = append(s.regs, reg)
 }
 
+// pickScenario selects a random scenario, weighted by the scenario ratios
+func (s *State) pickScenario() *scenario {
+	n := mrand.Intn(s.totalRatio)
+	for i := range s.scenarios {
+		if n < s.scenarios[i].ratio {
+			return &s.scenarios[i]
+		}
+		n -= s.scenarios[i].ratio
+	}
+	return &s.scenarios[len(s.scenarios)-1]
+}
+
 func (s *State) sendCall() {
 	defer s.wg.Done()
 	ctx := &context{}
+	sc := s.pickScenario()
 
-	for _, op := range s.operations {
+	failed := false
+	for _, op := range sc.operations {
 		err := op(s, ctx)
 		if err != nil {
 			method := runtime.FuncForPC(reflect.ValueOf(op).Pointer()).Name()
-			fmt.Printf("[FAILED] %s: %s\n", method, err)
+			fmt.Printf("[FAILED] %s: %s: %s\n", sc.name, method, err)
+			failed = true
 			break
 		}
 	}
+	s.report.addFlow(sc.name, failed)
 	// If the context's V1 registration
 	if ctx.reg != nil {
 		ctx.reg.update(ctx.finalizedAuthz, ctx.certs)
@@ -0,0 +1,486 @@
+// A tool that generates SQL filling an empty SA database with synthetic
+// accounts, authorizations and certificates, in order to test the performance
+// of rate limiting, the OCSP updater and the purgers against a production-like
+// amount of data. For example:
+//
+//	go run ./test/sa-fixture-gen -config test/sa-fixture-gen/config.json | mysql -uroot boulder_sa_integration
+//
+// Accounts get a Zipf distributed number of certificates, which are renewed
+// every 60 days or so until they're abandoned, and names are picked from a Zipf
+// distributed pool of registered domains. Every certificate has a valid
+// authorization for each of its names, some of which were preceded by failed
+// ones, and some certificates are revoked or left behind a pending
+// authorization. Certificates have no OCSP response yet, as if they had just
+// been added, so the OCSP updater signs them all in its first run.
+//
+// The same seed always gives the same accounts, names and times, but the keys
+// and signatures differ between runs.
+package main
+
+import (
+	"bufio"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"math/rand"
+	"net"
+	"os"
+	"time"
+
+	"github.com/cloudflare/cfssl/helpers"
+	jose "gopkg.in/square/go-jose.v2"
+
+	"github.com/letsencrypt/boulder/cmd"
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/probs"
+	"github.com/letsencrypt/boulder/sa"
+)
+
+type fixtureConfig struct {
+	FixtureGen struct {
+		// Accounts is how many accounts to generate.
+		Accounts int
+		// Domains is how many registered domains the names of certificates
+		// are under. A few of them are very popular, as with hosting providers.
+		Domains int
+		// MaxChainsPerAccount is how many renewal chains of certificates, each
+		// for a different set of names, an account may have. Most have none or
+		// one.
+		MaxChainsPerAccount int
+		// Span is how long before now the accounts were created over.
+		Span cmd.ConfigDuration
+		// Seed seeds the random choices. Defaults to 1.
+		Seed int64
+		// BatchSize is how many rows each INSERT statement holds.
+		BatchSize int
+		// IssuerCert and IssuerKey are the PEM files of the CA that issues the
+		// certificates, so that the OCSP updater can sign responses for them.
+		// If they're empty, an ephemeral CA is used.
+		IssuerCert string
+		IssuerKey  string
+	}
+}
+
+const (
+	certLifetime         = 90 * 24 * time.Hour
+	renewalPeriod        = 60 * 24 * time.Hour
+	authzLifetime        = 30 * 24 * time.Hour
+	pendingAuthzLifetime = 7 * 24 * time.Hour
+
+	// Chance of a certificate not being renewed
+	abandonRate = 0.15
+	// Chance of a certificate being revoked
+	revocationRate = 0.01
+	// Chance of a name having failed validation before it was issued for
+	chanceFailedAuthz = 0.1
+	// Chance of a certificate leaving a pending authorization behind
+	chancePendingAuthz = 0.05
+	// Chance of a chain being for a wildcard
+	chanceWildcard = 0.03
+
+	agreement = "http://boulder:4000/terms/v1"
+)
+
+var tlds = []string{"com", "com", "com", "com", "net", "org", "de", "uk", "io", "fr"}
+
+var challengeTypes = []string{core.ChallengeTypeHTTP01, core.ChallengeTypeDNS01, core.ChallengeTypeTLSALPN01}
+
+// combinations is the JSON combinations of every authorization, each of the
+// challengeTypes on its own.
+const combinations = "[[0],[1],[2]]"
+
+// Revocation reasons picked from for revoked certificates: unspecified,
+// keyCompromise, superseded and cessationOfOperation.
+var revocationReasons = []int{0, 1, 4, 5}
+
+type generator struct {
+	rand *rand.Rand
+	now  time.Time
+	span time.Duration
+
+	chainsPerAccount *rand.Zipf
+	domains          *rand.Zipf
+	networks         *rand.Zipf
+	namesTail        *rand.Zipf
+
+	issuer    *x509.Certificate
+	issuerKey crypto.Signer
+	leafKey   crypto.PublicKey
+
+	out               *sqlWriter
+	registrations     *table
+	authz             *table
+	pendingAuthzs     *table
+	challenges        *table
+	certificates      *table
+	certificateStatus *table
+	issuedNames       *table
+	fqdnSets          *table
+}
+
+// account is what's needed of an account to generate its certificates.
+type account struct {
+	id         int64
+	createdAt  time.Time
+	ip         net.IP
+	thumbprint string
+}
+
+func newGenerator(r *rand.Rand, now time.Time, span time.Duration, domains, accounts, maxChains int, out *sqlWriter) *generator {
+	g := &generator{
+		rand:             r,
+		now:              now,
+		span:             span,
+		chainsPerAccount: rand.NewZipf(r, 2.5, 1, uint64(maxChains)),
+		domains:          rand.NewZipf(r, 1.1, 1, uint64(domains-1)),
+		// Accounts are created from one of about a tenth as many /24s, most
+		// of them busy.
+		networks:  rand.NewZipf(r, 1.1, 1, uint64(accounts/10)),
+		namesTail: rand.NewZipf(r, 1.5, 1, 97),
+		out:       out,
+	}
+	g.registrations = out.table("registrations",
+		"id", "jwk", "jwk_sha256", "contact", "agreement", "initialIP", "createdAt", "LockCol", "status")
+	g.authz = out.table("authz",
+		"id", "identifier", "registrationID", "status", "expires", "combinations")
+	g.pendingAuthzs = out.table("pendingAuthorizations",
+		"id", "identifier", "registrationID", "status", "expires", "combinations", "LockCol")
+	g.challenges = out.table("challenges",
+		"authorizationID", "type", "status", "error", "token", "keyAuthorization", "validationRecord", "LockCol")
+	g.certificates = out.table("certificates",
+		"registrationID", "serial", "digest", "der", "issued", "expires")
+	g.certificateStatus = out.table("certificateStatus",
+		"serial", "status", "ocspLastUpdated", "revokedDate", "revokedReason", "lastExpirationNagSent",
+		"ocspResponse", "notAfter", "isExpired", "LockCol")
+	g.issuedNames = out.table("issuedNames",
+		"reversedName", "notBefore", "serial", "renewal")
+	g.fqdnSets = out.table("fqdnSets",
+		"setHash", "serial", "issued", "expires")
+	return g
+}
+
+// chance returns true with probability p.
+func (g *generator) chance(p float64) bool {
+	return g.rand.Float64() < p
+}
+
+// between returns a random time in [start, end).
+func (g *generator) between(start, end time.Time) time.Time {
+	if !end.After(start) {
+		return start
+	}
+	return start.Add(time.Duration(g.rand.Int63n(int64(end.Sub(start))))).Truncate(time.Second)
+}
+
+// token returns a random token in the format of core.NewToken.
+func (g *generator) token() string {
+	b := make([]byte, 32)
+	g.rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// namesPerCert returns how many names a certificate has: most have one or two,
+// with a long tail up to the limit of 100.
+func (g *generator) namesPerCert() int {
+	switch f := g.rand.Float64(); {
+	case f < 0.7:
+		return 1
+	case f < 0.9:
+		return 2
+	default:
+		return 3 + int(g.namesTail.Uint64())
+	}
+}
+
+// names returns the names of a new renewal chain.
+func (g *generator) names() []string {
+	domain := fmt.Sprintf("fixture-%d.%s", g.domains.Uint64(), tlds[g.rand.Intn(len(tlds))])
+	if g.chance(chanceWildcard) {
+		return []string{domain, "*." + domain}
+	}
+	names := []string{domain}
+	for i, n := 1, g.namesPerCert(); i < n; i++ {
+		if i == 1 {
+			names = append(names, "www."+domain)
+		} else {
+			names = append(names, fmt.Sprintf("host%d.%s", i, domain))
+		}
+	}
+	return names
+}
+
+func (g *generator) account(id int64) (*account, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	jwk := jose.JSONWebKey{Key: &key.PublicKey}
+	jwkJSON, err := jwk.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	keyDigest, err := core.KeyDigest(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	thumbprint, err := jwk.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	network := g.networks.Uint64()
+	acct := &account{
+		id:         id,
+		createdAt:  g.between(g.now.Add(-g.span), g.now),
+		ip:         net.IPv4(10, byte(network>>8), byte(network), byte(g.rand.Intn(256))),
+		thumbprint: base64.RawURLEncoding.EncodeToString(thumbprint),
+	}
+
+	contact := "[]"
+	if g.chance(0.7) {
+		contact = fmt.Sprintf(`["mailto:admin-%d@example.com"]`, id)
+	}
+	status := core.StatusValid
+	if g.chance(0.01) {
+		status = core.StatusDeactivated
+	}
+	g.out.insert(g.registrations, id, jwkJSON, keyDigest, contact, agreement,
+		[]byte(acct.ip.To16()), acct.createdAt, 0, string(status))
+	return acct, nil
+}
+
+// chain generates the certificates an account was issued for a set of names,
+// renewing them until they're abandoned.
+func (g *generator) chain(acct *account) error {
+	names := g.names()
+	issued := g.between(acct.createdAt, g.now)
+	for renewal := false; issued.Before(g.now); renewal = true {
+		for _, name := range names {
+			g.authorizations(acct, name, issued)
+		}
+		if g.chance(chancePendingAuthz) {
+			g.pendingAuthorization(acct, names[0], g.between(issued, g.now))
+		}
+		err := g.certificate(acct, names, issued, renewal)
+		if err != nil {
+			return err
+		}
+		if g.chance(abandonRate) {
+			break
+		}
+		issued = issued.Add(renewalPeriod + time.Duration(g.rand.Int63n(int64(24*time.Hour))))
+	}
+	return nil
+}
+
+// authorizations generates the valid authorization for a name of a
+// certificate issued at issued, preceded by an invalid one some of the time.
+func (g *generator) authorizations(acct *account, name string, issued time.Time) {
+	created := issued.Add(-time.Duration(1+g.rand.Intn(600)) * time.Second)
+	if g.chance(chanceFailedAuthz) {
+		failed := created.Add(-time.Duration(1+g.rand.Intn(3600)) * time.Second)
+		g.finalAuthorization(acct, name, core.StatusInvalid, failed.Add(pendingAuthzLifetime))
+	}
+	g.finalAuthorization(acct, name, core.StatusValid, created.Add(authzLifetime))
+}
+
+func identifier(name string) string {
+	if base, wildcard := core.WildcardBase(name); wildcard {
+		name = base
+	}
+	return fmt.Sprintf(`{"type":"dns","value":%q}`, name)
+}
+
+// finalAuthorization generates a valid or invalid authorization, and its
+// challenges, one of which was attempted. Wildcard names are validated with
+// DNS-01.
+func (g *generator) finalAuthorization(acct *account, name string, status core.AcmeStatus, expires time.Time) {
+	id := g.token()
+	g.out.insert(g.authz, id, identifier(name), acct.id, string(status), expires, combinations)
+
+	attempted := g.rand.Intn(len(challengeTypes))
+	if _, wildcard := core.WildcardBase(name); wildcard {
+		attempted = 1
+	}
+	token := g.token()
+	for i, typ := range challengeTypes {
+		if i != attempted {
+			g.challenge(id, typ, core.StatusPending, token, "", nil, nil)
+			continue
+		}
+		var validationErr []byte
+		if status == core.StatusInvalid {
+			validationErr, _ = json.Marshal(probs.Unauthorized("The key authorization file from the server did not match this challenge"))
+		}
+		host, _ := core.WildcardBase(name)
+		record := core.ValidationRecord{Hostname: host}
+		if typ == core.ChallengeTypeHTTP01 {
+			addr := net.IPv4(192, 0, 2, byte(g.rand.Intn(256)))
+			record.URL = fmt.Sprintf("http://%s/.well-known/acme-challenge/%s", host, token)
+			record.Port = "80"
+			record.AddressesResolved = []net.IP{addr}
+			record.AddressUsed = addr
+		}
+		validationRecord, _ := json.Marshal([]core.ValidationRecord{record})
+		g.challenge(id, typ, status, token, token+"."+acct.thumbprint, validationErr, validationRecord)
+	}
+}
+
+// pendingAuthorization generates a pending authorization created at created
+// that was never validated.
+func (g *generator) pendingAuthorization(acct *account, name string, created time.Time) {
+	id := g.token()
+	g.out.insert(g.pendingAuthzs, id, identifier(name), acct.id, string(core.StatusPending),
+		created.Add(pendingAuthzLifetime), combinations, 0)
+	token := g.token()
+	for _, typ := range challengeTypes {
+		g.challenge(id, typ, core.StatusPending, token, "", nil, nil)
+	}
+}
+
+func (g *generator) challenge(authzID, typ string, status core.AcmeStatus, token, keyAuthz string, validationErr, validationRecord []byte) {
+	g.out.insert(g.challenges, authzID, typ, string(status), validationErr, token, keyAuthz, validationRecord, 0)
+}
+
+// certificate generates a certificate for names issued at issued, with its
+// status, issued names and FQDN set.
+func (g *generator) certificate(acct *account, names []string, issued time.Time, renewal bool) error {
+	serialBytes := make([]byte, 18)
+	g.rand.Read(serialBytes)
+	serialBytes[0] = 0xff
+	serialNumber := big.NewInt(0).SetBytes(serialBytes)
+	serial := core.SerialToString(serialNumber)
+	expires := issued.Add(certLifetime - time.Second)
+
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: names[0]},
+		DNSNames:              names,
+		NotBefore:             issued,
+		NotAfter:              expires,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(crand.Reader, template, g.issuer, g.leafKey, g.issuerKey)
+	if err != nil {
+		return err
+	}
+	g.out.insert(g.certificates, acct.id, serial, core.Fingerprint256(der), der, issued, expires)
+
+	status := core.OCSPStatusGood
+	var revokedDate time.Time
+	var revokedReason int
+	if g.chance(revocationRate) {
+		status = core.OCSPStatusRevoked
+		end := expires
+		if g.now.Before(end) {
+			end = g.now
+		}
+		revokedDate = g.between(issued, end)
+		revokedReason = revocationReasons[g.rand.Intn(len(revocationReasons))]
+	}
+	g.out.insert(g.certificateStatus, serial, string(status), time.Time{}, revokedDate, revokedReason,
+		time.Time{}, []byte{}, expires, expires.Before(g.now), 0)
+
+	for _, name := range names {
+		g.out.insert(g.issuedNames, sa.ReverseName(name), issued, serial, renewal)
+	}
+	g.out.insert(g.fqdnSets, core.HashNames(names), serial, issued, expires)
+	return nil
+}
+
+// loadIssuer loads the issuer certificate and key, or makes an ephemeral
+// issuer if the files aren't configured.
+func loadIssuer(certFile, keyFile string) (*x509.Certificate, crypto.Signer, error) {
+	if certFile == "" && keyFile == "" {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		template := &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: "sa-fixture-gen issuer"},
+			NotBefore:             time.Now().Add(-10 * 365 * 24 * time.Hour),
+			NotAfter:              time.Now().Add(10 * 365 * 24 * time.Hour),
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}
+		der, err := x509.CreateCertificate(crand.Reader, template, template, key.Public(), key)
+		if err != nil {
+			return nil, nil, err
+		}
+		cert, err := x509.ParseCertificate(der)
+		return cert, key, err
+	}
+	cert, err := core.LoadCert(certFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := helpers.ParsePrivateKeyPEM(keyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+func main() {
+	configPath := flag.String("config", "config.json", "Path to configuration file")
+	outputPath := flag.String("output", "", "Path to write the SQL to, instead of stdout")
+	flag.Parse()
+
+	configJSON, err := ioutil.ReadFile(*configPath)
+	cmd.FailOnError(err, fmt.Sprintf("Failed to read config file '%s'", *configPath))
+	var config fixtureConfig
+	err = json.Unmarshal(configJSON, &config)
+	cmd.FailOnError(err, "Failed to parse config")
+	c := config.FixtureGen
+	if c.Accounts <= 0 || c.Domains <= 1 || c.MaxChainsPerAccount <= 0 || c.Span.Duration <= 0 || c.BatchSize <= 0 {
+		cmd.Fail("accounts, domains, maxChainsPerAccount, span and batchSize must be positive, and domains more than one")
+	}
+	if c.Seed == 0 {
+		c.Seed = 1
+	}
+
+	issuer, issuerKey, err := loadIssuer(c.IssuerCert, c.IssuerKey)
+	cmd.FailOnError(err, "Failed to load issuer")
+	// Every certificate is for the same key, as generating one per
+	// certificate would be slow and nothing reads them.
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	cmd.FailOnError(err, "Failed to generate certificate key")
+
+	output := os.Stdout
+	if *outputPath != "" {
+		output, err = os.Create(*outputPath)
+		cmd.FailOnError(err, "Failed to create output file")
+		defer output.Close()
+	}
+	out := newSQLWriter(bufio.NewWriterSize(output, 1<<20), c.BatchSize)
+
+	g := newGenerator(rand.New(rand.NewSource(c.Seed)), time.Now().UTC().Truncate(time.Second),
+		c.Span.Duration, c.Domains, c.Accounts, c.MaxChainsPerAccount, out)
+	g.issuer, g.issuerKey, g.leafKey = issuer, issuerKey, leafKey.Public()
+	for id := int64(1); id <= int64(c.Accounts); id++ {
+		acct, err := g.account(id)
+		cmd.FailOnError(err, "Failed to generate account")
+		for i := g.chainsPerAccount.Uint64(); i > 0; i-- {
+			err = g.chain(acct)
+			cmd.FailOnError(err, "Failed to generate certificates")
+		}
+	}
+	err = out.close()
+	cmd.FailOnError(err, "Failed to write SQL")
+}
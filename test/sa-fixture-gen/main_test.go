@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+// generate returns the SQL for accounts accounts generated with seed.
+func generate(t *testing.T, seed int64, accounts int) string {
+	var buf bytes.Buffer
+	out := newSQLWriter(bufio.NewWriter(&buf), 100)
+	now := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	g := newGenerator(rand.New(rand.NewSource(seed)), now, 365*24*time.Hour, 50, accounts, 3, out)
+	var err error
+	g.issuer, g.issuerKey, err = loadIssuer("", "")
+	test.AssertNotError(t, err, "loadIssuer failed")
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	test.AssertNotError(t, err, "generating key")
+	g.leafKey = leafKey.Public()
+	for id := int64(1); id <= int64(accounts); id++ {
+		acct, err := g.account(id)
+		test.AssertNotError(t, err, "account failed")
+		// Give every account a chain, so that each table gets rows
+		err = g.chain(acct)
+		test.AssertNotError(t, err, "chain failed")
+	}
+	err = out.close()
+	test.AssertNotError(t, err, "close failed")
+	return buf.String()
+}
+
+func TestGenerate(t *testing.T) {
+	sql := generate(t, 1, 20)
+	for _, table := range []string{"registrations", "authz", "challenges", "certificates", "certificateStatus", "issuedNames", "fqdnSets"} {
+		test.Assert(t, strings.Contains(sql, "INSERT INTO `"+table+"` "), "no rows were inserted into "+table)
+	}
+	test.AssertEquals(t, strings.Count(sql, "INSERT INTO `registrations` "), 1)
+	test.Assert(t, strings.HasSuffix(sql, "COMMIT;\nSET UNIQUE_CHECKS = 1;\nSET FOREIGN_KEY_CHECKS = 1;\n"), "SQL doesn't end by committing and turning checks back on")
+
+	// The same seed gives the same names, serials and times, which is all of
+	// an FQDN set
+	test.AssertEquals(t, insertInto(generate(t, 1, 20), "fqdnSets"), insertInto(sql, "fqdnSets"))
+	test.AssertNotEquals(t, insertInto(generate(t, 2, 20), "fqdnSets"), insertInto(sql, "fqdnSets"))
+}
+
+// insertInto returns the INSERT statements for table in sql.
+func insertInto(sql, table string) string {
+	var inserts []string
+	for _, statement := range strings.SplitAfter(sql, ";\n") {
+		if strings.HasPrefix(statement, "INSERT INTO `"+table+"` ") {
+			inserts = append(inserts, statement)
+		}
+	}
+	return strings.Join(inserts, "")
+}
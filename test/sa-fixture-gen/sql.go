@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// table is a table of the SA database that rows are written to, along with
+// the rows not yet written.
+type table struct {
+	name    string
+	columns []string
+	rows    []string
+}
+
+// sqlWriter writes rows as multi-row INSERT statements, so that millions of
+// them can be loaded in a reasonable time.
+type sqlWriter struct {
+	w         *bufio.Writer
+	batchSize int
+	tables    []*table
+}
+
+func newSQLWriter(w *bufio.Writer, batchSize int) *sqlWriter {
+	// Rows are inserted in batches per table, so a certificate may be written
+	// before its account is. The checks are turned back on at the end.
+	fmt.Fprintln(w, "SET FOREIGN_KEY_CHECKS = 0;")
+	fmt.Fprintln(w, "SET UNIQUE_CHECKS = 0;")
+	fmt.Fprintln(w, "SET AUTOCOMMIT = 0;")
+	return &sqlWriter{w: w, batchSize: batchSize}
+}
+
+// table returns a table with the given columns to insert rows into.
+func (s *sqlWriter) table(name string, columns ...string) *table {
+	t := &table{name: name, columns: columns}
+	s.tables = append(s.tables, t)
+	return t
+}
+
+// insert adds a row to t, writing the rows of t once there are batchSize of
+// them. The values must be in the order of the columns of t.
+func (s *sqlWriter) insert(t *table, values ...interface{}) {
+	if len(values) != len(t.columns) {
+		panic(fmt.Sprintf("%d values for the %d columns of %s", len(values), len(t.columns), t.name))
+	}
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = sqlValue(v)
+	}
+	t.rows = append(t.rows, "("+strings.Join(quoted, ", ")+")")
+	if len(t.rows) >= s.batchSize {
+		s.flush(t)
+	}
+}
+
+func (s *sqlWriter) flush(t *table) {
+	if len(t.rows) == 0 {
+		return
+	}
+	fmt.Fprintf(s.w, "INSERT INTO `%s` (`%s`) VALUES\n%s;\n",
+		t.name, strings.Join(t.columns, "`, `"), strings.Join(t.rows, ",\n"))
+	t.rows = t.rows[:0]
+}
+
+// close writes the remaining rows of every table, commits them, and turns
+// the checks turned off by newSQLWriter back on.
+func (s *sqlWriter) close() error {
+	for _, t := range s.tables {
+		s.flush(t)
+	}
+	fmt.Fprintln(s.w, "COMMIT;")
+	fmt.Fprintln(s.w, "SET UNIQUE_CHECKS = 1;")
+	fmt.Fprintln(s.w, "SET FOREIGN_KEY_CHECKS = 1;")
+	return s.w.Flush()
+}
+
+// sqlValue returns v as a MariaDB literal. Byte slices are written as hex
+// literals so that binary columns and JSON blobs need no escaping.
+func sqlValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+	case []byte:
+		if v == nil {
+			return "NULL"
+		}
+		return "X'" + hex.EncodeToString(v) + "'"
+	case time.Time:
+		return "'" + v.UTC().Format("2006-01-02 15:04:05") + "'"
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case int, int64:
+		return fmt.Sprintf("%d", v)
+	default:
+		panic(fmt.Sprintf("unsupported SQL value %T", v))
+	}
+}
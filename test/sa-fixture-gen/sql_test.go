@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestSQLValue(t *testing.T) {
+	testCases := []struct {
+		value    interface{}
+		expected string
+	}{
+		{nil, "NULL"},
+		{"plain", "'plain'"},
+		{`it's a \ path`, `'it\'s a \\ path'`},
+		{[]byte(nil), "NULL"},
+		{[]byte{}, "X''"},
+		{[]byte{0x01, 0xab}, "X'01ab'"},
+		{time.Date(2019, 3, 1, 12, 30, 5, 0, time.FixedZone("CET", 3600)), "'2019-03-01 11:30:05'"},
+		{true, "1"},
+		{false, "0"},
+		{42, "42"},
+		{int64(-7), "-7"},
+	}
+	for _, tc := range testCases {
+		test.AssertEquals(t, sqlValue(tc.value), tc.expected)
+	}
+}
+
+func TestSQLWriter(t *testing.T) {
+	var buf bytes.Buffer
+	out := newSQLWriter(bufio.NewWriter(&buf), 2)
+	accounts := out.table("registrations", "id", "contact")
+	certs := out.table("certificates", "serial")
+	out.insert(accounts, 1, "[]")
+	out.insert(certs, "00")
+	// The second row fills the batch, so the rows are written straight away,
+	// ahead of the certificate inserted before them
+	out.insert(accounts, 2, `["mailto:admin@example.com"]`)
+	out.insert(accounts, 3, "[]")
+	err := out.close()
+	test.AssertNotError(t, err, "close failed")
+
+	expected := "SET FOREIGN_KEY_CHECKS = 0;\n" +
+		"SET UNIQUE_CHECKS = 0;\n" +
+		"SET AUTOCOMMIT = 0;\n" +
+		"INSERT INTO `registrations` (`id`, `contact`) VALUES\n" +
+		"(1, '[]'),\n" +
+		"(2, '[\"mailto:admin@example.com\"]');\n" +
+		"INSERT INTO `registrations` (`id`, `contact`) VALUES\n" +
+		"(3, '[]');\n" +
+		"INSERT INTO `certificates` (`serial`) VALUES\n" +
+		"('00');\n" +
+		"COMMIT;\n" +
+		"SET UNIQUE_CHECKS = 1;\n" +
+		"SET FOREIGN_KEY_CHECKS = 1;\n"
+	test.AssertEquals(t, buf.String(), expected)
+}
+
+func TestSQLWriterWrongColumns(t *testing.T) {
+	out := newSQLWriter(bufio.NewWriter(&bytes.Buffer{}), 10)
+	accounts := out.table("registrations", "id", "contact")
+	defer func() {
+		test.Assert(t, recover() != nil, "insert of too few values didn't panic")
+	}()
+	out.insert(accounts, 1)
+}
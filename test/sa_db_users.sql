@@ -26,22 +26,30 @@ CREATE USER IF NOT EXISTS 'cert_checker'@'localhost';
 CREATE USER IF NOT EXISTS 'ocsp_update'@'localhost';
 CREATE USER IF NOT EXISTS 'test_setup'@'localhost';
 CREATE USER IF NOT EXISTS 'purger'@'localhost';
+CREATE USER IF NOT EXISTS 'stats'@'localhost';
 
 -- Storage Authority
-GRANT SELECT,INSERT,UPDATE ON authz TO 'sa'@'localhost';
+GRANT SELECT,INSERT,UPDATE,DELETE ON authz TO 'sa'@'localhost';
 GRANT SELECT,INSERT,UPDATE,DELETE ON pendingAuthorizations TO 'sa'@'localhost';
 GRANT SELECT(id,Lockcol) ON pendingAuthorizations TO 'sa'@'localhost';
 GRANT SELECT,INSERT ON certificates TO 'sa'@'localhost';
 GRANT SELECT,INSERT,UPDATE ON certificateStatus TO 'sa'@'localhost';
-GRANT SELECT,INSERT ON issuedNames TO 'sa'@'localhost';
+GRANT SELECT,INSERT,DELETE ON issuedNames TO 'sa'@'localhost';
 GRANT SELECT,INSERT ON sctReceipts TO 'sa'@'localhost';
 GRANT SELECT,INSERT,UPDATE ON registrations TO 'sa'@'localhost';
 GRANT SELECT,INSERT,UPDATE ON challenges TO 'sa'@'localhost';
-GRANT SELECT,INSERT on fqdnSets TO 'sa'@'localhost';
+GRANT SELECT,INSERT,DELETE on fqdnSets TO 'sa'@'localhost';
 GRANT SELECT,INSERT,UPDATE ON orders TO 'sa'@'localhost';
 GRANT SELECT,INSERT ON orderToAuthz TO 'sa'@'localhost';
 GRANT SELECT,INSERT ON requestedNames TO 'sa'@'localhost';
 GRANT SELECT,INSERT,DELETE ON orderFqdnSets TO 'sa'@'localhost';
+GRANT SELECT,INSERT,UPDATE ON rateLimitOverrides TO 'sa'@'localhost';
+GRANT SELECT,INSERT,UPDATE ON challengeWhitelist TO 'sa'@'localhost';
+GRANT SELECT,INSERT,UPDATE,DELETE ON dns01Rechecks TO 'sa'@'localhost';
+GRANT SELECT,INSERT ON keyHashToSerial TO 'sa'@'localhost';
+GRANT SELECT,INSERT,UPDATE ON replacementOrders TO 'sa'@'localhost';
+GRANT SELECT,INSERT ON orderExemptions TO 'sa'@'localhost';
+GRANT SELECT ON goose_db_version TO 'sa'@'localhost';
 
 -- OCSP Responder
 GRANT SELECT ON certificateStatus TO 'ocsp_resp'@'localhost';
@@ -51,15 +59,17 @@ GRANT SELECT ON certificates TO 'ocsp_update'@'localhost';
 GRANT SELECT,UPDATE ON certificateStatus TO 'ocsp_update'@'localhost';
 GRANT SELECT ON sctReceipts TO 'ocsp_update'@'localhost';
 
--- Revoker Tool
+-- Revoker Tool and Account Admin Tool
 GRANT SELECT ON registrations TO 'revoker'@'localhost';
 GRANT SELECT ON certificates TO 'revoker'@'localhost';
+GRANT SELECT ON orders TO 'revoker'@'localhost';
 
 -- Expiration mailer
 GRANT SELECT ON certificates TO 'mailer'@'localhost';
 GRANT SELECT ON registrations TO 'mailer'@'localhost';
 GRANT SELECT,UPDATE ON certificateStatus TO 'mailer'@'localhost';
 GRANT SELECT ON fqdnSets TO 'mailer'@'localhost';
+GRANT SELECT,INSERT,UPDATE,DELETE ON expirationNagClaims TO 'mailer'@'localhost';
 
 -- Cert checker
 GRANT SELECT ON certificates TO 'cert_checker'@'localhost';
@@ -69,5 +79,21 @@ GRANT SELECT,DELETE ON pendingAuthorizations TO 'purger'@'localhost';
 GRANT SELECT,DELETE ON authz TO 'purger'@'localhost';
 GRANT SELECT,DELETE ON challenges TO 'purger'@'localhost';
 
+-- Issuance statistics
+GRANT SELECT ON certificates TO 'stats'@'localhost';
+GRANT SELECT ON orders TO 'stats'@'localhost';
+GRANT SELECT ON orderToAuthz TO 'stats'@'localhost';
+GRANT SELECT ON authz TO 'stats'@'localhost';
+GRANT SELECT ON challenges TO 'stats'@'localhost';
+GRANT SELECT ON orderToAuthz2 TO 'stats'@'localhost';
+GRANT SELECT ON authz2 TO 'stats'@'localhost';
+GRANT SELECT,INSERT,DELETE ON issuanceStats TO 'stats'@'localhost';
+
+-- Key type inventory
+GRANT SELECT ON registrations TO 'stats'@'localhost';
+
+-- Hostname policy coverage analysis
+GRANT SELECT ON issuedNames TO 'stats'@'localhost';
+
 -- Test setup and teardown
 GRANT ALL PRIVILEGES ON * to 'test_setup'@'localhost';
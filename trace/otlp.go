@@ -0,0 +1,242 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+)
+
+const (
+	otlpTracesPath       = "/v1/traces"
+	defaultBatchSize     = 512
+	defaultFlushInterval = 5 * time.Second
+	// otlpStatusError is the OTLP StatusCode of a failed span.
+	otlpStatusError = 2
+)
+
+// OTLPExporter buffers finished spans and sends them in batches to an
+// OpenTelemetry collector using the JSON encoding of OTLP/HTTP. Spans are
+// dropped, rather than blocking the traced request, if the buffer is full.
+type OTLPExporter struct {
+	url       string
+	service   string
+	client    *http.Client
+	log       blog.Logger
+	batchSize int
+	interval  time.Duration
+
+	spans    chan *SpanData
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+
+	exported *prometheus.CounterVec
+}
+
+// NewOTLPExporter returns an OTLPExporter sending spans to the OTLP/HTTP
+// receiver at endpoint (e.g. "http://otel-collector:4318"), reporting them as
+// coming from service. The exporter's background goroutine is started
+// immediately.
+func NewOTLPExporter(endpoint, service string, logger blog.Logger, scope metrics.Scope) *OTLPExporter {
+	exported := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "trace_spans",
+		Help: "Number of sampled trace spans, by result (exported, dropped, failed)",
+	}, []string{"result"})
+	scope.MustRegister(exported)
+
+	e := &OTLPExporter{
+		url:       strings.TrimSuffix(endpoint, "/") + otlpTracesPath,
+		service:   service,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		log:       logger,
+		batchSize: defaultBatchSize,
+		interval:  defaultFlushInterval,
+		spans:     make(chan *SpanData, 4*defaultBatchSize),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		exported:  exported,
+	}
+	go e.run()
+	return e
+}
+
+// Export queues a span to be sent with the next batch.
+func (e *OTLPExporter) Export(span *SpanData) {
+	select {
+	case e.spans <- span:
+	default:
+		e.exported.With(prometheus.Labels{"result": "dropped"}).Inc()
+	}
+}
+
+// Shutdown sends any queued spans and stops the exporter. Spans exported
+// afterwards are never sent.
+func (e *OTLPExporter) Shutdown() {
+	e.stopOnce.Do(func() { close(e.stop) })
+	<-e.done
+}
+
+func (e *OTLPExporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	var batch []*SpanData
+	for {
+		select {
+		case span := <-e.spans:
+			batch = append(batch, span)
+			if len(batch) >= e.batchSize {
+				e.send(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			e.send(batch)
+			batch = nil
+		case <-e.stop:
+			for {
+				select {
+				case span := <-e.spans:
+					batch = append(batch, span)
+				default:
+					e.send(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (e *OTLPExporter) send(batch []*SpanData) {
+	if len(batch) == 0 {
+		return
+	}
+	body, err := json.Marshal(e.request(batch))
+	if err == nil {
+		err = e.post(body)
+	}
+	result := "exported"
+	if err != nil {
+		e.log.Warningf("Failed to export %d trace spans to %s: %s", len(batch), e.url, err)
+		result = "failed"
+	}
+	e.exported.With(prometheus.Labels{"result": result}).Add(float64(len(batch)))
+}
+
+func (e *OTLPExporter) post(body []byte) error {
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("collector returned %d: %s", resp.StatusCode, msg)
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+// The following types are the subset of the OTLP ExportTraceServiceRequest
+// JSON encoding that the exporter produces. Trace and span IDs are hex
+// encoded and timestamps are decimal strings, as required by OTLP/JSON.
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+func stringAttributes(attrs map[string]string) []otlpKeyValue {
+	var kvs []otlpKeyValue
+	for k, v := range attrs {
+		kvs = append(kvs, otlpKeyValue{Key: k, Value: otlpAnyValue{StringValue: v}})
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	return kvs
+}
+
+func (e *OTLPExporter) request(batch []*SpanData) otlpRequest {
+	spans := make([]otlpSpan, len(batch))
+	for i, s := range batch {
+		spans[i] = otlpSpan{
+			TraceID:           hex.EncodeToString(s.TraceID[:]),
+			SpanID:            hex.EncodeToString(s.SpanID[:]),
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        stringAttributes(s.Attributes),
+		}
+		if s.Parent != (SpanID{}) {
+			spans[i].ParentSpanID = hex.EncodeToString(s.Parent[:])
+		}
+		if s.Err != nil {
+			spans[i].Status = otlpStatus{Code: otlpStatusError, Message: s.Err.Error()}
+		}
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: stringAttributes(map[string]string{
+			"service.name": e.service,
+		})},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "github.com/letsencrypt/boulder/trace"},
+			Spans: spans,
+		}},
+	}}}
+}
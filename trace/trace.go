@@ -0,0 +1,265 @@
+// Package trace implements the small subset of distributed tracing that
+// Boulder needs: spans with W3C Trace Context identifiers that are propagated
+// between services in gRPC metadata and exported to an OpenTelemetry collector
+// using OTLP/HTTP (see otlp.go).
+//
+// Until a Tracer is installed with SetTracer every span is a no-op, so
+// instrumented code doesn't need to check whether tracing is configured.
+package trace
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	mrand "math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// TraceparentKey is the gRPC metadata key (and HTTP header) carrying the W3C
+// traceparent of the calling span.
+const TraceparentKey = "traceparent"
+
+// TraceID identifies a trace, i.e. every span caused by a single request.
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// SpanContext is the part of a span that is propagated to other services.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid returns true if the SpanContext has non-zero trace and span IDs.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent formats the SpanContext as a version 00 W3C traceparent.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", hex.EncodeToString(sc.TraceID[:]), hex.EncodeToString(sc.SpanID[:]), flags)
+}
+
+// ParseTraceparent parses a W3C traceparent. Only version 00 is accepted.
+func ParseTraceparent(tp string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(tp, "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, fmt.Errorf("malformed traceparent %q", tp)
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, fmt.Errorf("malformed trace ID in traceparent %q: %s", tp, err)
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, fmt.Errorf("malformed span ID in traceparent %q: %s", tp, err)
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, fmt.Errorf("malformed flags in traceparent %q: %s", tp, err)
+	}
+	if !sc.IsValid() {
+		return sc, fmt.Errorf("traceparent %q has zero trace or span ID", tp)
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
+
+// SpanKind describes the relationship of a span to the remote side of the
+// operation it covers. The values match the OTLP SpanKind enum.
+type SpanKind int
+
+const (
+	KindInternal = SpanKind(1)
+	KindServer   = SpanKind(2)
+	KindClient   = SpanKind(3)
+)
+
+// SpanData is a finished span, as handed to an Exporter.
+type SpanData struct {
+	SpanContext
+	Parent     SpanID
+	Name       string
+	Kind       SpanKind
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]string
+	Err        error
+}
+
+// Exporter sends finished spans to a tracing backend.
+type Exporter interface {
+	// Export is called once for each sampled span when it ends. It must not
+	// block.
+	Export(*SpanData)
+	// Shutdown flushes any buffered spans.
+	Shutdown()
+}
+
+// Span is an operation being traced. Spans that aren't sampled only carry
+// their SpanContext, so that the sampling decision can be propagated, and all
+// of their methods are cheap no-ops.
+type Span struct {
+	tracer *Tracer
+	data   *SpanData
+	sc     SpanContext
+}
+
+// Context returns the SpanContext to propagate to the services this span
+// calls.
+func (s *Span) Context() SpanContext {
+	return s.sc
+}
+
+// SetName renames a sampled span, for when a better name than the one it was
+// started with is only known later (e.g. the route that handled a request).
+func (s *Span) SetName(name string) {
+	if s.data == nil {
+		return
+	}
+	s.data.Name = name
+}
+
+// SetAttribute annotates a sampled span with a key/value pair.
+func (s *Span) SetAttribute(key, value string) {
+	if s.data == nil {
+		return
+	}
+	s.data.Attributes[key] = value
+}
+
+// SetError marks a sampled span as failed if err is non-nil.
+func (s *Span) SetError(err error) {
+	if s.data == nil || err == nil {
+		return
+	}
+	s.data.Err = err
+}
+
+// End finishes the span, handing it to the exporter if it was sampled.
+func (s *Span) End() {
+	if s.data == nil {
+		return
+	}
+	s.data.End = s.tracer.now()
+	s.tracer.exporter.Export(s.data)
+	s.data = nil
+}
+
+// Tracer creates spans, sampling root spans with a fixed probability. Child
+// spans, including the server side of an RPC, follow the sampling decision of
+// their parent.
+type Tracer struct {
+	exporter    Exporter
+	sampleRatio float64
+	now         func() time.Time
+
+	mu   sync.Mutex
+	rand *mrand.Rand
+}
+
+// NewTracer returns a Tracer that samples the provided ratio (between 0 and 1)
+// of root spans and exports them to exporter.
+func NewTracer(exporter Exporter, sampleRatio float64) *Tracer {
+	var seed int64
+	_ = binary.Read(crand.Reader, binary.LittleEndian, &seed)
+	return &Tracer{
+		exporter:    exporter,
+		sampleRatio: sampleRatio,
+		now:         time.Now,
+		rand:        mrand.New(mrand.NewSource(seed)),
+	}
+}
+
+func (t *Tracer) newIDs(sc *SpanContext, newTrace bool) (sampled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if newTrace {
+		_, _ = t.rand.Read(sc.TraceID[:])
+		sampled = t.rand.Float64() < t.sampleRatio
+	}
+	_, _ = t.rand.Read(sc.SpanID[:])
+	return sampled
+}
+
+// Start creates a span that is a child of the span in ctx, if any, and returns
+// a context containing the new span.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	parent, hasParent := FromContext(ctx)
+	sc := SpanContext{TraceID: parent.TraceID, Sampled: parent.Sampled}
+	sampled := t.newIDs(&sc, !hasParent)
+	if !hasParent {
+		sc.Sampled = sampled
+	}
+	span := &Span{tracer: t, sc: sc}
+	if sc.Sampled {
+		span.data = &SpanData{
+			SpanContext: sc,
+			Parent:      parent.SpanID,
+			Name:        name,
+			Kind:        kind,
+			Start:       t.now(),
+			Attributes:  make(map[string]string),
+		}
+	}
+	return NewContext(ctx, sc), span
+}
+
+var (
+	globalMu     sync.RWMutex
+	globalTracer *Tracer
+)
+
+// SetTracer installs the Tracer used by Start. Passing nil disables tracing.
+func SetTracer(t *Tracer) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	globalTracer = t
+}
+
+// Start creates a span using the Tracer installed with SetTracer. If there is
+// none the span is a no-op and ctx is returned unchanged.
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	globalMu.RLock()
+	t := globalTracer
+	globalMu.RUnlock()
+	if t == nil {
+		return ctx, &Span{}
+	}
+	return t.Start(ctx, name, kind)
+}
+
+// Shutdown flushes the spans buffered by the exporter of the Tracer installed
+// with SetTracer, if any.
+func Shutdown() {
+	globalMu.RLock()
+	t := globalTracer
+	globalMu.RUnlock()
+	if t != nil {
+		t.exporter.Shutdown()
+	}
+}
+
+type spanContextKey struct{}
+
+// NewContext returns a copy of ctx carrying sc as the current span.
+func NewContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// FromContext returns the SpanContext of the current span in ctx, if there is
+// one.
+func FromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
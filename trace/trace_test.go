@@ -0,0 +1,144 @@
+package trace
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+type recordingExporter struct {
+	spans []*SpanData
+}
+
+func (re *recordingExporter) Export(s *SpanData) {
+	re.spans = append(re.spans, s)
+}
+
+func (re *recordingExporter) Shutdown() {}
+
+func TestTraceparent(t *testing.T) {
+	tp := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceparent(tp)
+	test.AssertNotError(t, err, "ParseTraceparent failed")
+	test.Assert(t, sc.Sampled, "sampled flag not parsed")
+	test.AssertEquals(t, sc.Traceparent(), tp)
+
+	sc, err = ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	test.AssertNotError(t, err, "ParseTraceparent failed")
+	test.Assert(t, !sc.Sampled, "unsampled traceparent parsed as sampled")
+
+	for _, bad := range []string{
+		"",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+		"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+	} {
+		_, err = ParseTraceparent(bad)
+		test.AssertError(t, err, "ParseTraceparent accepted "+bad)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(exporter, 1)
+
+	ctx, root := tracer.Start(context.Background(), "root", KindServer)
+	_, child := tracer.Start(ctx, "child", KindClient)
+	child.SetAttribute("key", "value")
+	child.SetError(errors.New("broken"))
+	child.End()
+	root.End()
+	// Ending a span twice doesn't export it twice
+	root.End()
+
+	test.AssertEquals(t, len(exporter.spans), 2)
+	c, r := exporter.spans[0], exporter.spans[1]
+	test.AssertEquals(t, r.Parent, SpanID{})
+	test.AssertEquals(t, c.TraceID, r.TraceID)
+	test.AssertEquals(t, c.Parent, r.SpanID)
+	test.AssertEquals(t, c.Attributes["key"], "value")
+	test.AssertEquals(t, c.Err.Error(), "broken")
+
+	// A remote parent's decision not to sample is respected, but the trace is
+	// still propagated
+	unsampled, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	test.AssertNotError(t, err, "ParseTraceparent failed")
+	_, span := tracer.Start(NewContext(context.Background(), unsampled), "unsampled", KindServer)
+	span.End()
+	test.AssertEquals(t, len(exporter.spans), 2)
+	test.AssertEquals(t, span.Context().TraceID, unsampled.TraceID)
+	test.Assert(t, span.Context().SpanID != unsampled.SpanID, "child reused parent's span ID")
+
+	// A tracer that samples nothing still produces valid span contexts
+	_, span = NewTracer(exporter, 0).Start(context.Background(), "root", KindServer)
+	span.End()
+	test.AssertEquals(t, len(exporter.spans), 2)
+	test.Assert(t, span.Context().IsValid(), "unsampled root span has invalid context")
+}
+
+func TestGlobalTracerDisabled(t *testing.T) {
+	SetTracer(nil)
+	ctx := context.Background()
+	newCtx, span := Start(ctx, "noop", KindInternal)
+	test.AssertEquals(t, newCtx, ctx)
+	test.Assert(t, !span.Context().IsValid(), "no-op span has a valid context")
+	span.SetAttribute("key", "value")
+	span.End()
+	Shutdown()
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var received []otlpRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		test.AssertEquals(t, r.URL.Path, "/v1/traces")
+		body, err := ioutil.ReadAll(r.Body)
+		test.AssertNotError(t, err, "reading request body")
+		var req otlpRequest
+		test.AssertNotError(t, json.Unmarshal(body, &req), "unmarshaling request body")
+		received = append(received, req)
+	}))
+	defer srv.Close()
+
+	exporter := NewOTLPExporter(srv.URL+"/", "boulder-test", blog.NewMock(), metrics.NewNoopScope())
+	start := time.Unix(1552000000, 0)
+	exporter.Export(&SpanData{
+		SpanContext: SpanContext{TraceID: TraceID{1}, SpanID: SpanID{2}, Sampled: true},
+		Parent:      SpanID{3},
+		Name:        "/ra.RegistrationAuthority/FinalizeOrder",
+		Kind:        KindServer,
+		Start:       start,
+		End:         start.Add(time.Second),
+		Attributes:  map[string]string{"b": "2", "a": "1"},
+		Err:         errors.New("broken"),
+	})
+	exporter.Shutdown()
+	// Spans exported after shutdown are never sent, but don't block
+	exporter.Export(&SpanData{})
+	exporter.Shutdown()
+
+	test.AssertEquals(t, len(received), 1)
+	rs := received[0].ResourceSpans[0]
+	test.AssertEquals(t, rs.Resource.Attributes[0].Key, "service.name")
+	test.AssertEquals(t, rs.Resource.Attributes[0].Value.StringValue, "boulder-test")
+	span := rs.ScopeSpans[0].Spans[0]
+	test.AssertEquals(t, span.TraceID, "01000000000000000000000000000000")
+	test.AssertEquals(t, span.SpanID, "0200000000000000")
+	test.AssertEquals(t, span.ParentSpanID, "0300000000000000")
+	test.AssertEquals(t, span.Kind, KindServer)
+	test.AssertEquals(t, span.StartTimeUnixNano, "1552000000000000000")
+	test.AssertEquals(t, span.EndTimeUnixNano, "1552000001000000000")
+	test.AssertEquals(t, span.Attributes[0].Key, "a")
+	test.AssertEquals(t, span.Status.Code, otlpStatusError)
+	test.AssertEquals(t, span.Status.Message, "broken")
+}
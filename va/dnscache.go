@@ -0,0 +1,254 @@
+package va
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/bdns"
+	"github.com/letsencrypt/boulder/metrics"
+)
+
+// defaultDNSCacheEntries is how many lookups a DNS cache holds if its config
+// doesn't say.
+const defaultDNSCacheEntries = 10000
+
+// DNSCacheConfig configures briefly reusing the results of DNS lookups. The
+// validations of an order with many names under one base domain repeat many
+// of the same lookups: the CAA lookups of the parent domains, the TXT lookups
+// of names delegated to the same _acme-challenge CNAME target, and the
+// address lookups of the same redirect targets. Only lookups are reused,
+// never challenge results, and a reused TXT lookup that doesn't hold the
+// expected record is always looked up again.
+type DNSCacheConfig struct {
+	// TTL is how long a lookup's result is reused. It should only be a few
+	// seconds: long enough to cover the validations of one order, and short
+	// enough that changes made between validations are seen.
+	TTL time.Duration
+	// MaxEntries is the most lookups held at once. Defaults to 10000.
+	MaxEntries int
+}
+
+type dnsCacheKey struct {
+	qtype string
+	name  string
+}
+
+// dnsCacheEntry holds the result of one lookup. Lookups of the same name
+// made while it's in progress wait for it rather than making their own.
+type dnsCacheEntry struct {
+	// done is closed when the lookup has finished and the fields below are
+	// set.
+	done    chan struct{}
+	expires time.Time
+	result  interface{}
+	err     error
+}
+
+type txtResult struct {
+	txts, authorities []string
+}
+
+var errDNSCacheFull = errors.New("DNS cache is full")
+
+// cachingDNSClient is a bdns.DNSClient that reuses the results of successful
+// lookups made through it for a short time.
+type cachingDNSClient struct {
+	bdns.DNSClient
+	clk        clock.Clock
+	ttl        time.Duration
+	maxEntries int
+	lookups    *prometheus.CounterVec
+
+	mu      sync.Mutex
+	entries map[dnsCacheKey]*dnsCacheEntry
+}
+
+func newCachingDNSClient(client bdns.DNSClient, config DNSCacheConfig, clk clock.Clock, stats metrics.Scope) (*cachingDNSClient, error) {
+	if config.TTL <= 0 {
+		return nil, errors.New("DNS cache TTL must be positive")
+	}
+	maxEntries := config.MaxEntries
+	if maxEntries == 0 {
+		maxEntries = defaultDNSCacheEntries
+	}
+	lookups := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "dns_cache_lookups",
+		Help: "Number of DNS lookups made through the VA's DNS cache, by query type and whether the result was reused",
+	}, []string{"qtype", "result"})
+	stats.MustRegister(lookups)
+	return &cachingDNSClient{
+		DNSClient:  client,
+		clk:        clk,
+		ttl:        config.TTL,
+		maxEntries: maxEntries,
+		lookups:    lookups,
+		entries:    make(map[dnsCacheKey]*dnsCacheEntry),
+	}, nil
+}
+
+// start returns the entry for key that a lookup should use, and whether it
+// was already there. If it wasn't, the caller must make the lookup and
+// finish the entry. It returns errDNSCacheFull if there's no room for a new
+// entry.
+func (c *cachingDNSClient) start(key dnsCacheKey, refresh bool) (*dnsCacheEntry, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clk.Now()
+	if e, ok := c.entries[key]; ok && !refresh {
+		select {
+		case <-e.done:
+			if e.err == nil && now.Before(e.expires) {
+				return e, true, nil
+			}
+		default:
+			return e, true, nil
+		}
+	}
+	if len(c.entries) >= c.maxEntries {
+		for k, e := range c.entries {
+			select {
+			case <-e.done:
+				if e.err != nil || !now.Before(e.expires) {
+					delete(c.entries, k)
+				}
+			default:
+			}
+		}
+		if len(c.entries) >= c.maxEntries {
+			return nil, false, errDNSCacheFull
+		}
+	}
+	e := &dnsCacheEntry{done: make(chan struct{})}
+	c.entries[key] = e
+	return e, false, nil
+}
+
+// lookup returns the result of lookup for the query type and name, reusing an
+// earlier or in-progress lookup unless refresh is true. It returns true if the
+// result was reused.
+func (c *cachingDNSClient) lookup(
+	ctx context.Context,
+	qtype string,
+	name string,
+	refresh bool,
+	lookup func() (interface{}, error),
+) (interface{}, bool, error) {
+	key := dnsCacheKey{qtype: qtype, name: strings.ToLower(strings.TrimSuffix(name, "."))}
+	e, reused, err := c.start(key, refresh)
+	if err == errDNSCacheFull {
+		c.lookups.WithLabelValues(qtype, "full").Inc()
+		result, err := lookup()
+		return result, false, err
+	}
+	if reused {
+		select {
+		case <-e.done:
+			// A failed lookup isn't reused, in case it failed because of
+			// the context of the request that made it.
+			if e.err == nil {
+				c.lookups.WithLabelValues(qtype, "hit").Inc()
+				return e.result, true, nil
+			}
+		case <-ctx.Done():
+		}
+		c.lookups.WithLabelValues(qtype, "miss").Inc()
+		result, err := lookup()
+		return result, false, err
+	}
+	c.lookups.WithLabelValues(qtype, "miss").Inc()
+	e.result, e.err = lookup()
+	e.expires = c.clk.Now().Add(c.ttl)
+	close(e.done)
+	return e.result, false, e.err
+}
+
+// lookupTXT looks up the TXT records at hostname, returning true if the
+// result of an earlier lookup was reused. If refresh is true, no earlier
+// lookup is reused and the result replaces any cached one.
+func (c *cachingDNSClient) lookupTXT(ctx context.Context, hostname string, refresh bool) ([]string, []string, bool, error) {
+	result, reused, err := c.lookup(ctx, "TXT", hostname, refresh, func() (interface{}, error) {
+		txts, authorities, err := c.DNSClient.LookupTXT(ctx, hostname)
+		return txtResult{txts, authorities}, err
+	})
+	txt, _ := result.(txtResult)
+	return txt.txts, txt.authorities, reused, err
+}
+
+// LookupTXT implements bdns.DNSClient.
+func (c *cachingDNSClient) LookupTXT(ctx context.Context, hostname string) ([]string, []string, error) {
+	txts, authorities, _, err := c.lookupTXT(ctx, hostname, false)
+	return txts, authorities, err
+}
+
+// LookupHost implements bdns.DNSClient.
+func (c *cachingDNSClient) LookupHost(ctx context.Context, hostname string) ([]net.IP, error) {
+	result, _, err := c.lookup(ctx, "A", hostname, false, func() (interface{}, error) {
+		return c.DNSClient.LookupHost(ctx, hostname)
+	})
+	ips, _ := result.([]net.IP)
+	return ips, err
+}
+
+// LookupCAA implements bdns.DNSClient.
+func (c *cachingDNSClient) LookupCAA(ctx context.Context, hostname string) ([]*dns.CAA, error) {
+	result, _, err := c.lookup(ctx, "CAA", hostname, false, func() (interface{}, error) {
+		return c.DNSClient.LookupCAA(ctx, hostname)
+	})
+	caas, _ := result.([]*dns.CAA)
+	return caas, err
+}
+
+// LookupMX implements bdns.DNSClient.
+func (c *cachingDNSClient) LookupMX(ctx context.Context, hostname string) ([]string, error) {
+	result, _, err := c.lookup(ctx, "MX", hostname, false, func() (interface{}, error) {
+		return c.DNSClient.LookupMX(ctx, hostname)
+	})
+	mxs, _ := result.([]string)
+	return mxs, err
+}
+
+func hasTXT(txts []string, want string) bool {
+	for _, txt := range txts {
+		if subtle.ConstantTimeCompare([]byte(txt), []byte(want)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupChallengeTXT looks up the TXT records at hostname for a DNS-01
+// challenge. If a reused lookup doesn't hold the expected digest, the client
+// may have provisioned it since, so it's looked up again.
+func (va *ValidationAuthorityImpl) lookupChallengeTXT(ctx context.Context, hostname, digest string) ([]string, []string, error) {
+	if va.dnsCache == nil {
+		return va.dnsClient.LookupTXT(ctx, hostname)
+	}
+	txts, authorities, reused, err := va.dnsCache.lookupTXT(ctx, hostname, false)
+	if err != nil || !reused || hasTXT(txts, digest) {
+		return txts, authorities, err
+	}
+	txts, authorities, _, err = va.dnsCache.lookupTXT(ctx, hostname, true)
+	return txts, authorities, err
+}
+
+// SetDNSCache makes the VA briefly reuse the results of its DNS lookups as
+// configured. If DNS capture is enabled too, a reused lookup is only
+// captured with the validation that made it.
+func (va *ValidationAuthorityImpl) SetDNSCache(config DNSCacheConfig) error {
+	c, err := newCachingDNSClient(va.dnsClient, config, va.clk, va.stats)
+	if err != nil {
+		return err
+	}
+	va.dnsClient = c
+	va.dnsCache = c
+	return nil
+}
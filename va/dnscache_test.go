@@ -0,0 +1,160 @@
+package va
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/miekg/dns"
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/bdns"
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+// countingDNSClient counts the lookups of each name, and answers TXT lookups
+// with txts, which tests can change.
+type countingDNSClient struct {
+	bdns.DNSClient
+	mu      sync.Mutex
+	lookups map[string]int
+	txts    []string
+	fail    bool
+}
+
+func (c *countingDNSClient) count(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lookups[name]++
+	if c.fail {
+		return errors.New("lookup failed")
+	}
+	return nil
+}
+
+func (c *countingDNSClient) LookupTXT(_ context.Context, hostname string) ([]string, []string, error) {
+	err := c.count("TXT " + hostname)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.txts, nil, err
+}
+
+func (c *countingDNSClient) LookupHost(_ context.Context, hostname string) ([]net.IP, error) {
+	return []net.IP{net.ParseIP("127.0.0.1")}, c.count("A " + hostname)
+}
+
+func (c *countingDNSClient) LookupCAA(_ context.Context, hostname string) ([]*dns.CAA, error) {
+	return nil, c.count("CAA " + hostname)
+}
+
+func newTestDNSCache(t *testing.T, maxEntries int) (*cachingDNSClient, *countingDNSClient, clock.FakeClock) {
+	fc := clock.NewFake()
+	counting := &countingDNSClient{lookups: make(map[string]int)}
+	c, err := newCachingDNSClient(counting, DNSCacheConfig{TTL: 5 * time.Second, MaxEntries: maxEntries}, fc, metrics.NewNoopScope())
+	test.AssertNotError(t, err, "newCachingDNSClient failed")
+	return c, counting, fc
+}
+
+func TestDNSCacheReuse(t *testing.T) {
+	c, counting, fc := newTestDNSCache(t, 0)
+
+	for i := 0; i < 3; i++ {
+		_, err := c.LookupCAA(ctx, "example.com")
+		test.AssertNotError(t, err, "LookupCAA failed")
+		_, err = c.LookupCAA(ctx, "Example.com.")
+		test.AssertNotError(t, err, "LookupCAA failed")
+		ips, err := c.LookupHost(ctx, "example.com")
+		test.AssertNotError(t, err, "LookupHost failed")
+		test.AssertEquals(t, len(ips), 1)
+	}
+	test.AssertEquals(t, counting.lookups["CAA example.com"], 1)
+	test.AssertEquals(t, counting.lookups["A example.com"], 1)
+	test.AssertEquals(t, test.CountCounter(c.lookups.WithLabelValues("CAA", "hit")), 5)
+
+	// Results aren't reused after the TTL
+	fc.Add(5 * time.Second)
+	_, err := c.LookupCAA(ctx, "example.com")
+	test.AssertNotError(t, err, "LookupCAA failed")
+	test.AssertEquals(t, counting.lookups["CAA example.com"], 2)
+
+	// Failed lookups aren't reused
+	counting.fail = true
+	_, err = c.LookupCAA(ctx, "example.net")
+	test.AssertError(t, err, "failed lookup succeeded")
+	counting.fail = false
+	_, err = c.LookupCAA(ctx, "example.net")
+	test.AssertNotError(t, err, "LookupCAA failed")
+	test.AssertEquals(t, counting.lookups["CAA example.net"], 2)
+}
+
+func TestDNSCacheConcurrentLookups(t *testing.T) {
+	c, counting, _ := newTestDNSCache(t, 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.LookupCAA(ctx, "example.com")
+			test.AssertNotError(t, err, "LookupCAA failed")
+		}()
+	}
+	wg.Wait()
+	test.AssertEquals(t, counting.lookups["CAA example.com"], 1)
+}
+
+func TestDNSCacheFull(t *testing.T) {
+	c, counting, fc := newTestDNSCache(t, 2)
+	for _, name := range []string{"a.com", "b.com", "c.com", "c.com"} {
+		_, err := c.LookupCAA(ctx, name)
+		test.AssertNotError(t, err, "LookupCAA failed")
+	}
+	// c.com couldn't be cached
+	test.AssertEquals(t, counting.lookups["CAA c.com"], 2)
+	test.AssertEquals(t, test.CountCounter(c.lookups.WithLabelValues("CAA", "full")), 2)
+
+	// Expired entries make room
+	fc.Add(5 * time.Second)
+	for i := 0; i < 2; i++ {
+		_, err := c.LookupCAA(ctx, "c.com")
+		test.AssertNotError(t, err, "LookupCAA failed")
+	}
+	test.AssertEquals(t, counting.lookups["CAA c.com"], 3)
+	test.AssertEquals(t, len(c.entries), 1)
+}
+
+func TestNewCachingDNSClient(t *testing.T) {
+	_, err := newCachingDNSClient(&bdns.MockDNSClient{}, DNSCacheConfig{}, clock.NewFake(), metrics.NewNoopScope())
+	test.AssertError(t, err, "DNS cache without a TTL was created")
+}
+
+func TestDNSValidationCachedTXT(t *testing.T) {
+	va, _ := setup(nil, 0)
+	counting := &countingDNSClient{lookups: make(map[string]int)}
+	va.dnsClient = counting
+	err := va.SetDNSCache(DNSCacheConfig{TTL: time.Minute})
+	test.AssertNotError(t, err, "SetDNSCache failed")
+
+	chall := core.DNSChallenge01("")
+	chall.Token = expectedToken
+	chall.ProvidedKeyAuthorization = expectedKeyAuthorization
+	const name = "_acme-challenge.good-dns01.com"
+	const digest = "LPsIwTo7o8BoG0-vjCyGQGBWSVIPxI-i_X336eUOQZo"
+
+	// The lookup made before the record was provisioned isn't trusted
+	_, prob := va.validateChallenge(ctx, dnsi("good-dns01.com"), chall)
+	test.Assert(t, prob != nil, "validation without the record succeeded")
+	counting.txts = []string{digest}
+	_, prob = va.validateChallenge(ctx, dnsi("good-dns01.com"), chall)
+	test.Assert(t, prob == nil, "validation with a freshly provisioned record failed")
+	test.AssertEquals(t, counting.lookups["TXT "+name], 2)
+
+	// A lookup that holds the record is reused
+	_, prob = va.validateChallenge(ctx, dnsi("good-dns01.com"), chall)
+	test.Assert(t, prob == nil, "validation with a reused lookup failed")
+	test.AssertEquals(t, counting.lookups["TXT "+name], 2)
+}
@@ -0,0 +1,216 @@
+package va
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/letsencrypt/boulder/bdns"
+	"github.com/letsencrypt/boulder/core"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+)
+
+// dnsCaptureSuffix is the suffix of DNS capture files. Each is a series of
+// gzip members, one per validation, each holding one dnsCaptureRecord as a
+// line of JSON. Tools like zcat read them as a single stream.
+const dnsCaptureSuffix = ".jsonl.gz"
+
+// DNSCaptureConfig configures recording the raw DNS queries and responses of
+// each validation, including its CAA checks, so that misissuance
+// investigations can see exactly what the resolvers said.
+type DNSCaptureConfig struct {
+	// Directory is where captures are written, in a file per authorization
+	// named after its ID. It's created if it doesn't exist.
+	Directory string
+	// MaxAge is how long captures are kept after they're last written.
+	MaxAge time.Duration
+	// MaxBytes, if non-zero, limits the total size of the captures. Beyond
+	// it, the least recently written captures are removed first.
+	MaxBytes int64
+}
+
+// dnsCaptureRecord is the DNS capture of one validation.
+type dnsCaptureRecord struct {
+	AuthorizationID string
+	RegistrationID  int64
+	Hostname        string
+	ChallengeType   string
+	Time            time.Time
+	Exchanges       []bdns.CapturedExchange
+}
+
+// authzIDPattern matches the IDs of both kinds of authorizations, and nothing
+// that could escape the capture directory.
+var authzIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// dnsCaptureStore implements DNSCaptureConfig. A nil *dnsCaptureStore stores
+// nothing.
+type dnsCaptureStore struct {
+	dir      string
+	maxAge   time.Duration
+	maxBytes int64
+	clk      clock.Clock
+	log      blog.Logger
+
+	// mu serializes writes and pruning
+	mu sync.Mutex
+
+	stored *prometheus.CounterVec
+	pruned prometheus.Counter
+}
+
+func newDNSCaptureStore(config DNSCaptureConfig, clk clock.Clock, logger blog.Logger, stats metrics.Scope) (*dnsCaptureStore, error) {
+	if config.Directory == "" {
+		return nil, fmt.Errorf("DNS capture directory must be set")
+	}
+	if config.MaxAge <= 0 {
+		return nil, fmt.Errorf("DNS capture max age must be positive")
+	}
+	if config.MaxBytes < 0 {
+		return nil, fmt.Errorf("DNS capture max bytes can't be negative")
+	}
+	err := os.MkdirAll(config.Directory, 0750)
+	if err != nil {
+		return nil, err
+	}
+	stored := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "dns_captures",
+		Help: "Number of validations whose DNS exchanges were captured, by result (stored or failed)",
+	}, []string{"result"})
+	stats.MustRegister(stored)
+	pruned := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "dns_captures_pruned",
+		Help: "Number of DNS capture files removed by the retention limits",
+	})
+	stats.MustRegister(pruned)
+	return &dnsCaptureStore{
+		dir:      config.Directory,
+		maxAge:   config.MaxAge,
+		maxBytes: config.MaxBytes,
+		clk:      clk,
+		log:      logger,
+		stored:   stored,
+		pruned:   pruned,
+	}, nil
+}
+
+// store appends the capture of a validation of challType for authz to the
+// authorization's capture file. Failures are logged, not returned: a
+// validation isn't failed because its capture couldn't be stored.
+func (s *dnsCaptureStore) store(authz core.Authorization, hostname, challType string, capture *bdns.Capture) {
+	if s == nil {
+		return
+	}
+	err := s.write(dnsCaptureRecord{
+		AuthorizationID: authz.ID,
+		RegistrationID:  authz.RegistrationID,
+		Hostname:        hostname,
+		ChallengeType:   challType,
+		Time:            s.clk.Now(),
+		Exchanges:       capture.Exchanges(),
+	})
+	if err != nil {
+		s.stored.With(prometheus.Labels{"result": "failed"}).Inc()
+		s.log.Errf("Storing DNS capture for authorization %q: %s", authz.ID, err)
+		return
+	}
+	s.stored.With(prometheus.Labels{"result": "stored"}).Inc()
+}
+
+func (s *dnsCaptureStore) write(record dnsCaptureRecord) error {
+	if !authzIDPattern.MatchString(record.AuthorizationID) {
+		return fmt.Errorf("invalid authorization ID")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(
+		filepath.Join(s.dir, record.AuthorizationID+dnsCaptureSuffix),
+		os.O_WRONLY|os.O_APPEND|os.O_CREATE,
+		0640)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(f)
+	err = json.NewEncoder(gz).Encode(record)
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// prune removes the capture files older than the maximum age, then the least
+// recently written ones until they're within the maximum size.
+func (s *dnsCaptureStore) prune() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	infos, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	var files []os.FileInfo
+	for _, info := range infos {
+		if info.Mode().IsRegular() && strings.HasSuffix(info.Name(), dnsCaptureSuffix) {
+			files = append(files, info)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+	var total int64
+	for _, info := range files {
+		total += info.Size()
+	}
+	cutoff := s.clk.Now().Add(-s.maxAge)
+	for _, info := range files {
+		if !info.ModTime().Before(cutoff) && (s.maxBytes == 0 || total <= s.maxBytes) {
+			break
+		}
+		err := os.Remove(filepath.Join(s.dir, info.Name()))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		total -= info.Size()
+		s.pruned.Inc()
+	}
+	return nil
+}
+
+// SetDNSCapture makes the VA record the DNS exchanges of each validation as
+// configured.
+func (va *ValidationAuthorityImpl) SetDNSCapture(config DNSCaptureConfig) error {
+	store, err := newDNSCaptureStore(config, va.clk, va.log, va.stats)
+	if err != nil {
+		return err
+	}
+	va.dnsCapture = store
+	return nil
+}
+
+// PruneDNSCapturesLoop removes DNS captures beyond the retention limits every
+// interval, forever. It does nothing if DNS capture isn't enabled.
+func (va *ValidationAuthorityImpl) PruneDNSCapturesLoop(interval time.Duration) {
+	if va.dnsCapture == nil {
+		return
+	}
+	for {
+		if err := va.dnsCapture.prune(); err != nil {
+			va.log.Errf("Pruning DNS captures: %s", err)
+		}
+		va.clk.Sleep(interval)
+	}
+}
@@ -0,0 +1,174 @@
+package va
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/bdns"
+	"github.com/letsencrypt/boulder/core"
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestNewDNSCaptureStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dnscapture")
+	test.AssertNotError(t, err, "creating temp dir")
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name   string
+		config DNSCaptureConfig
+		err    string
+	}{
+		{
+			name:   "valid",
+			config: DNSCaptureConfig{Directory: dir, MaxAge: time.Hour, MaxBytes: 1000},
+		},
+		{
+			name:   "new directory",
+			config: DNSCaptureConfig{Directory: filepath.Join(dir, "new"), MaxAge: time.Hour},
+		},
+		{
+			name:   "no directory",
+			config: DNSCaptureConfig{MaxAge: time.Hour},
+			err:    "DNS capture directory must be set",
+		},
+		{
+			name:   "no max age",
+			config: DNSCaptureConfig{Directory: dir},
+			err:    "DNS capture max age must be positive",
+		},
+		{
+			name:   "negative max bytes",
+			config: DNSCaptureConfig{Directory: dir, MaxAge: time.Hour, MaxBytes: -1},
+			err:    "DNS capture max bytes can't be negative",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newDNSCaptureStore(tc.config, clock.NewFake(), blog.NewMock(), metrics.NewNoopScope())
+			if tc.err == "" {
+				test.AssertNotError(t, err, "newDNSCaptureStore failed")
+			} else {
+				test.AssertError(t, err, "newDNSCaptureStore didn't fail")
+				test.AssertEquals(t, err.Error(), tc.err)
+			}
+		})
+	}
+}
+
+func TestDNSCaptureStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dnscapture")
+	test.AssertNotError(t, err, "creating temp dir")
+	defer os.RemoveAll(dir)
+	fc := clock.NewFake()
+	fc.Set(time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC))
+	log := blog.NewMock()
+	s, err := newDNSCaptureStore(DNSCaptureConfig{Directory: dir, MaxAge: time.Hour}, fc, log, metrics.NewNoopScope())
+	test.AssertNotError(t, err, "newDNSCaptureStore failed")
+
+	authz := core.Authorization{ID: "abc_DEF-123", RegistrationID: 1}
+	capture := &bdns.Capture{}
+	s.store(authz, "example.com", core.ChallengeTypeDNS01, capture)
+	s.store(authz, "example.com", core.ChallengeTypeHTTP01, capture)
+
+	// Each validation is a separate gzip member, read back as one stream
+	f, err := os.Open(filepath.Join(dir, authz.ID+dnsCaptureSuffix))
+	test.AssertNotError(t, err, "opening capture file")
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	test.AssertNotError(t, err, "reading capture file")
+	dec := json.NewDecoder(gz)
+	var records []dnsCaptureRecord
+	for {
+		var record dnsCaptureRecord
+		err := dec.Decode(&record)
+		if err == io.EOF {
+			break
+		}
+		test.AssertNotError(t, err, "decoding capture record")
+		records = append(records, record)
+	}
+	test.AssertEquals(t, len(records), 2)
+	test.AssertEquals(t, records[0].ChallengeType, core.ChallengeTypeDNS01)
+	test.AssertEquals(t, records[1].ChallengeType, core.ChallengeTypeHTTP01)
+	test.AssertEquals(t, records[1].AuthorizationID, authz.ID)
+	test.AssertEquals(t, records[1].RegistrationID, int64(1))
+	test.AssertEquals(t, records[1].Hostname, "example.com")
+
+	// IDs that could escape the directory aren't written
+	s.store(core.Authorization{ID: "../evil"}, "example.com", core.ChallengeTypeDNS01, capture)
+	test.AssertEquals(t, len(log.GetAllMatching("Storing DNS capture")), 1)
+	_, err = os.Stat(filepath.Join(filepath.Dir(dir), "evil"+dnsCaptureSuffix))
+	test.Assert(t, os.IsNotExist(err), "capture was written outside the directory")
+
+	var nilStore *dnsCaptureStore
+	nilStore.store(authz, "example.com", core.ChallengeTypeDNS01, capture)
+}
+
+func TestDNSCapturePrune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dnscapture")
+	test.AssertNotError(t, err, "creating temp dir")
+	defer os.RemoveAll(dir)
+	fc := clock.NewFake()
+	fc.Set(time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	// Three 100 byte captures, written 3, 2 and 1 hours ago, and a file that
+	// isn't a capture
+	files := []string{"a", "b", "c"}
+	for i, name := range files {
+		path := filepath.Join(dir, name+dnsCaptureSuffix)
+		test.AssertNotError(t, ioutil.WriteFile(path, make([]byte, 100), 0640), "writing capture")
+		mtime := fc.Now().Add(-time.Duration(len(files)-i) * time.Hour)
+		test.AssertNotError(t, os.Chtimes(path, mtime, mtime), "setting capture mtime")
+	}
+	other := filepath.Join(dir, "other")
+	test.AssertNotError(t, ioutil.WriteFile(other, make([]byte, 1000), 0640), "writing other file")
+	old := fc.Now().Add(-24 * time.Hour)
+	test.AssertNotError(t, os.Chtimes(other, old, old), "setting other file mtime")
+
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+
+	s, err := newDNSCaptureStore(DNSCaptureConfig{Directory: dir, MaxAge: 150 * time.Minute, MaxBytes: 150}, fc, blog.NewMock(), metrics.NewNoopScope())
+	test.AssertNotError(t, err, "newDNSCaptureStore failed")
+	test.AssertNotError(t, s.prune(), "prune failed")
+	// a is too old, and b is removed to bring the total within 150 bytes
+	test.Assert(t, !exists("a"+dnsCaptureSuffix), "expired capture wasn't removed")
+	test.Assert(t, !exists("b"+dnsCaptureSuffix), "oldest capture over the size limit wasn't removed")
+	test.Assert(t, exists("c"+dnsCaptureSuffix), "capture within the limits was removed")
+	test.Assert(t, exists("other"), "file that isn't a capture was removed")
+
+	fc.Add(time.Hour * 2)
+	test.AssertNotError(t, s.prune(), "prune failed")
+	test.Assert(t, !exists("c"+dnsCaptureSuffix), "expired capture wasn't removed")
+}
+
+func TestPerformValidationDNSCapture(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dnscapture")
+	test.AssertNotError(t, err, "creating temp dir")
+	defer os.RemoveAll(dir)
+	va, _ := setup(nil, 0)
+	err = va.SetDNSCapture(DNSCaptureConfig{Directory: dir, MaxAge: time.Hour})
+	test.AssertNotError(t, err, "SetDNSCapture failed")
+
+	chalDNS := core.DNSChallenge01("")
+	chalDNS.Token = expectedToken
+	chalDNS.ProvidedKeyAuthorization = expectedKeyAuthorization
+	_, prob := va.PerformValidation(context.Background(), "good-dns01.com", chalDNS, core.Authorization{ID: "capture-me"})
+	test.Assert(t, prob == nil, "validation failed")
+	_, err = os.Stat(filepath.Join(dir, "capture-me"+dnsCaptureSuffix))
+	test.AssertNotError(t, err, "validation wasn't captured")
+}
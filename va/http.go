@@ -411,19 +411,7 @@ func (va *ValidationAuthorityImpl) processHTTPValidation(
 	}
 	// Immediately reconstruct the request using the validation context
 	initialReq = initialReq.WithContext(ctx)
-	if va.userAgent != "" {
-		initialReq.Header.Set("User-Agent", va.userAgent)
-	}
-	// Some of our users use mod_security. Mod_security sees a lack of Accept
-	// headers as bot behavior and rejects requests. While this is a bug in
-	// mod_security's rules (given that the HTTP specs disagree with that
-	// requirement), we add the Accept header now in order to fix our
-	// mod_security users' mysterious breakages. See
-	// <https://github.com/SpiderLabs/owasp-modsecurity-crs/issues/265> and
-	// <https://github.com/letsencrypt/boulder/issues/1019>. This was done
-	// because it's a one-line fix with no downside. We're not likely to want to
-	// do many more things to satisfy misunderstandings around HTTP.
-	initialReq.Header.Set("Accept", "*/*")
+	va.setHTTP01Headers(initialReq)
 
 	// Set up the initial validation request and a base validation record
 	dialer, baseRecord, err := va.setupHTTPValidation(ctx, initialReq.URL.String(), target)
@@ -552,5 +540,8 @@ func (va *ValidationAuthorityImpl) processHTTPValidation(
 		return nil, records, berrors.UnauthorizedError("Invalid response from %s [%s]: %d",
 			records[len(records)-1].URL, records[len(records)-1].AddressUsed, httpResponse.StatusCode)
 	}
+	if err := va.http01.checkResponse(records[len(records)-1].URL, httpResponse); err != nil {
+		return nil, records, err
+	}
 	return body, records, nil
 }
@@ -0,0 +1,134 @@
+package va
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	berrors "github.com/letsencrypt/boulder/errors"
+)
+
+// HTTP-01 strictness levels, which decide what a response may hold other
+// than the key authorization.
+const (
+	// HTTP01Lenient ignores whitespace before and after the key
+	// authorization, and the response's content type.
+	HTTP01Lenient = "lenient"
+	// HTTP01Default ignores whitespace after the key authorization, as RFC
+	// 8555 section 8.3 recommends, and the response's content type.
+	HTTP01Default = "default"
+	// HTTP01Strict only ignores a single newline after the key authorization,
+	// as written by `echo`, and requires the response to have one of the
+	// allowed content types.
+	HTTP01Strict = "strict"
+)
+
+// defaultHTTP01ContentTypes are the content types a response may have with
+// the strict level if the config doesn't list any.
+var defaultHTTP01ContentTypes = []string{"text/plain", "application/octet-stream"}
+
+// HTTP01Config configures the requests the VA makes for HTTP-01 validation,
+// so that operators can match the validation fingerprint they publish, and how
+// strictly it checks the responses, to help debug CDNs and proxies that alter
+// them. The User-Agent of the requests is the VA's UserAgent.
+type HTTP01Config struct {
+	// Accept is the Accept header of the requests. Defaults to "*/*", as
+	// mod_security rejects requests without one.
+	Accept string
+	// Strictness is "lenient", "default" or "strict". Defaults to "default".
+	Strictness string
+	// ContentTypes are the media types a response may have with the strict
+	// level. Defaults to "text/plain" and "application/octet-stream".
+	ContentTypes []string
+}
+
+// http01Policy is the parsed HTTP01Config the VA uses.
+type http01Policy struct {
+	accept       string
+	strictness   string
+	contentTypes map[string]bool
+}
+
+var defaultHTTP01Policy = http01Policy{accept: "*/*", strictness: HTTP01Default}
+
+// SetHTTP01Config sets the Accept header of the VA's HTTP-01 requests and how
+// strictly it checks the responses. It must be called before the VA is used.
+func (va *ValidationAuthorityImpl) SetHTTP01Config(config HTTP01Config) error {
+	p := defaultHTTP01Policy
+	if config.Accept != "" {
+		p.accept = config.Accept
+	}
+	switch config.Strictness {
+	case "":
+	case HTTP01Lenient, HTTP01Default, HTTP01Strict:
+		p.strictness = config.Strictness
+	default:
+		return fmt.Errorf("unknown HTTP-01 strictness %q", config.Strictness)
+	}
+	contentTypes := config.ContentTypes
+	if len(contentTypes) == 0 {
+		contentTypes = defaultHTTP01ContentTypes
+	} else if p.strictness != HTTP01Strict {
+		return fmt.Errorf("HTTP-01 content types are only checked with the %q strictness", HTTP01Strict)
+	}
+	p.contentTypes = make(map[string]bool, len(contentTypes))
+	for _, ct := range contentTypes {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return fmt.Errorf("invalid HTTP-01 content type %q: %s", ct, err)
+		}
+		p.contentTypes[mediaType] = true
+	}
+	va.http01 = p
+	return nil
+}
+
+// setHTTP01Headers sets the User-Agent and Accept headers of an HTTP-01
+// request.
+func (va *ValidationAuthorityImpl) setHTTP01Headers(req *http.Request) {
+	if va.userAgent != "" {
+		req.Header.Set("User-Agent", va.userAgent)
+	}
+	// Some of our users use mod_security. Mod_security sees a lack of Accept
+	// headers as bot behavior and rejects requests. While this is a bug in
+	// mod_security's rules (given that the HTTP specs disagree with that
+	// requirement), we add the Accept header now in order to fix our
+	// mod_security users' mysterious breakages. See
+	// <https://github.com/SpiderLabs/owasp-modsecurity-crs/issues/265> and
+	// <https://github.com/letsencrypt/boulder/issues/1019>.
+	req.Header.Set("Accept", va.http01.accept)
+}
+
+// checkResponse returns an Unauthorized error if the final response to an
+// HTTP-01 request from url doesn't have an allowed content type.
+func (p http01Policy) checkResponse(url string, resp *http.Response) error {
+	if p.strictness != HTTP01Strict {
+		return nil
+	}
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !p.contentTypes[mediaType] {
+		return berrors.UnauthorizedError("Invalid response from %s: unexpected Content-Type %q", url, contentType)
+	}
+	return nil
+}
+
+// keyAuthorization returns the key authorization in the body of an HTTP-01
+// response, ignoring the whitespace around it that the strictness allows.
+func (p http01Policy) keyAuthorization(body []byte) string {
+	switch p.strictness {
+	case HTTP01Lenient:
+		return strings.Trim(string(body), whitespaceCutset)
+	case HTTP01Strict:
+		payload := string(body)
+		for _, newline := range []string{"\r\n", "\n"} {
+			if strings.HasSuffix(payload, newline) {
+				return strings.TrimSuffix(payload, newline)
+			}
+		}
+		return payload
+	default:
+		return strings.TrimRight(string(body), whitespaceCutset)
+	}
+}
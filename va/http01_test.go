@@ -0,0 +1,94 @@
+package va
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/features"
+	"github.com/letsencrypt/boulder/probs"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestSetHTTP01Config(t *testing.T) {
+	va, _ := setup(nil, 0)
+	for _, config := range []HTTP01Config{
+		{Strictness: "pedantic"},
+		{ContentTypes: []string{"text/plain"}},
+		{Strictness: HTTP01Strict, ContentTypes: []string{"text/plain; /"}},
+	} {
+		test.AssertError(t, va.SetHTTP01Config(config), fmt.Sprintf("invalid config %+v was accepted", config))
+	}
+	test.AssertEquals(t, va.http01.strictness, HTTP01Default)
+
+	err := va.SetHTTP01Config(HTTP01Config{})
+	test.AssertNotError(t, err, "SetHTTP01Config failed")
+	test.AssertEquals(t, va.http01.accept, "*/*")
+	test.AssertEquals(t, va.http01.strictness, HTTP01Default)
+}
+
+func TestHTTP01KeyAuthorization(t *testing.T) {
+	testCases := []struct {
+		strictness string
+		body       string
+		expected   string
+	}{
+		{HTTP01Lenient, " \tkeyauth\r\n ", "keyauth"},
+		{HTTP01Default, " \tkeyauth\r\n ", " \tkeyauth"},
+		{HTTP01Default, "keyauth\n\n", "keyauth"},
+		{HTTP01Strict, "keyauth", "keyauth"},
+		{HTTP01Strict, "keyauth\n", "keyauth"},
+		{HTTP01Strict, "keyauth\r\n", "keyauth"},
+		{HTTP01Strict, "keyauth\n\n", "keyauth\n"},
+		{HTTP01Strict, "keyauth ", "keyauth "},
+	}
+	for _, tc := range testCases {
+		p := http01Policy{strictness: tc.strictness}
+		test.AssertEquals(t, p.keyAuthorization([]byte(tc.body)), tc.expected)
+	}
+}
+
+func TestValidateHTTP01Config(t *testing.T) {
+	chall := core.HTTPChallenge01("")
+	setChallengeToken(&chall, core.NewToken())
+	keyAuthz, err := chall.ExpectedKeyAuthorization(accountKey)
+	test.AssertNotError(t, err, "computing key authorization")
+
+	var accept, userAgent, contentType string
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept, userAgent = r.Header.Get("Accept"), r.Header.Get("User-Agent")
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		fmt.Fprint(w, keyAuthz, "\n")
+	}))
+	defer hs.Close()
+
+	for _, simplified := range []bool{false, true} {
+		err := features.Set(map[string]bool{"SimplifiedVAHTTP": simplified})
+		test.AssertNotError(t, err, "setting SimplifiedVAHTTP feature")
+
+		va, _ := setup(hs, 0)
+		err = va.SetHTTP01Config(HTTP01Config{
+			Accept:       "text/plain",
+			Strictness:   HTTP01Strict,
+			ContentTypes: []string{"text/plain"},
+		})
+		test.AssertNotError(t, err, "SetHTTP01Config failed")
+
+		contentType = "text/plain; charset=utf-8"
+		_, prob := va.validateChallenge(ctx, dnsi("localhost"), chall)
+		test.Assert(t, prob == nil, fmt.Sprintf("validation failed: %s", prob))
+		test.AssertEquals(t, accept, "text/plain")
+		test.AssertEquals(t, userAgent, "user agent 1.0")
+
+		contentType = "text/html"
+		_, prob = va.validateChallenge(ctx, dnsi("localhost"), chall)
+		test.Assert(t, prob != nil, "validation with a disallowed content type succeeded")
+		test.AssertEquals(t, prob.Type, probs.UnauthorizedProblem)
+		test.AssertContains(t, prob.Detail, `unexpected Content-Type "text/html"`)
+	}
+	features.Reset()
+}
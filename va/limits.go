@@ -0,0 +1,210 @@
+package va
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/probs"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+// ConcurrencyConfig limits how many validations the VA performs at once, so
+// that a flood of validations of one challenge type can't starve the others
+// or exhaust the VA's file descriptors.
+type ConcurrencyConfig struct {
+	// MaxValidations, if non-zero, is how many validations of any challenge
+	// type may run at once.
+	MaxValidations int
+	// MaxPerChallenge is how many validations of each challenge type may run
+	// at once. Challenge types that aren't listed are only limited by
+	// MaxValidations.
+	MaxPerChallenge map[string]int
+	// MaxQueued is how many validations may wait for a free slot. Validations
+	// that arrive when the queue is full fail immediately.
+	MaxQueued int
+	// QueueTimeout, if non-zero, is how long a validation may wait in the
+	// queue before it fails. Otherwise it waits until its deadline.
+	QueueTimeout time.Duration
+}
+
+// validationLimiter implements ConcurrencyConfig with a semaphore for all
+// validations and one for each limited challenge type. A nil
+// *validationLimiter doesn't limit anything.
+type validationLimiter struct {
+	clk          clock.Clock
+	global       chan struct{}
+	perChallenge map[string]chan struct{}
+	maxQueued    int
+	queueTimeout time.Duration
+
+	mu     sync.Mutex
+	queued int
+
+	inFlight    *prometheus.GaugeVec
+	queueLength prometheus.Gauge
+	queueTime   *prometheus.HistogramVec
+	shed        *prometheus.CounterVec
+}
+
+func newValidationLimiter(clk clock.Clock, stats metrics.Scope, config ConcurrencyConfig) (*validationLimiter, error) {
+	if config.MaxValidations < 0 || config.MaxQueued < 0 || config.QueueTimeout < 0 {
+		return nil, fmt.Errorf("validation concurrency limits can't be negative")
+	}
+	l := &validationLimiter{
+		clk:          clk,
+		perChallenge: make(map[string]chan struct{}, len(config.MaxPerChallenge)),
+		maxQueued:    config.MaxQueued,
+		queueTimeout: config.QueueTimeout,
+	}
+	if config.MaxValidations > 0 {
+		l.global = make(chan struct{}, config.MaxValidations)
+	}
+	for challType, max := range config.MaxPerChallenge {
+		if !core.ValidChallenge(challType) {
+			return nil, fmt.Errorf("validation concurrency limit for unknown challenge type %q", challType)
+		}
+		if max <= 0 {
+			return nil, fmt.Errorf("validation concurrency limit for %s must be positive", challType)
+		}
+		if config.MaxValidations > 0 && max > config.MaxValidations {
+			return nil, fmt.Errorf("validation concurrency limit for %s is more than maxValidations", challType)
+		}
+		l.perChallenge[challType] = make(chan struct{}, max)
+	}
+
+	l.inFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "validations_in_flight",
+			Help: "Number of validations currently being performed, by challenge type",
+		},
+		[]string{"type"})
+	stats.MustRegister(l.inFlight)
+	l.queueLength = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "validation_queue_length",
+			Help: "Number of validations waiting for a free concurrency slot",
+		})
+	stats.MustRegister(l.queueLength)
+	l.queueTime = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "validation_queue_time",
+			Help: "Time validations waited for a free concurrency slot, by challenge type",
+		},
+		[]string{"type"})
+	stats.MustRegister(l.queueTime)
+	l.shed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "validations_shed",
+			Help: "Number of validations refused because the VA was at its concurrency limits, by challenge type and reason",
+		},
+		[]string{"type", "reason"})
+	stats.MustRegister(l.shed)
+	return l, nil
+}
+
+// acquire waits for a free slot to validate a challenge of challType. It
+// returns a function that frees the slot once the validation is finished, or
+// a problem if the validation was shed because the queue was full or the
+// validation waited too long.
+func (l *validationLimiter) acquire(ctx context.Context, challType string) (func(), *probs.ProblemDetails) {
+	if l == nil {
+		return func() {}, nil
+	}
+	// The challenge type's semaphore is always acquired before the global one,
+	// so that validations of a limited type waiting for their own slot don't
+	// hold global slots other types could use.
+	var sems []chan struct{}
+	if sem, ok := l.perChallenge[challType]; ok {
+		sems = append(sems, sem)
+	}
+	if l.global != nil {
+		sems = append(sems, l.global)
+	}
+	var held []chan struct{}
+	release := func() {
+		for _, sem := range held {
+			<-sem
+		}
+	}
+
+	var queueStart time.Time
+	for _, sem := range sems {
+		select {
+		case sem <- struct{}{}:
+			held = append(held, sem)
+			continue
+		default:
+		}
+		if queueStart.IsZero() {
+			if !l.enqueue() {
+				release()
+				return nil, l.refuse(challType, "queue_full")
+			}
+			defer l.dequeue()
+			queueStart = l.clk.Now()
+			if l.queueTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, l.queueTimeout)
+				defer cancel()
+			}
+		}
+		select {
+		case sem <- struct{}{}:
+			held = append(held, sem)
+		case <-ctx.Done():
+			release()
+			return nil, l.refuse(challType, "timeout")
+		}
+	}
+	if !queueStart.IsZero() {
+		l.queueTime.With(prometheus.Labels{"type": challType}).Observe(l.clk.Since(queueStart).Seconds())
+	}
+
+	inFlight := l.inFlight.With(prometheus.Labels{"type": challType})
+	inFlight.Inc()
+	return func() {
+		inFlight.Dec()
+		release()
+	}, nil
+}
+
+// enqueue counts a validation as waiting, returning false if the queue is
+// full.
+func (l *validationLimiter) enqueue() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.queued >= l.maxQueued {
+		return false
+	}
+	l.queued++
+	l.queueLength.Set(float64(l.queued))
+	return true
+}
+
+func (l *validationLimiter) dequeue() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.queued--
+	l.queueLength.Set(float64(l.queued))
+}
+
+func (l *validationLimiter) refuse(challType, reason string) *probs.ProblemDetails {
+	l.shed.With(prometheus.Labels{"type": challType, "reason": reason}).Inc()
+	return probs.ServerInternal("The validation authority is overloaded, please try again later")
+}
+
+// SetConcurrencyLimits makes the VA limit how many validations it performs
+// at once, as configured.
+func (va *ValidationAuthorityImpl) SetConcurrencyLimits(config ConcurrencyConfig) error {
+	l, err := newValidationLimiter(va.clk, va.stats, config)
+	if err != nil {
+		return err
+	}
+	va.limiter = l
+	return nil
+}
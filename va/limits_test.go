@@ -0,0 +1,152 @@
+package va
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/letsencrypt/boulder/core"
+	"github.com/letsencrypt/boulder/metrics"
+	"github.com/letsencrypt/boulder/probs"
+	"github.com/letsencrypt/boulder/test"
+	"golang.org/x/net/context"
+)
+
+func TestNewValidationLimiter(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config ConcurrencyConfig
+		err    string
+	}{
+		{
+			name:   "global limit",
+			config: ConcurrencyConfig{MaxValidations: 10},
+		},
+		{
+			name: "per challenge limits",
+			config: ConcurrencyConfig{
+				MaxValidations:  10,
+				MaxPerChallenge: map[string]int{core.ChallengeTypeDNS01: 5},
+			},
+		},
+		{
+			name:   "negative",
+			config: ConcurrencyConfig{MaxValidations: -1},
+			err:    "validation concurrency limits can't be negative",
+		},
+		{
+			name:   "unknown challenge",
+			config: ConcurrencyConfig{MaxPerChallenge: map[string]int{"foo-01": 5}},
+			err:    `validation concurrency limit for unknown challenge type "foo-01"`,
+		},
+		{
+			name:   "zero challenge limit",
+			config: ConcurrencyConfig{MaxPerChallenge: map[string]int{core.ChallengeTypeDNS01: 0}},
+			err:    "validation concurrency limit for dns-01 must be positive",
+		},
+		{
+			name: "challenge limit above global",
+			config: ConcurrencyConfig{
+				MaxValidations:  5,
+				MaxPerChallenge: map[string]int{core.ChallengeTypeDNS01: 10},
+			},
+			err: "validation concurrency limit for dns-01 is more than maxValidations",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newValidationLimiter(clock.NewFake(), metrics.NewNoopScope(), tc.config)
+			if tc.err == "" {
+				test.AssertNotError(t, err, "newValidationLimiter failed")
+			} else {
+				test.AssertError(t, err, "newValidationLimiter accepted an invalid config")
+				test.AssertEquals(t, err.Error(), tc.err)
+			}
+		})
+	}
+}
+
+func TestValidationLimiterPerChallenge(t *testing.T) {
+	l, err := newValidationLimiter(clock.NewFake(), metrics.NewNoopScope(), ConcurrencyConfig{
+		MaxValidations:  3,
+		MaxPerChallenge: map[string]int{core.ChallengeTypeDNS01: 2},
+	})
+	test.AssertNotError(t, err, "newValidationLimiter failed")
+	ctx := context.Background()
+
+	var releases []func()
+	for i := 0; i < 2; i++ {
+		release, prob := l.acquire(ctx, core.ChallengeTypeDNS01)
+		test.Assert(t, prob == nil, "DNS-01 validation under its limit was shed")
+		releases = append(releases, release)
+	}
+	// DNS-01 is at its limit and there's no queue, so another is shed...
+	_, prob := l.acquire(ctx, core.ChallengeTypeDNS01)
+	test.Assert(t, prob != nil, "DNS-01 validation over its limit wasn't shed")
+	test.AssertEquals(t, prob.Type, probs.ServerInternalProblem)
+	// ...but an HTTP-01 validation can still use the last global slot
+	release, prob := l.acquire(ctx, core.ChallengeTypeHTTP01)
+	test.Assert(t, prob == nil, "HTTP-01 validation was starved by DNS-01 validations")
+	releases = append(releases, release)
+	// Now every global slot is taken
+	_, prob = l.acquire(ctx, core.ChallengeTypeHTTP01)
+	test.Assert(t, prob != nil, "HTTP-01 validation over the global limit wasn't shed")
+
+	for _, release := range releases {
+		release()
+	}
+	release, prob = l.acquire(ctx, core.ChallengeTypeDNS01)
+	test.Assert(t, prob == nil, "DNS-01 validation was shed after slots were released")
+	release()
+}
+
+func TestValidationLimiterQueue(t *testing.T) {
+	l, err := newValidationLimiter(clock.NewFake(), metrics.NewNoopScope(), ConcurrencyConfig{
+		MaxValidations: 1,
+		MaxQueued:      1,
+		QueueTimeout:   10 * time.Millisecond,
+	})
+	test.AssertNotError(t, err, "newValidationLimiter failed")
+	ctx := context.Background()
+
+	release, prob := l.acquire(ctx, core.ChallengeTypeHTTP01)
+	test.Assert(t, prob == nil, "first validation was shed")
+
+	// A queued validation gets the slot once it's released
+	acquired := make(chan *probs.ProblemDetails)
+	go func() {
+		release, prob := l.acquire(context.Background(), core.ChallengeTypeHTTP01)
+		if prob == nil {
+			release()
+		}
+		acquired <- prob
+	}()
+	for {
+		l.mu.Lock()
+		queued := l.queued
+		l.mu.Unlock()
+		if queued == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// The queue is full, so another validation is shed immediately
+	_, prob = l.acquire(ctx, core.ChallengeTypeHTTP01)
+	test.Assert(t, prob != nil, "validation wasn't shed when the queue was full")
+	release()
+	test.Assert(t, <-acquired == nil, "queued validation was shed")
+
+	// A queued validation that waits longer than the queue timeout is shed
+	release, prob = l.acquire(ctx, core.ChallengeTypeHTTP01)
+	test.Assert(t, prob == nil, "validation was shed with a free slot")
+	_, prob = l.acquire(ctx, core.ChallengeTypeHTTP01)
+	test.Assert(t, prob != nil, "validation wasn't shed after the queue timeout")
+	release()
+}
+
+func TestNilValidationLimiter(t *testing.T) {
+	var l *validationLimiter
+	release, prob := l.acquire(context.Background(), core.ChallengeTypeHTTP01)
+	test.Assert(t, prob == nil, "nil limiter shed a validation")
+	release()
+}
@@ -30,9 +30,11 @@ import (
 	berrors "github.com/letsencrypt/boulder/errors"
 	"github.com/letsencrypt/boulder/features"
 	bgrpc "github.com/letsencrypt/boulder/grpc"
+	"github.com/letsencrypt/boulder/killswitch"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
 	"github.com/letsencrypt/boulder/probs"
+	"github.com/miekg/dns"
 	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/net/context"
 )
@@ -149,6 +151,19 @@ type ValidationAuthorityImpl struct {
 	maxRemoteFailures  int
 	accountURIPrefixes []string
 	singleDialTimeout  time.Duration
+	limiter            *validationLimiter
+	// http01 is the VA's HTTP-01 request headers and response strictness. See
+	// SetHTTP01Config.
+	http01 http01Policy
+	// dnsCapture, if set, stores the DNS exchanges of each validation. See
+	// SetDNSCapture.
+	dnsCapture *dnsCaptureStore
+	// dnsCache, if set, is dnsClient, and briefly reuses the results of DNS
+	// lookups. See SetDNSCache.
+	dnsCache *cachingDNSClient
+	// killSwitch, if set, disables challenge types in an emergency. See
+	// SetChallengeKillSwitch.
+	killSwitch *killswitch.Challenges
 
 	metrics *vaMetrics
 }
@@ -196,6 +211,7 @@ func NewValidationAuthorityImpl(
 		remoteVAs:          remoteVAs,
 		maxRemoteFailures:  maxRemoteFailures,
 		accountURIPrefixes: accountURIPrefixes,
+		http01:             defaultHTTP01Policy,
 		// singleDialTimeout specifies how long an individual `DialContext` operation may take
 		// before timing out. This timeout ignores the base RPC timeout and is strictly
 		// used for the DialContext operations that take place during an
@@ -386,9 +402,7 @@ func (va *ValidationAuthorityImpl) fetchHTTP(ctx context.Context, identifier cor
 	}
 
 	httpRequest = httpRequest.WithContext(ctx)
-	if va.userAgent != "" {
-		httpRequest.Header["User-Agent"] = []string{va.userAgent}
-	}
+	va.setHTTP01Headers(httpRequest)
 
 	// Build a base validation record that we will later populate with relevant IP
 	// addresses etc
@@ -425,17 +439,6 @@ func (va *ValidationAuthorityImpl) fetchHTTP(ctx context.Context, identifier cor
 		TLSHandshakeTimeout: 10 * time.Second,
 	}
 
-	// Some of our users use mod_security. Mod_security sees a lack of Accept
-	// headers as bot behavior and rejects requests. While this is a bug in
-	// mod_security's rules (given that the HTTP specs disagree with that
-	// requirement), we add the Accept header now in order to fix our
-	// mod_security users' mysterious breakages. See
-	// <https://github.com/SpiderLabs/owasp-modsecurity-crs/issues/265> and
-	// <https://github.com/letsencrypt/boulder/issues/1019>. This was done
-	// because it's a one-line fix with no downside. We're not likely to want to
-	// do many more things to satisfy misunderstandings around HTTP.
-	httpRequest.Header.Set("Accept", "*/*")
-
 	numRedirects := 0
 	logRedirect := func(req *http.Request, via []*http.Request) error {
 		if numRedirects >= maxRedirect {
@@ -443,12 +446,7 @@ func (va *ValidationAuthorityImpl) fetchHTTP(ctx context.Context, identifier cor
 		}
 		numRedirects++
 
-		// Set Accept header for mod_security (see the other place the header is
-		// set)
-		req.Header.Set("Accept", "*/*")
-		if va.userAgent != "" {
-			req.Header["User-Agent"] = []string{va.userAgent}
-		}
+		va.setHTTP01Headers(req)
 
 		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
 			return berrors.ConnectionFailureError(
@@ -551,6 +549,9 @@ func (va *ValidationAuthorityImpl) fetchHTTP(ctx context.Context, identifier cor
 		return nil, validationRecords, probs.Unauthorized("Invalid response from %s [%s]: %d",
 			url, validationRecords[len(validationRecords)-1].AddressUsed, httpResponse.StatusCode)
 	}
+	if err := va.http01.checkResponse(url.String(), httpResponse); err != nil {
+		return nil, validationRecords, detailedError(err)
+	}
 
 	return body, validationRecords, nil
 }
@@ -564,6 +565,9 @@ func certNames(cert *x509.Certificate) []string {
 		names = append(names, cert.Subject.CommonName)
 	}
 	names = append(names, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
 	names = core.UniqueLowerNames(names)
 	for i, n := range names {
 		names[i] = replaceInvalidUTF8([]byte(n))
@@ -575,7 +579,19 @@ func (va *ValidationAuthorityImpl) tryGetTLSCerts(ctx context.Context,
 	identifier core.AcmeIdentifier, challenge core.Challenge,
 	tlsConfig *tls.Config) ([]*x509.Certificate, *tls.ConnectionState, []core.ValidationRecord, *probs.ProblemDetails) {
 
-	allAddrs, problem := va.getAddrs(ctx, identifier.Value)
+	var allAddrs []net.IP
+	var problem *probs.ProblemDetails
+	if identifier.Type == core.IdentifierIP {
+		// IP identifiers are validated by connecting to the address itself,
+		// without any DNS lookups.
+		if ip := net.ParseIP(identifier.Value); ip != nil {
+			allAddrs = []net.IP{ip}
+		} else {
+			problem = probs.Malformed("%q is not a valid IP address", identifier.Value)
+		}
+	} else {
+		allAddrs, problem = va.getAddrs(ctx, identifier.Value)
+	}
 	validationRecords := []core.ValidationRecord{
 		{
 			Hostname:          identifier.Value,
@@ -733,7 +749,7 @@ func (va *ValidationAuthorityImpl) validateHTTP01(ctx context.Context, identifie
 		return validationRecords, prob
 	}
 
-	payload := strings.TrimRight(string(body), whitespaceCutset)
+	payload := va.http01.keyAuthorization(body)
 
 	if payload != challenge.ProvidedKeyAuthorization {
 		problem := probs.Unauthorized("The key authorization file from the server did not match this challenge [%v] != [%v]",
@@ -760,14 +776,30 @@ func (va *ValidationAuthorityImpl) validateTLSSNI01(ctx context.Context, identif
 }
 
 func (va *ValidationAuthorityImpl) validateTLSALPN01(ctx context.Context, identifier core.AcmeIdentifier, challenge core.Challenge) ([]core.ValidationRecord, *probs.ProblemDetails) {
-	if identifier.Type != "dns" {
-		va.log.Info(fmt.Sprintf("Identifier type for TLS-ALPN-01 was not DNS: %s", identifier))
-		return nil, probs.Malformed("Identifier type for TLS-ALPN-01 was not DNS")
+	serverName := identifier.Value
+	var ip net.IP
+	switch identifier.Type {
+	case core.IdentifierDNS:
+	case core.IdentifierIP:
+		ip = net.ParseIP(identifier.Value)
+		if ip == nil {
+			return nil, probs.Malformed("%q is not a valid IP address", identifier.Value)
+		}
+		// IP addresses aren't allowed in SNI, so RFC 8738 Section 6 has us send
+		// the address's reverse DNS name instead, e.g. 4.3.2.1.in-addr.arpa.
+		arpa, err := dns.ReverseAddr(ip.String())
+		if err != nil {
+			return nil, probs.Malformed("%q is not a valid IP address", identifier.Value)
+		}
+		serverName = strings.TrimSuffix(arpa, ".")
+	default:
+		va.log.Info(fmt.Sprintf("Identifier type for TLS-ALPN-01 was not DNS or IP: %s", identifier))
+		return nil, probs.Malformed("Identifier type for TLS-ALPN-01 was not DNS or IP")
 	}
 
 	certs, cs, validationRecords, problem := va.tryGetTLSCerts(ctx, identifier, challenge, &tls.Config{
 		NextProtos: []string{ACMETLS1Protocol},
-		ServerName: identifier.Value,
+		ServerName: serverName,
 	})
 	if problem != nil {
 		return validationRecords, problem
@@ -784,8 +816,16 @@ func (va *ValidationAuthorityImpl) validateTLSALPN01(ctx context.Context, identi
 
 	leafCert := certs[0]
 
-	// Verify SNI - certificate returned must be issued only for the domain we are verifying.
-	if len(leafCert.DNSNames) != 1 || !strings.EqualFold(leafCert.DNSNames[0], identifier.Value) {
+	// Verify SNI - certificate returned must be issued only for the identifier
+	// we are verifying. For an IP identifier that's a single iPAddress SAN.
+	var namesMatch bool
+	if ip != nil {
+		namesMatch = len(leafCert.DNSNames) == 0 && len(leafCert.IPAddresses) == 1 &&
+			leafCert.IPAddresses[0].Equal(ip)
+	} else {
+		namesMatch = len(leafCert.DNSNames) == 1 && strings.EqualFold(leafCert.DNSNames[0], identifier.Value)
+	}
+	if !namesMatch {
 		hostPort := net.JoinHostPort(validationRecords[0].AddressUsed.String(), validationRecords[0].Port)
 		names := certNames(leafCert)
 		errText := fmt.Sprintf(
@@ -900,7 +940,7 @@ func (va *ValidationAuthorityImpl) validateDNS01(ctx context.Context, identifier
 
 	// Look for the required record in the DNS
 	challengeSubdomain := fmt.Sprintf("%s.%s", core.DNSPrefix, identifier.Value)
-	txts, authorities, err := va.dnsClient.LookupTXT(ctx, challengeSubdomain)
+	txts, authorities, err := va.lookupChallengeTXT(ctx, challengeSubdomain, authorizedKeysDigest)
 
 	if err != nil {
 		va.log.Infof("Failed to lookup TXT records for %s. err=[%#v] errStr=[%s]", identifier, err, err)
@@ -952,9 +992,7 @@ func (va *ValidationAuthorityImpl) validate(
 	// `baseIdentifier` here before starting the `va.checkCAA` goroutine with the
 	// `identifier` to avoid a data race.
 	baseIdentifier := identifier
-	if strings.HasPrefix(identifier.Value, "*.") {
-		baseIdentifier.Value = strings.TrimPrefix(identifier.Value, "*.")
-	}
+	baseIdentifier.Value, _ = core.WildcardBase(identifier.Value)
 
 	// va.checkCAA accepts wildcard identifiers and handles them appropriately so
 	// we can dispatch `checkCAA` with the provided `identifier` instead of
@@ -994,6 +1032,13 @@ func (va *ValidationAuthorityImpl) validateChallenge(ctx context.Context, identi
 	if err := challenge.CheckConsistencyForValidation(); err != nil {
 		return nil, probs.Malformed("Challenge failed consistency check: %s", err)
 	}
+	// The RA refuses to start validating disabled challenge types, so this
+	// only catches validations under way when the type was disabled. They
+	// fail with a server error rather than as though the challenge wasn't
+	// met.
+	if va.killSwitch.Disabled(challenge.Type) {
+		return nil, probs.ServerInternal("Challenge type %s is temporarily disabled", challenge.Type)
+	}
 	switch challenge.Type {
 	case core.ChallengeTypeHTTP01:
 		return va.validateHTTP01(ctx, identifier, challenge)
@@ -1007,6 +1052,12 @@ func (va *ValidationAuthorityImpl) validateChallenge(ctx context.Context, identi
 	return nil, probs.Malformed("invalid challenge type %s", challenge.Type)
 }
 
+// SetChallengeKillSwitch makes the VA refuse to validate the challenge types
+// disabled by ks. It must be called before the VA is used.
+func (va *ValidationAuthorityImpl) SetChallengeKillSwitch(ks *killswitch.Challenges) {
+	va.killSwitch = ks
+}
+
 func (va *ValidationAuthorityImpl) performRemoteValidation(ctx context.Context, domain string, challenge core.Challenge, authz core.Authorization, result chan *probs.ProblemDetails) {
 	s := va.clk.Now()
 	errors := make(chan error, len(va.remoteVAs))
@@ -1095,13 +1146,25 @@ func (va *ValidationAuthorityImpl) PerformValidation(ctx context.Context, domain
 	}
 	vStart := va.clk.Now()
 
+	var records []core.ValidationRecord
 	var remoteError chan *probs.ProblemDetails
-	if len(va.remoteVAs) > 0 {
-		remoteError = make(chan *probs.ProblemDetails, 1)
-		go va.performRemoteValidation(ctx, domain, challenge, authz, remoteError)
-	}
+	release, prob := va.limiter.acquire(ctx, challenge.Type)
+	if prob == nil {
+		defer release()
+		if len(va.remoteVAs) > 0 {
+			remoteError = make(chan *probs.ProblemDetails, 1)
+			go va.performRemoteValidation(ctx, domain, challenge, authz, remoteError)
+		}
 
-	records, prob := va.validate(ctx, core.AcmeIdentifier{Type: "dns", Value: domain}, challenge, authz)
+		validateCtx := ctx
+		var capture *bdns.Capture
+		if va.dnsCapture != nil {
+			capture = &bdns.Capture{}
+			validateCtx = bdns.WithCapture(ctx, capture)
+		}
+		records, prob = va.validate(validateCtx, core.AcmeIdentifier{Type: "dns", Value: domain}, challenge, authz)
+		va.dnsCapture.store(authz, domain, challenge.Type, capture)
+	}
 
 	challenge.ValidationRecord = records
 
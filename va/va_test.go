@@ -12,6 +12,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math/big"
 	mrand "math/rand"
 	"net"
@@ -34,6 +35,7 @@ import (
 	"github.com/letsencrypt/boulder/cmd"
 	"github.com/letsencrypt/boulder/core"
 	"github.com/letsencrypt/boulder/features"
+	"github.com/letsencrypt/boulder/killswitch"
 	blog "github.com/letsencrypt/boulder/log"
 	"github.com/letsencrypt/boulder/metrics"
 	"github.com/letsencrypt/boulder/metrics/mock_metrics"
@@ -223,7 +225,12 @@ func tlssniSrvWithNames(t *testing.T, chall core.Challenge, names ...string) *ht
 }
 
 func tlsalpn01Srv(t *testing.T, chall core.Challenge, oid asn1.ObjectIdentifier, names ...string) *httptest.Server {
-	template := tlsCertTemplate(names)
+	return tlsalpn01SrvWithTemplate(t, chall, oid, names[0], tlsCertTemplate(names))
+}
+
+// tlsalpn01SrvWithTemplate returns a TLS-ALPN-01 server that answers requests
+// with SNI serverName using certificates made from template.
+func tlsalpn01SrvWithTemplate(t *testing.T, chall core.Challenge, oid asn1.ObjectIdentifier, serverName string, template *x509.Certificate) *httptest.Server {
 	certBytes, _ := x509.CreateCertificate(rand.Reader, template, template, &TheKey.PublicKey, &TheKey)
 	cert := &tls.Certificate{
 		Certificate: [][]byte{certBytes},
@@ -249,7 +256,7 @@ func tlsalpn01Srv(t *testing.T, chall core.Challenge, oid asn1.ObjectIdentifier,
 		Certificates: []tls.Certificate{},
 		ClientAuth:   tls.NoClientCert,
 		GetCertificate: func(clientHello *tls.ClientHelloInfo) (*tls.Certificate, error) {
-			if clientHello.ServerName != names[0] {
+			if clientHello.ServerName != serverName {
 				return nil, nil
 			}
 			if len(clientHello.SupportedProtos) == 1 && clientHello.SupportedProtos[0] == ACMETLS1Protocol {
@@ -954,6 +961,34 @@ func TestValidateHTTP(t *testing.T) {
 	test.Assert(t, prob == nil, "validation failed")
 }
 
+func TestValidateKillSwitch(t *testing.T) {
+	chall := core.HTTPChallenge01("")
+	setChallengeToken(&chall, core.NewToken())
+
+	hs := httpSrv(t, chall.Token)
+	defer hs.Close()
+
+	va, log := setup(hs, 0)
+	f, _ := ioutil.TempFile("", "test-kill-switch.json")
+	defer os.Remove(f.Name())
+	err := ioutil.WriteFile(f.Name(), []byte(`{"http-01": {"disabledBy": "alice", "sunset": "2019-04-02T00:00:00Z"}}`), 0640)
+	test.AssertNotError(t, err, "Couldn't write kill switch file")
+	fc := clock.NewFake()
+	fc.Set(time.Date(2019, 4, 1, 0, 0, 0, 0, time.UTC))
+	ks := killswitch.NewChallenges(fc, log)
+	test.AssertNotError(t, ks.WatchFile(f.Name()), "Couldn't load kill switch file")
+	va.SetChallengeKillSwitch(ks)
+
+	_, prob := va.validateChallenge(ctx, dnsi("localhost"), chall)
+	test.Assert(t, prob != nil, "validation of a disabled challenge type succeeded")
+	test.AssertEquals(t, prob.Type, probs.ServerInternalProblem)
+	test.AssertContains(t, prob.Detail, "temporarily disabled")
+
+	fc.Set(time.Date(2019, 4, 2, 0, 0, 0, 0, time.UTC))
+	_, prob = va.validateChallenge(ctx, dnsi("localhost"), chall)
+	test.Assert(t, prob == nil, "validation failed after the kill switch's sunset")
+}
+
 func TestGSBAtValidation(t *testing.T) {
 	chall := core.HTTPChallenge01("")
 	setChallengeToken(&chall, core.NewToken())
@@ -1058,6 +1093,56 @@ func TestValidateTLSALPN01(t *testing.T) {
 	test.AssertEquals(t, test.CountCounterVec("oid", IdPeAcmeIdentifierV1Obsolete.String(), va.metrics.tlsALPNOIDCounter), 1)
 }
 
+func TestValidateTLSALPN01IP(t *testing.T) {
+	chall := createChallenge(core.ChallengeTypeTLSALPN01)
+	ipIdentifier := core.AcmeIdentifier{Type: core.IdentifierIP, Value: "127.0.0.1"}
+
+	template := tlsCertTemplate(nil)
+	template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	hs := tlsalpn01SrvWithTemplate(t, chall, IdPeAcmeIdentifier, "1.0.0.127.in-addr.arpa", template)
+	va, _ := setup(hs, 0)
+
+	records, prob := va.validateChallenge(ctx, ipIdentifier, chall)
+	if prob != nil {
+		t.Errorf("Validation failed: %v", prob)
+	}
+	test.AssertEquals(t, len(records), 1)
+	test.AssertEquals(t, records[0].AddressUsed.String(), "127.0.0.1")
+	hs.Close()
+
+	// A certificate for a different IP address must be rejected
+	template = tlsCertTemplate(nil)
+	template.IPAddresses = []net.IP{net.ParseIP("10.0.0.1")}
+	hs = tlsalpn01SrvWithTemplate(t, chall, IdPeAcmeIdentifier, "1.0.0.127.in-addr.arpa", template)
+	va, _ = setup(hs, 0)
+
+	_, prob = va.validateChallenge(ctx, ipIdentifier, chall)
+	if prob == nil {
+		t.Fatalf("TLS ALPN validation with the wrong IP address should have failed.")
+	}
+	test.AssertEquals(t, prob.Type, probs.UnauthorizedProblem)
+	test.Assert(t, strings.Contains(prob.Detail, "10.0.0.1"), "problem didn't include the certificate's IP address")
+	hs.Close()
+
+	// As must a certificate with a DNS name for the address
+	template = tlsCertTemplate([]string{"127.0.0.1"})
+	hs = tlsalpn01SrvWithTemplate(t, chall, IdPeAcmeIdentifier, "1.0.0.127.in-addr.arpa", template)
+	va, _ = setup(hs, 0)
+
+	_, prob = va.validateChallenge(ctx, ipIdentifier, chall)
+	if prob == nil {
+		t.Fatalf("TLS ALPN validation with a DNS name SAN should have failed.")
+	}
+	test.AssertEquals(t, prob.Type, probs.UnauthorizedProblem)
+	hs.Close()
+
+	_, prob = va.validateTLSALPN01(ctx, core.AcmeIdentifier{Type: core.IdentifierIP, Value: "localhost"}, chall)
+	if prob == nil {
+		t.Fatalf("TLS ALPN validation of an invalid IP address should have failed.")
+	}
+	test.AssertEquals(t, prob.Type, probs.MalformedProblem)
+}
+
 func TestValidateTLSALPN01BadChallenge(t *testing.T) {
 	chall := createChallenge(core.ChallengeTypeTLSALPN01)
 	chall2 := chall
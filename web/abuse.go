@@ -0,0 +1,273 @@
+package web
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"golang.org/x/net/context"
+	"gopkg.in/yaml.v2"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/probs"
+	"github.com/letsencrypt/boulder/reloader"
+)
+
+// AbuseSignals are what's known about a request when it's scored: the
+// endpoint, the thumbprint of the key its JWS verified with, the account that
+// key belongs to (zero for new account and revocation requests signed with a
+// certificate key), and the client's IP address and User-Agent.
+type AbuseSignals struct {
+	Endpoint       string
+	KeyThumbprint  string
+	RegistrationID int64
+	ClientIP       net.IP
+	UserAgent      string
+}
+
+// AbuseScore is an AbuseScorer's assessment of a request. Higher scores are
+// more likely abuse; the AbusePolicy decides what's done about them. Reason
+// is logged with the decision. ChallengeURL, if set, is where the client's
+// operator can prove they're human, overriding the policy's ChallengeURL, e.g.
+// to include a token identifying the request.
+type AbuseScore struct {
+	Score        float64
+	Reason       string
+	ChallengeURL string
+}
+
+// AbuseScorer scores requests for abuse. Implementations must be safe for
+// concurrent use. Operators can plug in their own by setting the WFE's
+// AbuseHook to one made with NewAbuseHook; AbuseRules is a simple one
+// configured by a file.
+type AbuseScorer interface {
+	Score(ctx context.Context, signals AbuseSignals) (AbuseScore, error)
+}
+
+// AbusePolicy decides what's done about a request given its score. Each
+// threshold is the lowest score the action is taken for, and zero disables
+// the action. If a score reaches more than one threshold, the most severe
+// action is taken: reject, then challenge, then tarpit.
+type AbusePolicy struct {
+	// TarpitScore is the score at or above which requests are held for
+	// TarpitDelay before they're handled, slowing down automated clients.
+	TarpitScore float64
+	TarpitDelay time.Duration
+	// ChallengeScore is the score at or above which requests are refused
+	// with a userActionRequired problem pointing at ChallengeURL, where a
+	// person can solve e.g. a captcha to be let through. The out-of-band
+	// flow is up to the scorer: it should stop scoring the client highly
+	// once the challenge is solved.
+	ChallengeScore float64
+	ChallengeURL   string
+	// RejectScore is the score at or above which requests are refused with
+	// an unauthorized problem.
+	RejectScore float64
+}
+
+// AbuseHook scores requests with an AbuseScorer and tarpits, challenges or
+// rejects them according to an AbusePolicy. Every decision other than letting
+// a request through is audit logged. If the scorer fails the request is let
+// through, so that an outage of the scorer doesn't stop issuance. A nil
+// *AbuseHook lets every request through.
+type AbuseHook struct {
+	scorer AbuseScorer
+	policy AbusePolicy
+	clk    clock.Clock
+	log    blog.Logger
+}
+
+// NewAbuseHook returns an AbuseHook that applies policy to the scores of
+// scorer.
+func NewAbuseHook(scorer AbuseScorer, policy AbusePolicy, clk clock.Clock, logger blog.Logger) (*AbuseHook, error) {
+	if policy.TarpitScore < 0 || policy.ChallengeScore < 0 || policy.RejectScore < 0 {
+		return nil, fmt.Errorf("abuse policy scores can't be negative")
+	}
+	if policy.TarpitScore > 0 && policy.TarpitDelay <= 0 {
+		return nil, fmt.Errorf("abuse policy tarpitScore needs a positive tarpitDelay")
+	}
+	if policy.ChallengeScore > 0 && policy.ChallengeURL == "" {
+		return nil, fmt.Errorf("abuse policy challengeScore needs a challengeURL")
+	}
+	return &AbuseHook{scorer: scorer, policy: policy, clk: clk, log: logger}, nil
+}
+
+// Check scores a request and returns the problem to refuse it with, if any.
+// Tarpitted requests are held before Check returns nil, unless ctx is done
+// first, in which case they are refused. The score and the decision are added
+// to logEvent.
+func (h *AbuseHook) Check(ctx context.Context, signals AbuseSignals, logEvent *RequestEvent) *probs.ProblemDetails {
+	if h == nil {
+		return nil
+	}
+	score, err := h.scorer.Score(ctx, signals)
+	if err != nil {
+		logEvent.AddError("scoring request for abuse: %s", err)
+		return nil
+	}
+	logEvent.Extra["AbuseScore"] = score.Score
+
+	var action string
+	var prob *probs.ProblemDetails
+	switch {
+	case h.policy.RejectScore > 0 && score.Score >= h.policy.RejectScore:
+		action = "reject"
+		prob = probs.Unauthorized("Your requests have been refused because they look automated and abusive")
+	case h.policy.ChallengeScore > 0 && score.Score >= h.policy.ChallengeScore:
+		action = "challenge"
+		url := score.ChallengeURL
+		if url == "" {
+			url = h.policy.ChallengeURL
+		}
+		prob = probs.UserActionRequired("Your requests look automated. Visit %s to continue", url)
+	case h.policy.TarpitScore > 0 && score.Score >= h.policy.TarpitScore:
+		action = "tarpit"
+	default:
+		return nil
+	}
+	logEvent.Extra["AbuseAction"] = action
+	h.log.AuditInfof("Abuse hook decided to %s request: endpoint=[%s] key=[%s] regID=[%d] ip=[%s] ua=[%s] score=[%g] reason=[%s]",
+		action, signals.Endpoint, signals.KeyThumbprint, signals.RegistrationID, signals.ClientIP,
+		signals.UserAgent, score.Score, score.Reason)
+	if action == "tarpit" {
+		timer := h.clk.NewTimer(h.policy.TarpitDelay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			// The client has gone away, so there's no point finishing the
+			// request.
+			logEvent.Extra["AbuseTarpitCancelled"] = true
+			return probs.RateLimited("Request was cancelled while it was held")
+		}
+	}
+	return prob
+}
+
+// abuseRuleYAML is one entry of an abuse rules file. A rule matches a request
+// when every one of its conditions that's set does: the key thumbprint is
+// one of KeyThumbprints, the client IP is in one of IPRanges, and the
+// User-Agent matches the UserAgent regular expression.
+type abuseRuleYAML struct {
+	Name           string   `yaml:"name"`
+	Score          float64  `yaml:"score"`
+	KeyThumbprints []string `yaml:"keyThumbprints"`
+	IPRanges       []string `yaml:"ipRanges"`
+	UserAgent      string   `yaml:"userAgent"`
+}
+
+type abuseRule struct {
+	name        string
+	score       float64
+	thumbprints map[string]bool
+	ipRanges    []*net.IPNet
+	userAgent   *regexp.Regexp
+}
+
+func (r abuseRule) matches(signals AbuseSignals) bool {
+	if r.thumbprints != nil && !r.thumbprints[signals.KeyThumbprint] {
+		return false
+	}
+	if r.ipRanges != nil {
+		found := false
+		for _, ipNet := range r.ipRanges {
+			if signals.ClientIP != nil && ipNet.Contains(signals.ClientIP) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if r.userAgent != nil && !r.userAgent.MatchString(signals.UserAgent) {
+		return false
+	}
+	return true
+}
+
+// AbuseRules is an AbuseScorer that scores requests with a list of rules,
+// each adding its score to the requests it matches. It has no rules until
+// they're loaded.
+type AbuseRules struct {
+	mu    sync.RWMutex
+	rules []abuseRule
+}
+
+// NewAbuseRules returns an AbuseRules with no rules.
+func NewAbuseRules() *AbuseRules {
+	return &AbuseRules{}
+}
+
+// WatchFile loads the rules from a YAML file listing them, and reloads them
+// whenever the file changes.
+func (a *AbuseRules) WatchFile(file string) error {
+	return reloader.Register(reloader.Section{
+		Name: "abuse rules",
+		File: file,
+		Load: a.load,
+	})
+}
+
+func (a *AbuseRules) load(contents []byte) error {
+	var entries []abuseRuleYAML
+	err := yaml.Unmarshal(contents, &entries)
+	if err != nil {
+		return err
+	}
+	rules := make([]abuseRule, 0, len(entries))
+	for _, e := range entries {
+		if e.Name == "" || e.Score == 0 {
+			return fmt.Errorf("abuse rule %q needs a name and a score", e.Name)
+		}
+		if len(e.KeyThumbprints) == 0 && len(e.IPRanges) == 0 && e.UserAgent == "" {
+			return fmt.Errorf("abuse rule %q has no conditions", e.Name)
+		}
+		r := abuseRule{name: e.Name, score: e.Score}
+		if len(e.KeyThumbprints) > 0 {
+			r.thumbprints = make(map[string]bool, len(e.KeyThumbprints))
+			for _, t := range e.KeyThumbprints {
+				r.thumbprints[t] = true
+			}
+		}
+		for _, cidr := range e.IPRanges {
+			_, ipNet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return fmt.Errorf("abuse rule %q: invalid IP range: %s", e.Name, err)
+			}
+			r.ipRanges = append(r.ipRanges, ipNet)
+		}
+		if e.UserAgent != "" {
+			r.userAgent, err = regexp.Compile(e.UserAgent)
+			if err != nil {
+				return fmt.Errorf("abuse rule %q: invalid userAgent pattern: %s", e.Name, err)
+			}
+		}
+		rules = append(rules, r)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rules = rules
+	return nil
+}
+
+// Score returns the sum of the scores of the rules matching a request, with
+// the names of those rules as the reason.
+func (a *AbuseRules) Score(_ context.Context, signals AbuseSignals) (AbuseScore, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	var score AbuseScore
+	var matched []string
+	for _, r := range a.rules {
+		if r.matches(signals) {
+			score.Score += r.score
+			matched = append(matched, r.name)
+		}
+	}
+	score.Reason = strings.Join(matched, ",")
+	return score, nil
+}
@@ -0,0 +1,180 @@
+package web
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"golang.org/x/net/context"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/probs"
+	"github.com/letsencrypt/boulder/test"
+)
+
+type staticAbuseScorer struct {
+	score AbuseScore
+	err   error
+}
+
+func (s *staticAbuseScorer) Score(context.Context, AbuseSignals) (AbuseScore, error) {
+	return s.score, s.err
+}
+
+func TestNewAbuseHook(t *testing.T) {
+	for _, policy := range []AbusePolicy{
+		{RejectScore: -1},
+		{TarpitScore: 1},
+		{ChallengeScore: 1},
+	} {
+		_, err := NewAbuseHook(&staticAbuseScorer{}, policy, clock.NewFake(), blog.NewMock())
+		test.AssertError(t, err, "invalid abuse policy was accepted")
+	}
+
+	var hook *AbuseHook
+	test.Assert(t, hook.Check(context.Background(), AbuseSignals{}, &RequestEvent{}) == nil, "nil hook refused a request")
+}
+
+func TestAbuseHookCheck(t *testing.T) {
+	scorer := &staticAbuseScorer{}
+	fc := clock.NewFake()
+	log := blog.NewMock()
+	hook, err := NewAbuseHook(scorer, AbusePolicy{
+		TarpitScore:    10,
+		TarpitDelay:    5 * time.Second,
+		ChallengeScore: 50,
+		ChallengeURL:   "https://example.com/captcha",
+		RejectScore:    100,
+	}, fc, log)
+	test.AssertNotError(t, err, "NewAbuseHook failed")
+	signals := AbuseSignals{
+		Endpoint:       "/acme/new-order",
+		KeyThumbprint:  "thumbprint",
+		RegistrationID: 1,
+		ClientIP:       net.ParseIP("192.0.2.1"),
+		UserAgent:      "evil-acme-bot/1.0",
+	}
+	check := func(score float64) (*probs.ProblemDetails, *RequestEvent) {
+		scorer.score = AbuseScore{Score: score, Reason: "test"}
+		logEvent := &RequestEvent{Extra: map[string]interface{}{}}
+		return hook.Check(context.Background(), signals, logEvent), logEvent
+	}
+	// checkHeld runs a check that is expected to be tarpitted, advancing the
+	// fake clock until it returns.
+	checkHeld := func(score float64) (*probs.ProblemDetails, *RequestEvent) {
+		done := make(chan struct{})
+		var prob *probs.ProblemDetails
+		var logEvent *RequestEvent
+		go func() {
+			prob, logEvent = check(score)
+			close(done)
+		}()
+		for {
+			select {
+			case <-done:
+				return prob, logEvent
+			case <-time.After(time.Millisecond):
+				fc.Add(time.Second)
+			}
+		}
+	}
+
+	start := fc.Now()
+	prob, logEvent := check(5)
+	test.Assert(t, prob == nil, "low scoring request was refused")
+	test.AssertEquals(t, fc.Now(), start)
+	test.AssertEquals(t, logEvent.Extra["AbuseAction"], nil)
+	test.AssertEquals(t, len(log.GetAllMatching("Abuse hook")), 0)
+
+	prob, logEvent = checkHeld(10)
+	test.Assert(t, prob == nil, "tarpitted request was refused")
+	test.Assert(t, !fc.Now().Before(start.Add(5*time.Second)), "tarpitted request wasn't held for the tarpit delay")
+	test.AssertEquals(t, logEvent.Extra["AbuseAction"], "tarpit")
+	test.AssertEquals(t, len(log.GetAllMatching(`Abuse hook decided to tarpit request: endpoint=\[/acme/new-order\] key=\[thumbprint\] regID=\[1\] ip=\[192.0.2.1\] ua=\[evil-acme-bot/1.0\] score=\[10\] reason=\[test\]`)), 1)
+
+	prob, _ = check(60)
+	test.AssertEquals(t, prob.Type, probs.UserActionRequiredProblem)
+	test.AssertContains(t, prob.Detail, "https://example.com/captcha")
+	scorer.score.ChallengeURL = "https://example.com/captcha?token=abc"
+	prob = hook.Check(context.Background(), signals, &RequestEvent{Extra: map[string]interface{}{}})
+	test.AssertContains(t, prob.Detail, "https://example.com/captcha?token=abc")
+
+	prob, logEvent = check(1000)
+	test.AssertEquals(t, prob.Type, probs.UnauthorizedProblem)
+	test.AssertEquals(t, logEvent.Extra["AbuseAction"], "reject")
+
+	// Requests are let through if the scorer fails
+	scorer.err = errors.New("scorer unavailable")
+	prob, logEvent = check(1000)
+	test.Assert(t, prob == nil, "request was refused when the scorer failed")
+	test.AssertEquals(t, len(logEvent.InternalErrors), 1)
+}
+
+func TestAbuseRules(t *testing.T) {
+	rules := NewAbuseRules()
+	err := rules.load([]byte(`
+- name: bad-key
+  score: 100
+  keyThumbprints: [bad-thumbprint]
+- name: doc-range-bot
+  score: 10
+  ipRanges: [192.0.2.0/24, "2001:db8::/32"]
+  userAgent: '^evil-acme-bot/'
+- name: doc-range
+  score: 5
+  ipRanges: [192.0.2.0/24]
+`))
+	test.AssertNotError(t, err, "Couldn't load abuse rules")
+
+	score := func(signals AbuseSignals) AbuseScore {
+		s, err := rules.Score(context.Background(), signals)
+		test.AssertNotError(t, err, "Score failed")
+		return s
+	}
+	test.AssertEquals(t, score(AbuseSignals{KeyThumbprint: "bad-thumbprint"}), AbuseScore{Score: 100, Reason: "bad-key"})
+	test.AssertEquals(t, score(AbuseSignals{ClientIP: net.ParseIP("192.0.2.7"), UserAgent: "evil-acme-bot/2"}),
+		AbuseScore{Score: 15, Reason: "doc-range-bot,doc-range"})
+	test.AssertEquals(t, score(AbuseSignals{ClientIP: net.ParseIP("2001:db8::1"), UserAgent: "certbot/0.32"}), AbuseScore{})
+	test.AssertEquals(t, score(AbuseSignals{UserAgent: "evil-acme-bot/2"}), AbuseScore{})
+
+	// Invalid rules are rejected and leave the previous rules in place
+	for _, invalid := range []string{
+		`- {name: no-score, userAgent: bot}`,
+		`- {name: no-conditions, score: 1}`,
+		`- {name: bad-range, score: 1, ipRanges: [192.0.2.1]}`,
+		`- {name: bad-pattern, score: 1, userAgent: '('}`,
+		`not a list`,
+	} {
+		test.AssertError(t, rules.load([]byte(invalid)), "invalid abuse rules were loaded: "+invalid)
+	}
+	test.AssertEquals(t, score(AbuseSignals{KeyThumbprint: "bad-thumbprint"}).Score, float64(100))
+}
+
+func TestAbuseHookTarpitCancelled(t *testing.T) {
+	fc := clock.NewFake()
+	hook, err := NewAbuseHook(&staticAbuseScorer{score: AbuseScore{Score: 10}}, AbusePolicy{
+		TarpitScore: 10,
+		TarpitDelay: time.Hour,
+	}, fc, blog.NewMock())
+	test.AssertNotError(t, err, "NewAbuseHook failed")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	logEvent := &RequestEvent{Extra: map[string]interface{}{}}
+	result := make(chan *probs.ProblemDetails, 1)
+	go func() {
+		result <- hook.Check(ctx, AbuseSignals{}, logEvent)
+	}()
+	cancel()
+
+	select {
+	case prob := <-result:
+		test.Assert(t, prob != nil, "cancelled tarpitted request wasn't refused")
+		test.AssertEquals(t, prob.Type, probs.RateLimitedProblem)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Check didn't return when its context was cancelled")
+	}
+	test.AssertEquals(t, logEvent.Extra["AbuseAction"], "tarpit")
+	test.AssertEquals(t, logEvent.Extra["AbuseTarpitCancelled"], true)
+}
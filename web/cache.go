@@ -0,0 +1,149 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+)
+
+// CachedResponse is a rendered response body along with the validators that
+// clients and CDNs use to make conditional requests for it.
+type CachedResponse struct {
+	Body []byte
+	// ETag is a strong entity tag derived from Body
+	ETag string
+	// Modified is when Body last changed. It's sent as Last-Modified.
+	Modified time.Time
+}
+
+// NewCachedResponse returns a CachedResponse for body with an ETag computed
+// from its contents.
+func NewCachedResponse(body []byte, modified time.Time) CachedResponse {
+	sum := sha256.Sum256(body)
+	return CachedResponse{
+		Body:     body,
+		ETag:     `"` + hex.EncodeToString(sum[:16]) + `"`,
+		Modified: modified,
+	}
+}
+
+// ResponseCache is an in-process cache of rendered responses that are the
+// same for every request with the same key, like the ACME directory. Entries
+// are rendered again after the cache's lifetime, and the cache never holds
+// more than a fixed number of entries, so that requests with made up keys,
+// e.g. Host headers, can't make it grow without bound. A nil *ResponseCache
+// renders every response.
+type ResponseCache struct {
+	clk        clock.Clock
+	lifetime   time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	CachedResponse
+	expires time.Time
+}
+
+// NewResponseCache returns an empty ResponseCache.
+func NewResponseCache(clk clock.Clock, lifetime time.Duration, maxEntries int) *ResponseCache {
+	return &ResponseCache{
+		clk:        clk,
+		lifetime:   lifetime,
+		maxEntries: maxEntries,
+		entries:    make(map[string]cacheEntry),
+	}
+}
+
+// Get returns the response cached for key. If there is none, or it has
+// expired, the response is rendered and cached. Errors from render are
+// returned and not cached.
+func (c *ResponseCache) Get(key string, render func() ([]byte, error)) (CachedResponse, error) {
+	if c == nil {
+		body, err := render()
+		if err != nil {
+			return CachedResponse{}, err
+		}
+		return NewCachedResponse(body, time.Time{}), nil
+	}
+	now := c.clk.Now()
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.CachedResponse, nil
+	}
+
+	body, err := render()
+	if err != nil {
+		return CachedResponse{}, err
+	}
+	// Last-Modified only has a resolution of a second
+	resp := NewCachedResponse(body, now.Truncate(time.Second))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.maxEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	if len(c.entries) < c.maxEntries {
+		c.entries[key] = cacheEntry{CachedResponse: resp, expires: now.Add(c.lifetime)}
+	}
+	return resp, nil
+}
+
+// CheckNotModified sets the ETag and, if modified isn't zero, Last-Modified
+// headers of a response. If the request is a conditional GET or HEAD for a
+// version of the resource the client already has, it writes a 304 Not
+// Modified response and returns true; the caller must not write anything
+// else. As RFC 7232 requires, If-Modified-Since is ignored if the request has
+// an If-None-Match header.
+func CheckNotModified(response http.ResponseWriter, request *http.Request, etag string, modified time.Time) bool {
+	response.Header().Set("ETag", etag)
+	if !modified.IsZero() {
+		response.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if request.Method != "GET" && request.Method != "HEAD" {
+		return false
+	}
+
+	notModified := false
+	if inm := request.Header.Get("If-None-Match"); inm != "" {
+		notModified = ETagMatches(inm, etag)
+	} else if ims := request.Header.Get("If-Modified-Since"); ims != "" && !modified.IsZero() {
+		t, err := http.ParseTime(ims)
+		notModified = err == nil && !modified.Truncate(time.Second).After(t)
+	}
+	if !notModified {
+		return false
+	}
+	// A 304 response has no body, so the headers describing one don't apply
+	response.Header().Del("Content-Type")
+	response.Header().Del("Content-Length")
+	response.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// ETagMatches reports whether an If-None-Match header value matches etag,
+// using the weak comparison RFC 7232 requires for If-None-Match.
+func ETagMatches(ifNoneMatch, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestResponseCache(t *testing.T) {
+	clk := clock.NewFake()
+	cache := NewResponseCache(clk, time.Minute, 2)
+	renders := 0
+	render := func() ([]byte, error) {
+		renders++
+		return []byte("hello"), nil
+	}
+
+	first, err := cache.Get("a", render)
+	test.AssertNotError(t, err, "Get failed")
+	test.AssertEquals(t, string(first.Body), "hello")
+	test.AssertEquals(t, first.Modified, clk.Now().Truncate(time.Second))
+	second, err := cache.Get("a", render)
+	test.AssertNotError(t, err, "Get failed")
+	test.AssertEquals(t, renders, 1)
+	test.AssertEquals(t, second.ETag, first.ETag)
+
+	// Expired entries are rendered again
+	clk.Add(time.Minute)
+	_, err = cache.Get("a", render)
+	test.AssertNotError(t, err, "Get failed")
+	test.AssertEquals(t, renders, 2)
+
+	// Once the cache is full new keys are rendered but not cached
+	_, _ = cache.Get("b", render)
+	_, _ = cache.Get("c", render)
+	_, _ = cache.Get("c", render)
+	test.AssertEquals(t, renders, 5)
+	test.AssertEquals(t, len(cache.entries), 2)
+
+	// Errors aren't cached
+	_, err = cache.Get("d", func() ([]byte, error) { return nil, errors.New("broken") })
+	test.AssertError(t, err, "render error wasn't returned")
+
+	// A nil cache renders every time
+	var nilCache *ResponseCache
+	_, _ = nilCache.Get("a", render)
+	_, _ = nilCache.Get("a", render)
+	test.AssertEquals(t, renders, 7)
+}
+
+func TestCheckNotModified(t *testing.T) {
+	modified := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	resp := NewCachedResponse([]byte("hello"), modified)
+
+	testCases := []struct {
+		name        string
+		method      string
+		headers     map[string]string
+		notModified bool
+	}{
+		{"unconditional", "GET", nil, false},
+		{"matching etag", "GET", map[string]string{"If-None-Match": resp.ETag}, true},
+		{"matching weak etag in list", "HEAD", map[string]string{"If-None-Match": `"other", W/` + resp.ETag}, true},
+		{"wildcard etag", "GET", map[string]string{"If-None-Match": "*"}, true},
+		{"different etag", "GET", map[string]string{"If-None-Match": `"other"`}, false},
+		{"not modified since", "GET", map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, true},
+		{"modified since", "GET", map[string]string{"If-Modified-Since": modified.Add(-time.Second).Format(http.TimeFormat)}, false},
+		{"invalid date", "GET", map[string]string{"If-Modified-Since": "yesterday"}, false},
+		// If-None-Match takes precedence over If-Modified-Since
+		{"etag mismatch overrides date", "GET", map[string]string{
+			"If-None-Match":     `"other"`,
+			"If-Modified-Since": modified.Format(http.TimeFormat),
+		}, false},
+		{"POST", "POST", map[string]string{"If-None-Match": resp.ETag}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/directory", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			w.Header().Set("Content-Type", "application/json")
+			notModified := CheckNotModified(w, req, resp.ETag, resp.Modified)
+			test.AssertEquals(t, notModified, tc.notModified)
+			test.AssertEquals(t, w.Header().Get("ETag"), resp.ETag)
+			test.AssertEquals(t, w.Header().Get("Last-Modified"), "Wed, 02 Jan 2019 03:04:05 GMT")
+			if tc.notModified {
+				test.AssertEquals(t, w.Code, http.StatusNotModified)
+				test.AssertEquals(t, w.Header().Get("Content-Type"), "")
+			}
+		})
+	}
+}
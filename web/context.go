@@ -1,15 +1,19 @@
 package web
 
 import (
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"strconv"
 	"time"
 
 	"golang.org/x/net/context"
 
 	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/trace"
 )
 
 type RequestEvent struct {
@@ -23,6 +27,9 @@ type RequestEvent struct {
 	Code      int     `json:"-"`
 	Latency   float64 `json:"-"`
 	RealIP    string  `json:"-"`
+	// AcceptLanguage is the request's Accept-Language header, which picks
+	// the language of any problem document sent. It isn't logged.
+	AcceptLanguage string `json:"-"`
 
 	Slug           string                 `json:",omitempty"`
 	InternalErrors []string               `json:",omitempty"`
@@ -53,6 +60,11 @@ type WFEHandlerFunc func(context.Context, *RequestEvent, http.ResponseWriter, *h
 
 func (f WFEHandlerFunc) ServeHTTP(e *RequestEvent, w http.ResponseWriter, r *http.Request) {
 	ctx := context.TODO()
+	// Carry over only the trace started by the TopHandler, not the request's
+	// cancellation.
+	if sc, ok := trace.FromContext(r.Context()); ok {
+		ctx = trace.NewContext(ctx, sc)
+	}
 	f(ctx, e, w, r)
 }
 
@@ -97,26 +109,49 @@ func (th *TopHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		Method:    r.Method,
 		UserAgent: r.Header.Get("User-Agent"),
 		Extra:     make(map[string]interface{}, 0),
+
+		AcceptLanguage: r.Header.Get("Accept-Language"),
 	}
 
+	// Start a new trace for each request. Any traceparent sent by the client
+	// is ignored, since clients don't get to pick our sampling decisions.
+	ctx, span := trace.Start(r.Context(), fmt.Sprintf("HTTP %s", r.Method), trace.KindServer)
+	r = r.WithContext(ctx)
+
 	begin := time.Now()
 	rwws := &responseWriterWithStatus{w, 0}
 	defer func() {
 		logEvent.Code = rwws.code
 		logEvent.Latency = time.Since(begin).Seconds()
-		th.logEvent(logEvent)
+		th.logEvent(logEvent, span.Context())
+		if logEvent.Endpoint != "" {
+			span.SetName(fmt.Sprintf("%s %s", logEvent.Method, logEvent.Endpoint))
+		}
+		span.SetAttribute("http.method", logEvent.Method)
+		span.SetAttribute("http.route", logEvent.Endpoint)
+		span.SetAttribute("http.status_code", strconv.Itoa(logEvent.Code))
+		if logEvent.Error != "" {
+			span.SetError(errors.New(logEvent.Error))
+		}
+		span.End()
 	}()
 	th.wfe.ServeHTTP(logEvent, rwws, r)
 }
 
-func (th *TopHandler) logEvent(logEvent *RequestEvent) {
+// logEvent logs the request. If the request is traced the trace ID is used as
+// the request ID, so that the request can be found among the traces.
+func (th *TopHandler) logEvent(logEvent *RequestEvent, sc trace.SpanContext) {
+	logger := th.log
+	if sc.IsValid() {
+		logger = logger.WithRequestID(hex.EncodeToString(sc.TraceID[:]))
+	}
 	var msg string
 	jsonEvent, err := json.Marshal(logEvent)
 	if err != nil {
-		th.log.AuditErrf("failed to marshal logEvent - %s - %#v", msg, err)
+		logger.AuditErrf("failed to marshal logEvent - %s - %#v", msg, err)
 		return
 	}
-	th.log.Infof("%s %s %d %d %d %s JSON=%s",
+	logger.Infof("%s %s %d %d %d %s JSON=%s",
 		logEvent.Method, logEvent.Endpoint, logEvent.Requester, logEvent.Code,
 		int(logEvent.Latency*1000), logEvent.RealIP, jsonEvent)
 }
@@ -0,0 +1,181 @@
+package web
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/letsencrypt/boulder/probs"
+	"github.com/letsencrypt/boulder/reloader"
+)
+
+// problemTranslationYAML is one entry of a problem catalog file. Pattern is
+// a regular expression that must match the whole English detail, and Detail
+// is its translation, in which $1, ${name}, etc. are replaced by the
+// pattern's submatches, as in regexp.Regexp.Expand.
+type problemTranslationYAML struct {
+	Pattern string `yaml:"pattern"`
+	Detail  string `yaml:"detail"`
+}
+
+type problemTranslation struct {
+	pattern *regexp.Regexp
+	detail  string
+}
+
+// ProblemCatalog translates the detail of problem documents into the
+// languages clients ask for with the Accept-Language header. Only the detail
+// is translated: the type stays canonical so clients can still act on it,
+// and the logs keep the English detail. A nil *ProblemCatalog translates
+// nothing.
+type ProblemCatalog struct {
+	mu sync.RWMutex
+	// translations are the translations into each language, by lower case
+	// language tag, tried in order.
+	translations map[string][]problemTranslation
+}
+
+// NewProblemCatalog returns a ProblemCatalog with no translations until one
+// is loaded.
+func NewProblemCatalog() *ProblemCatalog {
+	return &ProblemCatalog{}
+}
+
+// WatchFile loads the catalog from a YAML file mapping language tags to
+// lists of translations, and reloads it whenever the file changes.
+func (c *ProblemCatalog) WatchFile(file string) error {
+	return reloader.Register(reloader.Section{
+		Name: "problem catalog",
+		File: file,
+		Load: c.load,
+	})
+}
+
+func (c *ProblemCatalog) load(contents []byte) error {
+	var catalog map[string][]problemTranslationYAML
+	err := yaml.Unmarshal(contents, &catalog)
+	if err != nil {
+		return err
+	}
+	translations := make(map[string][]problemTranslation, len(catalog))
+	for lang, entries := range catalog {
+		lang = strings.ToLower(lang)
+		if lang == "" || lang == "*" {
+			return fmt.Errorf("invalid language %q in problem catalog", lang)
+		}
+		for _, e := range entries {
+			if e.Pattern == "" || e.Detail == "" {
+				return fmt.Errorf("problem catalog entry for %q needs a pattern and a detail", lang)
+			}
+			re, err := regexp.Compile("^(?:" + e.Pattern + ")$")
+			if err != nil {
+				return fmt.Errorf("problem catalog pattern %q: %s", e.Pattern, err)
+			}
+			translations[lang] = append(translations[lang], problemTranslation{re, e.Detail})
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.translations = translations
+	return nil
+}
+
+// acceptedLanguages returns the language tags in an Accept-Language header,
+// lower cased, most preferred first. Those with a weight of zero, and the
+// "*" wildcard, are left out.
+func acceptedLanguages(header string) []string {
+	type weighted struct {
+		lang string
+		q    float64
+	}
+	var langs []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		lang := strings.ToLower(strings.TrimSpace(fields[0]))
+		if lang == "" || lang == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				parsed, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					parsed = 0
+				}
+				q = parsed
+			}
+		}
+		if q > 0 {
+			langs = append(langs, weighted{lang, q})
+		}
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+	tags := make([]string, len(langs))
+	for i, l := range langs {
+		tags[i] = l.lang
+	}
+	return tags
+}
+
+// language returns the catalog language that best matches an
+// Accept-Language header, or "" if none does. A tag like "fr-CA" matches
+// the catalog's "fr-ca" translations, or failing that its "fr" ones.
+func (c *ProblemCatalog) language(acceptLanguage string) string {
+	for _, tag := range acceptedLanguages(acceptLanguage) {
+		if strings.HasPrefix(tag, "en") && (len(tag) == 2 || tag[2] == '-') {
+			// Problems are written in English
+			return ""
+		}
+		if _, ok := c.translations[tag]; ok {
+			return tag
+		}
+		if i := strings.Index(tag, "-"); i > 0 {
+			if _, ok := c.translations[tag[:i]]; ok {
+				return tag[:i]
+			}
+		}
+	}
+	return ""
+}
+
+func (c *ProblemCatalog) translate(lang, detail string) (string, bool) {
+	for _, t := range c.translations[lang] {
+		if m := t.pattern.FindStringSubmatchIndex(detail); m != nil {
+			return string(t.pattern.ExpandString(nil, t.detail, detail, m)), true
+		}
+	}
+	return detail, false
+}
+
+// Localize translates the detail of prob and its subproblems into the
+// language that best matches an Accept-Language header. It returns the
+// language tag of the translation, or "" if nothing was translated. Details
+// the catalog has no translation for are left in English.
+func (c *ProblemCatalog) Localize(prob *probs.ProblemDetails, acceptLanguage string) string {
+	if c == nil || acceptLanguage == "" {
+		return ""
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	lang := c.language(acceptLanguage)
+	if lang == "" {
+		return ""
+	}
+	var translated bool
+	var ok bool
+	prob.Detail, translated = c.translate(lang, prob.Detail)
+	for _, sub := range prob.SubProblems {
+		sub.Detail, ok = c.translate(lang, sub.Detail)
+		translated = translated || ok
+	}
+	if !translated {
+		return ""
+	}
+	return lang
+}
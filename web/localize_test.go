@@ -0,0 +1,118 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/probs"
+	"github.com/letsencrypt/boulder/test"
+)
+
+const testProblemCatalog = `
+fr:
+  - pattern: 'No such authorization'
+    detail: "Cette autorisation n'existe pas"
+  - pattern: 'No TXT record found at (\S+)'
+    detail: 'Aucun enregistrement TXT trouvé à $1'
+FR-CA:
+  - pattern: 'No such authorization'
+    detail: "Cette autorisation n'existe pas, eh"
+de:
+  - pattern: 'No such authorization'
+    detail: 'Diese Autorisierung existiert nicht'
+`
+
+func TestAcceptedLanguages(t *testing.T) {
+	test.AssertDeepEquals(t, acceptedLanguages("de;q=0.5, FR-ca, *;q=0.1, en;q=0, es;q=0.5"),
+		[]string{"fr-ca", "de", "es"})
+	test.AssertEquals(t, len(acceptedLanguages("")), 0)
+	test.AssertEquals(t, len(acceptedLanguages("fr;q=bogus")), 0)
+}
+
+func TestProblemCatalogLocalize(t *testing.T) {
+	c := NewProblemCatalog()
+	err := c.load([]byte(testProblemCatalog))
+	test.AssertNotError(t, err, "loading problem catalog failed")
+
+	testCases := []struct {
+		acceptLanguage string
+		detail         string
+		lang           string
+		translated     string
+	}{
+		{"fr", "No such authorization", "fr", "Cette autorisation n'existe pas"},
+		{"fr-CA", "No such authorization", "fr-ca", "Cette autorisation n'existe pas, eh"},
+		{"fr-BE", "No such authorization", "fr", "Cette autorisation n'existe pas"},
+		{"de;q=0.9, fr;q=0.5", "No such authorization", "de", "Diese Autorisierung existiert nicht"},
+		{"fr", "No TXT record found at _acme-challenge.example.com", "fr", "Aucun enregistrement TXT trouvé à _acme-challenge.example.com"},
+		// Patterns must match the whole detail
+		{"fr", "No such authorization, really", "", "No such authorization, really"},
+		// Clients that prefer English get English
+		{"en-US, fr", "No such authorization", "", "No such authorization"},
+		{"es", "No such authorization", "", "No such authorization"},
+		{"", "No such authorization", "", "No such authorization"},
+	}
+	for _, tc := range testCases {
+		prob := probs.NotFound("%s", tc.detail)
+		lang := c.Localize(prob, tc.acceptLanguage)
+		test.AssertEquals(t, lang, tc.lang)
+		test.AssertEquals(t, prob.Detail, tc.translated)
+		test.AssertEquals(t, prob.Type, probs.MalformedProblem)
+	}
+
+	// Subproblems are translated too
+	prob := probs.RejectedIdentifier("Error creating new order")
+	prob.SubProblems = []*probs.ProblemDetails{probs.Unauthorized("No TXT record found at example.com")}
+	test.AssertEquals(t, c.Localize(prob, "fr"), "fr")
+	test.AssertEquals(t, prob.Detail, "Error creating new order")
+	test.AssertEquals(t, prob.SubProblems[0].Detail, "Aucun enregistrement TXT trouvé à example.com")
+
+	// A nil catalog translates nothing
+	var nilCatalog *ProblemCatalog
+	prob = probs.NotFound("No such authorization")
+	test.AssertEquals(t, nilCatalog.Localize(prob, "fr"), "")
+	test.AssertEquals(t, prob.Detail, "No such authorization")
+}
+
+func TestProblemCatalogLoad(t *testing.T) {
+	c := NewProblemCatalog()
+	err := c.load([]byte(testProblemCatalog))
+	test.AssertNotError(t, err, "loading problem catalog failed")
+
+	// An invalid file leaves the loaded catalog in effect
+	for _, invalid := range []string{
+		"fr: [{pattern: '(', detail: x}]\n",
+		"fr: [{pattern: x}]\n",
+		"'*': [{pattern: x, detail: y}]\n",
+		"fr: [\n",
+	} {
+		err = c.load([]byte(invalid))
+		test.AssertError(t, err, "invalid problem catalog loaded: "+invalid)
+	}
+	prob := probs.NotFound("No such authorization")
+	test.AssertEquals(t, c.Localize(prob, "de"), "de")
+}
+
+func TestSendErrorLocalized(t *testing.T) {
+	c := NewProblemCatalog()
+	err := c.load([]byte(testProblemCatalog))
+	test.AssertNotError(t, err, "loading problem catalog failed")
+
+	recorder := httptest.NewRecorder()
+	logEvent := &RequestEvent{AcceptLanguage: "fr"}
+	SendError(blog.NewMock(), probs.V2ErrorNS, c, recorder, logEvent, probs.NotFound("No such authorization"), nil)
+	test.AssertEquals(t, recorder.Header().Get("Content-Language"), "fr")
+	var prob probs.ProblemDetails
+	err = json.Unmarshal(recorder.Body.Bytes(), &prob)
+	test.AssertNotError(t, err, "unmarshaling problem")
+	test.AssertEquals(t, prob.Detail, "Cette autorisation n'existe pas")
+	test.AssertEquals(t, string(prob.Type), probs.V2ErrorNS+string(probs.MalformedProblem))
+	// The log keeps the English detail
+	test.AssertContains(t, logEvent.Error, "No such authorization")
+
+	recorder = httptest.NewRecorder()
+	SendError(blog.NewMock(), probs.V2ErrorNS, c, recorder, &RequestEvent{}, probs.NotFound("No such authorization"), nil)
+	test.AssertEquals(t, recorder.Header().Get("Content-Language"), "")
+}
@@ -0,0 +1,114 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/probs"
+	"github.com/letsencrypt/boulder/reloader"
+)
+
+// defaultMaintenanceRetryAfter is how long clients are told to wait before
+// retrying during maintenance, if the maintenance file doesn't say
+const defaultMaintenanceRetryAfter = 5 * time.Minute
+
+// maintenanceState is the contents of a maintenance file, which is a JSON
+// object like {"enabled": true, "reason": "database upgrade", "retryAfter":
+// "30m"}. The reason is included in the problems returned to clients, and
+// retryAfter is sent to them in a Retry-After header.
+type maintenanceState struct {
+	Enabled    bool   `json:"enabled"`
+	Reason     string `json:"reason"`
+	RetryAfter string `json:"retryAfter"`
+}
+
+// Maintenance is a switch operators can flip during planned maintenance,
+// e.g. of the database, to make the WFEs refuse requests that write to it with
+// a 503 Service Unavailable response and a Retry-After header, rather than
+// failing them unpredictably. A nil *Maintenance is never in maintenance.
+type Maintenance struct {
+	log blog.Logger
+
+	mu         sync.RWMutex
+	enabled    bool
+	reason     string
+	retryAfter time.Duration
+}
+
+// NewMaintenance returns a Maintenance switch that isn't in maintenance until
+// it's set.
+func NewMaintenance(logger blog.Logger) *Maintenance {
+	return &Maintenance{log: logger}
+}
+
+// WatchFile loads the switch's state from file and reloads it whenever the
+// file changes.
+func (m *Maintenance) WatchFile(file string) error {
+	return reloader.Register(reloader.Section{
+		Name: "maintenance mode",
+		File: file,
+		Load: m.load,
+	})
+}
+
+func (m *Maintenance) load(contents []byte) error {
+	var st maintenanceState
+	dec := json.NewDecoder(bytes.NewReader(contents))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&st); err != nil {
+		return err
+	}
+	retryAfter := defaultMaintenanceRetryAfter
+	if st.RetryAfter != "" {
+		var err error
+		retryAfter, err = time.ParseDuration(st.RetryAfter)
+		if err != nil {
+			return err
+		}
+		if retryAfter <= 0 {
+			return errors.New("maintenance retryAfter must be positive")
+		}
+	}
+	m.Set(st.Enabled, st.Reason, retryAfter)
+	return nil
+}
+
+// Set starts maintenance, giving reason as the cause and telling clients to
+// retry after retryAfter, or ends it.
+func (m *Maintenance) Set(enabled bool, reason string, retryAfter time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if enabled && !m.enabled {
+		m.log.AuditInfof("Maintenance mode started: %s", reason)
+	} else if !enabled && m.enabled {
+		m.log.AuditInfo("Maintenance mode ended")
+	}
+	m.enabled, m.reason, m.retryAfter = enabled, reason, retryAfter
+}
+
+// Problem returns the problem to respond to a request that's refused because
+// of maintenance with, or nil if there's no maintenance in progress.
+func (m *Maintenance) Problem() *probs.ProblemDetails {
+	if m == nil {
+		return nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if !m.enabled {
+		return nil
+	}
+	var prob *probs.ProblemDetails
+	if m.reason == "" {
+		prob = probs.ServerInternal("The service is down for maintenance, retry later")
+	} else {
+		prob = probs.ServerInternal("The service is down for maintenance, retry later: %s", m.reason)
+	}
+	prob.HTTPStatus = http.StatusServiceUnavailable
+	prob.RetryAfter = m.retryAfter
+	return prob
+}
@@ -0,0 +1,50 @@
+package web
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/probs"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestMaintenanceProblem(t *testing.T) {
+	var nilMaintenance *Maintenance
+	test.Assert(t, nilMaintenance.Problem() == nil, "nil Maintenance returned a problem")
+
+	m := NewMaintenance(blog.NewMock())
+	test.Assert(t, m.Problem() == nil, "new Maintenance returned a problem")
+
+	m.Set(true, "database upgrade", time.Hour)
+	prob := m.Problem()
+	test.Assert(t, prob != nil, "Maintenance didn't return a problem")
+	test.AssertEquals(t, prob.Type, probs.ServerInternalProblem)
+	test.AssertEquals(t, prob.HTTPStatus, http.StatusServiceUnavailable)
+	test.AssertEquals(t, prob.RetryAfter, time.Hour)
+	test.AssertEquals(t, prob.Detail, "The service is down for maintenance, retry later: database upgrade")
+
+	m.Set(false, "", 0)
+	test.Assert(t, m.Problem() == nil, "Maintenance returned a problem after it ended")
+}
+
+func TestMaintenanceLoad(t *testing.T) {
+	m := NewMaintenance(blog.NewMock())
+	test.AssertNotError(t, m.load([]byte(`{"enabled": true}`)), "load failed")
+	prob := m.Problem()
+	test.Assert(t, prob != nil, "loaded Maintenance didn't return a problem")
+	test.AssertEquals(t, prob.RetryAfter, defaultMaintenanceRetryAfter)
+	test.AssertEquals(t, prob.Detail, "The service is down for maintenance, retry later")
+
+	// Invalid contents leave the current state in effect
+	test.AssertError(t, m.load([]byte(`{"enabled": false, "retryAfter": "soon"}`)), "load accepted an invalid duration")
+	test.AssertError(t, m.load([]byte(`{"enabled": false, "retryAfter": "-1m"}`)), "load accepted a negative duration")
+	test.AssertError(t, m.load([]byte(`{"enable": false}`)), "load accepted an unknown field")
+	test.Assert(t, m.Problem() != nil, "invalid contents changed the Maintenance")
+
+	test.AssertNotError(t, m.load([]byte(`{"enabled": true, "retryAfter": "90s"}`)), "load failed")
+	test.AssertEquals(t, m.Problem().RetryAfter, 90*time.Second)
+	test.AssertNotError(t, m.load([]byte(`{"enabled": false}`)), "load failed")
+	test.Assert(t, m.Problem() == nil, "Maintenance returned a problem after it ended")
+}
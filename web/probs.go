@@ -1,6 +1,9 @@
 package web
 
 import (
+	"net/http"
+
+	"github.com/letsencrypt/boulder/core"
 	berrors "github.com/letsencrypt/boulder/errors"
 	"github.com/letsencrypt/boulder/probs"
 )
@@ -14,7 +17,9 @@ func problemDetailsForBoulderError(err *berrors.BoulderError, msg string) *probs
 	case berrors.NotFound:
 		return probs.NotFound("%s :: %s", msg, err)
 	case berrors.RateLimit:
-		return probs.RateLimited("%s :: %s", msg, err)
+		prob := probs.RateLimited("%s :: %s", msg, err)
+		prob.RetryAfter = err.RetryAfter
+		return prob
 	case berrors.InternalServer:
 		// Internal server error messages may include sensitive data, so we do
 		// not include it.
@@ -23,14 +28,38 @@ func problemDetailsForBoulderError(err *berrors.BoulderError, msg string) *probs
 		return probs.RejectedIdentifier("%s :: %s", msg, err)
 	case berrors.InvalidEmail:
 		return probs.InvalidEmail("%s :: %s", msg, err)
+	case berrors.InvalidContact:
+		return probs.InvalidContact("%s :: %s", msg, err)
+	case berrors.ConnectionFailure:
+		return probs.ConnectionFailure("%s :: %s", msg, err)
 	case berrors.WrongAuthorizationState:
 		return probs.Malformed("%s :: %s", msg, err)
+	case berrors.Duplicate:
+		return probs.Conflict("%s :: %s", msg, err)
 	case berrors.CAA:
 		return probs.CAA("%s :: %s", msg, err)
+	case berrors.BadPublicKey:
+		return probs.BadPublicKey("%s :: %s", msg, err)
+	case berrors.BadCSR:
+		return probs.BadCSR("%s :: %s", msg, err)
+	case berrors.AlreadyRevoked:
+		return probs.AlreadyRevoked("%s :: %s", msg, err)
+	case berrors.BadRevocationReason:
+		return probs.BadRevocationReason("%s :: %s", msg, err)
+	case berrors.OrderNotReady:
+		return probs.OrderNotReady("%s :: %s", msg, err)
+	case berrors.UnsupportedIdentifier:
+		return probs.UnsupportedIdentifier("%s :: %s", msg, err)
 	case berrors.MissingSCTs:
 		// MissingSCTs are an internal server error, but with a specific error
 		// message related to the SCT problem
 		return probs.ServerInternal("%s :: %s", msg, "Unable to meet CA SCT embedding requirements")
+	case berrors.IssuanceDisabled:
+		// Issuance has been stopped by an operator and will resume later, so
+		// this is reported as the service being unavailable.
+		prob := probs.ServerInternal("%s :: %s", msg, err)
+		prob.HTTPStatus = http.StatusServiceUnavailable
+		return prob
 	default:
 		// Internal server error messages may include sensitive data, so we do
 		// not include it.
@@ -47,7 +76,15 @@ func ProblemDetailsForError(err error, msg string) *probs.ProblemDetails {
 	case *probs.ProblemDetails:
 		return e
 	case *berrors.BoulderError:
-		return problemDetailsForBoulderError(e, msg)
+		prob := problemDetailsForBoulderError(e, msg)
+		for _, subErr := range e.SubErrors {
+			// The subproblem's detail is the suberror's alone, since msg is
+			// already in the problem's.
+			subProb := problemDetailsForBoulderError(&berrors.BoulderError{Type: subErr.Type}, msg)
+			subProb.Detail = subErr.Detail
+			prob.WithSubProblems(subProb.WithIdentifier(string(core.IdentifierDNS), subErr.Name))
+		}
+		return prob
 	default:
 		// Internal server error messages may include sensitive data, so we do
 		// not include it.
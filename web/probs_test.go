@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	berrors "github.com/letsencrypt/boulder/errors"
 	"github.com/letsencrypt/boulder/probs"
@@ -35,7 +36,17 @@ func TestProblemDetailsFromError(t *testing.T) {
 		{berrors.NotFoundError(detailMsg), 404, probs.MalformedProblem, fullDetail},
 		{berrors.RateLimitError(detailMsg), 429, probs.RateLimitedProblem, fullDetail + ": see https://letsencrypt.org/docs/rate-limits/"},
 		{berrors.InvalidEmailError(detailMsg), 400, probs.InvalidEmailProblem, fullDetail},
+		{berrors.InvalidContactError(detailMsg), 400, probs.InvalidContactProblem, fullDetail},
 		{berrors.RejectedIdentifierError(detailMsg), 400, probs.RejectedIdentifierProblem, fullDetail},
+		{berrors.BadPublicKeyError(detailMsg), 400, probs.BadPublicKeyProblem, fullDetail},
+		{berrors.BadCSRError(detailMsg), 400, probs.BadCSRProblem, fullDetail},
+		{berrors.IssuanceDisabledError(detailMsg), 503, probs.ServerInternalProblem, fullDetail},
+		{berrors.ConnectionFailureError(detailMsg), 400, probs.ConnectionProblem, fullDetail},
+		{berrors.DuplicateError(detailMsg), 409, probs.MalformedProblem, fullDetail},
+		{berrors.AlreadyRevokedError(detailMsg), 400, probs.AlreadyRevokedProblem, fullDetail},
+		{berrors.BadRevocationReasonError(detailMsg), 400, probs.BadRevocationReasonProblem, fullDetail},
+		{berrors.OrderNotReadyError(detailMsg), 403, probs.OrderNotReadyProblem, fullDetail},
+		{berrors.UnsupportedIdentifierError(detailMsg), 400, probs.UnsupportedIdentifierProblem, fullDetail},
 	}
 	for _, c := range testCases {
 		p := ProblemDetailsForError(c.err, errMsg)
@@ -58,3 +69,29 @@ func TestProblemDetailsFromError(t *testing.T) {
 	p := ProblemDetailsForError(expected, "k")
 	test.AssertDeepEquals(t, expected, p)
 }
+
+func TestProblemDetailsRetryAfter(t *testing.T) {
+	p := ProblemDetailsForError(berrors.RateLimitErrorWithRetryAfter(time.Hour, "slow down"), "k")
+	test.AssertEquals(t, p.Type, probs.RateLimitedProblem)
+	test.AssertEquals(t, p.RetryAfter, time.Hour)
+
+	p = ProblemDetailsForError(berrors.RateLimitError("slow down"), "k")
+	test.AssertEquals(t, p.RetryAfter, time.Duration(0))
+}
+
+func TestProblemDetailsSubErrors(t *testing.T) {
+	p := ProblemDetailsForError(&berrors.BoulderError{
+		Type:   berrors.Malformed,
+		Detail: "bad names",
+		SubErrors: []berrors.SubBoulderError{
+			{Type: berrors.Malformed, Detail: "bad name", Name: "example.com"},
+			{Type: berrors.RejectedIdentifier, Detail: "forbidden name", Name: "example.net"},
+		},
+	}, "k")
+	test.AssertEquals(t, p.Type, probs.MalformedProblem)
+	test.AssertEquals(t, p.Detail, "k :: bad names")
+	test.AssertDeepEquals(t, p.SubProblems, []*probs.ProblemDetails{
+		probs.Malformed("bad name").WithIdentifier("dns", "example.com"),
+		probs.RejectedIdentifier("forbidden name").WithIdentifier("dns", "example.net"),
+	})
+}
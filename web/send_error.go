@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -17,11 +19,14 @@ import (
 //  - Adds both the external and the internal error to a RequestEvent.
 //  - If the ProblemDetails provided is a ServerInternalProblem, audit logs the
 //    internal error.
+//  - Translates the Detail field of the ProblemDetails into the language the
+//    client prefers, if catalog has a translation. The logs keep the English.
 //  - Prefixes the Type field of the ProblemDetails with a namespace.
 //  - Sends an HTTP response containing the error and an error code to the user.
 func SendError(
 	log blog.Logger,
 	namespace string,
+	catalog *ProblemCatalog,
 	response http.ResponseWriter,
 	logEvent *RequestEvent,
 	prob *probs.ProblemDetails,
@@ -38,10 +43,12 @@ func SendError(
 
 	// Only audit log internal errors so users cannot purposefully cause
 	// auditable events. Also, skip the audit log for deadline exceeded errors
-	// since we don't need to keep those long-term. Note that they are still
-	// included in the request logs.
+	// since we don't need to keep those long-term, and for the 503s sent
+	// while an operator has deliberately made the service unavailable. Note
+	// that they are still included in the request logs.
 	deadlineExceeded := ierr == context.DeadlineExceeded || grpc.Code(ierr) == codes.DeadlineExceeded
-	if prob.Type == probs.ServerInternalProblem && !deadlineExceeded {
+	unavailable := code == http.StatusServiceUnavailable
+	if prob.Type == probs.ServerInternalProblem && !deadlineExceeded && !unavailable {
 		if ierr != nil {
 			log.AuditErrf("Internal error - %s - %s", prob.Detail, ierr)
 		} else {
@@ -49,7 +56,8 @@ func SendError(
 		}
 	}
 
-	prob.Type = probs.ProblemType(namespace) + prob.Type
+	lang := catalog.Localize(prob, logEvent.AcceptLanguage)
+	prob.AddNamespace(namespace)
 	problemDoc, err := json.MarshalIndent(prob, "", "  ")
 	if err != nil {
 		log.AuditErrf("Could not marshal error message: %s - %+v", err, prob)
@@ -58,6 +66,15 @@ func SendError(
 
 	// Write the JSON problem response
 	response.Header().Set("Content-Type", "application/problem+json")
+	if lang != "" {
+		response.Header().Set("Content-Language", lang)
+	}
+	if prob.RetryAfter > 0 {
+		// Retry-After is in whole seconds, so round up to avoid telling the
+		// client to come back too early.
+		seconds := int64((prob.RetryAfter + time.Second - 1) / time.Second)
+		response.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	}
 	response.WriteHeader(code)
 	response.Write(problemDoc)
 }
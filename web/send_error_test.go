@@ -0,0 +1,37 @@
+package web
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	blog "github.com/letsencrypt/boulder/log"
+	"github.com/letsencrypt/boulder/probs"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func TestSendErrorRetryAfter(t *testing.T) {
+	prob := probs.RateLimited("slow down")
+	prob.RetryAfter = 90*time.Second + time.Millisecond
+	recorder := httptest.NewRecorder()
+	SendError(blog.NewMock(), probs.V2ErrorNS, nil, recorder, &RequestEvent{}, prob, nil)
+	test.AssertEquals(t, recorder.Code, 429)
+	test.AssertEquals(t, recorder.Header().Get("Retry-After"), "91")
+
+	recorder = httptest.NewRecorder()
+	SendError(blog.NewMock(), probs.V2ErrorNS, nil, recorder, &RequestEvent{}, probs.Malformed("bad"), nil)
+	test.AssertEquals(t, recorder.Header().Get("Retry-After"), "")
+}
+
+func TestSendErrorUnavailableNotAudited(t *testing.T) {
+	log := blog.NewMock()
+	prob := probs.ServerInternal("down for maintenance")
+	prob.HTTPStatus = 503
+	recorder := httptest.NewRecorder()
+	SendError(log, probs.V2ErrorNS, nil, recorder, &RequestEvent{}, prob, nil)
+	test.AssertEquals(t, recorder.Code, 503)
+	test.AssertEquals(t, len(log.GetAllMatching("Internal error")), 0)
+
+	SendError(log, probs.V2ErrorNS, nil, httptest.NewRecorder(), &RequestEvent{}, probs.ServerInternal("oops"), nil)
+	test.AssertEquals(t, len(log.GetAllMatching("Internal error")), 1)
+}
@@ -0,0 +1,176 @@
+package web
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmhodges/clock"
+)
+
+// defaultMaxClients is how many client IPs a Throttle tracks if its config
+// doesn't say.
+const defaultMaxClients = 100000
+
+// ThrottleConfig configures a Throttle.
+type ThrottleConfig struct {
+	// RequestsPerSecond is the sustained rate of requests allowed from each
+	// client IP. IPv6 clients are throttled by /64. If zero, nothing is
+	// throttled.
+	RequestsPerSecond float64
+	// Burst is how many requests a client IP can make at once before being
+	// held to RequestsPerSecond.
+	Burst int
+	// TrustedProxies are the CIDR ranges of the load balancers and proxies in
+	// front of the WFE. The X-Forwarded-For header is only believed when
+	// the request comes from one of them, and the client IP is the rightmost
+	// address in it that isn't a trusted proxy.
+	TrustedProxies []string
+	// MaxClients is the most client IPs tracked at once, to bound the memory
+	// used. Defaults to 100000.
+	MaxClients int
+}
+
+// Throttle limits the rate of requests from each client IP, independently of
+// the rate limits the RA enforces. It's meant to shed abusive clients at the
+// edge, before their requests cost the RA and SA anything. A nil *Throttle
+// allows every request.
+type Throttle struct {
+	clk        clock.Clock
+	rate       float64
+	burst      float64
+	maxClients int
+	trusted    []*net.IPNet
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// tokenBucket holds the number of requests a client can make right now, as
+// of updated.
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewThrottle returns a Throttle configured by config, or nil if config
+// doesn't enable throttling.
+func NewThrottle(clk clock.Clock, config ThrottleConfig) (*Throttle, error) {
+	if config.RequestsPerSecond == 0 {
+		return nil, nil
+	}
+	if config.RequestsPerSecond < 0 || config.Burst < 1 {
+		return nil, fmt.Errorf("throttle requestsPerSecond must be positive and burst at least 1")
+	}
+	t := &Throttle{
+		clk:        clk,
+		rate:       config.RequestsPerSecond,
+		burst:      float64(config.Burst),
+		maxClients: config.MaxClients,
+		buckets:    make(map[string]*tokenBucket),
+	}
+	if t.maxClients == 0 {
+		t.maxClients = defaultMaxClients
+	}
+	for _, cidr := range config.TrustedProxies {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy range: %s", err)
+		}
+		t.trusted = append(t.trusted, ipNet)
+	}
+	return t, nil
+}
+
+// Allow reports whether the request's client IP may make another request. If
+// not it also returns how long until it may.
+func (t *Throttle) Allow(request *http.Request) (bool, time.Duration) {
+	if t == nil {
+		return true, 0
+	}
+	ip := t.ClientIP(request)
+	if ip == nil {
+		return true, 0
+	}
+	key := ip.String()
+	if ip.To4() == nil {
+		key = ip.Mask(net.CIDRMask(64, 128)).String()
+	}
+
+	now := t.clk.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b, ok := t.buckets[key]
+	if ok {
+		b.tokens = math.Min(t.burst, b.tokens+now.Sub(b.updated).Seconds()*t.rate)
+		b.updated = now
+	} else {
+		b = &tokenBucket{tokens: t.burst, updated: now}
+		if len(t.buckets) >= t.maxClients {
+			t.sweep(now)
+		}
+		// If every tracked client is still throttled there's no room for
+		// another, so this one goes untracked.
+		if len(t.buckets) < t.maxClients {
+			t.buckets[key] = b
+		}
+	}
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / t.rate * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep forgets the clients whose buckets have refilled, since a new bucket
+// for them would be the same. t.mu must be held.
+func (t *Throttle) sweep(now time.Time) {
+	for key, b := range t.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*t.rate >= t.burst {
+			delete(t.buckets, key)
+		}
+	}
+}
+
+// ClientIP returns the IP address of the client that made a request: the
+// address at the other end of the connection, unless it's a trusted proxy, in
+// which case the address the proxy says it forwarded the request for. It
+// returns nil if the address can't be parsed.
+func (t *Throttle) ClientIP(request *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return nil
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !t.isTrusted(ip) {
+		return ip
+	}
+	// Each proxy appends the address it received the request from, so the
+	// client is the rightmost address that isn't one of our own proxies.
+	forwarded := strings.Split(strings.Join(request.Header["X-Forwarded-For"], ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !t.isTrusted(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+func (t *Throttle) isTrusted(ip net.IP) bool {
+	for _, ipNet := range t.trusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package web
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jmhodges/clock"
+	"github.com/letsencrypt/boulder/test"
+)
+
+func requestFrom(remoteAddr string, forwardedFor ...string) *http.Request {
+	req := &http.Request{RemoteAddr: remoteAddr, Header: http.Header{}}
+	for _, f := range forwardedFor {
+		req.Header.Add("X-Forwarded-For", f)
+	}
+	return req
+}
+
+func TestNewThrottle(t *testing.T) {
+	clk := clock.NewFake()
+	throttle, err := NewThrottle(clk, ThrottleConfig{})
+	test.AssertNotError(t, err, "NewThrottle failed")
+	test.Assert(t, throttle == nil, "throttle without a rate wasn't nil")
+	ok, _ := throttle.Allow(requestFrom("10.0.0.1:1234"))
+	test.Assert(t, ok, "nil throttle refused a request")
+
+	_, err = NewThrottle(clk, ThrottleConfig{RequestsPerSecond: 1})
+	test.AssertError(t, err, "throttle without a burst was accepted")
+	_, err = NewThrottle(clk, ThrottleConfig{RequestsPerSecond: 1, Burst: 1, TrustedProxies: []string{"10.0.0.1"}})
+	test.AssertError(t, err, "throttle with an invalid trusted proxy range was accepted")
+}
+
+func TestThrottleAllow(t *testing.T) {
+	clk := clock.NewFake()
+	throttle, err := NewThrottle(clk, ThrottleConfig{RequestsPerSecond: 0.5, Burst: 2})
+	test.AssertNotError(t, err, "NewThrottle failed")
+
+	// A client can make Burst requests at once
+	for i := 0; i < 2; i++ {
+		ok, _ := throttle.Allow(requestFrom("10.0.0.1:1234"))
+		test.Assert(t, ok, "request within the burst was refused")
+	}
+	ok, wait := throttle.Allow(requestFrom("10.0.0.1:1234"))
+	test.Assert(t, !ok, "request over the burst was allowed")
+	test.AssertEquals(t, wait, 2*time.Second)
+
+	// Other clients aren't affected
+	ok, _ = throttle.Allow(requestFrom("10.0.0.2:1234"))
+	test.Assert(t, ok, "request from another client was refused")
+
+	// IPv6 clients are throttled by /64
+	for i := 0; i < 2; i++ {
+		ok, _ = throttle.Allow(requestFrom("[2001:db8::1]:1234"))
+		test.Assert(t, ok, "request within the burst was refused")
+	}
+	ok, _ = throttle.Allow(requestFrom("[2001:db8::2]:1234"))
+	test.Assert(t, !ok, "request from the same /64 over the burst was allowed")
+
+	// Once the tokens are refilled the client can make requests again
+	clk.Add(2 * time.Second)
+	ok, _ = throttle.Allow(requestFrom("10.0.0.1:1234"))
+	test.Assert(t, ok, "request after waiting was refused")
+	ok, _ = throttle.Allow(requestFrom("10.0.0.1:1234"))
+	test.Assert(t, !ok, "second request after waiting was allowed")
+}
+
+func TestThrottleMaxClients(t *testing.T) {
+	clk := clock.NewFake()
+	throttle, err := NewThrottle(clk, ThrottleConfig{RequestsPerSecond: 1, Burst: 1, MaxClients: 1})
+	test.AssertNotError(t, err, "NewThrottle failed")
+
+	ok, _ := throttle.Allow(requestFrom("10.0.0.1:1234"))
+	test.Assert(t, ok, "first request was refused")
+	// There's no room to track a second client while the first is throttled
+	ok, _ = throttle.Allow(requestFrom("10.0.0.2:1234"))
+	test.Assert(t, ok, "untracked client was refused")
+	ok, _ = throttle.Allow(requestFrom("10.0.0.2:1234"))
+	test.Assert(t, ok, "untracked client was refused")
+	test.AssertEquals(t, len(throttle.buckets), 1)
+
+	// Once the first client's bucket refills it's forgotten, making room
+	clk.Add(time.Second)
+	ok, _ = throttle.Allow(requestFrom("10.0.0.2:1234"))
+	test.Assert(t, ok, "first request from second client was refused")
+	ok, _ = throttle.Allow(requestFrom("10.0.0.2:1234"))
+	test.Assert(t, !ok, "second request from second client was allowed")
+}
+
+func TestThrottleClientIP(t *testing.T) {
+	throttle, err := NewThrottle(clock.NewFake(), ThrottleConfig{
+		RequestsPerSecond: 1,
+		Burst:             1,
+		TrustedProxies:    []string{"10.0.0.0/8", "fd00::/8"},
+	})
+	test.AssertNotError(t, err, "NewThrottle failed")
+
+	testCases := []struct {
+		name     string
+		request  *http.Request
+		expected string
+	}{
+		{"direct", requestFrom("192.0.2.1:1234"), "192.0.2.1"},
+		{"untrusted proxy", requestFrom("192.0.2.1:1234", "198.51.100.1"), "192.0.2.1"},
+		{"trusted proxy", requestFrom("10.0.0.1:1234", "198.51.100.1"), "198.51.100.1"},
+		{"spoofed header", requestFrom("10.0.0.1:1234", "203.0.113.1, 198.51.100.1"), "198.51.100.1"},
+		{"chained proxies", requestFrom("10.0.0.1:1234", "198.51.100.1", "10.0.0.2"), "198.51.100.1"},
+		{"IPv6 proxy", requestFrom("[fd00::1]:1234", "2001:db8::1"), "2001:db8::1"},
+		{"garbage header", requestFrom("10.0.0.1:1234", "junk"), "10.0.0.1"},
+		{"no header", requestFrom("10.0.0.1:1234"), "10.0.0.1"},
+		{"bad remote address", requestFrom("junk"), "<nil>"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test.AssertEquals(t, throttle.ClientIP(tc.request).String(), tc.expected)
+		})
+	}
+}
@@ -57,6 +57,15 @@ const (
 	rolloverPath   = "/acme/key-change"
 )
 
+const (
+	// directoryCacheLifetime is how long a rendered directory is served
+	// before it's rendered again with a new random key
+	directoryCacheLifetime = 10 * time.Minute
+	// maxCachedDirectories bounds the number of Host and X-Forwarded-Proto
+	// combinations whose directories are cached
+	maxCachedDirectories = 100
+)
+
 // WebFrontEndImpl provides all the logic for Boulder's web-facing interface,
 // i.e., ACME.  Its members configure the paths for various ACME functions,
 // plus a few other data items used in ACME.  Its methods are primarily handlers
@@ -86,6 +95,11 @@ type WebFrontEndImpl struct {
 	// "website" field.
 	DirectoryWebsite string
 
+	// directoryCache holds rendered directories, keyed by the URL they're
+	// relative to, the meta entries above and whether the client is
+	// intolerant of directory changes
+	directoryCache *web.ResponseCache
+
 	// Register of anti-replay nonces
 	nonceService *nonce.NonceService
 
@@ -101,6 +115,14 @@ type WebFrontEndImpl struct {
 	AcceptRevocationReason bool
 	AllowAuthzDeactivation bool
 
+	// Maintenance, if set, makes the WFE refuse POST requests with a 503
+	// while it's in maintenance.
+	Maintenance *web.Maintenance
+
+	// ProblemCatalog, if set, translates the detail of problem documents
+	// into the language clients ask for with Accept-Language.
+	ProblemCatalog *web.ProblemCatalog
+
 	csrSignatureAlgs *prometheus.CounterVec
 }
 
@@ -132,6 +154,7 @@ func NewWebFrontEndImpl(
 		stats:            stats,
 		keyPolicy:        keyPolicy,
 		csrSignatureAlgs: csrSignatureAlgs,
+		directoryCache:   web.NewResponseCache(clk, directoryCacheLifetime, maxCachedDirectories),
 	}, nil
 }
 
@@ -199,6 +222,15 @@ func (wfe *WebFrontEndImpl) HandleFunc(mux *http.ServeMux, pattern string, h web
 				return
 			}
 
+			// During maintenance, refuse every POST, since they all write to
+			// the database or need it to look up the requesting account
+			if request.Method == "POST" {
+				if prob := wfe.Maintenance.Problem(); prob != nil {
+					wfe.sendError(response, logEvent, prob, nil)
+					return
+				}
+			}
+
 			wfe.setCORSHeaders(response, request, "")
 
 			timeout := wfe.RequestTimeout
@@ -341,8 +373,41 @@ func addRequesterHeader(w http.ResponseWriter, requester int64) {
 
 // Directory is an HTTP request handler that provides the directory
 // object stored in the WFE's DirectoryEndpoints member with paths prefixed
-// using the `request.Host` of the HTTP request.
+// using the `request.Host` of the HTTP request. Rendered directories are
+// cached, and clients can make conditional requests for them.
 func (wfe *WebFrontEndImpl) Directory(ctx context.Context, logEvent *web.RequestEvent, response http.ResponseWriter, request *http.Request) {
+	// Versions of Certbot pre-0.6.0 (named LetsEncryptPythonClient at the time) break when they
+	// encounter a directory containing elements they don't expect so we gate
+	// adding new directory fields for clients matching this UA.
+	clientDirChangeIntolerant := strings.HasPrefix(request.UserAgent(), "LetsEncryptPythonClient")
+
+	response.Header().Set("Content-Type", "application/json")
+
+	key := strings.Join([]string{
+		web.RelativeEndpoint(request, ""),
+		wfe.SubscriberAgreementURL,
+		wfe.DirectoryCAAIdentity,
+		wfe.DirectoryWebsite,
+		strconv.FormatBool(clientDirChangeIntolerant),
+	}, "\x00")
+	dir, err := wfe.directoryCache.Get(key, func() ([]byte, error) {
+		return wfe.renderDirectory(request, clientDirChangeIntolerant)
+	})
+	if err != nil {
+		marshalProb := probs.ServerInternal("unable to marshal JSON directory")
+		wfe.sendError(response, logEvent, marshalProb, nil)
+		return
+	}
+
+	if web.CheckNotModified(response, request, dir.ETag, dir.Modified) {
+		return
+	}
+	response.Write(dir.Body)
+}
+
+// renderDirectory returns the JSON directory relative to request. Clients
+// that are intolerant of directory changes get the original directory.
+func (wfe *WebFrontEndImpl) renderDirectory(request *http.Request, clientDirChangeIntolerant bool) ([]byte, error) {
 	directoryEndpoints := map[string]interface{}{
 		"new-reg":     newRegPath,
 		"new-authz":   newAuthzPath,
@@ -350,10 +415,6 @@ func (wfe *WebFrontEndImpl) Directory(ctx context.Context, logEvent *web.Request
 		"revoke-cert": revokeCertPath,
 	}
 
-	// Versions of Certbot pre-0.6.0 (named LetsEncryptPythonClient at the time) break when they
-	// encounter a directory containing elements they don't expect so we gate
-	// adding new directory fields for clients matching this UA.
-	clientDirChangeIntolerant := strings.HasPrefix(request.UserAgent(), "LetsEncryptPythonClient")
 	if !clientDirChangeIntolerant {
 		directoryEndpoints["key-change"] = rolloverPath
 	}
@@ -386,16 +447,7 @@ func (wfe *WebFrontEndImpl) Directory(ctx context.Context, logEvent *web.Request
 		directoryEndpoints["meta"] = metaMap
 	}
 
-	response.Header().Set("Content-Type", "application/json")
-
-	relDir, err := wfe.relativeDirectory(request, directoryEndpoints)
-	if err != nil {
-		marshalProb := probs.ServerInternal("unable to marshal JSON directory")
-		wfe.sendError(response, logEvent, marshalProb, nil)
-		return
-	}
-
-	response.Write(relDir)
+	return wfe.relativeDirectory(request, directoryEndpoints)
 }
 
 const (
@@ -535,13 +587,17 @@ func (wfe *WebFrontEndImpl) verifyPOST(ctx context.Context, logEvent *web.Reques
 	logEvent.Payload = string(payload)
 
 	// Check that the request has a known anti-replay nonce
-	nonce := parsedJws.Signatures[0].Header.Nonce
-	if len(nonce) == 0 {
+	jwsNonce := parsedJws.Signatures[0].Header.Nonce
+	if len(jwsNonce) == 0 {
 		wfe.stats.Inc("Errors.JWSMissingNonce", 1)
 		return nil, nil, reg, probs.BadNonce("JWS has no anti-replay nonce")
-	} else if !wfe.nonceService.Valid(nonce) {
+	} else if err := wfe.nonceService.Redeem(jwsNonce); err != nil {
 		wfe.stats.Inc("Errors.JWSInvalidNonce", 1)
-		return nil, nil, reg, probs.BadNonce("JWS has invalid anti-replay nonce %s", nonce)
+		if badNonce, ok := err.(*nonce.BadNonceError); ok {
+			logEvent.Extra["BadNonceCause"] = badNonce.Cause
+			logEvent.Extra["BadNonceAge"] = badNonce.Age.String()
+		}
+		return nil, nil, reg, probs.BadNonce("JWS has invalid anti-replay nonce %s", jwsNonce)
 	}
 
 	// Check that the "resource" field is present and has the correct value
@@ -566,8 +622,23 @@ func (wfe *WebFrontEndImpl) verifyPOST(ctx context.Context, logEvent *web.Reques
 
 // sendError wraps web.SendError
 func (wfe *WebFrontEndImpl) sendError(response http.ResponseWriter, logEvent *web.RequestEvent, prob *probs.ProblemDetails, ierr error) {
+	// ACME v1 doesn't define the badPublicKey problem type, which the RA
+	// returns for unacceptable keys, so they're reported as malformed.
+	if prob.Type == probs.BadPublicKeyProblem {
+		prob = probs.Malformed("%s", prob.Detail)
+	}
 	wfe.stats.Inc(fmt.Sprintf("HTTP.ProblemTypes.%s", prob.Type), 1)
-	web.SendError(wfe.log, probs.V1ErrorNS, response, logEvent, prob, ierr)
+	web.SendError(wfe.log, probs.V1ErrorNS, wfe.ProblemCatalog, response, logEvent, prob, ierr)
+}
+
+// v1ContactProblem returns prob, unless it's an invalidContact problem, which
+// ACME v1 doesn't define, in which case it returns an invalidEmail problem
+// with the same detail instead.
+func v1ContactProblem(prob *probs.ProblemDetails) *probs.ProblemDetails {
+	if prob.Type != probs.InvalidContactProblem {
+		return prob
+	}
+	return probs.InvalidEmail("%s", prob.Detail)
 }
 
 func link(url, relation string) string {
@@ -624,7 +695,7 @@ func (wfe *WebFrontEndImpl) NewRegistration(ctx context.Context, logEvent *web.R
 
 	reg, err := wfe.RA.NewRegistration(ctx, init)
 	if err != nil {
-		wfe.sendError(response, logEvent, web.ProblemDetailsForError(err, "Error creating new registration"), err)
+		wfe.sendError(response, logEvent, v1ContactProblem(web.ProblemDetailsForError(err, "Error creating new registration")), err)
 		return
 	}
 	logEvent.Requester = reg.ID
@@ -1174,7 +1245,7 @@ func (wfe *WebFrontEndImpl) postChallenge(
 
 	// assumption: PerformValidation does not modify order of challenges
 	challenge := returnAuthz.Challenges[challengeIndex]
-	wfe.prepChallengeForDisplay(request, authz, &challenge)
+	wfe.prepChallengeForDisplay(request, returnAuthz, &challenge)
 
 	authzURL := web.RelativeEndpoint(request, authzPath+string(authz.ID))
 	response.Header().Add("Location", challenge.URI)
@@ -1263,7 +1334,7 @@ func (wfe *WebFrontEndImpl) Registration(ctx context.Context, logEvent *web.Requ
 
 	updatedReg, err := wfe.RA.UpdateRegistration(ctx, currReg, update)
 	if err != nil {
-		wfe.sendError(response, logEvent, web.ProblemDetailsForError(err, "Unable to update registration"), err)
+		wfe.sendError(response, logEvent, v1ContactProblem(web.ProblemDetailsForError(err, "Unable to update registration")), err)
 		return
 	}
 
@@ -1417,6 +1488,11 @@ func (wfe *WebFrontEndImpl) Certificate(ctx context.Context, logEvent *web.Reque
 	response.Header().Set("Content-Type", "application/pkix-cert")
 	relativeIssuerPath := web.RelativeEndpoint(request, issuerPath)
 	response.Header().Add("Link", link(relativeIssuerPath, "up"))
+	// Certificates never change, so their ETag and Last-Modified never do
+	etag := web.NewCachedResponse(cert.DER, time.Time{}).ETag
+	if web.CheckNotModified(response, request, etag, cert.Issued) {
+		return
+	}
 	response.WriteHeader(http.StatusOK)
 	if _, err = response.Write(cert.DER); err != nil {
 		wfe.log.Warningf("Could not write response: %s", err)
@@ -25,6 +25,7 @@ import (
 	"github.com/jmhodges/clock"
 	"github.com/letsencrypt/boulder/core"
 	corepb "github.com/letsencrypt/boulder/core/proto"
+	"github.com/letsencrypt/boulder/csr"
 	"github.com/letsencrypt/boulder/ctpolicy"
 	berrors "github.com/letsencrypt/boulder/errors"
 	"github.com/letsencrypt/boulder/features"
@@ -267,6 +268,14 @@ func (ra *MockRegistrationAuthority) FinalizeOrder(ctx context.Context, _ *rapb.
 	return nil, nil
 }
 
+func (ra *MockRegistrationAuthority) CheckIssuance(ctx context.Context, _ *rapb.CheckIssuanceRequest) ([]*probs.ProblemDetails, error) {
+	return nil, nil
+}
+
+func (ra *MockRegistrationAuthority) RateLimitUsage(ctx context.Context, _ *rapb.RateLimitUsageRequest) (*rapb.RateLimitUsageResponse, error) {
+	return &rapb.RateLimitUsageResponse{}, nil
+}
+
 type mockPA struct{}
 
 func (pa *mockPA) ChallengesFor(identifier core.AcmeIdentifier, registrationID int64, revalidation bool) (challenges []core.Challenge, combinations [][]int, err error) {
@@ -281,6 +290,14 @@ func (pa *mockPA) WillingToIssueWildcard(id core.AcmeIdentifier) error {
 	return nil
 }
 
+func (pa *mockPA) WillingToIssueExempt(id core.AcmeIdentifier, exemptEntry string) error {
+	return nil
+}
+
+func (pa *mockPA) CheckOrderNames(names []string) error {
+	return nil
+}
+
 func (pa *mockPA) ChallengeTypeEnabled(t string, registrationID int64) bool {
 	return true
 }
@@ -885,6 +902,16 @@ func TestRelativeDirectory(t *testing.T) {
 	}
 }
 
+// mockSACertKeyNotAccountKey is a mock SA that finds no account for any key,
+// so the RA accepts any CSR key that isn't the requesting account's key.
+type mockSACertKeyNotAccountKey struct {
+	core.StorageAuthority
+}
+
+func (sa *mockSACertKeyNotAccountKey) GetRegistrationByKey(ctx context.Context, jwk *jose.JSONWebKey) (core.Registration, error) {
+	return core.Registration{}, berrors.NotFoundError("not found")
+}
+
 // TODO: Write additional test cases for:
 //  - RA returns with a failure
 func TestIssueCertificate(t *testing.T) {
@@ -914,7 +941,7 @@ func TestIssueCertificate(t *testing.T) {
 		stats,
 		0,
 		testKeyPolicy,
-		100,
+		csr.NameLimits{Default: 100},
 		true,
 		false,
 		300*24*time.Hour,
@@ -926,7 +953,7 @@ func TestIssueCertificate(t *testing.T) {
 		nil,
 		nil,
 	)
-	ra.SA = mocks.NewStorageAuthority(fc)
+	ra.SA = &mockSACertKeyNotAccountKey{mocks.NewStorageAuthority(fc)}
 	ra.CA = &mocks.MockCA{
 		PEM: mockCertPEM,
 	}
@@ -2275,6 +2302,19 @@ func TestBadKeyCSR(t *testing.T) {
 		`{"type":"`+probs.V1ErrorNS+`malformed","detail":"Invalid key in certificate request :: key too small: 512","status":400}`)
 }
 
+func TestBadPublicKeyFromRA(t *testing.T) {
+	wfe, _ := setupWFE(t)
+	responseWriter := httptest.NewRecorder()
+
+	// ACME v1 doesn't define badPublicKey, so the RA's BadPublicKey errors are
+	// reported as malformed
+	err := berrors.BadPublicKeyError("invalid public key in CSR: key too small: 512")
+	wfe.sendError(responseWriter, newRequestEvent(), web.ProblemDetailsForError(err, "Error creating new cert"), err)
+	test.AssertUnmarshaledEquals(t,
+		responseWriter.Body.String(),
+		`{"type":"`+probs.V1ErrorNS+`malformed","detail":"Error creating new cert :: invalid public key in CSR: key too small: 512","status":400}`)
+}
+
 // This uses httptest.NewServer because ServeMux.ServeHTTP won't prevent the
 // body from being sent like the net/http Server's actually do.
 func TestGetCertificateHEADHasCorrectBodyLength(t *testing.T) {
@@ -2696,3 +2736,12 @@ func TestChallengeNewIDScheme(t *testing.T) {
 			tc.expected)
 	}
 }
+
+func TestV1ContactProblem(t *testing.T) {
+	prob := v1ContactProblem(probs.InvalidContact("contact emails @mailinator.com can't be used"))
+	test.AssertEquals(t, prob.Type, probs.InvalidEmailProblem)
+	test.AssertEquals(t, prob.Detail, "contact emails @mailinator.com can't be used")
+
+	malformed := probs.Malformed("bad")
+	test.AssertEquals(t, v1ContactProblem(malformed), malformed)
+}
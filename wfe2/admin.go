@@ -0,0 +1,238 @@
+package wfe2
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/probs"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+	"github.com/letsencrypt/boulder/web"
+)
+
+const (
+	adminCertificatesPath = "/admin/certificates"
+	// adminAccountsPath is followed by an account ID and
+	// adminAccountCertificatesSuffix.
+	adminAccountsPath              = "/admin/accounts/"
+	adminAccountCertificatesSuffix = "/certificates"
+)
+
+// AdminHandler returns an http.Handler for the endpoints used by operators,
+// rather than ACME clients. It must only be served on a TLS listener that
+// verifies client certificates, and it only serves clients whose certificate
+// has one of clientNames as a subjectAltName.
+func (wfe *WebFrontEndImpl) AdminHandler(clientNames []string) http.Handler {
+	accepted := make(map[string]bool, len(clientNames))
+	for _, name := range clientNames {
+		accepted[name] = true
+	}
+	m := http.NewServeMux()
+	m.Handle(adminCertificatesPath, wfe.adminEndpoint(adminCertificatesPath, "Certificate search", accepted, wfe.SearchCertificates))
+	m.Handle(adminAccountsPath, wfe.adminEndpoint(adminAccountsPath, "Account certificate listing", accepted, wfe.ListAccountCertificates))
+	return m
+}
+
+// adminEndpoint wraps the handler for an admin endpoint, only allowing GET
+// requests from clients with an accepted name, and audit logging each request
+// as an action by that client.
+func (wfe *WebFrontEndImpl) adminEndpoint(endpoint, action string, accepted map[string]bool, h web.WFEHandlerFunc) http.Handler {
+	return web.NewTopHandler(wfe.log,
+		web.WFEHandlerFunc(func(ctx context.Context, logEvent *web.RequestEvent, response http.ResponseWriter, request *http.Request) {
+			logEvent.Endpoint = endpoint
+			if request.Method != "GET" {
+				response.Header().Set("Allow", "GET")
+				wfe.sendError(response, logEvent, probs.MethodNotAllowed(), nil)
+				return
+			}
+			clientName := adminClientName(request, accepted)
+			if clientName == "" {
+				wfe.sendError(response, logEvent, probs.Unauthorized("A client certificate for an operator is required"), nil)
+				return
+			}
+			logEvent.Extra["AdminClient"] = clientName
+			wfe.log.AuditInfof("%s by %q: %s", action, clientName, request.URL.RequestURI())
+			h(ctx, logEvent, response, request)
+		}))
+}
+
+// adminClientName returns the first of the accepted names in the request's
+// verified client certificate, or "" if it has none of them.
+func adminClientName(request *http.Request, accepted map[string]bool) string {
+	if request.TLS == nil || len(request.TLS.VerifiedChains) == 0 {
+		return ""
+	}
+	for _, name := range request.TLS.VerifiedChains[0][0].DNSNames {
+		if accepted[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+// certificateSearchResult is a certificate in the response to a certificate
+// search.
+type certificateSearchResult struct {
+	Serial         string     `json:"serial"`
+	RegistrationID int64      `json:"registrationID"`
+	Issued         time.Time  `json:"issued"`
+	Expires        time.Time  `json:"expires"`
+	DNSNames       []string   `json:"dnsNames"`
+	Status         string     `json:"status"`
+	RevokedDate    *time.Time `json:"revokedDate,omitempty"`
+	RevokedReason  *int64     `json:"revokedReason,omitempty"`
+}
+
+type certificateSearchResponse struct {
+	Certificates []certificateSearchResult `json:"certificates"`
+	// NextCursor is passed as the cursor parameter to get the next page of
+	// results. It is omitted from the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// SearchCertificates returns a page of the certificates matching exactly one
+// of the serial, fqdn, registeredDomain or spkiHash (the hex encoded SHA-256
+// hash of a SubjectPublicKeyInfo) query parameters. The limit and cursor
+// parameters control paging.
+func (wfe *WebFrontEndImpl) SearchCertificates(ctx context.Context, logEvent *web.RequestEvent, response http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+	req := &sapb.SearchCertificatesRequest{}
+	if serial := query.Get("serial"); serial != "" {
+		req.Serial = &serial
+	}
+	if fqdn := query.Get("fqdn"); fqdn != "" {
+		req.Fqdn = &fqdn
+	}
+	if domain := query.Get("registeredDomain"); domain != "" {
+		req.RegisteredDomain = &domain
+	}
+	if spkiHash := query.Get("spkiHash"); spkiHash != "" {
+		hash, err := hex.DecodeString(spkiHash)
+		if err != nil {
+			wfe.sendError(response, logEvent, probs.Malformed("spkiHash must be hex encoded"), err)
+			return
+		}
+		req.SpkiHash = hash
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || n <= 0 {
+			wfe.sendError(response, logEvent, probs.Malformed("limit must be a positive integer"), err)
+			return
+		}
+		req.Limit = &n
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		req.Cursor = &cursor
+	}
+	results, err := wfe.SA.SearchCertificates(ctx, req)
+	if err != nil {
+		wfe.sendError(response, logEvent, web.ProblemDetailsForError(err, "Error searching certificates"), err)
+		return
+	}
+	wfe.writeCertificateSearchResults(response, logEvent, results)
+}
+
+// ListAccountCertificates returns a page of the certificates of the account
+// whose ID follows adminAccountsPath, newest first. They can be filtered by
+// the status ("good" or "revoked"), expiresAfter and expiresBefore (RFC 3339
+// timestamps) and name (a substring of one of their DNS names) query
+// parameters. The limit and cursor parameters control paging. A page filtered
+// by name may be short, or even empty, while still having a next cursor.
+func (wfe *WebFrontEndImpl) ListAccountCertificates(ctx context.Context, logEvent *web.RequestEvent, response http.ResponseWriter, request *http.Request) {
+	idStr := strings.TrimPrefix(request.URL.Path, adminAccountsPath)
+	if !strings.HasSuffix(idStr, adminAccountCertificatesSuffix) {
+		wfe.sendError(response, logEvent, probs.NotFound("Unknown admin endpoint"), nil)
+		return
+	}
+	idStr = strings.TrimSuffix(idStr, adminAccountCertificatesSuffix)
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		wfe.sendError(response, logEvent, probs.Malformed("Invalid account ID"), err)
+		return
+	}
+	req, prob, err := listCertificatesRequest(request.URL.Query(), id)
+	if prob != nil {
+		wfe.sendError(response, logEvent, prob, err)
+		return
+	}
+	results, err := wfe.SA.ListCertificatesForAccount(ctx, req)
+	if err != nil {
+		wfe.sendError(response, logEvent, web.ProblemDetailsForError(err, "Error listing certificates"), err)
+		return
+	}
+	wfe.writeCertificateSearchResults(response, logEvent, results)
+}
+
+// listCertificatesRequest returns the request listing the certificates of the
+// account id, filtered and paged by the query parameters described for
+// ListAccountCertificates.
+func listCertificatesRequest(query url.Values, id int64) (*sapb.ListCertificatesForAccountRequest, *probs.ProblemDetails, error) {
+	req := &sapb.ListCertificatesForAccountRequest{RegistrationID: &id}
+	if status := query.Get("status"); status != "" {
+		req.Status = &status
+	}
+	for param, field := range map[string]**int64{
+		"expiresAfter":  &req.ExpiresAfter,
+		"expiresBefore": &req.ExpiresBefore,
+	} {
+		if value := query.Get(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return nil, probs.Malformed("%s must be an RFC 3339 timestamp", param), err
+			}
+			ns := t.UnixNano()
+			*field = &ns
+		}
+	}
+	if name := query.Get("name"); name != "" {
+		req.NameContains = &name
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || n <= 0 {
+			return nil, probs.Malformed("limit must be a positive integer"), err
+		}
+		req.Limit = &n
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		req.Cursor = &cursor
+	}
+	return req, nil, nil
+}
+
+// writeCertificateSearchResults writes a page of certificate search results
+// as the response.
+func (wfe *WebFrontEndImpl) writeCertificateSearchResults(response http.ResponseWriter, logEvent *web.RequestEvent, results *sapb.CertificateSearchResults) {
+	resp := certificateSearchResponse{Certificates: []certificateSearchResult{}}
+	for _, c := range results.Certificates {
+		result := certificateSearchResult{
+			Serial:         *c.Serial,
+			RegistrationID: *c.RegistrationID,
+			Issued:         time.Unix(0, *c.Issued).UTC(),
+			Expires:        time.Unix(0, *c.Expires).UTC(),
+			DNSNames:       c.DnsNames,
+			Status:         *c.Status,
+			RevokedReason:  c.RevokedReason,
+		}
+		if c.RevokedDate != nil {
+			revokedDate := time.Unix(0, *c.RevokedDate).UTC()
+			result.RevokedDate = &revokedDate
+		}
+		resp.Certificates = append(resp.Certificates, result)
+	}
+	if results.NextCursor != nil {
+		resp.NextCursor = *results.NextCursor
+	}
+
+	err := wfe.writeJsonResponse(response, logEvent, http.StatusOK, resp)
+	if err != nil {
+		wfe.sendError(response, logEvent, probs.ServerInternal("Error marshalling search results"), err)
+		return
+	}
+}
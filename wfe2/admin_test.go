@@ -0,0 +1,145 @@
+package wfe2
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/core"
+	berrors "github.com/letsencrypt/boulder/errors"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+	"github.com/letsencrypt/boulder/test"
+)
+
+type mockSASearch struct {
+	core.StorageGetter
+	req     *sapb.SearchCertificatesRequest
+	listReq *sapb.ListCertificatesForAccountRequest
+}
+
+func (msa *mockSASearch) ListCertificatesForAccount(_ context.Context, req *sapb.ListCertificatesForAccountRequest) (*sapb.CertificateSearchResults, error) {
+	msa.listReq = req
+	if req.Status != nil && *req.Status == "bad" {
+		return nil, berrors.MalformedError("invalid status")
+	}
+	return &sapb.CertificateSearchResults{}, nil
+}
+
+func (msa *mockSASearch) SearchCertificates(_ context.Context, req *sapb.SearchCertificatesRequest) (*sapb.CertificateSearchResults, error) {
+	msa.req = req
+	if req.Serial != nil && *req.Serial == "bad" {
+		return nil, berrors.MalformedError("invalid serial")
+	}
+	serial, regID, status := "000000000000000000000000000000000001", int64(1), string(core.OCSPStatusRevoked)
+	issued, expires, revoked, reason := int64(0), int64(time.Hour), int64(time.Minute), int64(1)
+	next := "cursor"
+	return &sapb.CertificateSearchResults{
+		Certificates: []*sapb.CertificateSearchResult{{
+			Serial:         &serial,
+			RegistrationID: &regID,
+			Issued:         &issued,
+			Expires:        &expires,
+			DnsNames:       []string{"example.com"},
+			Status:         &status,
+			RevokedDate:    &revoked,
+			RevokedReason:  &reason,
+		}},
+		NextCursor: &next,
+	}, nil
+}
+
+func TestAdminSearchCertificates(t *testing.T) {
+	wfe, _ := setupWFE(t)
+	sa := &mockSASearch{}
+	wfe.SA = sa
+	handler := wfe.AdminHandler([]string{"admin.boulder"})
+
+	search := func(query, clientName string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", adminCertificatesPath+"?"+query, nil)
+		req.TLS = &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{DNSNames: []string{clientName}}}},
+		}
+		responseWriter := httptest.NewRecorder()
+		handler.ServeHTTP(responseWriter, req)
+		return responseWriter
+	}
+
+	// Clients without an accepted name are refused
+	resp := search("fqdn=example.com", "wfe.boulder")
+	test.AssertEquals(t, resp.Code, http.StatusForbidden)
+	test.Assert(t, sa.req == nil, "SA was searched for an unauthorized client")
+
+	resp = search("fqdn=example.com&limit=10&cursor=abc", "admin.boulder")
+	test.AssertEquals(t, resp.Code, http.StatusOK)
+	test.AssertEquals(t, *sa.req.Fqdn, "example.com")
+	test.AssertEquals(t, *sa.req.Limit, int64(10))
+	test.AssertEquals(t, *sa.req.Cursor, "abc")
+	var body certificateSearchResponse
+	test.AssertNotError(t, json.Unmarshal(resp.Body.Bytes(), &body), "unmarshalling response")
+	test.AssertEquals(t, len(body.Certificates), 1)
+	test.AssertEquals(t, body.Certificates[0].Status, "revoked")
+	test.AssertEquals(t, body.Certificates[0].Expires, time.Unix(0, 0).Add(time.Hour).UTC())
+	test.AssertEquals(t, *body.Certificates[0].RevokedReason, int64(1))
+	test.AssertEquals(t, body.NextCursor, "cursor")
+
+	resp = search("spkiHash=00ff", "admin.boulder")
+	test.AssertEquals(t, resp.Code, http.StatusOK)
+	test.AssertDeepEquals(t, sa.req.SpkiHash, []byte{0, 255})
+
+	for _, query := range []string{"spkiHash=xyz", "fqdn=example.com&limit=-1", "serial=bad"} {
+		resp = search(query, "admin.boulder")
+		test.AssertEquals(t, resp.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAdminListAccountCertificates(t *testing.T) {
+	wfe, _ := setupWFE(t)
+	sa := &mockSASearch{}
+	wfe.SA = sa
+	handler := wfe.AdminHandler([]string{"admin.boulder"})
+
+	list := func(path, clientName string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.TLS = &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{DNSNames: []string{clientName}}}},
+		}
+		responseWriter := httptest.NewRecorder()
+		handler.ServeHTTP(responseWriter, req)
+		return responseWriter
+	}
+
+	resp := list("/admin/accounts/1/certificates", "wfe.boulder")
+	test.AssertEquals(t, resp.Code, http.StatusForbidden)
+	test.Assert(t, sa.listReq == nil, "SA was searched for an unauthorized client")
+
+	resp = list("/admin/accounts/1/certificates?status=good&expiresAfter=2019-01-01T00:00:00Z&expiresBefore=2019-02-01T00:00:00Z&name=example&limit=10&cursor=abc", "admin.boulder")
+	test.AssertEquals(t, resp.Code, http.StatusOK)
+	test.AssertEquals(t, *sa.listReq.RegistrationID, int64(1))
+	test.AssertEquals(t, *sa.listReq.Status, "good")
+	test.AssertEquals(t, *sa.listReq.ExpiresAfter, time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano())
+	test.AssertEquals(t, *sa.listReq.ExpiresBefore, time.Date(2019, 2, 1, 0, 0, 0, 0, time.UTC).UnixNano())
+	test.AssertEquals(t, *sa.listReq.NameContains, "example")
+	test.AssertEquals(t, *sa.listReq.Limit, int64(10))
+	test.AssertEquals(t, *sa.listReq.Cursor, "abc")
+	test.AssertEquals(t, resp.Body.String(), `{
+  "certificates": []
+}`)
+
+	test.AssertEquals(t, list("/admin/accounts/1", "admin.boulder").Code, http.StatusNotFound)
+	for _, path := range []string{
+		"/admin/accounts/x/certificates",
+		"/admin/accounts/0/certificates",
+		"/admin/accounts/1/certificates?expiresAfter=yesterday",
+		"/admin/accounts/1/certificates?limit=0",
+		"/admin/accounts/1/certificates?status=bad",
+	} {
+		resp = list(path, "admin.boulder")
+		test.AssertEquals(t, resp.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,307 @@
+package wfe2
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/core"
+	corepb "github.com/letsencrypt/boulder/core/proto"
+	bgrpc "github.com/letsencrypt/boulder/grpc"
+	"github.com/letsencrypt/boulder/probs"
+	rapb "github.com/letsencrypt/boulder/ra/proto"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+	"github.com/letsencrypt/boulder/web"
+)
+
+const (
+	portalCertificatesPath       = "/portal/certificates"
+	portalOrdersPath             = "/portal/orders"
+	portalRateLimitsPath         = "/portal/rate-limits"
+	portalValidationFailuresPath = "/portal/validation-failures"
+)
+
+// PortalHandler returns an http.Handler for the account portal: read-only
+// endpoints where subscribers can see their account's certificates, orders,
+// rate limit usage and recent validation failures. Every request is a
+// POST-as-GET signed with the account's key and URL, like an ACME request, so
+// the portal serves new-nonce too. Query parameters are part of the URL the
+// JWS signs. It's meant to be served on a listener of its own, and needs
+// AccountURLPrefix to be set if that's on a different host than the ACME API.
+func (wfe *WebFrontEndImpl) PortalHandler() http.Handler {
+	m := http.NewServeMux()
+	wfe.HandleFunc(m, newNoncePath, wfe.Nonce, "GET")
+	wfe.HandleFunc(m, portalCertificatesPath, wfe.PortalCertificates, "POST")
+	wfe.HandleFunc(m, portalOrdersPath, wfe.PortalOrders, "POST")
+	wfe.HandleFunc(m, portalRateLimitsPath, wfe.PortalRateLimits, "POST")
+	wfe.HandleFunc(m, portalValidationFailuresPath, wfe.PortalValidationFailures, "POST")
+	return m
+}
+
+// portalAccount authenticates a portal request, returning the requesting
+// account, or false if a problem has been sent instead.
+func (wfe *WebFrontEndImpl) portalAccount(ctx context.Context, logEvent *web.RequestEvent, response http.ResponseWriter, request *http.Request) (*core.Registration, bool) {
+	acct, prob := wfe.validPOSTAsGETForAccount(request, ctx, logEvent)
+	addRequesterHeader(response, logEvent.Requester)
+	if prob != nil {
+		wfe.sendError(response, logEvent, prob, nil)
+		return nil, false
+	}
+	return acct, true
+}
+
+// portalLimit returns the value of the limit query parameter, or nil if it's
+// unset.
+func portalLimit(request *http.Request) (*int64, *probs.ProblemDetails, error) {
+	limit := request.URL.Query().Get("limit")
+	if limit == "" {
+		return nil, nil, nil
+	}
+	n, err := strconv.ParseInt(limit, 10, 64)
+	if err != nil || n <= 0 {
+		return nil, probs.Malformed("limit must be a positive integer"), err
+	}
+	return &n, nil, nil
+}
+
+// PortalCertificates returns a page of the requesting account's certificates,
+// newest first, filtered and paged by the same query parameters as the admin
+// endpoint ListAccountCertificates.
+func (wfe *WebFrontEndImpl) PortalCertificates(ctx context.Context, logEvent *web.RequestEvent, response http.ResponseWriter, request *http.Request) {
+	acct, ok := wfe.portalAccount(ctx, logEvent, response, request)
+	if !ok {
+		return
+	}
+	req, prob, err := listCertificatesRequest(request.URL.Query(), acct.ID)
+	if prob != nil {
+		wfe.sendError(response, logEvent, prob, err)
+		return
+	}
+	results, err := wfe.SA.ListCertificatesForAccount(ctx, req)
+	if err != nil {
+		wfe.sendError(response, logEvent, web.ProblemDetailsForError(err, "Error listing certificates"), err)
+		return
+	}
+	wfe.writeCertificateSearchResults(response, logEvent, results)
+}
+
+// portalOrder is an order in the response to a PortalOrders request.
+type portalOrder struct {
+	ID                int64                 `json:"id"`
+	Status            core.AcmeStatus       `json:"status"`
+	Expires           time.Time             `json:"expires"`
+	Identifiers       []core.AcmeIdentifier `json:"identifiers"`
+	Error             *probs.ProblemDetails `json:"error,omitempty"`
+	CertificateSerial string                `json:"certificateSerial,omitempty"`
+}
+
+type portalOrdersResponse struct {
+	Orders []portalOrder `json:"orders"`
+	// NextCursor is passed as the cursor parameter to get the next page of
+	// orders. It is omitted from the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// PortalOrders returns a page of the requesting account's orders, newest
+// first. The limit and cursor query parameters control paging.
+func (wfe *WebFrontEndImpl) PortalOrders(ctx context.Context, logEvent *web.RequestEvent, response http.ResponseWriter, request *http.Request) {
+	acct, ok := wfe.portalAccount(ctx, logEvent, response, request)
+	if !ok {
+		return
+	}
+	req := &sapb.ListOrdersForAccountRequest{RegistrationID: &acct.ID}
+	limit, prob, err := portalLimit(request)
+	if prob != nil {
+		wfe.sendError(response, logEvent, prob, err)
+		return
+	}
+	req.Limit = limit
+	if cursor := request.URL.Query().Get("cursor"); cursor != "" {
+		req.Cursor = &cursor
+	}
+	list, err := wfe.SA.ListOrdersForAccount(ctx, req)
+	if err != nil {
+		wfe.sendError(response, logEvent, web.ProblemDetailsForError(err, "Error listing orders"), err)
+		return
+	}
+
+	resp := portalOrdersResponse{Orders: []portalOrder{}}
+	for _, order := range list.Orders {
+		resp.Orders = append(resp.Orders, wfe.portalOrder(order))
+	}
+	if list.NextCursor != nil {
+		resp.NextCursor = *list.NextCursor
+	}
+	err = wfe.writeJsonResponse(response, logEvent, http.StatusOK, resp)
+	if err != nil {
+		wfe.sendError(response, logEvent, probs.ServerInternal("Error marshalling orders"), err)
+		return
+	}
+}
+
+// portalOrder summarizes an order for the portal. Unlike orderToOrderJSON it
+// has no URLs, since the portal isn't served on the ACME API's host.
+func (wfe *WebFrontEndImpl) portalOrder(order *corepb.Order) portalOrder {
+	result := portalOrder{
+		ID:          *order.Id,
+		Status:      core.AcmeStatus(*order.Status),
+		Expires:     time.Unix(0, *order.Expires).UTC(),
+		Identifiers: make([]core.AcmeIdentifier, len(order.Names)),
+	}
+	for i, name := range order.Names {
+		result.Identifiers[i] = core.AcmeIdentifier{Type: core.IdentifierDNS, Value: name}
+	}
+	if order.Error != nil {
+		prob, err := bgrpc.PBToProblemDetails(order.Error)
+		if err != nil {
+			wfe.log.AuditErrf("Internal error converting order ID %d "+
+				"proto buf prob to problem details: %q", *order.Id, err)
+		} else {
+			prob.Type = probs.V2ErrorNS + prob.Type
+			result.Error = prob
+		}
+	}
+	if order.CertificateSerial != nil {
+		result.CertificateSerial = *order.CertificateSerial
+	}
+	return result
+}
+
+// portalRateLimit is a rate limit in the response to a PortalRateLimits
+// request.
+type portalRateLimit struct {
+	Limit     string `json:"limit"`
+	Key       string `json:"key,omitempty"`
+	Count     int64  `json:"count"`
+	Threshold int64  `json:"threshold"`
+	// Window is a Go duration string, e.g. "168h0m0s", omitted for limits on
+	// what's currently pending.
+	Window string `json:"window,omitempty"`
+}
+
+type portalRateLimitsResponse struct {
+	RateLimits []portalRateLimit `json:"rateLimits"`
+}
+
+// PortalRateLimits returns how much of each rate limit on the requesting
+// account it has used. If the names query parameter is set to a comma
+// separated list of DNS names, the limits on issuing a certificate for them
+// are included too.
+func (wfe *WebFrontEndImpl) PortalRateLimits(ctx context.Context, logEvent *web.RequestEvent, response http.ResponseWriter, request *http.Request) {
+	acct, ok := wfe.portalAccount(ctx, logEvent, response, request)
+	if !ok {
+		return
+	}
+	req := &rapb.RateLimitUsageRequest{RegistrationID: &acct.ID}
+	if names := request.URL.Query().Get("names"); names != "" {
+		req.Names = strings.Split(names, ",")
+		if prob := tooManyNamesProblem(req.Names, wfe.NameLimits.Max()); prob != nil {
+			wfe.sendError(response, logEvent, prob, nil)
+			return
+		}
+	}
+	usage, err := wfe.RA.RateLimitUsage(ctx, req)
+	if err != nil {
+		wfe.sendError(response, logEvent, web.ProblemDetailsForError(err, "Error getting rate limit usage"), err)
+		return
+	}
+
+	resp := portalRateLimitsResponse{RateLimits: []portalRateLimit{}}
+	for _, u := range usage.Usages {
+		limit := portalRateLimit{
+			Limit:     *u.Limit,
+			Count:     *u.Count,
+			Threshold: *u.Threshold,
+		}
+		if u.Key != nil {
+			limit.Key = *u.Key
+		}
+		if *u.Window != 0 {
+			limit.Window = time.Duration(*u.Window).String()
+		}
+		resp.RateLimits = append(resp.RateLimits, limit)
+	}
+	err = wfe.writeJsonResponse(response, logEvent, http.StatusOK, resp)
+	if err != nil {
+		wfe.sendError(response, logEvent, probs.ServerInternal("Error marshalling rate limits"), err)
+		return
+	}
+}
+
+// portalValidationFailure is an invalid authorization in the response to a
+// PortalValidationFailures request, with the challenges that failed.
+type portalValidationFailure struct {
+	Authorization string                  `json:"authorization"`
+	Identifier    core.AcmeIdentifier     `json:"identifier"`
+	Expires       time.Time               `json:"expires"`
+	Challenges    []portalFailedChallenge `json:"challenges"`
+}
+
+type portalFailedChallenge struct {
+	Type  string                `json:"type"`
+	Error *probs.ProblemDetails `json:"error"`
+}
+
+type portalValidationFailuresResponse struct {
+	Failures []portalValidationFailure `json:"failures"`
+}
+
+// PortalValidationFailures returns the requesting account's invalid
+// authorizations that haven't expired yet, latest expiry first, with the
+// errors their challenges failed with. The limit query parameter caps how
+// many are returned.
+func (wfe *WebFrontEndImpl) PortalValidationFailures(ctx context.Context, logEvent *web.RequestEvent, response http.ResponseWriter, request *http.Request) {
+	acct, ok := wfe.portalAccount(ctx, logEvent, response, request)
+	if !ok {
+		return
+	}
+	limit, prob, err := portalLimit(request)
+	if prob != nil {
+		wfe.sendError(response, logEvent, prob, err)
+		return
+	}
+	now := wfe.clk.Now().UnixNano()
+	list, err := wfe.SA.ListInvalidAuthorizationsForAccount(ctx, &sapb.ListInvalidAuthorizationsForAccountRequest{
+		RegistrationID: &acct.ID,
+		ExpiresAfter:   &now,
+		Limit:          limit,
+	})
+	if err != nil {
+		wfe.sendError(response, logEvent, web.ProblemDetailsForError(err, "Error listing validation failures"), err)
+		return
+	}
+
+	resp := portalValidationFailuresResponse{Failures: []portalValidationFailure{}}
+	for _, authzPB := range list.Authorizations {
+		authz, err := bgrpc.PBToAuthz(authzPB)
+		if err != nil {
+			wfe.sendError(response, logEvent, probs.ServerInternal("Error listing validation failures"), err)
+			return
+		}
+		failure := portalValidationFailure{
+			Authorization: authz.ID,
+			Identifier:    authz.Identifier,
+			Challenges:    []portalFailedChallenge{},
+		}
+		if authz.Expires != nil {
+			failure.Expires = authz.Expires.UTC()
+		}
+		for _, chall := range authz.Challenges {
+			if chall.Status != core.StatusInvalid || chall.Error == nil {
+				continue
+			}
+			prob := *chall.Error
+			prob.Type = probs.V2ErrorNS + prob.Type
+			failure.Challenges = append(failure.Challenges, portalFailedChallenge{Type: chall.Type, Error: &prob})
+		}
+		resp.Failures = append(resp.Failures, failure)
+	}
+	err = wfe.writeJsonResponse(response, logEvent, http.StatusOK, resp)
+	if err != nil {
+		wfe.sendError(response, logEvent, probs.ServerInternal("Error marshalling validation failures"), err)
+		return
+	}
+}
@@ -0,0 +1,155 @@
+package wfe2
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/letsencrypt/boulder/core"
+	corepb "github.com/letsencrypt/boulder/core/proto"
+	bgrpc "github.com/letsencrypt/boulder/grpc"
+	"github.com/letsencrypt/boulder/probs"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
+	"github.com/letsencrypt/boulder/test"
+)
+
+type mockSAPortal struct {
+	core.StorageGetter
+	ordersReq *sapb.ListOrdersForAccountRequest
+	authzReq  *sapb.ListInvalidAuthorizationsForAccountRequest
+}
+
+func (msa *mockSAPortal) ListOrdersForAccount(_ context.Context, req *sapb.ListOrdersForAccountRequest) (*sapb.OrderList, error) {
+	msa.ordersReq = req
+	id, status, expires, serial := int64(7), string(core.StatusValid), int64(time.Hour), "serial"
+	invalidID, invalid := int64(6), string(core.StatusInvalid)
+	prob, _ := bgrpc.ProblemDetailsToPB(probs.Unauthorized("no"))
+	next := "6"
+	return &sapb.OrderList{
+		Orders: []*corepb.Order{
+			{Id: &id, Status: &status, Expires: &expires, Names: []string{"example.com"}, CertificateSerial: &serial},
+			{Id: &invalidID, Status: &invalid, Expires: &expires, Names: []string{"example.net"}, Error: prob},
+		},
+		NextCursor: &next,
+	}, nil
+}
+
+func (msa *mockSAPortal) ListInvalidAuthorizationsForAccount(_ context.Context, req *sapb.ListInvalidAuthorizationsForAccountRequest) (*sapb.AuthorizationList, error) {
+	msa.authzReq = req
+	expires := time.Unix(0, int64(time.Hour)).UTC()
+	authz, err := bgrpc.AuthzToPB(core.Authorization{
+		ID:             "authz",
+		Identifier:     core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "example.com"},
+		RegistrationID: *req.RegistrationID,
+		Status:         core.StatusInvalid,
+		Expires:        &expires,
+		Challenges: []core.Challenge{
+			{Type: core.ChallengeTypeHTTP01, Status: core.StatusInvalid, Error: probs.ConnectionFailure("timeout")},
+			{Type: core.ChallengeTypeDNS01, Status: core.StatusPending},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sapb.AuthorizationList{Authorizations: []*corepb.Authorization{authz}}, nil
+}
+
+func TestPortal(t *testing.T) {
+	wfe, _ := setupWFE(t)
+	sa := &mockSAPortal{StorageGetter: wfe.SA}
+	wfe.SA = sa
+	// The portal is on a host of its own, so account URLs in key IDs don't
+	// match it.
+	wfe.AccountURLPrefix = "http://localhost/acme/acct/"
+	handler := wfe.PortalHandler()
+
+	post := func(keyID int64, path string) *httptest.ResponseRecorder {
+		signedURL := "http://portal.localhost" + path
+		_, _, body := signRequestKeyID(t, keyID, nil, signedURL, "", wfe.nonceService)
+		request := makePostRequestWithPath(signedURL, body)
+		request.Host = "portal.localhost"
+		request.RequestURI = request.URL.RequestURI()
+		responseWriter := httptest.NewRecorder()
+		handler.ServeHTTP(responseWriter, request)
+		return responseWriter
+	}
+
+	responseWriter := post(1, "/portal/orders?limit=2&cursor=8")
+	test.AssertEquals(t, responseWriter.Code, http.StatusOK)
+	test.AssertEquals(t, *sa.ordersReq.RegistrationID, int64(1))
+	test.AssertEquals(t, *sa.ordersReq.Limit, int64(2))
+	test.AssertEquals(t, *sa.ordersReq.Cursor, "8")
+	test.AssertUnmarshaledEquals(t, responseWriter.Body.String(), `{
+		"orders": [
+			{
+				"id": 7,
+				"status": "valid",
+				"expires": "1970-01-01T01:00:00Z",
+				"identifiers": [{"type": "dns", "value": "example.com"}],
+				"certificateSerial": "serial"
+			},
+			{
+				"id": 6,
+				"status": "invalid",
+				"expires": "1970-01-01T01:00:00Z",
+				"identifiers": [{"type": "dns", "value": "example.net"}],
+				"error": {"type": "`+probs.V2ErrorNS+`unauthorized", "detail": "no", "status": 403}
+			}
+		],
+		"nextCursor": "6"
+	}`)
+
+	responseWriter = post(1, "/portal/validation-failures")
+	test.AssertEquals(t, responseWriter.Code, http.StatusOK)
+	test.Assert(t, sa.authzReq.Limit == nil, "limit was set without a limit parameter")
+	test.AssertUnmarshaledEquals(t, responseWriter.Body.String(), `{
+		"failures": [{
+			"authorization": "authz",
+			"identifier": {"type": "dns", "value": "example.com"},
+			"expires": "1970-01-01T01:00:00Z",
+			"challenges": [{
+				"type": "http-01",
+				"error": {"type": "`+probs.V2ErrorNS+`connection", "detail": "timeout", "status": 400}
+			}]
+		}]
+	}`)
+
+	responseWriter = post(1, "/portal/rate-limits?names=example.com,example.net")
+	test.AssertEquals(t, responseWriter.Code, http.StatusOK)
+	test.AssertUnmarshaledEquals(t, responseWriter.Body.String(), `{
+		"rateLimits": [
+			{"limit": "newOrdersPerAccount", "count": 3, "threshold": 300, "window": "168h0m0s"},
+			{"limit": "certificatesPerName", "key": "example.com", "count": 3, "threshold": 300, "window": "168h0m0s"},
+			{"limit": "certificatesPerName", "key": "example.net", "count": 3, "threshold": 300, "window": "168h0m0s"}
+		]
+	}`)
+
+	responseWriter = post(1, "/portal/certificates")
+	test.AssertEquals(t, responseWriter.Code, http.StatusOK)
+
+	responseWriter = post(1, "/portal/orders?limit=0")
+	test.AssertEquals(t, responseWriter.Code, http.StatusBadRequest)
+	test.AssertContains(t, responseWriter.Body.String(), "limit must be a positive integer")
+
+	// Requests must be signed by an active account
+	responseWriter = post(3, "/portal/orders")
+	test.AssertEquals(t, responseWriter.Code, http.StatusForbidden)
+
+	// The query is part of the signed URL
+	signedURL := "http://portal.localhost/portal/orders?limit=1"
+	_, _, body := signRequestKeyID(t, 1, nil, signedURL, "", wfe.nonceService)
+	request := makePostRequestWithPath("http://portal.localhost/portal/orders?limit=100", body)
+	request.Host = "portal.localhost"
+	request.RequestURI = request.URL.RequestURI()
+	responseWriter = httptest.NewRecorder()
+	handler.ServeHTTP(responseWriter, request)
+	test.AssertEquals(t, responseWriter.Code, http.StatusBadRequest)
+	test.AssertContains(t, responseWriter.Body.String(), "JWS header parameter 'url' incorrect")
+
+	// The ACME API isn't served by the portal
+	responseWriter = post(1, newOrderPath)
+	test.AssertEquals(t, responseWriter.Code, http.StatusNotFound)
+}
@@ -15,6 +15,9 @@ type wfe2Stats struct {
 	// csrSignatureAlgs counts the signature algorithms in use for order
 	// finalization CSRs
 	csrSignatureAlgs *prometheus.CounterVec
+	// throttledRequests counts requests refused by the per-IP throttle, by
+	// endpoint
+	throttledRequests *prometheus.CounterVec
 }
 
 func initStats(scope metrics.Scope) wfe2Stats {
@@ -43,9 +46,19 @@ func initStats(scope metrics.Scope) wfe2Stats {
 	)
 	scope.MustRegister(csrSignatureAlgs)
 
+	throttledRequests := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "throttledRequests",
+			Help: "Number of requests refused by the per-IP throttle, by endpoint",
+		},
+		[]string{"endpoint"},
+	)
+	scope.MustRegister(throttledRequests)
+
 	return wfe2Stats{
-		httpErrorCount:   httpErrorCount,
-		joseErrorCount:   joseErrorCount,
-		csrSignatureAlgs: csrSignatureAlgs,
+		httpErrorCount:    httpErrorCount,
+		joseErrorCount:    joseErrorCount,
+		csrSignatureAlgs:  csrSignatureAlgs,
+		throttledRequests: throttledRequests,
 	}
 }
@@ -5,10 +5,12 @@ import (
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/rsa"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -19,6 +21,7 @@ import (
 
 	"github.com/letsencrypt/boulder/core"
 	berrors "github.com/letsencrypt/boulder/errors"
+	"github.com/letsencrypt/boulder/nonce"
 	"github.com/letsencrypt/boulder/probs"
 	"github.com/letsencrypt/boulder/web"
 )
@@ -174,20 +177,25 @@ func (wfe *WebFrontEndImpl) validPOSTRequest(request *http.Request) *probs.Probl
 }
 
 // validNonce checks a JWS' Nonce header to ensure it is one that the
-// nonceService knows about, otherwise a bad nonce problem is returned.
+// nonceService knows about, otherwise a bad nonce problem is returned and the
+// cause is added to the logEvent.
 // NOTE: this function assumes the JWS has already been verified with the
 // correct public key.
-func (wfe *WebFrontEndImpl) validNonce(jws *jose.JSONWebSignature) *probs.ProblemDetails {
+func (wfe *WebFrontEndImpl) validNonce(jws *jose.JSONWebSignature, logEvent *web.RequestEvent) *probs.ProblemDetails {
 	// validNonce is called after validPOSTRequest() and parseJWS() which
 	// defend against the incorrect number of signatures.
 	header := jws.Signatures[0].Header
-	nonce := header.Nonce
-	if len(nonce) == 0 {
+	jwsNonce := header.Nonce
+	if len(jwsNonce) == 0 {
 		wfe.stats.joseErrorCount.With(prometheus.Labels{"type": "JWSMissingNonce"}).Inc()
 		return probs.BadNonce("JWS has no anti-replay nonce")
-	} else if !wfe.nonceService.Valid(nonce) {
+	} else if err := wfe.nonceService.Redeem(jwsNonce); err != nil {
 		wfe.stats.joseErrorCount.With(prometheus.Labels{"type": "JWSInvalidNonce"}).Inc()
-		return probs.BadNonce("JWS has an invalid anti-replay nonce: %q", nonce)
+		if badNonce, ok := err.(*nonce.BadNonceError); ok {
+			logEvent.Extra["BadNonceCause"] = badNonce.Cause
+			logEvent.Extra["BadNonceAge"] = badNonce.Age.String()
+		}
+		return probs.BadNonce("JWS has an invalid anti-replay nonce: %q", jwsNonce)
 	}
 	return nil
 }
@@ -214,12 +222,17 @@ func (wfe *WebFrontEndImpl) validPOSTURL(
 		wfe.stats.joseErrorCount.With(prometheus.Labels{"type": "JWSMissingURL"}).Inc()
 		return probs.Malformed("JWS header parameter 'url' required")
 	}
-	// Compute the URL we expect to be in the JWS based on the HTTP request
-	expectedURL := url.URL{
-		Scheme: requestProto(request),
-		Host:   request.Host,
-		Path:   request.RequestURI,
+	// Compute the URL we expect to be in the JWS based on the HTTP request.
+	// request.URL has had the endpoint's prefix stripped, so the RequestURI is
+	// parsed instead, keeping its original escaping. The query, which only
+	// portal requests have, is signed as part of it.
+	expectedURL, err := url.ParseRequestURI(request.RequestURI)
+	if err != nil {
+		wfe.stats.joseErrorCount.With(prometheus.Labels{"type": "JWSMismatchedURL"}).Inc()
+		return probs.Malformed("Invalid request URI")
 	}
+	expectedURL.Scheme = requestProto(request)
+	expectedURL.Host = request.Host
 	// Check that the URL we expect is the one that was found in the signed JWS
 	// header
 	if expectedURL.String() != headerURL {
@@ -383,6 +396,8 @@ func (wfe *WebFrontEndImpl) acctIDFromURL(acctURL string, request *http.Request)
 	var accountIDStr string
 	if strings.HasPrefix(acctURL, expectedURLPrefix) {
 		accountIDStr = strings.TrimPrefix(acctURL, expectedURLPrefix)
+	} else if wfe.AccountURLPrefix != "" && strings.HasPrefix(acctURL, wfe.AccountURLPrefix) {
+		accountIDStr = strings.TrimPrefix(acctURL, wfe.AccountURLPrefix)
 	} else if strings.HasPrefix(acctURL, wfe.LegacyKeyIDPrefix) {
 		accountIDStr = strings.TrimPrefix(acctURL, wfe.LegacyKeyIDPrefix)
 	} else {
@@ -470,7 +485,7 @@ func (wfe *WebFrontEndImpl) validJWSForKey(
 	// Check that the public key and JWS algorithms match expected
 	if err := checkAlgorithm(jwk, jws); err != nil {
 		wfe.stats.joseErrorCount.With(prometheus.Labels{"type": "JWSAlgorithmCheckFailed"}).Inc()
-		return nil, probs.Malformed(err.Error())
+		return nil, probs.BadSignatureAlgorithm("%s", err)
 	}
 
 	// Verify the JWS signature with the public key.
@@ -488,7 +503,7 @@ func (wfe *WebFrontEndImpl) validJWSForKey(
 	logEvent.Payload = string(payload)
 
 	// Check that the JWS contains a correct Nonce header
-	if prob := wfe.validNonce(jws); prob != nil {
+	if prob := wfe.validNonce(jws, logEvent); prob != nil {
 		return nil, prob
 	}
 
@@ -508,9 +523,39 @@ func (wfe *WebFrontEndImpl) validJWSForKey(
 		return nil, probs.Malformed("Request payload did not parse as JSON")
 	}
 
+	// Only now that the request is known to be signed with jwk is its
+	// thumbprint worth scoring.
+	if prob := wfe.checkAbuse(request, jwk, logEvent); prob != nil {
+		return nil, prob
+	}
+
 	return payload, nil
 }
 
+// checkAbuse scores a request whose JWS verified with jwk with the WFE's
+// AbuseHook, returning the problem to refuse it with if the hook decides to.
+func (wfe *WebFrontEndImpl) checkAbuse(request *http.Request, jwk *jose.JSONWebKey, logEvent *web.RequestEvent) *probs.ProblemDetails {
+	if wfe.AbuseHook == nil {
+		return nil
+	}
+	thumbprint, err := jwk.Thumbprint(crypto.SHA256)
+	if err != nil {
+		logEvent.AddError("computing JWK thumbprint: %s", err)
+		return nil
+	}
+	ip := net.ParseIP(logEvent.RealIP)
+	if wfe.Throttle != nil {
+		ip = wfe.Throttle.ClientIP(request)
+	}
+	return wfe.AbuseHook.Check(request.Context(), web.AbuseSignals{
+		Endpoint:       logEvent.Endpoint,
+		KeyThumbprint:  base64.RawURLEncoding.EncodeToString(thumbprint),
+		RegistrationID: logEvent.Requester,
+		ClientIP:       ip,
+		UserAgent:      request.Header.Get("User-Agent"),
+	}, logEvent)
+}
+
 // validJWSForAccount checks that a given JWS is valid and verifies with the
 // public key associated to a known account specified by the JWS Key ID. If the
 // JWS is valid (e.g. the JWS is well formed, verifies with the JWK stored for the
@@ -602,7 +647,7 @@ func (wfe *WebFrontEndImpl) validSelfAuthenticatedJWS(
 	// If the key doesn't meet the GoodKey policy return a problem immediately
 	if err := wfe.keyPolicy.GoodKey(pubKey.Key); err != nil {
 		wfe.stats.joseErrorCount.With(prometheus.Labels{"type": "JWKRejectedByGoodKey"}).Inc()
-		return nil, nil, probs.Malformed(err.Error())
+		return nil, nil, probs.BadPublicKey("%s", err)
 	}
 
 	// Verify the JWS with the embedded JWK
@@ -676,7 +721,7 @@ func (wfe *WebFrontEndImpl) validKeyRollover(
 	// If the key doesn't meet the GoodKey policy return a problem immediately
 	if err := wfe.keyPolicy.GoodKey(jwk.Key); err != nil {
 		wfe.stats.joseErrorCount.With(prometheus.Labels{"type": "KeyRolloverJWKRejectedByGoodKey"}).Inc()
-		return nil, probs.Malformed(err.Error())
+		return nil, probs.BadPublicKey("%s", err)
 	}
 
 	// Check that the public key and JWS algorithms match expected
@@ -7,6 +7,7 @@ import (
 	"crypto/rsa"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 
 	"golang.org/x/net/context"
@@ -603,7 +604,7 @@ func TestValidNonce(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.Name, func(t *testing.T) {
 			wfe.stats.joseErrorCount.Reset()
-			prob := wfe.validNonce(tc.JWS)
+			prob := wfe.validNonce(tc.JWS, newRequestEvent())
 			if tc.ExpectedResult == nil && prob != nil {
 				t.Fatalf("Expected nil result, got %#v", prob)
 			} else {
@@ -671,6 +672,30 @@ func TestValidPOSTURL(t *testing.T) {
 	correctURLHeaderJWS, _, correctURLHeaderJWSBody := signRequestEmbed(t, nil, "http://localhost/test-path", "", wfe.nonceService)
 	correctURLHeaderRequest := makePostRequestWithPath("test-path", correctURLHeaderJWSBody)
 
+	// requestWithURI returns an HTTP request for requestURI, whose URL has had
+	// the endpoint's prefix stripped like the WFE's handlers see it.
+	requestWithURI := func(prefix, requestURI, body string) *http.Request {
+		request := makePostRequestWithPath(requestURI, body)
+		request.RequestURI = requestURI
+		request.URL.Path = strings.TrimPrefix(request.URL.Path, prefix)
+		return request
+	}
+
+	// An ordinary endpoint with no query
+	orderJWS, _, orderJWSBody := signRequestEmbed(t, nil, "http://localhost/acme/order/1/2", "", wfe.nonceService)
+	orderRequest := requestWithURI(orderPath, "/acme/order/1/2", orderJWSBody)
+
+	// A path with an escaped slash isn't escaped again
+	escapedJWS, _, escapedJWSBody := signRequestEmbed(t, nil, "http://localhost/acme/order/a%2Fb", "", wfe.nonceService)
+	escapedRequest := requestWithURI(orderPath, "/acme/order/a%2Fb", escapedJWSBody)
+
+	// A portal URL with a query, which is signed as part of the URL
+	portalURL := "http://localhost/portal/certificates?cursor=abc%3D&limit=10"
+	portalJWS, _, portalJWSBody := signRequestEmbed(t, nil, portalURL, "", wfe.nonceService)
+	portalRequest := requestWithURI(portalCertificatesPath, "/portal/certificates?cursor=abc%3D&limit=10", portalJWSBody)
+	wrongQueryJWS, _, wrongQueryJWSBody := signRequestEmbed(t, nil, "http://localhost/portal/certificates?limit=10", "", wfe.nonceService)
+	wrongQueryRequest := requestWithURI(portalCertificatesPath, "/portal/certificates?cursor=abc%3D&limit=10", wrongQueryJWSBody)
+
 	testCases := []struct {
 		Name           string
 		JWS            *jose.JSONWebSignature
@@ -717,6 +742,35 @@ func TestValidPOSTURL(t *testing.T) {
 			Request:        correctURLHeaderRequest,
 			ExpectedResult: nil,
 		},
+		{
+			Name:           "Correct URL header for an endpoint with no query",
+			JWS:            orderJWS,
+			Request:        orderRequest,
+			ExpectedResult: nil,
+		},
+		{
+			Name:           "Correct URL header for an escaped path",
+			JWS:            escapedJWS,
+			Request:        escapedRequest,
+			ExpectedResult: nil,
+		},
+		{
+			Name:           "Correct URL header for a portal URL with a query",
+			JWS:            portalJWS,
+			Request:        portalRequest,
+			ExpectedResult: nil,
+		},
+		{
+			Name:    "URL header for a portal URL with the wrong query",
+			JWS:     wrongQueryJWS,
+			Request: wrongQueryRequest,
+			ExpectedResult: &probs.ProblemDetails{
+				Type:       probs.MalformedProblem,
+				Detail:     "JWS header parameter 'url' incorrect. Expected \"" + portalURL + "\" got \"http://localhost/portal/certificates?limit=10\"",
+				HTTPStatus: http.StatusBadRequest,
+			},
+			ErrorStatType: "JWSMismatchedURL",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -1161,7 +1215,7 @@ func TestValidJWSForKey(t *testing.T) {
 			JWS:  wrongAlgJWS,
 			JWK:  goodJWK,
 			ExpectedProblem: &probs.ProblemDetails{
-				Type:       probs.MalformedProblem,
+				Type:       probs.BadSignatureAlgorithmProblem,
 				Detail:     "signature type 'HS256' in JWS header is not supported, expected one of RS256, ES256, ES384 or ES512",
 				HTTPStatus: http.StatusBadRequest,
 			},
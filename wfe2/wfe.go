@@ -8,9 +8,11 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"math"
 	"net"
 	"net/http"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -18,6 +20,7 @@ import (
 	"github.com/jmhodges/clock"
 	"github.com/letsencrypt/boulder/core"
 	corepb "github.com/letsencrypt/boulder/core/proto"
+	csrlib "github.com/letsencrypt/boulder/csr"
 	berrors "github.com/letsencrypt/boulder/errors"
 	"github.com/letsencrypt/boulder/features"
 	"github.com/letsencrypt/boulder/goodkey"
@@ -55,8 +58,32 @@ const (
 	newOrderPath      = "/acme/new-order"
 	orderPath         = "/acme/order/"
 	finalizeOrderPath = "/acme/finalize/"
+	checkIssuancePath = "/acme/check-issuance"
+	orderAuthzsPath   = "/acme/order-authzs/"
 )
 
+const (
+	// defaultOrderAuthzPollInterval is how often the SA is queried while a
+	// request for the state of an order's authorizations waits for one of them
+	// to change, if OrderAuthzPollInterval isn't set
+	defaultOrderAuthzPollInterval = time.Second
+	// directoryCacheLifetime is how long a rendered directory is served
+	// before it's rendered again with a new random key
+	directoryCacheLifetime = 10 * time.Minute
+	// maxCachedDirectories bounds the number of Host and X-Forwarded-Proto
+	// combinations whose directories are cached
+	maxCachedDirectories = 100
+)
+
+// throttledPaths are the endpoints whose requests are limited by the WFE's
+// Throttle. They're the ones that are cheap to call but create state or make
+// RPCs.
+var throttledPaths = map[string]bool{
+	newNoncePath: true,
+	newAcctPath:  true,
+	newOrderPath: true,
+}
+
 // WebFrontEndImpl provides all the logic for Boulder's web-facing interface,
 // i.e., ACME.  Its members configure the paths for various ACME functions,
 // plus a few other data items used in ACME.  Its methods are primarily handlers
@@ -79,6 +106,10 @@ type WebFrontEndImpl struct {
 
 	// URL to the current subscriber agreement (should contain some version identifier)
 	SubscriberAgreementURL string
+	// TOSReacceptanceDeadline, if set, is when accounts that haven't agreed
+	// to the current subscriber agreement must agree to it before they can
+	// create new orders.
+	TOSReacceptanceDeadline time.Time
 
 	// DirectoryCAAIdentity is used for the /directory response's "meta"
 	// element's "caaIdentities" field. It should match the VA's issuerDomain
@@ -89,11 +120,21 @@ type WebFrontEndImpl struct {
 	// "website" field.
 	DirectoryWebsite string
 
+	// directoryCache holds rendered directories, keyed by the URL they're
+	// relative to and the meta entries above
+	directoryCache *web.ResponseCache
+
 	// Allowed prefix for legacy accounts used by verify.go's `lookupJWK`.
 	// See `cmd/boulder-wfe2/main.go`'s comment on the configuration field
 	// `LegacyKeyIDPrefix` for more informaton.
 	LegacyKeyIDPrefix string
 
+	// AccountURLPrefix, if set, is the prefix of account URLs as the ACME
+	// API serves them, which is accepted in key IDs on any host. The account
+	// portal needs it, since it's served on a host of its own but its
+	// requests are signed with the account URL.
+	AccountURLPrefix string
+
 	// Register of anti-replay nonces
 	nonceService *nonce.NonceService
 
@@ -108,6 +149,36 @@ type WebFrontEndImpl struct {
 
 	AcceptRevocationReason bool
 	AllowAuthzDeactivation bool
+
+	// Throttle, if set, limits the rate of requests to the endpoints in
+	// throttledPaths from each client IP, before any RPCs are made for them.
+	Throttle *web.Throttle
+
+	// Maintenance, if set, makes the WFE refuse POST requests other than
+	// certificate downloads with a 503 while it's in maintenance.
+	Maintenance *web.Maintenance
+
+	// AbuseHook, if set, scores requests once their JWS is verified, by key
+	// thumbprint, client IP and User-Agent, and tarpits, challenges or
+	// rejects them if they look abusive.
+	AbuseHook *web.AbuseHook
+
+	// ProblemCatalog, if set, translates the detail of problem documents
+	// into the language clients ask for with Accept-Language.
+	ProblemCatalog *web.ProblemCatalog
+
+	// NameLimits are the maximum numbers of names in a certificate, checked
+	// before making any RPCs so that each excess name can be reported. The RA
+	// and CA enforce their own limits regardless. If zero, the WFE doesn't
+	// check the number of names.
+	NameLimits csrlib.NameLimits
+
+	// OrderAuthzLongPoll, if non-zero, is how long a request for the state of
+	// an order's authorizations that has an If-None-Match header waits for
+	// one of them to change before it's answered. OrderAuthzPollInterval is
+	// how often the SA is queried while it waits.
+	OrderAuthzLongPoll     time.Duration
+	OrderAuthzPollInterval time.Duration
 }
 
 // NewWebFrontEndImpl constructs a web service for Boulder
@@ -131,6 +202,7 @@ func NewWebFrontEndImpl(
 		certificateChains: certificateChains,
 		stats:             initStats(scope),
 		scope:             scope,
+		directoryCache:    web.NewResponseCache(clk, directoryCacheLifetime, maxCachedDirectories),
 	}, nil
 }
 
@@ -165,6 +237,15 @@ func (wfe *WebFrontEndImpl) HandleFunc(mux *http.ServeMux, pattern string, h web
 	methodsStr := strings.Join(methods, ", ")
 	handler := http.StripPrefix(pattern, web.NewTopHandler(wfe.log,
 		web.WFEHandlerFunc(func(ctx context.Context, logEvent *web.RequestEvent, response http.ResponseWriter, request *http.Request) {
+			if throttledPaths[pattern] && request.Method != "OPTIONS" {
+				if ok, wait := wfe.Throttle.Allow(request); !ok {
+					logEvent.Endpoint = pattern
+					wfe.stats.throttledRequests.With(prometheus.Labels{"endpoint": pattern}).Inc()
+					response.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+					wfe.sendError(response, logEvent, probs.RateLimited("Too many requests from your IP address, retry later"), nil)
+					return
+				}
+			}
 			if request.Method != "GET" || pattern == newNoncePath {
 				// We do not propagate errors here, because (1) they should be
 				// transient, and (2) they fail closed.
@@ -207,6 +288,17 @@ func (wfe *WebFrontEndImpl) HandleFunc(mux *http.ServeMux, pattern string, h web
 				return
 			}
 
+			// During maintenance, refuse every POST, since they write to the
+			// database or need it to look up the requesting account. POST-as-GET
+			// certificate downloads are the exception, so that they keep working
+			// as long as the database can be read.
+			if request.Method == "POST" && pattern != certPath {
+				if prob := wfe.Maintenance.Problem(); prob != nil {
+					wfe.sendError(response, logEvent, prob, nil)
+					return
+				}
+			}
+
 			wfe.setCORSHeaders(response, request, "")
 
 			timeout := wfe.RequestTimeout
@@ -305,6 +397,7 @@ func (wfe *WebFrontEndImpl) Handler() http.Handler {
 	// Boulder specific endpoints
 	wfe.HandleFunc(m, issuerPath, wfe.Issuer, "GET")
 	wfe.HandleFunc(m, buildIDPath, wfe.BuildID, "GET")
+	wfe.HandleFunc(m, checkIssuancePath, wfe.CheckIssuance, "POST")
 
 	// GETable ACME endpoints
 	wfe.HandleFunc(m, directoryPath, wfe.Directory, "GET")
@@ -325,6 +418,7 @@ func (wfe *WebFrontEndImpl) Handler() http.Handler {
 	wfe.HandleFunc(m, authzPath, wfe.Authorization, "GET", "POST")
 	wfe.HandleFunc(m, challengePath, wfe.Challenge, "GET", "POST")
 	wfe.HandleFunc(m, certPath, wfe.Certificate, "GET", "POST")
+	wfe.HandleFunc(m, orderAuthzsPath, wfe.OrderAuthorizations, "GET", "POST")
 
 	// We don't use our special HandleFunc for "/" because it matches everything,
 	// meaning we can wind up returning 405 when we mean to return 404. See
@@ -377,12 +471,40 @@ func addRequesterHeader(w http.ResponseWriter, requester int64) {
 
 // Directory is an HTTP request handler that provides the directory
 // object stored in the WFE's DirectoryEndpoints member with paths prefixed
-// using the `request.Host` of the HTTP request.
+// using the `request.Host` of the HTTP request. Rendered directories are
+// cached, and clients can make conditional requests for them.
 func (wfe *WebFrontEndImpl) Directory(
 	ctx context.Context,
 	logEvent *web.RequestEvent,
 	response http.ResponseWriter,
 	request *http.Request) {
+	response.Header().Set("Content-Type", "application/json")
+
+	// The directory only depends on the URL it's relative to and the
+	// configured meta entries
+	key := strings.Join([]string{
+		web.RelativeEndpoint(request, ""),
+		wfe.SubscriberAgreementURL,
+		wfe.DirectoryCAAIdentity,
+		wfe.DirectoryWebsite,
+	}, "\x00")
+	dir, err := wfe.directoryCache.Get(key, func() ([]byte, error) {
+		return wfe.renderDirectory(request)
+	})
+	if err != nil {
+		marshalProb := probs.ServerInternal("unable to marshal JSON directory")
+		wfe.sendError(response, logEvent, marshalProb, nil)
+		return
+	}
+
+	if web.CheckNotModified(response, request, dir.ETag, dir.Modified) {
+		return
+	}
+	response.Write(dir.Body)
+}
+
+// renderDirectory returns the JSON directory relative to request.
+func (wfe *WebFrontEndImpl) renderDirectory(request *http.Request) ([]byte, error) {
 	directoryEndpoints := map[string]interface{}{
 		"newAccount": newAcctPath,
 		"newNonce":   newNoncePath,
@@ -418,16 +540,7 @@ func (wfe *WebFrontEndImpl) Directory(
 	}
 	directoryEndpoints["meta"] = metaMap
 
-	response.Header().Set("Content-Type", "application/json")
-
-	relDir, err := wfe.relativeDirectory(request, directoryEndpoints)
-	if err != nil {
-		marshalProb := probs.ServerInternal("unable to marshal JSON directory")
-		wfe.sendError(response, logEvent, marshalProb, nil)
-		return
-	}
-
-	response.Write(relDir)
+	return wfe.relativeDirectory(request, directoryEndpoints)
 }
 
 // Nonce is an endpoint for getting a fresh nonce with an HTTP GET or HEAD
@@ -452,7 +565,7 @@ func (wfe *WebFrontEndImpl) Nonce(
 // sendError wraps web.SendError
 func (wfe *WebFrontEndImpl) sendError(response http.ResponseWriter, logEvent *web.RequestEvent, prob *probs.ProblemDetails, ierr error) {
 	wfe.stats.httpErrorCount.With(prometheus.Labels{"type": string(prob.Type)}).Inc()
-	web.SendError(wfe.log, probs.V2ErrorNS, response, logEvent, prob, ierr)
+	web.SendError(wfe.log, probs.V2ErrorNS, wfe.ProblemCatalog, response, logEvent, prob, ierr)
 }
 
 func link(url, relation string) string {
@@ -488,15 +601,27 @@ func (wfe *WebFrontEndImpl) NewAccount(
 		return
 	}
 
+	// Whether or not the request has a true "onlyReturnExisting" field, an
+	// existing account with the key is returned rather than creating another.
 	existingAcct, err := wfe.SA.GetRegistrationByKey(ctx, key)
 	if err == nil {
+		// A deactivated account can't be used for anything, including being
+		// looked up by its key.
+		if existingAcct.Status == core.StatusDeactivated {
+			wfe.sendError(response, logEvent, probs.Unauthorized(
+				"An account with the provided public key exists but is deactivated"), nil)
+			return
+		}
 		response.Header().Set("Location",
 			web.RelativeEndpoint(request, fmt.Sprintf("%s%d", acctPath, existingAcct.ID)))
 		logEvent.Requester = existingAcct.ID
+		addRequesterHeader(response, existingAcct.ID)
+		// As for a new account, don't send the ACMEv1 Agreement field
+		existingAcct.Agreement = ""
 
 		err = wfe.writeJsonResponse(response, logEvent, http.StatusOK, existingAcct)
 		if err != nil {
-			// ServerInternal because we just created this account, and it
+			// ServerInternal because the account came from the SA, and it
 			// should be OK.
 			wfe.sendError(response, logEvent, probs.ServerInternal("Error marshaling account"), err)
 			return
@@ -578,6 +703,21 @@ func (wfe *WebFrontEndImpl) NewAccount(
 	}
 }
 
+// checkTOSAgreement returns a userActionRequired problem if the terms of
+// service have changed since acct agreed to them and the deadline for agreeing
+// to the current terms has passed.
+func (wfe *WebFrontEndImpl) checkTOSAgreement(acct *core.Registration) *probs.ProblemDetails {
+	if wfe.TOSReacceptanceDeadline.IsZero() || wfe.SubscriberAgreementURL == "" {
+		return nil
+	}
+	if acct.Agreement == wfe.SubscriberAgreementURL || wfe.clk.Now().Before(wfe.TOSReacceptanceDeadline) {
+		return nil
+	}
+	return probs.UserActionRequired(
+		"The terms of service have changed. Agree to the current terms at %s by updating your account with "+
+			"\"termsOfServiceAgreed\": true before creating new orders", wfe.SubscriberAgreementURL)
+}
+
 func (wfe *WebFrontEndImpl) acctHoldsAuthorizations(ctx context.Context, acctID int64, names []string) (bool, error) {
 	authz, err := wfe.SA.GetValidAuthorizations(ctx, acctID, names, wfe.clk.Now())
 	if err != nil {
@@ -677,7 +817,7 @@ func (wfe *WebFrontEndImpl) processRevocation(
 	reason := revocation.Reason(0)
 	if revokeRequest.Reason != nil && wfe.AcceptRevocationReason {
 		if _, present := revocation.UserAllowedReasons[*revokeRequest.Reason]; !present {
-			return probs.Malformed("unsupported revocation reason code provided")
+			return probs.BadRevocationReason("unsupported revocation reason code provided")
 		}
 		reason = *revokeRequest.Reason
 	}
@@ -985,8 +1125,8 @@ func (wfe *WebFrontEndImpl) prepAuthorizationForDisplay(request *http.Request, a
 	// corresponds to a wildcard request (e.g. to handle CAA properly). We strip
 	// the "*." prefix from the Authz's Identifier's Value here to respect the law
 	// of the protocol.
-	if strings.HasPrefix(authz.Identifier.Value, "*.") {
-		authz.Identifier.Value = strings.TrimPrefix(authz.Identifier.Value, "*.")
+	if base, wildcard := core.WildcardBase(authz.Identifier.Value); wildcard {
+		authz.Identifier.Value = base
 		// Mark that the authorization corresponds to a wildcard request since we've
 		// now removed the wildcard prefix from the identifier.
 		authz.Wildcard = true
@@ -1096,7 +1236,7 @@ func (wfe *WebFrontEndImpl) postChallenge(
 
 	// assumption: PerformValidation does not modify order of challenges
 	challenge := returnAuthz.Challenges[challengeIndex]
-	wfe.prepChallengeForDisplay(request, authz, &challenge)
+	wfe.prepChallengeForDisplay(request, returnAuthz, &challenge)
 
 	authzURL := web.RelativeEndpoint(request, authzPath+string(authz.ID))
 	response.Header().Add("Location", challenge.URL)
@@ -1180,11 +1320,13 @@ func (wfe *WebFrontEndImpl) updateAccount(
 	ctx context.Context,
 	requestBody []byte,
 	currAcct *core.Registration) (*core.Registration, *probs.ProblemDetails) {
-	// Only the Contact and Status fields of an account may be updated this way.
+	// Only the Contact and Status fields of an account, and the terms of
+	// service it agreed to, may be updated this way.
 	// For key updates clients should be using the key change endpoint.
 	var accountUpdateRequest struct {
-		Contact *[]string       `json:"contact"`
-		Status  core.AcmeStatus `json:"status"`
+		Contact              *[]string       `json:"contact"`
+		Status               core.AcmeStatus `json:"status"`
+		TermsOfServiceAgreed bool            `json:"termsOfServiceAgreed"`
 	}
 
 	err := json.Unmarshal(requestBody, &accountUpdateRequest)
@@ -1198,6 +1340,11 @@ func (wfe *WebFrontEndImpl) updateAccount(
 		Contact: accountUpdateRequest.Contact,
 		Status:  accountUpdateRequest.Status,
 	}
+	// Agreeing to the terms of service records the current subscriber
+	// agreement as the one the account agreed to, as when it was created.
+	if accountUpdateRequest.TermsOfServiceAgreed {
+		update.Agreement = wfe.SubscriberAgreementURL
+	}
 
 	// People *will* POST their full accounts to this endpoint, including
 	// the 'valid' status, to avoid always failing out when that happens only
@@ -1462,6 +1609,13 @@ func (wfe *WebFrontEndImpl) Certificate(ctx context.Context, logEvent *web.Reque
 		responsePEM = leafPEM
 	}
 
+	// Certificates never change, so the chain served for one only changes if
+	// the configured certificateChains do
+	etag := web.NewCachedResponse(responsePEM, time.Time{}).ETag
+	if web.CheckNotModified(response, request, etag, cert.Issued) {
+		return
+	}
+
 	// NOTE(@cpu): We must explicitly set the Content-Length header here. The Go
 	// HTTP library will only add this header if the body is below a certain size
 	// and with the addition of a PEM encoded certificate chain the body size of
@@ -1707,6 +1861,11 @@ func (wfe *WebFrontEndImpl) NewOrder(
 		wfe.sendError(response, logEvent, prob, nil)
 		return
 	}
+	if prob := wfe.checkTOSAgreement(acct); prob != nil {
+		response.Header().Add("Link", link(wfe.SubscriberAgreementURL, "terms-of-service"))
+		wfe.sendError(response, logEvent, prob, nil)
+		return
+	}
 
 	// We only allow specifying Identifiers in a new order request - if the
 	// `notBefore` and/or `notAfter` fields described in Section 7.4 of acme-08
@@ -1714,6 +1873,12 @@ func (wfe *WebFrontEndImpl) NewOrder(
 	var newOrderRequest struct {
 		Identifiers         []core.AcmeIdentifier `json:"identifiers"`
 		NotBefore, NotAfter string
+		// Replaces is the ARI certificate ID of the certificate the order
+		// replaces, if any
+		Replaces string `json:"replaces"`
+		// PolicyExemptions are tokens, given to the account by the CA's
+		// operators, that exempt names in the order from the hostname policy
+		PolicyExemptions []string `json:"policyExemptions"`
 	}
 	err := json.Unmarshal(body, &newOrderRequest)
 	if err != nil {
@@ -1745,11 +1910,29 @@ func (wfe *WebFrontEndImpl) NewOrder(
 		}
 		names[i] = ident.Value
 	}
+	names, err = core.NormalizeNames(names)
+	if err != nil {
+		wfe.sendError(response, logEvent, web.ProblemDetailsForError(err, "Error creating new order"), err)
+		return
+	}
 
-	order, err := wfe.RA.NewOrder(ctx, &rapb.NewOrderRequest{
+	// The key, and so the profile the certificate will be issued with, isn't
+	// known until the order is finalized, so only the largest limit of any
+	// profile can be enforced here.
+	if prob := tooManyNamesProblem(names, wfe.NameLimits.Max()); prob != nil {
+		wfe.sendError(response, logEvent, prob, nil)
+		return
+	}
+
+	newOrder := &rapb.NewOrderRequest{
 		RegistrationID: &acct.ID,
 		Names:          names,
-	})
+		Exemptions:     newOrderRequest.PolicyExemptions,
+	}
+	if newOrderRequest.Replaces != "" {
+		newOrder.Replaces = &newOrderRequest.Replaces
+	}
+	order, err := wfe.RA.NewOrder(ctx, newOrder)
 	if err != nil {
 		wfe.sendError(response, logEvent, web.ProblemDetailsForError(err, "Error creating new order"), err)
 		return
@@ -1830,6 +2013,240 @@ func (wfe *WebFrontEndImpl) GetOrder(ctx context.Context, logEvent *web.RequestE
 	}
 }
 
+// orderAuthzJSON is the state of one of an order's authorizations in a
+// response to an OrderAuthorizations request.
+type orderAuthzJSON struct {
+	URL        string              `json:"url"`
+	Identifier core.AcmeIdentifier `json:"identifier"`
+	Status     core.AcmeStatus     `json:"status"`
+	Expires    *time.Time          `json:"expires,omitempty"`
+}
+
+// orderAuthzsJSON is the body of a response to an OrderAuthorizations
+// request.
+type orderAuthzsJSON struct {
+	Authorizations []orderAuthzJSON `json:"authorizations"`
+}
+
+// orderAuthzStates fetches the state of every authorization of an order from
+// the SA in a single RPC, and returns it rendered as JSON. The authorizations
+// are sorted by identifier, so the JSON only changes when their states do.
+func (wfe *WebFrontEndImpl) orderAuthzStates(ctx context.Context, request *http.Request, acctID, orderID int64) ([]byte, *probs.ProblemDetails, error) {
+	authzs, err := wfe.SA.GetOrderAuthorizations(ctx, &sapb.GetOrderAuthorizationsRequest{
+		Id:     &orderID,
+		AcctID: &acctID,
+	})
+	if err != nil {
+		return nil, probs.ServerInternal("Failed to retrieve authorizations for order ID %d", orderID), err
+	}
+	// Every order has at least one authorization, so there's no order with
+	// this ID for this account
+	if len(authzs) == 0 {
+		return nil, probs.NotFound("No order for ID %d", orderID), nil
+	}
+
+	respObj := orderAuthzsJSON{Authorizations: make([]orderAuthzJSON, 0, len(authzs))}
+	for _, authz := range authzs {
+		respObj.Authorizations = append(respObj.Authorizations, orderAuthzJSON{
+			URL:        web.RelativeEndpoint(request, authzPath+authz.ID),
+			Identifier: authz.Identifier,
+			Status:     authz.Status,
+			Expires:    authz.Expires,
+		})
+	}
+	sort.Slice(respObj.Authorizations, func(i, j int) bool {
+		return respObj.Authorizations[i].Identifier.Value < respObj.Authorizations[j].Identifier.Value
+	})
+	body, err := marshalIndent(respObj)
+	if err != nil {
+		return nil, probs.ServerInternal("Error marshaling order authorizations"), err
+	}
+	return body, nil, nil
+}
+
+// OrderAuthorizations is a Boulder specific endpoint that returns the state of
+// every authorization of an order in one response, so that a client watching
+// an order with many names doesn't have to poll each authorization's URL. The
+// response has an ETag, and if a request's If-None-Match header matches the
+// current state it waits, for up to OrderAuthzLongPoll, for one of the
+// authorizations to change before responding. A GET request that's still
+// unchanged after that is answered with 304 Not Modified.
+func (wfe *WebFrontEndImpl) OrderAuthorizations(ctx context.Context, logEvent *web.RequestEvent, response http.ResponseWriter, request *http.Request) {
+	var requesterAccount *core.Registration
+	// Like the order endpoint, any POSTs must be POST-as-GET requests
+	if request.Method == "POST" {
+		acct, prob := wfe.validPOSTAsGETForAccount(request, ctx, logEvent)
+		if prob != nil {
+			wfe.sendError(response, logEvent, prob, nil)
+			return
+		}
+		requesterAccount = acct
+	}
+
+	// Path prefix is stripped, so this should be like "<account ID>/<order ID>"
+	fields := strings.SplitN(request.URL.Path, "/", 2)
+	if len(fields) != 2 {
+		wfe.sendError(response, logEvent, probs.NotFound("Invalid request path"), nil)
+		return
+	}
+	acctID, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		wfe.sendError(response, logEvent, probs.Malformed("Invalid account ID"), err)
+		return
+	}
+	orderID, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		wfe.sendError(response, logEvent, probs.Malformed("Invalid order ID"), err)
+		return
+	}
+	if requesterAccount != nil && requesterAccount.ID != acctID {
+		wfe.sendError(response, logEvent, probs.NotFound("No order found for account ID %d", acctID), nil)
+		return
+	}
+
+	body, prob, err := wfe.orderAuthzStates(ctx, request, acctID, orderID)
+	if prob != nil {
+		wfe.sendError(response, logEvent, prob, err)
+		return
+	}
+	etag := web.NewCachedResponse(body, time.Time{}).ETag
+
+	if inm := request.Header.Get("If-None-Match"); inm != "" && wfe.OrderAuthzLongPoll > 0 && web.ETagMatches(inm, etag) {
+		interval := wfe.OrderAuthzPollInterval
+		if interval == 0 {
+			interval = defaultOrderAuthzPollInterval
+		}
+		deadline := time.NewTimer(wfe.OrderAuthzLongPoll)
+		defer deadline.Stop()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				break wait
+			case <-deadline.C:
+				break wait
+			case <-ticker.C:
+			}
+			newBody, prob, err := wfe.orderAuthzStates(ctx, request, acctID, orderID)
+			if prob != nil {
+				// Answer with the last state that was fetched rather than
+				// failing a request that's been waiting
+				wfe.log.Warningf("Failed to poll authorizations for order ID %d: %s", orderID, err)
+				break
+			}
+			body = newBody
+			etag = web.NewCachedResponse(body, time.Time{}).ETag
+			if !web.ETagMatches(inm, etag) {
+				break
+			}
+		}
+	}
+
+	if web.CheckNotModified(response, request, etag, time.Time{}) {
+		return
+	}
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusOK)
+	_, err = response.Write(body)
+	if err != nil {
+		wfe.log.Warningf("Could not write response: %s", err)
+		logEvent.AddError(fmt.Sprintf("failed to write response: %s", err))
+	}
+}
+
+// tooManyNamesProblem returns a problem with a subproblem for each of the
+// names beyond limit, or nil if there are no more than limit names. A limit of
+// zero isn't enforced.
+func tooManyNamesProblem(names []string, limit int) *probs.ProblemDetails {
+	if limit == 0 || len(names) <= limit {
+		return nil
+	}
+	prob := probs.Malformed("Certificates can't have more than %d DNS names, but %d were requested", limit, len(names))
+	for _, name := range names[limit:] {
+		prob.WithSubProblems(
+			probs.RejectedIdentifier("Identifier exceeds the limit of %d DNS names", limit).
+				WithIdentifier(string(core.IdentifierDNS), name))
+	}
+	return prob
+}
+
+// checkIssuanceResponse is the body of a response to a CheckIssuance request.
+type checkIssuanceResponse struct {
+	Problems []*probs.ProblemDetails `json:"problems"`
+}
+
+// CheckIssuance is a Boulder specific endpoint that lets an account check
+// whether a certificate could be issued for a set of identifiers and a CSR,
+// without creating an order or issuing anything. It takes the identifiers of
+// a new order and the CSR of a finalize request, and responds with every
+// problem the RA and CA find with them, or an empty list if there are none.
+func (wfe *WebFrontEndImpl) CheckIssuance(ctx context.Context, logEvent *web.RequestEvent, response http.ResponseWriter, request *http.Request) {
+	body, _, acct, prob := wfe.validPOSTForAccount(request, ctx, logEvent)
+	addRequesterHeader(response, logEvent.Requester)
+	if prob != nil {
+		wfe.sendError(response, logEvent, prob, nil)
+		return
+	}
+
+	var checkRequest struct {
+		Identifiers []core.AcmeIdentifier `json:"identifiers"`
+		CSR         core.JSONBuffer       `json:"csr"`
+	}
+	err := json.Unmarshal(body, &checkRequest)
+	if err != nil {
+		wfe.sendError(response, logEvent,
+			probs.Malformed("Unable to unmarshal check issuance request body"), err)
+		return
+	}
+	if len(checkRequest.Identifiers) == 0 {
+		wfe.sendError(response, logEvent,
+			probs.Malformed("Check issuance request did not specify any identifiers"), nil)
+		return
+	}
+	if len(checkRequest.CSR) == 0 {
+		wfe.sendError(response, logEvent,
+			probs.Malformed("Check issuance request did not specify a CSR"), nil)
+		return
+	}
+
+	// Identifiers that aren't DNS names are reported rather than refused, so
+	// that every problem is returned at once.
+	problems := []*probs.ProblemDetails{}
+	var names []string
+	for _, ident := range checkRequest.Identifiers {
+		if ident.Type != core.IdentifierDNS {
+			problems = append(problems,
+				probs.Malformed("Error creating new order :: invalid non-DNS type identifier: type %q, value %q",
+					ident.Type, ident.Value).WithIdentifier(string(ident.Type), ident.Value))
+			continue
+		}
+		names = append(names, ident.Value)
+	}
+
+	raProblems, err := wfe.RA.CheckIssuance(ctx, &rapb.CheckIssuanceRequest{
+		RegistrationID: &acct.ID,
+		Names:          names,
+		Csr:            checkRequest.CSR,
+	})
+	if err != nil {
+		wfe.sendError(response, logEvent, web.ProblemDetailsForError(err, "Error checking issuance"), err)
+		return
+	}
+	problems = append(problems, raProblems...)
+	for _, prob := range problems {
+		prob.AddNamespace(probs.V2ErrorNS)
+	}
+	logEvent.Extra["Problems"] = len(problems)
+
+	err = wfe.writeJsonResponse(response, logEvent, http.StatusOK, checkIssuanceResponse{Problems: problems})
+	if err != nil {
+		wfe.sendError(response, logEvent, probs.ServerInternal("Error marshaling check issuance response"), err)
+		return
+	}
+}
+
 // FinalizeOrder is used to request issuance for a existing order object.
 // Most processing of the order details is handled by the RA but
 // we do attempt to throw away requests with invalid CSRs here.
@@ -1883,8 +2300,12 @@ func (wfe *WebFrontEndImpl) FinalizeOrder(ctx context.Context, logEvent *web.Req
 		return
 	}
 
-	// Only ready orders can be finalized.
-	if *order.Status != string(core.StatusReady) {
+	// Only ready orders can be finalized. Orders that have already been
+	// finalized with a CSR are passed on to the RA too, which returns the
+	// order if it's the same CSR again.
+	finalized := len(order.CsrHash) > 0 &&
+		(*order.Status == string(core.StatusProcessing) || *order.Status == string(core.StatusValid))
+	if *order.Status != string(core.StatusReady) && !finalized {
 		wfe.sendError(response, logEvent,
 			probs.OrderNotReady(
 				"Order's status (%q) is not acceptable for finalization",
@@ -1916,6 +2337,22 @@ func (wfe *WebFrontEndImpl) FinalizeOrder(ctx context.Context, logEvent *web.Req
 		return
 	}
 
+	// Reject CSRs with bad keys, such as ones generated by vulnerable Infineon
+	// hardware, before making any RPCs. The RA and CA check the key again.
+	if err := wfe.keyPolicy.GoodKey(csr.PublicKey); err != nil {
+		wfe.sendError(response, logEvent, probs.BadPublicKey("Invalid key in certificate request :: %s", err), err)
+		return
+	}
+
+	csrNames := csr.DNSNames
+	if csr.Subject.CommonName != "" {
+		csrNames = append([]string{csr.Subject.CommonName}, csrNames...)
+	}
+	if prob := tooManyNamesProblem(core.UniqueLowerNames(csrNames), wfe.NameLimits.ForKey(csr.PublicKey)); prob != nil {
+		wfe.sendError(response, logEvent, prob, nil)
+		return
+	}
+
 	certificateRequest := core.CertificateRequest{Bytes: rawCSR.CSR}
 	certificateRequest.CSR = csr
 	wfe.logCsr(request, certificateRequest, *acct)
@@ -7,6 +7,7 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
 	"encoding/pem"
 	"errors"
@@ -19,6 +20,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -28,6 +30,7 @@ import (
 
 	"github.com/letsencrypt/boulder/core"
 	corepb "github.com/letsencrypt/boulder/core/proto"
+	csrlib "github.com/letsencrypt/boulder/csr"
 	berrors "github.com/letsencrypt/boulder/errors"
 	"github.com/letsencrypt/boulder/features"
 	"github.com/letsencrypt/boulder/goodkey"
@@ -38,6 +41,7 @@ import (
 	"github.com/letsencrypt/boulder/probs"
 	rapb "github.com/letsencrypt/boulder/ra/proto"
 	"github.com/letsencrypt/boulder/revocation"
+	sapb "github.com/letsencrypt/boulder/sa/proto"
 	"github.com/letsencrypt/boulder/test"
 	"github.com/letsencrypt/boulder/web"
 )
@@ -277,6 +281,34 @@ func (ra *MockRegistrationAuthority) FinalizeOrder(ctx context.Context, req *rap
 	return req.Order, nil
 }
 
+// CheckIssuance reports a problem for each name starting with "bad."
+func (ra *MockRegistrationAuthority) CheckIssuance(ctx context.Context, req *rapb.CheckIssuanceRequest) ([]*probs.ProblemDetails, error) {
+	var problems []*probs.ProblemDetails
+	for _, name := range req.Names {
+		if strings.HasPrefix(name, "bad.") {
+			problems = append(problems, probs.RejectedIdentifier("Error creating new order :: %s is forbidden", name).WithIdentifier("dns", name))
+		}
+	}
+	return problems, nil
+}
+
+// RateLimitUsage reports the account's orders, and a certificatesPerName usage
+// for each name
+func (ra *MockRegistrationAuthority) RateLimitUsage(ctx context.Context, req *rapb.RateLimitUsageRequest) (*rapb.RateLimitUsageResponse, error) {
+	newOrders, week := "newOrdersPerAccount", (7 * 24 * time.Hour).Nanoseconds()
+	count, threshold := int64(3), int64(300)
+	resp := &rapb.RateLimitUsageResponse{Usages: []*rapb.RateLimitUsage{
+		{Limit: &newOrders, Count: &count, Threshold: &threshold, Window: &week},
+	}}
+	for i := range req.Names {
+		limit := "certificatesPerName"
+		resp.Usages = append(resp.Usages, &rapb.RateLimitUsage{
+			Limit: &limit, Key: &req.Names[i], Count: &count, Threshold: &threshold, Window: &week,
+		})
+	}
+	return resp, nil
+}
+
 type mockPA struct{}
 
 func (pa *mockPA) ChallengesFor(identifier core.AcmeIdentifier) (challenges []core.Challenge, combinations [][]int, err error) {
@@ -291,6 +323,10 @@ func (pa *mockPA) WillingToIssueWildcard(id core.AcmeIdentifier) error {
 	return nil
 }
 
+func (pa *mockPA) CheckOrderNames(names []string) error {
+	return nil
+}
+
 func makeBody(s string) io.ReadCloser {
 	return ioutil.NopCloser(strings.NewReader(s))
 }
@@ -379,7 +415,8 @@ func makePostRequestWithPath(path string, body string) *http.Request {
 	}
 	url := mustParseURL(path)
 	request.URL = url
-	request.RequestURI = url.Path
+	// Request URIs received by a server always begin with a slash
+	request.RequestURI = "/" + strings.TrimPrefix(url.RequestURI(), "/")
 	return request
 }
 
@@ -770,6 +807,50 @@ func TestDirectory(t *testing.T) {
 		true)
 }
 
+func TestDirectoryConditional(t *testing.T) {
+	wfe, _ := setupWFE(t)
+	mux := wfe.Handler()
+
+	makeGet := func(headers map[string]string) *http.Request {
+		req := &http.Request{
+			Method: "GET",
+			URL:    mustParseURL(directoryPath),
+			Host:   "localhost:4300",
+			Header: make(http.Header),
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		return req
+	}
+
+	first := httptest.NewRecorder()
+	mux.ServeHTTP(first, makeGet(nil))
+	test.AssertEquals(t, first.Code, http.StatusOK)
+	etag := first.Header().Get("ETag")
+	test.AssertNotEquals(t, etag, "")
+	test.AssertNotEquals(t, first.Header().Get("Last-Modified"), "")
+
+	// The cached directory, including its random key, is served again
+	second := httptest.NewRecorder()
+	mux.ServeHTTP(second, makeGet(nil))
+	test.AssertEquals(t, second.Header().Get("ETag"), etag)
+	test.AssertEquals(t, second.Body.String(), first.Body.String())
+
+	notModified := httptest.NewRecorder()
+	mux.ServeHTTP(notModified, makeGet(map[string]string{"If-None-Match": etag}))
+	test.AssertEquals(t, notModified.Code, http.StatusNotModified)
+	test.AssertEquals(t, notModified.Body.Len(), 0)
+
+	// Changing the meta entries changes the directory
+	wfe.DirectoryWebsite = "zombo.com"
+	changed := httptest.NewRecorder()
+	mux.ServeHTTP(changed, makeGet(map[string]string{"If-None-Match": etag}))
+	test.AssertEquals(t, changed.Code, http.StatusOK)
+	test.AssertNotEquals(t, changed.Header().Get("ETag"), etag)
+	test.Assert(t, strings.Contains(changed.Body.String(), "zombo.com"), "directory didn't include the new website")
+}
+
 func TestRelativeDirectory(t *testing.T) {
 	wfe, _ := setupWFE(t)
 	mux := wfe.Handler()
@@ -882,6 +963,145 @@ func TestNonceEndpointGET(t *testing.T) {
 	}
 }
 
+func TestThrottle(t *testing.T) {
+	wfe, fc := setupWFE(t)
+	var err error
+	wfe.Throttle, err = web.NewThrottle(fc, web.ThrottleConfig{RequestsPerSecond: 1, Burst: 1})
+	test.AssertNotError(t, err, "Couldn't create throttle")
+	mux := wfe.Handler()
+
+	nonceRequest := func(remoteAddr string) *httptest.ResponseRecorder {
+		responseWriter := httptest.NewRecorder()
+		mux.ServeHTTP(responseWriter, &http.Request{
+			Method:     "GET",
+			URL:        mustParseURL(newNoncePath),
+			RemoteAddr: remoteAddr,
+		})
+		return responseWriter
+	}
+
+	responseWriter := nonceRequest("10.0.0.1:1234")
+	test.AssertEquals(t, responseWriter.Code, http.StatusNoContent)
+
+	// The client's second request is refused, without a nonce
+	responseWriter = nonceRequest("10.0.0.1:1234")
+	test.AssertEquals(t, responseWriter.Code, http.StatusTooManyRequests)
+	test.AssertEquals(t, responseWriter.Header().Get("Retry-After"), "1")
+	test.AssertEquals(t, responseWriter.Header().Get("Replay-Nonce"), "")
+	test.AssertUnmarshaledEquals(t, responseWriter.Body.String(), `{
+		"type": "`+probs.V2ErrorNS+`rateLimited",
+		"detail": "Too many requests from your IP address, retry later",
+		"status": 429
+	}`)
+	test.AssertEquals(t, test.CountCounterVec("endpoint", newNoncePath, wfe.stats.throttledRequests), 1)
+
+	// Other clients and unthrottled endpoints aren't affected
+	responseWriter = nonceRequest("10.0.0.2:1234")
+	test.AssertEquals(t, responseWriter.Code, http.StatusNoContent)
+	responseWriter = httptest.NewRecorder()
+	mux.ServeHTTP(responseWriter, &http.Request{
+		Method:     "GET",
+		URL:        mustParseURL(directoryPath),
+		RemoteAddr: "10.0.0.1:1234",
+	})
+	test.AssertEquals(t, responseWriter.Code, http.StatusOK)
+}
+
+func TestMaintenance(t *testing.T) {
+	wfe, _ := setupWFE(t)
+	wfe.Maintenance = web.NewMaintenance(blog.NewMock())
+	wfe.Maintenance.Set(true, "database upgrade", 30*time.Minute)
+	mux := wfe.Handler()
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		responseWriter := httptest.NewRecorder()
+		mux.ServeHTTP(responseWriter, &http.Request{
+			Method: method,
+			URL:    mustParseURL(path),
+			Body:   ioutil.NopCloser(strings.NewReader("{}")),
+		})
+		return responseWriter
+	}
+
+	responseWriter := request("POST", newOrderPath)
+	test.AssertEquals(t, responseWriter.Code, http.StatusServiceUnavailable)
+	test.AssertEquals(t, responseWriter.Header().Get("Retry-After"), "1800")
+	test.AssertUnmarshaledEquals(t, responseWriter.Body.String(), `{
+		"type": "`+probs.V2ErrorNS+`serverInternal",
+		"detail": "The service is down for maintenance, retry later: database upgrade",
+		"status": 503
+	}`)
+
+	// GETs and certificate downloads aren't refused
+	responseWriter = request("GET", directoryPath)
+	test.AssertEquals(t, responseWriter.Code, http.StatusOK)
+	responseWriter = request("POST", certPath)
+	test.AssertNotEquals(t, responseWriter.Code, http.StatusServiceUnavailable)
+
+	wfe.Maintenance.Set(false, "", 0)
+	responseWriter = request("POST", newOrderPath)
+	test.AssertNotEquals(t, responseWriter.Code, http.StatusServiceUnavailable)
+}
+
+// fakeAbuseScorer gives every request the same score, recording the signals
+// it was given.
+type fakeAbuseScorer struct {
+	score   float64
+	signals []web.AbuseSignals
+}
+
+func (s *fakeAbuseScorer) Score(_ context.Context, signals web.AbuseSignals) (web.AbuseScore, error) {
+	s.signals = append(s.signals, signals)
+	return web.AbuseScore{Score: s.score, Reason: "test"}, nil
+}
+
+func TestAbuseHook(t *testing.T) {
+	wfe, fc := setupWFE(t)
+	scorer := &fakeAbuseScorer{}
+	var err error
+	wfe.AbuseHook, err = web.NewAbuseHook(scorer, web.AbusePolicy{
+		ChallengeScore: 50,
+		ChallengeURL:   "https://example.com/captcha",
+		RejectScore:    100,
+	}, fc, blog.NewMock())
+	test.AssertNotError(t, err, "Couldn't create abuse hook")
+
+	newOrder := func() *httptest.ResponseRecorder {
+		responseWriter := httptest.NewRecorder()
+		request := signAndPost(t, "new-order", "http://localhost/new-order",
+			`{"Identifiers": [{"type": "dns", "value": "not-example.com"}]}`, 1, wfe.nonceService)
+		request.Header.Set("User-Agent", "evil-acme-bot/1.0")
+		wfe.NewOrder(ctx, newRequestEvent(), responseWriter, request)
+		return responseWriter
+	}
+
+	responseWriter := newOrder()
+	test.AssertEquals(t, responseWriter.Code, http.StatusCreated)
+	test.AssertEquals(t, len(scorer.signals), 1)
+	key := loadKey(t, []byte(test1KeyPrivatePEM))
+	thumbprint, err := (&jose.JSONWebKey{Key: key.Public()}).Thumbprint(crypto.SHA256)
+	test.AssertNotError(t, err, "Couldn't compute thumbprint")
+	test.AssertEquals(t, scorer.signals[0].KeyThumbprint, base64.RawURLEncoding.EncodeToString(thumbprint))
+	test.AssertEquals(t, scorer.signals[0].RegistrationID, int64(1))
+	test.AssertEquals(t, scorer.signals[0].UserAgent, "evil-acme-bot/1.0")
+
+	scorer.score = 50
+	responseWriter = newOrder()
+	test.AssertUnmarshaledEquals(t, responseWriter.Body.String(), `{
+		"type": "`+probs.V2ErrorNS+`userActionRequired",
+		"detail": "Your requests look automated. Visit https://example.com/captcha to continue",
+		"status": 403
+	}`)
+
+	scorer.score = 100
+	responseWriter = newOrder()
+	test.AssertUnmarshaledEquals(t, responseWriter.Body.String(), `{
+		"type": "`+probs.V2ErrorNS+`unauthorized",
+		"detail": "Your requests have been refused because they look automated and abusive",
+		"status": 403
+	}`)
+}
+
 func TestHTTPMethods(t *testing.T) {
 	wfe, _ := setupWFE(t)
 	mux := wfe.Handler()
@@ -1258,7 +1478,7 @@ func TestNewECDSAAccount(t *testing.T) {
 	responseWriter = httptest.NewRecorder()
 	// POST, Valid JSON, Key already in use
 	wfe.NewAccount(ctx, newRequestEvent(), responseWriter, request)
-	test.AssertEquals(t, responseWriter.Body.String(), "{\n  \"id\": 3,\n  \"key\": {\n    \"kty\": \"EC\",\n    \"crv\": \"P-256\",\n    \"x\": \"FwvSZpu06i3frSk_mz9HcD9nETn4wf3mQ-zDtG21Gao\",\n    \"y\": \"S8rR-0dWa8nAcw1fbunF_ajS3PQZ-QwLps-2adgLgPk\"\n  },\n  \"agreement\": \"http://example.invalid/terms\",\n  \"initialIp\": \"\",\n  \"createdAt\": \"0001-01-01T00:00:00Z\",\n  \"status\": \"\"\n}")
+	test.AssertEquals(t, responseWriter.Body.String(), "{\n  \"id\": 3,\n  \"key\": {\n    \"kty\": \"EC\",\n    \"crv\": \"P-256\",\n    \"x\": \"FwvSZpu06i3frSk_mz9HcD9nETn4wf3mQ-zDtG21Gao\",\n    \"y\": \"S8rR-0dWa8nAcw1fbunF_ajS3PQZ-QwLps-2adgLgPk\"\n  },\n  \"initialIp\": \"\",\n  \"createdAt\": \"0001-01-01T00:00:00Z\",\n  \"status\": \"\"\n}")
 	test.AssertEquals(t, responseWriter.Header().Get("Location"), "http://localhost/acme/acct/3")
 	test.AssertEquals(t, responseWriter.Code, 200)
 }
@@ -1410,7 +1630,7 @@ func TestNewAccount(t *testing.T) {
 		t, responseWriter.Header().Get("Location"),
 		"http://localhost/acme/acct/1")
 	test.AssertEquals(t, responseWriter.Code, 200)
-	test.AssertEquals(t, responseWriter.Body.String(), "{\n  \"id\": 1,\n  \"key\": {\n    \"kty\": \"RSA\",\n    \"n\": \"yNWVhtYEKJR21y9xsHV-PD_bYwbXSeNuFal46xYxVfRL5mqha7vttvjB_vc7Xg2RvgCxHPCqoxgMPTzHrZT75LjCwIW2K_klBYN8oYvTwwmeSkAz6ut7ZxPv-nZaT5TJhGk0NT2kh_zSpdriEJ_3vW-mqxYbbBmpvHqsa1_zx9fSuHYctAZJWzxzUZXykbWMWQZpEiE0J4ajj51fInEzVn7VxV-mzfMyboQjujPh7aNJxAWSq4oQEJJDgWwSh9leyoJoPpONHxh5nEE5AjE01FkGICSxjpZsF-w8hOTI3XXohUdu29Se26k2B0PolDSuj0GIQU6-W9TdLXSjBb2SpQ\",\n    \"e\": \"AQAB\"\n  },\n  \"contact\": [\n    \"mailto:person@mail.com\"\n  ],\n  \"agreement\": \"http://example.invalid/terms\",\n  \"initialIp\": \"\",\n  \"createdAt\": \"0001-01-01T00:00:00Z\",\n  \"status\": \"valid\"\n}")
+	test.AssertEquals(t, responseWriter.Body.String(), "{\n  \"id\": 1,\n  \"key\": {\n    \"kty\": \"RSA\",\n    \"n\": \"yNWVhtYEKJR21y9xsHV-PD_bYwbXSeNuFal46xYxVfRL5mqha7vttvjB_vc7Xg2RvgCxHPCqoxgMPTzHrZT75LjCwIW2K_klBYN8oYvTwwmeSkAz6ut7ZxPv-nZaT5TJhGk0NT2kh_zSpdriEJ_3vW-mqxYbbBmpvHqsa1_zx9fSuHYctAZJWzxzUZXykbWMWQZpEiE0J4ajj51fInEzVn7VxV-mzfMyboQjujPh7aNJxAWSq4oQEJJDgWwSh9leyoJoPpONHxh5nEE5AjE01FkGICSxjpZsF-w8hOTI3XXohUdu29Se26k2B0PolDSuj0GIQU6-W9TdLXSjBb2SpQ\",\n    \"e\": \"AQAB\"\n  },\n  \"contact\": [\n    \"mailto:person@mail.com\"\n  ],\n  \"initialIp\": \"\",\n  \"createdAt\": \"0001-01-01T00:00:00Z\",\n  \"status\": \"valid\"\n}")
 }
 
 func TestGetAuthorization(t *testing.T) {
@@ -1689,6 +1909,123 @@ func TestAccount(t *testing.T) {
 	}`)
 }
 
+// mockRAUpdateCapture records the update of the last UpdateRegistration call
+type mockRAUpdateCapture struct {
+	MockRegistrationAuthority
+	update core.Registration
+}
+
+func (ra *mockRAUpdateCapture) UpdateRegistration(ctx context.Context, acct core.Registration, update core.Registration) (core.Registration, error) {
+	ra.update = update
+	return ra.MockRegistrationAuthority.UpdateRegistration(ctx, acct, update)
+}
+
+func TestCheckIssuance(t *testing.T) {
+	wfe, _ := setupWFE(t)
+	csr := "MIICYjCCAUoCAQAwHTEbMBkGA1UEAwwSbm90LWFuLWV4YW1wbGUuY29tMIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAmqs7nue5oFxKBk2WaFZJAma2nm1oFyPIq19gYEAdQN4mWvaJ8RjzHFkDMYUrlIrGxCYuFJDHFUk9dh19Na1MIY-NVLgcSbyNcOML3bLbLEwGmvXPbbEOflBA9mxUS9TLMgXW5ghf_qbt4vmSGKloIim41QXt55QFW6O-84s8Kd2OE6df0wTsEwLhZB3j5pDU-t7j5vTMv4Tc7EptaPkOdfQn-68viUJjlYM_4yIBVRhWCdexFdylCKVLg0obsghQEwULKYCUjdg6F0VJUI115DU49tzscXU_3FS3CyY8rchunuYszBNkdmgpAwViHNWuP7ESdEd_emrj1xuioSe6PwIDAQABoAAwDQYJKoZIhvcNAQELBQADggEBAE_T1nWU38XVYL28hNVSXU0rW5IBUKtbvr0qAkD4kda4HmQRTYkt-LNSuvxoZCC9lxijjgtJi-OJe_DCTdZZpYzewlVvcKToWSYHYQ6Wm1-fxxD_XzphvZOujpmBySchdiz7QSVWJmVZu34XD5RJbIcrmj_cjRt42J1hiTFjNMzQu9U6_HwIMmliDL-soFY2RTvvZf-dAFvOUQ-Wbxt97eM1PbbmxJNWRhbAmgEpe9PWDPTpqV5AK56VAa991cQ1P8ZVmPss5hvwGWhOtpnpTZVHN3toGNYFKqxWPboirqushQlfKiFqT9rpRgM3-mFjOHidGqsKEkTdmfSVlVEk3oo="
+
+	testCases := []struct {
+		Name         string
+		Body         string
+		ExpectedCode int
+		ExpectedBody string
+	}{
+		{
+			Name:         "no identifiers",
+			Body:         `{"csr": "` + csr + `"}`,
+			ExpectedCode: http.StatusBadRequest,
+			ExpectedBody: `{"type":"` + probs.V2ErrorNS + `malformed","detail":"Check issuance request did not specify any identifiers","status":400}`,
+		},
+		{
+			Name:         "no CSR",
+			Body:         `{"identifiers": [{"type": "dns", "value": "not-an-example.com"}]}`,
+			ExpectedCode: http.StatusBadRequest,
+			ExpectedBody: `{"type":"` + probs.V2ErrorNS + `malformed","detail":"Check issuance request did not specify a CSR","status":400}`,
+		},
+		{
+			Name:         "no problems",
+			Body:         `{"identifiers": [{"type": "dns", "value": "not-an-example.com"}], "csr": "` + csr + `"}`,
+			ExpectedCode: http.StatusOK,
+			ExpectedBody: `{"problems":[]}`,
+		},
+		{
+			Name: "every problem",
+			Body: `{"identifiers": [
+				{"type": "ip", "value": "10.0.0.1"},
+				{"type": "dns", "value": "bad.example.com"}
+			], "csr": "` + csr + `"}`,
+			ExpectedCode: http.StatusOK,
+			ExpectedBody: `{"problems":[
+				{
+					"type":"` + probs.V2ErrorNS + `malformed",
+					"detail":"Error creating new order :: invalid non-DNS type identifier: type \"ip\", value \"10.0.0.1\"",
+					"status":400,
+					"identifier":{"type":"ip","value":"10.0.0.1"}
+				},
+				{
+					"type":"` + probs.V2ErrorNS + `rejectedIdentifier",
+					"detail":"Error creating new order :: bad.example.com is forbidden",
+					"status":400,
+					"identifier":{"type":"dns","value":"bad.example.com"}
+				}
+			]}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			responseWriter := httptest.NewRecorder()
+			_, _, body := signRequestKeyID(t, 1, nil, "http://localhost/check-issuance", tc.Body, wfe.nonceService)
+			wfe.CheckIssuance(ctx, newRequestEvent(), responseWriter, makePostRequestWithPath("check-issuance", body))
+			test.AssertEquals(t, responseWriter.Code, tc.ExpectedCode)
+			test.AssertUnmarshaledEquals(t, responseWriter.Body.String(), tc.ExpectedBody)
+		})
+	}
+}
+
+func TestTOSReacceptance(t *testing.T) {
+	wfe, fc := setupWFE(t)
+	newTerms := "http://example.invalid/terms-v2"
+	wfe.SubscriberAgreementURL = newTerms
+	wfe.TOSReacceptanceDeadline = fc.Now().Add(time.Hour)
+	orderBody := `{"Identifiers": [{"type": "dns", "value": "not-example.com"}]}`
+
+	newOrder := func() *httptest.ResponseRecorder {
+		responseWriter := httptest.NewRecorder()
+		_, _, body := signRequestKeyID(t, 1, nil, "http://localhost/new-order", orderBody, wfe.nonceService)
+		wfe.NewOrder(ctx, newRequestEvent(), responseWriter, makePostRequestWithPath("new-order", body))
+		return responseWriter
+	}
+
+	// Accounts that agreed to the old terms can create orders until the
+	// deadline
+	responseWriter := newOrder()
+	test.AssertEquals(t, responseWriter.Code, http.StatusCreated)
+
+	fc.Add(2 * time.Hour)
+	responseWriter = newOrder()
+	test.AssertEquals(t, responseWriter.Code, http.StatusForbidden)
+	test.AssertEquals(t, responseWriter.Header().Get("Link"), link(newTerms, "terms-of-service"))
+	var prob probs.ProblemDetails
+	err := json.Unmarshal(responseWriter.Body.Bytes(), &prob)
+	test.AssertNotError(t, err, "unmarshalling problem")
+	test.AssertEquals(t, string(prob.Type), probs.V2ErrorNS+string(probs.UserActionRequiredProblem))
+	test.AssertContains(t, prob.Detail, newTerms)
+
+	// Agreeing to the terms of service records the current terms
+	ra := &mockRAUpdateCapture{}
+	wfe.RA = ra
+	responseWriter = httptest.NewRecorder()
+	_, _, body := signRequestKeyID(t, 1, nil, "http://localhost/1", `{"termsOfServiceAgreed": true}`, wfe.nonceService)
+	wfe.Account(ctx, newRequestEvent(), responseWriter, makePostRequestWithPath("1", body))
+	test.AssertEquals(t, responseWriter.Code, http.StatusOK)
+	test.AssertEquals(t, ra.update.Agreement, newTerms)
+
+	// Accounts that agreed to the current terms can create orders
+	wfe.SubscriberAgreementURL = agreementURL
+	responseWriter = newOrder()
+	test.AssertEquals(t, responseWriter.Code, http.StatusCreated)
+}
+
 func TestIssuer(t *testing.T) {
 	wfe, _ := setupWFE(t)
 	wfe.IssuerCert = []byte{0, 0, 1}
@@ -1845,6 +2182,39 @@ func TestGetCertificate(t *testing.T) {
 
 // This uses httptest.NewServer because ServeMux.ServeHTTP won't prevent the
 // body from being sent like the net/http Server's actually do.
+func TestGetCertificateConditional(t *testing.T) {
+	wfe, _ := setupWFE(t)
+	mux := wfe.Handler()
+
+	makeGet := func(headers map[string]string) *http.Request {
+		req := &http.Request{
+			URL:    &url.URL{Path: "/acme/cert/0000000000000000000000000000000000b2"},
+			Method: "GET",
+			Header: make(http.Header),
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		return req
+	}
+
+	first := httptest.NewRecorder()
+	mux.ServeHTTP(first, makeGet(nil))
+	test.AssertEquals(t, first.Code, http.StatusOK)
+	etag := first.Header().Get("ETag")
+	test.AssertNotEquals(t, etag, "")
+
+	notModified := httptest.NewRecorder()
+	mux.ServeHTTP(notModified, makeGet(map[string]string{"If-None-Match": etag}))
+	test.AssertEquals(t, notModified.Code, http.StatusNotModified)
+	test.AssertEquals(t, notModified.Body.Len(), 0)
+
+	stale := httptest.NewRecorder()
+	mux.ServeHTTP(stale, makeGet(map[string]string{"If-None-Match": `"stale"`}))
+	test.AssertEquals(t, stale.Code, http.StatusOK)
+	test.AssertEquals(t, stale.Body.String(), first.Body.String())
+}
+
 func TestGetCertificateHEADHasCorrectBodyLength(t *testing.T) {
 	wfe, _ := setupWFE(t)
 
@@ -2033,6 +2403,40 @@ func TestDeactivateAccount(t *testing.T) {
 		}`)
 }
 
+func TestNewOrderNameLimits(t *testing.T) {
+	wfe, _ := setupWFE(t)
+	wfe.NameLimits = csrlib.NameLimits{Default: 1}
+	responseWriter := httptest.NewRecorder()
+
+	body := `{"identifiers": [
+		{"type": "dns", "value": "a.not-example.com"},
+		{"type": "dns", "value": "b.not-example.com"},
+		{"type": "dns", "value": "c.not-example.com"}
+	]}`
+	wfe.NewOrder(ctx, newRequestEvent(), responseWriter,
+		signAndPost(t, "new-order", "http://localhost/new-order", body, 1, wfe.nonceService))
+	test.AssertEquals(t, responseWriter.Code, http.StatusBadRequest)
+	test.AssertUnmarshaledEquals(t, responseWriter.Body.String(), `{
+		"type": "`+probs.V2ErrorNS+`malformed",
+		"detail": "Certificates can't have more than 1 DNS names, but 3 were requested",
+		"status": 400,
+		"subproblems": [
+			{
+				"type": "`+probs.V2ErrorNS+`rejectedIdentifier",
+				"detail": "Identifier exceeds the limit of 1 DNS names",
+				"status": 400,
+				"identifier": {"type": "dns", "value": "b.not-example.com"}
+			},
+			{
+				"type": "`+probs.V2ErrorNS+`rejectedIdentifier",
+				"detail": "Identifier exceeds the limit of 1 DNS names",
+				"status": 400,
+				"identifier": {"type": "dns", "value": "c.not-example.com"}
+			}
+		]
+	}`)
+}
+
 func TestNewOrder(t *testing.T) {
 	wfe, _ := setupWFE(t)
 	responseWriter := httptest.NewRecorder()
@@ -2118,6 +2522,29 @@ func TestNewOrder(t *testing.T) {
 						"finalize": "http://localhost/acme/finalize/1/1"
 					}`,
 		},
+		{
+			Name:    "POST, names are normalized",
+			Request: signAndPost(t, targetPath, signedURL, `{"identifiers":[{"type": "dns", "value": "WWW.Not-Example.com."}, {"type": "dns", "value": "bücher.example"}, {"type": "dns", "value": "not-example.com"}, {"type": "dns", "value": "www.not-example.com"}]}`, 1, wfe.nonceService),
+			ExpectedBody: `
+					{
+						"status": "pending",
+						"expires": "1970-01-01T00:00:00Z",
+						"identifiers": [
+							{ "type": "dns", "value": "not-example.com"},
+							{ "type": "dns", "value": "www.not-example.com"},
+							{ "type": "dns", "value": "xn--bcher-kva.example"}
+						],
+						"authorizations": [
+							"http://localhost/acme/authz/hello"
+						],
+						"finalize": "http://localhost/acme/finalize/1/1"
+					}`,
+		},
+		{
+			Name:         "POST, invalid internationalized name",
+			Request:      signAndPost(t, targetPath, signedURL, `{"identifiers":[{"type": "dns", "value": "bad\u200dname.example"}]}`, 1, wfe.nonceService),
+			ExpectedBody: `{"type":"` + probs.V2ErrorNS + `malformed","detail":"Error creating new order :: DNS name \"bad\\u200dname.example\" is not a valid internationalized domain name","status":400}`,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -2285,6 +2712,15 @@ func TestFinalizeOrder(t *testing.T) {
 	wfe.FinalizeOrder(ctx, newRequestEvent(), responseWriter, badCSRReq)
 	responseBody := responseWriter.Body.String()
 	test.AssertContains(t, responseBody, "Error parsing certificate request")
+
+	// A CSR for a key generated by vulnerable Infineon hardware (ROCA) is
+	// rejected before it reaches the RA. This is ca/testdata/roca_key.der.csr.
+	rocaCSRReq := signAndPost(t, "1/8", "http://localhost/1/8", `{"CSR": "MIICjDCCAXQCAQAwGjEYMBYGA1UEAxMPbm90LWV4YW1wbGUuY29tMIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAlze9c7qGdjDLVR_ntk-4ZkfMcYsAnmfTFHfe3Xv7jRQqPCXCULtr0y0jG3aRJmEenoXO9uDveqr43gFB9yvAdLEhu0aJqpB7lNZ-yXsvfVp_96dkSN8oWYL_dd9Z7GQOvVniHUY3Xsd7zdw2eYOyHSXhhA2Ttwnj3c1jEYfC0y9q1cU99aL0ogGDqolcOvlkJu-mGb-6-WyboFa1gwRukYxBHZWKiHCt_eihvXsPTzTlXmTXWdGJtA1xZDnCBWuZ90b5R0agXVIESTl0cCyHaQM_tLZmktJIU-Eu7ALBXemPg9kh3SCnYd3_YvDGCtYSXOWthHwlP5CImRBcQaNncQIDAQABoC0wKwYJKoZIhvcNAQkOMR4wHDAaBgNVHREEEzARgg9ub3QtZXhhbXBsZS5jb20wDQYJKoZIhvcNAQELBQADggEBADZH8vlS7h1FbsR9xt8pMYLQyVQWi9jJG9FFOhE7so0M1pWESPCMUArlcpaOuLmhuCpIS8EEWZXGQ1dVRUT9VFHX1WhO-pn-BMo3SZZD-AZ_e7OD87-JfzSYk749aVZ6ZDRZOjawVfXUtTAsqZeWSXBx9ftIoGgYxziUAlxq1ssz5ydgc7e1NKwKchdVJiIE4P4qAHEEsDUwJRe7t-s4fajONda0Rwp-4l9LqgVemjJRcBNwZ4Ui9lMrgxrniOhehF6Dw8R4pkwcA2VdKmrvJsFCh7gC7LUJ5lAny_IIjy18fHppzYVeQ91XhCQ8bWqMHW6soNzSqhlPcXLf-8cTU6M"}`, 1, wfe.nonceService)
+	responseWriter.Body.Reset()
+	responseWriter.HeaderMap = http.Header{}
+	wfe.FinalizeOrder(ctx, newRequestEvent(), responseWriter, rocaCSRReq)
+	test.AssertUnmarshaledEquals(t, responseWriter.Body.String(),
+		`{"type":"`+probs.V2ErrorNS+`badPublicKey","detail":"Invalid key in certificate request :: key generated by vulnerable Infineon-based hardware","status":400}`)
 }
 
 func TestKeyRollover(t *testing.T) {
@@ -2461,6 +2897,115 @@ func TestGetOrder(t *testing.T) {
 	}
 }
 
+// mockSAOrderAuthzs is a mock SA whose order 1 of account 1 has two
+// authorizations, one of which becomes valid once GetOrderAuthorizations has
+// been called pendingCalls times.
+type mockSAOrderAuthzs struct {
+	core.StorageGetter
+	mu           sync.Mutex
+	calls        int
+	pendingCalls int
+}
+
+func (sa *mockSAOrderAuthzs) GetOrderAuthorizations(_ context.Context, req *sapb.GetOrderAuthorizationsRequest) (map[string]*core.Authorization, error) {
+	if *req.Id == 2 {
+		return nil, errors.New("broken")
+	}
+	if *req.Id != 1 || *req.AcctID != 1 {
+		return nil, nil
+	}
+	sa.mu.Lock()
+	defer sa.mu.Unlock()
+	sa.calls++
+	status := core.StatusPending
+	if sa.calls > sa.pendingCalls {
+		status = core.StatusValid
+	}
+	expires := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	return map[string]*core.Authorization{
+		"b.com": {
+			ID:         "b",
+			Identifier: core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "b.com"},
+			Status:     status,
+			Expires:    &expires,
+		},
+		"a.com": {
+			ID:         "a",
+			Identifier: core.AcmeIdentifier{Type: core.IdentifierDNS, Value: "a.com"},
+			Status:     core.StatusValid,
+			Expires:    &expires,
+		},
+	}, nil
+}
+
+func TestOrderAuthorizations(t *testing.T) {
+	wfe, fc := setupWFE(t)
+	sa := &mockSAOrderAuthzs{StorageGetter: mocks.NewStorageAuthority(fc), pendingCalls: 1}
+	wfe.SA = sa
+
+	makeGet := func(path, etag string) *http.Request {
+		req := &http.Request{URL: &url.URL{Path: path}, Method: "GET", Header: http.Header{}}
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		return req
+	}
+	pending := `{"authorizations":[
+		{"url":"http://localhost/acme/authz/a","identifier":{"type":"dns","value":"a.com"},"status":"valid","expires":"2019-03-01T00:00:00Z"},
+		{"url":"http://localhost/acme/authz/b","identifier":{"type":"dns","value":"b.com"},"status":"pending","expires":"2019-03-01T00:00:00Z"}]}`
+	valid := strings.Replace(pending, `"pending"`, `"valid"`, 1)
+
+	for _, tc := range []struct {
+		name     string
+		path     string
+		response string
+	}{
+		{"Unknown order", "1/3", `{"type":"` + probs.V2ErrorNS + `malformed","detail":"No order for ID 3","status":404}`},
+		{"Wrong account", "2/1", `{"type":"` + probs.V2ErrorNS + `malformed","detail":"No order for ID 1","status":404}`},
+		{"Invalid order ID", "1/asd", `{"type":"` + probs.V2ErrorNS + `malformed","detail":"Invalid order ID","status":400}`},
+		{"Internal error", "1/2", `{"type":"` + probs.V2ErrorNS + `serverInternal","detail":"Failed to retrieve authorizations for order ID 2","status":500}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			responseWriter := httptest.NewRecorder()
+			wfe.OrderAuthorizations(ctx, newRequestEvent(), responseWriter, makeGet(tc.path, ""))
+			test.AssertUnmarshaledEquals(t, responseWriter.Body.String(), tc.response)
+		})
+	}
+
+	// An unconditional request is answered immediately
+	responseWriter := httptest.NewRecorder()
+	wfe.OrderAuthorizations(ctx, newRequestEvent(), responseWriter, makeGet("1/1", ""))
+	test.AssertEquals(t, responseWriter.Code, http.StatusOK)
+	test.AssertUnmarshaledEquals(t, responseWriter.Body.String(), pending)
+	pendingETag := responseWriter.Header().Get("ETag")
+	test.Assert(t, pendingETag != "", "response had no ETag")
+
+	// Without a long poll configured, a conditional request for the current
+	// state is answered immediately with 304 Not Modified
+	sa.pendingCalls = 3
+	responseWriter = httptest.NewRecorder()
+	wfe.OrderAuthorizations(ctx, newRequestEvent(), responseWriter, makeGet("1/1", pendingETag))
+	test.AssertEquals(t, responseWriter.Code, http.StatusNotModified)
+	test.AssertEquals(t, responseWriter.Body.String(), "")
+
+	// With a long poll, it waits for the authorization to become valid
+	wfe.OrderAuthzLongPoll = time.Minute
+	wfe.OrderAuthzPollInterval = time.Millisecond
+	responseWriter = httptest.NewRecorder()
+	wfe.OrderAuthorizations(ctx, newRequestEvent(), responseWriter, makeGet("1/1", pendingETag))
+	test.AssertEquals(t, responseWriter.Code, http.StatusOK)
+	test.AssertUnmarshaledEquals(t, responseWriter.Body.String(), valid)
+	test.Assert(t, responseWriter.Header().Get("ETag") != pendingETag, "ETag didn't change with the state")
+	test.AssertEquals(t, sa.calls, 4)
+
+	// If nothing changes before the long poll ends, it's answered with 304
+	wfe.OrderAuthzLongPoll = 5 * time.Millisecond
+	validETag := responseWriter.Header().Get("ETag")
+	responseWriter = httptest.NewRecorder()
+	wfe.OrderAuthorizations(ctx, newRequestEvent(), responseWriter, makeGet("1/1", validETag))
+	test.AssertEquals(t, responseWriter.Code, http.StatusNotModified)
+}
+
 func makeRevokeRequestJSON(reason *revocation.Reason) ([]byte, error) {
 	certPemBytes, err := ioutil.ReadFile("test/238.crt")
 	if err != nil {
@@ -2558,13 +3103,13 @@ func TestRevokeCertificateReasons(t *testing.T) {
 			Name:             "Unsupported reason",
 			Reason:           &reason2,
 			ExpectedHTTPCode: http.StatusBadRequest,
-			ExpectedBody:     `{"type":"` + probs.V2ErrorNS + `malformed","detail":"unsupported revocation reason code provided","status":400}`,
+			ExpectedBody:     `{"type":"` + probs.V2ErrorNS + `badRevocationReason","detail":"unsupported revocation reason code provided","status":400}`,
 		},
 		{
 			Name:             "Non-existent reason",
 			Reason:           &reason100,
 			ExpectedHTTPCode: http.StatusBadRequest,
-			ExpectedBody:     `{"type":"` + probs.V2ErrorNS + `malformed","detail":"unsupported revocation reason code provided","status":400}`,
+			ExpectedBody:     `{"type":"` + probs.V2ErrorNS + `badRevocationReason","detail":"unsupported revocation reason code provided","status":400}`,
 		},
 	}
 
@@ -2800,6 +3345,39 @@ func TestNewAccountWhenGetRegByKeyNotFound(t *testing.T) {
 	}`)
 }
 
+func TestNewAccountOnlyReturnExisting(t *testing.T) {
+	wfe, _ := setupWFE(t)
+	payload := `{"onlyReturnExisting":true}`
+	signedURL := "http://localhost/new-account"
+
+	// An existing account is returned without being changed
+	key := loadKey(t, []byte(test1KeyPrivatePEM))
+	responseWriter := httptest.NewRecorder()
+	_, _, body := signRequestEmbed(t, key, signedURL, payload, wfe.nonceService)
+	wfe.NewAccount(ctx, newRequestEvent(), responseWriter, makePostRequestWithPath("/new-account", body))
+	test.AssertEquals(t, responseWriter.Code, http.StatusOK)
+	test.AssertEquals(t, responseWriter.Header().Get("Location"), "http://localhost/acme/acct/1")
+	var acct core.Registration
+	err := json.Unmarshal(responseWriter.Body.Bytes(), &acct)
+	test.AssertNotError(t, err, "Couldn't unmarshal returned account object")
+	test.AssertEquals(t, acct.ID, int64(1))
+	test.AssertEquals(t, acct.Agreement, "")
+
+	// A deactivated account isn't returned
+	key = loadKey(t, []byte(test3KeyPrivatePEM))
+	responseWriter = httptest.NewRecorder()
+	_, _, body = signRequestEmbed(t, key, signedURL, payload, wfe.nonceService)
+	wfe.NewAccount(ctx, newRequestEvent(), responseWriter, makePostRequestWithPath("/new-account", body))
+	test.AssertEquals(t, responseWriter.Code, http.StatusForbidden)
+	test.AssertEquals(t, responseWriter.Header().Get("Location"), "")
+	test.AssertUnmarshaledEquals(t, responseWriter.Body.String(), `
+	{
+		"type": "urn:ietf:params:acme:error:unauthorized",
+		"detail": "An account with the provided public key exists but is deactivated",
+		"status": 403
+	}`)
+}
+
 func TestPrepAuthzForDisplay(t *testing.T) {
 	wfe, _ := setupWFE(t)
 
@@ -2849,6 +3427,17 @@ func TestPrepAuthzForDisplay(t *testing.T) {
 	chal = authz.Challenges[0]
 	test.AssertEquals(t, chal.URL, "http://localhost/acme/challenge/v2/12345/po1V2w==")
 	test.AssertEquals(t, chal.URI, "")
+	// Preparing the authz again doesn't lose the wildcard mark
+	test.AssertEquals(t, authz.Wildcard, true)
+
+	// An authz for a name that isn't a wildcard isn't marked as one
+	plain := &core.Authorization{
+		ID:         "12346",
+		Identifier: core.AcmeIdentifier{Type: "dns", Value: "example.com"},
+	}
+	wfe.prepAuthorizationForDisplay(&http.Request{Host: "localhost"}, plain)
+	test.AssertEquals(t, plain.Identifier.Value, "example.com")
+	test.AssertEquals(t, plain.Wildcard, false)
 }
 
 // noSCTMockRA is a mock RA that always returns a `berrors.MissingSCTsError` from `FinalizeOrder`